@@ -2,11 +2,21 @@ package app
 
 import (
 	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/audit"
+	"daily-notes/pkg/backup"
+	"daily-notes/pkg/buildinfo"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/jobs"
+	"daily-notes/pkg/maintenance"
+	"daily-notes/pkg/pubsub"
+	"daily-notes/pkg/rendercache"
 	"daily-notes/services"
 	"daily-notes/session"
 	"daily-notes/sync"
 	"daily-notes/validator"
 	"log/slog"
+	"time"
 )
 
 // App holds all application dependencies
@@ -18,19 +28,81 @@ type App struct {
 	SessionStore *session.Store
 	Validator    *validator.Validator
 	Logger       *slog.Logger
+	RenderCache  *rendercache.Cache
+	AuditLog     *audit.Log // Debug-mode request recordings
+	Clock        clock.Clock
+	TestClock    *clock.Fake                      // Set only in test mode
+	Updates      *buildinfo.UpdateChecker         // Set only when UPDATE_CHECK_REPO is
+	Backups      *backup.Scheduler                // Set only when BACKUP_DIR is
+	Jobs         *jobs.Queue                      // Background work that survives restarts; set by setup.InitApp
+	NoteEvents   *pubsub.Broker[models.NoteEvent] // Note changes by user ID, streamed by /ws
+	Maintenance  *maintenance.Mode                // Refuses writes while on; set by setup.InitApp
+	StartedAt    time.Time
 
 	// Services (Business Logic Layer)
 	NoteService    *services.NoteService
 	ContextService *services.ContextService
 	AuthService    *services.AuthService
+	PaletteService *services.PaletteService
+	ProfileService *services.ProfileService
+	ImportService  *services.ImportService
+	LinkPreviews   *services.LinkPreviewService // Fetches only when LINK_PREVIEWS is enabled
+	PublicService  *services.PublicService
+	PublishService *services.PublishService // Pushes only when publishing is enabled
+	StorageService *services.StorageProviderService
+	Timezones      *services.TimezoneService
+	TagService     *services.TagService // Runs tag renames and merges in the background
+	Attachments    *services.AttachmentService
+	APITokens      *services.APITokenService     // Tokens of integrations, limited to one context
+	APIKeys        *services.APIKeyService       // Keys of scripts and apps, read-only or read-write
+	NoteSchedules  *services.NoteScheduleService // Creates daily notes from templates at users' local times
+	Drops          *services.DropService         // Signed URLs appending to a note without signing in
+	Digests        *services.DigestService       // Weekly email digests; sends only when SMTP is configured
+	Reminders      *services.ReminderService     // Sends @remind tokens of notes by email and web push, when configured
+	Push           *services.PushService         // Web push notifications; sends only when VAPID keys are configured
+	Members        *services.MemberService       // Contexts shared with other accounts, and invitations to them
+	Encryption     *services.EncryptionService   // End-to-end encryption of notes; keys never reach the server
 }
 
 // New creates a new App instance with all dependencies
 func New(repo *database.Repository, syncWorker *sync.Worker, sessionStore *session.Store, storageFactory services.StorageFactory, logger *slog.Logger) *App {
 	// Create services with proper dependency injection
+	renderCache := rendercache.New(rendercache.DefaultMaxEntries)
+	noteEvents := pubsub.New[models.NoteEvent](pubsub.DefaultBuffer)
 	noteService := services.NewNoteService(repo, syncWorker)
+	noteService.SetRenderCache(renderCache)
+	noteService.SetNoteEvents(noteEvents)
+	if syncWorker != nil {
+		syncWorker.SetNoteEvents(noteEvents)
+	}
+	linkPreviews := services.NewLinkPreviewService(repo)
+	noteService.SetLinkPreviews(linkPreviews)
 	contextService := services.NewContextService(repo, storageFactory)
 	authService := services.NewAuthService(repo, sessionStore, syncWorker, storageFactory)
+	paletteService := services.NewPaletteService(repo)
+	profileService := services.NewProfileService(repo)
+	importService := services.NewImportService(repo)
+	importService.SetRenderCache(renderCache)
+	publicService := services.NewPublicService(repo)
+	publicService.SetRenderCache(renderCache)
+	publishService := services.NewPublishService(repo)
+	storageService := services.NewStorageProviderService(repo)
+	timezones := services.NewTimezoneService(repo)
+	tagService := services.NewTagService(noteService)
+	attachments := services.NewAttachmentService(repo, storageFactory)
+	apiTokens := services.NewAPITokenService(repo)
+	apiKeys := services.NewAPIKeyService(repo)
+	noteSchedules := services.NewNoteScheduleService(repo, noteService)
+	drops := services.NewDropService(repo, noteService)
+	digests := services.NewDigestService(repo)
+	reminders := services.NewReminderService(repo)
+	push := services.NewPushService(repo)
+	reminders.SetPusher(push)
+	members := services.NewMemberService(repo)
+	noteService.SetMembers(repo)
+	contextService.SetMembers(repo)
+	encryption := services.NewEncryptionService(repo)
+	noteService.SetEncryption(repo)
 
 	return &App{
 		// Infrastructure
@@ -39,10 +111,74 @@ func New(repo *database.Repository, syncWorker *sync.Worker, sessionStore *sessi
 		SessionStore: sessionStore,
 		Validator:    validator.New(),
 		Logger:       logger,
+		RenderCache:  renderCache,
+		NoteEvents:   noteEvents,
+		AuditLog:     audit.New(audit.DefaultCapacity, clock.Real()),
+		Clock:        clock.Real(),
+		StartedAt:    time.Now(),
 
 		// Services
 		NoteService:    noteService,
 		ContextService: contextService,
 		AuthService:    authService,
+		PaletteService: paletteService,
+		ProfileService: profileService,
+		ImportService:  importService,
+		LinkPreviews:   linkPreviews,
+		PublicService:  publicService,
+		PublishService: publishService,
+		StorageService: storageService,
+		Timezones:      timezones,
+		TagService:     tagService,
+		Attachments:    attachments,
+		APITokens:      apiTokens,
+		APIKeys:        apiKeys,
+		NoteSchedules:  noteSchedules,
+		Drops:          drops,
+		Digests:        digests,
+		Reminders:      reminders,
+		Push:           push,
+		Members:        members,
+		Encryption:     encryption,
 	}
 }
+
+// UseMaintenance makes the app refuse writes and hold the background work of
+// its services while mode is on. The sync worker and job queue take it before
+// they start (see setup.InitApp).
+func (a *App) UseMaintenance(mode *maintenance.Mode) {
+	a.Maintenance = mode
+	a.NoteSchedules.SetMaintenance(mode)
+	a.TagService.SetMaintenance(mode)
+	a.Digests.SetMaintenance(mode)
+	a.Reminders.SetMaintenance(mode)
+	a.PublishService.SetMaintenance(mode)
+}
+
+// UseClock points the app and every service at c
+// The session store and sync worker take their clock before they start (see setup.InitApp)
+func (a *App) UseClock(c clock.Clock) {
+	a.Clock = c
+	a.AuditLog.SetClock(c)
+	a.NoteService.SetClock(c)
+	a.ContextService.SetClock(c)
+	a.AuthService.SetClock(c)
+	a.PaletteService.SetClock(c)
+	a.ProfileService.SetClock(c)
+	a.ImportService.SetClock(c)
+	a.LinkPreviews.SetClock(c)
+	a.PublicService.SetClock(c)
+	a.PublishService.SetClock(c)
+	a.Timezones.SetClock(c)
+	a.TagService.SetClock(c)
+	a.Attachments.SetClock(c)
+	a.APITokens.SetClock(c)
+	a.APIKeys.SetClock(c)
+	a.NoteSchedules.SetClock(c)
+	a.Drops.SetClock(c)
+	a.Digests.SetClock(c)
+	a.Reminders.SetClock(c)
+	a.Push.SetClock(c)
+	a.Members.SetClock(c)
+	a.Encryption.SetClock(c)
+}
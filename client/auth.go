@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"daily-notes/models"
+	"net/http"
+)
+
+// User is the signed-in user as returned by the login and me endpoints
+type User struct {
+	ID            string              `json:"id"`
+	Email         string              `json:"email"`
+	Name          string              `json:"name"`
+	Picture       string              `json:"picture"`
+	Settings      models.UserSettings `json:"settings"`
+	HasNoContexts bool                `json:"hasNoContexts"`
+}
+
+// LoginWithIDToken signs in with a Google ID token; the session cookie is kept by the client
+func (c *Client) LoginWithIDToken(ctx context.Context, idToken string) (*User, error) {
+	return c.login(ctx, models.LoginRequest{IDToken: idToken})
+}
+
+// LoginWithToken signs in with a Google OAuth access token (and optional refresh token)
+func (c *Client) LoginWithToken(ctx context.Context, accessToken, refreshToken string, expiresIn int64) (*User, error) {
+	return c.login(ctx, models.LoginRequest{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    expiresIn,
+	})
+}
+
+func (c *Client) login(ctx context.Context, body models.LoginRequest) (*User, error) {
+	var resp struct {
+		User User `json:"user"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/api/auth/login", body: body}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.User, nil
+}
+
+// Me returns the user of the current session
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var resp struct {
+		Authenticated bool `json:"authenticated"`
+		User          User `json:"user"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/auth/me"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.User, nil
+}
+
+// Logout ends the current session
+func (c *Client) Logout(ctx context.Context) error {
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/api/auth/logout"}, nil)
+	return err
+}
+
+// UpdateSettings replaces the user's settings
+func (c *Client) UpdateSettings(ctx context.Context, settings models.UpdateSettingsRequest) (*models.UserSettings, error) {
+	var resp struct {
+		Settings models.UserSettings `json:"settings"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodPut, path: "/api/settings", body: settings}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Settings, nil
+}
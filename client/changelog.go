@@ -0,0 +1,34 @@
+package client
+
+import (
+	"context"
+	"daily-notes/models"
+	"net/http"
+)
+
+// Changelog returns the release notes with the ones the user hasn't seen yet
+func (c *Client) Changelog(ctx context.Context) (*models.Changelog, error) {
+	var resp struct {
+		Changelog models.Changelog `json:"changelog"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/changelog"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Changelog, nil
+}
+
+// MarkChangelogSeen records that the user was shown a release; "" marks the current one
+func (c *Client) MarkChangelogSeen(ctx context.Context, version string) (*models.Changelog, error) {
+	var resp struct {
+		Changelog models.Changelog `json:"changelog"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/changelog/seen",
+		body:   models.MarkChangelogSeenRequest{Version: version},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Changelog, nil
+}
@@ -0,0 +1,223 @@
+// Package client is a typed Go client for the daily-notes HTTP API.
+//
+// It is shared by the CLI, integration tests and third-party tools:
+//
+//	c, _ := client.New("https://dailynotes.example.com")
+//	if _, err := c.LoginWithIDToken(ctx, idToken); err != nil { ... }
+//	note, err := c.GetNote(ctx, "Work", "2025-10-17")
+//
+// Transient failures (network errors, 429 and 5xx) are retried with exponential
+// backoff. Writes carry an X-Idempotency-Key that stays the same across retries,
+// so a retried request is never applied twice by the server.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Defaults for retries
+const (
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 250 * time.Millisecond
+	maxBackoff        = 5 * time.Second
+)
+
+// IdempotencyKeyHeader is the header the server uses to deduplicate retried writes
+const IdempotencyKeyHeader = "X-Idempotency-Key"
+
+// Client talks to a daily-notes server
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient uses a custom HTTP client. Its cookie jar keeps the session after login.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithBearerToken authenticates every request with a Google ID token instead of a session
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithAPIKey authenticates every request with an API key ("dn_key_..."), as
+// scripts and the CLI do
+func WithAPIKey(key string) Option {
+	return WithBearerToken(key)
+}
+
+// WithRetries sets how many times transient failures are retried and the initial backoff
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New creates a client for the server at baseURL
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL: %q", baseURL)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Jar: jar, Timeout: 30 * time.Second},
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// request describes a single API call
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	header http.Header
+
+	// raw is sent as is instead of JSON-encoding body, e.g. multipart uploads
+	raw         []byte
+	contentType string
+}
+
+// do sends the request, retrying transient failures, and decodes the JSON response into out
+// It returns the final response headers for callers that need them (e.g. ETag)
+func (c *Client) do(ctx context.Context, req request, out interface{}) (http.Header, error) {
+	payload := req.raw
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return nil, err
+		}
+	}
+
+	target := *c.baseURL
+	target.Path += req.path
+	target.RawQuery = req.query.Encode()
+
+	// One key per logical write, reused by every retry
+	idempotencyKey := ""
+	if req.method != http.MethodGet && req.method != http.MethodHead {
+		idempotencyKey = uuid.New().String()
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req, target.String(), payload, idempotencyKey)
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			defer resp.Body.Close()
+			return resp.Header, decode(resp, out)
+		}
+
+		if err == nil {
+			// Keep the last error response in case we run out of retries
+			err = decode(resp, nil)
+			resp.Body.Close()
+		}
+		if attempt >= c.maxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// send performs one HTTP round trip
+func (c *Client) send(ctx context.Context, req request, target string, payload []byte, idempotencyKey string) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	} else if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if idempotencyKey != "" {
+		httpReq.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+	}
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return c.httpClient.Do(httpReq)
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests ||
+		status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout
+}
+
+// decode turns a response into out, or into an *APIError for non-2xx statuses
+func decode(resp *http.Response, out interface{}) error {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if out == nil || len(data) == 0 {
+			return nil
+		}
+		return json.Unmarshal(data, out)
+	}
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, raw: data}
+	if jsonErr := json.Unmarshal(data, apiErr); jsonErr != nil || apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// AsAPIError unwraps err into an *APIError if possible
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	ok := errors.As(err, &apiErr)
+	return apiErr, ok
+}
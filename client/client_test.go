@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL, WithRetries(3, time.Millisecond))
+	require.NoError(t, err)
+	return c
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("not a url")
+	assert.Error(t, err)
+
+	c, err := New("http://localhost:3000/")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:3000", c.baseURL.String())
+}
+
+func TestRetriesKeepIdempotencyKey(t *testing.T) {
+	var keys []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if len(keys) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"note": map[string]interface{}{"context": "Work", "date": "2025-10-17", "content": "hi", "revision": 1},
+		})
+	})
+
+	note, err := c.SaveNote(context.Background(), "Work", "2025-10-17", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, 1, note.Revision)
+
+	require.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+}
+
+func TestGivesUpAfterMaxRetries(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"Rate limit exceeded"}`))
+	})
+
+	_, err := c.ListContexts(context.Background())
+	apiErr, ok := AsAPIError(err)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Equal(t, "Rate limit exceeded", apiErr.Message)
+	assert.Equal(t, 4, calls)
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"Validation failed","errors":[{"field":"name","message":"name is required","tag":"required"}]}`))
+	})
+
+	_, err := c.CreateContext(context.Background(), "", "primary")
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, err.Error(), "name is required")
+}
+
+func TestSaveNoteAtRevisionConflict(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(t, float64(2), body["revision"])
+
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"Note was updated elsewhere.","note":{"content":"theirs","revision":3}}`))
+	})
+
+	current, err := c.SaveNoteAtRevision(context.Background(), "Work", "2025-10-17", "mine", 2)
+	assert.True(t, errors.Is(err, ErrConflict))
+	require.NotNil(t, current)
+	assert.Equal(t, "theirs", current.Content)
+	assert.Equal(t, 3, current.Revision)
+}
+
+func TestBearerTokenAndQuery(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer id-token", r.Header.Get("Authorization"))
+		assert.Empty(t, r.Header.Get(IdempotencyKeyHeader))
+		assert.Equal(t, "/api/notes", r.URL.Path)
+		assert.Equal(t, "Work & Life", r.URL.Query().Get("context"))
+		w.Write([]byte(`{"note":{"context":"Work & Life","date":"2025-10-17"},"parents":[{"type":"week","key":"2025-W42"}]}`))
+	})
+	WithBearerToken("id-token")(c)
+
+	result, err := c.GetNote(context.Background(), "Work & Life", "2025-10-17")
+	require.NoError(t, err)
+	assert.Equal(t, "Work & Life", result.Note.Context)
+	require.Len(t, result.Parents, 1)
+	assert.Equal(t, "2025-W42", result.Parents[0].Key)
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"daily-notes/models"
+	"net/http"
+	"net/url"
+)
+
+// ListContexts returns the user's contexts
+func (c *Client) ListContexts(ctx context.Context) ([]models.Context, error) {
+	var resp struct {
+		Contexts []models.Context `json:"contexts"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/contexts"}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Contexts, nil
+}
+
+// CreateContext creates a context
+func (c *Client) CreateContext(ctx context.Context, name, color string) (*models.Context, error) {
+	var resp struct {
+		Context models.Context `json:"context"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/contexts",
+		body:   models.CreateContextRequest{Name: name, Color: color},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Context, nil
+}
+
+// UpdateContext renames or recolors a context
+func (c *Client) UpdateContext(ctx context.Context, id, name, color string) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/api/contexts/" + url.PathEscape(id),
+		body:   models.UpdateContextRequest{Name: name, Color: color},
+	}, nil)
+	return err
+}
+
+// ContextStats returns the note count, first and last note dates, words and
+// sync state of a context
+func (c *Client) ContextStats(ctx context.Context, id string) (*models.ContextStats, error) {
+	var resp struct {
+		Stats models.ContextStats `json:"stats"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/contexts/" + url.PathEscape(id) + "/stats"}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Stats, nil
+}
+
+// SetContextTemplate sets the template used for new notes in a context
+func (c *Client) SetContextTemplate(ctx context.Context, id, template string) (*models.Context, error) {
+	var resp struct {
+		Context models.Context `json:"context"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/api/contexts/" + url.PathEscape(id) + "/template",
+		body:   models.UpdateContextTemplateRequest{Template: template},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Context, nil
+}
+
+// DeleteContext deletes a context and its notes (restorable from the trash for a while)
+func (c *Client) DeleteContext(ctx context.Context, id string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/api/contexts/" + url.PathEscape(id)}, nil)
+	return err
+}
+
+// ListContextTrash returns deleted contexts that can still be restored
+func (c *Client) ListContextTrash(ctx context.Context) ([]models.TrashedContext, error) {
+	var resp struct {
+		Contexts []models.TrashedContext `json:"contexts"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/contexts/trash"}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Contexts, nil
+}
+
+// RestoreContext brings a deleted context back
+func (c *Client) RestoreContext(ctx context.Context, id string) (*models.Context, error) {
+	var resp struct {
+		Context models.Context `json:"context"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/api/contexts/trash/" + url.PathEscape(id) + "/restore",
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Context, nil
+}
+
+// ListPublicContexts returns the user's contexts published at /@handle
+func (c *Client) ListPublicContexts(ctx context.Context) ([]models.PublicContext, error) {
+	var resp struct {
+		Public []models.PublicContext `json:"public"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/contexts/public"}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Public, nil
+}
+
+// PublishContext publishes a context read-only at /@handle, or changes its handle
+func (c *Client) PublishContext(ctx context.Context, id, handle string, indexable bool) (*models.PublicContext, error) {
+	var resp struct {
+		Public models.PublicContext `json:"public"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/api/contexts/" + url.PathEscape(id) + "/public",
+		body:   models.PublishContextRequest{Handle: handle, Indexable: indexable},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Public, nil
+}
+
+// UnpublishContext takes a context off its public page
+func (c *Client) UnpublishContext(ctx context.Context, id string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/api/contexts/" + url.PathEscape(id) + "/public"}, nil)
+	return err
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrConflict is returned when a save is rejected because the note changed on the server
+var ErrConflict = errors.New("note was updated elsewhere")
+
+// FieldError describes one invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Tag     string `json:"tag"`
+}
+
+// APIError is a non-2xx response from the server
+type APIError struct {
+	StatusCode int          `json:"-"`
+	Message    string       `json:"error"`
+	Errors     []FieldError `json:"errors,omitempty"`
+
+	raw []byte
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("daily-notes: %d %s", e.StatusCode, e.Message)
+	}
+
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Message
+	}
+	return fmt.Sprintf("daily-notes: %d %s: %s", e.StatusCode, e.Message, strings.Join(msgs, "; "))
+}
+
+// Is lets callers match conflicts with errors.Is(err, client.ErrConflict)
+func (e *APIError) Is(target error) bool {
+	return target == ErrConflict && e.StatusCode == http.StatusConflict
+}
+
+// decodeBody unmarshals the raw error body into v (e.g. the current note of a conflict)
+func (e *APIError) decodeBody(v interface{}) error {
+	return json.Unmarshal(e.raw, v)
+}
+
+// IsNotFound reports whether err is a 404 from the server
+func IsNotFound(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsUnauthorized reports whether err means the client must log in again
+func IsUnauthorized(err error) bool {
+	apiErr, ok := AsAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusUnauthorized
+}
@@ -0,0 +1,442 @@
+package client
+
+import (
+	"context"
+	"daily-notes/models"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// NoteResult is a note together with the links to the week, month and year notes containing it
+// and the previews of the web pages it links to
+type NoteResult struct {
+	Note         models.Note              `json:"note"`
+	Parents      []models.NoteLink        `json:"parents"`
+	LinkPreviews []models.LinkPreview     `json:"link_previews"`
+	Publications []models.NotePublication `json:"publications"`
+}
+
+// PeriodNoteResult is a week, month or year note with its rollup and parents
+type PeriodNoteResult struct {
+	Note    models.Note       `json:"note"`
+	Rollup  []models.NoteLink `json:"rollup"`
+	Parents []models.NoteLink `json:"parents"`
+}
+
+// GetNote fetches the note of a context for a date (YYYY-MM-DD)
+// Missing notes come back empty with revision 0
+func (c *Client) GetNote(ctx context.Context, contextName, date string) (*NoteResult, error) {
+	var resp NoteResult
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/notes",
+		query:  url.Values{"context": {contextName}, "date": {date}},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SaveNote creates or overwrites a note
+func (c *Client) SaveNote(ctx context.Context, contextName, date, content string) (*models.Note, error) {
+	return c.saveNote(ctx, "/api/notes", models.CreateNoteRequest{
+		Context: contextName,
+		Date:    date,
+		Content: content,
+	})
+}
+
+// SaveNoteAtRevision saves a note only if it is still at revision (0 = must not exist yet)
+// On conflict it returns the server's current note and an error matching ErrConflict
+func (c *Client) SaveNoteAtRevision(ctx context.Context, contextName, date, content string, revision int) (*models.Note, error) {
+	return c.saveNote(ctx, "/api/notes", models.CreateNoteRequest{
+		Context:  contextName,
+		Date:     date,
+		Content:  content,
+		Revision: &revision,
+	})
+}
+
+// AppendNote adds content under the markdown heading section of a note ("" appends to the end)
+// Missing notes and sections are created.
+func (c *Client) AppendNote(ctx context.Context, contextName, date, content, section string) (*models.Note, error) {
+	return c.saveNote(ctx, "/api/notes", models.CreateNoteRequest{
+		Context: contextName,
+		Date:    date,
+		Content: content,
+		Append:  true,
+		Section: section,
+	})
+}
+
+// QuickAppend adds a line or block to a note through POST /api/notes/append,
+// which fills in today's date and the timestamp the request asks for
+func (c *Client) QuickAppend(ctx context.Context, req models.AppendNoteRequest) (*models.Note, error) {
+	return c.saveNote(ctx, "/api/notes/append", req)
+}
+
+// SavePeriodNote creates or overwrites a week, month or year note
+func (c *Client) SavePeriodNote(ctx context.Context, contextName, noteType, key, content string) (*models.Note, error) {
+	return c.saveNote(ctx, "/api/notes/period", models.UpsertPeriodNoteRequest{
+		Context: contextName,
+		Type:    noteType,
+		Key:     key,
+		Content: content,
+	})
+}
+
+// SaveNotes saves several notes in one request; either all of them are saved or none is
+// Meant for catching up on edits made offline. A note may only be listed once.
+func (c *Client) SaveNotes(ctx context.Context, notes []models.BatchNoteItem) ([]models.Note, error) {
+	var resp struct {
+		Notes []models.Note `json:"notes"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/api/notes/batch", body: notes}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Notes, nil
+}
+
+func (c *Client) saveNote(ctx context.Context, path string, body interface{}) (*models.Note, error) {
+	var resp struct {
+		Note models.Note `json:"note"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodPost, path: path, body: body}, &resp)
+	if err != nil {
+		if apiErr, ok := AsAPIError(err); ok && errors.Is(err, ErrConflict) {
+			if decodeErr := apiErr.decodeBody(&resp); decodeErr == nil {
+				return &resp.Note, err
+			}
+		}
+		return nil, err
+	}
+	return &resp.Note, nil
+}
+
+// GetPeriodNote fetches a week (2025-W42), month (2025-10) or year (2025) note
+func (c *Client) GetPeriodNote(ctx context.Context, contextName, noteType, key string) (*PeriodNoteResult, error) {
+	var resp PeriodNoteResult
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/notes/period",
+		query:  url.Values{"context": {contextName}, "type": {noteType}, "key": {key}},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SeedPeriodNote creates a period note pre-filled with a digest of the notes it covers
+func (c *Client) SeedPeriodNote(ctx context.Context, contextName, noteType, key string) (*models.Note, error) {
+	return c.saveNote(ctx, "/api/notes/period/seed", models.SeedPeriodNoteRequest{
+		Context: contextName,
+		Type:    noteType,
+		Key:     key,
+	})
+}
+
+// ListNotes lists the notes of a context, newest first (content is not included)
+func (c *Client) ListNotes(ctx context.Context, contextName string, limit, offset int) ([]models.Note, error) {
+	var resp struct {
+		Notes []models.Note `json:"notes"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/notes/list",
+		query: url.Values{
+			"context": {contextName},
+			"limit":   {strconv.Itoa(limit)},
+			"offset":  {strconv.Itoa(offset)},
+		},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Notes, nil
+}
+
+// ListTags lists the user's #tags with how many notes use each, most used first
+func (c *Client) ListTags(ctx context.Context) ([]models.Tag, error) {
+	var resp struct {
+		Tags []models.Tag `json:"tags"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/tags"}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Tags, nil
+}
+
+// ListNotesByTag lists the notes tagged with tag across contexts, newest first (content is not included)
+func (c *Client) ListNotesByTag(ctx context.Context, tag string, limit, offset int) ([]models.Note, error) {
+	var resp struct {
+		Notes []models.Note `json:"notes"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/notes/by-tag",
+		query: url.Values{
+			"tag":    {tag},
+			"limit":  {strconv.Itoa(limit)},
+			"offset": {strconv.Itoa(offset)},
+		},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Notes, nil
+}
+
+// SearchNotes finds the notes matching query, best matches first, with snippets
+// in which matches are wrapped in <mark>
+func (c *Client) SearchNotes(ctx context.Context, query string, filter models.NoteSearchFilter, limit, offset int) ([]models.NoteSearchResult, error) {
+	params := url.Values{"q": {query}}
+	if filter.Context != "" {
+		params.Set("context", filter.Context)
+	}
+	if filter.From != "" {
+		params.Set("from", filter.From)
+	}
+	if filter.To != "" {
+		params.Set("to", filter.To)
+	}
+	if filter.SyncStatus != "" {
+		params.Set("sync_status", string(filter.SyncStatus))
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		params.Set("offset", strconv.Itoa(offset))
+	}
+
+	var resp struct {
+		Results []models.NoteSearchResult `json:"results"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/notes/search", query: params}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// ListNoteRevisions lists the earlier versions of a note, newest first
+func (c *Client) ListNoteRevisions(ctx context.Context, contextName, date string) ([]models.NoteRevision, error) {
+	var resp struct {
+		Revisions []models.NoteRevision `json:"revisions"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/notes/revisions",
+		query:  url.Values{"context": {contextName}, "date": {date}},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Revisions, nil
+}
+
+// RestoreNoteRevision makes an earlier version the current content of its note
+// The replaced content is kept as a revision, so the restore can be undone.
+func (c *Client) RestoreNoteRevision(ctx context.Context, id int64) (*models.Note, error) {
+	return c.saveNote(ctx, "/api/notes/revisions/"+strconv.FormatInt(id, 10)+"/restore", nil)
+}
+
+// ListNoteTrash lists the deleted notes that can still be restored, most
+// recently deleted first
+func (c *Client) ListNoteTrash(ctx context.Context) ([]models.TrashedNote, error) {
+	var resp struct {
+		Notes []models.TrashedNote `json:"notes"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/notes/trash"}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Notes, nil
+}
+
+// RestoreTrashedNote brings a deleted note back to its context and day
+func (c *Client) RestoreTrashedNote(ctx context.Context, id int64) (*models.Note, error) {
+	return c.saveNote(ctx, "/api/notes/"+strconv.FormatInt(id, 10)+"/restore", nil)
+}
+
+// ListConflicts lists the notes that changed both locally and in storage since their last sync
+func (c *Client) ListConflicts(ctx context.Context) ([]models.NoteConflict, error) {
+	var resp struct {
+		Conflicts []models.NoteConflict `json:"conflicts"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/notes/conflicts"}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Conflicts, nil
+}
+
+// ResolveConflict keeps the "local" or "remote" version of a conflicted note, or
+// saves content with the "merged" resolution
+func (c *Client) ResolveConflict(ctx context.Context, contextName, date, resolution, content string) (*models.Note, error) {
+	return c.saveNote(ctx, "/api/notes/conflicts/resolve", models.ResolveConflictRequest{
+		Context:    contextName,
+		Date:       date,
+		Resolution: resolution,
+		Content:    content,
+	})
+}
+
+// MonthNotesResult is the daily notes of a context for a calendar month
+type MonthNotesResult struct {
+	Notes     []models.MonthNote `json:"notes"`
+	Truncated bool               `json:"truncated"` // Some content was left out to keep the response small
+}
+
+// MonthNotes fetches every daily note of a context for a month in one request
+// include is "preview" (the default when empty) or "content"
+func (c *Client) MonthNotes(ctx context.Context, contextName string, year, month int, include string) (*MonthNotesResult, error) {
+	query := url.Values{
+		"context": {contextName},
+		"year":    {strconv.Itoa(year)},
+		"month":   {strconv.Itoa(month)},
+	}
+	if include != "" {
+		query.Set("include", include)
+	}
+
+	var resp MonthNotesResult
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/api/notes/month", query: query}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Agenda returns the daily notes of every context from from to to (YYYY-MM-DD, at most
+// 62 days), grouped by day with context colors and task rollups
+func (c *Client) Agenda(ctx context.Context, from, to string) (*models.Agenda, error) {
+	var resp struct {
+		Agenda models.Agenda `json:"agenda"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/notes/agenda",
+		query:  url.Values{"from": {from}, "to": {to}},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Agenda, nil
+}
+
+// Day returns the daily notes of every context on a date
+func (c *Client) Day(ctx context.Context, date string) (*models.AgendaDay, error) {
+	var resp struct {
+		Day models.AgendaDay `json:"day"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/notes/day",
+		query:  url.Values{"date": {date}},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Day, nil
+}
+
+// NoteSections lists the markdown headings of a note with the text under each
+func (c *Client) NoteSections(ctx context.Context, contextName, date string) ([]models.NoteSection, error) {
+	var resp struct {
+		Sections []models.NoteSection `json:"sections"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/notes/sections",
+		query:  url.Values{"context": {contextName}, "date": {date}},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Sections, nil
+}
+
+// UpdateNoteSection replaces the text under the heading with the given slug
+func (c *Client) UpdateNoteSection(ctx context.Context, contextName, date, slug, content string) (*models.Note, error) {
+	var resp struct {
+		Note models.Note `json:"note"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/api/notes/sections/" + url.PathEscape(slug),
+		body:   models.UpdateSectionRequest{Context: contextName, Date: date, Content: content},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Note, nil
+}
+
+// RelatedNotes returns past notes similar to the note of a context and date
+func (c *Client) RelatedNotes(ctx context.Context, contextName, date string, limit int) ([]models.RelatedNote, error) {
+	var resp struct {
+		Related []models.RelatedNote `json:"related"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/notes/related",
+		query: url.Values{
+			"context": {contextName},
+			"date":    {date},
+			"limit":   {strconv.Itoa(limit)},
+		},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Related, nil
+}
+
+// SplitResult is the two notes written by a split
+type SplitResult struct {
+	Source models.Note `json:"source"`
+	Target models.Note `json:"target"`
+}
+
+// SplitNote moves a line range of a note into the note of another date or context
+func (c *Client) SplitNote(ctx context.Context, req models.SplitNoteRequest) (*SplitResult, error) {
+	var resp SplitResult
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/api/notes/split", body: req}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CopyNotes copies notes to another context; set Date for one note or
+// StartDate and EndDate for a range of daily notes
+func (c *Client) CopyNotes(ctx context.Context, req models.TransferNotesRequest) ([]models.Note, error) {
+	return c.transferNotes(ctx, "/api/notes/copy", req)
+}
+
+// MoveNotes moves notes to another context; the originals are removed from storage by the sync worker
+func (c *Client) MoveNotes(ctx context.Context, req models.TransferNotesRequest) ([]models.Note, error) {
+	return c.transferNotes(ctx, "/api/notes/move", req)
+}
+
+func (c *Client) transferNotes(ctx context.Context, path string, req models.TransferNotesRequest) ([]models.Note, error) {
+	var resp struct {
+		Notes []models.Note `json:"notes"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: path, body: req}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Notes, nil
+}
+
+// DeleteNote deletes the note of a context for a date
+func (c *Client) DeleteNote(ctx context.Context, contextName, date string) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   "/api/notes/" + url.PathEscape(contextName) + "/" + url.PathEscape(date),
+	}, nil)
+	return err
+}
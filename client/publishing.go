@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"daily-notes/models"
+	"net/http"
+	"net/url"
+)
+
+// ListPublishTargets returns the external blogs the user publishes notes to
+func (c *Client) ListPublishTargets(ctx context.Context) ([]models.PublishTarget, error) {
+	var resp struct {
+		Targets []models.PublishTarget `json:"targets"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/publish/targets"}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Targets, nil
+}
+
+// CreatePublishTarget adds a Ghost, WordPress or Hugo publish target
+func (c *Client) CreatePublishTarget(ctx context.Context, target models.CreatePublishTargetRequest) (*models.PublishTarget, error) {
+	var resp struct {
+		Target models.PublishTarget `json:"target"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/api/publish/targets", body: target}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Target, nil
+}
+
+// DeletePublishTarget deletes a publish target; posts already published stay on the blog
+func (c *Client) DeletePublishTarget(ctx context.Context, id string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/api/publish/targets/" + url.PathEscape(id)}, nil)
+	return err
+}
+
+// PublishNote queues a note for publishing to a target; the outcome shows up in NoteResult.Publications
+func (c *Client) PublishNote(ctx context.Context, req models.PublishNoteRequest) (*models.NotePublication, error) {
+	var resp struct {
+		Publication models.NotePublication `json:"publication"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/api/notes/publish", body: req}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Publication, nil
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"daily-notes/models"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// SyncStatus summarises the Drive sync state of the user's notes
+type SyncStatus struct {
+	PendingCount int           `json:"pending_count"`
+	FailedCount  int           `json:"failed_count"`
+	FailedNotes  []models.Note `json:"failed_notes"`
+
+	FailedOperations []models.SyncOperation `json:"failed_operations"` // Context folder renames and deletions
+}
+
+// SyncStatus returns how many notes are waiting for or failed to sync
+func (c *Client) SyncStatus(ctx context.Context) (*SyncStatus, error) {
+	var resp struct {
+		SyncStatus SyncStatus `json:"sync_status"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/sync/status"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.SyncStatus, nil
+}
+
+// RetrySync queues a failed note for another sync attempt
+func (c *Client) RetrySync(ctx context.Context, noteID string) error {
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/api/sync/retry/" + url.PathEscape(noteID)}, nil)
+	return err
+}
+
+// RetryAllSync queues every failed note and context folder change for another
+// sync attempt, returning how many notes and operations were queued
+func (c *Client) RetryAllSync(ctx context.Context) (notes, operations int, err error) {
+	var resp struct {
+		Notes      int `json:"notes"`
+		Operations int `json:"operations"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/api/sync/retry-all"}, &resp); err != nil {
+		return 0, 0, err
+	}
+	return resp.Notes, resp.Operations, nil
+}
+
+// VerifySync checks the synced notes of a context against the content hashes kept in storage
+// Changed files are also reported as sync conflicts, see ListConflicts.
+func (c *Client) VerifySync(ctx context.Context, contextName string) (*models.SyncVerification, error) {
+	var resp struct {
+		Verification models.SyncVerification `json:"verification"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/sync/verify",
+		query:  url.Values{"context": {contextName}},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Verification, nil
+}
+
+// Storage returns the user's storage provider and the providers they can switch to
+func (c *Client) Storage(ctx context.Context) (*models.StorageStatus, error) {
+	var resp struct {
+		Storage models.StorageStatus `json:"storage"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/storage"}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Storage, nil
+}
+
+// SetStorageProvider switches the user's notes to another storage provider
+// Returns how many notes were queued for upload to it
+func (c *Client) SetStorageProvider(ctx context.Context, provider string) (int, error) {
+	var resp struct {
+		NotesQueued int `json:"notes_queued"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodPut,
+		path:   "/api/storage",
+		body:   models.UpdateStorageProviderRequest{Provider: provider},
+	}, &resp)
+	if err != nil {
+		return 0, err
+	}
+	return resp.NotesQueued, nil
+}
+
+// Search queries the command palette index (contexts, notes, tags and commands)
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]models.PaletteResult, error) {
+	var resp struct {
+		Results []models.PaletteResult `json:"results"`
+	}
+	_, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/palette",
+		query:  url.Values{"q": {query}, "limit": {strconv.Itoa(limit)}},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// ExportProfile downloads the user's settings and contexts
+func (c *Client) ExportProfile(ctx context.Context) (*models.Profile, error) {
+	var profile models.Profile
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/profile/export"}, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// ImportProfile applies an exported profile to the current user
+func (c *Client) ImportProfile(ctx context.Context, profile *models.Profile) (*models.ProfileImportResult, error) {
+	var resp struct {
+		Result models.ProfileImportResult `json:"result"`
+	}
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/api/profile/import", body: profile}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Result, nil
+}
+
+// ExportAccount downloads the user's profile together with all their notes
+func (c *Client) ExportAccount(ctx context.Context) (*models.AccountExport, error) {
+	var account models.AccountExport
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/export", query: url.Values{"format": {"json"}}}, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ImportAccount restores an account export: settings and contexts are applied like a
+// profile and every note is saved
+func (c *Client) ImportAccount(ctx context.Context, account *models.AccountExport) (*models.NoteImportResult, error) {
+	data, err := json.Marshal(account)
+	if err != nil {
+		return nil, err
+	}
+	return c.importArchive(ctx, data, "json")
+}
+
+// ImportNotes uploads a zip archive of context folders with one markdown file per day
+// (YYYY-MM-DD.md or DD-MM-YYYY.md) and returns what happened to each file
+func (c *Client) ImportNotes(ctx context.Context, archive []byte) (*models.NoteImportResult, error) {
+	return c.importArchive(ctx, archive, "folders")
+}
+
+// ImportObsidianVault uploads a zipped Obsidian vault, whose top folders become contexts,
+// and returns what happened to each file
+func (c *Client) ImportObsidianVault(ctx context.Context, archive []byte) (*models.NoteImportResult, error) {
+	return c.importArchive(ctx, archive, "obsidian")
+}
+
+// importArchive uploads a zip archive, or a JSON export, to /api/import in the given format
+func (c *Client) importArchive(ctx context.Context, archive []byte, format string) (*models.NoteImportResult, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("format", format); err != nil {
+		return nil, err
+	}
+	filename := "notes.zip"
+	if format == "json" {
+		filename = "export.json"
+	}
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(archive); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Result models.NoteImportResult `json:"result"`
+	}
+	_, err = c.do(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/import",
+		raw:         body.Bytes(),
+		contentType: form.FormDataContentType(),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Result, nil
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"daily-notes/models"
+	"net/http"
+)
+
+// VersionInfo is the server build, with the outcome of its update check when enabled
+type VersionInfo struct {
+	Build  models.BuildInfo     `json:"build"`
+	Update *models.UpdateStatus `json:"update,omitempty"`
+}
+
+// Version returns the server's version, commit and build date
+func (c *Client) Version(ctx context.Context) (*VersionInfo, error) {
+	var info VersionInfo
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/api/version"}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
@@ -0,0 +1,271 @@
+// Command daily-notes-cli adds to, shows and searches notes from a terminal,
+// through the HTTP API of a daily-notes server and an API key:
+//
+//	daily-notes-cli add --context work "Deployed the new search"
+//	daily-notes-cli show --date 2025-10-18
+//	daily-notes-cli search "search index"
+//
+// The server and key come from DAILY_NOTES_URL and DAILY_NOTES_API_KEY, or the
+// --server and --key flags; create a key with POST /api/keys.
+package main
+
+import (
+	"context"
+	"daily-notes/client"
+	"daily-notes/models"
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `usage: daily-notes-cli [--server URL] [--key KEY] <command> [flags] [args]
+
+commands:
+  add     [--context NAME] [--date YYYY-MM-DD] [--section HEADING] [--time] TEXT...
+          append TEXT (or standard input when TEXT is "-") to a note, today's by default;
+          --time starts it with the time, in your timezone setting
+  show    [--context NAME] [--date YYYY-MM-DD]
+          print a note, or the notes of every context when --context isn't given
+  search  [--context NAME] [--limit N] QUERY...
+          list the notes matching QUERY, best matches first
+
+environment:
+  DAILY_NOTES_URL      server address (default http://localhost:3000)
+  DAILY_NOTES_API_KEY  API key, from POST /api/keys ("dn_key_...")
+  DAILY_NOTES_CONTEXT  context used when --context isn't given
+`
+
+// errUsage is returned for invalid command lines, after the usage is printed
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli is one invocation: the client and where output goes
+type cli struct {
+	client *client.Client
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	now    func() time.Time
+	// color wraps search matches in terminal bold when stdout is a terminal
+	color bool
+}
+
+// run runs a command line and returns the exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("daily-notes-cli", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	server := flags.String("server", getEnv("DAILY_NOTES_URL", "http://localhost:3000"), "server address")
+	key := flags.String("key", os.Getenv("DAILY_NOTES_API_KEY"), "API key")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if *key == "" {
+		fmt.Fprintln(stderr, "daily-notes-cli: set DAILY_NOTES_API_KEY or --key to an API key")
+		return 2
+	}
+
+	c, err := client.New(*server, client.WithAPIKey(*key))
+	if err != nil {
+		fmt.Fprintln(stderr, "daily-notes-cli:", err)
+		return 2
+	}
+	cmd := &cli{client: c, stdin: stdin, stdout: stdout, stderr: stderr, now: time.Now, color: isTerminal(stdout)}
+
+	command, rest := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "add":
+		err = cmd.add(ctx, rest)
+	case "show":
+		err = cmd.show(ctx, rest)
+	case "search":
+		err = cmd.search(ctx, rest)
+	default:
+		fmt.Fprintf(stderr, "daily-notes-cli: unknown command %q\n\n%s", command, usage)
+		return 2
+	}
+
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case err != nil:
+		fmt.Fprintln(stderr, "daily-notes-cli:", err)
+		return 1
+	}
+	return 0
+}
+
+// add appends text to a note
+func (cmd *cli) add(ctx context.Context, args []string) error {
+	flags := cmd.flags("add")
+	contextName := flags.String("context", os.Getenv("DAILY_NOTES_CONTEXT"), "context of the note")
+	date := flags.String("date", "", "date of the note (default today)")
+	section := flags.String("section", "", "heading to append under, created if missing")
+	stamp := flags.Bool("time", false, "start the entry with the time")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if *contextName == "" || flags.NArg() == 0 {
+		fmt.Fprint(cmd.stderr, usage)
+		return errUsage
+	}
+
+	text := strings.Join(flags.Args(), " ")
+	if text == "-" {
+		data, err := io.ReadAll(cmd.stdin)
+		if err != nil {
+			return err
+		}
+		text = strings.TrimRight(string(data), "\n")
+	}
+	if strings.TrimSpace(text) == "" {
+		return errors.New("nothing to add")
+	}
+
+	var note *models.Note
+	var err error
+	if *stamp {
+		// The server takes the time, so it is the same one the note's day was picked by
+		note, err = cmd.client.QuickAppend(ctx, models.AppendNoteRequest{
+			Context: *contextName, Date: *date, Content: text, Section: *section, Timestamp: true,
+		})
+	} else {
+		note, err = cmd.client.AppendNote(ctx, *contextName, cmd.date(*date), text, *section)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.stdout, "Added to %s %s\n", note.Context, note.Date)
+	return nil
+}
+
+// show prints the note of a context, or every context's notes of a day
+func (cmd *cli) show(ctx context.Context, args []string) error {
+	flags := cmd.flags("show")
+	contextName := flags.String("context", os.Getenv("DAILY_NOTES_CONTEXT"), "context of the note (default every context)")
+	date := flags.String("date", "", "date of the note (default today)")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprint(cmd.stderr, usage)
+		return errUsage
+	}
+	day := cmd.date(*date)
+
+	if *contextName != "" {
+		result, err := cmd.client.GetNote(ctx, *contextName, day)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(result.Note.Content) == "" {
+			fmt.Fprintf(cmd.stderr, "No note in %s on %s\n", *contextName, day)
+			return nil
+		}
+		fmt.Fprintln(cmd.stdout, strings.TrimRight(result.Note.Content, "\n"))
+		return nil
+	}
+
+	notes, err := cmd.client.Day(ctx, day)
+	if err != nil {
+		return err
+	}
+	printed := 0
+	for _, note := range notes.Notes {
+		if strings.TrimSpace(note.Content) == "" {
+			continue
+		}
+		if printed > 0 {
+			fmt.Fprintln(cmd.stdout)
+		}
+		fmt.Fprintf(cmd.stdout, "# %s\n\n%s\n", note.Context, strings.TrimRight(note.Content, "\n"))
+		printed++
+	}
+	if printed == 0 {
+		fmt.Fprintf(cmd.stderr, "No notes on %s\n", day)
+	}
+	return nil
+}
+
+// search lists the notes matching a query, one per line
+func (cmd *cli) search(ctx context.Context, args []string) error {
+	flags := cmd.flags("search")
+	contextName := flags.String("context", "", "only search this context")
+	limit := flags.Int("limit", 20, "most results to list")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprint(cmd.stderr, usage)
+		return errUsage
+	}
+
+	results, err := cmd.client.SearchNotes(ctx, strings.Join(flags.Args(), " "), models.NoteSearchFilter{Context: *contextName}, *limit, 0)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(cmd.stderr, "No matches")
+		return nil
+	}
+	for _, result := range results {
+		fmt.Fprintf(cmd.stdout, "%s  %s  %s\n", result.Date, result.Context, cmd.snippet(result.Snippet))
+	}
+	return nil
+}
+
+// flags returns the flag set of a command, reporting errors on stderr
+func (cmd *cli) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(cmd.stderr)
+	flags.Usage = func() { fmt.Fprint(cmd.stderr, usage) }
+	return flags
+}
+
+// date returns the date given with --date, or today's local date
+func (cmd *cli) date(date string) string {
+	if date != "" {
+		return date
+	}
+	return cmd.now().Format("2006-01-02")
+}
+
+// snippet turns a search snippet, HTML with matches in <mark>, into one line of
+// text with matches in bold on terminals
+func (cmd *cli) snippet(snippet string) string {
+	start, end := "", ""
+	if cmd.color {
+		start, end = "\x1b[1m", "\x1b[0m"
+	}
+	snippet = strings.NewReplacer("<mark>", start, "</mark>", end, "\n", " ").Replace(snippet)
+	return html.UnescapeString(snippet)
+}
+
+// isTerminal reports whether w is a terminal rather than a pipe or file
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCLI(t *testing.T) {
+	var requests []*http.Request
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		switch r.URL.Path {
+		case "/api/notes":
+			if r.Method == http.MethodPost {
+				json.NewEncoder(w).Encode(map[string]any{"note": map[string]any{"context": body["context"], "date": body["date"]}})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"note": map[string]any{"content": "Standup at 10\n"}})
+		case "/api/notes/append":
+			json.NewEncoder(w).Encode(map[string]any{"note": map[string]any{"context": body["context"], "date": "2025-10-18"}})
+		case "/api/notes/day":
+			json.NewEncoder(w).Encode(map[string]any{"day": map[string]any{"notes": []any{
+				map[string]any{"context": "Work", "content": "Standup"},
+				map[string]any{"context": "Home", "content": ""},
+			}}})
+		case "/api/notes/search":
+			json.NewEncoder(w).Encode(map[string]any{"results": []any{
+				map[string]any{"context": "Work", "date": "2025-10-17", "snippet": "rebuilt the <mark>search</mark> &amp; index"},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cli := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), append([]string{"--server", server.URL, "--key", "dn_key_test"}, args...),
+			strings.NewReader(stdin), &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	t.Run("add appends with the API key", func(t *testing.T) {
+		code, stdout, _ := cli("", "add", "--context", "Work", "--date", "2025-10-18", "Deployed", "search")
+		require.Equal(t, 0, code)
+		assert.Equal(t, "Added to Work 2025-10-18\n", stdout)
+		last := len(requests) - 1
+		assert.Equal(t, "Bearer dn_key_test", requests[last].Header.Get("Authorization"))
+		assert.Equal(t, "Deployed search", bodies[last]["content"])
+		assert.Equal(t, true, bodies[last]["append"])
+	})
+
+	t.Run("add reads standard input", func(t *testing.T) {
+		code, _, _ := cli("- milk\n- eggs\n", "add", "--context", "Home", "-")
+		require.Equal(t, 0, code)
+		assert.Equal(t, "- milk\n- eggs", bodies[len(bodies)-1]["content"])
+	})
+
+	t.Run("add --time leaves the day and time to the server", func(t *testing.T) {
+		code, stdout, _ := cli("", "add", "--context", "Work", "--time", "Deployed")
+		require.Equal(t, 0, code)
+		assert.Equal(t, "Added to Work 2025-10-18\n", stdout)
+		last := len(requests) - 1
+		assert.Equal(t, "/api/notes/append", requests[last].URL.Path)
+		assert.Equal(t, true, bodies[last]["timestamp"])
+		assert.Nil(t, bodies[last]["date"])
+	})
+
+	t.Run("show prints one note or the day's", func(t *testing.T) {
+		_, stdout, _ := cli("", "show", "--context", "Work", "--date", "2025-10-18")
+		assert.Equal(t, "Standup at 10\n", stdout)
+		assert.Equal(t, "2025-10-18", requests[len(requests)-1].URL.Query().Get("date"))
+
+		_, stdout, _ = cli("", "show", "--date", "2025-10-18")
+		assert.Equal(t, "# Work\n\nStandup\n", stdout)
+		assert.Equal(t, "2025-10-18", requests[len(requests)-1].URL.Query().Get("date"))
+	})
+
+	t.Run("search lists matches", func(t *testing.T) {
+		_, stdout, _ := cli("", "search", "search", "index")
+		assert.Equal(t, "2025-10-17  Work  rebuilt the search & index\n", stdout)
+		assert.Equal(t, "search index", requests[len(requests)-1].URL.Query().Get("q"))
+	})
+
+	t.Run("Usage errors", func(t *testing.T) {
+		code, _, _ := cli("", "add", "--context", "Work")
+		assert.Equal(t, 2, code)
+		code, _, stderr := cli("", "delete")
+		assert.Equal(t, 2, code)
+		assert.Contains(t, stderr, "unknown command")
+	})
+}
@@ -3,17 +3,74 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Port               string
-	Env                string
-	GoogleClientID     string
-	GoogleClientSecret string
-	GoogleRedirectURL  string
-	OpenAIAPIKey       string
+	Port                string
+	Env                 string
+	GoogleClientID      string
+	GoogleClientSecret  string
+	GoogleRedirectURL   string
+	OpenAIAPIKey        string
+	WeatherLocation     string        // Enables the {{weather}} template placeholder
+	TestMode            bool          // Fake clock, seeded demo user and /api/test endpoints
+	TestModeStart       string        // RFC3339 start time of the fake clock
+	SeedFile            string        // YAML or JSON fixture of users, contexts and notes loaded on first start
+	QueryTimeout        time.Duration // Deadline for single-row queries and writes
+	ScanTimeout         time.Duration // Deadline for queries over all of a user's notes
+	StorageTimeout      time.Duration // Deadline for cloud storage operations
+	SyncVerifyInterval  time.Duration // How often synced notes are checked against their content hashes; 0 disables it
+	SyncPullInterval    time.Duration // How often notes changed in storage are pulled; 0 disables it
+	SyncArchiveInterval time.Duration // How often a compressed archive of each user's notes is uploaded to storage; 0 disables it
+	SyncArchiveKeep     int           // Archives kept per user in storage
+	SyncDebounce        time.Duration // How long autosaved notes wait for the saves to pause before syncing; 0 syncs each save
+	DriveWebhookURL     string        // Public address of /api/drive/webhook; enables Drive change notifications
+	NoteSizeWarning     int           // Notes above this many bytes get a size warning on save; 0 disables it
+	NoteRevisions       int           // Earlier versions kept per note; 0 disables the revision history
+	NoteTrashDays       int           // Days deleted notes can be restored; 0 deletes them for good
+	DropboxAppKey       string        // Enables Dropbox as a storage provider
+	DropboxAppSecret    string
+	DropboxRedirectURL  string // OAuth callback, e.g. https://example.com/api/storage/dropbox/callback
+	LocalStorageDir     string // Enables storing notes on this server's disk
+	S3Bucket            string // Enables an S3-compatible bucket as a storage provider
+	S3Prefix            string // Key prefix inside the bucket, e.g. "daily-notes"
+	S3Region            string
+	S3Endpoint          string // Non-AWS services (MinIO, Backblaze B2), e.g. https://s3.us-west-004.backblazeb2.com
+	S3AccessKeyID       string
+	S3SecretAccessKey   string
+	S3PathStyle         bool   // Bucket in the URL path instead of the host name, as MinIO expects
+	WebDAVURL           string // Enables a WebDAV folder (Nextcloud, ownCloud) as a storage provider
+	WebDAVUsername      string
+	WebDAVPassword      string        // App password for Nextcloud accounts with two-factor login
+	NoteFilenamePattern string        // dd-mm-yyyy or yyyy-mm-dd; see storage.SetFilenamePattern
+	SupportToken        string        // Enables /api/support endpoints for holders of this token
+	UpdateCheckRepo     string        // GitHub repository (owner/name) checked for newer releases; empty disables the check
+	LinkPreviews        bool          // Fetch titles and descriptions of web pages linked from saved notes
+	DBDriver            string        // sqlite (default) or postgres
+	DBDSN               string        // PostgreSQL connection URL; SQLite uses DB_PATH when empty
+	BackupDir           string        // Enables scheduled database backups into this directory
+	BackupInterval      time.Duration // How often the database is backed up
+	BackupRetention     int           // Backups kept; older ones are deleted
+	AttachmentsDir      string        // Where files attached to notes are kept, one folder per user
+	AttachmentMaxSize   int           // Largest attachment accepted, in bytes
+	MaintenanceDir      string        // Where the maintenance mode state and its journal of note saves are kept
+	DropSecret          string        // Key signing drop box URLs, which append to a note without signing in; empty disables them
+	DropMaxSize         int           // Largest payload accepted by a drop box URL, in bytes
+	SMTPHost            string        // Mail server for weekly digests; empty disables email
+	SMTPPort            int           // 465 for TLS from the start, else STARTTLS when offered
+	SMTPUsername        string        // Empty sends without signing in
+	SMTPPassword        string
+	SMTPFrom            string // Sender of emails, e.g. "Daily Notes <notes@example.com>"
+	PublicURL           string // Address users reach the server at, for links in emails, e.g. https://notes.example.com
+	VAPIDPublicKey      string // Web push key pair, from "daily-notes vapid-keys"; empty disables push notifications
+	VAPIDPrivateKey     string
+	VAPIDSubject        string // How push services reach the operator, a mailto: or https: URL; PUBLIC_URL when empty
+	SessionEncKey       string // Base64 AES-256 key encrypting OAuth tokens and publish target secrets at rest; empty keeps them in plaintext
 }
 
 var AppConfig *Config
@@ -22,12 +79,76 @@ func Load() {
 	_ = godotenv.Load()
 
 	AppConfig = &Config{
-		Port:               GetEnv("PORT", "3000"),
-		Env:                GetEnv("ENV", "development"),
-		GoogleClientID:     GetEnv("GOOGLE_CLIENT_ID", ""),
-		GoogleClientSecret: GetEnv("GOOGLE_CLIENT_SECRET", ""),
-		GoogleRedirectURL:  GetEnv("GOOGLE_REDIRECT_URL", "postmessage"),
-		OpenAIAPIKey:       GetEnv("OPENAI_API_KEY", ""),
+		Port:                GetEnv("PORT", "3000"),
+		Env:                 GetEnv("ENV", "development"),
+		GoogleClientID:      GetEnv("GOOGLE_CLIENT_ID", ""),
+		GoogleClientSecret:  GetEnv("GOOGLE_CLIENT_SECRET", ""),
+		GoogleRedirectURL:   GetEnv("GOOGLE_REDIRECT_URL", "postmessage"),
+		OpenAIAPIKey:        GetEnv("OPENAI_API_KEY", ""),
+		WeatherLocation:     GetEnv("WEATHER_LOCATION", ""),
+		TestMode:            GetEnv("TEST_MODE", "") == "true" || GetEnv("TEST_MODE", "") == "1",
+		TestModeStart:       GetEnv("TEST_MODE_START", "2025-01-06T09:00:00Z"),
+		SeedFile:            GetEnv("SEED_FILE", ""),
+		QueryTimeout:        GetDuration("QUERY_TIMEOUT", 5*time.Second),
+		ScanTimeout:         GetDuration("SCAN_TIMEOUT", 30*time.Second),
+		StorageTimeout:      GetDuration("STORAGE_TIMEOUT", 2*time.Minute),
+		SyncVerifyInterval:  GetDuration("SYNC_VERIFY_INTERVAL", 24*time.Hour),
+		SyncPullInterval:    GetDuration("SYNC_PULL_INTERVAL", 5*time.Minute),
+		SyncArchiveInterval: GetDuration("SYNC_ARCHIVE_INTERVAL", 7*24*time.Hour),
+		SyncArchiveKeep:     GetInt("SYNC_ARCHIVE_KEEP", 4),
+		SyncDebounce:        GetDuration("SYNC_DEBOUNCE", 15*time.Second),
+		DriveWebhookURL:     GetEnv("DRIVE_WEBHOOK_URL", ""),
+		NoteSizeWarning:     GetInt("NOTE_SIZE_WARNING", 256*1024),
+		NoteRevisions:       GetInt("NOTE_REVISIONS", 50),
+		NoteTrashDays:       GetInt("NOTE_TRASH_DAYS", 10),
+		DropboxAppKey:       GetEnv("DROPBOX_APP_KEY", ""),
+		DropboxAppSecret:    GetEnv("DROPBOX_APP_SECRET", ""),
+		DropboxRedirectURL:  GetEnv("DROPBOX_REDIRECT_URL", ""),
+		LocalStorageDir:     GetEnv("LOCAL_STORAGE_DIR", ""),
+		S3Bucket:            GetEnv("S3_BUCKET", ""),
+		S3Prefix:            GetEnv("S3_PREFIX", ""),
+		S3Region:            GetEnv("S3_REGION", "us-east-1"),
+		S3Endpoint:          GetEnv("S3_ENDPOINT", ""),
+		S3AccessKeyID:       GetEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:   GetEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3PathStyle:         GetEnv("S3_PATH_STYLE", "") == "true" || GetEnv("S3_PATH_STYLE", "") == "1",
+		WebDAVURL:           GetEnv("WEBDAV_URL", ""),
+		WebDAVUsername:      GetEnv("WEBDAV_USERNAME", ""),
+		WebDAVPassword:      GetEnv("WEBDAV_PASSWORD", ""),
+		NoteFilenamePattern: GetEnv("NOTE_FILENAME_PATTERN", "dd-mm-yyyy"),
+		SupportToken:        GetEnv("SUPPORT_TOKEN", ""),
+		UpdateCheckRepo:     GetEnv("UPDATE_CHECK_REPO", ""),
+		LinkPreviews:        GetEnv("LINK_PREVIEWS", "") == "true" || GetEnv("LINK_PREVIEWS", "") == "1",
+		DBDriver:            GetEnv("DB_DRIVER", "sqlite"),
+		DBDSN:               GetEnv("DB_DSN", ""),
+		BackupDir:           GetEnv("BACKUP_DIR", ""),
+		BackupInterval:      GetDuration("BACKUP_INTERVAL", 24*time.Hour),
+		BackupRetention:     GetInt("BACKUP_RETENTION", 7),
+		AttachmentsDir:      GetEnv("ATTACHMENTS_DIR", "./data/attachments"),
+		AttachmentMaxSize:   GetInt("ATTACHMENT_MAX_SIZE", 10<<20),
+		MaintenanceDir:      GetEnv("MAINTENANCE_DIR", "./data/maintenance"),
+		DropSecret:          GetEnv("DROP_SECRET", ""),
+		DropMaxSize:         GetInt("DROP_MAX_SIZE", 16<<10),
+		SMTPHost:            GetEnv("SMTP_HOST", ""),
+		SMTPPort:            GetInt("SMTP_PORT", 587),
+		SMTPUsername:        GetEnv("SMTP_USERNAME", ""),
+		SMTPPassword:        GetEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:            GetEnv("SMTP_FROM", ""),
+		PublicURL:           strings.TrimSuffix(GetEnv("PUBLIC_URL", ""), "/"),
+		VAPIDPublicKey:      GetEnv("VAPID_PUBLIC_KEY", ""),
+		VAPIDPrivateKey:     GetEnv("VAPID_PRIVATE_KEY", ""),
+		VAPIDSubject:        GetEnv("VAPID_SUBJECT", ""),
+		SessionEncKey:       GetEnv("SESSION_ENC_KEY", ""),
+	}
+
+	// Test mode never talks to Google, so OAuth credentials are optional. Its
+	// login endpoint signs in without credentials, so production refuses it.
+	if AppConfig.TestMode {
+		if AppConfig.Env == "production" {
+			log.Fatal("TEST_MODE can't be enabled with ENV=production")
+		}
+		log.Println("TEST_MODE enabled: do not use in production")
+		return
 	}
 
 	if AppConfig.GoogleClientID == "" {
@@ -44,3 +165,31 @@ func GetEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// GetInt reads a non-negative integer from the environment
+func GetInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("invalid %s %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// GetDuration reads a Go duration (e.g. "30s") from the environment
+func GetDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("invalid %s %q, using %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
@@ -0,0 +1,26 @@
+//go:build chaos
+
+package setup
+
+import (
+	"daily-notes/storage"
+	"daily-notes/storage/chaos"
+	"log"
+	"log/slog"
+)
+
+// Chaos builds fail storage calls at the rates of the CHAOS_* variables, see
+// storage/chaos; one injector is shared by every factory so CHAOS_SEED replays
+// a run
+func init() {
+	config, err := chaos.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	injector := chaos.New(config)
+	wrapStorage = func(factory storage.Factory) storage.Factory {
+		slog.Warn("chaos build: injecting storage faults",
+			"rate_limit", config.RateLimit, "timeout", config.Timeout, "token_expiry", config.TokenExpiry, "seed", config.Seed)
+		return injector.Wrap(factory)
+	}
+}
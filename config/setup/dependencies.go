@@ -3,20 +3,37 @@ package setup
 import (
 	"context"
 	"daily-notes/app"
+	"daily-notes/config"
 	"daily-notes/database"
+	"daily-notes/pkg/backup"
+	"daily-notes/pkg/buildinfo"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/jobs"
+	"daily-notes/pkg/mail"
+	"daily-notes/pkg/maintenance"
+	"daily-notes/pkg/notetemplate"
+	"daily-notes/pkg/unfurl"
+	"daily-notes/pkg/webpush"
 	"daily-notes/services"
 	"daily-notes/session"
-	"daily-notes/storage/drive"
+	"daily-notes/storage"
 	"daily-notes/sync"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/oauth2"
 )
 
-// InitDatabase initializes the SQLite database and runs migrations
-func InitDatabase(dbPath string, logger *slog.Logger) (*database.DB, error) {
-	db, err := database.New(dbPath)
+// InitDatabase opens the database of the given dialect and runs migrations
+// dsn is the file path for SQLite and the connection URL for PostgreSQL.
+func InitDatabase(dialect database.Dialect, dsn string, logger *slog.Logger) (*database.DB, error) {
+	db, err := database.Open(dialect, dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -26,17 +43,109 @@ func InitDatabase(dbPath string, logger *slog.Logger) (*database.DB, error) {
 		return nil, err
 	}
 
-	logger.Info("database initialized", "path", dbPath)
+	version, err := db.SchemaVersion()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if dialect == database.SQLite {
+		logger.Info("database initialized", "driver", dialect, "path", dsn, "schema_version", version)
+	} else {
+		logger.Info("database initialized", "driver", dialect, "schema_version", version)
+	}
 	return db, nil
 }
 
+// NewSessionStore creates the session store, encrypting OAuth tokens at rest
+// when SESSION_ENC_KEY is set; tokens saved in plaintext before are encrypted
+// right away
+func NewSessionStore(db *database.DB, logger *slog.Logger) (*session.Store, error) {
+	store := session.NewStore(db)
+	if config.AppConfig.SessionEncKey == "" {
+		return store, nil
+	}
+
+	key, err := session.ParseTokenKey(config.AppConfig.SessionEncKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.SetTokenKey(key); err != nil {
+		return nil, err
+	}
+	encrypted, err := store.EncryptTokens()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt stored tokens: %w", err)
+	}
+	if encrypted > 0 {
+		logger.Info("encrypted OAuth tokens of existing sessions", "sessions", encrypted)
+	}
+	return store, nil
+}
+
+// SealSecrets encrypts the storage provider tokens and publish target secrets
+// of repo at rest with SESSION_ENC_KEY, like the tokens of sessions; those
+// saved in plaintext before are encrypted right away
+func SealSecrets(repo *database.Repository, logger *slog.Logger) error {
+	if config.AppConfig.SessionEncKey == "" {
+		return nil
+	}
+
+	key, err := session.ParseTokenKey(config.AppConfig.SessionEncKey)
+	if err != nil {
+		return err
+	}
+	if err := repo.SetSecretKey(key); err != nil {
+		return err
+	}
+	encrypted, err := repo.EncryptSecrets(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to encrypt stored secrets: %w", err)
+	}
+	if encrypted > 0 {
+		logger.Info("encrypted storage tokens and publish target secrets", "rows", encrypted)
+	}
+	return nil
+}
+
+// errTestModeStorage is returned by the storage factories in test mode, which never talks to Drive
+var errTestModeStorage = errors.New("cloud storage is disabled in test mode")
+
 // InitApp initializes the application with all dependencies
 func InitApp(db *database.DB, logger *slog.Logger) *app.App {
 	// Create repository
 	repo := database.NewRepository(db)
 
+	// Outside production a context query by ID without a user scope is a bug worth crashing on
+	database.PanicOnUnscoped = config.AppConfig.Env != "production"
+	database.RevisionRetention = config.AppConfig.NoteRevisions
+
+	// In test mode every time-dependent component shares one controllable clock
+	var testClock *clock.Fake
+	if config.AppConfig.TestMode {
+		start, err := ParseTestModeStart(config.AppConfig.TestModeStart)
+		if err != nil {
+			logger.Warn("falling back to current time for test clock", "error", err)
+			start = time.Now().UTC()
+		}
+		testClock = clock.NewFake(start)
+		logger.Info("test mode enabled", "clock", start.Format(time.RFC3339))
+	}
+
 	// Initialize session store with database
-	sessionStore := session.NewStore(db.DB)
+	sessionStore, err := NewSessionStore(db, logger)
+	if err != nil {
+		// Starting anyway would save the tokens the operator asked to encrypt in plaintext
+		logger.Error("invalid SESSION_ENC_KEY", "error", err)
+		os.Exit(1)
+	}
+	if err := SealSecrets(repo, logger); err != nil {
+		logger.Error("invalid SESSION_ENC_KEY", "error", err)
+		os.Exit(1)
+	}
+	if testClock != nil {
+		sessionStore.SetClock(testClock)
+	}
 	logger.Info("session store initialized with database")
 
 	// Start session cleanup
@@ -56,39 +165,247 @@ func InitApp(db *database.DB, logger *slog.Logger) *app.App {
 		}, nil
 	}
 
-	// Create storage factory using Drive
+	// Name new note files with the configured pattern; existing files stay readable either way
+	if err := storage.SetFilenamePattern(config.AppConfig.NoteFilenamePattern); err != nil {
+		logger.Warn("keeping default note filename pattern", "error", err, "pattern", storage.FilenamePattern())
+	}
+
+	// Create storage factory resolving each user's provider (Drive unless they picked another)
+	storageRegistry := NewStorageRegistry(repo)
+	openStorage := NewStorageFactory(repo, storageRegistry)
 	storageFactory := func(ctx context.Context, token *oauth2.Token, userID string) (services.StorageService, error) {
-		return drive.NewService(ctx, token, userID)
+		if testClock != nil {
+			return nil, errTestModeStorage
+		}
+		return openStorage(ctx, token, userID)
 	}
-	logger.Info("storage factory configured with Drive")
+	logger.Info("storage factory configured", "providers", storageRegistry.Names())
 
 	// Create sync worker storage factory
 	syncStorageFactory := func(ctx context.Context, token *oauth2.Token, userID string) (sync.StorageService, error) {
-		return drive.NewService(ctx, token, userID)
+		if testClock != nil {
+			return nil, errTestModeStorage
+		}
+		return openStorage(ctx, token, userID)
+	}
+
+	// Maintenance mode is kept on disk, so it lasts through the restart of a migration.
+	// Background work that writes holds while it's on, so it's opened before any starts.
+	mode, err := maintenance.Open(config.AppConfig.MaintenanceDir)
+	if err != nil {
+		logger.Warn("maintenance mode unavailable", "error", err, "dir", config.AppConfig.MaintenanceDir)
+	} else {
+		if testClock != nil {
+			mode.SetClock(testClock)
+		}
+		if status := mode.Status(); status.Enabled {
+			logger.Warn("server is in maintenance mode", "since", status.Since, "reason", status.Reason, "journaled", status.Journaled)
+		}
 	}
 
-	// Start sync worker for background sync
+	// Create sync worker for background sync; it starts once the app is wired
 	syncWorker := sync.NewWorker(repo, sessionStore, syncStorageFactory, getUserToken)
-	syncWorker.Start()
-	logger.Info("sync worker started")
+	syncWorker.SetMaintenance(mode)
+	if testClock != nil {
+		syncWorker.SetClock(testClock)
+	}
+	syncWorker.SetVerifyInterval(config.AppConfig.SyncVerifyInterval)
+	syncWorker.SetPullInterval(config.AppConfig.SyncPullInterval)
+	syncWorker.SetArchiveInterval(config.AppConfig.SyncArchiveInterval, config.AppConfig.SyncArchiveKeep)
+	syncWorker.SetWebhookURL(config.AppConfig.DriveWebhookURL)
+	syncWorker.SetDebounce(config.AppConfig.SyncDebounce)
 
 	// Create App with all dependencies injected
 	application := app.New(repo, syncWorker, sessionStore, storageFactory, logger)
+	application.NoteService.SetTemplateEngine(InitTemplates(logger))
+	application.StorageService.SetAvailable(storageRegistry.Names()...)
+	syncWorker.SetAbandonHandler(application.Push.NoteAbandoned)
+	application.UseMaintenance(mode)
+
+	syncWorker.Start()
+	logger.Info("sync worker started")
+
+	// Storage work queued by requests and logins runs as jobs, so a restart doesn't lose it
+	application.Jobs = jobs.New(repo, jobs.DefaultWorkers, logger)
+	if testClock != nil {
+		application.Jobs.SetClock(testClock)
+	}
+	application.ContextService.SetJobQueue(application.Jobs, getUserToken)
+	application.AuthService.SetJobQueue(application.Jobs, getUserToken)
+	application.Attachments.SetJobQueue(application.Jobs, getUserToken)
+	application.Jobs.SetMaintenance(mode)
+	application.Jobs.Handle(services.JobRenameFolder, application.ContextService.RunFolderJob)
+	application.Jobs.Handle(services.JobDeleteFolder, application.ContextService.RunFolderJob)
+	application.Jobs.Handle(services.JobRestoreFolder, application.ContextService.RunFolderJob)
+	application.Jobs.Handle(services.JobSaveConfig, application.ContextService.RunFolderJob)
+	application.Jobs.Handle(services.JobImport, application.AuthService.RunStorageJob)
+	application.Jobs.Handle(services.JobCleanup, application.AuthService.RunStorageJob)
+	application.Jobs.Handle(services.JobAttachment, application.Attachments.RunUploadJob)
+	application.Jobs.Handle(services.JobDigest, application.Digests.RunDigestJob)
+	application.Jobs.Handle(services.JobReminder, application.Reminders.RunReminderJob)
+	application.Jobs.Handle(services.JobPush, application.Push.RunPushJob)
+	application.Jobs.Handle(services.JobInvitation, application.Members.RunInvitationJob)
+	application.Jobs.Start()
+	logger.Info("job queue started", "workers", jobs.DefaultWorkers)
+
+	timeouts := services.Timeouts{
+		Query:   config.AppConfig.QueryTimeout,
+		Scan:    config.AppConfig.ScanTimeout,
+		Storage: config.AppConfig.StorageTimeout,
+	}
+	application.NoteService.SetTimeouts(timeouts)
+	application.NoteService.SetSizeWarning(config.AppConfig.NoteSizeWarning)
+	noteTrash := time.Duration(config.AppConfig.NoteTrashDays) * 24 * time.Hour
+	application.NoteService.SetTrashRetention(noteTrash)
+	application.ContextService.SetNoteTrashRetention(noteTrash)
+	application.ContextService.SetTimeouts(timeouts)
+	application.Attachments.SetTimeouts(timeouts)
+	application.Attachments.SetDir(config.AppConfig.AttachmentsDir)
+	application.Attachments.SetMaxSize(int64(config.AppConfig.AttachmentMaxSize))
+	application.Drops.SetSecret([]byte(config.AppConfig.DropSecret))
+	application.Drops.SetMaxSize(config.AppConfig.DropMaxSize)
+	application.Digests.SetJobQueue(application.Jobs)
+	application.Reminders.SetJobQueue(application.Jobs)
+	application.Push.SetJobQueue(application.Jobs)
+	application.Members.SetJobQueue(application.Jobs)
+
+	if config.AppConfig.UpdateCheckRepo != "" {
+		application.Updates = buildinfo.NewUpdateChecker(config.AppConfig.UpdateCheckRepo)
+	}
+
+	if dir := config.AppConfig.BackupDir; dir != "" && db.Dialect() != database.SQLite {
+		logger.Warn("database backups need SQLite; back up the database with its own tools", "driver", db.Dialect())
+	} else if dir != "" {
+		application.Backups = backup.NewScheduler(db.Backup, dir, config.AppConfig.BackupInterval, config.AppConfig.BackupRetention, logger)
+		application.Backups.Start()
+		logger.Info("database backups enabled", "dir", dir, "interval", config.AppConfig.BackupInterval, "retention", config.AppConfig.BackupRetention)
+	}
+
+	// Test mode never reaches other sites, like cloud storage
+	if config.AppConfig.LinkPreviews && testClock == nil {
+		application.LinkPreviews.SetFetcher(unfurl.NewFetcher())
+		logger.Info("link previews enabled")
+	}
+	if testClock == nil {
+		application.PublishService.SetOpener(OpenPublishTarget)
+		application.PublishService.Start()
+		logger.Info("publishing started")
+	}
+	// Digests, reminders and invitations link to the server, so they need its public address as well
+	if cfg := config.AppConfig; cfg.SMTPHost != "" && testClock == nil {
+		if cfg.PublicURL == "" {
+			logger.Warn("weekly digests, reminders and invitation emails need PUBLIC_URL for the links in emails")
+		} else {
+			mailer := &mail.SMTP{
+				Host:     cfg.SMTPHost,
+				Port:     cfg.SMTPPort,
+				Username: cfg.SMTPUsername,
+				Password: cfg.SMTPPassword,
+				From:     cfg.SMTPFrom,
+			}
+			application.Digests.SetMailer(mailer, cfg.PublicURL)
+			application.Reminders.SetMailer(mailer, cfg.PublicURL)
+			application.Members.SetMailer(mailer, cfg.PublicURL)
+			logger.Info("weekly digests, reminders and invitation emails enabled", "smtp_host", cfg.SMTPHost)
+		}
+	}
+
+	// Push notifications go straight to browsers' push services, signed with the server's VAPID keys
+	if cfg := config.AppConfig; cfg.VAPIDPrivateKey != "" && testClock == nil {
+		subject := cfg.VAPIDSubject
+		if subject == "" {
+			subject = cfg.PublicURL
+		}
+		if sender, err := webpush.New(cfg.VAPIDPublicKey, cfg.VAPIDPrivateKey, subject); err != nil {
+			logger.Warn("push notifications disabled", "error", err)
+		} else {
+			application.Push.SetSender(sender)
+			logger.Info("push notifications enabled")
+		}
+	}
+
+	if testClock != nil {
+		application.UseClock(testClock)
+		application.TestClock = testClock
+	}
+	application.NoteSchedules.Start()
+	if application.Digests.Enabled() {
+		application.Digests.Start()
+	}
+	if application.Reminders.Enabled() {
+		application.Reminders.Start()
+	}
+
+	// Fixture users come first so a SEED_FILE can replace the built-in demo user
+	if path := config.AppConfig.SeedFile; path != "" {
+		if err := SeedFromFile(context.Background(), repo, path, application.Clock, logger); err != nil {
+			logger.Error("failed to seed from file", "path", path, "error", err)
+		}
+	}
+	if testClock != nil {
+		if err := SeedTestData(context.Background(), repo, testClock, logger); err != nil {
+			logger.Error("failed to seed test data", "error", err)
+		}
+	}
 	logger.Info("application initialized with dependency injection")
 
 	return application
 }
 
+// InitTemplates registers the content providers available to context templates
+func InitTemplates(logger *slog.Logger) *notetemplate.Engine {
+	engine := notetemplate.New(logger)
+	engine.Register(notetemplate.StaticList("quote", notetemplate.DefaultQuotes))
+
+	if location := config.AppConfig.WeatherLocation; location != "" {
+		weatherURL := "https://wttr.in/" + url.PathEscape(location) + "?format=3"
+		client := &http.Client{Timeout: notetemplate.DefaultTimeout}
+		engine.Register(notetemplate.Cached(notetemplate.HTTPText("weather", weatherURL, client), time.Hour))
+		logger.Info("weather template provider enabled", "location", location)
+	}
+
+	return engine
+}
+
 // Shutdown performs graceful shutdown of all services
-func Shutdown(syncWorker *sync.Worker, db *database.DB, logger *slog.Logger) {
+func Shutdown(application *app.App, db *database.DB, logger *slog.Logger) {
 	logger.Info("shutting down services...")
 
+	// Stop tag jobs and note schedules first, as the notes they save are queued for sync
+	application.TagService.Stop()
+	logger.Info("tag jobs stopped")
+	application.NoteSchedules.Stop()
+	logger.Info("note schedules stopped")
+	application.Digests.Stop()
+	logger.Info("weekly digests stopped")
+	application.Reminders.Stop()
+	logger.Info("reminders stopped")
+
+	// Stop jobs, which use the sync worker; interrupted ones run again after a restart
+	if application.Jobs != nil {
+		application.Jobs.Stop()
+		logger.Info("job queue stopped")
+	}
+
 	// Stop sync worker
-	if syncWorker != nil {
-		syncWorker.Stop()
+	if application.SyncWorker != nil {
+		application.SyncWorker.Stop()
 		logger.Info("sync worker stopped")
 	}
 
+	// End the note update streams of open clients
+	application.NoteEvents.Close()
+
+	// Stop backups, letting one in progress finish
+	if application.Backups != nil {
+		application.Backups.Stop()
+		logger.Info("backup scheduler stopped")
+	}
+
+	// Stop publishing, letting the publication in progress finish
+	application.PublishService.Stop()
+	logger.Info("publishing stopped")
+
 	// Close database
 	if db != nil {
 		db.Close()
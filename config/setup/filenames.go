@@ -0,0 +1,118 @@
+package setup
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/storage"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"golang.org/x/oauth2"
+)
+
+// MigrateFilenamesOptions selects what MigrateFilenames touches
+type MigrateFilenamesOptions struct {
+	UserID string // Only this user; empty migrates everyone
+	DryRun bool   // Log the plan without renaming anything
+}
+
+// MigrateFilenames renames every user's remote note files to the configured
+// NOTE_FILENAME_PATTERN and records the new file IDs
+// It runs before the server starts, so no sync can write a file mid-rename.
+// Each user's plan is verified before anything is renamed and rolled back if
+// the renamed files don't read back as the same notes; a failed user is logged
+// and skipped, and the error lists how many failed.
+func MigrateFilenames(ctx context.Context, db *database.DB, logger *slog.Logger, opts MigrateFilenamesOptions) error {
+	repo := database.NewRepository(db)
+	sessionStore, err := NewSessionStore(db, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open sessions: %w", err)
+	}
+	if err := SealSecrets(repo, logger); err != nil {
+		return fmt.Errorf("failed to open storage tokens: %w", err)
+	}
+	openStorage := NewStorageFactory(repo, NewStorageRegistry(repo))
+	pattern := storage.FilenamePattern()
+
+	userIDs := []string{opts.UserID}
+	if opts.UserID == "" {
+		if userIDs, err = repo.GetUserIDs(ctx); err != nil {
+			return fmt.Errorf("failed to list users: %w", err)
+		}
+	}
+
+	failed := 0
+	for _, userID := range userIDs {
+		log := logger.With("user_id", userID)
+
+		sess := sessionStore.GetByUserID(userID)
+		if sess == nil {
+			log.Warn("skipping user without a session; their storage can't be opened")
+			continue
+		}
+		token := &oauth2.Token{
+			AccessToken:  sess.AccessToken,
+			RefreshToken: sess.RefreshToken,
+			Expiry:       sess.TokenExpiry,
+		}
+
+		renamed, err := migrateUserFilenames(ctx, repo, openStorage, token, userID, pattern, opts.DryRun, log)
+		if err != nil {
+			log.Error("filename migration failed", "error", err)
+			failed++
+			continue
+		}
+		log.Info("filename migration done", "pattern", pattern, "renamed", renamed, "dry_run", opts.DryRun)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("filename migration failed for %d of %d users", failed, len(userIDs))
+	}
+	return nil
+}
+
+// migrateUserFilenames migrates one user's storage and returns the number of renamed files
+func migrateUserFilenames(ctx context.Context, repo *database.Repository, open storage.Factory, token *oauth2.Token, userID, pattern string, dryRun bool, log *slog.Logger) (int, error) {
+	provider, err := open(ctx, token, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open storage: %w", err)
+	}
+	renamer, ok := provider.(storage.NoteFileRenamer)
+	if !ok {
+		return 0, errors.New("storage provider can't rename note files")
+	}
+
+	contexts, err := provider.GetContexts()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list contexts: %w", err)
+	}
+	names := make([]string, len(contexts))
+	for i, c := range contexts {
+		names[i] = c.Name
+	}
+
+	plan, err := storage.PlanFilenameMigration(renamer, names, pattern)
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		for _, rename := range plan {
+			log.Info("would rename", "context", rename.Context, "from", rename.From.Name, "to", rename.To.Name)
+		}
+		return len(plan), nil
+	}
+
+	applied, err := storage.ApplyFilenameMigration(renamer, plan)
+	if err != nil {
+		return 0, err
+	}
+
+	// The files are renamed and verified; a failed ID update only costs a re-lookup on the next sync
+	for _, rename := range applied {
+		if err := repo.UpdateNoteFileID(ctx, userID, rename.Context, rename.Key, rename.To.ID); err != nil {
+			log.Warn("failed to record renamed file ID", "context", rename.Context, "file", rename.To.Name, "error", err)
+		}
+	}
+	return len(applied), nil
+}
@@ -20,8 +20,9 @@ func ApplyMiddleware(app *fiber.App, logger *slog.Logger) {
 		middleware.Security(),
 		cors.New(cors.Config{
 			AllowOrigins:     config.GetEnv("CORS_ORIGINS", "*"),
-			AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-			AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+			AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+			AllowHeaders:     "Origin,Content-Type,Accept,Authorization,If-Match,If-Unmodified-Since,X-Idempotency-Key",
+			ExposeHeaders:    "ETag,Last-Modified",
 			AllowCredentials: false,
 			MaxAge:           86400,
 		}),
@@ -0,0 +1,38 @@
+package setup
+
+import (
+	"daily-notes/models"
+	"daily-notes/pkg/unfurl"
+	"daily-notes/publish"
+	"daily-notes/publish/ghost"
+	"daily-notes/publish/hugo"
+	"daily-notes/publish/wordpress"
+	"fmt"
+	"time"
+)
+
+// publishRequestTimeout bounds each request to a publish target
+const publishRequestTimeout = 30 * time.Second
+
+// OpenPublishTarget connects to a user's publish target
+// Targets are configured by users, so requests only reach public addresses.
+func OpenPublishTarget(t models.PublishTarget) (publish.Target, error) {
+	client := unfurl.PublicClient(publishRequestTimeout)
+
+	switch t.Kind {
+	case publish.Ghost:
+		return ghost.New(t.URL, t.Secret, client)
+	case publish.WordPress:
+		return wordpress.New(t.URL, t.Username, t.Secret, client), nil
+	case publish.Hugo:
+		return hugo.New(hugo.Config{
+			APIURL:  t.URL,
+			Repo:    t.Repo,
+			Branch:  t.Branch,
+			Dir:     t.Dir,
+			Token:   t.Secret,
+			SiteURL: t.SiteURL,
+		}, client)
+	}
+	return nil, fmt.Errorf("unknown publish target kind %q", t.Kind)
+}
@@ -2,6 +2,7 @@ package setup
 
 import (
 	"daily-notes/app"
+	"daily-notes/config"
 	"daily-notes/handlers"
 	"daily-notes/middleware"
 	"time"
@@ -13,6 +14,9 @@ import (
 // RegisterRoutes registers all application routes
 func RegisterRoutes(fiberApp *fiber.App, application *app.App) {
 
+	// Refuses writes while the server is in maintenance mode
+	fiberApp.Use(middleware.Maintenance(application.Maintenance))
+
 	// Static assets with aggressive caching
 	fiberApp.Static("/static", "./static", fiber.Static{
 		Compress:      true,
@@ -26,7 +30,23 @@ func RegisterRoutes(fiberApp *fiber.App, application *app.App) {
 	// Public routes
 	fiberApp.Get("/", handlers.HomePage)
 	fiberApp.Get("/health", func(c *fiber.Ctx) error { return c.JSON(fiber.Map{"status": "ok"}) })
-	fiberApp.Get("/api/time", handlers.ServerTime)
+	// Public, so strictly limited per IP on top of the badge being reused for a minute
+	fiberApp.Get("/status/badge.svg", limiter.New(limiter.Config{Max: 10, Expiration: time.Minute}), handlers.StatusBadge(application))
+	fiberApp.Get("/api/time", handlers.ServerTime(application))
+	fiberApp.Get("/api/version", handlers.GetVersion(application))
+
+	// Contexts published read-only under a handle
+	fiberApp.Get("/@:handle", handlers.PublicIndexPage(application))
+	fiberApp.Get("/@:handle/feed.xml", handlers.PublicFeed(application))
+	fiberApp.Get("/@:handle/:date", handlers.PublicNotePage(application))
+	// Notes shared by a secret link; limited per IP so tokens can't be guessed
+	fiberApp.Get("/s/:token", limiter.New(limiter.Config{Max: 60, Expiration: time.Minute}), handlers.SharedNotePage(application))
+
+	// Test mode helpers (only registered when TEST_MODE is enabled)
+	if application.TestClock != nil {
+		fiberApp.Post("/api/test/login", handlers.TestLogin(application, TestLoginUsers(config.AppConfig.SeedFile, application.Logger)))
+		fiberApp.Post("/api/test/advance-time", handlers.TestAdvanceTime(application))
+	}
 
 	// Auth routes
 	fiberApp.Post("/api/auth/login", handlers.Login(application))
@@ -34,10 +54,14 @@ func RegisterRoutes(fiberApp *fiber.App, application *app.App) {
 	fiberApp.Get("/api/auth/me", handlers.Me(application))
 
 	// Protected page routes
-	fiberApp.Get("/voice", middleware.AuthRequired(application.SessionStore, application.AuthService), handlers.VoicePage)
+	fiberApp.Get("/voice", middleware.AuthRequired(application.SessionStore, application.AuthService, nil, nil), handlers.VoicePage)
+	// Changes of the user's notes pushed to every open client
+	fiberApp.Get("/ws", middleware.AuthRequired(application.SessionStore, application.AuthService, nil, nil), handlers.NoteUpdates(application))
+	// Script-free version of today's note for screen readers, old browsers and scripts
+	fiberApp.Get("/plain", handlers.PlainAuth(application), handlers.PlainPage(application))
 
 	// Protected API routes (with auto token refresh)
-	api := fiberApp.Group("/api", middleware.AuthRequired(application.SessionStore, application.AuthService), limiter.New(limiter.Config{
+	userLimiter := limiter.New(limiter.Config{
 		Max:        100,
 		Expiration: time.Minute,
 		KeyGenerator: func(c *fiber.Ctx) string {
@@ -51,21 +75,169 @@ func RegisterRoutes(fiberApp *fiber.App, application *app.App) {
 				"error": "Rate limit exceeded for your account",
 			})
 		},
-	}))
+	})
+
+	// Drive change notifications (only registered when DRIVE_WEBHOOK_URL is set)
+	// Authenticated by the channel token Drive sends back, not a session.
+	if config.AppConfig.DriveWebhookURL != "" {
+		fiberApp.Post("/api/drive/webhook", handlers.DriveWebhook(application))
+	}
+
+	// Signed drop box URLs, authenticated by their signature (only registered when DROP_SECRET is set)
+	if config.AppConfig.DropSecret != "" {
+		fiberApp.Post("/api/drop/:user/:context/:date", limiter.New(limiter.Config{Max: 30, Expiration: time.Minute}), handlers.Drop(application))
+	}
+
+	// Unsubscribe links of weekly digest emails, authenticated by their token; kept
+	// when digests are turned off, as emails sent before still link here
+	fiberApp.Get("/digest/unsubscribe/:token", handlers.DigestUnsubscribePage(application))
+	fiberApp.Post("/digest/unsubscribe/:token", limiter.New(limiter.Config{Max: 30, Expiration: time.Minute}), handlers.DigestUnsubscribe(application))
+
+	// Support access to debug recordings, diagnostics and backups (only registered when SUPPORT_TOKEN is set)
+	if config.AppConfig.SupportToken != "" {
+		fiberApp.Get("/api/support/audit/:userID", handlers.GetUserAudit(application))
+		fiberApp.Get("/api/support/diagnostics", handlers.GetDiagnostics(application))
+		fiberApp.Get("/api/admin/backups", handlers.GetBackups(application))
+		fiberApp.Get("/api/admin/jobs", handlers.GetJobs(application))
+		fiberApp.Get("/api/admin/maintenance", handlers.GetMaintenance(application))
+		fiberApp.Put("/api/admin/maintenance", handlers.StartMaintenance(application))
+		fiberApp.Delete("/api/admin/maintenance", handlers.EndMaintenance(application))
+	}
+
+	// Audit records requests of users in debug mode, including idempotent replays
+	// Replays the stored response when a user retries a write with the same X-Idempotency-Key
+	api := fiberApp.Group("/api", middleware.AuthRequired(application.SessionStore, application.AuthService, application.APITokens, application.APIKeys), middleware.Audit(application.AuditLog), userLimiter, middleware.Idempotency(application.Repo, application.Clock))
 
 	api.Get("/contexts", handlers.GetContexts(application))
 	api.Post("/contexts", handlers.CreateContext(application))
+	api.Post("/contexts/suggest", handlers.SuggestContext(application))
+	api.Patch("/contexts/reorder", handlers.ReorderContexts(application))
 	api.Put("/contexts/:id", handlers.UpdateContext(application))
+	api.Put("/contexts/:id/template", handlers.UpdateContextTemplate(application))
+	api.Put("/contexts/:id/local-only", handlers.SetContextLocalOnly(application))
+	api.Put("/contexts/:id/language", handlers.SetContextLanguage(application))
+	api.Get("/contexts/:id/stats", handlers.GetContextStats(application))
+	api.Get("/contexts/public", handlers.GetPublicContexts(application))
+	api.Put("/contexts/:id/public", handlers.PublishContext(application))
+	api.Delete("/contexts/:id/public", handlers.UnpublishContext(application))
+	api.Get("/contexts/:id/members", handlers.GetContextMembers(application))
+	api.Post("/contexts/:id/invite", handlers.InviteContextMember(application))
+	api.Delete("/contexts/:id/members/:memberId", handlers.RemoveContextMember(application))
+	api.Get("/invitations", handlers.GetInvitations(application))
+	api.Post("/invitations/:id/accept", handlers.AcceptInvitation(application))
+	api.Delete("/invitations/:id", handlers.LeaveInvitation(application))
 	api.Delete("/contexts/:id", handlers.DeleteContext(application))
+	api.Get("/contexts/trash", handlers.GetContextTrash(application))
+	api.Post("/contexts/trash/:id/restore", handlers.RestoreContext(application))
 	api.Get("/notes", handlers.GetNote(application))
-	api.Post("/notes", handlers.UpsertNote(application))
+	api.Get("/notes/view", handlers.GetNoteView(application))
+	api.Post("/notes", handlers.PlainFormRedirect(), handlers.UpsertNote(application))
+	api.Patch("/notes", handlers.PatchNote(application))
+	api.Post("/notes/batch", handlers.BatchUpsertNotes(application))
+	api.Post("/notes/append", handlers.AppendToNote(application))
 	api.Get("/notes/list", handlers.GetNotesByContext(application))
+	api.Get("/notes/changes", handlers.GetNoteChanges(application))
+	api.Get("/notes/by-tag", handlers.GetNotesByTag(application))
+	api.Get("/notes/month", handlers.GetMonthNotes(application))
+	api.Get("/notes/calendar", handlers.GetNoteCalendar(application))
+	api.Get("/notes/agenda", handlers.GetAgenda(application))
+	api.Get("/notes/day", handlers.GetNotesDay(application))
+	api.Get("/notes/meta", handlers.GetNotesMeta(application))
+	api.Get("/notes/sections", handlers.GetNoteSections(application))
+	api.Put("/notes/sections/:slug", handlers.UpdateNoteSection(application))
+	api.Post("/notes/split", handlers.SplitNote(application))
+	api.Post("/notes/copy", handlers.CopyNotes(application))
+	api.Post("/notes/move", handlers.MoveNotes(application))
+	api.Post("/notes/redate", handlers.RedateNotes(application))
+	api.Get("/notes/related", handlers.GetRelatedNotes(application))
+	api.Get("/notes/search", handlers.SearchNotes(application))
+	api.Get("/notes/sizes", handlers.GetNoteSizeStats(application))
+	api.Get("/notes/revisions", handlers.GetNoteRevisions(application))
+	api.Post("/notes/revisions/:id/restore", handlers.RestoreNoteRevision(application))
+	api.Get("/notes/trash", handlers.GetNoteTrash(application))
+	api.Post("/notes/:id/restore", handlers.RestoreTrashedNote(application))
+	api.Get("/notes/attachments", handlers.GetNoteAttachments(application))
+	api.Post("/notes/attachments", handlers.UploadAttachment(application))
+	api.Get("/attachments/:id", handlers.GetAttachment(application))
+	api.Get("/notes/conflicts", handlers.GetNoteConflicts(application))
+	api.Post("/notes/conflicts/resolve", handlers.ResolveNoteConflict(application))
+	api.Get("/notes/period", handlers.GetPeriodNote(application))
+	api.Post("/notes/period", handlers.UpsertPeriodNote(application))
+	api.Post("/notes/period/seed", handlers.SeedPeriodNote(application))
+	api.Post("/notes/publish", handlers.PublishNote(application))
+	api.Get("/notes/share", handlers.GetShares(application))
+	api.Post("/notes/share", handlers.ShareNote(application))
+	api.Delete("/notes/share/:id", handlers.DeleteShare(application))
+	api.Put("/notes/local-only", handlers.SetNoteLocalOnly(application))
+	api.Put("/notes/pin", handlers.PinNote(application))
+	api.Get("/notes/pinned", handlers.GetPinnedNotes(application))
+	api.Get("/notes/schedule", handlers.GetNoteSchedule(application))
+	api.Put("/notes/schedule", handlers.SetNoteSchedule(application))
+	api.Delete("/notes/schedule", handlers.DeleteNoteSchedule(application))
+	api.Get("/digest", handlers.GetDigest(application))
+	api.Put("/digest", handlers.SubscribeDigest(application))
+	api.Delete("/digest", handlers.UnsubscribeDigest(application))
+	api.Get("/push", handlers.GetPush(application))
+	api.Post("/push/subscribe", handlers.SubscribePush(application))
+	api.Post("/push/unsubscribe", handlers.UnsubscribePush(application))
 	api.Delete("/notes/:context/:date", handlers.DeleteNote(application))
 	api.Put("/settings", handlers.UpdateSettings(application))
+	api.Get("/encryption", handlers.GetEncryption(application))
+	api.Put("/encryption", handlers.EnableEncryption(application))
+	api.Post("/encryption/verify", limiter.New(limiter.Config{Max: 10, Expiration: time.Minute}), handlers.VerifyEncryptionKey(application))
+	api.Delete("/encryption", handlers.DisableEncryption(application))
+	api.Get("/timezone/review", handlers.GetTimezoneReview(application))
+	api.Post("/timezone/review/:id/dismiss", handlers.DismissTimezoneChange(application))
+	api.Get("/stats/activity", handlers.GetActivity(application))
+	api.Get("/stats/writing", handlers.GetWritingStats(application))
+	// Queries of graph/schema.graphqls; over GET too, so read-only API keys can use it
+	graphQL := handlers.GraphQL(application)
+	api.Get("/graphql", graphQL)
+	api.Post("/graphql", graphQL)
+	api.Get("/tags", handlers.GetTags(application))
+	api.Post("/tags/rename", handlers.RenameTag(application))
+	api.Post("/tags/merge", handlers.MergeTags(application))
+	api.Get("/tags/jobs/:id", handlers.GetTagJob(application))
+	api.Get("/tasks", handlers.GetTasks(application))
+	api.Patch("/tasks/:id/toggle", handlers.ToggleTask(application))
+	api.Get("/reminders", handlers.GetReminders(application))
+	api.Post("/reminders", handlers.CreateReminder(application))
+	api.Delete("/reminders/:id", handlers.DeleteReminder(application))
+	api.Get("/tokens", handlers.GetAPITokens(application))
+	api.Post("/tokens", handlers.CreateAPIToken(application))
+	api.Delete("/tokens/:id", handlers.RevokeAPIToken(application))
+	api.Get("/keys", handlers.GetAPIKeys(application))
+	api.Post("/keys", handlers.CreateAPIKey(application))
+	api.Delete("/keys/:id", handlers.RevokeAPIKey(application))
+	if config.AppConfig.DropSecret != "" {
+		api.Post("/drops", handlers.CreateDropURL(application))
+	}
+	api.Get("/palette", handlers.SearchPalette(application))
+	api.Get("/profile/export", handlers.ExportProfile(application))
+	api.Post("/profile/import", handlers.ImportProfile(application))
+	api.Post("/import", handlers.ImportNotes(application))
+	api.Get("/export", handlers.ExportAccount(application))
+	api.Get("/changelog", handlers.GetChangelog(application))
+	api.Post("/changelog/seen", handlers.MarkChangelogSeen(application))
+	api.Get("/storage", handlers.GetStorage(application))
+	api.Put("/storage", handlers.UpdateStorage(application))
+	api.Get("/storage/dropbox/connect", handlers.ConnectDropbox(application))
+	api.Get("/storage/dropbox/callback", handlers.DropboxCallback(application))
+	api.Delete("/storage/dropbox", handlers.DisconnectDropbox(application))
+	api.Get("/debug/audit", handlers.GetAudit(application))
+	api.Post("/debug/audit", handlers.EnableAudit(application))
+	api.Delete("/debug/audit", handlers.DisableAudit(application))
 	api.Get("/sync/status", handlers.GetSyncStatus(application))
+	api.Get("/sync/events", handlers.StreamSyncEvents(application))
 	api.Post("/sync/retry/:id", handlers.RetryNoteSync(application))
+	api.Post("/sync/retry-all", handlers.RetryAllSync(application))
+	api.Get("/sync/verify", handlers.VerifySync(application))
+
+	api.Get("/publish/targets", handlers.GetPublishTargets(application))
+	api.Post("/publish/targets", handlers.CreatePublishTarget(application))
+	api.Delete("/publish/targets/:id", handlers.DeletePublishTarget(application))
 
 	// Voice/Speech-to-Text API routes
-	api.Post("/voice/transcribe", handlers.TranscribeAudio)
+	api.Post("/voice/transcribe", handlers.TranscribeAudio(application))
 	api.Get("/voice/status/:id", handlers.GetTranscriptionStatus)
 }
@@ -0,0 +1,217 @@
+package setup
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/period"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is the content of a SEED_FILE: users with their contexts and notes
+type Fixture struct {
+	Users []FixtureUser `json:"users" yaml:"users"`
+}
+
+// FixtureUser is a seeded user. The ID is the Google account ID the user signs in with,
+// so a fixture can prepare data for a developer's real account.
+type FixtureUser struct {
+	ID       string           `json:"id" yaml:"id"`
+	Email    string           `json:"email" yaml:"email"`
+	Name     string           `json:"name" yaml:"name"`
+	Settings FixtureSettings  `json:"settings" yaml:"settings"`
+	Contexts []FixtureContext `json:"contexts" yaml:"contexts"`
+}
+
+// FixtureSettings are the stored user settings; empty fields get the test mode defaults
+type FixtureSettings struct {
+	Theme      string `json:"theme" yaml:"theme"`
+	Timezone   string `json:"timezone" yaml:"timezone"`
+	DateFormat string `json:"date_format" yaml:"date_format"`
+	WeekStart  int    `json:"week_start" yaml:"week_start"`
+}
+
+// FixtureContext is a seeded context with its notes
+type FixtureContext struct {
+	Name     string        `json:"name" yaml:"name"`
+	Color    string        `json:"color" yaml:"color"`
+	Template string        `json:"template" yaml:"template"`
+	Notes    []FixtureNote `json:"notes" yaml:"notes"`
+}
+
+// FixtureNote is a seeded note. Date is a note key (2025-01-06, 2025-W02, 2025-01, 2025)
+// or a day relative to the current date in the user's time zone: today, today-3, today+1.
+type FixtureNote struct {
+	Date    string `json:"date" yaml:"date"`
+	Content string `json:"content" yaml:"content"`
+}
+
+var relativeDatePattern = regexp.MustCompile(`^today(?:([+-])(\d+))?$`)
+
+// LoadFixture reads a seed fixture; files ending in .json are parsed as JSON, others as YAML
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed file: %w", err)
+	}
+
+	var fixture Fixture
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&fixture)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&fixture)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed file %s: %w", path, err)
+	}
+
+	if err := fixture.validate(); err != nil {
+		return nil, fmt.Errorf("invalid seed file %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// validate checks the whole fixture up front so a typo doesn't leave a half-seeded database
+func (f *Fixture) validate() error {
+	users := map[string]bool{}
+	for i, u := range f.Users {
+		if u.ID == "" || u.Email == "" {
+			return fmt.Errorf("user %d: id and email are required", i+1)
+		}
+		if users[u.ID] {
+			return fmt.Errorf("user %s: listed twice", u.ID)
+		}
+		users[u.ID] = true
+		if u.Settings.Timezone != "" {
+			if _, err := time.LoadLocation(u.Settings.Timezone); err != nil {
+				return fmt.Errorf("user %s: unknown timezone %q", u.ID, u.Settings.Timezone)
+			}
+		}
+
+		contexts := map[string]bool{}
+		for _, c := range u.Contexts {
+			if c.Name == "" {
+				return fmt.Errorf("user %s: context name is required", u.ID)
+			}
+			if contexts[c.Name] {
+				return fmt.Errorf("user %s: context %s listed twice", u.ID, c.Name)
+			}
+			contexts[c.Name] = true
+
+			for _, n := range c.Notes {
+				if !relativeDatePattern.MatchString(n.Date) && period.Kind(n.Date) == "" {
+					return fmt.Errorf("user %s, context %s: invalid note date %q", u.ID, c.Name, n.Date)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// noteDate resolves a fixture note date against today's date in loc
+func noteDate(date string, now time.Time, loc *time.Location) string {
+	match := relativeDatePattern.FindStringSubmatch(date)
+	if match == nil {
+		return date
+	}
+
+	days := 0
+	if match[2] != "" {
+		days, _ = strconv.Atoi(match[2])
+		if match[1] == "-" {
+			days = -days
+		}
+	}
+	return now.In(loc).AddDate(0, 0, days).Format(period.DateLayout)
+}
+
+// SeedFromFile loads the fixture at path into the database. Users that already
+// exist are skipped with all their contexts and notes, so seeding only happens on
+// first start and restarts keep edits. Seeded notes are not queued for sync.
+func SeedFromFile(ctx context.Context, repo *database.Repository, path string, clk clock.Clock, logger *slog.Logger) error {
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		return err
+	}
+
+	now := clk.Now()
+	for _, u := range fixture.Users {
+		existing, err := repo.GetUser(ctx, u.ID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			logger.Info("seed: user already exists", "user_id", u.ID)
+			continue
+		}
+
+		user := &models.User{
+			ID:       u.ID,
+			GoogleID: u.ID,
+			Email:    u.Email,
+			Name:     u.Name,
+			Settings: models.UserSettings{
+				Theme:      cmp.Or(u.Settings.Theme, "dark"),
+				Timezone:   cmp.Or(u.Settings.Timezone, "UTC"),
+				DateFormat: cmp.Or(u.Settings.DateFormat, "YYYY-MM-DD"),
+				WeekStart:  u.Settings.WeekStart,
+			},
+			CreatedAt:   now,
+			LastLoginAt: now,
+		}
+		if err := repo.UpsertUser(ctx, user); err != nil {
+			return fmt.Errorf("failed to seed user %s: %w", u.ID, err)
+		}
+		loc, _ := time.LoadLocation(user.Settings.Timezone)
+
+		notes := 0
+		for i, c := range u.Contexts {
+			seeded := &models.Context{
+				ID:        fmt.Sprintf("seed-%s-%d", u.ID, i+1),
+				UserID:    u.ID,
+				Name:      c.Name,
+				Color:     cmp.Or(c.Color, "primary"),
+				Template:  c.Template,
+				CreatedAt: now,
+			}
+			if err := repo.CreateContext(ctx, seeded); err != nil {
+				return fmt.Errorf("failed to seed context %s/%s: %w", u.ID, c.Name, err)
+			}
+
+			for _, n := range c.Notes {
+				note := &models.Note{
+					UserID:    u.ID,
+					Context:   c.Name,
+					Date:      noteDate(n.Date, now, loc),
+					Content:   n.Content,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+				if err := repo.UpsertNote(ctx, note, false); err != nil {
+					return fmt.Errorf("failed to seed note %s/%s/%s: %w", u.ID, c.Name, note.Date, err)
+				}
+				notes++
+			}
+		}
+
+		logger.Info("seed: user seeded", "user_id", u.ID, "contexts", len(u.Contexts), "notes", notes)
+	}
+	return nil
+}
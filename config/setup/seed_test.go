@@ -0,0 +1,104 @@
+package setup
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/pkg/clock"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedFromFile(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Migrate())
+	repo := database.NewRepository(db)
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// 23:30 UTC is already the next day in Berlin
+	clk := clock.NewFake(time.Date(2025, 10, 16, 23, 30, 0, 0, time.UTC))
+
+	t.Run("Seeds the example fixture", func(t *testing.T) {
+		require.NoError(t, SeedFromFile(ctx, repo, "../../scripts/seed.example.yaml", clk, logger))
+
+		user, err := repo.GetUser(ctx, "demo-alice")
+		require.NoError(t, err)
+		require.NotNil(t, user)
+		assert.Equal(t, "Europe/Berlin", user.Settings.Timezone)
+
+		contexts, err := repo.GetContexts(ctx, database.ScopeUser("demo-alice"))
+		require.NoError(t, err)
+		require.Len(t, contexts, 2)
+
+		note, err := repo.GetNote(ctx, database.ScopeUser("demo-alice"), "Work", "2025-10-17")
+		require.NoError(t, err)
+		require.NotNil(t, note)
+		assert.Contains(t, note.Content, "#release checklist")
+
+		note, err = repo.GetNote(ctx, database.ScopeUser("demo-alice"), "Work", "2025-W02")
+		require.NoError(t, err)
+		require.NotNil(t, note)
+
+		bob, err := repo.GetUser(ctx, "demo-bob")
+		require.NoError(t, err)
+		require.NotNil(t, bob)
+		assert.Equal(t, "UTC", bob.Settings.Timezone)
+		note, err = repo.GetNote(ctx, database.ScopeUser("demo-bob"), "Journal", "2025-10-14")
+		require.NoError(t, err)
+		require.NotNil(t, note)
+	})
+
+	t.Run("Skips users that already exist", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "seed.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"users": [
+			{"id": "demo-alice", "email": "alice@example.com", "contexts": [{"name": "Extra"}]},
+			{"id": "demo-carol", "email": "carol@example.com", "contexts": [{"name": "Work", "notes": [{"date": "today", "content": "Hi"}]}]}
+		]}`), 0o644))
+		require.NoError(t, SeedFromFile(ctx, repo, path, clk, logger))
+
+		contexts, err := repo.GetContexts(ctx, database.ScopeUser("demo-alice"))
+		require.NoError(t, err)
+		assert.Len(t, contexts, 2)
+
+		note, err := repo.GetNote(ctx, database.ScopeUser("demo-carol"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, note)
+		assert.Equal(t, "Hi", note.Content)
+	})
+
+	t.Run("Rejects invalid fixtures", func(t *testing.T) {
+		cases := map[string]string{
+			"bad-date.yaml":  "users:\n  - id: u\n    email: u@example.com\n    contexts:\n      - name: Work\n        notes:\n          - date: yesterday\n",
+			"unknown.yaml":   "users:\n  - id: u\n    email: u@example.com\n    colour: red\n",
+			"duplicate.json": `{"users": [{"id": "u", "email": "u@example.com", "contexts": [{"name": "A"}, {"name": "A"}]}]}`,
+			"no-email.json":  `{"users": [{"id": "u"}]}`,
+		}
+		for name, content := range cases {
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			_, err := LoadFixture(path)
+			assert.Error(t, err, name)
+		}
+
+		user, err := repo.GetUser(ctx, "u")
+		require.NoError(t, err)
+		assert.Nil(t, user)
+	})
+}
+
+func TestTestLoginUsers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	assert.Equal(t, []string{TestUserID}, TestLoginUsers("", logger))
+	assert.Equal(t, []string{TestUserID, "demo-alice", "demo-bob"}, TestLoginUsers("../../scripts/seed.example.yaml", logger))
+	assert.Equal(t, []string{TestUserID}, TestLoginUsers("missing.yaml", logger), "an unreadable fixture signs in no one else")
+}
@@ -17,6 +17,8 @@ func NewFiberApp(logger *slog.Logger) *fiber.App {
 		DisableStartupMessage: config.AppConfig.Env == "production",
 		ErrorHandler:          CustomErrorHandler(logger),
 		ReadBufferSize:        8192,
+		// Room for an attachment and the rest of its form; fiber's default is 4 MB
+		BodyLimit: max(4<<20, config.AppConfig.AttachmentMaxSize+64<<10),
 	})
 }
 
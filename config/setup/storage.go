@@ -0,0 +1,79 @@
+package setup
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/storage"
+	"daily-notes/storage/drive"
+	"daily-notes/storage/dropbox"
+	"daily-notes/storage/local"
+	"daily-notes/storage/s3"
+	"daily-notes/storage/webdav"
+
+	"golang.org/x/oauth2"
+)
+
+// NewStorageRegistry registers Google Drive and every other provider configured on this server
+func NewStorageRegistry(repo *database.Repository) *storage.Registry {
+	registry := storage.NewRegistry(storage.Drive)
+
+	registry.Register(storage.Drive, func(ctx context.Context, token *oauth2.Token, userID string) (storage.Provider, error) {
+		return drive.NewService(ctx, token, userID)
+	})
+
+	if dropbox.Enabled() {
+		registry.Register(storage.Dropbox, func(ctx context.Context, token *oauth2.Token, userID string) (storage.Provider, error) {
+			dropboxToken, err := repo.GetStorageToken(ctx, userID, storage.Dropbox)
+			if err != nil {
+				return nil, err
+			}
+			return dropbox.NewService(ctx, dropbox.Credentials{
+				Token: dropboxToken,
+				Save: func(refreshed *oauth2.Token) error {
+					// The provider's context may already be done when the token is refreshed
+					return repo.SaveStorageToken(context.Background(), userID, storage.Dropbox, refreshed)
+				},
+			}, token, userID)
+		})
+	}
+
+	if local.Enabled() {
+		registry.Register(storage.Local, func(ctx context.Context, token *oauth2.Token, userID string) (storage.Provider, error) {
+			return local.NewService(local.Dir(), token, userID)
+		})
+	}
+
+	if s3.Enabled() {
+		registry.Register(storage.S3, func(ctx context.Context, token *oauth2.Token, userID string) (storage.Provider, error) {
+			client, err := s3.NewClient()
+			if err != nil {
+				return nil, err
+			}
+			return s3.NewService(ctx, client, s3.Prefix(), token, userID)
+		})
+	}
+
+	if webdav.Enabled() {
+		registry.Register(storage.WebDAV, func(ctx context.Context, token *oauth2.Token, userID string) (storage.Provider, error) {
+			client, err := webdav.NewClient()
+			if err != nil {
+				return nil, err
+			}
+			return webdav.NewService(ctx, client, token, userID)
+		})
+	}
+
+	return registry
+}
+
+// wrapStorage wraps the storage factory; builds with the chaos tag replace it to
+// inject storage faults, see chaos.go
+var wrapStorage = func(factory storage.Factory) storage.Factory {
+	return factory
+}
+
+// NewStorageFactory opens the storage provider each user picked, defaulting to Google Drive
+// The choice is read on every call, so the sync worker follows a switch on its next run.
+func NewStorageFactory(repo *database.Repository, registry *storage.Registry) storage.Factory {
+	return wrapStorage(registry.Factory(repo.GetStorageProvider))
+}
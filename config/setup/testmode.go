@@ -0,0 +1,117 @@
+package setup
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/period"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Demo user seeded in test mode; POST /api/test/login signs in as this user
+const (
+	TestUserID    = "test-user"
+	TestUserEmail = "demo@dailynotes.dev"
+	TestUserName  = "Demo User"
+)
+
+// testContexts are created for the demo user, in this order
+var testContexts = []models.Context{
+	{ID: "test-context-work", Name: "Work", Color: "primary"},
+	{ID: "test-context-personal", Name: "Personal", Color: "success"},
+}
+
+// testNoteDays is how many days of fixture notes (ending today) are seeded per context
+const testNoteDays = 7
+
+// ParseTestModeStart parses the TEST_MODE_START value for the fake clock
+func ParseTestModeStart(value string) (time.Time, error) {
+	start, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid TEST_MODE_START %q: %w", value, err)
+	}
+	return start.UTC(), nil
+}
+
+// TestLoginUsers are the users POST /api/test/login may sign in as: the demo
+// user and the users of the SEED_FILE fixture, never other accounts
+func TestLoginUsers(seedFile string, logger *slog.Logger) []string {
+	users := []string{TestUserID}
+	if seedFile == "" {
+		return users
+	}
+	fixture, err := LoadFixture(seedFile)
+	if err != nil {
+		logger.Error("test mode: fixture users can't sign in", "path", seedFile, "error", err)
+		return users
+	}
+	for _, u := range fixture.Users {
+		users = append(users, u.ID)
+	}
+	return users
+}
+
+// SeedTestData creates the demo user with contexts and a week of fixture notes
+// Seeding is skipped when the demo user already exists so restarts keep edits
+func SeedTestData(ctx context.Context, repo *database.Repository, clk clock.Clock, logger *slog.Logger) error {
+	existing, err := repo.GetUser(ctx, TestUserID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		logger.Info("test mode: demo user already seeded", "user_id", TestUserID)
+		return nil
+	}
+
+	now := clk.Now()
+	user := &models.User{
+		ID:       TestUserID,
+		GoogleID: TestUserID,
+		Email:    TestUserEmail,
+		Name:     TestUserName,
+		Settings: models.UserSettings{
+			Theme:          "dark",
+			Timezone:       "UTC",
+			DateFormat:     "YYYY-MM-DD",
+			ShowBreadcrumb: true,
+		},
+		CreatedAt:   now,
+		LastLoginAt: now,
+	}
+	if err := repo.UpsertUser(ctx, user); err != nil {
+		return fmt.Errorf("failed to seed demo user: %w", err)
+	}
+
+	for _, c := range testContexts {
+		c.UserID = TestUserID
+		c.CreatedAt = now
+		if err := repo.CreateContext(ctx, &c); err != nil {
+			return fmt.Errorf("failed to seed context %s: %w", c.Name, err)
+		}
+
+		for i := testNoteDays - 1; i >= 0; i-- {
+			day := now.AddDate(0, 0, -i)
+			note := &models.Note{
+				UserID:    TestUserID,
+				Context:   c.Name,
+				Date:      day.Format(period.DateLayout),
+				Content:   fmt.Sprintf("# %s\n\n%s notes for %s.\n\n- [ ] Fixture task %d\n", day.Format("Monday"), c.Name, day.Format("January 2"), i+1),
+				CreatedAt: day,
+				UpdatedAt: day,
+			}
+			if err := repo.UpsertNote(ctx, note, false); err != nil {
+				return fmt.Errorf("failed to seed note %s/%s: %w", c.Name, note.Date, err)
+			}
+		}
+	}
+
+	logger.Info("test mode: demo data seeded",
+		"user_id", TestUserID,
+		"contexts", len(testContexts),
+		"notes", len(testContexts)*testNoteDays,
+	)
+	return nil
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ==================== API KEYS ====================
+
+// apiKeyColumns are the columns scanned by scanAPIKey
+const apiKeyColumns = `id, user_id, name, scope, created_at, last_used_at`
+
+// CreateAPIKey saves a key under the hash of its secret and sets its ID
+func (r *Repository) CreateAPIKey(ctx context.Context, key *models.APIKey, hash string) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (user_id, name, key_hash, scope, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, key.UserID, key.Name, hash, key.Scope, key.CreatedAt).Scan(&key.ID)
+}
+
+// GetAPIKeys lists the keys of a user, newest first
+func (r *Repository) GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = ? ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// GetAPIKeyByHash returns the key with the hash of a secret, nil if there is none
+func (r *Repository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?
+	`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return key, err
+}
+
+// TouchAPIKey records when a key was last used
+func (r *Repository) TouchAPIKey(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, at, id)
+	return err
+}
+
+// DeleteAPIKey revokes a key of a user; it reports false if there was none with that ID
+func (r *Repository) DeleteAPIKey(ctx context.Context, userID string, id int64) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `DELETE FROM api_keys WHERE user_id = ? AND id = ?`, userID, id))
+}
+
+// scanAPIKey reads a row selecting apiKeyColumns
+func scanAPIKey(row interface{ Scan(...any) error }) (*models.APIKey, error) {
+	var key models.APIKey
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Scope, &key.CreatedAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	return &key, nil
+}
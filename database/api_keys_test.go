@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	shortcut := &models.APIKey{UserID: "test-user", Name: "Shortcut", Scope: models.KeyReadWrite, CreatedAt: now}
+	require.NoError(t, repo.CreateAPIKey(ctx, shortcut, "hash-1"))
+	assert.NotZero(t, shortcut.ID)
+
+	t.Run("Keys are found by the hash of their secret", func(t *testing.T) {
+		got, err := repo.GetAPIKeyByHash(ctx, "hash-1")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "test-user", got.UserID)
+		assert.Equal(t, models.KeyReadWrite, got.Scope)
+		assert.Nil(t, got.LastUsedAt)
+
+		got, err = repo.GetAPIKeyByHash(ctx, "hash-2")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Use is recorded", func(t *testing.T) {
+		require.NoError(t, repo.TouchAPIKey(ctx, shortcut.ID, now.Add(time.Hour)))
+		keys, err := repo.GetAPIKeys(ctx, "test-user")
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		require.NotNil(t, keys[0].LastUsedAt)
+		assert.True(t, keys[0].LastUsedAt.Equal(now.Add(time.Hour)))
+	})
+
+	t.Run("Only the owner revokes a key", func(t *testing.T) {
+		deleted, err := repo.DeleteAPIKey(ctx, "other-user", shortcut.ID)
+		require.NoError(t, err)
+		assert.False(t, deleted)
+
+		deleted, err = repo.DeleteAPIKey(ctx, "test-user", shortcut.ID)
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		got, err := repo.GetAPIKeyByHash(ctx, "hash-1")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ==================== API TOKENS ====================
+
+// apiTokenColumns are the columns scanned by scanAPIToken
+const apiTokenColumns = `id, user_id, name, context, operations, created_at, last_used_at`
+
+// CreateAPIToken saves a token under the hash of its secret and sets its ID
+func (r *Repository) CreateAPIToken(ctx context.Context, token *models.APIToken, hash string) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO api_tokens (user_id, name, token_hash, context, operations, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, token.UserID, token.Name, hash, token.Context, strings.Join(token.Operations, ","), token.CreatedAt).Scan(&token.ID)
+}
+
+// GetAPITokens lists the tokens of a user, newest first
+func (r *Repository) GetAPITokens(ctx context.Context, userID string) ([]models.APIToken, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetAPITokenByHash returns the token with the hash of a secret, nil if there is none
+func (r *Repository) GetAPITokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	token, err := scanAPIToken(r.db.QueryRowContext(ctx, `
+		SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?
+	`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return token, err
+}
+
+// TouchAPIToken records when a token was last used
+func (r *Repository) TouchAPIToken(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, at, id)
+	return err
+}
+
+// DeleteAPIToken revokes a token of a user; it reports false if there was none with that ID
+func (r *Repository) DeleteAPIToken(ctx context.Context, userID string, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE user_id = ? AND id = ?`, userID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// scanAPIToken reads a row selecting apiTokenColumns
+func scanAPIToken(row interface{ Scan(...any) error }) (*models.APIToken, error) {
+	var token models.APIToken
+	var operations string
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Context, &operations,
+		&token.CreatedAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	token.Operations = strings.Split(operations, ",")
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return &token, nil
+}
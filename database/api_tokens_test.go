@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokens(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	scale := &models.APIToken{UserID: "test-user", Name: "Scale", Context: "Fitness",
+		Operations: []string{models.TokenAppend}, CreatedAt: now}
+	require.NoError(t, repo.CreateAPIToken(ctx, scale, "hash-1"))
+	assert.NotZero(t, scale.ID)
+
+	t.Run("Tokens are found by the hash of their secret", func(t *testing.T) {
+		got, err := repo.GetAPITokenByHash(ctx, "hash-1")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "test-user", got.UserID)
+		assert.Equal(t, []string{models.TokenAppend}, got.Operations)
+		assert.Nil(t, got.LastUsedAt)
+
+		got, err = repo.GetAPITokenByHash(ctx, "hash-2")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Use is recorded", func(t *testing.T) {
+		require.NoError(t, repo.TouchAPIToken(ctx, scale.ID, now.Add(time.Hour)))
+		tokens, err := repo.GetAPITokens(ctx, "test-user")
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		require.NotNil(t, tokens[0].LastUsedAt)
+		assert.True(t, tokens[0].LastUsedAt.Equal(now.Add(time.Hour)))
+	})
+
+	t.Run("Tokens follow their context's renames", func(t *testing.T) {
+		require.NoError(t, repo.UpdateNotesContextName(ctx, ScopeUser("test-user"), "Fitness", "Health"))
+		got, err := repo.GetAPITokenByHash(ctx, "hash-1")
+		require.NoError(t, err)
+		assert.Equal(t, "Health", got.Context)
+	})
+
+	t.Run("Only the owner revokes a token", func(t *testing.T) {
+		deleted, err := repo.DeleteAPIToken(ctx, "other-user", scale.ID)
+		require.NoError(t, err)
+		assert.False(t, deleted)
+
+		deleted, err = repo.DeleteAPIToken(ctx, "test-user", scale.ID)
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		got, err := repo.GetAPITokenByHash(ctx, "hash-1")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+}
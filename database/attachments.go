@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+)
+
+// ==================== ATTACHMENTS ====================
+
+// attachmentColumns are the columns scanned by scanAttachment
+const attachmentColumns = `id, user_id, context, date, filename, content_type, size,
+	storage_status, COALESCE(storage_error, ''), created_at`
+
+// CreateAttachment saves the record of a file attached to a note
+func (r *Repository) CreateAttachment(ctx context.Context, a *models.Attachment) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO attachments (id, user_id, context, date, filename, content_type, size, storage_status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, a.ID, a.UserID, a.Context, a.Date, a.Filename, a.ContentType, a.Size, a.StorageStatus, a.CreatedAt)
+	return err
+}
+
+// GetAttachment returns an attachment of a user, nil if there is none with that ID
+func (r *Repository) GetAttachment(ctx context.Context, userID, id string) (*models.Attachment, error) {
+	a, err := scanAttachment(r.db.QueryRowContext(ctx, `
+		SELECT `+attachmentColumns+` FROM attachments WHERE user_id = ? AND id = ?
+	`, userID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return a, err
+}
+
+// GetNoteAttachments returns the attachments of a note, oldest first
+func (r *Repository) GetNoteAttachments(ctx context.Context, userID, contextName, date string) ([]models.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM attachments
+		WHERE user_id = ? AND context = ? AND date = ?
+		ORDER BY created_at ASC, id ASC
+	`, userID, contextName, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+	}
+	return attachments, rows.Err()
+}
+
+// SetAttachmentStorageStatus records whether an attachment was copied to storage,
+// with the error of a failed copy
+func (r *Repository) SetAttachmentStorageStatus(ctx context.Context, id, status, errorMsg string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE attachments SET storage_status = ?, storage_error = NULLIF(?, '') WHERE id = ?
+	`, status, errorMsg, id)
+	return err
+}
+
+// scanAttachment reads a row selecting attachmentColumns
+func scanAttachment(row interface{ Scan(...any) error }) (*models.Attachment, error) {
+	var a models.Attachment
+	if err := row.Scan(&a.ID, &a.UserID, &a.Context, &a.Date, &a.Filename, &a.ContentType, &a.Size,
+		&a.StorageStatus, &a.StorageError, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
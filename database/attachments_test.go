@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachments(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	photo := &models.Attachment{ID: "att-1", UserID: "test-user", Context: "Work", Date: "2025-10-16",
+		Filename: "board.png", ContentType: "image/png", Size: 2048, StorageStatus: models.AttachmentPending, CreatedAt: now}
+	require.NoError(t, repo.CreateAttachment(ctx, photo))
+
+	t.Run("Attachments are read back by user", func(t *testing.T) {
+		got, err := repo.GetAttachment(ctx, "test-user", "att-1")
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, "board.png", got.Filename)
+		assert.Equal(t, int64(2048), got.Size)
+		assert.Equal(t, models.AttachmentPending, got.StorageStatus)
+
+		got, err = repo.GetAttachment(ctx, "other-user", "att-1")
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Storage status is recorded", func(t *testing.T) {
+		require.NoError(t, repo.SetAttachmentStorageStatus(ctx, "att-1", models.AttachmentPending, "quota exceeded"))
+		got, err := repo.GetAttachment(ctx, "test-user", "att-1")
+		require.NoError(t, err)
+		assert.Equal(t, "quota exceeded", got.StorageError)
+
+		require.NoError(t, repo.SetAttachmentStorageStatus(ctx, "att-1", models.AttachmentStored, ""))
+		got, err = repo.GetAttachment(ctx, "test-user", "att-1")
+		require.NoError(t, err)
+		assert.Equal(t, models.AttachmentStored, got.StorageStatus)
+		assert.Empty(t, got.StorageError)
+	})
+
+	t.Run("Attachments follow their context's renames", func(t *testing.T) {
+		require.NoError(t, repo.UpdateNotesContextName(ctx, ScopeUser("test-user"), "Work", "Job"))
+		attachments, err := repo.GetNoteAttachments(ctx, "test-user", "Job", "2025-10-16")
+		require.NoError(t, err)
+		require.Len(t, attachments, 1)
+		assert.Equal(t, "att-1", attachments[0].ID)
+
+		attachments, err = repo.GetNoteAttachments(ctx, "test-user", "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Empty(t, attachments)
+	})
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrBackupUnsupported is returned by Backup on databases other than SQLite,
+// which are backed up with their own tools (pg_dump)
+var ErrBackupUnsupported = errors.New("database backups need SQLite")
+
+// Backup writes a consistent copy of the database to path with VACUUM INTO,
+// which runs alongside other connections and includes changes still in the WAL.
+// path must not exist yet.
+func (db *DB) Backup(ctx context.Context, path string) error {
+	if db.dialect != SQLite {
+		return ErrBackupUnsupported
+	}
+	if _, err := db.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// CheckBackup opens a backup read-only and runs SQLite's integrity check on it
+func CheckBackup(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	backup, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer backup.Close()
+
+	var result string
+	if err := backup.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("failed to check backup: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup is corrupt: %s", result)
+	}
+
+	var users int
+	if err := backup.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&users); err != nil {
+		return fmt.Errorf("not a daily-notes database: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the database at dbPath with a backup. The server must not be
+// running. The current database is first saved to dbPath+".pre-restore", so a
+// restore can be undone by restoring that file.
+func Restore(backupPath, dbPath string) error {
+	if err := CheckBackup(backupPath); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		current, err := New(dbPath)
+		if err != nil {
+			return err
+		}
+		previous := dbPath + ".pre-restore"
+		os.Remove(previous)
+		err = current.Backup(context.Background(), previous)
+		current.Close()
+		if err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	data, err := os.ReadFile(backupPath)
+	if err != nil {
+		return err
+	}
+	tmp := dbPath + ".restore"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	// The WAL and shared-memory files belong to the replaced database
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, dbPath)
+}
+
+// DumpSchema returns the statements creating the database's tables, indexes and
+// triggers, one per line, so a snapshot of notes can be loaded back into a fresh database.
+// The shadow tables of the full-text index are left out: creating notes_fts makes them.
+func (r *Repository) DumpSchema(ctx context.Context) (string, error) {
+	if r.db.dialect == Postgres {
+		return r.dumpPostgresSchema(ctx)
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+			AND NOT (type = 'table' AND name GLOB 'notes_fts_*')
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END, name
+	`)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var schema strings.Builder
+	for rows.Next() {
+		var statement string
+		if err := rows.Scan(&statement); err != nil {
+			return "", err
+		}
+		schema.WriteString(statement)
+		schema.WriteString(";\n")
+	}
+	return schema.String(), rows.Err()
+}
+
+// dumpPostgresSchema rebuilds CREATE TABLE statements from PostgreSQL's catalog
+// Only columns, their types and NOT NULL are included, enough to load the data back.
+func (r *Repository) dumpPostgresSchema(ctx context.Context) (string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT table_name, column_name, data_type, is_nullable
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		ORDER BY table_name, ordinal_position
+	`)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var schema strings.Builder
+	table := ""
+	for rows.Next() {
+		var name, column, dataType, nullable string
+		if err := rows.Scan(&name, &column, &dataType, &nullable); err != nil {
+			return "", err
+		}
+		if name != table {
+			if table != "" {
+				schema.WriteString("\n);\n")
+			}
+			fmt.Fprintf(&schema, "CREATE TABLE %s (\n", name)
+			table = name
+		} else {
+			schema.WriteString(",\n")
+		}
+		fmt.Fprintf(&schema, "\t%s %s", column, dataType)
+		if nullable == "NO" {
+			schema.WriteString(" NOT NULL")
+		}
+	}
+	if table != "" {
+		schema.WriteString("\n);\n")
+	}
+	return schema.String(), rows.Err()
+}
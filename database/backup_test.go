@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "daily-notes.db")
+	ctx := context.Background()
+
+	db, err := New(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, db.Migrate())
+	repo := NewRepository(db)
+	require.NoError(t, repo.UpsertUser(ctx, &models.User{ID: "test-user", GoogleID: "google-123", Email: "test@example.com", CreatedAt: time.Now()}))
+
+	save := func(content string) {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: content,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}, false))
+	}
+	content := func(path string) string {
+		db, err := New(path)
+		require.NoError(t, err)
+		defer db.Close()
+		note, err := NewRepository(db).GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, note)
+		return note.Content
+	}
+
+	save("Before the backup")
+	backupPath := filepath.Join(dir, "backup.db")
+	require.NoError(t, db.Backup(ctx, backupPath))
+	require.NoError(t, CheckBackup(backupPath))
+	assert.Error(t, db.Backup(ctx, backupPath), "an existing file is never overwritten")
+
+	save("After the backup")
+	require.NoError(t, db.Close())
+
+	require.NoError(t, Restore(backupPath, dbPath))
+	assert.Equal(t, "Before the backup", content(dbPath))
+	assert.Equal(t, "After the backup", content(dbPath+".pre-restore"))
+
+	t.Run("Corrupt backups are refused", func(t *testing.T) {
+		corrupt := filepath.Join(dir, "corrupt.db")
+		require.NoError(t, os.WriteFile(corrupt, []byte("not a database"), 0600))
+
+		assert.Error(t, Restore(corrupt, dbPath))
+		assert.Error(t, Restore(filepath.Join(dir, "missing.db"), dbPath))
+		assert.Equal(t, "Before the backup", content(dbPath))
+	})
+}
+
+func TestDumpSchema(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	schema, err := repo.DumpSchema(ctx)
+	require.NoError(t, err)
+
+	// The dump recreates the database in an empty one
+	fresh, err := New(filepath.Join(t.TempDir(), "fresh.db"))
+	require.NoError(t, err)
+	defer fresh.Close()
+	_, err = fresh.ExecContext(ctx, schema)
+	require.NoError(t, err)
+
+	var tables int
+	require.NoError(t, fresh.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'notes'`).Scan(&tables))
+	assert.Equal(t, 1, tables)
+}
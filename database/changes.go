@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ==================== CHANGES PULLED FROM STORAGE ====================
+
+// NoteSyncState is what pulling a note from storage needs to know about its local copy
+type NoteSyncState struct {
+	ID          string
+	Revision    int
+	Content     string
+	ContentHash string // Hash of the last upload; empty if the note never uploaded
+	Pending     bool   // Has changes that aren't in storage yet, or is in conflict
+	Deleted     bool   // Waiting for its file to be deleted
+	LocalOnly   bool   // Never synced, marked so itself or through its context
+}
+
+// GetNoteSyncState returns the sync state of a note, nil if there is no such note
+func (r *Repository) GetNoteSyncState(ctx context.Context, userID, contextName, date string) (*NoteSyncState, error) {
+	var state NoteSyncState
+	var content, contentHash sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, revision, content, content_hash, sync_pending = 1 OR sync_status != ?, deleted = 1,
+		       `+localOnlyCondition+`
+		FROM notes
+		WHERE user_id = ? AND context = ? AND date = ?
+	`, string(models.SyncStatusSynced), userID, contextName, date).Scan(
+		&state.ID, &state.Revision, &content, &contentHash, &state.Pending, &state.Deleted, &state.LocalOnly,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state.Content = content.String
+	state.ContentHash = contentHash.String
+	return &state, nil
+}
+
+// ApplyRemoteNote saves a note changed in storage as the synced local version.
+// fileID and contentHash describe the file it was read from, and its modification
+// time (note.UpdatedAt) counts as the last sync. With a baseRevision of 0 the note
+// must not exist locally yet; otherwise it is only overwritten while it is still at
+// baseRevision with no local changes pending. Returns false (without error) when
+// the note was changed locally in the meantime.
+func (r *Repository) ApplyRemoteNote(ctx context.Context, note *models.Note, fileID, contentHash string, baseRevision int) (bool, error) {
+	if note.ID == "" {
+		note.ID = fmt.Sprintf("%s-%s-%s", note.UserID, note.Context, note.Date)
+	}
+	setGranularity(note)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var result sql.Result
+	if baseRevision == 0 {
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO notes (id, user_id, context, date, granularity, content, drive_file_id, content_hash,
+				synced_at, sync_pending, sync_status, sync_retry_count, deleted, revision, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, 0, 0, 1, ?, ?)
+			ON CONFLICT(user_id, context, date) DO NOTHING
+		`,
+			note.ID, note.UserID, note.Context, note.Date, note.Type, note.Content, fileID, contentHash,
+			note.UpdatedAt, string(models.SyncStatusSynced), note.CreatedAt, note.UpdatedAt,
+		)
+	} else {
+		if err := saveRevision(ctx, tx, note, baseRevision); err != nil {
+			return false, err
+		}
+		result, err = tx.ExecContext(ctx, `
+			UPDATE notes SET
+				content = ?,
+				drive_file_id = ?,
+				content_hash = ?,
+				synced_at = ?,
+				revision = revision + 1,
+				updated_at = ?
+			WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0
+			  AND sync_pending = 0 AND sync_status = ? AND revision = ?
+		`,
+			note.Content, fileID, contentHash, note.UpdatedAt, note.UpdatedAt,
+			note.UserID, note.Context, note.Date, string(models.SyncStatusSynced), baseRevision,
+		)
+	}
+	if saved, err := affected(result, err); err != nil || !saved {
+		return false, err
+	}
+
+	if err := saveNoteTags(ctx, tx, note); err != nil {
+		return false, err
+	}
+	if err := saveNoteTasks(ctx, tx, note); err != nil {
+		return false, err
+	}
+	if err := saveNoteReminders(ctx, tx, note); err != nil {
+		return false, err
+	}
+	if err := saveNoteCounts(ctx, tx, note); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetChangeToken returns where the next pull of a user's storage changes starts, "" before the first
+func (r *Repository) GetChangeToken(ctx context.Context, userID string) (string, error) {
+	var token string
+	err := r.db.QueryRowContext(ctx, `SELECT page_token FROM change_tokens WHERE user_id = ?`, userID).Scan(&token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return token, err
+}
+
+// SaveChangeToken records where the next pull of a user's storage changes starts
+func (r *Repository) SaveChangeToken(ctx context.Context, userID, token string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO change_tokens (user_id, page_token, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET page_token = excluded.page_token, updated_at = excluded.updated_at
+	`, userID, token, time.Now())
+	return err
+}
+
+// GetUsersToPull returns the signed-in users with notes in storage
+// Users without a session have no token to read storage with.
+func (r *Repository) GetUsersToPull(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT n.user_id
+		FROM notes n
+		JOIN sessions s ON s.user_id = n.user_id AND s.expires_at > ?
+		WHERE n.drive_file_id IS NOT NULL
+		ORDER BY n.user_id
+	`, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		users = append(users, userID)
+	}
+	return users, rows.Err()
+}
+
+// GetChangeChannel returns the user's channel for storage change notifications, nil if there is none
+func (r *Repository) GetChangeChannel(ctx context.Context, userID string) (*models.ChangeChannel, error) {
+	return r.getChangeChannel(ctx, `user_id = ?`, userID)
+}
+
+// GetChangeChannelByID returns the channel a change notification was sent to, nil if it is unknown
+func (r *Repository) GetChangeChannelByID(ctx context.Context, channelID string) (*models.ChangeChannel, error) {
+	return r.getChangeChannel(ctx, `id = ?`, channelID)
+}
+
+func (r *Repository) getChangeChannel(ctx context.Context, where string, arg string) (*models.ChangeChannel, error) {
+	var channel models.ChangeChannel
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, resource_id, token, expires_at FROM change_channels WHERE `+where, arg,
+	).Scan(&channel.ID, &channel.UserID, &channel.ResourceID, &channel.Token, &channel.Expiration)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// SaveChangeChannel records a user's channel for storage change notifications,
+// replacing their previous one
+func (r *Repository) SaveChangeChannel(ctx context.Context, channel *models.ChangeChannel) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO change_channels (id, user_id, resource_id, token, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			id = excluded.id,
+			resource_id = excluded.resource_id,
+			token = excluded.token,
+			expires_at = excluded.expires_at,
+			created_at = excluded.created_at
+	`, channel.ID, channel.UserID, channel.ResourceID, channel.Token, channel.Expiration, time.Now())
+	return err
+}
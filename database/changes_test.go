@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyRemoteNote(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	remote := func(content string) *models.Note {
+		now := time.Now()
+		return &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: content, CreatedAt: now, UpdatedAt: now}
+	}
+
+	t.Run("New notes are saved as synced", func(t *testing.T) {
+		applied, err := repo.ApplyRemoteNote(ctx, remote("from the phone"), "file-1", "hash-1", 0)
+		require.NoError(t, err)
+		require.True(t, applied)
+
+		state, err := repo.GetNoteSyncState(ctx, "test-user", "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, state)
+		assert.Equal(t, "from the phone", state.Content)
+		assert.Equal(t, "hash-1", state.ContentHash)
+		assert.False(t, state.Pending)
+
+		applied, err = repo.ApplyRemoteNote(ctx, remote("again"), "file-1", "hash-2", 0)
+		require.NoError(t, err)
+		assert.False(t, applied, "the note exists now")
+	})
+
+	t.Run("Local edits are never overwritten", func(t *testing.T) {
+		state, err := repo.GetNoteSyncState(ctx, "test-user", "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NoError(t, repo.UpsertNote(ctx, remote("local edit"), true))
+
+		applied, err := repo.ApplyRemoteNote(ctx, remote("phone edit"), "file-1", "hash-2", state.Revision)
+		require.NoError(t, err)
+		assert.False(t, applied)
+
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, "local edit", note.Content)
+	})
+
+	t.Run("Change tokens are kept per user", func(t *testing.T) {
+		token, err := repo.GetChangeToken(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Empty(t, token)
+
+		require.NoError(t, repo.SaveChangeToken(ctx, "test-user", "42"))
+		require.NoError(t, repo.SaveChangeToken(ctx, "test-user", "43"))
+		token, err = repo.GetChangeToken(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, "43", token)
+	})
+}
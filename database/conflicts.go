@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ==================== SYNC CONFLICTS ====================
+
+// MarkNoteConflict keeps the storage version of a note that was also changed
+// there since its last sync and stops syncing the note until the conflict is
+// resolved. A later conflict on the same note replaces the kept version.
+func (r *Repository) MarkNoteConflict(ctx context.Context, noteID, remoteContent string, remoteModifiedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO note_conflicts (note_id, user_id, remote_content, remote_modified_at, detected_at)
+		SELECT id, user_id, ?, ?, ? FROM notes WHERE id = ?
+		ON CONFLICT(note_id) DO UPDATE SET
+			remote_content = excluded.remote_content,
+			remote_modified_at = excluded.remote_modified_at,
+			detected_at = excluded.detected_at
+	`, remoteContent, remoteModifiedAt, time.Now(), noteID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE notes SET
+			sync_pending = 0,
+			sync_status = ?,
+			sync_error = 'Changed in storage since the last sync',
+			sync_last_attempt_at = ?
+		WHERE id = ?
+	`, string(models.SyncStatusConflict), time.Now(), noteID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetNoteConflicts returns the user's live notes in sync conflict, oldest first
+func (r *Repository) GetNoteConflicts(ctx context.Context, userID string) ([]models.NoteConflict, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT n.context, n.date, COALESCE(n.content, ''), c.remote_content, c.remote_modified_at, c.detected_at
+		FROM note_conflicts c
+		JOIN notes n ON n.id = c.note_id AND n.deleted = 0
+		WHERE c.user_id = ? AND n.user_id = c.user_id
+		ORDER BY c.detected_at ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conflicts := []models.NoteConflict{}
+	for rows.Next() {
+		var conflict models.NoteConflict
+		if err := rows.Scan(
+			&conflict.Context, &conflict.Date, &conflict.LocalContent, &conflict.RemoteContent,
+			&conflict.RemoteModifiedAt, &conflict.DetectedAt,
+		); err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, rows.Err()
+}
+
+// GetNoteConflict returns the conflict of one of the user's live notes, nil if there is none
+func (r *Repository) GetNoteConflict(ctx context.Context, userID, contextName, date string) (*models.NoteConflict, error) {
+	var conflict models.NoteConflict
+	err := r.db.QueryRowContext(ctx, `
+		SELECT n.context, n.date, COALESCE(n.content, ''), c.remote_content, c.remote_modified_at, c.detected_at
+		FROM note_conflicts c
+		JOIN notes n ON n.id = c.note_id AND n.deleted = 0
+		WHERE n.user_id = ? AND n.context = ? AND n.date = ? AND c.user_id = n.user_id
+	`, userID, contextName, date).Scan(
+		&conflict.Context, &conflict.Date, &conflict.LocalContent, &conflict.RemoteContent,
+		&conflict.RemoteModifiedAt, &conflict.DetectedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conflict, nil
+}
+
+// ResolveNoteConflict saves the content chosen for a conflicted note and queues it
+// for sync, unless it is local only by now. The storage version counts as seen (synced_at becomes its modification
+// time), so the upload overwrites it unless storage changes again in the meantime.
+// Returns false (without error) when the note has no conflict.
+func (r *Repository) ResolveNoteConflict(ctx context.Context, note *models.Note) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var noteID string
+	var remoteModifiedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT n.id, c.remote_modified_at
+		FROM note_conflicts c
+		JOIN notes n ON n.id = c.note_id AND n.deleted = 0
+		WHERE n.user_id = ? AND n.context = ? AND n.date = ? AND c.user_id = n.user_id
+	`, note.UserID, note.Context, note.Date).Scan(&noteID, &remoteModifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	syncPending, syncStatus, err := saveSyncState(ctx, tx, note, true)
+	if err != nil {
+		return false, err
+	}
+	if err := saveRevision(ctx, tx, note, 0); err != nil {
+		return false, err
+	}
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE notes SET
+			content = ?,
+			sync_pending = ?,
+			sync_status = ?,
+			sync_retry_count = 0,
+			sync_error = NULL,
+			sync_error_class = NULL,
+			next_retry_at = NULL,
+			synced_at = ?,
+			revision = revision + 1,
+			updated_at = ?
+		WHERE id = ?
+		RETURNING id, revision
+	`, note.Content, syncPending, syncStatus, remoteModifiedAt, note.UpdatedAt, noteID).Scan(&note.ID, &note.Revision); err != nil {
+		return false, err
+	}
+	setSyncState(note, syncStatus)
+	if err := saveNoteTags(ctx, tx, note); err != nil {
+		return false, err
+	}
+	if err := saveNoteTasks(ctx, tx, note); err != nil {
+		return false, err
+	}
+	if err := saveNoteReminders(ctx, tx, note); err != nil {
+		return false, err
+	}
+	if err := saveNoteCounts(ctx, tx, note); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM note_conflicts WHERE note_id = ?`, noteID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteConflicts(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	note := &models.Note{
+		UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "local edit",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}
+	require.NoError(t, repo.UpsertNote(ctx, note, true))
+
+	remoteModifiedAt := time.Date(2025, 10, 16, 9, 30, 0, 0, time.UTC)
+	require.NoError(t, repo.MarkNoteConflict(ctx, note.ID, "phone edit", remoteModifiedAt))
+
+	t.Run("Conflicted notes stop syncing", func(t *testing.T) {
+		current, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, models.SyncStatusConflict, current.SyncStatus)
+
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("Both versions are listed", func(t *testing.T) {
+		conflicts, err := repo.GetNoteConflicts(ctx, "test-user")
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, "local edit", conflicts[0].LocalContent)
+		assert.Equal(t, "phone edit", conflicts[0].RemoteContent)
+		assert.True(t, remoteModifiedAt.Equal(conflicts[0].RemoteModifiedAt))
+
+		other, err := repo.GetNoteConflicts(ctx, "other-user")
+		require.NoError(t, err)
+		assert.Empty(t, other)
+	})
+
+	t.Run("Resolving queues the chosen content", func(t *testing.T) {
+		resolved, err := repo.ResolveNoteConflict(ctx, &models.Note{
+			UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "phone edit", UpdatedAt: time.Now(),
+		})
+		require.NoError(t, err)
+		require.True(t, resolved)
+
+		current, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, "phone edit", current.Content)
+		assert.Equal(t, models.SyncStatusPending, current.SyncStatus)
+
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		require.NotNil(t, pending[0].SyncedAt)
+		assert.True(t, remoteModifiedAt.Equal(*pending[0].SyncedAt), "the storage version counts as seen")
+
+		revisions, err := repo.GetNoteRevisions(ctx, "test-user", "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, "local edit", revisions[0].Content)
+
+		conflict, err := repo.GetNoteConflict(ctx, "test-user", "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Nil(t, conflict)
+
+		resolved, err = repo.ResolveNoteConflict(ctx, &models.Note{
+			UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "again", UpdatedAt: time.Now(),
+		})
+		require.NoError(t, err)
+		assert.False(t, resolved)
+	})
+}
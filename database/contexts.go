@@ -1,20 +1,27 @@
 package database
 
 import (
+	"context"
 	"daily-notes/models"
 	"database/sql"
+	"errors"
 	"time"
 )
 
 // ==================== CONTEXT OPERATIONS ====================
 
-// GetContexts retrieves all contexts for a user
-func (r *Repository) GetContexts(userID string) ([]models.Context, error) {
-	rows, err := r.db.Query(`
-		SELECT id, user_id, name, color, created_at
+// GetContexts retrieves all contexts for a user, in the order they set
+func (r *Repository) GetContexts(ctx context.Context, scope UserScope) ([]models.Context, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, name, color, icon, template, local_only, language, sort_order, created_at
 		FROM contexts
 		WHERE user_id = ?
-		ORDER BY created_at ASC
+		ORDER BY sort_order ASC, created_at ASC
 	`, userID)
 	if err != nil {
 		return nil, err
@@ -24,90 +31,339 @@ func (r *Repository) GetContexts(userID string) ([]models.Context, error) {
 	// Initialize with empty slice to avoid returning nil
 	contexts := make([]models.Context, 0)
 	for rows.Next() {
-		var ctx models.Context
-		if err := rows.Scan(&ctx.ID, &ctx.UserID, &ctx.Name, &ctx.Color, &ctx.CreatedAt); err != nil {
+		var c models.Context
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.Color, &c.Icon, &c.Template, &c.LocalOnly, &c.Language, &c.SortOrder, &c.CreatedAt); err != nil {
 			return nil, err
 		}
-		contexts = append(contexts, ctx)
+		contexts = append(contexts, c)
 	}
 
 	return contexts, rows.Err()
 }
 
 // GetContextByName retrieves a context by name for a user
-func (r *Repository) GetContextByName(userID, name string) (*models.Context, error) {
-	var ctx models.Context
-	err := r.db.QueryRow(`
-		SELECT id, user_id, name, color, created_at
+func (r *Repository) GetContextByName(ctx context.Context, scope UserScope, name string) (*models.Context, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	var c models.Context
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, name, color, icon, template, local_only, language, sort_order, created_at
 		FROM contexts
 		WHERE user_id = ? AND name = ?
-	`, userID, name).Scan(&ctx.ID, &ctx.UserID, &ctx.Name, &ctx.Color, &ctx.CreatedAt)
+	`, userID, name).Scan(&c.ID, &c.UserID, &c.Name, &c.Color, &c.Icon, &c.Template, &c.LocalOnly, &c.Language, &c.SortOrder, &c.CreatedAt)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &ctx, nil
+	return &c, nil
 }
 
-// GetContextByID retrieves a context by its ID
-func (r *Repository) GetContextByID(contextID string) (*models.Context, error) {
-	var ctx models.Context
-	err := r.db.QueryRow(`
-		SELECT id, user_id, name, color, created_at
+// GetContextByID retrieves a context by its ID; contexts of users other than the
+// scope's are not found
+func (r *Repository) GetContextByID(ctx context.Context, scope UserScope, contextID string) (*models.Context, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+
+	var c models.Context
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, name, color, icon, template, local_only, language, sort_order, created_at
 		FROM contexts
-		WHERE id = ?
-	`, contextID).Scan(&ctx.ID, &ctx.UserID, &ctx.Name, &ctx.Color, &ctx.CreatedAt)
+		WHERE id = ? AND user_id = ?
+	`, contextID, scope.userID).Scan(&c.ID, &c.UserID, &c.Name, &c.Color, &c.Icon, &c.Template, &c.LocalOnly, &c.Language, &c.SortOrder, &c.CreatedAt)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &ctx, nil
+	return &c, nil
 }
 
-// CreateContext creates a new context
-func (r *Repository) CreateContext(ctx *models.Context) error {
-	_, err := r.db.Exec(`
-		INSERT INTO contexts (id, user_id, name, color, drive_folder_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+// CreateContext creates a new context, last in the user's order
+func (r *Repository) CreateContext(ctx context.Context, c *models.Context) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO contexts (id, user_id, name, color, icon, template, local_only, language, drive_folder_id, sort_order, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, `+nextSortOrder+`, ?, ?)
+		RETURNING sort_order
 	`,
-		ctx.ID, ctx.UserID, ctx.Name, ctx.Color, ctx.ID, ctx.CreatedAt, time.Now(),
-	)
-	return err
+		c.ID, c.UserID, c.Name, c.Color, c.Icon, c.Template, c.LocalOnly, c.Language, c.ID, c.UserID, c.CreatedAt, time.Now(),
+	).Scan(&c.SortOrder)
 }
 
-// UpdateContext updates a context's name and color
-func (r *Repository) UpdateContext(contextID string, name string, color string) error {
-	_, err := r.db.Exec(`
+// nextSortOrder puts a context after every other context of the user given as its parameter
+const nextSortOrder = `(SELECT COALESCE(MAX(sort_order) + 1, 0) FROM contexts WHERE user_id = ?)`
+
+// ReorderContexts puts the user's contexts in the order of ids; contexts left out
+// follow them in their current order. Returns false, changing nothing, if an ID
+// isn't one of the user's contexts.
+func (r *Repository) ReorderContexts(ctx context.Context, scope UserScope, ids []string) (bool, error) {
+	if err := scope.check(); err != nil {
+		return false, err
+	}
+	userID := scope.userID
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM contexts
+		WHERE user_id = ?
+		ORDER BY sort_order ASC, created_at ASC
+	`, userID)
+	if err != nil {
+		return false, err
+	}
+	var current []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return false, err
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	listed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		listed[id] = true
+	}
+	order := append([]string{}, ids...)
+	for _, id := range current {
+		if listed[id] {
+			delete(listed, id)
+		} else {
+			order = append(order, id)
+		}
+	}
+	if len(listed) > 0 {
+		return false, nil
+	}
+
+	for position, id := range order {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE contexts SET sort_order = ? WHERE user_id = ? AND id = ?
+		`, position, userID, id); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// UpdateContext updates a context's name, color and icon
+func (r *Repository) UpdateContext(ctx context.Context, scope UserScope, contextID, name, color, icon string) error {
+	if err := scope.check(); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, `
 		UPDATE contexts SET
 			name = ?,
 			color = ?,
+			icon = ?,
 			updated_at = ?
-		WHERE id = ?
-	`, name, color, time.Now(), contextID)
+		WHERE id = ? AND user_id = ?
+	`, name, color, icon, time.Now(), contextID, scope.userID)
 	return err
 }
 
-// UpdateNotesContextName updates the context field for all notes when a context is renamed
-func (r *Repository) UpdateNotesContextName(oldName string, newName string, userID string) error {
-	_, err := r.db.Exec(`
+// UpdateContextTemplate sets the template used to scaffold new notes in a context
+func (r *Repository) UpdateContextTemplate(ctx context.Context, scope UserScope, contextID, template string) error {
+	if err := scope.check(); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE contexts SET
+			template = ?,
+			updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, template, time.Now(), contextID, scope.userID)
+	return err
+}
+
+// UpdateContextLanguage sets the language of a context's notes
+func (r *Repository) UpdateContextLanguage(ctx context.Context, scope UserScope, contextID, language string) error {
+	if err := scope.check(); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE contexts SET
+			language = ?,
+			updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, language, time.Now(), contextID, scope.userID)
+	return err
+}
+
+// UpdateNotesContextName updates the context field of all notes and attachments when a context is renamed
+func (r *Repository) UpdateNotesContextName(ctx context.Context, scope UserScope, oldName, newName string) error {
+	if err := scope.check(); err != nil {
+		return err
+	}
+	userID := scope.userID
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
 		UPDATE notes SET
 			context = ?,
 			updated_at = ?
 		WHERE context = ? AND user_id = ?
-	`, newName, time.Now(), oldName, userID)
-	return err
+	`, newName, time.Now(), oldName, userID); err != nil {
+		return err
+	}
+	// Attachments are in the context's folder in storage, which is renamed with it
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE attachments SET context = ? WHERE context = ? AND user_id = ?
+	`, newName, oldName, userID); err != nil {
+		return err
+	}
+	// Integrations keep writing to the context they were given
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE api_tokens SET context = ? WHERE context = ? AND user_id = ?
+	`, newName, oldName, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // DeleteContext deletes a context by ID
-func (r *Repository) DeleteContext(contextID string) error {
-	_, err := r.db.Exec("DELETE FROM contexts WHERE id = ?", contextID)
-	return err
+// The context is kept in context_trash (stamped with deletedAt) so it can be restored later
+func (r *Repository) DeleteContext(ctx context.Context, scope UserScope, contextID string, deletedAt time.Time) error {
+	if err := scope.check(); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO context_trash (id, user_id, name, color, icon, template, local_only, language, created_at, deleted_at)
+		SELECT id, user_id, name, color, icon, template, local_only, language, created_at, ?
+		FROM contexts
+		WHERE id = ? AND user_id = ?
+		ON CONFLICT(id) DO UPDATE SET
+			user_id = excluded.user_id, name = excluded.name, color = excluded.color, icon = excluded.icon,
+			template = excluded.template, local_only = excluded.local_only, language = excluded.language,
+			created_at = excluded.created_at, deleted_at = excluded.deleted_at
+	`, deletedAt, contextID, scope.userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM contexts WHERE id = ? AND user_id = ?", contextID, scope.userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ==================== CONTEXT TRASH OPERATIONS ====================
+
+// GetTrashedContexts retrieves contexts deleted after the given time
+func (r *Repository) GetTrashedContexts(ctx context.Context, scope UserScope, since time.Time) ([]models.TrashedContext, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, name, color, icon, template, local_only, language, created_at, deleted_at
+		FROM context_trash
+		WHERE user_id = ? AND deleted_at > ?
+		ORDER BY deleted_at DESC
+	`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trashed := make([]models.TrashedContext, 0)
+	for rows.Next() {
+		var c models.TrashedContext
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.Color, &c.Icon, &c.Template, &c.LocalOnly, &c.Language, &c.CreatedAt, &c.DeletedAt); err != nil {
+			return nil, err
+		}
+		trashed = append(trashed, c)
+	}
+
+	return trashed, rows.Err()
+}
+
+// GetTrashedContext retrieves a single deleted context for a user
+func (r *Repository) GetTrashedContext(ctx context.Context, scope UserScope, contextID string) (*models.TrashedContext, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	var c models.TrashedContext
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, name, color, icon, template, local_only, language, created_at, deleted_at
+		FROM context_trash
+		WHERE user_id = ? AND id = ?
+	`, userID, contextID).Scan(&c.ID, &c.UserID, &c.Name, &c.Color, &c.Icon, &c.Template, &c.LocalOnly, &c.Language, &c.CreatedAt, &c.DeletedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// RestoreContext moves a deleted context from context_trash back into contexts,
+// last in the user's order
+func (r *Repository) RestoreContext(ctx context.Context, scope UserScope, contextID string) error {
+	if err := scope.check(); err != nil {
+		return err
+	}
+	userID := scope.userID
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO contexts (id, user_id, name, color, icon, template, local_only, language, drive_folder_id, sort_order, created_at, updated_at)
+		SELECT id, user_id, name, color, icon, template, local_only, language, id, `+nextSortOrder+`, created_at, ?
+		FROM context_trash
+		WHERE user_id = ? AND id = ?
+	`, userID, time.Now(), userID, contextID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM context_trash WHERE user_id = ? AND id = ?", userID, contextID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
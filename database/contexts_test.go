@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReorderContexts(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	created := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	for i, name := range []string{"Work", "Home", "Gym"} {
+		c := &models.Context{ID: "ctx-" + name, UserID: "test-user", Name: name, Color: "primary", CreatedAt: created.Add(time.Duration(i) * time.Hour)}
+		require.NoError(t, repo.CreateContext(ctx, c))
+		assert.Equal(t, i, c.SortOrder, "new contexts go last")
+	}
+	require.NoError(t, repo.UpsertUser(ctx, &models.User{ID: "other-user", GoogleID: "google-other", Email: "other@example.com", CreatedAt: created}))
+	require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-other", UserID: "other-user", Name: "Other", CreatedAt: created}))
+	names := func() []string {
+		contexts, err := repo.GetContexts(ctx, ScopeUser("test-user"))
+		require.NoError(t, err)
+		names := []string{}
+		for _, c := range contexts {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+
+	t.Run("Listed contexts come first, the rest keep their order", func(t *testing.T) {
+		reordered, err := repo.ReorderContexts(ctx, ScopeUser("test-user"), []string{"ctx-Gym"})
+		require.NoError(t, err)
+		assert.True(t, reordered)
+		assert.Equal(t, []string{"Gym", "Work", "Home"}, names())
+
+		reordered, err = repo.ReorderContexts(ctx, ScopeUser("test-user"), []string{"ctx-Home", "ctx-Work", "ctx-Gym"})
+		require.NoError(t, err)
+		assert.True(t, reordered)
+		assert.Equal(t, []string{"Home", "Work", "Gym"}, names())
+	})
+
+	t.Run("Other users' contexts change nothing", func(t *testing.T) {
+		reordered, err := repo.ReorderContexts(ctx, ScopeUser("test-user"), []string{"ctx-Gym", "ctx-other"})
+		require.NoError(t, err)
+		assert.False(t, reordered)
+		assert.Equal(t, []string{"Home", "Work", "Gym"}, names())
+	})
+
+	t.Run("Restored contexts go last", func(t *testing.T) {
+		require.NoError(t, repo.DeleteContext(ctx, ScopeUser("test-user"), "ctx-Home", time.Now()))
+		require.NoError(t, repo.RestoreContext(ctx, ScopeUser("test-user"), "ctx-Home"))
+		assert.Equal(t, []string{"Work", "Gym", "Home"}, names())
+	})
+}
+
+func TestContextIcons(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	created := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-work", UserID: "test-user", Name: "Work", Color: "primary", Icon: "briefcase", CreatedAt: created}))
+	icon := func() string {
+		c, err := repo.GetContextByID(ctx, ScopeUser("test-user"), "ctx-work")
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		return c.Icon
+	}
+	assert.Equal(t, "briefcase", icon())
+
+	require.NoError(t, repo.UpdateContext(ctx, ScopeUser("test-user"), "ctx-work", "Work", "primary", "💼"))
+	assert.Equal(t, "💼", icon())
+
+	require.NoError(t, repo.DeleteContext(ctx, ScopeUser("test-user"), "ctx-work", created.Add(time.Hour)))
+	trashed, err := repo.GetTrashedContexts(ctx, ScopeUser("test-user"), created)
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, "💼", trashed[0].Icon)
+
+	require.NoError(t, repo.RestoreContext(ctx, ScopeUser("test-user"), "ctx-work"))
+	assert.Equal(t, "💼", icon(), "restored contexts keep their icon")
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the SQL flavor of the database server. Queries in this package are
+// written for SQLite with ? placeholders and portable SQL (ON CONFLICT, COALESCE);
+// DB and Tx rewrite them for the dialect they were opened with, and Migrate
+// translates the schema. SQLite-only features (full-text search, VACUUM INTO
+// backups) are left out on other dialects.
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// postgresDriver is the database/sql driver for PostgreSQL, registered by builds
+// with the postgres tag; empty when this binary has none
+var postgresDriver = ""
+
+// ParseDialect returns the dialect named by DB_DRIVER; empty means SQLite
+func ParseDialect(name string) (Dialect, error) {
+	switch strings.ToLower(name) {
+	case "", "sqlite", "sqlite3":
+		return SQLite, nil
+	case "postgres", "postgresql", "pgx":
+		return Postgres, nil
+	}
+	return "", fmt.Errorf("unknown database driver %q (use sqlite or postgres)", name)
+}
+
+// driverName returns the database/sql driver opening the dialect
+func (d Dialect) driverName() (string, error) {
+	if d == Postgres {
+		if postgresDriver == "" {
+			return "", fmt.Errorf("this binary was built without PostgreSQL support; build it with -tags postgres")
+		}
+		return postgresDriver, nil
+	}
+	return "sqlite3", nil
+}
+
+// Rebind rewrites the ? placeholders of a query into the dialect's ($1, $2... for
+// PostgreSQL). Question marks inside quoted strings and identifiers are kept.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// args converts query arguments the dialect's driver can't store: PostgreSQL
+// won't write Go booleans into the INTEGER flag columns SQLite uses, so they
+// become 0 and 1
+func (d Dialect) args(args []interface{}) []interface{} {
+	if d != Postgres {
+		return args
+	}
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		converted[i] = arg
+		if b, ok := arg.(bool); ok {
+			converted[i] = 0
+			if b {
+				converted[i] = 1
+			}
+		}
+	}
+	return converted
+}
+
+// translate rewrites a schema statement written for SQLite into the dialect
+func (d Dialect) translate(ddl string) string {
+	if d != Postgres {
+		return ddl
+	}
+	return strings.NewReplacer(
+		" INTEGER PRIMARY KEY AUTOINCREMENT", " BIGSERIAL PRIMARY KEY",
+		" INTEGER", " BIGINT",
+		" DATETIME", " TIMESTAMPTZ",
+		" ADD COLUMN ", " ADD COLUMN IF NOT EXISTS ",
+	).Replace(ddl)
+}
+
+// tableExistsQuery counts the tables named by its single argument
+func (d Dialect) tableExistsQuery() string {
+	if d == Postgres {
+		return `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+	}
+	return `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+}
+
+// columnExistsQuery counts the columns named by its second argument in the table
+// named by its first
+func (d Dialect) columnExistsQuery() string {
+	if d == Postgres {
+		return `SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`
+	}
+	return `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+}
+
+// likeOperator matches text case-insensitively, as SQLite's LIKE does
+func (d Dialect) likeOperator() string {
+	if d == Postgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// dayNumber numbers the day of a YYYY-MM-DD column, so consecutive days have
+// consecutive numbers
+func (d Dialect) dayNumber(column string) string {
+	if d == Postgres {
+		return "(CAST(" + column + " AS DATE) - DATE '1970-01-01')"
+	}
+	return "CAST(julianday(" + column + ") AS INTEGER)"
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDialect(t *testing.T) {
+	for name, want := range map[string]Dialect{"": SQLite, "sqlite3": SQLite, "Postgres": Postgres, "postgresql": Postgres} {
+		got, err := ParseDialect(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, name)
+	}
+
+	_, err := ParseDialect("mysql")
+	assert.Error(t, err)
+}
+
+func TestRebind(t *testing.T) {
+	query := `SELECT id FROM notes WHERE user_id = ? AND content LIKE '%?%' AND "odd?" = ? LIMIT ?`
+	assert.Equal(t, query, SQLite.Rebind(query))
+	assert.Equal(t, `SELECT id FROM notes WHERE user_id = $1 AND content LIKE '%?%' AND "odd?" = $2 LIMIT $3`, Postgres.Rebind(query))
+}
+
+func TestDialectArgs(t *testing.T) {
+	args := []interface{}{"user", true, false, 3}
+	assert.Equal(t, []interface{}{"user", 1, 0, 3}, Postgres.args(args))
+	assert.Equal(t, true, args[1], "the caller's arguments are left alone")
+	assert.Equal(t, args, SQLite.args(args))
+}
+
+func TestTranslate(t *testing.T) {
+	ddl := `CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tag_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`
+	assert.Equal(t, ddl, SQLite.translate(ddl))
+	assert.Equal(t, `CREATE TABLE IF NOT EXISTS tags (
+		id BIGSERIAL PRIMARY KEY,
+		tag_id BIGINT NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	)`, Postgres.translate(ddl))
+
+	assert.Equal(t, `ALTER TABLE notes ADD COLUMN IF NOT EXISTS next_retry_at TIMESTAMPTZ`,
+		Postgres.translate(`ALTER TABLE notes ADD COLUMN next_retry_at DATETIME`))
+}
+
+func TestOpenPostgresWithoutDriver(t *testing.T) {
+	if postgresDriver != "" {
+		t.Skip("built with PostgreSQL support")
+	}
+	_, err := Open(Postgres, "postgres://localhost/daily_notes")
+	assert.ErrorContains(t, err, "-tags postgres")
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/visibility"
+	"database/sql"
+	"errors"
+)
+
+// ==================== WEEKLY DIGESTS ====================
+
+// digestSubscriptionQuery selects the columns scanned by scanDigestSubscription,
+// with the address, name and timezone of the subscription's user
+const digestSubscriptionQuery = `
+	SELECT s.user_id, u.email, COALESCE(u.name, ''), COALESCE(u.settings_timezone, 'UTC'), s.token, COALESCE(s.last_week, '')
+	FROM digest_subscriptions s
+	JOIN users u ON u.id = s.user_id`
+
+// GetDigestSubscription returns the digest subscription of a user, nil if they have none
+func (r *Repository) GetDigestSubscription(ctx context.Context, userID string) (*models.DigestSubscription, error) {
+	subscription, err := scanDigestSubscription(r.db.QueryRowContext(ctx, digestSubscriptionQuery+` WHERE s.user_id = ?`, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return subscription, err
+}
+
+// GetDigestSubscriptions returns the digest subscriptions of all users
+func (r *Repository) GetDigestSubscriptions(ctx context.Context) ([]models.DigestSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, digestSubscriptionQuery+` ORDER BY s.user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.DigestSubscription
+	for rows.Next() {
+		subscription, err := scanDigestSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+// SubscribeDigest subscribes a user to the weekly digest with the secret of its
+// unsubscribe link, as if the digest of lastWeek was sent. An existing
+// subscription is kept as it is, so subscribing twice sends no extra digest.
+func (r *Repository) SubscribeDigest(ctx context.Context, userID, token, lastWeek string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO digest_subscriptions (user_id, token, last_week) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO NOTHING
+	`, userID, token, lastWeek)
+	return err
+}
+
+// UnsubscribeDigest ends a user's digest subscription; it reports false if there was none
+func (r *Repository) UnsubscribeDigest(ctx context.Context, userID string) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `DELETE FROM digest_subscriptions WHERE user_id = ?`, userID))
+}
+
+// UnsubscribeDigestByToken ends the subscription whose unsubscribe link has
+// token; it reports false if there was none
+func (r *Repository) UnsubscribeDigestByToken(ctx context.Context, token string) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `DELETE FROM digest_subscriptions WHERE token = ?`, token))
+}
+
+// SetDigestWeek records the ISO week of the latest digest queued for a user
+func (r *Repository) SetDigestWeek(ctx context.Context, userID, week string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE digest_subscriptions SET last_week = ? WHERE user_id = ?`, week, userID)
+	return err
+}
+
+// GetDigestNotes retrieves the user's daily notes from from to to (inclusive)
+// that may be emailed, like GetAgendaNotes. Local-only notes are left out, as
+// emails leave the server.
+func (r *Repository) GetDigestNotes(ctx context.Context, userID, from, to string) ([]models.AgendaNote, error) {
+	return r.agendaNotes(ctx, userID, from, to, visibility.Digest)
+}
+
+// scanDigestSubscription reads a row selected by digestSubscriptionQuery
+func scanDigestSubscription(row interface{ Scan(...any) error }) (*models.DigestSubscription, error) {
+	var s models.DigestSubscription
+	if err := row.Scan(&s.UserID, &s.Email, &s.Name, &s.Timezone, &s.Token, &s.LastWeek); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
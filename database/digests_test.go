@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestSubscriptions(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	subscription, err := repo.GetDigestSubscription(ctx, "test-user")
+	require.NoError(t, err)
+	assert.Nil(t, subscription)
+
+	require.NoError(t, repo.UpdateUserSettings(ctx, "test-user", models.UserSettings{Theme: "dark", Timezone: "Europe/Madrid", DateFormat: "DD-MM-YY"}))
+	require.NoError(t, repo.SubscribeDigest(ctx, "test-user", "token-1", "2025-W41"))
+
+	t.Run("Subscriptions carry their user's address and timezone", func(t *testing.T) {
+		subscriptions, err := repo.GetDigestSubscriptions(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []models.DigestSubscription{{
+			UserID: "test-user", Email: "test@example.com", Name: "Test User",
+			Timezone: "Europe/Madrid", Token: "token-1", LastWeek: "2025-W41",
+		}}, subscriptions)
+	})
+
+	t.Run("Subscribing again keeps the subscription", func(t *testing.T) {
+		require.NoError(t, repo.SetDigestWeek(ctx, "test-user", "2025-W42"))
+		require.NoError(t, repo.SubscribeDigest(ctx, "test-user", "token-2", "2025-W41"))
+		subscription, err := repo.GetDigestSubscription(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, "token-1", subscription.Token)
+		assert.Equal(t, "2025-W42", subscription.LastWeek)
+	})
+
+	t.Run("Unsubscribe links need the token", func(t *testing.T) {
+		removed, err := repo.UnsubscribeDigestByToken(ctx, "token-2")
+		require.NoError(t, err)
+		assert.False(t, removed)
+		removed, err = repo.UnsubscribeDigestByToken(ctx, "token-1")
+		require.NoError(t, err)
+		assert.True(t, removed)
+	})
+
+	t.Run("Unsubscribing reports whether there was a subscription", func(t *testing.T) {
+		require.NoError(t, repo.SubscribeDigest(ctx, "test-user", "token-3", "2025-W42"))
+		removed, err := repo.UnsubscribeDigest(ctx, "test-user")
+		require.NoError(t, err)
+		assert.True(t, removed)
+		removed, err = repo.UnsubscribeDigest(ctx, "test-user")
+		require.NoError(t, err)
+		assert.False(t, removed)
+	})
+}
@@ -0,0 +1,9 @@
+//go:build postgres
+
+package database
+
+import _ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" database/sql driver
+
+func init() {
+	postgresDriver = "pgx"
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// ==================== DROP BOX ====================
+
+// UseDropNonce records that the drop box URL with nonce, valid until expiresAt,
+// was used. It reports false if it was used before. Nonces of URLs expired by
+// now are pruned first, as those URLs are refused anyway.
+func (r *Repository) UseDropNonce(ctx context.Context, userID, nonce string, expiresAt, now time.Time) (bool, error) {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM drop_nonces WHERE expires_at < ?`, now); err != nil {
+		return false, err
+	}
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO drop_nonces (nonce, user_id, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(nonce) DO NOTHING
+	`, nonce, userID, expiresAt)
+	return affected(result, err)
+}
+
+// ReleaseDropNonce forgets the use of a nonce, so its URL can be used again
+func (r *Repository) ReleaseDropNonce(ctx context.Context, nonce string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM drop_nonces WHERE nonce = ?`, nonce)
+	return err
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDropNonces(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	fresh, err := repo.UseDropNonce(ctx, "test-user", "nonce-1", now.Add(time.Hour), now)
+	require.NoError(t, err)
+	assert.True(t, fresh)
+
+	fresh, err = repo.UseDropNonce(ctx, "test-user", "nonce-1", now.Add(time.Hour), now)
+	require.NoError(t, err)
+	assert.False(t, fresh, "a nonce is used once")
+
+	t.Run("Released nonces can be used again", func(t *testing.T) {
+		require.NoError(t, repo.ReleaseDropNonce(ctx, "nonce-1"))
+		fresh, err := repo.UseDropNonce(ctx, "test-user", "nonce-1", now.Add(time.Hour), now)
+		require.NoError(t, err)
+		assert.True(t, fresh)
+	})
+
+	t.Run("Nonces of expired URLs are pruned", func(t *testing.T) {
+		_, err := repo.UseDropNonce(ctx, "test-user", "nonce-2", now.Add(3*time.Hour), now.Add(2*time.Hour))
+		require.NoError(t, err)
+
+		var count int
+		require.NoError(t, repo.db.QueryRow(`SELECT COUNT(*) FROM drop_nonces`).Scan(&count))
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/pkg/visibility"
+	"database/sql"
+	"errors"
+)
+
+// ==================== END-TO-END ENCRYPTION ====================
+
+// encryptedOf holds for notes, of the notes table referred to as table, whose
+// content is an end-to-end encrypted envelope
+func encryptedOf(table string) string {
+	return `(` + table + `.content LIKE '` + e2ee.Begin + `%')`
+}
+
+// GetEncryptionKey returns how a user's clients derive their key, nil if the
+// user's notes aren't end-to-end encrypted
+func (r *Repository) GetEncryptionKey(ctx context.Context, userID string) (*models.EncryptionKey, error) {
+	var key models.EncryptionKey
+	err := r.db.QueryRowContext(ctx, `
+		SELECT user_id, cipher, kdf, iterations, salt, verifier_hash, created_at
+		FROM encryption_keys WHERE user_id = ?
+	`, userID).Scan(&key.UserID, &key.Cipher, &key.KDF, &key.Iterations, &key.Salt, &key.VerifierHash, &key.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// CreateEncryptionKey turns on end-to-end encryption for a user; it fails if
+// the user already has a key
+func (r *Repository) CreateEncryptionKey(ctx context.Context, key *models.EncryptionKey) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO encryption_keys (user_id, cipher, kdf, iterations, salt, verifier_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, key.UserID, key.Cipher, key.KDF, key.Iterations, key.Salt, key.VerifierHash, key.CreatedAt)
+	return err
+}
+
+// DeleteEncryptionKey turns off end-to-end encryption for a user; it reports
+// false if it wasn't on
+func (r *Repository) DeleteEncryptionKey(ctx context.Context, userID string) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `DELETE FROM encryption_keys WHERE user_id = ?`, userID))
+}
+
+// CountEncryptedNotes returns how many of the notes a user sees are encrypted,
+// and how many non-empty ones aren't
+func (r *Repository) CountEncryptedNotes(ctx context.Context, userID string) (encrypted, plaintext int, err error) {
+	err = r.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(CASE WHEN `+encryptedOf("notes")+` THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN NOT `+encryptedOf("notes")+` AND TRIM(content) != '' THEN 1 ELSE 0 END), 0)
+		FROM notes
+		WHERE user_id = ? AND `+visibleCondition("notes", visibility.App),
+		userID).Scan(&encrypted, &plaintext)
+	return encrypted, plaintext, err
+}
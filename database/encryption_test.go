@@ -0,0 +1,89 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/pkg/visibility"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryption(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	key, err := repo.GetEncryptionKey(ctx, "test-user")
+	require.NoError(t, err)
+	assert.Nil(t, key)
+
+	require.NoError(t, repo.CreateEncryptionKey(ctx, &models.EncryptionKey{
+		UserID: "test-user", Cipher: e2ee.CipherAESGCM, KDF: e2ee.KDFPBKDF2, Iterations: e2ee.MinIterations,
+		Salt: "c2FsdHNhbHRzYWx0c2FsdA==", VerifierHash: "hash", CreatedAt: time.Now(),
+	}))
+	assert.Error(t, repo.CreateEncryptionKey(ctx, &models.EncryptionKey{UserID: "test-user", CreatedAt: time.Now()}), "one key per user")
+
+	key, err = repo.GetEncryptionKey(ctx, "test-user")
+	require.NoError(t, err)
+	require.NotNil(t, key)
+	assert.Equal(t, e2ee.MinIterations, key.Iterations)
+
+	envelope := (&e2ee.Envelope{
+		Version: e2ee.Version, Cipher: e2ee.CipherAESGCM, KDF: e2ee.KDFPBKDF2, Iterations: e2ee.MinIterations,
+		Salt: bytes.Repeat([]byte{1}, 16), Nonce: bytes.Repeat([]byte{2}, 12), Ciphertext: bytes.Repeat([]byte{3}, 48),
+	}).String()
+	for date, content := range map[string]string{"2025-10-15": "Plain #diary words", "2025-10-16": envelope, "2025-10-17": ""} {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: "Journal", Date: date, Content: content,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}, true))
+	}
+
+	t.Run("Encrypted notes are marked and count no words", func(t *testing.T) {
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Journal", "2025-10-16")
+		require.NoError(t, err)
+		assert.True(t, note.Encrypted)
+		assert.Empty(t, note.Tags)
+		assert.Zero(t, note.WordCount)
+		assert.Zero(t, note.CharCount)
+
+		note, err = repo.GetNote(ctx, ScopeUser("test-user"), "Journal", "2025-10-15")
+		require.NoError(t, err)
+		assert.False(t, note.Encrypted)
+	})
+
+	t.Run("Counts", func(t *testing.T) {
+		encrypted, plaintext, err := repo.CountEncryptedNotes(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, 1, encrypted)
+		assert.Equal(t, 1, plaintext, "empty notes need no encryption")
+	})
+
+	t.Run("Only policies that keep them encrypted see encrypted notes", func(t *testing.T) {
+		for name, policy := range map[string]visibility.Policy{"app": visibility.App, "storage": visibility.Storage, "export": visibility.Export} {
+			notes, err := repo.GetVisibleNotes(ctx, ScopeUser("test-user"), policy)
+			require.NoError(t, err)
+			assert.Len(t, notes, 3, name)
+		}
+		notes, err := repo.GetVisibleNotes(ctx, ScopeUser("test-user"), visibility.Digest)
+		require.NoError(t, err)
+		assert.Len(t, notes, 2)
+		for _, note := range notes {
+			assert.False(t, note.Encrypted)
+		}
+	})
+
+	t.Run("Turning encryption off", func(t *testing.T) {
+		removed, err := repo.DeleteEncryptionKey(ctx, "test-user")
+		require.NoError(t, err)
+		assert.True(t, removed)
+		removed, err = repo.DeleteEncryptionKey(ctx, "test-user")
+		require.NoError(t, err)
+		assert.False(t, removed)
+	})
+}
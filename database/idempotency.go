@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ==================== IDEMPOTENCY KEYS ====================
+
+// GetIdempotentResponse retrieves the response stored for a user's request with
+// an idempotency key, if stored after since
+func (r *Repository) GetIdempotentResponse(ctx context.Context, userID, method, path, key string, since time.Time) (*models.IdempotentResponse, error) {
+	response := models.IdempotentResponse{UserID: userID, Method: method, Path: path, Key: key}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT request_hash, status, content_type, body, created_at
+		FROM idempotency_keys
+		WHERE user_id = ? AND method = ? AND path = ? AND idempotency_key = ? AND created_at > ?
+	`, userID, method, path, key, since).Scan(
+		&response.RequestHash, &response.Status, &response.ContentType, &response.Body, &response.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// SaveIdempotentResponse stores a response for replaying, dropping the responses
+// stored before expired
+func (r *Repository) SaveIdempotentResponse(ctx context.Context, response *models.IdempotentResponse, expired time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE created_at <= ?
+	`, expired); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (user_id, method, path, idempotency_key, request_hash, status, content_type, body, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, method, path, idempotency_key) DO UPDATE SET
+			request_hash = excluded.request_hash,
+			status = excluded.status,
+			content_type = excluded.content_type,
+			body = excluded.body,
+			created_at = excluded.created_at
+	`, response.UserID, response.Method, response.Path, response.Key, response.RequestHash,
+		response.Status, response.ContentType, string(response.Body), response.CreatedAt)
+	return err
+}
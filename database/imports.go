@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// ==================== IMPORT CHECKPOINTS ====================
+
+// ImportCheckpoint is how far the import of one context has got
+// PageToken is the page being imported and LastFile the last file of it that
+// was saved; a resumed import re-reads that page and skips up to LastFile.
+type ImportCheckpoint struct {
+	UserID    string
+	Context   string
+	PageToken string
+	LastFile  string
+	Imported  int // Notes saved so far
+	Done      bool
+}
+
+// StartImport records the contexts of a new import, keeping the progress of any already recorded
+func (r *Repository) StartImport(ctx context.Context, userID string, contexts []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, name := range contexts {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO import_checkpoints (user_id, context) VALUES (?, ?)
+			ON CONFLICT(user_id, context) DO NOTHING
+		`, userID, name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetImportCheckpoints returns the checkpoints of a user's import by context name
+func (r *Repository) GetImportCheckpoints(ctx context.Context, userID string) (map[string]ImportCheckpoint, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id, context, page_token, last_file, imported, done
+		FROM import_checkpoints
+		WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpoints := make(map[string]ImportCheckpoint)
+	for rows.Next() {
+		var cp ImportCheckpoint
+		if err := rows.Scan(&cp.UserID, &cp.Context, &cp.PageToken, &cp.LastFile, &cp.Imported, &cp.Done); err != nil {
+			return nil, err
+		}
+		checkpoints[cp.Context] = cp
+	}
+	return checkpoints, rows.Err()
+}
+
+// SaveImportCheckpoint records the progress of one context
+func (r *Repository) SaveImportCheckpoint(ctx context.Context, cp ImportCheckpoint) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO import_checkpoints (user_id, context, page_token, last_file, imported, done, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, context) DO UPDATE SET
+			page_token = excluded.page_token,
+			last_file = excluded.last_file,
+			imported = excluded.imported,
+			done = excluded.done,
+			updated_at = excluded.updated_at
+	`, cp.UserID, cp.Context, cp.PageToken, cp.LastFile, cp.Imported, cp.Done, time.Now())
+	return err
+}
+
+// FinishImport drops a user's checkpoints once every context is imported
+func (r *Repository) FinishImport(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM import_checkpoints WHERE user_id = ?`, userID)
+	return err
+}
+
+// GetUnfinishedImports returns the users with an import that was interrupted
+func (r *Repository) GetUnfinishedImports(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT user_id FROM import_checkpoints WHERE done = 0 ORDER BY user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ==================== JOBS ====================
+
+// jobColumns are the columns scanned by scanJob
+const jobColumns = `id, user_id, kind, payload, status, attempts, COALESCE(error, ''),
+	run_at, created_at, started_at, finished_at`
+
+// EnqueueJob saves a job, due at job.RunAt, and writes its new ID back to job.ID
+func (r *Repository) EnqueueJob(ctx context.Context, job *models.Job) error {
+	job.Status = models.JobQueued
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO jobs (user_id, kind, payload, status, run_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, job.UserID, job.Kind, job.Payload, job.Status, job.RunAt, job.CreatedAt).Scan(&job.ID)
+}
+
+// ClaimJob marks the job that has been due the longest as running and returns
+// it, nil if no job is due. Each job is claimed by one caller only.
+func (r *Repository) ClaimJob(ctx context.Context, now time.Time) (*models.Job, error) {
+	job, err := scanJob(r.db.QueryRowContext(ctx, `
+		UPDATE jobs
+		SET status = ?, attempts = attempts + 1, started_at = ?, finished_at = NULL
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = ? AND run_at <= ?
+			ORDER BY run_at ASC, id ASC
+			LIMIT 1
+		) AND status = ?
+		RETURNING `+jobColumns,
+		models.JobRunning, now, models.JobQueued, now, models.JobQueued))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// CompleteJob marks a running job done
+func (r *Repository) CompleteJob(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs SET status = ?, error = NULL, finished_at = ? WHERE id = ?
+	`, models.JobDone, at, id)
+	return err
+}
+
+// FailJob records a failed attempt at a running job: it is queued again for
+// retryAt, or marked failed for good when retryAt is nil
+func (r *Repository) FailJob(ctx context.Context, id int64, errorMsg string, retryAt *time.Time, at time.Time) error {
+	if retryAt != nil {
+		_, err := r.db.ExecContext(ctx, `
+			UPDATE jobs SET status = ?, error = ?, run_at = ? WHERE id = ?
+		`, models.JobQueued, errorMsg, *retryAt, id)
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE jobs SET status = ?, error = ?, finished_at = ? WHERE id = ?
+	`, models.JobFailed, errorMsg, at, id)
+	return err
+}
+
+// RequeueRunningJobs queues the jobs left running, due right away. Call it before
+// claiming jobs after a start: running jobs were interrupted by a stop or crash.
+func (r *Repository) RequeueRunningJobs(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE jobs SET status = ?, started_at = NULL WHERE status = ?
+	`, models.JobQueued, models.JobRunning)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetJobs returns the latest jobs of every user, newest first, only those with
+// status unless it is empty
+func (r *Repository) GetJobs(ctx context.Context, status string, limit int) ([]models.Job, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE ? = '' OR status = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, status, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// DeleteFinishedJobs removes the jobs that finished before a time
+func (r *Repository) DeleteFinishedJobs(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM jobs WHERE status IN (?, ?) AND finished_at < ?
+	`, models.JobDone, models.JobFailed, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanJob reads a row selecting jobColumns
+func scanJob(row interface{ Scan(...any) error }) (*models.Job, error) {
+	var job models.Job
+	var startedAt, finishedAt sql.NullTime
+	if err := row.Scan(&job.ID, &job.UserID, &job.Kind, &job.Payload, &job.Status, &job.Attempts,
+		&job.Error, &job.RunAt, &job.CreatedAt, &startedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobs(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	first := &models.Job{UserID: "test-user", Kind: "context.rename", Payload: `{"context_id":"ctx-work"}`, RunAt: now, CreatedAt: now}
+	later := &models.Job{UserID: "test-user", Kind: "storage.cleanup", Payload: `{}`, RunAt: now.Add(time.Hour), CreatedAt: now}
+	require.NoError(t, repo.EnqueueJob(ctx, first))
+	require.NoError(t, repo.EnqueueJob(ctx, later))
+	assert.NotZero(t, first.ID)
+
+	t.Run("Due jobs are claimed once", func(t *testing.T) {
+		job, err := repo.ClaimJob(ctx, now)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, first.ID, job.ID)
+		assert.Equal(t, models.JobRunning, job.Status)
+		assert.Equal(t, 1, job.Attempts)
+		assert.Equal(t, `{"context_id":"ctx-work"}`, job.Payload)
+		require.NotNil(t, job.StartedAt)
+
+		job, err = repo.ClaimJob(ctx, now)
+		require.NoError(t, err)
+		assert.Nil(t, job, "the other job isn't due yet")
+	})
+
+	t.Run("Failed attempts are queued again until they fail for good", func(t *testing.T) {
+		retryAt := now.Add(time.Minute)
+		require.NoError(t, repo.FailJob(ctx, first.ID, "connection reset", &retryAt, now))
+
+		job, err := repo.ClaimJob(ctx, retryAt)
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, 2, job.Attempts)
+		assert.Equal(t, "connection reset", job.Error)
+
+		require.NoError(t, repo.FailJob(ctx, first.ID, "still failing", nil, retryAt))
+		jobs, err := repo.GetJobs(ctx, models.JobFailed, 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "still failing", jobs[0].Error)
+		require.NotNil(t, jobs[0].FinishedAt)
+	})
+
+	t.Run("Interrupted jobs are queued again", func(t *testing.T) {
+		job, err := repo.ClaimJob(ctx, now.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, later.ID, job.ID)
+
+		requeued, err := repo.RequeueRunningJobs(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), requeued)
+
+		job, err = repo.ClaimJob(ctx, now.Add(2*time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, 2, job.Attempts)
+		require.NoError(t, repo.CompleteJob(ctx, job.ID, now.Add(2*time.Hour)))
+	})
+
+	t.Run("Jobs are listed newest first and finished ones pruned", func(t *testing.T) {
+		jobs, err := repo.GetJobs(ctx, "", 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 2)
+		assert.Equal(t, later.ID, jobs[0].ID)
+		assert.Equal(t, models.JobDone, jobs[0].Status)
+
+		deleted, err := repo.DeleteFinishedJobs(ctx, now.Add(90*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted, "only the job that failed before then")
+
+		jobs, err = repo.GetJobs(ctx, "", 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+	})
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"strings"
+	"time"
+)
+
+// ==================== LINK PREVIEWS ====================
+
+// GetLinkPreviews returns the fetched previews of urls, in the order given
+// URLs without a preview, or whose fetch failed, are left out.
+func (r *Repository) GetLinkPreviews(ctx context.Context, urls []string) ([]models.LinkPreview, error) {
+	previews := []models.LinkPreview{}
+	if len(urls) == 0 {
+		return previews, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT url, title, description, image_url, site_name, fetched_at
+		FROM link_previews
+		WHERE url IN (`+placeholders(len(urls))+`) AND error = ''
+	`, stringArgs(urls)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]models.LinkPreview, len(urls))
+	for rows.Next() {
+		var p models.LinkPreview
+		if err := rows.Scan(&p.URL, &p.Title, &p.Description, &p.ImageURL, &p.SiteName, &p.FetchedAt); err != nil {
+			return nil, err
+		}
+		found[p.URL] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, u := range urls {
+		if p, ok := found[u]; ok {
+			previews = append(previews, p)
+		}
+	}
+	return previews, nil
+}
+
+// GetStaleLinkURLs returns which of urls need fetching: those never fetched, previews
+// fetched before fetchedBefore and failures recorded before failedBefore
+func (r *Repository) GetStaleLinkURLs(ctx context.Context, urls []string, fetchedBefore, failedBefore time.Time) ([]string, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT url FROM link_previews
+		WHERE url IN (`+placeholders(len(urls))+`)
+		AND fetched_at >= CASE WHEN error = '' THEN ? ELSE ? END
+	`, append(stringArgs(urls), fetchedBefore, failedBefore)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fresh := make(map[string]bool, len(urls))
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		fresh[u] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for _, u := range urls {
+		if !fresh[u] {
+			stale = append(stale, u)
+		}
+	}
+	return stale, nil
+}
+
+// SaveLinkPreview stores the preview of a URL, or the reason its fetch failed,
+// replacing what was stored before
+func (r *Repository) SaveLinkPreview(ctx context.Context, preview models.LinkPreview, fetchErr string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO link_previews (url, title, description, image_url, site_name, error, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET
+			title = excluded.title,
+			description = excluded.description,
+			image_url = excluded.image_url,
+			site_name = excluded.site_name,
+			error = excluded.error,
+			fetched_at = excluded.fetched_at
+	`, preview.URL, preview.Title, preview.Description, preview.ImageURL, preview.SiteName, fetchErr, preview.FetchedAt)
+	return err
+}
+
+// placeholders returns n comma-separated SQL parameters
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// stringArgs converts values into query arguments
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkPreviews(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	fresh := "https://example.com/fresh"
+	old := "https://example.com/old"
+	failed := "https://example.com/failed"
+	missing := "https://example.com/missing"
+
+	require.NoError(t, repo.SaveLinkPreview(ctx, models.LinkPreview{URL: fresh, Title: "Fresh", SiteName: "Example", FetchedAt: now}, ""))
+	require.NoError(t, repo.SaveLinkPreview(ctx, models.LinkPreview{URL: old, Title: "Old", FetchedAt: now.Add(-10 * 24 * time.Hour)}, ""))
+	require.NoError(t, repo.SaveLinkPreview(ctx, models.LinkPreview{URL: failed, FetchedAt: now.Add(-2 * time.Hour)}, "page is not HTML"))
+
+	t.Run("Previews come back in the order asked, without failures", func(t *testing.T) {
+		previews, err := repo.GetLinkPreviews(ctx, []string{missing, old, failed, fresh})
+		require.NoError(t, err)
+		require.Len(t, previews, 2)
+		assert.Equal(t, "Old", previews[0].Title)
+		assert.Equal(t, "Fresh", previews[1].Title)
+		assert.Equal(t, "Example", previews[1].SiteName)
+		assert.True(t, previews[1].FetchedAt.Equal(now))
+	})
+
+	t.Run("No URLs", func(t *testing.T) {
+		previews, err := repo.GetLinkPreviews(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, previews)
+		assert.NotNil(t, previews)
+	})
+
+	t.Run("Stale URLs", func(t *testing.T) {
+		urls := []string{fresh, old, failed, missing}
+
+		stale, err := repo.GetStaleLinkURLs(ctx, urls, now.Add(-7*24*time.Hour), now.Add(-24*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []string{old, missing}, stale)
+
+		stale, err = repo.GetStaleLinkURLs(ctx, urls, now.Add(-7*24*time.Hour), now.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []string{old, failed, missing}, stale)
+	})
+
+	t.Run("Saving replaces a failure", func(t *testing.T) {
+		require.NoError(t, repo.SaveLinkPreview(ctx, models.LinkPreview{URL: failed, Title: "Recovered", FetchedAt: now}, ""))
+
+		previews, err := repo.GetLinkPreviews(ctx, []string{failed})
+		require.NoError(t, err)
+		require.Len(t, previews, 1)
+		assert.Equal(t, "Recovered", previews[0].Title)
+	})
+}
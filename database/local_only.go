@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+)
+
+// ==================== LOCAL-ONLY NOTES ====================
+
+// localOnlyCondition holds for notes marked local only themselves or kept in a
+// local-only context; it refers to the notes table by name
+var localOnlyCondition = localOnlyOf("notes")
+
+// localOnlyOf is localOnlyCondition for the notes table referred to as table
+func localOnlyOf(table string) string {
+	return `(` + table + `.local_only = 1 OR EXISTS (
+	SELECT 1 FROM contexts lc
+	WHERE lc.user_id = ` + table + `.user_id AND lc.name = ` + table + `.context AND lc.local_only = 1
+))`
+}
+
+// SetNoteLocalOnly marks a note local only, so it is never synced to storage, or
+// queues it for sync again. Returns false if there is no such note.
+func (r *Repository) SetNoteLocalOnly(ctx context.Context, userID, contextName, date string, localOnly bool) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	updated, err := affected(tx.ExecContext(ctx, `
+		UPDATE notes SET local_only = ?
+		WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0
+	`, localOnly, userID, contextName, date))
+	if err != nil || !updated {
+		return false, err
+	}
+
+	if err := applyLocalOnly(ctx, tx, userID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// SetContextLocalOnly marks every note of a context local only, or queues the
+// notes that aren't marked themselves for sync again
+func (r *Repository) SetContextLocalOnly(ctx context.Context, scope UserScope, contextID string, localOnly bool) error {
+	if err := scope.check(); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE contexts SET local_only = ?
+		WHERE id = ? AND user_id = ?
+		RETURNING user_id
+	`, localOnly, contextID, scope.userID).Scan(&userID); err != nil {
+		return err
+	}
+
+	if err := applyLocalOnly(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// applyLocalOnly brings the sync state of a user's notes in line with their
+// local-only marks: local-only notes leave the sync queue with the local status,
+// and notes that were local only before are queued for upload
+func applyLocalOnly(ctx context.Context, tx *Tx, userID string) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE notes SET
+			sync_pending = 0,
+			sync_status = ?,
+			sync_retry_count = 0,
+			sync_error = NULL,
+			sync_error_class = NULL,
+			next_retry_at = NULL
+		WHERE user_id = ? AND deleted = 0 AND sync_status != ? AND `+localOnlyCondition,
+		string(models.SyncStatusLocal), userID, string(models.SyncStatusLocal),
+	); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE notes SET
+			sync_pending = 1,
+			sync_status = ?,
+			next_retry_at = NULL
+		WHERE user_id = ? AND deleted = 0 AND sync_status = ? AND NOT `+localOnlyCondition,
+		string(models.SyncStatusPending), userID, string(models.SyncStatusLocal),
+	)
+	return err
+}
+
+// isLocalOnly reports whether a note, or the context it is saved in, is marked
+// local only; the note doesn't need to exist yet
+func isLocalOnly(ctx context.Context, tx *Tx, note *models.Note) (bool, error) {
+	if note.LocalOnly {
+		return true, nil
+	}
+
+	var marks int
+	err := tx.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM notes WHERE user_id = ? AND context = ? AND date = ? AND local_only = 1)
+		     + (SELECT COUNT(*) FROM contexts WHERE user_id = ? AND name = ? AND local_only = 1)
+	`, note.UserID, note.Context, note.Date, note.UserID, note.Context).Scan(&marks)
+	return marks > 0, err
+}
+
+// CountLocalOnlyNotes returns how many of a user's notes are kept out of sync
+func (r *Repository) CountLocalOnlyNotes(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notes
+		WHERE user_id = ? AND deleted = 0 AND `+localOnlyCondition,
+		userID,
+	).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalOnlyNotes(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	save := func(contextName, date, content string) *models.Note {
+		note := &models.Note{
+			UserID: "test-user", Context: contextName, Date: date, Content: content,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}
+		require.NoError(t, repo.UpsertNote(ctx, note, true))
+		return note
+	}
+	pendingDates := func() []string {
+		pending, err := repo.GetPendingSyncNotes(ctx, 100)
+		require.NoError(t, err)
+		dates := []string{}
+		for _, note := range pending {
+			dates = append(dates, note.Context+"/"+note.Date)
+		}
+		return dates
+	}
+
+	save("Work", "2025-10-16", "plans")
+	save("Work", "2025-10-17", "more plans")
+
+	t.Run("Marked notes leave the sync queue", func(t *testing.T) {
+		updated, err := repo.SetNoteLocalOnly(ctx, "test-user", "Work", "2025-10-16", true)
+		require.NoError(t, err)
+		assert.True(t, updated)
+		assert.Equal(t, []string{"Work/2025-10-17"}, pendingDates())
+
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.True(t, note.LocalOnly)
+		assert.Equal(t, models.SyncStatusLocal, note.SyncStatus)
+	})
+
+	t.Run("Saving a local-only note doesn't queue it", func(t *testing.T) {
+		note := save("Work", "2025-10-16", "secret plans")
+		assert.True(t, note.LocalOnly)
+		assert.Equal(t, models.SyncStatusLocal, note.SyncStatus)
+		assert.Equal(t, []string{"Work/2025-10-17"}, pendingDates())
+	})
+
+	t.Run("Unmarked notes are queued again", func(t *testing.T) {
+		updated, err := repo.SetNoteLocalOnly(ctx, "test-user", "Work", "2025-10-16", false)
+		require.NoError(t, err)
+		assert.True(t, updated)
+		assert.ElementsMatch(t, []string{"Work/2025-10-16", "Work/2025-10-17"}, pendingDates())
+	})
+
+	t.Run("Missing notes can't be marked", func(t *testing.T) {
+		updated, err := repo.SetNoteLocalOnly(ctx, "test-user", "Work", "2025-01-01", true)
+		require.NoError(t, err)
+		assert.False(t, updated)
+	})
+
+	t.Run("Local-only contexts keep every note local", func(t *testing.T) {
+		journal := &models.Context{ID: "ctx-journal", UserID: "test-user", Name: "Journal", Color: "dark", CreatedAt: time.Now()}
+		require.NoError(t, repo.CreateContext(ctx, journal))
+		save("Journal", "2025-10-16", "dear diary")
+
+		require.NoError(t, repo.SetContextLocalOnly(ctx, ScopeUser("test-user"), journal.ID, true))
+		assert.ElementsMatch(t, []string{"Work/2025-10-16", "Work/2025-10-17"}, pendingDates())
+
+		note := save("Journal", "2025-10-17", "dear diary again")
+		assert.True(t, note.LocalOnly)
+
+		c, err := repo.GetContextByID(ctx, ScopeUser("test-user"), journal.ID)
+		require.NoError(t, err)
+		assert.True(t, c.LocalOnly)
+
+		count, err := repo.CountLocalOnlyNotes(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		state, err := repo.GetNoteSyncState(ctx, "test-user", "Journal", "2025-10-16")
+		require.NoError(t, err)
+		assert.True(t, state.LocalOnly)
+
+		require.NoError(t, repo.SetContextLocalOnly(ctx, ScopeUser("test-user"), journal.ID, false))
+		assert.ElementsMatch(t, []string{"Work/2025-10-16", "Work/2025-10-17", "Journal/2025-10-16", "Journal/2025-10-17"}, pendingDates())
+	})
+
+	t.Run("Copies of local-only notes stay local only", func(t *testing.T) {
+		updated, err := repo.SetNoteLocalOnly(ctx, "test-user", "Work", "2025-10-17", true)
+		require.NoError(t, err)
+		require.True(t, updated)
+
+		copied, err := repo.TransferNotes(ctx, ScopeUser("test-user"), "Work", "Home", []string{"2025-10-17"}, false, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, copied, 1)
+		assert.True(t, copied[0].LocalOnly)
+		assert.NotContains(t, pendingDates(), "Home/2025-10-17")
+	})
+
+	t.Run("Deleting a local-only note still removes its file", func(t *testing.T) {
+		require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17"))
+		assert.Contains(t, pendingDates(), "Work/2025-10-17")
+	})
+}
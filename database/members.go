@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ==================== CONTEXT MEMBERS ====================
+
+// CreateContextInvitation invites an email address to a context and sets the
+// invitation's ID. Inviting an address again only changes its role, so a member
+// who already accepted keeps access under the new role.
+func (r *Repository) CreateContextInvitation(ctx context.Context, m *models.ContextMember) error {
+	var userID sql.NullString
+	var acceptedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO context_members (context_id, email, role, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(context_id, email) DO UPDATE SET role = excluded.role
+		RETURNING id, user_id, created_at, accepted_at
+	`, m.ContextID, m.Email, m.Role, m.CreatedAt).Scan(&m.ID, &userID, &m.CreatedAt, &acceptedAt)
+	if err != nil {
+		return err
+	}
+	m.UserID = userID.String
+	if acceptedAt.Valid {
+		m.AcceptedAt = &acceptedAt.Time
+	}
+	return nil
+}
+
+// GetContextMembers returns the members and pending invitations of a context,
+// oldest first
+func (r *Repository) GetContextMembers(ctx context.Context, contextID string) ([]models.ContextMember, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT m.id, m.context_id, m.email, m.user_id, COALESCE(u.name, ''), m.role, m.created_at, m.accepted_at
+		FROM context_members m
+		LEFT JOIN users u ON u.id = m.user_id
+		WHERE m.context_id = ?
+		ORDER BY m.created_at ASC, m.id ASC
+	`, contextID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.ContextMember{}
+	for rows.Next() {
+		var m models.ContextMember
+		var userID sql.NullString
+		var acceptedAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.ContextID, &m.Email, &userID, &m.Name, &m.Role, &m.CreatedAt, &acceptedAt); err != nil {
+			return nil, err
+		}
+		m.UserID = userID.String
+		if acceptedAt.Valid {
+			m.AcceptedAt = &acceptedAt.Time
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// DeleteContextMember removes a member or pending invitation of a context; it
+// reports false if there was none
+func (r *Repository) DeleteContextMember(ctx context.Context, contextID string, id int64) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `DELETE FROM context_members WHERE context_id = ? AND id = ?`, contextID, id))
+}
+
+// GetContextInvitation returns an invitation with the context's current name
+// and owner, nil if there is none or its context is in the trash
+func (r *Repository) GetContextInvitation(ctx context.Context, id int64) (*models.ContextInvitation, error) {
+	var inv models.ContextInvitation
+	err := r.db.QueryRowContext(ctx, `
+		SELECT m.id, m.context_id, c.name, o.name, m.email, m.role, m.user_id IS NOT NULL, m.created_at
+		FROM context_members m
+		JOIN contexts c ON c.id = m.context_id
+		JOIN users o ON o.id = c.user_id
+		WHERE m.id = ?
+	`, id).Scan(&inv.ID, &inv.ContextID, &inv.Context, &inv.Owner, &inv.Email, &inv.Role, &inv.Accepted, &inv.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// GetContextInvitations returns the contexts shared with a user: invitations
+// to their email address and the ones they accepted, newest first
+func (r *Repository) GetContextInvitations(ctx context.Context, userID, email string) ([]models.ContextInvitation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT m.id, m.context_id, c.name, o.name, m.email, m.role, m.user_id IS NOT NULL, m.created_at
+		FROM context_members m
+		JOIN contexts c ON c.id = m.context_id
+		JOIN users o ON o.id = c.user_id
+		WHERE m.user_id = ? OR (m.user_id IS NULL AND m.email = ?)
+		ORDER BY m.created_at DESC, m.id DESC
+	`, userID, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.ContextInvitation{}
+	for rows.Next() {
+		var inv models.ContextInvitation
+		if err := rows.Scan(&inv.ID, &inv.ContextID, &inv.Context, &inv.Owner, &inv.Email, &inv.Role, &inv.Accepted, &inv.CreatedAt); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+// AcceptContextInvitation makes the user with email a member through a pending
+// invitation to that address; it reports false if there is none
+func (r *Repository) AcceptContextInvitation(ctx context.Context, id int64, userID, email string, at time.Time) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `
+		UPDATE context_members SET user_id = ?, accepted_at = ?
+		WHERE id = ? AND email = ? AND user_id IS NULL
+	`, userID, at, id, email))
+}
+
+// LeaveContext ends a user's membership, or declines an invitation to their
+// email address; it reports false if there was neither
+func (r *Repository) LeaveContext(ctx context.Context, id int64, userID, email string) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `
+		DELETE FROM context_members
+		WHERE id = ? AND (user_id = ? OR (user_id IS NULL AND email = ?))
+	`, id, userID, email))
+}
+
+// GetSharedContexts returns the contexts of other users a user is a member of,
+// with the user's role and the owner's name
+func (r *Repository) GetSharedContexts(ctx context.Context, userID string) ([]models.Context, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.user_id, c.name, c.color, c.icon, c.template, c.local_only, c.language, m.role, o.name, c.created_at
+		FROM context_members m
+		JOIN contexts c ON c.id = m.context_id
+		JOIN users o ON o.id = c.user_id
+		WHERE m.user_id = ? AND c.user_id <> ?
+		ORDER BY m.accepted_at ASC, m.id ASC
+	`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contexts := []models.Context{}
+	for rows.Next() {
+		var c models.Context
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &c.Color, &c.Icon, &c.Template, &c.LocalOnly, &c.Language, &c.Role, &c.Owner, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, c)
+	}
+	return contexts, rows.Err()
+}
+
+// GetContextAccess returns whose notes contextName leads userID to. The user's
+// own context of that name comes first; otherwise only an accepted membership
+// of a context that isn't in the trash leads to another user's notes. Without
+// either, the notes are the user's own, with no context.
+func (r *Repository) GetContextAccess(ctx context.Context, userID, contextName string) (*models.ContextAccess, error) {
+	access := models.ContextAccess{OwnerID: userID}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT c.user_id, c.id, COALESCE(m.role, '')
+		FROM contexts c
+		LEFT JOIN context_members m ON m.context_id = c.id AND m.user_id = ?
+		WHERE c.name = ? AND (c.user_id = ? OR m.id IS NOT NULL)
+		ORDER BY c.user_id = ? DESC, m.accepted_at ASC
+		LIMIT 1
+	`, userID, contextName, userID, userID).Scan(&access.OwnerID, &access.ContextID, &access.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &access, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if access.OwnerID == userID {
+		access.Role = ""
+	}
+	return &access, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextMembers(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	require.NoError(t, repo.UpsertUser(ctx, &models.User{ID: "member", GoogleID: "google-456", Email: "ana@example.com", Name: "Ana", CreatedAt: now}))
+	require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-family", UserID: "test-user", Name: "Family", Color: "primary", CreatedAt: now}))
+
+	invitation := &models.ContextMember{ContextID: "ctx-family", Email: "ana@example.com", Role: models.MemberRead, CreatedAt: now}
+	require.NoError(t, repo.CreateContextInvitation(ctx, invitation))
+	require.NotZero(t, invitation.ID)
+
+	t.Run("Invitations grant nothing until accepted", func(t *testing.T) {
+		access, err := repo.GetContextAccess(ctx, "member", "Family")
+		require.NoError(t, err)
+		assert.Equal(t, &models.ContextAccess{OwnerID: "member"}, access)
+
+		invitations, err := repo.GetContextInvitations(ctx, "member", "ana@example.com")
+		require.NoError(t, err)
+		require.Len(t, invitations, 1)
+		assert.Equal(t, "Family", invitations[0].Context)
+		assert.Equal(t, "Test User", invitations[0].Owner)
+		assert.False(t, invitations[0].Accepted)
+	})
+
+	t.Run("Only the invited address accepts", func(t *testing.T) {
+		accepted, err := repo.AcceptContextInvitation(ctx, invitation.ID, "member", "other@example.com", now)
+		require.NoError(t, err)
+		assert.False(t, accepted)
+
+		accepted, err = repo.AcceptContextInvitation(ctx, invitation.ID, "member", "ana@example.com", now)
+		require.NoError(t, err)
+		assert.True(t, accepted)
+
+		access, err := repo.GetContextAccess(ctx, "member", "Family")
+		require.NoError(t, err)
+		assert.Equal(t, &models.ContextAccess{OwnerID: "test-user", ContextID: "ctx-family", Role: models.MemberRead}, access)
+
+		shared, err := repo.GetSharedContexts(ctx, "member")
+		require.NoError(t, err)
+		require.Len(t, shared, 1)
+		assert.Equal(t, "Test User", shared[0].Owner)
+		assert.Equal(t, models.MemberRead, shared[0].Role)
+	})
+
+	t.Run("Inviting again changes the role", func(t *testing.T) {
+		again := &models.ContextMember{ContextID: "ctx-family", Email: "ana@example.com", Role: models.MemberWrite, CreatedAt: now}
+		require.NoError(t, repo.CreateContextInvitation(ctx, again))
+		assert.Equal(t, invitation.ID, again.ID)
+		assert.Equal(t, "member", again.UserID)
+
+		members, err := repo.GetContextMembers(ctx, "ctx-family")
+		require.NoError(t, err)
+		require.Len(t, members, 1)
+		assert.Equal(t, models.MemberWrite, members[0].Role)
+		assert.Equal(t, "Ana", members[0].Name)
+	})
+
+	t.Run("Own contexts come first", func(t *testing.T) {
+		require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-own", UserID: "member", Name: "Family", Color: "primary", CreatedAt: now}))
+		access, err := repo.GetContextAccess(ctx, "member", "Family")
+		require.NoError(t, err)
+		assert.Equal(t, &models.ContextAccess{OwnerID: "member", ContextID: "ctx-own"}, access)
+		require.NoError(t, repo.DeleteContext(ctx, ScopeUser("member"), "ctx-own", now))
+	})
+
+	t.Run("Trashed contexts grant nothing", func(t *testing.T) {
+		require.NoError(t, repo.DeleteContext(ctx, ScopeUser("test-user"), "ctx-family", now))
+		access, err := repo.GetContextAccess(ctx, "member", "Family")
+		require.NoError(t, err)
+		assert.Equal(t, "member", access.OwnerID)
+
+		require.NoError(t, repo.RestoreContext(ctx, ScopeUser("test-user"), "ctx-family"))
+		access, err = repo.GetContextAccess(ctx, "member", "Family")
+		require.NoError(t, err)
+		assert.Equal(t, "test-user", access.OwnerID, "members come back with the context")
+	})
+
+	t.Run("Leaving ends access", func(t *testing.T) {
+		left, err := repo.LeaveContext(ctx, invitation.ID, "member", "ana@example.com")
+		require.NoError(t, err)
+		assert.True(t, left)
+
+		access, err := repo.GetContextAccess(ctx, "member", "Family")
+		require.NoError(t, err)
+		assert.Equal(t, "member", access.OwnerID)
+		removed, err := repo.DeleteContextMember(ctx, "ctx-family", invitation.ID)
+		require.NoError(t, err)
+		assert.False(t, removed)
+	})
+}
@@ -0,0 +1,357 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ==================== SCHEMA MIGRATIONS ====================
+
+// migrationFiles holds the schema changes, one numbered migration per change:
+// NNNN_name.up.sql applies it and NNNN_name.down.sql reverts it. Files are
+// written for SQLite and translated for other dialects; a migration that needs
+// each dialect's own SQL (triggers) comes as NNNN_name.<dialect>.up.sql files.
+// Applied migrations are never edited: schema changes add a new file.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrUnknownSchemaVersion means the database was migrated by a newer binary;
+// running against a schema this binary doesn't know could corrupt data
+var ErrUnknownSchemaVersion = errors.New("database schema is newer than this binary")
+
+// schemaMigrationsTable records the migrations applied to the database
+const schemaMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`
+
+// legacyColumns are the columns added to existing tables before migrations were
+// versioned. Databases from that time may lack some of them; they are added
+// once, when such a database adopts the baseline migration.
+var legacyColumns = []struct{ table, column, definition string }{
+	{"notes", "deleted", "INTEGER DEFAULT 0"},
+	{"notes", "sync_status", "TEXT DEFAULT 'pending'"},
+	{"notes", "sync_retry_count", "INTEGER DEFAULT 0"},
+	{"notes", "sync_last_attempt_at", "DATETIME"},
+	{"notes", "sync_error", "TEXT"},
+	{"notes", "revision", "INTEGER DEFAULT 1"},
+	{"notes", "granularity", "TEXT DEFAULT 'day'"},
+	{"contexts", "template", "TEXT DEFAULT ''"},
+	{"context_trash", "template", "TEXT DEFAULT ''"},
+	{"sessions", "settings_suggest_context", "INTEGER DEFAULT 0"},
+	{"users", "storage_provider", "TEXT DEFAULT 'drive'"},
+	{"notes", "content_size", "INTEGER"},
+	{"notes", "content_hash", "TEXT"},
+	{"notes", "sync_error_class", "TEXT"},
+	{"notes", "next_retry_at", "DATETIME"},
+	{"users", "last_seen_version", "TEXT"},
+	{"notes", "local_only", "INTEGER DEFAULT 0"},
+	{"contexts", "local_only", "INTEGER DEFAULT 0"},
+	{"context_trash", "local_only", "INTEGER DEFAULT 0"},
+}
+
+// migrationBackfills fill the tables of a migration with data only Go can derive
+// from existing rows, in the migration's transaction, by migration version
+var migrationBackfills = map[int]func(tx *Tx) error{
+	9:  backfillTasks,
+	12: backfillWordCounts,
+	14: backfillCharCounts,
+	16: backfillReminders,
+	28: backfillTags,
+}
+
+// migration is one numbered schema change, with its SQL for the dialect
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string // Empty if the migration can't be reverted
+}
+
+// loadMigrations reads the embedded migrations for a dialect, oldest first
+func loadMigrations(dialect Dialect) ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	specific := map[string]bool{} // Files written for this dialect, which win over generic ones
+	for _, entry := range entries {
+		// NNNN_name[.dialect].(up|down).sql
+		parts := strings.Split(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("migration %s: name must be NNNN_name[.dialect].up.sql or .down.sql", entry.Name())
+		}
+		direction := parts[len(parts)-1]
+		if direction != "up" && direction != "down" {
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", entry.Name())
+		}
+		if len(parts) == 3 && Dialect(parts[1]) != dialect {
+			continue
+		}
+
+		number, name, ok := strings.Cut(parts[0], "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", entry.Name())
+		}
+
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		sql := string(content)
+		if len(parts) == 2 {
+			sql = dialect.translate(sql)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		} else if m.name != name {
+			return nil, fmt.Errorf("migration %d is both %s and %s", version, m.name, name)
+		}
+
+		key := number + "." + direction
+		if len(parts) == 2 && specific[key] {
+			continue
+		}
+		if len(parts) == 3 {
+			specific[key] = true
+		}
+		if direction == "up" {
+			m.up = sql
+		} else {
+			m.down = sql
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up file for %s", m.version, m.name, dialect)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// Migrate brings the schema up to date: it applies the migrations the database
+// hasn't seen yet, each in its own transaction, then sets up tags and full-text
+// search. It refuses to touch a database migrated by a newer binary.
+func (db *DB) Migrate() error {
+	migrations, err := loadMigrations(db.dialect)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(db.dialect.translate(schemaMigrationsTable)); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(applied, migrations); err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		if err := db.adoptLegacySchema(); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := db.runMigration(m, true); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+	}
+
+	return db.migrateFullText()
+}
+
+// MigrateDown reverts the applied migrations above version, newest first, and
+// returns the version the schema is left at. Version 0 empties the database.
+func (db *DB) MigrateDown(version int) (int, error) {
+	migrations, err := loadMigrations(db.dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := db.Exec(db.dialect.translate(schemaMigrationsTable)); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if err := checkSchemaVersion(applied, migrations); err != nil {
+		return 0, err
+	}
+
+	current := schemaVersion(applied)
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version <= version || !applied[m.version] {
+			continue
+		}
+		if m.down == "" {
+			return current, fmt.Errorf("migration %d (%s) can't be reverted", m.version, m.name)
+		}
+		if err := db.runMigration(m, false); err != nil {
+			return current, fmt.Errorf("reverting migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		delete(applied, m.version)
+		current = schemaVersion(applied)
+	}
+	return current, nil
+}
+
+// SchemaVersion returns the newest migration applied to the database, 0 for a
+// database that was never migrated
+func (db *DB) SchemaVersion() (int, error) {
+	var exists int
+	if err := db.QueryRow(db.dialect.tableExistsQuery(), "schema_migrations").Scan(&exists); err != nil || exists == 0 {
+		return 0, err
+	}
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	return schemaVersion(applied), nil
+}
+
+// LatestSchemaVersion returns the newest migration this binary knows
+func (db *DB) LatestSchemaVersion() (int, error) {
+	migrations, err := loadMigrations(db.dialect)
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].version, nil
+}
+
+// runMigration applies a migration, or reverts it, and records the result
+func (db *DB) runMigration(m migration, up bool) error {
+	statements := m.down
+	if up {
+		statements = m.up
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(statements); err != nil {
+		return err
+	}
+	if backfill := migrationBackfills[m.version]; up && backfill != nil {
+		if err := backfill(tx); err != nil {
+			return err
+		}
+	}
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name)
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// appliedMigrations returns the versions recorded in schema_migrations
+func (db *DB) appliedMigrations() (map[int]bool, error) {
+	rows, err := db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// checkSchemaVersion fails with ErrUnknownSchemaVersion if the database has
+// migrations applied that this binary doesn't have
+func checkSchemaVersion(applied map[int]bool, migrations []migration) error {
+	known := make(map[int]bool, len(migrations))
+	latest := 0
+	for _, m := range migrations {
+		known[m.version] = true
+		latest = m.version
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("%w: database is at version %d, this binary knows up to %d",
+				ErrUnknownSchemaVersion, schemaVersion(applied), latest)
+		}
+	}
+	return nil
+}
+
+// schemaVersion is the newest of the applied migrations
+func schemaVersion(applied map[int]bool) int {
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version
+}
+
+// adoptLegacySchema prepares a database created before migrations were
+// versioned for the baseline migration, by adding the columns it lacks. Tables
+// the database doesn't have yet are left for the baseline to create, and empty
+// databases are left alone.
+func (db *DB) adoptLegacySchema() error {
+	var exists int
+	if err := db.QueryRow(db.dialect.tableExistsQuery(), "notes").Scan(&exists); err != nil || exists == 0 {
+		return err
+	}
+
+	for _, c := range legacyColumns {
+		var table, found int
+		if err := db.QueryRow(db.dialect.tableExistsQuery(), c.table).Scan(&table); err != nil {
+			return err
+		}
+		if table == 0 {
+			continue
+		}
+		if err := db.QueryRow(db.dialect.columnExistsQuery(), c.table, c.column).Scan(&found); err != nil {
+			return err
+		}
+		if found > 0 {
+			continue
+		}
+		query := db.dialect.translate(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.column, c.definition))
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return nil
+}
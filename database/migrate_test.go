@@ -0,0 +1,173 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	for _, dialect := range []Dialect{SQLite, Postgres} {
+		migrations, err := loadMigrations(dialect)
+		require.NoError(t, err, dialect)
+		require.NotEmpty(t, migrations)
+
+		for i, m := range migrations {
+			assert.Equal(t, i+1, m.version, "%s: versions are numbered without gaps", dialect)
+			assert.NotEmpty(t, m.down, "%s: migration %d can be reverted", dialect, m.version)
+		}
+	}
+
+	sqlite, _ := loadMigrations(SQLite)
+	postgres, _ := loadMigrations(Postgres)
+	assert.Contains(t, sqlite[0].up, "created_at DATETIME")
+	assert.Contains(t, postgres[0].up, "created_at TIMESTAMPTZ", "generic migrations are translated")
+	assert.Contains(t, sqlite[1].up, "CREATE TRIGGER IF NOT EXISTS notes_content_size_insert")
+	assert.Contains(t, postgres[1].up, "LANGUAGE plpgsql", "dialect migrations replace generic ones")
+}
+
+func TestMigrate(t *testing.T) {
+	open := func(t *testing.T) *DB {
+		db, err := New(filepath.Join(t.TempDir(), "test.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	count := func(t *testing.T, db *DB, query string, args ...interface{}) int {
+		var n int
+		require.NoError(t, db.QueryRow(query, args...).Scan(&n))
+		return n
+	}
+
+	t.Run("Fresh databases get every migration", func(t *testing.T) {
+		db := open(t)
+		require.NoError(t, db.Migrate())
+		require.NoError(t, db.Migrate(), "migrating again is a no-op")
+
+		version, err := db.SchemaVersion()
+		require.NoError(t, err)
+		latest, err := db.LatestSchemaVersion()
+		require.NoError(t, err)
+		assert.Equal(t, latest, version)
+		assert.Equal(t, latest, count(t, db, `SELECT COUNT(*) FROM schema_migrations`))
+	})
+
+	t.Run("Databases from before versioning adopt the baseline", func(t *testing.T) {
+		db := open(t)
+		require.NoError(t, db.Migrate())
+		_, err := db.Exec(`INSERT INTO users (id, google_id, email) VALUES ('u1', 'g1', 'u1@example.com')`)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO notes (id, user_id, context, date, content) VALUES ('n1', 'u1', 'Work', '2025-10-16', 'plans')`)
+		require.NoError(t, err)
+
+		// Roll the database back to an older layout: no migrations table, no local_only,
+		// nothing added by later migrations
+		for _, query := range []string{
+			`DROP TABLE schema_migrations`,
+			`ALTER TABLE notes DROP COLUMN local_only`,
+			`ALTER TABLE contexts DROP COLUMN local_only`,
+			`ALTER TABLE contexts DROP COLUMN language`,
+			`ALTER TABLE context_trash DROP COLUMN language`,
+			`ALTER TABLE notes DROP COLUMN word_count`,
+			`ALTER TABLE notes DROP COLUMN char_count`,
+			`ALTER TABLE notes DROP COLUMN pinned_at`,
+			`ALTER TABLE contexts DROP COLUMN sort_order`,
+			`ALTER TABLE contexts DROP COLUMN icon`,
+			`ALTER TABLE context_trash DROP COLUMN icon`,
+			`ALTER TABLE users DROP COLUMN settings_suggest_context`,
+			`ALTER TABLE note_trash DROP COLUMN revision`,
+			`ALTER TABLE note_trash DROP COLUMN granularity`,
+		} {
+			_, err := db.Exec(query)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, db.Migrate())
+		assert.Equal(t, 1, count(t, db, `SELECT COUNT(*) FROM pragma_table_info('notes') WHERE name = 'local_only'`))
+		assert.Equal(t, 1, count(t, db, `SELECT COUNT(*) FROM pragma_table_info('contexts') WHERE name = 'local_only'`))
+		assert.Equal(t, 1, count(t, db, `SELECT COUNT(*) FROM pragma_table_info('contexts') WHERE name = 'language'`))
+		assert.Equal(t, 1, count(t, db, `SELECT COUNT(*) FROM notes WHERE id = 'n1' AND local_only = 0`), "notes are kept")
+		assert.Equal(t, 1, count(t, db, `SELECT COUNT(*) FROM notes WHERE id = 'n1' AND word_count = 1 AND char_count = 5`), "words and characters of existing notes are counted")
+
+		version, err := db.SchemaVersion()
+		require.NoError(t, err)
+		latest, _ := db.LatestSchemaVersion()
+		assert.Equal(t, latest, version)
+	})
+
+	t.Run("Databases with the baseline release's schema are upgraded", func(t *testing.T) {
+		db := open(t)
+		schema, err := os.ReadFile(filepath.Join("testdata", "baseline_schema.sql"))
+		require.NoError(t, err)
+		_, err = db.Exec(string(schema))
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO users (id, google_id, email) VALUES ('u1', 'g1', 'u1@example.com')`)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO notes (id, user_id, context, date, content) VALUES ('n1', 'u1', 'Work', '2025-10-16', '- [ ] ship #release')`)
+		require.NoError(t, err)
+
+		require.NoError(t, db.Migrate())
+		assert.Equal(t, 1, count(t, db, `SELECT COUNT(*) FROM pragma_table_info('context_trash') WHERE name = 'local_only'`), "tables added since are created")
+		assert.Equal(t, 1, count(t, db, `SELECT COUNT(*) FROM notes WHERE id = 'n1' AND revision = 1 AND local_only = 0`), "notes are kept")
+		assert.Equal(t, 1, count(t, db, `SELECT COUNT(*) FROM note_tags WHERE note_id = 'n1'`), "notes are backfilled")
+
+		version, err := db.SchemaVersion()
+		require.NoError(t, err)
+		latest, _ := db.LatestSchemaVersion()
+		assert.Equal(t, latest, version)
+	})
+
+	t.Run("Databases from newer binaries are refused", func(t *testing.T) {
+		db := open(t)
+		require.NoError(t, db.Migrate())
+		_, err := db.Exec(`INSERT INTO schema_migrations (version, name) VALUES (9999, 'from_the_future')`)
+		require.NoError(t, err)
+
+		err = db.Migrate()
+		assert.ErrorIs(t, err, ErrUnknownSchemaVersion)
+		assert.True(t, strings.Contains(err.Error(), "version 9999"), err.Error())
+
+		_, err = db.MigrateDown(0)
+		assert.ErrorIs(t, err, ErrUnknownSchemaVersion)
+	})
+
+	t.Run("Tasks of existing notes are backfilled", func(t *testing.T) {
+		db := open(t)
+		require.NoError(t, db.Migrate())
+		_, err := db.MigrateDown(8)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO users (id, google_id, email) VALUES ('u1', 'g1', 'u1@example.com')`)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO notes (id, user_id, context, date, content) VALUES ('n1', 'u1', 'Work', '2025-10-16', ?)`, "- [ ] plans\n- [x] done")
+		require.NoError(t, err)
+
+		require.NoError(t, db.Migrate())
+		assert.Equal(t, 2, count(t, db, `SELECT COUNT(*) FROM tasks WHERE note_id = 'n1'`))
+		assert.Equal(t, 1, count(t, db, `SELECT COUNT(*) FROM tasks WHERE note_id = 'n1' AND line = 2 AND done = 1`))
+	})
+
+	t.Run("Migrations are reverted down to a version", func(t *testing.T) {
+		db := open(t)
+		require.NoError(t, db.Migrate())
+		triggers := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = 'notes_content_size_insert'`
+		require.Equal(t, 1, count(t, db, triggers))
+
+		version, err := db.MigrateDown(1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, version)
+		assert.Equal(t, 0, count(t, db, triggers))
+
+		require.NoError(t, db.Migrate(), "reverted migrations are applied again")
+		assert.Equal(t, 1, count(t, db, triggers))
+
+		version, err = db.MigrateDown(0)
+		require.NoError(t, err)
+		assert.Equal(t, 0, version)
+		assert.Equal(t, 0, count(t, db, db.dialect.tableExistsQuery(), "notes"))
+		assert.Equal(t, 0, count(t, db, db.dialect.tableExistsQuery(), "tags"))
+	})
+}
@@ -0,0 +1,20 @@
+-- Drops every table, including the tag and search tables Migrate keeps up
+-- outside the migrations, leaving an empty database
+DROP TABLE IF EXISTS notes_fts;
+DROP TABLE IF EXISTS note_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS note_conflicts;
+DROP TABLE IF EXISTS note_revisions;
+DROP TABLE IF EXISTS link_previews;
+DROP TABLE IF EXISTS note_publications;
+DROP TABLE IF EXISTS publish_targets;
+DROP TABLE IF EXISTS public_contexts;
+DROP TABLE IF EXISTS import_checkpoints;
+DROP TABLE IF EXISTS change_channels;
+DROP TABLE IF EXISTS change_tokens;
+DROP TABLE IF EXISTS storage_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS context_trash;
+DROP TABLE IF EXISTS notes;
+DROP TABLE IF EXISTS contexts;
+DROP TABLE IF EXISTS users;
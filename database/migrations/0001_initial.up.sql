@@ -0,0 +1,233 @@
+-- Baseline schema: every table as of the switch to versioned migrations. It
+-- only creates what is missing, so databases from before the switch adopt it
+-- after Migrate adds the columns they lack (see legacyColumns in migrate.go).
+
+CREATE TABLE IF NOT EXISTS users (
+	id TEXT PRIMARY KEY,
+	google_id TEXT UNIQUE NOT NULL,
+	email TEXT NOT NULL,
+	name TEXT,
+	picture TEXT,
+	settings_theme TEXT DEFAULT 'dark',
+	settings_week_start INTEGER DEFAULT 0,
+	settings_timezone TEXT DEFAULT 'UTC',
+	settings_date_format TEXT DEFAULT 'DD-MM-YY',
+	settings_unique_context_mode INTEGER DEFAULT 0,
+	storage_provider TEXT DEFAULT 'drive',
+	last_seen_version TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_login_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS contexts (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	color TEXT NOT NULL,
+	template TEXT DEFAULT '',
+	local_only INTEGER DEFAULT 0,
+	drive_folder_id TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	UNIQUE(user_id, name)
+);
+
+-- Notes with their sync state
+CREATE TABLE IF NOT EXISTS notes (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	context TEXT NOT NULL,
+	date TEXT NOT NULL,
+	granularity TEXT DEFAULT 'day',
+	content TEXT,
+	content_size INTEGER,
+	drive_file_id TEXT,
+	synced_at DATETIME,
+	content_hash TEXT,
+	sync_pending INTEGER DEFAULT 1,
+	sync_status TEXT DEFAULT 'pending',
+	sync_retry_count INTEGER DEFAULT 0,
+	sync_last_attempt_at DATETIME,
+	sync_error TEXT,
+	sync_error_class TEXT,
+	next_retry_at DATETIME,
+	local_only INTEGER DEFAULT 0,
+	deleted INTEGER DEFAULT 0,
+	revision INTEGER DEFAULT 1,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	UNIQUE(user_id, context, date)
+);
+
+-- Deleted contexts kept for restore (mirrors Drive's _DELETED folder)
+CREATE TABLE IF NOT EXISTS context_trash (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	color TEXT NOT NULL,
+	template TEXT DEFAULT '',
+	local_only INTEGER DEFAULT 0,
+	created_at DATETIME,
+	deleted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	email TEXT NOT NULL,
+	name TEXT NOT NULL,
+	picture TEXT,
+	access_token TEXT NOT NULL,
+	refresh_token TEXT,
+	token_expiry DATETIME,
+	settings_theme TEXT DEFAULT 'dark',
+	settings_week_start INTEGER DEFAULT 0,
+	settings_timezone TEXT DEFAULT 'UTC',
+	settings_date_format TEXT DEFAULT 'DD-MM-YY',
+	settings_unique_context_mode INTEGER DEFAULT 0,
+	settings_show_breadcrumb INTEGER DEFAULT 1,
+	settings_show_markdown_editor INTEGER DEFAULT 0,
+	settings_hide_new_context_button INTEGER DEFAULT 0,
+	settings_suggest_context INTEGER DEFAULT 0,
+	expires_at DATETIME NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_used_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- OAuth tokens for storage providers other than the sign-in account (e.g. Dropbox)
+CREATE TABLE IF NOT EXISTS storage_tokens (
+	user_id TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	access_token TEXT NOT NULL,
+	refresh_token TEXT,
+	token_expiry DATETIME,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, provider),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Where the next pull of changes made in storage starts, per user
+CREATE TABLE IF NOT EXISTS change_tokens (
+	user_id TEXT PRIMARY KEY,
+	page_token TEXT NOT NULL,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS change_channels (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL UNIQUE,
+	resource_id TEXT NOT NULL,
+	token TEXT NOT NULL,
+	expires_at DATETIME NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Progress of the first import from storage, one row per context, so a restart resumes it
+CREATE TABLE IF NOT EXISTS import_checkpoints (
+	user_id TEXT NOT NULL,
+	context TEXT NOT NULL,
+	page_token TEXT NOT NULL DEFAULT '',
+	last_file TEXT NOT NULL DEFAULT '',
+	imported INTEGER NOT NULL DEFAULT 0,
+	done INTEGER NOT NULL DEFAULT 0,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, context),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Contexts published read-only at /@handle; see public.go
+CREATE TABLE IF NOT EXISTS public_contexts (
+	handle TEXT PRIMARY KEY,
+	context_id TEXT NOT NULL UNIQUE,
+	user_id TEXT NOT NULL,
+	indexable INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- External blogs users publish notes to, and the notes published there; see publishing.go
+CREATE TABLE IF NOT EXISTS publish_targets (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	name TEXT NOT NULL,
+	url TEXT NOT NULL DEFAULT '',
+	username TEXT NOT NULL DEFAULT '',
+	secret TEXT NOT NULL DEFAULT '',
+	repo TEXT NOT NULL DEFAULT '',
+	branch TEXT NOT NULL DEFAULT '',
+	dir TEXT NOT NULL DEFAULT '',
+	site_url TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS note_publications (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	note_id TEXT NOT NULL,
+	target_id TEXT NOT NULL,
+	status TEXT NOT NULL,
+	publish_at DATETIME NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	external_id TEXT NOT NULL DEFAULT '',
+	external_url TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	published_at DATETIME,
+	updated_at DATETIME NOT NULL,
+	UNIQUE(note_id, target_id),
+	FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+	FOREIGN KEY (target_id) REFERENCES publish_targets(id) ON DELETE CASCADE
+);
+
+-- Previews of web pages linked from notes, shared by all users; see links.go
+CREATE TABLE IF NOT EXISTS link_previews (
+	url TEXT PRIMARY KEY,
+	title TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	image_url TEXT NOT NULL DEFAULT '',
+	site_name TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	fetched_at DATETIME NOT NULL
+);
+
+-- Earlier contents of notes, written before each change; see revisions.go
+CREATE TABLE IF NOT EXISTS note_revisions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	note_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	revision INTEGER NOT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Storage versions of notes in sync conflict; see conflicts.go
+CREATE TABLE IF NOT EXISTS note_conflicts (
+	note_id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	remote_content TEXT NOT NULL,
+	remote_modified_at DATETIME NOT NULL,
+	detected_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_user_context ON notes(user_id, context);
+CREATE INDEX IF NOT EXISTS idx_notes_user_date ON notes(user_id, date);
+CREATE INDEX IF NOT EXISTS idx_notes_user_granularity ON notes(user_id, context, granularity, date);
+CREATE INDEX IF NOT EXISTS idx_notes_sync_pending ON notes(sync_pending) WHERE sync_pending = 1;
+CREATE INDEX IF NOT EXISTS idx_notes_sync_status ON notes(sync_status);
+CREATE INDEX IF NOT EXISTS idx_notes_user_size ON notes(user_id, content_size) WHERE deleted = 0;
+CREATE INDEX IF NOT EXISTS idx_note_revisions_note ON note_revisions(note_id, id);
+CREATE INDEX IF NOT EXISTS idx_contexts_user ON contexts(user_id);
+CREATE INDEX IF NOT EXISTS idx_note_publications_due ON note_publications(status, publish_at);
+CREATE INDEX IF NOT EXISTS idx_context_trash_user ON context_trash(user_id, deleted_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
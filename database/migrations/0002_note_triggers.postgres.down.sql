@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS notes_content_size ON notes;
+DROP TRIGGER IF EXISTS notes_purge ON notes;
+DROP FUNCTION IF EXISTS notes_content_size();
+DROP FUNCTION IF EXISTS notes_purge();
@@ -0,0 +1,26 @@
+-- content_size is the note's length in bytes, kept by a trigger for size stats
+UPDATE notes SET content_size = octet_length(COALESCE(content, '')) WHERE content_size IS NULL;
+
+CREATE OR REPLACE FUNCTION notes_content_size() RETURNS trigger AS $$
+BEGIN
+	NEW.content_size := octet_length(COALESCE(NEW.content, ''));
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notes_content_size ON notes;
+CREATE TRIGGER notes_content_size BEFORE INSERT OR UPDATE OF content ON notes
+	FOR EACH ROW EXECUTE FUNCTION notes_content_size();
+
+-- Revisions and conflicts go with their note when it is purged
+CREATE OR REPLACE FUNCTION notes_purge() RETURNS trigger AS $$
+BEGIN
+	DELETE FROM note_revisions WHERE note_id = OLD.id;
+	DELETE FROM note_conflicts WHERE note_id = OLD.id;
+	RETURN OLD;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notes_purge ON notes;
+CREATE TRIGGER notes_purge AFTER DELETE ON notes
+	FOR EACH ROW EXECUTE FUNCTION notes_purge();
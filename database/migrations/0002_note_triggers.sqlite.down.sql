@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS notes_content_size_insert;
+DROP TRIGGER IF EXISTS notes_content_size_update;
+DROP TRIGGER IF EXISTS notes_revisions_delete;
+DROP TRIGGER IF EXISTS notes_conflicts_delete;
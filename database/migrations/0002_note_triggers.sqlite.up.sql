@@ -0,0 +1,19 @@
+-- content_size is the note's length in bytes, kept by triggers for size stats
+UPDATE notes SET content_size = length(CAST(COALESCE(content, '') AS BLOB)) WHERE content_size IS NULL;
+
+CREATE TRIGGER IF NOT EXISTS notes_content_size_insert AFTER INSERT ON notes BEGIN
+	UPDATE notes SET content_size = length(CAST(COALESCE(new.content, '') AS BLOB)) WHERE rowid = new.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS notes_content_size_update AFTER UPDATE OF content ON notes BEGIN
+	UPDATE notes SET content_size = length(CAST(COALESCE(new.content, '') AS BLOB)) WHERE rowid = new.rowid;
+END;
+
+-- Revisions and conflicts go with their note when it is purged
+CREATE TRIGGER IF NOT EXISTS notes_revisions_delete AFTER DELETE ON notes BEGIN
+	DELETE FROM note_revisions WHERE note_id = old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS notes_conflicts_delete AFTER DELETE ON notes BEGIN
+	DELETE FROM note_conflicts WHERE note_id = old.id;
+END;
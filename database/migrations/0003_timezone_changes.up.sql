@@ -0,0 +1,13 @@
+-- Timezone setting changes, kept until the user reviews the notes dated around
+-- them in the timezone assistant; see timezones.go
+CREATE TABLE IF NOT EXISTS timezone_changes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id TEXT NOT NULL,
+	from_timezone TEXT NOT NULL,
+	to_timezone TEXT NOT NULL,
+	changed_at DATETIME NOT NULL,
+	reviewed_at DATETIME,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_timezone_changes_user ON timezone_changes(user_id, changed_at);
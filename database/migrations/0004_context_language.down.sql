@@ -0,0 +1,2 @@
+ALTER TABLE context_trash DROP COLUMN language;
+ALTER TABLE contexts DROP COLUMN language;
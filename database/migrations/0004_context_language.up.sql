@@ -0,0 +1,4 @@
+-- Language of a context's notes (ISO 639-1, e.g. es), used for transcription;
+-- empty means the server default
+ALTER TABLE contexts ADD COLUMN language TEXT DEFAULT '';
+ALTER TABLE context_trash ADD COLUMN language TEXT DEFAULT '';
@@ -0,0 +1,19 @@
+-- Changes to context folders in storage (renames, deletions) that failed, kept
+-- until the sync worker manages to apply them; see operations.go
+CREATE TABLE IF NOT EXISTS sync_operations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	context_id TEXT NOT NULL,
+	context_name TEXT NOT NULL,
+	new_name TEXT DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'failed',
+	retry_count INTEGER DEFAULT 0,
+	error TEXT,
+	error_class TEXT,
+	next_retry_at DATETIME,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_operations_user ON sync_operations(user_id, status);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS notes_changes ON notes;
+DROP FUNCTION IF EXISTS notes_changes();
+DROP TABLE IF EXISTS note_changes;
@@ -0,0 +1,54 @@
+-- The latest change of each note, numbered in the order they happened, so
+-- offline clients can fetch what changed since a cursor; see note_changes.go.
+-- A trigger keeps it, so every write to notes is seen. created_seq is the seq of
+-- the change that created the note, NULL when that is this change.
+CREATE TABLE IF NOT EXISTS note_changes (
+	seq BIGSERIAL PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	note_id TEXT NOT NULL,
+	context TEXT NOT NULL,
+	date TEXT NOT NULL,
+	deleted INTEGER NOT NULL DEFAULT 0,
+	created_seq BIGINT
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_changes_user ON note_changes(user_id, seq);
+CREATE INDEX IF NOT EXISTS idx_note_changes_note ON note_changes(note_id);
+
+-- Existing notes count as created, so a client starting from cursor 0 gets them all
+INSERT INTO note_changes (user_id, note_id, context, date)
+SELECT user_id, id, context, date FROM notes WHERE deleted = 0 ORDER BY updated_at, id;
+
+CREATE OR REPLACE FUNCTION notes_changes() RETURNS trigger AS $$
+DECLARE
+	created BIGINT;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM note_changes WHERE note_id = OLD.id;
+		-- Purged notes leave a deletion behind, unless their user is being deleted
+		IF EXISTS (SELECT 1 FROM users WHERE id = OLD.user_id) THEN
+			INSERT INTO note_changes (user_id, note_id, context, date, deleted)
+			VALUES (OLD.user_id, OLD.id, OLD.context, OLD.date, 1);
+		END IF;
+		RETURN OLD;
+	END IF;
+
+	IF TG_OP = 'UPDATE' THEN
+		-- Only changes clients see count, not sync bookkeeping
+		IF OLD.content IS NOT DISTINCT FROM NEW.content AND OLD.context = NEW.context AND OLD.date = NEW.date
+			AND OLD.granularity IS NOT DISTINCT FROM NEW.granularity AND OLD.deleted IS NOT DISTINCT FROM NEW.deleted THEN
+			RETURN NEW;
+		END IF;
+		SELECT COALESCE(created_seq, seq) INTO created FROM note_changes WHERE note_id = NEW.id ORDER BY seq DESC LIMIT 1;
+	END IF;
+
+	DELETE FROM note_changes WHERE note_id = NEW.id;
+	INSERT INTO note_changes (user_id, note_id, context, date, deleted, created_seq)
+	VALUES (NEW.user_id, NEW.id, NEW.context, NEW.date, NEW.deleted, created);
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS notes_changes ON notes;
+CREATE TRIGGER notes_changes AFTER INSERT OR UPDATE OR DELETE ON notes
+	FOR EACH ROW EXECUTE FUNCTION notes_changes();
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS notes_changes_insert;
+DROP TRIGGER IF EXISTS notes_changes_update;
+DROP TRIGGER IF EXISTS notes_changes_delete;
+DROP TABLE IF EXISTS note_changes;
@@ -0,0 +1,46 @@
+-- The latest change of each note, numbered in the order they happened, so
+-- offline clients can fetch what changed since a cursor; see note_changes.go.
+-- Triggers keep it, so every write to notes is seen. created_seq is the seq of
+-- the change that created the note, NULL when that is this change.
+CREATE TABLE IF NOT EXISTS note_changes (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id TEXT NOT NULL,
+	note_id TEXT NOT NULL,
+	context TEXT NOT NULL,
+	date TEXT NOT NULL,
+	deleted INTEGER NOT NULL DEFAULT 0,
+	created_seq INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_changes_user ON note_changes(user_id, seq);
+CREATE INDEX IF NOT EXISTS idx_note_changes_note ON note_changes(note_id);
+
+-- Existing notes count as created, so a client starting from cursor 0 gets them all
+INSERT INTO note_changes (user_id, note_id, context, date)
+SELECT user_id, id, context, date FROM notes WHERE deleted = 0 ORDER BY updated_at, id;
+
+CREATE TRIGGER IF NOT EXISTS notes_changes_insert AFTER INSERT ON notes BEGIN
+	DELETE FROM note_changes WHERE note_id = new.id;
+	INSERT INTO note_changes (user_id, note_id, context, date, deleted)
+	VALUES (new.user_id, new.id, new.context, new.date, new.deleted);
+END;
+
+-- Only changes clients see count, not sync bookkeeping
+CREATE TRIGGER IF NOT EXISTS notes_changes_update AFTER UPDATE ON notes
+WHEN old.content IS NOT new.content OR old.context IS NOT new.context OR old.date IS NOT new.date
+	OR old.granularity IS NOT new.granularity OR old.deleted IS NOT new.deleted
+BEGIN
+	INSERT INTO note_changes (user_id, note_id, context, date, deleted, created_seq)
+	VALUES (new.user_id, new.id, new.context, new.date, new.deleted,
+		(SELECT COALESCE(created_seq, seq) FROM note_changes WHERE note_id = new.id ORDER BY seq DESC LIMIT 1));
+	DELETE FROM note_changes
+	WHERE note_id = new.id AND seq < (SELECT MAX(seq) FROM note_changes WHERE note_id = new.id);
+END;
+
+-- Purged notes leave a deletion behind, unless their user is being deleted
+CREATE TRIGGER IF NOT EXISTS notes_changes_delete AFTER DELETE ON notes BEGIN
+	DELETE FROM note_changes WHERE note_id = old.id;
+	INSERT INTO note_changes (user_id, note_id, context, date, deleted)
+	SELECT old.user_id, old.id, old.context, old.date, 1
+	WHERE EXISTS (SELECT 1 FROM users WHERE id = old.user_id);
+END;
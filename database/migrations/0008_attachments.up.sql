@@ -0,0 +1,17 @@
+-- Files attached to notes (images, PDFs), kept in ATTACHMENTS_DIR and copied to
+-- the _attachments folder of the note's context in storage; see attachments.go
+CREATE TABLE IF NOT EXISTS attachments (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	context TEXT NOT NULL,
+	date TEXT NOT NULL,
+	filename TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	storage_status TEXT NOT NULL DEFAULT 'pending',
+	storage_error TEXT,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_note ON attachments(user_id, context, date);
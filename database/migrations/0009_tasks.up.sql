@@ -0,0 +1,14 @@
+-- Checkbox list items of notes ("- [ ] Call Ana"), parsed from the content on
+-- every save like tags; see tasks.go. Existing notes are parsed by backfillTasks.
+CREATE TABLE IF NOT EXISTS tasks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id TEXT NOT NULL,
+	note_id TEXT NOT NULL,
+	line INTEGER NOT NULL,
+	text TEXT NOT NULL,
+	done INTEGER NOT NULL DEFAULT 0,
+	UNIQUE(note_id, line),
+	FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user ON tasks(user_id, done);
@@ -0,0 +1,9 @@
+-- The local time at which the server creates each user's daily notes from their
+-- context templates, in the user's timezone setting; see note_schedules.go.
+-- last_run_date is the user's local date of the latest run.
+CREATE TABLE IF NOT EXISTS note_schedules (
+	user_id TEXT PRIMARY KEY,
+	local_time TEXT NOT NULL,
+	last_run_date TEXT,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
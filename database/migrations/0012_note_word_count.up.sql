@@ -0,0 +1,3 @@
+-- Words of a note's content, counted on every save like tags so activity stats
+-- can sum them in SQL; see stats.go. Existing notes are counted by backfillWordCounts.
+ALTER TABLE notes ADD COLUMN word_count INTEGER DEFAULT 0;
@@ -0,0 +1,10 @@
+-- Nonces of the signed drop box URLs that have been used, so each URL appends
+-- to its note once; see drops.go. Rows are pruned once their URL expires.
+CREATE TABLE IF NOT EXISTS drop_nonces (
+	nonce TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	expires_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_drop_nonces_expires ON drop_nonces(expires_at);
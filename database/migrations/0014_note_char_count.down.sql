@@ -0,0 +1 @@
+ALTER TABLE notes DROP COLUMN char_count;
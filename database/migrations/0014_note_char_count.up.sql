@@ -0,0 +1,3 @@
+-- Characters of a note's content, counted on every save next to word_count for
+-- writing stats; see stats.go. Existing notes are counted by backfillCharCounts.
+ALTER TABLE notes ADD COLUMN char_count INTEGER DEFAULT 0;
@@ -0,0 +1,10 @@
+-- Users who get the weekly email digest of their notes; see digests.go. token
+-- is the secret of the unsubscribe link in each email, and last_week the ISO
+-- week (2025-W42) of the latest digest queued for the user.
+CREATE TABLE IF NOT EXISTS digest_subscriptions (
+	user_id TEXT PRIMARY KEY,
+	token TEXT NOT NULL UNIQUE,
+	last_week TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,22 @@
+-- Reminders at a local time in the user's timezone setting, written in notes as
+-- @remind(2025-11-02 09:00) or made through the API; see reminders.go. Reminders
+-- of notes are parsed on every save like tasks, keyed by their time and text so
+-- edits elsewhere in the note don't send them again; API reminders have no note.
+-- status is pending until the reminder is sent, or missed when it came due
+-- while reminders couldn't be delivered.
+CREATE TABLE IF NOT EXISTS reminders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id TEXT NOT NULL,
+	note_id TEXT,
+	line INTEGER NOT NULL DEFAULT 0,
+	text TEXT NOT NULL,
+	remind_at TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	sent_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(note_id, remind_at, text),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_due ON reminders(status, remind_at);
@@ -0,0 +1,14 @@
+-- Browsers that receive web push notifications for a user; see push.go. endpoint
+-- is the push service URL of one browser, p256dh and auth the keys messages to it
+-- are encrypted with (base64url, as PushManager.subscribe() returns them).
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id TEXT NOT NULL,
+	endpoint TEXT NOT NULL UNIQUE,
+	p256dh TEXT NOT NULL,
+	auth TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions(user_id);
@@ -0,0 +1,14 @@
+-- Secret links showing one note read-only without signing in (/s/<token>); see
+-- shares.go. expires_at is empty for links that don't expire.
+CREATE TABLE IF NOT EXISTS shares (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	token TEXT NOT NULL UNIQUE,
+	user_id TEXT NOT NULL,
+	note_id TEXT NOT NULL,
+	expires_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shares_user ON shares(user_id);
@@ -0,0 +1,19 @@
+-- Accounts a context is shared with; see members.go. Members are invited by
+-- email (lowercase) and join when the account with that email accepts, so user_id
+-- and accepted_at are empty until then. context_id has no foreign key: a
+-- context in the trash keeps its members for when it is restored, and access
+-- checks join contexts.
+CREATE TABLE IF NOT EXISTS context_members (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	context_id TEXT NOT NULL,
+	email TEXT NOT NULL,
+	user_id TEXT,
+	role TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	accepted_at DATETIME,
+	UNIQUE (context_id, email),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_context_members_user ON context_members(user_id);
+CREATE INDEX IF NOT EXISTS idx_context_members_email ON context_members(email);
@@ -0,0 +1,13 @@
+-- How the clients of users with end-to-end encrypted notes derive the key from
+-- their passphrase; see encryption.go. Only a hash of a verifier derived from
+-- the key is kept, never the key.
+CREATE TABLE IF NOT EXISTS encryption_keys (
+	user_id TEXT PRIMARY KEY,
+	cipher TEXT NOT NULL,
+	kdf TEXT NOT NULL,
+	iterations INTEGER NOT NULL,
+	salt TEXT NOT NULL,
+	verifier_hash TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
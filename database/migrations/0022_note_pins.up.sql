@@ -0,0 +1,3 @@
+-- When a note was pinned to the top of its context, NULL while it isn't; see
+-- pins.go. Pinned notes are listed most recently pinned first.
+ALTER TABLE notes ADD COLUMN pinned_at DATETIME;
@@ -0,0 +1 @@
+ALTER TABLE contexts DROP COLUMN sort_order;
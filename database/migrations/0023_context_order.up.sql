@@ -0,0 +1,4 @@
+-- Position of a context in the user's list, set by PATCH /api/contexts/reorder;
+-- see contexts.go. Contexts with the same position keep their creation order,
+-- so lists from before this migration don't change.
+ALTER TABLE contexts ADD COLUMN sort_order INTEGER NOT NULL DEFAULT 0;
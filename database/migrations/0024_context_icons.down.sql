@@ -0,0 +1,2 @@
+ALTER TABLE context_trash DROP COLUMN icon;
+ALTER TABLE contexts DROP COLUMN icon;
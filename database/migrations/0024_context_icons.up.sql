@@ -0,0 +1,4 @@
+-- An emoji or icon name shown next to a context's name, besides its color; see
+-- contexts.go. Kept in the trash too, so restored contexts get theirs back.
+ALTER TABLE contexts ADD COLUMN icon TEXT DEFAULT '';
+ALTER TABLE context_trash ADD COLUMN icon TEXT DEFAULT '';
@@ -0,0 +1,14 @@
+-- Deleted notes kept for restoring until the trash window passes; see
+-- note_trash.go. The notes row itself still goes once storage is in step.
+CREATE TABLE IF NOT EXISTS note_trash (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id TEXT NOT NULL,
+	context TEXT NOT NULL,
+	date TEXT NOT NULL,
+	content TEXT NOT NULL,
+	local_only INTEGER DEFAULT 0,
+	deleted_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_trash_user ON note_trash(user_id, deleted_at);
@@ -0,0 +1,17 @@
+-- Responses of writes retried with the same X-Idempotency-Key, per user; see
+-- idempotency.go. Kept in the database so every instance replays them.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	user_id TEXT NOT NULL,
+	method TEXT NOT NULL,
+	path TEXT NOT NULL,
+	idempotency_key TEXT NOT NULL,
+	request_hash TEXT NOT NULL,
+	status INTEGER NOT NULL,
+	content_type TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (user_id, method, path, idempotency_key),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
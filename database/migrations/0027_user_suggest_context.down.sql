@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN settings_suggest_context;
@@ -0,0 +1,10 @@
+-- The context suggestion opt-in was only kept with sessions, so requests made
+-- with API keys or tokens couldn't see it; see users.go. Users keep the choice
+-- of their latest session.
+ALTER TABLE users ADD COLUMN settings_suggest_context INTEGER DEFAULT 0;
+UPDATE users SET settings_suggest_context = COALESCE((
+	SELECT s.settings_suggest_context FROM sessions s
+	WHERE s.user_id = users.id
+	ORDER BY s.last_used_at DESC
+	LIMIT 1
+), 0);
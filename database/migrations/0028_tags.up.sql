@@ -0,0 +1,22 @@
+-- Each user's tags and the notes they appear in, parsed from #hashtags on every
+-- save; see tags.go. note_tags rows go away with their note, and tags without
+-- live notes are left out of listings. The tables used to be created outside
+-- the numbered migrations, so they may exist already; backfillTags parses the
+-- existing notes again either way.
+CREATE TABLE IF NOT EXISTS tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	UNIQUE(user_id, name),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS note_tags (
+	note_id TEXT NOT NULL,
+	tag_id INTEGER NOT NULL,
+	PRIMARY KEY (note_id, tag_id),
+	FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+	FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_tags_tag ON note_tags(tag_id);
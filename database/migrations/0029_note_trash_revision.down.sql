@@ -0,0 +1,2 @@
+ALTER TABLE note_trash DROP COLUMN granularity;
+ALTER TABLE note_trash DROP COLUMN revision;
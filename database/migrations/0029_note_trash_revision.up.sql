@@ -0,0 +1,5 @@
+-- Trashed notes keep their revision and granularity, so a restored note carries
+-- on from the revision clients last saw; see note_trash.go. Entries from before
+-- restore at revision 1, their granularity read from the date.
+ALTER TABLE note_trash ADD COLUMN revision INTEGER DEFAULT 0;
+ALTER TABLE note_trash ADD COLUMN granularity TEXT DEFAULT '';
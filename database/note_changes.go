@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/markdown"
+	"database/sql"
+)
+
+// ==================== NOTE CHANGES ====================
+
+// GetNoteChanges returns up to limit of a user's note changes after the cursor
+// since, oldest first, with the notes as they are now. note_changes holds only the
+// latest change of each note, kept by the triggers of migration 0007, so a note
+// changed many times since the cursor is returned once. A note created after
+// since is NoteCreated even if it changed again later.
+func (r *Repository) GetNoteChanges(ctx context.Context, userID string, since int64, limit int) ([]models.NoteChange, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT nc.seq, nc.note_id, nc.context, nc.date, nc.deleted,
+		       CASE WHEN nc.created_seq IS NULL OR nc.created_seq > ? THEN 1 ELSE 0 END,
+		       notes.id, notes.granularity, notes.content, notes.revision, `+localOnlyCondition+`,
+		       notes.created_at, notes.updated_at
+		FROM note_changes nc
+		LEFT JOIN notes ON notes.id = nc.note_id AND nc.deleted = 0 AND notes.deleted = 0
+		WHERE nc.user_id = ? AND nc.seq > ?
+		ORDER BY nc.seq ASC
+		LIMIT ?
+	`, since, userID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.NoteChange{}
+	for rows.Next() {
+		var change models.NoteChange
+		var deleted, created bool
+		var noteID, granularity, content sql.NullString
+		var revision sql.NullInt64
+		var localOnly sql.NullBool
+		var createdAt, updatedAt sql.NullTime
+		if err := rows.Scan(&change.Cursor, &change.NoteID, &change.Context, &change.Date, &deleted, &created,
+			&noteID, &granularity, &content, &revision, &localOnly, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case deleted || !noteID.Valid:
+			change.Kind = models.NoteDeleted
+		case created:
+			change.Kind = models.NoteCreated
+		default:
+			change.Kind = models.NoteUpdated
+		}
+		if change.Kind != models.NoteDeleted {
+			change.Note = &models.Note{
+				ID:        noteID.String,
+				UserID:    userID,
+				Context:   change.Context,
+				Date:      change.Date,
+				Type:      granularity.String,
+				Content:   content.String,
+				Tags:      markdown.ExtractHashtags(content.String),
+				Revision:  int(revision.Int64),
+				LocalOnly: localOnly.Bool,
+				CreatedAt: createdAt.Time,
+				UpdatedAt: updatedAt.Time,
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteChanges(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	save := func(contextName, date, content string) {
+		t.Helper()
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: contextName, Date: date, Content: content, CreatedAt: now, UpdatedAt: now,
+		}, true))
+	}
+	changes := func(since int64) []models.NoteChange {
+		t.Helper()
+		changes, err := repo.GetNoteChanges(ctx, "test-user", since, 10)
+		require.NoError(t, err)
+		return changes
+	}
+
+	save("Work", "2025-10-15", "first")
+	save("Work", "2025-10-16", "second")
+	initial := changes(0)
+	require.Len(t, initial, 2)
+	cursor := initial[1].Cursor
+
+	t.Run("New notes are created with their content", func(t *testing.T) {
+		assert.Equal(t, models.NoteCreated, initial[0].Kind)
+		assert.Equal(t, "2025-10-15", initial[0].Date)
+		require.NotNil(t, initial[0].Note)
+		assert.Equal(t, "first", initial[0].Note.Content)
+		assert.Equal(t, 1, initial[0].Note.Revision)
+	})
+
+	t.Run("Each note is listed once, after its latest change", func(t *testing.T) {
+		save("Work", "2025-10-15", "first, edited")
+		save("Work", "2025-10-15", "first, edited twice")
+		got := changes(cursor)
+		require.Len(t, got, 1)
+		assert.Equal(t, models.NoteUpdated, got[0].Kind)
+		assert.Equal(t, "first, edited twice", got[0].Note.Content)
+		assert.Greater(t, got[0].Cursor, cursor)
+
+		// From before its creation, the note is still new
+		all := changes(0)
+		require.Len(t, all, 2)
+		assert.Equal(t, "2025-10-16", all[0].Date)
+		assert.Equal(t, models.NoteCreated, all[1].Kind)
+		cursor = got[0].Cursor
+	})
+
+	t.Run("Sync bookkeeping isn't a change", func(t *testing.T) {
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NoError(t, repo.MarkNoteSynced(ctx, note.ID, "file-1", "hash"))
+		assert.Empty(t, changes(cursor))
+	})
+
+	t.Run("Deleted and purged notes are deleted", func(t *testing.T) {
+		require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16"))
+		got := changes(cursor)
+		require.Len(t, got, 1)
+		assert.Equal(t, models.NoteDeleted, got[0].Kind)
+		assert.Equal(t, "2025-10-16", got[0].Date)
+		assert.Nil(t, got[0].Note)
+
+		require.NoError(t, repo.HardDeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16"))
+		got = changes(cursor)
+		require.Len(t, got, 1)
+		assert.Equal(t, models.NoteDeleted, got[0].Kind)
+	})
+
+	t.Run("Other users' changes aren't listed", func(t *testing.T) {
+		other, err := repo.GetNoteChanges(ctx, "other-user", 0, 10)
+		require.NoError(t, err)
+		assert.Empty(t, other)
+	})
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+)
+
+// ==================== NOTE SCHEDULES ====================
+
+// noteScheduleQuery selects the columns scanned by scanNoteSchedule, with the
+// timezone of the schedule's user
+const noteScheduleQuery = `
+	SELECT s.user_id, s.local_time, COALESCE(u.settings_timezone, 'UTC'), COALESCE(s.last_run_date, '')
+	FROM note_schedules s
+	JOIN users u ON u.id = s.user_id`
+
+// GetNoteSchedule returns the schedule of a user, nil if they have none
+func (r *Repository) GetNoteSchedule(ctx context.Context, userID string) (*models.NoteSchedule, error) {
+	schedule, err := scanNoteSchedule(r.db.QueryRowContext(ctx, noteScheduleQuery+` WHERE s.user_id = ?`, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return schedule, err
+}
+
+// GetNoteSchedules returns the schedules of all users
+func (r *Repository) GetNoteSchedules(ctx context.Context) ([]models.NoteSchedule, error) {
+	rows, err := r.db.QueryContext(ctx, noteScheduleQuery+` ORDER BY s.user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []models.NoteSchedule
+	for rows.Next() {
+		schedule, err := scanNoteSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *schedule)
+	}
+	return schedules, rows.Err()
+}
+
+// SetNoteSchedule sets the local time of a user's schedule, keeping the date of
+// its latest run so a new time doesn't run the schedule twice in a day
+func (r *Repository) SetNoteSchedule(ctx context.Context, userID, localTime string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO note_schedules (user_id, local_time) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET local_time = excluded.local_time
+	`, userID, localTime)
+	return err
+}
+
+// SetNoteScheduleRun records the local date a user's schedule last ran
+func (r *Repository) SetNoteScheduleRun(ctx context.Context, userID, date string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE note_schedules SET last_run_date = ? WHERE user_id = ?`, date, userID)
+	return err
+}
+
+// DeleteNoteSchedule turns a user's schedule off; it reports false if there was none
+func (r *Repository) DeleteNoteSchedule(ctx context.Context, userID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM note_schedules WHERE user_id = ?`, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// scanNoteSchedule reads a row selected by noteScheduleQuery
+func scanNoteSchedule(row interface{ Scan(...any) error }) (*models.NoteSchedule, error) {
+	var s models.NoteSchedule
+	if err := row.Scan(&s.UserID, &s.Time, &s.Timezone, &s.LastRun); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteSchedules(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	schedule, err := repo.GetNoteSchedule(ctx, "test-user")
+	require.NoError(t, err)
+	assert.Nil(t, schedule)
+
+	require.NoError(t, repo.UpdateUserSettings(ctx, "test-user", models.UserSettings{Theme: "dark", Timezone: "Europe/Madrid", DateFormat: "DD-MM-YY"}))
+	require.NoError(t, repo.SetNoteSchedule(ctx, "test-user", "07:30"))
+
+	t.Run("Schedules carry their user's timezone", func(t *testing.T) {
+		schedules, err := repo.GetNoteSchedules(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []models.NoteSchedule{{UserID: "test-user", Time: "07:30", Timezone: "Europe/Madrid"}}, schedules)
+	})
+
+	t.Run("A new time keeps the latest run", func(t *testing.T) {
+		require.NoError(t, repo.SetNoteScheduleRun(ctx, "test-user", "2025-10-16"))
+		require.NoError(t, repo.SetNoteSchedule(ctx, "test-user", "09:00"))
+		schedule, err := repo.GetNoteSchedule(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, "09:00", schedule.Time)
+		assert.Equal(t, "2025-10-16", schedule.LastRun)
+	})
+
+	t.Run("Deleting turns the schedule off", func(t *testing.T) {
+		deleted, err := repo.DeleteNoteSchedule(ctx, "test-user")
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		deleted, err = repo.DeleteNoteSchedule(ctx, "test-user")
+		require.NoError(t, err)
+		assert.False(t, deleted)
+	})
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ==================== NOTE TRASH ====================
+
+// TrashNote deletes a note like DeleteNote, first keeping its content, revision
+// and granularity in the trash so it can be restored. Empty notes are deleted
+// without a trash entry.
+func (r *Repository) TrashNote(ctx context.Context, scope UserScope, contextName, date string, deletedAt time.Time) error {
+	if err := scope.check(); err != nil {
+		return err
+	}
+	userID := scope.userID
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := trashNote(ctx, tx, userID, contextName, date, deletedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// trashNote is the write behind TrashNote, and behind the originals of moved
+// and redated notes; a zero deletedAt deletes the note without a trash entry
+func trashNote(ctx context.Context, tx *Tx, userID, contextName, date string, deletedAt time.Time) error {
+	if !deletedAt.IsZero() {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO note_trash (user_id, context, date, granularity, content, revision, local_only, deleted_at)
+			SELECT user_id, context, date, granularity, content, revision, local_only, ?
+			FROM notes
+			WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0 AND TRIM(COALESCE(content, '')) != ''
+		`, deletedAt, userID, contextName, date); err != nil {
+			return err
+		}
+	}
+	return deleteNote(ctx, tx, userID, contextName, date)
+}
+
+// GetTrashedNotes retrieves the user's notes deleted after since, most recently
+// deleted first
+func (r *Repository) GetTrashedNotes(ctx context.Context, scope UserScope, since time.Time) ([]models.TrashedNote, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, context, date, granularity, content, revision, local_only, deleted_at
+		FROM note_trash
+		WHERE user_id = ? AND deleted_at > ?
+		ORDER BY deleted_at DESC, id DESC
+	`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trashed := make([]models.TrashedNote, 0)
+	for rows.Next() {
+		var note models.TrashedNote
+		if err := rows.Scan(&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type, &note.Content, &note.Revision, &note.LocalOnly, &note.DeletedAt); err != nil {
+			return nil, err
+		}
+		trashed = append(trashed, note)
+	}
+
+	return trashed, rows.Err()
+}
+
+// GetTrashedNote retrieves a single deleted note of a user
+func (r *Repository) GetTrashedNote(ctx context.Context, scope UserScope, id int64) (*models.TrashedNote, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	var note models.TrashedNote
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, context, date, granularity, content, revision, local_only, deleted_at
+		FROM note_trash
+		WHERE user_id = ? AND id = ?
+	`, userID, id).Scan(&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type, &note.Content, &note.Revision, &note.LocalOnly, &note.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// RestoreTrashedNote saves note, the content of trash entry id, as a live note
+// queued for sync and removes the entry. The note takes the revision after the
+// one it was deleted at (note.Revision), so clients that saw it before see the
+// change. A deleted row still waiting for its storage deletion gives way to it.
+// Returns false, changing nothing, when a live note has taken the day in the meantime.
+func (r *Repository) RestoreTrashedNote(ctx context.Context, id int64, note *models.Note) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var live int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notes
+		WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0
+	`, note.UserID, note.Context, note.Date).Scan(&live); err != nil {
+		return false, err
+	}
+	if live > 0 {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM notes
+		WHERE user_id = ? AND context = ? AND date = ? AND deleted = 1
+	`, note.UserID, note.Context, note.Date); err != nil {
+		return false, err
+	}
+	revision := note.Revision + 1
+	if err := upsertNote(ctx, tx, note, true); err != nil {
+		return false, err
+	}
+	if revision > note.Revision {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE notes SET revision = ? WHERE id = ?
+		`, revision, note.ID); err != nil {
+			return false, err
+		}
+		note.Revision = revision
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM note_trash WHERE user_id = ? AND id = ?
+	`, note.UserID, id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// PurgeNoteTrash permanently removes the user's notes deleted before before
+// and returns how many it removed
+func (r *Repository) PurgeNoteTrash(ctx context.Context, scope UserScope, before time.Time) (int64, error) {
+	if err := scope.check(); err != nil {
+		return 0, err
+	}
+	userID := scope.userID
+
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM note_trash WHERE user_id = ? AND deleted_at <= ?
+	`, userID, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
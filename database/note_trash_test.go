@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteTrash(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	deletedAt := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-work", UserID: "test-user", Name: "Work"}))
+	save := func(date, content string) {
+		note := &models.Note{UserID: "test-user", Context: "Work", Date: date, Content: content, CreatedAt: deletedAt, UpdatedAt: deletedAt}
+		require.NoError(t, repo.UpsertNote(ctx, note, true))
+	}
+	save("2025-10-16", "Shipped it")
+	save("2025-10-17", "  ")
+	require.NoError(t, repo.TrashNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16", deletedAt))
+	require.NoError(t, repo.TrashNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17", deletedAt))
+
+	trashed, err := repo.GetTrashedNotes(ctx, ScopeUser("test-user"), deletedAt.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, trashed, 1, "empty notes aren't kept")
+	assert.Equal(t, "Shipped it", trashed[0].Content)
+	note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+	require.NoError(t, err)
+	assert.Nil(t, note, "trashed notes are deleted")
+
+	t.Run("Restores over a deletion waiting for storage", func(t *testing.T) {
+		entry, err := repo.GetTrashedNote(ctx, ScopeUser("test-user"), trashed[0].ID)
+		require.NoError(t, err)
+		require.NotNil(t, entry)
+
+		restored, err := repo.RestoreTrashedNote(ctx, entry.ID, &models.Note{
+			UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: entry.Content, CreatedAt: deletedAt, UpdatedAt: deletedAt,
+		})
+		require.NoError(t, err)
+		assert.True(t, restored)
+
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, note)
+		assert.Equal(t, "Shipped it", note.Content)
+		assert.Equal(t, models.SyncStatusPending, note.SyncStatus)
+
+		entry, err = repo.GetTrashedNote(ctx, ScopeUser("test-user"), entry.ID)
+		require.NoError(t, err)
+		assert.Nil(t, entry)
+
+		// The storage deletion that was in flight doesn't take the restored note
+		require.NoError(t, repo.HardDeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16"))
+		note, err = repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.NotNil(t, note)
+	})
+
+	t.Run("Days written again since stay", func(t *testing.T) {
+		require.NoError(t, repo.TrashNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16", deletedAt))
+		require.NoError(t, repo.HardDeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16"))
+		save("2025-10-16", "Written again")
+		trashed, err := repo.GetTrashedNotes(ctx, ScopeUser("test-user"), deletedAt.Add(-time.Hour))
+		require.NoError(t, err)
+		require.Len(t, trashed, 1)
+
+		restored, err := repo.RestoreTrashedNote(ctx, trashed[0].ID, &models.Note{
+			UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: trashed[0].Content, CreatedAt: deletedAt, UpdatedAt: deletedAt,
+		})
+		require.NoError(t, err)
+		assert.False(t, restored)
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, "Written again", note.Content)
+	})
+
+	t.Run("Restored notes carry on from their revision and granularity", func(t *testing.T) {
+		save("2025-W42", "Week plan")
+		save("2025-W42", "Week plan, revised")
+		require.NoError(t, repo.TrashNote(ctx, ScopeUser("test-user"), "Work", "2025-W42", deletedAt))
+		entry := trashedAt(t, repo, "2025-W42")
+		assert.Equal(t, "week", entry.Type)
+		assert.Equal(t, 2, entry.Revision)
+
+		note := &models.Note{
+			UserID: "test-user", Context: "Work", Date: entry.Date, Type: entry.Type, Content: entry.Content,
+			Revision: entry.Revision, CreatedAt: deletedAt, UpdatedAt: deletedAt,
+		}
+		restored, err := repo.RestoreTrashedNote(ctx, entry.ID, note)
+		require.NoError(t, err)
+		require.True(t, restored)
+		assert.Equal(t, 3, note.Revision)
+
+		saved, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-W42")
+		require.NoError(t, err)
+		assert.Equal(t, 3, saved.Revision, "clients holding revision 2 see the restore")
+		assert.Equal(t, "week", saved.Type)
+	})
+
+	t.Run("Moved and redated notes leave their originals in the trash", func(t *testing.T) {
+		require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-archive", UserID: "test-user", Name: "Archive"}))
+		save("2025-10-20", "Moving out")
+		save("2025-10-21", "Off by a day")
+		_, err := repo.TransferNotes(ctx, ScopeUser("test-user"), "Work", "Archive", []string{"2025-10-20"}, true, deletedAt)
+		require.NoError(t, err)
+		_, err = repo.RedateNotes(ctx, ScopeUser("test-user"), "Work", []string{"2025-10-21"}, 1, deletedAt)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Moving out", trashedAt(t, repo, "2025-10-20").Content)
+		assert.Equal(t, "Off by a day", trashedAt(t, repo, "2025-10-21").Content)
+	})
+
+	t.Run("Purges notes past the window", func(t *testing.T) {
+		purged, err := repo.PurgeNoteTrash(ctx, ScopeUser("test-user"), deletedAt.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, purged)
+
+		purged, err = repo.PurgeNoteTrash(ctx, ScopeUser("test-user"), deletedAt)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, purged)
+		trashed, err := repo.GetTrashedNotes(ctx, ScopeUser("test-user"), time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, trashed)
+	})
+}
+
+// trashedAt returns the newest trash entry of the Work note at date
+func trashedAt(t *testing.T, repo *Repository, date string) models.TrashedNote {
+	t.Helper()
+	trashed, err := repo.GetTrashedNotes(context.Background(), ScopeUser("test-user"), time.Time{})
+	require.NoError(t, err)
+	for _, entry := range trashed {
+		if entry.Context == "Work" && entry.Date == date {
+			return entry
+		}
+	}
+	t.Fatalf("no trash entry for %s", date)
+	return models.TrashedNote{}
+}
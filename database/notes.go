@@ -1,40 +1,57 @@
 package database
 
 import (
+	"context"
 	"daily-notes/models"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/period"
+	"daily-notes/pkg/visibility"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 )
 
 // ==================== NOTE OPERATIONS ====================
 
 // GetNote retrieves a single note by user, context, and date
-func (r *Repository) GetNote(userID, context, date string) (*models.Note, error) {
+func (r *Repository) GetNote(ctx context.Context, scope UserScope, contextName, date string) (*models.Note, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
 	var note models.Note
 	var syncStatus string
 	var syncLastAttemptAt sql.NullTime
 	var syncError sql.NullString
 
-	err := r.db.QueryRow(`
-		SELECT id, user_id, context, date, content, drive_file_id,
-		       sync_status, sync_retry_count, sync_last_attempt_at, sync_error,
-		       created_at, updated_at
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, context, date, granularity, content, drive_file_id, revision,
+		       (SELECT COUNT(*) FROM note_revisions r WHERE r.note_id = notes.id),
+		       sync_status, sync_retry_count, sync_last_attempt_at, sync_error, `+localOnlyCondition+`,
+		       pinned_at IS NOT NULL, word_count, char_count, created_at, updated_at
 		FROM notes
 		WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0
-	`, userID, context, date).Scan(
-		&note.ID, &note.UserID, &note.Context, &note.Date,
-		&note.Content, &note.ID,
-		&syncStatus, &note.SyncRetryCount, &syncLastAttemptAt, &syncError,
-		&note.CreatedAt, &note.UpdatedAt,
+	`, userID, contextName, date).Scan(
+		&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type,
+		&note.Content, &note.ID, &note.Revision, &note.RevisionCount,
+		&syncStatus, &note.SyncRetryCount, &syncLastAttemptAt, &syncError, &note.LocalOnly,
+		&note.Pinned, &note.WordCount, &note.CharCount, &note.CreatedAt, &note.UpdatedAt,
 	)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
 
+	note.Tags = markdown.ExtractHashtags(note.Content)
+	note.Encrypted = e2ee.IsEncrypted(note.Content)
 	note.SyncStatus = models.SyncStatus(syncStatus)
 	if syncLastAttemptAt.Valid {
 		note.SyncLastAttemptAt = &syncLastAttemptAt.Time
@@ -47,47 +64,255 @@ func (r *Repository) GetNote(userID, context, date string) (*models.Note, error)
 }
 
 // UpsertNote creates or updates a note
-// markForSync: if true, marks the note as pending sync
-func (r *Repository) UpsertNote(note *models.Note, markForSync bool) error {
-	syncPending := 0
-	syncStatus := string(models.SyncStatusSynced)
-	if markForSync {
-		syncPending = 1
-		syncStatus = string(models.SyncStatusPending)
+// markForSync: if true, marks the note as pending sync, unless the note or its
+// context is local only (see local_only.go); a note.LocalOnly of true marks it so
+// The stored revision is bumped on every update and written back to note.Revision;
+// changed content is kept in the revision history first
+func (r *Repository) UpsertNote(ctx context.Context, note *models.Note, markForSync bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := upsertNote(ctx, tx, note, markForSync); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpsertNotes saves several notes like UpsertNote, in a single transaction:
+// either every note is saved or none is
+func (r *Repository) UpsertNotes(ctx context.Context, notes []*models.Note, markForSync bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, note := range notes {
+		if err := upsertNote(ctx, tx, note, markForSync); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// upsertNote is the write behind UpsertNote and UpsertNotes
+func upsertNote(ctx context.Context, tx *Tx, note *models.Note, markForSync bool) error {
+	syncPending, syncStatus, err := saveSyncState(ctx, tx, note, markForSync)
+	if err != nil {
+		return err
 	}
 
 	id := fmt.Sprintf("%s-%s-%s", note.UserID, note.Context, note.Date)
 	if note.ID == "" {
 		note.ID = id
 	}
+	setGranularity(note)
 
-	_, err := r.db.Exec(`
-		INSERT INTO notes (id, user_id, context, date, content, drive_file_id,
-			sync_pending, sync_status, sync_retry_count, deleted, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, 0, ?, ?)
+	if err := saveRevision(ctx, tx, note, 0); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO notes (id, user_id, context, date, granularity, content, drive_file_id,
+			sync_pending, sync_status, sync_retry_count, deleted, revision, local_only, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0, 1, ?, ?, ?)
 		ON CONFLICT(user_id, context, date) DO UPDATE SET
 			content = CASE WHEN notes.deleted = 0 THEN excluded.content ELSE notes.content END,
+			local_only = CASE WHEN notes.deleted = 0 AND excluded.local_only = 1 THEN 1 ELSE notes.local_only END,
 			sync_pending = CASE WHEN notes.deleted = 0 THEN excluded.sync_pending ELSE notes.sync_pending END,
 			sync_status = CASE WHEN notes.deleted = 0 THEN excluded.sync_status ELSE notes.sync_status END,
 			sync_retry_count = CASE WHEN notes.deleted = 0 THEN 0 ELSE notes.sync_retry_count END,
 			sync_error = CASE WHEN notes.deleted = 0 THEN NULL ELSE notes.sync_error END,
+			sync_error_class = CASE WHEN notes.deleted = 0 THEN NULL ELSE notes.sync_error_class END,
+			next_retry_at = CASE WHEN notes.deleted = 0 THEN NULL ELSE notes.next_retry_at END,
+			revision = CASE WHEN notes.deleted = 0 THEN notes.revision + 1 ELSE notes.revision END,
 			updated_at = CASE WHEN notes.deleted = 0 THEN excluded.updated_at ELSE notes.updated_at END
+		RETURNING revision
 	`,
-		id, note.UserID, note.Context, note.Date, note.Content,
-		note.ID, syncPending, syncStatus, note.CreatedAt, note.UpdatedAt,
-	)
-	return err
+		id, note.UserID, note.Context, note.Date, note.Type, note.Content,
+		note.ID, syncPending, syncStatus, note.LocalOnly, note.CreatedAt, note.UpdatedAt,
+	).Scan(&note.Revision); err != nil {
+		return err
+	}
+	setSyncState(note, syncStatus)
+
+	if err := saveNoteTags(ctx, tx, note); err != nil {
+		return err
+	}
+	if err := saveNoteTasks(ctx, tx, note); err != nil {
+		return err
+	}
+	if err := saveNoteReminders(ctx, tx, note); err != nil {
+		return err
+	}
+	return saveNoteCounts(ctx, tx, note)
+}
+
+// UpsertNoteAtRevision saves a note only if its stored revision still equals baseRevision
+// A baseRevision of 0 means the client expects the note not to exist yet
+// Returns false (without error) when the note was changed elsewhere in the meantime
+func (r *Repository) UpsertNoteAtRevision(ctx context.Context, note *models.Note, baseRevision int, markForSync bool) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	saved, err := saveNoteAtRevision(ctx, tx, note, baseRevision, markForSync)
+	if err != nil || !saved {
+		return saved, err
+	}
+	return true, tx.Commit()
+}
+
+// execer is satisfied by both *DB and *Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// saveSyncState returns the sync_pending and sync_status a saved note is written
+// with: pending when markForSync is set, unless the note is local only
+func saveSyncState(ctx context.Context, tx *Tx, note *models.Note, markForSync bool) (int, string, error) {
+	if !markForSync {
+		return 0, string(models.SyncStatusSynced), nil
+	}
+
+	localOnly, err := isLocalOnly(ctx, tx, note)
+	if err != nil {
+		return 0, "", err
+	}
+	if localOnly {
+		return 0, string(models.SyncStatusLocal), nil
+	}
+	return 1, string(models.SyncStatusPending), nil
+}
+
+// setSyncState records the sync state a note was saved with on the note; the
+// local_only column only holds marks made on the note itself, so this comes
+// after the write
+func setSyncState(note *models.Note, syncStatus string) {
+	note.SyncStatus = models.SyncStatus(syncStatus)
+	if note.SyncStatus == models.SyncStatusLocal {
+		note.LocalOnly = true
+	}
+}
+
+// saveNoteAtRevision is the revision-checked write behind UpsertNoteAtRevision,
+// run inside a transaction; the note's tags and revision history are saved with it
+func saveNoteAtRevision(ctx context.Context, tx *Tx, note *models.Note, baseRevision int, markForSync bool) (bool, error) {
+	syncPending, syncStatus, err := saveSyncState(ctx, tx, note, markForSync)
+	if err != nil {
+		return false, err
+	}
+
+	if note.ID == "" {
+		note.ID = fmt.Sprintf("%s-%s-%s", note.UserID, note.Context, note.Date)
+	}
+	setGranularity(note)
+
+	var result sql.Result
+	if baseRevision == 0 {
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO notes (id, user_id, context, date, granularity, content, drive_file_id,
+				sync_pending, sync_status, sync_retry_count, deleted, revision, local_only, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, 0, 1, ?, ?, ?)
+			ON CONFLICT(user_id, context, date) DO NOTHING
+		`,
+			note.ID, note.UserID, note.Context, note.Date, note.Type, note.Content,
+			note.ID, syncPending, syncStatus, note.LocalOnly, note.CreatedAt, note.UpdatedAt,
+		)
+	} else {
+		if err := saveRevision(ctx, tx, note, baseRevision); err != nil {
+			return false, err
+		}
+		result, err = tx.ExecContext(ctx, `
+			UPDATE notes SET
+				content = ?,
+				sync_pending = ?,
+				sync_status = ?,
+				sync_retry_count = 0,
+				sync_error = NULL,
+				sync_error_class = NULL,
+				next_retry_at = NULL,
+				revision = revision + 1,
+				local_only = CASE WHEN ? = 1 THEN 1 ELSE local_only END,
+				updated_at = ?
+			WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0 AND revision = ?
+		`,
+			note.Content, syncPending, syncStatus, note.LocalOnly, note.UpdatedAt,
+			note.UserID, note.Context, note.Date, baseRevision,
+		)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	note.Revision = baseRevision + 1
+	setSyncState(note, syncStatus)
+	if err := saveNoteTags(ctx, tx, note); err != nil {
+		return false, err
+	}
+	if err := saveNoteTasks(ctx, tx, note); err != nil {
+		return false, err
+	}
+	if err := saveNoteReminders(ctx, tx, note); err != nil {
+		return false, err
+	}
+	return true, saveNoteCounts(ctx, tx, note)
+}
+
+// SplitNote saves the two notes of a split in one transaction: source with the
+// selection removed and target with it added. Each write is checked against its
+// base revision (0 = target must not exist yet) and both are queued for sync.
+// Returns false (without error) and writes nothing if either note changed meanwhile.
+func (r *Repository) SplitNote(ctx context.Context, source *models.Note, sourceRevision int, target *models.Note, targetRevision int) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	for _, write := range []struct {
+		note     *models.Note
+		revision int
+	}{{source, sourceRevision}, {target, targetRevision}} {
+		saved, err := saveNoteAtRevision(ctx, tx, write.note, write.revision, true)
+		if err != nil || !saved {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // GetNotesByContext retrieves all notes for a context (paginated)
-func (r *Repository) GetNotesByContext(userID, context string, limit, offset int) ([]models.Note, error) {
-	rows, err := r.db.Query(`
-		SELECT id, user_id, context, date, content, created_at, updated_at
+func (r *Repository) GetNotesByContext(ctx context.Context, scope UserScope, contextName string, limit, offset int) ([]models.Note, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, context, date, granularity, content, pinned_at IS NOT NULL, word_count, char_count, created_at, updated_at
 		FROM notes
 		WHERE user_id = ? AND context = ? AND deleted = 0
 		ORDER BY date DESC
 		LIMIT ? OFFSET ?
-	`, userID, context, limit, offset)
+	`, userID, contextName, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -97,12 +322,14 @@ func (r *Repository) GetNotesByContext(userID, context string, limit, offset int
 	for rows.Next() {
 		var note models.Note
 		if err := rows.Scan(
-			&note.ID, &note.UserID, &note.Context, &note.Date,
-			&note.Content, &note.CreatedAt, &note.UpdatedAt,
+			&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type,
+			&note.Content, &note.Pinned, &note.WordCount, &note.CharCount, &note.CreatedAt, &note.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
 		// Don't load content for list view (performance optimization)
+		note.Tags = markdown.ExtractHashtags(note.Content)
+		note.Encrypted = e2ee.IsEncrypted(note.Content)
 		note.Content = ""
 		notes = append(notes, note)
 	}
@@ -110,12 +337,22 @@ func (r *Repository) GetNotesByContext(userID, context string, limit, offset int
 	return notes, rows.Err()
 }
 
-// GetAllNotesByUser retrieves all notes for a user
-func (r *Repository) GetAllNotesByUser(userID string) ([]models.Note, error) {
-	rows, err := r.db.Query(`
-		SELECT id, user_id, context, date, content, created_at, updated_at
+// GetAllNotesByUser retrieves all notes a user sees in the app, see visibility.App
+func (r *Repository) GetAllNotesByUser(ctx context.Context, scope UserScope) ([]models.Note, error) {
+	return r.GetVisibleNotes(ctx, scope, visibility.App)
+}
+
+// GetVisibleNotes retrieves all notes of a user that policy shows, most recently updated first
+func (r *Repository) GetVisibleNotes(ctx context.Context, scope UserScope, policy visibility.Policy) ([]models.Note, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, context, date, granularity, content, `+localOnlyCondition+`, word_count, char_count, created_at, updated_at
 		FROM notes
-		WHERE user_id = ? AND deleted = 0
+		WHERE user_id = ? AND `+visibleCondition("notes", policy)+`
 		ORDER BY updated_at DESC
 	`, userID)
 	if err != nil {
@@ -127,34 +364,540 @@ func (r *Repository) GetAllNotesByUser(userID string) ([]models.Note, error) {
 	for rows.Next() {
 		var note models.Note
 		if err := rows.Scan(
-			&note.ID, &note.UserID, &note.Context, &note.Date,
-			&note.Content, &note.CreatedAt, &note.UpdatedAt,
+			&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type,
+			&note.Content, &note.LocalOnly, &note.WordCount, &note.CharCount, &note.CreatedAt, &note.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		note.Tags = markdown.ExtractHashtags(note.Content)
+		note.Encrypted = e2ee.IsEncrypted(note.Content)
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// GetLastNoteChange returns when a user's notes last changed, deletions included;
+// zero when they have no notes
+func (r *Repository) GetLastNoteChange(ctx context.Context, scope UserScope) (time.Time, error) {
+	if err := scope.check(); err != nil {
+		return time.Time{}, err
+	}
+	userID := scope.userID
+
+	var changedAt time.Time
+	err := r.db.QueryRowContext(ctx, `
+		SELECT updated_at FROM notes WHERE user_id = ? ORDER BY updated_at DESC LIMIT 1
+	`, userID).Scan(&changedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return changedAt, err
+}
+
+// GetNotesByKeys retrieves the notes of a context whose keys (dates, weeks, months...) are in keys
+func (r *Repository) GetNotesByKeys(ctx context.Context, scope UserScope, contextName string, keys []string) ([]models.Note, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keys)), ",")
+	args := []interface{}{userID, contextName}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, context, date, granularity, content, word_count, char_count, created_at, updated_at
+		FROM notes
+		WHERE user_id = ? AND context = ? AND date IN (`+placeholders+`) AND deleted = 0
+		ORDER BY date ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(
+			&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type,
+			&note.Content, &note.WordCount, &note.CharCount, &note.CreatedAt, &note.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		note.Tags = markdown.ExtractHashtags(note.Content)
+		note.Encrypted = e2ee.IsEncrypted(note.Content)
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// GetMonthNotes retrieves the notes of several contexts whose keys are in keys,
+// with their revision and sync status, in one query
+func (r *Repository) GetMonthNotes(ctx context.Context, scope UserScope, contextNames, keys []string) ([]models.Note, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	if len(contextNames) == 0 || len(keys) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{userID}
+	for _, name := range contextNames {
+		args = append(args, name)
+	}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, context, date, granularity, content, revision, sync_status, `+localOnlyCondition+`,
+		       word_count, char_count, created_at, updated_at
+		FROM notes
+		WHERE user_id = ? AND context IN (`+placeholders(len(contextNames))+`) AND date IN (`+placeholders(len(keys))+`) AND deleted = 0
+		ORDER BY context ASC, date ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(
+			&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type, &note.Content,
+			&note.Revision, &note.SyncStatus, &note.LocalOnly,
+			&note.WordCount, &note.CharCount, &note.CreatedAt, &note.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
+		note.Tags = markdown.ExtractHashtags(note.Content)
+		note.Encrypted = e2ee.IsEncrypted(note.Content)
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// GetCalendarDays returns the days from from to to (inclusive) that have a daily
+// note in a context, with the note's word count and sync status, in one query
+// that leaves the content alone
+func (r *Repository) GetCalendarDays(ctx context.Context, scope UserScope, contextName, from, to string) ([]models.CalendarDay, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT notes.date, notes.word_count, COALESCE(notes.sync_status, '')
+		FROM notes
+		WHERE notes.user_id = ? AND notes.context = ? AND notes.granularity = ? AND notes.date BETWEEN ? AND ?
+			AND `+visibleCondition("notes", visibility.App)+`
+		ORDER BY notes.date ASC
+	`, userID, contextName, period.Day, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []models.CalendarDay{}
+	for rows.Next() {
+		day := models.CalendarDay{Exists: true}
+		if err := rows.Scan(&day.Date, &day.WordCount, &day.SyncStatus); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+// GetAgendaNotes retrieves the user's daily notes from from to to (inclusive) in every
+// context, with the context color, ordered by date and context. Notes of deleted
+// contexts are left out.
+func (r *Repository) GetAgendaNotes(ctx context.Context, scope UserScope, from, to string) ([]models.AgendaNote, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+
+	return r.agendaNotes(ctx, scope.userID, from, to, visibility.App)
+}
+
+// agendaNotes retrieves the daily notes from from to to that policy shows, for
+// GetAgendaNotes and GetDigestNotes
+func (r *Repository) agendaNotes(ctx context.Context, userID, from, to string, policy visibility.Policy) ([]models.AgendaNote, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT n.context, c.color, n.date, COALESCE(n.content, ''), n.updated_at
+		FROM notes n
+		JOIN contexts c ON c.user_id = n.user_id AND c.name = n.context
+		WHERE n.user_id = ? AND n.granularity = ? AND n.date BETWEEN ? AND ? AND `+visibleCondition("n", policy)+`
+		ORDER BY n.date ASC, n.context ASC
+	`, userID, period.Day, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.AgendaNote{}
+	for rows.Next() {
+		var note models.AgendaNote
+		if err := rows.Scan(&note.Context, &note.Color, &note.Date, &note.Content, &note.UpdatedAt); err != nil {
+			return nil, err
+		}
 		notes = append(notes, note)
 	}
 
 	return notes, rows.Err()
 }
 
-// DeleteNote marks a note as deleted and pending sync
+// GetAdjacentNoteDates returns the keys of the notes of the same granularity
+// right before and after key in a context, empty where there is none
+func (r *Repository) GetAdjacentNoteDates(ctx context.Context, scope UserScope, contextName, key string) (previous, next string, err error) {
+	if err := scope.check(); err != nil {
+		return "", "", err
+	}
+	userID := scope.userID
+
+	granularity := period.Kind(key)
+	err = r.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE((SELECT MAX(date) FROM notes
+				WHERE user_id = ? AND context = ? AND granularity = ? AND date < ? AND deleted = 0), ''),
+			COALESCE((SELECT MIN(date) FROM notes
+				WHERE user_id = ? AND context = ? AND granularity = ? AND date > ? AND deleted = 0), '')
+	`, userID, contextName, granularity, key, userID, contextName, granularity, key).Scan(&previous, &next)
+	return previous, next, err
+}
+
+// GetNotesOnDate retrieves the user's notes with the given key in every context,
+// with the context color, ordered by context. Notes of deleted contexts are left out.
+func (r *Repository) GetNotesOnDate(ctx context.Context, scope UserScope, key string) ([]models.AgendaNote, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT n.context, c.color, n.date, COALESCE(n.content, ''), n.updated_at
+		FROM notes n
+		JOIN contexts c ON c.user_id = n.user_id AND c.name = n.context
+		WHERE n.user_id = ? AND n.date = ? AND `+visibleCondition("n", visibility.App)+`
+		ORDER BY n.context ASC
+	`, userID, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.AgendaNote
+	for rows.Next() {
+		var note models.AgendaNote
+		if err := rows.Scan(&note.Context, &note.Color, &note.Date, &note.Content, &note.UpdatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// setGranularity derives the note granularity from its key when the caller didn't set it
+// (e.g. notes imported from Drive)
+func setGranularity(note *models.Note) {
+	if note.Type == "" {
+		note.Type = period.Kind(note.Date)
+	}
+	if note.Type == "" {
+		note.Type = period.Day
+	}
+}
+
+// DeleteNote marks a note as deleted and pending sync, unpins it and revokes its
+// share links so a note written on the same day later isn't pinned or shared by them
 // It doesn't actually delete the note - that's done after Drive deletion
-func (r *Repository) DeleteNote(userID, context, date string) error {
-	_, err := r.db.Exec(`
+func (r *Repository) DeleteNote(ctx context.Context, scope UserScope, contextName, date string) error {
+	if err := scope.check(); err != nil {
+		return err
+	}
+	userID := scope.userID
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteNote(ctx, tx, userID, contextName, date); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteNote is the write behind DeleteNote and TrashNote
+func deleteNote(ctx context.Context, tx *Tx, userID, contextName, date string) error {
+	if _, err := tx.ExecContext(ctx, `
 		UPDATE notes
-		SET deleted = 1, sync_pending = 1, updated_at = CURRENT_TIMESTAMP
+		SET deleted = 1, sync_pending = 1, next_retry_at = NULL, pinned_at = NULL, updated_at = CURRENT_TIMESTAMP
 		WHERE user_id = ? AND context = ? AND date = ?
-	`, userID, context, date)
+	`, userID, contextName, date); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM shares
+		WHERE note_id IN (SELECT id FROM notes WHERE user_id = ? AND context = ? AND date = ?)
+	`, userID, contextName, date)
 	return err
 }
 
-// HardDeleteNote permanently removes a note from the database
-// Only called after successful Drive deletion
-func (r *Repository) HardDeleteNote(userID, context, date string) error {
-	_, err := r.db.Exec(`
+// HardDeleteNote permanently removes a deleted note from the database
+// Only called after successful Drive deletion; a note restored from the trash
+// in the meantime is live again and stays
+func (r *Repository) HardDeleteNote(ctx context.Context, scope UserScope, contextName, date string) error {
+	if err := scope.check(); err != nil {
+		return err
+	}
+	userID := scope.userID
+
+	_, err := r.db.ExecContext(ctx, `
 		DELETE FROM notes
-		WHERE user_id = ? AND context = ? AND date = ?
-	`, userID, context, date)
+		WHERE user_id = ? AND context = ? AND date = ? AND deleted = 1
+	`, userID, contextName, date)
 	return err
 }
+
+// TransferNotes copies the notes of fromContext with the given keys into toContext,
+// keeping content, granularity, timestamps and revision. Copies are queued for
+// upload; with move the originals are marked deleted so the sync worker removes
+// them from storage, kept in the trash stamped with trashedAt unless it's zero.
+// Existing notes in toContext are overwritten and keep a revision above their
+// current one. Copies of local-only notes, marked so themselves or through their
+// context, stay local only. Returns the notes written to toContext.
+func (r *Repository) TransferNotes(ctx context.Context, scope UserScope, fromContext, toContext string, keys []string, move bool, trashedAt time.Time) ([]models.Note, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keys)), ",")
+	args := []interface{}{userID, fromContext}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT date, granularity, content, revision, `+localOnlyCondition+`, created_at, updated_at
+		FROM notes
+		WHERE user_id = ? AND context = ? AND date IN (`+placeholders+`) AND deleted = 0
+		ORDER BY date ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []models.Note
+	for rows.Next() {
+		note := models.Note{UserID: userID, Context: toContext}
+		if err := rows.Scan(&note.Date, &note.Type, &note.Content, &note.Revision, &note.LocalOnly, &note.CreatedAt, &note.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range notes {
+		note := &notes[i]
+		if err := writeTransferredNote(ctx, tx, note); err != nil {
+			return nil, err
+		}
+
+		if move {
+			if err := trashNote(ctx, tx, userID, fromContext, note.Date, trashedAt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Copies written above as pending leave the queue if they are local only
+	if err := applyLocalOnly(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// RedateNotes moves the day notes of a context at the given dates by days,
+// keeping content, timestamps and revision. Like moves between contexts, the
+// copies are queued for upload and the originals are marked deleted so the sync
+// worker removes them from storage, kept in the trash stamped with trashedAt
+// unless it's zero. Notes already at a new date are overwritten.
+// Returns the notes at their new dates.
+func (r *Repository) RedateNotes(ctx context.Context, scope UserScope, contextName string, dates []string, days int, trashedAt time.Time) ([]models.Note, error) {
+	if err := scope.check(); err != nil {
+		return nil, err
+	}
+	userID := scope.userID
+
+	if len(dates) == 0 || days == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(dates)), ",")
+	args := []interface{}{userID, contextName, period.Day}
+	for _, date := range dates {
+		args = append(args, date)
+	}
+
+	// Notes move away from the end of the range first, so a note never lands on
+	// one that hasn't moved yet
+	order := "ASC"
+	if days > 0 {
+		order = "DESC"
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT date, granularity, content, revision, `+localOnlyCondition+`, created_at, updated_at
+		FROM notes
+		WHERE user_id = ? AND context = ? AND granularity = ? AND date IN (`+placeholders+`) AND deleted = 0
+		ORDER BY date `+order+`
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []models.Note
+	var from []string
+	for rows.Next() {
+		note := models.Note{UserID: userID, Context: contextName}
+		if err := rows.Scan(&note.Date, &note.Type, &note.Content, &note.Revision, &note.LocalOnly, &note.CreatedAt, &note.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		from = append(from, note.Date)
+		notes = append(notes, note)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range notes {
+		note := &notes[i]
+		if note.Date, err = period.AddDays(note.Date, days); err != nil {
+			return nil, err
+		}
+		if err := writeTransferredNote(ctx, tx, note); err != nil {
+			return nil, err
+		}
+
+		if err := trashNote(ctx, tx, userID, contextName, from[i], trashedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	// Copies written above as pending leave the queue if they are local only
+	if err := applyLocalOnly(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(notes, func(i, j int) bool { return notes[i].Date < notes[j].Date })
+	return notes, nil
+}
+
+// writeTransferredNote writes a note copied from another context or date as
+// pending upload, overwriting the note at its key, and sets its ID, revision and
+// sync status to the stored ones
+func writeTransferredNote(ctx context.Context, tx *Tx, note *models.Note) error {
+	note.ID = fmt.Sprintf("%s-%s-%s", note.UserID, note.Context, note.Date)
+	note.SyncStatus = models.SyncStatusPending
+
+	// An overwritten target note keeps its old content in the history
+	if err := saveRevision(ctx, tx, note, 0); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO notes (id, user_id, context, date, granularity, content, drive_file_id,
+			sync_pending, sync_status, sync_retry_count, deleted, revision, local_only, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?, 0, 0, ?, ?, ?, ?)
+		ON CONFLICT(user_id, context, date) DO UPDATE SET
+			granularity = excluded.granularity,
+			content = excluded.content,
+			deleted = 0,
+			local_only = CASE WHEN excluded.local_only = 1 THEN 1 ELSE notes.local_only END,
+			sync_pending = 1,
+			sync_status = excluded.sync_status,
+			sync_retry_count = 0,
+			sync_error = NULL,
+			sync_error_class = NULL,
+			next_retry_at = NULL,
+			revision = CASE WHEN notes.revision + 1 > excluded.revision THEN notes.revision + 1 ELSE excluded.revision END,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at
+		RETURNING id, revision
+	`,
+		note.ID, note.UserID, note.Context, note.Date, note.Type, note.Content,
+		note.ID, string(models.SyncStatusPending), note.Revision, note.LocalOnly, note.CreatedAt, note.UpdatedAt,
+	).Scan(&note.ID, &note.Revision); err != nil {
+		return err
+	}
+	if err := saveNoteTags(ctx, tx, note); err != nil {
+		return err
+	}
+	if err := saveNoteTasks(ctx, tx, note); err != nil {
+		return err
+	}
+	if err := saveNoteReminders(ctx, tx, note); err != nil {
+		return err
+	}
+	if err := saveNoteCounts(ctx, tx, note); err != nil {
+		return err
+	}
+
+	local, err := isLocalOnly(ctx, tx, note)
+	if err != nil {
+		return err
+	}
+	note.LocalOnly = local
+	if note.LocalOnly {
+		note.SyncStatus = models.SyncStatusLocal
+	}
+	return nil
+}
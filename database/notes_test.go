@@ -0,0 +1,433 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteRevisions(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	newNote := func(content string) *models.Note {
+		return &models.Note{
+			UserID:    "test-user",
+			Context:   "Work",
+			Date:      "2025-10-17",
+			Content:   content,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+	}
+
+	t.Run("Revision increments on every upsert", func(t *testing.T) {
+		first := newNote("v1")
+		require.NoError(t, repo.UpsertNote(ctx, first, true))
+		assert.Equal(t, 1, first.Revision)
+
+		second := newNote("v2")
+		require.NoError(t, repo.UpsertNote(ctx, second, true))
+		assert.Equal(t, 2, second.Revision)
+
+		retrieved, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17")
+		require.NoError(t, err)
+		assert.Equal(t, 2, retrieved.Revision)
+	})
+
+	t.Run("Conditional upsert rejects stale revision", func(t *testing.T) {
+		saved, err := repo.UpsertNoteAtRevision(ctx, newNote("stale"), 1, true)
+		require.NoError(t, err)
+		assert.False(t, saved)
+
+		retrieved, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17")
+		require.NoError(t, err)
+		assert.Equal(t, "v2", retrieved.Content)
+	})
+
+	t.Run("Conditional upsert accepts current revision", func(t *testing.T) {
+		note := newNote("v3")
+		saved, err := repo.UpsertNoteAtRevision(ctx, note, 2, true)
+		require.NoError(t, err)
+		assert.True(t, saved)
+		assert.Equal(t, 3, note.Revision)
+	})
+
+	t.Run("Revision zero only creates missing notes", func(t *testing.T) {
+		saved, err := repo.UpsertNoteAtRevision(ctx, newNote("overwrite"), 0, true)
+		require.NoError(t, err)
+		assert.False(t, saved)
+
+		fresh := newNote("fresh")
+		fresh.Date = "2025-10-18"
+		saved, err = repo.UpsertNoteAtRevision(ctx, fresh, 0, true)
+		require.NoError(t, err)
+		assert.True(t, saved)
+		assert.Equal(t, 1, fresh.Revision)
+	})
+}
+
+func TestNoteGranularity(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, key := range []string{"2025-10-12", "2025-10-13", "2025-10-19", "2025-W42", "2025-10"} {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID:    "test-user",
+			Context:   "Work",
+			Date:      key,
+			Content:   "note " + key,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}, true))
+	}
+
+	week, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-W42")
+	require.NoError(t, err)
+	require.NotNil(t, week)
+	assert.Equal(t, "week", week.Type)
+
+	month, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10")
+	require.NoError(t, err)
+	require.NotNil(t, month)
+	assert.Equal(t, "month", month.Type)
+
+	notes, err := repo.GetNotesByKeys(ctx, ScopeUser("test-user"), "Work", []string{"2025-10-13", "2025-10-14", "2025-W42"})
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	assert.Equal(t, "2025-10-13", notes[0].Date)
+	assert.Equal(t, "day", notes[0].Type)
+	assert.Equal(t, "2025-W42", notes[1].Date)
+}
+
+func TestCanceledContext(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.GetAllNotesByUser(ctx, ScopeUser("test-user"))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestTransferNotes(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	created := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+	for _, content := range []string{"v1", "v2"} {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID:    "test-user",
+			Context:   "Work",
+			Date:      "2025-10-17",
+			Content:   content,
+			CreatedAt: created,
+			UpdatedAt: created,
+		}, false))
+	}
+
+	t.Run("Copy keeps revision and source", func(t *testing.T) {
+		notes, err := repo.TransferNotes(ctx, ScopeUser("test-user"), "Work", "Personal", []string{"2025-10-17", "2025-10-18"}, false, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, notes, 1)
+		assert.Equal(t, 2, notes[0].Revision)
+
+		copied, err := repo.GetNote(ctx, ScopeUser("test-user"), "Personal", "2025-10-17")
+		require.NoError(t, err)
+		require.NotNil(t, copied)
+		assert.Equal(t, "v2", copied.Content)
+		assert.Equal(t, models.SyncStatusPending, copied.SyncStatus)
+		assert.True(t, copied.CreatedAt.Equal(created))
+
+		source, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17")
+		require.NoError(t, err)
+		assert.NotNil(t, source)
+	})
+
+	t.Run("Move over an existing note bumps its revision and deletes the source", func(t *testing.T) {
+		notes, err := repo.TransferNotes(ctx, ScopeUser("test-user"), "Work", "Personal", []string{"2025-10-17"}, true, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, notes, 1)
+		assert.Equal(t, 3, notes[0].Revision)
+
+		source, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17")
+		require.NoError(t, err)
+		assert.Nil(t, source)
+
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		var deleted bool
+		for _, note := range pending {
+			if note.Context == "Work" && note.Deleted {
+				deleted = true
+			}
+		}
+		assert.True(t, deleted, "moved note should be queued for deletion from storage")
+	})
+}
+
+func TestRedateNotes(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	created := time.Date(2025, 10, 1, 8, 0, 0, 0, time.UTC)
+	for _, date := range []string{"2025-10-16", "2025-10-17", "2025-W42"} {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID:    "test-user",
+			Context:   "Work",
+			Date:      date,
+			Content:   "written " + date,
+			CreatedAt: created,
+			UpdatedAt: created,
+		}, false))
+	}
+
+	t.Run("Consecutive notes shift without overwriting each other", func(t *testing.T) {
+		notes, err := repo.RedateNotes(ctx, ScopeUser("test-user"), "Work", []string{"2025-10-16", "2025-10-17", "2025-W42"}, 1, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, notes, 2, "only daily notes are re-dated")
+		assert.Equal(t, "2025-10-17", notes[0].Date)
+		assert.Equal(t, "2025-10-18", notes[1].Date)
+
+		for date, content := range map[string]string{"2025-10-17": "written 2025-10-16", "2025-10-18": "written 2025-10-17"} {
+			note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", date)
+			require.NoError(t, err)
+			require.NotNil(t, note, date)
+			assert.Equal(t, content, note.Content)
+			assert.Equal(t, models.SyncStatusPending, note.SyncStatus)
+			assert.True(t, note.CreatedAt.Equal(created))
+		}
+
+		source, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Nil(t, source)
+	})
+
+	t.Run("Shifting back restores the dates", func(t *testing.T) {
+		notes, err := repo.RedateNotes(ctx, ScopeUser("test-user"), "Work", []string{"2025-10-17", "2025-10-18"}, -1, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, notes, 2)
+
+		dates, err := repo.GetNoteDates(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2025-10-16", "2025-10-17"}, dates)
+	})
+}
+
+func TestSplitNote(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	newNote := func(date, content string) *models.Note {
+		return &models.Note{
+			UserID:    "test-user",
+			Context:   "Work",
+			Date:      date,
+			Content:   content,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+	}
+	require.NoError(t, repo.UpsertNote(ctx, newNote("2025-10-17", "a\nb"), false))
+
+	t.Run("Stale target revision writes nothing", func(t *testing.T) {
+		require.NoError(t, repo.UpsertNote(ctx, newNote("2025-10-16", "existing"), false))
+
+		saved, err := repo.SplitNote(ctx, newNote("2025-10-17", "a"), 1, newNote("2025-10-16", "b"), 0)
+		require.NoError(t, err)
+		assert.False(t, saved)
+
+		source, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17")
+		require.NoError(t, err)
+		assert.Equal(t, "a\nb", source.Content)
+		assert.Equal(t, 1, source.Revision)
+	})
+
+	t.Run("Both notes are saved and queued for sync", func(t *testing.T) {
+		saved, err := repo.SplitNote(ctx, newNote("2025-10-17", "a"), 1, newNote("2025-10-16", "existing\n\nb"), 1)
+		require.NoError(t, err)
+		assert.True(t, saved)
+
+		source, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17")
+		require.NoError(t, err)
+		assert.Equal(t, "a", source.Content)
+		assert.Equal(t, models.SyncStatusPending, source.SyncStatus)
+
+		target, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, "existing\n\nb", target.Content)
+		assert.Equal(t, 2, target.Revision)
+	})
+}
+
+func TestUpsertNotes(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	newNote := func(userID, date, content string) *models.Note {
+		return &models.Note{UserID: userID, Context: "Work", Date: date, Content: content, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	}
+
+	t.Run("A failing note writes nothing", func(t *testing.T) {
+		err := repo.UpsertNotes(ctx, []*models.Note{
+			newNote("test-user", "2025-10-16", "saved first"),
+			newNote("missing-user", "2025-10-17", "violates the user foreign key"),
+		}, true)
+		require.Error(t, err)
+
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Nil(t, note)
+	})
+
+	t.Run("All notes are saved and queued for sync", func(t *testing.T) {
+		require.NoError(t, repo.UpsertNotes(ctx, []*models.Note{
+			newNote("test-user", "2025-10-16", "one"),
+			newNote("test-user", "2025-10-17", "two #offline"),
+		}, true))
+
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		assert.Len(t, pending, 2)
+
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"offline"}, note.Tags)
+	})
+}
+
+func TestGetAgendaNotes(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, c := range []models.Context{
+		{ID: "ctx-work", UserID: "test-user", Name: "Work", Color: "primary"},
+		{ID: "ctx-home", UserID: "test-user", Name: "Home", Color: "success"},
+		{ID: "ctx-old", UserID: "test-user", Name: "Old", Color: "danger"},
+	} {
+		require.NoError(t, repo.CreateContext(ctx, &c))
+	}
+	for _, note := range []models.Note{
+		{Context: "Work", Date: "2025-10-14", Content: "before the range"},
+		{Context: "Work", Date: "2025-10-15", Content: "work"},
+		{Context: "Home", Date: "2025-10-15", Content: "home"},
+		{Context: "Old", Date: "2025-10-16", Content: "deleted context"},
+		{Context: "Work", Date: "2025-W42", Content: "weekly"},
+		{Context: "Work", Date: "2025-10-17", Content: "deleted note"},
+	} {
+		note.UserID = "test-user"
+		note.CreatedAt, note.UpdatedAt = time.Now(), time.Now()
+		require.NoError(t, repo.UpsertNote(ctx, &note, false))
+	}
+	require.NoError(t, repo.DeleteContext(ctx, ScopeUser("test-user"), "ctx-old", time.Now()))
+	require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17"))
+
+	notes, err := repo.GetAgendaNotes(ctx, ScopeUser("test-user"), "2025-10-15", "2025-10-21")
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	assert.Equal(t, models.AgendaNote{Context: "Home", Color: "success", Date: "2025-10-15", Content: "home", UpdatedAt: notes[0].UpdatedAt}, notes[0])
+	assert.Equal(t, "Work", notes[1].Context)
+	assert.Equal(t, "primary", notes[1].Color)
+
+	other, err := repo.GetAgendaNotes(ctx, ScopeUser("other-user"), "2025-10-15", "2025-10-21")
+	require.NoError(t, err)
+	assert.Empty(t, other)
+}
+
+func TestGetCalendarDays(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-work", UserID: "test-user", Name: "Work", Color: "primary"}))
+	for _, note := range []models.Note{
+		{Context: "Work", Date: "2025-09-30", Content: "before the month"},
+		{Context: "Work", Date: "2025-10-01", Content: "three short words"},
+		{Context: "Work", Date: "2025-10-02", Content: "deleted note"},
+		{Context: "Work", Date: "2025-10", Content: "monthly"},
+		{Context: "Home", Date: "2025-10-03", Content: "other context"},
+	} {
+		note.UserID = "test-user"
+		note.CreatedAt, note.UpdatedAt = time.Now(), time.Now()
+		require.NoError(t, repo.UpsertNote(ctx, &note, true))
+	}
+	require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-02"))
+
+	days, err := repo.GetCalendarDays(ctx, ScopeUser("test-user"), "Work", "2025-10-01", "2025-10-31")
+	require.NoError(t, err)
+	assert.Equal(t, []models.CalendarDay{
+		{Date: "2025-10-01", Exists: true, WordCount: 3, SyncStatus: models.SyncStatusPending},
+	}, days)
+}
+
+func TestNoteNeighbours(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, c := range []models.Context{
+		{ID: "ctx-work", UserID: "test-user", Name: "Work", Color: "primary"},
+		{ID: "ctx-home", UserID: "test-user", Name: "Home", Color: "success"},
+		{ID: "ctx-old", UserID: "test-user", Name: "Old", Color: "danger"},
+	} {
+		require.NoError(t, repo.CreateContext(ctx, &c))
+	}
+	for _, note := range []models.Note{
+		{Context: "Work", Date: "2025-10-10", Content: "first"},
+		{Context: "Work", Date: "2025-10-13", Content: "deleted note"},
+		{Context: "Work", Date: "2025-10-15", Content: "work"},
+		{Context: "Work", Date: "2025-W42", Content: "weekly"},
+		{Context: "Work", Date: "2025-10-20", Content: "last"},
+		{Context: "Home", Date: "2025-10-14", Content: "other context"},
+		{Context: "Home", Date: "2025-10-15", Content: "home"},
+		{Context: "Old", Date: "2025-10-15", Content: "deleted context"},
+	} {
+		note.UserID = "test-user"
+		note.CreatedAt, note.UpdatedAt = time.Now(), time.Now()
+		require.NoError(t, repo.UpsertNote(ctx, &note, false))
+	}
+	require.NoError(t, repo.DeleteContext(ctx, ScopeUser("test-user"), "ctx-old", time.Now()))
+	require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-13"))
+
+	t.Run("Adjacent notes skip deleted notes, other contexts and other kinds", func(t *testing.T) {
+		previous, next, err := repo.GetAdjacentNoteDates(ctx, ScopeUser("test-user"), "Work", "2025-10-15")
+		require.NoError(t, err)
+		assert.Equal(t, "2025-10-10", previous)
+		assert.Equal(t, "2025-10-20", next)
+
+		previous, next, err = repo.GetAdjacentNoteDates(ctx, ScopeUser("test-user"), "Work", "2025-10-10")
+		require.NoError(t, err)
+		assert.Empty(t, previous)
+		assert.Equal(t, "2025-10-15", next)
+
+		previous, next, err = repo.GetAdjacentNoteDates(ctx, ScopeUser("test-user"), "Work", "2025-W42")
+		require.NoError(t, err)
+		assert.Empty(t, previous)
+		assert.Empty(t, next)
+	})
+
+	t.Run("Notes on a date in every live context", func(t *testing.T) {
+		notes, err := repo.GetNotesOnDate(ctx, ScopeUser("test-user"), "2025-10-15")
+		require.NoError(t, err)
+		require.Len(t, notes, 2)
+		assert.Equal(t, "Home", notes[0].Context)
+		assert.Equal(t, "success", notes[0].Color)
+		assert.Equal(t, "home", notes[0].Content)
+		assert.Equal(t, "Work", notes[1].Context)
+
+		other, err := repo.GetNotesOnDate(ctx, ScopeUser("other-user"), "2025-10-15")
+		require.NoError(t, err)
+		assert.Empty(t, other)
+	})
+}
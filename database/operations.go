@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"time"
+)
+
+// ==================== SYNC OPERATIONS ====================
+
+// syncOperationColumns are the columns scanned by scanSyncOperations
+const syncOperationColumns = `id, user_id, kind, context_id, context_name, COALESCE(new_name, ''),
+	status, retry_count, COALESCE(error, ''), COALESCE(error_class, ''), next_retry_at, created_at`
+
+// RecordSyncOperation saves a context folder change that failed, due for a retry right away
+// The new ID is written back to op.ID.
+func (r *Repository) RecordSyncOperation(ctx context.Context, op *models.SyncOperation) error {
+	op.Status = models.SyncStatusFailed
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO sync_operations (user_id, kind, context_id, context_name, new_name, status, error, error_class, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, op.UserID, op.Kind, op.ContextID, op.ContextName, op.NewName, string(op.Status),
+		op.Error, string(op.ErrorClass), op.CreatedAt).Scan(&op.ID)
+}
+
+// GetFailedSyncOperations returns the operations of every user waiting for a
+// retry, due or not, oldest first
+func (r *Repository) GetFailedSyncOperations(ctx context.Context, limit int) ([]models.SyncOperation, error) {
+	return r.querySyncOperations(ctx, `
+		SELECT `+syncOperationColumns+`
+		FROM sync_operations
+		WHERE status = ?
+		ORDER BY id ASC
+		LIMIT ?
+	`, string(models.SyncStatusFailed), limit)
+}
+
+// GetUserSyncOperations returns a user's failed and abandoned operations, oldest first
+func (r *Repository) GetUserSyncOperations(ctx context.Context, userID string) ([]models.SyncOperation, error) {
+	return r.querySyncOperations(ctx, `
+		SELECT `+syncOperationColumns+`
+		FROM sync_operations
+		WHERE user_id = ?
+		ORDER BY id ASC
+	`, userID)
+}
+
+// querySyncOperations runs a query selecting syncOperationColumns
+func (r *Repository) querySyncOperations(ctx context.Context, query string, args ...interface{}) ([]models.SyncOperation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ops := []models.SyncOperation{}
+	for rows.Next() {
+		var op models.SyncOperation
+		var status, class string
+		var nextRetryAt sql.NullTime
+		if err := rows.Scan(&op.ID, &op.UserID, &op.Kind, &op.ContextID, &op.ContextName, &op.NewName,
+			&status, &op.RetryCount, &op.Error, &class, &nextRetryAt, &op.CreatedAt); err != nil {
+			return nil, err
+		}
+		op.Status = models.SyncStatus(status)
+		op.ErrorClass = models.SyncErrorClass(class)
+		if nextRetryAt.Valid {
+			op.NextRetryAt = &nextRetryAt.Time
+		}
+		ops = append(ops, op)
+	}
+	return ops, rows.Err()
+}
+
+// CompleteSyncOperation removes an operation once it was applied
+func (r *Repository) CompleteSyncOperation(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM sync_operations WHERE id = ?`, id)
+	return err
+}
+
+// MarkSyncOperationFailed records another failed attempt and when the operation
+// is due again. Like MarkNoteSyncFailed, failures whose class counts as a retry
+// increment the retry count and abandon the operation once MaxSyncRetries is reached.
+func (r *Repository) MarkSyncOperationFailed(ctx context.Context, id int64, errorMsg string, class models.SyncErrorClass, nextRetryAt time.Time) error {
+	counted := 0
+	if class.CountsAsRetry() {
+		counted = 1
+	}
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE sync_operations SET
+			status = CASE
+				WHEN retry_count + ? >= ? THEN ?
+				ELSE ?
+			END,
+			retry_count = retry_count + ?,
+			error = ?,
+			error_class = ?,
+			next_retry_at = ?
+		WHERE id = ?
+	`, counted, models.MaxSyncRetries, string(models.SyncStatusAbandoned), string(models.SyncStatusFailed),
+		counted, errorMsg, string(class), nextRetryAt, id)
+	return err
+}
+
+// ReleaseAuthFailedSyncOperations makes a user's operations that failed for lack
+// of a valid sign-in due right away. Called after the user signs in again.
+func (r *Repository) ReleaseAuthFailedSyncOperations(ctx context.Context, userID string) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE sync_operations SET next_retry_at = NULL
+		WHERE user_id = ? AND status = ? AND error_class = ?
+	`, userID, string(models.SyncStatusFailed), string(models.SyncErrorAuth))
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
+
+// RetryUserSyncOperations gives a user's failed and abandoned operations a fresh
+// start, due right away
+func (r *Repository) RetryUserSyncOperations(ctx context.Context, userID string) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE sync_operations SET
+			status = ?,
+			retry_count = 0,
+			next_retry_at = NULL
+		WHERE user_id = ?
+	`, string(models.SyncStatusFailed), userID)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncOperations(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	rename := &models.SyncOperation{UserID: "test-user", Kind: models.SyncOperationRename, ContextID: "ctx-work", ContextName: "Work", NewName: "Projects", Error: "connection reset", CreatedAt: now}
+	remove := &models.SyncOperation{UserID: "test-user", Kind: models.SyncOperationDelete, ContextID: "ctx-old", ContextName: "Old", Error: "connection reset", CreatedAt: now}
+	require.NoError(t, repo.RecordSyncOperation(ctx, rename))
+	require.NoError(t, repo.RecordSyncOperation(ctx, remove))
+	assert.NotZero(t, rename.ID)
+
+	t.Run("Recorded operations are due, oldest first", func(t *testing.T) {
+		ops, err := repo.GetFailedSyncOperations(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, ops, 2)
+		assert.Equal(t, rename.ID, ops[0].ID)
+		assert.Equal(t, "Projects", ops[0].NewName)
+		assert.Equal(t, models.SyncStatusFailed, ops[0].Status)
+		assert.Equal(t, "connection reset", ops[0].Error)
+		assert.Nil(t, ops[0].NextRetryAt)
+		assert.Equal(t, models.SyncOperationDelete, ops[1].Kind)
+	})
+
+	t.Run("Counted failures abandon operations", func(t *testing.T) {
+		for i := 0; i < models.MaxSyncRetries; i++ {
+			require.NoError(t, repo.MarkSyncOperationFailed(ctx, remove.ID, "bad request", models.SyncErrorPayload, now.Add(time.Hour)))
+		}
+		ops, err := repo.GetFailedSyncOperations(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, ops, 1, "abandoned operations aren't retried")
+
+		ops, err = repo.GetUserSyncOperations(ctx, "test-user")
+		require.NoError(t, err)
+		require.Len(t, ops, 2)
+		assert.Equal(t, models.SyncStatusAbandoned, ops[1].Status)
+		assert.Equal(t, models.MaxSyncRetries, ops[1].RetryCount)
+		assert.Equal(t, models.SyncErrorPayload, ops[1].ErrorClass)
+	})
+
+	t.Run("Sign-ins release auth failures", func(t *testing.T) {
+		require.NoError(t, repo.MarkSyncOperationFailed(ctx, rename.ID, "token expired", models.SyncErrorAuth, now.Add(24*time.Hour)))
+		released, err := repo.ReleaseAuthFailedSyncOperations(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, 1, released)
+
+		ops, err := repo.GetFailedSyncOperations(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, ops, 1)
+		assert.Nil(t, ops[0].NextRetryAt)
+		assert.Zero(t, ops[0].RetryCount, "auth failures don't count")
+	})
+
+	t.Run("Retrying gives every operation a fresh start", func(t *testing.T) {
+		count, err := repo.RetryUserSyncOperations(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		ops, err := repo.GetFailedSyncOperations(ctx, 10)
+		require.NoError(t, err)
+		require.Len(t, ops, 2)
+		assert.Zero(t, ops[1].RetryCount)
+	})
+
+	t.Run("Completed operations are removed", func(t *testing.T) {
+		require.NoError(t, repo.CompleteSyncOperation(ctx, rename.ID))
+		ops, err := repo.GetUserSyncOperations(ctx, "test-user")
+		require.NoError(t, err)
+		require.Len(t, ops, 1)
+		assert.Equal(t, remove.ID, ops[0].ID)
+	})
+}
+
+func TestRetryUserSyncNotes(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, date := range []string{"2025-10-15", "2025-10-16", "2025-10-17"} {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: date, Content: date, CreatedAt: time.Now(), UpdatedAt: time.Now()}, true))
+	}
+	// A failed upload, an abandoned deletion and a note that synced
+	require.NoError(t, repo.MarkNoteSyncFailed(ctx, "test-user-Work-2025-10-15", "timeout", models.SyncErrorNetwork, time.Now().Add(time.Hour)))
+	require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16"))
+	require.NoError(t, repo.MarkNoteAsNotPending(ctx, "test-user-Work-2025-10-16"))
+	require.NoError(t, repo.MarkNoteSynced(ctx, "test-user-Work-2025-10-17", "file-17", "hash"))
+
+	count, err := repo.RetryUserSyncNotes(ctx, "test-user")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	pending, err := repo.GetPendingSyncNotes(ctx, 10)
+	require.NoError(t, err)
+	ids := make([]string, 0, len(pending))
+	for _, note := range pending {
+		ids = append(ids, note.ID)
+		assert.Zero(t, note.SyncRetryCount)
+	}
+	assert.ElementsMatch(t, []string{"test-user-Work-2025-10-15", "test-user-Work-2025-10-16"}, ids)
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/pkg/markdown"
+	"time"
+)
+
+// ==================== PINNED NOTES ====================
+
+// SetNotePinned pins a note to the top of its context at pinnedAt, or unpins it
+// when pinnedAt is nil. Pinning a pinned note keeps its original pin time.
+// Returns false if there is no such note.
+func (r *Repository) SetNotePinned(ctx context.Context, userID, contextName, date string, pinnedAt *time.Time) (bool, error) {
+	if pinnedAt == nil {
+		return affected(r.db.ExecContext(ctx, `
+			UPDATE notes SET pinned_at = NULL
+			WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0
+		`, userID, contextName, date))
+	}
+	return affected(r.db.ExecContext(ctx, `
+		UPDATE notes SET pinned_at = COALESCE(pinned_at, ?)
+		WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0
+	`, *pinnedAt, userID, contextName, date))
+}
+
+// GetPinnedNotes retrieves the pinned notes of a context, most recently pinned
+// first, without their content like GetNotesByContext
+func (r *Repository) GetPinnedNotes(ctx context.Context, userID, contextName string) ([]models.Note, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, context, date, granularity, content, word_count, char_count, created_at, updated_at
+		FROM notes
+		WHERE user_id = ? AND context = ? AND deleted = 0 AND pinned_at IS NOT NULL
+		ORDER BY pinned_at DESC, date DESC
+	`, userID, contextName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.Note{}
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(
+			&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type,
+			&note.Content, &note.WordCount, &note.CharCount, &note.CreatedAt, &note.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		note.Tags = markdown.ExtractHashtags(note.Content)
+		note.Encrypted = e2ee.IsEncrypted(note.Content)
+		note.Pinned = true
+		note.Content = ""
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
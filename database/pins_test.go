@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinnedNotes(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, date := range []string{"2025-10-15", "2025-10-16", "2025-10-17"} {
+		note := &models.Note{
+			UserID: "test-user", Context: "Work", Date: date, Content: "Retro #team",
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}
+		require.NoError(t, repo.UpsertNote(ctx, note, false))
+	}
+	pin := func(date string, at time.Time) {
+		pinned, err := repo.SetNotePinned(ctx, "test-user", "Work", date, &at)
+		require.NoError(t, err)
+		require.True(t, pinned)
+	}
+	pinnedDates := func() []string {
+		notes, err := repo.GetPinnedNotes(ctx, "test-user", "Work")
+		require.NoError(t, err)
+		dates := []string{}
+		for _, note := range notes {
+			assert.True(t, note.Pinned)
+			assert.Empty(t, note.Content)
+			dates = append(dates, note.Date)
+		}
+		return dates
+	}
+
+	monday := time.Date(2025, 10, 20, 9, 0, 0, 0, time.UTC)
+	pin("2025-10-15", monday)
+	pin("2025-10-16", monday.Add(time.Hour))
+	assert.Equal(t, []string{"2025-10-16", "2025-10-15"}, pinnedDates())
+
+	t.Run("Pinning again keeps the pin time", func(t *testing.T) {
+		pin("2025-10-15", monday.Add(2*time.Hour))
+		assert.Equal(t, []string{"2025-10-16", "2025-10-15"}, pinnedDates())
+	})
+
+	t.Run("Notes and lists say whether a note is pinned", func(t *testing.T) {
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.True(t, note.Pinned)
+
+		notes, err := repo.GetNotesByContext(ctx, ScopeUser("test-user"), "Work", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, notes, 3)
+		assert.False(t, notes[0].Pinned)
+		assert.True(t, notes[1].Pinned)
+	})
+
+	t.Run("Unpinning", func(t *testing.T) {
+		unpinned, err := repo.SetNotePinned(ctx, "test-user", "Work", "2025-10-16", nil)
+		require.NoError(t, err)
+		assert.True(t, unpinned)
+		assert.Equal(t, []string{"2025-10-15"}, pinnedDates())
+	})
+
+	t.Run("Deleted notes lose their pin", func(t *testing.T) {
+		require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-15"))
+		assert.Empty(t, pinnedDates())
+
+		note := &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-15", Content: "Rewritten", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		require.NoError(t, repo.UpsertNote(ctx, note, false))
+		assert.Empty(t, pinnedDates())
+	})
+
+	t.Run("Missing and other users' notes", func(t *testing.T) {
+		pinned, err := repo.SetNotePinned(ctx, "test-user", "Work", "2025-01-01", &monday)
+		require.NoError(t, err)
+		assert.False(t, pinned)
+
+		pinned, err = repo.SetNotePinned(ctx, "other-user", "Work", "2025-10-17", &monday)
+		require.NoError(t, err)
+		assert.False(t, pinned)
+	})
+}
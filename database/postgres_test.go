@@ -0,0 +1,110 @@
+//go:build postgres
+
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Runs with TEST_POSTGRES_DSN=postgres://... go test -tags postgres ./database:
+// each test gets a schema of its own in that database, dropped afterwards
+func openPostgres(t *testing.T) *DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	admin, err := Open(Postgres, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	_, err = admin.Exec(`CREATE SCHEMA ` + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	db, err := Open(Postgres, u.String())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestPostgresMigrate(t *testing.T) {
+	db := openPostgres(t)
+	require.NoError(t, db.Migrate())
+	require.NoError(t, db.Migrate(), "migrating again is a no-op")
+
+	version, err := db.SchemaVersion()
+	require.NoError(t, err)
+	latest, err := db.LatestSchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+
+	version, err = db.MigrateDown(0)
+	require.NoError(t, err)
+	assert.Zero(t, version)
+	var tables int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name <> 'schema_migrations'`).Scan(&tables))
+	assert.Zero(t, tables, "every migration can be reverted")
+
+	require.NoError(t, db.Migrate(), "reverted databases migrate again")
+}
+
+func TestPostgresRepository(t *testing.T) {
+	db := openPostgres(t)
+	require.NoError(t, db.Migrate())
+	repo := NewRepository(db)
+	ctx := context.Background()
+
+	require.NoError(t, repo.UpsertUser(ctx, &models.User{ID: "test-user", GoogleID: "google-123", Email: "test@example.com", CreatedAt: time.Now()}))
+	require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-work", UserID: "test-user", Name: "Work", Color: "primary"}))
+
+	note := &models.Note{
+		UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "Shipped the #Release",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}
+	require.NoError(t, repo.UpsertNote(ctx, note, true))
+
+	t.Run("Notes are saved and read back", func(t *testing.T) {
+		saved, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, saved)
+		assert.Equal(t, "Shipped the #Release", saved.Content)
+		assert.Equal(t, 1, saved.Revision)
+
+		var size int
+		require.NoError(t, db.QueryRow(`SELECT content_size FROM notes WHERE id = ?`, saved.ID).Scan(&size))
+		assert.Equal(t, len(note.Content), size, "the triggers of the dialect keep the size")
+	})
+
+	t.Run("Boolean arguments are stored as flags", func(t *testing.T) {
+		var pending int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM notes WHERE sync_pending = ?`, true).Scan(&pending))
+		assert.Equal(t, 1, pending)
+	})
+
+	t.Run("Tags and search use the dialect", func(t *testing.T) {
+		tagged, err := repo.GetNotesByTag(ctx, "test-user", "release", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, tagged, 1)
+
+		results, err := repo.SearchNotes(ctx, "test-user", "shipped", models.NoteSearchFilter{}, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1, "search is case-insensitive")
+		assert.Equal(t, "2025-10-16", results[0].Date)
+	})
+}
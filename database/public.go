@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/visibility"
+	"database/sql"
+	"errors"
+)
+
+// ==================== PUBLIC CONTEXTS ====================
+
+// GetPublicContext returns the context published under handle, nil if there is none
+func (r *Repository) GetPublicContext(ctx context.Context, handle string) (*models.PublicContext, error) {
+	var p models.PublicContext
+	err := r.db.QueryRowContext(ctx, `
+		SELECT p.handle, p.context_id, c.name, p.user_id, p.indexable, p.created_at
+		FROM public_contexts p
+		JOIN contexts c ON c.id = p.context_id AND c.user_id = p.user_id
+		WHERE p.handle = ?
+	`, handle).Scan(&p.Handle, &p.ContextID, &p.Context, &p.UserID, &p.Indexable, &p.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetPublicContexts returns the published contexts of a user, by handle
+func (r *Repository) GetPublicContexts(ctx context.Context, userID string) ([]models.PublicContext, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT p.handle, p.context_id, c.name, p.user_id, p.indexable, p.created_at
+		FROM public_contexts p
+		JOIN contexts c ON c.id = p.context_id AND c.user_id = p.user_id
+		WHERE p.user_id = ?
+		ORDER BY p.handle
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	published := []models.PublicContext{}
+	for rows.Next() {
+		var p models.PublicContext
+		if err := rows.Scan(&p.Handle, &p.ContextID, &p.Context, &p.UserID, &p.Indexable, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		published = append(published, p)
+	}
+	return published, rows.Err()
+}
+
+// PublishContext publishes a context under p.Handle, replacing the handle and
+// settings it was published with before
+func (r *Repository) PublishContext(ctx context.Context, p *models.PublicContext) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO public_contexts (handle, context_id, user_id, indexable, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(context_id) DO UPDATE SET
+			handle = excluded.handle,
+			indexable = excluded.indexable
+	`, p.Handle, p.ContextID, p.UserID, p.Indexable, p.CreatedAt)
+	return err
+}
+
+// UnpublishContext takes a user's context off its public page
+// Returns false when the context wasn't published.
+func (r *Repository) UnpublishContext(ctx context.Context, userID, contextID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM public_contexts WHERE user_id = ? AND context_id = ?
+	`, userID, contextID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetPublicNotes returns up to limit daily notes of a context with content, newest first
+func (r *Repository) GetPublicNotes(ctx context.Context, userID, contextName string, limit int) ([]models.Note, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, context, date, granularity, content, revision, created_at, updated_at
+		FROM notes
+		WHERE user_id = ? AND context = ? AND granularity = 'day' AND `+visibleCondition("notes", visibility.Public)+` AND TRIM(content) != ''
+		ORDER BY date DESC
+		LIMIT ?
+	`, userID, contextName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(
+			&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type,
+			&note.Content, &note.Revision, &note.CreatedAt, &note.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
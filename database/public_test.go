@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicContexts(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-garden", UserID: "test-user", Name: "Garden", Color: "success", CreatedAt: now}))
+
+	t.Run("Unknown handle", func(t *testing.T) {
+		p, err := repo.GetPublicContext(ctx, "garden")
+		require.NoError(t, err)
+		assert.Nil(t, p)
+	})
+
+	t.Run("Publishing again changes the handle", func(t *testing.T) {
+		require.NoError(t, repo.PublishContext(ctx, &models.PublicContext{Handle: "garden", ContextID: "ctx-garden", UserID: "test-user", CreatedAt: now}))
+		require.NoError(t, repo.PublishContext(ctx, &models.PublicContext{Handle: "my-garden", ContextID: "ctx-garden", UserID: "test-user", Indexable: true, CreatedAt: now}))
+
+		p, err := repo.GetPublicContext(ctx, "garden")
+		require.NoError(t, err)
+		assert.Nil(t, p)
+
+		p, err = repo.GetPublicContext(ctx, "my-garden")
+		require.NoError(t, err)
+		require.NotNil(t, p)
+		assert.Equal(t, "Garden", p.Context)
+		assert.Equal(t, "test-user", p.UserID)
+		assert.True(t, p.Indexable)
+
+		published, err := repo.GetPublicContexts(ctx, "test-user")
+		require.NoError(t, err)
+		require.Len(t, published, 1)
+		assert.Equal(t, "my-garden", published[0].Handle)
+	})
+
+	t.Run("Public notes are daily notes with content, newest first", func(t *testing.T) {
+		for _, note := range []models.Note{
+			{Date: "2025-10-14", Content: "Seeds"},
+			{Date: "2025-10-15", Content: "  "},
+			{Date: "2025-10-16", Content: "Sprouts"},
+			{Date: "2025-W42", Type: "week", Content: "Week plan"},
+		} {
+			note.UserID, note.Context, note.CreatedAt, note.UpdatedAt = "test-user", "Garden", now, now
+			require.NoError(t, repo.UpsertNote(ctx, &note, false))
+		}
+
+		notes, err := repo.GetPublicNotes(ctx, "test-user", "Garden", 10)
+		require.NoError(t, err)
+		require.Len(t, notes, 2)
+		assert.Equal(t, "2025-10-16", notes[0].Date)
+		assert.Equal(t, "2025-10-14", notes[1].Date)
+
+		notes, err = repo.GetPublicNotes(ctx, "test-user", "Garden", 1)
+		require.NoError(t, err)
+		assert.Len(t, notes, 1)
+	})
+
+	t.Run("Unpublish", func(t *testing.T) {
+		removed, err := repo.UnpublishContext(ctx, "other-user", "ctx-garden")
+		require.NoError(t, err)
+		assert.False(t, removed)
+
+		removed, err = repo.UnpublishContext(ctx, "test-user", "ctx-garden")
+		require.NoError(t, err)
+		assert.True(t, removed)
+
+		p, err := repo.GetPublicContext(ctx, "my-garden")
+		require.NoError(t, err)
+		assert.Nil(t, p)
+	})
+}
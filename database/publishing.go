@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/pkg/markdown"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ==================== PUBLISHING ====================
+
+const publishTargetColumns = `id, user_id, kind, name, url, username, secret, repo, branch, dir, site_url, created_at`
+
+// scanPublishTarget reads a publish target, decrypting its secret
+func (r *Repository) scanPublishTarget(row interface{ Scan(...any) error }) (*models.PublishTarget, error) {
+	var t models.PublishTarget
+	err := row.Scan(&t.ID, &t.UserID, &t.Kind, &t.Name, &t.URL, &t.Username, &t.Secret,
+		&t.Repo, &t.Branch, &t.Dir, &t.SiteURL, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if t.Secret, err = r.secrets.Open(publishSecretColumn, t.Secret); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreatePublishTarget stores a user's publish target, its secret encrypted
+// when a key is set
+func (r *Repository) CreatePublishTarget(ctx context.Context, t *models.PublishTarget) error {
+	secret, err := r.secrets.Seal(publishSecretColumn, t.Secret)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO publish_targets (`+publishTargetColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.ID, t.UserID, t.Kind, t.Name, t.URL, t.Username, secret, t.Repo, t.Branch, t.Dir, t.SiteURL, t.CreatedAt)
+	return err
+}
+
+// GetPublishTargets returns a user's publish targets, oldest first
+func (r *Repository) GetPublishTargets(ctx context.Context, userID string) ([]models.PublishTarget, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+publishTargetColumns+`
+		FROM publish_targets
+		WHERE user_id = ?
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := []models.PublishTarget{}
+	for rows.Next() {
+		t, err := r.scanPublishTarget(rows)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, *t)
+	}
+	return targets, rows.Err()
+}
+
+// GetPublishTarget returns one of a user's publish targets, nil if there is none
+func (r *Repository) GetPublishTarget(ctx context.Context, userID, targetID string) (*models.PublishTarget, error) {
+	t, err := r.scanPublishTarget(r.db.QueryRowContext(ctx, `
+		SELECT `+publishTargetColumns+`
+		FROM publish_targets
+		WHERE user_id = ? AND id = ?
+	`, userID, targetID))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// DeletePublishTarget deletes a user's publish target and its publication records
+// Posts already published stay on the blog. Returns false when there was no such target.
+func (r *Repository) DeletePublishTarget(ctx context.Context, userID, targetID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM publish_targets WHERE user_id = ? AND id = ?
+	`, userID, targetID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+const publicationColumns = `id, user_id, note_id, target_id, status, publish_at, attempts,
+	external_id, external_url, error, published_at, updated_at`
+
+func scanPublication(row interface{ Scan(...any) error }) (*models.NotePublication, error) {
+	var p models.NotePublication
+	var publishedAt sql.NullTime
+	err := row.Scan(&p.ID, &p.UserID, &p.NoteID, &p.TargetID, &p.Status, &p.PublishAt, &p.Attempts,
+		&p.ExternalID, &p.ExternalURL, &p.Error, &publishedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if publishedAt.Valid {
+		p.PublishedAt = &publishedAt.Time
+	}
+	return &p, nil
+}
+
+func scanPublications(rows *sql.Rows) ([]models.NotePublication, error) {
+	defer rows.Close()
+
+	publications := []models.NotePublication{}
+	for rows.Next() {
+		p, err := scanPublication(rows)
+		if err != nil {
+			return nil, err
+		}
+		publications = append(publications, *p)
+	}
+	return publications, rows.Err()
+}
+
+// QueuePublication queues a user's note for publishing to p.TargetID at p.PublishAt
+// A note published to the target before keeps its external ID, so the post is
+// updated. p.ID and p.NoteID are set to the stored publication's. Returns
+// false when the note doesn't exist.
+func (r *Repository) QueuePublication(ctx context.Context, p *models.NotePublication, contextName, date string) (bool, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO note_publications (id, user_id, note_id, target_id, status, publish_at, attempts, updated_at)
+		SELECT ?, user_id, id, ?, ?, ?, 0, ?
+		FROM notes
+		WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0
+		ON CONFLICT(note_id, target_id) DO UPDATE SET
+			status = excluded.status,
+			publish_at = excluded.publish_at,
+			attempts = 0,
+			error = '',
+			updated_at = excluded.updated_at
+		RETURNING id, note_id
+	`, p.ID, p.TargetID, models.PublicationPending, p.PublishAt, p.UpdatedAt,
+		p.UserID, contextName, date).Scan(&p.ID, &p.NoteID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	p.Status = models.PublicationPending
+	return true, nil
+}
+
+// GetNotePublications returns the publications of a user's note, by target
+func (r *Repository) GetNotePublications(ctx context.Context, userID, contextName, date string) ([]models.NotePublication, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+publicationColumns+`
+		FROM note_publications
+		WHERE user_id = ? AND note_id = (
+			SELECT id FROM notes WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0
+		)
+		ORDER BY target_id
+	`, userID, userID, contextName, date)
+	if err != nil {
+		return nil, err
+	}
+	return scanPublications(rows)
+}
+
+// GetDuePublications returns up to limit pending publications of all users due by now, oldest first
+func (r *Repository) GetDuePublications(ctx context.Context, now time.Time, limit int) ([]models.NotePublication, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+publicationColumns+`
+		FROM note_publications
+		WHERE status = ? AND publish_at <= ?
+		ORDER BY publish_at
+		LIMIT ?
+	`, models.PublicationPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanPublications(rows)
+}
+
+// GetPublicationNote returns the note of a publication, nil once it was deleted
+func (r *Repository) GetPublicationNote(ctx context.Context, p models.NotePublication) (*models.Note, error) {
+	var note models.Note
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, user_id, context, date, granularity, content, revision, created_at, updated_at
+		FROM notes
+		WHERE id = ? AND user_id = ? AND deleted = 0
+	`, p.NoteID, p.UserID).Scan(
+		&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type,
+		&note.Content, &note.Revision, &note.CreatedAt, &note.UpdatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	note.Tags = markdown.ExtractHashtags(note.Content)
+	note.Encrypted = e2ee.IsEncrypted(note.Content)
+	return &note, nil
+}
+
+// SavePublication stores the outcome of a publishing attempt on a publication
+// last updated at queuedAt. Returns false when it was queued again meanwhile,
+// leaving the new request in place.
+func (r *Repository) SavePublication(ctx context.Context, p *models.NotePublication, queuedAt time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE note_publications SET
+			status = ?, publish_at = ?, attempts = ?, external_id = ?, external_url = ?,
+			error = ?, published_at = ?, updated_at = ?
+		WHERE id = ? AND updated_at = ?
+	`, p.Status, p.PublishAt, p.Attempts, p.ExternalID, p.ExternalURL, p.Error, p.PublishedAt, p.UpdatedAt,
+		p.ID, queuedAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishing(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+		UserID: "test-user", Context: "Garden", Date: "2025-10-16", Content: "# Tomatoes",
+		CreatedAt: now, UpdatedAt: now,
+	}, false))
+	require.NoError(t, repo.CreatePublishTarget(ctx, &models.PublishTarget{
+		ID: "blog", UserID: "test-user", Kind: "ghost", Name: "Blog", URL: "https://blog.example.com",
+		Secret: "id:00", CreatedAt: now,
+	}))
+
+	t.Run("Targets", func(t *testing.T) {
+		targets, err := repo.GetPublishTargets(ctx, "test-user")
+		require.NoError(t, err)
+		require.Len(t, targets, 1)
+		assert.Equal(t, "id:00", targets[0].Secret)
+
+		target, err := repo.GetPublishTarget(ctx, "other-user", "blog")
+		require.NoError(t, err)
+		assert.Nil(t, target)
+	})
+
+	queue := func(at time.Time) *models.NotePublication {
+		p := &models.NotePublication{ID: "pub-" + at.Format("150405"), UserID: "test-user", TargetID: "blog", PublishAt: at, UpdatedAt: at}
+		found, err := repo.QueuePublication(ctx, p, "Garden", "2025-10-16")
+		require.NoError(t, err)
+		require.True(t, found)
+		return p
+	}
+
+	t.Run("Missing notes aren't queued", func(t *testing.T) {
+		found, err := repo.QueuePublication(ctx, &models.NotePublication{ID: "x", UserID: "test-user", TargetID: "blog", PublishAt: now, UpdatedAt: now}, "Garden", "2025-10-17")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Due publications and their outcome", func(t *testing.T) {
+		queued := queue(now.Add(time.Hour))
+
+		due, err := repo.GetDuePublications(ctx, now, 10)
+		require.NoError(t, err)
+		assert.Empty(t, due, "scheduled for later")
+
+		due, err = repo.GetDuePublications(ctx, now.Add(time.Hour), 10)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.Equal(t, queued.NoteID, due[0].NoteID)
+
+		note, err := repo.GetPublicationNote(ctx, due[0])
+		require.NoError(t, err)
+		require.NotNil(t, note)
+		assert.Equal(t, "# Tomatoes", note.Content)
+
+		published := due[0]
+		publishedAt := now.Add(2 * time.Hour)
+		published.Status = models.PublicationPublished
+		published.Attempts = 1
+		published.ExternalID = "post-1"
+		published.ExternalURL = "https://blog.example.com/tomatoes/"
+		published.PublishedAt = &publishedAt
+		published.UpdatedAt = publishedAt
+		saved, err := repo.SavePublication(ctx, &published, due[0].UpdatedAt)
+		require.NoError(t, err)
+		assert.True(t, saved)
+
+		publications, err := repo.GetNotePublications(ctx, "test-user", "Garden", "2025-10-16")
+		require.NoError(t, err)
+		require.Len(t, publications, 1)
+		assert.Equal(t, models.PublicationPublished, publications[0].Status)
+		assert.Equal(t, "https://blog.example.com/tomatoes/", publications[0].ExternalURL)
+		require.NotNil(t, publications[0].PublishedAt)
+	})
+
+	t.Run("Publishing again keeps the external ID", func(t *testing.T) {
+		requeued := queue(now.Add(3 * time.Hour))
+		assert.Equal(t, "pub-130000", requeued.ID, "the existing publication is reused")
+
+		due, err := repo.GetDuePublications(ctx, now.Add(3*time.Hour), 10)
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.Equal(t, "post-1", due[0].ExternalID)
+		assert.Equal(t, 0, due[0].Attempts)
+
+		// An outcome for the earlier request doesn't overwrite the new one
+		stale := due[0]
+		stale.Status = models.PublicationFailed
+		saved, err := repo.SavePublication(ctx, &stale, now)
+		require.NoError(t, err)
+		assert.False(t, saved)
+	})
+
+	t.Run("Deleting a target deletes its publications", func(t *testing.T) {
+		deleted, err := repo.DeletePublishTarget(ctx, "test-user", "blog")
+		require.NoError(t, err)
+		assert.True(t, deleted)
+
+		publications, err := repo.GetNotePublications(ctx, "test-user", "Garden", "2025-10-16")
+		require.NoError(t, err)
+		assert.Empty(t, publications)
+	})
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+)
+
+// ==================== WEB PUSH ====================
+
+// SavePushSubscription saves the push subscription of a browser. A browser
+// subscribing again, possibly for another user after signing in as them,
+// replaces its subscription.
+func (r *Repository) SavePushSubscription(ctx context.Context, subscription *models.PushSubscription) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(endpoint) DO UPDATE SET
+			user_id = excluded.user_id, p256dh = excluded.p256dh, auth = excluded.auth, created_at = excluded.created_at
+	`, subscription.UserID, subscription.Endpoint, subscription.P256dh, subscription.Auth, subscription.CreatedAt)
+	return err
+}
+
+// GetPushSubscriptions returns the push subscriptions of a user's browsers, oldest first
+func (r *Repository) GetPushSubscriptions(ctx context.Context, userID string) ([]models.PushSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, endpoint, p256dh, auth, created_at
+		FROM push_subscriptions
+		WHERE user_id = ?
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []models.PushSubscription
+	for rows.Next() {
+		var s models.PushSubscription
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Endpoint, &s.P256dh, &s.Auth, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+// DeletePushSubscription deletes the user's subscription of endpoint; it reports
+// false if there was none
+func (r *Repository) DeletePushSubscription(ctx context.Context, userID, endpoint string) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `DELETE FROM push_subscriptions WHERE user_id = ? AND endpoint = ?`, userID, endpoint))
+}
+
+// DeletePushSubscriptionByID deletes a subscription the push service reports gone
+func (r *Repository) DeletePushSubscriptionByID(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM push_subscriptions WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushSubscriptions(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	require.NoError(t, repo.UpsertUser(ctx, &models.User{ID: "other-user", GoogleID: "google-456", Email: "other@example.com", CreatedAt: time.Now()}))
+
+	subscription := &models.PushSubscription{UserID: "test-user", Endpoint: "https://push.example.com/1", P256dh: "key-1", Auth: "auth-1", CreatedAt: time.Now()}
+	require.NoError(t, repo.SavePushSubscription(ctx, subscription))
+	require.NoError(t, repo.SavePushSubscription(ctx, &models.PushSubscription{UserID: "test-user", Endpoint: "https://push.example.com/2", P256dh: "key-2", Auth: "auth-2", CreatedAt: time.Now()}))
+
+	subscriptions, err := repo.GetPushSubscriptions(ctx, "test-user")
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 2)
+	assert.Equal(t, "key-1", subscriptions[0].P256dh)
+
+	t.Run("A browser subscribing for another user moves to them", func(t *testing.T) {
+		require.NoError(t, repo.SavePushSubscription(ctx, &models.PushSubscription{UserID: "other-user", Endpoint: "https://push.example.com/1", P256dh: "key-3", Auth: "auth-3", CreatedAt: time.Now()}))
+		subscriptions, err := repo.GetPushSubscriptions(ctx, "test-user")
+		require.NoError(t, err)
+		require.Len(t, subscriptions, 1)
+		assert.Equal(t, "https://push.example.com/2", subscriptions[0].Endpoint)
+
+		subscriptions, err = repo.GetPushSubscriptions(ctx, "other-user")
+		require.NoError(t, err)
+		require.Len(t, subscriptions, 1)
+		assert.Equal(t, "key-3", subscriptions[0].P256dh)
+	})
+
+	t.Run("Users delete only their own subscriptions", func(t *testing.T) {
+		deleted, err := repo.DeletePushSubscription(ctx, "test-user", "https://push.example.com/1")
+		require.NoError(t, err)
+		assert.False(t, deleted)
+		deleted, err = repo.DeletePushSubscription(ctx, "test-user", "https://push.example.com/2")
+		require.NoError(t, err)
+		assert.True(t, deleted)
+	})
+}
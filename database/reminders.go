@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/visibility"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ==================== REMINDERS ====================
+
+// saveNoteReminders replaces the reminders of a live note with the @remind(...)
+// tokens of its content. A reminder keeps its row, and its status, while the
+// note has one at the same time with the same text, so a sent reminder isn't
+// sent again when the note is edited.
+func saveNoteReminders(ctx context.Context, db execer, note *models.Note) error {
+	reminders := markdown.ExtractReminders(note.Content)
+
+	args := []any{note.UserID, note.Context, note.Date}
+	keep := ""
+	if len(reminders) > 0 {
+		keep = " AND NOT (" + strings.TrimPrefix(strings.Repeat(" OR (remind_at = ? AND text = ?)", len(reminders)), " OR ") + ")"
+		for _, reminder := range reminders {
+			args = append(args, reminder.At, reminder.Text)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `
+		DELETE FROM reminders
+		WHERE note_id = (SELECT id FROM notes WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0)`+keep,
+		args...); err != nil {
+		return err
+	}
+
+	for _, reminder := range reminders {
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO reminders (user_id, note_id, line, text, remind_at)
+			SELECT n.user_id, n.id, ?, ?, ?
+			FROM notes n
+			WHERE n.user_id = ? AND n.context = ? AND n.date = ? AND n.deleted = 0
+			ON CONFLICT(note_id, remind_at, text) DO UPDATE SET line = excluded.line
+		`, reminder.Line, reminder.Text, reminder.At, note.UserID, note.Context, note.Date); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillReminders saves the reminders of the notes that existed before the reminders table
+func backfillReminders(tx *Tx) error {
+	notes, err := liveNotesContaining(tx, "@remind(")
+	if err != nil {
+		return err
+	}
+	for i := range notes {
+		if err := saveNoteReminders(context.Background(), tx, &notes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reminderSelect selects the columns scanned by scanReminder: reminders r with
+// the note n they're written in, if any, and the timezone of their user. Notes
+// are shown by the app policy, and API reminders always are.
+var reminderSelect = `
+	SELECT r.id, r.user_id, COALESCE(n.context, ''), COALESCE(n.date, ''), r.line, r.text, r.remind_at, r.status,
+		CASE WHEN ` + localOnlyOf("n") + ` THEN 1 ELSE 0 END, COALESCE(u.settings_timezone, 'UTC'), r.sent_at, r.created_at
+	FROM reminders r
+	JOIN users u ON u.id = r.user_id
+	LEFT JOIN notes n ON n.id = r.note_id
+	WHERE (r.note_id IS NULL OR ` + visibleCondition("n", visibility.App) + `)`
+
+// CreateReminder saves a reminder made through the API and sets its ID
+func (r *Repository) CreateReminder(ctx context.Context, reminder *models.Reminder) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO reminders (user_id, text, remind_at, status, created_at) VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, reminder.UserID, reminder.Text, reminder.RemindAt, reminder.Status, reminder.CreatedAt).Scan(&reminder.ID)
+}
+
+// GetReminders returns a user's reminders, soonest first. status is one of the
+// reminder statuses, or "" for all of them.
+func (r *Repository) GetReminders(ctx context.Context, userID, status string, limit, offset int) ([]models.Reminder, error) {
+	query := reminderSelect + ` AND r.user_id = ?`
+	args := []any{userID}
+	if status != "" {
+		query += ` AND r.status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY r.remind_at ASC, r.id ASC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+	return r.queryReminders(ctx, query, args...)
+}
+
+// GetReminder returns a reminder of a user, nil if there is none with that ID
+func (r *Repository) GetReminder(ctx context.Context, userID string, id int64) (*models.Reminder, error) {
+	reminder, err := scanReminder(r.db.QueryRowContext(ctx, reminderSelect+` AND r.user_id = ? AND r.id = ?`, userID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return reminder, err
+}
+
+// GetDueReminders returns the pending reminders of all users whose local time
+// is at or before before; callers pass a time far enough ahead to cover every
+// timezone and compare with each user's own
+func (r *Repository) GetDueReminders(ctx context.Context, before string) ([]models.Reminder, error) {
+	return r.queryReminders(ctx, reminderSelect+` AND r.status = ? AND r.remind_at <= ? ORDER BY r.remind_at ASC, r.id ASC`,
+		models.ReminderPending, before)
+}
+
+// SetReminderStatus records that a reminder was sent, or missed, at at
+func (r *Repository) SetReminderStatus(ctx context.Context, id int64, status string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE reminders SET status = ?, sent_at = ? WHERE id = ?`, status, at, id)
+	return err
+}
+
+// DeleteReminder deletes a reminder made through the API; it reports false if
+// the user has none with that ID. Reminders of notes go away with their token.
+func (r *Repository) DeleteReminder(ctx context.Context, userID string, id int64) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `DELETE FROM reminders WHERE user_id = ? AND id = ? AND note_id IS NULL`, userID, id))
+}
+
+// queryReminders runs a query selecting reminderSelect columns
+func (r *Repository) queryReminders(ctx context.Context, query string, args ...any) ([]models.Reminder, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []models.Reminder{}
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, *reminder)
+	}
+	return reminders, rows.Err()
+}
+
+// scanReminder reads a row selected by reminderSelect
+func scanReminder(row interface{ Scan(...any) error }) (*models.Reminder, error) {
+	var reminder models.Reminder
+	var sentAt sql.NullTime
+	if err := row.Scan(
+		&reminder.ID, &reminder.UserID, &reminder.Context, &reminder.Date, &reminder.Line, &reminder.Text,
+		&reminder.RemindAt, &reminder.Status, &reminder.LocalOnly, &reminder.Timezone, &sentAt, &reminder.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if sentAt.Valid {
+		reminder.SentAt = &sentAt.Time
+	}
+	return &reminder, nil
+}
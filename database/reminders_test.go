@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReminders(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	save := func(contextName, date, content string) {
+		t.Helper()
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: contextName, Date: date, Content: content, CreatedAt: now, UpdatedAt: now,
+		}, true))
+	}
+	reminders := func(status string) []models.Reminder {
+		t.Helper()
+		reminders, err := repo.GetReminders(ctx, "test-user", status, 50, 0)
+		require.NoError(t, err)
+		return reminders
+	}
+
+	require.NoError(t, repo.UpdateUserSettings(ctx, "test-user", models.UserSettings{Theme: "dark", Timezone: "Europe/Madrid", DateFormat: "DD-MM-YY"}))
+	save("Work", "2025-10-16", "# Plan\n- [ ] Call Ana @remind(2025-11-02 09:30)\nRenew passport @remind(2025-12-01)")
+
+	t.Run("Reminders of notes are listed, soonest first", func(t *testing.T) {
+		all := reminders("")
+		require.Len(t, all, 2)
+		assert.Equal(t, "Work", all[0].Context)
+		assert.Equal(t, "2025-10-16", all[0].Date)
+		assert.Equal(t, 2, all[0].Line)
+		assert.Equal(t, "Call Ana", all[0].Text)
+		assert.Equal(t, "2025-11-02 09:30", all[0].RemindAt)
+		assert.Equal(t, models.ReminderPending, all[0].Status)
+		assert.Equal(t, "Europe/Madrid", all[0].Timezone)
+		assert.Equal(t, "2025-12-01 09:00", all[1].RemindAt)
+	})
+
+	t.Run("Sent reminders aren't sent again after edits", func(t *testing.T) {
+		first := reminders("")[0]
+		require.NoError(t, repo.SetReminderStatus(ctx, first.ID, models.ReminderSent, now))
+		save("Work", "2025-10-16", "# Plan for the week\n\n- [ ] Call Ana @remind(2025-11-02 09:30)")
+
+		all := reminders("")
+		require.Len(t, all, 1, "removed tokens remove their reminder")
+		assert.Equal(t, first.ID, all[0].ID)
+		assert.Equal(t, 3, all[0].Line)
+		assert.Equal(t, models.ReminderSent, all[0].Status)
+		require.NotNil(t, all[0].SentAt)
+		assert.Empty(t, reminders(models.ReminderPending))
+	})
+
+	t.Run("API reminders", func(t *testing.T) {
+		reminder := &models.Reminder{UserID: "test-user", Text: "Stretch", RemindAt: "2025-10-20 18:00", Status: models.ReminderPending, CreatedAt: now}
+		require.NoError(t, repo.CreateReminder(ctx, reminder))
+		assert.NotZero(t, reminder.ID)
+
+		got, err := repo.GetReminder(ctx, "test-user", reminder.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Stretch", got.Text)
+		assert.Empty(t, got.Context)
+
+		noteReminder := reminders(models.ReminderSent)[0]
+		deleted, err := repo.DeleteReminder(ctx, "test-user", noteReminder.ID)
+		require.NoError(t, err)
+		assert.False(t, deleted, "reminders of notes are deleted by editing the note")
+		deleted, err = repo.DeleteReminder(ctx, "test-user", reminder.ID)
+		require.NoError(t, err)
+		assert.True(t, deleted)
+	})
+
+	t.Run("Due reminders leave out deleted notes and mark local-only ones", func(t *testing.T) {
+		save("Home", "2025-10-17", "Water plants @remind(2025-10-18 08:00)")
+		save("Home", "2025-10-18", "Old @remind(2025-10-18 07:00)")
+		require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Home", "2025-10-18"))
+		_, err := repo.SetNoteLocalOnly(ctx, "test-user", "Home", "2025-10-17", true)
+		require.NoError(t, err)
+
+		due, err := repo.GetDueReminders(ctx, "2025-10-19 00:00")
+		require.NoError(t, err)
+		require.Len(t, due, 1)
+		assert.Equal(t, "Water plants", due[0].Text)
+		assert.True(t, due[0].LocalOnly)
+	})
+}
@@ -1,16 +1,59 @@
 package database
 
+import (
+	"context"
+	"daily-notes/pkg/seal"
+)
+
 // Repository provides database operations organized by domain
 // See domain-specific files:
 // - users.go: User and settings operations
 // - contexts.go: Context operations
 // - notes.go: Note CRUD operations
+// - note_changes.go: Changes of notes after a cursor, for delta sync
+// - revisions.go: Earlier versions of notes
+// - search.go: Full-text search over notes
+// - tags.go: #hashtags parsed from notes
+// - tasks.go: Checkbox list items parsed from notes
+// - reminders.go: Reminders parsed from notes or made through the API
+// - attachments.go: Files attached to notes
+// - api_tokens.go: Tokens letting integrations use one context
+// - api_keys.go: Keys letting scripts use the whole API, read-only or read-write
+// - idempotency.go: Responses replayed to writes retried with the same X-Idempotency-Key
+// - note_schedules.go: Local times at which daily notes are created from templates
+// - drops.go: Used nonces of signed drop box URLs
+// - digests.go: Subscriptions to the weekly email digest
+// - push.go: Browsers subscribed to web push notifications
+// - links.go: Previews of web pages linked from notes
+// - public.go: Contexts published read-only under a handle
+// - shares.go: Secret links showing one note read-only
+// - members.go: Accounts contexts are shared with, and invitations to them
+// - encryption.go: Key-derivation parameters of end-to-end encrypted notes
+// - publishing.go: External blogs notes are published to, and publication jobs
+// - sizes.go: Note content size statistics
+// - stats.go: Words and notes written per day, and streaks
 // - sync.go: Sync-related operations
+// - operations.go: Context folder changes in storage awaiting a retry
+// - jobs.go: Background jobs that survive restarts
+// - conflicts.go: Notes changed both locally and in storage
+// - changes.go: Notes pulled from storage after edits made there, change channels
+// - storage.go: Storage provider choice and provider credentials
+// - secrets.go: Encryption of provider tokens and publish target secrets at rest
+// - imports.go: Checkpoints of resumable imports from storage
+// - timezones.go: Timezone changes awaiting review, and the note dates they affect
+// - scope.go: UserScope, the user the context lookups and writes by ID are restricted to
 type Repository struct {
 	db *DB
+	// secrets encrypts credentials at rest once SetSecretKey is called
+	secrets *seal.Box
 }
 
 // NewRepository creates a new repository instance
 func NewRepository(db *DB) *Repository {
 	return &Repository{db: db}
 }
+
+// Ping checks that the database can be reached
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"daily-notes/models"
 	"os"
 	"path/filepath"
@@ -34,7 +35,7 @@ func setupTestRepo(t *testing.T) (*Repository, func()) {
 		Name:      "Test User",
 		CreatedAt: time.Now(),
 	}
-	err = repo.UpsertUser(testUser)
+	err = repo.UpsertUser(context.Background(), testUser)
 	require.NoError(t, err)
 
 	cleanup := func() {
@@ -48,6 +49,7 @@ func setupTestRepo(t *testing.T) (*Repository, func()) {
 func TestSyncStateManagement(t *testing.T) {
 	repo, cleanup := setupTestRepo(t)
 	defer cleanup()
+	ctx := context.Background()
 
 	t.Run("New note starts with pending status", func(t *testing.T) {
 		note := &models.Note{
@@ -59,10 +61,10 @@ func TestSyncStateManagement(t *testing.T) {
 			UpdatedAt: time.Now(),
 		}
 
-		err := repo.UpsertNote(note, true)
+		err := repo.UpsertNote(ctx, note, true)
 		require.NoError(t, err)
 
-		retrieved, err := repo.GetNote("test-user", "Work", "2025-10-17")
+		retrieved, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17")
 		require.NoError(t, err)
 		require.NotNil(t, retrieved)
 
@@ -82,14 +84,14 @@ func TestSyncStateManagement(t *testing.T) {
 			UpdatedAt: time.Now(),
 		}
 
-		err := repo.UpsertNote(note, true)
+		err := repo.UpsertNote(ctx, note, true)
 		require.NoError(t, err)
 
 		noteID := note.ID
-		err = repo.MarkNoteSyncing(noteID)
+		err = repo.MarkNoteSyncing(ctx, noteID)
 		require.NoError(t, err)
 
-		retrieved, err := repo.GetNote("test-user", "Personal", "2025-10-17")
+		retrieved, err := repo.GetNote(ctx, ScopeUser("test-user"), "Personal", "2025-10-17")
 		require.NoError(t, err)
 
 		assert.Equal(t, models.SyncStatusSyncing, retrieved.SyncStatus)
@@ -106,16 +108,16 @@ func TestSyncStateManagement(t *testing.T) {
 			UpdatedAt: time.Now(),
 		}
 
-		err := repo.UpsertNote(note, true)
+		err := repo.UpsertNote(ctx, note, true)
 		require.NoError(t, err)
 
 		noteID := note.ID
 		driveFileID := "drive-file-123"
 
-		err = repo.MarkNoteSynced(noteID, driveFileID)
+		err = repo.MarkNoteSynced(ctx, noteID, driveFileID, "hash")
 		require.NoError(t, err)
 
-		retrieved, err := repo.GetNote("test-user", "Projects", "2025-10-17")
+		retrieved, err := repo.GetNote(ctx, ScopeUser("test-user"), "Projects", "2025-10-17")
 		require.NoError(t, err)
 
 		assert.Equal(t, models.SyncStatusSynced, retrieved.SyncStatus)
@@ -134,16 +136,16 @@ func TestSyncStateManagement(t *testing.T) {
 			UpdatedAt: time.Now(),
 		}
 
-		err := repo.UpsertNote(note, true)
+		err := repo.UpsertNote(ctx, note, true)
 		require.NoError(t, err)
 
 		noteID := note.ID
 
 		// First failure
-		err = repo.MarkNoteSyncFailed(noteID, "Network error")
+		err = repo.MarkNoteSyncFailed(ctx, noteID, "Network error", models.SyncErrorNetwork, time.Now())
 		require.NoError(t, err)
 
-		retrieved, err := repo.GetNote("test-user", "Failed", "2025-10-17")
+		retrieved, err := repo.GetNote(ctx, ScopeUser("test-user"), "Failed", "2025-10-17")
 		require.NoError(t, err)
 
 		assert.Equal(t, models.SyncStatusFailed, retrieved.SyncStatus)
@@ -152,10 +154,10 @@ func TestSyncStateManagement(t *testing.T) {
 		assert.NotNil(t, retrieved.SyncLastAttemptAt)
 
 		// Second failure
-		err = repo.MarkNoteSyncFailed(noteID, "Timeout")
+		err = repo.MarkNoteSyncFailed(ctx, noteID, "Timeout", models.SyncErrorNetwork, time.Now())
 		require.NoError(t, err)
 
-		retrieved, err = repo.GetNote("test-user", "Failed", "2025-10-17")
+		retrieved, err = repo.GetNote(ctx, ScopeUser("test-user"), "Failed", "2025-10-17")
 		require.NoError(t, err)
 
 		assert.Equal(t, models.SyncStatusFailed, retrieved.SyncStatus)
@@ -173,18 +175,18 @@ func TestSyncStateManagement(t *testing.T) {
 			UpdatedAt: time.Now(),
 		}
 
-		err := repo.UpsertNote(note, true)
+		err := repo.UpsertNote(ctx, note, true)
 		require.NoError(t, err)
 
 		noteID := note.ID
 
 		// Fail MaxSyncRetries times
 		for i := 0; i < models.MaxSyncRetries; i++ {
-			err = repo.MarkNoteSyncFailed(noteID, "Persistent error")
+			err = repo.MarkNoteSyncFailed(ctx, noteID, "Persistent error", models.SyncErrorNetwork, time.Now())
 			require.NoError(t, err)
 		}
 
-		retrieved, err := repo.GetNote("test-user", "Abandoned", "2025-10-17")
+		retrieved, err := repo.GetNote(ctx, ScopeUser("test-user"), "Abandoned", "2025-10-17")
 		require.NoError(t, err)
 
 		assert.Equal(t, models.SyncStatusAbandoned, retrieved.SyncStatus)
@@ -201,20 +203,20 @@ func TestSyncStateManagement(t *testing.T) {
 			UpdatedAt: time.Now(),
 		}
 
-		err := repo.UpsertNote(note, true)
+		err := repo.UpsertNote(ctx, note, true)
 		require.NoError(t, err)
 
 		noteID := note.ID
 
 		// Mark as failed
-		err = repo.MarkNoteSyncFailed(noteID, "Initial failure")
+		err = repo.MarkNoteSyncFailed(ctx, noteID, "Initial failure", models.SyncErrorNetwork, time.Now())
 		require.NoError(t, err)
 
 		// Retry
-		err = repo.RetrySyncNote(noteID)
+		err = repo.RetrySyncNote(ctx, noteID)
 		require.NoError(t, err)
 
-		retrieved, err := repo.GetNote("test-user", "Retry", "2025-10-17")
+		retrieved, err := repo.GetNote(ctx, ScopeUser("test-user"), "Retry", "2025-10-17")
 		require.NoError(t, err)
 
 		assert.Equal(t, models.SyncStatusPending, retrieved.SyncStatus)
@@ -234,14 +236,14 @@ func TestSyncStateManagement(t *testing.T) {
 				UpdatedAt: time.Now(),
 			}
 
-			err := repo.UpsertNote(note, true)
+			err := repo.UpsertNote(ctx, note, true)
 			require.NoError(t, err)
 
-			err = repo.MarkNoteSyncFailed(note.ID, "Test error")
+			err = repo.MarkNoteSyncFailed(ctx, note.ID, "Test error", models.SyncErrorNetwork, time.Now())
 			require.NoError(t, err)
 		}
 
-		failedNotes, err := repo.GetFailedSyncNotes("test-user", 10)
+		failedNotes, err := repo.GetFailedSyncNotes(ctx, "test-user", 10)
 		require.NoError(t, err)
 
 		// Should have at least 3 failed notes
@@ -260,6 +262,7 @@ func TestSyncStateManagement(t *testing.T) {
 func TestPendingSyncNotes(t *testing.T) {
 	repo, cleanup := setupTestRepo(t)
 	defer cleanup()
+	ctx := context.Background()
 
 	// Create notes with different sync states
 	notes := []struct {
@@ -281,11 +284,11 @@ func TestPendingSyncNotes(t *testing.T) {
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
-		err := repo.UpsertNote(note, n.markForSync)
+		err := repo.UpsertNote(ctx, note, n.markForSync)
 		require.NoError(t, err)
 	}
 
-	pendingNotes, err := repo.GetPendingSyncNotes(10)
+	pendingNotes, err := repo.GetPendingSyncNotes(ctx, 10)
 	require.NoError(t, err)
 
 	// Should have 2 pending notes
@@ -295,3 +298,62 @@ func TestPendingSyncNotes(t *testing.T) {
 		assert.Contains(t, []string{"Pending1", "Pending2"}, note.Context)
 	}
 }
+
+func TestSyncRetrySchedule(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	note := &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-17", Content: "Content", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, repo.UpsertNote(ctx, note, true))
+
+	pending := func() int {
+		t.Helper()
+		notes, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		return len(notes)
+	}
+
+	t.Run("Notes wait for their next retry", func(t *testing.T) {
+		require.NoError(t, repo.MarkNoteSyncFailed(ctx, note.ID, "Rate limit", models.SyncErrorQuota, time.Now().Add(time.Hour)))
+		assert.Zero(t, pending())
+
+		require.NoError(t, repo.MarkNoteSyncFailed(ctx, note.ID, "Timeout", models.SyncErrorNetwork, time.Now().Add(-time.Second)))
+		assert.Equal(t, 1, pending())
+	})
+
+	t.Run("Only some classes count towards abandoning", func(t *testing.T) {
+		for i := 0; i < models.MaxSyncRetries; i++ {
+			require.NoError(t, repo.MarkNoteSyncFailed(ctx, note.ID, "Token expired", models.SyncErrorAuth, time.Now().Add(time.Hour)))
+		}
+
+		failed, err := repo.GetFailedSyncNotes(ctx, "test-user", 10)
+		require.NoError(t, err)
+		require.Len(t, failed, 1)
+		assert.Equal(t, models.SyncStatusFailed, failed[0].SyncStatus)
+		assert.Equal(t, 1, failed[0].SyncRetryCount, "only the network failure counted")
+		assert.Equal(t, models.SyncErrorAuth, failed[0].SyncErrorClass)
+		assert.NotNil(t, failed[0].SyncNextRetryAt)
+	})
+
+	t.Run("Signing in releases auth failures", func(t *testing.T) {
+		released, err := repo.ReleaseAuthFailedNotes(ctx, "other-user")
+		require.NoError(t, err)
+		assert.Zero(t, released)
+		assert.Zero(t, pending())
+
+		released, err = repo.ReleaseAuthFailedNotes(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, 1, released)
+		assert.Equal(t, 1, pending())
+	})
+
+	t.Run("Edits are due right away", func(t *testing.T) {
+		require.NoError(t, repo.MarkNoteSyncFailed(ctx, note.ID, "Too large", models.SyncErrorPayload, time.Now().Add(time.Hour)))
+		assert.Zero(t, pending())
+
+		note.Content = "Shorter"
+		require.NoError(t, repo.UpsertNote(ctx, note, true))
+		assert.Equal(t, 1, pending())
+	})
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"database/sql"
+	"errors"
+)
+
+// ==================== REVISIONS ====================
+
+// RevisionRetention is how many earlier versions are kept per note; 0 turns the
+// revision history off. Setup sets it from NOTE_REVISIONS.
+var RevisionRetention = 50
+
+// saveRevision copies the current content of a live note into note_revisions
+// before it is overwritten with note.Content, then drops the versions beyond
+// RevisionRetention. Saves that don't change the content add no revision, and
+// with a baseRevision other than 0 neither do saves that will be rejected.
+func saveRevision(ctx context.Context, db execer, note *models.Note, baseRevision int) error {
+	if RevisionRetention <= 0 {
+		return nil
+	}
+
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO note_revisions (note_id, user_id, revision, content, created_at)
+		SELECT id, user_id, revision, COALESCE(content, ''), updated_at
+		FROM notes
+		WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0 AND COALESCE(content, '') != ?
+		  AND (? = 0 OR revision = ?)
+	`, note.UserID, note.Context, note.Date, note.Content, baseRevision, baseRevision)
+	if saved, err := affected(result, err); err != nil || !saved {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		DELETE FROM note_revisions
+		WHERE id IN (
+			SELECT r.id
+			FROM note_revisions r
+			JOIN notes n ON n.id = r.note_id
+			WHERE n.user_id = ? AND n.context = ? AND n.date = ?
+			ORDER BY r.id DESC
+			LIMIT -1 OFFSET ?
+		)
+	`, note.UserID, note.Context, note.Date, RevisionRetention)
+	return err
+}
+
+// GetNoteRevisions returns the earlier versions of a live note, newest first
+func (r *Repository) GetNoteRevisions(ctx context.Context, userID, contextName, date string) ([]models.NoteRevision, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT r.id, n.context, n.date, r.revision, r.content, r.created_at
+		FROM note_revisions r
+		JOIN notes n ON n.id = r.note_id
+		WHERE n.user_id = ? AND n.context = ? AND n.date = ? AND n.deleted = 0 AND r.user_id = n.user_id
+		ORDER BY r.id DESC
+	`, userID, contextName, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.NoteRevision{}
+	for rows.Next() {
+		var revision models.NoteRevision
+		if err := rows.Scan(
+			&revision.ID, &revision.Context, &revision.Date, &revision.Revision,
+			&revision.Content, &revision.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// GetNoteRevision returns one earlier version of a user's live note, nil if there is none
+func (r *Repository) GetNoteRevision(ctx context.Context, userID string, id int64) (*models.NoteRevision, error) {
+	var revision models.NoteRevision
+	err := r.db.QueryRowContext(ctx, `
+		SELECT r.id, n.context, n.date, r.revision, r.content, r.created_at
+		FROM note_revisions r
+		JOIN notes n ON n.id = r.note_id
+		WHERE r.id = ? AND r.user_id = ? AND n.user_id = r.user_id AND n.deleted = 0
+	`, id, userID).Scan(
+		&revision.ID, &revision.Context, &revision.Date, &revision.Revision,
+		&revision.Content, &revision.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteRevisionHistory(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	upsert := func(contextName, date, content string) *models.Note {
+		t.Helper()
+		note := &models.Note{
+			UserID: "test-user", Context: contextName, Date: date, Content: content,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}
+		require.NoError(t, repo.UpsertNote(ctx, note, false))
+		return note
+	}
+	contents := func(contextName, date string) []string {
+		t.Helper()
+		revisions, err := repo.GetNoteRevisions(ctx, "test-user", contextName, date)
+		require.NoError(t, err)
+		var result []string
+		for _, r := range revisions {
+			result = append(result, r.Content)
+		}
+		return result
+	}
+
+	t.Run("Keeps earlier content on change", func(t *testing.T) {
+		upsert("Work", "2025-10-16", "v1")
+		assert.Empty(t, contents("Work", "2025-10-16"), "a new note has no history")
+
+		upsert("Work", "2025-10-16", "v2")
+		upsert("Work", "2025-10-16", "v2")
+		upsert("Work", "2025-10-16", "v3")
+		assert.Equal(t, []string{"v2", "v1"}, contents("Work", "2025-10-16"), "unchanged saves add nothing")
+
+		revisions, err := repo.GetNoteRevisions(ctx, "test-user", "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, 1, revisions[1].Revision)
+		assert.Equal(t, "2025-10-16", revisions[0].Date)
+
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, 2, note.RevisionCount)
+	})
+
+	t.Run("Revision-checked saves", func(t *testing.T) {
+		current, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+
+		note := &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "stale", UpdatedAt: time.Now()}
+		saved, err := repo.UpsertNoteAtRevision(ctx, note, current.Revision-1, false)
+		require.NoError(t, err)
+		require.False(t, saved)
+		assert.Equal(t, []string{"v2", "v1"}, contents("Work", "2025-10-16"), "rejected saves add nothing")
+
+		note.Content = "v4"
+		saved, err = repo.UpsertNoteAtRevision(ctx, note, current.Revision, false)
+		require.NoError(t, err)
+		require.True(t, saved)
+		assert.Equal(t, []string{"v3", "v2", "v1"}, contents("Work", "2025-10-16"))
+	})
+
+	t.Run("Overwritten transfer targets", func(t *testing.T) {
+		upsert("Archive", "2025-10-16", "archived")
+		_, err := repo.TransferNotes(ctx, ScopeUser("test-user"), "Work", "Archive", []string{"2025-10-16"}, false, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"archived"}, contents("Archive", "2025-10-16"))
+	})
+
+	t.Run("Lookup is scoped to the user", func(t *testing.T) {
+		revisions, err := repo.GetNoteRevisions(ctx, "test-user", "Work", "2025-10-16")
+		require.NoError(t, err)
+
+		found, err := repo.GetNoteRevision(ctx, "test-user", revisions[0].ID)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.Equal(t, "v3", found.Content)
+		assert.Equal(t, "Work", found.Context)
+
+		found, err = repo.GetNoteRevision(ctx, "other-user", revisions[0].ID)
+		require.NoError(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("Retention drops the oldest", func(t *testing.T) {
+		defer func(keep int) { RevisionRetention = keep }(RevisionRetention)
+		RevisionRetention = 2
+
+		upsert("Work", "2025-10-16", "v5")
+		assert.Equal(t, []string{"v4", "v3"}, contents("Work", "2025-10-16"))
+
+		RevisionRetention = 0
+		upsert("Work", "2025-10-16", "v6")
+		assert.Equal(t, []string{"v4", "v3"}, contents("Work", "2025-10-16"), "a retention of 0 records nothing")
+	})
+
+	t.Run("Purged notes lose their history", func(t *testing.T) {
+		require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16"))
+		require.NoError(t, repo.HardDeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16"))
+
+		var count int
+		require.NoError(t, repo.db.QueryRow(`SELECT COUNT(*) FROM note_revisions WHERE note_id = 'test-user-Work-2025-10-16'`).Scan(&count))
+		assert.Zero(t, count)
+	})
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ==================== USER SCOPE ====================
+
+// ErrUnscoped is returned when a note or context query is made without a user scope
+var ErrUnscoped = errors.New("database: note or context query without a user scope")
+
+// PanicOnUnscoped makes unscoped access panic instead of returning ErrUnscoped
+// Off by default; setup turns it on outside production.
+var PanicOnUnscoped = false
+
+// UserScope names the user a query is restricted to. The note and context
+// queries (notes.go, note_trash.go and contexts.go) take one in place of a user
+// ID, so none of them can forget "AND user_id = ?": notes and contexts of other
+// users are simply not found. Writes of a whole note or context take the user
+// from it instead (UpsertNote, CreateContext).
+// Its field is unexported, so the only ways to get one are ScopeUser and the zero
+// value; the zero value is rejected on first use.
+type UserScope struct {
+	userID string
+}
+
+// ScopeUser returns the scope of a user
+func ScopeUser(userID string) UserScope {
+	return UserScope{userID: userID}
+}
+
+// UserID returns the user the scope is restricted to
+func (s UserScope) UserID() string {
+	return s.userID
+}
+
+// check fails (or panics, see PanicOnUnscoped) when the scope has no user
+func (s UserScope) check() error {
+	if s.userID != "" {
+		return nil
+	}
+	if PanicOnUnscoped {
+		panic(ErrUnscoped)
+	}
+	return ErrUnscoped
+}
+
+// affected reports whether a statement changed any row
+func affected(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserScope_Isolation(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, repo.UpsertUser(ctx, &models.User{ID: "other-user", GoogleID: "google-456", Email: "other@example.com", CreatedAt: time.Now()}))
+
+	mine := ScopeUser("test-user")
+	theirs := ScopeUser("other-user")
+
+	work := &models.Context{ID: "ctx-work", UserID: "test-user", Name: "Work", Color: "primary", Icon: "💼", CreatedAt: time.Now()}
+	require.NoError(t, repo.CreateContext(ctx, work))
+
+	t.Run("Lookups by ID don't cross users", func(t *testing.T) {
+		c, err := repo.GetContextByID(ctx, theirs, "ctx-work")
+		require.NoError(t, err)
+		assert.Nil(t, c)
+
+		c, err = repo.GetContextByID(ctx, mine, "ctx-work")
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		assert.Equal(t, "Work", c.Name)
+		assert.Equal(t, "💼", c.Icon)
+	})
+
+	t.Run("Writes by ID don't cross users", func(t *testing.T) {
+		require.NoError(t, repo.UpdateContext(ctx, theirs, "ctx-work", "Hijacked", "danger", ""))
+		require.NoError(t, repo.UpdateContextTemplate(ctx, theirs, "ctx-work", "# hijacked"))
+		require.NoError(t, repo.UpdateContextLanguage(ctx, theirs, "ctx-work", "fr"))
+		assert.Error(t, repo.SetContextLocalOnly(ctx, theirs, "ctx-work", true))
+		require.NoError(t, repo.DeleteContext(ctx, theirs, "ctx-work", time.Now()))
+
+		c, err := repo.GetContextByID(ctx, mine, "ctx-work")
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		assert.Equal(t, "Work", c.Name)
+		assert.Empty(t, c.Template)
+		assert.Empty(t, c.Language)
+		assert.False(t, c.LocalOnly)
+
+		trashed, err := repo.GetTrashedContexts(ctx, theirs, time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, trashed)
+	})
+
+	t.Run("Note queries don't cross users", func(t *testing.T) {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "Mine", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}, false))
+
+		note, err := repo.GetNote(ctx, theirs, "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Nil(t, note)
+		notes, err := repo.GetNotesByContext(ctx, theirs, "Work", 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, notes)
+		require.NoError(t, repo.DeleteNote(ctx, theirs, "Work", "2025-10-16"))
+
+		note, err = repo.GetNote(ctx, mine, "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, note)
+		assert.Equal(t, "Mine", note.Content)
+	})
+
+	t.Run("Owner can delete", func(t *testing.T) {
+		require.NoError(t, repo.DeleteContext(ctx, mine, "ctx-work", time.Now()))
+		c, err := repo.GetContextByID(ctx, mine, "ctx-work")
+		require.NoError(t, err)
+		assert.Nil(t, c)
+	})
+}
+
+func TestUserScope_Unscoped(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := repo.GetContextByID(ctx, UserScope{}, "ctx-work")
+	assert.ErrorIs(t, err, ErrUnscoped)
+	assert.ErrorIs(t, repo.UpdateContext(ctx, ScopeUser(""), "ctx-work", "Work", "primary", ""), ErrUnscoped)
+	_, err = repo.GetNote(ctx, UserScope{}, "Work", "2025-10-16")
+	assert.ErrorIs(t, err, ErrUnscoped)
+	_, err = repo.GetContexts(ctx, UserScope{})
+	assert.ErrorIs(t, err, ErrUnscoped)
+	assert.ErrorIs(t, repo.DeleteNote(ctx, UserScope{}, "Work", "2025-10-16"), ErrUnscoped)
+
+	PanicOnUnscoped = true
+	defer func() { PanicOnUnscoped = false }()
+	assert.PanicsWithValue(t, ErrUnscoped, func() { repo.GetContextByID(ctx, UserScope{}, "ctx-work") })
+	assert.PanicsWithValue(t, ErrUnscoped, func() { repo.DeleteContext(ctx, UserScope{}, "ctx-work", time.Now()) })
+	assert.PanicsWithValue(t, ErrUnscoped, func() { repo.TrashNote(ctx, UserScope{}, "Work", "2025-10-16", time.Now()) })
+}
@@ -0,0 +1,301 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/period"
+	"daily-notes/pkg/visibility"
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ==================== FULL-TEXT SEARCH ====================
+
+// Snippet markers; FTS5 and the LIKE fallback wrap matches in these, and
+// highlight turns them into <mark> after escaping the note text.
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// snippetTokens is the approximate length of a search snippet in words
+const snippetTokens = 16
+
+// fullTextTriggers keep notes_fts in step with every write to notes, so upserts,
+// deletes, context renames and imports need no search-specific code. The index
+// shares rowids with notes and only holds live notes.
+var fullTextTriggers = []string{
+	`CREATE TRIGGER IF NOT EXISTS notes_fts_insert AFTER INSERT ON notes WHEN new.deleted = 0 BEGIN
+		INSERT INTO notes_fts(rowid, content) VALUES (new.rowid, COALESCE(new.content, ''));
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS notes_fts_update AFTER UPDATE OF content, deleted ON notes BEGIN
+		DELETE FROM notes_fts WHERE rowid = old.rowid;
+		INSERT INTO notes_fts(rowid, content) SELECT new.rowid, COALESCE(new.content, '') WHERE new.deleted = 0;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS notes_fts_delete AFTER DELETE ON notes BEGIN
+		DELETE FROM notes_fts WHERE rowid = old.rowid;
+	END`,
+}
+
+// migrateFullText creates the notes_fts index when SQLite has FTS5 (build tag sqlite_fts5)
+// Without it the triggers are dropped, so note writes keep working and search
+// falls back to LIKE. The index is rebuilt whenever its triggers were missing,
+// which covers the first run and a binary built without FTS5 having written notes.
+// Other dialects always search with the LIKE fallback.
+func (db *DB) migrateFullText() error {
+	if db.dialect != SQLite {
+		db.fullText = false
+		return nil
+	}
+	if _, err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(content, tokenize = 'unicode61 remove_diacritics 2')`); err != nil {
+		if !strings.Contains(err.Error(), "no such module") {
+			return fmt.Errorf("failed to create notes_fts: %w", err)
+		}
+		for _, name := range []string{"notes_fts_insert", "notes_fts_update", "notes_fts_delete"} {
+			if _, err := db.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+				return err
+			}
+		}
+		db.fullText = false
+		return nil
+	}
+
+	var triggers int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'notes_fts_%'`).Scan(&triggers); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if triggers < len(fullTextTriggers) {
+		if _, err := tx.Exec(`DELETE FROM notes_fts`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO notes_fts(rowid, content) SELECT rowid, COALESCE(content, '') FROM notes WHERE deleted = 0`); err != nil {
+			return fmt.Errorf("failed to index notes: %w", err)
+		}
+	}
+	for _, query := range fullTextTriggers {
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("failed to create search trigger: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	db.fullText = true
+	return nil
+}
+
+// SearchNotes returns a user's live notes matching every word of query, best match first
+// Each word also matches as a prefix ("meet" finds "meeting"). Snippets show the
+// matched words in context, see models.NoteSearchResult. filter narrows the notes searched.
+func (r *Repository) SearchNotes(ctx context.Context, userID, query string, filter models.NoteSearchFilter, limit, offset int) ([]models.NoteSearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []models.NoteSearchResult{}, nil
+	}
+	if r.db.fullText {
+		return r.searchFullText(ctx, userID, terms, filter, limit, offset)
+	}
+	return r.searchLike(ctx, userID, terms, filter, limit, offset)
+}
+
+// searchFilter returns the conditions and arguments that apply filter to the notes table alias
+// A date range only matches daily notes, whose keys compare as dates.
+func searchFilter(alias string, filter models.NoteSearchFilter) (string, []interface{}) {
+	var where string
+	var args []interface{}
+	if filter.Context != "" {
+		where += " AND " + alias + "context = ?"
+		args = append(args, filter.Context)
+	}
+	if filter.From != "" || filter.To != "" {
+		where += " AND " + alias + "granularity = ?"
+		args = append(args, period.Day)
+	}
+	if filter.From != "" {
+		where += " AND " + alias + "date >= ?"
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		where += " AND " + alias + "date <= ?"
+		args = append(args, filter.To)
+	}
+	if filter.SyncStatus != "" {
+		where += " AND " + alias + "sync_status = ?"
+		args = append(args, filter.SyncStatus)
+	}
+	return where, args
+}
+
+// searchFullText ranks notes by bm25 through notes_fts
+func (r *Repository) searchFullText(ctx context.Context, userID string, terms []string, filter models.NoteSearchFilter, limit, offset int) ([]models.NoteSearchResult, error) {
+	// Quote every term so user input can't use (or break) FTS5 query syntax
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+
+	where, filterArgs := searchFilter("n.", filter)
+	args := []interface{}{markStart, markEnd, snippetTokens, strings.Join(match, " "), userID}
+	args = append(append(args, filterArgs...), limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT n.context, n.date, n.granularity, COALESCE(n.sync_status, ''), n.updated_at,
+		       snippet(notes_fts, 0, ?, ?, '…', ?)
+		FROM notes_fts
+		JOIN notes n ON n.rowid = notes_fts.rowid
+		WHERE notes_fts MATCH ? AND n.user_id = ? AND `+visibleCondition("n", visibility.App)+where+`
+		ORDER BY rank, n.date DESC
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.NoteSearchResult{}
+	for rows.Next() {
+		var result models.NoteSearchResult
+		var snippet sql.NullString
+		if err := rows.Scan(&result.Context, &result.Date, &result.Type, &result.SyncStatus, &result.UpdatedAt, &snippet); err != nil {
+			return nil, err
+		}
+		result.Snippet = highlight(snippet.String)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// searchLike matches terms with LIKE when SQLite was built without FTS5, or on
+// other dialects; newest notes first
+func (r *Repository) searchLike(ctx context.Context, userID string, terms []string, filter models.NoteSearchFilter, limit, offset int) ([]models.NoteSearchResult, error) {
+	where, args := searchFilter("", filter)
+	where = "user_id = ? AND " + visibleCondition("notes", visibility.App) + where
+	args = append([]interface{}{userID}, args...)
+	for _, term := range terms {
+		where += ` AND content ` + r.db.dialect.likeOperator() + ` ? ESCAPE '\'`
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT context, date, granularity, COALESCE(sync_status, ''), updated_at, COALESCE(content, '')
+		FROM notes
+		WHERE `+where+`
+		ORDER BY updated_at DESC
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.NoteSearchResult{}
+	for rows.Next() {
+		var result models.NoteSearchResult
+		var content string
+		if err := rows.Scan(&result.Context, &result.Date, &result.Type, &result.SyncStatus, &result.UpdatedAt, &content); err != nil {
+			return nil, err
+		}
+		result.Snippet = highlight(markTerms(content, terms))
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// searchTerms splits a query into lowercase words, dropping punctuation
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '_'
+	})
+}
+
+// escapeLike escapes LIKE wildcards with a backslash
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// markTerms cuts a snippet of content around the first matched term and wraps
+// every matching token in the snippet markers, the way FTS5's snippet() does
+func markTerms(content string, terms []string) string {
+	words := strings.Fields(content)
+	first := 0
+	for i, word := range words {
+		if markWord(word, terms) != word {
+			first = i
+			break
+		}
+	}
+
+	start := max(first-snippetTokens/4, 0)
+	end := min(start+snippetTokens, len(words))
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i, word := range words[start:end] {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(markWord(word, terms))
+	}
+	if end < len(words) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// markWord wraps the letter and number runs of word that contain a term, leaving punctuation outside
+func markWord(word string, terms []string) string {
+	var b strings.Builder
+	token := -1
+	flush := func(end int) {
+		if token < 0 {
+			return
+		}
+		t := word[token:end]
+		lower := strings.ToLower(t)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				t = markStart + t + markEnd
+				break
+			}
+		}
+		b.WriteString(t)
+		token = -1
+	}
+
+	for i, r := range word {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_' {
+			if token < 0 {
+				token = i
+			}
+			continue
+		}
+		flush(i)
+		b.WriteRune(r)
+	}
+	flush(len(word))
+	return b.String()
+}
+
+// highlight escapes a marked snippet for HTML and turns the markers into <mark> tags
+func highlight(snippet string) string {
+	if !utf8.ValidString(snippet) {
+		snippet = strings.ToValidUTF8(snippet, "")
+	}
+	escaped := html.EscapeString(snippet)
+	return strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>").Replace(escaped)
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Runs against FTS5 with -tags sqlite_fts5 and against the LIKE fallback without
+func TestSearchNotes(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, repo.UpsertUser(ctx, &models.User{ID: "other-user", GoogleID: "google-456", Email: "other@example.com"}))
+
+	upsert := func(userID, contextName, date, content string) {
+		t.Helper()
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: userID, Context: contextName, Date: date, Content: content,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}, false))
+	}
+	upsert("test-user", "Work", "2025-10-16", "Planning meeting with <b>Ana</b> about the roadmap")
+	upsert("test-user", "Personal", "2025-10-17", "Dentist at 9, then groceries")
+	upsert("test-user", "Work", "2025-W42", "Weekly review: roadmap slipped")
+	upsert("other-user", "Work", "2025-10-16", "Roadmap of someone else")
+
+	keys := func(results []models.NoteSearchResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Context+"/"+r.Date)
+		}
+		return out
+	}
+
+	t.Run("Matches every word as a prefix, only for the user", func(t *testing.T) {
+		results, err := repo.SearchNotes(ctx, "test-user", "roadmap", models.NoteSearchFilter{}, 10, 0)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"Work/2025-10-16", "Work/2025-W42"}, keys(results))
+
+		results, err = repo.SearchNotes(ctx, "test-user", "meet ROADMAP", models.NoteSearchFilter{}, 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Work/2025-10-16"}, keys(results))
+		assert.Equal(t, "day", results[0].Type)
+	})
+
+	t.Run("Snippet is escaped and highlighted", func(t *testing.T) {
+		results, err := repo.SearchNotes(ctx, "test-user", "ana", models.NoteSearchFilter{}, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Contains(t, results[0].Snippet, "&lt;b&gt;<mark>Ana</mark>&lt;/b&gt;")
+	})
+
+	t.Run("Query syntax is treated as text", func(t *testing.T) {
+		results, err := repo.SearchNotes(ctx, "test-user", `"dentist" OR (x* NEAR`, models.NoteSearchFilter{}, 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, results, "OR, NEAR and x are all required words")
+
+		results, err = repo.SearchNotes(ctx, "test-user", "%", models.NoteSearchFilter{}, 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+
+	t.Run("Migrating again keeps the index", func(t *testing.T) {
+		require.NoError(t, repo.db.Migrate())
+		results, err := repo.SearchNotes(ctx, "test-user", "dentist", models.NoteSearchFilter{}, 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Personal/2025-10-17"}, keys(results))
+	})
+
+	t.Run("Follows updates and deletes", func(t *testing.T) {
+		upsert("test-user", "Personal", "2025-10-17", "Dentist moved to Friday")
+		results, err := repo.SearchNotes(ctx, "test-user", "groceries", models.NoteSearchFilter{}, 10, 0)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+
+		require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-W42"))
+		results, err = repo.SearchNotes(ctx, "test-user", "roadmap", models.NoteSearchFilter{}, 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Work/2025-10-16"}, keys(results))
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		upsert("test-user", "Work", "2025-10-18", "Roadmap again")
+		first, err := repo.SearchNotes(ctx, "test-user", "roadmap", models.NoteSearchFilter{}, 1, 0)
+		require.NoError(t, err)
+		second, err := repo.SearchNotes(ctx, "test-user", "roadmap", models.NoteSearchFilter{}, 1, 1)
+		require.NoError(t, err)
+		require.Len(t, first, 1)
+		require.Len(t, second, 1)
+		assert.NotEqual(t, keys(first), keys(second))
+	})
+}
+
+func TestSearchNotes_Filters(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	upsert := func(contextName, date string, markForSync bool) {
+		t.Helper()
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: contextName, Date: date, Content: "Roadmap notes",
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}, markForSync))
+	}
+	upsert("Work", "2025-09-30", false)
+	upsert("Work", "2025-10-01", true)
+	upsert("Work", "2025-10-31", false)
+	upsert("Work", "2025-10", false)
+	upsert("Personal", "2025-10-15", false)
+
+	search := func(filter models.NoteSearchFilter) []string {
+		t.Helper()
+		results, err := repo.SearchNotes(ctx, "test-user", "roadmap", filter, 10, 0)
+		require.NoError(t, err)
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Context+"/"+r.Date)
+		}
+		return out
+	}
+
+	assert.Len(t, search(models.NoteSearchFilter{}), 5)
+	assert.ElementsMatch(t, []string{"Personal/2025-10-15"}, search(models.NoteSearchFilter{Context: "Personal"}))
+	assert.ElementsMatch(t, []string{"Work/2025-10-01", "Work/2025-10-31", "Personal/2025-10-15"},
+		search(models.NoteSearchFilter{From: "2025-10-01", To: "2025-10-31"}), "period notes are left out of date ranges")
+	assert.ElementsMatch(t, []string{"Work/2025-09-30", "Work/2025-10-01"}, search(models.NoteSearchFilter{To: "2025-10-01"}))
+	assert.ElementsMatch(t, []string{"Work/2025-10-01"}, search(models.NoteSearchFilter{SyncStatus: models.SyncStatusPending}))
+	assert.ElementsMatch(t, []string{"Work/2025-10-31"},
+		search(models.NoteSearchFilter{Context: "Work", From: "2025-10-02", SyncStatus: models.SyncStatusSynced}))
+}
+
+func TestMarkTerms(t *testing.T) {
+	snippet := markTerms("one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty", []string{"twelve"})
+	assert.Equal(t, "…eight nine ten eleven \x02twelve\x03 thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty", snippet)
+	assert.Equal(t, "a <mark>b</mark>", highlight("a \x02b\x03"))
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"daily-notes/pkg/seal"
+)
+
+// ==================== SECRETS AT REST ====================
+
+// Columns holding credentials, sealed with the SESSION_ENC_KEY like the tokens
+// of sessions. The column is authenticated along with the value, so one can't
+// be passed off as another.
+const (
+	storageAccessColumn  = "storage_tokens.access_token"
+	storageRefreshColumn = "storage_tokens.refresh_token"
+	publishSecretColumn  = "publish_targets.secret"
+)
+
+// SetSecretKey makes the repository encrypt storage provider tokens and publish
+// target secrets with AES-256-GCM before saving them and decrypt them on reads
+func (r *Repository) SetSecretKey(key []byte) error {
+	box, err := seal.New(key)
+	if err != nil {
+		return err
+	}
+	r.secrets = box
+	return nil
+}
+
+// EncryptSecrets encrypts the storage provider tokens and publish target
+// secrets saved in plaintext, before a key was set, and returns how many rows
+// it rewrote. Without a key it does nothing.
+func (r *Repository) EncryptSecrets(ctx context.Context) (int, error) {
+	if r.secrets == nil {
+		return 0, nil
+	}
+	tokens, err := r.encryptStorageTokens(ctx)
+	if err != nil {
+		return tokens, err
+	}
+	targets, err := r.encryptPublishSecrets(ctx)
+	return tokens + targets, err
+}
+
+// plaintextToken is a storage provider token saved before a key was set
+type plaintextToken struct {
+	userID, provider, accessToken, refreshToken string
+}
+
+// encryptStorageTokens encrypts the storage provider tokens saved in plaintext
+func (r *Repository) encryptStorageTokens(ctx context.Context) (int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id, provider, access_token, COALESCE(refresh_token, '')
+		FROM storage_tokens
+		WHERE (access_token != '' AND access_token NOT LIKE ?)
+			OR (refresh_token != '' AND refresh_token NOT LIKE ?)
+	`, seal.Prefix+"%", seal.Prefix+"%")
+	if err != nil {
+		return 0, err
+	}
+	var tokens []plaintextToken
+	for rows.Next() {
+		var p plaintextToken
+		if err := rows.Scan(&p.userID, &p.provider, &p.accessToken, &p.refreshToken); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		tokens = append(tokens, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, p := range tokens {
+		accessToken, refreshToken := p.accessToken, p.refreshToken
+		if !seal.Sealed(accessToken) {
+			if accessToken, err = r.secrets.Seal(storageAccessColumn, accessToken); err != nil {
+				return i, err
+			}
+		}
+		if !seal.Sealed(refreshToken) {
+			if refreshToken, err = r.secrets.Seal(storageRefreshColumn, refreshToken); err != nil {
+				return i, err
+			}
+		}
+		if _, err := r.db.ExecContext(ctx, `
+			UPDATE storage_tokens SET access_token = ?, refresh_token = ? WHERE user_id = ? AND provider = ?
+		`, accessToken, refreshToken, p.userID, p.provider); err != nil {
+			return i, err
+		}
+	}
+	return len(tokens), nil
+}
+
+// encryptPublishSecrets encrypts the publish target secrets saved in plaintext
+func (r *Repository) encryptPublishSecrets(ctx context.Context) (int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, secret FROM publish_targets WHERE secret != '' AND secret NOT LIKE ?
+	`, seal.Prefix+"%")
+	if err != nil {
+		return 0, err
+	}
+	secrets := map[string]string{}
+	for rows.Next() {
+		var id, secret string
+		if err := rows.Scan(&id, &secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		secrets[id] = secret
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	encrypted := 0
+	for id, secret := range secrets {
+		sealed, err := r.secrets.Seal(publishSecretColumn, secret)
+		if err != nil {
+			return encrypted, err
+		}
+		if _, err := r.db.ExecContext(ctx, `UPDATE publish_targets SET secret = ? WHERE id = ?`, sealed, id); err != nil {
+			return encrypted, err
+		}
+		encrypted++
+	}
+	return encrypted, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/seal"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestSecretEncryption(t *testing.T) {
+	plain, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	raw := func(query string, args ...any) string {
+		var value string
+		require.NoError(t, plain.db.QueryRowContext(ctx, query, args...).Scan(&value))
+		return value
+	}
+
+	// Saved before the key was set
+	require.NoError(t, plain.SaveStorageToken(ctx, "test-user", "dropbox", &oauth2.Token{AccessToken: "old-access", RefreshToken: "old-refresh"}))
+	require.NoError(t, plain.CreatePublishTarget(ctx, &models.PublishTarget{
+		ID: "blog", UserID: "test-user", Kind: "ghost", Name: "Blog", URL: "https://blog.example.com", Secret: "id:00", CreatedAt: time.Now(),
+	}))
+
+	repo := NewRepository(plain.db)
+	require.NoError(t, repo.SetSecretKey(bytes.Repeat([]byte{1}, seal.KeySize)))
+
+	t.Run("Plaintext rows stay readable and are encrypted once", func(t *testing.T) {
+		token, err := repo.GetStorageToken(ctx, "test-user", "dropbox")
+		require.NoError(t, err)
+		assert.Equal(t, "old-access", token.AccessToken)
+
+		encrypted, err := repo.EncryptSecrets(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, encrypted)
+		encrypted, err = repo.EncryptSecrets(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, encrypted)
+
+		assert.True(t, seal.Sealed(raw(`SELECT access_token FROM storage_tokens WHERE user_id = ?`, "test-user")))
+		assert.NotContains(t, raw(`SELECT refresh_token FROM storage_tokens WHERE user_id = ?`, "test-user"), "old-refresh")
+		assert.True(t, seal.Sealed(raw(`SELECT secret FROM publish_targets WHERE id = ?`, "blog")))
+
+		token, err = repo.GetStorageToken(ctx, "test-user", "dropbox")
+		require.NoError(t, err)
+		assert.Equal(t, "old-access", token.AccessToken)
+		assert.Equal(t, "old-refresh", token.RefreshToken)
+		target, err := repo.GetPublishTarget(ctx, "test-user", "blog")
+		require.NoError(t, err)
+		assert.Equal(t, "id:00", target.Secret)
+	})
+
+	t.Run("Secrets are encrypted on every write", func(t *testing.T) {
+		require.NoError(t, repo.SaveStorageToken(ctx, "test-user", "dropbox", &oauth2.Token{AccessToken: "new-access"}))
+		assert.NotContains(t, raw(`SELECT access_token FROM storage_tokens WHERE user_id = ?`, "test-user"), "new-access")
+
+		token, err := repo.GetStorageToken(ctx, "test-user", "dropbox")
+		require.NoError(t, err)
+		assert.Equal(t, "new-access", token.AccessToken)
+		assert.Equal(t, "old-refresh", token.RefreshToken, "refreshes keep the stored refresh token")
+	})
+
+	t.Run("Encrypted rows need the key", func(t *testing.T) {
+		_, err := plain.GetStorageToken(ctx, "test-user", "dropbox")
+		assert.ErrorIs(t, err, seal.ErrKeyMissing)
+		_, err = plain.GetPublishTargets(ctx, "test-user")
+		assert.ErrorIs(t, err, seal.ErrKeyMissing)
+
+		other := NewRepository(plain.db)
+		require.NoError(t, other.SetSecretKey(bytes.Repeat([]byte{2}, seal.KeySize)))
+		_, err = other.GetPublishTarget(ctx, "test-user", "blog")
+		assert.ErrorIs(t, err, seal.ErrUnreadable)
+	})
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/visibility"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ==================== SHARE LINKS ====================
+
+// CreateShare saves a share link of a note and sets its ID
+func (r *Repository) CreateShare(ctx context.Context, share *models.NoteShare) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO shares (token, user_id, note_id, expires_at, created_at) VALUES (?, ?, ?, ?, ?)
+		RETURNING id
+	`, share.Token, share.UserID, share.NoteID, share.ExpiresAt, share.CreatedAt).Scan(&share.ID)
+}
+
+// GetShares returns a user's share links of notes that still exist, newest first,
+// including expired ones
+func (r *Repository) GetShares(ctx context.Context, userID string) ([]models.NoteShare, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.id, s.token, s.user_id, s.note_id, n.context, n.date, s.expires_at, s.created_at
+		FROM shares s
+		JOIN notes n ON n.id = s.note_id
+		WHERE s.user_id = ? AND n.deleted = 0
+		ORDER BY s.created_at DESC, s.id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []models.NoteShare{}
+	for rows.Next() {
+		var share models.NoteShare
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&share.ID, &share.Token, &share.UserID, &share.NoteID, &share.Context, &share.Date, &expiresAt, &share.CreatedAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			share.ExpiresAt = &expiresAt.Time
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// DeleteShare revokes a user's share link; it reports false if there was none
+func (r *Repository) DeleteShare(ctx context.Context, userID string, id int64) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `DELETE FROM shares WHERE user_id = ? AND id = ?`, userID, id))
+}
+
+// GetSharedNote returns the note of a share link that hasn't expired at now,
+// nil if there is none. Links only show notes the Public policy shows, so
+// notes made local-only, deleted or trashed since they were shared aren't.
+func (r *Repository) GetSharedNote(ctx context.Context, token string, now time.Time) (*models.Note, error) {
+	return scanSharedNote(r.db.QueryRowContext(ctx, `
+		SELECT n.id, n.user_id, n.context, n.date, n.granularity, n.content, n.revision, n.created_at, n.updated_at
+		FROM shares s
+		JOIN notes n ON n.id = s.note_id
+		WHERE s.token = ? AND (s.expires_at IS NULL OR s.expires_at > ?) AND `+visibleCondition("n", visibility.Public),
+		token, now))
+}
+
+// GetLiveSharedNote returns a note when one of its share links hasn't expired
+// at now and shows it, nil otherwise
+func (r *Repository) GetLiveSharedNote(ctx context.Context, noteID string, now time.Time) (*models.Note, error) {
+	return scanSharedNote(r.db.QueryRowContext(ctx, `
+		SELECT n.id, n.user_id, n.context, n.date, n.granularity, n.content, n.revision, n.created_at, n.updated_at
+		FROM notes n
+		WHERE n.id = ? AND `+visibleCondition("n", visibility.Public)+` AND EXISTS (
+			SELECT 1 FROM shares s WHERE s.note_id = n.id AND (s.expires_at IS NULL OR s.expires_at > ?)
+		)`,
+		noteID, now))
+}
+
+// scanSharedNote reads the note of a share link query, nil if there is none
+func scanSharedNote(row *sql.Row) (*models.Note, error) {
+	var note models.Note
+	err := row.Scan(
+		&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type,
+		&note.Content, &note.Revision, &note.CreatedAt, &note.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShares(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "standup"}, true))
+	note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+	require.NoError(t, err)
+
+	share := &models.NoteShare{Token: "token-1", UserID: "test-user", NoteID: note.ID, CreatedAt: now}
+	require.NoError(t, repo.CreateShare(ctx, share))
+	expiresAt := now.Add(-time.Minute)
+	require.NoError(t, repo.CreateShare(ctx, &models.NoteShare{Token: "expired", UserID: "test-user", NoteID: note.ID, ExpiresAt: &expiresAt, CreatedAt: now}))
+
+	t.Run("Links show their note until they expire", func(t *testing.T) {
+		shared, err := repo.GetSharedNote(ctx, "token-1", now)
+		require.NoError(t, err)
+		require.NotNil(t, shared)
+		assert.Equal(t, "standup", shared.Content)
+
+		shared, err = repo.GetSharedNote(ctx, "expired", now)
+		require.NoError(t, err)
+		assert.Nil(t, shared)
+	})
+
+	t.Run("Notes with live links are found by ID", func(t *testing.T) {
+		shared, err := repo.GetLiveSharedNote(ctx, note.ID, now)
+		require.NoError(t, err)
+		require.NotNil(t, shared)
+		assert.Equal(t, note.Revision, shared.Revision)
+
+		shared, err = repo.GetLiveSharedNote(ctx, note.ID, now.AddDate(10, 0, 0))
+		require.NoError(t, err)
+		assert.NotNil(t, shared, "token-1 never expires")
+
+		shared, err = repo.GetLiveSharedNote(ctx, "missing-note", now)
+		require.NoError(t, err)
+		assert.Nil(t, shared)
+	})
+
+	t.Run("Shares list their note", func(t *testing.T) {
+		shares, err := repo.GetShares(ctx, "test-user")
+		require.NoError(t, err)
+		require.Len(t, shares, 2)
+		assert.Equal(t, "Work", shares[1].Context)
+		assert.Equal(t, "2025-10-16", shares[1].Date)
+		assert.Nil(t, shares[1].ExpiresAt)
+		require.NotNil(t, shares[0].ExpiresAt)
+	})
+
+	t.Run("Notes made local-only stop showing", func(t *testing.T) {
+		_, err := repo.SetNoteLocalOnly(ctx, "test-user", "Work", "2025-10-16", true)
+		require.NoError(t, err)
+		shared, err := repo.GetSharedNote(ctx, "token-1", now)
+		require.NoError(t, err)
+		assert.Nil(t, shared)
+		_, err = repo.SetNoteLocalOnly(ctx, "test-user", "Work", "2025-10-16", false)
+		require.NoError(t, err)
+	})
+
+	t.Run("Deleting the note revokes its links", func(t *testing.T) {
+		require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16"))
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "rewritten"}, true))
+
+		shared, err := repo.GetSharedNote(ctx, "token-1", now)
+		require.NoError(t, err)
+		assert.Nil(t, shared)
+		deleted, err := repo.DeleteShare(ctx, "test-user", share.ID)
+		require.NoError(t, err)
+		assert.False(t, deleted)
+	})
+}
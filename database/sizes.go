@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"math"
+)
+
+// ==================== NOTE SIZES ====================
+
+// GetNoteSizeStats returns size percentiles and the largest notes of a user
+// limit counts the notes above it as oversized; largest is how many notes to list.
+func (r *Repository) GetNoteSizeStats(ctx context.Context, userID string, limit, largest int) (*models.NoteSizeStats, error) {
+	stats := &models.NoteSizeStats{Limit: limit, Largest: []models.NoteSize{}}
+
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(content_size), 0), COALESCE(MAX(content_size), 0),
+		       COALESCE(SUM(CASE WHEN ? > 0 AND content_size > ? THEN 1 ELSE 0 END), 0)
+		FROM notes
+		WHERE user_id = ? AND deleted = 0
+	`, limit, limit, userID).Scan(&stats.Count, &stats.Total, &stats.Max, &stats.Oversized); err != nil {
+		return nil, err
+	}
+	if stats.Count == 0 {
+		return stats, nil
+	}
+
+	for _, p := range []struct {
+		percentile float64
+		value      *int
+	}{{50, &stats.P50}, {90, &stats.P90}, {99, &stats.P99}} {
+		rank := int(math.Ceil(p.percentile / 100 * float64(stats.Count)))
+		if err := r.db.QueryRowContext(ctx, `
+			SELECT content_size FROM notes
+			WHERE user_id = ? AND deleted = 0
+			ORDER BY content_size
+			LIMIT 1 OFFSET ?
+		`, userID, rank-1).Scan(p.value); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT context, date, content_size FROM notes
+		WHERE user_id = ? AND deleted = 0
+		ORDER BY content_size DESC, date DESC
+		LIMIT ?
+	`, userID, largest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var size models.NoteSize
+		if err := rows.Scan(&size.Context, &size.Date, &size.Size); err != nil {
+			return nil, err
+		}
+		stats.Largest = append(stats.Largest, size)
+	}
+	return stats, rows.Err()
+}
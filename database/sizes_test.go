@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNoteSizeStats(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	t.Run("No notes", func(t *testing.T) {
+		stats, err := repo.GetNoteSizeStats(ctx, "test-user", 50, 3)
+		require.NoError(t, err)
+		assert.Equal(t, &models.NoteSizeStats{Limit: 50, Largest: []models.NoteSize{}}, stats)
+	})
+
+	// Sizes 10, 20, ..., 100 bytes
+	for i := 1; i <= 10; i++ {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID:    "test-user",
+			Context:   "Work",
+			Date:      fmt.Sprintf("2025-10-%02d", i),
+			Content:   strings.Repeat("x", i*10),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}, false))
+	}
+
+	t.Run("Percentiles and largest notes", func(t *testing.T) {
+		stats, err := repo.GetNoteSizeStats(ctx, "test-user", 75, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 10, stats.Count)
+		assert.Equal(t, 550, stats.Total)
+		assert.Equal(t, 50, stats.P50)
+		assert.Equal(t, 90, stats.P90)
+		assert.Equal(t, 100, stats.P99)
+		assert.Equal(t, 100, stats.Max)
+		assert.Equal(t, 3, stats.Oversized)
+		assert.Equal(t, []models.NoteSize{
+			{Context: "Work", Date: "2025-10-10", Size: 100},
+			{Context: "Work", Date: "2025-10-09", Size: 90},
+		}, stats.Largest)
+	})
+
+	t.Run("Sizes follow edits and deletes and count bytes", func(t *testing.T) {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: "Work", Date: "2025-10-01", Content: strings.Repeat("é", 100),
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}, false))
+		require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-10"))
+
+		stats, err := repo.GetNoteSizeStats(ctx, "test-user", 0, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 9, stats.Count)
+		assert.Equal(t, 200, stats.Max)
+		assert.Equal(t, 0, stats.Oversized, "no limit, nothing oversized")
+		assert.Equal(t, []models.NoteSize{{Context: "Work", Date: "2025-10-01", Size: 200}}, stats.Largest)
+	})
+}
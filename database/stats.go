@@ -0,0 +1,223 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/period"
+	"daily-notes/pkg/visibility"
+)
+
+// ==================== ACTIVITY STATS ====================
+
+// saveNoteCounts stores the word and character counts of a live note's content
+// for the stats and sets them on note. Encrypted notes count nothing, as their
+// words can't be told from the ciphertext.
+func saveNoteCounts(ctx context.Context, db execer, note *models.Note) error {
+	note.WordCount, note.CharCount = 0, 0
+	if !e2ee.IsEncrypted(note.Content) {
+		note.WordCount = markdown.WordCount(note.Content)
+		note.CharCount = markdown.CharCount(note.Content)
+	}
+	_, err := db.ExecContext(ctx, `
+		UPDATE notes SET word_count = ?, char_count = ? WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0
+	`, note.WordCount, note.CharCount, note.UserID, note.Context, note.Date)
+	return err
+}
+
+// backfillWordCounts counts the words of the notes saved before word_count existed
+func backfillWordCounts(tx *Tx) error {
+	return backfillCount(tx, "word_count", markdown.WordCount)
+}
+
+// backfillCharCounts counts the characters of the notes saved before char_count existed
+func backfillCharCounts(tx *Tx) error {
+	return backfillCount(tx, "char_count", markdown.CharCount)
+}
+
+// backfillCount sets column, a count of the content, on every live note; each
+// migration only fills its own column, since later ones don't exist yet
+func backfillCount(tx *Tx, column string, count func(string) int) error {
+	rows, err := tx.Query(`SELECT id, COALESCE(content, '') FROM notes WHERE deleted = 0`)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int)
+	for rows.Next() {
+		var id, content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return err
+		}
+		counts[id] = count(content)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, n := range counts {
+		if _, err := tx.Exec(`UPDATE notes SET `+column+` = ? WHERE id = ?`, n, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetActivityDays returns the number of daily notes and their words on each day
+// from from to to (inclusive) that has notes, by date. Notes of deleted contexts
+// are left out.
+func (r *Repository) GetActivityDays(ctx context.Context, userID, from, to string) ([]models.ActivityDay, error) {
+	return r.activityDays(ctx, userID, ` AND n.date BETWEEN ? AND ?`, from, to)
+}
+
+// GetAllActivityDays returns the number of daily notes and their words on every
+// day that has notes, like GetActivityDays
+func (r *Repository) GetAllActivityDays(ctx context.Context, userID string) ([]models.ActivityDay, error) {
+	return r.activityDays(ctx, userID, ``)
+}
+
+// activityDays returns the activity of the days with notes n that where, an
+// "AND ..." condition, keeps
+func (r *Repository) activityDays(ctx context.Context, userID, where string, args ...interface{}) ([]models.ActivityDay, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT n.date, COUNT(*), COALESCE(SUM(n.word_count), 0)
+		FROM notes n
+		JOIN contexts c ON c.user_id = n.user_id AND c.name = n.context
+		WHERE n.user_id = ? AND n.granularity = ? AND `+visibleCondition("n", visibility.App)+where+`
+		GROUP BY n.date
+		ORDER BY n.date ASC
+	`, append([]interface{}{userID, period.Day}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []models.ActivityDay{}
+	for rows.Next() {
+		var day models.ActivityDay
+		if err := rows.Scan(&day.Date, &day.Notes, &day.Words); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// GetContextStreaks returns the streaks of daily notes of each of the user's
+// contexts as of today, by context name. Consecutive days are grouped into runs
+// by subtracting each note's rank from its day number, which is the same within
+// a run; notes dated after today don't count.
+func (r *Repository) GetContextStreaks(ctx context.Context, userID, today string) ([]models.ContextStreak, error) {
+	yesterday, err := period.AddDays(today, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH days AS (
+			SELECT context, date,
+				`+r.db.dialect.dayNumber("date")+` - ROW_NUMBER() OVER (PARTITION BY context ORDER BY date) AS run
+			FROM notes
+			WHERE user_id = ? AND granularity = ? AND date <= ? AND `+visibleCondition("notes", visibility.App)+`
+		), runs AS (
+			SELECT context, MAX(date) AS last_date, COUNT(*) AS length
+			FROM days
+			GROUP BY context, run
+		)
+		SELECT c.name,
+			COALESCE(MAX(CASE WHEN r.last_date >= ? THEN r.length END), 0),
+			COALESCE(MAX(r.length), 0)
+		FROM contexts c
+		LEFT JOIN runs r ON r.context = c.name
+		WHERE c.user_id = ?
+		GROUP BY c.name
+		ORDER BY c.name ASC
+	`, userID, period.Day, today, yesterday, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	streaks := []models.ContextStreak{}
+	for rows.Next() {
+		var streak models.ContextStreak
+		if err := rows.Scan(&streak.Context, &streak.Current, &streak.Longest); err != nil {
+			return nil, err
+		}
+		streaks = append(streaks, streak)
+	}
+	return streaks, rows.Err()
+}
+
+// GetWritingTotals returns the number of notes of every kind a user sees and
+// their words and characters
+func (r *Repository) GetWritingTotals(ctx context.Context, userID string) (*models.WritingTotals, error) {
+	var totals models.WritingTotals
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(word_count), 0), COALESCE(SUM(char_count), 0)
+		FROM notes
+		WHERE user_id = ? AND `+visibleCondition("notes", visibility.App)+`
+	`, userID).Scan(&totals.Notes, &totals.Words, &totals.Chars)
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+// GetContextWriting returns the notes, words and characters of the user's limit
+// contexts with the most words, most first
+func (r *Repository) GetContextWriting(ctx context.Context, userID string, limit int) ([]models.ContextWriting, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT n.context, COUNT(*), COALESCE(SUM(n.word_count), 0), COALESCE(SUM(n.char_count), 0)
+		FROM notes n
+		JOIN contexts c ON c.user_id = n.user_id AND c.name = n.context
+		WHERE n.user_id = ? AND `+visibleCondition("n", visibility.App)+`
+		GROUP BY n.context
+		ORDER BY SUM(n.word_count) DESC, n.context ASC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contexts := []models.ContextWriting{}
+	for rows.Next() {
+		var c models.ContextWriting
+		if err := rows.Scan(&c.Context, &c.Notes, &c.Words, &c.Chars); err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, c)
+	}
+	return contexts, rows.Err()
+}
+
+// GetContextStats returns how many notes of every kind a context holds with
+// their words, the dates of its first and last daily notes and how its notes
+// are syncing. Pending is left for the caller, as the notes counted in no other
+// sync state.
+func (r *Repository) GetContextStats(ctx context.Context, userID, contextName string) (*models.ContextStats, error) {
+	stats := models.ContextStats{Context: contextName}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(notes.word_count), 0),
+			COALESCE(MIN(CASE WHEN notes.granularity = ? THEN notes.date END), ''),
+			COALESCE(MAX(CASE WHEN notes.granularity = ? THEN notes.date END), ''),
+			COALESCE(SUM(CASE WHEN `+localOnlyCondition+` THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN NOT `+localOnlyCondition+` AND notes.sync_status IN (?, ?) THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN NOT `+localOnlyCondition+` AND notes.sync_status = ? THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN NOT `+localOnlyCondition+` AND notes.sync_status = ? AND notes.sync_pending = 0 THEN 1 ELSE 0 END), 0)
+		FROM notes
+		WHERE notes.user_id = ? AND notes.context = ? AND `+visibleCondition("notes", visibility.App)+`
+	`, period.Day, period.Day,
+		string(models.SyncStatusFailed), string(models.SyncStatusAbandoned),
+		string(models.SyncStatusConflict), string(models.SyncStatusSynced),
+		userID, contextName,
+	).Scan(&stats.Notes, &stats.Words, &stats.FirstDate, &stats.LastDate,
+		&stats.Sync.Local, &stats.Sync.Failed, &stats.Sync.Conflicts, &stats.Sync.Synced)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
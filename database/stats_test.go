@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityStats(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, c := range []models.Context{
+		{ID: "ctx-work", UserID: "test-user", Name: "Work"},
+		{ID: "ctx-home", UserID: "test-user", Name: "Home"},
+		{ID: "ctx-idle", UserID: "test-user", Name: "Idle"},
+	} {
+		require.NoError(t, repo.CreateContext(ctx, &c))
+	}
+	for _, note := range []models.Note{
+		{Context: "Work", Date: "2025-10-10", Content: "one"},
+		{Context: "Work", Date: "2025-10-11", Content: "two words"},
+		{Context: "Work", Date: "2025-10-12", Content: "three more words"},
+		{Context: "Work", Date: "2025-10-15", Content: "- [ ] back again"},
+		{Context: "Work", Date: "2025-10-16", Content: "today"},
+		{Context: "Work", Date: "2025-10-20", Content: "planned ahead"},
+		{Context: "Work", Date: "2025-W42", Content: "weekly review"},
+		{Context: "Home", Date: "2025-10-12", Content: "chores"},
+		{Context: "Home", Date: "2025-10-13", Content: "deleted"},
+	} {
+		note.UserID = "test-user"
+		note.CreatedAt, note.UpdatedAt = time.Now(), time.Now()
+		require.NoError(t, repo.UpsertNote(ctx, &note, false))
+	}
+	require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Home", "2025-10-13"))
+
+	t.Run("Days sum the notes and words of daily notes", func(t *testing.T) {
+		days, err := repo.GetActivityDays(ctx, "test-user", "2025-10-11", "2025-10-15")
+		require.NoError(t, err)
+		assert.Equal(t, []models.ActivityDay{
+			{Date: "2025-10-11", Notes: 1, Words: 2},
+			{Date: "2025-10-12", Notes: 2, Words: 4},
+			{Date: "2025-10-15", Notes: 1, Words: 2},
+		}, days)
+	})
+
+	t.Run("Word counts follow edits", func(t *testing.T) {
+		note := &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-11", Content: "now four words here", UpdatedAt: time.Now()}
+		saved, err := repo.UpsertNoteAtRevision(ctx, note, 1, false)
+		require.NoError(t, err)
+		require.True(t, saved)
+
+		days, err := repo.GetActivityDays(ctx, "test-user", "2025-10-11", "2025-10-11")
+		require.NoError(t, err)
+		assert.Equal(t, []models.ActivityDay{{Date: "2025-10-11", Notes: 1, Words: 4}}, days)
+	})
+
+	t.Run("Streaks are runs of consecutive days per context", func(t *testing.T) {
+		streaks, err := repo.GetContextStreaks(ctx, "test-user", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, []models.ContextStreak{
+			{Context: "Home", Current: 0, Longest: 1},
+			{Context: "Idle", Current: 0, Longest: 0},
+			{Context: "Work", Current: 2, Longest: 3},
+		}, streaks)
+
+		// Today's note isn't written yet: the streak lasts until the day is over
+		streaks, err = repo.GetContextStreaks(ctx, "test-user", "2025-10-17")
+		require.NoError(t, err)
+		assert.Equal(t, 2, streaks[2].Current)
+	})
+}
+
+func TestWritingStats(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, c := range []models.Context{
+		{ID: "ctx-work", UserID: "test-user", Name: "Work"},
+		{ID: "ctx-home", UserID: "test-user", Name: "Home"},
+	} {
+		require.NoError(t, repo.CreateContext(ctx, &c))
+	}
+	for _, note := range []models.Note{
+		{Context: "Work", Date: "2025-10-13", Content: "one two"},
+		{Context: "Work", Date: "2025-W42", Content: "weekly review done"},
+		{Context: "Home", Date: "2025-10-13", Content: "chores"},
+		{Context: "Home", Date: "2025-10-14", Content: "deleted words"},
+	} {
+		note.UserID = "test-user"
+		note.CreatedAt, note.UpdatedAt = time.Now(), time.Now()
+		require.NoError(t, repo.UpsertNote(ctx, &note, false))
+	}
+	require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Home", "2025-10-14"))
+
+	t.Run("Notes carry their counts", func(t *testing.T) {
+		note := &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-13", Content: "one two three", UpdatedAt: time.Now()}
+		require.NoError(t, repo.UpsertNote(ctx, note, false))
+		assert.Equal(t, 3, note.WordCount)
+		assert.Equal(t, 13, note.CharCount)
+
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-13")
+		require.NoError(t, err)
+		assert.Equal(t, 3, note.WordCount)
+		assert.Equal(t, 13, note.CharCount)
+
+		notes, err := repo.GetNotesByContext(ctx, ScopeUser("test-user"), "Work", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, notes, 2)
+		assert.Equal(t, 3, notes[1].WordCount, "lists leave content out but keep the counts")
+	})
+
+	t.Run("Totals cover every kind of note", func(t *testing.T) {
+		totals, err := repo.GetWritingTotals(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, &models.WritingTotals{Notes: 3, Words: 7, Chars: 37}, totals)
+	})
+
+	t.Run("Contexts with the most words come first", func(t *testing.T) {
+		contexts, err := repo.GetContextWriting(ctx, "test-user", 5)
+		require.NoError(t, err)
+		assert.Equal(t, []models.ContextWriting{
+			{Context: "Work", Notes: 2, Words: 6, Chars: 31},
+			{Context: "Home", Notes: 1, Words: 1, Chars: 6},
+		}, contexts)
+
+		contexts, err = repo.GetContextWriting(ctx, "test-user", 1)
+		require.NoError(t, err)
+		assert.Len(t, contexts, 1)
+	})
+
+	t.Run("Every day with daily notes", func(t *testing.T) {
+		days, err := repo.GetAllActivityDays(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, []models.ActivityDay{{Date: "2025-10-13", Notes: 2, Words: 4}}, days)
+	})
+}
+
+func TestContextStats(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-work", UserID: "test-user", Name: "Work"}))
+	for _, note := range []models.Note{
+		{Context: "Work", Date: "2025-10-13", Content: "one two"},
+		{Context: "Work", Date: "2025-10-14", Content: "kept here"},
+		{Context: "Work", Date: "2025-10-15", Content: "three"},
+		{Context: "Work", Date: "2025-10-16", Content: "deleted words"},
+		{Context: "Work", Date: "2025-W42", Content: "weekly review done"},
+	} {
+		note.UserID = "test-user"
+		note.CreatedAt, note.UpdatedAt = time.Now(), time.Now()
+		require.NoError(t, repo.UpsertNote(ctx, &note, true))
+	}
+	noteID := func(date string) string {
+		note, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", date)
+		require.NoError(t, err)
+		return note.ID
+	}
+	require.NoError(t, repo.MarkNoteSynced(ctx, noteID("2025-10-13"), "file-1", "hash"))
+	_, err := repo.SetNoteLocalOnly(ctx, "test-user", "Work", "2025-10-14", true)
+	require.NoError(t, err)
+	require.NoError(t, repo.MarkNoteSyncFailed(ctx, noteID("2025-10-15"), "boom", models.SyncErrorNetwork, time.Now()))
+	require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16"))
+
+	stats, err := repo.GetContextStats(ctx, "test-user", "Work")
+	require.NoError(t, err)
+	assert.Equal(t, &models.ContextStats{
+		Context: "Work", Notes: 4, Words: 8, FirstDate: "2025-10-13", LastDate: "2025-10-15",
+		Sync: models.ContextSync{Synced: 1, Failed: 1, Local: 1},
+	}, stats, "the weekly note is the one pending")
+
+	stats, err = repo.GetContextStats(ctx, "test-user", "Empty")
+	require.NoError(t, err)
+	assert.Equal(t, &models.ContextStats{Context: "Empty"}, stats)
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ==================== STORAGE PROVIDER OPERATIONS ====================
+
+// GetStorageProvider returns the storage provider a user syncs notes to
+// Unknown users get an empty name, which callers treat as the default provider
+func (r *Repository) GetStorageProvider(ctx context.Context, userID string) (string, error) {
+	var provider sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT storage_provider FROM users WHERE id = ?
+	`, userID).Scan(&provider)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return provider.String, nil
+}
+
+// SetStorageProvider changes the storage provider a user syncs notes to
+func (r *Repository) SetStorageProvider(ctx context.Context, userID, provider string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE users SET storage_provider = ?, updated_at = ? WHERE id = ?
+	`, provider, time.Now(), userID)
+	return err
+}
+
+// GetStorageToken returns a user's OAuth token for a storage provider, or nil if not connected
+func (r *Repository) GetStorageToken(ctx context.Context, userID, provider string) (*oauth2.Token, error) {
+	var token oauth2.Token
+	var refreshToken sql.NullString
+	var expiry sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT access_token, refresh_token, token_expiry
+		FROM storage_tokens
+		WHERE user_id = ? AND provider = ?
+	`, userID, provider).Scan(&token.AccessToken, &refreshToken, &expiry)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if token.AccessToken, err = r.secrets.Open(storageAccessColumn, token.AccessToken); err != nil {
+		return nil, err
+	}
+	if token.RefreshToken, err = r.secrets.Open(storageRefreshColumn, refreshToken.String); err != nil {
+		return nil, err
+	}
+	if expiry.Valid {
+		token.Expiry = expiry.Time
+	}
+	return &token, nil
+}
+
+// SaveStorageToken stores a user's OAuth token for a storage provider, encrypted
+// when a key is set. An empty refresh token keeps the stored one, since
+// refreshes may not return it again.
+func (r *Repository) SaveStorageToken(ctx context.Context, userID, provider string, token *oauth2.Token) error {
+	accessToken, err := r.secrets.Seal(storageAccessColumn, token.AccessToken)
+	if err != nil {
+		return err
+	}
+	refreshToken, err := r.secrets.Seal(storageRefreshColumn, token.RefreshToken)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO storage_tokens (user_id, provider, access_token, refresh_token, token_expiry, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, provider) DO UPDATE SET
+			access_token = excluded.access_token,
+			refresh_token = COALESCE(NULLIF(excluded.refresh_token, ''), storage_tokens.refresh_token),
+			token_expiry = excluded.token_expiry,
+			updated_at = excluded.updated_at
+	`, userID, provider, accessToken, refreshToken, token.Expiry, time.Now())
+	return err
+}
+
+// DeleteStorageToken disconnects a storage provider
+func (r *Repository) DeleteStorageToken(ctx context.Context, userID, provider string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM storage_tokens WHERE user_id = ? AND provider = ?
+	`, userID, provider)
+	return err
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestStorageProvider(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	t.Run("Users default to Drive", func(t *testing.T) {
+		provider, err := repo.GetStorageProvider(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, "drive", provider)
+
+		require.NoError(t, repo.SetStorageProvider(ctx, "test-user", "dropbox"))
+		provider, err = repo.GetStorageProvider(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, "dropbox", provider)
+	})
+
+	t.Run("Refreshed tokens keep the stored refresh token", func(t *testing.T) {
+		token, err := repo.GetStorageToken(ctx, "test-user", "dropbox")
+		require.NoError(t, err)
+		assert.Nil(t, token)
+
+		expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		require.NoError(t, repo.SaveStorageToken(ctx, "test-user", "dropbox", &oauth2.Token{AccessToken: "a1", RefreshToken: "r1", Expiry: expiry}))
+		require.NoError(t, repo.SaveStorageToken(ctx, "test-user", "dropbox", &oauth2.Token{AccessToken: "a2", Expiry: expiry}))
+
+		token, err = repo.GetStorageToken(ctx, "test-user", "dropbox")
+		require.NoError(t, err)
+		require.NotNil(t, token)
+		assert.Equal(t, "a2", token.AccessToken)
+		assert.Equal(t, "r1", token.RefreshToken)
+		assert.True(t, token.Expiry.Equal(expiry))
+
+		require.NoError(t, repo.DeleteStorageToken(ctx, "test-user", "dropbox"))
+		token, err = repo.GetStorageToken(ctx, "test-user", "dropbox")
+		require.NoError(t, err)
+		assert.Nil(t, token)
+	})
+
+	t.Run("Switching re-queues live notes", func(t *testing.T) {
+		for _, date := range []string{"2025-10-16", "2025-10-17"} {
+			require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+				UserID:    "test-user",
+				Context:   "Work",
+				Date:      date,
+				Content:   "synced",
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}, false))
+		}
+
+		queued, err := repo.MarkUserNotesForSync(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, 2, queued)
+
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		assert.Len(t, pending, 2)
+	})
+}
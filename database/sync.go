@@ -1,8 +1,10 @@
 package database
 
 import (
+	"context"
 	"daily-notes/models"
 	"database/sql"
+	"errors"
 	"time"
 )
 
@@ -15,18 +17,22 @@ type NoteWithMeta struct {
 	DriveFileID       string
 	Deleted           bool
 	SyncLastAttemptAt *time.Time
+	SyncedAt          *time.Time // Last successful upload; nil if the note never synced
 }
 
 // GetPendingSyncNotes retrieves notes that need to be synced to Drive
-func (r *Repository) GetPendingSyncNotes(limit int) ([]NoteWithMeta, error) {
-	rows, err := r.db.Query(`
-		SELECT id, user_id, context, date, content, drive_file_id, deleted,
-		       sync_last_attempt_at, created_at, updated_at
+// Failed notes are left out until their next_retry_at has passed, and local-only
+// notes are never uploaded (their deletion still removes earlier uploads).
+func (r *Repository) GetPendingSyncNotes(ctx context.Context, limit int) ([]NoteWithMeta, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user_id, context, date, content, drive_file_id, deleted, sync_retry_count,
+		       sync_last_attempt_at, synced_at, created_at, updated_at
 		FROM notes
-		WHERE sync_pending = 1
+		WHERE sync_pending = 1 AND (next_retry_at IS NULL OR next_retry_at <= ?)
+		  AND (deleted = 1 OR NOT `+localOnlyCondition+`)
 		ORDER BY updated_at ASC
 		LIMIT ?
-	`, limit)
+	`, time.Now(), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -36,11 +42,11 @@ func (r *Repository) GetPendingSyncNotes(limit int) ([]NoteWithMeta, error) {
 	for rows.Next() {
 		var note NoteWithMeta
 		var driveFileID sql.NullString
-		var syncLastAttemptAt sql.NullTime
+		var syncLastAttemptAt, syncedAt sql.NullTime
 		var deleted int
 		if err := rows.Scan(
 			&note.ID, &note.UserID, &note.Context, &note.Date,
-			&note.Content, &driveFileID, &deleted, &syncLastAttemptAt,
+			&note.Content, &driveFileID, &deleted, &note.SyncRetryCount, &syncLastAttemptAt, &syncedAt,
 			&note.CreatedAt, &note.UpdatedAt,
 		); err != nil {
 			return nil, err
@@ -50,31 +56,130 @@ func (r *Repository) GetPendingSyncNotes(limit int) ([]NoteWithMeta, error) {
 		if syncLastAttemptAt.Valid {
 			note.SyncLastAttemptAt = &syncLastAttemptAt.Time
 		}
+		if syncedAt.Valid {
+			note.SyncedAt = &syncedAt.Time
+		}
 		notes = append(notes, note)
 	}
 
 	return notes, rows.Err()
 }
 
+// CountPendingSyncNotes counts the notes GetPendingSyncNotes would return right now,
+// across all users; none are left once the sync worker drained
+func (r *Repository) CountPendingSyncNotes(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notes
+		WHERE sync_pending = 1 AND (next_retry_at IS NULL OR next_retry_at <= ?)
+		  AND (deleted = 1 OR NOT `+localOnlyCondition+`)
+	`, time.Now()).Scan(&count)
+	return count, err
+}
+
 // MarkNoteSynced marks a note as successfully synced to Drive
-func (r *Repository) MarkNoteSynced(noteID, driveFileID string) error {
-	_, err := r.db.Exec(`
+// contentHash is the SHA-256 of the uploaded content, checked by sync verification.
+func (r *Repository) MarkNoteSynced(ctx context.Context, noteID, driveFileID, contentHash string) error {
+	_, err := r.db.ExecContext(ctx, `
 		UPDATE notes SET
 			drive_file_id = ?,
+			content_hash = ?,
 			sync_pending = 0,
 			sync_status = ?,
 			sync_retry_count = 0,
 			sync_error = NULL,
+			sync_error_class = NULL,
+			next_retry_at = NULL,
 			sync_last_attempt_at = ?,
 			synced_at = ?
 		WHERE id = ?
-	`, driveFileID, string(models.SyncStatusSynced), time.Now(), time.Now(), noteID)
+	`, driveFileID, contentHash, string(models.SyncStatusSynced), time.Now(), time.Now(), noteID)
+	return err
+}
+
+// GetNoteSyncedAt returns when a note was last uploaded, nil if it never was
+func (r *Repository) GetNoteSyncedAt(ctx context.Context, noteID string) (*time.Time, error) {
+	var syncedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `SELECT synced_at FROM notes WHERE id = ?`, noteID).Scan(&syncedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil || !syncedAt.Valid {
+		return nil, err
+	}
+	return &syncedAt.Time, nil
+}
+
+// SyncedNoteHash is the hash a note's content had when it was last uploaded
+type SyncedNoteHash struct {
+	NoteID string
+	Date   string
+	Hash   string
+}
+
+// GetSyncedNoteHashes returns the upload hashes of a user's synced notes in a context
+// Notes waiting to upload, notes already in conflict, local-only notes and notes
+// that have no hash yet (not uploaded since hashes were introduced) are left out.
+func (r *Repository) GetSyncedNoteHashes(ctx context.Context, userID, contextName string) ([]SyncedNoteHash, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, date, content_hash
+		FROM notes
+		WHERE user_id = ? AND context = ? AND deleted = 0 AND sync_pending = 0
+		  AND sync_status NOT IN (?, ?) AND content_hash IS NOT NULL
+		ORDER BY date ASC
+	`, userID, contextName, string(models.SyncStatusConflict), string(models.SyncStatusLocal))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []SyncedNoteHash
+	for rows.Next() {
+		var hash SyncedNoteHash
+		if err := rows.Scan(&hash.NoteID, &hash.Date, &hash.Hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// GetHashedContexts returns, per user, the contexts that hold notes with an upload hash
+func (r *Repository) GetHashedContexts(ctx context.Context) (map[string][]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT user_id, context
+		FROM notes
+		WHERE deleted = 0 AND content_hash IS NOT NULL
+		ORDER BY user_id, context
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contexts := make(map[string][]string)
+	for rows.Next() {
+		var userID, contextName string
+		if err := rows.Scan(&userID, &contextName); err != nil {
+			return nil, err
+		}
+		contexts[userID] = append(contexts[userID], contextName)
+	}
+	return contexts, rows.Err()
+}
+
+// UpdateNoteFileID records a note's storage file ID after its file was renamed
+func (r *Repository) UpdateNoteFileID(ctx context.Context, userID, contextName, date, fileID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE notes SET drive_file_id = ?
+		WHERE user_id = ? AND context = ? AND date = ? AND drive_file_id IS NOT NULL
+	`, fileID, userID, contextName, date)
 	return err
 }
 
 // MarkNoteSyncing marks a note as currently being synced
-func (r *Repository) MarkNoteSyncing(noteID string) error {
-	_, err := r.db.Exec(`
+func (r *Repository) MarkNoteSyncing(ctx context.Context, noteID string) error {
+	_, err := r.db.ExecContext(ctx, `
 		UPDATE notes SET
 			sync_status = ?,
 			sync_last_attempt_at = ?
@@ -83,33 +188,54 @@ func (r *Repository) MarkNoteSyncing(noteID string) error {
 	return err
 }
 
-// MarkNoteSyncFailed marks a note sync as failed and increments retry count
-// Automatically abandons the note if max retries is reached
-func (r *Repository) MarkNoteSyncFailed(noteID string, errorMsg string) error {
-	_, err := r.db.Exec(`
+// MarkNoteSyncFailed records a failed sync attempt and when the note is due again
+// Failures whose class counts as a retry increment the retry count and abandon the
+// note once MaxSyncRetries is reached; the others leave the count as it is.
+func (r *Repository) MarkNoteSyncFailed(ctx context.Context, noteID string, errorMsg string, class models.SyncErrorClass, nextRetryAt time.Time) error {
+	counted := 0
+	if class.CountsAsRetry() {
+		counted = 1
+	}
+	_, err := r.db.ExecContext(ctx, `
 		UPDATE notes SET
 			sync_status = CASE
-				WHEN sync_retry_count + 1 >= ? THEN ?
+				WHEN sync_retry_count + ? >= ? THEN ?
 				ELSE ?
 			END,
-			sync_retry_count = sync_retry_count + 1,
+			sync_retry_count = sync_retry_count + ?,
 			sync_error = ?,
+			sync_error_class = ?,
+			next_retry_at = ?,
 			sync_last_attempt_at = ?,
 			sync_pending = CASE
-				WHEN sync_retry_count + 1 >= ? THEN 0
+				WHEN sync_retry_count + ? >= ? THEN 0
 				ELSE 1
 			END
 		WHERE id = ?
-	`, models.MaxSyncRetries, string(models.SyncStatusAbandoned),
-		string(models.SyncStatusFailed), errorMsg, time.Now(),
-		models.MaxSyncRetries, noteID)
+	`, counted, models.MaxSyncRetries, string(models.SyncStatusAbandoned),
+		string(models.SyncStatusFailed), counted, errorMsg, string(class), nextRetryAt, time.Now(),
+		counted, models.MaxSyncRetries, noteID)
 	return err
 }
 
+// ReleaseAuthFailedNotes makes a user's notes that failed to sync for lack of a
+// valid sign-in due right away. Called after the user signs in again.
+func (r *Repository) ReleaseAuthFailedNotes(ctx context.Context, userID string) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notes SET next_retry_at = NULL
+		WHERE user_id = ? AND sync_pending = 1 AND sync_error_class = ?
+	`, userID, string(models.SyncErrorAuth))
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
+
 // MarkNoteAsNotPending marks a note as not pending sync
 // Used to avoid infinite retry loops when sync is not possible
-func (r *Repository) MarkNoteAsNotPending(noteID string) error {
-	_, err := r.db.Exec(`
+func (r *Repository) MarkNoteAsNotPending(ctx context.Context, noteID string) error {
+	_, err := r.db.ExecContext(ctx, `
 		UPDATE notes SET
 			sync_pending = 0,
 			sync_status = ?
@@ -120,11 +246,11 @@ func (r *Repository) MarkNoteAsNotPending(noteID string) error {
 
 // GetFailedSyncNotes returns notes that have failed sync
 // Useful for admin/debugging and showing users which notes couldn't sync
-func (r *Repository) GetFailedSyncNotes(userID string, limit int) ([]models.Note, error) {
-	rows, err := r.db.Query(`
+func (r *Repository) GetFailedSyncNotes(ctx context.Context, userID string, limit int) ([]models.Note, error) {
+	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, user_id, context, date, content,
 		       sync_status, sync_retry_count, sync_last_attempt_at, sync_error,
-		       created_at, updated_at
+		       sync_error_class, next_retry_at, created_at, updated_at
 		FROM notes
 		WHERE user_id = ? AND sync_status IN (?, ?)
 		ORDER BY sync_last_attempt_at DESC
@@ -139,13 +265,13 @@ func (r *Repository) GetFailedSyncNotes(userID string, limit int) ([]models.Note
 	for rows.Next() {
 		var note models.Note
 		var syncStatus string
-		var syncLastAttemptAt sql.NullTime
-		var syncError sql.NullString
+		var syncLastAttemptAt, nextRetryAt sql.NullTime
+		var syncError, syncErrorClass sql.NullString
 
 		if err := rows.Scan(
 			&note.ID, &note.UserID, &note.Context, &note.Date, &note.Content,
 			&syncStatus, &note.SyncRetryCount, &syncLastAttemptAt, &syncError,
-			&note.CreatedAt, &note.UpdatedAt,
+			&syncErrorClass, &nextRetryAt, &note.CreatedAt, &note.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -157,6 +283,10 @@ func (r *Repository) GetFailedSyncNotes(userID string, limit int) ([]models.Note
 		if syncError.Valid {
 			note.SyncError = syncError.String
 		}
+		note.SyncErrorClass = models.SyncErrorClass(syncErrorClass.String)
+		if nextRetryAt.Valid {
+			note.SyncNextRetryAt = &nextRetryAt.Time
+		}
 
 		notes = append(notes, note)
 	}
@@ -166,14 +296,68 @@ func (r *Repository) GetFailedSyncNotes(userID string, limit int) ([]models.Note
 
 // RetrySyncNote resets a failed note's sync status to retry synchronization
 // Clears the error and retry count to give it a fresh start
-func (r *Repository) RetrySyncNote(noteID string) error {
-	_, err := r.db.Exec(`
+func (r *Repository) RetrySyncNote(ctx context.Context, noteID string) error {
+	_, err := r.db.ExecContext(ctx, `
 		UPDATE notes SET
 			sync_pending = 1,
 			sync_status = ?,
 			sync_retry_count = 0,
-			sync_error = NULL
+			sync_error = NULL,
+			sync_error_class = NULL,
+			next_retry_at = NULL
 		WHERE id = ?
 	`, string(models.SyncStatusPending), noteID)
 	return err
 }
+
+// RetryUserSyncNotes resets the sync status of a user's failed and abandoned
+// notes, deletions included, like RetrySyncNote does for one note
+// Local-only notes stay out of the queue.
+func (r *Repository) RetryUserSyncNotes(ctx context.Context, userID string) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notes SET
+			sync_pending = 1,
+			sync_status = ?,
+			sync_retry_count = 0,
+			sync_error = NULL,
+			sync_error_class = NULL,
+			next_retry_at = NULL
+		WHERE user_id = ? AND sync_status IN (?, ?) AND NOT `+localOnlyCondition+`
+	`, string(models.SyncStatusPending), userID,
+		string(models.SyncStatusFailed), string(models.SyncStatusAbandoned))
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
+
+// MarkUserNotesForSync queues every live note of a user for upload, except the
+// local-only ones. Used after switching storage providers so the new provider
+// receives all notes
+func (r *Repository) MarkUserNotesForSync(ctx context.Context, userID string) (int, error) {
+	// Local-only notes stay out of the queue but aren't in the new provider either
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE notes SET drive_file_id = NULL
+		WHERE user_id = ? AND deleted = 0 AND `+localOnlyCondition+`
+	`, userID); err != nil {
+		return 0, err
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notes SET
+			sync_pending = 1,
+			sync_status = ?,
+			sync_retry_count = 0,
+			sync_error = NULL,
+			sync_error_class = NULL,
+			next_retry_at = NULL,
+			drive_file_id = NULL
+		WHERE user_id = ? AND deleted = 0 AND NOT `+localOnlyCondition+`
+	`, string(models.SyncStatusPending), userID)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/visibility"
+)
+
+// ==================== TAGS ====================
+
+// backfillTags tags the notes that existed before the tags tables
+func backfillTags(tx *Tx) error {
+	notes, err := liveNotesContaining(tx, "#")
+	if err != nil {
+		return err
+	}
+	for i := range notes {
+		if err := saveNoteTags(context.Background(), tx, &notes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveNoteTags replaces the tags of a live note with the #hashtags in its content
+// and sets note.Tags, and note.Encrypted along with them. Deleted notes are left alone.
+func saveNoteTags(ctx context.Context, db execer, note *models.Note) error {
+	note.Tags = markdown.ExtractHashtags(note.Content)
+	note.Encrypted = e2ee.IsEncrypted(note.Content)
+
+	if _, err := db.ExecContext(ctx, `
+		DELETE FROM note_tags
+		WHERE note_id = (SELECT id FROM notes WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0)
+	`, note.UserID, note.Context, note.Date); err != nil {
+		return err
+	}
+
+	for _, tag := range note.Tags {
+		if _, err := db.ExecContext(ctx, `INSERT INTO tags (user_id, name) VALUES (?, ?) ON CONFLICT DO NOTHING`, note.UserID, tag); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO note_tags (note_id, tag_id)
+			SELECT n.id, t.id
+			FROM notes n, tags t
+			WHERE n.user_id = ? AND n.context = ? AND n.date = ? AND n.deleted = 0
+			  AND t.user_id = n.user_id AND t.name = ?
+			ON CONFLICT DO NOTHING
+		`, note.UserID, note.Context, note.Date, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTags returns the user's tags with the number of live notes using each, most used first
+func (r *Repository) GetTags(ctx context.Context, userID string) ([]models.Tag, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.name, COUNT(*)
+		FROM tags t
+		JOIN note_tags nt ON nt.tag_id = t.id
+		JOIN notes n ON n.id = nt.note_id AND `+visibleCondition("n", visibility.App)+`
+		WHERE t.user_id = ?
+		GROUP BY t.id
+		ORDER BY COUNT(*) DESC, t.name ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetNotesByTag retrieves the user's live notes tagged with tag across contexts, newest date first (paginated)
+// Like GetNotesByContext, content is left out of the list.
+func (r *Repository) GetNotesByTag(ctx context.Context, userID, tag string, limit, offset int) ([]models.Note, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT n.id, n.user_id, n.context, n.date, n.granularity, n.content, n.word_count, n.char_count, n.created_at, n.updated_at
+		FROM tags t
+		JOIN note_tags nt ON nt.tag_id = t.id
+		JOIN notes n ON n.id = nt.note_id AND `+visibleCondition("n", visibility.App)+`
+		WHERE t.user_id = ? AND t.name = ?
+		ORDER BY n.date DESC, n.context ASC
+		LIMIT ? OFFSET ?
+	`, userID, tag, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.Note{}
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(
+			&note.ID, &note.UserID, &note.Context, &note.Date, &note.Type,
+			&note.Content, &note.WordCount, &note.CharCount, &note.CreatedAt, &note.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		note.Tags = markdown.ExtractHashtags(note.Content)
+		note.Encrypted = e2ee.IsEncrypted(note.Content)
+		note.Content = ""
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
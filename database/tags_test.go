@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	upsert := func(contextName, date, content string) *models.Note {
+		t.Helper()
+		note := &models.Note{
+			UserID: "test-user", Context: contextName, Date: date, Content: content,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}
+		require.NoError(t, repo.UpsertNote(ctx, note, false))
+		return note
+	}
+	byTag := func(tag string) []string {
+		t.Helper()
+		notes, err := repo.GetNotesByTag(ctx, "test-user", tag, 10, 0)
+		require.NoError(t, err)
+		var keys []string
+		for _, n := range notes {
+			keys = append(keys, n.Context+"/"+n.Date)
+		}
+		return keys
+	}
+
+	note := upsert("Work", "2025-10-16", "# Standup\nShipped #Release and #infra work")
+	assert.Equal(t, []string{"release", "infra"}, note.Tags)
+	upsert("Personal", "2025-10-17", "Read about #release engineering")
+
+	t.Run("Lists tags by use", func(t *testing.T) {
+		tags, err := repo.GetTags(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, []models.Tag{{Name: "release", Count: 2}, {Name: "infra", Count: 1}}, tags)
+	})
+
+	t.Run("Finds notes across contexts", func(t *testing.T) {
+		assert.Equal(t, []string{"Personal/2025-10-17", "Work/2025-10-16"}, byTag("release"))
+
+		notes, err := repo.GetNotesByTag(ctx, "test-user", "infra", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, notes, 1)
+		assert.Empty(t, notes[0].Content)
+		assert.Equal(t, []string{"release", "infra"}, notes[0].Tags)
+	})
+
+	t.Run("Follows edits, revision saves and deletes", func(t *testing.T) {
+		upsert("Work", "2025-10-16", "Only #infra now")
+		assert.Equal(t, []string{"Personal/2025-10-17"}, byTag("release"))
+
+		current, err := repo.GetNote(ctx, ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"infra"}, current.Tags)
+
+		saved, err := repo.UpsertNoteAtRevision(ctx, &models.Note{
+			UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "Back to #release",
+			UpdatedAt: time.Now(),
+		}, current.Revision, false)
+		require.NoError(t, err)
+		require.True(t, saved)
+		assert.Empty(t, byTag("infra"))
+
+		require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Personal", "2025-10-17"))
+		assert.Equal(t, []string{"Work/2025-10-16"}, byTag("release"))
+	})
+
+	t.Run("Moved notes keep their tags", func(t *testing.T) {
+		_, err := repo.TransferNotes(ctx, ScopeUser("test-user"), "Work", "Archive", []string{"2025-10-16"}, true, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Archive/2025-10-16"}, byTag("release"))
+	})
+
+	t.Run("Existing notes are tagged by their migration", func(t *testing.T) {
+		_, err := repo.db.MigrateDown(27)
+		require.NoError(t, err)
+		require.NoError(t, repo.db.Migrate())
+		assert.Equal(t, []string{"Archive/2025-10-16"}, byTag("release"))
+	})
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/visibility"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// ==================== TASKS ====================
+
+// saveNoteTasks replaces the tasks of a live note with the checkbox list items
+// of its content. A task keeps its row, and ID, while it stays on the same line.
+func saveNoteTasks(ctx context.Context, db execer, note *models.Note) error {
+	tasks := markdown.ExtractTasks(note.Content)
+
+	args := []any{note.UserID, note.Context, note.Date}
+	keep := ""
+	if len(tasks) > 0 {
+		keep = " AND line NOT IN (?" + strings.Repeat(", ?", len(tasks)-1) + ")"
+		for _, task := range tasks {
+			args = append(args, task.Line)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `
+		DELETE FROM tasks
+		WHERE note_id = (SELECT id FROM notes WHERE user_id = ? AND context = ? AND date = ? AND deleted = 0)`+keep,
+		args...); err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO tasks (user_id, note_id, line, text, done)
+			SELECT n.user_id, n.id, ?, ?, ?
+			FROM notes n
+			WHERE n.user_id = ? AND n.context = ? AND n.date = ? AND n.deleted = 0
+			ON CONFLICT(note_id, line) DO UPDATE SET text = excluded.text, done = excluded.done
+		`, task.Line, task.Text, task.Done, note.UserID, note.Context, note.Date); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillTasks saves the tasks of the notes that existed before the tasks table
+func backfillTasks(tx *Tx) error {
+	notes, err := liveNotesContaining(tx, "[")
+	if err != nil {
+		return err
+	}
+	for i := range notes {
+		if err := saveNoteTasks(context.Background(), tx, &notes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// liveNotesContaining loads the live notes whose content contains text, for
+// backfills parsing it
+func liveNotesContaining(tx *Tx, text string) ([]models.Note, error) {
+	rows, err := tx.Query(`SELECT user_id, context, date, COALESCE(content, '') FROM notes WHERE deleted = 0 AND content LIKE ?`, "%"+text+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(&note.UserID, &note.Context, &note.Date, &note.Content); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// taskColumns are the columns scanned by scanTask, from tasks t joined with notes n
+const taskColumns = `t.id, n.context, n.date, t.line, t.text, t.done`
+
+// GetTasks returns the tasks of a user's live notes, newest notes first and in
+// note order within a note. status is TaskOpen, TaskDone or "" for both, and a
+// non-empty contextName keeps one context, in any case.
+func (r *Repository) GetTasks(ctx context.Context, userID, status, contextName string, limit, offset int) ([]models.Task, error) {
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks t
+		JOIN notes n ON n.id = t.note_id
+		WHERE t.user_id = ? AND ` + visibleCondition("n", visibility.App)
+	args := []any{userID}
+	switch status {
+	case models.TaskOpen:
+		query += ` AND t.done = 0`
+	case models.TaskDone:
+		query += ` AND t.done = 1`
+	}
+	if contextName != "" {
+		query += ` AND LOWER(n.context) = LOWER(?)`
+		args = append(args, contextName)
+	}
+	query += ` ORDER BY n.date DESC, n.context ASC, t.line ASC LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, rows.Err()
+}
+
+// GetTask returns a task of a user's live notes, nil if there is none with that ID
+func (r *Repository) GetTask(ctx context.Context, userID string, id int64) (*models.Task, error) {
+	task, err := scanTask(r.db.QueryRowContext(ctx, `
+		SELECT `+taskColumns+`
+		FROM tasks t
+		JOIN notes n ON n.id = t.note_id
+		WHERE t.user_id = ? AND t.id = ? AND `+visibleCondition("n", visibility.App)+`
+	`, userID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return task, err
+}
+
+// scanTask reads a row selecting taskColumns
+func scanTask(row interface{ Scan(...any) error }) (*models.Task, error) {
+	var task models.Task
+	if err := row.Scan(&task.ID, &task.Context, &task.Date, &task.Line, &task.Text, &task.Done); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
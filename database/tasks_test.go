@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTasks(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	save := func(contextName, date, content string) {
+		t.Helper()
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: contextName, Date: date, Content: content, CreatedAt: now, UpdatedAt: now,
+		}, true))
+	}
+	tasks := func(status, contextName string) []models.Task {
+		t.Helper()
+		tasks, err := repo.GetTasks(ctx, "test-user", status, contextName, 50, 0)
+		require.NoError(t, err)
+		return tasks
+	}
+
+	save("Work", "2025-10-15", "# Plan\n- [ ] Call Ana\n- [x] Send invoice")
+	save("Home", "2025-10-16", "- [ ] Groceries\n```\n- [ ] not a task\n```")
+
+	t.Run("Checkboxes of notes are listed, newest notes first", func(t *testing.T) {
+		all := tasks("", "")
+		require.Len(t, all, 3)
+		assert.Equal(t, models.Task{ID: all[0].ID, Context: "Home", Date: "2025-10-16", Line: 1, Text: "Groceries"}, all[0])
+		assert.Equal(t, "Call Ana", all[1].Text)
+		assert.Equal(t, 2, all[1].Line)
+		assert.True(t, all[2].Done)
+
+		assert.Len(t, tasks(models.TaskOpen, ""), 2)
+		assert.Len(t, tasks(models.TaskDone, ""), 1)
+		assert.Len(t, tasks(models.TaskOpen, "work"), 1, "contexts match in any case")
+	})
+
+	t.Run("Tasks keep their ID while they keep their line", func(t *testing.T) {
+		before := tasks("", "Work")
+		save("Work", "2025-10-15", "# Plan\n- [x] Call Ana")
+		after := tasks("", "Work")
+		require.Len(t, after, 1)
+		assert.Equal(t, before[0].ID, after[0].ID)
+		assert.True(t, after[0].Done)
+
+		task, err := repo.GetTask(ctx, "test-user", after[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, &after[0], task)
+	})
+
+	t.Run("Tasks of deleted notes are left out", func(t *testing.T) {
+		home := tasks("", "Home")
+		require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Home", "2025-10-16"))
+		assert.Empty(t, tasks("", "Home"))
+
+		task, err := repo.GetTask(ctx, "test-user", home[0].ID)
+		require.NoError(t, err)
+		assert.Nil(t, task)
+	})
+}
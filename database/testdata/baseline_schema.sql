@@ -0,0 +1,76 @@
+-- Schema of databases created before migrations were versioned, as the
+-- baseline release's Migrate() left it. Used to test upgrades.
+CREATE TABLE users (
+	id TEXT PRIMARY KEY,
+	google_id TEXT UNIQUE NOT NULL,
+	email TEXT NOT NULL,
+	name TEXT,
+	picture TEXT,
+	settings_theme TEXT DEFAULT 'dark',
+	settings_week_start INTEGER DEFAULT 0,
+	settings_timezone TEXT DEFAULT 'UTC',
+	settings_date_format TEXT DEFAULT 'DD-MM-YY',
+	settings_unique_context_mode INTEGER DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_login_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE contexts (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	color TEXT NOT NULL,
+	drive_folder_id TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	UNIQUE(user_id, name)
+);
+CREATE TABLE notes (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	context TEXT NOT NULL,
+	date TEXT NOT NULL,
+	content TEXT,
+	drive_file_id TEXT,
+	synced_at DATETIME,
+	sync_pending INTEGER DEFAULT 1,
+	sync_status TEXT DEFAULT 'pending',
+	sync_retry_count INTEGER DEFAULT 0,
+	sync_last_attempt_at DATETIME,
+	sync_error TEXT,
+	deleted INTEGER DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	UNIQUE(user_id, context, date)
+);
+CREATE TABLE sessions (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	email TEXT NOT NULL,
+	name TEXT NOT NULL,
+	picture TEXT,
+	access_token TEXT NOT NULL,
+	refresh_token TEXT,
+	token_expiry DATETIME,
+	settings_theme TEXT DEFAULT 'dark',
+	settings_week_start INTEGER DEFAULT 0,
+	settings_timezone TEXT DEFAULT 'UTC',
+	settings_date_format TEXT DEFAULT 'DD-MM-YY',
+	settings_unique_context_mode INTEGER DEFAULT 0,
+	settings_show_breadcrumb INTEGER DEFAULT 1,
+	settings_show_markdown_editor INTEGER DEFAULT 0,
+	settings_hide_new_context_button INTEGER DEFAULT 0,
+	expires_at DATETIME NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_used_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_notes_user_context ON notes(user_id, context);
+CREATE INDEX idx_notes_user_date ON notes(user_id, date);
+CREATE INDEX idx_notes_sync_pending ON notes(sync_pending) WHERE sync_pending = 1;
+CREATE INDEX idx_notes_sync_status ON notes(sync_status);
+CREATE INDEX idx_contexts_user ON contexts(user_id);
+CREATE INDEX idx_sessions_user ON sessions(user_id);
+CREATE INDEX idx_sessions_expires ON sessions(expires_at);
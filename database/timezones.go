@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/period"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ==================== TIMEZONE CHANGES ====================
+
+// RecordTimezoneChange records that a user switched timezone at the given time
+func (r *Repository) RecordTimezoneChange(ctx context.Context, userID, from, to string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO timezone_changes (user_id, from_timezone, to_timezone, changed_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, from, to, at)
+	return err
+}
+
+// GetTimezoneChange returns the user's latest timezone change that wasn't reviewed, nil if there is none
+func (r *Repository) GetTimezoneChange(ctx context.Context, userID string) (*models.TimezoneChange, error) {
+	var change models.TimezoneChange
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, from_timezone, to_timezone, changed_at
+		FROM timezone_changes
+		WHERE user_id = ? AND reviewed_at IS NULL
+		ORDER BY changed_at DESC, id DESC
+		LIMIT 1
+	`, userID).Scan(&change.ID, &change.From, &change.To, &change.ChangedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// DismissTimezoneChange marks a timezone change, and the ones before it, as reviewed
+// Returns false if the user has no such change.
+func (r *Repository) DismissTimezoneChange(ctx context.Context, userID string, id int64, at time.Time) (bool, error) {
+	var exists int
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM timezone_changes WHERE user_id = ? AND id = ?
+	`, userID, id).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists == 0 {
+		return false, nil
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE timezone_changes
+		SET reviewed_at = ?
+		WHERE user_id = ? AND id <= ? AND reviewed_at IS NULL
+	`, at, userID, id)
+	return err == nil, err
+}
+
+// GetDayNotesCreatedSince returns the user's daily notes created at or after since,
+// oldest first, without their content
+func (r *Repository) GetDayNotesCreatedSince(ctx context.Context, userID string, since time.Time) ([]models.Note, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, context, date, created_at
+		FROM notes
+		WHERE user_id = ? AND granularity = ? AND created_at >= ? AND deleted = 0
+		ORDER BY created_at ASC
+	`, userID, period.Day, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		note := models.Note{UserID: userID, Type: period.Day}
+		if err := rows.Scan(&note.ID, &note.Context, &note.Date, &note.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// GetNoteDates returns the dates of the user's daily notes in every context, in
+// order, once per note
+func (r *Repository) GetNoteDates(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT date
+		FROM notes
+		WHERE user_id = ? AND granularity = ? AND deleted = 0
+		ORDER BY date ASC
+	`, userID, period.Day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dates []string
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, err
+		}
+		dates = append(dates, date)
+	}
+	return dates, rows.Err()
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimezoneChanges(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	changedAt := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	t.Run("No change to review", func(t *testing.T) {
+		change, err := repo.GetTimezoneChange(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Nil(t, change)
+	})
+
+	t.Run("Latest change is reviewed first", func(t *testing.T) {
+		require.NoError(t, repo.RecordTimezoneChange(ctx, "test-user", "UTC", "Europe/Madrid", changedAt))
+		require.NoError(t, repo.RecordTimezoneChange(ctx, "test-user", "Europe/Madrid", "America/Bogota", changedAt.Add(time.Hour)))
+
+		change, err := repo.GetTimezoneChange(ctx, "test-user")
+		require.NoError(t, err)
+		require.NotNil(t, change)
+		assert.Equal(t, "Europe/Madrid", change.From)
+		assert.Equal(t, "America/Bogota", change.To)
+		assert.True(t, change.ChangedAt.Equal(changedAt.Add(time.Hour)))
+	})
+
+	t.Run("Dismissing a change dismisses the earlier ones", func(t *testing.T) {
+		change, err := repo.GetTimezoneChange(ctx, "test-user")
+		require.NoError(t, err)
+
+		found, err := repo.DismissTimezoneChange(ctx, "other-user", change.ID, time.Now())
+		require.NoError(t, err)
+		assert.False(t, found, "changes of other users are not found")
+
+		found, err = repo.DismissTimezoneChange(ctx, "test-user", change.ID, time.Now())
+		require.NoError(t, err)
+		assert.True(t, found)
+
+		change, err = repo.GetTimezoneChange(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Nil(t, change)
+	})
+}
+
+func TestGetDayNotesCreatedSince(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	since := time.Date(2025, 10, 10, 0, 0, 0, 0, time.UTC)
+	for date, created := range map[string]time.Time{
+		"2025-10-01": since.Add(-time.Hour),
+		"2025-10-12": since.Add(48 * time.Hour),
+		"2025-W42":   since.Add(48 * time.Hour),
+	} {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID:    "test-user",
+			Context:   "Work",
+			Date:      date,
+			Content:   "notes",
+			CreatedAt: created,
+			UpdatedAt: created,
+		}, false))
+	}
+
+	notes, err := repo.GetDayNotesCreatedSince(ctx, "test-user", since)
+	require.NoError(t, err)
+	require.Len(t, notes, 1)
+	assert.Equal(t, "2025-10-12", notes[0].Date)
+	assert.Equal(t, "Work", notes[0].Context)
+
+	dates, err := repo.GetNoteDates(ctx, "test-user")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2025-10-01", "2025-10-12"}, dates)
+}
@@ -1,32 +1,34 @@
 package database
 
 import (
+	"context"
 	"daily-notes/models"
 	"database/sql"
+	"errors"
 	"time"
 )
 
 // ==================== USER OPERATIONS ====================
 
 // GetUser retrieves a user by ID with their settings
-func (r *Repository) GetUser(userID string) (*models.User, error) {
+func (r *Repository) GetUser(ctx context.Context, userID string) (*models.User, error) {
 	var user models.User
 	var settings models.UserSettings
 
-	err := r.db.QueryRow(`
+	err := r.db.QueryRowContext(ctx, `
 		SELECT id, google_id, email, name, picture,
 			   settings_theme, settings_week_start, settings_timezone,
-			   settings_date_format, settings_unique_context_mode,
-			   created_at, last_login_at
+			   settings_date_format, settings_unique_context_mode, settings_suggest_context,
+			   COALESCE(storage_provider, ''), created_at, last_login_at
 		FROM users WHERE id = ?
 	`, userID).Scan(
 		&user.ID, &user.GoogleID, &user.Email, &user.Name, &user.Picture,
 		&settings.Theme, &settings.WeekStart, &settings.Timezone,
-		&settings.DateFormat, &settings.UniqueContextMode,
-		&user.CreatedAt, &user.LastLoginAt,
+		&settings.DateFormat, &settings.UniqueContextMode, &settings.SuggestContext,
+		&settings.StorageProvider, &user.CreatedAt, &user.LastLoginAt,
 	)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	if err != nil {
@@ -38,13 +40,13 @@ func (r *Repository) GetUser(userID string) (*models.User, error) {
 }
 
 // UpsertUser creates or updates a user record
-func (r *Repository) UpsertUser(user *models.User) error {
-	_, err := r.db.Exec(`
+func (r *Repository) UpsertUser(ctx context.Context, user *models.User) error {
+	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO users (id, google_id, email, name, picture,
 			settings_theme, settings_week_start, settings_timezone,
-			settings_date_format, settings_unique_context_mode,
+			settings_date_format, settings_unique_context_mode, settings_suggest_context,
 			created_at, last_login_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			email = excluded.email,
 			name = excluded.name,
@@ -54,27 +56,65 @@ func (r *Repository) UpsertUser(user *models.User) error {
 	`,
 		user.ID, user.GoogleID, user.Email, user.Name, user.Picture,
 		user.Settings.Theme, user.Settings.WeekStart, user.Settings.Timezone,
-		user.Settings.DateFormat, user.Settings.UniqueContextMode,
+		user.Settings.DateFormat, user.Settings.UniqueContextMode, user.Settings.SuggestContext,
 		user.CreatedAt, user.LastLoginAt, time.Now(),
 	)
 	return err
 }
 
+// GetUserIDs returns the IDs of all users, oldest first
+func (r *Repository) GetUserIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM users ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // UpdateUserSettings updates only the user's settings
-func (r *Repository) UpdateUserSettings(userID string, settings models.UserSettings) error {
-	_, err := r.db.Exec(`
+func (r *Repository) UpdateUserSettings(ctx context.Context, userID string, settings models.UserSettings) error {
+	_, err := r.db.ExecContext(ctx, `
 		UPDATE users SET
 			settings_theme = ?,
 			settings_week_start = ?,
 			settings_timezone = ?,
 			settings_date_format = ?,
 			settings_unique_context_mode = ?,
+			settings_suggest_context = ?,
 			updated_at = ?
 		WHERE id = ?
 	`,
 		settings.Theme, settings.WeekStart, settings.Timezone,
-		settings.DateFormat, settings.UniqueContextMode,
+		settings.DateFormat, settings.UniqueContextMode, settings.SuggestContext,
 		time.Now(), userID,
 	)
 	return err
 }
+
+// GetLastSeenVersion returns the changelog version the user was last shown, "" if none
+func (r *Repository) GetLastSeenVersion(ctx context.Context, userID string) (string, error) {
+	var version sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT last_seen_version FROM users WHERE id = ?`, userID).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return version.String, err
+}
+
+// SetLastSeenVersion records the changelog version the user was last shown
+func (r *Repository) SetLastSeenVersion(ctx context.Context, userID, version string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE users SET last_seen_version = ?, updated_at = ? WHERE id = ?
+	`, version, time.Now(), userID)
+	return err
+}
@@ -0,0 +1,40 @@
+package database
+
+import "daily-notes/pkg/visibility"
+
+// ==================== VISIBILITY ====================
+
+// trashedContextOf holds for notes, of the notes table referred to as table,
+// whose context is in the trash; notes of contexts that never had a row count
+// as live
+func trashedContextOf(table string) string {
+	return `(EXISTS (
+	SELECT 1 FROM context_trash vt WHERE vt.user_id = ` + table + `.user_id AND vt.name = ` + table + `.context
+) AND NOT EXISTS (
+	SELECT 1 FROM contexts vc WHERE vc.user_id = ` + table + `.user_id AND vc.name = ` + table + `.context
+))`
+}
+
+// visibleCondition holds for the notes, of the notes table referred to as
+// table, that policy shows: live notes, leaving out those of trashed contexts,
+// local-only and encrypted ones unless the policy shows them
+func visibleCondition(table string, policy visibility.Policy) string {
+	condition := table + `.deleted = 0`
+	if !policy.TrashedContexts {
+		condition += ` AND NOT ` + trashedContextOf(table)
+	}
+	if !policy.LocalOnly {
+		condition += ` AND NOT ` + localOnlyOf(table)
+		if policy.TrashedContexts {
+			// A trashed context keeps its local-only mark in the trash
+			condition += ` AND NOT EXISTS (
+	SELECT 1 FROM context_trash lt
+	WHERE lt.user_id = ` + table + `.user_id AND lt.name = ` + table + `.context AND lt.local_only = 1
+)`
+		}
+	}
+	if !policy.Encrypted {
+		condition += ` AND NOT ` + encryptedOf(table)
+	}
+	return condition
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/visibility"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVisibility(t *testing.T) {
+	repo, cleanup := setupTestRepo(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	save := func(contextName, date, content string) {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: contextName, Date: date, Content: content,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}, true))
+	}
+
+	trashed := &models.Context{ID: "ctx-trashed", UserID: "test-user", Name: "Old", Color: "primary"}
+	require.NoError(t, repo.CreateContext(ctx, trashed))
+	save("Old", "2025-10-14", "visible #plans")
+	require.NoError(t, repo.DeleteContext(ctx, ScopeUser("test-user"), trashed.ID, time.Now()))
+
+	save("Work", "2025-10-15", "visible #plans")
+	save("Work", "2025-10-16", "visible #plans")
+	save("Work", "2025-10-17", "visible #plans")
+	_, err := repo.SetNoteLocalOnly(ctx, "test-user", "Work", "2025-10-16", true)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteNote(ctx, ScopeUser("test-user"), "Work", "2025-10-17"))
+
+	dates := func(policy visibility.Policy) []string {
+		notes, err := repo.GetVisibleNotes(ctx, ScopeUser("test-user"), policy)
+		require.NoError(t, err)
+		dates := []string{}
+		for _, note := range notes {
+			dates = append(dates, note.Date)
+		}
+		return dates
+	}
+
+	t.Run("Policies", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"2025-10-15", "2025-10-16"}, dates(visibility.App))
+		assert.ElementsMatch(t, []string{"2025-10-15"}, dates(visibility.Export))
+		assert.ElementsMatch(t, []string{"2025-10-15", "2025-10-16"}, dates(visibility.Export.WithLocalOnly()))
+		assert.ElementsMatch(t, []string{"2025-10-15"}, dates(visibility.Storage))
+		assert.ElementsMatch(t, []string{"2025-10-15"}, dates(visibility.Digest))
+		assert.ElementsMatch(t, []string{"2025-10-14", "2025-10-15"}, dates(visibility.Policy{TrashedContexts: true}))
+	})
+
+	t.Run("Search follows the app policy", func(t *testing.T) {
+		results, err := repo.SearchNotes(ctx, "test-user", "visible", models.NoteSearchFilter{}, 10, 0)
+		require.NoError(t, err)
+		found := []string{}
+		for _, result := range results {
+			found = append(found, result.Date)
+		}
+		assert.ElementsMatch(t, []string{"2025-10-15", "2025-10-16"}, found)
+	})
+
+	t.Run("Restored contexts are visible again", func(t *testing.T) {
+		require.NoError(t, repo.RestoreContext(ctx, ScopeUser("test-user"), trashed.ID))
+		assert.ElementsMatch(t, []string{"2025-10-14", "2025-10-15", "2025-10-16"}, dates(visibility.App))
+	})
+}
@@ -0,0 +1,18 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package graph
+
+import (
+	"daily-notes/models"
+)
+
+type Query struct {
+}
+
+type SyncStatusSummary struct {
+	Pending     int                `json:"pending"`
+	Failed      int                `json:"failed"`
+	LocalOnly   int                `json:"localOnly"`
+	FailedNotes []*models.Note     `json:"failedNotes"`
+	Health      *models.SyncHealth `json:"health"`
+}
@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/services"
+	"net/http"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+)
+
+// Resolver serves graph/schema.graphqls with the services the REST handlers use,
+// for the user the request was authenticated as
+type Resolver struct {
+	NoteService    *services.NoteService
+	ContextService *services.ContextService
+	Health         func(userID string) models.SyncHealth // Sync health of a user, as in REST responses
+}
+
+// NewHandler serves queries over GET and POST. Each request gets its own
+// monthLoader, so the months of the listed contexts are loaded together.
+func NewHandler(r *Resolver) http.Handler {
+	srv := handler.New(NewExecutableSchema(Config{Resolvers: r}))
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.Use(extension.Introspection{})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		loader := &monthLoader{notes: r.NoteService, months: make(map[[2]int]*monthLoad)}
+		srv.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), monthLoaderKey{}, loader)))
+	})
+}
+
+// userID returns the user the request was authenticated as. The fiber adaptor
+// passes the request's locals on as values of its context.
+func userID(ctx context.Context) string {
+	id, _ := ctx.Value("userID").(string)
+	return id
+}
+
+type monthLoaderKey struct{}
+
+// monthLoader loads a month of the notes of every context listed by the
+// contexts query at once, the first time one of them asks for that month,
+// instead of one query per context
+type monthLoader struct {
+	notes    *services.NoteService
+	mu       sync.Mutex
+	contexts []string
+	months   map[[2]int]*monthLoad
+}
+
+type monthLoad struct {
+	once  sync.Once
+	notes map[string][]models.Note
+	err   error
+}
+
+// list records the contexts whose months are loaded together
+func (l *monthLoader) list(contexts []*models.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range contexts {
+		l.contexts = append(l.contexts, c.Name)
+	}
+}
+
+// month returns the notes of a context for a month, loading that month for
+// every listed context on the first call
+func (l *monthLoader) month(ctx context.Context, contextName string, year, month int) ([]models.Note, error) {
+	l.mu.Lock()
+	load, ok := l.months[[2]int{year, month}]
+	if !ok {
+		load = &monthLoad{}
+		l.months[[2]int{year, month}] = load
+	}
+	contexts := l.contexts
+	l.mu.Unlock()
+
+	load.once.Do(func() {
+		load.notes, load.err = l.notes.MonthNotes(ctx, userID(ctx), contexts, year, month)
+	})
+	if load.err != nil {
+		return nil, load.err
+	}
+	if notes, ok := load.notes[contextName]; ok {
+		return notes, nil
+	}
+
+	// A context the contexts query didn't list
+	notes, err := l.notes.MonthNotes(ctx, userID(ctx), []string{contextName}, year, month)
+	if err != nil {
+		return nil, err
+	}
+	return notes[contextName], nil
+}
+
+func loaderFor(ctx context.Context) *monthLoader {
+	return ctx.Value(monthLoaderKey{}).(*monthLoader)
+}
+
+// pointers returns pointers to the items of a slice, as gqlgen lists them
+func pointers[T any](items []T) []*T {
+	result := make([]*T, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+	"daily-notes/models"
+)
+
+// Month is the resolver for the month field.
+func (r *contextResolver) Month(ctx context.Context, obj *models.Context, year int, month int) ([]*models.Note, error) {
+	notes, err := loaderFor(ctx).month(ctx, obj.Name, year, month)
+	if err != nil {
+		return nil, err
+	}
+	return pointers(notes), nil
+}
+
+// SyncStatus is the resolver for the syncStatus field.
+func (r *noteResolver) SyncStatus(ctx context.Context, obj *models.Note) (*models.SyncStatus, error) {
+	if obj.SyncStatus == "" {
+		return nil, nil
+	}
+	return &obj.SyncStatus, nil
+}
+
+// Note is the resolver for the note field.
+func (r *queryResolver) Note(ctx context.Context, context string, date string) (*models.Note, error) {
+	return r.NoteService.Get(ctx, userID(ctx), context, date)
+}
+
+// Month is the resolver for the month field.
+func (r *queryResolver) Month(ctx context.Context, context string, year int, month int) ([]*models.Note, error) {
+	notes, err := r.NoteService.MonthNotes(ctx, userID(ctx), []string{context}, year, month)
+	if err != nil {
+		return nil, err
+	}
+	return pointers(notes[context]), nil
+}
+
+// Contexts is the resolver for the contexts field.
+func (r *queryResolver) Contexts(ctx context.Context) ([]*models.Context, error) {
+	contexts, err := r.ContextService.List(ctx, userID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	listed := pointers(contexts)
+	loaderFor(ctx).list(listed)
+	return listed, nil
+}
+
+// SyncStatus is the resolver for the syncStatus field.
+func (r *queryResolver) SyncStatus(ctx context.Context) (*SyncStatusSummary, error) {
+	status, err := r.NoteService.GetSyncStatus(ctx, userID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	failedNotes, _ := status["failed_notes"].([]models.Note)
+	pending, _ := status["pending_count"].(int)
+	failed, _ := status["failed_count"].(int)
+	localOnly, _ := status["local_only_count"].(int)
+	health := r.Health(userID(ctx))
+	return &SyncStatusSummary{
+		Pending:     pending,
+		Failed:      failed,
+		LocalOnly:   localOnly,
+		FailedNotes: pointers(failedNotes),
+		Health:      &health,
+	}, nil
+}
+
+// SizeStats is the resolver for the sizeStats field.
+func (r *queryResolver) SizeStats(ctx context.Context) (*models.NoteSizeStats, error) {
+	return r.NoteService.SizeStats(ctx, userID(ctx))
+}
+
+// Context returns ContextResolver implementation.
+func (r *Resolver) Context() ContextResolver { return &contextResolver{r} }
+
+// Note returns NoteResolver implementation.
+func (r *Resolver) Note() NoteResolver { return &noteResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type contextResolver struct{ *Resolver }
+type noteResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
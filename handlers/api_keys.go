@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAPIKeys lists the user's API keys, without their secrets
+func GetAPIKeys(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		keys, err := a.APIKeys.List(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch API keys", err)
+		}
+		return success(c, fiber.Map{"keys": keys})
+	}
+}
+
+// CreateAPIKey mints a read-only or read-write key for scripts and apps. The
+// secret is only in this response.
+func CreateAPIKey(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.CreateAPIKeyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		key, err := a.APIKeys.Create(c.Context(), middleware.GetUserID(c), req.Name, req.Scope)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to create API key", err)
+		}
+		return created(c, fiber.Map{"key": key})
+	}
+}
+
+// RevokeAPIKey deletes an API key; requests made with it fail from then on
+func RevokeAPIKey(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid key ID")
+		}
+
+		err = a.APIKeys.Revoke(c.Context(), middleware.GetUserID(c), id)
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API key not found"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to revoke API key", err)
+		}
+		return success(c, fiber.Map{"message": "API key revoked"})
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAPITokens lists the user's API tokens, without their secrets
+func GetAPITokens(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokens, err := a.APITokens.List(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch API tokens", err)
+		}
+		return success(c, fiber.Map{"tokens": tokens})
+	}
+}
+
+// CreateAPIToken mints a token limited to one context and a subset of read,
+// append and write. The secret is only in this response.
+func CreateAPIToken(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.CreateAPITokenRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		token, err := a.APITokens.Create(c.Context(), middleware.GetUserID(c), req.Name, req.Context, req.Operations)
+		if errors.Is(err, services.ErrContextNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Context not found"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to create API token", err)
+		}
+		return created(c, fiber.Map{"token": token})
+	}
+}
+
+// RevokeAPIToken deletes an API token; requests made with it fail from then on
+func RevokeAPIToken(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid token ID")
+		}
+
+		err = a.APITokens.Revoke(c.Context(), middleware.GetUserID(c), id)
+		if errors.Is(err, services.ErrAPITokenNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "API token not found"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to revoke API token", err)
+		}
+		return success(c, fiber.Map{"message": "API token revoked"})
+	}
+}
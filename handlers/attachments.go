@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/services"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UploadAttachment attaches an uploaded file (form field "file") to the note of
+// the form's context and date, and returns it with markdown embedding it
+func UploadAttachment(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName := c.FormValue("context")
+		date := c.FormValue("date")
+		if contextName == "" || date == "" {
+			return badRequest(c, "context and date are required")
+		}
+
+		header, err := c.FormFile("file")
+		if err != nil {
+			return badRequest(c, "A file is required")
+		}
+		if header.Size > a.Attachments.MaxSize() {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": services.ErrAttachmentTooLarge.Error()})
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to read the upload", err)
+		}
+		defer file.Close()
+
+		userID := middleware.GetUserID(c)
+		attachment, err := a.Attachments.Upload(c.Context(), userID, contextName, date, header.Filename, file, getToken(c))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrContextNotFound):
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Context not found"})
+			case errors.Is(err, services.ErrAttachmentTooLarge):
+				return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": services.ErrAttachmentTooLarge.Error()})
+			}
+			if target := matchError(err, services.ErrInvalidPeriodKey, services.ErrEmptyAttachment); target != nil {
+				return badRequest(c, target.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to save attachment", err)
+		}
+
+		return created(c, fiber.Map{"attachment": attachment})
+	}
+}
+
+// GetNoteAttachments lists the files attached to the note of ?context= and ?date=
+func GetNoteAttachments(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName := c.Query("context")
+		date := c.Query("date")
+		if contextName == "" || date == "" {
+			return badRequest(c, "context and date are required")
+		}
+
+		attachments, err := a.Attachments.List(c.Context(), middleware.GetUserID(c), contextName, date)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch attachments", err)
+		}
+		return success(c, fiber.Map{"attachments": attachments})
+	}
+}
+
+// GetAttachment serves an attached file to its owner. Images are shown inline;
+// other files are downloaded, so an uploaded page can't run in the app's origin.
+func GetAttachment(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		attachment, file, err := a.Attachments.Open(c.Context(), middleware.GetUserID(c), c.Params("id"))
+		if err != nil {
+			if errors.Is(err, services.ErrAttachmentNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attachment not found"})
+			}
+			return serverErrorWithDetails(c, "Failed to read attachment", err)
+		}
+
+		disposition := "attachment"
+		contentType := "application/octet-stream"
+		if services.IsInlineAttachment(attachment) {
+			disposition, contentType = "inline", attachment.ContentType
+		}
+		c.Set(fiber.HeaderContentType, contentType)
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("%s; filename=%q", disposition, attachment.Filename))
+		c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		// Files never change once uploaded
+		c.Set(fiber.HeaderCacheControl, "private, max-age=31536000, immutable")
+		return c.SendStream(file, int(attachment.Size))
+	}
+}
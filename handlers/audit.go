@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"daily-notes/app"
+	"daily-notes/config"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultAuditMinutes is how long debug recording stays on when no duration is given
+const defaultAuditMinutes = 60
+
+// GetAudit returns the user's debug recording state and recorded requests
+func GetAudit(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return success(c, auditResponse(a, middleware.GetUserID(c)))
+	}
+}
+
+// EnableAudit turns on recording of the user's note and sync requests for a limited time
+func EnableAudit(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.EnableAuditRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return badRequest(c, "Invalid request body")
+			}
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+		if req.Minutes == 0 {
+			req.Minutes = defaultAuditMinutes
+		}
+
+		userID := middleware.GetUserID(c)
+		until := a.AuditLog.Enable(userID, time.Duration(req.Minutes)*time.Minute)
+		a.Logger.Info("audit mode enabled", "user_id", userID, "until", until)
+
+		return success(c, auditResponse(a, userID))
+	}
+}
+
+// DisableAudit stops recording; with ?clear=true the recorded requests are dropped too
+func DisableAudit(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := middleware.GetUserID(c)
+		if c.QueryBool("clear") {
+			a.AuditLog.Clear(userID)
+		} else {
+			a.AuditLog.Disable(userID)
+		}
+
+		return success(c, auditResponse(a, userID))
+	}
+}
+
+// GetUserAudit lets support read a user's recorded requests
+// Requires the X-Support-Token header to match SUPPORT_TOKEN.
+func GetUserAudit(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !supportAuthorized(c) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid support token"})
+		}
+
+		return success(c, auditResponse(a, c.Params("userID")))
+	}
+}
+
+// supportAuthorized reports whether the X-Support-Token header matches SUPPORT_TOKEN
+func supportAuthorized(c *fiber.Ctx) bool {
+	token := c.Get("X-Support-Token")
+	expected := config.AppConfig.SupportToken
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+func auditResponse(a *app.App, userID string) fiber.Map {
+	response := fiber.Map{
+		"enabled": false,
+		"entries": a.AuditLog.Entries(userID),
+	}
+	if until, ok := a.AuditLog.Active(userID); ok {
+		response["enabled"] = true
+		response["until"] = until
+	}
+	return response
+}
@@ -28,15 +28,15 @@ func Login(a *app.App) fiber.Handler {
 		if req.Code != "" {
 			// Authorization Code Flow (modern, recommended)
 			log.Printf("[AUTH] Using authorization code flow")
-			loginResponse, err = a.AuthService.LoginWithCode(req.Code)
+			loginResponse, err = a.AuthService.LoginWithCode(c.Context(), req.Code)
 		} else if req.IDToken != "" {
 			// One Tap Sign-in (ID token from Google)
 			log.Printf("[AUTH] Using One Tap ID token flow")
-			loginResponse, err = a.AuthService.LoginWithIDToken(req.IDToken)
+			loginResponse, err = a.AuthService.LoginWithIDToken(c.Context(), req.IDToken)
 		} else if req.AccessToken != "" {
 			// Direct Token Flow (legacy support)
 			log.Printf("[AUTH] Using direct access token flow (legacy)")
-			loginResponse, err = a.AuthService.LoginWithToken(req.AccessToken, req.RefreshToken, req.ExpiresIn)
+			loginResponse, err = a.AuthService.LoginWithToken(c.Context(), req.AccessToken, req.RefreshToken, req.ExpiresIn)
 		} else {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "code, id_token, or access_token is required",
@@ -77,7 +77,7 @@ func Login(a *app.App) fiber.Handler {
 				"email":         loginResponse.Session.Email,
 				"name":          loginResponse.Session.Name,
 				"picture":       loginResponse.Session.Picture,
-				"settings":      loginResponse.Session.Settings,
+				"settings":      storedSettings(c, a, loginResponse.Session.UserID, loginResponse.Session.Settings),
 				"hasNoContexts": loginResponse.HasNoContexts,
 			},
 		})
@@ -128,8 +128,9 @@ func Me(a *app.App) fiber.Handler {
 				"email":    sess.Email,
 				"name":     sess.Name,
 				"picture":  sess.Picture,
-				"settings": sess.Settings,
+				"settings": storedSettings(c, a, sess.UserID, sess.Settings),
 			},
+			"sync_health": syncHealth(a, sess.UserID),
 		})
 	}
 }
@@ -166,21 +167,33 @@ func UpdateSettings(a *app.App) fiber.Handler {
 			ShowBreadcrumb:       req.ShowBreadcrumb,
 			ShowMarkdownEditor:   req.ShowMarkdownEditor,
 			HideNewContextButton: req.HideNewContextButton,
+			SuggestContext:       req.SuggestContext,
 		}
 
-		if err := a.Repo.UpdateUserSettings(sess.UserID, settings); err != nil {
+		if req.StorageProvider != "" {
+			if _, err := a.StorageService.Select(c.Context(), sess.UserID, req.StorageProvider); err != nil {
+				if target := matchError(err, services.ErrStorageUnavailable, services.ErrStorageNotConnected); target != nil {
+					return badRequest(c, target.Error())
+				}
+				return serverErrorWithDetails(c, "Failed to update storage provider", err)
+			}
+		}
+
+		if err := a.Repo.UpdateUserSettings(c.Context(), sess.UserID, settings); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to update settings",
 			})
 		}
 
+		recordTimezoneChange(c, a, sess.UserID, sess.Settings.Timezone, settings.Timezone)
+
 		// Update session with new settings
 		sess.Settings = settings
 		a.SessionStore.Update(sessionID, sess)
 
 		return c.JSON(fiber.Map{
 			"success": true,
-			"settings": settings,
+			"settings": storedSettings(c, a, sess.UserID, settings),
 		})
 	}
 }
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"daily-notes/app"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetBackups lists the database backups in BACKUP_DIR, newest first
+// Requires the X-Support-Token header to match SUPPORT_TOKEN.
+func GetBackups(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !supportAuthorized(c) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid support token"})
+		}
+
+		if a.Backups == nil {
+			return success(c, fiber.Map{"enabled": false, "backups": []any{}})
+		}
+
+		backups, err := a.Backups.List()
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to list backups", err)
+		}
+		return success(c, fiber.Map{"enabled": true, "backups": backups})
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/pkg/changelog"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetChangelog returns the release notes with the ones the user hasn't seen yet,
+// so clients can show a what's-new dialog once per release
+func GetChangelog(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		lastSeen, err := a.Repo.GetLastSeenVersion(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to get changelog", err)
+		}
+
+		return success(c, fiber.Map{"changelog": changelog.ForUser(lastSeen)})
+	}
+}
+
+// MarkChangelogSeen records the release the user was shown, the current one by default
+func MarkChangelogSeen(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.MarkChangelogSeenRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return badRequest(c, "Invalid request body")
+			}
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+		if req.Version == "" {
+			req.Version = changelog.Current()
+		}
+		if !changelog.Has(req.Version) {
+			return badRequest(c, "Unknown version")
+		}
+
+		userID := middleware.GetUserID(c)
+		if err := a.Repo.SetLastSeenVersion(c.Context(), userID, req.Version); err != nil {
+			return serverErrorWithDetails(c, "Failed to save the last seen version", err)
+		}
+
+		return success(c, fiber.Map{"changelog": changelog.ForUser(req.Version)})
+	}
+}
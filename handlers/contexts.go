@@ -5,6 +5,7 @@ import (
 	"daily-notes/middleware"
 	"daily-notes/models"
 	"daily-notes/services"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/oauth2"
@@ -29,7 +30,7 @@ func GetContexts(a *app.App) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userID := middleware.GetUserID(c)
 
-		contexts, err := a.ContextService.List(userID)
+		contexts, err := a.ContextService.List(c.Context(), userID)
 		if err != nil {
 			return serverErrorWithDetails(c, "Failed to fetch contexts", err)
 		}
@@ -38,6 +39,58 @@ func GetContexts(a *app.App) fiber.Handler {
 	}
 }
 
+// ReorderContexts puts the user's contexts in the order of the IDs in the body,
+// as dragged in a list; contexts left out follow in their current order
+func ReorderContexts(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.ReorderContextsRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		contexts, err := a.ContextService.Reorder(c.Context(), middleware.GetUserID(c), req.IDs)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrContextNotFound):
+				return badRequest(c, "Context not found")
+			case errors.Is(err, services.ErrContextListedTwice):
+				return badRequest(c, services.ErrContextListedTwice.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to reorder contexts", err)
+		}
+
+		return success(c, fiber.Map{"contexts": contexts})
+	}
+}
+
+// SuggestContext ranks the user's contexts for a piece of content
+func SuggestContext(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.SuggestContextRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		// Validate request
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		suggestions, err := a.ContextService.Suggest(c.Context(), userID, req.Content)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to suggest context", err)
+		}
+
+		return success(c, fiber.Map{"suggestions": suggestions})
+	}
+}
+
 // CreateContext creates a new context for a user
 func CreateContext(a *app.App) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -53,9 +106,9 @@ func CreateContext(a *app.App) fiber.Handler {
 
 		userID := middleware.GetUserID(c)
 
-		ctx, err := a.ContextService.Create(userID, req.Name, req.Color)
+		ctx, err := a.ContextService.Create(c.Context(), userID, req.Name, req.Color, req.Icon, getToken(c))
 		if err != nil {
-			if err == services.ErrContextAlreadyExists {
+			if errors.Is(err, services.ErrContextAlreadyExists) {
 				return badRequest(c, "Context with this name already exists")
 			}
 			return serverErrorWithDetails(c, "Failed to create context", err)
@@ -86,8 +139,8 @@ func UpdateContext(a *app.App) fiber.Handler {
 		userID := middleware.GetUserID(c)
 		token := getToken(c)
 
-		if err := a.ContextService.Update(contextID, req.Name, req.Color, userID, token); err != nil {
-			if err == services.ErrContextNotFound {
+		if err := a.ContextService.Update(c.Context(), contextID, req.Name, req.Color, req.Icon, userID, token); err != nil {
+			if errors.Is(err, services.ErrContextNotFound) {
 				return badRequest(c, "Context not found")
 			}
 			return serverErrorWithDetails(c, "Failed to update context", err)
@@ -97,6 +150,126 @@ func UpdateContext(a *app.App) fiber.Handler {
 	}
 }
 
+// UpdateContextTemplate sets the template used to scaffold new notes in a context
+func UpdateContextTemplate(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextID := c.Params("id")
+		if contextID == "" {
+			return badRequest(c, "context ID is required")
+		}
+
+		var req models.UpdateContextTemplateRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		ctx, err := a.ContextService.SetTemplate(c.Context(), contextID, userID, req.Template)
+		if err != nil {
+			if errors.Is(err, services.ErrContextNotFound) {
+				return badRequest(c, "Context not found")
+			}
+			return serverErrorWithDetails(c, "Failed to update template", err)
+		}
+
+		return success(c, fiber.Map{"context": ctx})
+	}
+}
+
+// SetContextLocalOnly marks a context local only, so none of its notes sync to
+// cloud storage, or lets its notes sync again
+func SetContextLocalOnly(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextID := c.Params("id")
+		if contextID == "" {
+			return badRequest(c, "context ID is required")
+		}
+
+		var req models.SetContextLocalOnlyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		userID := middleware.GetUserID(c)
+
+		ctx, err := a.ContextService.SetLocalOnly(c.Context(), contextID, userID, req.LocalOnly)
+		if err != nil {
+			if errors.Is(err, services.ErrContextNotFound) {
+				return badRequest(c, "Context not found")
+			}
+			return serverErrorWithDetails(c, "Failed to update context", err)
+		}
+
+		return success(c, fiber.Map{"context": ctx})
+	}
+}
+
+// SetContextLanguage sets the language of a context's notes, which transcription uses
+// when the request doesn't name one
+func SetContextLanguage(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextID := c.Params("id")
+		if contextID == "" {
+			return badRequest(c, "context ID is required")
+		}
+
+		var req models.SetContextLanguageRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		ctx, err := a.ContextService.SetLanguage(c.Context(), contextID, userID, req.Language)
+		if err != nil {
+			if errors.Is(err, services.ErrContextNotFound) {
+				return badRequest(c, "Context not found")
+			}
+			return serverErrorWithDetails(c, "Failed to update language", err)
+		}
+
+		return success(c, fiber.Map{"context": ctx})
+	}
+}
+
+// GetContextStats returns the note count, first and last note dates, words and
+// sync state of a context, for the contexts sidebar
+func GetContextStats(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextID := c.Params("id")
+		if contextID == "" {
+			return badRequest(c, "context ID is required")
+		}
+
+		userID := middleware.GetUserID(c)
+
+		stats, err := a.ContextService.Stats(c.Context(), contextID, userID)
+		if err != nil {
+			if errors.Is(err, services.ErrContextNotFound) {
+				return badRequest(c, "Context not found")
+			}
+			return serverErrorWithDetails(c, "Failed to get context stats", err)
+		}
+
+		// Unreachable storage holds up every note that isn't kept local
+		if health := syncHealth(a, userID); health.State == models.SyncHealthOffline && stats.Sync.Local < stats.Notes {
+			stats.Sync.State = health.State
+			stats.Sync.Message = health.Message
+		}
+
+		return success(c, fiber.Map{"stats": stats})
+	}
+}
+
 // DeleteContext deletes a context and its notes
 func DeleteContext(a *app.App) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -108,8 +281,8 @@ func DeleteContext(a *app.App) fiber.Handler {
 		userID := middleware.GetUserID(c)
 		token := getToken(c)
 
-		if err := a.ContextService.Delete(contextID, userID, token); err != nil {
-			if err == services.ErrContextNotFound {
+		if err := a.ContextService.Delete(c.Context(), contextID, userID, token); err != nil {
+			if errors.Is(err, services.ErrContextNotFound) {
 				return badRequest(c, "Context not found")
 			}
 			return serverErrorWithDetails(c, "Failed to delete context", err)
@@ -120,3 +293,46 @@ func DeleteContext(a *app.App) fiber.Handler {
 		})
 	}
 }
+
+// GetContextTrash lists deleted contexts that can still be restored
+func GetContextTrash(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := middleware.GetUserID(c)
+
+		trashed, err := a.ContextService.ListTrash(c.Context(), userID)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch deleted contexts", err)
+		}
+
+		return success(c, fiber.Map{"contexts": trashed})
+	}
+}
+
+// RestoreContext restores a deleted context and re-imports its notes
+func RestoreContext(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextID := c.Params("id")
+		if contextID == "" {
+			return badRequest(c, "context ID is required")
+		}
+
+		userID := middleware.GetUserID(c)
+		token := getToken(c)
+
+		ctx, err := a.ContextService.Restore(c.Context(), contextID, userID, token)
+		if err != nil {
+			if errors.Is(err, services.ErrContextNotInTrash) {
+				return badRequest(c, "Context not found in trash")
+			}
+			if errors.Is(err, services.ErrContextAlreadyExists) {
+				return badRequest(c, "Context with this name already exists")
+			}
+			return serverErrorWithDetails(c, "Failed to restore context", err)
+		}
+
+		return success(c, fiber.Map{
+			"context": ctx,
+			"message": "Context restored. Notes are being re-imported from Google Drive.",
+		})
+	}
+}
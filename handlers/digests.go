@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/services"
+	"daily-notes/templates/pages"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetDigest returns whether the server sends weekly digests and whether the user gets them
+func GetDigest(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		settings, err := a.Digests.Get(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch digest settings", err)
+		}
+		return success(c, fiber.Map{"digest": settings})
+	}
+}
+
+// SubscribeDigest emails the user a digest of their notes every week, from the
+// current one (in the timezone setting)
+func SubscribeDigest(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		settings, err := a.Digests.Subscribe(c.Context(), middleware.GetUserID(c), userLocation(c))
+		if errors.Is(err, services.ErrDigestsDisabled) {
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": services.ErrDigestsDisabled.Error()})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to subscribe to the weekly digest", err)
+		}
+		return success(c, fiber.Map{"digest": settings})
+	}
+}
+
+// UnsubscribeDigest stops emailing the user weekly digests
+func UnsubscribeDigest(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := a.Digests.Unsubscribe(c.Context(), middleware.GetUserID(c))
+		if errors.Is(err, services.ErrDigestNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Not subscribed to the weekly digest"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to unsubscribe from the weekly digest", err)
+		}
+		return success(c, fiber.Map{"message": "Unsubscribed from the weekly digest"})
+	}
+}
+
+// DigestUnsubscribePage asks to confirm the unsubscribe link of a digest email.
+// Opening the link changes nothing, as mail scanners open links too.
+func DigestUnsubscribePage(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return renderPage(c, pages.DigestUnsubscribe(pages.DigestUnsubscribeView{Token: c.Params("token")}))
+	}
+}
+
+// DigestUnsubscribe ends the subscription of an unsubscribe link, without
+// signing in. Mail clients post here for one-click unsubscribes (RFC 8058), and
+// the confirmation form of DigestUnsubscribePage does too.
+func DigestUnsubscribe(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := a.Digests.UnsubscribeToken(c.Context(), c.Params("token"))
+		if errors.Is(err, services.ErrDigestNotFound) {
+			c.Status(fiber.StatusNotFound)
+			return renderPage(c, pages.DigestUnsubscribe(pages.DigestUnsubscribeView{NotFound: true}))
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to unsubscribe from the weekly digest", err)
+		}
+		return renderPage(c, pages.DigestUnsubscribe(pages.DigestUnsubscribeView{Done: true}))
+	}
+}
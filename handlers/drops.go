@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateDropURL signs a URL that appends one payload to a note of the user
+// without signing in, until it expires
+func CreateDropURL(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.CreateDropURLRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		ttl := time.Duration(req.ExpiresIn) * time.Second
+		drop, err := a.Drops.Sign(c.Context(), middleware.GetUserID(c), req.Context, req.Date, ttl)
+		if errors.Is(err, services.ErrContextNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Context not found"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to create drop box URL", err)
+		}
+		drop.URL = c.BaseURL() + drop.URL
+		return created(c, fiber.Map{"drop": drop})
+	}
+}
+
+// Drop appends the request body to the note a signed drop box URL was made for.
+// The URL is the only credential, and works once.
+func Drop(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var query models.DropQuery
+		if err := c.QueryParser(&query); err != nil {
+			return badRequest(c, "Invalid drop box URL")
+		}
+		userID, userErr := url.PathUnescape(c.Params("user"))
+		contextName, contextErr := url.PathUnescape(c.Params("context"))
+		date, dateErr := url.PathUnescape(c.Params("date"))
+		if userErr != nil || contextErr != nil || dateErr != nil {
+			return badRequest(c, "Invalid drop box URL")
+		}
+
+		note, err := a.Drops.Submit(c.Context(), userID, contextName, date, query, string(c.Body()))
+		switch {
+		case errors.Is(err, services.ErrInvalidDropURL):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": services.ErrInvalidDropURL.Error()})
+		case errors.Is(err, services.ErrDropExpired):
+			return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": services.ErrDropExpired.Error()})
+		case errors.Is(err, services.ErrDropUsed):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": services.ErrDropUsed.Error()})
+		case errors.Is(err, services.ErrDropTooLarge):
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": services.ErrDropTooLarge.Error()})
+		case errors.Is(err, services.ErrEmptyDrop):
+			return badRequest(c, services.ErrEmptyDrop.Error())
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to add to the note", err)
+		}
+
+		// The URL can only write, so the note's content isn't sent back
+		return success(c, fiber.Map{"context": note.Context, "date": note.Date, "revision": note.Revision})
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetEncryption returns whether the user's notes are end-to-end encrypted, the
+// parameters clients derive the key with, and how many notes aren't encrypted yet
+func GetEncryption(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		status, err := a.Encryption.Status(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch encryption settings", err)
+		}
+		return success(c, fiber.Map{"encryption": status})
+	}
+}
+
+// EnableEncryption turns on end-to-end encryption; from then on notes are only
+// saved encrypted with the key the request describes
+func EnableEncryption(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.EnableEncryptionRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		_, err := a.Encryption.Enable(c.Context(), userID, req)
+		switch {
+		case errors.Is(err, services.ErrInvalidEncryptionSalt):
+			return badRequest(c, services.ErrInvalidEncryptionSalt.Error())
+		case errors.Is(err, services.ErrEncryptionEnabled):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": services.ErrEncryptionEnabled.Error()})
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to enable encryption", err)
+		}
+
+		status, err := a.Encryption.Status(c.Context(), userID)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch encryption settings", err)
+		}
+		return success(c, fiber.Map{"encryption": status})
+	}
+}
+
+// VerifyEncryptionKey tells a client whether the key it derived from a
+// passphrase is the user's, before it encrypts or decrypts with it
+func VerifyEncryptionKey(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.VerifyEncryptionKeyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		valid, err := a.Encryption.Verify(c.Context(), middleware.GetUserID(c), req.Verifier)
+		if errors.Is(err, services.ErrEncryptionNotEnabled) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Notes are not encrypted"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to verify encryption key", err)
+		}
+		return success(c, fiber.Map{"valid": valid})
+	}
+}
+
+// DisableEncryption turns end-to-end encryption off once no note is encrypted
+func DisableEncryption(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := a.Encryption.Disable(c.Context(), middleware.GetUserID(c))
+		switch {
+		case errors.Is(err, services.ErrEncryptionNotEnabled):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Notes are not encrypted"})
+		case errors.Is(err, services.ErrEncryptedNotesRemain):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": services.ErrEncryptedNotesRemain.Error()})
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to disable encryption", err)
+		}
+		return success(c, fiber.Map{"message": "Encryption turned off"})
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bufio"
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// syncEventsKeepAlive is how often an idle event stream sends a comment, so
+	// proxies keep it open and streams of clients that went away end
+	syncEventsKeepAlive = 20 * time.Second
+
+	// syncEventsRetry is how long browsers wait before reconnecting a dropped stream
+	syncEventsRetry = 3 * time.Second
+)
+
+// StreamSyncEvents streams the sync state changes of the user's notes as
+// server-sent events, one "sync" event per change with a models.SyncEvent as
+// data. Events published while a client is disconnected are not replayed;
+// clients reload GET /api/sync/status after reconnecting.
+func StreamSyncEvents(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if a.SyncWorker == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Sync is not running"})
+		}
+
+		events, unsubscribe := a.SyncWorker.Subscribe(middleware.GetUserID(c))
+		conn := c.Context().Conn()
+
+		c.Set(fiber.HeaderContentType, "text/event-stream")
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+		c.Set(fiber.HeaderConnection, "keep-alive")
+		c.Set("X-Accel-Buffering", "no") // Keeps nginx from buffering the stream
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer unsubscribe()
+
+			keepAlive := time.NewTicker(syncEventsKeepAlive)
+			defer keepAlive.Stop()
+
+			// The server's write timeout covers the whole response, so every
+			// write pushes it past the next keep-alive
+			flush := func() bool {
+				if conn != nil {
+					conn.SetWriteDeadline(time.Now().Add(2 * syncEventsKeepAlive))
+				}
+				return w.Flush() == nil
+			}
+
+			fmt.Fprintf(w, "retry: %d\n\n", syncEventsRetry.Milliseconds())
+			if !flush() {
+				return
+			}
+			for {
+				select {
+				case event, ok := <-events:
+					if !ok {
+						return // The worker stopped
+					}
+					data, err := json.Marshal(event)
+					if err != nil {
+						continue
+					}
+					fmt.Fprintf(w, "event: sync\ndata: %s\n\n", data)
+				case <-keepAlive.C:
+					fmt.Fprint(w, ": keep-alive\n\n")
+				}
+				if !flush() {
+					return
+				}
+			}
+		})
+		return nil
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/graph"
+	"daily-notes/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+// GraphQL serves the queries of graph/schema.graphqls, which fetch what several
+// REST routes return in one round trip, e.g. a month of notes with the contexts
+// and the sync status
+func GraphQL(a *app.App) fiber.Handler {
+	return adaptor.HTTPHandler(graph.NewHandler(&graph.Resolver{
+		NoteService:    a.NoteService,
+		ContextService: a.ContextService,
+		Health:         func(userID string) models.SyncHealth { return syncHealth(a, userID) },
+	}))
+}
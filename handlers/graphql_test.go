@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"daily-notes/handlers"
+	"daily-notes/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGraphQL tests fetching a month of notes with the contexts and the sync
+// status in one query
+func TestGraphQL(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, c := range []models.Context{
+		{ID: "ctx-work", UserID: "test-user-id", Name: "Work", Color: "primary", CreatedAt: time.Now()},
+		{ID: "ctx-home", UserID: "test-user-id", Name: "Home", Color: "info", Icon: "🏠", LocalOnly: true, CreatedAt: time.Now()},
+	} {
+		require.NoError(t, application.Repo.CreateContext(ctx, &c))
+	}
+	for _, note := range []models.Note{
+		{Context: "Work", Date: "2025-10-01", Content: "Kickoff #project"},
+		{Context: "Work", Date: "2025-10-15", Content: "Review"},
+		{Context: "Work", Date: "2025-11-03", Content: "Next month"},
+		{Context: "Home", Date: "2025-10-02", Content: "Groceries"},
+	} {
+		note.UserID = "test-user-id"
+		note.CreatedAt, note.UpdatedAt = time.Now(), time.Now()
+		require.NoError(t, application.Repo.UpsertNote(ctx, &note, true))
+	}
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/graphql", handlers.GraphQL(application))
+	fiberApp.Post("/api/graphql", handlers.GraphQL(application))
+
+	type note struct {
+		Date       string   `json:"date"`
+		Content    string   `json:"content"`
+		Tags       []string `json:"tags"`
+		SyncStatus *string  `json:"syncStatus"`
+	}
+	var result struct {
+		Data struct {
+			Contexts []struct {
+				Name  string `json:"name"`
+				Icon  string `json:"icon"`
+				Month []note `json:"month"`
+			} `json:"contexts"`
+			Month      []note `json:"month"`
+			SyncStatus struct {
+				Pending   int `json:"pending"`
+				Failed    int `json:"failed"`
+				LocalOnly int `json:"localOnly"`
+				Health    struct {
+					State string `json:"state"`
+				} `json:"health"`
+			} `json:"syncStatus"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	query := `{
+		contexts { name icon month(year: 2025, month: 10) { date content tags syncStatus } }
+		month(context: "Work", year: 2025, month: 11) { date content }
+		syncStatus { pending failed localOnly health { state } }
+	}`
+
+	t.Run("Fetches contexts, months and sync status in one request", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		req := httptest.NewRequest(http.MethodPost, "/api/graphql", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		require.Empty(t, result.Errors)
+
+		contexts := map[string][]note{}
+		for _, c := range result.Data.Contexts {
+			contexts[c.Name] = c.Month
+		}
+		require.Len(t, contexts, 2)
+		require.Len(t, contexts["Work"], 2, "only the notes of October")
+		assert.Equal(t, "2025-10-01", contexts["Work"][0].Date)
+		assert.Equal(t, []string{"project"}, contexts["Work"][0].Tags)
+		require.NotNil(t, contexts["Work"][0].SyncStatus)
+		assert.Equal(t, "pending", *contexts["Work"][0].SyncStatus)
+		require.Len(t, contexts["Home"], 1)
+		assert.Equal(t, "Groceries", contexts["Home"][0].Content)
+
+		require.Len(t, result.Data.Month, 1)
+		assert.Equal(t, "Next month", result.Data.Month[0].Content)
+
+		assert.Equal(t, 1, result.Data.SyncStatus.LocalOnly)
+		assert.Equal(t, "ok", result.Data.SyncStatus.Health.State)
+	})
+
+	t.Run("Answers queries over GET", func(t *testing.T) {
+		resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/graphql?query="+url.QueryEscape(`{ contexts { name } }`), nil), -1)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var result fiber.Map
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Len(t, result["data"].(map[string]any)["contexts"], 2)
+	})
+
+	t.Run("Reports invalid months as errors", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]string{"query": `{ month(context: "Work", year: 2025, month: 13) { date } }`})
+		req := httptest.NewRequest(http.MethodPost, "/api/graphql", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		var result fiber.Map
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.NotEmpty(t, result["errors"])
+	})
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ImportNotes imports the notes of an uploaded zip archive (form field "file") with
+// one folder per context and one markdown file per day, of a zipped Obsidian vault
+// with format=obsidian, or of an account export with format=json, and reports each file
+func ImportNotes(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		format, err := services.ParseImportFormat(c.FormValue("format"))
+		if err != nil {
+			return badRequest(c, err.Error())
+		}
+
+		header, err := c.FormFile("file")
+		if err != nil {
+			return badRequest(c, "A zip file is required")
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to read the upload", err)
+		}
+		defer file.Close()
+
+		userID := middleware.GetUserID(c)
+
+		if format == services.ImportFormatJSON {
+			return importAccount(a, c, userID, file)
+		}
+
+		result, err := a.ImportService.Import(c.Context(), userID, format, file, header.Size)
+		if err != nil {
+			if target := matchError(err, services.ErrInvalidArchive, services.ErrTooManyImportFiles); target != nil {
+				return badRequest(c, target.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to import notes", err)
+		}
+
+		return success(c, fiber.Map{
+			"result":      result,
+			"sync_health": syncHealth(a, userID),
+		})
+	}
+}
+
+// importAccount restores an account export: the profile's settings and contexts, then its notes
+func importAccount(a *app.App, c *fiber.Ctx, userID string, file io.Reader) error {
+	var account models.AccountExport
+	if err := json.NewDecoder(file).Decode(&account); err != nil {
+		return badRequest(c, "Upload is not a valid JSON export")
+	}
+	if err := a.Validator.Validate(&account); err != nil {
+		return validationError(c, err)
+	}
+
+	profile, err := a.ProfileService.Import(c.Context(), userID, &account.Profile)
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedProfile) {
+			return badRequest(c, "Export was made by a newer version")
+		}
+		return serverErrorWithDetails(c, "Failed to import account", err)
+	}
+
+	// Keep the current session in sync with the imported settings
+	if sess, ok := c.Locals("session").(*models.Session); ok && sess != nil {
+		sess.Settings = profile.Settings
+		a.SessionStore.Update(c.Cookies("session_id"), sess)
+	}
+
+	result, err := a.ImportService.ImportNotes(c.Context(), userID, account.Notes)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyImportFiles) {
+			return badRequest(c, err.Error())
+		}
+		return serverErrorWithDetails(c, "Failed to import account", err)
+	}
+
+	return success(c, fiber.Map{
+		"result":      result,
+		"profile":     profile,
+		"sync_health": syncHealth(a, userID),
+	})
+}
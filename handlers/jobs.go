@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetJobs lists the latest background jobs of every user, newest first.
+// Accepts ?status=queued|running|done|failed and ?limit= (default 50, at most 200).
+// Requires the X-Support-Token header to match SUPPORT_TOKEN.
+func GetJobs(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !supportAuthorized(c) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid support token"})
+		}
+
+		status := c.Query("status")
+		switch status {
+		case "", models.JobQueued, models.JobRunning, models.JobDone, models.JobFailed:
+		default:
+			return badRequest(c, "Invalid status")
+		}
+		limit := min(max(c.QueryInt("limit", 50), 1), 200)
+
+		if a.Jobs == nil {
+			return success(c, fiber.Map{"enabled": false, "jobs": []any{}})
+		}
+
+		jobs, err := a.Jobs.List(c.Context(), status, limit)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to list jobs", err)
+		}
+		return success(c, fiber.Map{"enabled": true, "jobs": jobs})
+	}
+}
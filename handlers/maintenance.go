@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"daily-notes/app"
+	"daily-notes/models"
+	"daily-notes/pkg/maintenance"
+	"daily-notes/services"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetMaintenance returns the maintenance mode state, with the notes the sync
+// worker has yet to upload so admins can wait for it to drain.
+// Requires the X-Support-Token header to match SUPPORT_TOKEN.
+func GetMaintenance(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !supportAuthorized(c) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid support token"})
+		}
+		if a.Maintenance == nil {
+			return maintenanceUnavailable(c)
+		}
+		return maintenanceResponse(c, a, fiber.Map{})
+	}
+}
+
+// StartMaintenance turns maintenance mode on, or changes its reason, retry time
+// and whether note saves are journaled. Requires the X-Support-Token header.
+func StartMaintenance(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !supportAuthorized(c) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid support token"})
+		}
+		if a.Maintenance == nil {
+			return maintenanceUnavailable(c)
+		}
+
+		var req models.StartMaintenanceRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		if err := a.Maintenance.Enable(req.Reason, time.Duration(req.RetryAfter)*time.Second, req.JournalSaves); err != nil {
+			return serverErrorWithDetails(c, "Failed to start maintenance", err)
+		}
+		a.Logger.Warn("maintenance mode started", "reason", req.Reason, "journal_saves", req.JournalSaves)
+		return maintenanceResponse(c, a, fiber.Map{})
+	}
+}
+
+// EndMaintenance replays the journaled note saves and turns maintenance mode
+// off. Saves that fail to replay are skipped and listed in the response.
+// Requires the X-Support-Token header.
+func EndMaintenance(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !supportAuthorized(c) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid support token"})
+		}
+		if a.Maintenance == nil {
+			return maintenanceUnavailable(c)
+		}
+
+		replayed, failed, err := a.Maintenance.End(func(save models.JournaledSave) error {
+			return replayNoteSave(c.Context(), a, save)
+		})
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to replay journaled saves", err)
+		}
+		for _, f := range failed {
+			if f.Save != nil {
+				a.Logger.Warn("journaled save failed to replay", "user_id", f.Save.UserID, "context", f.Save.Context, "date", f.Save.Date, "error", f.Error)
+			} else {
+				a.Logger.Warn("journaled save failed to replay", "error", f.Error)
+			}
+		}
+		a.Logger.Warn("maintenance mode ended", "replayed", replayed, "failed", len(failed))
+		if failed == nil {
+			failed = []models.FailedSave{}
+		}
+		return maintenanceResponse(c, a, fiber.Map{"replayed": replayed, "failed": failed})
+	}
+}
+
+// journalNoteSave keeps a note save made during maintenance for replay once it
+// ends, after the checks a save runs. The base revision is checked against the
+// note with the saves journaled before, and kept so the save only replays onto
+// it. It reports false when saves aren't journaled anymore, so the save goes through.
+func journalNoteSave(c *fiber.Ctx, a *app.App, userID string, req models.CreateNoteRequest) (bool, error) {
+	current, err := a.NoteService.CheckSave(c.Context(), userID, req)
+	if errors.Is(err, services.ErrContextNotFound) {
+		return true, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Context not found"})
+	}
+	if err != nil {
+		return true, serverErrorWithDetails(c, "Failed to save note", err)
+	}
+
+	save := models.JournaledSave{
+		UserID:  userID,
+		OwnerID: current.UserID,
+		Context: req.Context,
+		Date:    req.Date,
+		Content: req.Content,
+		Append:  req.Append,
+		Section: req.Section,
+	}
+	if revision, ok := baseRevision(c, req.Revision); ok {
+		save.BaseRevision = &revision
+	} else if since, ok := unmodifiedSince(c); ok && !req.Append {
+		if current.UpdatedAt.Truncate(time.Second).After(since) {
+			return true, noteConflict(c, current, journalConflictDiff(current, req))
+		}
+		// Journaled saves will modify the note too, so it must have none
+		save.BaseRevision = &current.Revision
+	}
+
+	revision, err := a.Maintenance.Journal(save, current.Revision)
+	switch {
+	case errors.Is(err, maintenance.ErrNotJournaling):
+		return false, nil
+	case errors.Is(err, maintenance.ErrConflict) && revision == current.Revision:
+		return true, noteConflict(c, current, journalConflictDiff(current, req))
+	case errors.Is(err, maintenance.ErrConflict):
+		// The saved note is behind the journal, so there's nothing to merge with yet
+		c.Set(fiber.HeaderETag, fmt.Sprintf(`"%d"`, revision))
+		return true, c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":    "Note was updated elsewhere during maintenance. Reload it once maintenance ends.",
+			"revision": revision,
+		})
+	case err != nil:
+		return true, serverErrorWithDetails(c, "Failed to save note", err)
+	}
+	// Clients base their next save on the revision the note has once this one replays
+	c.Set(fiber.HeaderETag, fmt.Sprintf(`"%d"`, revision))
+	return true, c.Status(fiber.StatusAccepted).JSON(fiber.Map{"journaled": true, "revision": revision})
+}
+
+// journalConflictDiff is the diff of a refused save, none for appends
+func journalConflictDiff(current *models.Note, req models.CreateNoteRequest) fiber.Map {
+	if req.Append {
+		return nil
+	}
+	return conflictDiff(current, req.Content)
+}
+
+// replayNoteSave applies a journaled save onto the revision it was based on, if
+// any; overwritten content stays in the revision history
+func replayNoteSave(ctx context.Context, a *app.App, save models.JournaledSave) error {
+	var err error
+	switch {
+	case save.Append:
+		_, err = a.NoteService.Append(ctx, save.UserID, save.Context, save.Date, save.Content, save.Section, save.BaseRevision)
+	case save.BaseRevision != nil:
+		_, err = a.NoteService.UpsertAtRevision(ctx, save.UserID, save.Context, save.Date, save.Content, *save.BaseRevision)
+	default:
+		_, err = a.NoteService.Upsert(ctx, save.UserID, save.Context, save.Date, save.Content)
+	}
+	return err
+}
+
+func maintenanceResponse(c *fiber.Ctx, a *app.App, response fiber.Map) error {
+	status := a.Maintenance.Status()
+	pending, err := a.Repo.CountPendingSyncNotes(c.Context())
+	if err != nil {
+		return serverErrorWithDetails(c, "Failed to count notes waiting to sync", err)
+	}
+	status.PendingSync = pending
+	response["maintenance"] = status
+	return success(c, response)
+}
+
+func maintenanceUnavailable(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Maintenance mode is unavailable, see the server log"})
+}
@@ -0,0 +1,102 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"daily-notes/config"
+	"daily-notes/database"
+	"daily-notes/handlers"
+	"daily-notes/models"
+	"daily-notes/pkg/maintenance"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournaledSaves(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{SupportToken: "support"}
+	defer func() { config.AppConfig = previous }()
+
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Post("/api/notes", handlers.UpsertNote(application))
+	fiberApp.Delete("/api/admin/maintenance", handlers.EndMaintenance(application))
+
+	ctx := context.Background()
+	// Local-only, so saves don't reach the sync worker the tests run without
+	require.NoError(t, application.Repo.CreateContext(ctx, &models.Context{
+		ID: "ctx-work", UserID: "test-user-id", Name: "Work", Color: "info", LocalOnly: true, CreatedAt: time.Now(),
+	}))
+	_, err := application.NoteService.Upsert(ctx, "test-user-id", "Work", "2025-10-16", "Original")
+	require.NoError(t, err)
+
+	mode, err := maintenance.Open(t.TempDir())
+	require.NoError(t, err)
+	application.UseMaintenance(mode)
+	require.NoError(t, mode.Enable("migration", time.Minute, true))
+
+	save := func(context, content string, revision int) *http.Response {
+		body, _ := json.Marshal(fiber.Map{"context": context, "date": "2025-10-16", "content": content, "revision": revision})
+		req := httptest.NewRequest(http.MethodPost, "/api/notes", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Saves are checked before they're journaled", func(t *testing.T) {
+		resp := save("Home", "Elsewhere", 1)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Zero(t, mode.Status().Journaled)
+	})
+
+	t.Run("Journaled saves take the next revision", func(t *testing.T) {
+		resp := save("Work", "First", 1)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+		resp = save("Work", "Second", 1)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode, "the first save took revision 2")
+		assert.Equal(t, 1, mode.Status().Journaled)
+
+		resp = save("Work", "Third", 2)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, `"3"`, resp.Header.Get("ETag"))
+	})
+
+	t.Run("Saves that fail to replay are skipped", func(t *testing.T) {
+		// A save that went around the journal takes revision 2 first
+		_, err := application.NoteService.Upsert(ctx, "test-user-id", "Work", "2025-10-16", "Direct")
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/admin/maintenance", nil)
+		req.Header.Set("X-Support-Token", "support")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var result struct {
+			Replayed int                 `json:"replayed"`
+			Failed   []models.FailedSave `json:"failed"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, 1, result.Replayed)
+		require.Len(t, result.Failed, 1)
+		assert.Equal(t, "First", result.Failed[0].Save.Content)
+		assert.False(t, mode.Status().Enabled)
+
+		note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, "Third", note.Content)
+		assert.Equal(t, 3, note.Revision)
+	})
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetContextMembers lists the members and pending invitations of one of the user's contexts
+func GetContextMembers(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		members, err := a.Members.Members(c.Context(), middleware.GetUserID(c), c.Params("id"))
+		if errors.Is(err, services.ErrContextNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Context not found"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch members", err)
+		}
+
+		return success(c, fiber.Map{"members": members})
+	}
+}
+
+// InviteContextMember shares one of the user's contexts with the account of an email address
+func InviteContextMember(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.InviteMemberRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		member, err := a.Members.Invite(c.Context(), userID, c.Params("id"), req)
+		switch {
+		case errors.Is(err, services.ErrContextNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Context not found"})
+		case errors.Is(err, services.ErrInviteSelf):
+			return badRequest(c, services.ErrInviteSelf.Error())
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to invite member", err)
+		}
+
+		return created(c, fiber.Map{"member": member})
+	}
+}
+
+// RemoveContextMember takes a member or pending invitation off one of the user's contexts
+func RemoveContextMember(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("memberId"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid member ID")
+		}
+
+		err = a.Members.Remove(c.Context(), middleware.GetUserID(c), c.Params("id"), id)
+		switch {
+		case errors.Is(err, services.ErrContextNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Context not found"})
+		case errors.Is(err, services.ErrMemberNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Member not found"})
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to remove member", err)
+		}
+
+		return success(c, fiber.Map{"message": "Member removed"})
+	}
+}
+
+// GetInvitations lists the contexts shared with the user, pending and accepted
+func GetInvitations(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		invitations, err := a.Members.Invitations(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch invitations", err)
+		}
+
+		return success(c, fiber.Map{"invitations": invitations})
+	}
+}
+
+// AcceptInvitation makes the user a member of the context of an invitation
+func AcceptInvitation(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid invitation ID")
+		}
+
+		invitation, err := a.Members.Accept(c.Context(), middleware.GetUserID(c), id)
+		switch {
+		case errors.Is(err, services.ErrInvitationNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Invitation not found"})
+		case errors.Is(err, services.ErrContextAlreadyExists):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "You already have a context of that name; rename it to accept"})
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to accept invitation", err)
+		}
+
+		return success(c, fiber.Map{"invitation": invitation})
+	}
+}
+
+// LeaveInvitation declines an invitation, or leaves a context shared with the user
+func LeaveInvitation(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid invitation ID")
+		}
+
+		err = a.Members.Leave(c.Context(), middleware.GetUserID(c), id)
+		switch {
+		case errors.Is(err, services.ErrInvitationNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Invitation not found"})
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to leave context", err)
+		}
+
+		return success(c, fiber.Map{"message": "Left context"})
+	}
+}
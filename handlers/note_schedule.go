@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetNoteSchedule returns the local time at which the user's daily notes are
+// created from their templates; schedule is null when none is set
+func GetNoteSchedule(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		schedule, err := a.NoteSchedules.Get(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch note schedule", err)
+		}
+		return success(c, fiber.Map{"schedule": schedule})
+	}
+}
+
+// SetNoteSchedule creates the user's daily notes from their context templates
+// every day at a local time ("07:30", in the timezone setting)
+func SetNoteSchedule(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.NoteScheduleRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		schedule, err := a.NoteSchedules.Set(c.Context(), middleware.GetUserID(c), req.Time)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to save note schedule", err)
+		}
+		return success(c, fiber.Map{"schedule": schedule})
+	}
+}
+
+// DeleteNoteSchedule stops creating the user's daily notes on a schedule
+func DeleteNoteSchedule(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := a.NoteSchedules.Delete(c.Context(), middleware.GetUserID(c))
+		if errors.Is(err, services.ErrScheduleNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No note schedule is set"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to delete note schedule", err)
+		}
+		return success(c, fiber.Map{"message": "Note schedule deleted"})
+	}
+}
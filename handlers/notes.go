@@ -4,75 +4,1128 @@ import (
 	"daily-notes/app"
 	"daily-notes/middleware"
 	"daily-notes/models"
+	"daily-notes/pkg/linediff"
+	"daily-notes/pkg/period"
 	"daily-notes/services"
+	"daily-notes/sync"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// noteETag builds the ETag for a note from its revision
+func noteETag(note *models.Note) string {
+	return fmt.Sprintf(`"%d"`, note.Revision)
+}
+
+// setNoteValidators sets the ETag and Last-Modified headers of a note, which
+// saves send back in If-Match or If-Unmodified-Since
+func setNoteValidators(c *fiber.Ctx, note *models.Note) {
+	c.Set(fiber.HeaderETag, noteETag(note))
+	if !note.UpdatedAt.IsZero() {
+		c.Set(fiber.HeaderLastModified, note.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// baseRevision returns the revision a save is based on, taken from the request
+// body or the If-Match header. ok is false when the client sent neither.
+func baseRevision(c *fiber.Ctx, bodyRevision *int) (revision int, ok bool) {
+	if bodyRevision != nil {
+		return *bodyRevision, true
+	}
+
+	ifMatch := strings.TrimPrefix(strings.TrimSpace(c.Get(fiber.HeaderIfMatch)), "W/")
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, false
+	}
+
+	revision, err := strconv.Atoi(strings.Trim(ifMatch, `"`))
+	if err != nil {
+		return 0, false
+	}
+	return revision, true
+}
+
+// unmodifiedSince returns the date of an If-Unmodified-Since header, which
+// clients without the note's revision send with its Last-Modified date. ok is
+// false without a valid one.
+func unmodifiedSince(c *fiber.Ctx) (since time.Time, ok bool) {
+	header := c.Get(fiber.HeaderIfUnmodifiedSince)
+	if header == "" {
+		return time.Time{}, false
+	}
+	since, err := http.ParseTime(header)
+	return since, err == nil
+}
+
+// hasPrecondition reports whether a save names the version it is based on
+func hasPrecondition(c *fiber.Ctx, bodyRevision *int) bool {
+	if _, ok := baseRevision(c, bodyRevision); ok {
+		return true
+	}
+	_, ok := unmodifiedSince(c)
+	return ok
+}
+
+// periodKeyHint explains the expected key formats for period notes
+const periodKeyHint = "key does not match type (week: 2025-W42, month: 2025-10, year: 2025)"
+
 // GetNote retrieves a note for a specific context and date
 func GetNote(a *app.App) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		contextName, date := c.Query("context"), c.Query("date")
-		if contextName == "" || date == "" {
-			return badRequest(c, "context and date are required")
+		contextName, date := c.Query("context"), c.Query("date")
+		if contextName == "" || date == "" {
+			return badRequest(c, "context and date are required")
+		}
+
+		userID := middleware.GetUserID(c)
+
+		note, err := a.NoteService.Get(c.Context(), userID, contextName, date)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch note", err)
+		}
+
+		parents, err := a.NoteService.Backlinks(c.Context(), userID, contextName, date)
+		if err != nil && !errors.Is(err, services.ErrInvalidPeriodKey) {
+			return serverErrorWithDetails(c, "Failed to fetch note", err)
+		}
+
+		previews, err := a.LinkPreviews.ForContent(c.Context(), note.Content)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch note", err)
+		}
+
+		publications, err := a.PublishService.Publications(c.Context(), userID, contextName, date)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch note", err)
+		}
+
+		setNoteValidators(c, note)
+		return success(c, fiber.Map{
+			"note":          note,
+			"parents":       parents,
+			"link_previews": previews,
+			"publications":  publications,
+		})
+	}
+}
+
+// GetNoteView retrieves a note rendered for reading with the dates of the
+// previous and next notes of its context and the same day's notes in other contexts
+func GetNoteView(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName, date := c.Query("context"), c.Query("date")
+		if contextName == "" || date == "" {
+			return badRequest(c, "context and date are required")
+		}
+
+		view, err := a.NoteService.View(c.Context(), middleware.GetUserID(c), contextName, date)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidPeriodKey):
+				return badRequest(c, "date must be a day (2025-10-16), week (2025-W42), month (2025-10) or year (2025)")
+			case errors.Is(err, services.ErrNoteNotFound):
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Note not found"})
+			}
+			return serverErrorWithDetails(c, "Failed to fetch note", err)
+		}
+
+		setNoteValidators(c, view.Note)
+		return success(c, fiber.Map{"view": view})
+	}
+}
+
+// suggestContextEnabled reports whether the user opted in to automatic context
+// suggestion. API keys and tokens have no session, so their user's setting is
+// loaded.
+func suggestContextEnabled(c *fiber.Ctx, a *app.App, userID string) (bool, error) {
+	if sess, ok := c.Locals("session").(*models.Session); ok && sess != nil {
+		return sess.Settings.SuggestContext, nil
+	}
+
+	user, err := a.Repo.GetUser(c.Context(), userID)
+	if err != nil {
+		return false, err
+	}
+	return user != nil && user.Settings.SuggestContext, nil
+}
+
+// suggestContext returns the context quick-captured content without one goes
+// to, or "" when the user didn't opt in or there's no suggestion
+func suggestContext(c *fiber.Ctx, a *app.App, userID, content string) (string, error) {
+	if content == "" {
+		return "", nil
+	}
+	enabled, err := suggestContextEnabled(c, a, userID)
+	if err != nil || !enabled {
+		return "", err
+	}
+	suggested, err := a.ContextService.SuggestBest(c.Context(), userID, content)
+	if errors.Is(err, services.ErrNoContextSuggestion) {
+		return "", nil
+	}
+	return suggested, err
+}
+
+// UpsertNote creates or updates a note
+func UpsertNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.CreateNoteRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		userID := middleware.GetUserID(c)
+
+		// Quick captures may omit the context if the user opted in to suggestions
+		if req.Context == "" {
+			suggested, err := suggestContext(c, a, userID, req.Content)
+			if err != nil {
+				return serverErrorWithDetails(c, "Failed to suggest context", err)
+			}
+			req.Context = suggested
+		}
+
+		// Capture integrations may pass the append options in the query string
+		if !req.Append {
+			req.Append = c.QueryBool("append")
+		}
+		if req.Section == "" {
+			req.Section = c.Query("section")
+		}
+
+		// Validate request
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		if req.Section != "" && !req.Append {
+			return badRequest(c, "section requires append=true")
+		}
+
+		// While maintenance journals saves, they are kept for replay instead
+		if a.Maintenance != nil && a.Maintenance.Journaling() {
+			if journaled, err := journalNoteSave(c, a, userID, req); journaled {
+				return err
+			}
+		}
+
+		if req.Append {
+			return appendNote(c, a, userID, req)
+		}
+		return saveNote(c, a, userID, req.Context, req.Date, req.Content, req.Revision)
+	}
+}
+
+// appendNote adds the request content under its section, honouring the base revision when the client sent one
+func appendNote(c *fiber.Ctx, a *app.App, userID string, req models.CreateNoteRequest) error {
+	var revision *int
+	if r, ok := baseRevision(c, req.Revision); ok {
+		revision = &r
+	}
+
+	note, err := a.NoteService.Append(c.Context(), userID, req.Context, req.Date, req.Content, req.Section, revision)
+	return noteSaved(c, a, userID, note, err)
+}
+
+// AppendToNote adds a line or block to a note without the client sending the
+// rest of it, e.g. from a shortcut or a script; concurrent appends don't
+// overwrite each other. Notes are today's and timestamps local in the request
+// timezone, or else the user's.
+func AppendToNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.AppendNoteRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		// Like quick captures, appends may omit the context if the user opted in to suggestions
+		if req.Context == "" {
+			suggested, err := suggestContext(c, a, userID, req.Content)
+			if err != nil {
+				return serverErrorWithDetails(c, "Failed to suggest context", err)
+			}
+			if suggested == "" {
+				return badRequest(c, "context is required")
+			}
+			req.Context = suggested
+		}
+
+		loc, err := appendLocation(c, a, userID, req.Timezone)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to load settings", err)
+		}
+		if loc == nil {
+			return badRequest(c, "Unknown timezone")
+		}
+		resolved := a.NoteService.ResolveAppend(req, loc)
+
+		// While maintenance journals saves, they are kept for replay instead
+		if a.Maintenance != nil && a.Maintenance.Journaling() {
+			if journaled, err := journalNoteSave(c, a, userID, resolved); journaled {
+				return err
+			}
+		}
+
+		return appendNote(c, a, userID, resolved)
+	}
+}
+
+// appendLocation returns the timezone of a quick append: the one it names, nil
+// when that's unknown, or else the user's. API keys and tokens have no session,
+// so their user's setting is loaded.
+func appendLocation(c *fiber.Ctx, a *app.App, userID, timezone string) (*time.Location, error) {
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, nil
+		}
+		return loc, nil
+	}
+	if sess, ok := c.Locals("session").(*models.Session); ok && sess != nil {
+		return userLocation(c), nil
+	}
+
+	user, err := a.Repo.GetUser(c.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		if loc, err := time.LoadLocation(user.Settings.Timezone); err == nil {
+			return loc, nil
+		}
+	}
+	return time.UTC, nil
+}
+
+// PatchNote is the autosave of an editor: it saves a note like UpsertNote but
+// answers without the content, and the sync of the note waits until the
+// autosaves pause (SYNC_DEBOUNCE) so storage gets one upload instead of one
+// every few seconds. Editors must say which version they edited, so an
+// autosave without a revision or precondition header is refused with 428.
+func PatchNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.PatchNoteRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		if !hasPrecondition(c, req.Revision) {
+			return c.Status(fiber.StatusPreconditionRequired).JSON(fiber.Map{
+				"error": "revision, If-Match or If-Unmodified-Since is required",
+			})
+		}
+
+		userID := middleware.GetUserID(c)
+		c.Locals(services.AutosaveKey, true)
+
+		note, err := storeNote(c, a, userID, req.Context, req.Date, req.Content, req.Revision)
+		if err != nil {
+			if errors.Is(err, services.ErrRevisionConflict) {
+				return noteConflict(c, note, conflictDiff(note, req.Content))
+			}
+			return serverErrorWithDetails(c, "Failed to save note", err)
+		}
+
+		// The client has the content; it needs the revision to send the next autosave
+		setNoteValidators(c, note)
+		response := fiber.Map{
+			"revision":    note.Revision,
+			"updated_at":  note.UpdatedAt,
+			"sync_status": note.SyncStatus,
+			"sync_health": syncHealth(a, userID),
+		}
+		if warning := a.NoteService.SizeWarning(note); warning != nil {
+			response["size_warning"] = warning
+		}
+		return success(c, response)
+	}
+}
+
+// saveNote stores a note and writes the response. Conflicts come with the diff
+// of the saved content to the client's.
+func saveNote(c *fiber.Ctx, a *app.App, userID, contextName, key, content string, bodyRevision *int) error {
+	note, err := storeNote(c, a, userID, contextName, key, content, bodyRevision)
+	if errors.Is(err, services.ErrRevisionConflict) {
+		return noteConflict(c, note, conflictDiff(note, content))
+	}
+	return noteSaved(c, a, userID, note, err)
+}
+
+// storeNote stores a note, honouring the base revision or the If-Unmodified-Since
+// date when the client sent one
+func storeNote(c *fiber.Ctx, a *app.App, userID, contextName, key, content string, bodyRevision *int) (*models.Note, error) {
+	if revision, ok := baseRevision(c, bodyRevision); ok {
+		return a.NoteService.UpsertAtRevision(c.Context(), userID, contextName, key, content, revision)
+	}
+	if since, ok := unmodifiedSince(c); ok {
+		return a.NoteService.UpsertUnmodifiedSince(c.Context(), userID, contextName, key, content, since)
+	}
+	return a.NoteService.Upsert(c.Context(), userID, contextName, key, content)
+}
+
+// noteSaved writes the response for a note save, including revision conflicts
+func noteSaved(c *fiber.Ctx, a *app.App, userID string, note *models.Note, err error) error {
+	if err != nil {
+		if errors.Is(err, services.ErrRevisionConflict) {
+			return noteConflict(c, note, nil)
+		}
+		return serverErrorWithDetails(c, "Failed to save note", err)
+	}
+
+	setNoteValidators(c, note)
+	response := fiber.Map{
+		"note":        note,
+		"sync_health": syncHealth(a, userID),
+	}
+	if warning := a.NoteService.SizeWarning(note); warning != nil {
+		response["size_warning"] = warning
+	}
+	return success(c, response)
+}
+
+// noteConflict answers a save based on an outdated version of a note with 409,
+// the current note and, when there is one, the diff to the client's content
+func noteConflict(c *fiber.Ctx, note *models.Note, diff fiber.Map) error {
+	setNoteValidators(c, note)
+	response := fiber.Map{
+		"error": "Note was updated elsewhere. Reload or merge your changes.",
+		"note":  note,
+	}
+	if diff != nil {
+		response["diff"] = diff
+	}
+	return c.Status(fiber.StatusConflict).JSON(response)
+}
+
+// conflictDiff compares the saved content of a note with the content a client
+// tried to save over it, as hunks and as a unified diff that applies to the
+// saved content; nil for encrypted notes, whose lines are ciphertext
+func conflictDiff(note *models.Note, content string) fiber.Map {
+	if note.Encrypted {
+		return nil
+	}
+	hunks := linediff.Hunks(linediff.Lines(note.Content, content), conflictDiffContext)
+	return fiber.Map{
+		"hunks":   hunks,
+		"unified": linediff.Unified("saved", "yours", hunks),
+	}
+}
+
+// conflictDiffContext is how many unchanged lines surround the changes of a
+// conflict diff, as many as diff -u shows
+const conflictDiffContext = 3
+
+// BatchUpsertNotes saves an array of notes in one transaction, e.g. when a client
+// catches up on edits made offline; either all notes are saved or none is
+func BatchUpsertNotes(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.BatchUpsertNotesRequest
+		if err := c.BodyParser(&req.Notes); err != nil {
+			return badRequest(c, "Request body must be an array of notes")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		notes, err := a.NoteService.UpsertBatch(c.Context(), userID, req.Notes)
+		if err != nil {
+			if errors.Is(err, services.ErrDuplicateNote) {
+				return badRequest(c, "Each note may only be listed once")
+			}
+			return serverErrorWithDetails(c, "Failed to save notes", err)
+		}
+
+		return success(c, fiber.Map{
+			"notes":       notes,
+			"sync_health": syncHealth(a, userID),
+		})
+	}
+}
+
+// GetNoteSections lists the markdown headings of a note with the text under each
+func GetNoteSections(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName, date := c.Query("context"), c.Query("date")
+		if contextName == "" || date == "" {
+			return badRequest(c, "context and date are required")
+		}
+
+		note, sections, err := a.NoteService.Sections(c.Context(), middleware.GetUserID(c), contextName, date)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch note sections", err)
+		}
+
+		setNoteValidators(c, note)
+		return success(c, fiber.Map{
+			"sections": sections,
+			"revision": note.Revision,
+		})
+	}
+}
+
+// UpdateNoteSection replaces the text under one heading, so widgets can edit
+// their part of a note without overwriting the rest
+func UpdateNoteSection(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.UpdateSectionRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		var revision *int
+		if rev, ok := baseRevision(c, req.Revision); ok {
+			revision = &rev
+		}
+
+		userID := middleware.GetUserID(c)
+
+		note, section, err := a.NoteService.UpdateSection(c.Context(), userID, req.Context, req.Date, c.Params("slug"), req.Content, revision)
+		if errors.Is(err, services.ErrSectionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Section not found"})
+		}
+		if err != nil {
+			return noteSaved(c, a, userID, note, err)
+		}
+
+		setNoteValidators(c, note)
+		response := fiber.Map{
+			"note":        note,
+			"section":     section,
+			"sync_health": syncHealth(a, userID),
+		}
+		if warning := a.NoteService.SizeWarning(note); warning != nil {
+			response["size_warning"] = warning
+		}
+		return success(c, response)
+	}
+}
+
+// GetPeriodNote retrieves a week, month or year note with the notes it rolls up
+// and links to the coarser notes containing it
+func GetPeriodNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName, key := c.Query("context"), c.Query("key")
+		noteType := c.Query("type", "week")
+		if contextName == "" || key == "" {
+			return badRequest(c, "context and key are required")
+		}
+
+		userID := middleware.GetUserID(c)
+
+		note, rollup, err := a.NoteService.GetPeriod(c.Context(), userID, contextName, noteType, key)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidPeriodKey) {
+				return badRequest(c, periodKeyHint)
+			}
+			return serverErrorWithDetails(c, "Failed to fetch note", err)
+		}
+
+		parents, err := a.NoteService.Backlinks(c.Context(), userID, contextName, key)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch note", err)
+		}
+
+		setNoteValidators(c, note)
+		return success(c, fiber.Map{
+			"note":    note,
+			"rollup":  rollup,
+			"parents": parents,
+		})
+	}
+}
+
+// SeedPeriodNote creates a week, month or year note pre-filled with a digest of its notes
+func SeedPeriodNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.SeedPeriodNoteRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		note, isNew, err := a.NoteService.SeedPeriod(c.Context(), userID, req.Context, req.Type, req.Key)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidPeriodKey) {
+				return badRequest(c, periodKeyHint)
+			}
+			return serverErrorWithDetails(c, "Failed to seed note", err)
+		}
+
+		setNoteValidators(c, note)
+		if isNew {
+			return created(c, fiber.Map{"note": note})
+		}
+		return success(c, fiber.Map{"note": note})
+	}
+}
+
+// UpsertPeriodNote creates or updates a week note
+func UpsertPeriodNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.UpsertPeriodNoteRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+		return saveNote(c, a, userID, req.Context, req.Key, req.Content, req.Revision)
+	}
+}
+
+// GetNotesByContext retrieves all notes for a specific context
+func GetNotesByContext(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName := c.Query("context")
+		if contextName == "" {
+			return badRequest(c, "context is required")
+		}
+
+		limit := c.QueryInt("limit", 30)
+		offset := c.QueryInt("offset", 0)
+		userID := middleware.GetUserID(c)
+
+		notes, err := a.NoteService.ListByContext(c.Context(), userID, contextName, limit, offset)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch notes", err)
+		}
+
+		return success(c, fiber.Map{
+			"notes":  notes,
+			"limit":  limit,
+			"offset": offset,
+		})
+	}
+}
+
+// GetNoteChanges lists the notes created, updated or deleted after ?since=<cursor>
+// (0 or missing for every note), oldest change first, for offline clients.
+// The response's cursor is the since of the next request; has_more asks for it now.
+func GetNoteChanges(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var since int64
+		if raw := c.Query("since"); raw != "" {
+			var err error
+			if since, err = strconv.ParseInt(raw, 10, 64); err != nil || since < 0 {
+				return badRequest(c, "Invalid since cursor")
+			}
+		}
+		limit := c.QueryInt("limit", 100)
+		userID := middleware.GetUserID(c)
+
+		page, err := a.NoteService.Changes(c.Context(), userID, since, limit)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch note changes", err)
+		}
+
+		return success(c, fiber.Map{
+			"changes":  page.Changes,
+			"cursor":   page.Cursor,
+			"has_more": page.HasMore,
+		})
+	}
+}
+
+// GetNotesByTag retrieves the notes tagged with a #tag across all contexts
+func GetNotesByTag(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tag := c.Query("tag")
+		limit := c.QueryInt("limit", 30)
+		offset := c.QueryInt("offset", 0)
+		userID := middleware.GetUserID(c)
+
+		notes, err := a.NoteService.ListByTag(c.Context(), userID, tag, limit, offset)
+		if err != nil {
+			if errors.Is(err, services.ErrEmptyTag) {
+				return badRequest(c, "tag is required")
+			}
+			return serverErrorWithDetails(c, "Failed to fetch notes", err)
+		}
+
+		return success(c, fiber.Map{
+			"tag":    tag,
+			"notes":  notes,
+			"limit":  limit,
+			"offset": offset,
+		})
+	}
+}
+
+// GetTags lists the user's #tags with how many notes use each
+func GetTags(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tags, err := a.NoteService.Tags(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch tags", err)
+		}
+		return success(c, fiber.Map{"tags": tags})
+	}
+}
+
+// GetNoteRevisions lists the earlier versions of a note, newest first
+func GetNoteRevisions(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName, date := c.Query("context"), c.Query("date")
+		if contextName == "" || date == "" {
+			return badRequest(c, "context and date are required")
+		}
+
+		revisions, err := a.NoteService.Revisions(c.Context(), middleware.GetUserID(c), contextName, date)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch revisions", err)
+		}
+		return success(c, fiber.Map{"revisions": revisions})
+	}
+}
+
+// RestoreNoteRevision makes an earlier version the current content of its note
+func RestoreNoteRevision(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid revision ID")
+		}
+
+		userID := middleware.GetUserID(c)
+		note, err := a.NoteService.RestoreRevision(c.Context(), userID, id)
+		if errors.Is(err, services.ErrRevisionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Revision not found"})
+		}
+		return noteSaved(c, a, userID, note, err)
+	}
+}
+
+// GetNoteConflicts lists the notes that changed both locally and in storage since their last sync
+func GetNoteConflicts(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		conflicts, err := a.NoteService.Conflicts(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch conflicts", err)
+		}
+		return success(c, fiber.Map{"conflicts": conflicts})
+	}
+}
+
+// ResolveNoteConflict keeps the local, storage or merged version of a conflicted note
+func ResolveNoteConflict(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.ResolveConflictRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+		note, err := a.NoteService.ResolveConflict(c.Context(), userID, req.Context, req.Date, req.Resolution, req.Content)
+		if errors.Is(err, services.ErrConflictNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Note has no sync conflict"})
+		}
+		return noteSaved(c, a, userID, note, err)
+	}
+}
+
+// GetMonthNotes returns all daily notes of a context for a calendar month with
+// previews or content, so calendar views don't need one request per day
+func GetMonthNotes(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName := c.Query("context")
+		if contextName == "" {
+			return badRequest(c, "context is required")
 		}
 
+		year, month := c.QueryInt("year"), c.QueryInt("month")
+		include := c.Query("include", services.MonthIncludePreview)
 		userID := middleware.GetUserID(c)
 
-		note, err := a.NoteService.Get(userID, contextName, date)
+		notes, truncated, err := a.NoteService.Month(c.Context(), userID, contextName, year, month, include)
 		if err != nil {
-			return serverErrorWithDetails(c, "Failed to fetch note", err)
+			if target := matchError(err, services.ErrInvalidMonth, services.ErrInvalidInclude); target != nil {
+				return badRequest(c, target.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to fetch notes", err)
 		}
 
-		return success(c, fiber.Map{"note": note})
+		return success(c, fiber.Map{
+			"notes":     notes,
+			"year":      year,
+			"month":     month,
+			"include":   include,
+			"truncated": truncated,
+		})
 	}
 }
 
-// UpsertNote creates or updates a note
-func UpsertNote(a *app.App) fiber.Handler {
+// GetNoteCalendar returns every day of a month in a context with whether it
+// has a note, its word count and sync status, for calendar views
+func GetNoteCalendar(a *app.App) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var req models.CreateNoteRequest
+		contextName := c.Query("context")
+		if contextName == "" {
+			return badRequest(c, "context is required")
+		}
+
+		year, month := c.QueryInt("year"), c.QueryInt("month")
+		userID := middleware.GetUserID(c)
+
+		days, err := a.NoteService.Calendar(c.Context(), userID, contextName, year, month)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidMonth) {
+				return badRequest(c, services.ErrInvalidMonth.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to fetch calendar", err)
+		}
+
+		return success(c, fiber.Map{
+			"context": contextName,
+			"year":    year,
+			"month":   month,
+			"days":    days,
+		})
+	}
+}
+
+// GetAgenda returns the daily notes of every context for a date range (at most 62 days),
+// grouped by day with context colors and task rollups, for printed agendas and digests
+func GetAgenda(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.AgendaRequest
+		if err := c.QueryParser(&req); err != nil {
+			return badRequest(c, "Invalid agenda parameters")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+		userID := middleware.GetUserID(c)
+
+		agenda, err := a.NoteService.Agenda(c.Context(), userID, req.From, req.To)
+		if err != nil {
+			if errors.Is(err, services.ErrAgendaRange) {
+				return badRequest(c, services.ErrAgendaRange.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to fetch agenda", err)
+		}
+
+		return success(c, fiber.Map{"agenda": agenda})
+	}
+}
+
+// GetNotesDay returns the daily notes of every context on ?date=, as one agenda
+// day, for a dashboard of the day
+func GetNotesDay(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.DayRequest
+		if err := c.QueryParser(&req); err != nil {
+			return badRequest(c, "Invalid day parameters")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		day, err := a.NoteService.Day(c.Context(), middleware.GetUserID(c), req.Date)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidPeriodKey) {
+				return badRequest(c, "date must be a day (2025-10-16)")
+			}
+			return serverErrorWithDetails(c, "Failed to fetch day", err)
+		}
+
+		return success(c, fiber.Map{"day": day})
+	}
+}
+
+// GetNotesMeta returns per-day word counts, moods, tags and tasks of the daily
+// notes of all contexts in ?from= to ?to=, as columns for dashboards. No content.
+func GetNotesMeta(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.AgendaRequest
+		if err := c.QueryParser(&req); err != nil {
+			return badRequest(c, "Invalid range parameters")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		meta, err := a.NoteService.Meta(c.Context(), middleware.GetUserID(c), req.From, req.To)
+		if err != nil {
+			if errors.Is(err, services.ErrMetaRange) {
+				return badRequest(c, services.ErrMetaRange.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to fetch notes metadata", err)
+		}
+
+		return success(c, fiber.Map{"meta": meta})
+	}
+}
+
+// SplitNote moves a line range of a note into the note of another date or context
+func SplitNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.SplitNoteRequest
 		if err := c.BodyParser(&req); err != nil {
 			return badRequest(c, "Invalid request body")
 		}
 
-		// Validate request
 		if err := a.Validator.Validate(&req); err != nil {
 			return validationError(c, err)
 		}
 
+		if req.ToContext == "" {
+			req.ToContext = req.Context
+		}
+		if req.ToDate == "" {
+			req.ToDate = req.Date
+		}
+
+		var revision *int
+		if rev, ok := baseRevision(c, req.Revision); ok {
+			revision = &rev
+		}
+
 		userID := middleware.GetUserID(c)
 
-		note, err := a.NoteService.Upsert(userID, req.Context, req.Date, req.Content)
+		source, target, err := a.NoteService.Split(c.Context(), userID, req.Context, req.Date,
+			req.StartLine, req.EndLine, req.ToContext, req.ToDate, revision)
 		if err != nil {
-			return serverErrorWithDetails(c, "Failed to save note", err)
+			switch {
+			case errors.Is(err, services.ErrRevisionConflict):
+				setNoteValidators(c, source)
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": "Note was updated elsewhere. Reload and select the lines again.",
+					"note":  source,
+				})
+			case errors.Is(err, services.ErrNoteNotFound):
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Note not found"})
+			case errors.Is(err, services.ErrContextNotFound):
+				return badRequest(c, "Context not found")
+			}
+			if target := matchError(err, services.ErrInvalidLineRange, services.ErrSameNote); target != nil {
+				return badRequest(c, target.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to split note", err)
 		}
 
-		return success(c, fiber.Map{"note": note})
+		setNoteValidators(c, source)
+		return success(c, fiber.Map{
+			"source":      source,
+			"target":      target,
+			"sync_health": syncHealth(a, userID),
+		})
 	}
 }
 
-// GetNotesByContext retrieves all notes for a specific context
-func GetNotesByContext(a *app.App) fiber.Handler {
+// CopyNotes copies a note or a range of daily notes to another context
+func CopyNotes(a *app.App) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		contextName := c.Query("context")
-		if contextName == "" {
-			return badRequest(c, "context is required")
+		return transferNotes(c, a, false)
+	}
+}
+
+// MoveNotes moves a note or a range of daily notes to another context
+func MoveNotes(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return transferNotes(c, a, true)
+	}
+}
+
+// transferNotes handles the shared copy and move request
+func transferNotes(c *fiber.Ctx, a *app.App, move bool) error {
+	var req models.TransferNotesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return badRequest(c, "Invalid request body")
+	}
+
+	if err := a.Validator.Validate(&req); err != nil {
+		return validationError(c, err)
+	}
+
+	var keys []string
+	switch {
+	case req.Date != "":
+		if period.Kind(req.Date) == "" {
+			return badRequest(c, "date must be a valid note key")
+		}
+		keys = []string{req.Date}
+	case req.StartDate != "" && req.EndDate != "":
+		var err error
+		if keys, err = period.Range(req.StartDate, req.EndDate); err != nil {
+			return badRequest(c, "start_date must not be after end_date")
+		}
+	default:
+		return badRequest(c, "date or start_date and end_date are required")
+	}
+
+	userID := middleware.GetUserID(c)
+
+	notes, err := a.NoteService.Transfer(c.Context(), userID, req.FromContext, req.ToContext, keys, move, req.Overwrite)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNoteExists):
+			conflicts := make([]string, 0, len(notes))
+			for _, note := range notes {
+				conflicts = append(conflicts, note.Date)
+			}
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":     "Notes already exist in the target context. Retry with overwrite to replace them.",
+				"conflicts": conflicts,
+			})
+		case errors.Is(err, services.ErrNoteNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No notes found to transfer"})
+		case errors.Is(err, services.ErrContextNotFound):
+			return badRequest(c, "Context not found")
+		}
+		if target := matchError(err, services.ErrSameContext, services.ErrTransferRange); target != nil {
+			return badRequest(c, target.Error())
+		}
+		return serverErrorWithDetails(c, "Failed to transfer notes", err)
+	}
+
+	return success(c, fiber.Map{
+		"notes":       notes,
+		"moved":       move,
+		"sync_health": syncHealth(a, userID),
+	})
+}
+
+// RedateNotes moves a range of daily notes of a context by a number of days
+func RedateNotes(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.RedateNotesRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		dates, err := period.Range(req.StartDate, req.EndDate)
+		if err != nil {
+			return badRequest(c, "start_date must not be after end_date")
 		}
 
-		limit := c.QueryInt("limit", 30)
-		offset := c.QueryInt("offset", 0)
 		userID := middleware.GetUserID(c)
 
-		notes, err := a.NoteService.ListByContext(userID, contextName, limit, offset)
+		notes, err := a.NoteService.Redate(c.Context(), userID, req.Context, dates, req.Days, req.Overwrite)
 		if err != nil {
-			return serverErrorWithDetails(c, "Failed to fetch notes", err)
+			switch {
+			case errors.Is(err, services.ErrNoteAtNewDate):
+				conflicts := make([]string, 0, len(notes))
+				for _, note := range notes {
+					conflicts = append(conflicts, note.Date)
+				}
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error":     "Notes already exist at the new dates. Retry with overwrite to replace them.",
+					"conflicts": conflicts,
+				})
+			case errors.Is(err, services.ErrNoteNotFound):
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No notes found to re-date"})
+			}
+			if target := matchError(err, services.ErrTransferRange); target != nil {
+				return badRequest(c, target.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to re-date notes", err)
 		}
 
 		return success(c, fiber.Map{
-			"notes":  notes,
-			"limit":  limit,
-			"offset": offset,
+			"notes":       notes,
+			"sync_health": syncHealth(a, userID),
+		})
+	}
+}
+
+// GetRelatedNotes returns past notes similar to the note for a context and date
+func GetRelatedNotes(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName, date := c.Query("context"), c.Query("date")
+		if contextName == "" || date == "" {
+			return badRequest(c, "context and date are required")
+		}
+
+		limit := c.QueryInt("limit", 5)
+		userID := middleware.GetUserID(c)
+
+		related, err := a.NoteService.Related(c.Context(), userID, contextName, date, limit)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch related notes", err)
+		}
+
+		return success(c, fiber.Map{"related": related})
+	}
+}
+
+// GetNoteSizeStats returns size percentiles and the largest notes of the user
+func GetNoteSizeStats(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		stats, err := a.NoteService.SizeStats(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch note sizes", err)
+		}
+		return success(c, fiber.Map{"sizes": stats})
+	}
+}
+
+// SearchNotes runs a full-text search over the user's notes
+// Snippets are HTML with the matched words wrapped in <mark>. The optional context,
+// from, to and sync_status parameters filter the notes searched.
+func SearchNotes(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := models.SearchNotesRequest{Limit: 20}
+		if err := c.QueryParser(&req); err != nil {
+			return badRequest(c, "Invalid search parameters")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+		userID := middleware.GetUserID(c)
+
+		results, err := a.NoteService.Search(c.Context(), userID, req.Query, req.Filter(), req.Limit, req.Offset)
+		if err != nil {
+			if errors.Is(err, services.ErrEmptySearch) {
+				return badRequest(c, "q is required")
+			}
+			return serverErrorWithDetails(c, "Failed to search notes", err)
+		}
+
+		return success(c, fiber.Map{
+			"query":   req.Query,
+			"filters": req.Filter(),
+			"results": results,
+			"limit":   req.Limit,
+			"offset":  req.Offset,
 		})
 	}
 }
@@ -89,7 +1142,7 @@ func DeleteNote(a *app.App) fiber.Handler {
 
 		userID := middleware.GetUserID(c)
 
-		if err := a.NoteService.Delete(userID, contextName, date); err != nil {
+		if err := a.NoteService.Delete(c.Context(), userID, contextName, date); err != nil {
 			return serverErrorWithDetails(c, "Failed to delete note", err)
 		}
 
@@ -99,12 +1152,45 @@ func DeleteNote(a *app.App) fiber.Handler {
 	}
 }
 
+// GetNoteTrash lists the user's deleted notes that can still be restored
+func GetNoteTrash(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		notes, err := a.NoteService.Trash(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch trash", err)
+		}
+		return success(c, fiber.Map{"notes": notes})
+	}
+}
+
+// RestoreTrashedNote brings a deleted note back from the trash
+func RestoreTrashedNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid trash ID")
+		}
+
+		userID := middleware.GetUserID(c)
+		note, err := a.NoteService.RestoreFromTrash(c.Context(), userID, id)
+		switch {
+		case errors.Is(err, services.ErrNoteNotInTrash):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Note not found in trash"})
+		case errors.Is(err, services.ErrNoteDayTaken):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": services.ErrNoteDayTaken.Error()})
+		case errors.Is(err, services.ErrContextNotFound):
+			return badRequest(c, "Context not found; restore the context first")
+		}
+		return noteSaved(c, a, userID, note, err)
+	}
+}
+
 // GetSyncStatus returns sync status information for the user
 func GetSyncStatus(a *app.App) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userID := middleware.GetUserID(c)
 
-		syncStatus, err := a.NoteService.GetSyncStatus(userID)
+		syncStatus, err := a.NoteService.GetSyncStatus(c.Context(), userID)
 		if err != nil {
 			return serverErrorWithDetails(c, "Failed to get sync status", err)
 		}
@@ -125,8 +1211,8 @@ func RetryNoteSync(a *app.App) fiber.Handler {
 
 		userID := middleware.GetUserID(c)
 
-		if err := a.NoteService.RetrySync(noteID, userID); err != nil {
-			if err == services.ErrUnauthorized {
+		if err := a.NoteService.RetrySync(c.Context(), noteID, userID); err != nil {
+			if errors.Is(err, services.ErrUnauthorized) {
 				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 					"error": "Access denied",
 				})
@@ -139,3 +1225,113 @@ func RetryNoteSync(a *app.App) fiber.Handler {
 		})
 	}
 }
+
+// RetryAllSync retries every failed or abandoned note of the user, deletions
+// included, and every failed rename or deletion of a context folder
+func RetryAllSync(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		notes, operations, err := a.NoteService.RetryAllSync(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to retry sync", err)
+		}
+
+		return success(c, fiber.Map{
+			"message":    "Queued for sync retry",
+			"notes":      notes,
+			"operations": operations,
+		})
+	}
+}
+
+// SetNoteLocalOnly marks a note local only, so it never syncs to cloud storage, or
+// lets it sync again
+func SetNoteLocalOnly(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.SetNoteLocalOnlyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		note, err := a.NoteService.SetLocalOnly(c.Context(), userID, req.Context, req.Date, req.LocalOnly)
+		if err != nil {
+			if errors.Is(err, services.ErrNoteNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Note not found"})
+			}
+			return serverErrorWithDetails(c, "Failed to update note", err)
+		}
+
+		return success(c, fiber.Map{"note": note})
+	}
+}
+
+// PinNote pins a note to the top of its context, or unpins it
+func PinNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.PinNoteRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		note, err := a.NoteService.SetPinned(c.Context(), userID, req.Context, req.Date, req.Pinned)
+		if err != nil {
+			if errors.Is(err, services.ErrNoteNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Note not found"})
+			}
+			return serverErrorWithDetails(c, "Failed to pin note", err)
+		}
+
+		return success(c, fiber.Map{"note": note})
+	}
+}
+
+// GetPinnedNotes lists the pinned notes of ?context=, most recently pinned
+// first, without their content
+func GetPinnedNotes(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName := c.Query("context")
+		if contextName == "" {
+			return badRequest(c, "context is required")
+		}
+
+		notes, err := a.NoteService.Pinned(c.Context(), middleware.GetUserID(c), contextName)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch pinned notes", err)
+		}
+
+		return success(c, fiber.Map{"notes": notes})
+	}
+}
+
+// VerifySync checks the user's synced notes in a context against their content hashes in storage
+func VerifySync(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextName := c.Query("context")
+		if contextName == "" {
+			return badRequest(c, "context is required")
+		}
+		if a.SyncWorker == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Sync is not running"})
+		}
+
+		verification, err := a.SyncWorker.Verify(middleware.GetUserID(c), contextName)
+		if errors.Is(err, sync.ErrVerifyUnsupported) {
+			return badRequest(c, "Your storage provider doesn't support verification")
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to verify notes", err)
+		}
+		return success(c, fiber.Map{"verification": verification})
+	}
+}
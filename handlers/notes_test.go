@@ -2,19 +2,28 @@ package handlers_test
 
 import (
 	"daily-notes/handlers"
+	"archive/zip"
 	"bytes"
 	"context"
 	"daily-notes/app"
 	"daily-notes/database"
+	"daily-notes/middleware"
 	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/publish"
+	"daily-notes/services"
 	"daily-notes/session"
 	"daily-notes/sync"
+	"encoding/base64"
 	"encoding/json"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -50,7 +59,7 @@ func setupTestDB(t *testing.T) (*app.App, func()) {
 	require.NoError(t, err, "Failed to run migrations")
 
 	repo := database.NewRepository(db)
-	sessionStore := session.NewStore(db.DB)
+	sessionStore := session.NewStore(db)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	// Create mock sync worker (nil for tests that don't need it)
@@ -69,7 +78,7 @@ func setupTestDB(t *testing.T) (*app.App, func()) {
 		Name:      "Test User",
 		CreatedAt: time.Now(),
 	}
-	err = repo.UpsertUser(testUser)
+	err = repo.UpsertUser(context.Background(), testUser)
 	require.NoError(t, err, "Failed to create test user")
 
 	// Return cleanup function
@@ -182,7 +191,7 @@ func TestGetNote(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Setup: Insert note if needed
 			if tt.setupNote != nil {
-				err := application.Repo.UpsertNote(tt.setupNote, false)
+				err := application.Repo.UpsertNote(context.Background(), tt.setupNote, false)
 				require.NoError(t, err)
 			}
 
@@ -257,7 +266,7 @@ func TestUpsertNote(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 			validateNote: func(t *testing.T, userID string) {
-				note, err := application.Repo.GetNote(userID, "Work", "2025-10-16")
+				note, err := application.Repo.GetNote(context.Background(), database.ScopeUser(userID), "Work", "2025-10-16")
 				require.NoError(t, err)
 				assert.NotNil(t, note)
 				assert.Equal(t, "New note content", note.Content)
@@ -272,7 +281,7 @@ func TestUpsertNote(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 			validateNote: func(t *testing.T, userID string) {
-				note, err := application.Repo.GetNote(userID, "Work", "2025-10-16")
+				note, err := application.Repo.GetNote(context.Background(), database.ScopeUser(userID), "Work", "2025-10-16")
 				require.NoError(t, err)
 				assert.Equal(t, "Updated content", note.Content)
 			},
@@ -356,7 +365,7 @@ func TestGetNotesByContext(t *testing.T) {
 	}
 
 	for _, note := range testNotes {
-		err := application.Repo.UpsertNote(note, false)
+		err := application.Repo.UpsertNote(context.Background(), note, false)
 		require.NoError(t, err)
 	}
 
@@ -442,6 +451,1059 @@ func TestGetNotesByContext(t *testing.T) {
 	}
 }
 
+// TestSearchNotes tests full-text search over the user's notes
+func TestSearchNotes(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/notes/search", handlers.SearchNotes(application))
+
+	for date, content := range map[string]string{
+		"2025-10-16": "Roadmap planning",
+		"2025-10-17": "Groceries",
+	} {
+		err := application.Repo.UpsertNote(context.Background(), &models.Note{
+			UserID:    "test-user-id",
+			Context:   "Work",
+			Date:      date,
+			Content:   content,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}, false)
+		require.NoError(t, err)
+	}
+
+	t.Run("Missing query", func(t *testing.T) {
+		resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes/search", nil), -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Matching notes with highlighted snippets", func(t *testing.T) {
+		resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes/search?q=road", nil), -1)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body struct {
+			Results []models.NoteSearchResult `json:"results"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Len(t, body.Results, 1)
+		assert.Equal(t, "2025-10-16", body.Results[0].Date)
+		assert.Equal(t, "<mark>Roadmap</mark> planning", body.Results[0].Snippet)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes/search?q=road&context=Work&from=2025-10-17&sync_status=synced", nil), -1)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body struct {
+			Results []models.NoteSearchResult `json:"results"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Empty(t, body.Results)
+	})
+
+	t.Run("Invalid filters", func(t *testing.T) {
+		for _, query := range []string{"from=17-10-2025", "from=2025-10-17&to=2025-10-16", "sync_status=lost"} {
+			resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes/search?q=road&"+query, nil), -1)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+		}
+	})
+}
+
+// TestTags tests listing #tags and the notes using them
+func TestTags(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/tags", handlers.GetTags(application))
+	fiberApp.Get("/api/notes/by-tag", handlers.GetNotesByTag(application))
+
+	err := application.Repo.UpsertNote(context.Background(), &models.Note{
+		UserID:    "test-user-id",
+		Context:   "Work",
+		Date:      "2025-10-16",
+		Content:   "Planning the #roadmap",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}, false)
+	require.NoError(t, err)
+
+	resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/tags", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var tags struct {
+		Tags []models.Tag `json:"tags"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tags))
+	assert.Equal(t, []models.Tag{{Name: "roadmap", Count: 1}}, tags.Tags)
+
+	resp, err = fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes/by-tag?tag=%23Roadmap", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var notes struct {
+		Notes []models.Note `json:"notes"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&notes))
+	require.Len(t, notes.Notes, 1)
+	assert.Equal(t, []string{"roadmap"}, notes.Notes[0].Tags)
+
+	resp, err = fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes/by-tag", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// TestNoteRevisions tests listing earlier versions of a note and restore errors
+func TestNoteRevisions(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/notes/revisions", handlers.GetNoteRevisions(application))
+	fiberApp.Post("/api/notes/revisions/:id/restore", handlers.RestoreNoteRevision(application))
+
+	for _, content := range []string{"First draft", "Second draft"} {
+		err := application.Repo.UpsertNote(context.Background(), &models.Note{
+			UserID:    "test-user-id",
+			Context:   "Work",
+			Date:      "2025-10-16",
+			Content:   content,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}, false)
+		require.NoError(t, err)
+	}
+
+	resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes/revisions?context=Work&date=2025-10-16", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var result struct {
+		Revisions []models.NoteRevision `json:"revisions"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.Len(t, result.Revisions, 1)
+	assert.Equal(t, "First draft", result.Revisions[0].Content)
+
+	resp, err = fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes/revisions?context=Work", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = fiberApp.Test(httptest.NewRequest(http.MethodPost, "/api/notes/revisions/abc/restore", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = fiberApp.Test(httptest.NewRequest(http.MethodPost, "/api/notes/revisions/999/restore", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestNoteConflicts tests listing sync conflicts and resolve validation
+func TestNoteConflicts(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/notes/conflicts", handlers.GetNoteConflicts(application))
+	fiberApp.Post("/api/notes/conflicts/resolve", handlers.ResolveNoteConflict(application))
+
+	note := &models.Note{
+		UserID:    "test-user-id",
+		Context:   "Work",
+		Date:      "2025-10-16",
+		Content:   "Laptop edit",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	require.NoError(t, application.Repo.UpsertNote(ctx, note, true))
+	require.NoError(t, application.Repo.MarkNoteConflict(ctx, note.ID, "Phone edit", time.Now()))
+
+	resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes/conflicts", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var result struct {
+		Conflicts []models.NoteConflict `json:"conflicts"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.Len(t, result.Conflicts, 1)
+	assert.Equal(t, "Laptop edit", result.Conflicts[0].LocalContent)
+	assert.Equal(t, "Phone edit", result.Conflicts[0].RemoteContent)
+
+	resolve := func(body string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/notes/conflicts/resolve", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusBadRequest, resolve(`{"context":"Work","date":"2025-10-16","resolution":"both"}`))
+	assert.Equal(t, http.StatusNotFound, resolve(`{"context":"Work","date":"2025-10-17","resolution":"local"}`))
+}
+
+func TestVerifySync(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/sync/verify", handlers.VerifySync(application))
+
+	verify := func(target string) int {
+		t.Helper()
+		resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, target, nil), -1)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusBadRequest, verify("/api/sync/verify"))
+	assert.Equal(t, http.StatusServiceUnavailable, verify("/api/sync/verify?context=Work"), "no sync worker in tests")
+}
+
+func TestDriveWebhook(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Post("/api/drive/webhook", handlers.DriveWebhook(application))
+
+	notify := func(state string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/drive/webhook", nil)
+		req.Header.Set("X-Goog-Channel-ID", "channel-1")
+		req.Header.Set("X-Goog-Resource-State", state)
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusOK, notify("sync"))
+	assert.Equal(t, http.StatusServiceUnavailable, notify("change"), "no sync worker in tests")
+}
+
+func TestChangelog(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/changelog", handlers.GetChangelog(application))
+	fiberApp.Post("/api/changelog/seen", handlers.MarkChangelogSeen(application))
+
+	call := func(method, body string) (int, models.Changelog) {
+		t.Helper()
+		target := "/api/changelog"
+		if method == http.MethodPost {
+			target += "/seen"
+		}
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+
+		var result struct {
+			Changelog models.Changelog `json:"changelog"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.Changelog
+	}
+
+	status, changelog := call(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, changelog.Unseen, 1)
+	assert.Equal(t, changelog.CurrentVersion, changelog.Unseen[0].Version)
+	assert.Empty(t, changelog.LastSeenVersion)
+
+	status, _ = call(http.MethodPost, `{"version": "0.0.1"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = call(http.MethodPost, "")
+	require.Equal(t, http.StatusOK, status)
+	_, changelog = call(http.MethodGet, "")
+	assert.Equal(t, changelog.CurrentVersion, changelog.LastSeenVersion)
+	assert.Empty(t, changelog.Unseen)
+}
+
+func TestGetVersion(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/version", handlers.GetVersion(application))
+
+	resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/version", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result map[string]json.RawMessage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+
+	var build models.BuildInfo
+	require.NoError(t, json.Unmarshal(result["build"], &build))
+	assert.Equal(t, "dev", build.Version)
+	assert.NotEmpty(t, build.GoVersion)
+	assert.NotContains(t, result, "update", "update checks are off by default")
+}
+
+func TestImportNotes(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Post("/api/import", handlers.ImportNotes(application))
+
+	upload := func(archive []byte, format string) (*http.Response, models.NoteImportResult) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		if format != "" {
+			require.NoError(t, form.WriteField("format", format))
+		}
+		part, err := form.CreateFormFile("file", "notes.zip")
+		require.NoError(t, err)
+		part.Write(archive)
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/import", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+
+		var result struct {
+			Result models.NoteImportResult `json:"result"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp, result.Result
+	}
+
+	zipFiles := func(files map[string]string) []byte {
+		var archive bytes.Buffer
+		zw := zip.NewWriter(&archive)
+		for name, content := range files {
+			w, err := zw.Create(name)
+			require.NoError(t, err)
+			w.Write([]byte(content))
+		}
+		require.NoError(t, zw.Close())
+		return archive.Bytes()
+	}
+
+	resp, result := upload(zipFiles(map[string]string{
+		"Journal/16-10-2025.md": "Imported entry",
+		"Journal/draft.md":      "No date",
+	}), "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 1, result.ContextsCreated)
+
+	note, err := application.Repo.GetNote(context.Background(), database.ScopeUser("test-user-id"), "Journal", "2025-10-16")
+	require.NoError(t, err)
+	require.NotNil(t, note)
+	assert.Equal(t, "Imported entry", note.Content)
+	assert.Equal(t, models.SyncStatusPending, note.SyncStatus)
+
+	resp, _ = upload([]byte("not a zip"), "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, result = upload(zipFiles(map[string]string{
+		"Vault/.obsidian/app.json":          "{}",
+		"Vault/2025-10-17.md":               "---\ntags: [standup]\n---\nRoot daily note",
+		"Vault/Work/2025/2025-10-18 Sat.md": "Nested daily note",
+	}), "obsidian")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, result.Imported)
+	assert.Zero(t, result.Failed)
+
+	note, err = application.Repo.GetNote(context.Background(), database.ScopeUser("test-user-id"), services.ObsidianRootContext, "2025-10-17")
+	require.NoError(t, err)
+	require.NotNil(t, note)
+	assert.Equal(t, "Root daily note\n\n#standup\n", note.Content)
+
+	note, err = application.Repo.GetNote(context.Background(), database.ScopeUser("test-user-id"), "Work", "2025-10-18")
+	require.NoError(t, err)
+	require.NotNil(t, note)
+
+	resp, _ = upload(zipFiles(map[string]string{"Journal/2025-10-19.md": "x"}), "notion")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestExportImportAccount(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/export", handlers.ExportAccount(application))
+	fiberApp.Post("/api/import", handlers.ImportNotes(application))
+
+	ctx := context.Background()
+	require.NoError(t, application.Repo.CreateContext(ctx, &models.Context{
+		ID: "ctx-work", UserID: "test-user-id", Name: "Work", Color: "info", CreatedAt: time.Now(),
+	}))
+	require.NoError(t, application.Repo.UpsertNote(ctx, &models.Note{
+		UserID: "test-user-id", Context: "Work", Date: "2025-10-16", Content: "Original #work",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}, false))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/export?format=json", nil)
+	resp, err := fiberApp.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "daily-notes-export-")
+
+	var account models.AccountExport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&account))
+	assert.Equal(t, 1, account.Version)
+	require.Len(t, account.Notes, 1)
+	assert.Equal(t, "Original #work", account.Notes[0].Content)
+	assert.Equal(t, []models.ProfileContext{{Name: "Work", Color: "info"}}, account.Contexts)
+
+	// Restore the export over a changed note and into a context that is gone
+	require.NoError(t, application.Repo.UpsertNote(ctx, &models.Note{
+		UserID: "test-user-id", Context: "Work", Date: "2025-10-16", Content: "Changed",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}, false))
+	account.Settings = models.UpdateSettingsRequest{Theme: "dark", Timezone: "UTC", DateFormat: "YYYY-MM-DD"}
+	account.Notes = append(account.Notes, models.ExportedNote{Context: "Home", Type: "week", Date: "2025-W42", Content: "Week plan"})
+
+	data, err := json.Marshal(account)
+	require.NoError(t, err)
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	require.NoError(t, form.WriteField("format", "json"))
+	part, err := form.CreateFormFile("file", "export.json")
+	require.NoError(t, err)
+	part.Write(data)
+	require.NoError(t, form.Close())
+
+	req = httptest.NewRequest(http.MethodPost, "/api/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err = fiberApp.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Result  models.NoteImportResult    `json:"result"`
+		Profile models.ProfileImportResult `json:"profile"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 2, result.Result.Imported)
+	assert.Equal(t, 1, result.Result.ContextsCreated)
+	assert.Equal(t, "dark", result.Profile.Settings.Theme)
+
+	note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Work", "2025-10-16")
+	require.NoError(t, err)
+	assert.Equal(t, "Original #work", note.Content)
+
+	week, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Home", "2025-W42")
+	require.NoError(t, err)
+	require.NotNil(t, week)
+	assert.Equal(t, "week", week.Type)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/export?format=csv", nil)
+	resp, err = fiberApp.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestPublicContextPages(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Put("/api/contexts/:id/public", handlers.PublishContext(application))
+	fiberApp.Get("/@:handle", handlers.PublicIndexPage(application))
+	fiberApp.Get("/@:handle/feed.xml", handlers.PublicFeed(application))
+	fiberApp.Get("/@:handle/:date", handlers.PublicNotePage(application))
+
+	ctx := context.Background()
+	require.NoError(t, application.Repo.CreateContext(ctx, &models.Context{
+		ID: "ctx-garden", UserID: "test-user-id", Name: "Garden", Color: "success", CreatedAt: time.Now(),
+	}))
+	require.NoError(t, application.Repo.UpsertNote(ctx, &models.Note{
+		UserID: "test-user-id", Context: "Garden", Date: "2025-10-16", Content: "# Tomatoes\n\nRipe <script>x</script>",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}, false))
+
+	get := func(path string) (*http.Response, string) {
+		resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, path, nil), -1)
+		require.NoError(t, err)
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		return resp, body.String()
+	}
+
+	resp, _ := get("/@garden")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "nothing is public before publishing")
+
+	req := httptest.NewRequest(http.MethodPut, "/api/contexts/ctx-garden/public", bytes.NewBufferString(`{"handle":"garden"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := fiberApp.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, body := get("/@garden")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "noindex, nofollow", resp.Header.Get("X-Robots-Tag"))
+	assert.Contains(t, body, `href="/@garden/2025-10-16"`)
+	assert.Contains(t, body, "Tomatoes")
+
+	resp, body = get("/@garden/2025-10-16")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "<h1>Tomatoes</h1>")
+	assert.NotContains(t, body, "<script>x</script>")
+
+	resp, body = get("/@garden/feed.xml")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/rss+xml")
+	assert.Contains(t, body, "<title>Tomatoes</title>")
+	assert.Contains(t, body, "/@garden/2025-10-16</link>")
+
+	resp, _ = get("/@garden/2025-10-17")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = get("/@garden/2025-W42")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestShareLinks(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/notes/share", handlers.GetShares(application))
+	fiberApp.Post("/api/notes/share", handlers.ShareNote(application))
+	fiberApp.Delete("/api/notes/share/:id", handlers.DeleteShare(application))
+	fiberApp.Get("/s/:token", handlers.SharedNotePage(application))
+
+	ctx := context.Background()
+	require.NoError(t, application.Repo.UpsertNote(ctx, &models.Note{
+		UserID: "test-user-id", Context: "Garden", Date: "2025-10-16", Content: "# Tomatoes\n\nRipe <script>x</script>",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}, false))
+
+	share := func(body string) (*http.Response, fiber.Map) {
+		req := httptest.NewRequest(http.MethodPost, "/api/notes/share", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		var result fiber.Map
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp, result
+	}
+	get := func(path string) (*http.Response, string) {
+		resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, path, nil), -1)
+		require.NoError(t, err)
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		return resp, body.String()
+	}
+
+	resp, _ := share(`{"context":"Garden","date":"2025-10-17"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = share(`{"context":"Garden","date":"2025-10-16","expires_in_days":400}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, result := share(`{"context":"Garden","date":"2025-10-16","expires_in_days":7}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	link := result["share"].(map[string]any)
+	assert.NotEmpty(t, link["expires_at"])
+
+	resp, body := get(link["url"].(string))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "noindex, nofollow", resp.Header.Get("X-Robots-Tag"))
+	assert.Equal(t, "no-referrer", resp.Header.Get("Referrer-Policy"))
+	assert.Contains(t, body, "<h1>Tomatoes</h1>")
+	assert.NotContains(t, body, "<script>x</script>")
+
+	resp, body = get("/api/notes/share")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, link["token"].(string))
+
+	id := strconv.FormatInt(int64(link["id"].(float64)), 10)
+	resp, err := fiberApp.Test(httptest.NewRequest(http.MethodDelete, "/api/notes/share/"+id, nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = fiberApp.Test(httptest.NewRequest(http.MethodDelete, "/api/notes/share/"+id, nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = get(link["url"].(string))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "revoked links show nothing")
+}
+
+func TestSharedContexts(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, application.Repo.UpsertUser(ctx, &models.User{ID: "member-id", GoogleID: "google-member", Email: "ana@example.com", Name: "Ana", CreatedAt: time.Now()}))
+	// Local-only, so saves don't reach the sync worker the tests run without
+	require.NoError(t, application.Repo.CreateContext(ctx, &models.Context{
+		ID: "ctx-family", UserID: "test-user-id", Name: "Family", Color: "info", LocalOnly: true, CreatedAt: time.Now(),
+	}))
+	require.NoError(t, application.Repo.UpsertNote(ctx, &models.Note{
+		UserID: "test-user-id", Context: "Family", Date: "2025-10-16", Content: "Groceries",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}, false))
+
+	// Requests are made as the user of the X-User header
+	fiberApp := fiber.New()
+	fiberApp.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", c.Get("X-User"))
+		return c.Next()
+	})
+	fiberApp.Get("/api/contexts", handlers.GetContexts(application))
+	fiberApp.Post("/api/contexts/:id/invite", handlers.InviteContextMember(application))
+	fiberApp.Get("/api/invitations", handlers.GetInvitations(application))
+	fiberApp.Post("/api/invitations/:id/accept", handlers.AcceptInvitation(application))
+	fiberApp.Get("/api/notes", handlers.GetNote(application))
+	fiberApp.Post("/api/notes", handlers.UpsertNote(application))
+
+	do := func(user, method, path, body string) (*http.Response, fiber.Map) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User", user)
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		var result fiber.Map
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp, result
+	}
+
+	resp, _ := do("member-id", http.MethodPost, "/api/contexts/ctx-family/invite", `{"email":"eve@example.com","role":"read"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "only the owner invites")
+	resp, _ = do("test-user-id", http.MethodPost, "/api/contexts/ctx-family/invite", `{"email":"ana@example.com","role":"read"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp, result := do("member-id", http.MethodGet, "/api/notes?context=Family&date=2025-10-16", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, result["note"].(map[string]any)["content"], "invitations grant nothing until accepted")
+
+	_, result = do("member-id", http.MethodGet, "/api/invitations", "")
+	invitations := result["invitations"].([]any)
+	require.Len(t, invitations, 1)
+	id := strconv.FormatInt(int64(invitations[0].(map[string]any)["id"].(float64)), 10)
+	resp, _ = do("member-id", http.MethodPost, "/api/invitations/"+id+"/accept", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, result = do("member-id", http.MethodGet, "/api/contexts", "")
+	contexts := result["contexts"].([]any)
+	require.Len(t, contexts, 1)
+	assert.Equal(t, "read", contexts[0].(map[string]any)["role"])
+
+	_, result = do("member-id", http.MethodGet, "/api/notes?context=Family&date=2025-10-16", "")
+	assert.Equal(t, "Groceries", result["note"].(map[string]any)["content"])
+	resp, _ = do("member-id", http.MethodPost, "/api/notes", `{"context":"Family","date":"2025-10-16","content":"Mine now"}`)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "read-only members can't write")
+
+	resp, _ = do("test-user-id", http.MethodPost, "/api/contexts/ctx-family/invite", `{"email":"ana@example.com","role":"write"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp, _ = do("member-id", http.MethodPost, "/api/notes", `{"context":"Family","date":"2025-10-16","content":"Groceries, milk"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Family", "2025-10-16")
+	require.NoError(t, err)
+	assert.Equal(t, "Groceries, milk", note.Content, "members write the owner's note")
+}
+
+func TestEncryption(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	// Local-only, so saves don't reach the sync worker the tests run without
+	require.NoError(t, application.Repo.CreateContext(ctx, &models.Context{
+		ID: "ctx-journal", UserID: "test-user-id", Name: "Journal", Color: "info", LocalOnly: true, CreatedAt: time.Now(),
+	}))
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/encryption", handlers.GetEncryption(application))
+	fiberApp.Put("/api/encryption", handlers.EnableEncryption(application))
+	fiberApp.Post("/api/encryption/verify", handlers.VerifyEncryptionKey(application))
+	fiberApp.Delete("/api/encryption", handlers.DisableEncryption(application))
+	fiberApp.Post("/api/notes", handlers.UpsertNote(application))
+
+	do := func(method, path string, body any) (*http.Response, fiber.Map) {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		var result fiber.Map
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp, result
+	}
+
+	// What a client does with the passphrase; the server only sees the salt and the verifier
+	salt := bytes.Repeat([]byte{7}, e2ee.MinSaltSize)
+	key, err := e2ee.DeriveKey("correct horse", salt, e2ee.MinIterations)
+	require.NoError(t, err)
+	wrong, err := e2ee.DeriveKey("battery staple", salt, e2ee.MinIterations)
+	require.NoError(t, err)
+
+	resp, _ := do(http.MethodPut, "/api/encryption", fiber.Map{
+		"cipher": e2ee.CipherAESGCM, "kdf": e2ee.KDFPBKDF2, "iterations": e2ee.MinIterations,
+		"salt": base64.StdEncoding.EncodeToString(salt), "verifier": e2ee.Verifier(key),
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, result := do(http.MethodPost, "/api/encryption/verify", fiber.Map{"verifier": e2ee.Verifier(wrong)})
+	assert.Equal(t, false, result["valid"])
+	_, result = do(http.MethodPost, "/api/encryption/verify", fiber.Map{"verifier": e2ee.Verifier(key)})
+	assert.Equal(t, true, result["valid"])
+
+	resp, _ = do(http.MethodPost, "/api/notes", fiber.Map{"context": "Journal", "date": "2025-10-16", "content": "Dear diary"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "plaintext is refused")
+
+	envelope, err := e2ee.Seal(key, salt, e2ee.MinIterations, "Dear diary")
+	require.NoError(t, err)
+	resp, result = do(http.MethodPost, "/api/notes", fiber.Map{"context": "Journal", "date": "2025-10-16", "content": envelope.String()})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, true, result["note"].(map[string]any)["encrypted"])
+
+	note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Journal", "2025-10-16")
+	require.NoError(t, err)
+	assert.NotContains(t, note.Content, "diary", "the server stores the ciphertext")
+
+	_, result = do(http.MethodGet, "/api/encryption", nil)
+	assert.Equal(t, float64(1), result["encryption"].(map[string]any)["encrypted_notes"])
+	resp, _ = do(http.MethodDelete, "/api/encryption", nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "encrypted notes are decrypted first")
+}
+
+func TestAPIKeys(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	// Local-only, so saves don't reach the sync worker the tests run without
+	require.NoError(t, application.Repo.CreateContext(ctx, &models.Context{
+		ID: "ctx-inbox", UserID: "test-user-id", Name: "Inbox", Color: "info", LocalOnly: true, CreatedAt: time.Now(),
+	}))
+
+	// Signed in by the real middleware, which only knows API keys here
+	fiberApp := fiber.New()
+	api := fiberApp.Group("/api", middleware.AuthRequired(application.SessionStore, nil, application.APITokens, application.APIKeys))
+	api.Get("/notes", handlers.GetNote(application))
+	api.Post("/notes", handlers.UpsertNote(application))
+	api.Post("/keys", handlers.CreateAPIKey(application))
+
+	do := func(secret, method, path string, body any) int {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+secret)
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	add := fiber.Map{"context": "Inbox", "date": "2025-10-16", "content": "Buy milk", "append": true}
+
+	reader, err := application.APIKeys.Create(ctx, "test-user-id", "Dashboard", models.KeyRead)
+	require.NoError(t, err)
+	writer, err := application.APIKeys.Create(ctx, "test-user-id", "Shortcut", models.KeyReadWrite)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, do(writer.Key, http.MethodPost, "/api/notes", add))
+	assert.Equal(t, http.StatusOK, do(reader.Key, http.MethodGet, "/api/notes?context=Inbox&date=2025-10-16", nil))
+	assert.Equal(t, http.StatusForbidden, do(reader.Key, http.MethodPost, "/api/notes", add), "read-only keys don't save")
+	assert.Equal(t, http.StatusForbidden, do(writer.Key, http.MethodPost, "/api/keys", fiber.Map{"name": "More", "scope": "read"}), "keys don't mint keys")
+
+	note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Inbox", "2025-10-16")
+	require.NoError(t, err)
+	assert.Contains(t, note.Content, "Buy milk")
+
+	require.NoError(t, application.APIKeys.Revoke(ctx, "test-user-id", writer.ID))
+	assert.Equal(t, http.StatusUnauthorized, do(writer.Key, http.MethodPost, "/api/notes", add))
+}
+
+func TestAppendToNote(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, application.Repo.CreateContext(ctx, &models.Context{
+		ID: "ctx-inbox", UserID: "test-user-id", Name: "Inbox", Color: "info", LocalOnly: true, CreatedAt: time.Now(),
+	}))
+	application.NoteService.SetClock(clock.NewFake(time.Date(2025, 10, 16, 23, 30, 0, 0, time.UTC)))
+
+	fiberApp := setupTestApp()
+	fiberApp.Post("/api/notes/append", handlers.AppendToNote(application))
+
+	do := func(body any) *http.Response {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/notes/append", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Appends keep what's already there", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(fiber.Map{"context": "Inbox", "content": "- Buy milk"}).StatusCode)
+		assert.Equal(t, http.StatusOK, do(fiber.Map{"context": "Inbox", "content": "- Call Sam", "timestamp": true}).StatusCode)
+
+		note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Inbox", "2025-10-16")
+		require.NoError(t, err)
+		assert.Contains(t, note.Content, "- Buy milk")
+		assert.Contains(t, note.Content, "- 23:30 Call Sam")
+	})
+
+	t.Run("Today and timestamps follow the timezone", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(fiber.Map{"context": "Inbox", "content": "Landed", "timestamp": true, "timezone": "Asia/Tokyo"}).StatusCode)
+
+		note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Inbox", "2025-10-17")
+		require.NoError(t, err)
+		assert.Contains(t, note.Content, "08:30 Landed")
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(fiber.Map{"context": "Inbox"}).StatusCode)
+		assert.Equal(t, http.StatusBadRequest, do(fiber.Map{"context": "Inbox", "content": "x", "timezone": "Mars/Base"}).StatusCode)
+		assert.Equal(t, http.StatusBadRequest, do(fiber.Map{"content": "No context"}).StatusCode, "suggestions are opt-in")
+	})
+}
+
+func TestSuggestedContext(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+	application.NoteService.SetClock(clock.NewFake(time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)))
+
+	ctx := context.Background()
+	for _, c := range []models.Context{
+		{ID: "ctx-inbox", Name: "Inbox", Color: "info"},
+		{ID: "ctx-fitness", Name: "Fitness", Color: "success"},
+	} {
+		c.UserID, c.LocalOnly, c.CreatedAt = "test-user-id", true, time.Now()
+		require.NoError(t, application.Repo.CreateContext(ctx, &c))
+	}
+	require.NoError(t, application.Repo.UpsertNote(ctx, &models.Note{
+		UserID: "test-user-id", Context: "Inbox", Date: "2025-10-14", Content: "Buy milk and call the plumber",
+	}, false))
+	require.NoError(t, application.Repo.UpsertNote(ctx, &models.Note{
+		UserID: "test-user-id", Context: "Fitness", Date: "2025-10-15", Content: "Ran 5km, then squats and deadlifts",
+	}, false))
+	require.NoError(t, application.Repo.UpdateUserSettings(ctx, "test-user-id", models.UserSettings{Timezone: "UTC", SuggestContext: true}))
+
+	// Requests of API keys have no session; the stored setting applies
+	fiberApp := fiber.New()
+	fiberApp.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", "test-user-id")
+		return c.Next()
+	})
+	fiberApp.Post("/api/notes", handlers.UpsertNote(application))
+	fiberApp.Post("/api/notes/append", handlers.AppendToNote(application))
+	post := func(path, body string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Captures without a context go to the suggested one", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, post("/api/notes", `{"date": "2025-10-16", "content": "Squats and a 5km run"}`).StatusCode)
+
+		note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Fitness", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, note)
+		assert.Equal(t, "Squats and a 5km run", note.Content)
+	})
+
+	t.Run("Appends without a context go to the suggested one", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, post("/api/notes/append", `{"content": "Deadlifts after the run"}`).StatusCode)
+
+		note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Fitness", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, note)
+		assert.Contains(t, note.Content, "Squats and a 5km run")
+		assert.Contains(t, note.Content, "Deadlifts after the run")
+	})
+}
+
+func TestNoteSavePreconditions(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, application.Repo.CreateContext(ctx, &models.Context{
+		ID: "ctx-inbox", UserID: "test-user-id", Name: "Inbox", Color: "info", LocalOnly: true, CreatedAt: time.Now(),
+	}))
+	fake := clock.NewFake(time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC))
+	application.NoteService.SetClock(fake)
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/notes", handlers.GetNote(application))
+	fiberApp.Post("/api/notes", handlers.UpsertNote(application))
+
+	save := func(content string, headers map[string]string) *http.Response {
+		payload, _ := json.Marshal(fiber.Map{"context": "Inbox", "date": "2025-10-16", "content": content})
+		req := httptest.NewRequest(http.MethodPost, "/api/notes", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	require.Equal(t, http.StatusOK, save("# Tasks\n- [ ] Call Sam\n- [ ] Buy milk\n", nil).StatusCode)
+	resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes?context=Inbox&date=2025-10-16", nil), -1)
+	require.NoError(t, err)
+	lastModified := resp.Header.Get("Last-Modified")
+	assert.Equal(t, "Thu, 16 Oct 2025 09:00:00 GMT", lastModified)
+
+	// Another tab saves a minute later
+	fake.Advance(time.Minute)
+	require.Equal(t, http.StatusOK, save("# Tasks\n- [x] Call Sam\n- [ ] Buy milk\n", nil).StatusCode)
+
+	t.Run("Stale saves conflict with a diff", func(t *testing.T) {
+		resp := save("# Tasks\n- [ ] Call Sam\n- [ ] Buy milk\n- [ ] Book flights\n", map[string]string{"If-Unmodified-Since": lastModified})
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Equal(t, "Thu, 16 Oct 2025 09:01:00 GMT", resp.Header.Get("Last-Modified"))
+
+		var body struct {
+			Note models.Note `json:"note"`
+			Diff struct {
+				Hunks   []map[string]any `json:"hunks"`
+				Unified string           `json:"unified"`
+			} `json:"diff"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Contains(t, body.Note.Content, "- [x] Call Sam")
+		assert.Len(t, body.Diff.Hunks, 1)
+		assert.Equal(t, "--- saved\n+++ yours\n@@ -1,3 +1,4 @@\n # Tasks\n-- [x] Call Sam\n+- [ ] Call Sam\n - [ ] Buy milk\n+- [ ] Book flights\n", body.Diff.Unified)
+
+		note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Inbox", "2025-10-16")
+		require.NoError(t, err)
+		assert.NotContains(t, note.Content, "Book flights")
+	})
+
+	t.Run("Saves based on the latest version go through", func(t *testing.T) {
+		resp := save("# Tasks\n- [x] Call Sam\n", map[string]string{"If-Unmodified-Since": "Thu, 16 Oct 2025 09:01:00 GMT"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Conflicts by revision have a diff too", func(t *testing.T) {
+		resp := save("Stale\n", map[string]string{"If-Match": `"1"`})
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Contains(t, body, "diff")
+	})
+}
+
+func TestPatchNote(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	require.NoError(t, application.Repo.CreateContext(ctx, &models.Context{
+		ID: "ctx-inbox", UserID: "test-user-id", Name: "Inbox", Color: "info", LocalOnly: true, CreatedAt: time.Now(),
+	}))
+
+	fiberApp := setupTestApp()
+	fiberApp.Patch("/api/notes", handlers.PatchNote(application))
+
+	autosave := func(body fiber.Map) (*http.Response, map[string]any) {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPatch, "/api/notes", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		var response map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		return resp, response
+	}
+
+	resp, body := autosave(fiber.Map{"context": "Inbox", "date": "2025-10-16", "content": "Draft", "revision": 0})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, float64(1), body["revision"])
+	assert.NotContains(t, body, "note", "the client has the content")
+	assert.Equal(t, "ok", body["sync_health"].(map[string]any)["state"])
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+
+	resp, body = autosave(fiber.Map{"context": "Inbox", "date": "2025-10-16", "content": "Draft done", "revision": 1})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, float64(2), body["revision"])
+
+	resp, body = autosave(fiber.Map{"context": "Inbox", "date": "2025-10-16", "content": "Stale tab", "revision": 1})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Contains(t, body, "diff")
+
+	resp, _ = autosave(fiber.Map{"context": "Inbox", "content": "No date"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = autosave(fiber.Map{"context": "Inbox", "date": "2025-10-16", "content": "Blind overwrite"})
+	assert.Equal(t, http.StatusPreconditionRequired, resp.StatusCode, "an autosave must say which version it edited")
+
+	note, err := application.Repo.GetNote(ctx, database.ScopeUser("test-user-id"), "Inbox", "2025-10-16")
+	require.NoError(t, err)
+	assert.Equal(t, "Draft done", note.Content)
+}
+
+// fakeBlog publishes every post to the same address
+type fakeBlog struct{}
+
+func (fakeBlog) Publish(ctx context.Context, post publish.Post) (*publish.Result, error) {
+	return &publish.Result{ExternalID: "post-1", URL: "https://blog.example.com/" + post.Slug + "/"}, nil
+}
+
+func TestPublishNote(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+	application.PublishService.SetOpener(func(models.PublishTarget) (publish.Target, error) { return fakeBlog{}, nil })
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/api/notes", handlers.GetNote(application))
+	fiberApp.Post("/api/notes/publish", handlers.PublishNote(application))
+	fiberApp.Get("/api/publish/targets", handlers.GetPublishTargets(application))
+	fiberApp.Post("/api/publish/targets", handlers.CreatePublishTarget(application))
+
+	ctx := context.Background()
+	require.NoError(t, application.Repo.UpsertNote(ctx, &models.Note{
+		UserID: "test-user-id", Context: "Garden", Date: "2025-10-16", Content: "# Tomatoes\n\nRipe",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}, false))
+
+	post := func(path, body string) (*http.Response, fiber.Map) {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		var result fiber.Map
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp, result
+	}
+
+	resp, result := post("/api/publish/targets", `{"kind":"wordpress","name":"Blog","url":"https://example.com","secret":"pass"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, result["error"], "username is required")
+
+	resp, result = post("/api/publish/targets", `{"kind":"ghost","name":"Blog","url":"https://blog.example.com","secret":"id:00"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	target := result["target"].(map[string]any)
+	assert.NotContains(t, target, "secret")
+
+	resp, _ = post("/api/notes/publish", `{"context":"Garden","date":"2025-10-16","target_id":"`+target["id"].(string)+`"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, application.PublishService.RunDue(ctx))
+
+	resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/api/notes?context=Garden&date=2025-10-16", nil), -1)
+	require.NoError(t, err)
+	var note struct {
+		Publications []models.NotePublication `json:"publications"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&note))
+	require.Len(t, note.Publications, 1)
+	assert.Equal(t, models.PublicationPublished, note.Publications[0].Status)
+	assert.Equal(t, "https://blog.example.com/2025-10-16-tomatoes/", note.Publications[0].ExternalURL)
+
+	resp, _ = post("/api/notes/publish", `{"context":"Garden","date":"2025-10-16","target_id":"missing"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
 // TestConcurrentNoteUpdates tests race conditions when updating the same note
 func TestConcurrentNoteUpdates(t *testing.T) {
 	t.Skip("Skipping temporarily - syncWorker needs proper mock implementation")
@@ -496,7 +1558,7 @@ func TestConcurrentNoteUpdates(t *testing.T) {
 	}
 
 	// Assert: Note exists in database
-	note, err := application.Repo.GetNote("test-user-id", "Work", "2025-10-16")
+	note, err := application.Repo.GetNote(context.Background(), database.ScopeUser("test-user-id"), "Work", "2025-10-16")
 	require.NoError(t, err)
 	assert.NotNil(t, note)
 }
@@ -514,7 +1576,7 @@ func BenchmarkUpsertNote(b *testing.B) {
 	defer db.Close()
 
 	repo := database.NewRepository(db)
-	sessionStore := session.NewStore(db.DB)
+	sessionStore := session.NewStore(db)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	application := app.New(repo, nil, sessionStore, nil, logger)
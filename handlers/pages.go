@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"daily-notes/app"
 	"daily-notes/config"
 	"daily-notes/templates/pages"
 	"daily-notes/utils"
@@ -31,19 +32,21 @@ func HomePage(c *fiber.Ctx) error {
 	).Render(c.Context(), c.Response().BodyWriter())
 }
 
-func ServerTime(c *fiber.Ctx) error {
-	timezone := c.Query("timezone", "UTC")
+func ServerTime(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		timezone := c.Query("timezone", "UTC")
 
-	loc, err := time.LoadLocation(timezone)
-	if err != nil {
-		loc = time.UTC
-	}
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			loc = time.UTC
+		}
 
-	now := time.Now().In(loc)
+		now := a.Clock.Now().In(loc)
 
-	return c.JSON(fiber.Map{
-		"timestamp": now.Unix(),
-		"timezone":  timezone,
-		"iso":       now.Format(time.RFC3339),
-	})
+		return c.JSON(fiber.Map{
+			"timestamp": now.Unix(),
+			"timezone":  timezone,
+			"iso":       now.Format(time.RFC3339),
+		})
+	}
 }
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SearchPalette returns ranked contexts, notes, tags and commands for the command palette
+func SearchPalette(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		query := c.Query("q")
+		limit := c.QueryInt("limit", 20)
+		userID := middleware.GetUserID(c)
+
+		results, err := a.PaletteService.Search(c.Context(), userID, query, limit)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to search", err)
+		}
+
+		return success(c, fiber.Map{
+			"query":   query,
+			"results": results,
+		})
+	}
+}
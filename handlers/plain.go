@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/pkg/period"
+	"daily-notes/templates/pages"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/gofiber/fiber/v2"
+)
+
+// PlainAuth requires a session like the API does, but shows signed-out visitors
+// a plain page explaining how to sign in instead of a JSON error
+func PlainAuth(a *app.App) fiber.Handler {
+	auth := middleware.AuthRequired(a.SessionStore, a.AuthService, nil, nil)
+	return func(c *fiber.Ctx) error {
+		if c.Cookies("session_id") == "" && c.Get(fiber.HeaderAuthorization) == "" {
+			c.Status(fiber.StatusUnauthorized)
+			return renderPage(c, pages.PlainSignedOut())
+		}
+		return auth(c)
+	}
+}
+
+// PlainPage renders one daily note as HTML without scripts, for screen readers,
+// old browsers and scripts. Query: context and date (default: first context, today).
+// Its append form posts to the normal notes API, see PlainFormRedirect.
+func PlainPage(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := middleware.GetUserID(c)
+
+		contexts, err := a.ContextService.List(c.Context(), userID)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch contexts", err)
+		}
+		if len(contexts) == 0 {
+			return renderPage(c, pages.PlainNoContexts())
+		}
+
+		day := a.Clock.Now().In(userLocation(c))
+		today := day.Format(period.DateLayout)
+		view := pages.PlainView{
+			Context: contexts[0].Name,
+			Date:    today,
+			Today:   today,
+			Saved:   c.QueryBool("saved"),
+			Error:   c.Query("error"),
+		}
+		for _, item := range contexts {
+			view.Contexts = append(view.Contexts, item.Name)
+		}
+
+		if name := c.Query("context"); name != "" {
+			if slices.Contains(view.Contexts, name) {
+				view.Context = name
+			} else {
+				c.Status(fiber.StatusNotFound)
+				view.Error = "There is no context named " + name + "."
+			}
+		}
+
+		if date := c.Query("date"); date != "" {
+			if parsed, err := time.Parse(period.DateLayout, date); err == nil {
+				day = parsed
+				view.Date = date
+			} else {
+				c.Status(fiber.StatusBadRequest)
+				view.Error = "The date must be in YYYY-MM-DD format; showing today instead."
+			}
+		}
+		view.Title = period.Title(view.Date)
+		view.Previous = day.AddDate(0, 0, -1).Format(period.DateLayout)
+		view.Next = day.AddDate(0, 0, 1).Format(period.DateLayout)
+
+		note, err := a.NoteService.Get(c.Context(), userID, view.Context, view.Date)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch note", err)
+		}
+		view.Content = note.Content
+
+		return renderPage(c, pages.Plain(view))
+	}
+}
+
+// PlainFormRedirect answers HTML form posts to a notes API route, as sent by the
+// plain page, with a redirect back to the note instead of the JSON response.
+// Requests with other content types pass through untouched.
+func PlainFormRedirect() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !strings.HasPrefix(string(c.Request().Header.ContentType()), fiber.MIMEApplicationForm) {
+			return c.Next()
+		}
+
+		query := url.Values{}
+		query.Set("context", c.FormValue("context"))
+		query.Set("date", c.FormValue("date"))
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() < fiber.StatusBadRequest {
+			query.Set("saved", "true")
+		} else {
+			query.Set("error", responseError(c.Response().Body()))
+		}
+
+		c.Response().ResetBody()
+		return c.Redirect("/plain?"+query.Encode(), fiber.StatusSeeOther)
+	}
+}
+
+// responseError reads the message of a JSON error response, including validation errors
+func responseError(body []byte) string {
+	var response struct {
+		Error  string `json:"error"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Error == "" {
+		return "The note could not be saved."
+	}
+
+	messages := []string{response.Error}
+	for _, e := range response.Errors {
+		messages = append(messages, e.Message)
+	}
+	return strings.Join(messages, ": ")
+}
+
+// userLocation returns the time zone of the signed-in user, UTC if unknown
+func userLocation(c *fiber.Ctx) *time.Location {
+	if sess, ok := c.Locals("session").(*models.Session); ok && sess != nil {
+		if loc, err := time.LoadLocation(sess.Settings.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// renderPage writes a Templ component as the HTML response
+func renderPage(c *fiber.Ctx, component templ.Component) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return component.Render(c.Context(), c.Response().BodyWriter())
+}
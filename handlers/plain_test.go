@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"context"
+	"daily-notes/handlers"
+	"daily-notes/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPlainPage tests the script-free HTML version of a note
+func TestPlainPage(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, name := range []string{"Work", "Personal"} {
+		require.NoError(t, application.Repo.CreateContext(ctx, &models.Context{
+			ID: "ctx-" + name, UserID: "test-user-id", Name: name, Color: "primary", CreatedAt: time.Now(),
+		}))
+	}
+	require.NoError(t, application.Repo.UpsertNote(ctx, &models.Note{
+		UserID: "test-user-id", Context: "Work", Date: "2025-10-16", Content: "Ship <release> notes",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}, false))
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/plain", handlers.PlainPage(application))
+
+	get := func(target string) (int, string) {
+		t.Helper()
+		resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, target, nil), -1)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("Renders the note without scripts", func(t *testing.T) {
+		status, body := get("/plain?context=Work&date=2025-10-16")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "Ship &lt;release&gt; notes")
+		assert.Contains(t, body, "Thursday, October 16, 2025")
+		assert.Contains(t, body, `aria-current="page">Work</a>`)
+		assert.Contains(t, body, `href="/plain?context=Work&amp;date=2025-10-15"`)
+		assert.Contains(t, body, `action="/api/notes?append=true"`)
+		assert.NotContains(t, body, "<script")
+	})
+
+	t.Run("Shows append results", func(t *testing.T) {
+		_, body := get("/plain?context=Personal&date=2025-10-16&saved=true")
+		assert.Contains(t, body, "This note is empty.")
+		assert.Contains(t, body, `role="status"`)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		status, body := get("/plain?context=Work&date=16-10-2025")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, `role="alert"`)
+
+		status, _ = get("/plain?context=Missing")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+// TestPlainAuth tests that signed-out visitors get a sign-in page instead of JSON
+func TestPlainAuth(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := fiber.New()
+	fiberApp.Get("/plain", handlers.PlainAuth(application), handlers.PlainPage(application))
+
+	resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/plain", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get(fiber.HeaderContentType), "text/html")
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "Sign in required")
+}
+
+// TestPlainFormRedirect tests that form posts from the plain page are redirected back to it
+func TestPlainFormRedirect(t *testing.T) {
+	fiberApp := fiber.New()
+	fiberApp.Post("/api/notes", handlers.PlainFormRedirect(), func(c *fiber.Ctx) error {
+		if c.QueryBool("fail") {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":  "Validation failed",
+				"errors": []fiber.Map{{"message": "content is required"}},
+			})
+		}
+		return c.JSON(fiber.Map{"success": true})
+	})
+
+	post := func(target, contentType, body string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := post("/api/notes?append=true", fiber.MIMEApplicationForm, "context=Work&date=2025-10-16&content=Done")
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	assert.Equal(t, "/plain?context=Work&date=2025-10-16&saved=true", resp.Header.Get("Location"))
+
+	resp = post("/api/notes?append=true&fail=true", fiber.MIMEApplicationForm, "context=Work&date=2025-10-16&content=")
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "Validation failed: content is required", location.Query().Get("error"))
+
+	resp = post("/api/notes?append=true", fiber.MIMEApplicationJSON, `{"content":"Done"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "JSON requests keep their response")
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// userSettings returns the settings of the signed-in user
+// Sessions carry the full settings; Bearer-token requests fall back to the stored user.
+func userSettings(a *app.App, c *fiber.Ctx, userID string) (models.UserSettings, error) {
+	if sess, ok := c.Locals("session").(*models.Session); ok && sess != nil {
+		return sess.Settings, nil
+	}
+	user, err := a.Repo.GetUser(c.Context(), userID)
+	if err != nil || user == nil {
+		return models.UserSettings{}, err
+	}
+	return user.Settings, nil
+}
+
+// ExportProfile downloads the user's settings and contexts as a portable JSON profile
+func ExportProfile(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := middleware.GetUserID(c)
+
+		settings, err := userSettings(a, c, userID)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to export profile", err)
+		}
+
+		profile, err := a.ProfileService.Export(c.Context(), userID, settings)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to export profile", err)
+		}
+
+		filename := fmt.Sprintf("daily-notes-profile-%s.json", profile.ExportedAt.Format("2006-01-02"))
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+		return c.JSON(profile)
+	}
+}
+
+// ExportAccount downloads the user's profile and all their notes as one versioned JSON
+// document (format=json, the only format so far), which POST /api/import restores.
+// Local-only notes are left out unless include_local_only=true.
+func ExportAccount(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if format := c.Query("format", "json"); format != "json" {
+			return badRequest(c, "Export format must be json")
+		}
+
+		userID := middleware.GetUserID(c)
+
+		settings, err := userSettings(a, c, userID)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to export account", err)
+		}
+
+		account, err := a.ProfileService.ExportAccount(c.Context(), userID, settings, c.QueryBool("include_local_only"))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to export account", err)
+		}
+
+		filename := fmt.Sprintf("daily-notes-export-%s.json", account.ExportedAt.Format("2006-01-02"))
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+		return c.JSON(account)
+	}
+}
+
+// ImportProfile applies a previously exported profile to the current user
+func ImportProfile(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var profile models.Profile
+		if err := c.BodyParser(&profile); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&profile); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		result, err := a.ProfileService.Import(c.Context(), userID, &profile)
+		if err != nil {
+			if errors.Is(err, services.ErrUnsupportedProfile) {
+				return badRequest(c, "Profile was exported by a newer version")
+			}
+			return serverErrorWithDetails(c, "Failed to import profile", err)
+		}
+
+		// Keep the current session in sync with the imported settings
+		if sess, ok := c.Locals("session").(*models.Session); ok && sess != nil {
+			recordTimezoneChange(c, a, userID, sess.Settings.Timezone, result.Settings.Timezone)
+			sess.Settings = result.Settings
+			a.SessionStore.Update(c.Cookies("session_id"), sess)
+		}
+
+		return success(c, fiber.Map{"result": result})
+	}
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"daily-notes/templates/pages"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPublicContexts lists the user's contexts published at /@handle
+func GetPublicContexts(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := middleware.GetUserID(c)
+
+		published, err := a.PublicService.List(c.Context(), userID)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch public contexts", err)
+		}
+
+		return success(c, fiber.Map{"public": published})
+	}
+}
+
+// PublishContext publishes a context read-only at /@handle, or changes its handle
+func PublishContext(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextID := c.Params("id")
+		if contextID == "" {
+			return badRequest(c, "context ID is required")
+		}
+
+		var req models.PublishContextRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		published, err := a.PublicService.Publish(c.Context(), userID, contextID, req)
+		if err != nil {
+			if target := matchError(err, services.ErrContextNotFound, services.ErrHandleTaken); target != nil {
+				return badRequest(c, target.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to publish context", err)
+		}
+
+		return success(c, fiber.Map{"public": published})
+	}
+}
+
+// UnpublishContext takes a context off its public page
+func UnpublishContext(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		contextID := c.Params("id")
+		if contextID == "" {
+			return badRequest(c, "context ID is required")
+		}
+
+		userID := middleware.GetUserID(c)
+
+		if err := a.PublicService.Unpublish(c.Context(), userID, contextID); err != nil {
+			if errors.Is(err, services.ErrContextNotPublic) {
+				return badRequest(c, "Context is not published")
+			}
+			return serverErrorWithDetails(c, "Failed to unpublish context", err)
+		}
+
+		return success(c, fiber.Map{"message": "Context unpublished"})
+	}
+}
+
+// PublicIndexPage lists the published notes of the context at /@handle
+func PublicIndexPage(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		published, notes, err := a.PublicService.Index(c.Context(), c.Params("handle"))
+		if err != nil {
+			return publicError(c, "Failed to load public page", err)
+		}
+
+		setRobots(c, published.Indexable)
+		return renderPage(c, pages.PublicIndex(pages.PublicView{
+			Handle:    published.Handle,
+			Context:   published.Context,
+			Indexable: published.Indexable,
+			Notes:     notes,
+		}))
+	}
+}
+
+// PublicNotePage renders the published note of a day at /@handle/<date>
+func PublicNotePage(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		published, note, err := a.PublicService.Note(c.Context(), c.Params("handle"), c.Params("date"))
+		if err != nil {
+			return publicError(c, "Failed to load public note", err)
+		}
+
+		setRobots(c, published.Indexable)
+		return renderPage(c, pages.PublicNote(pages.PublicView{
+			Handle:    published.Handle,
+			Context:   published.Context,
+			Indexable: published.Indexable,
+			Note:      note,
+		}))
+	}
+}
+
+// ShareNote creates a secret link showing a note read-only at /s/<token>
+func ShareNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.CreateShareRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		share, err := a.PublicService.Share(c.Context(), userID, req.Context, req.Date, req.ExpiresInDays)
+		switch {
+		case errors.Is(err, services.ErrNoteNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Note not found"})
+		case errors.Is(err, services.ErrShareLocalOnly):
+			return badRequest(c, services.ErrShareLocalOnly.Error())
+		case errors.Is(err, services.ErrShareEncrypted):
+			return badRequest(c, services.ErrShareEncrypted.Error())
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to share note", err)
+		}
+
+		return created(c, fiber.Map{"share": share})
+	}
+}
+
+// GetShares lists the user's share links, newest first
+func GetShares(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shares, err := a.PublicService.Shares(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch share links", err)
+		}
+
+		return success(c, fiber.Map{"shares": shares})
+	}
+}
+
+// DeleteShare revokes a share link
+func DeleteShare(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid share ID")
+		}
+
+		err = a.PublicService.Unshare(c.Context(), middleware.GetUserID(c), id)
+		switch {
+		case errors.Is(err, services.ErrShareNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Share link not found"})
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to revoke share link", err)
+		}
+
+		return success(c, fiber.Map{"message": "Share link revoked"})
+	}
+}
+
+// SharedNotePage renders the note of a share link at /s/<token>. The token is
+// the only secret, so the page is never indexed, cached or leaked as a referrer.
+func SharedNotePage(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		setRobots(c, false)
+		c.Set("Referrer-Policy", "no-referrer")
+		c.Set(fiber.HeaderCacheControl, "no-store")
+
+		note, contextName, err := a.PublicService.SharedNote(c.Context(), c.Params("token"))
+		if err != nil {
+			return publicError(c, "Failed to load shared note", err)
+		}
+
+		return renderPage(c, pages.SharedNote(note, contextName))
+	}
+}
+
+// rssFeed is an RSS 2.0 document
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description"` // Rendered HTML, escaped by the encoder
+}
+
+// PublicFeed serves the latest published notes of /@handle as an RSS feed
+func PublicFeed(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		published, notes, err := a.PublicService.Feed(c.Context(), c.Params("handle"))
+		if err != nil {
+			return publicError(c, "Failed to load feed", err)
+		}
+
+		link := c.BaseURL() + "/@" + published.Handle
+		feed := rssFeed{
+			Version: "2.0",
+			Channel: rssChannel{
+				Title:       published.Context,
+				Link:        link,
+				Description: "Daily notes of " + published.Context,
+				Items:       make([]rssItem, 0, len(notes)),
+			},
+		}
+		for _, note := range notes {
+			title := note.Title
+			if note.Heading != "" {
+				title = note.Heading
+			}
+			// Notes are published on their day; the feed dates them at midnight UTC
+			day, _ := time.Parse("2006-01-02", note.Date)
+			feed.Channel.Items = append(feed.Channel.Items, rssItem{
+				Title:       title,
+				Link:        link + "/" + note.Date,
+				GUID:        link + "/" + note.Date,
+				PubDate:     day.Format(time.RFC1123Z),
+				Description: note.HTML,
+			})
+		}
+
+		body, err := xml.MarshalIndent(feed, "", "  ")
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to build feed", err)
+		}
+
+		setRobots(c, published.Indexable)
+		c.Set(fiber.HeaderContentType, "application/rss+xml; charset=utf-8")
+		return c.Send(append([]byte(xml.Header), body...))
+	}
+}
+
+// setRobots tells search engines whether they may index a public page
+func setRobots(c *fiber.Ctx, indexable bool) {
+	if indexable {
+		c.Set("X-Robots-Tag", "index, follow")
+	} else {
+		c.Set("X-Robots-Tag", "noindex, nofollow")
+	}
+}
+
+// publicError answers a failed public page request: an HTML 404 for pages that
+// aren't published, a server error otherwise
+func publicError(c *fiber.Ctx, message string, err error) error {
+	if errors.Is(err, services.ErrPublicPageNotFound) {
+		c.Status(fiber.StatusNotFound)
+		return renderPage(c, pages.PublicNotFound())
+	}
+	return serverErrorWithDetails(c, message, err)
+}
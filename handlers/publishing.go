@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPublishTargets lists the external blogs the user publishes notes to
+func GetPublishTargets(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := middleware.GetUserID(c)
+
+		targets, err := a.PublishService.Targets(c.Context(), userID)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch publish targets", err)
+		}
+
+		return success(c, fiber.Map{"targets": targets})
+	}
+}
+
+// CreatePublishTarget adds a Ghost, WordPress or Hugo publish target
+func CreatePublishTarget(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.CreatePublishTargetRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		target, err := a.PublishService.CreateTarget(c.Context(), userID, req)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidPublishTarget) {
+				return badRequest(c, errors.Unwrap(err).Error())
+			}
+			return serverErrorWithDetails(c, "Failed to create publish target", err)
+		}
+
+		return success(c, fiber.Map{"target": target})
+	}
+}
+
+// DeletePublishTarget deletes a publish target; posts already published stay on the blog
+func DeletePublishTarget(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := middleware.GetUserID(c)
+
+		if err := a.PublishService.DeleteTarget(c.Context(), userID, c.Params("id")); err != nil {
+			if errors.Is(err, services.ErrPublishTargetNotFound) {
+				return badRequest(c, services.ErrPublishTargetNotFound.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to delete publish target", err)
+		}
+
+		return success(c, fiber.Map{"message": "Publish target deleted"})
+	}
+}
+
+// PublishNote queues a note for publishing to a target, now or at publish_at
+// Publishing a note again updates the post it created.
+func PublishNote(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.PublishNoteRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		publication, err := a.PublishService.Publish(c.Context(), userID, req)
+		if err != nil {
+			if target := matchError(err, services.ErrPublishTargetNotFound, services.ErrNoteNotFound, services.ErrPublishingUnavailable); target != nil {
+				return badRequest(c, target.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to publish note", err)
+		}
+
+		return success(c, fiber.Map{"publication": publication})
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPush returns whether the server sends web push notifications, and the
+// VAPID public key browsers subscribe with
+func GetPush(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return success(c, fiber.Map{"push": a.Push.Settings()})
+	}
+}
+
+// SubscribePush sends the user's notifications, such as reminders and notes
+// that stopped syncing, to the browser whose PushSubscription is posted
+func SubscribePush(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.PushSubscribeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		err := a.Push.Subscribe(c.Context(), middleware.GetUserID(c), &req)
+		switch {
+		case errors.Is(err, services.ErrPushDisabled):
+			return c.Status(fiber.StatusNotImplemented).JSON(fiber.Map{"error": services.ErrPushDisabled.Error()})
+		case errors.Is(err, services.ErrInvalidPushSubscription):
+			return badRequest(c, err.Error())
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to subscribe to push notifications", err)
+		}
+		return created(c, fiber.Map{"message": "Subscribed to push notifications"})
+	}
+}
+
+// UnsubscribePush stops sending the user's notifications to a browser
+func UnsubscribePush(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.PushUnsubscribeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		err := a.Push.Unsubscribe(c.Context(), middleware.GetUserID(c), req.Endpoint)
+		if errors.Is(err, services.ErrPushSubscriptionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Push subscription not found"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to unsubscribe from push notifications", err)
+		}
+		return success(c, fiber.Map{"message": "Unsubscribed from push notifications"})
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetReminders lists the user's reminders, soonest first, filtered by status
+// (pending, sent, missed or all)
+func GetReminders(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", 100)
+		offset := c.QueryInt("offset", 0)
+
+		reminders, err := a.Reminders.List(c.Context(), middleware.GetUserID(c), c.Query("status"), limit, offset)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidReminderStatus) {
+				return badRequest(c, services.ErrInvalidReminderStatus.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to fetch reminders", err)
+		}
+
+		return success(c, fiber.Map{
+			"reminders": reminders,
+			"enabled":   a.Reminders.Enabled(),
+			"limit":     limit,
+			"offset":    offset,
+		})
+	}
+}
+
+// CreateReminder reminds the user of a text at a local time in their timezone
+// setting, without writing it in a note
+func CreateReminder(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.CreateReminderRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		reminder, err := a.Reminders.Create(c.Context(), middleware.GetUserID(c), req.Text, req.RemindAt)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidReminderTime) {
+				return badRequest(c, services.ErrInvalidReminderTime.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to create reminder", err)
+		}
+		return created(c, fiber.Map{"reminder": reminder})
+	}
+}
+
+// DeleteReminder deletes a reminder made through the API; reminders written in
+// notes are deleted by removing them from the note
+func DeleteReminder(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid reminder ID")
+		}
+
+		err = a.Reminders.Delete(c.Context(), middleware.GetUserID(c), id)
+		switch {
+		case errors.Is(err, services.ErrReminderNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Reminder not found"})
+		case errors.Is(err, services.ErrReminderInNote):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": services.ErrReminderInNote.Error()})
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to delete reminder", err)
+		}
+		return success(c, fiber.Map{"message": "Reminder deleted"})
+	}
+}
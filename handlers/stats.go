@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetActivity returns the daily notes and words written each day from ?from= to
+// ?to=, for a contribution graph, with every context's current and longest
+// streak as of today in the user's timezone
+func GetActivity(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.AgendaRequest
+		if err := c.QueryParser(&req); err != nil {
+			return badRequest(c, "Invalid range parameters")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		activity, err := a.NoteService.Activity(c.Context(), middleware.GetUserID(c), req.From, req.To, userLocation(c))
+		if err != nil {
+			if errors.Is(err, services.ErrActivityRange) {
+				return badRequest(c, services.ErrActivityRange.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to fetch activity", err)
+		}
+
+		return success(c, fiber.Map{"activity": activity})
+	}
+}
+
+// GetWritingStats returns how much the user has written: totals with the average
+// words per note, words per weekday and the most active contexts
+func GetWritingStats(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		stats, err := a.NoteService.Writing(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to fetch writing stats", err)
+		}
+
+		return success(c, fiber.Map{"writing": stats})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"daily-notes/app"
+	"daily-notes/models"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	readinessTimeout = 2 * time.Second // Per check, so a hung database reads as degraded
+	badgeTTL         = time.Minute     // How long a badge is reused, by the server and by caches
+)
+
+// badgeSVG is a shields-style "status | ok" badge; the arguments are the width
+// of the value half, its color and its text, twice
+const badgeSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="status: %[3]s">` +
+	`<title>status: %[3]s</title>` +
+	`<rect width="46" height="20" fill="#555"/><rect x="46" width="%[4]d" height="20" fill="%[2]s"/>` +
+	`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` +
+	`<text x="23" y="14">status</text><text x="%[5]d" y="14">%[3]s</text></g></svg>`
+
+// StatusBadge renders an SVG badge saying whether the server is ok or degraded,
+// for READMEs and status pages of self-hosted instances. It is public, so it
+// tells nothing but that: GetDiagnostics has the failing checks.
+// The outcome is reused for badgeTTL, so requests never pile up on the checks.
+func StatusBadge(a *app.App) fiber.Handler {
+	var (
+		mu         sync.Mutex
+		badge      []byte
+		renderedAt time.Time
+	)
+	return func(c *fiber.Ctx) error {
+		mu.Lock()
+		if badge == nil || time.Since(renderedAt) >= badgeTTL {
+			badge, renderedAt = renderBadge(ready(readiness(c.Context(), a))), time.Now()
+		}
+		body := badge
+		mu.Unlock()
+
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+		c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'")
+		c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(badgeTTL.Seconds())))
+		return c.Send(body)
+	}
+}
+
+// renderBadge renders the status badge
+func renderBadge(ok bool) []byte {
+	if ok {
+		return fmt.Appendf(nil, badgeSVG, 46+30, "#4c1", "ok", 30, 46+15)
+	}
+	return fmt.Appendf(nil, badgeSVG, 46+62, "#dfb317", "degraded", 62, 46+31)
+}
+
+// readiness runs the checks of what the server needs to serve requests:
+// a reachable database and a writable attachments directory
+func readiness(ctx context.Context, a *app.App) []models.ReadinessCheck {
+	return []models.ReadinessCheck{
+		readinessCheck("database", func() error {
+			ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+			defer cancel()
+			return a.Repo.Ping(ctx)
+		}),
+		readinessCheck("attachments", func() error {
+			return checkWritable(a.Attachments.Dir())
+		}),
+	}
+}
+
+// ready reports whether every readiness check passed
+func ready(checks []models.ReadinessCheck) bool {
+	for _, check := range checks {
+		if !check.OK {
+			return false
+		}
+	}
+	return true
+}
+
+func readinessCheck(name string, check func() error) models.ReadinessCheck {
+	if err := check(); err != nil {
+		return models.ReadinessCheck{Name: name, Error: err.Error()}
+	}
+	return models.ReadinessCheck{Name: name, OK: true}
+}
+
+// checkWritable creates and removes a file in dir
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, ".ready-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,50 @@
+package handlers_test
+
+import (
+	"daily-notes/handlers"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatusBadge tests the public badge of the server's readiness
+func TestStatusBadge(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+	application.Attachments.SetDir(t.TempDir())
+
+	badge := func(handler fiber.Handler) string {
+		t.Helper()
+		fiberApp := fiber.New()
+		fiberApp.Get("/status/badge.svg", handler)
+		resp, err := fiberApp.Test(httptest.NewRequest("GET", "/status/badge.svg", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "image/svg+xml", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "public, max-age=60", resp.Header.Get("Cache-Control"))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("Reads ok when every check passes", func(t *testing.T) {
+		assert.Contains(t, badge(handlers.StatusBadge(application)), "status: ok")
+	})
+
+	t.Run("Reads degraded without saying why", func(t *testing.T) {
+		// A file where the attachments directory should be
+		blocked := filepath.Join(t.TempDir(), "attachments")
+		require.NoError(t, os.WriteFile(blocked, nil, 0o644))
+		application.Attachments.SetDir(blocked)
+
+		body := badge(handlers.StatusBadge(application))
+		assert.Contains(t, body, "status: degraded")
+		assert.NotContains(t, body, "attachments")
+	})
+}
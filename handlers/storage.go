@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/config"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"daily-notes/storage"
+	"daily-notes/storage/dropbox"
+	"daily-notes/sync"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// dropboxStateCookie holds the OAuth state between the connect redirect and the callback
+const dropboxStateCookie = "dropbox_oauth_state"
+
+// GetStorage returns the user's storage provider and the providers they can switch to
+func GetStorage(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := middleware.GetUserID(c)
+
+		status, err := a.StorageService.Status(c.Context(), userID)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to load storage provider", err)
+		}
+
+		return success(c, fiber.Map{"storage": status})
+	}
+}
+
+// storedSettings fills in the storage provider a user's notes sync to and
+// whether they're end-to-end encrypted. Both live apart from the other
+// settings, so session copies of the settings don't carry them.
+func storedSettings(c *fiber.Ctx, a *app.App, userID string, settings models.UserSettings) models.UserSettings {
+	if provider, err := a.StorageService.Current(c.Context(), userID); err == nil {
+		settings.StorageProvider = provider
+	}
+	if enabled, err := a.Encryption.Enabled(c.Context(), userID); err == nil {
+		settings.Encryption = enabled
+	}
+	return settings
+}
+
+// UpdateStorage switches the user's notes to another storage provider
+func UpdateStorage(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.UpdateStorageProviderRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		userID := middleware.GetUserID(c)
+
+		queued, err := a.StorageService.Select(c.Context(), userID, req.Provider)
+		if err != nil {
+			if target := matchError(err, services.ErrStorageUnavailable, services.ErrStorageNotConnected); target != nil {
+				return badRequest(c, target.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to update storage provider", err)
+		}
+
+		return success(c, fiber.Map{
+			"provider":     req.Provider,
+			"notes_queued": queued,
+		})
+	}
+}
+
+// ConnectDropbox redirects the browser to Dropbox to authorize note storage
+func ConnectDropbox(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !a.StorageService.Available(storage.Dropbox) {
+			return badRequest(c, services.ErrStorageUnavailable.Error())
+		}
+
+		state := uuid.New().String()
+		c.Cookie(&fiber.Cookie{
+			Name:     dropboxStateCookie,
+			Value:    state,
+			Expires:  time.Now().Add(10 * time.Minute),
+			HTTPOnly: true,
+			Secure:   config.AppConfig.Env == "production",
+			SameSite: "Lax",
+			Path:     "/api/storage/dropbox",
+		})
+
+		return c.Redirect(dropbox.AuthCodeURL(state), fiber.StatusSeeOther)
+	}
+}
+
+// DropboxCallback stores the token Dropbox issued and returns to the app
+func DropboxCallback(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		state := c.Cookies(dropboxStateCookie)
+		c.ClearCookie(dropboxStateCookie)
+		if state == "" || c.Query("state") != state {
+			return badRequest(c, "Invalid OAuth state")
+		}
+
+		if c.Query("error") != "" {
+			// The user declined access; nothing to store
+			return c.Redirect("/", fiber.StatusSeeOther)
+		}
+
+		code := c.Query("code")
+		if code == "" {
+			return badRequest(c, "code is required")
+		}
+
+		token, err := dropbox.OAuthConfig().Exchange(c.Context(), code)
+		if err != nil {
+			a.Logger.Warn("dropbox code exchange failed", "error", err)
+			return badRequest(c, services.ErrInvalidAuthCode.Error())
+		}
+
+		userID := middleware.GetUserID(c)
+		if err := a.StorageService.Connect(c.Context(), userID, storage.Dropbox, token); err != nil {
+			return serverErrorWithDetails(c, "Failed to connect Dropbox", err)
+		}
+
+		return c.Redirect("/", fiber.StatusSeeOther)
+	}
+}
+
+// DisconnectDropbox forgets the user's Dropbox token, moving their notes back to Google Drive if needed
+func DisconnectDropbox(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := middleware.GetUserID(c)
+
+		if err := a.StorageService.Disconnect(c.Context(), userID, storage.Dropbox); err != nil {
+			return serverErrorWithDetails(c, "Failed to disconnect Dropbox", err)
+		}
+
+		status, err := a.StorageService.Status(c.Context(), userID)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to load storage provider", err)
+		}
+
+		return success(c, fiber.Map{"storage": status})
+	}
+}
+
+// DriveWebhook receives Drive's notifications for the change channels subscribed by
+// the sync worker, and pulls the changed notes of the channel's user right away
+func DriveWebhook(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Drive confirms every new channel with a "sync" message, even before it's saved
+		if c.Get("X-Goog-Resource-State") == "sync" {
+			return c.SendStatus(fiber.StatusOK)
+		}
+		if a.SyncWorker == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "Sync is not running"})
+		}
+
+		err := a.SyncWorker.NotifyChanges(c.Get("X-Goog-Channel-ID"), c.Get("X-Goog-Channel-Token"))
+		if errors.Is(err, sync.ErrUnknownChannel) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown channel"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to handle the change notification", err)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	}
+}
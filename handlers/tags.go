@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RenameTag queues rewriting a #tag as another in every note using it and
+// answers 202 with the job, whose progress GetTagJob reports
+func RenameTag(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.RenameTagRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		job, err := a.TagService.Rename(c.Context(), middleware.GetUserID(c), req.From, req.To)
+		if err != nil {
+			return tagJobError(c, err, "Failed to rename tag")
+		}
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": job})
+	}
+}
+
+// MergeTags queues rewriting several #tags as one in every note using them and
+// answers 202 with the job, whose progress GetTagJob reports
+func MergeTags(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req models.MergeTagsRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+		if err := a.Validator.Validate(&req); err != nil {
+			return validationError(c, err)
+		}
+
+		job, err := a.TagService.Merge(c.Context(), middleware.GetUserID(c), req.Tags, req.Into)
+		if err != nil {
+			return tagJobError(c, err, "Failed to merge tags")
+		}
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job": job})
+	}
+}
+
+// GetTagJob reports the progress of a tag rename or merge
+func GetTagJob(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		job, err := a.TagService.Job(middleware.GetUserID(c), c.Params("id"))
+		if errors.Is(err, services.ErrTagJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tag job not found"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to get tag job", err)
+		}
+		return success(c, fiber.Map{"job": job})
+	}
+}
+
+// tagJobError answers a rename or merge that couldn't be queued
+func tagJobError(c *fiber.Ctx, err error, message string) error {
+	if target := matchError(err, services.ErrInvalidTag, services.ErrSameTag); target != nil {
+		return badRequest(c, target.Error())
+	}
+	if errors.Is(err, services.ErrTagNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": services.ErrTagNotFound.Error()})
+	}
+	if errors.Is(err, services.ErrTagExists) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": services.ErrTagExists.Error()})
+	}
+	return serverErrorWithDetails(c, message, err)
+}
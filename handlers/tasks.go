@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetTasks lists the checkbox tasks of the user's notes, newest notes first,
+// filtered by status (open, done or all) and optionally by context
+func GetTasks(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", 100)
+		offset := c.QueryInt("offset", 0)
+
+		tasks, err := a.NoteService.Tasks(c.Context(), middleware.GetUserID(c), c.Query("status"), c.Query("context"), limit, offset)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidTaskStatus) {
+				return badRequest(c, services.ErrInvalidTaskStatus.Error())
+			}
+			return serverErrorWithDetails(c, "Failed to fetch tasks", err)
+		}
+
+		return success(c, fiber.Map{
+			"tasks":  tasks,
+			"limit":  limit,
+			"offset": offset,
+		})
+	}
+}
+
+// ToggleTask checks an open task or unchecks a done one, rewriting its line in
+// the note, and returns the task with the saved note
+func ToggleTask(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid task ID")
+		}
+
+		userID := middleware.GetUserID(c)
+		task, note, err := a.NoteService.ToggleTask(c.Context(), userID, id)
+		switch {
+		case errors.Is(err, services.ErrTaskNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Task not found"})
+		case errors.Is(err, services.ErrTaskChanged):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": services.ErrTaskChanged.Error()})
+		case err != nil:
+			return serverErrorWithDetails(c, "Failed to toggle task", err)
+		}
+
+		setNoteValidators(c, note)
+		return success(c, fiber.Map{
+			"task":        task,
+			"note":        note,
+			"sync_health": syncHealth(a, userID),
+		})
+	}
+}
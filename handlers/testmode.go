@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/config"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdvanceTimeRequest moves the test clock either forward by Duration or to the absolute time To
+type AdvanceTimeRequest struct {
+	Duration string `json:"duration"` // Go duration, e.g. "36h" or "15m"
+	To       string `json:"to"`       // RFC3339 timestamp
+}
+
+// TestAdvanceTime moves the fake clock used in test mode
+func TestAdvanceTime(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req AdvanceTimeRequest
+		if err := c.BodyParser(&req); err != nil {
+			return badRequest(c, "Invalid request body")
+		}
+
+		switch {
+		case req.To != "":
+			to, err := time.Parse(time.RFC3339, req.To)
+			if err != nil {
+				return badRequest(c, "to must be an RFC3339 timestamp")
+			}
+			a.TestClock.Set(to.UTC())
+		case req.Duration != "":
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d < 0 {
+				return badRequest(c, "duration must be a positive Go duration such as 36h")
+			}
+			a.TestClock.Advance(d)
+		default:
+			return badRequest(c, "duration or to is required")
+		}
+
+		now := a.TestClock.Now()
+		return success(c, fiber.Map{
+			"timestamp": now.Unix(),
+			"iso":       now.Format(time.RFC3339),
+		})
+	}
+}
+
+// TestLogin signs in as the seeded demo user without going through Google.
+// Query: user_id signs in as another of users, the ones seeded from the
+// SEED_FILE fixture; the first is the default.
+func TestLogin(a *app.App, users []string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := c.Query("user_id", users[0])
+		if !slices.Contains(users, userID) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only seeded users can sign in"})
+		}
+		user, err := a.Repo.GetUser(c.Context(), userID)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to load user", err)
+		}
+		if user == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not seeded"})
+		}
+
+		// The fake access token never expires so no refresh against Google is attempted
+		tokenExpiry := a.Clock.Now().AddDate(100, 0, 0)
+		sess, err := a.SessionStore.Create(user.ID, user.Email, user.Name, user.Picture,
+			"test-access-token", "", tokenExpiry, user.Settings)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to create session", err)
+		}
+
+		// Browser cookie without Expires: the fake clock may lag real time, expiry is enforced by the store
+		c.Cookie(&fiber.Cookie{
+			Name:     "session_id",
+			Value:    sess.ID,
+			HTTPOnly: true,
+			Secure:   config.AppConfig.Env == "production",
+			SameSite: "Lax",
+			Path:     "/",
+		})
+
+		return success(c, fiber.Map{
+			"success": true,
+			"user": fiber.Map{
+				"id":    user.ID,
+				"email": user.Email,
+				"name":  user.Name,
+			},
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handlers_test
+
+import (
+	"context"
+	"daily-notes/config"
+	"daily-notes/handlers"
+	"daily-notes/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestLogin(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{Env: "development"}
+	defer func() { config.AppConfig = previous }()
+
+	require.NoError(t, application.Repo.UpsertUser(context.Background(), &models.User{
+		ID: "real-user", GoogleID: "real-google-id", Email: "real@example.com", CreatedAt: time.Now(),
+	}))
+
+	fiberApp := fiber.New()
+	fiberApp.Post("/api/test/login", handlers.TestLogin(application, []string{"test-user-id"}))
+	login := func(query string) *http.Response {
+		resp, err := fiberApp.Test(httptest.NewRequest(http.MethodPost, "/api/test/login"+query, nil), -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := login("")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, resp.Cookies(), "the session cookie is set")
+
+	resp = login("?user_id=real-user")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "accounts not seeded from the fixture can't be signed in")
+	assert.Empty(t, resp.Cookies())
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/middleware"
+	"daily-notes/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetTimezoneReview returns the latest timezone change the user hasn't reviewed,
+// with the notes whose date may be off by one and the day statistics before and
+// after it; review is null when there is nothing to review
+func GetTimezoneReview(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		review, err := a.Timezones.Review(c.Context(), middleware.GetUserID(c))
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to review timezone change", err)
+		}
+		return success(c, fiber.Map{"review": review})
+	}
+}
+
+// DismissTimezoneChange marks a timezone change, and the ones before it, as reviewed
+func DismissTimezoneChange(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil || id < 1 {
+			return badRequest(c, "Invalid timezone change ID")
+		}
+
+		err = a.Timezones.Dismiss(c.Context(), middleware.GetUserID(c), id)
+		if errors.Is(err, services.ErrTimezoneChangeNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Timezone change not found"})
+		}
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to dismiss timezone change", err)
+		}
+		return success(c, fiber.Map{"dismissed": id})
+	}
+}
+
+// recordTimezoneChange queues a change of the timezone setting for review; the
+// settings are saved either way, so a failure is only logged
+func recordTimezoneChange(c *fiber.Ctx, a *app.App, userID, from, to string) {
+	if err := a.Timezones.RecordChange(c.Context(), userID, from, to); err != nil {
+		a.Logger.Warn("failed to record timezone change", "user_id", userID, "error", err)
+	}
+}
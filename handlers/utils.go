@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"daily-notes/app"
+	"daily-notes/models"
+	"daily-notes/services"
 	"daily-notes/validator"
+	"errors"
 	"log/slog"
 
 	"github.com/gofiber/fiber/v2"
@@ -24,6 +28,22 @@ func serverError(c *fiber.Ctx, message string) error {
 }
 
 func serverErrorWithDetails(c *fiber.Ctx, message string, err error) error {
+	// The note service refuses API tokens outside their scope, as the auth middleware does
+	if errors.Is(err, services.ErrTokenScope) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": services.ErrTokenScope.Error()})
+	}
+	// and members of shared contexts beyond their role, on any note operation
+	if errors.Is(err, services.ErrContextReadOnly) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": services.ErrContextReadOnly.Error()})
+	}
+	if errors.Is(err, services.ErrSharedContextMove) {
+		return badRequest(c, services.ErrSharedContextMove.Error())
+	}
+	// Saves of content the owner's end-to-end encryption setting doesn't allow
+	if target := matchError(err, services.ErrPlaintextNote, services.ErrInvalidEncryptedNote, services.ErrEncryptionKeyMismatch, services.ErrEncryptionNotEnabled, services.ErrNoteEncrypted); target != nil {
+		return badRequest(c, target.Error())
+	}
+
 	requestID := ""
 	if id, ok := c.Locals("requestID").(string); ok {
 		requestID = id
@@ -40,9 +60,21 @@ func serverErrorWithDetails(c *fiber.Ctx, message string, err error) error {
 	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": message})
 }
 
+// matchError returns the first of targets that err wraps, nil if none
+// Clients get the message of the matched sentinel, not the wrapped error's.
+func matchError(err error, targets ...error) error {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return target
+		}
+	}
+	return nil
+}
+
 // validationError returns a validation error response
 func validationError(c *fiber.Ctx, err error) error {
-	if validationErrs, ok := err.(validator.ValidationErrors); ok {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":  "Validation failed",
 			"errors": validationErrs,
@@ -52,3 +84,12 @@ func validationError(c *fiber.Ctx, err error) error {
 		"error": err.Error(),
 	})
 }
+
+// syncHealth returns the user's sync health for offline banners
+// Reported as ok when no sync worker is running
+func syncHealth(a *app.App, userID string) models.SyncHealth {
+	if a.SyncWorker == nil {
+		return models.SyncHealth{State: models.SyncHealthOK}
+	}
+	return a.SyncWorker.Health(userID)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"daily-notes/models"
+	"daily-notes/pkg/buildinfo"
+	"runtime"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetVersion returns the server's version, commit and build date, and whether a
+// newer release exists when update checks are enabled
+func GetVersion(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		response := fiber.Map{"build": buildinfo.Get()}
+		if a.Updates != nil {
+			response["update"] = a.Updates.Check(c.Context())
+		}
+		return success(c, response)
+	}
+}
+
+// GetDiagnostics describes the running server for support
+// Requires the X-Support-Token header to match SUPPORT_TOKEN.
+func GetDiagnostics(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !supportAuthorized(c) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid support token"})
+		}
+
+		diagnostics := models.Diagnostics{
+			Build:         buildinfo.Get(),
+			StartedAt:     a.StartedAt,
+			UptimeSeconds: int64(time.Since(a.StartedAt).Seconds()),
+			Goroutines:    runtime.NumGoroutine(),
+			Checks:        readiness(c.Context(), a),
+		}
+		if a.Updates != nil {
+			update := a.Updates.Check(c.Context())
+			diagnostics.Update = &update
+		}
+		return success(c, fiber.Map{"diagnostics": diagnostics})
+	}
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"daily-notes/app"
 	"daily-notes/config"
+	"daily-notes/middleware"
 	"daily-notes/pkg/audio"
 	"daily-notes/pkg/transcriber"
 	"daily-notes/templates/pages"
@@ -55,6 +57,9 @@ type TranscribeAudioResponse struct {
 	ProcessID string  `json:"process_id"`
 }
 
+// defaultTranscriptionLanguage se usa cuando ni la request ni el contexto indican idioma
+const defaultTranscriptionLanguage = "es"
+
 var (
 	localTranscriberInstance *transcriber.LocalTranscriber
 	transcriberError         error
@@ -102,14 +107,34 @@ func initLocalTranscriber() (*transcriber.LocalTranscriber, error) {
 }
 
 // TranscribeAudio procesa audio y retorna transcripción
-func TranscribeAudio(c *fiber.Ctx) error {
-	logger := slog.Default()
+// El idioma sale del query param language, si no del contexto indicado en context.
+func TranscribeAudio(a *app.App) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		language, err := transcriptionLanguage(c, a)
+		if err != nil {
+			return serverErrorWithDetails(c, "Failed to get context language", err)
+		}
+		return transcribeAudio(c, language)
+	}
+}
 
-	// Obtener idioma del query param o form
-	language := c.Query("language", "es")
-	if language == "" {
-		language = "es"
+// transcriptionLanguage elige el idioma de una transcripción: el pedido, el del contexto o el por defecto
+func transcriptionLanguage(c *fiber.Ctx, a *app.App) (string, error) {
+	if language := c.Query("language"); language != "" {
+		return language, nil
 	}
+	if contextName := c.Query("context"); contextName != "" {
+		language, err := a.ContextService.Language(c.Context(), middleware.GetUserID(c), contextName)
+		if err != nil || language != "" {
+			return language, err
+		}
+	}
+	return defaultTranscriptionLanguage, nil
+}
+
+// transcribeAudio transcribe el archivo de audio de la request en el idioma dado
+func transcribeAudio(c *fiber.Ctx, language string) error {
+	logger := slog.Default()
 
 	logger.Info("Received transcription request", "language", language)
 
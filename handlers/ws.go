@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"daily-notes/app"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// noteUpdatesPing is how often an idle connection is pinged, so proxies keep
+	// it open and connections of clients that went away end
+	noteUpdatesPing = 25 * time.Second
+
+	// noteUpdatesPongWait is how long a client may go without answering a ping
+	noteUpdatesPongWait = 2 * noteUpdatesPing
+
+	// noteUpdatesWriteWait is how long sending one message may take
+	noteUpdatesWriteWait = 10 * time.Second
+)
+
+// NoteUpdates streams the changes of the user's notes over a WebSocket, one
+// models.NoteEvent as JSON per message, so an edit made on one device shows up
+// on the others. Changes come from the API and from storage (imports and pulls).
+// Messages from clients are ignored. Events published while a client is
+// disconnected are not replayed; clients reload the notes they show after reconnecting.
+func NoteUpdates(a *app.App) fiber.Handler {
+	stream := websocket.New(func(conn *websocket.Conn) {
+		userID, _ := conn.Locals("userID").(string)
+		events, unsubscribe := a.NoteEvents.Subscribe(userID)
+		defer unsubscribe()
+
+		// Reading notices clients that close the connection and handles their pongs
+		closed := make(chan struct{})
+		conn.SetReadDeadline(time.Now().Add(noteUpdatesPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(noteUpdatesPongWait))
+		})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		// The connection goes back to a pool on return, so the reader must be done
+		defer func() {
+			conn.Close()
+			<-closed
+		}()
+
+		ping := time.NewTicker(noteUpdatesPing)
+		defer ping.Stop()
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok { // The server is shutting down
+					conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+						time.Now().Add(noteUpdatesWriteWait))
+					return
+				}
+				conn.SetWriteDeadline(time.Now().Add(noteUpdatesWriteWait))
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(noteUpdatesWriteWait)); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	})
+
+	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{"error": "WebSocket upgrade required"})
+		}
+		// Browsers send the session cookie from any site, so only our own pages may connect
+		if !sameOrigin(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Cross-origin connection not allowed"})
+		}
+		return stream(c)
+	}
+}
+
+// sameOrigin reports whether a request comes from a page of this host; requests
+// without an Origin header, such as those of scripts, are not from browsers
+func sameOrigin(c *fiber.Ctx) bool {
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == string(c.Request().Host())
+}
@@ -0,0 +1,72 @@
+package handlers_test
+
+import (
+	"context"
+	"daily-notes/handlers"
+	"daily-notes/models"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNoteUpdates tests the WebSocket streaming note changes to the user's clients
+func TestNoteUpdates(t *testing.T) {
+	application, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	fiberApp := setupTestApp()
+	fiberApp.Get("/ws", handlers.NoteUpdates(application))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go fiberApp.Listener(ln)
+	defer fiberApp.Shutdown()
+	url := "ws://" + ln.Addr().String() + "/ws"
+
+	t.Run("Streams the user's note changes", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"http://" + ln.Addr().String()}})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// Subscribed once the upgrade is done
+		require.Eventually(t, func() bool {
+			return application.NoteEvents.Subscribers("test-user-id") == 1
+		}, time.Second, 10*time.Millisecond)
+
+		require.NoError(t, application.NoteService.Delete(context.Background(), "test-user-id", "Work", "2025-10-16"))
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var event models.NoteEvent
+		require.NoError(t, conn.ReadJSON(&event))
+		assert.Equal(t, models.NoteEventDeleted, event.Type)
+		assert.Equal(t, "test-user-id-Work-2025-10-16", event.NoteID)
+		assert.Equal(t, "Work", event.Context)
+		assert.Equal(t, "2025-10-16", event.Date)
+		assert.Equal(t, models.NoteSourceAPI, event.Source)
+	})
+
+	t.Run("Closing the connection ends the subscription", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			return application.NoteEvents.Subscribers("test-user-id") == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Rejects other sites", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example"}})
+		require.Error(t, err)
+		require.NotNil(t, resp)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Requires an upgrade", func(t *testing.T) {
+		resp, err := fiberApp.Test(httptest.NewRequest(http.MethodGet, "/ws", nil), -1)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUpgradeRequired, resp.StatusCode)
+	})
+}
@@ -4,14 +4,28 @@ import (
 	"context"
 	"daily-notes/config"
 	"daily-notes/config/setup"
+	"daily-notes/database"
+	"daily-notes/pkg/backup"
+	"daily-notes/pkg/buildinfo"
+	"daily-notes/pkg/webpush"
+	"daily-notes/storage"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
 
 func main() {
+	// "daily-notes vapid-keys" prints a new key pair for web push notifications
+	// and exits; it runs before the configuration is loaded, which it doesn't need
+	if len(os.Args) > 1 && os.Args[1] == "vapid-keys" {
+		os.Exit(runVAPIDKeys())
+	}
+
 	// Load configuration
 	config.Load()
 
@@ -19,15 +33,48 @@ func main() {
 	logger := setupLogger()
 	slog.SetDefault(logger)
 
-	// Initialize database
 	dbPath := config.GetEnv("DB_PATH", "./data/daily-notes.db")
-	db, err := setup.InitDatabase(dbPath, logger)
+	dialect, err := database.ParseDialect(config.AppConfig.DBDriver)
+	if err != nil {
+		logger.Error("invalid DB_DRIVER", "error", err)
+		os.Exit(1)
+	}
+	dsn := config.AppConfig.DBDSN
+	if dsn == "" {
+		dsn = dbPath
+	}
+
+	// "daily-notes restore-backup <backup>" replaces the database and exits; it runs
+	// before the database is opened, so the server must be stopped
+	if len(os.Args) > 1 && os.Args[1] == "restore-backup" {
+		if dialect != database.SQLite {
+			logger.Error("restore-backup restores SQLite databases only", "driver", dialect)
+			os.Exit(1)
+		}
+		os.Exit(runRestoreBackup(dsn, logger, os.Args[2:]))
+	}
+
+	// "daily-notes migrate-down <version>" reverts schema migrations and exits; run it
+	// with the newer binary before going back to an older one
+	if len(os.Args) > 1 && os.Args[1] == "migrate-down" {
+		os.Exit(runMigrateDown(dialect, dsn, logger, os.Args[2:]))
+	}
+
+	// Initialize database
+	db, err := setup.InitDatabase(dialect, dsn, logger)
 	if err != nil {
 		logger.Error("failed to initialize database", "error", err)
 		os.Exit(1)
 	}
 	defer db.Close()
 
+	// "daily-notes migrate-filenames" renames stored note files and exits without serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate-filenames" {
+		code := runMigrateFilenames(db, logger, os.Args[2:])
+		db.Close()
+		os.Exit(code)
+	}
+
 	// Initialize application with all dependencies
 	application := setup.InitApp(db, logger)
 
@@ -41,7 +88,7 @@ func main() {
 	setup.RegisterRoutes(fiberApp, application)
 
 	// Start server
-	logger.Info("starting server", "port", config.AppConfig.Port, "env", config.AppConfig.Env)
+	logger.Info("starting server", "port", config.AppConfig.Port, "env", config.AppConfig.Env, "version", buildinfo.Version)
 
 	go func() {
 		if err := fiberApp.Listen(":" + config.AppConfig.Port); err != nil {
@@ -58,7 +105,7 @@ func main() {
 	logger.Info("shutting down server gracefully")
 
 	// Shutdown services
-	setup.Shutdown(application.SyncWorker, db, logger)
+	setup.Shutdown(application, db, logger)
 
 	// Shutdown Fiber server
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -71,6 +118,101 @@ func main() {
 	logger.Info("server stopped")
 }
 
+// runVAPIDKeys prints a new VAPID key pair in the form of the environment variables it goes in
+func runVAPIDKeys() int {
+	public, private, err := webpush.GenerateKeys()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to generate VAPID keys:", err)
+		return 1
+	}
+	fmt.Printf("VAPID_PUBLIC_KEY=%s\nVAPID_PRIVATE_KEY=%s\n", public, private)
+	return 0
+}
+
+// runMigrateFilenames renames every user's note files to NOTE_FILENAME_PATTERN
+func runMigrateFilenames(db *database.DB, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("migrate-filenames", flag.ExitOnError)
+	userID := flags.String("user", "", "migrate only this user ID")
+	dryRun := flags.Bool("dry-run", false, "log the planned renames without applying them")
+	flags.Parse(args)
+
+	if err := storage.SetFilenamePattern(config.AppConfig.NoteFilenamePattern); err != nil {
+		logger.Error("invalid NOTE_FILENAME_PATTERN", "error", err)
+		return 1
+	}
+
+	opts := setup.MigrateFilenamesOptions{UserID: *userID, DryRun: *dryRun}
+	if err := setup.MigrateFilenames(context.Background(), db, logger, opts); err != nil {
+		logger.Error("filename migration incomplete", "error", err)
+		return 1
+	}
+	return 0
+}
+
+// runRestoreBackup replaces the database with a backup, given as a path or the name of one in BACKUP_DIR
+func runRestoreBackup(dbPath string, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("restore-backup", flag.ExitOnError)
+	list := flags.Bool("list", false, "list the backups in BACKUP_DIR instead of restoring one")
+	flags.Parse(args)
+
+	dir := config.AppConfig.BackupDir
+	if *list {
+		backups, err := backup.List(dir)
+		if err != nil {
+			logger.Error("failed to list backups", "dir", dir, "error", err)
+			return 1
+		}
+		for _, b := range backups {
+			fmt.Printf("%s\t%s\t%d bytes\n", b.Name, b.CreatedAt.Format(time.RFC3339), b.Size)
+		}
+		return 0
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: daily-notes restore-backup [-list] <backup file or name>")
+		return 2
+	}
+
+	path := backup.Resolve(dir, flags.Arg(0))
+	if err := database.Restore(path, dbPath); err != nil {
+		logger.Error("restore failed", "backup", path, "error", err)
+		return 1
+	}
+	logger.Info("database restored", "backup", path, "db", dbPath, "previous", dbPath+".pre-restore")
+	return 0
+}
+
+// runMigrateDown reverts the schema migrations above a version
+func runMigrateDown(dialect database.Dialect, dsn string, logger *slog.Logger, args []string) int {
+	flags := flag.NewFlagSet("migrate-down", flag.ExitOnError)
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: daily-notes migrate-down <version>")
+		return 2
+	}
+	target, err := strconv.Atoi(flags.Arg(0))
+	if err != nil || target < 0 {
+		fmt.Fprintln(os.Stderr, "version must be a migration number, 0 to empty the database")
+		return 2
+	}
+
+	db, err := database.Open(dialect, dsn)
+	if err != nil {
+		logger.Error("failed to open database", "error", err)
+		return 1
+	}
+	defer db.Close()
+
+	version, err := db.MigrateDown(target)
+	if err != nil {
+		logger.Error("migrate-down failed", "version", version, "error", err)
+		return 1
+	}
+	logger.Info("schema migrated down", "version", version)
+	return 0
+}
+
 func setupLogger() *slog.Logger {
 	var handler slog.Handler
 
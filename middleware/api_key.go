@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"daily-notes/services"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiKeyAuth signs a request in with an API key, as the key's user, for the
+// routes and methods its scope allows (see services.KeyAllows)
+func apiKeyAuth(c *fiber.Ctx, apiKeys APIKeyAuthenticator, secret string) error {
+	key, err := apiKeys.Authenticate(c.Context(), secret)
+	if err != nil {
+		if !errors.Is(err, services.ErrInvalidAPIKey) {
+			log.Printf("[AUTH] API key lookup failed: %v", err)
+		}
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or revoked API key",
+		})
+	}
+
+	if !services.KeyAllows(key, c.Method(), c.Path()) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": services.ErrKeyScope.Error(),
+		})
+	}
+
+	c.Locals("userID", key.UserID)
+	return c.Next()
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"daily-notes/pkg/audit"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// auditedPrefixes are the endpoints recorded in debug mode: note reads/writes and sync
+var auditedPrefixes = []string{"/api/notes", "/api/sync"}
+
+// auditedHeaders help tell concurrent edits and client retries apart
+var auditedHeaders = []string{fiber.HeaderIfMatch, "X-Idempotency-Key", fiber.HeaderUserAgent}
+
+// Audit records note and sync requests of users who enabled debug mode
+// Must run after AuthRequired so the user is known.
+func Audit(log *audit.Log) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID := GetUserID(c)
+		if _, active := log.Active(userID); !active || !audited(c.Path()) {
+			return c.Next()
+		}
+
+		start := log.Now()
+		request := audit.Sanitize(c.Body())
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			status = fiberErr.Code
+		}
+
+		headers := map[string]string{}
+		for _, name := range auditedHeaders {
+			if value := c.Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		if etag := string(c.Response().Header.Peek(fiber.HeaderETag)); etag != "" {
+			headers["Response-"+fiber.HeaderETag] = etag
+		}
+
+		requestID, _ := c.Locals("requestID").(string)
+		log.Record(userID, audit.Entry{
+			Time:       start,
+			RequestID:  requestID,
+			Method:     c.Method(),
+			Path:       c.Path(),
+			Query:      string(c.Request().URI().QueryString()),
+			Headers:    headers,
+			Status:     status,
+			DurationMS: log.Now().Sub(start).Milliseconds(),
+			Request:    request,
+			Response:   audit.Sanitize(c.Response().Body()),
+		})
+
+		return err
+	}
+}
+
+func audited(path string) bool {
+	for _, prefix := range auditedPrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"daily-notes/config"
 	"daily-notes/models"
+	"daily-notes/services"
 	"daily-notes/session"
 	"log"
 	"strings"
@@ -17,9 +18,21 @@ type TokenRefresher interface {
 	RefreshTokenIfNeeded(session *models.Session) (interface{}, error)
 }
 
+// APITokenAuthenticator resolves the secret of an API token to the token
+type APITokenAuthenticator interface {
+	Authenticate(ctx context.Context, secret string) (*models.APIToken, error)
+}
+
+// APIKeyAuthenticator resolves the secret of an API key to the key
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, secret string) (*models.APIKey, error)
+}
+
 // AuthRequired creates an authentication middleware that requires a valid session or Bearer token
-// If a tokenRefresher is provided, it will automatically refresh expired tokens
-func AuthRequired(sessionStore *session.Store, tokenRefresher TokenRefresher) fiber.Handler {
+// If a tokenRefresher is provided, it will automatically refresh expired tokens. If apiTokens
+// is provided, Bearer tokens may also be API tokens, limited to the routes apiTokenRoute allows,
+// and if apiKeys is provided they may be API keys, limited by their scope.
+func AuthRequired(sessionStore *session.Store, tokenRefresher TokenRefresher, apiTokens APITokenAuthenticator, apiKeys APIKeyAuthenticator) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		sessionID := c.Cookies("session_id")
 		if sessionID != "" {
@@ -58,6 +71,13 @@ func AuthRequired(sessionStore *session.Store, tokenRefresher TokenRefresher) fi
 
 		token := parts[1]
 
+		if apiKeys != nil && strings.HasPrefix(token, services.APIKeyPrefix) {
+			return apiKeyAuth(c, apiKeys, token)
+		}
+		if apiTokens != nil && strings.HasPrefix(token, services.APITokenPrefix) {
+			return apiTokenAuth(c, apiTokens, token)
+		}
+
 		payload, err := idtoken.Validate(context.Background(), token, config.AppConfig.GoogleClientID)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"encoding/hex"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IdempotencyHeader carries the key clients send with writes they may retry
+const IdempotencyHeader = "X-Idempotency-Key"
+
+// IdempotencyLifetime is how long a response is replayed for its key
+const IdempotencyLifetime = 30 * time.Minute
+
+// maxIdempotencyKey caps the length of keys, which are usually UUIDs
+const maxIdempotencyKey = 255
+
+// IdempotencyStore keeps the responses Idempotency replays
+type IdempotencyStore interface {
+	GetIdempotentResponse(ctx context.Context, userID, method, path, key string, since time.Time) (*models.IdempotentResponse, error)
+	SaveIdempotentResponse(ctx context.Context, response *models.IdempotentResponse, expired time.Time) error
+}
+
+// Idempotency replays the stored response when a user retries a write with the
+// same X-Idempotency-Key. Keys belong to the user, method and path they were
+// sent with; reusing one for another body is refused with 422. Only successful
+// responses are stored, so failed writes can be retried with the same key.
+// Must run after AuthRequired so the user is known.
+func Idempotency(store IdempotencyStore, clk clock.Clock) fiber.Handler {
+	var mu sync.Mutex
+	inFlight := make(map[string]bool)
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKey {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": IdempotencyHeader + " is too long",
+			})
+		}
+
+		userID, method, path := GetUserID(c), c.Method(), c.Path()
+		hash := sha256.Sum256(c.Body())
+		requestHash := hex.EncodeToString(hash[:])
+
+		scoped := strings.Join([]string{userID, method, path, key}, "\x00")
+		mu.Lock()
+		if inFlight[scoped] {
+			mu.Unlock()
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "A request with this " + IdempotencyHeader + " is still in progress",
+			})
+		}
+		inFlight[scoped] = true
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			delete(inFlight, scoped)
+			mu.Unlock()
+		}()
+
+		stored, err := store.GetIdempotentResponse(c.Context(), userID, method, path, key, clk.Now().Add(-IdempotencyLifetime))
+		if err != nil {
+			log.Printf("[IDEMPOTENCY] Lookup failed: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to check " + IdempotencyHeader,
+			})
+		}
+		if stored != nil {
+			if stored.RequestHash != requestHash {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+					"error": IdempotencyHeader + " was already used for another request",
+				})
+			}
+			if stored.ContentType != "" {
+				c.Set(fiber.HeaderContentType, stored.ContentType)
+			}
+			return c.Status(stored.Status).Send(stored.Body)
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status < 200 || status > 299 || c.Response().IsBodyStream() {
+			return nil
+		}
+		now := clk.Now()
+		response := &models.IdempotentResponse{
+			UserID:      userID,
+			Method:      method,
+			Path:        path,
+			Key:         key,
+			RequestHash: requestHash,
+			Status:      status,
+			ContentType: string(c.Response().Header.ContentType()),
+			Body:        append([]byte(nil), c.Response().Body()...),
+			CreatedAt:   now,
+		}
+		if err := store.SaveIdempotentResponse(c.Context(), response, now.Add(-IdempotencyLifetime)); err != nil {
+			log.Printf("[IDEMPOTENCY] Failed to store response: %v", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"daily-notes/database"
+	"daily-notes/middleware"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Migrate())
+	repo := database.NewRepository(db)
+	for _, id := range []string{"ana", "bob"} {
+		require.NoError(t, repo.UpsertUser(context.Background(), &models.User{ID: id, GoogleID: "google-" + id, Email: id + "@example.com", CreatedAt: time.Now()}))
+	}
+
+	now := clock.NewFake(time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC))
+	calls := 0
+	fiberApp := fiber.New()
+	// Requests are made as the user of the X-User header
+	fiberApp.Use(func(c *fiber.Ctx) error {
+		c.Locals("userID", c.Get("X-User"))
+		return c.Next()
+	})
+	fiberApp.Use(middleware.Idempotency(repo, now))
+	fiberApp.Post("/notes", func(c *fiber.Ctx) error {
+		calls++
+		if c.Query("fail") != "" {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "try again"})
+		}
+		return c.JSON(fiber.Map{"user": c.Locals("userID"), "call": calls})
+	})
+
+	send := func(user, path, key, body string) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("X-User", user)
+		req.Header.Set(middleware.IdempotencyHeader, key)
+		resp, err := fiberApp.Test(req, -1)
+		require.NoError(t, err)
+		payload, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(payload)
+	}
+
+	t.Run("Replays retries of the same user", func(t *testing.T) {
+		status, first := send("ana", "/notes", "key-1", `{"content":"plans"}`)
+		require.Equal(t, http.StatusOK, status)
+		status, retry := send("ana", "/notes", "key-1", `{"content":"plans"}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, first, retry)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Keeps users with the same key apart", func(t *testing.T) {
+		status, body := send("bob", "/notes", "key-1", `{"content":"plans"}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, `"user":"bob"`, "another user's response isn't replayed")
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Refuses the key for another body", func(t *testing.T) {
+		status, _ := send("ana", "/notes", "key-1", `{"content":"other"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Doesn't store failed responses", func(t *testing.T) {
+		status, _ := send("ana", "/notes?fail=1", "key-2", `{}`)
+		require.Equal(t, http.StatusServiceUnavailable, status)
+		status, _ = send("ana", "/notes?fail=1", "key-2", `{}`)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+		assert.Equal(t, 4, calls, "retries of failed writes run again")
+	})
+
+	t.Run("Forgets responses after their lifetime", func(t *testing.T) {
+		now.Advance(middleware.IdempotencyLifetime + time.Minute)
+		status, _ := send("ana", "/notes", "key-1", `{"content":"other"}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 5, calls)
+	})
+}
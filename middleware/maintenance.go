@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"daily-notes/pkg/maintenance"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Maintenance answers writes with 503 and Retry-After while the server is in
+// maintenance mode. Reads are served as usual, as are the admin routes that end
+// maintenance. Note saves go through when they are journaled; the handler
+// journals them instead of saving.
+func Maintenance(mode *maintenance.Mode) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if mode == nil {
+			return c.Next()
+		}
+		active, retryAfter := mode.Active()
+		if !active {
+			return c.Next()
+		}
+
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		if strings.HasPrefix(c.Path(), "/api/admin/") {
+			return c.Next()
+		}
+		if c.Method() == fiber.MethodPost && isNoteSave(c.Path()) && mode.Journaling() {
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())))
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":       "Down for maintenance, changes can't be saved right now",
+			"maintenance": true,
+		})
+	}
+}
+
+// isNoteSave reports whether a POST to path saves a note, which maintenance
+// journals rather than refuses
+func isNoteSave(path string) bool {
+	return path == "/api/notes" || path == "/api/notes/append"
+}
@@ -11,6 +11,100 @@ const (
 	SyncStatusSynced     SyncStatus = "synced"      // Successfully synced
 	SyncStatusFailed     SyncStatus = "failed"      // Sync failed (will retry)
 	SyncStatusAbandoned  SyncStatus = "abandoned"   // Too many failures, stopped retrying
+	SyncStatusConflict   SyncStatus = "conflict"    // Changed locally and in storage since the last sync, see NoteConflict
+	SyncStatusLocal      SyncStatus = "local"       // Local only: the note or its context is never synced to storage
+)
+
+// SyncErrorClass groups sync failures by cause; each class has its own retry schedule
+type SyncErrorClass string
+
+const (
+	SyncErrorAuth    SyncErrorClass = "auth"    // Sign-in expired or revoked, retried once the user signs in again
+	SyncErrorQuota   SyncErrorClass = "quota"   // Storage rate limit or space exhausted, retried after a long pause
+	SyncErrorNetwork SyncErrorClass = "network" // Storage unreachable or failing, retried with exponential backoff
+	SyncErrorPayload SyncErrorClass = "payload" // Storage rejected the note itself, retried rarely
+)
+
+// CountsAsRetry reports whether failures of the class count towards MaxSyncRetries
+// Auth and quota failures are problems of the account rather than of the note,
+// so they never get a note abandoned.
+func (c SyncErrorClass) CountsAsRetry() bool {
+	return c == SyncErrorNetwork || c == SyncErrorPayload
+}
+
+// SyncHealthState describes whether a user's notes are reaching cloud storage
+type SyncHealthState string
+
+const (
+	SyncHealthOK       SyncHealthState = "ok"       // Last sync attempt succeeded
+	SyncHealthDegraded SyncHealthState = "degraded" // Some notes failed, storage is reachable
+	SyncHealthOffline  SyncHealthState = "offline"  // Storage unreachable or sign-in required
+)
+
+// SyncHealth is the per-user sync state computed by the sync worker so clients
+// can show an offline banner without waiting for individual notes to fail
+type SyncHealth struct {
+	State        SyncHealthState `json:"state"`
+	Message      string          `json:"message,omitempty"`
+	Since        *time.Time      `json:"since,omitempty"`          // When the current state began
+	LastSyncedAt *time.Time      `json:"last_synced_at,omitempty"` // Last successful sync
+}
+
+// SyncEvent is a change of one note's sync state, published by the sync worker
+// and streamed to the user's clients by GET /api/sync/events
+type SyncEvent struct {
+	NoteID      string         `json:"note_id"`
+	Context     string         `json:"context"`
+	Date        string         `json:"date"`
+	Status      SyncStatus     `json:"status"`
+	Deleted     bool           `json:"deleted,omitempty"` // A deletion; synced means the file is gone from storage
+	Error       string         `json:"error,omitempty"`
+	ErrorClass  SyncErrorClass `json:"error_class,omitempty"`
+	NextRetryAt *time.Time     `json:"next_retry_at,omitempty"`
+	At          time.Time      `json:"at"`
+}
+
+// NoteEvent tells a user's open clients that one of their notes changed, so
+// other devices reload it; streamed by the /ws endpoint
+type NoteEvent struct {
+	Type    string    `json:"type"` // NoteEventUpdated or NoteEventDeleted
+	NoteID  string    `json:"note_id"`
+	Context string    `json:"context"`
+	Date    string    `json:"date"`
+	Source  string    `json:"source"` // NoteSourceAPI or NoteSourceSync
+	At      time.Time `json:"at"`
+}
+
+// Note event types and sources
+const (
+	NoteEventUpdated = "note.updated"
+	NoteEventDeleted = "note.deleted"
+
+	NoteSourceAPI  = "api"  // Saved through the API, e.g. on another device
+	NoteSourceSync = "sync" // Imported or pulled from storage
+)
+
+// SyncOperation is a change to a context's folder in storage that failed and
+// is retried by the sync worker, like the notes that failed to sync
+type SyncOperation struct {
+	ID          int64          `json:"id"`
+	UserID      string         `json:"user_id"`
+	Kind        string         `json:"kind"` // SyncOperationRename or SyncOperationDelete
+	ContextID   string         `json:"context_id"`
+	ContextName string         `json:"context_name"` // The folder's name in storage; the old name for renames
+	NewName     string         `json:"new_name,omitempty"`
+	Status      SyncStatus     `json:"status"` // failed, or abandoned after MaxSyncRetries
+	RetryCount  int            `json:"retry_count"`
+	Error       string         `json:"error,omitempty"`
+	ErrorClass  SyncErrorClass `json:"error_class,omitempty"`
+	NextRetryAt *time.Time     `json:"next_retry_at,omitempty"` // Due right away when empty
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// Kinds of sync operations
+const (
+	SyncOperationRename = "rename_context"
+	SyncOperationDelete = "delete_context"
 )
 
 const (
@@ -27,6 +121,9 @@ type UserSettings struct {
 	ShowBreadcrumb       bool   `json:"showBreadcrumb"`
 	ShowMarkdownEditor   bool   `json:"showMarkdownEditor"`
 	HideNewContextButton bool   `json:"hideNewContextButton"`
+	SuggestContext       bool   `json:"suggestContext"`             // Opt-in: pick a context for captures that don't specify one
+	StorageProvider      string `json:"storage_provider,omitempty"` // Where notes sync to; stored apart from the other settings
+	Encryption           bool   `json:"encryption"`                 // Notes are end-to-end encrypted, see EncryptionKey; set through /api/encryption
 }
 
 type User struct {
@@ -49,6 +146,45 @@ type UpdateSettingsRequest struct {
 	ShowBreadcrumb       bool   `json:"showBreadcrumb"`
 	ShowMarkdownEditor   bool   `json:"showMarkdownEditor"`
 	HideNewContextButton bool   `json:"hideNewContextButton"`
+	SuggestContext       bool   `json:"suggestContext"`
+	StorageProvider      string `json:"storage_provider" validate:"omitempty,oneof=drive dropbox local s3 webdav"` // Empty keeps the current provider
+}
+
+// TimezoneChange is a change of the timezone setting the user hasn't reviewed yet
+// Notes are dated by the user's "today", so notes written around the change may
+// be off by a day, and streaks shift.
+type TimezoneChange struct {
+	ID        int64     `json:"id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// DayStats are the statistics derived from which days have notes, as seen from a timezone
+type DayStats struct {
+	Timezone      string `json:"timezone"`
+	Today         string `json:"today"`
+	DaysWritten   int    `json:"days_written"`
+	CurrentStreak int    `json:"current_streak"`
+	LongestStreak int    `json:"longest_streak"`
+}
+
+// TimezoneSuspect is a daily note whose date may be off by one after a timezone change:
+// it was written on Date in the old timezone, which was SuggestedDate in the new one
+type TimezoneSuspect struct {
+	Context       string    `json:"context"`
+	Date          string    `json:"date"`
+	SuggestedDate string    `json:"suggested_date"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TimezoneReview helps the user check their notes after a timezone change
+// Before and After are the day statistics in the old and the new timezone.
+type TimezoneReview struct {
+	Change   TimezoneChange    `json:"change"`
+	Before   DayStats          `json:"before"`
+	After    DayStats          `json:"after"`
+	Suspects []TimezoneSuspect `json:"suspects"`
 }
 
 type Note struct {
@@ -56,11 +192,22 @@ type Note struct {
 	UserID             string     `json:"user_id"`
 	Context            string     `json:"context"`
 	Date               string     `json:"date"`
+	Type               string     `json:"type"` // Granularity: day, week, month or year (Date then holds the period key, e.g. 2025-W42)
 	Content            string     `json:"content"`
+	Tags               []string   `json:"tags"` // #hashtags in Content, lowercased
+	Revision           int        `json:"revision"`
+	RevisionCount      int        `json:"revision_count"` // Earlier versions kept in the revision history
 	SyncStatus         SyncStatus `json:"sync_status,omitempty"`
 	SyncRetryCount     int        `json:"sync_retry_count,omitempty"`
 	SyncLastAttemptAt  *time.Time `json:"sync_last_attempt_at,omitempty"`
 	SyncError          string     `json:"sync_error,omitempty"`
+	SyncErrorClass     SyncErrorClass `json:"sync_error_class,omitempty"`
+	SyncNextRetryAt    *time.Time `json:"sync_next_retry_at,omitempty"` // When a failed note is due for its next attempt
+	LocalOnly          bool       `json:"local_only,omitempty"` // Never synced to storage, marked on the note or its context
+	Encrypted          bool       `json:"encrypted,omitempty"` // Content is an end-to-end encrypted envelope, see pkg/e2ee
+	Pinned             bool       `json:"pinned,omitempty"` // Listed at the top of its context, see /api/notes/pinned
+	WordCount          int        `json:"word_count"` // Words of Content, counted on save
+	CharCount          int        `json:"char_count"` // Characters of Content, counted on save
 	CreatedAt          time.Time  `json:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at"`
 }
@@ -70,23 +217,1277 @@ type Context struct {
 	UserID    string    `json:"user_id"`
 	Name      string    `json:"name"`
 	Color     string    `json:"color"`
+	Icon      string    `json:"icon,omitempty"` // Emoji or icon name shown with the name, see the contexticon validation
+	Template  string    `json:"template,omitempty"` // Initial content for new notes, may contain {{placeholders}}
+	LocalOnly bool      `json:"local_only,omitempty"` // Its notes are never synced to storage
+	Language  string    `json:"language,omitempty"` // ISO 639-1 code its notes are written in; empty for the server default
+	Role      string    `json:"role,omitempty"` // MemberRead or MemberWrite for contexts shared with the user; empty for their own
+	Owner     string    `json:"owner,omitempty"` // Name of the account sharing the context
+	SortOrder int       `json:"sort_order"` // Position in the owner's list, see PATCH /api/contexts/reorder
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// NoteRevision is an earlier version of a note's content
+type NoteRevision struct {
+	ID        int64     `json:"id"`
+	Context   string    `json:"context"`
+	Date      string    `json:"date"`
+	Revision  int       `json:"revision"` // The note's revision while this content was current
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"` // When this content was saved
+}
+
+// NoteConflict is a note that was edited both locally and in storage since its last sync
+// Sync of the note stops until the conflict is resolved; both versions are kept until then.
+type NoteConflict struct {
+	Context          string    `json:"context"`
+	Date             string    `json:"date"`
+	LocalContent     string    `json:"local_content"`
+	RemoteContent    string    `json:"remote_content"`
+	RemoteModifiedAt time.Time `json:"remote_modified_at"`
+	DetectedAt       time.Time `json:"detected_at"`
+}
+
+// Problems reported in a SyncMismatch
+const (
+	SyncMismatchChanged = "changed" // The file's content doesn't hash to the last upload
+	SyncMismatchMissing = "missing" // The synced note has no file in storage
+)
+
+// SyncMismatch is a synced note whose file in storage no longer matches its last upload
+type SyncMismatch struct {
+	Date        string `json:"date"`
+	Problem     string `json:"problem"`                // SyncMismatchChanged or SyncMismatchMissing
+	SyncedHash  string `json:"synced_hash"`            // SHA-256 of the content at the last upload
+	FileHash    string `json:"file_hash,omitempty"`    // Hash stored with the file
+	ContentHash string `json:"content_hash,omitempty"` // SHA-256 of the file's current content
+}
+
+// SyncVerification is the outcome of comparing a context's synced notes with storage
+// Changed files are reported as sync conflicts as well.
+type SyncVerification struct {
+	Context    string         `json:"context"`
+	Checked    int            `json:"checked"`
+	Mismatches []SyncMismatch `json:"mismatches"`
+	VerifiedAt time.Time      `json:"verified_at"`
+}
+
+// ChangeChannel is a subscription to push notifications of changes in a user's storage
+// Never sent to clients: the token authenticates the notifications.
+type ChangeChannel struct {
+	ID         string // Chosen when subscribing, sent with every notification
+	UserID     string
+	ResourceID string // Set by the provider, needed to unsubscribe
+	Token      string // Secret sent with every notification
+	Expiration time.Time
+}
+
+// ResolveConflictRequest picks the content a conflicted note keeps
+// "local" and "remote" keep one version, "merged" saves Content instead.
+type ResolveConflictRequest struct {
+	Context    string `json:"context" validate:"required,max=100,contextname"`
+	Date       string `json:"date" validate:"required,max=20"`
+	Resolution string `json:"resolution" validate:"required,oneof=local remote merged"`
+	Content    string `json:"content"`
+}
+
+// RelatedNote is a past note similar to the one being viewed
+type RelatedNote struct {
+	Context     string   `json:"context"`
+	Date        string   `json:"date"`
+	Score       float64  `json:"score"`
+	SharedTags  []string `json:"shared_tags,omitempty"`
+	SharedLinks []string `json:"shared_links,omitempty"`
+	Excerpt     string   `json:"excerpt"`
+}
+
+// NoteSizeWarning tells the client a saved note is large enough to slow down editing and sync
+type NoteSizeWarning struct {
+	Size        int      `json:"size"`  // Bytes
+	Limit       int      `json:"limit"` // NOTE_SIZE_WARNING
+	Message     string   `json:"message"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// NoteSize is the size of one note
+type NoteSize struct {
+	Context string `json:"context"`
+	Date    string `json:"date"`
+	Size    int    `json:"size"`
+}
+
+// NoteSizeStats summarizes the content sizes of a user's notes, in bytes
+// Percentiles use the nearest-rank method.
+type NoteSizeStats struct {
+	Count     int        `json:"count"`
+	Total     int        `json:"total"`
+	P50       int        `json:"p50"`
+	P90       int        `json:"p90"`
+	P99       int        `json:"p99"`
+	Max       int        `json:"max"`
+	Limit     int        `json:"limit"`     // Size above which notes get a warning; 0 when disabled
+	Oversized int        `json:"oversized"` // Notes above Limit
+	Largest   []NoteSize `json:"largest"`
+}
+
+// NoteSearchResult is a note matching a full-text search
+// Snippet is HTML-escaped text with the matched terms wrapped in <mark></mark>.
+type NoteSearchResult struct {
+	Context    string     `json:"context"`
+	Date       string     `json:"date"`
+	Type       string     `json:"type"`
+	SyncStatus SyncStatus `json:"sync_status"`
+	Snippet    string     `json:"snippet"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Tag is a #hashtag with the number of notes using it
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Task is a checkbox list item of a live note ("- [ ] Call Ana"), kept in step
+// with the note's content on every save. Its ID stays while the task keeps its line.
+type Task struct {
+	ID      int64  `json:"id"`
+	Context string `json:"context"`
+	Date    string `json:"date"`
+	Line    int    `json:"line"` // 1-based line of the note
+	Text    string `json:"text"`
+	Done    bool   `json:"done"`
+}
+
+// Task statuses accepted by GET /api/tasks
+const (
+	TaskOpen = "open"
+	TaskDone = "done"
+)
+
+// TagJob is a rename or merge of #tags running in the background over a user's notes
+type TagJob struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	Kind       string     `json:"kind"` // TagJobRename or TagJobMerge
+	From       []string   `json:"from"`
+	To         string     `json:"to"`
+	Status     string     `json:"status"`    // TagJobQueued, TagJobRunning, TagJobDone or TagJobFailed
+	Total      int        `json:"total"`     // Notes using the tags when the job started
+	Processed  int        `json:"processed"` // Notes handled so far, of Total
+	Updated    int        `json:"updated"`
+	Failed     int        `json:"failed"` // Notes left unchanged because saving them failed
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Kinds and statuses of tag jobs
+const (
+	TagJobRename = "rename"
+	TagJobMerge  = "merge"
+
+	TagJobQueued  = "queued"
+	TagJobRunning = "running"
+	TagJobDone    = "done"
+	TagJobFailed  = "failed"
+)
+
+// RenameTagRequest renames a #tag in every note using it
+type RenameTagRequest struct {
+	From string `json:"from" validate:"required,max=100"`
+	To   string `json:"to" validate:"required,max=100"`
+}
+
+// MergeTagsRequest rewrites several #tags as one
+type MergeTagsRequest struct {
+	Tags []string `json:"tags" validate:"required,min=1,max=20,dive,max=100"`
+	Into string   `json:"into" validate:"required,max=100"`
+}
+
+// NoteSearchFilter narrows a search; empty fields don't filter
+// From and To are inclusive dates, and limit the search to daily notes.
+type NoteSearchFilter struct {
+	Context    string     `json:"context,omitempty"`
+	From       string     `json:"from,omitempty"`
+	To         string     `json:"to,omitempty"`
+	SyncStatus SyncStatus `json:"sync_status,omitempty"`
+}
+
+// SearchNotesRequest is the query string of a note search
+type SearchNotesRequest struct {
+	Query      string `json:"q" query:"q"`
+	Context    string `json:"context" query:"context" validate:"omitempty,max=100,contextname"`
+	From       string `json:"from" query:"from" validate:"omitempty,dateformat"`
+	To         string `json:"to" query:"to" validate:"omitempty,dateformat,notbefore=From"`
+	SyncStatus string `json:"sync_status" query:"sync_status" validate:"omitempty,oneof=pending syncing synced failed abandoned conflict local"`
+	Limit      int    `json:"limit" query:"limit"`
+	Offset     int    `json:"offset" query:"offset"`
+}
+
+// Filter returns the filters set in the request
+func (r SearchNotesRequest) Filter() NoteSearchFilter {
+	return NoteSearchFilter{Context: r.Context, From: r.From, To: r.To, SyncStatus: SyncStatus(r.SyncStatus)}
+}
+
+// PaletteResult is a typed, ranked entry for the command palette
+type PaletteResult struct {
+	Type     string  `json:"type"` // context, note, tag or command
+	Title    string  `json:"title"`
+	Subtitle string  `json:"subtitle,omitempty"`
+	Context  string  `json:"context,omitempty"`
+	Date     string  `json:"date,omitempty"`
+	Color    string  `json:"color,omitempty"`
+	Action   string  `json:"action,omitempty"`
+	Count    int     `json:"count,omitempty"`
+	Score    float64 `json:"score"`
+}
+
+// ContextSuggestion is a context ranked by how well it matches some text
+type ContextSuggestion struct {
+	Context string  `json:"context"`
+	Color   string  `json:"color"`
+	Score   float64 `json:"score"`
+}
+
+// MonthNote is a daily note returned by the month prefetch, carrying either a
+// preview or the full content depending on what was requested
+type MonthNote struct {
+	Date      string    `json:"date"`
+	Size      int       `json:"size"` // Content length in bytes
+	Preview   string    `json:"preview,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	Truncated bool      `json:"truncated,omitempty"` // Content left out to keep the response under the size limit
+	Encrypted bool      `json:"encrypted,omitempty"` // End-to-end encrypted: no preview, only Content
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CalendarDay is one day of a context's month calendar: whether it has a note
+// and, when it does, its word count and sync state
+type CalendarDay struct {
+	Date       string     `json:"date"`
+	Exists     bool       `json:"exists"`
+	WordCount  int        `json:"word_count"`
+	SyncStatus SyncStatus `json:"sync_status,omitempty"`
+}
+
+// AgendaRequest is the query string of an agenda
+type AgendaRequest struct {
+	From string `json:"from" query:"from" validate:"required,dateformat"`
+	To   string `json:"to" query:"to" validate:"required,dateformat,notbefore=From"`
+}
+
+// DayRequest is the query string of a day across contexts
+type DayRequest struct {
+	Date string `json:"date" query:"date" validate:"required,dateformat"`
+}
+
+// AgendaTask is a checkbox list item of a note ("- [ ] Call Ana")
+type AgendaTask struct {
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// TaskRollup counts the checkboxes of one or more notes
+type TaskRollup struct {
+	Open int `json:"open"`
+	Done int `json:"done"`
+}
+
+// AgendaNote is a daily note in an agenda, with the color of its context
+type AgendaNote struct {
+	Context   string       `json:"context"`
+	Color     string       `json:"color"`
+	Date      string       `json:"date"`
+	Title     string       `json:"title"` // First heading, or a preview when the note has none
+	Content   string       `json:"content"`
+	Tags      []string     `json:"tags"`
+	Tasks     []AgendaTask `json:"tasks"`
+	Rollup    TaskRollup   `json:"rollup"`
+	Encrypted bool         `json:"encrypted,omitempty"` // End-to-end encrypted: no title, tags or tasks
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// AgendaDay is one day of an agenda with the notes of every context
+type AgendaDay struct {
+	Date   string       `json:"date"`
+	Title  string       `json:"title"` // "Monday, January 2, 2006"
+	Notes  []AgendaNote `json:"notes"`
+	Rollup TaskRollup   `json:"rollup"`
+}
+
+// Agenda lists the daily notes of all contexts in a date range, by day
+// Days without notes are included so printed agendas show the whole range.
+type Agenda struct {
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+	Days   []AgendaDay `json:"days"`
+	Rollup TaskRollup  `json:"rollup"`
+}
+
+// NotesMeta is structured data about the daily notes of all contexts in a date
+// range, without their content, for dashboards. It is columnar: entry i of each
+// column is about Dates[i], and days without notes are included.
+type NotesMeta struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Dates     []string   `json:"dates"`
+	Notes     []int      `json:"notes"` // Notes written that day
+	Words     []int      `json:"words"`
+	Moods     []*string  `json:"moods"` // From a "Mood: ..." line, as written; null without one
+	Tags      [][]string `json:"tags"`
+	TasksOpen []int      `json:"tasks_open"`
+	TasksDone []int      `json:"tasks_done"`
+}
+
+// ActivityDay is how much was written on a day, for contribution graphs
+type ActivityDay struct {
+	Date  string `json:"date"`
+	Notes int    `json:"notes"`
+	Words int    `json:"words"`
+}
+
+// ContextStreak is the runs of consecutive days with a daily note in a context:
+// Current ends today, or yesterday while today has no note yet
+type ContextStreak struct {
+	Context string `json:"context"`
+	Current int    `json:"current"`
+	Longest int    `json:"longest"`
+}
+
+// Activity is the daily notes written each day of a range, days without notes
+// included, with the streaks of every context as of today
+type Activity struct {
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Today   string          `json:"today"`
+	Days    []ActivityDay   `json:"days"`
+	Streaks []ContextStreak `json:"streaks"`
+}
+
+// WritingTotals is how much a user has written in notes of every kind
+type WritingTotals struct {
+	Notes        int     `json:"notes"`
+	Words        int     `json:"words"`
+	Chars        int     `json:"chars"`
+	AverageWords float64 `json:"average_words"` // Per note
+}
+
+// WeekdayWriting is how much was written in daily notes on one day of the week,
+// averaged over the days of that weekday with notes
+type WeekdayWriting struct {
+	Weekday      string  `json:"weekday"` // "monday" to "sunday"
+	Days         int     `json:"days"`
+	Notes        int     `json:"notes"`
+	Words        int     `json:"words"`
+	AverageWords float64 `json:"average_words"` // Per day with notes
+}
+
+// ContextWriting is how much was written in the notes of a context
+type ContextWriting struct {
+	Context string `json:"context"`
+	Notes   int    `json:"notes"`
+	Words   int    `json:"words"`
+	Chars   int    `json:"chars"`
+}
+
+// WritingStats is how much a user writes: totals, by weekday from Monday, and
+// the contexts with the most words
+type WritingStats struct {
+	Totals   WritingTotals    `json:"totals"`
+	Weekdays []WeekdayWriting `json:"weekdays"`
+	Contexts []ContextWriting `json:"contexts"`
+}
+
+// NoteView is a note rendered for reading with the notes around it, so a
+// reader can page through notes without listing them
+type NoteView struct {
+	Note     *Note         `json:"note"`
+	HTML     string        `json:"html"`
+	Title    string        `json:"title"`              // e.g. "Monday, January 2, 2006" or "Week 42, 2025"
+	Previous string        `json:"previous,omitempty"` // Key of the context's previous note of the same kind
+	Next     string        `json:"next,omitempty"`     // Key of the context's next note of the same kind
+	SameDay  []SameDayNote `json:"same_day"`           // Notes with the same key in other contexts
+}
+
+// SameDayNote is a note with the same date in another context
+type SameDayNote struct {
+	Context string `json:"context"`
+	Color   string `json:"color"`
+	Title   string `json:"title"` // First heading, or a preview when the note has none
+}
+
+// NoteChange is the latest change of a note after a delta sync cursor
+type NoteChange struct {
+	Cursor  int64  `json:"cursor"` // Position of the change; later changes of the note get a higher one
+	Kind    string `json:"kind"`   // NoteCreated, NoteUpdated or NoteDeleted, as seen from the requested cursor
+	NoteID  string `json:"note_id"`
+	Context string `json:"context"`
+	Date    string `json:"date"`
+	Note    *Note  `json:"note,omitempty"` // The note as it is now; nil when deleted
+}
+
+// Note change kinds
+const (
+	NoteCreated = "created"
+	NoteUpdated = "updated"
+	NoteDeleted = "deleted"
+)
+
+// NoteChanges is a page of a user's note changes after a cursor (GET /api/notes/changes)
+type NoteChanges struct {
+	Changes []NoteChange `json:"changes"`
+	Cursor  int64        `json:"cursor"`   // Pass as since to get the next changes
+	HasMore bool         `json:"has_more"` // More changes follow; ask again right away
+}
+
+// NoteLink points to another note in a rollup, e.g. the daily notes of a week
+type NoteLink struct {
+	Type    string `json:"type"`
+	Key     string `json:"key"`
+	Exists  bool   `json:"exists"`
+	Excerpt string `json:"excerpt,omitempty"`
+}
+
+// TrashedContext is a deleted context that can still be restored
+type TrashedContext struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	Icon      string    `json:"icon,omitempty"`
+	Template  string    `json:"template,omitempty"`
+	LocalOnly bool      `json:"local_only,omitempty"`
+	Language  string    `json:"language,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// TrashedNote is a deleted note that can still be restored until ExpiresAt.
+// Lists carry an excerpt of the content, left empty for encrypted notes.
+type TrashedNote struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	Context   string    `json:"context"`
+	Date      string    `json:"date"`
+	Type      string    `json:"type"`     // Granularity of the note
+	Revision  int       `json:"revision"` // The note's revision when it was deleted
+	Content   string    `json:"-"`
+	Excerpt   string    `json:"excerpt"`
+	Encrypted bool      `json:"encrypted,omitempty"`
+	LocalOnly bool      `json:"local_only,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ContextSync is how the notes of a context are reaching cloud storage
+type ContextSync struct {
+	State     SyncHealthState `json:"state"` // Degraded while notes failed or conflict, offline while storage is unreachable
+	Message   string          `json:"message,omitempty"`
+	Synced    int             `json:"synced"`
+	Pending   int             `json:"pending"`
+	Failed    int             `json:"failed"` // Failed and abandoned notes
+	Conflicts int             `json:"conflicts"`
+	Local     int             `json:"local"` // Notes kept off storage on purpose
+}
+
+// ContextStats is what a context holds, for the contexts sidebar. The dates are
+// those of its first and last daily notes, empty when it has none.
+type ContextStats struct {
+	ContextID string      `json:"context_id"`
+	Context   string      `json:"context"`
+	Notes     int         `json:"notes"`
+	Words     int         `json:"words"`
+	FirstDate string      `json:"first_date,omitempty"`
+	LastDate  string      `json:"last_date,omitempty"`
+	Sync      ContextSync `json:"sync"`
+}
+
 type CreateNoteRequest struct {
 	Context string `json:"context" validate:"required,min=1,max=100,contextname"`
 	Date    string `json:"date" validate:"required,dateformat"`
 	Content string `json:"content"` // Content can be empty
+	// Revision is the note revision the client's edit is based on (optional).
+	// When set, the save is rejected if the note changed in the meantime.
+	Revision *int `json:"revision,omitempty" validate:"omitempty,gte=0"`
+	// Append adds Content to the note instead of replacing it.
+	// Section names the markdown heading to append under ("Tasks" or "## Tasks").
+	Append  bool   `json:"append,omitempty"`
+	Section string `json:"section,omitempty" validate:"omitempty,max=200"`
+}
+
+// PatchNoteRequest is the body of PATCH /api/notes, the autosave of a note:
+// a save like POST /api/notes whose sync waits for the autosaves to pause
+type PatchNoteRequest struct {
+	Context  string `json:"context" validate:"required,min=1,max=100,contextname"`
+	Date     string `json:"date" validate:"required,dateformat"`
+	Content  string `json:"content"`
+	Revision *int   `json:"revision,omitempty" validate:"omitempty,gte=0"`
+}
+
+// AppendNoteRequest is the body of POST /api/notes/append, which adds a line
+// or block to a note without the client sending the rest of it. Context may be
+// left out by users who opted in to context suggestions.
+type AppendNoteRequest struct {
+	Context   string `json:"context,omitempty" validate:"omitempty,min=1,max=100,contextname"`
+	Date      string `json:"date,omitempty" validate:"omitempty,dateformat"` // Today when empty
+	Content   string `json:"content" validate:"required"`
+	Section   string `json:"section,omitempty" validate:"omitempty,max=200"`
+	Timestamp bool   `json:"timestamp,omitempty"` // Start the entry with the time it was added, "14:05"
+	// Timezone is where today and the timestamp are taken; the user's setting when empty
+	Timezone string `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	Revision *int   `json:"revision,omitempty" validate:"omitempty,gte=0"`
+}
+
+// BatchNoteItem is one note of a batch save
+type BatchNoteItem struct {
+	Context string `json:"context" validate:"required,min=1,max=100,contextname"`
+	Date    string `json:"date" validate:"required,dateformat"`
+	Content string `json:"content"`
+}
+
+// BatchUpsertNotesRequest saves several notes at once, e.g. edits made while offline
+// The request body is the JSON array of notes.
+type BatchUpsertNotesRequest struct {
+	Notes []BatchNoteItem `validate:"required,min=1,max=100,dive"`
+}
+
+// UpsertPeriodNoteRequest saves a note for a longer period, e.g. {"type": "week", "key": "2025-W42"}
+type UpsertPeriodNoteRequest struct {
+	Context  string `json:"context" validate:"required,min=1,max=100,contextname"`
+	Type     string `json:"type" validate:"required,oneof=week month year"`
+	Key      string `json:"key" validate:"required,periodkey=Type"`
+	Content  string `json:"content"`
+	Revision *int   `json:"revision,omitempty" validate:"omitempty,gte=0"`
+}
+
+// SeedPeriodNoteRequest asks for a period note to be pre-filled with a digest of its notes
+type SeedPeriodNoteRequest struct {
+	Context string `json:"context" validate:"required,min=1,max=100,contextname"`
+	Type    string `json:"type" validate:"required,oneof=week month year"`
+	Key     string `json:"key" validate:"required,periodkey=Type"`
+}
+
+// TransferNotesRequest copies or moves one note, or a range of daily notes, to another context
+type TransferNotesRequest struct {
+	FromContext string `json:"from_context" validate:"required,min=1,max=100,contextname"`
+	ToContext   string `json:"to_context" validate:"required,min=1,max=100,contextname"`
+	Date        string `json:"date,omitempty"` // Key of a single note (day, week, month or year)
+	StartDate   string `json:"start_date,omitempty" validate:"omitempty,dateformat"`
+	EndDate     string `json:"end_date,omitempty" validate:"omitempty,dateformat"`
+	Overwrite   bool   `json:"overwrite"` // Replace notes that already exist in the target context
+}
+
+// RedateNotesRequest moves the daily notes of a context from StartDate to EndDate by Days,
+// e.g. -1 to fix notes dated a day late after a timezone change
+type RedateNotesRequest struct {
+	Context   string `json:"context" validate:"required,min=1,max=100,contextname"`
+	StartDate string `json:"start_date" validate:"required,dateformat"`
+	EndDate   string `json:"end_date" validate:"required,dateformat"`
+	Days      int    `json:"days" validate:"required,min=-31,max=31"`
+	Overwrite bool   `json:"overwrite"` // Replace notes that already exist at the new dates
+}
+
+// SetNoteLocalOnlyRequest marks a note local only, or syncs it again
+type SetNoteLocalOnlyRequest struct {
+	Context   string `json:"context" validate:"required,min=1,max=100,contextname"`
+	Date      string `json:"date" validate:"required,max=20"` // Day or period key of an existing note
+	LocalOnly bool   `json:"local_only"`
+}
+
+// PinNoteRequest pins a note to the top of its context, or unpins it
+type PinNoteRequest struct {
+	Context string `json:"context" validate:"required,min=1,max=100,contextname"`
+	Date    string `json:"date" validate:"required,max=20"` // Day or period key of an existing note
+	Pinned  bool   `json:"pinned"`
+}
+
+// SplitNoteRequest moves a line range out of a note into the note of another date or context
+type SplitNoteRequest struct {
+	Context   string `json:"context" validate:"required,min=1,max=100,contextname"`
+	Date      string `json:"date" validate:"required,dateformat"`
+	StartLine int    `json:"start_line" validate:"gte=1"` // 1-based, inclusive
+	EndLine   int    `json:"end_line" validate:"gte=1"`   // 1-based, inclusive
+	// ToContext and ToDate pick the destination note; each defaults to the source's
+	ToContext string `json:"to_context,omitempty" validate:"omitempty,min=1,max=100,contextname"`
+	ToDate    string `json:"to_date,omitempty" validate:"omitempty,dateformat"`
+	// Revision is the source note revision the selection was made on (optional)
+	Revision *int `json:"revision,omitempty" validate:"omitempty,gte=0"`
+}
+
+// NoteSection is a markdown heading of a note and the text directly under it
+// Text under nested headings belongs to those sections.
+type NoteSection struct {
+	Slug    string `json:"slug"`
+	Title   string `json:"title"`
+	Level   int    `json:"level"`
+	Line    int    `json:"line"` // 1-based line of the heading
+	Content string `json:"content"`
+}
+
+// UpdateSectionRequest replaces the text under one heading of a note
+type UpdateSectionRequest struct {
+	Context string `json:"context" validate:"required,min=1,max=100,contextname"`
+	Date    string `json:"date" validate:"required,dateformat"`
+	Content string `json:"content"`
+	// Revision is the note revision the edit is based on (optional)
+	Revision *int `json:"revision,omitempty" validate:"omitempty,gte=0"`
+}
+
+// EnableAuditRequest turns on debug recording of the user's note and sync requests
+type EnableAuditRequest struct {
+	Minutes int `json:"minutes" validate:"omitempty,gte=1,lte=1440"` // Defaults to 60
+}
+
+// UpdateStorageProviderRequest picks where a user's notes are synced to
+type UpdateStorageProviderRequest struct {
+	Provider string `json:"provider" validate:"required,oneof=drive dropbox local s3 webdav"`
+}
+
+// StorageProviderStatus describes one storage provider for the current user
+type StorageProviderStatus struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"` // Configured on this server
+	Connected bool   `json:"connected"` // Authorized by the user
+}
+
+// StorageStatus reports the selected storage provider and the ones a user can pick
+type StorageStatus struct {
+	Provider  string                  `json:"provider"`
+	Providers []StorageProviderStatus `json:"providers"`
+}
+
+type SuggestContextRequest struct {
+	Content string `json:"content" validate:"required,max=100000"`
 }
 
 type CreateContextRequest struct {
 	Name  string `json:"name" validate:"required,min=2,max=100,contextname"`
 	Color string `json:"color" validate:"required,bulmacolor"`
+	Icon  string `json:"icon,omitempty" validate:"omitempty,contexticon"`
+}
+
+// Profile is a portable copy of a user's setup, without note content
+type Profile struct {
+	Version    int                   `json:"version" validate:"gte=1"`
+	ExportedAt time.Time             `json:"exported_at"`
+	Settings   UpdateSettingsRequest `json:"settings"`
+	Contexts   []ProfileContext      `json:"contexts" validate:"max=500,dive"`
+	Tags       []string              `json:"tags"`
+}
+
+// ProfileContext is a context as stored in a profile
+type ProfileContext struct {
+	Name      string `json:"name" validate:"required,min=2,max=100,contextname"`
+	Color     string `json:"color" validate:"required,bulmacolor"`
+	Icon      string `json:"icon,omitempty" validate:"omitempty,contexticon"`
+	Template  string `json:"template,omitempty" validate:"max=20000"`
+	LocalOnly bool   `json:"local_only,omitempty"`
+	Language  string `json:"language,omitempty" validate:"omitempty,language"`
+}
+
+// AccountExport is a full copy of a user's account: the profile plus every note
+// Its fields sit next to the profile's in the JSON document, so it can also be imported as a profile.
+type AccountExport struct {
+	Profile
+	Notes []ExportedNote `json:"notes" validate:"max=100000,dive"`
+}
+
+// ExportedNote is a note as stored in an account export
+type ExportedNote struct {
+	Context   string    `json:"context" validate:"required,min=1,max=100,contextname"`
+	Type      string    `json:"type" validate:"required,oneof=day week month year"`
+	Date      string    `json:"date" validate:"required,periodkey=Type"` // Day or period key, e.g. 2025-10-16 or 2025-W42
+	Content   string    `json:"content"`
+	LocalOnly bool      `json:"local_only,omitempty"` // Only in exports that include local-only notes
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProfileImportResult summarises what a profile import changed
+type ProfileImportResult struct {
+	Settings        UserSettings `json:"settings"`
+	ContextsCreated int          `json:"contexts_created"`
+	ContextsUpdated int          `json:"contexts_updated"`
+}
+
+// ImportFileResult is the outcome of importing one file of a note archive
+type ImportFileResult struct {
+	Path    string `json:"path"`
+	Context string `json:"context,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Error   string `json:"error,omitempty"` // Empty when the note was saved
+}
+
+// NoteImportResult summarises a note archive import
+type NoteImportResult struct {
+	Imported        int                `json:"imported"`
+	Failed          int                `json:"failed"`
+	ContextsCreated int                `json:"contexts_created"`
+	Files           []ImportFileResult `json:"files"`
+}
+
+// LinkPreview is the title, description and image of a web page linked from notes
+// Previews are fetched by the server once per URL and shared by every note linking it.
+type LinkPreview struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// BuildInfo identifies the running server build
+type BuildInfo struct {
+	Version   string `json:"version"` // "dev" unless set at link time
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// UpdateStatus is the outcome of checking for a newer release of the server
+type UpdateStatus struct {
+	LatestVersion   string    `json:"latest_version,omitempty"`
+	UpdateAvailable bool      `json:"update_available"`
+	ReleaseURL      string    `json:"release_url,omitempty"`
+	CheckedAt       time.Time `json:"checked_at"`
+	Error           string    `json:"error,omitempty"` // Why the check failed; it is retried later
+}
+
+// Diagnostics describes the running server for self-hosters and support
+type Diagnostics struct {
+	Build         BuildInfo        `json:"build"`
+	Update        *UpdateStatus    `json:"update,omitempty"` // Only when update checks are enabled
+	StartedAt     time.Time        `json:"started_at"`
+	UptimeSeconds int64            `json:"uptime_seconds"`
+	Goroutines    int              `json:"goroutines"`
+	Checks        []ReadinessCheck `json:"checks"` // Same checks as the status badge, with their errors
+}
+
+// ReadinessCheck is the outcome of checking one thing the server needs to serve requests
+type ReadinessCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Maintenance is the state of maintenance mode, in which writes are refused with 503
+type Maintenance struct {
+	Enabled      bool       `json:"enabled"`
+	Since        *time.Time `json:"since,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+	RetryAfter   int        `json:"retry_after,omitempty"` // Seconds clients are told to wait
+	JournalSaves bool       `json:"journal_saves"`         // Note saves are journaled for replay instead of refused
+	Journaled    int        `json:"journaled"`             // Saves waiting for replay
+	PendingSync  int        `json:"pending_sync"`          // Notes the sync worker has yet to upload; 0 once it drained
+}
+
+// StartMaintenanceRequest turns maintenance mode on (PUT /api/admin/maintenance)
+type StartMaintenanceRequest struct {
+	Reason       string `json:"reason" validate:"max=200"`
+	RetryAfter   int    `json:"retry_after" validate:"gte=0,lte=86400"` // Seconds; 0 for the default of 5 minutes
+	JournalSaves bool   `json:"journal_saves"`
+}
+
+// JournaledSave is a note save received during maintenance, replayed when it ends
+type JournaledSave struct {
+	UserID       string    `json:"user_id"`
+	OwnerID      string    `json:"owner_id,omitempty"` // Of the note, which differs from UserID in shared contexts
+	Context      string    `json:"context"`
+	Date         string    `json:"date"`
+	Content      string    `json:"content"`
+	Append       bool      `json:"append,omitempty"`
+	Section      string    `json:"section,omitempty"`
+	BaseRevision *int      `json:"base_revision,omitempty"` // Replays only onto this revision; nil for saves without one
+	ReceivedAt   time.Time `json:"received_at"`
+}
+
+// FailedSave is a journaled save that didn't replay when maintenance ended
+// It is kept in the failed saves file of MAINTENANCE_DIR.
+type FailedSave struct {
+	Save  *JournaledSave `json:"save,omitempty"`
+	Line  string         `json:"line,omitempty"` // The journal line, when it couldn't be read
+	Error string         `json:"error"`
+}
+
+// Backup is a snapshot of the database kept in BACKUP_DIR
+type Backup struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Attachment is a file attached to a note, embedded in its markdown through URL
+type Attachment struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"-"`
+	Context       string    `json:"context"`
+	Date          string    `json:"date"`
+	Filename      string    `json:"filename"` // Cleaned up name of the uploaded file
+	ContentType   string    `json:"content_type"`
+	Size          int64     `json:"size"`
+	StorageStatus string    `json:"storage_status"`          // AttachmentPending, AttachmentStored or AttachmentNotStored
+	StorageError  string    `json:"storage_error,omitempty"` // Of the latest failed copy to storage
+	URL           string    `json:"url"`                     // Where the file is served, e.g. /api/attachments/<id>
+	Markdown      string    `json:"markdown"`                // ![name](url) for images, [name](url) for other files
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Attachment storage statuses
+const (
+	AttachmentPending   = "pending"    // Waiting to be copied to the context folder in storage
+	AttachmentStored    = "stored"     // Copied to the context's _attachments folder
+	AttachmentNotStored = "not_stored" // The storage provider doesn't keep attachments; only on the server
+)
+
+// APIToken lets an integration, such as a script posting smart-scale readings,
+// use the API for a single context and a subset of operations. Only a hash of
+// the secret is kept; the secret itself is returned once, when it's created.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	Context    string     `json:"context"`
+	Operations []string   `json:"operations"`      // TokenRead, TokenAppend and/or TokenWrite
+	Token      string     `json:"token,omitempty"` // The secret ("dn_..."), only when created
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Operations an API token can be granted
+const (
+	TokenRead   = "read"   // Read the context's notes
+	TokenAppend = "append" // Add text to notes, creating them if needed
+	TokenWrite  = "write"  // Replace the content of notes; implies append
+)
+
+// CreateAPITokenRequest is the body of POST /api/tokens
+type CreateAPITokenRequest struct {
+	Name       string   `json:"name" validate:"required,max=100"`
+	Context    string   `json:"context" validate:"required"`
+	Operations []string `json:"operations" validate:"required,min=1,dive,oneof=read append write"`
+}
+
+// APIKey lets scripts and apps use the API as its user, like a session but
+// without signing in; unlike an APIToken it isn't limited to one context
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`         // KeyRead or KeyReadWrite
+	Key        string     `json:"key,omitempty"` // The secret ("dn_key_..."), only when created
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Scopes of API keys
+const (
+	KeyRead      = "read"       // GET requests only
+	KeyReadWrite = "read-write" // Any request but managing keys, tokens, storage and encryption
+)
+
+// CreateAPIKeyRequest is the body of POST /api/keys
+type CreateAPIKeyRequest struct {
+	Name  string `json:"name" validate:"required,max=100"`
+	Scope string `json:"scope" validate:"required,oneof=read read-write"`
+}
+
+// NoteSchedule makes the server create a user's daily notes from their context
+// templates at a local time, so calendars show no gaps and reminders can link
+// to notes that exist
+type NoteSchedule struct {
+	UserID   string `json:"-"`
+	Time     string `json:"time"`               // Local time of day, "07:30"
+	Timezone string `json:"timezone"`           // The user's timezone setting
+	LastRun  string `json:"last_run,omitempty"` // Local date of the latest run
+}
+
+// NoteScheduleRequest is the body of PUT /api/notes/schedule
+type NoteScheduleRequest struct {
+	Time string `json:"time" validate:"required,datetime=15:04"`
+}
+
+// DropURL is a signed URL that appends one payload to a note without signing in
+type DropURL struct {
+	URL       string    `json:"url"`
+	Context   string    `json:"context"`
+	Date      string    `json:"date"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateDropURLRequest is the body of POST /api/drops; ExpiresIn is in seconds
+type CreateDropURLRequest struct {
+	Context   string `json:"context" validate:"required"`
+	Date      string `json:"date" validate:"required,dateformat"`
+	ExpiresIn int    `json:"expires_in" validate:"omitempty,min=60,max=2592000"`
+}
+
+// DropQuery is the signature part of a drop box URL
+type DropQuery struct {
+	Expires   int64  `query:"expires"`
+	Nonce     string `query:"nonce"`
+	Signature string `query:"sig"`
+}
+
+// DigestSubscription is a user's subscription to the weekly email digest of
+// their notes, with where and when to send it
+type DigestSubscription struct {
+	UserID   string `json:"-"`
+	Email    string `json:"-"`
+	Name     string `json:"-"`
+	Timezone string `json:"-"`                   // The user's timezone setting
+	Token    string `json:"-"`                   // Secret of the unsubscribe link in each digest
+	LastWeek string `json:"last_week,omitempty"` // ISO week of the latest digest queued
+}
+
+// DigestSettings is whether the server sends weekly digests and whether the user gets them
+type DigestSettings struct {
+	Enabled    bool   `json:"enabled"`
+	Subscribed bool   `json:"subscribed"`
+	LastWeek   string `json:"last_week,omitempty"`
+}
+
+// Reminder is a reminder at a local time in the user's timezone setting,
+// written in a note as @remind(2025-11-02 09:00) or made through the API
+type Reminder struct {
+	ID        int64      `json:"id"`
+	UserID    string     `json:"-"`
+	Context   string     `json:"context,omitempty"` // Note it's written in; empty for API reminders
+	Date      string     `json:"date,omitempty"`
+	Line      int        `json:"line,omitempty"` // 1-based line of the note
+	Text      string     `json:"text"`
+	RemindAt  string     `json:"remind_at"` // Local time, "2025-11-02 09:00"
+	Status    string     `json:"status"`    // ReminderPending, ReminderSent or ReminderMissed
+	LocalOnly bool       `json:"-"`         // Written in a local-only note
+	Timezone  string     `json:"-"`         // The user's timezone setting, for due reminders
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
+// Reminder statuses, also accepted by GET /api/reminders
+const (
+	ReminderPending = "pending"
+	ReminderSent    = "sent"
+	ReminderMissed  = "missed" // Came due while reminders couldn't be delivered
+)
+
+// CreateReminderRequest is the body of POST /api/reminders
+type CreateReminderRequest struct {
+	Text     string `json:"text" validate:"required,max=200"`
+	RemindAt string `json:"remind_at" validate:"required,datetime=2006-01-02 15:04"`
+}
+
+// PushSubscription is a browser receiving a user's web push notifications
+type PushSubscription struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"-"`
+	Endpoint  string    `json:"endpoint"`
+	P256dh    string    `json:"-"`
+	Auth      string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PushSubscribeRequest is the PushSubscription of the browser, as its toJSON() returns it
+type PushSubscribeRequest struct {
+	Endpoint string `json:"endpoint" validate:"required,url,max=2048"`
+	Keys     struct {
+		P256dh string `json:"p256dh" validate:"required,max=200"`
+		Auth   string `json:"auth" validate:"required,max=200"`
+	} `json:"keys"`
+}
+
+// PushUnsubscribeRequest names the browser to stop notifying by its endpoint
+type PushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" validate:"required"`
+}
+
+// PushSettings tells clients whether the server sends web push notifications,
+// and the VAPID key to subscribe with (applicationServerKey)
+type PushSettings struct {
+	Enabled   bool   `json:"enabled"`
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// PushNotification is the payload of a web push message, shown by the service worker
+type PushNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body,omitempty"`
+	URL   string `json:"url,omitempty"` // Opened when the notification is clicked
+	Tag   string `json:"tag,omitempty"` // Replaces a shown notification with the same tag
+}
+
+// Job is background work kept in the database until it is done, so it survives
+// restarts (see pkg/jobs)
+type Job struct {
+	ID         int64      `json:"id"`
+	UserID     string     `json:"user_id"`
+	Kind       string     `json:"kind"`
+	Payload    string     `json:"payload"` // JSON arguments of the kind's handler
+	Status     string     `json:"status"`  // JobQueued, JobRunning, JobDone or JobFailed
+	Attempts   int        `json:"attempts"`
+	Error      string     `json:"error,omitempty"` // Of the latest failed attempt
+	RunAt      time.Time  `json:"run_at"`          // When the job is due, again after a failed attempt
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Job statuses
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed" // Gave up after its last attempt
+)
+
+// ReleaseNote is one change listed in a release
+type ReleaseNote struct {
+	Kind string `json:"kind"` // "feature", "improvement" or "fix"
+	Text string `json:"text"`
+}
+
+// Release is a version in the user-facing changelog
+type Release struct {
+	Version           string        `json:"version"`
+	Date              string        `json:"date"` // YYYY-MM-DD
+	Title             string        `json:"title,omitempty"`
+	Notes             []ReleaseNote `json:"notes"`
+	RequiresReconsent bool          `json:"requires_reconsent,omitempty"` // Uses new OAuth scopes the user must grant
+	Scopes            []string      `json:"scopes,omitempty"`             // The new scopes, when RequiresReconsent
+}
+
+// Changelog is the changelog as seen by a user
+// Unseen holds the releases after the last one the user was shown, newest first.
+type Changelog struct {
+	CurrentVersion    string    `json:"current_version"`
+	LastSeenVersion   string    `json:"last_seen_version,omitempty"`
+	Unseen            []Release `json:"unseen"`
+	RequiresReconsent bool      `json:"requires_reconsent"` // An unseen release needs the user to grant new scopes
+	Releases          []Release `json:"releases"`
+}
+
+// MarkChangelogSeenRequest records the release a user was shown; empty means the current one
+type MarkChangelogSeenRequest struct {
+	Version string `json:"version,omitempty" validate:"omitempty,max=20"`
+}
+
+// PublicContext is a context published read-only at /@handle
+type PublicContext struct {
+	Handle    string    `json:"handle"`
+	ContextID string    `json:"context_id"`
+	Context   string    `json:"context"` // Current name of the context
+	UserID    string    `json:"-"`
+	Indexable bool      `json:"indexable"` // Search engines may index the pages
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PublishContextRequest publishes a context under a handle, or changes its handle
+type PublishContextRequest struct {
+	Handle    string `json:"handle" validate:"required,handle"`
+	Indexable bool   `json:"indexable"`
+}
+
+// PublicNote is a note as shown on a public page
+type PublicNote struct {
+	Date      string    `json:"date"`
+	Title     string    `json:"title"`   // Day title, e.g. "Thursday, October 16, 2025"
+	Heading   string    `json:"heading"` // First heading, else the start of the first line
+	HTML      string    `json:"html"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NoteShare is a secret link showing one note read-only to anyone who has it
+type NoteShare struct {
+	ID        int64      `json:"id"`
+	Token     string     `json:"token"`
+	UserID    string     `json:"-"`
+	NoteID    string     `json:"-"`
+	Context   string     `json:"context"`
+	Date      string     `json:"date"`
+	URL       string     `json:"url"` // Path of the shared page, /s/<token>
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CreateShareRequest shares a note, for good or for a number of days
+type CreateShareRequest struct {
+	Context       string `json:"context" validate:"required,min=1,max=100,contextname"`
+	Date          string `json:"date" validate:"required,dateformat"`
+	ExpiresInDays int    `json:"expires_in_days,omitempty" validate:"omitempty,min=1,max=365"`
+}
+
+// Roles of the members of a shared context
+const (
+	MemberRead  = "read"  // Reads the context's notes
+	MemberWrite = "write" // Also writes and deletes them
+)
+
+// ContextMember is an account a context is shared with, or an email address
+// invited to it that hasn't accepted yet
+type ContextMember struct {
+	ID         int64      `json:"id"`
+	ContextID  string     `json:"context_id"`
+	Email      string     `json:"email"`
+	UserID     string     `json:"user_id,omitempty"` // Empty until the invitation is accepted
+	Name       string     `json:"name,omitempty"`
+	Role       string     `json:"role"`
+	CreatedAt  time.Time  `json:"created_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+// ContextInvitation is a context shared with the user, as the user sees it
+type ContextInvitation struct {
+	ID        int64     `json:"id"`
+	ContextID string    `json:"context_id"`
+	Context   string    `json:"context"`
+	Owner     string    `json:"owner"` // Name of the account sharing the context
+	Email     string    `json:"-"` // The address invited
+	Role      string    `json:"role"`
+	Accepted  bool      `json:"accepted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ContextAccess is whose notes a context name leads a user to: their own, or
+// those of the owner of a context shared with them
+type ContextAccess struct {
+	OwnerID   string
+	ContextID string // Empty when there is no context of that name
+	Role      string // MemberRead or MemberWrite for shared contexts; empty for the user's own
+}
+
+// InviteMemberRequest shares a context with the account of an email address
+type InviteMemberRequest struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+	Role  string `json:"role" validate:"required,oneof=read write"`
+}
+
+// EncryptionKey is how a user's clients derive the key their notes are
+// encrypted with from the passphrase. The server keeps only a hash of the
+// key's verifier (see e2ee.Verifier), never the key.
+type EncryptionKey struct {
+	UserID       string    `json:"-"`
+	Cipher       string    `json:"cipher"`
+	KDF          string    `json:"kdf"`
+	Iterations   int       `json:"iterations"`
+	Salt         string    `json:"salt"` // Base64
+	VerifierHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// EncryptionStatus is whether a user's notes are end-to-end encrypted, and how
+// many of their notes still aren't, for clients to encrypt
+type EncryptionStatus struct {
+	Enabled        bool           `json:"enabled"`
+	Key            *EncryptionKey `json:"key,omitempty"`
+	EncryptedNotes int            `json:"encrypted_notes"`
+	PlaintextNotes int            `json:"plaintext_notes"`
+}
+
+// EnableEncryptionRequest turns on end-to-end encryption with the parameters
+// the client derived its key with
+type EnableEncryptionRequest struct {
+	Cipher     string `json:"cipher" validate:"required,oneof=AES-256-GCM"`
+	KDF        string `json:"kdf" validate:"required,oneof=PBKDF2-SHA256"`
+	Iterations int    `json:"iterations" validate:"gte=100000,lte=10000000"`
+	Salt       string `json:"salt" validate:"required,base64,min=24,max=88"`
+	Verifier   string `json:"verifier" validate:"required,base64,max=88"`
+}
+
+// VerifyEncryptionKeyRequest checks a key derived from a passphrase before a client encrypts with it
+type VerifyEncryptionKeyRequest struct {
+	Verifier string `json:"verifier" validate:"required,base64,max=88"`
+}
+
+// PublishTarget is an external blog a user publishes notes to
+// Ghost and WordPress use URL (and Username for WordPress); Hugo sites use Repo,
+// Branch, Dir and SiteURL, and URL for a contents API other than GitHub's.
+// Secret is the Ghost Admin API key, the WordPress application password or the
+// repository token, and is never returned.
+type PublishTarget struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"`
+	Kind      string    `json:"kind"` // ghost, wordpress or hugo
+	Name      string    `json:"name"`
+	URL       string    `json:"url,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	Secret    string    `json:"-"`
+	Repo      string    `json:"repo,omitempty"` // owner/name
+	Branch    string    `json:"branch,omitempty"`
+	Dir       string    `json:"dir,omitempty"` // e.g. content/posts
+	SiteURL   string    `json:"site_url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreatePublishTargetRequest adds a publish target; see PublishTarget for the fields each kind needs
+type CreatePublishTargetRequest struct {
+	Kind     string `json:"kind" validate:"required,oneof=ghost wordpress hugo"`
+	Name     string `json:"name" validate:"required,max=100"`
+	URL      string `json:"url" validate:"omitempty,url,max=500"`
+	Username string `json:"username" validate:"max=200"`
+	Secret   string `json:"secret" validate:"required,max=500"`
+	Repo     string `json:"repo" validate:"max=200"`
+	Branch   string `json:"branch" validate:"max=200"`
+	Dir      string `json:"dir" validate:"max=200"`
+	SiteURL  string `json:"site_url" validate:"omitempty,url,max=500"`
+}
+
+// Publication states
+const (
+	PublicationPending   = "pending"   // Waiting for its publish time or a retry
+	PublicationPublished = "published" // Pushed to the target; ExternalURL links to it
+	PublicationFailed    = "failed"    // Gave up after repeated errors; see Error
+)
+
+// NotePublication tracks a note published to a target
+// Publishing the note again updates the post at ExternalID.
+type NotePublication struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"-"`
+	NoteID      string     `json:"note_id"`
+	TargetID    string     `json:"target_id"`
+	Status      string     `json:"status"`
+	PublishAt   time.Time  `json:"publish_at"`
+	Attempts    int        `json:"attempts"`
+	ExternalID  string     `json:"external_id,omitempty"`
+	ExternalURL string     `json:"external_url,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// PublishNoteRequest queues a note for publishing to a target, now or at PublishAt
+type PublishNoteRequest struct {
+	Context   string     `json:"context" validate:"required,max=100,contextname"`
+	Date      string     `json:"date" validate:"required,max=20"`
+	TargetID  string     `json:"target_id" validate:"required"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+}
+
+type UpdateContextTemplateRequest struct {
+	Template string `json:"template" validate:"max=20000"`
+}
+
+// SetContextLocalOnlyRequest marks a context local only, or syncs it again
+type SetContextLocalOnlyRequest struct {
+	LocalOnly bool `json:"local_only"`
+}
+
+// SetContextLanguageRequest sets the language of a context's notes; empty uses the server default
+type SetContextLanguageRequest struct {
+	Language string `json:"language" validate:"omitempty,language"`
+}
+
+// ReorderContextsRequest lists context IDs in the order the user wants them
+type ReorderContextsRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1,max=500,dive,required,max=100"`
+}
+
+// UpdateContextRequest renames or restyles a context; a missing icon keeps the
+// current one and an empty one removes it
 type UpdateContextRequest struct {
-	Name  string `json:"name" validate:"required,min=2,max=100,contextname"`
-	Color string `json:"color" validate:"required,bulmacolor"`
+	Name  string  `json:"name" validate:"required,min=2,max=100,contextname"`
+	Color string  `json:"color" validate:"required,bulmacolor"`
+	Icon  *string `json:"icon,omitempty" validate:"omitempty,contexticon"`
 }
 
 type Session struct {
@@ -113,3 +1514,17 @@ type LoginRequest struct {
 	// For One Tap sign-in (ID token from Google)
 	IDToken string `json:"id_token,omitempty"`
 }
+
+// IdempotentResponse is the response to a write sent with an X-Idempotency-Key,
+// replayed when the user retries the same request with the same key
+type IdempotentResponse struct {
+	UserID      string
+	Method      string
+	Path        string
+	Key         string
+	RequestHash string // SHA-256 of the request body, telling retries from other requests reusing the key
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
@@ -0,0 +1,208 @@
+// Package audit records request/response pairs for users who turned on debug
+// mode, so support can reproduce sync problems such as "my content reverted".
+// Recording is opt-in per user, expires on its own and keeps a bounded ring
+// buffer per user in memory; secrets are redacted before anything is stored.
+package audit
+
+import (
+	"daily-notes/pkg/clock"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCapacity is how many entries are kept per user
+	DefaultCapacity = 200
+
+	// MaxDuration caps how long a user can keep recording on
+	MaxDuration = 24 * time.Hour
+
+	// maxBodyLength truncates recorded bodies; notes rarely exceed it
+	maxBodyLength = 16 * 1024
+)
+
+// redactedKeys are JSON fields whose values are never recorded
+var redactedKeys = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"token":         true,
+	"code":          true,
+	"password":      true,
+	"secret":        true,
+	"authorization": true,
+}
+
+// Entry is one recorded request and its response
+type Entry struct {
+	Time       time.Time         `json:"time"`
+	RequestID  string            `json:"request_id,omitempty"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Query      string            `json:"query,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"` // Revision and retry headers only
+	Status     int               `json:"status"`
+	DurationMS int64             `json:"duration_ms"`
+	Request    string            `json:"request,omitempty"`
+	Response   string            `json:"response,omitempty"`
+}
+
+type userLog struct {
+	until   time.Time
+	entries []Entry
+	next    int // Index the next entry is written to once the buffer is full
+}
+
+// Log holds the recording state and ring buffers of all users
+type Log struct {
+	mu       sync.Mutex
+	capacity int
+	clock    clock.Clock
+	users    map[string]*userLog
+}
+
+// New creates a log keeping at most capacity entries per user
+func New(capacity int, clk clock.Clock) *Log {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+	return &Log{capacity: capacity, clock: clk, users: make(map[string]*userLog)}
+}
+
+// SetClock replaces the clock used for expiry and entry timestamps
+func (l *Log) SetClock(c clock.Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock = c
+}
+
+// Now returns the log's current time
+func (l *Log) Now() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.clock.Now()
+}
+
+// Enable turns recording on for a user for d (capped at MaxDuration) and returns when it stops
+// Entries recorded earlier are kept.
+func (l *Log) Enable(userID string, d time.Duration) time.Time {
+	if d > MaxDuration {
+		d = MaxDuration
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	u, ok := l.users[userID]
+	if !ok {
+		u = &userLog{}
+		l.users[userID] = u
+	}
+	u.until = l.clock.Now().Add(d)
+	return u.until
+}
+
+// Disable stops recording for a user; recorded entries stay retrievable
+func (l *Log) Disable(userID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if u, ok := l.users[userID]; ok {
+		u.until = time.Time{}
+	}
+}
+
+// Clear stops recording for a user and drops their entries
+func (l *Log) Clear(userID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.users, userID)
+}
+
+// Active reports whether a user is being recorded, and until when
+func (l *Log) Active(userID string) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	u, ok := l.users[userID]
+	if !ok || !l.clock.Now().Before(u.until) {
+		return time.Time{}, false
+	}
+	return u.until, true
+}
+
+// Record stores an entry if the user is being recorded, evicting the oldest when full
+func (l *Log) Record(userID string, e Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	u, ok := l.users[userID]
+	if !ok || !l.clock.Now().Before(u.until) {
+		return
+	}
+
+	if len(u.entries) < l.capacity {
+		u.entries = append(u.entries, e)
+		return
+	}
+	u.entries[u.next] = e
+	u.next = (u.next + 1) % l.capacity
+}
+
+// Entries returns a user's recorded entries, oldest first
+func (l *Log) Entries(userID string) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	u, ok := l.users[userID]
+	if !ok {
+		return []Entry{}
+	}
+
+	entries := make([]Entry, 0, len(u.entries))
+	entries = append(entries, u.entries[u.next:]...)
+	entries = append(entries, u.entries[:u.next]...)
+	return entries
+}
+
+// Sanitize returns body as text with secret JSON fields redacted, truncated to a bounded length
+// Bodies that are not JSON are kept as-is apart from truncation.
+func Sanitize(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err == nil {
+		if redacted, err := json.Marshal(redact(value)); err == nil {
+			body = redacted
+		}
+	}
+
+	if len(body) > maxBodyLength {
+		return fmt.Sprintf("%s…(%d more bytes)", body[:maxBodyLength], len(body)-maxBodyLength)
+	}
+	return string(body)
+}
+
+// redact replaces the values of redactedKeys anywhere in a decoded JSON value
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if redactedKeys[strings.ToLower(key)] {
+				v[key] = "[redacted]"
+				continue
+			}
+			v[key] = redact(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"daily-notes/pkg/clock"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	start := time.Date(2025, 10, 17, 9, 0, 0, 0, time.UTC)
+
+	t.Run("Records only while enabled", func(t *testing.T) {
+		clk := clock.NewFake(start)
+		l := New(10, clk)
+
+		l.Record("u1", Entry{Path: "/api/notes"})
+		assert.Empty(t, l.Entries("u1"))
+
+		until := l.Enable("u1", 30*time.Minute)
+		assert.Equal(t, start.Add(30*time.Minute), until)
+		l.Record("u1", Entry{Path: "/api/notes"})
+
+		clk.Advance(31 * time.Minute)
+		_, active := l.Active("u1")
+		assert.False(t, active)
+		l.Record("u1", Entry{Path: "/api/sync/status"})
+
+		entries := l.Entries("u1")
+		assert.Len(t, entries, 1, "entries outlive the recording window")
+		assert.Equal(t, "/api/notes", entries[0].Path)
+	})
+
+	t.Run("Duration is capped", func(t *testing.T) {
+		l := New(10, clock.NewFake(start))
+		assert.Equal(t, start.Add(MaxDuration), l.Enable("u1", 7*24*time.Hour))
+	})
+
+	t.Run("Ring buffer keeps the newest entries in order", func(t *testing.T) {
+		l := New(3, clock.NewFake(start))
+		l.Enable("u1", time.Hour)
+		for _, path := range []string{"a", "b", "c", "d", "e"} {
+			l.Record("u1", Entry{Path: path})
+		}
+
+		var paths []string
+		for _, e := range l.Entries("u1") {
+			paths = append(paths, e.Path)
+		}
+		assert.Equal(t, []string{"c", "d", "e"}, paths)
+
+		l.Clear("u1")
+		assert.Empty(t, l.Entries("u1"))
+	})
+}
+
+func TestSanitize(t *testing.T) {
+	got := Sanitize([]byte(`{"content":"my note","nested":{"refresh_token":"r1"},"items":[{"Code":"abc"}]}`))
+	assert.Contains(t, got, `"content":"my note"`)
+	assert.NotContains(t, got, "r1")
+	assert.NotContains(t, got, "abc")
+	assert.Equal(t, 2, strings.Count(got, "[redacted]"))
+
+	assert.Equal(t, "plain text", Sanitize([]byte("plain text")))
+	assert.Contains(t, Sanitize([]byte(strings.Repeat("x", maxBodyLength+5))), "…(5 more bytes)")
+}
@@ -0,0 +1,220 @@
+// Package backup snapshots the database on a schedule into a directory,
+// keeping only the most recent snapshots
+package backup
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	prefix     = "daily-notes-"
+	suffix     = ".db"
+	nameLayout = "20060102T150405Z"
+)
+
+// SnapshotFunc writes a consistent copy of the database to a path that doesn't exist yet
+type SnapshotFunc func(ctx context.Context, path string) error
+
+// Scheduler takes a snapshot every interval and deletes all but the newest retain
+type Scheduler struct {
+	snapshot SnapshotFunc
+	dir      string
+	interval time.Duration
+	retain   int
+	clock    clock.Clock
+	logger   *slog.Logger
+
+	mu       sync.Mutex // One snapshot at a time
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewScheduler creates a scheduler writing snapshots to dir; retain below 1 keeps one
+func NewScheduler(snapshot SnapshotFunc, dir string, interval time.Duration, retain int, logger *slog.Logger) *Scheduler {
+	if retain < 1 {
+		retain = 1
+	}
+	return &Scheduler{
+		snapshot: snapshot,
+		dir:      dir,
+		interval: interval,
+		retain:   retain,
+		clock:    clock.Real(),
+		logger:   logger,
+	}
+}
+
+// SetClock replaces the clock used to name snapshots
+func (s *Scheduler) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Dir returns the directory holding the snapshots
+func (s *Scheduler) Dir() string {
+	return s.dir
+}
+
+// Start takes a snapshot every interval until Stop. The first one is due an
+// interval after the newest snapshot in the directory, so restarts don't add
+// snapshots that push older ones out of the retention count.
+func (s *Scheduler) Start() {
+	s.stopChan = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		timer := time.NewTimer(s.firstDelay())
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				s.runScheduled()
+				timer.Reset(s.interval)
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// firstDelay is how long until the first scheduled snapshot is due
+func (s *Scheduler) firstDelay() time.Duration {
+	backups, err := List(s.dir)
+	if err != nil || len(backups) == 0 {
+		return 0
+	}
+	delay := backups[0].CreatedAt.Add(s.interval).Sub(s.clock.Now())
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Stop stops the schedule, waiting for a snapshot in progress
+func (s *Scheduler) Stop() {
+	if s.stopChan == nil {
+		return
+	}
+	close(s.stopChan)
+	<-s.done
+	s.stopChan = nil
+}
+
+func (s *Scheduler) runScheduled() {
+	backup, err := s.Run(context.Background())
+	if err != nil {
+		s.logger.Error("database backup failed", "dir", s.dir, "error", err)
+		return
+	}
+	s.logger.Info("database backed up", "name", backup.Name, "size", backup.Size)
+}
+
+// Run takes a snapshot now and deletes the snapshots beyond the retention count
+func (s *Scheduler) Run(ctx context.Context) (*models.Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := s.clock.Now().UTC()
+	name := prefix + now.Format(nameLayout) + suffix
+	path := filepath.Join(s.dir, name)
+
+	// Snapshots are written under a temporary name so a crash never leaves a partial one listed
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if err := s.snapshot(ctx, tmp); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.prune(); err != nil {
+		s.logger.Warn("failed to delete old backups", "dir", s.dir, "error", err)
+	}
+	return &models.Backup{Name: name, Size: info.Size(), CreatedAt: now}, nil
+}
+
+// List returns the snapshots in the directory, newest first
+func (s *Scheduler) List() ([]models.Backup, error) {
+	return List(s.dir)
+}
+
+// prune deletes all but the newest retain snapshots
+func (s *Scheduler) prune() error {
+	backups, err := List(s.dir)
+	if err != nil {
+		return err
+	}
+	for i := s.retain; i < len(backups); i++ {
+		if err := os.Remove(filepath.Join(s.dir, backups[i].Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the snapshots in dir, newest first; a missing dir has none
+func List(dir string) ([]models.Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []models.Backup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []models.Backup{}
+	for _, entry := range entries {
+		createdAt, ok := parseName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, models.Backup{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// Resolve finds a snapshot: a path to a file, or the name of one in dir
+func Resolve(dir, name string) string {
+	if _, err := os.Stat(name); err == nil || dir == "" || strings.ContainsRune(name, filepath.Separator) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// parseName returns the time in a snapshot's file name
+func parseName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(nameLayout, strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix))
+	return t, err == nil
+}
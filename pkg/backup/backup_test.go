@@ -0,0 +1,80 @@
+package backup
+
+import (
+	"context"
+	"daily-notes/pkg/clock"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSnapshot(ctx context.Context, path string) error {
+	return os.WriteFile(path, []byte("snapshot"), 0600)
+}
+
+func TestSchedulerRun(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	now := time.Date(2025, 10, 16, 3, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+
+	s := NewScheduler(writeSnapshot, dir, time.Hour, 2, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.SetClock(fake)
+
+	for i := 0; i < 3; i++ {
+		b, err := s.Run(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(len("snapshot")), b.Size)
+		fake.Advance(24 * time.Hour)
+	}
+
+	backups, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, backups, 2, "older backups beyond the retention count are deleted")
+	assert.Equal(t, "daily-notes-20251018T030000Z.db", backups[0].Name)
+	assert.Equal(t, "daily-notes-20251017T030000Z.db", backups[1].Name)
+	assert.True(t, backups[1].CreatedAt.Equal(now.Add(24*time.Hour)))
+
+	t.Run("Failed snapshots leave nothing behind", func(t *testing.T) {
+		failing := NewScheduler(func(ctx context.Context, path string) error {
+			os.WriteFile(path, []byte("partial"), 0600)
+			return errors.New("disk full")
+		}, dir, time.Hour, 2, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		_, err := failing.Run(context.Background())
+		assert.Error(t, err)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"daily-notes-20251016T030000Z.db", "notes.db", "daily-notes-20251017T030000Z.db.tmp"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+
+	backups, err := List(dir)
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.Equal(t, "daily-notes-20251016T030000Z.db", backups[0].Name)
+
+	backups, err = List(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, filepath.Join(dir, "daily-notes-20251016T030000Z.db"), Resolve(dir, "daily-notes-20251016T030000Z.db"))
+	assert.Equal(t, "/tmp/copy.db", Resolve(dir, "/tmp/copy.db"))
+	assert.Equal(t, "copy.db", Resolve("", "copy.db"))
+}
@@ -0,0 +1,43 @@
+// Package buildinfo identifies the running build and checks GitHub for newer releases.
+// Version, Commit and Date are set at link time (see the Makefile):
+//
+//	go build -ldflags "-X daily-notes/pkg/buildinfo.Version=1.3.0 -X daily-notes/pkg/buildinfo.Commit=..."
+//
+// Builds without them fall back to the VCS details Go stamps into binaries built
+// from a checkout.
+package buildinfo
+
+import (
+	"daily-notes/models"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at link time
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = "" // RFC 3339
+)
+
+// Get returns the build details of the running binary
+func Get() models.BuildInfo {
+	info := models.BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: Date,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+	return info
+}
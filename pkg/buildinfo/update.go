@@ -0,0 +1,106 @@
+package buildinfo
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/changelog"
+	"daily-notes/pkg/clock"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CheckInterval is how long the outcome of a successful check is reused
+	// GitHub allows 60 unauthenticated API requests an hour per address.
+	CheckInterval = 6 * time.Hour
+	// retryInterval is how long a failed check is reused
+	retryInterval = 15 * time.Minute
+)
+
+// UpdateChecker compares the running version with the latest release of a GitHub repository
+type UpdateChecker struct {
+	repo    string // owner/name
+	apiURL  string
+	current string
+	client  *http.Client
+	clock   clock.Clock
+
+	mu   sync.Mutex
+	last *models.UpdateStatus
+}
+
+// NewUpdateChecker creates a checker for the releases of repo ("owner/name")
+func NewUpdateChecker(repo string) *UpdateChecker {
+	return &UpdateChecker{
+		repo:    repo,
+		apiURL:  "https://api.github.com",
+		current: Version,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		clock:   clock.Real(),
+	}
+}
+
+// Check returns whether a newer release than the running version exists
+// Results are cached (see CheckInterval); failures are reported in the status
+// rather than returned. Development builds never have an update available.
+func (u *UpdateChecker) Check(ctx context.Context) models.UpdateStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.last != nil {
+		reuse := CheckInterval
+		if u.last.Error != "" {
+			reuse = retryInterval
+		}
+		if u.clock.Now().Before(u.last.CheckedAt.Add(reuse)) {
+			return *u.last
+		}
+	}
+
+	status := models.UpdateStatus{CheckedAt: u.clock.Now()}
+	latest, url, err := u.latestRelease(ctx)
+	if err != nil {
+		status.Error = err.Error()
+	} else {
+		status.LatestVersion = latest
+		status.ReleaseURL = url
+		status.UpdateAvailable = changelog.IsVersion(u.current) && changelog.Compare(latest, u.current) > 0
+	}
+	u.last = &status
+	return status
+}
+
+// latestRelease returns the version and page of the repository's latest release
+func (u *UpdateChecker) latestRelease(ctx context.Context) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/repos/%s/releases/latest", u.apiURL, u.repo), nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("GitHub answered %s", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", "", err
+	}
+	version := strings.TrimPrefix(release.TagName, "v")
+	if !changelog.IsVersion(version) {
+		return "", "", fmt.Errorf("latest release %q is not a version", release.TagName)
+	}
+	return version, release.HTMLURL, nil
+}
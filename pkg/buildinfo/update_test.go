@@ -0,0 +1,73 @@
+package buildinfo
+
+import (
+	"context"
+	"daily-notes/pkg/clock"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateChecker(t *testing.T) {
+	tag := "v1.4.0"
+	status := http.StatusOK
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/repos/gmoqa/daily-notes/releases/latest", r.URL.Path)
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"tag_name": %q, "html_url": "https://github.com/gmoqa/daily-notes/releases/tag/%s"}`, tag, tag)
+	}))
+	defer server.Close()
+
+	fake := clock.NewFake(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	newChecker := func(current string) *UpdateChecker {
+		checker := NewUpdateChecker("gmoqa/daily-notes")
+		checker.apiURL = server.URL
+		checker.current = current
+		checker.clock = fake
+		return checker
+	}
+
+	t.Run("Newer releases are reported", func(t *testing.T) {
+		result := newChecker("1.3.0").Check(context.Background())
+		assert.True(t, result.UpdateAvailable)
+		assert.Equal(t, "1.4.0", result.LatestVersion)
+		assert.Equal(t, "https://github.com/gmoqa/daily-notes/releases/tag/v1.4.0", result.ReleaseURL)
+		assert.Empty(t, result.Error)
+	})
+
+	t.Run("Current and development builds are up to date", func(t *testing.T) {
+		assert.False(t, newChecker("1.4.0").Check(context.Background()).UpdateAvailable)
+		assert.False(t, newChecker("dev").Check(context.Background()).UpdateAvailable)
+	})
+
+	t.Run("Results are cached", func(t *testing.T) {
+		checker := newChecker("1.3.0")
+		requests = 0
+		checker.Check(context.Background())
+		fake.Advance(time.Hour)
+		checker.Check(context.Background())
+		assert.Equal(t, 1, requests)
+
+		fake.Advance(CheckInterval)
+		checker.Check(context.Background())
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("Failures are reported and retried sooner", func(t *testing.T) {
+		checker := newChecker("1.3.0")
+		status = http.StatusForbidden
+		result := checker.Check(context.Background())
+		assert.False(t, result.UpdateAvailable)
+		assert.Contains(t, result.Error, "403")
+
+		status = http.StatusOK
+		fake.Advance(retryInterval)
+		assert.True(t, checker.Check(context.Background()).UpdateAvailable)
+	})
+}
@@ -0,0 +1,161 @@
+// Package changelog serves the user-facing release notes embedded in the binary
+// (changelog.json, newest release first) and tells which of them a user hasn't seen.
+// Versions are MAJOR.MINOR.PATCH.
+package changelog
+
+import (
+	"daily-notes/models"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:embed changelog.json
+var embedded []byte
+
+// releases is the embedded changelog; a broken file fails at startup, see Parse
+var releases = mustParse(embedded)
+
+// Note kinds
+const (
+	Feature     = "feature"
+	Improvement = "improvement"
+	Fix         = "fix"
+)
+
+// Releases returns every release, newest first
+func Releases() []models.Release {
+	return releases
+}
+
+// Current returns the newest version
+func Current() string {
+	return releases[0].Version
+}
+
+// Has reports whether version is in the changelog
+func Has(version string) bool {
+	for _, release := range releases {
+		if release.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// ForUser builds the changelog shown to a user who last saw lastSeen
+// Users who never saw one are only shown the current release, not the whole history.
+func ForUser(lastSeen string) models.Changelog {
+	changelog := models.Changelog{
+		CurrentVersion:  Current(),
+		LastSeenVersion: lastSeen,
+		Unseen:          []models.Release{},
+		Releases:        releases,
+	}
+	if lastSeen == "" {
+		changelog.Unseen = append(changelog.Unseen, releases[0])
+	} else {
+		for _, release := range releases {
+			if Compare(release.Version, lastSeen) <= 0 {
+				break
+			}
+			changelog.Unseen = append(changelog.Unseen, release)
+		}
+	}
+	for _, release := range changelog.Unseen {
+		if release.RequiresReconsent {
+			changelog.RequiresReconsent = true
+		}
+	}
+	return changelog
+}
+
+// Parse reads a changelog and checks that it lists at least one release, newest
+// first, each with a date and notes of a known kind
+func Parse(data []byte) ([]models.Release, error) {
+	var parsed []models.Release
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+	if len(parsed) == 0 {
+		return nil, errors.New("changelog has no releases")
+	}
+
+	for i, release := range parsed {
+		if _, err := parseVersion(release.Version); err != nil {
+			return nil, err
+		}
+		if i > 0 && Compare(release.Version, parsed[i-1].Version) >= 0 {
+			return nil, fmt.Errorf("release %s is listed after %s", release.Version, parsed[i-1].Version)
+		}
+		if release.Date == "" || len(release.Notes) == 0 {
+			return nil, fmt.Errorf("release %s needs a date and notes", release.Version)
+		}
+		if release.RequiresReconsent && len(release.Scopes) == 0 {
+			return nil, fmt.Errorf("release %s requires reconsent without naming the scopes", release.Version)
+		}
+		for _, note := range release.Notes {
+			if note.Kind != Feature && note.Kind != Improvement && note.Kind != Fix {
+				return nil, fmt.Errorf("release %s has a note of unknown kind %q", release.Version, note.Kind)
+			}
+		}
+	}
+	return parsed, nil
+}
+
+func mustParse(data []byte) []models.Release {
+	parsed, err := Parse(data)
+	if err != nil {
+		panic("changelog: " + err.Error())
+	}
+	return parsed
+}
+
+// Compare returns -1, 0 or 1 as version a is older than, the same as or newer than b
+// Versions that don't parse count as older than any that do.
+func Compare(a, b string) int {
+	va, errA := parseVersion(a)
+	vb, errB := parseVersion(b)
+	switch {
+	case errA != nil && errB != nil:
+		return 0
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			if va[i] < vb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// IsVersion reports whether version is a MAJOR.MINOR.PATCH version Compare can order
+func IsVersion(version string) bool {
+	_, err := parseVersion(version)
+	return err == nil
+}
+
+func parseVersion(version string) ([3]int, error) {
+	var parts [3]int
+	fields := strings.Split(version, ".")
+	if len(fields) != 3 {
+		return parts, fmt.Errorf("invalid version %q", version)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, fmt.Errorf("invalid version %q", version)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}
@@ -0,0 +1,73 @@
+package changelog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedChangelog(t *testing.T) {
+	parsed, err := Parse(embedded)
+	require.NoError(t, err)
+	assert.Equal(t, parsed[0].Version, Current())
+	assert.True(t, Has(Current()))
+	assert.False(t, Has("0.0.1"))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"valid", `[{"version": "1.1.0", "date": "2026-06-02", "notes": [{"kind": "fix", "text": "x"}]},
+			{"version": "1.0.0", "date": "2026-03-09", "notes": [{"kind": "feature", "text": "y"}]}]`, ""},
+		{"empty", `[]`, "no releases"},
+		{"bad version", `[{"version": "1.1", "date": "2026-06-02", "notes": [{"kind": "fix", "text": "x"}]}]`, "invalid version"},
+		{"oldest first", `[{"version": "1.0.0", "date": "2026-03-09", "notes": [{"kind": "fix", "text": "x"}]},
+			{"version": "1.1.0", "date": "2026-06-02", "notes": [{"kind": "fix", "text": "y"}]}]`, "listed after"},
+		{"no notes", `[{"version": "1.0.0", "date": "2026-03-09", "notes": []}]`, "needs a date and notes"},
+		{"unknown kind", `[{"version": "1.0.0", "date": "2026-03-09", "notes": [{"kind": "news", "text": "x"}]}]`, "unknown kind"},
+		{"reconsent without scopes", `[{"version": "1.0.0", "date": "2026-03-09", "requires_reconsent": true,
+			"notes": [{"kind": "feature", "text": "x"}]}]`, "without naming the scopes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestForUser(t *testing.T) {
+	saved := releases
+	defer func() { releases = saved }()
+
+	var err error
+	releases, err = Parse([]byte(`[
+		{"version": "1.10.0", "date": "2026-10-16", "notes": [{"kind": "feature", "text": "c"}]},
+		{"version": "1.9.1", "date": "2026-08-20", "requires_reconsent": true, "scopes": ["calendar"], "notes": [{"kind": "feature", "text": "b"}]},
+		{"version": "1.9.0", "date": "2026-06-02", "notes": [{"kind": "feature", "text": "a"}]}
+	]`))
+	require.NoError(t, err)
+
+	versions := func(lastSeen string) []string {
+		var versions []string
+		for _, release := range ForUser(lastSeen).Unseen {
+			versions = append(versions, release.Version)
+		}
+		return versions
+	}
+
+	assert.Equal(t, []string{"1.10.0"}, versions(""), "new users only see the current release")
+	assert.Equal(t, []string{"1.10.0", "1.9.1"}, versions("1.9.0"))
+	assert.Nil(t, versions("1.10.0"))
+
+	assert.True(t, ForUser("1.9.0").RequiresReconsent)
+	assert.False(t, ForUser("1.9.1").RequiresReconsent)
+}
@@ -0,0 +1,141 @@
+// Package classifier provides a small multinomial naive Bayes text classifier
+// used to suggest which context a piece of text belongs to.
+package classifier
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// minTokenLength skips very short words that carry little signal
+const minTokenLength = 3
+
+// stopwords are frequent words ignored during tokenization (English and Spanish)
+var stopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true, "this": true,
+	"are": true, "was": true, "were": true, "have": true, "has": true, "had": true,
+	"but": true, "not": true, "you": true, "your": true, "from": true, "they": true,
+	"will": true, "would": true, "there": true, "their": true, "what": true, "about": true,
+	"into": true, "can": true, "just": true, "all": true, "our": true, "out": true,
+	"los": true, "las": true, "del": true, "que": true, "por": true, "para": true,
+	"con": true, "una": true, "uno": true, "como": true, "pero": true, "mas": true,
+	"este": true, "esta": true, "son": true, "fue": true, "hay": true, "sin": true,
+}
+
+// Tokenize splits text into lowercase word tokens, dropping stopwords,
+// markdown punctuation and very short words
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) < minTokenLength || stopwords[field] {
+			continue
+		}
+		tokens = append(tokens, field)
+	}
+	return tokens
+}
+
+// Prediction is a scored label
+type Prediction struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"` // Probability in [0, 1]
+}
+
+// NaiveBayes is a multinomial naive Bayes classifier with Laplace smoothing
+// It is not safe for concurrent training; build one per request
+type NaiveBayes struct {
+	docCounts  map[string]int
+	wordCounts map[string]map[string]int
+	totalWords map[string]int
+	vocabulary map[string]bool
+	totalDocs  int
+}
+
+// New creates an empty classifier
+func New() *NaiveBayes {
+	return &NaiveBayes{
+		docCounts:  make(map[string]int),
+		wordCounts: make(map[string]map[string]int),
+		totalWords: make(map[string]int),
+		vocabulary: make(map[string]bool),
+	}
+}
+
+// Train adds a document with a known label
+func (nb *NaiveBayes) Train(label, text string) {
+	tokens := Tokenize(text)
+	if len(tokens) == 0 {
+		return
+	}
+
+	if nb.wordCounts[label] == nil {
+		nb.wordCounts[label] = make(map[string]int)
+	}
+
+	nb.docCounts[label]++
+	nb.totalDocs++
+	for _, token := range tokens {
+		nb.wordCounts[label][token]++
+		nb.totalWords[label]++
+		nb.vocabulary[token] = true
+	}
+}
+
+// Labels returns the number of distinct labels seen during training
+func (nb *NaiveBayes) Labels() int {
+	return len(nb.docCounts)
+}
+
+// Predict scores every trained label for the given text, best match first
+// Returns nil if the classifier has no training data or the text has no usable words
+func (nb *NaiveBayes) Predict(text string) []Prediction {
+	tokens := Tokenize(text)
+	if nb.totalDocs == 0 || len(tokens) == 0 {
+		return nil
+	}
+
+	vocabSize := float64(len(nb.vocabulary))
+	logScores := make(map[string]float64, len(nb.docCounts))
+	maxLog := math.Inf(-1)
+
+	for label, docs := range nb.docCounts {
+		score := math.Log(float64(docs) / float64(nb.totalDocs))
+		denominator := float64(nb.totalWords[label]) + vocabSize
+		for _, token := range tokens {
+			score += math.Log((float64(nb.wordCounts[label][token]) + 1) / denominator)
+		}
+		logScores[label] = score
+		if score > maxLog {
+			maxLog = score
+		}
+	}
+
+	// Normalize log scores into probabilities (log-sum-exp)
+	var sum float64
+	for _, score := range logScores {
+		sum += math.Exp(score - maxLog)
+	}
+
+	predictions := make([]Prediction, 0, len(logScores))
+	for label, score := range logScores {
+		predictions = append(predictions, Prediction{
+			Label: label,
+			Score: math.Exp(score-maxLog) / sum,
+		})
+	}
+
+	sort.Slice(predictions, func(i, j int) bool {
+		if predictions[i].Score == predictions[j].Score {
+			return predictions[i].Label < predictions[j].Label
+		}
+		return predictions[i].Score > predictions[j].Score
+	})
+
+	return predictions
+}
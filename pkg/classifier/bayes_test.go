@@ -0,0 +1,50 @@
+package classifier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens := Tokenize("## Standup: Fixed the deploy pipeline, and reviewed PR #42!")
+	assert.Equal(t, []string{"standup", "fixed", "deploy", "pipeline", "reviewed"}, tokens)
+}
+
+func TestNaiveBayes_Predict(t *testing.T) {
+	nb := New()
+	nb.Train("Work", "Deploy pipeline failed, reviewed the pull request and fixed the build")
+	nb.Train("Work", "Sprint planning meeting, estimated tickets for the release")
+	nb.Train("Fitness", "Ran 5km in the park, then stretching and push-ups")
+	nb.Train("Fitness", "Gym session: squats, deadlifts, bench press")
+
+	t.Run("Ranks the most likely label first", func(t *testing.T) {
+		predictions := nb.Predict("Fixed the failing build before the release")
+		require.Len(t, predictions, 2)
+		assert.Equal(t, "Work", predictions[0].Label)
+		assert.Greater(t, predictions[0].Score, predictions[1].Score)
+		assert.InDelta(t, 1.0, predictions[0].Score+predictions[1].Score, 1e-9)
+	})
+
+	t.Run("Returns nil for text without usable words", func(t *testing.T) {
+		assert.Nil(t, nb.Predict("a b c"))
+	})
+
+	t.Run("Returns nil without training data", func(t *testing.T) {
+		assert.Nil(t, New().Predict("anything at all"))
+	})
+}
+
+func TestSimilarity(t *testing.T) {
+	scores := Similarity("kubernetes deployment rollback", []string{
+		"Rolled back the kubernetes deployment after errors",
+		"Grocery list: apples, bread, coffee",
+		"",
+	})
+
+	require.Len(t, scores, 3)
+	assert.Greater(t, scores[0], 0.0)
+	assert.Equal(t, 0.0, scores[1])
+	assert.Equal(t, 0.0, scores[2])
+}
@@ -0,0 +1,63 @@
+package classifier
+
+import "math"
+
+// Similarity scores each document against the query using TF-IDF weighted cosine similarity
+// Scores are in [0, 1] and returned in the same order as docs
+func Similarity(query string, docs []string) []float64 {
+	queryTokens := Tokenize(query)
+	docTokens := make([][]string, len(docs))
+	docFreq := make(map[string]int)
+
+	for i, doc := range docs {
+		docTokens[i] = Tokenize(doc)
+		seen := make(map[string]bool)
+		for _, token := range docTokens[i] {
+			if !seen[token] {
+				seen[token] = true
+				docFreq[token]++
+			}
+		}
+	}
+
+	// The query counts as a document too, so unseen words get a finite weight
+	total := float64(len(docs) + 1)
+	idf := func(token string) float64 {
+		return math.Log(total / float64(docFreq[token]+1))
+	}
+
+	queryVec := tfidf(queryTokens, idf)
+	scores := make([]float64, len(docs))
+	for i, tokens := range docTokens {
+		scores[i] = cosine(queryVec, tfidf(tokens, idf))
+	}
+	return scores
+}
+
+// tfidf builds a term-frequency vector weighted by inverse document frequency
+func tfidf(tokens []string, idf func(string) float64) map[string]float64 {
+	vec := make(map[string]float64)
+	for _, token := range tokens {
+		vec[token]++
+	}
+	for token, tf := range vec {
+		vec[token] = tf * idf(token)
+	}
+	return vec
+}
+
+// cosine returns the cosine similarity of two sparse vectors
+func cosine(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for token, weight := range a {
+		dot += weight * b[token]
+		normA += weight * weight
+	}
+	for _, weight := range b {
+		normB += weight * weight
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
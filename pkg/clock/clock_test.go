@@ -0,0 +1,26 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	assert.Equal(t, start, c.Now())
+	assert.Equal(t, start.Add(36*time.Hour), c.Advance(36*time.Hour))
+	assert.Equal(t, start.Add(36*time.Hour), c.Now())
+
+	c.Set(start)
+	assert.Equal(t, start, c.Now())
+}
+
+func TestReal(t *testing.T) {
+	before := time.Now()
+	now := Real().Now()
+	assert.False(t, now.Before(before))
+}
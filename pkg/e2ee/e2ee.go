@@ -0,0 +1,226 @@
+// Package e2ee defines the envelope of end-to-end encrypted notes. Clients
+// derive a key from the user's passphrase, encrypt a note with it and save the
+// envelope as the note's content. The server stores, searches and syncs the
+// envelope like any other content but never holds the key: it only checks
+// that envelopes are well-formed and, through a verifier derived from the key,
+// lets clients check a passphrase before they encrypt with it.
+//
+// An envelope is plain text, so the file in cloud storage can be decrypted on
+// its own with the passphrase:
+//
+//	-----BEGIN ENCRYPTED NOTE-----
+//	Version: 1
+//	Cipher: AES-256-GCM
+//	KDF: PBKDF2-SHA256
+//	Iterations: 600000
+//	Salt: <base64>
+//	Nonce: <base64>
+//
+//	<base64 ciphertext and GCM tag, in lines of 64>
+//	-----END ENCRYPTED NOTE-----
+package e2ee
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Envelope markers and the only version, cipher and key derivation so far
+const (
+	Begin = "-----BEGIN ENCRYPTED NOTE-----"
+	End   = "-----END ENCRYPTED NOTE-----"
+
+	Version      = 1
+	CipherAESGCM = "AES-256-GCM"
+	KDFPBKDF2    = "PBKDF2-SHA256"
+)
+
+// Bounds of the key derivation parameters clients may pick
+const (
+	MinIterations = 100_000
+	MaxIterations = 10_000_000
+	MinSaltSize   = 16
+	NonceSize     = 12
+	KeySize       = 32
+)
+
+// verifierLabel keeps the verifier apart from anything else the key could sign
+const verifierLabel = "daily-notes key verifier"
+
+// lineLength is how long the base64 lines of an envelope's body are
+const lineLength = 64
+
+// ErrMalformed is returned for content that starts like an envelope but isn't one
+var ErrMalformed = errors.New("malformed encrypted note")
+
+// Envelope is an encrypted note with what it takes to derive its key again
+type Envelope struct {
+	Version    int
+	Cipher     string
+	KDF        string
+	Iterations int
+	Salt       []byte
+	Nonce      []byte
+	Ciphertext []byte // With the GCM tag at the end
+}
+
+// IsEncrypted reports whether note content is an envelope, well-formed or not
+func IsEncrypted(content string) bool {
+	return strings.HasPrefix(content, Begin)
+}
+
+// Parse reads an envelope and checks its parameters; it doesn't decrypt
+func Parse(content string) (*Envelope, error) {
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n "), "\n")
+	if len(lines) < 3 || lines[0] != Begin || lines[len(lines)-1] != End {
+		return nil, fmt.Errorf("%w: missing %s or %s line", ErrMalformed, Begin, End)
+	}
+	lines = lines[1 : len(lines)-1]
+
+	e := &Envelope{}
+	i := 0
+	for ; i < len(lines) && lines[i] != ""; i++ {
+		name, value, ok := strings.Cut(lines[i], ": ")
+		if !ok {
+			return nil, fmt.Errorf("%w: header %q", ErrMalformed, lines[i])
+		}
+		var err error
+		switch name {
+		case "Version":
+			e.Version, err = strconv.Atoi(value)
+		case "Cipher":
+			e.Cipher = value
+		case "KDF":
+			e.KDF = value
+		case "Iterations":
+			e.Iterations, err = strconv.Atoi(value)
+		case "Salt":
+			e.Salt, err = base64.StdEncoding.DecodeString(value)
+		case "Nonce":
+			e.Nonce, err = base64.StdEncoding.DecodeString(value)
+		default:
+			return nil, fmt.Errorf("%w: unknown header %s", ErrMalformed, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: header %s", ErrMalformed, name)
+		}
+	}
+
+	body, err := base64.StdEncoding.DecodeString(strings.Join(lines[i:], ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: body isn't base64", ErrMalformed)
+	}
+	e.Ciphertext = body
+
+	if err := e.check(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// check reports the first parameter of e this version doesn't accept
+func (e *Envelope) check() error {
+	switch {
+	case e.Version != Version:
+		return fmt.Errorf("%w: unsupported version %d", ErrMalformed, e.Version)
+	case e.Cipher != CipherAESGCM:
+		return fmt.Errorf("%w: unsupported cipher %q", ErrMalformed, e.Cipher)
+	case e.KDF != KDFPBKDF2:
+		return fmt.Errorf("%w: unsupported key derivation %q", ErrMalformed, e.KDF)
+	case e.Iterations < MinIterations || e.Iterations > MaxIterations:
+		return fmt.Errorf("%w: %d iterations, want %d to %d", ErrMalformed, e.Iterations, MinIterations, MaxIterations)
+	case len(e.Salt) < MinSaltSize:
+		return fmt.Errorf("%w: salt shorter than %d bytes", ErrMalformed, MinSaltSize)
+	case len(e.Nonce) != NonceSize:
+		return fmt.Errorf("%w: nonce isn't %d bytes", ErrMalformed, NonceSize)
+	case len(e.Ciphertext) < 16:
+		return fmt.Errorf("%w: ciphertext shorter than its tag", ErrMalformed)
+	}
+	return nil
+}
+
+// String formats e as note content
+func (e *Envelope) String() string {
+	var b strings.Builder
+	b.WriteString(Begin + "\n")
+	fmt.Fprintf(&b, "Version: %d\nCipher: %s\nKDF: %s\nIterations: %d\n", e.Version, e.Cipher, e.KDF, e.Iterations)
+	b.WriteString("Salt: " + base64.StdEncoding.EncodeToString(e.Salt) + "\n")
+	b.WriteString("Nonce: " + base64.StdEncoding.EncodeToString(e.Nonce) + "\n\n")
+	body := base64.StdEncoding.EncodeToString(e.Ciphertext)
+	for len(body) > lineLength {
+		b.WriteString(body[:lineLength] + "\n")
+		body = body[lineLength:]
+	}
+	b.WriteString(body + "\n")
+	b.WriteString(End + "\n")
+	return b.String()
+}
+
+// DeriveKey derives the key of a passphrase with the given salt and iterations
+func DeriveKey(passphrase string, salt []byte, iterations int) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, passphrase, salt, iterations, KeySize)
+}
+
+// Verifier is what clients send the server to check a key: an HMAC of a fixed
+// label, which tells nothing about the key or the notes
+func Verifier(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(verifierLabel))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Seal encrypts plaintext with key into an envelope carrying the salt and
+// iterations the key was derived with
+func Seal(key []byte, salt []byte, iterations int, plaintext string) (*Envelope, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		Version:    Version,
+		Cipher:     CipherAESGCM,
+		KDF:        KDFPBKDF2,
+		Iterations: iterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, []byte(plaintext), nil),
+	}, nil
+}
+
+// Open decrypts e with key; a wrong key fails authentication
+func (e *Envelope) Open(key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := gcm.Open(nil, e.Nonce, e.Ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// newGCM returns AES-256-GCM keyed with key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes", KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,59 @@
+package e2ee
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelope(t *testing.T) {
+	salt := bytes.Repeat([]byte{7}, MinSaltSize)
+	key, err := DeriveKey("correct horse", salt, MinIterations)
+	require.NoError(t, err)
+
+	sealed, err := Seal(key, salt, MinIterations, "## Dear diary\nNobody reads this.")
+	require.NoError(t, err)
+	content := sealed.String()
+	assert.True(t, IsEncrypted(content))
+	assert.NotContains(t, content, "diary")
+
+	t.Run("Round trip", func(t *testing.T) {
+		parsed, err := Parse(content)
+		require.NoError(t, err)
+		assert.Equal(t, sealed, parsed)
+
+		plaintext, err := parsed.Open(key)
+		require.NoError(t, err)
+		assert.Equal(t, "## Dear diary\nNobody reads this.", plaintext)
+	})
+
+	t.Run("Line endings and trailing space don't matter", func(t *testing.T) {
+		_, err := Parse(strings.ReplaceAll(content, "\n", "\r\n") + "\r\n ")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Wrong passphrases fail", func(t *testing.T) {
+		wrong, err := DeriveKey("battery staple", salt, MinIterations)
+		require.NoError(t, err)
+		assert.NotEqual(t, Verifier(key), Verifier(wrong))
+		_, err = sealed.Open(wrong)
+		assert.Error(t, err)
+	})
+
+	t.Run("Malformed envelopes", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"no end":          strings.TrimSuffix(content, End+"\n"),
+			"unknown header":  strings.Replace(content, "Version: 1", "Hint: birthday", 1),
+			"weak derivation": strings.Replace(content, "Iterations: 100000", "Iterations: 1000", 1),
+			"not base64":      strings.Replace(content, "\n\n", "\n\n!", 1),
+			"plaintext body":  Begin + "\nVersion: 1\n\nDear diary\n" + End,
+		} {
+			_, err := Parse(content)
+			assert.ErrorIs(t, err, ErrMalformed, name)
+		}
+		assert.False(t, IsEncrypted("Dear diary\n"+Begin))
+	})
+}
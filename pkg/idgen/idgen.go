@@ -0,0 +1,46 @@
+// Package idgen abstracts ID generation so records created by services get
+// predictable IDs in tests and test mode.
+package idgen
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Generator produces unique string IDs
+type Generator interface {
+	NewID() string
+}
+
+// uuidGenerator issues random version 4 UUIDs
+type uuidGenerator struct{}
+
+func (uuidGenerator) NewID() string { return uuid.New().String() }
+
+// UUID returns the default generator backed by random UUIDs
+func UUID() Generator {
+	return uuidGenerator{}
+}
+
+// Sequence issues "<prefix>-1", "<prefix>-2", ... in call order
+type Sequence struct {
+	mu     sync.Mutex
+	prefix string
+	next   int
+}
+
+// NewSequence creates a deterministic generator starting at 1
+func NewSequence(prefix string) *Sequence {
+	return &Sequence{prefix: prefix, next: 1}
+}
+
+// NewID returns the next ID in the sequence
+func (s *Sequence) NewID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := fmt.Sprintf("%s-%d", s.prefix, s.next)
+	s.next++
+	return id
+}
@@ -0,0 +1,23 @@
+package idgen
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequence(t *testing.T) {
+	s := NewSequence("ctx")
+
+	assert.Equal(t, "ctx-1", s.NewID())
+	assert.Equal(t, "ctx-2", s.NewID())
+}
+
+func TestUUID(t *testing.T) {
+	a, b := UUID().NewID(), UUID().NewID()
+
+	_, err := uuid.Parse(a)
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, b)
+}
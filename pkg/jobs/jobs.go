@@ -0,0 +1,285 @@
+// Package jobs runs background work kept in the database, so work queued before
+// a restart or crash still runs. Handlers are registered per kind of job; a pool
+// of workers claims due jobs and retries failed ones with a backoff.
+package jobs
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/maintenance"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxAttempts is how often a job runs before it fails for good
+	MaxAttempts = 5
+
+	// DefaultWorkers is how many jobs run at the same time
+	DefaultWorkers = 2
+
+	// Retention is how long finished jobs are kept for inspection
+	Retention = 7 * 24 * time.Hour
+
+	pollInterval  = 5 * time.Second // How often idle workers look for due jobs
+	pruneInterval = time.Hour
+	retryBase     = time.Minute // Delay after the first failed attempt, doubling after each one
+	retryMax      = time.Hour
+)
+
+// ErrUnknownKind fails jobs of a kind no handler is registered for
+var ErrUnknownKind = errors.New("no handler for job kind")
+
+// Store keeps the jobs (see database/jobs.go)
+type Store interface {
+	EnqueueJob(ctx context.Context, job *models.Job) error
+	ClaimJob(ctx context.Context, now time.Time) (*models.Job, error)
+	CompleteJob(ctx context.Context, id int64, at time.Time) error
+	FailJob(ctx context.Context, id int64, errorMsg string, retryAt *time.Time, at time.Time) error
+	RequeueRunningJobs(ctx context.Context) (int64, error)
+	GetJobs(ctx context.Context, status string, limit int) ([]models.Job, error)
+	DeleteFinishedJobs(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Handler does the work of one job. Returned errors are retried until
+// MaxAttempts; wrap them with Permanent to fail the job right away. The context
+// is canceled when the queue stops, and a job interrupted that way runs again
+// after the next start, so handlers must be safe to repeat.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// permanentError is an error retrying won't fix
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error of a handler as one retrying won't fix
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// Decode reads the payload of a job into v
+func Decode(job *models.Job, v any) error {
+	if err := json.Unmarshal([]byte(job.Payload), v); err != nil {
+		return Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+	return nil
+}
+
+// Queue runs the jobs of a store with a pool of workers
+type Queue struct {
+	store       Store
+	workers     int
+	handlers    map[string]Handler
+	clock       clock.Clock
+	logger      *slog.Logger
+	maintenance *maintenance.Mode // No jobs start while the server is in maintenance; optional
+
+	wake     chan struct{}
+	stopChan chan struct{}
+	ctx      context.Context // Canceled by Stop to interrupt running handlers
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// New creates a queue running up to workers jobs at the same time; below 1 runs one
+func New(store Store, workers int, logger *slog.Logger) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		store:    store,
+		workers:  workers,
+		handlers: make(map[string]Handler),
+		clock:    clock.Real(),
+		logger:   logger,
+		wake:     make(chan struct{}, workers),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// SetClock replaces the clock used to decide when jobs are due
+func (q *Queue) SetClock(c clock.Clock) {
+	q.clock = c
+}
+
+// SetMaintenance holds the jobs that are due while the server is in maintenance
+// Running jobs finish. Call it before Start.
+func (q *Queue) SetMaintenance(mode *maintenance.Mode) {
+	q.maintenance = mode
+}
+
+// Handle registers the handler of a kind of job. Call it before Start.
+func (q *Queue) Handle(kind string, handler Handler) {
+	q.handlers[kind] = handler
+}
+
+// Enqueue saves a job of a user with payload as its JSON arguments, due right away
+func (q *Queue) Enqueue(ctx context.Context, userID, kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	now := q.clock.Now()
+	job := &models.Job{UserID: userID, Kind: kind, Payload: string(data), RunAt: now, CreatedAt: now}
+	if err := q.store.EnqueueJob(ctx, job); err != nil {
+		return err
+	}
+	q.Wake()
+	return nil
+}
+
+// List returns the latest jobs of every user, newest first, only those with
+// status unless it is empty
+func (q *Queue) List(ctx context.Context, status string, limit int) ([]models.Job, error) {
+	return q.store.GetJobs(ctx, status, limit)
+}
+
+// Start queues the jobs a stop or crash interrupted again and runs jobs until Stop
+func (q *Queue) Start() {
+	if requeued, err := q.store.RequeueRunningJobs(q.ctx); err != nil {
+		q.logger.Error("failed to requeue interrupted jobs", "error", err)
+	} else if requeued > 0 {
+		q.logger.Info("requeued interrupted jobs", "count", requeued)
+	}
+
+	q.stopChan = make(chan struct{})
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	q.wg.Add(1)
+	go q.prune()
+}
+
+// Stop interrupts the running jobs and waits for the workers
+func (q *Queue) Stop() {
+	if q.stopChan == nil {
+		return
+	}
+	close(q.stopChan)
+	q.cancel()
+	q.wg.Wait()
+	q.stopChan = nil
+}
+
+// Wake makes an idle worker look for due jobs now
+func (q *Queue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work runs due jobs until Stop, looking for more every pollInterval when idle
+func (q *Queue) work() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for q.runNext() {
+		}
+		select {
+		case <-ticker.C:
+		case <-q.wake:
+		case <-q.stopChan:
+			return
+		}
+	}
+}
+
+// runNext claims the job due the longest and runs it; false if none was due
+// or the queue is held for maintenance
+func (q *Queue) runNext() bool {
+	if q.maintenance.Paused() {
+		return false
+	}
+	job, err := q.store.ClaimJob(q.ctx, q.clock.Now())
+	if err != nil {
+		if q.ctx.Err() == nil {
+			q.logger.Error("failed to claim job", "error", err)
+		}
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	err = q.run(job)
+	if err != nil && q.ctx.Err() != nil {
+		// Interrupted by Stop: left running, so the next start queues it again
+		return false
+	}
+
+	// The job is finished even if the queue is stopping now
+	ctx := context.Background()
+	now := q.clock.Now()
+	if err == nil {
+		if err := q.store.CompleteJob(ctx, job.ID, now); err != nil {
+			q.logger.Error("failed to complete job", "id", job.ID, "kind", job.Kind, "error", err)
+		}
+		return true
+	}
+
+	var retryAt *time.Time
+	var permanent permanentError
+	if job.Attempts < MaxAttempts && !errors.As(err, &permanent) {
+		at := now.Add(retryDelay(job.Attempts))
+		retryAt = &at
+	}
+	q.logger.Warn("job failed", "id", job.ID, "kind", job.Kind, "user_id", job.UserID,
+		"attempt", job.Attempts, "retry", retryAt != nil, "error", err)
+	if err := q.store.FailJob(ctx, job.ID, err.Error(), retryAt, now); err != nil {
+		q.logger.Error("failed to record job failure", "id", job.ID, "kind", job.Kind, "error", err)
+	}
+	return true
+}
+
+// run calls the handler of a job, turning a panic into a failed attempt
+func (q *Queue) run(job *models.Job) (err error) {
+	handler, ok := q.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("%w %q", ErrUnknownKind, job.Kind))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(q.ctx, job)
+}
+
+// prune deletes the jobs finished longer than Retention ago, every pruneInterval
+func (q *Queue) prune() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := q.store.DeleteFinishedJobs(q.ctx, q.clock.Now().Add(-Retention)); err != nil && q.ctx.Err() == nil {
+			q.logger.Warn("failed to delete finished jobs", "error", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-q.stopChan:
+			return
+		}
+	}
+}
+
+// retryDelay is how long to wait after a job's attempt-th failed attempt
+func retryDelay(attempt int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempt && delay < retryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMax)
+}
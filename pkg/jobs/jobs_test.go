@@ -0,0 +1,234 @@
+package jobs
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/maintenance"
+	"errors"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore keeps jobs in memory like database/jobs.go keeps them in the database
+type memStore struct {
+	mu   sync.Mutex
+	jobs map[int64]*models.Job
+	next int64
+}
+
+func newMemStore() *memStore {
+	return &memStore{jobs: make(map[int64]*models.Job)}
+}
+
+func (s *memStore) EnqueueJob(_ context.Context, job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	job.ID, job.Status = s.next, models.JobQueued
+	stored := *job
+	s.jobs[job.ID] = &stored
+	return nil
+}
+
+func (s *memStore) ClaimJob(_ context.Context, now time.Time) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := int64(1); id <= s.next; id++ {
+		job, ok := s.jobs[id]
+		if ok && job.Status == models.JobQueued && !job.RunAt.After(now) {
+			job.Status = models.JobRunning
+			job.Attempts++
+			claimed := *job
+			return &claimed, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memStore) CompleteJob(_ context.Context, id int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[id].Status, s.jobs[id].FinishedAt = models.JobDone, &at
+	return nil
+}
+
+func (s *memStore) FailJob(_ context.Context, id int64, errorMsg string, retryAt *time.Time, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.jobs[id]
+	job.Error = errorMsg
+	if retryAt != nil {
+		job.Status, job.RunAt = models.JobQueued, *retryAt
+	} else {
+		job.Status, job.FinishedAt = models.JobFailed, &at
+	}
+	return nil
+}
+
+func (s *memStore) RequeueRunningJobs(context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, job := range s.jobs {
+		if job.Status == models.JobRunning {
+			job.Status = models.JobQueued
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *memStore) GetJobs(_ context.Context, status string, limit int) ([]models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := []models.Job{}
+	for _, job := range s.jobs {
+		if status == "" || job.Status == status {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID > jobs[j].ID })
+	return jobs[:min(limit, len(jobs))], nil
+}
+
+func (s *memStore) DeleteFinishedJobs(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// job returns a copy of a stored job
+func (s *memStore) job(id int64) models.Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.jobs[id]
+}
+
+func newTestQueue(store *memStore, now time.Time) (*Queue, *clock.Fake) {
+	fake := clock.NewFake(now)
+	q := New(store, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	q.SetClock(fake)
+	return q, fake
+}
+
+// waitForStatus waits until a stored job has a status
+func waitForStatus(t *testing.T, store *memStore, id int64, status string) models.Job {
+	t.Helper()
+	require.Eventually(t, func() bool { return store.job(id).Status == status }, time.Second, 5*time.Millisecond)
+	return store.job(id)
+}
+
+func TestQueue(t *testing.T) {
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	t.Run("Enqueued jobs run with their payload", func(t *testing.T) {
+		store := newMemStore()
+		q, _ := newTestQueue(store, now)
+		got := make(chan string, 1)
+		q.Handle("greet", func(_ context.Context, job *models.Job) error {
+			var payload struct{ Name string }
+			if err := Decode(job, &payload); err != nil {
+				return err
+			}
+			got <- job.UserID + ":" + payload.Name
+			return nil
+		})
+		q.Start()
+		defer q.Stop()
+
+		require.NoError(t, q.Enqueue(context.Background(), "user123", "greet", map[string]string{"Name": "Ana"}))
+		assert.Equal(t, "user123:Ana", <-got)
+		job := waitForStatus(t, store, 1, models.JobDone)
+		assert.Equal(t, 1, job.Attempts)
+	})
+
+	t.Run("Failed jobs are retried with a backoff until they fail for good", func(t *testing.T) {
+		store := newMemStore()
+		q, fake := newTestQueue(store, now)
+		q.Handle("flaky", func(context.Context, *models.Job) error { return errors.New("connection reset") })
+		q.Start()
+		defer q.Stop()
+
+		require.NoError(t, q.Enqueue(context.Background(), "user123", "flaky", nil))
+		for attempt := 1; attempt < MaxAttempts; attempt++ {
+			require.Eventually(t, func() bool { return store.job(1).Attempts == attempt && store.job(1).Status == models.JobQueued },
+				time.Second, 5*time.Millisecond)
+			job := store.job(1)
+			assert.Equal(t, fake.Now().Add(retryDelay(attempt)), job.RunAt)
+			fake.Set(job.RunAt)
+			q.Wake()
+		}
+
+		job := waitForStatus(t, store, 1, models.JobFailed)
+		assert.Equal(t, MaxAttempts, job.Attempts)
+		assert.Equal(t, "connection reset", job.Error)
+	})
+
+	t.Run("Permanent errors and unknown kinds aren't retried", func(t *testing.T) {
+		store := newMemStore()
+		q, _ := newTestQueue(store, now)
+		q.Handle("broken", func(context.Context, *models.Job) error { return Permanent(errors.New("bad payload")) })
+		q.Start()
+		defer q.Stop()
+
+		require.NoError(t, q.Enqueue(context.Background(), "user123", "broken", nil))
+		require.NoError(t, q.Enqueue(context.Background(), "user123", "missing", nil))
+		assert.Equal(t, 1, waitForStatus(t, store, 1, models.JobFailed).Attempts)
+		assert.Contains(t, waitForStatus(t, store, 2, models.JobFailed).Error, ErrUnknownKind.Error())
+	})
+
+	t.Run("Jobs interrupted by a stop run after the next start", func(t *testing.T) {
+		store := newMemStore()
+		q, _ := newTestQueue(store, now)
+		started := make(chan struct{})
+		q.Handle("slow", func(ctx context.Context, _ *models.Job) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		q.Start()
+		require.NoError(t, q.Enqueue(context.Background(), "user123", "slow", nil))
+		<-started
+		q.Stop()
+		assert.Equal(t, models.JobRunning, store.job(1).Status)
+
+		restarted, _ := newTestQueue(store, now)
+		restarted.Handle("slow", func(context.Context, *models.Job) error { return nil })
+		restarted.Start()
+		defer restarted.Stop()
+		assert.Equal(t, 2, waitForStatus(t, store, 1, models.JobDone).Attempts)
+	})
+
+	t.Run("Jobs wait while the server is in maintenance", func(t *testing.T) {
+		store := newMemStore()
+		q, _ := newTestQueue(store, now)
+		mode, err := maintenance.Open(t.TempDir())
+		require.NoError(t, err)
+		require.NoError(t, mode.Enable("migration", time.Minute, false))
+		q.SetMaintenance(mode)
+		q.Handle("greet", func(context.Context, *models.Job) error { return nil })
+		q.Start()
+		defer q.Stop()
+
+		require.NoError(t, q.Enqueue(context.Background(), "user123", "greet", nil))
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, models.JobQueued, store.job(1).Status)
+
+		_, _, err = mode.End(nil)
+		require.NoError(t, err)
+		q.Wake()
+		waitForStatus(t, store, 1, models.JobDone)
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Minute, retryDelay(1))
+	assert.Equal(t, 8*time.Minute, retryDelay(4))
+	assert.Equal(t, time.Hour, retryDelay(20))
+}
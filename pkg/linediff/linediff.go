@@ -0,0 +1,220 @@
+// Package linediff compares two texts line by line and formats the result as
+// hunks, for clients merging their version of a note with the saved one.
+package linediff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is what a diff line does to the old text
+type Op string
+
+const (
+	Equal  Op = "equal"  // The line is in both texts
+	Delete Op = "delete" // The line is only in the old text
+	Insert Op = "insert" // The line is only in the new text
+)
+
+// Line is one line of a diff
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Hunk is a run of changes with the unchanged lines around them, as in a unified
+// diff. Line numbers are 1-based; a hunk that adds lines to an empty text starts
+// at line 0 of it.
+type Hunk struct {
+	OldStart int    `json:"old_start"`
+	OldLines int    `json:"old_lines"`
+	NewStart int    `json:"new_start"`
+	NewLines int    `json:"new_lines"`
+	Lines    []Line `json:"lines"`
+}
+
+// maxCells bounds the table of the longest common subsequence; past it, the
+// changed middle of the texts is diffed as replaced outright
+const maxCells = 4 << 20
+
+// Lines returns the lines of a diff turning from into to, with as few changes
+// as the longest common subsequence of their lines allows
+func Lines(from, to string) []Line {
+	a, b := split(from), split(to)
+
+	// Lines shared at both ends need no table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{Equal, text})
+	}
+	lines = append(lines, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Equal, text})
+	}
+	return lines
+}
+
+// middle diffs the lines between the shared prefix and suffix
+func middle(a, b []string) []Line {
+	var lines []Line
+	if len(a)*len(b) > maxCells {
+		for _, text := range a {
+			lines = append(lines, Line{Delete, text})
+		}
+		for _, text := range b {
+			lines = append(lines, Line{Insert, text})
+		}
+		return lines
+	}
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Equal, a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			lines = append(lines, Line{Delete, a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Insert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{Delete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{Insert, b[j]})
+	}
+	return lines
+}
+
+// Hunks groups the changes of a diff into hunks with up to context unchanged
+// lines around them; changes closer than twice that share a hunk. Texts that
+// are the same have none.
+func Hunks(lines []Line, context int) []Hunk {
+	hunks := []Hunk{}
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			i++
+			oldLine++
+			newLine++
+			continue
+		}
+
+		// Start with the unchanged lines before the change
+		start := max(i-context, 0)
+		hunk := Hunk{OldStart: oldLine - (i - start), NewStart: newLine - (i - start)}
+
+		// Extend the hunk while the next change is within reach, then keep
+		// context unchanged lines after the last one
+		end, equal := i, 0
+		for end < len(lines) {
+			if lines[end].Op != Equal {
+				equal = 0
+			} else if equal == 2*context {
+				break
+			} else {
+				equal++
+			}
+			end++
+		}
+		end -= max(equal-context, 0)
+
+		hunk.Lines = lines[start:end]
+		for _, line := range hunk.Lines {
+			if line.Op != Insert {
+				hunk.OldLines++
+			}
+			if line.Op != Delete {
+				hunk.NewLines++
+			}
+		}
+		for _, line := range lines[i:end] {
+			if line.Op != Insert {
+				oldLine++
+			}
+			if line.Op != Delete {
+				newLine++
+			}
+		}
+		if hunk.OldLines == 0 {
+			hunk.OldStart--
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart--
+		}
+		hunks = append(hunks, hunk)
+		i = end
+	}
+	return hunks
+}
+
+// Unified formats hunks as a unified diff between texts named oldName and
+// newName, which patch and most merge tools read
+func Unified(oldName, newName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range hunks {
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", span(hunk.OldStart, hunk.OldLines), span(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case Equal:
+				b.WriteByte(' ')
+			case Delete:
+				b.WriteByte('-')
+			case Insert:
+				b.WriteByte('+')
+			}
+			b.WriteString(line.Text)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// span formats the range of a hunk header, leaving out a count of 1
+func span(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// split returns the lines of a text; a final newline doesn't start another line
+func split(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,58 @@
+package linediff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLines(t *testing.T) {
+	assert.Equal(t, []Line{
+		{Equal, "# Tasks"},
+		{Delete, "- [ ] Call Sam"},
+		{Insert, "- [x] Call Sam"},
+		{Equal, "- [ ] Buy milk"},
+		{Insert, "- [ ] Book flights"},
+	}, Lines("# Tasks\n- [ ] Call Sam\n- [ ] Buy milk\n", "# Tasks\n- [x] Call Sam\n- [ ] Buy milk\n- [ ] Book flights\n"))
+
+	assert.Equal(t, []Line{{Insert, "First"}}, Lines("", "First"))
+	assert.Equal(t, []Line{{Equal, "Same"}}, Lines("Same", "Same\n"))
+}
+
+func TestHunks(t *testing.T) {
+	from := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+
+	t.Run("Far apart changes get a hunk each", func(t *testing.T) {
+		hunks := Hunks(Lines(from, "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"), 2)
+		assert.Equal(t, `--- saved
++++ yours
+@@ -1,3 +1,3 @@
+-1
++one
+ 2
+ 3
+@@ -8,3 +8,3 @@
+ 8
+ 9
+-10
++ten
+`, Unified("saved", "yours", hunks))
+	})
+
+	t.Run("Close changes share one", func(t *testing.T) {
+		hunks := Hunks(Lines(from, "1\n2\nthree\n4\n5\n6\nseven\n8\n9\n10\n"), 2)
+		if assert.Len(t, hunks, 1) {
+			assert.Equal(t, []int{1, 9, 1, 9}, []int{hunks[0].OldStart, hunks[0].OldLines, hunks[0].NewStart, hunks[0].NewLines})
+		}
+	})
+
+	t.Run("Pure insertions and deletions", func(t *testing.T) {
+		assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1 @@\n+New\n", Unified("a", "b", Hunks(Lines("", "New\n"), 3)))
+		assert.Equal(t, "--- a\n+++ b\n@@ -1,2 +1 @@\n 1\n-2\n", Unified("a", "b", Hunks(Lines("1\n2\n", "1\n"), 3)))
+	})
+
+	t.Run("Same texts have none", func(t *testing.T) {
+		assert.Empty(t, Hunks(Lines(from, from), 3))
+		assert.Empty(t, Unified("a", "b", nil))
+	})
+}
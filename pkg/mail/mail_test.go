@@ -0,0 +1,142 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageBytes(t *testing.T) {
+	date := time.Date(2025, 10, 20, 8, 0, 0, 0, time.UTC)
+
+	t.Run("Text and HTML are alternatives", func(t *testing.T) {
+		data, err := Message{
+			To:      "Ana <ana@example.com>",
+			Subject: "Your week: 3 notes ✍️",
+			Text:    "Work\nPlans for the week",
+			HTML:    "<h2>Work</h2><p>Plans for the week</p>",
+			Headers: map[string]string{"list-unsubscribe": "<https://example.com/u/1>"},
+		}.Bytes("Daily Notes <notes@example.com>", date)
+		require.NoError(t, err)
+
+		msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+		require.NoError(t, err)
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		require.NoError(t, err)
+		assert.Equal(t, "Your week: 3 notes ✍️", subject)
+		assert.Equal(t, `"Daily Notes" <notes@example.com>`, msg.Header.Get("From"))
+		assert.Equal(t, `"Ana" <ana@example.com>`, msg.Header.Get("To"))
+		assert.Equal(t, "<https://example.com/u/1>", msg.Header.Get("List-Unsubscribe"))
+		assert.Contains(t, msg.Header.Get("Message-ID"), "@example.com>")
+		sent, err := msg.Header.Date()
+		require.NoError(t, err)
+		assert.True(t, date.Equal(sent))
+
+		mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		assert.Equal(t, "multipart/alternative", mediaType)
+		parts := multipart.NewReader(msg.Body, params["boundary"])
+		var bodies []string
+		for {
+			part, err := parts.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			body, err := io.ReadAll(quotedprintable.NewReader(part))
+			require.NoError(t, err)
+			bodies = append(bodies, part.Header.Get("Content-Type")+": "+string(body))
+		}
+		assert.Equal(t, []string{
+			"text/plain; charset=utf-8: Work\r\nPlans for the week",
+			"text/html; charset=utf-8: <h2>Work</h2><p>Plans for the week</p>",
+		}, bodies)
+	})
+
+	t.Run("Text only", func(t *testing.T) {
+		data, err := Message{To: "ana@example.com", Subject: "Hi", Text: "Hello"}.Bytes("notes@example.com", date)
+		require.NoError(t, err)
+		msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+		require.NoError(t, err)
+		assert.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"))
+		body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+		require.NoError(t, err)
+		assert.Equal(t, "Hello", string(body))
+	})
+
+	t.Run("Addresses are checked", func(t *testing.T) {
+		_, err := Message{To: "not an address", Text: "Hello"}.Bytes("notes@example.com", date)
+		assert.Error(t, err)
+		_, err = Message{To: "ana@example.com", Text: "Hello"}.Bytes("", date)
+		assert.Error(t, err)
+	})
+}
+
+func TestSMTPSend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// A server answering just enough SMTP to take one message, without STARTTLS
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ready")
+		var commands []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			commands = append(commands, line)
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case line == "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil || data == ".\r\n" {
+						break
+					}
+				}
+				reply("250 queued")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- commands
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	sender := &SMTP{Host: "127.0.0.1", Port: port, From: "Daily Notes <notes@example.com>"}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, sender.Send(ctx, Message{To: "Ana <ana@example.com>", Subject: "Hi", Text: "Hello"}))
+
+	commands := <-received
+	assert.Contains(t, commands, "MAIL FROM:<notes@example.com>")
+	assert.Contains(t, commands, "RCPT TO:<ana@example.com>")
+	assert.Contains(t, commands, "DATA")
+}
@@ -0,0 +1,290 @@
+// Package maintenance switches the server into maintenance mode, in which writes
+// are refused so backups and migrations see data that holds still. Note saves
+// can instead be journaled and replayed when maintenance ends. The state and the
+// journal are files, so both survive the restart of a migration.
+package maintenance
+
+import (
+	"bufio"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	stateFile   = "state.json"
+	journalFile = "journal.jsonl"
+	failedFile  = "failed.jsonl" // Saves that didn't replay, kept for admins to recover
+
+	// DefaultRetryAfter is how long clients are told to wait when no other time is given
+	DefaultRetryAfter = 5 * time.Minute
+)
+
+var (
+	// ErrNotJournaling is returned by Journal when saves aren't being journaled
+	// (anymore); the save should then go through as usual
+	ErrNotJournaling = errors.New("note saves aren't being journaled")
+
+	// ErrConflict is returned by Journal for a save based on a revision the note
+	// won't be at when the save replays
+	ErrConflict = errors.New("note changed since the revision the save is based on")
+)
+
+// Mode is the maintenance switch of the server
+type Mode struct {
+	dir   string
+	clock clock.Clock
+
+	mu    sync.RWMutex // Guards state
+	state models.Maintenance
+
+	journalMu sync.Mutex     // Held while the journal is written or replayed
+	pending   map[string]int // Journaled saves by note, see noteKey
+}
+
+// Open reads the maintenance state kept in dir, creating the directory if needed
+func Open(dir string) (*Mode, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create maintenance directory: %w", err)
+	}
+	m := &Mode{dir: dir, clock: clock.Real()}
+
+	data, err := os.ReadFile(filepath.Join(dir, stateFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &m.state); err != nil {
+			return nil, fmt.Errorf("failed to read maintenance state: %w", err)
+		}
+	}
+
+	saves, unreadable, err := m.read()
+	if err != nil {
+		return nil, err
+	}
+	m.count(saves)
+	m.state.Journaled = len(saves) + len(unreadable)
+	return m, nil
+}
+
+// SetClock replaces the clock stamping when maintenance started and saves arrived
+func (m *Mode) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// Status returns the maintenance state
+func (m *Mode) Status() models.Maintenance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Active reports whether the server is in maintenance, and for how long clients should wait
+func (m *Mode) Active() (bool, time.Duration) {
+	if m == nil {
+		return false, 0
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.Enabled, time.Duration(m.state.RetryAfter) * time.Second
+}
+
+// Paused reports whether background work that writes, like pulls from storage
+// and scheduled notes, should wait as the server is in maintenance. A nil Mode
+// never pauses it.
+func (m *Mode) Paused() bool {
+	active, _ := m.Active()
+	return active
+}
+
+// Journaling reports whether note saves are journaled instead of refused
+func (m *Mode) Journaling() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.Enabled && m.state.JournalSaves
+}
+
+// Enable starts maintenance, or changes its reason, retry time and journaling;
+// a retryAfter below one second uses DefaultRetryAfter
+func (m *Mode) Enable(reason string, retryAfter time.Duration, journalSaves bool) error {
+	if retryAfter < time.Second {
+		retryAfter = DefaultRetryAfter
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state := m.state
+	if !state.Enabled {
+		now := m.clock.Now()
+		state.Enabled, state.Since = true, &now
+	}
+	state.Reason = reason
+	state.RetryAfter = int(retryAfter.Seconds())
+	state.JournalSaves = journalSaves
+	return m.save(state)
+}
+
+// Journal keeps a note save for replay when maintenance ends. revision is the
+// note's saved revision, which the saves of the note journaled before raise by
+// one each. A save based on another revision than that fails with ErrConflict,
+// as it would have when saved. Journal returns the revision the note is at once
+// the save replays, or with ErrConflict, the one it is at before.
+func (m *Mode) Journal(save models.JournaledSave, revision int) (int, error) {
+	m.journalMu.Lock()
+	defer m.journalMu.Unlock()
+	if !m.Journaling() {
+		return 0, ErrNotJournaling
+	}
+
+	revision += m.pending[noteKey(save)]
+	if save.BaseRevision != nil && *save.BaseRevision != revision {
+		return revision, ErrConflict
+	}
+
+	save.ReceivedAt = m.clock.Now()
+	line, err := json.Marshal(save)
+	if err != nil {
+		return 0, err
+	}
+	if err := appendLines(filepath.Join(m.dir, journalFile), line); err != nil {
+		return 0, err
+	}
+
+	m.pending[noteKey(save)]++
+	m.mu.Lock()
+	m.state.Journaled++
+	m.mu.Unlock()
+	return revision + 1, nil
+}
+
+// End replays the journaled saves in the order they arrived and leaves
+// maintenance. A save that fails to replay, e.g. as an earlier save of its
+// note failed, is skipped: it is returned with the error and moved to the
+// failed saves file, so it isn't lost and can't hold maintenance on. err is
+// only set when the journal can't be read or cleared; maintenance stays on then.
+func (m *Mode) End(replay func(models.JournaledSave) error) (replayed int, failed []models.FailedSave, err error) {
+	m.journalMu.Lock()
+	defer m.journalMu.Unlock()
+
+	saves, unreadable, err := m.read()
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, line := range unreadable {
+		failed = append(failed, models.FailedSave{Line: line, Error: "unreadable journal line"})
+	}
+	for i := range saves {
+		if err := replay(saves[i]); err != nil {
+			failed = append(failed, models.FailedSave{Save: &saves[i], Error: err.Error()})
+			continue
+		}
+		replayed++
+	}
+
+	if len(failed) > 0 {
+		lines := make([][]byte, len(failed))
+		for i, save := range failed {
+			if lines[i], err = json.Marshal(save); err != nil {
+				return replayed, failed, err
+			}
+		}
+		if err := appendLines(filepath.Join(m.dir, failedFile), lines...); err != nil {
+			return replayed, failed, fmt.Errorf("failed to keep the saves that didn't replay: %w", err)
+		}
+	}
+	if err := writeAtomic(filepath.Join(m.dir, journalFile), nil); err != nil {
+		return replayed, failed, err
+	}
+	m.pending = make(map[string]int)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return replayed, failed, m.save(models.Maintenance{})
+}
+
+// save persists state and makes it current; callers hold mu
+func (m *Mode) save(state models.Maintenance) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := writeAtomic(filepath.Join(m.dir, stateFile), data); err != nil {
+		return err
+	}
+	m.state = state
+	return nil
+}
+
+// read returns the journaled saves, oldest first, and the lines that aren't
+// saves, like one cut short by a crash
+func (m *Mode) read() (saves []models.JournaledSave, unreadable []string, err error) {
+	file, err := os.Open(filepath.Join(m.dir, journalFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20) // Lines hold whole notes
+	for scanner.Scan() {
+		var save models.JournaledSave
+		if err := json.Unmarshal(scanner.Bytes(), &save); err != nil {
+			unreadable = append(unreadable, scanner.Text())
+			continue
+		}
+		saves = append(saves, save)
+	}
+	return saves, unreadable, scanner.Err()
+}
+
+// count sets the journaled saves of each note from saves
+func (m *Mode) count(saves []models.JournaledSave) {
+	m.pending = make(map[string]int)
+	for _, save := range saves {
+		m.pending[noteKey(save)]++
+	}
+}
+
+// noteKey identifies the note of a save
+func noteKey(save models.JournaledSave) string {
+	owner := save.OwnerID
+	if owner == "" {
+		owner = save.UserID
+	}
+	return owner + "\x00" + save.Context + "\x00" + save.Date
+}
+
+// appendLines adds lines to a file and flushes them to disk, as the saves in
+// them were accepted and must outlive a crash
+func appendLines(path string, lines ...[]byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for _, line := range lines {
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return file.Sync()
+}
+
+// writeAtomic replaces a file with data, never leaving it half written
+func writeAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
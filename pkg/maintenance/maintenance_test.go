@@ -0,0 +1,109 @@
+package maintenance
+
+import (
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMode(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 10, 16, 3, 0, 0, 0, time.UTC)
+	at := func(revision int) *int { return &revision }
+
+	m, err := Open(dir)
+	require.NoError(t, err)
+	m.SetClock(clock.NewFake(now))
+	active, _ := m.Active()
+	assert.False(t, active)
+	assert.False(t, m.Paused())
+	_, err = m.Journal(models.JournaledSave{UserID: "u1"}, 0)
+	assert.ErrorIs(t, err, ErrNotJournaling)
+
+	require.NoError(t, m.Enable("backup", 0, true))
+	active, retryAfter := m.Active()
+	assert.True(t, active)
+	assert.True(t, m.Paused())
+	assert.Equal(t, DefaultRetryAfter, retryAfter)
+	for i, content := range []string{"first", "second", "third"} {
+		revision, err := m.Journal(models.JournaledSave{UserID: "u1", Context: "Work", Date: "2025-10-16", Content: content, BaseRevision: at(4 + i)}, 4)
+		require.NoError(t, err)
+		assert.Equal(t, 5+i, revision, "each save raises the revision of the note")
+	}
+
+	t.Run("Saves based on an older revision are refused", func(t *testing.T) {
+		revision, err := m.Journal(models.JournaledSave{UserID: "u1", Context: "Work", Date: "2025-10-16", Content: "stale", BaseRevision: at(4)}, 4)
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, 7, revision)
+		assert.Equal(t, 3, m.Status().Journaled)
+
+		revision, err = m.Journal(models.JournaledSave{UserID: "u1", Context: "Work", Date: "2025-10-17", Content: "other note", BaseRevision: at(0)}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1, revision, "notes are counted apart")
+	})
+
+	t.Run("State and journal survive a restart", func(t *testing.T) {
+		reopened, err := Open(dir)
+		require.NoError(t, err)
+		status := reopened.Status()
+		assert.True(t, status.Enabled)
+		assert.True(t, status.Since.Equal(now))
+		assert.Equal(t, "backup", status.Reason)
+		assert.Equal(t, 4, status.Journaled)
+
+		_, err = reopened.Journal(models.JournaledSave{UserID: "u1", Context: "Work", Date: "2025-10-16", BaseRevision: at(6)}, 4)
+		assert.ErrorIs(t, err, ErrConflict, "the saves of each note are counted again")
+		m = reopened
+	})
+
+	t.Run("Saves that fail to replay are skipped and kept", func(t *testing.T) {
+		// A crash in the middle of a write leaves half a line
+		file, err := os.OpenFile(filepath.Join(dir, journalFile), os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"user_id":"u1","cont`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		var replayed []string
+		n, failed, err := m.End(func(save models.JournaledSave) error {
+			if save.Content == "second" {
+				return errors.New("revision conflict")
+			}
+			replayed = append(replayed, save.Content)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, n)
+		assert.Equal(t, []string{"first", "third", "other note"}, replayed)
+		require.Len(t, failed, 2)
+		assert.Equal(t, "unreadable journal line", failed[0].Error)
+		assert.Equal(t, "second", failed[1].Save.Content)
+		assert.Equal(t, "revision conflict", failed[1].Error)
+		assert.Equal(t, models.Maintenance{}, m.Status(), "maintenance ends anyway")
+
+		kept, err := os.ReadFile(filepath.Join(dir, failedFile))
+		require.NoError(t, err)
+		assert.Contains(t, string(kept), `"content":"second"`)
+		assert.Contains(t, string(kept), `"line":"{\"user_id\":\"u1\",\"cont"`)
+	})
+
+	t.Run("Saves after the end go through as usual", func(t *testing.T) {
+		_, err := m.Journal(models.JournaledSave{UserID: "u1"}, 0)
+		assert.ErrorIs(t, err, ErrNotJournaling)
+		reopened, err := Open(dir)
+		require.NoError(t, err)
+		assert.Equal(t, models.Maintenance{}, reopened.Status())
+	})
+
+	t.Run("A nil mode never pauses", func(t *testing.T) {
+		var none *Mode
+		assert.False(t, none.Paused())
+	})
+}
@@ -0,0 +1,249 @@
+// Package markdown contains lightweight helpers for working with note content
+package markdown
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// hashtagPattern matches #tags that start a word; headings ("# Title") never match
+	// because a space follows the hash
+	hashtagPattern = regexp.MustCompile(`(?:^|[\s(\[])#([\p{L}\p{N}][\p{L}\p{N}_\-/]*)`)
+
+	// linkPattern matches bare http(s) URLs, stopping at whitespace and markdown delimiters
+	linkPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"']+`)
+
+	// taskPattern matches a checkbox list item: "- [ ] open", "* [x] done"
+	taskPattern = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.+)$`)
+
+	// reminderPattern matches a reminder at a local date and time: "@remind(2025-11-02 09:00)";
+	// the time may be left out
+	reminderPattern = regexp.MustCompile(`@remind\((\d{4}-\d{2}-\d{2})(?:[ T](\d{2}:\d{2}))?\)`)
+
+	// moodPattern matches a line giving the mood of the day: "Mood: 7/10", "- **Mood:** calm"
+	moodPattern = regexp.MustCompile(`(?i)^\s*(?:[-*+]\s+)?\**mood\**\s*:\s*\**\s*(.*\S)\s*$`)
+)
+
+// Task is a checkbox list item of a note
+type Task struct {
+	Line int // 1-based
+	Text string
+	Done bool
+}
+
+// Reminders in notes
+const (
+	ReminderLayout      = "2006-01-02 15:04" // Of Reminder.At, a local time
+	DefaultReminderTime = "09:00"            // Of reminders written without a time
+	maxReminderText     = 200
+)
+
+// Reminder is a reminder written in a note
+type Reminder struct {
+	Line int    // 1-based
+	Text string // The rest of its line, e.g. the task it is about
+	At   string // Local time in ReminderLayout
+}
+
+// ExtractHashtags returns the unique, lowercased #tags found in content, in order of appearance
+func ExtractHashtags(content string) []string {
+	matches := hashtagPattern.FindAllStringSubmatch(content, -1)
+
+	seen := make(map[string]bool, len(matches))
+	tags := make([]string, 0, len(matches))
+	for _, match := range matches {
+		tag := strings.ToLower(strings.TrimRight(match[1], "-/"))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// ReplaceHashtag rewrites every #tag of content that is from, in any case, as #to
+// Longer tags that start like from, such as #from-notes, are left alone.
+func ReplaceHashtag(content, from, to string) string {
+	return hashtagPattern.ReplaceAllStringFunc(content, func(match string) string {
+		hash := strings.IndexByte(match, '#')
+		name := match[hash+1:]
+		tag := strings.TrimRight(name, "-/")
+		if strings.ToLower(tag) != from {
+			return match
+		}
+		return match[:hash+1] + to + name[len(tag):]
+	})
+}
+
+// IsHashtag reports whether name, without its #, is a tag as ExtractHashtags finds them
+func IsHashtag(name string) bool {
+	tags := ExtractHashtags("#" + name)
+	return len(tags) == 1 && tags[0] == strings.ToLower(name)
+}
+
+// ExtractLinks returns the unique URLs found in content, in order of appearance
+func ExtractLinks(content string) []string {
+	matches := linkPattern.FindAllString(content, -1)
+
+	seen := make(map[string]bool, len(matches))
+	links := make([]string, 0, len(matches))
+	for _, link := range matches {
+		link = strings.TrimRight(link, ".,;:!?")
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+	return links
+}
+
+// ExtractTasks returns the checkbox list items of content in order of appearance
+// Items inside fenced code blocks are ignored.
+func ExtractTasks(content string) []Task {
+	var tasks []Task
+	for i, line := range linesOutsideCode(content) {
+		if match := taskPattern.FindStringSubmatch(line); match != nil {
+			tasks = append(tasks, Task{Line: i + 1, Text: strings.TrimSpace(match[2]), Done: match[1] != " "})
+		}
+	}
+	return tasks
+}
+
+// CheckTask checks or unchecks the task on a 1-based line of content. It
+// reports false, leaving content alone, unless that line is a task reading text.
+func CheckTask(content string, line int, text string, done bool) (string, bool) {
+	tasks := ExtractTasks(content)
+	i := slices.IndexFunc(tasks, func(t Task) bool { return t.Line == line })
+	if i < 0 || tasks[i].Text != text {
+		return content, false
+	}
+
+	lines := strings.Split(content, "\n")
+	mark := "[ ]"
+	if done {
+		mark = "[x]"
+	}
+	box := strings.Index(lines[line-1], "[")
+	lines[line-1] = lines[line-1][:box] + mark + lines[line-1][box+3:]
+	return strings.Join(lines, "\n"), true
+}
+
+// ExtractReminders returns the @remind(...) tokens of content with valid dates
+// and times, in order of appearance. Their text is the rest of the line without
+// list markers or checkbox. Tokens inside fenced code blocks are ignored.
+func ExtractReminders(content string) []Reminder {
+	var reminders []Reminder
+	for i, line := range linesOutsideCode(content) {
+		matches := reminderPattern.FindAllStringSubmatch(line, -1)
+		if matches == nil {
+			continue
+		}
+		text := reminderPattern.ReplaceAllString(line, "")
+		if match := taskPattern.FindStringSubmatch(text); match != nil {
+			text = match[2]
+		}
+		text = Excerpt(text, maxReminderText)
+		for _, match := range matches {
+			at := match[1] + " " + DefaultReminderTime
+			if match[2] != "" {
+				at = match[1] + " " + match[2]
+			}
+			if _, err := time.Parse(ReminderLayout, at); err != nil {
+				continue
+			}
+			reminders = append(reminders, Reminder{Line: i + 1, Text: text, At: at})
+		}
+	}
+	return reminders
+}
+
+// ExtractMood returns the value of the first "Mood: ..." line of content, as
+// written ("7/10", "calm"), or "" when there is none. Lines in code blocks are ignored.
+func ExtractMood(content string) string {
+	for _, line := range linesOutsideCode(content) {
+		if match := moodPattern.FindStringSubmatch(line); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// WordCount counts the words of content, leaving out markdown markers such as
+// "#", "-" and checkboxes
+func WordCount(content string) int {
+	count := 0
+	for _, field := range strings.Fields(content) {
+		if field == "[x]" || field == "[X]" {
+			continue
+		}
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// CharCount counts the characters of content, markdown markers and whitespace
+// included, like an editor's status bar
+func CharCount(content string) int {
+	return utf8.RuneCountInString(content)
+}
+
+// linesOutsideCode returns the lines of content with those of fenced code blocks
+// blanked, so indexes still match the content's lines
+func linesOutsideCode(content string) []string {
+	lines := strings.Split(content, "\n")
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			lines[i] = ""
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			lines[i] = ""
+		}
+	}
+	return lines
+}
+
+// Excerpt returns the first maxLen runes of content with markdown heading and
+// list markers stripped and whitespace collapsed, suitable for previews
+func Excerpt(content string, maxLen int) string {
+	var parts []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimLeft(line, "#>-*+ ")
+		if line != "" {
+			parts = append(parts, line)
+		}
+	}
+
+	text := []rune(strings.Join(strings.Fields(strings.Join(parts, " ")), " "))
+	if len(text) <= maxLen {
+		return string(text)
+	}
+	return strings.TrimSpace(string(text[:maxLen])) + "…"
+}
+
+// FirstLine returns the Excerpt of the first line of content that has text,
+// e.g. as the title of a note without a heading
+func FirstLine(content string, maxLen int) string {
+	for _, line := range strings.Split(content, "\n") {
+		if text := Excerpt(line, maxLen); text != "" {
+			return text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	content := "# Heading\nWorked on #Deploy and #infra/k8s.\n(#deploy again) issue#12 is not a tag"
+	assert.Equal(t, []string{"deploy", "infra/k8s"}, ExtractHashtags(content))
+}
+
+func TestReplaceHashtag(t *testing.T) {
+	content := "# Heading\n#Deploy done, (#deploy/) and #deploy-notes.\nissue#deploy stays"
+	assert.Equal(t, "# Heading\n#release done, (#release/) and #deploy-notes.\nissue#deploy stays",
+		ReplaceHashtag(content, "deploy", "release"))
+
+	assert.True(t, IsHashtag("infra/k8s"))
+	assert.False(t, IsHashtag("two words"))
+	assert.False(t, IsHashtag("trailing-"))
+	assert.False(t, IsHashtag(""))
+}
+
+func TestExtractLinks(t *testing.T) {
+	content := "See https://example.com/a, and [docs](https://docs.example.com/x). Again: https://example.com/a"
+	assert.Equal(t, []string{"https://example.com/a", "https://docs.example.com/x"}, ExtractLinks(content))
+}
+
+func TestExtractTasks(t *testing.T) {
+	content := "## Tasks\n- [ ] Call Ana\n  * [x] Send invoice\n- plain item\n```\n- [ ] in code\n```\n+ [X] Done too"
+	assert.Equal(t, []Task{
+		{Line: 2, Text: "Call Ana"},
+		{Line: 3, Text: "Send invoice", Done: true},
+		{Line: 8, Text: "Done too", Done: true},
+	}, ExtractTasks(content))
+}
+
+func TestCheckTask(t *testing.T) {
+	content := "## Tasks\n- [ ] Call Ana\n  * [x] Send invoice\n```\n- [ ] in code\n```"
+
+	checked, ok := CheckTask(content, 2, "Call Ana", true)
+	require.True(t, ok)
+	assert.Equal(t, "## Tasks\n- [x] Call Ana\n  * [x] Send invoice\n```\n- [ ] in code\n```", checked)
+
+	checked, ok = CheckTask(checked, 3, "Send invoice", false)
+	require.True(t, ok)
+	assert.Contains(t, checked, "\n  * [ ] Send invoice\n")
+
+	for _, stale := range []struct {
+		line int
+		text string
+	}{{2, "Call Bob"}, {1, "Tasks"}, {5, "in code"}, {42, "Call Ana"}} {
+		unchanged, ok := CheckTask(content, stale.line, stale.text, true)
+		assert.False(t, ok)
+		assert.Equal(t, content, unchanged)
+	}
+}
+
+func TestExtractReminders(t *testing.T) {
+	content := "- [ ] Call Ana @remind(2025-11-02 09:30)\n@remind(2025-11-03)\nRenew passport @remind(2025-13-40 10:00) @remind(2025-12-01T08:00)\n```\n@remind(2025-11-02 10:00)\n```"
+	assert.Equal(t, []Reminder{
+		{Line: 1, Text: "Call Ana", At: "2025-11-02 09:30"},
+		{Line: 2, Text: "", At: "2025-11-03 09:00"},
+		{Line: 3, Text: "Renew passport", At: "2025-12-01 08:00"},
+	}, ExtractReminders(content))
+}
+
+func TestExtractMood(t *testing.T) {
+	assert.Equal(t, "7/10", ExtractMood("# Monday\nMood: 7/10\nmood: 3"))
+	assert.Equal(t, "calm", ExtractMood("- **Mood:** calm "))
+	assert.Equal(t, "", ExtractMood("```\nmood: 1\n```\nMoody day"))
+}
+
+func TestWordCount(t *testing.T) {
+	assert.Equal(t, 6, WordCount("## Plan\n- [ ] Call Ana #work\n\n---\n- [x] 2 invoices"))
+	assert.Equal(t, 0, WordCount("  # - > "))
+}
+
+func TestCharCount(t *testing.T) {
+	assert.Equal(t, 12, CharCount("## Café\n- ok"))
+	assert.Equal(t, 0, CharCount(""))
+}
+
+func TestExcerpt(t *testing.T) {
+	assert.Equal(t, "Title first item second", Excerpt("## Title\n- first item\n\n- second", 100))
+	assert.Equal(t, "Title…", Excerpt("## Title\n- first item", 6))
+}
+
+func TestFirstLine(t *testing.T) {
+	assert.Equal(t, "Standup", FirstLine("\n\n## Standup\n- shipped", 100))
+	assert.Equal(t, "Call the…", FirstLine("* Call the bank", 8))
+	assert.Equal(t, "", FirstLine("\n  \n#", 100))
+}
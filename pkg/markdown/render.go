@@ -0,0 +1,22 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// renderer turns note content into HTML: GitHub-flavoured markdown (tables,
+// task lists, strikethrough, bare links). Raw HTML in notes is left out and
+// javascript: and similar links are dropped, so the output is safe to serve.
+var renderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// Render returns the HTML of note content
+func Render(content string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(content), &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
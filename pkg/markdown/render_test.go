@@ -0,0 +1,25 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	html, err := Render("# Today\n\n- [x] Shipped ~~late~~\n\nSee https://example.com")
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "<h1>Today</h1>")
+	assert.Contains(t, string(html), `<input checked="" disabled="" type="checkbox"`)
+	assert.Contains(t, string(html), "<del>late</del>")
+	assert.Contains(t, string(html), `<a href="https://example.com">https://example.com</a>`)
+}
+
+func TestRenderIsSafe(t *testing.T) {
+	html, err := Render("<script>alert(1)</script>\n\n[click](javascript:alert(1)) <img src=x onerror=alert(1)>")
+	require.NoError(t, err)
+	assert.NotContains(t, string(html), "<script>")
+	assert.NotContains(t, string(html), "javascript:")
+	assert.NotContains(t, string(html), "onerror")
+}
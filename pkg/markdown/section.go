@@ -0,0 +1,214 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Section is an ATX heading ("## Tasks") and the lines it covers
+// Lines are 0-based indexes into the content split on "\n"; a section runs from
+// its heading up to, but not including, the next heading of the same or a higher level.
+type Section struct {
+	Title   string // Heading text without the leading #s
+	Slug    string // URL-safe title, unique within the note ("tasks", "tasks-1")
+	Level   int    // 1 for "#", 2 for "##", ...
+	Start   int    // Line of the heading
+	End     int    // First line after the section
+	BodyEnd int    // First line after the section's own text, i.e. the next heading of any level
+}
+
+// Sections returns the headings of content in order of appearance
+// Headings inside fenced code blocks are ignored.
+func Sections(content string) []Section {
+	lines := strings.Split(content, "\n")
+
+	var sections []Section
+	slugs := map[string]int{}
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+
+		level, title, ok := parseHeading(line)
+		if !ok {
+			continue
+		}
+		for j := len(sections) - 1; j >= 0; j-- {
+			if sections[j].End == len(lines) && sections[j].Level >= level {
+				sections[j].End = i
+			}
+		}
+		if n := len(sections); n > 0 && sections[n-1].BodyEnd == len(lines) {
+			sections[n-1].BodyEnd = i
+		}
+
+		// Repeated titles get GitHub-style suffixes: tasks, tasks-1, tasks-2
+		slug := Slug(title)
+		if count := slugs[slug]; count > 0 {
+			slugs[slug] = count + 1
+			slug += "-" + strconv.Itoa(count)
+		} else {
+			slugs[slug] = 1
+		}
+
+		sections = append(sections, Section{Title: title, Slug: slug, Level: level, Start: i, End: len(lines), BodyEnd: len(lines)})
+	}
+	return sections
+}
+
+// Slug turns a heading title into a lowercase, dash-separated identifier
+func Slug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// ReplaceSection replaces the text of the section with the given slug
+// Only the section's own text changes: its heading, nested headings and the
+// blank lines before the next heading are kept. Reports false if no section has the slug.
+func ReplaceSection(content, slug, text string) (string, bool) {
+	var section Section
+	found := false
+	for _, s := range Sections(content) {
+		if s.Slug == slug {
+			section, found = s, true
+			break
+		}
+	}
+	if !found {
+		return content, false
+	}
+
+	lines := strings.Split(content, "\n")
+	bodyEnd := section.BodyEnd
+	for bodyEnd > section.Start+1 && strings.TrimSpace(lines[bodyEnd-1]) == "" {
+		bodyEnd--
+	}
+
+	result := make([]string, 0, len(lines))
+	result = append(result, lines[:section.Start+1]...)
+	if text = strings.Trim(text, "\n"); text != "" {
+		result = append(result, text)
+	}
+	result = append(result, lines[bodyEnd:]...)
+	return strings.Join(result, "\n"), true
+}
+
+// FindSection returns the first section whose title matches heading
+// heading may include its #s ("## Tasks") to also match the level; titles are
+// compared case-insensitively.
+func FindSection(content, heading string) (Section, bool) {
+	level, title, ok := parseHeading(heading)
+	if !ok {
+		level, title = 0, strings.TrimSpace(heading)
+	}
+
+	for _, s := range Sections(content) {
+		if strings.EqualFold(s.Title, title) && (level == 0 || s.Level == level) {
+			return s, true
+		}
+	}
+	return Section{}, false
+}
+
+// AppendToSection adds text at the end of the section named by heading
+// The text goes after the section's last non-blank line, so blank lines before
+// the next heading are kept. A missing section is created at the end of content,
+// as "## heading" unless heading carries its own #s. An empty heading appends to
+// the end of content.
+func AppendToSection(content, heading, text string) string {
+	text = strings.Trim(text, "\n")
+	if strings.TrimSpace(heading) == "" {
+		return appendBlock(content, text, "\n")
+	}
+
+	section, ok := FindSection(content, heading)
+	if !ok {
+		if _, _, hasLevel := parseHeading(heading); !hasLevel {
+			heading = "## " + strings.TrimSpace(heading)
+		}
+		return appendBlock(content, strings.TrimSpace(heading)+"\n"+text, "\n\n")
+	}
+
+	lines := strings.Split(content, "\n")
+	insertAt := section.End
+	for insertAt > section.Start+1 && strings.TrimSpace(lines[insertAt-1]) == "" {
+		insertAt--
+	}
+
+	result := make([]string, 0, len(lines)+1)
+	result = append(result, lines[:insertAt]...)
+	result = append(result, text)
+	result = append(result, lines[insertAt:]...)
+	return strings.Join(result, "\n")
+}
+
+// listMarkerPattern matches the marker starting a list item or task: "- ", "1. ", "- [ ] "
+var listMarkerPattern = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?`)
+
+// PrefixEntry puts prefix, such as the time an entry was written, at the start
+// of text, after the list or task marker of its first line if it has one, so
+// "- [ ] call Bob" stays a task
+func PrefixEntry(text, prefix string) string {
+	text = strings.TrimLeft(text, "\n")
+	marker := listMarkerPattern.FindString(text)
+	return marker + prefix + " " + text[len(marker):]
+}
+
+// appendBlock adds text after content's last non-blank line, separated by sep
+func appendBlock(content, text, sep string) string {
+	trimmed := strings.TrimRight(content, "\n")
+	if strings.TrimSpace(trimmed) == "" {
+		return text + "\n"
+	}
+	return trimmed + sep + text + "\n"
+}
+
+// parseHeading splits an ATX heading line into its level and title
+func parseHeading(line string) (level int, title string, ok bool) {
+	line = strings.TrimRight(line, " \t")
+	if strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+		return 0, "", false // indented code
+	}
+	line = strings.TrimLeft(line, " ")
+
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+	if level < len(line) && line[level] != ' ' && line[level] != '\t' {
+		return 0, "", false // "#tag", not a heading
+	}
+
+	title = strings.TrimSpace(line[level:])
+	// Closing sequence: "## Tasks ##"
+	if stripped := strings.TrimRight(title, "#"); stripped != title && (stripped == "" || strings.HasSuffix(stripped, " ")) {
+		title = strings.TrimSpace(stripped)
+	}
+	return level, title, true
+}
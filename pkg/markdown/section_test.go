@@ -0,0 +1,85 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sectionNote = "# Friday\n\n## Tasks\n- [ ] ship\n\n```\n## not a heading\n```\n\n### Later\n- maybe\n\n## Notes\nquiet day\n"
+
+func TestSections(t *testing.T) {
+	sections := Sections(sectionNote)
+	require.Len(t, sections, 4)
+
+	assert.Equal(t, Section{Title: "Friday", Slug: "friday", Level: 1, Start: 0, End: 15, BodyEnd: 2}, sections[0])
+	assert.Equal(t, Section{Title: "Tasks", Slug: "tasks", Level: 2, Start: 2, End: 12, BodyEnd: 9}, sections[1])
+	assert.Equal(t, Section{Title: "Later", Slug: "later", Level: 3, Start: 9, End: 12, BodyEnd: 12}, sections[2])
+	assert.Equal(t, Section{Title: "Notes", Slug: "notes", Level: 2, Start: 12, End: 15, BodyEnd: 15}, sections[3])
+}
+
+func TestSlug(t *testing.T) {
+	assert.Equal(t, "daily-standup", Slug("Daily Standup!"))
+	assert.Equal(t, "café-q4-2025", Slug("  Café: Q4 / 2025 "))
+
+	sections := Sections("## Tasks\n## Tasks\n## Tasks")
+	assert.Equal(t, "tasks-2", sections[2].Slug)
+}
+
+func TestReplaceSection(t *testing.T) {
+	content := "# Friday\n## Standup\nold\nlines\n\n### Blockers\nnone\n## Notes\nkeep\n"
+
+	got, ok := ReplaceSection(content, "standup", "shipped the release\n")
+	require.True(t, ok)
+	assert.Equal(t, "# Friday\n## Standup\nshipped the release\n\n### Blockers\nnone\n## Notes\nkeep\n", got)
+
+	got, ok = ReplaceSection(content, "notes", "")
+	require.True(t, ok)
+	assert.Equal(t, "# Friday\n## Standup\nold\nlines\n\n### Blockers\nnone\n## Notes\n", got)
+
+	_, ok = ReplaceSection(content, "missing", "x")
+	assert.False(t, ok)
+}
+
+func TestFindSection(t *testing.T) {
+	s, ok := FindSection(sectionNote, "tasks")
+	require.True(t, ok)
+	assert.Equal(t, 2, s.Start)
+
+	_, ok = FindSection(sectionNote, "### Tasks")
+	assert.False(t, ok, "a level in the heading must match too")
+
+	_, ok = FindSection("#tasks are not headings", "tasks")
+	assert.False(t, ok)
+}
+
+func TestAppendToSection(t *testing.T) {
+	t.Run("Inserts after the section's last line", func(t *testing.T) {
+		got := AppendToSection("## Tasks\n- [ ] ship\n\n## Notes\nquiet day\n", "Tasks", "- [ ] review")
+		assert.Equal(t, "## Tasks\n- [ ] ship\n- [ ] review\n\n## Notes\nquiet day\n", got)
+	})
+
+	t.Run("Nested headings belong to the section", func(t *testing.T) {
+		got := AppendToSection("## Tasks\n### Later\n- maybe\n## Notes\n", "## Tasks", "- [ ] review")
+		assert.Equal(t, "## Tasks\n### Later\n- maybe\n- [ ] review\n## Notes\n", got)
+	})
+
+	t.Run("Missing sections are created at the end", func(t *testing.T) {
+		assert.Equal(t, "# Friday\n\n## Inbox\nidea\n", AppendToSection("# Friday\n", "Inbox", "idea"))
+		assert.Equal(t, "### Inbox\nidea\n", AppendToSection("", "### Inbox", "idea"))
+	})
+
+	t.Run("No heading appends to the end", func(t *testing.T) {
+		assert.Equal(t, "first\nsecond\n", AppendToSection("first\n\n", "", "second\n"))
+	})
+}
+
+func TestPrefixEntry(t *testing.T) {
+	assert.Equal(t, "14:05 Deployed", PrefixEntry("Deployed", "14:05"))
+	assert.Equal(t, "14:05 Deployed\nall green", PrefixEntry("\nDeployed\nall green", "14:05"))
+	assert.Equal(t, "- 14:05 milk", PrefixEntry("- milk", "14:05"))
+	assert.Equal(t, "  2. 14:05 eggs", PrefixEntry("  2. eggs", "14:05"))
+	assert.Equal(t, "- [ ] 14:05 call Bob", PrefixEntry("- [ ] call Bob", "14:05"))
+	assert.Equal(t, "14:05 #tag", PrefixEntry("#tag", "14:05"), "headings and tags aren't list markers")
+}
@@ -0,0 +1,129 @@
+// Package notetemplate renders context templates into the initial content of new notes.
+// Templates may contain placeholders such as {{date}} or {{quote}} that are resolved
+// server-side by registered providers, and lookbacks such as {{yesterday.section("Today")}}
+// or {{last_note.tasks_open}} that carry parts of earlier notes over. A failing or slow
+// provider never blocks note creation: its placeholder simply renders as an empty string.
+package notetemplate
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds how long a single provider may take to resolve
+const DefaultTimeout = 2 * time.Second
+
+// placeholderPattern matches {{name}} with optional inner whitespace
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z][a-zA-Z0-9_]*)\s*\}\}`)
+
+// Vars describe the note being created
+type Vars struct {
+	Date    time.Time
+	Context string
+	// Previous looks up the earlier note of the context a lookback refers to
+	// (Yesterday or LastNote), nil if there is none. Without it lookbacks are
+	// left untouched.
+	Previous func(ctx context.Context, ref string) (*PreviousNote, error)
+}
+
+// Provider resolves the value of a placeholder
+type Provider interface {
+	Name() string
+	Resolve(ctx context.Context, vars Vars) (string, error)
+}
+
+// Engine renders templates using its registered providers
+type Engine struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+	timeout   time.Duration
+	logger    *slog.Logger
+}
+
+// New creates an engine with the built-in {{date}}, {{weekday}} and {{context}} placeholders
+func New(logger *slog.Logger) *Engine {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	e := &Engine{
+		providers: make(map[string]Provider),
+		timeout:   DefaultTimeout,
+		logger:    logger,
+	}
+	e.Register(Func("date", func(_ context.Context, v Vars) (string, error) {
+		return v.Date.Format("2006-01-02"), nil
+	}))
+	e.Register(Func("weekday", func(_ context.Context, v Vars) (string, error) {
+		return v.Date.Weekday().String(), nil
+	}))
+	e.Register(Func("context", func(_ context.Context, v Vars) (string, error) {
+		return v.Context, nil
+	}))
+	return e
+}
+
+// Register adds a provider, replacing any provider with the same name
+func (e *Engine) Register(p Provider) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.providers[strings.ToLower(p.Name())] = p
+}
+
+// SetTimeout changes how long each provider may take
+func (e *Engine) SetTimeout(timeout time.Duration) {
+	e.timeout = timeout
+}
+
+// Render replaces every known placeholder and lookback in tmpl
+// Unknown placeholders are left untouched so literal braces in notes survive
+func (e *Engine) Render(ctx context.Context, tmpl string, vars Vars) string {
+	if !strings.Contains(tmpl, "{{") {
+		return tmpl
+	}
+
+	// Resolve each distinct placeholder once
+	resolved := make(map[string]string)
+	for _, m := range placeholderPattern.FindAllStringSubmatch(tmpl, -1) {
+		name := strings.ToLower(m[1])
+		if _, done := resolved[name]; done {
+			continue
+		}
+
+		e.mu.RLock()
+		p, ok := e.providers[name]
+		e.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		resolved[name] = e.resolve(ctx, p, vars)
+	}
+
+	rendered := placeholderPattern.ReplaceAllStringFunc(tmpl, func(match string) string {
+		name := strings.ToLower(placeholderPattern.FindStringSubmatch(match)[1])
+		if value, ok := resolved[name]; ok {
+			return value
+		}
+		return match
+	})
+
+	// Lookbacks go last so the content of earlier notes isn't rendered again
+	return e.renderLookbacks(ctx, rendered, vars)
+}
+
+// resolve runs a provider with a timeout, degrading to an empty string on failure
+func (e *Engine) resolve(ctx context.Context, p Provider, vars Vars) string {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	value, err := p.Resolve(ctx, vars)
+	if err != nil {
+		e.logger.Warn("template provider failed", "provider", p.Name(), "error", err)
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,99 @@
+package notetemplate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	date := time.Date(2025, 10, 17, 0, 0, 0, 0, time.UTC)
+	vars := Vars{Date: date, Context: "Work"}
+
+	t.Run("Built-in placeholders", func(t *testing.T) {
+		e := New(nil)
+		out := e.Render(context.Background(), "# {{context}} – {{ weekday }} {{date}}", vars)
+		assert.Equal(t, "# Work – Friday 2025-10-17", out)
+	})
+
+	t.Run("Unknown placeholders are kept", func(t *testing.T) {
+		e := New(nil)
+		assert.Equal(t, "{{nope}}", e.Render(context.Background(), "{{nope}}", vars))
+	})
+
+	t.Run("Failing provider renders empty", func(t *testing.T) {
+		e := New(nil)
+		e.Register(Func("weather", func(context.Context, Vars) (string, error) {
+			return "", errors.New("api down")
+		}))
+		assert.Equal(t, "Weather: ", e.Render(context.Background(), "Weather: {{weather}}", vars))
+	})
+
+	t.Run("Slow provider times out", func(t *testing.T) {
+		e := New(nil)
+		e.SetTimeout(10 * time.Millisecond)
+		e.Register(Func("slow", func(ctx context.Context, _ Vars) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}))
+		assert.Equal(t, "[]", e.Render(context.Background(), "[{{slow}}]", vars))
+	})
+
+	t.Run("Static list is stable per date", func(t *testing.T) {
+		e := New(nil)
+		e.Register(StaticList("quote", []string{"a", "b", "c"}))
+		first := e.Render(context.Background(), "{{quote}}", vars)
+		assert.Equal(t, first, e.Render(context.Background(), "{{quote}}", vars))
+		assert.Contains(t, []string{"a", "b", "c"}, first)
+	})
+
+	t.Run("Lookbacks", func(t *testing.T) {
+		e := New(nil)
+		lookups := 0
+		vars := vars
+		vars.Previous = func(_ context.Context, ref string) (*PreviousNote, error) {
+			lookups++
+			switch ref {
+			case Yesterday:
+				return &PreviousNote{Date: date.AddDate(0, 0, -1), Content: "# Standup\n## Today\nship it\n- [ ] review\n## Blockers\nnone\n"}, nil
+			case LastNote:
+				return nil, errors.New("database is locked")
+			}
+			return nil, nil
+		}
+
+		tmpl := "{{yesterday.date}}: {{ yesterday.section(\"today\") }}|{{yesterday.tasks_open}}|{{yesterday.tasks_done}}|{{last_note.content}}|{{tomorrow.content}}|{{yesterday.nope}}"
+		out := e.Render(context.Background(), tmpl, vars)
+		assert.Equal(t, "2025-10-16: ship it\n- [ ] review|- [ ] review|||{{tomorrow.content}}|{{yesterday.nope}}", out)
+		assert.Equal(t, 2, lookups)
+	})
+
+	t.Run("Lookbacks without previous notes are kept", func(t *testing.T) {
+		e := New(nil)
+		assert.Equal(t, "{{yesterday.content}}", e.Render(context.Background(), "{{yesterday.content}}", vars))
+	})
+}
+
+func TestCachedHTTPText(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte("Sunny +20°C\n"))
+	}))
+	defer server.Close()
+
+	p := Cached(HTTPText("weather", server.URL, nil), time.Hour)
+	vars := Vars{Date: time.Date(2025, 10, 17, 0, 0, 0, 0, time.UTC)}
+
+	for i := 0; i < 3; i++ {
+		value, err := p.Resolve(context.Background(), vars)
+		assert.NoError(t, err)
+		assert.Equal(t, "Sunny +20°C", value)
+	}
+	assert.Equal(t, 1, calls)
+}
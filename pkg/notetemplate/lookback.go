@@ -0,0 +1,106 @@
+package notetemplate
+
+import (
+	"context"
+	"daily-notes/pkg/markdown"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Lookback references name an earlier note of the same context
+const (
+	Yesterday = "yesterday" // The note of the day before
+	LastNote  = "last_note" // The latest note before the new one, however old
+)
+
+// lookbackPattern matches {{ref.field}} and {{ref.field("argument")}}
+var lookbackPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z][a-zA-Z0-9_]*)\.([a-zA-Z][a-zA-Z0-9_]*)(?:\(\s*"([^"]*)"\s*\))?\s*\}\}`)
+
+// PreviousNote is an earlier note a template refers to
+type PreviousNote struct {
+	Date    time.Time
+	Content string
+}
+
+// lookbackFields render a part of a previous note; arg is the quoted argument, if any
+var lookbackFields = map[string]func(note *PreviousNote, arg string) string{
+	"content": func(note *PreviousNote, _ string) string {
+		return strings.TrimSpace(note.Content)
+	},
+	"date": func(note *PreviousNote, _ string) string {
+		return note.Date.Format("2006-01-02")
+	},
+	"section": func(note *PreviousNote, heading string) string {
+		section, ok := markdown.FindSection(note.Content, heading)
+		if !ok {
+			return ""
+		}
+		lines := strings.Split(note.Content, "\n")
+		return strings.TrimSpace(strings.Join(lines[section.Start+1:section.End], "\n"))
+	},
+	"tasks_open": func(note *PreviousNote, _ string) string {
+		return taskList(note.Content, false)
+	},
+	"tasks_done": func(note *PreviousNote, _ string) string {
+		return taskList(note.Content, true)
+	},
+}
+
+// taskList renders the open or done checkbox tasks of content, one per line
+func taskList(content string, done bool) string {
+	var lines []string
+	for _, task := range markdown.ExtractTasks(content) {
+		if task.Done != done {
+			continue
+		}
+		if done {
+			lines = append(lines, "- [x] "+task.Text)
+		} else {
+			lines = append(lines, "- [ ] "+task.Text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderLookbacks replaces the lookback expressions of tmpl with parts of the
+// notes they refer to. Each note is looked up once; a note that doesn't exist
+// or fails to load renders every expression about it as an empty string.
+// Unknown references and fields are left untouched.
+func (e *Engine) renderLookbacks(ctx context.Context, tmpl string, vars Vars) string {
+	if vars.Previous == nil {
+		return tmpl
+	}
+
+	notes := make(map[string]*PreviousNote)
+	return lookbackPattern.ReplaceAllStringFunc(tmpl, func(match string) string {
+		m := lookbackPattern.FindStringSubmatch(match)
+		ref, field := strings.ToLower(m[1]), lookbackFields[strings.ToLower(m[2])]
+		if (ref != Yesterday && ref != LastNote) || field == nil {
+			return match
+		}
+
+		note, loaded := notes[ref]
+		if !loaded {
+			note = e.previous(ctx, ref, vars)
+			notes[ref] = note
+		}
+		if note == nil {
+			return ""
+		}
+		return field(note, m[3])
+	})
+}
+
+// previous looks up a previous note with a timeout, degrading to no note on failure
+func (e *Engine) previous(ctx context.Context, ref string, vars Vars) *PreviousNote {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	note, err := vars.Previous(ctx, ref)
+	if err != nil {
+		e.logger.Warn("template lookback failed", "ref", ref, "error", err)
+		return nil
+	}
+	return note
+}
@@ -0,0 +1,134 @@
+package notetemplate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRemoteValueLength caps values fetched from external APIs
+const maxRemoteValueLength = 500
+
+// funcProvider adapts a function to the Provider interface
+type funcProvider struct {
+	name string
+	fn   func(ctx context.Context, vars Vars) (string, error)
+}
+
+// Func creates a provider from a function
+func Func(name string, fn func(ctx context.Context, vars Vars) (string, error)) Provider {
+	return &funcProvider{name: name, fn: fn}
+}
+
+func (p *funcProvider) Name() string { return p.name }
+
+func (p *funcProvider) Resolve(ctx context.Context, vars Vars) (string, error) {
+	return p.fn(ctx, vars)
+}
+
+// StaticList picks one item per day from a fixed list, so a note always gets
+// the same value for its date
+func StaticList(name string, items []string) Provider {
+	return Func(name, func(_ context.Context, vars Vars) (string, error) {
+		if len(items) == 0 {
+			return "", nil
+		}
+		return items[vars.Date.YearDay()%len(items)], nil
+	})
+}
+
+// HTTPText fetches a plain-text value from an external API
+// The URL may contain {{date}} which is replaced by the note date
+func HTTPText(name, url string, client *http.Client) Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return Func(name, func(ctx context.Context, vars Vars) (string, error) {
+		target := strings.ReplaceAll(url, "{{date}}", vars.Date.Format("2006-01-02"))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Accept", "text/plain")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("%s: unexpected status %d", name, resp.StatusCode)
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteValueLength))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(body)), nil
+	})
+}
+
+// cachedProvider remembers successful values per date for a while
+type cachedProvider struct {
+	Provider
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// Cached wraps a provider so each date is resolved at most once per ttl
+// Errors are not cached, so a flaky API is retried on the next note
+func Cached(p Provider, ttl time.Duration) Provider {
+	return &cachedProvider{
+		Provider: p,
+		ttl:      ttl,
+		entries:  make(map[string]cacheEntry),
+	}
+}
+
+func (c *cachedProvider) Resolve(ctx context.Context, vars Vars) (string, error) {
+	key := vars.Date.Format("2006-01-02") + "|" + vars.Context
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	value, err := c.Provider.Resolve(ctx, vars)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{value: value, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return value, nil
+}
+
+// DefaultQuotes is the built-in list behind {{quote}}
+var DefaultQuotes = []string{
+	"“The secret of getting ahead is getting started.” — Mark Twain",
+	"“Well done is better than well said.” — Benjamin Franklin",
+	"“It always seems impossible until it's done.” — Nelson Mandela",
+	"“What we think, we become.” — Buddha",
+	"“Simplicity is the ultimate sophistication.” — Leonardo da Vinci",
+	"“Action is the foundational key to all success.” — Pablo Picasso",
+	"“Little by little, one travels far.” — J.R.R. Tolkien",
+	"“The best way out is always through.” — Robert Frost",
+	"“Either write something worth reading or do something worth writing.” — Benjamin Franklin",
+	"“We are what we repeatedly do.” — Will Durant",
+}
@@ -0,0 +1,236 @@
+// Package period handles note keys for the different note granularities:
+// daily notes are keyed by date (2025-10-17), week notes by ISO week (2025-W42),
+// month notes by month (2025-10) and year notes by year (2025).
+package period
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Note granularities
+const (
+	Day   = "day"
+	Week  = "week"
+	Month = "month"
+	Year  = "year"
+)
+
+// Key layouts for the granularities that map directly onto time layouts
+const (
+	DateLayout  = "2006-01-02"
+	MonthLayout = "2006-01"
+	YearLayout  = "2006"
+)
+
+var (
+	dayPattern   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	weekPattern  = regexp.MustCompile(`^(\d{4})-W(\d{2})$`)
+	monthPattern = regexp.MustCompile(`^\d{4}-\d{2}$`)
+	yearPattern  = regexp.MustCompile(`^\d{4}$`)
+)
+
+// ErrInvalidKey is returned when a key doesn't match its granularity
+var ErrInvalidKey = errors.New("invalid period key")
+
+// Kind returns the granularity of a note key, or "" if the key is not recognised
+func Kind(key string) string {
+	switch {
+	case dayPattern.MatchString(key):
+		return Day
+	case weekPattern.MatchString(key):
+		if _, err := WeekStart(key); err == nil {
+			return Week
+		}
+	case monthPattern.MatchString(key):
+		if _, err := time.Parse(MonthLayout, key); err == nil {
+			return Month
+		}
+	case yearPattern.MatchString(key):
+		return Year
+	}
+	return ""
+}
+
+// WeekKey returns the ISO week key (e.g. 2025-W42) containing t
+func WeekKey(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// WeekStart returns the Monday that starts the ISO week identified by key
+func WeekStart(key string) (time.Time, error) {
+	m := weekPattern.FindStringSubmatch(key)
+	if m == nil {
+		return time.Time{}, ErrInvalidKey
+	}
+	year, _ := strconv.Atoi(m[1])
+	week, _ := strconv.Atoi(m[2])
+
+	// January 4th is always in ISO week 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	offset := (int(jan4.Weekday()) + 6) % 7 // days since Monday
+	start := jan4.AddDate(0, 0, -offset+(week-1)*7)
+
+	// Reject weeks that don't exist in that year (e.g. W53 in a 52-week year)
+	if y, w := start.ISOWeek(); y != year || w != week {
+		return time.Time{}, ErrInvalidKey
+	}
+	return start, nil
+}
+
+// Parent returns the key of the next coarser note containing key:
+// day → week → month → year. Years have no parent.
+// A week belongs to the month of its Thursday, matching how ISO assigns weeks to years.
+func Parent(key string) (string, error) {
+	switch Kind(key) {
+	case Day:
+		t, err := time.Parse(DateLayout, key)
+		if err != nil {
+			return "", ErrInvalidKey
+		}
+		return WeekKey(t), nil
+	case Week:
+		start, _ := WeekStart(key)
+		return start.AddDate(0, 0, 3).Format(MonthLayout), nil
+	case Month:
+		return key[:4], nil
+	case Year:
+		return "", nil
+	}
+	return "", ErrInvalidKey
+}
+
+// Ancestors returns the chain of parents of key, nearest first
+func Ancestors(key string) ([]string, error) {
+	var chain []string
+	for {
+		parent, err := Parent(key)
+		if err != nil {
+			return nil, err
+		}
+		if parent == "" {
+			return chain, nil
+		}
+		chain = append(chain, parent)
+		key = parent
+	}
+}
+
+// Children returns the keys of the next finer notes rolled up by key:
+// the days of a week, the weeks of a month and the months of a year.
+// Days have no children.
+func Children(key string) ([]string, error) {
+	switch Kind(key) {
+	case Day:
+		return nil, nil
+	case Week:
+		start, _ := WeekStart(key)
+		days := make([]string, 7)
+		for i := range days {
+			days[i] = start.AddDate(0, 0, i).Format(DateLayout)
+		}
+		return days, nil
+	case Month:
+		first, _ := time.Parse(MonthLayout, key)
+		// The first Thursday of the month identifies its first week
+		thursday := first.AddDate(0, 0, (int(time.Thursday)-int(first.Weekday())+7)%7)
+		var weeks []string
+		for t := thursday; t.Month() == first.Month(); t = t.AddDate(0, 0, 7) {
+			weeks = append(weeks, WeekKey(t))
+		}
+		return weeks, nil
+	case Year:
+		year, _ := strconv.Atoi(key)
+		months := make([]string, 12)
+		for i := range months {
+			months[i] = fmt.Sprintf("%04d-%02d", year, i+1)
+		}
+		return months, nil
+	}
+	return nil, ErrInvalidKey
+}
+
+// Days returns the date keys of every day in a month key, in order
+func Days(monthKey string) ([]string, error) {
+	if Kind(monthKey) != Month {
+		return nil, ErrInvalidKey
+	}
+	first, _ := time.Parse(MonthLayout, monthKey)
+	var days []string
+	for t := first; t.Month() == first.Month(); t = t.AddDate(0, 0, 1) {
+		days = append(days, t.Format(DateLayout))
+	}
+	return days, nil
+}
+
+// Range returns the date keys from start to end inclusive
+func Range(start, end string) ([]string, error) {
+	from, err := time.Parse(DateLayout, start)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	to, err := time.Parse(DateLayout, end)
+	if err != nil || to.Before(from) {
+		return nil, ErrInvalidKey
+	}
+
+	var days []string
+	for t := from; !t.After(to); t = t.AddDate(0, 0, 1) {
+		days = append(days, t.Format(DateLayout))
+	}
+	return days, nil
+}
+
+// AddDays returns the date key days after a date key; negative days go back
+func AddDays(key string, days int) (string, error) {
+	t, err := time.Parse(DateLayout, key)
+	if err != nil {
+		return "", ErrInvalidKey
+	}
+	return t.AddDate(0, 0, days).Format(DateLayout), nil
+}
+
+// Streaks returns the runs of consecutive days among date keys, which must be
+// sorted and unique: current is the run ending today, or yesterday while today
+// has no note yet, and longest the longest run. Keys that aren't dates are skipped.
+func Streaks(dates []string, today string) (current, longest int) {
+	yesterday, _ := AddDays(today, -1)
+	run := 0
+	var previous time.Time
+	for _, key := range dates {
+		t, err := time.Parse(DateLayout, key)
+		if err != nil {
+			continue
+		}
+		if run > 0 && t.Equal(previous.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		previous = t
+		longest = max(longest, run)
+		if key == today || key == yesterday {
+			current = run
+		}
+	}
+	return current, longest
+}
+
+// Title returns a human-readable heading for a note key
+func Title(key string) string {
+	switch Kind(key) {
+	case Day:
+		t, _ := time.Parse(DateLayout, key)
+		return t.Format("Monday, January 2, 2006")
+	case Week:
+		return "Week " + key[len(key)-2:] + ", " + key[:4]
+	case Month:
+		t, _ := time.Parse(MonthLayout, key)
+		return t.Format("January 2006")
+	}
+	return key
+}
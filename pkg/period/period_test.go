@@ -0,0 +1,117 @@
+package period
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKind(t *testing.T) {
+	assert.Equal(t, Day, Kind("2025-10-17"))
+	assert.Equal(t, Week, Kind("2025-W42"))
+	assert.Equal(t, Week, Kind("2020-W53"))
+	assert.Equal(t, Month, Kind("2025-10"))
+	assert.Equal(t, Year, Kind("2025"))
+	assert.Equal(t, "", Kind("2025-W53")) // 2025 has 52 ISO weeks
+	assert.Equal(t, "", Kind("2025-W00"))
+	assert.Equal(t, "", Kind("2025-13"))
+	assert.Equal(t, "", Kind("17-10-2025"))
+}
+
+func TestWeekKey(t *testing.T) {
+	assert.Equal(t, "2025-W42", WeekKey(time.Date(2025, 10, 17, 12, 0, 0, 0, time.UTC)))
+	// Early January can belong to the previous ISO year
+	assert.Equal(t, "2020-W53", WeekKey(time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestAncestors(t *testing.T) {
+	chain, err := Ancestors("2025-10-17")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2025-W42", "2025-10", "2025"}, chain)
+
+	// Week 1 of 2026 starts in December 2025 but its Thursday is in January
+	chain, err = Ancestors("2025-12-29")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026-W01", "2026-01", "2026"}, chain)
+
+	_, err = Ancestors("nope")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestDays(t *testing.T) {
+	days, err := Days("2024-02")
+	require.NoError(t, err)
+	assert.Len(t, days, 29)
+	assert.Equal(t, "2024-02-01", days[0])
+	assert.Equal(t, "2024-02-29", days[28])
+
+	_, err = Days("2024-W05")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestRange(t *testing.T) {
+	days, err := Range("2024-12-30", "2025-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-12-30", "2024-12-31", "2025-01-01", "2025-01-02"}, days)
+
+	_, err = Range("2025-01-02", "2024-12-30")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestChildren(t *testing.T) {
+	days, err := Children("2025-W42")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"2025-10-13", "2025-10-14", "2025-10-15", "2025-10-16",
+		"2025-10-17", "2025-10-18", "2025-10-19",
+	}, days)
+
+	weeks, err := Children("2025-10")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2025-W40", "2025-W41", "2025-W42", "2025-W43", "2025-W44"}, weeks)
+
+	months, err := Children("2025")
+	require.NoError(t, err)
+	assert.Len(t, months, 12)
+	assert.Equal(t, "2025-12", months[11])
+
+	// Every week of a month lists that month as its parent
+	for _, week := range weeks {
+		parent, err := Parent(week)
+		require.NoError(t, err)
+		assert.Equal(t, "2025-10", parent)
+	}
+}
+
+func TestAddDays(t *testing.T) {
+	day, err := AddDays("2024-12-31", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01-01", day)
+
+	day, err = AddDays("2025-03-01", -1)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-02-28", day)
+
+	_, err = AddDays("2025-W42", 1)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestStreaks(t *testing.T) {
+	dates := []string{"2025-10-01", "2025-10-02", "2025-10-03", "2025-10-10", "2025-10-11"}
+
+	current, longest := Streaks(dates, "2025-10-11")
+	assert.Equal(t, 2, current)
+	assert.Equal(t, 3, longest)
+
+	current, _ = Streaks(dates, "2025-10-12")
+	assert.Equal(t, 2, current, "the streak holds until today is over")
+
+	current, _ = Streaks(dates, "2025-10-13")
+	assert.Equal(t, 0, current)
+
+	current, longest = Streaks(nil, "2025-10-13")
+	assert.Zero(t, current)
+	assert.Zero(t, longest)
+}
@@ -0,0 +1,96 @@
+// Package pubsub fans out in-process events to subscribers by topic, such as a
+// user ID. Publishing never blocks: a subscriber whose buffer is full misses the
+// event, so subscribers that need the full state reload it themselves.
+package pubsub
+
+import "sync"
+
+// DefaultBuffer is how many events a subscriber can fall behind before events
+// are dropped for it
+const DefaultBuffer = 64
+
+// Broker delivers events of type T to the subscribers of their topic
+type Broker[T any] struct {
+	mu     sync.Mutex
+	subs   map[string]map[chan T]struct{}
+	buffer int
+	closed bool
+}
+
+// New creates a broker giving each subscriber a buffer of the given size
+func New[T any](buffer int) *Broker[T] {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return &Broker[T]{subs: make(map[string]map[chan T]struct{}), buffer: buffer}
+}
+
+// Subscribe returns a channel receiving the events published to topic from now
+// on, and a function ending the subscription. The channel is closed when the
+// subscription ends or the broker is closed.
+func (b *Broker[T]) Subscribe(topic string) (<-chan T, func()) {
+	ch := make(chan T, b.buffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[chan T]struct{})
+	}
+	b.subs[topic][ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subs[topic][ch]; !ok {
+				return // Closed with the broker
+			}
+			delete(b.subs[topic], ch)
+			if len(b.subs[topic]) == 0 {
+				delete(b.subs, topic)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Publish sends an event to the current subscribers of topic, skipping those
+// whose buffer is full
+func (b *Broker[T]) Publish(topic string, event T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribers returns how many subscriptions topic has
+func (b *Broker[T]) Subscribers(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs[topic])
+}
+
+// Close ends every subscription; later subscriptions end right away
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for topic, subs := range b.subs {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(b.subs, topic)
+	}
+}
@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker(t *testing.T) {
+	t.Run("Delivers events to the subscribers of their topic", func(t *testing.T) {
+		b := New[string](4)
+		alice, stopAlice := b.Subscribe("alice")
+		defer stopAlice()
+		bob, stopBob := b.Subscribe("bob")
+		defer stopBob()
+
+		b.Publish("alice", "saved")
+		assert.Equal(t, "saved", <-alice)
+		assert.Empty(t, bob)
+	})
+
+	t.Run("Drops events for subscribers that fall behind", func(t *testing.T) {
+		b := New[int](2)
+		ch, stop := b.Subscribe("alice")
+		defer stop()
+
+		for i := 1; i <= 3; i++ {
+			b.Publish("alice", i)
+		}
+		assert.Equal(t, 1, <-ch)
+		assert.Equal(t, 2, <-ch)
+		assert.Empty(t, ch)
+	})
+
+	t.Run("Ending a subscription closes its channel", func(t *testing.T) {
+		b := New[int](0)
+		ch, stop := b.Subscribe("alice")
+		assert.Equal(t, 1, b.Subscribers("alice"))
+
+		stop()
+		stop()
+		_, open := <-ch
+		assert.False(t, open)
+		assert.Equal(t, 0, b.Subscribers("alice"))
+		b.Publish("alice", 1)
+	})
+
+	t.Run("Closing the broker ends every subscription", func(t *testing.T) {
+		b := New[int](0)
+		ch, stop := b.Subscribe("alice")
+		b.Close()
+		stop()
+
+		_, open := <-ch
+		assert.False(t, open)
+
+		late, _ := b.Subscribe("alice")
+		_, open = <-late
+		assert.False(t, open)
+	})
+}
@@ -0,0 +1,107 @@
+// Package rendercache keeps rendered HTML of notes keyed by note revision.
+// A cached entry is only served for the exact revision it was rendered from,
+// so a stale render can never be returned even if an invalidation is missed.
+package rendercache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultMaxEntries bounds memory use when no explicit size is given
+const DefaultMaxEntries = 1000
+
+type entry struct {
+	noteID   string
+	revision int
+	html     []byte
+}
+
+// Cache is a size-bounded LRU of rendered notes, one entry per note
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+// New creates a cache holding at most maxEntries notes
+func New(maxEntries int) *Cache {
+	if maxEntries < 1 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Cache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the rendered HTML for a note at the given revision
+func (c *Cache) Get(noteID string, revision int) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[noteID]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if e.revision != revision {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.html, true
+}
+
+// Put stores the rendered HTML for a note, replacing any older revision
+func (c *Cache) Put(noteID string, revision int, html []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[noteID]; ok {
+		e := el.Value.(*entry)
+		e.revision, e.html = revision, html
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[noteID] = c.order.PushFront(&entry{noteID: noteID, revision: revision, html: html})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).noteID)
+	}
+}
+
+// GetOrRender returns the cached HTML or renders and caches it
+func (c *Cache) GetOrRender(noteID string, revision int, render func() ([]byte, error)) ([]byte, error) {
+	if html, ok := c.Get(noteID, revision); ok {
+		return html, nil
+	}
+
+	html, err := render()
+	if err != nil {
+		return nil, err
+	}
+	c.Put(noteID, revision, html)
+	return html, nil
+}
+
+// Invalidate drops the cached render of a note (called when it is saved or deleted)
+func (c *Cache) Invalidate(noteID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[noteID]; ok {
+		c.order.Remove(el)
+		delete(c.entries, noteID)
+	}
+}
+
+// Len returns the number of cached notes
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
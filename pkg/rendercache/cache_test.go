@@ -0,0 +1,65 @@
+package rendercache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	t.Run("Serves only the cached revision", func(t *testing.T) {
+		c := New(10)
+		c.Put("n1", 2, []byte("<p>v2</p>"))
+
+		html, ok := c.Get("n1", 2)
+		assert.True(t, ok)
+		assert.Equal(t, "<p>v2</p>", string(html))
+
+		_, ok = c.Get("n1", 3)
+		assert.False(t, ok)
+	})
+
+	t.Run("Invalidate removes entry", func(t *testing.T) {
+		c := New(10)
+		c.Put("n1", 1, []byte("x"))
+		c.Invalidate("n1")
+		_, ok := c.Get("n1", 1)
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("Evicts least recently used", func(t *testing.T) {
+		c := New(2)
+		c.Put("a", 1, []byte("a"))
+		c.Put("b", 1, []byte("b"))
+		c.Get("a", 1)
+		c.Put("c", 1, []byte("c"))
+
+		_, ok := c.Get("b", 1)
+		assert.False(t, ok)
+		_, ok = c.Get("a", 1)
+		assert.True(t, ok)
+	})
+
+	t.Run("GetOrRender renders once per revision", func(t *testing.T) {
+		c := New(10)
+		renders := 0
+		render := func() ([]byte, error) {
+			renders++
+			return []byte("html"), nil
+		}
+
+		for i := 0; i < 3; i++ {
+			html, err := c.GetOrRender("n1", 1, render)
+			require.NoError(t, err)
+			assert.Equal(t, "html", string(html))
+		}
+		assert.Equal(t, 1, renders)
+
+		_, err := c.GetOrRender("n2", 1, func() ([]byte, error) { return nil, errors.New("boom") })
+		assert.Error(t, err)
+		assert.Equal(t, 1, c.Len())
+	})
+}
@@ -0,0 +1,103 @@
+// Package seal encrypts secrets kept in database columns, such as OAuth tokens,
+// with AES-256-GCM under the SESSION_ENC_KEY. Sealed values carry a prefix, so
+// rows saved in plaintext before a key was set are still read as they are.
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix marks sealed values
+const Prefix = "enc:v1:"
+
+// KeySize is the size of the key, an AES-256 key
+const KeySize = 32
+
+var (
+	// ErrKeyMissing is returned when opening a sealed value without a key
+	ErrKeyMissing = errors.New("value is encrypted but no key is set")
+
+	// ErrUnreadable is returned when a sealed value doesn't decrypt with the
+	// key, e.g. after the key was changed
+	ErrUnreadable = errors.New("value doesn't decrypt with the key")
+)
+
+// ParseKey decodes a key, 32 random bytes in base64 as printed by
+// "openssl rand -base64 32"
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("key isn't base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key is %d bytes, want %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
+// Box seals and opens values with a key. A nil Box has no key: it leaves
+// values as they are and can't open sealed ones.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New creates a box sealing with key
+func New(key []byte) (*Box, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Sealed reports whether value was sealed by a Box
+func Sealed(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Seal encrypts a value for column, which is authenticated along with it so
+// the value of one column can't be passed off as another's. Empty values stay
+// empty, and without a key values are returned as they are.
+func (b *Box) Seal(column, value string) (string, error) {
+	if b == nil || value == "" {
+		return value, nil
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(value), []byte(column))
+	return Prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value Seal saved in column; plaintext values of rows saved
+// before the key was set are returned as they are
+func (b *Box) Open(column, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, Prefix)
+	if !ok {
+		return value, nil
+	}
+	if b == nil {
+		return "", ErrKeyMissing
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", ErrUnreadable
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, []byte(column))
+	if err != nil {
+		return "", ErrUnreadable
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,61 @@
+package seal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBox(t *testing.T) {
+	box, err := New(bytes.Repeat([]byte{1}, KeySize))
+	require.NoError(t, err)
+
+	t.Run("Sealed values open for their column only", func(t *testing.T) {
+		sealed, err := box.Seal("secret", "hunter2")
+		require.NoError(t, err)
+		assert.True(t, Sealed(sealed))
+		assert.NotContains(t, sealed, "hunter2")
+
+		opened, err := box.Open("secret", sealed)
+		require.NoError(t, err)
+		assert.Equal(t, "hunter2", opened)
+
+		_, err = box.Open("token", sealed)
+		assert.ErrorIs(t, err, ErrUnreadable)
+	})
+
+	t.Run("Plaintext and empty values pass through", func(t *testing.T) {
+		opened, err := box.Open("secret", "saved-before-the-key")
+		require.NoError(t, err)
+		assert.Equal(t, "saved-before-the-key", opened)
+
+		sealed, err := box.Seal("secret", "")
+		require.NoError(t, err)
+		assert.Empty(t, sealed)
+	})
+
+	t.Run("Without a key values stay as they are", func(t *testing.T) {
+		var none *Box
+		value, err := none.Seal("secret", "hunter2")
+		require.NoError(t, err)
+		assert.Equal(t, "hunter2", value)
+
+		sealed, _ := box.Seal("secret", "hunter2")
+		_, err = none.Open("secret", sealed)
+		assert.ErrorIs(t, err, ErrKeyMissing)
+	})
+
+	t.Run("Keys are 32 bytes of base64", func(t *testing.T) {
+		key, err := ParseKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, KeySize)) + "\n")
+		require.NoError(t, err)
+		assert.Len(t, key, KeySize)
+
+		_, err = ParseKey("c2hvcnQ=")
+		assert.Error(t, err)
+		_, err = ParseKey("not base64!")
+		assert.Error(t, err)
+	})
+}
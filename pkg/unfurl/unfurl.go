@@ -0,0 +1,254 @@
+// Package unfurl fetches the title, description and image of web pages for link previews.
+//
+// Requests only reach public addresses: every connection, including those made
+// for redirects, is checked after DNS resolution, so a URL can't be used to probe
+// the server's network (loopback, private ranges, cloud metadata endpoints).
+package unfurl
+
+import (
+	"context"
+	"daily-notes/models"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// DefaultTimeout bounds a whole fetch, redirects included
+	DefaultTimeout = 5 * time.Second
+	// maxBodySize is how much of a page is read looking for its metadata
+	maxBodySize = 512 * 1024
+	// maxRedirects is how many redirects are followed
+	maxRedirects = 3
+	// maxTextLength caps titles and descriptions, in runes
+	maxTextLength = 300
+)
+
+// Errors returned by Fetch
+var (
+	ErrUnsupportedURL   = errors.New("only http and https URLs can be previewed")
+	ErrBlockedAddress   = errors.New("address is not public or not on port 80 or 443")
+	ErrNotHTML          = errors.New("page is not HTML")
+	ErrNoMetadata       = errors.New("page has no title")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// Fetcher reads link previews from public web pages
+type Fetcher struct {
+	client *http.Client
+
+	// allowAddress reports whether a connection may be made to ip and port; tests allow loopback
+	allowAddress func(ip net.IP, port string) bool
+}
+
+// NewFetcher creates a fetcher that only connects to public addresses
+func NewFetcher() *Fetcher {
+	f := &Fetcher{allowAddress: allowAddress}
+	f.client = newClient(DefaultTimeout, func(ip net.IP, port string) bool { return f.allowAddress(ip, port) })
+	return f
+}
+
+// PublicClient returns an HTTP client that, like the fetcher, only connects to
+// public addresses on ports 80 and 443, for requests to user-supplied URLs
+func PublicClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, allowAddress)
+}
+
+// newClient creates an HTTP client that checks every connection, redirects included, with allow
+func newClient(timeout time.Duration, allow func(ip net.IP, port string) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allow(ip, port) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil, // A proxy would make the dialer check the proxy instead of the target
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return ErrTooManyRedirects
+			}
+			return checkURL(req.URL)
+		},
+	}
+}
+
+// Fetch returns the preview of the page at rawURL
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*models.LinkPreview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, ErrUnsupportedURL
+	}
+	if err := checkURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "daily-notes link preview")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("page returned status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return nil, ErrNotHTML
+	}
+
+	preview := parse(io.LimitReader(resp.Body, maxBodySize), resp.Request.URL)
+	if preview.Title == "" {
+		return nil, ErrNoMetadata
+	}
+	preview.URL = rawURL
+	return preview, nil
+}
+
+// checkURL accepts http(s) URLs without credentials
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return ErrUnsupportedURL
+	}
+	return nil
+}
+
+// allowAddress accepts the default web ports of globally routable unicast addresses
+func allowAddress(ip net.IP, port string) bool {
+	if port != "80" && port != "443" {
+		return false
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	// "This network" (0.0.0.0/8) and carrier-grade NAT (100.64.0.0/10) aren't covered above
+	if ip4 := ip.To4(); ip4 != nil && (ip4[0] == 0 || ip4[0] == 100 && ip4[1]&0xc0 == 64) {
+		return false
+	}
+	return true
+}
+
+// parse reads the preview fields from the head of an HTML page
+// Open Graph properties win over the <title> and description meta tags.
+func parse(r io.Reader, base *url.URL) *models.LinkPreview {
+	var title, description, ogTitle, ogDescription, image, siteName string
+
+	z := html.NewTokenizer(r)
+	inTitle := false
+head:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break head
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = string(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break head
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = true
+			case "body":
+				break head
+			case "meta":
+				attrs := map[string]string{}
+				for hasAttr {
+					var key, value []byte
+					key, value, hasAttr = z.TagAttr()
+					attrs[string(key)] = string(value)
+				}
+				key := attrs["property"]
+				if key == "" {
+					key = attrs["name"]
+				}
+				content := attrs["content"]
+				switch strings.ToLower(key) {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "description":
+					description = content
+				case "og:image":
+					image = content
+				case "og:site_name":
+					siteName = content
+				}
+			}
+		}
+	}
+
+	preview := &models.LinkPreview{
+		Title:       clean(firstNonEmpty(ogTitle, title)),
+		Description: clean(firstNonEmpty(ogDescription, description)),
+		SiteName:    clean(siteName),
+	}
+	if image != "" {
+		if u, err := base.Parse(strings.TrimSpace(image)); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			preview.ImageURL = u.String()
+		}
+	}
+	return preview
+}
+
+// clean collapses whitespace and caps the length of a text field
+func clean(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "")
+	}
+	if runes := []rune(s); len(runes) > maxTextLength {
+		s = strings.TrimSpace(string(runes[:maxTextLength])) + "…"
+	}
+	return s
+}
+
+// firstNonEmpty returns the first value that isn't blank
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package unfurl
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFetcher connects to the local test server, which the default fetcher refuses
+func testFetcher() *Fetcher {
+	f := NewFetcher()
+	f.allowAddress = func(ip net.IP, port string) bool { return ip.IsLoopback() }
+	return f
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!doctype html><html><head>
+			<title>Plain   title</title>
+			<meta name="description" content="Plain description">
+			<meta property="og:title" content="Open Graph title">
+			<meta property="og:image" content="/cover.png">
+			<meta property="og:site_name" content="Example">
+			</head><body><meta property="og:title" content="ignored"></body></html>`))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Only a title</title></head></html>`))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/plain", http.StatusFound)
+	})
+	mux.HandleFunc("/file.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("Open Graph properties win", func(t *testing.T) {
+		preview, err := testFetcher().Fetch(ctx, server.URL+"/article")
+		require.NoError(t, err)
+		assert.Equal(t, server.URL+"/article", preview.URL)
+		assert.Equal(t, "Open Graph title", preview.Title)
+		assert.Equal(t, "Plain description", preview.Description)
+		assert.Equal(t, server.URL+"/cover.png", preview.ImageURL)
+		assert.Equal(t, "Example", preview.SiteName)
+	})
+
+	t.Run("Redirects are followed", func(t *testing.T) {
+		preview, err := testFetcher().Fetch(ctx, server.URL+"/redirect")
+		require.NoError(t, err)
+		assert.Equal(t, "Only a title", preview.Title)
+		assert.Equal(t, server.URL+"/redirect", preview.URL)
+	})
+
+	t.Run("Only HTML pages are previewed", func(t *testing.T) {
+		_, err := testFetcher().Fetch(ctx, server.URL+"/file.pdf")
+		assert.ErrorIs(t, err, ErrNotHTML)
+
+		_, err = testFetcher().Fetch(ctx, "ftp://example.com/file")
+		assert.ErrorIs(t, err, ErrUnsupportedURL)
+	})
+
+	t.Run("Local addresses are refused", func(t *testing.T) {
+		_, err := NewFetcher().Fetch(ctx, server.URL+"/article")
+		assert.ErrorIs(t, err, ErrBlockedAddress)
+	})
+}
+
+func TestAllowAddress(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fd00::1", "fe80::1"} {
+		assert.False(t, allowAddress(net.ParseIP(addr), "443"), addr)
+	}
+	assert.True(t, allowAddress(net.ParseIP("93.184.215.14"), "443"))
+	assert.True(t, allowAddress(net.ParseIP("2606:4700::1"), "80"))
+	assert.False(t, allowAddress(net.ParseIP("93.184.215.14"), "22"))
+}
+
+func TestParseLimitsText(t *testing.T) {
+	page := `<html><head><title>` + strings.Repeat("word ", 100) + `</title></head></html>`
+	preview := parse(strings.NewReader(page), nil)
+	assert.LessOrEqual(t, len([]rune(preview.Title)), maxTextLength+1)
+	assert.True(t, strings.HasSuffix(preview.Title, "…"))
+}
@@ -0,0 +1,48 @@
+// Package visibility decides which of a user's notes each read path may return.
+// Deleted notes never are; a Policy says whether notes of trashed contexts,
+// local-only notes and end-to-end encrypted notes are too. Read paths use one of the named policies instead of
+// filtering on their own, so a change of policy is made here.
+package visibility
+
+// Policy is which notes a read path sees besides the live notes of live contexts
+type Policy struct {
+	TrashedContexts bool // Notes of contexts moved to the trash
+	LocalOnly       bool // Notes marked local only, or kept in a local-only context
+	Encrypted       bool // End-to-end encrypted notes, whose content only the user's clients can read
+}
+
+var (
+	// App is what the signed-in user sees: search, agendas and period digests,
+	// stats, tasks and tags
+	App = Policy{LocalOnly: true, Encrypted: true}
+
+	// Export is what account exports hold; local-only notes only when asked
+	// for, see WithLocalOnly
+	Export = Policy{Encrypted: true}
+
+	// Storage is what leaves the server for cloud storage, such as archives;
+	// encrypted notes leave as they're stored, encrypted
+	Storage = Policy{Encrypted: true}
+
+	// Public is what published pages and feeds show to anyone
+	Public = Policy{}
+
+	// Digest is what weekly email digests quote, as emails leave the server
+	Digest = Policy{}
+
+	// Notify is what reminders quote when they're sent by email, which leaves
+	// the server; reminders of other notes are sent without their text
+	Notify = Policy{}
+)
+
+// WithLocalOnly returns p showing local-only notes as well
+func (p Policy) WithLocalOnly() Policy {
+	p.LocalOnly = true
+	return p
+}
+
+// Allows reports whether p shows a live note, for notes loaded without the
+// policy applied
+func (p Policy) Allows(localOnly, trashedContext, encrypted bool) bool {
+	return (p.LocalOnly || !localOnly) && (p.TrashedContexts || !trashedContext) && (p.Encrypted || !encrypted)
+}
@@ -0,0 +1,28 @@
+package visibility
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicies(t *testing.T) {
+	assert.True(t, App.Allows(true, false, false), "users see their local-only notes")
+	assert.False(t, App.Allows(false, true, false), "notes of trashed contexts are hidden")
+
+	for name, p := range map[string]Policy{"export": Export, "storage": Storage, "public": Public, "digest": Digest, "notify": Notify} {
+		assert.True(t, p.Allows(false, false, false), name)
+		assert.False(t, p.Allows(true, false, false), name+" leaves local-only notes out")
+		assert.False(t, p.Allows(false, true, false), name+" leaves notes of trashed contexts out")
+	}
+
+	for name, p := range map[string]Policy{"app": App, "export": Export, "storage": Storage} {
+		assert.True(t, p.Allows(false, false, true), name+" keeps encrypted notes, encrypted")
+	}
+	for name, p := range map[string]Policy{"public": Public, "digest": Digest, "notify": Notify} {
+		assert.False(t, p.Allows(false, false, true), name+" leaves encrypted notes out")
+	}
+
+	assert.True(t, Export.WithLocalOnly().Allows(true, false, false))
+	assert.False(t, Export.Allows(true, false, false), "WithLocalOnly returns a copy")
+}
@@ -0,0 +1,308 @@
+// Package webpush sends Web Push messages (RFC 8030) to the push services of
+// browsers, encrypted for the subscription (RFC 8291) and signed with the
+// server's VAPID key (RFC 8292), so they need no account with any push service.
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultTimeout bounds a request to a push service
+	DefaultTimeout = 30 * time.Second
+	// DefaultTTL is how long push services keep a message for a browser that is offline
+	DefaultTTL = 24 * time.Hour
+	// recordSize is the size of the single record messages are encrypted in
+	recordSize = 4096
+	// MaxPayload is the largest payload that fits a record, with the padding
+	// delimiter and the AES-GCM tag
+	MaxPayload = recordSize - 17
+	// tokenLifetime is how long VAPID tokens are valid; push services refuse more than 24 hours
+	tokenLifetime = 12 * time.Hour
+)
+
+var (
+	// ErrGone means the subscription expired or was cancelled in the browser:
+	// it should be deleted
+	ErrGone = errors.New("webpush: subscription is gone")
+	// ErrPayloadTooLarge means the payload is over MaxPayload
+	ErrPayloadTooLarge = errors.New("webpush: payload too large")
+)
+
+// StatusError is a push service refusing a message
+type StatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webpush: push service answered %d %s: %s", e.Status, http.StatusText(e.Status), e.Body)
+}
+
+// Subscription is what the browser's PushManager.subscribe() returns: where to
+// send messages and the keys to encrypt them with, base64url-encoded
+type Subscription struct {
+	Endpoint string
+	P256dh   string // The browser's P-256 public key
+	Auth     string // The 16-byte authentication secret
+}
+
+// Validate checks that the subscription's endpoint is an https URL and its keys decode
+func (s Subscription) Validate() error {
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return errors.New("webpush: endpoint must be an https URL")
+	}
+	_, _, err = s.keys()
+	return err
+}
+
+// keys decodes the browser's public key and authentication secret
+func (s Subscription) keys() (*ecdh.PublicKey, []byte, error) {
+	public, err := decode(s.P256dh)
+	if err != nil {
+		return nil, nil, errors.New("webpush: invalid p256dh key")
+	}
+	key, err := ecdh.P256().NewPublicKey(public)
+	if err != nil {
+		return nil, nil, errors.New("webpush: invalid p256dh key")
+	}
+	auth, err := decode(s.Auth)
+	if err != nil || len(auth) != 16 {
+		return nil, nil, errors.New("webpush: invalid auth secret")
+	}
+	return key, auth, nil
+}
+
+// Options are the delivery options of a message
+type Options struct {
+	TTL     time.Duration // DefaultTTL when zero
+	Topic   string        // Replaces a message of the same topic still waiting for the browser
+	Urgency string        // very-low, low, normal (the default) or high
+}
+
+// Sender sends messages signed with a VAPID key pair
+type Sender struct {
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+	client    *http.Client
+	now       func() time.Time
+}
+
+// New creates a sender from a VAPID key pair, base64url-encoded as GenerateKeys
+// returns it. subject is how push services can reach the server's operator, a
+// mailto: or https: URL.
+func New(publicKey, privateKey, subject string) (*Sender, error) {
+	d, err := decode(privateKey)
+	if err != nil {
+		return nil, errors.New("webpush: invalid VAPID private key")
+	}
+	private, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, errors.New("webpush: invalid VAPID private key")
+	}
+	public := private.PublicKey().Bytes()
+	if base64.RawURLEncoding.EncodeToString(public) != publicKey {
+		return nil, errors.New("webpush: VAPID public key doesn't match the private key")
+	}
+	if subject == "" {
+		return nil, errors.New("webpush: a VAPID subject is required")
+	}
+
+	// The uncompressed point is 0x04, X and Y
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+	return &Sender{
+		key:       key,
+		publicKey: publicKey,
+		subject:   subject,
+		client:    &http.Client{Timeout: DefaultTimeout},
+		now:       time.Now,
+	}, nil
+}
+
+// GenerateKeys returns a new VAPID key pair, base64url-encoded: the public key
+// is what browsers subscribe with (applicationServerKey)
+func GenerateKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// PublicKey returns the VAPID public key browsers subscribe with
+func (s *Sender) PublicKey() string {
+	return s.publicKey
+}
+
+// Send encrypts payload for the subscription and posts it to its push service.
+// It fails with ErrGone when the subscription no longer exists.
+func (s *Sender) Send(ctx context.Context, sub Subscription, payload []byte, opts Options) error {
+	body, err := encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+	token, err := s.token(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Authorization", "vapid t="+token+", k="+s.publicKey)
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode >= 300:
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{Status: resp.StatusCode, Body: string(detail)}
+	}
+	return nil
+}
+
+// token returns the VAPID token for the push service of endpoint: a JWT signed
+// with ES256, its audience the origin of the endpoint
+func (s *Sender) token(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": s.now().Add(tokenLifetime).Unix(),
+		"sub": s.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signed))
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS signatures are R and S as 32 bytes each, not ASN.1
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// encrypt encrypts payload for the subscription with the aes128gcm content
+// encoding (RFC 8188), keyed as RFC 8291 says, in a single record
+func encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayload {
+		return nil, ErrPayloadTooLarge
+	}
+	browserKey, auth, err := sub.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := serverKey.ECDH(browserKey)
+	if err != nil {
+		return nil, err
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	info := append([]byte("WebPush: info\x00"), browserKey.Bytes()...)
+	info = append(info, serverPublic...)
+	ikm, err := hkdf.Key(sha256.New, shared, auth, string(info), 32)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt, record size, and the server's public key as the key ID
+	body := make([]byte, 0, 16+4+1+len(serverPublic)+len(payload)+17)
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(serverPublic)))
+	body = append(body, serverPublic...)
+	// 0x02 ends the padding of the last record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+// decode decodes base64url, with or without padding, as browsers differ
+func decode(s string) ([]byte, error) {
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
@@ -0,0 +1,177 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// browser is the receiving end of a subscription
+type browser struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newBrowser(t *testing.T, endpoint string) (*browser, Subscription) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	b := &browser{key: key, auth: make([]byte, 16)}
+	_, err = rand.Read(b.auth)
+	require.NoError(t, err)
+	return b, Subscription{
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(b.auth),
+	}
+}
+
+// decrypt decrypts a message the way browsers do (RFC 8291)
+func (b *browser) decrypt(t *testing.T, body []byte) []byte {
+	salt, rs, idLen := body[:16], binary.BigEndian.Uint32(body[16:20]), int(body[20])
+	assert.Equal(t, uint32(recordSize), rs)
+	serverKey, err := ecdh.P256().NewPublicKey(body[21 : 21+idLen])
+	require.NoError(t, err)
+	shared, err := b.key.ECDH(serverKey)
+	require.NoError(t, err)
+
+	info := append([]byte("WebPush: info\x00"), b.key.PublicKey().Bytes()...)
+	info = append(info, serverKey.Bytes()...)
+	ikm, err := hkdf.Key(sha256.New, shared, b.auth, string(info), 32)
+	require.NoError(t, err)
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	require.NoError(t, err)
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(cek)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, body[21+idLen:], nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
+
+func newSender(t *testing.T) *Sender {
+	public, private, err := GenerateKeys()
+	require.NoError(t, err)
+	sender, err := New(public, private, "mailto:admin@example.com")
+	require.NoError(t, err)
+	return sender
+}
+
+func TestNew(t *testing.T) {
+	public, private, err := GenerateKeys()
+	require.NoError(t, err)
+	other, _, err := GenerateKeys()
+	require.NoError(t, err)
+
+	_, err = New(other, private, "mailto:admin@example.com")
+	assert.Error(t, err, "mismatched keys")
+	_, err = New(public, "not a key", "mailto:admin@example.com")
+	assert.Error(t, err)
+	_, err = New(public, private, "")
+	assert.Error(t, err)
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	_, sub := newBrowser(t, "https://push.example.com/send/abc")
+	assert.NoError(t, sub.Validate())
+
+	plain := sub
+	plain.Endpoint = "http://push.example.com/send/abc"
+	assert.Error(t, plain.Validate())
+	short := sub
+	short.Auth = base64.RawURLEncoding.EncodeToString([]byte("short"))
+	assert.Error(t, short.Validate())
+	bad := sub
+	bad.P256dh = base64.RawURLEncoding.EncodeToString(make([]byte, 65))
+	assert.Error(t, bad.Validate())
+}
+
+func TestSend(t *testing.T) {
+	sender := newSender(t)
+	sender.now = func() time.Time { return time.Unix(1_700_000_000, 0) }
+
+	var got *http.Request
+	var body []byte
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	sender.client = server.Client()
+
+	b, sub := newBrowser(t, server.URL+"/send/abc")
+	err := sender.Send(context.Background(), sub, []byte(`{"title":"Hello"}`), Options{TTL: time.Hour, Topic: "sync"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "3600", got.Header.Get("TTL"))
+	assert.Equal(t, "aes128gcm", got.Header.Get("Content-Encoding"))
+	assert.Equal(t, "sync", got.Header.Get("Topic"))
+	assert.Equal(t, `{"title":"Hello"}`, string(b.decrypt(t, body)))
+
+	// The VAPID token is signed by the key it names, for the endpoint's origin
+	auth := got.Header.Get("Authorization")
+	require.True(t, strings.HasPrefix(auth, "vapid t="))
+	token, key, ok := strings.Cut(strings.TrimPrefix(auth, "vapid t="), ", k=")
+	require.True(t, ok)
+	assert.Equal(t, sender.PublicKey(), key)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(claims, &decoded))
+	assert.Equal(t, server.URL, decoded["aud"])
+	assert.Equal(t, "mailto:admin@example.com", decoded["sub"])
+	assert.EqualValues(t, 1_700_000_000+12*3600, decoded["exp"])
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	assert.True(t, ecdsa.Verify(&sender.key.PublicKey, digest[:], r, s))
+}
+
+func TestSendErrors(t *testing.T) {
+	sender := newSender(t)
+	status := http.StatusGone
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte("nope"))
+	}))
+	defer server.Close()
+	sender.client = server.Client()
+	_, sub := newBrowser(t, server.URL+"/send/abc")
+
+	assert.ErrorIs(t, sender.Send(context.Background(), sub, []byte("hi"), Options{}), ErrGone)
+
+	status = http.StatusTooManyRequests
+	var statusErr *StatusError
+	require.ErrorAs(t, sender.Send(context.Background(), sub, []byte("hi"), Options{}), &statusErr)
+	assert.Equal(t, http.StatusTooManyRequests, statusErr.Status)
+
+	assert.ErrorIs(t, sender.Send(context.Background(), sub, make([]byte, MaxPayload+1), Options{}), ErrPayloadTooLarge)
+}
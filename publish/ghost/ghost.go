@@ -0,0 +1,156 @@
+// Package ghost publishes posts to a Ghost site through its Admin API
+package ghost
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"daily-notes/publish"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrInvalidKey is returned for Admin API keys not of the form "id:secret"
+var ErrInvalidKey = errors.New(`ghost admin API key must look like "id:secret"`)
+
+// tokenLifetime is how long an Admin API token is valid; Ghost accepts at most 5 minutes
+const tokenLifetime = 5 * time.Minute
+
+// Target publishes to one Ghost site
+type Target struct {
+	siteURL string
+	keyID   string
+	secret  []byte
+	client  *http.Client
+	now     func() time.Time
+}
+
+// New creates a target for the site at siteURL with an Admin API key from a custom integration
+func New(siteURL, adminKey string, client *http.Client) (*Target, error) {
+	id, hexSecret, ok := strings.Cut(adminKey, ":")
+	if !ok || id == "" {
+		return nil, ErrInvalidKey
+	}
+	secret, err := hex.DecodeString(hexSecret)
+	if err != nil || len(secret) == 0 {
+		return nil, ErrInvalidKey
+	}
+	return &Target{
+		siteURL: strings.TrimRight(siteURL, "/"),
+		keyID:   id,
+		secret:  secret,
+		client:  client,
+		now:     time.Now,
+	}, nil
+}
+
+type tag struct {
+	Name string `json:"name"`
+}
+
+type post struct {
+	ID        string `json:"id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Slug      string `json:"slug,omitempty"`
+	HTML      string `json:"html,omitempty"`
+	Tags      []tag  `json:"tags,omitempty"`
+	Status    string `json:"status,omitempty"`
+	URL       string `json:"url,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"` // Required on updates, to detect concurrent edits
+}
+
+type envelope struct {
+	Posts []post `json:"posts"`
+}
+
+// Publish creates the post, or updates the one with post.ExternalID
+// A post deleted in Ghost is created again.
+func (t *Target) Publish(ctx context.Context, p publish.Post) (*publish.Result, error) {
+	body := post{Title: p.Title, Slug: p.Slug, HTML: p.HTML, Status: "published"}
+	for _, name := range p.Tags {
+		body.Tags = append(body.Tags, tag{Name: name})
+	}
+
+	if p.ExternalID != "" {
+		current, err := t.get(ctx, p.ExternalID)
+		if err == nil {
+			body.UpdatedAt = current.UpdatedAt
+			return t.send(ctx, http.MethodPut, "/posts/"+p.ExternalID+"/?source=html", body)
+		}
+		if !errors.Is(err, publish.ErrNotFound) {
+			return nil, err
+		}
+	}
+	return t.send(ctx, http.MethodPost, "/posts/?source=html", body)
+}
+
+func (t *Target) get(ctx context.Context, id string) (*post, error) {
+	req, err := t.request(ctx, http.MethodGet, "/posts/"+id+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp envelope
+	if err := publish.Do(t.client, req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Posts) == 0 {
+		return nil, publish.ErrNotFound
+	}
+	return &resp.Posts[0], nil
+}
+
+func (t *Target) send(ctx context.Context, method, path string, body post) (*publish.Result, error) {
+	req, err := t.request(ctx, method, path, envelope{Posts: []post{body}})
+	if err != nil {
+		return nil, err
+	}
+	var resp envelope
+	if err := publish.Do(t.client, req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Posts) == 0 {
+		return nil, errors.New("ghost returned no post")
+	}
+	return &publish.Result{ExternalID: resp.Posts[0].ID, URL: resp.Posts[0].URL}, nil
+}
+
+func (t *Target) request(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	req, err := publish.JSONRequest(ctx, method, t.siteURL+"/ghost/api/admin"+path, body)
+	if err != nil {
+		return nil, err
+	}
+	token, err := t.token()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Ghost "+token)
+	return req, nil
+}
+
+// token signs a short-lived JWT for the Admin API with the key's secret
+func (t *Target) token() (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT", "kid": t.keyID})
+	if err != nil {
+		return "", err
+	}
+	now := t.now()
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Unix(),
+		"exp": now.Add(tokenLifetime).Unix(),
+		"aud": "/admin/",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + encoding.EncodeToString(mac.Sum(nil)), nil
+}
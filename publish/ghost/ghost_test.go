@@ -0,0 +1,89 @@
+package ghost
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"daily-notes/publish"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const adminKey = "key-id:0123456789abcdef"
+
+// verifyToken checks the Admin API token in an Authorization header
+func verifyToken(t *testing.T, header string) {
+	t.Helper()
+	token, ok := strings.CutPrefix(header, "Ghost ")
+	require.True(t, ok)
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	secret := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), parts[2])
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	assert.Contains(t, string(claims), `"aud":"/admin/"`)
+}
+
+func TestPublish(t *testing.T) {
+	var created, updated envelope
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifyToken(t, r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/ghost/api/admin/posts/":
+			assert.Equal(t, "html", r.URL.Query().Get("source"))
+			json.NewDecoder(r.Body).Decode(&created)
+			json.NewEncoder(w).Encode(envelope{Posts: []post{{ID: "p1", URL: "https://blog.example.com/tomatoes/"}}})
+		case r.Method == http.MethodGet && r.URL.Path == "/ghost/api/admin/posts/p1/":
+			json.NewEncoder(w).Encode(envelope{Posts: []post{{ID: "p1", UpdatedAt: "2025-10-16T12:00:00.000Z"}}})
+		case r.Method == http.MethodPut && r.URL.Path == "/ghost/api/admin/posts/p1/":
+			json.NewDecoder(r.Body).Decode(&updated)
+			json.NewEncoder(w).Encode(envelope{Posts: []post{{ID: "p1", URL: "https://blog.example.com/tomatoes/"}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	target, err := New(server.URL+"/", adminKey, server.Client())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	result, err := target.Publish(ctx, publish.Post{Title: "Tomatoes", Slug: "tomatoes", HTML: "<p>Ripe</p>", Tags: []string{"garden"}})
+	require.NoError(t, err)
+	assert.Equal(t, &publish.Result{ExternalID: "p1", URL: "https://blog.example.com/tomatoes/"}, result)
+	require.Len(t, created.Posts, 1)
+	assert.Equal(t, "published", created.Posts[0].Status)
+	assert.Equal(t, []tag{{Name: "garden"}}, created.Posts[0].Tags)
+
+	result, err = target.Publish(ctx, publish.Post{ExternalID: "p1", Title: "Tomatoes", HTML: "<p>Ripe and red</p>"})
+	require.NoError(t, err)
+	assert.Equal(t, "p1", result.ExternalID)
+	require.Len(t, updated.Posts, 1)
+	assert.Equal(t, "2025-10-16T12:00:00.000Z", updated.Posts[0].UpdatedAt)
+	assert.Equal(t, "<p>Ripe and red</p>", updated.Posts[0].HTML)
+
+	// A post deleted in Ghost is created again
+	created = envelope{}
+	_, err = target.Publish(ctx, publish.Post{ExternalID: "gone", Title: "Tomatoes"})
+	require.NoError(t, err)
+	assert.Len(t, created.Posts, 1)
+}
+
+func TestNewRejectsMalformedKeys(t *testing.T) {
+	for _, key := range []string{"", "no-secret", ":0123", "id:not-hex"} {
+		_, err := New("https://blog.example.com", key, http.DefaultClient)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
@@ -0,0 +1,165 @@
+// Package hugo publishes posts as Markdown files with front matter to a Hugo
+// site kept in a GitHub repository, through the GitHub contents API. The
+// site's own build (e.g. GitHub Actions or Netlify) then deploys them.
+package hugo
+
+import (
+	"bytes"
+	"context"
+	"daily-notes/publish"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultAPIURL is the GitHub API; GitHub Enterprise and Gitea serve the same contents API elsewhere
+const DefaultAPIURL = "https://api.github.com"
+
+// Config locates the site's repository
+type Config struct {
+	APIURL  string // Defaults to DefaultAPIURL
+	Repo    string // owner/name
+	Branch  string // Defaults to the repository's default branch
+	Dir     string // Content directory of the posts, e.g. "content/posts"
+	Token   string // Token allowed to write the repository's contents
+	SiteURL string // Address of the built site, for the post URLs
+}
+
+// Target publishes to one repository
+type Target struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New creates a target for the repository in cfg
+func New(cfg Config, client *http.Client) (*Target, error) {
+	if owner, name, ok := strings.Cut(cfg.Repo, "/"); !ok || owner == "" || name == "" {
+		return nil, errors.New(`hugo repository must look like "owner/name"`)
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultAPIURL
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = "content/posts"
+	}
+	cfg.APIURL = strings.TrimRight(cfg.APIURL, "/")
+	cfg.Dir = strings.Trim(cfg.Dir, "/")
+	cfg.SiteURL = strings.TrimRight(cfg.SiteURL, "/")
+	return &Target{cfg: cfg, client: client}, nil
+}
+
+// frontMatter is the YAML header Hugo reads from a content file
+type frontMatter struct {
+	Title string   `yaml:"title"`
+	Date  string   `yaml:"date"`
+	Tags  []string `yaml:"tags,omitempty"`
+	Draft bool     `yaml:"draft"`
+}
+
+type file struct {
+	SHA string `json:"sha"`
+}
+
+type putFile struct {
+	Message string `json:"message"`
+	Content string `json:"content"` // Base64
+	SHA     string `json:"sha,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+}
+
+// Publish writes the post's file, replacing the one at post.ExternalID
+// The external ID is the file's path in the repository.
+func (t *Target) Publish(ctx context.Context, p publish.Post) (*publish.Result, error) {
+	filePath := p.ExternalID
+	if filePath == "" {
+		filePath = t.cfg.Dir + "/" + p.Slug + ".md"
+	}
+
+	content, err := render(p)
+	if err != nil {
+		return nil, err
+	}
+
+	body := putFile{
+		Message: "Publish " + p.Title,
+		Content: base64.StdEncoding.EncodeToString(content),
+		Branch:  t.cfg.Branch,
+	}
+	existing, err := t.get(ctx, filePath)
+	if err != nil && !errors.Is(err, publish.ErrNotFound) {
+		return nil, err
+	}
+	if existing != nil {
+		body.SHA = existing.SHA
+		body.Message = "Update " + p.Title
+	}
+
+	req, err := t.request(ctx, http.MethodPut, filePath, body)
+	if err != nil {
+		return nil, err
+	}
+	if err := publish.Do(t.client, req, nil); err != nil {
+		return nil, err
+	}
+	return &publish.Result{ExternalID: filePath, URL: t.postURL(filePath)}, nil
+}
+
+func (t *Target) get(ctx context.Context, filePath string) (*file, error) {
+	req, err := t.request(ctx, http.MethodGet, filePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if t.cfg.Branch != "" {
+		req.URL.RawQuery = url.Values{"ref": {t.cfg.Branch}}.Encode()
+	}
+	var f file
+	if err := publish.Do(t.client, req, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (t *Target) request(ctx context.Context, method, filePath string, body any) (*http.Request, error) {
+	req, err := publish.JSONRequest(ctx, method, t.cfg.APIURL+"/repos/"+t.cfg.Repo+"/contents/"+filePath, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.cfg.Token)
+	return req, nil
+}
+
+// postURL is where Hugo serves a content file by default: its section and slug
+func (t *Target) postURL(filePath string) string {
+	if t.cfg.SiteURL == "" {
+		return ""
+	}
+	section := strings.TrimPrefix(path.Dir(filePath), "content")
+	slug := strings.TrimSuffix(path.Base(filePath), ".md")
+	return t.cfg.SiteURL + strings.TrimRight(section, "/") + "/" + slug + "/"
+}
+
+// render writes the post as a content file with YAML front matter
+func render(p publish.Post) ([]byte, error) {
+	date := p.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	header, err := yaml.Marshal(frontMatter{Title: p.Title, Date: date.Format(time.RFC3339), Tags: p.Tags})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(header)
+	buf.WriteString("---\n\n")
+	buf.WriteString(strings.TrimSpace(p.Markdown))
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
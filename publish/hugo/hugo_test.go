@@ -0,0 +1,88 @@
+package hugo
+
+import (
+	"context"
+	"daily-notes/publish"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublish(t *testing.T) {
+	files := map[string]string{} // path -> content
+	var puts []putFile
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		filePath, ok := strings.CutPrefix(r.URL.Path, "/repos/alice/site/contents/")
+		require.True(t, ok, r.URL.Path)
+
+		switch r.Method {
+		case http.MethodGet:
+			assert.Equal(t, "main", r.URL.Query().Get("ref"))
+			if _, ok := files[filePath]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(file{SHA: "sha-" + filePath})
+		case http.MethodPut:
+			var body putFile
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			content, err := base64.StdEncoding.DecodeString(body.Content)
+			require.NoError(t, err)
+			files[filePath] = string(content)
+			puts = append(puts, body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	target, err := New(Config{
+		APIURL:  server.URL,
+		Repo:    "alice/site",
+		Branch:  "main",
+		Token:   "token",
+		SiteURL: "https://alice.example.com/",
+	}, server.Client())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	post := publish.Post{
+		Title:    "Tomatoes",
+		Slug:     "2025-10-16-tomatoes",
+		Markdown: "Ripe #garden\n",
+		Tags:     []string{"garden"},
+		Date:     time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC),
+	}
+	result, err := target.Publish(ctx, post)
+	require.NoError(t, err)
+	assert.Equal(t, &publish.Result{
+		ExternalID: "content/posts/2025-10-16-tomatoes.md",
+		URL:        "https://alice.example.com/posts/2025-10-16-tomatoes/",
+	}, result)
+	assert.Equal(t, "---\ntitle: Tomatoes\ndate: \"2025-10-16T09:00:00Z\"\ntags:\n    - garden\ndraft: false\n---\n\nRipe #garden\n",
+		files["content/posts/2025-10-16-tomatoes.md"])
+	assert.Equal(t, "Publish Tomatoes", puts[0].Message)
+	assert.Empty(t, puts[0].SHA)
+
+	// Publishing again replaces the file, even after the title changed
+	post.ExternalID = result.ExternalID
+	post.Slug = "2025-10-16-red-tomatoes"
+	_, err = target.Publish(ctx, post)
+	require.NoError(t, err)
+	require.Len(t, puts, 2)
+	assert.Equal(t, "sha-content/posts/2025-10-16-tomatoes.md", puts[1].SHA)
+	assert.Len(t, files, 1)
+}
+
+func TestNewRequiresRepo(t *testing.T) {
+	_, err := New(Config{Repo: "site"}, http.DefaultClient)
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+// Package publish pushes notes to external blogs (publish targets)
+//
+// Each target kind lives in its own package (ghost, wordpress, hugo) and
+// implements Target. A post published before carries the ID the target gave it,
+// so publishing it again updates the same post instead of creating another.
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Target kinds
+const (
+	Ghost     = "ghost"
+	WordPress = "wordpress"
+	Hugo      = "hugo"
+)
+
+// ErrNotFound is returned when updating a post that was deleted on the target
+var ErrNotFound = errors.New("post no longer exists on the target")
+
+// Post is a note converted for publishing
+type Post struct {
+	ExternalID string // The target's ID from an earlier publication; empty creates a new post
+	Title      string
+	Slug       string
+	Markdown   string // Content without the title heading
+	HTML       string // Markdown rendered by pkg/markdown
+	Tags       []string
+	Date       time.Time
+}
+
+// Result locates a published post
+type Result struct {
+	ExternalID string
+	URL        string
+}
+
+// Target publishes posts to one external blog
+type Target interface {
+	Publish(ctx context.Context, post Post) (*Result, error)
+}
+
+// maxErrorBody is how much of a failed response is quoted in the error
+const maxErrorBody = 300
+
+// Do sends a request and decodes a JSON response into out (when not nil)
+// 404 responses return ErrNotFound; other failures quote the start of the body.
+func Do(client *http.Client, req *http.Request, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("%s %s returned status %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+// JSONRequest creates a request with a JSON body
+func JSONRequest(ctx context.Context, method, url string, body any) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = strings.NewReader(string(data))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
@@ -0,0 +1,75 @@
+// Package wordpress publishes posts to a WordPress site through its REST API
+// It authenticates with an application password (Users > Profile > Application Passwords).
+package wordpress
+
+import (
+	"context"
+	"daily-notes/publish"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Target publishes to one WordPress site
+type Target struct {
+	siteURL  string
+	username string
+	password string
+	client   *http.Client
+}
+
+// New creates a target for the site at siteURL
+func New(siteURL, username, appPassword string, client *http.Client) *Target {
+	return &Target{
+		siteURL:  strings.TrimRight(siteURL, "/"),
+		username: username,
+		password: appPassword,
+		client:   client,
+	}
+}
+
+type post struct {
+	Title   string `json:"title"`
+	Slug    string `json:"slug,omitempty"`
+	Content string `json:"content"`
+	Status  string `json:"status"`
+	Date    string `json:"date_gmt,omitempty"`
+}
+
+type response struct {
+	ID   int    `json:"id"`
+	Link string `json:"link"`
+}
+
+// Publish creates the post, or updates the one with post.ExternalID
+// A post deleted in WordPress is created again. Tags aren't sent: WordPress
+// only accepts the IDs of existing terms.
+func (t *Target) Publish(ctx context.Context, p publish.Post) (*publish.Result, error) {
+	body := post{Title: p.Title, Slug: p.Slug, Content: p.HTML, Status: "publish"}
+	if !p.Date.IsZero() {
+		body.Date = p.Date.UTC().Format("2006-01-02T15:04:05")
+	}
+
+	if p.ExternalID != "" {
+		result, err := t.send(ctx, "/posts/"+p.ExternalID, body)
+		if !errors.Is(err, publish.ErrNotFound) {
+			return result, err
+		}
+	}
+	return t.send(ctx, "/posts", body)
+}
+
+func (t *Target) send(ctx context.Context, path string, body post) (*publish.Result, error) {
+	req, err := publish.JSONRequest(ctx, http.MethodPost, t.siteURL+"/wp-json/wp/v2"+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(t.username, t.password)
+
+	var resp response
+	if err := publish.Do(t.client, req, &resp); err != nil {
+		return nil, err
+	}
+	return &publish.Result{ExternalID: strconv.Itoa(resp.ID), URL: resp.Link}, nil
+}
@@ -0,0 +1,61 @@
+package wordpress
+
+import (
+	"context"
+	"daily-notes/publish"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublish(t *testing.T) {
+	var requests []string
+	var sent post
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "app pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&sent)
+		switch r.URL.Path {
+		case "/wp-json/wp/v2/posts", "/wp-json/wp/v2/posts/7":
+			json.NewEncoder(w).Encode(response{ID: 7, Link: "https://example.com/?p=7"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"rest_post_invalid_id"}`))
+		}
+	}))
+	defer server.Close()
+
+	target := New(server.URL, "alice", "app pass", server.Client())
+	ctx := context.Background()
+	date := time.Date(2025, 10, 16, 9, 30, 0, 0, time.UTC)
+
+	result, err := target.Publish(ctx, publish.Post{Title: "Tomatoes", Slug: "tomatoes", HTML: "<p>Ripe</p>", Date: date})
+	require.NoError(t, err)
+	assert.Equal(t, &publish.Result{ExternalID: "7", URL: "https://example.com/?p=7"}, result)
+	assert.Equal(t, post{Title: "Tomatoes", Slug: "tomatoes", Content: "<p>Ripe</p>", Status: "publish", Date: "2025-10-16T09:30:00"}, sent)
+
+	_, err = target.Publish(ctx, publish.Post{ExternalID: "7", Title: "Tomatoes"})
+	require.NoError(t, err)
+
+	// A post deleted in WordPress is created again
+	_, err = target.Publish(ctx, publish.Post{ExternalID: "8", Title: "Tomatoes"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"POST /wp-json/wp/v2/posts",
+		"POST /wp-json/wp/v2/posts/7",
+		"POST /wp-json/wp/v2/posts/8",
+		"POST /wp-json/wp/v2/posts",
+	}, requests)
+
+	_, err = New(server.URL, "alice", "wrong", server.Client()).Publish(ctx, publish.Post{Title: "Tomatoes"})
+	assert.ErrorContains(t, err, "status 401")
+}
@@ -30,7 +30,7 @@ fi
 
 # Step 1: Build production binary
 echo -e "\n${YELLOW}📦 Building production binary...${NC}"
-CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -a -installsuffix cgo -ldflags="-w -s" -o bin/$BINARY_NAME main.go
+CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -tags sqlite_fts5 -a -installsuffix cgo -ldflags="-w -s" -o bin/$BINARY_NAME main.go
 
 if [ ! -f "bin/$BINARY_NAME" ]; then
     echo -e "${RED}❌ Build failed${NC}"
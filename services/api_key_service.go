@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"net/http"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key secret. It extends APITokenPrefix, so Bearer
+// secrets are still told apart from Google ID tokens by it, and no token secret
+// starts with it (see newSecret).
+const APIKeyPrefix = APITokenPrefix + "key_"
+
+// keyRoute is a request an API key may make; segments of the pattern starting
+// with ':' match any segment
+type keyRoute struct {
+	method, pattern string
+}
+
+// keyRoutes are the requests API keys may make: reading and writing notes,
+// contexts, tags, tasks and reminders. Everything else is done in the app, so a
+// leaked key can't mint other credentials, move the notes to other storage,
+// change their encryption, hand them to someone else or read the secrets of
+// publish targets. Routes are closed to keys until they're added here.
+var keyRoutes = []keyRoute{
+	{http.MethodGet, "/api/contexts"},
+	{http.MethodPost, "/api/contexts"},
+	{http.MethodPost, "/api/contexts/suggest"},
+	{http.MethodPatch, "/api/contexts/reorder"},
+	{http.MethodPut, "/api/contexts/:id"},
+	{http.MethodPut, "/api/contexts/:id/template"},
+	{http.MethodPut, "/api/contexts/:id/language"},
+	{http.MethodGet, "/api/contexts/:id/stats"},
+	{http.MethodGet, "/api/contexts/public"},
+	{http.MethodDelete, "/api/contexts/:id/public"},
+	{http.MethodDelete, "/api/contexts/:id"},
+	{http.MethodGet, "/api/contexts/trash"},
+	{http.MethodPost, "/api/contexts/trash/:id/restore"},
+
+	{http.MethodGet, "/api/notes"},
+	{http.MethodGet, "/api/notes/view"},
+	{http.MethodPost, "/api/notes"},
+	{http.MethodPatch, "/api/notes"},
+	{http.MethodPost, "/api/notes/batch"},
+	{http.MethodPost, "/api/notes/append"},
+	{http.MethodGet, "/api/notes/list"},
+	{http.MethodGet, "/api/notes/changes"},
+	{http.MethodGet, "/api/notes/by-tag"},
+	{http.MethodGet, "/api/notes/month"},
+	{http.MethodGet, "/api/notes/calendar"},
+	{http.MethodGet, "/api/notes/agenda"},
+	{http.MethodGet, "/api/notes/day"},
+	{http.MethodGet, "/api/notes/meta"},
+	{http.MethodGet, "/api/notes/sections"},
+	{http.MethodPut, "/api/notes/sections/:slug"},
+	{http.MethodPost, "/api/notes/split"},
+	{http.MethodPost, "/api/notes/copy"},
+	{http.MethodPost, "/api/notes/move"},
+	{http.MethodPost, "/api/notes/redate"},
+	{http.MethodGet, "/api/notes/related"},
+	{http.MethodGet, "/api/notes/search"},
+	{http.MethodGet, "/api/notes/sizes"},
+	{http.MethodGet, "/api/notes/revisions"},
+	{http.MethodPost, "/api/notes/revisions/:id/restore"},
+	{http.MethodGet, "/api/notes/trash"},
+	{http.MethodPost, "/api/notes/:id/restore"},
+	{http.MethodGet, "/api/notes/attachments"},
+	{http.MethodPost, "/api/notes/attachments"},
+	{http.MethodGet, "/api/attachments/:id"},
+	{http.MethodGet, "/api/notes/conflicts"},
+	{http.MethodPost, "/api/notes/conflicts/resolve"},
+	{http.MethodGet, "/api/notes/period"},
+	{http.MethodPost, "/api/notes/period"},
+	{http.MethodPost, "/api/notes/period/seed"},
+	{http.MethodPost, "/api/notes/publish"},
+	{http.MethodGet, "/api/notes/share"},
+	{http.MethodDelete, "/api/notes/share/:id"},
+	{http.MethodPut, "/api/notes/pin"},
+	{http.MethodGet, "/api/notes/pinned"},
+	{http.MethodGet, "/api/notes/schedule"},
+	{http.MethodPut, "/api/notes/schedule"},
+	{http.MethodDelete, "/api/notes/schedule"},
+	{http.MethodDelete, "/api/notes/:context/:date"},
+	{http.MethodGet, "/api/graphql"},
+	{http.MethodPost, "/api/graphql"},
+
+	{http.MethodGet, "/api/tags"},
+	{http.MethodPost, "/api/tags/rename"},
+	{http.MethodPost, "/api/tags/merge"},
+	{http.MethodGet, "/api/tags/jobs/:id"},
+	{http.MethodGet, "/api/tasks"},
+	{http.MethodPatch, "/api/tasks/:id/toggle"},
+	{http.MethodGet, "/api/reminders"},
+	{http.MethodPost, "/api/reminders"},
+	{http.MethodDelete, "/api/reminders/:id"},
+	{http.MethodGet, "/api/palette"},
+	{http.MethodGet, "/api/stats/activity"},
+	{http.MethodGet, "/api/stats/writing"},
+	{http.MethodGet, "/api/export"},
+	{http.MethodPost, "/api/import"},
+	{http.MethodGet, "/api/sync/status"},
+	{http.MethodPost, "/api/voice/transcribe"},
+	{http.MethodGet, "/api/voice/status/:id"},
+}
+
+// APIKeyService manages the API keys scripts and apps use instead of a session
+type APIKeyService struct {
+	repo     APIKeyRepository
+	clock    clock.Clock
+	timeouts Timeouts
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(repo APIKeyRepository) *APIKeyService {
+	return &APIKeyService{repo: repo, clock: clock.Real(), timeouts: DefaultTimeouts}
+}
+
+// SetClock replaces the clock used for creation and last use times
+func (ks *APIKeyService) SetClock(c clock.Clock) {
+	ks.clock = c
+}
+
+// Create mints a key with a scope, KeyRead or KeyReadWrite. The returned key
+// holds the secret, which can't be read again.
+func (ks *APIKeyService) Create(ctx context.Context, userID, name, scope string) (_ *models.APIKey, err error) {
+	defer wrapOp("create API key", &err)
+	ctx, cancel := ks.timeouts.query(ctx)
+	defer cancel()
+
+	secret, err := newSecret(APIKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Scope:     scope,
+		Key:       secret,
+		CreatedAt: ks.clock.Now(),
+	}
+	if err := ks.repo.CreateAPIKey(ctx, key, hashAPIToken(secret)); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// List returns the user's keys, newest first, without their secrets
+func (ks *APIKeyService) List(ctx context.Context, userID string) (_ []models.APIKey, err error) {
+	defer wrapOp("list API keys", &err)
+	ctx, cancel := ks.timeouts.query(ctx)
+	defer cancel()
+
+	return ks.repo.GetAPIKeys(ctx, userID)
+}
+
+// Revoke deletes a key of the user; requests made with it fail from then on
+func (ks *APIKeyService) Revoke(ctx context.Context, userID string, id int64) (err error) {
+	defer wrapOp("revoke API key", &err)
+	ctx, cancel := ks.timeouts.query(ctx)
+	defer cancel()
+
+	deleted, err := ks.repo.DeleteAPIKey(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate returns the key of a secret and records its use, or fails with
+// ErrInvalidAPIKey
+func (ks *APIKeyService) Authenticate(ctx context.Context, secret string) (_ *models.APIKey, err error) {
+	defer wrapOp("authenticate API key", &err)
+	if !strings.HasPrefix(secret, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	ctx, cancel := ks.timeouts.query(ctx)
+	defer cancel()
+	key, err := ks.repo.GetAPIKeyByHash(ctx, hashAPIToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := ks.clock.Now()
+	if err := ks.repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+		return nil, err
+	}
+	key.LastUsedAt = &now
+	return key, nil
+}
+
+// KeyAllows reports whether key may make a request with method to path: one
+// of keyRoutes, and only a GET for read-only keys.
+func KeyAllows(key *models.APIKey, method, path string) bool {
+	method = strings.ToUpper(method)
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	switch key.Scope {
+	case models.KeyReadWrite:
+	case models.KeyRead:
+		if method != http.MethodGet {
+			return false
+		}
+	default:
+		return false
+	}
+
+	// Routes match regardless of case and trailing slashes
+	path = strings.TrimSuffix(strings.ToLower(path), "/")
+	for _, route := range keyRoutes {
+		if method == route.method && matchesRoute(path, route.pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRoute reports whether path is a request to the route pattern, whose
+// segments starting with ':' are parameters
+func matchesRoute(path, pattern string) bool {
+	segments, parts := strings.Split(path, "/"), strings.Split(pattern, "/")
+	if len(segments) != len(parts) {
+		return false
+	}
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segments[i] != part {
+			return false
+		}
+	}
+	return true
+}
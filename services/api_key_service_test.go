@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAPIKeyRepository is a mock implementation of APIKeyRepository
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey, hash string) error {
+	args := m.Called(key, hash)
+	key.ID = 1
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) TouchAPIKey(ctx context.Context, id int64, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) DeleteAPIKey(ctx context.Context, userID string, id int64) (bool, error) {
+	args := m.Called(userID, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestAPIKeyService(t *testing.T) {
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+	repo := new(MockAPIKeyRepository)
+	service := NewAPIKeyService(repo)
+	service.SetClock(clock.NewFake(now))
+
+	repo.On("CreateAPIKey", mock.AnythingOfType("*models.APIKey"), mock.AnythingOfType("string")).Return(nil)
+
+	key, err := service.Create(context.Background(), "user123", "Shortcut", models.KeyReadWrite)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key.Key, APIKeyPrefix))
+	hash := repo.Calls[len(repo.Calls)-1].Arguments.String(1)
+	assert.Equal(t, hashAPIToken(key.Key), hash)
+
+	t.Run("Secrets authenticate and record their use", func(t *testing.T) {
+		stored := &models.APIKey{ID: 1, UserID: "user123", Scope: models.KeyReadWrite}
+		repo.On("GetAPIKeyByHash", hash).Return(stored, nil)
+		repo.On("GetAPIKeyByHash", mock.AnythingOfType("string")).Return(nil, nil)
+		repo.On("TouchAPIKey", int64(1), now).Return(nil)
+
+		got, err := service.Authenticate(context.Background(), key.Key)
+		require.NoError(t, err)
+		assert.Equal(t, "user123", got.UserID)
+		assert.Equal(t, now, *got.LastUsedAt)
+
+		_, err = service.Authenticate(context.Background(), APIKeyPrefix+"guessed")
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+		_, err = service.Authenticate(context.Background(), APITokenPrefix+"token")
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("Revoking a missing key fails", func(t *testing.T) {
+		repo.On("DeleteAPIKey", "user123", int64(2)).Return(false, nil)
+		assert.ErrorIs(t, service.Revoke(context.Background(), "user123", 2), ErrAPIKeyNotFound)
+	})
+}
+
+func TestKeyAllows(t *testing.T) {
+	reader := &models.APIKey{Scope: models.KeyRead}
+	assert.True(t, KeyAllows(reader, "GET", "/api/notes"))
+	assert.True(t, KeyAllows(reader, "GET", "/api/notes/search"))
+	assert.False(t, KeyAllows(reader, "POST", "/api/notes"))
+	assert.False(t, KeyAllows(reader, "DELETE", "/api/notes/Work/2025-10-16"))
+
+	writer := &models.APIKey{Scope: models.KeyReadWrite}
+	assert.True(t, KeyAllows(writer, "POST", "/api/notes"))
+	assert.True(t, KeyAllows(writer, "DELETE", "/api/notes/Work/2025-10-16"))
+	for _, path := range []string{"/api/keys", "/api/keys/1", "/API/Keys", "/api/tokens", "/api/storage", "/api/encryption/verify"} {
+		assert.False(t, KeyAllows(writer, "POST", path), path)
+	}
+	assert.True(t, KeyAllows(writer, "GET", "/API/Notes/"), "routes match regardless of case and trailing slashes")
+	assert.True(t, KeyAllows(reader, "HEAD", "/api/notes"))
+
+	t.Run("Routes not made for keys are closed", func(t *testing.T) {
+		for _, request := range [][2]string{
+			{"POST", "/api/push/subscribe"},
+			{"PUT", "/api/settings"},
+			{"PUT", "/api/contexts/ctx-1/local-only"},
+			{"GET", "/api/keysmith"},
+			{"GET", "/api/notes/Work"},
+			{"DELETE", "/api/notes/Work/2025-10-16/extra"},
+		} {
+			assert.False(t, KeyAllows(writer, request[0], request[1]), "%s %s", request[0], request[1])
+		}
+	})
+
+	t.Run("Keys don't share notes or read publish secrets", func(t *testing.T) {
+		for _, request := range [][2]string{
+			{"POST", "/api/drops"},
+			{"POST", "/api/notes/share"},
+			{"POST", "/api/notes/share/"},
+			{"PUT", "/api/contexts/ctx-1/public"},
+			{"POST", "/api/contexts/ctx-1/invite"},
+			{"GET", "/api/publish/targets"},
+			{"POST", "/api/publish/targets"},
+			{"DELETE", "/api/publish/targets/3"},
+		} {
+			assert.False(t, KeyAllows(writer, request[0], request[1]), "%s %s", request[0], request[1])
+		}
+		assert.False(t, KeyAllows(reader, "GET", "/api/publish/targets"))
+
+		assert.True(t, KeyAllows(writer, "GET", "/api/notes/share"), "listing shares is allowed")
+		assert.True(t, KeyAllows(writer, "DELETE", "/api/notes/share/4"), "revoking a share is allowed")
+		assert.True(t, KeyAllows(writer, "DELETE", "/api/contexts/ctx-1/public"), "unpublishing is allowed")
+		assert.True(t, KeyAllows(writer, "PUT", "/api/contexts/ctx-1"))
+		assert.True(t, KeyAllows(writer, "POST", "/api/notes/publish"))
+	})
+
+	assert.False(t, KeyAllows(&models.APIKey{Scope: "admin"}, "GET", "/api/notes"))
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// APITokenPrefix starts every API token secret, so Bearer tokens can be told
+// apart from Google ID tokens
+const APITokenPrefix = "dn_"
+
+// apiTokenKey is the context key of the API token a request was made with
+type apiTokenKey struct{}
+
+// APITokenKey is where the auth middleware keeps the API token of a request, in
+// the request context (c.Locals), for the note operations to check
+var APITokenKey = apiTokenKey{}
+
+// APITokenService manages the API tokens of integrations, each limited to one
+// context and a subset of operations
+type APITokenService struct {
+	repo     APITokenRepository
+	clock    clock.Clock
+	timeouts Timeouts
+}
+
+// NewAPITokenService creates a new API token service
+func NewAPITokenService(repo APITokenRepository) *APITokenService {
+	return &APITokenService{repo: repo, clock: clock.Real(), timeouts: DefaultTimeouts}
+}
+
+// SetClock replaces the clock used for creation and last use times
+func (ts *APITokenService) SetClock(c clock.Clock) {
+	ts.clock = c
+}
+
+// Create mints a token for one of the user's contexts. The returned token holds
+// the secret, which can't be read again.
+func (ts *APITokenService) Create(ctx context.Context, userID, name, contextName string, operations []string) (_ *models.APIToken, err error) {
+	defer wrapOp("create API token", &err)
+	ctx, cancel := ts.timeouts.query(ctx)
+	defer cancel()
+
+	c, err := ts.repo.GetContextByName(ctx, database.ScopeUser(userID), contextName)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrContextNotFound
+	}
+
+	secret, err := newSecret(APITokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	operations = slices.Clone(operations)
+	slices.Sort(operations)
+	token := &models.APIToken{
+		UserID:     userID,
+		Name:       name,
+		Context:    c.Name,
+		Operations: slices.Compact(operations),
+		Token:      secret,
+		CreatedAt:  ts.clock.Now(),
+	}
+	if err := ts.repo.CreateAPIToken(ctx, token, hashAPIToken(token.Token)); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// List returns the user's tokens, newest first, without their secrets
+func (ts *APITokenService) List(ctx context.Context, userID string) (_ []models.APIToken, err error) {
+	defer wrapOp("list API tokens", &err)
+	ctx, cancel := ts.timeouts.query(ctx)
+	defer cancel()
+
+	return ts.repo.GetAPITokens(ctx, userID)
+}
+
+// Revoke deletes a token of the user; requests made with it fail from then on
+func (ts *APITokenService) Revoke(ctx context.Context, userID string, id int64) (err error) {
+	defer wrapOp("revoke API token", &err)
+	ctx, cancel := ts.timeouts.query(ctx)
+	defer cancel()
+
+	deleted, err := ts.repo.DeleteAPIToken(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// Authenticate returns the token of a secret and records its use, or fails with
+// ErrInvalidAPIToken
+func (ts *APITokenService) Authenticate(ctx context.Context, secret string) (_ *models.APIToken, err error) {
+	defer wrapOp("authenticate API token", &err)
+	if !strings.HasPrefix(secret, APITokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	ctx, cancel := ts.timeouts.query(ctx)
+	defer cancel()
+	token, err := ts.repo.GetAPITokenByHash(ctx, hashAPIToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrInvalidAPIToken
+	}
+
+	now := ts.clock.Now()
+	if err := ts.repo.TouchAPIToken(ctx, token.ID, now); err != nil {
+		return nil, err
+	}
+	token.LastUsedAt = &now
+	return token, nil
+}
+
+// newSecret returns a random API token or key secret starting with prefix.
+// Tokens never start with APIKeyPrefix, so the auth middleware can tell them apart.
+func newSecret(prefix string) (string, error) {
+	for {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return "", err
+		}
+		secret := prefix + base64.RawURLEncoding.EncodeToString(random)
+		if prefix == APIKeyPrefix || !strings.HasPrefix(secret, APIKeyPrefix) {
+			return secret, nil
+		}
+	}
+}
+
+// hashAPIToken is what the database keeps of a secret; secrets are random, so
+// an unsalted hash is enough
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenAllows reports whether token may run operation on the notes of
+// contextName. Write implies append.
+func TokenAllows(token *models.APIToken, contextName, operation string) bool {
+	if token.Context != contextName {
+		return false
+	}
+	if slices.Contains(token.Operations, operation) {
+		return true
+	}
+	return operation == models.TokenAppend && slices.Contains(token.Operations, models.TokenWrite)
+}
+
+// authorizeToken fails with ErrTokenScope when ctx carries an API token that
+// doesn't allow operation on contextName; requests without a token pass
+func authorizeToken(ctx context.Context, contextName, operation string) error {
+	token, ok := ctx.Value(APITokenKey).(*models.APIToken)
+	if !ok || token == nil {
+		return nil
+	}
+	if !TokenAllows(token, contextName, operation) {
+		return ErrTokenScope
+	}
+	return nil
+}
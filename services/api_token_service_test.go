@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAPITokenRepository is a mock implementation of APITokenRepository
+type MockAPITokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPITokenRepository) GetContextByName(ctx context.Context, scope database.UserScope, name string) (*models.Context, error) {
+	args := m.Called(scope.UserID(), name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Context), args.Error(1)
+}
+
+func (m *MockAPITokenRepository) CreateAPIToken(ctx context.Context, token *models.APIToken, hash string) error {
+	args := m.Called(token, hash)
+	token.ID = 1
+	return args.Error(0)
+}
+
+func (m *MockAPITokenRepository) GetAPITokens(ctx context.Context, userID string) ([]models.APIToken, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.APIToken), args.Error(1)
+}
+
+func (m *MockAPITokenRepository) GetAPITokenByHash(ctx context.Context, hash string) (*models.APIToken, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIToken), args.Error(1)
+}
+
+func (m *MockAPITokenRepository) TouchAPIToken(ctx context.Context, id int64, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *MockAPITokenRepository) DeleteAPIToken(ctx context.Context, userID string, id int64) (bool, error) {
+	args := m.Called(userID, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestAPITokenService(t *testing.T) {
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+	repo := new(MockAPITokenRepository)
+	service := NewAPITokenService(repo)
+	service.SetClock(clock.NewFake(now))
+
+	repo.On("GetContextByName", "user123", "Fitness").Return(&models.Context{Name: "Fitness"}, nil)
+	repo.On("GetContextByName", "user123", "Nope").Return(nil, nil)
+	repo.On("CreateAPIToken", mock.AnythingOfType("*models.APIToken"), mock.AnythingOfType("string")).Return(nil)
+
+	token, err := service.Create(context.Background(), "user123", "Scale", "Fitness", []string{"append", "read", "append"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token.Token, APITokenPrefix))
+	assert.Equal(t, []string{models.TokenAppend, models.TokenRead}, token.Operations)
+	hash := repo.Calls[len(repo.Calls)-1].Arguments.String(1)
+	assert.Equal(t, hashAPIToken(token.Token), hash)
+	assert.NotContains(t, hash, token.Token)
+
+	t.Run("Tokens are only made for existing contexts", func(t *testing.T) {
+		_, err := service.Create(context.Background(), "user123", "Scale", "Nope", []string{"read"})
+		assert.ErrorIs(t, err, ErrContextNotFound)
+	})
+
+	t.Run("Secrets authenticate and record their use", func(t *testing.T) {
+		stored := &models.APIToken{ID: 1, UserID: "user123", Context: "Fitness", Operations: []string{models.TokenAppend}}
+		repo.On("GetAPITokenByHash", hash).Return(stored, nil)
+		repo.On("GetAPITokenByHash", mock.AnythingOfType("string")).Return(nil, nil)
+		repo.On("TouchAPIToken", int64(1), now).Return(nil)
+
+		got, err := service.Authenticate(context.Background(), token.Token)
+		require.NoError(t, err)
+		assert.Equal(t, "user123", got.UserID)
+		assert.Equal(t, now, *got.LastUsedAt)
+
+		_, err = service.Authenticate(context.Background(), APITokenPrefix+"guessed")
+		assert.ErrorIs(t, err, ErrInvalidAPIToken)
+		_, err = service.Authenticate(context.Background(), "google-id-token")
+		assert.ErrorIs(t, err, ErrInvalidAPIToken)
+	})
+
+	t.Run("Revoking a missing token fails", func(t *testing.T) {
+		repo.On("DeleteAPIToken", "user123", int64(2)).Return(false, nil)
+		assert.ErrorIs(t, service.Revoke(context.Background(), "user123", 2), ErrAPITokenNotFound)
+	})
+}
+
+func TestTokenAllows(t *testing.T) {
+	appendOnly := &models.APIToken{Context: "Fitness", Operations: []string{models.TokenAppend}}
+	assert.True(t, TokenAllows(appendOnly, "Fitness", models.TokenAppend))
+	assert.False(t, TokenAllows(appendOnly, "Fitness", models.TokenRead))
+	assert.False(t, TokenAllows(appendOnly, "Fitness", models.TokenWrite))
+	assert.False(t, TokenAllows(appendOnly, "Work", models.TokenAppend))
+
+	writer := &models.APIToken{Context: "Fitness", Operations: []string{models.TokenWrite}}
+	assert.True(t, TokenAllows(writer, "Fitness", models.TokenAppend))
+}
+
+func TestNoteService_RefusesTokensOutsideTheirScope(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewNoteService(mockRepo, nil)
+	token := &models.APIToken{UserID: "user123", Context: "Fitness", Operations: []string{models.TokenAppend}}
+	ctx := context.WithValue(context.Background(), APITokenKey, token)
+
+	_, err := service.Get(ctx, "user123", "Fitness", "2025-10-16")
+	assert.ErrorIs(t, err, ErrTokenScope)
+	_, err = service.Upsert(ctx, "user123", "Fitness", "2025-10-16", "72.4 kg")
+	assert.ErrorIs(t, err, ErrTokenScope)
+	_, err = service.Append(ctx, "user123", "Journal", "2025-10-16", "72.4 kg", "", nil)
+	assert.ErrorIs(t, err, ErrTokenScope)
+	assert.ErrorIs(t, service.Delete(ctx, "user123", "Fitness", "2025-10-16"), ErrTokenScope)
+	mockRepo.AssertNotCalled(t, "GetNote", mock.Anything, mock.Anything, mock.Anything)
+}
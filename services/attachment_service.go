@@ -0,0 +1,408 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"daily-notes/pkg/jobs"
+	"daily-notes/pkg/period"
+	"daily-notes/storage"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// DefaultAttachmentsDir is where uploaded attachments are kept unless ATTACHMENTS_DIR says otherwise
+	DefaultAttachmentsDir = "./data/attachments"
+
+	// DefaultAttachmentMaxSize is the largest attachment accepted unless ATTACHMENT_MAX_SIZE says otherwise
+	DefaultAttachmentMaxSize = 10 << 20
+
+	attachmentNameMax = 100 // Bytes kept of an uploaded file's name
+)
+
+// inlineImageTypes are the attachment types embedded as images and served inline;
+// everything else is linked and downloaded. SVG is left out as it can run scripts.
+var inlineImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// AttachmentService keeps the files attached to notes: on the server's disk,
+// which serves them, and copied to the _attachments folder of the note's
+// context in storage, which keeps them with the markdown
+type AttachmentService struct {
+	repo           AttachmentRepository
+	storageFactory StorageFactory
+	dir            string
+	maxSize        int64
+	clock          clock.Clock
+	ids            idgen.Generator
+	timeouts       Timeouts
+	jobs           JobQueue    // Copies attachments to storage when set
+	tokens         TokenSource // Signs jobs in to storage
+}
+
+// attachmentUpload is the payload of a JobAttachment job
+type attachmentUpload struct {
+	ID string `json:"id"`
+}
+
+// NewAttachmentService creates a new attachment service
+func NewAttachmentService(repo AttachmentRepository, storageFactory StorageFactory) *AttachmentService {
+	return &AttachmentService{
+		repo:           repo,
+		storageFactory: storageFactory,
+		dir:            DefaultAttachmentsDir,
+		maxSize:        DefaultAttachmentMaxSize,
+		clock:          clock.Real(),
+		ids:            idgen.UUID(),
+		timeouts:       DefaultTimeouts,
+	}
+}
+
+// SetDir replaces the directory attachments are kept in, one folder per user
+func (as *AttachmentService) SetDir(dir string) {
+	as.dir = dir
+}
+
+// Dir returns the directory attachments are kept in
+func (as *AttachmentService) Dir() string {
+	return as.dir
+}
+
+// SetMaxSize replaces the largest attachment accepted, in bytes
+func (as *AttachmentService) SetMaxSize(size int64) {
+	as.maxSize = size
+}
+
+// MaxSize returns the largest attachment accepted, in bytes
+func (as *AttachmentService) MaxSize() int64 {
+	return as.maxSize
+}
+
+// SetClock replaces the clock used for timestamps
+func (as *AttachmentService) SetClock(c clock.Clock) {
+	as.clock = c
+}
+
+// SetIDGenerator replaces the generator used for attachment IDs
+func (as *AttachmentService) SetIDGenerator(g idgen.Generator) {
+	as.ids = g
+}
+
+// SetTimeouts replaces the per-operation deadlines
+func (as *AttachmentService) SetTimeouts(t Timeouts) {
+	as.timeouts = t.WithDefaults()
+}
+
+// SetJobQueue copies attachments to storage through jobs of queue, signed in
+// with tokens, instead of goroutines that a restart loses. Register
+// RunUploadJob for JobAttachment.
+func (as *AttachmentService) SetJobQueue(queue JobQueue, tokens TokenSource) {
+	as.jobs = queue
+	as.tokens = tokens
+}
+
+// Upload keeps a file attached to the note of a context and date, which doesn't
+// have to exist yet, and queues its copy to storage. The returned attachment's
+// Markdown embeds it in the note. token signs the copy in when there is no queue.
+func (as *AttachmentService) Upload(ctx context.Context, userID, contextName, date, filename string, content io.Reader, token *oauth2.Token) (_ *models.Attachment, err error) {
+	defer wrapOp("upload attachment", &err)
+	if period.Kind(date) == "" {
+		return nil, ErrInvalidPeriodKey
+	}
+
+	queryCtx, cancel := as.timeouts.query(ctx)
+	defer cancel()
+	c, err := as.repo.GetContextByName(queryCtx, database.ScopeUser(userID), contextName)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrContextNotFound
+	}
+
+	a := &models.Attachment{
+		ID:            as.ids.NewID(),
+		UserID:        userID,
+		Context:       c.Name,
+		Date:          date,
+		Filename:      attachmentFilename(filename),
+		StorageStatus: models.AttachmentPending,
+		CreatedAt:     as.clock.Now(),
+	}
+	if c.LocalOnly {
+		a.StorageStatus = models.AttachmentNotStored
+	}
+	if a.ContentType, a.Size, err = as.save(a, content); err != nil {
+		return nil, err
+	}
+
+	queryCtx, cancel = as.timeouts.query(ctx)
+	defer cancel()
+	if err := as.repo.CreateAttachment(queryCtx, a); err != nil {
+		os.Remove(as.path(a))
+		return nil, err
+	}
+	if a.StorageStatus == models.AttachmentPending {
+		as.queueUpload(a, token)
+	}
+	setAttachmentLinks(a)
+	return a, nil
+}
+
+// Open returns an attachment of a user with its file, which the caller closes
+func (as *AttachmentService) Open(ctx context.Context, userID, id string) (_ *models.Attachment, _ *os.File, err error) {
+	defer wrapOp("open attachment", &err)
+	ctx, cancel := as.timeouts.query(ctx)
+	defer cancel()
+
+	a, err := as.repo.GetAttachment(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a == nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	file, err := os.Open(as.path(a))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	setAttachmentLinks(a)
+	return a, file, nil
+}
+
+// List returns the attachments of a note, oldest first
+func (as *AttachmentService) List(ctx context.Context, userID, contextName, date string) (_ []models.Attachment, err error) {
+	defer wrapOp("list attachments", &err)
+	ctx, cancel := as.timeouts.query(ctx)
+	defer cancel()
+
+	attachments, err := as.repo.GetNoteAttachments(ctx, userID, contextName, date)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		setAttachmentLinks(&attachments[i])
+	}
+	return attachments, nil
+}
+
+// RunUploadJob copies an attachment queued by Upload to storage; failed copies are retried by the queue
+func (as *AttachmentService) RunUploadJob(ctx context.Context, job *models.Job) (err error) {
+	defer wrapOp("run attachment job", &err)
+	var upload attachmentUpload
+	if err := jobs.Decode(job, &upload); err != nil {
+		return err
+	}
+
+	queryCtx, cancel := as.timeouts.query(ctx)
+	defer cancel()
+	a, err := as.repo.GetAttachment(queryCtx, job.UserID, upload.ID)
+	if err != nil {
+		return err
+	}
+	if a == nil {
+		return nil
+	}
+
+	token, err := as.tokens(job.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get authentication token: %w", err)
+	}
+	return as.upload(a, token)
+}
+
+// queueUpload queues the copy of an attachment to storage, or starts it right
+// away when there is no queue or queueing fails
+func (as *AttachmentService) queueUpload(a *models.Attachment, token *oauth2.Token) {
+	if as.jobs != nil {
+		ctx, cancel := as.timeouts.query(context.Background())
+		defer cancel()
+		err := as.jobs.Enqueue(ctx, a.UserID, JobAttachment, attachmentUpload{ID: a.ID})
+		if err == nil {
+			return
+		}
+		slog.Warn("failed to queue attachment upload, running it now", "attachment", a.ID, "error", err)
+	}
+	if as.storageFactory == nil {
+		return
+	}
+	queued := *a
+	go func() {
+		if err := as.upload(&queued, token); err != nil {
+			slog.Warn("failed to upload attachment", "attachment", queued.ID, "error", err)
+		}
+	}()
+}
+
+// upload copies an attachment to its context's _attachments folder in storage
+// and records the outcome
+func (as *AttachmentService) upload(a *models.Attachment, token *oauth2.Token) error {
+	ctx, cancel := as.timeouts.storage()
+	defer cancel()
+
+	provider, err := as.storageFactory(ctx, token, a.UserID)
+	if err != nil {
+		err = fmt.Errorf("failed to connect to cloud storage: %w", err)
+		as.setStorageStatus(a, models.AttachmentPending, err)
+		return err
+	}
+	uploader, ok := provider.(storage.AttachmentUploader)
+	if !ok {
+		as.setStorageStatus(a, models.AttachmentNotStored, nil)
+		return nil
+	}
+
+	file, err := os.Open(as.path(a))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	defer file.Close()
+
+	if err := uploader.UploadAttachment(a.Context, attachmentStorageName(a), a.ContentType, file); err != nil {
+		as.setStorageStatus(a, models.AttachmentPending, err)
+		return err
+	}
+	as.setStorageStatus(a, models.AttachmentStored, nil)
+	return nil
+}
+
+// setStorageStatus records whether an attachment is in storage, logging failures to do so
+func (as *AttachmentService) setStorageStatus(a *models.Attachment, status string, cause error) {
+	ctx, cancel := as.timeouts.query(context.Background())
+	defer cancel()
+
+	errorMsg := ""
+	if cause != nil {
+		errorMsg = cause.Error()
+	}
+	if err := as.repo.SetAttachmentStorageStatus(ctx, a.ID, status, errorMsg); err != nil {
+		slog.Warn("failed to record attachment storage status", "attachment", a.ID, "status", status, "error", err)
+	}
+}
+
+// save writes an upload to the attachment's file, up to the size limit, and
+// returns its detected content type and size
+func (as *AttachmentService) save(a *models.Attachment, content io.Reader) (string, int64, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", 0, err
+	}
+	if n == 0 {
+		return "", 0, ErrEmptyAttachment
+	}
+	contentType := attachmentContentType(a.Filename, head[:n])
+
+	filename := as.path(a)
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return "", 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".tmp-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	// One byte over the limit tells a file that is too large
+	size, err := io.Copy(tmp, io.LimitReader(io.MultiReader(bytes.NewReader(head[:n]), content), as.maxSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if size > as.maxSize {
+		return "", 0, ErrAttachmentTooLarge
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return "", 0, err
+	}
+	return contentType, size, nil
+}
+
+// path returns the file an attachment is kept in on the server
+func (as *AttachmentService) path(a *models.Attachment) string {
+	return filepath.Join(as.dir, a.UserID, a.ID)
+}
+
+// setAttachmentLinks fills in where an attachment is served and the markdown embedding it
+func setAttachmentLinks(a *models.Attachment) {
+	a.URL = "/api/attachments/" + a.ID
+	a.Markdown = fmt.Sprintf("[%s](%s)", a.Filename, a.URL)
+	if IsInlineAttachment(a) {
+		a.Markdown = "!" + a.Markdown
+	}
+}
+
+// IsInlineAttachment reports whether an attachment is an image shown inline
+func IsInlineAttachment(a *models.Attachment) bool {
+	return inlineImageTypes[a.ContentType]
+}
+
+// attachmentStorageName is the name of an attachment's file in storage, unique
+// within the context and readable in a file browser ("<id>-board.png")
+func attachmentStorageName(a *models.Attachment) string {
+	return a.ID + "-" + a.Filename
+}
+
+// attachmentFilename cleans up the name of an uploaded file so it is safe in
+// paths, storage queries and markdown: path and unusual characters become _ and
+// long names are shortened, keeping the extension
+func attachmentFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+	name = strings.TrimLeft(name, "._")
+	if len(name) > attachmentNameMax {
+		ext := path.Ext(name)
+		if len(ext) > attachmentNameMax/2 {
+			ext = ""
+		}
+		name = name[:attachmentNameMax-len(ext)] + ext
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// attachmentContentType detects a file's type from its first bytes, falling
+// back to its extension when those don't tell
+func attachmentContentType(filename string, head []byte) string {
+	contentType := http.DetectContentType(head)
+	if contentType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(path.Ext(filename)); byExt != "" {
+			contentType = byExt
+		}
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return contentType
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// MockAttachmentRepository is a mock implementation of AttachmentRepository interface
+type MockAttachmentRepository struct {
+	mock.Mock
+}
+
+var _ AttachmentRepository = (*MockAttachmentRepository)(nil)
+
+func (m *MockAttachmentRepository) GetContextByName(_ context.Context, scope database.UserScope, name string) (*models.Context, error) {
+	args := m.Called(scope.UserID(), name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Context), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) CreateAttachment(_ context.Context, a *models.Attachment) error {
+	args := m.Called(a)
+	return args.Error(0)
+}
+
+func (m *MockAttachmentRepository) GetAttachment(_ context.Context, userID, id string) (*models.Attachment, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) GetNoteAttachments(_ context.Context, userID, contextName, date string) ([]models.Attachment, error) {
+	args := m.Called(userID, contextName, date)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) SetAttachmentStorageStatus(_ context.Context, id, status, errorMsg string) error {
+	args := m.Called(id, status, errorMsg)
+	return args.Error(0)
+}
+
+// uploadingStorage is a storage provider that keeps attachments
+type uploadingStorage struct {
+	MockStorageService
+}
+
+func (m *uploadingStorage) UploadAttachment(contextName, name, contentType string, content io.Reader) error {
+	data, _ := io.ReadAll(content)
+	args := m.Called(contextName, name, contentType, string(data))
+	return args.Error(0)
+}
+
+// pngHeader is enough of a PNG file for its type to be detected
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newTestAttachmentService(t *testing.T, repo *MockAttachmentRepository, factory StorageFactory) *AttachmentService {
+	service := NewAttachmentService(repo, factory)
+	service.SetDir(t.TempDir())
+	service.SetClock(clock.NewFake(time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)))
+	service.SetIDGenerator(idgen.NewSequence("att"))
+	return service
+}
+
+func TestAttachmentService_Upload(t *testing.T) {
+	work := &models.Context{ID: "ctx1", UserID: "user123", Name: "Work"}
+
+	t.Run("Images are kept, embedded and queued for storage", func(t *testing.T) {
+		repo := new(MockAttachmentRepository)
+		repo.On("GetContextByName", "user123", "Work").Return(work, nil)
+		repo.On("CreateAttachment", mock.MatchedBy(func(a *models.Attachment) bool {
+			return a.ID == "att-1" && a.ContentType == "image/png" && a.Size == int64(len(pngHeader)) &&
+				a.StorageStatus == models.AttachmentPending
+		})).Return(nil)
+		queue := new(MockJobQueue)
+		queue.On("Enqueue", "user123", JobAttachment, attachmentUpload{ID: "att-1"}).Return(nil)
+		service := newTestAttachmentService(t, repo, nil)
+		service.SetJobQueue(queue, nil)
+
+		a, err := service.Upload(context.Background(), "user123", "Work", "2025-10-16", `C:\Users\ana\My board (1).png`, bytes.NewReader(pngHeader), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "My_board__1_.png", a.Filename)
+		assert.Equal(t, "/api/attachments/att-1", a.URL)
+		assert.Equal(t, "![My_board__1_.png](/api/attachments/att-1)", a.Markdown)
+		repo.AssertExpectations(t)
+		queue.AssertExpectations(t)
+
+		data, err := os.ReadFile(filepath.Join(service.dir, "user123", "att-1"))
+		require.NoError(t, err)
+		assert.Equal(t, pngHeader, data)
+	})
+
+	t.Run("Other files are linked, and only kept on the server for local-only contexts", func(t *testing.T) {
+		repo := new(MockAttachmentRepository)
+		repo.On("GetContextByName", "user123", "Diary").Return(&models.Context{Name: "Diary", LocalOnly: true}, nil)
+		repo.On("CreateAttachment", mock.MatchedBy(func(a *models.Attachment) bool {
+			return a.StorageStatus == models.AttachmentNotStored
+		})).Return(nil)
+		queue := new(MockJobQueue)
+		service := newTestAttachmentService(t, repo, nil)
+		service.SetJobQueue(queue, nil)
+
+		a, err := service.Upload(context.Background(), "user123", "Diary", "2025-W42", "notes.txt", strings.NewReader("plain text"), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "text/plain", a.ContentType)
+		assert.Equal(t, "[notes.txt](/api/attachments/att-1)", a.Markdown)
+		queue.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Uploads are checked", func(t *testing.T) {
+		repo := new(MockAttachmentRepository)
+		repo.On("GetContextByName", "user123", "Work").Return(work, nil)
+		repo.On("GetContextByName", "user123", "Missing").Return(nil, nil)
+		service := newTestAttachmentService(t, repo, nil)
+		service.SetMaxSize(4)
+
+		_, err := service.Upload(context.Background(), "user123", "Work", "yesterday", "a.png", bytes.NewReader(pngHeader), nil)
+		assert.ErrorIs(t, err, ErrInvalidPeriodKey)
+		_, err = service.Upload(context.Background(), "user123", "Missing", "2025-10-16", "a.png", bytes.NewReader(pngHeader), nil)
+		assert.ErrorIs(t, err, ErrContextNotFound)
+		_, err = service.Upload(context.Background(), "user123", "Work", "2025-10-16", "a.png", strings.NewReader(""), nil)
+		assert.ErrorIs(t, err, ErrEmptyAttachment)
+		_, err = service.Upload(context.Background(), "user123", "Work", "2025-10-16", "a.png", bytes.NewReader(pngHeader), nil)
+		assert.ErrorIs(t, err, ErrAttachmentTooLarge)
+		repo.AssertNotCalled(t, "CreateAttachment", mock.Anything)
+
+		entries, err := os.ReadDir(filepath.Join(service.dir, "user123"))
+		require.NoError(t, err)
+		assert.Empty(t, entries, "rejected uploads leave no files behind")
+	})
+}
+
+func TestAttachmentService_RunUploadJob(t *testing.T) {
+	attachment := &models.Attachment{ID: "att-1", UserID: "user123", Context: "Work", Date: "2025-10-16",
+		Filename: "board.png", ContentType: "image/png"}
+	job := &models.Job{UserID: "user123", Kind: JobAttachment, Payload: `{"id":"att-1"}`}
+	withToken := func(userID string) (*oauth2.Token, error) { return &oauth2.Token{AccessToken: "token"}, nil }
+
+	saveFile := func(t *testing.T, service *AttachmentService) {
+		require.NoError(t, os.MkdirAll(filepath.Join(service.dir, "user123"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(service.dir, "user123", "att-1"), pngHeader, 0o644))
+	}
+
+	t.Run("Attachments are copied to the context's folder", func(t *testing.T) {
+		repo := new(MockAttachmentRepository)
+		repo.On("GetAttachment", "user123", "att-1").Return(attachment, nil)
+		repo.On("SetAttachmentStorageStatus", "att-1", models.AttachmentStored, "").Return(nil).Once()
+		provider := new(uploadingStorage)
+		provider.On("UploadAttachment", "Work", "att-1-board.png", "image/png", string(pngHeader)).Return(nil)
+		service := newTestAttachmentService(t, repo, func(context.Context, *oauth2.Token, string) (StorageService, error) {
+			return provider, nil
+		})
+		service.SetJobQueue(new(MockJobQueue), withToken)
+		saveFile(t, service)
+
+		require.NoError(t, service.RunUploadJob(context.Background(), job))
+		repo.AssertExpectations(t)
+		provider.AssertExpectations(t)
+	})
+
+	t.Run("Providers without attachments keep them on the server", func(t *testing.T) {
+		repo := new(MockAttachmentRepository)
+		repo.On("GetAttachment", "user123", "att-1").Return(attachment, nil)
+		repo.On("SetAttachmentStorageStatus", "att-1", models.AttachmentNotStored, "").Return(nil).Once()
+		service := newTestAttachmentService(t, repo, func(context.Context, *oauth2.Token, string) (StorageService, error) {
+			return new(MockStorageService), nil
+		})
+		service.SetJobQueue(new(MockJobQueue), withToken)
+		saveFile(t, service)
+
+		require.NoError(t, service.RunUploadJob(context.Background(), job))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Failed copies are recorded and retried", func(t *testing.T) {
+		repo := new(MockAttachmentRepository)
+		repo.On("GetAttachment", "user123", "att-1").Return(attachment, nil)
+		repo.On("SetAttachmentStorageStatus", "att-1", models.AttachmentPending, "quota exceeded").Return(nil).Once()
+		provider := new(uploadingStorage)
+		provider.On("UploadAttachment", "Work", "att-1-board.png", "image/png", string(pngHeader)).Return(errors.New("quota exceeded"))
+		service := newTestAttachmentService(t, repo, func(context.Context, *oauth2.Token, string) (StorageService, error) {
+			return provider, nil
+		})
+		service.SetJobQueue(new(MockJobQueue), withToken)
+		saveFile(t, service)
+
+		assert.Error(t, service.RunUploadJob(context.Background(), job))
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestAttachmentFilename(t *testing.T) {
+	assert.Equal(t, "report.pdf", attachmentFilename("../../report.pdf"))
+	assert.Equal(t, "env", attachmentFilename(".env"))
+	assert.Equal(t, "file", attachmentFilename(""))
+	long := attachmentFilename(strings.Repeat("a", 150) + ".jpeg")
+	assert.Len(t, long, attachmentNameMax)
+	assert.True(t, strings.HasSuffix(long, ".jpeg"))
+}
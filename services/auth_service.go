@@ -3,8 +3,13 @@ package services
 import (
 	"context"
 	"daily-notes/config"
+	"daily-notes/database"
 	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/jobs"
 	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -19,6 +24,9 @@ type AuthService struct {
 	sessionStore   SessionStore
 	syncWorker     SyncWorker
 	storageFactory StorageFactory
+	clock          clock.Clock
+	jobs           JobQueue    // Runs imports and cleanups when set
+	tokens         TokenSource // Signs jobs in to storage
 }
 
 // NewAuthService creates a new auth service
@@ -28,9 +36,23 @@ func NewAuthService(repo AuthRepository, sessionStore SessionStore, syncWorker S
 		sessionStore:   sessionStore,
 		syncWorker:     syncWorker,
 		storageFactory: storageFactory,
+		clock:          clock.Real(),
 	}
 }
 
+// SetClock replaces the clock used for token expiry and login timestamps
+func (as *AuthService) SetClock(c clock.Clock) {
+	as.clock = c
+}
+
+// SetJobQueue runs the imports and cleanups that follow logins as jobs of queue,
+// signed in with tokens, instead of goroutines that a restart loses. Register
+// RunStorageJob for JobImport and JobCleanup.
+func (as *AuthService) SetJobQueue(queue JobQueue, tokens TokenSource) {
+	as.jobs = queue
+	as.tokens = tokens
+}
+
 // UserInfo represents user information from Google
 type UserInfo struct {
 	GoogleID string
@@ -47,8 +69,8 @@ type LoginResponse struct {
 }
 
 // LoginWithCode handles login via OAuth authorization code
-func (as *AuthService) LoginWithCode(code string) (*LoginResponse, error) {
-	ctx := context.Background()
+func (as *AuthService) LoginWithCode(ctx context.Context, code string) (_ *LoginResponse, err error) {
+	defer wrapOp("log in with code", &err)
 	oauthConfig := &oauth2.Config{
 		ClientID:     config.AppConfig.GoogleClientID,
 		ClientSecret: config.AppConfig.GoogleClientSecret,
@@ -77,7 +99,7 @@ func (as *AuthService) LoginWithCode(code string) (*LoginResponse, error) {
 	userSettings := as.getUserSettings(token, userInfo.GoogleID)
 
 	// Create or update user
-	if err := as.createOrUpdateUser(userInfo, userSettings); err != nil {
+	if err := as.createOrUpdateUser(ctx, userInfo, userSettings); err != nil {
 		return nil, err
 	}
 
@@ -97,7 +119,7 @@ func (as *AuthService) LoginWithCode(code string) (*LoginResponse, error) {
 	}
 
 	// Check if this is first login by checking if user has any contexts
-	hasNoContexts := as.checkFirstLogin(userInfo.GoogleID)
+	hasNoContexts := as.checkFirstLogin(ctx, userInfo.GoogleID)
 
 	// Return login response with metadata
 	return &LoginResponse{
@@ -108,9 +130,8 @@ func (as *AuthService) LoginWithCode(code string) (*LoginResponse, error) {
 }
 
 // LoginWithIDToken handles login via Google One Tap ID token
-func (as *AuthService) LoginWithIDToken(idToken string) (*LoginResponse, error) {
-	ctx := context.Background()
-
+func (as *AuthService) LoginWithIDToken(ctx context.Context, idToken string) (_ *LoginResponse, err error) {
+	defer wrapOp("log in with ID token", &err)
 	// Validate the ID token
 	payload, err := idtoken.Validate(ctx, idToken, config.AppConfig.GoogleClientID)
 	if err != nil {
@@ -143,7 +164,7 @@ func (as *AuthService) LoginWithIDToken(idToken string) (*LoginResponse, error)
 	}
 
 	// Create or update user
-	if err := as.createOrUpdateUser(userInfo, defaultSettings); err != nil {
+	if err := as.createOrUpdateUser(ctx, userInfo, defaultSettings); err != nil {
 		return nil, err
 	}
 
@@ -155,7 +176,7 @@ func (as *AuthService) LoginWithIDToken(idToken string) (*LoginResponse, error)
 		userInfo.Picture,
 		"", // No access token
 		"", // No refresh token
-		time.Now().Add(30*24*time.Hour), // Session expires in 30 days
+		as.clock.Now().Add(30*24*time.Hour), // Session expires in 30 days
 		defaultSettings,
 	)
 	if err != nil {
@@ -163,7 +184,7 @@ func (as *AuthService) LoginWithIDToken(idToken string) (*LoginResponse, error)
 	}
 
 	// Check if this is first login
-	hasNoContexts := as.checkFirstLogin(userInfo.GoogleID)
+	hasNoContexts := as.checkFirstLogin(ctx, userInfo.GoogleID)
 
 	return &LoginResponse{
 		Session:       sess,
@@ -173,10 +194,11 @@ func (as *AuthService) LoginWithIDToken(idToken string) (*LoginResponse, error)
 }
 
 // LoginWithToken handles login via direct access token (legacy)
-func (as *AuthService) LoginWithToken(accessToken, refreshToken string, expiresIn int64) (*LoginResponse, error) {
-	tokenExpiry := time.Now().Add(1 * time.Hour)
+func (as *AuthService) LoginWithToken(ctx context.Context, accessToken, refreshToken string, expiresIn int64) (_ *LoginResponse, err error) {
+	defer wrapOp("log in with token", &err)
+	tokenExpiry := as.clock.Now().Add(1 * time.Hour)
 	if expiresIn > 0 {
-		tokenExpiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+		tokenExpiry = as.clock.Now().Add(time.Duration(expiresIn) * time.Second)
 	}
 
 	token := &oauth2.Token{
@@ -195,7 +217,7 @@ func (as *AuthService) LoginWithToken(accessToken, refreshToken string, expiresI
 	userSettings := as.getUserSettings(token, userInfo.GoogleID)
 
 	// Create or update user
-	if err := as.createOrUpdateUser(userInfo, userSettings); err != nil {
+	if err := as.createOrUpdateUser(ctx, userInfo, userSettings); err != nil {
 		return nil, err
 	}
 
@@ -215,7 +237,7 @@ func (as *AuthService) LoginWithToken(accessToken, refreshToken string, expiresI
 	}
 
 	// Check if this is first login
-	hasNoContexts := as.checkFirstLogin(userInfo.GoogleID)
+	hasNoContexts := as.checkFirstLogin(ctx, userInfo.GoogleID)
 
 	// Return login response with metadata
 	return &LoginResponse{
@@ -226,12 +248,14 @@ func (as *AuthService) LoginWithToken(accessToken, refreshToken string, expiresI
 }
 
 // Logout handles user logout
-func (as *AuthService) Logout(sessionID string) error {
+func (as *AuthService) Logout(sessionID string) (err error) {
+	defer wrapOp("log out", &err)
 	return as.sessionStore.Delete(sessionID)
 }
 
 // GetSessionInfo returns current session information
-func (as *AuthService) GetSessionInfo(sessionID string) (*models.Session, error) {
+func (as *AuthService) GetSessionInfo(sessionID string) (_ *models.Session, err error) {
+	defer wrapOp("get session", &err)
 	sess, err := as.sessionStore.Get(sessionID)
 	if err != nil || sess == nil {
 		return nil, ErrSessionNotFound
@@ -307,7 +331,7 @@ func (as *AuthService) getUserSettings(token *oauth2.Token, userID string) model
 }
 
 // createOrUpdateUser saves or updates user in database
-func (as *AuthService) createOrUpdateUser(userInfo *UserInfo, settings models.UserSettings) error {
+func (as *AuthService) createOrUpdateUser(ctx context.Context, userInfo *UserInfo, settings models.UserSettings) error {
 	user := &models.User{
 		ID:          userInfo.GoogleID,
 		GoogleID:    userInfo.GoogleID,
@@ -315,24 +339,25 @@ func (as *AuthService) createOrUpdateUser(userInfo *UserInfo, settings models.Us
 		Name:        userInfo.Name,
 		Picture:     userInfo.Picture,
 		Settings:    settings,
-		CreatedAt:   time.Now(),
-		LastLoginAt: time.Now(),
+		CreatedAt:   as.clock.Now(),
+		LastLoginAt: as.clock.Now(),
 	}
 
-	return as.repo.UpsertUser(user)
+	return as.repo.UpsertUser(ctx, user)
 }
 
 // checkFirstLogin checks if user has any contexts (returns true if no contexts)
-func (as *AuthService) checkFirstLogin(userID string) bool {
-	contexts, err := as.repo.GetContexts(userID)
+func (as *AuthService) checkFirstLogin(ctx context.Context, userID string) bool {
+	contexts, err := as.repo.GetContexts(ctx, database.ScopeUser(userID))
 	return err == nil && len(contexts) == 0
 }
 
 // RefreshTokenIfNeeded checks if the access token is expiring soon and refreshes it if needed
 // Returns the updated token or the original if no refresh was needed
-func (as *AuthService) RefreshTokenIfNeeded(session *models.Session) (interface{}, error) {
+func (as *AuthService) RefreshTokenIfNeeded(session *models.Session) (_ interface{}, err error) {
+	defer wrapOp("refresh token", &err)
 	// If token expires in less than 5 minutes, refresh it
-	if time.Until(session.TokenExpiry) > 5*time.Minute {
+	if session.TokenExpiry.Sub(as.clock.Now()) > 5*time.Minute {
 		// Token is still valid, return current token
 		return &oauth2.Token{
 			AccessToken:  session.AccessToken,
@@ -393,24 +418,63 @@ func (as *AuthService) HandlePostLogin(loginResponse *LoginResponse) {
 		return
 	}
 
+	userID := loginResponse.Session.UserID
+
 	// If user has no contexts and has a valid token, import from Drive in background
 	if loginResponse.HasNoContexts && as.syncWorker != nil && loginResponse.Token.AccessToken != "" {
-		go func() {
-			userID := loginResponse.Session.UserID
-			if err := as.syncWorker.ImportFromDrive(userID, loginResponse.Token); err != nil {
-				// Log error but don't fail the login
-				// The error is already logged in the SyncWorker
-			}
-		}()
+		as.queueStorageJob(JobImport, userID, loginResponse.Token)
+	}
+
+	// Notes that failed to sync for lack of a valid sign-in can go now
+	if !loginResponse.HasNoContexts && as.syncWorker != nil && loginResponse.Token.AccessToken != "" {
+		as.syncWorker.RetryAfterSignIn(loginResponse.Session.UserID)
 	}
 
 	// Cleanup old deleted folders in background
 	if loginResponse.Token.AccessToken != "" {
-		go func() {
-			provider, err := as.storageFactory(context.Background(), loginResponse.Token, loginResponse.Session.UserID)
-			if err == nil {
-				_ = provider.CleanupOldDeletedFolders()
-			}
-		}()
+		as.queueStorageJob(JobCleanup, userID, loginResponse.Token)
 	}
 }
+
+// queueStorageJob runs an import or cleanup in the background: as a job when a
+// queue is set, else in a goroutine with the token of the login
+func (as *AuthService) queueStorageJob(kind, userID string, token *oauth2.Token) {
+	if as.jobs != nil {
+		err := as.jobs.Enqueue(context.Background(), userID, kind, struct{}{})
+		if err == nil {
+			return
+		}
+		slog.Warn("failed to queue storage job, running it now", "kind", kind, "user_id", userID, "error", err)
+	}
+	go func() {
+		// Log error but don't fail the login
+		// Import errors are already logged in the SyncWorker
+		_ = as.runStorageJob(context.Background(), kind, userID, token)
+	}()
+}
+
+// RunStorageJob runs an import from storage or a cleanup of its old deleted
+// folders queued by HandlePostLogin. Failures are retried by the queue.
+func (as *AuthService) RunStorageJob(ctx context.Context, job *models.Job) (err error) {
+	defer wrapOp("run storage job", &err)
+	token, err := as.tokens(job.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get authentication token: %w", err)
+	}
+	return as.runStorageJob(ctx, job.Kind, job.UserID, token)
+}
+
+// runStorageJob imports a user's notes from storage or cleans up its old deleted folders
+func (as *AuthService) runStorageJob(ctx context.Context, kind, userID string, token *oauth2.Token) error {
+	switch kind {
+	case JobImport:
+		return as.syncWorker.ImportFromDrive(userID, token)
+	case JobCleanup:
+		provider, err := as.storageFactory(ctx, token, userID)
+		if err != nil {
+			return err
+		}
+		return provider.CleanupOldDeletedFolders()
+	}
+	return jobs.Permanent(fmt.Errorf("unknown storage job %q", kind))
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"context"
+	"daily-notes/database"
 	"daily-notes/models"
+	"daily-notes/pkg/clock"
 	"errors"
 	"testing"
 	"time"
@@ -21,13 +23,13 @@ type MockAuthRepository struct {
 
 var _ AuthRepository = (*MockAuthRepository)(nil)
 
-func (m *MockAuthRepository) UpsertUser(user *models.User) error {
+func (m *MockAuthRepository) UpsertUser(_ context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockAuthRepository) GetContexts(userID string) ([]models.Context, error) {
-	args := m.Called(userID)
+func (m *MockAuthRepository) GetContexts(_ context.Context, scope database.UserScope) ([]models.Context, error) {
+	args := m.Called(scope.UserID())
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -57,6 +59,16 @@ func (m *MockSessionStore) Get(sessionID string) (*models.Session, error) {
 	return args.Get(0).(*models.Session), args.Error(1)
 }
 
+func (m *MockSessionStore) Update(sessionID string, session *models.Session) error {
+	args := m.Called(sessionID, session)
+	return args.Error(0)
+}
+
+func (m *MockSessionStore) UpdateUserToken(userID string, accessToken, refreshToken string, tokenExpiry time.Time) error {
+	args := m.Called(userID, accessToken, refreshToken, tokenExpiry)
+	return args.Error(0)
+}
+
 func (m *MockSessionStore) Delete(sessionID string) error {
 	args := m.Called(sessionID)
 	return args.Error(0)
@@ -104,7 +116,7 @@ func TestAuthService_Logout(t *testing.T) {
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
-				assert.Equal(t, tt.expectedError.Error(), err.Error())
+				assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 			} else {
 				assert.NoError(t, err)
 			}
@@ -249,10 +261,11 @@ func TestAuthService_createOrUpdateUser(t *testing.T) {
 			}
 
 			service := &AuthService{
-				repo: mockRepo,
+				repo:  mockRepo,
+				clock: clock.Real(),
 			}
 
-			err := service.createOrUpdateUser(tt.userInfo, tt.settings)
+			err := service.createOrUpdateUser(context.Background(), tt.userInfo, tt.settings)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -313,7 +326,7 @@ func TestAuthService_checkFirstLogin(t *testing.T) {
 				repo: mockRepo,
 			}
 
-			result := service.checkFirstLogin(tt.userID)
+			result := service.checkFirstLogin(context.Background(), tt.userID)
 
 			assert.Equal(t, tt.expectedResult, result)
 
@@ -459,11 +472,13 @@ func TestAuthService_HandlePostLogin(t *testing.T) {
 					AccessToken: "valid_token",
 				},
 			},
-			mockWorkerSetup: nil, // Should not be called
+			mockWorkerSetup: func(worker *MockSyncWorker) {
+				worker.On("RetryAfterSignIn", "user123").Return()
+			},
 			mockStorageSetup: func(provider *MockStorageService) {
 				provider.On("CleanupOldDeletedFolders").Return(nil)
 			},
-			expectWorkerCall:  false,
+			expectWorkerCall:  true,
 			expectStorageCall: true,
 		},
 		{
@@ -521,3 +536,45 @@ func TestAuthService_HandlePostLogin(t *testing.T) {
 	// Suppress unused variable warning
 	_ = now
 }
+
+func TestAuthService_StorageJobs(t *testing.T) {
+	login := &LoginResponse{
+		Session:       &models.Session{UserID: "user123"},
+		HasNoContexts: true,
+		Token:         &oauth2.Token{AccessToken: "valid_token"},
+	}
+	token := &oauth2.Token{AccessToken: "stored_token"}
+	tokens := func(userID string) (*oauth2.Token, error) { return token, nil }
+
+	t.Run("Imports and cleanups after a login are queued as jobs", func(t *testing.T) {
+		queue := new(MockJobQueue)
+		queue.On("Enqueue", "user123", JobImport, struct{}{}).Return(nil)
+		queue.On("Enqueue", "user123", JobCleanup, struct{}{}).Return(nil)
+
+		service := &AuthService{syncWorker: new(MockSyncWorker)}
+		service.SetJobQueue(queue, tokens)
+		service.HandlePostLogin(login)
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("Jobs run with the stored token", func(t *testing.T) {
+		worker := new(MockSyncWorker)
+		worker.On("ImportFromDrive", "user123", token).Return(errors.New("rate limited"))
+		provider := new(MockStorageService)
+		provider.On("CleanupOldDeletedFolders").Return(nil)
+
+		service := &AuthService{
+			syncWorker: worker,
+			storageFactory: func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+				return provider, nil
+			},
+		}
+		service.SetJobQueue(new(MockJobQueue), tokens)
+
+		err := service.RunStorageJob(context.Background(), &models.Job{UserID: "user123", Kind: JobImport})
+		assert.ErrorContains(t, err, "rate limited")
+		assert.NoError(t, service.RunStorageJob(context.Background(), &models.Job{UserID: "user123", Kind: JobCleanup}))
+		worker.AssertExpectations(t)
+		provider.AssertExpectations(t)
+	})
+}
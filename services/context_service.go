@@ -2,18 +2,43 @@ package services
 
 import (
 	"context"
+	"daily-notes/database"
 	"daily-notes/models"
+	"daily-notes/pkg/classifier"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"daily-notes/pkg/jobs"
+	"daily-notes/storage"
+	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"golang.org/x/oauth2"
 )
 
+// ContextTrashRetention is how long deleted contexts can be restored
+// Matches the cleanup window of Drive's _DELETED folder
+const ContextTrashRetention = 10 * 24 * time.Hour
+
+// NoteTrashRetention is how long deleted notes can be restored unless set
+// otherwise, the same window as contexts
+const NoteTrashRetention = ContextTrashRetention
+
+// suggestionTrainingLimit caps how many past notes train the context classifier
+const suggestionTrainingLimit = 500
+
 // ContextService handles business logic for contexts
 type ContextService struct {
 	repo           ContextRepository
 	storageFactory StorageFactory
+	clock          clock.Clock
+	ids            idgen.Generator
+	timeouts       Timeouts
+	jobs           JobQueue         // Runs folder changes in storage when set
+	tokens         TokenSource      // Signs jobs in to storage
+	members        MemberRepository // Lists contexts shared with the user when set
+	noteTrash      time.Duration    // How long the notes of deleted contexts can be restored; 0 keeps no trash
 }
 
 // NewContextService creates a new context service
@@ -21,16 +46,83 @@ func NewContextService(repo ContextRepository, storageFactory StorageFactory) *C
 	return &ContextService{
 		repo:           repo,
 		storageFactory: storageFactory,
+		clock:          clock.Real(),
+		ids:            idgen.UUID(),
+		timeouts:       DefaultTimeouts,
+		noteTrash:      NoteTrashRetention,
 	}
 }
 
-// List retrieves all contexts for a user
-func (cs *ContextService) List(userID string) ([]models.Context, error) {
-	return cs.repo.GetContexts(userID)
+// SetClock replaces the clock used for timestamps and trash retention
+func (cs *ContextService) SetClock(c clock.Clock) {
+	cs.clock = c
+}
+
+// SetIDGenerator replaces the generator used for new context IDs
+func (cs *ContextService) SetIDGenerator(g idgen.Generator) {
+	cs.ids = g
+}
+
+// SetTimeouts replaces the per-operation deadlines
+func (cs *ContextService) SetTimeouts(t Timeouts) {
+	cs.timeouts = t.WithDefaults()
+}
+
+// SetJobQueue changes context folders in storage through jobs of queue, signed
+// in with tokens, instead of goroutines that a restart loses. Register
+// RunFolderJob for JobRenameFolder, JobDeleteFolder, JobRestoreFolder and JobSaveConfig.
+func (cs *ContextService) SetJobQueue(queue JobQueue, tokens TokenSource) {
+	cs.jobs = queue
+	cs.tokens = tokens
+}
+
+// SetMembers lists the contexts shared with a user after their own
+func (cs *ContextService) SetMembers(members MemberRepository) {
+	cs.members = members
+}
+
+// SetNoteTrashRetention sets how long the notes of deleted contexts can be
+// restored, as NoteService.SetTrashRetention does for deleted notes
+func (cs *ContextService) SetNoteTrashRetention(d time.Duration) {
+	cs.noteTrash = d
 }
 
-// Create creates a new context for a user
-func (cs *ContextService) Create(userID, name, color string) (*models.Context, error) {
+// List retrieves all contexts for a user, then the contexts shared with them
+// whose name none of theirs hides
+func (cs *ContextService) List(ctx context.Context, userID string) (_ []models.Context, err error) {
+	defer wrapOp("list contexts", &err)
+	ctx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
+	contexts, err := cs.repo.GetContexts(ctx, database.ScopeUser(userID))
+	if err != nil || cs.members == nil {
+		return contexts, err
+	}
+
+	shared, err := cs.members.GetSharedContexts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(contexts))
+	for _, c := range contexts {
+		names[c.Name] = true
+	}
+	for _, c := range shared {
+		if !names[c.Name] {
+			names[c.Name] = true
+			contexts = append(contexts, c)
+		}
+	}
+	return contexts, nil
+}
+
+// Create creates a new context for a user. An icon is also written to the
+// config of the user's storage when token is given.
+func (cs *ContextService) Create(ctx context.Context, userID, name, color, icon string, token *oauth2.Token) (_ *models.Context, err error) {
+	defer wrapOp("create context", &err)
+	ctx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
 	// Trim whitespace
 	name = strings.TrimSpace(name)
 
@@ -40,7 +132,7 @@ func (cs *ContextService) Create(userID, name, color string) (*models.Context, e
 	}
 
 	// Check if context already exists
-	existing, err := cs.repo.GetContextByName(userID, name)
+	existing, err := cs.repo.GetContextByName(ctx, database.ScopeUser(userID), name)
 	if err != nil {
 		return nil, err
 	}
@@ -49,23 +141,58 @@ func (cs *ContextService) Create(userID, name, color string) (*models.Context, e
 	}
 
 	// Create in local database
-	ctx := &models.Context{
-		ID:        uuid.New().String(),
+	c := &models.Context{
+		ID:        cs.ids.NewID(),
 		UserID:    userID,
 		Name:      name,
 		Color:     color,
-		CreatedAt: time.Now(),
+		Icon:      icon,
+		CreatedAt: cs.clock.Now(),
 	}
 
-	if err := cs.repo.CreateContext(ctx); err != nil {
+	if err := cs.repo.CreateContext(ctx, c); err != nil {
 		return nil, err
 	}
 
-	return ctx, nil
+	if icon != "" && token != nil {
+		cs.queueFolderChange(JobSaveConfig, folderChange{ContextID: c.ID, Name: c.Name}, userID, token)
+	}
+
+	return c, nil
 }
 
-// Update updates an existing context
-func (cs *ContextService) Update(contextID, name, color string, userID string, token *oauth2.Token) error {
+// Reorder puts the user's contexts in the order of ids, as dragged in a list;
+// contexts left out follow in their current order. Returns the reordered list.
+func (cs *ContextService) Reorder(ctx context.Context, userID string, ids []string) (_ []models.Context, err error) {
+	defer wrapOp("reorder contexts", &err)
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return nil, ErrContextListedTwice
+		}
+		seen[id] = true
+	}
+
+	queryCtx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
+	reordered, err := cs.repo.ReorderContexts(queryCtx, database.ScopeUser(userID), ids)
+	if err != nil {
+		return nil, err
+	}
+	if !reordered {
+		return nil, ErrContextNotFound
+	}
+	return cs.List(ctx, userID)
+}
+
+// Update updates an existing context; a nil icon keeps the current one. A new
+// color or icon is also written to the config of the user's storage.
+func (cs *ContextService) Update(ctx context.Context, contextID, name, color string, icon *string, userID string, token *oauth2.Token) (err error) {
+	defer wrapOp("update context", &err)
+	ctx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
 	// Trim whitespace
 	name = strings.TrimSpace(name)
 
@@ -75,97 +202,507 @@ func (cs *ContextService) Update(contextID, name, color string, userID string, t
 	}
 
 	// Get the old context to check if name is changing
-	oldContext, err := cs.repo.GetContextByID(contextID)
+	oldContext, err := cs.repo.GetContextByID(ctx, database.ScopeUser(userID), contextID)
 	if err != nil {
 		return err
 	}
-	if oldContext == nil {
+	if oldContext == nil || oldContext.UserID != userID {
 		return ErrContextNotFound
 	}
 
 	// Check if name changed
 	nameChanged := oldContext.Name != name
 
+	newIcon := oldContext.Icon
+	if icon != nil {
+		newIcon = *icon
+	}
+	styleChanged := oldContext.Color != color || oldContext.Icon != newIcon
+
 	// Update context in local database
-	if err := cs.repo.UpdateContext(contextID, name, color); err != nil {
+	if err := cs.repo.UpdateContext(ctx, database.ScopeUser(userID), contextID, name, color, newIcon); err != nil {
 		return err
 	}
 
 	// If name changed, update all notes with the new context name
 	if nameChanged {
-		if err := cs.repo.UpdateNotesContextName(oldContext.Name, name, userID); err != nil {
+		if err := cs.repo.UpdateNotesContextName(ctx, database.ScopeUser(userID), oldContext.Name, name); err != nil {
 			return err
 		}
 
 		// Also rename folder in Google Drive if token is provided
 		if token != nil {
-			go cs.renameDriveFolder(contextID, oldContext.Name, name, userID, token)
+			cs.queueFolderChange(JobRenameFolder, folderChange{ContextID: contextID, Name: oldContext.Name, NewName: name}, userID, token)
 		}
 	}
 
+	// Queued after a rename, so the config already lists the new name
+	if styleChanged && token != nil {
+		cs.queueFolderChange(JobSaveConfig, folderChange{ContextID: contextID, Name: name}, userID, token)
+	}
+
 	return nil
 }
 
+// SetTemplate changes the template used to scaffold new notes in a context
+func (cs *ContextService) SetTemplate(ctx context.Context, contextID, userID, template string) (_ *models.Context, err error) {
+	defer wrapOp("set context template", &err)
+	ctx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
+	c, err := cs.repo.GetContextByID(ctx, database.ScopeUser(userID), contextID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.UserID != userID {
+		return nil, ErrContextNotFound
+	}
+
+	if err := cs.repo.UpdateContextTemplate(ctx, database.ScopeUser(userID), contextID, template); err != nil {
+		return nil, err
+	}
+
+	c.Template = template
+	return c, nil
+}
+
+// SetLocalOnly marks a context local only, so none of its notes are synced to
+// cloud storage from now on, or lets its notes sync again. Notes it holds that
+// were uploaded before are left in storage.
+func (cs *ContextService) SetLocalOnly(ctx context.Context, contextID, userID string, localOnly bool) (_ *models.Context, err error) {
+	defer wrapOp("set context local only", &err)
+	ctx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
+	c, err := cs.repo.GetContextByID(ctx, database.ScopeUser(userID), contextID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.UserID != userID {
+		return nil, ErrContextNotFound
+	}
+
+	if err := cs.repo.SetContextLocalOnly(ctx, database.ScopeUser(userID), contextID, localOnly); err != nil {
+		return nil, err
+	}
+
+	c.LocalOnly = localOnly
+	return c, nil
+}
+
+// SetLanguage sets the language a context's notes are written in, used for
+// transcription; empty falls back to the server default
+func (cs *ContextService) SetLanguage(ctx context.Context, contextID, userID, language string) (_ *models.Context, err error) {
+	defer wrapOp("set context language", &err)
+	ctx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
+	c, err := cs.repo.GetContextByID(ctx, database.ScopeUser(userID), contextID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.UserID != userID {
+		return nil, ErrContextNotFound
+	}
+
+	if err := cs.repo.UpdateContextLanguage(ctx, database.ScopeUser(userID), contextID, language); err != nil {
+		return nil, err
+	}
+
+	c.Language = language
+	return c, nil
+}
+
+// Language returns the language set on a user's context, empty if it has none
+// or the context doesn't exist
+func (cs *ContextService) Language(ctx context.Context, userID, contextName string) (_ string, err error) {
+	defer wrapOp("get context language", &err)
+	ctx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
+	c, err := cs.repo.GetContextByName(ctx, database.ScopeUser(userID), contextName)
+	if err != nil || c == nil {
+		return "", err
+	}
+	return c.Language, nil
+}
+
+// Stats returns what a context of the user holds and how its notes are
+// syncing. The state is degraded while notes failed or conflict; whether storage
+// is reachable is up to the caller, who knows the sync worker.
+func (cs *ContextService) Stats(ctx context.Context, contextID, userID string) (_ *models.ContextStats, err error) {
+	defer wrapOp("get context stats", &err)
+	ctx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
+	c, err := cs.repo.GetContextByID(ctx, database.ScopeUser(userID), contextID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.UserID != userID {
+		return nil, ErrContextNotFound
+	}
+
+	stats, err := cs.repo.GetContextStats(ctx, userID, c.Name)
+	if err != nil {
+		return nil, err
+	}
+	stats.ContextID = c.ID
+	stats.Sync.Pending = stats.Notes - stats.Sync.Synced - stats.Sync.Failed - stats.Sync.Conflicts - stats.Sync.Local
+	stats.Sync.State = models.SyncHealthOK
+	if stats.Sync.Failed > 0 || stats.Sync.Conflicts > 0 {
+		stats.Sync.State = models.SyncHealthDegraded
+	}
+	return stats, nil
+}
+
 // Delete deletes a context and its notes
-func (cs *ContextService) Delete(contextID, userID string, token *oauth2.Token) error {
+func (cs *ContextService) Delete(ctx context.Context, contextID, userID string, token *oauth2.Token) (err error) {
+	defer wrapOp("delete context", &err)
+	ctx, cancel := cs.timeouts.scan(ctx)
+	defer cancel()
+
 	// Get the context to retrieve its name
-	ctx, err := cs.repo.GetContextByID(contextID)
+	c, err := cs.repo.GetContextByID(ctx, database.ScopeUser(userID), contextID)
 	if err != nil {
 		return err
 	}
-	if ctx == nil {
+	if c == nil || c.UserID != userID {
 		return ErrContextNotFound
 	}
 
 	// Get all notes for this context and mark them as deleted
-	notes, err := cs.repo.GetNotesByContext(userID, ctx.Name, 1000, 0)
+	notes, err := cs.repo.GetNotesByContext(ctx, database.ScopeUser(userID), c.Name, 1000, 0)
 	if err != nil {
 		return err
 	}
 
-	// Mark all notes in this context as deleted (soft delete with sync pending)
+	// Mark all notes in this context as deleted (soft delete with sync pending),
+	// keeping them in the trash while it's on
+	now := cs.clock.Now()
 	for _, note := range notes {
 		// Ignore errors for individual notes, continue deleting others
-		cs.repo.DeleteNote(userID, ctx.Name, note.Date)
+		if cs.noteTrash > 0 {
+			cs.repo.TrashNote(ctx, database.ScopeUser(userID), c.Name, note.Date, now)
+		} else {
+			cs.repo.DeleteNote(ctx, database.ScopeUser(userID), c.Name, note.Date)
+		}
 	}
 
 	// Delete from local database
-	if err := cs.repo.DeleteContext(contextID); err != nil {
+	if err := cs.repo.DeleteContext(ctx, database.ScopeUser(userID), contextID, cs.clock.Now()); err != nil {
 		return err
 	}
 
 	// Move folder to _DELETED in Google Drive (async)
 	if token != nil {
-		go cs.deleteDriveFolder(contextID, ctx.Name, userID, token)
+		cs.queueFolderChange(JobDeleteFolder, folderChange{ContextID: contextID, Name: c.Name}, userID, token)
 	}
 
 	return nil
 }
 
+// ListTrash retrieves deleted contexts that are still within the restore window
+func (cs *ContextService) ListTrash(ctx context.Context, userID string) (_ []models.TrashedContext, err error) {
+	defer wrapOp("list trashed contexts", &err)
+	ctx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
+	return cs.repo.GetTrashedContexts(ctx, database.ScopeUser(userID), cs.clock.Now().Add(-ContextTrashRetention))
+}
+
+// Restore brings a deleted context back and re-imports its notes from cloud storage
+func (cs *ContextService) Restore(ctx context.Context, contextID, userID string, token *oauth2.Token) (_ *models.Context, err error) {
+	defer wrapOp("restore context", &err)
+	ctx, cancel := cs.timeouts.query(ctx)
+	defer cancel()
+
+	trashed, err := cs.repo.GetTrashedContext(ctx, database.ScopeUser(userID), contextID)
+	if err != nil {
+		return nil, err
+	}
+	if trashed == nil || cs.clock.Now().Sub(trashed.DeletedAt) > ContextTrashRetention {
+		return nil, ErrContextNotInTrash
+	}
+
+	// A new context may have taken the name in the meantime
+	existing, err := cs.repo.GetContextByName(ctx, database.ScopeUser(userID), trashed.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrContextAlreadyExists
+	}
+
+	if err := cs.repo.RestoreContext(ctx, database.ScopeUser(userID), contextID); err != nil {
+		return nil, err
+	}
+
+	c := &models.Context{
+		ID:        trashed.ID,
+		UserID:    trashed.UserID,
+		Name:      trashed.Name,
+		Color:     trashed.Color,
+		Icon:      trashed.Icon,
+		Template:  trashed.Template,
+		LocalOnly: trashed.LocalOnly,
+		Language:  trashed.Language,
+		CreatedAt: trashed.CreatedAt,
+	}
+
+	// Move folder back from _DELETED and re-import notes (async)
+	if token != nil {
+		cs.queueFolderChange(JobRestoreFolder, folderChange{ContextID: c.ID, Name: c.Name}, userID, token)
+	}
+
+	return c, nil
+}
+
+// Suggest ranks the user's contexts by how well their past notes match the given content
+// Uses a naive Bayes classifier trained on the most recently updated notes
+func (cs *ContextService) Suggest(ctx context.Context, userID, content string) (_ []models.ContextSuggestion, err error) {
+	defer wrapOp("suggest contexts", &err)
+	ctx, cancel := cs.timeouts.scan(ctx)
+	defer cancel()
+
+	contexts, err := cs.repo.GetContexts(ctx, database.ScopeUser(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	colors := make(map[string]string, len(contexts))
+	for _, c := range contexts {
+		colors[c.Name] = c.Color
+	}
+
+	notes, err := cs.repo.GetAllNotesByUser(ctx, database.ScopeUser(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	model := classifier.New()
+	for i, note := range notes {
+		if i >= suggestionTrainingLimit {
+			break
+		}
+		// Only learn from contexts that still exist
+		if _, ok := colors[note.Context]; ok {
+			model.Train(note.Context, note.Content)
+		}
+	}
+
+	suggestions := make([]models.ContextSuggestion, 0)
+	for _, prediction := range model.Predict(content) {
+		suggestions = append(suggestions, models.ContextSuggestion{
+			Context: prediction.Label,
+			Color:   colors[prediction.Label],
+			Score:   prediction.Score,
+		})
+	}
+
+	return suggestions, nil
+}
+
+// SuggestBest returns the single best matching context name for the content
+// Returns ErrNoContextSuggestion when there is not enough history to decide
+func (cs *ContextService) SuggestBest(ctx context.Context, userID, content string) (_ string, err error) {
+	defer wrapOp("suggest context", &err)
+	suggestions, err := cs.Suggest(ctx, userID, content)
+	if err != nil {
+		return "", err
+	}
+	if len(suggestions) == 0 {
+		return "", ErrNoContextSuggestion
+	}
+	return suggestions[0].Context, nil
+}
+
+// folderChange is the payload of the jobs changing a context folder in storage
+type folderChange struct {
+	ContextID string `json:"context_id"`
+	Name      string `json:"name"` // The folder's name in storage; the old name for renames
+	NewName   string `json:"new_name,omitempty"`
+}
+
+// queueFolderChange changes a context folder in storage in the background: as a
+// job when a queue is set, else in a goroutine with the token of the request
+func (cs *ContextService) queueFolderChange(kind string, change folderChange, userID string, token *oauth2.Token) {
+	if cs.jobs != nil {
+		ctx, cancel := cs.timeouts.query(context.Background())
+		defer cancel()
+		err := cs.jobs.Enqueue(ctx, userID, kind, change)
+		if err == nil {
+			return
+		}
+		slog.Warn("failed to queue context folder change, running it now", "kind", kind, "context", change.ContextID, "error", err)
+	}
+	go cs.runFolderChange(kind, change, userID, token)
+}
+
+// RunFolderJob applies a change of a context folder in storage queued by Create,
+// Update, Delete or Restore. Renames and deletions that fail are handed to the sync
+// worker's retries (see recordFailedOperation); restores are retried by the queue.
+func (cs *ContextService) RunFolderJob(ctx context.Context, job *models.Job) (err error) {
+	defer wrapOp("run context folder job", &err)
+	var change folderChange
+	if err := jobs.Decode(job, &change); err != nil {
+		return err
+	}
+
+	token, err := cs.tokens(job.UserID)
+	if err != nil {
+		err = fmt.Errorf("failed to get authentication token: %w", err)
+		if op, ok := folderOperation(job.Kind, change, job.UserID); ok {
+			cs.recordFailedOperation(op, err)
+			return nil
+		}
+		return err
+	}
+	return cs.runFolderChange(job.Kind, change, job.UserID, token)
+}
+
+// runFolderChange makes a change of a context folder in storage
+func (cs *ContextService) runFolderChange(kind string, change folderChange, userID string, token *oauth2.Token) error {
+	switch kind {
+	case JobRenameFolder:
+		cs.renameDriveFolder(change.ContextID, change.Name, change.NewName, userID, token)
+		return nil
+	case JobDeleteFolder:
+		cs.deleteDriveFolder(change.ContextID, change.Name, userID, token)
+		return nil
+	case JobRestoreFolder:
+		return cs.restoreDriveFolder(change.ContextID, userID, token)
+	case JobSaveConfig:
+		return cs.saveContextConfig(change.ContextID, userID, token)
+	}
+	return jobs.Permanent(fmt.Errorf("unknown context folder change %q", kind))
+}
+
+// folderOperation is the sync operation retrying a rename or deletion of a folder
+func folderOperation(kind string, change folderChange, userID string) (models.SyncOperation, bool) {
+	op := models.SyncOperation{UserID: userID, ContextID: change.ContextID, ContextName: change.Name, NewName: change.NewName}
+	switch kind {
+	case JobRenameFolder:
+		op.Kind = models.SyncOperationRename
+	case JobDeleteFolder:
+		op.Kind = models.SyncOperationDelete
+	default:
+		return op, false
+	}
+	return op, true
+}
+
 // renameDriveFolder renames a folder in cloud storage (runs in background)
 func (cs *ContextService) renameDriveFolder(contextID, oldName, newName, userID string, token *oauth2.Token) {
-	provider, err := cs.storageFactory(context.Background(), token, userID)
+	ctx, cancel := cs.timeouts.storage()
+	defer cancel()
+
+	op, _ := folderOperation(JobRenameFolder, folderChange{ContextID: contextID, Name: oldName, NewName: newName}, userID)
+	provider, err := cs.storageFactory(ctx, token, userID)
 	if err != nil {
-		// Log error but don't fail - already updated locally
+		// Already updated locally; the sync worker retries the rename
+		cs.recordFailedOperation(op, fmt.Errorf("failed to connect to cloud storage: %w", err))
 		return
 	}
 
 	if err := provider.RenameContext(contextID, oldName, newName); err != nil {
-		// Log error but don't fail - already updated locally
+		cs.recordFailedOperation(op, err)
 		return
 	}
 }
 
 // deleteDriveFolder moves a folder to _DELETED in cloud storage (runs in background)
 func (cs *ContextService) deleteDriveFolder(contextID, contextName, userID string, token *oauth2.Token) {
-	provider, err := cs.storageFactory(context.Background(), token, userID)
+	ctx, cancel := cs.timeouts.storage()
+	defer cancel()
+
+	op, _ := folderOperation(JobDeleteFolder, folderChange{ContextID: contextID, Name: contextName}, userID)
+	provider, err := cs.storageFactory(ctx, token, userID)
 	if err != nil {
-		// Log error but context is already deleted locally
+		// Already deleted locally; the sync worker retries moving the folder
+		cs.recordFailedOperation(op, fmt.Errorf("failed to connect to cloud storage: %w", err))
 		return
 	}
 
 	if err := provider.DeleteContext(contextID, contextName); err != nil {
-		// Log error but context is already deleted locally
+		cs.recordFailedOperation(op, err)
 		return
 	}
 }
+
+// recordFailedOperation queues a folder change that failed in storage for the
+// sync worker to retry (see sync/operations.go)
+func (cs *ContextService) recordFailedOperation(op models.SyncOperation, cause error) {
+	ctx, cancel := cs.timeouts.query(context.Background())
+	defer cancel()
+
+	op.Error = cause.Error()
+	op.CreatedAt = cs.clock.Now()
+	if err := cs.repo.RecordSyncOperation(ctx, &op); err != nil {
+		slog.Warn("failed to queue context folder change for retry", "kind", op.Kind, "context", op.ContextID, "error", err)
+	}
+}
+
+// restoreDriveFolder moves a folder back from _DELETED and re-imports its notes (runs in background)
+// A context deleted again in the meantime is left alone.
+func (cs *ContextService) restoreDriveFolder(contextID, userID string, token *oauth2.Token) error {
+	ctx, cancel := cs.timeouts.storage()
+	defer cancel()
+
+	c, err := cs.repo.GetContextByID(ctx, database.ScopeUser(userID), contextID)
+	if err != nil {
+		return err
+	}
+	if c == nil || c.UserID != userID {
+		return nil
+	}
+
+	provider, err := cs.storageFactory(ctx, token, userID)
+	if err != nil {
+		// Context is already restored locally
+		return fmt.Errorf("failed to connect to cloud storage: %w", err)
+	}
+
+	if err := provider.RestoreContext(*c); err != nil {
+		// Context is already restored locally
+		return err
+	}
+
+	notes, err := provider.GetAllNotesInContext(c.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, note := range notes {
+		note.UserID = userID
+		// Already in storage, so don't mark for sync
+		cs.repo.UpsertNote(ctx, &note, false)
+	}
+	return nil
+}
+
+// saveContextConfig writes the color and icon of a context to the config of the
+// user's storage, for providers that keep one (runs in background). Failures are
+// retried by the queue; a context deleted in the meantime is left alone.
+func (cs *ContextService) saveContextConfig(contextID, userID string, token *oauth2.Token) error {
+	ctx, cancel := cs.timeouts.storage()
+	defer cancel()
+
+	c, err := cs.repo.GetContextByID(ctx, database.ScopeUser(userID), contextID)
+	if err != nil {
+		return err
+	}
+	if c == nil || c.UserID != userID {
+		return nil
+	}
+
+	provider, err := cs.storageFactory(ctx, token, userID)
+	if err != nil {
+		return fmt.Errorf("failed to connect to cloud storage: %w", err)
+	}
+	writer, ok := provider.(storage.ContextConfigWriter)
+	if !ok {
+		return nil
+	}
+	return writer.UpdateContextConfig(*c)
+}
@@ -2,13 +2,18 @@ package services
 
 import (
 	"context"
+	"daily-notes/database"
 	"daily-notes/models"
-	"daily-notes/storage/drive"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"daily-notes/storage"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/oauth2"
 )
 
@@ -22,23 +27,23 @@ type MockContextRepository struct {
 // Ensure MockContextRepository implements ContextRepository interface
 var _ ContextRepository = (*MockContextRepository)(nil)
 
-func (m *MockContextRepository) GetContexts(userID string) ([]models.Context, error) {
-	args := m.Called(userID)
+func (m *MockContextRepository) GetContexts(_ context.Context, scope database.UserScope) ([]models.Context, error) {
+	args := m.Called(scope.UserID())
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]models.Context), args.Error(1)
 }
 
-func (m *MockContextRepository) GetContextByName(userID, name string) (*models.Context, error) {
-	args := m.Called(userID, name)
+func (m *MockContextRepository) GetContextByName(_ context.Context, scope database.UserScope, name string) (*models.Context, error) {
+	args := m.Called(scope.UserID(), name)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Context), args.Error(1)
 }
 
-func (m *MockContextRepository) GetContextByID(contextID string) (*models.Context, error) {
+func (m *MockContextRepository) GetContextByID(_ context.Context, _ database.UserScope, contextID string) (*models.Context, error) {
 	args := m.Called(contextID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -46,36 +51,120 @@ func (m *MockContextRepository) GetContextByID(contextID string) (*models.Contex
 	return args.Get(0).(*models.Context), args.Error(1)
 }
 
-func (m *MockContextRepository) CreateContext(ctx *models.Context) error {
-	args := m.Called(ctx)
+func (m *MockContextRepository) CreateContext(_ context.Context, c *models.Context) error {
+	args := m.Called(c)
 	return args.Error(0)
 }
 
-func (m *MockContextRepository) UpdateContext(contextID, name, color string) error {
-	args := m.Called(contextID, name, color)
+func (m *MockContextRepository) UpdateContext(_ context.Context, _ database.UserScope, contextID, name, color, icon string) error {
+	args := m.Called(contextID, name, color, icon)
 	return args.Error(0)
 }
 
-func (m *MockContextRepository) UpdateNotesContextName(oldName, newName, userID string) error {
-	args := m.Called(oldName, newName, userID)
+func (m *MockContextRepository) UpdateNotesContextName(_ context.Context, scope database.UserScope, oldName, newName string) error {
+	args := m.Called(oldName, newName, scope.UserID())
 	return args.Error(0)
 }
 
-func (m *MockContextRepository) DeleteContext(contextID string) error {
-	args := m.Called(contextID)
+func (m *MockContextRepository) DeleteContext(_ context.Context, _ database.UserScope, contextID string, deletedAt time.Time) error {
+	args := m.Called(contextID, deletedAt)
+	return args.Error(0)
+}
+
+func (m *MockContextRepository) GetTrashedContexts(_ context.Context, scope database.UserScope, since time.Time) ([]models.TrashedContext, error) {
+	args := m.Called(scope.UserID(), since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TrashedContext), args.Error(1)
+}
+
+func (m *MockContextRepository) GetTrashedContext(_ context.Context, scope database.UserScope, contextID string) (*models.TrashedContext, error) {
+	args := m.Called(scope.UserID(), contextID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TrashedContext), args.Error(1)
+}
+
+func (m *MockContextRepository) RestoreContext(_ context.Context, scope database.UserScope, contextID string) error {
+	args := m.Called(scope.UserID(), contextID)
+	return args.Error(0)
+}
+
+func (m *MockContextRepository) ReorderContexts(_ context.Context, scope database.UserScope, ids []string) (bool, error) {
+	args := m.Called(scope.UserID(), ids)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockContextRepository) UpdateContextTemplate(_ context.Context, _ database.UserScope, contextID, template string) error {
+	args := m.Called(contextID, template)
+	return args.Error(0)
+}
+
+func (m *MockContextRepository) SetContextLocalOnly(_ context.Context, _ database.UserScope, contextID string, localOnly bool) error {
+	args := m.Called(contextID, localOnly)
+	return args.Error(0)
+}
+
+func (m *MockContextRepository) UpdateContextLanguage(_ context.Context, _ database.UserScope, contextID, language string) error {
+	args := m.Called(contextID, language)
 	return args.Error(0)
 }
 
-func (m *MockContextRepository) GetNotesByContext(userID, contextName string, limit, offset int) ([]models.Note, error) {
-	args := m.Called(userID, contextName, limit, offset)
+func (m *MockContextRepository) GetContextStats(_ context.Context, userID, contextName string) (*models.ContextStats, error) {
+	args := m.Called(userID, contextName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ContextStats), args.Error(1)
+}
+
+func (m *MockContextRepository) RecordSyncOperation(_ context.Context, op *models.SyncOperation) error {
+	args := m.Called(op)
+	return args.Error(0)
+}
+
+func (m *MockContextRepository) GetAllNotesByUser(_ context.Context, scope database.UserScope) ([]models.Note, error) {
+	args := m.Called(scope.UserID())
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func (m *MockContextRepository) UpsertNote(_ context.Context, note *models.Note, syncPending bool) error {
+	args := m.Called(note, syncPending)
+	return args.Error(0)
+}
+
+func (m *MockContextRepository) GetNotesByContext(_ context.Context, scope database.UserScope, contextName string, limit, offset int) ([]models.Note, error) {
+	args := m.Called(scope.UserID(), contextName, limit, offset)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]models.Note), args.Error(1)
 }
 
-func (m *MockContextRepository) DeleteNote(userID, contextName, date string) error {
-	args := m.Called(userID, contextName, date)
+func (m *MockContextRepository) DeleteNote(_ context.Context, scope database.UserScope, contextName, date string) error {
+	args := m.Called(scope.UserID(), contextName, date)
+	return args.Error(0)
+}
+
+func (m *MockContextRepository) TrashNote(_ context.Context, scope database.UserScope, contextName, date string, deletedAt time.Time) error {
+	args := m.Called(scope.UserID(), contextName, date, deletedAt)
+	return args.Error(0)
+}
+
+// MockJobQueue is a mock implementation of JobQueue interface
+type MockJobQueue struct {
+	mock.Mock
+}
+
+var _ JobQueue = (*MockJobQueue)(nil)
+
+func (m *MockJobQueue) Enqueue(_ context.Context, userID, kind string, payload any) error {
+	args := m.Called(userID, kind, payload)
 	return args.Error(0)
 }
 
@@ -128,18 +217,23 @@ func (m *MockStorageService) DeleteContext(contextID, contextName string) error
 }
 
 // Settings operations
+func (m *MockStorageService) RestoreContext(ctx models.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 func (m *MockStorageService) GetSettings() (models.UserSettings, error) {
 	args := m.Called()
 	return args.Get(0).(models.UserSettings), args.Error(1)
 }
 
 // Config operations
-func (m *MockStorageService) GetConfig() (*drive.Config, error) {
+func (m *MockStorageService) GetConfig() (*storage.Config, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*drive.Config), args.Error(1)
+	return args.Get(0).(*storage.Config), args.Error(1)
 }
 
 // Utility operations
@@ -210,15 +304,16 @@ func TestContextService_List(t *testing.T) {
 			}
 
 			service := &ContextService{
+				clock:          clock.Real(),
 				repo:           mockRepo,
 				storageFactory: nil,
 			}
 
-			contexts, err := service.List(tt.userID)
+			contexts, err := service.List(context.Background(), tt.userID)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
-				assert.Equal(t, tt.expectedError.Error(), err.Error())
+				assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 				assert.Nil(t, contexts)
 			} else {
 				assert.NoError(t, err)
@@ -327,18 +422,20 @@ func TestContextService_Create(t *testing.T) {
 			}
 
 			service := &ContextService{
+				clock:          clock.Real(),
+				ids:            idgen.UUID(),
 				repo:           mockRepo,
 				storageFactory: nil,
 			}
 
-			ctx, err := service.Create(tt.userID, tt.contextName, tt.color)
+			ctx, err := service.Create(context.Background(), tt.userID, tt.contextName, tt.color, "", nil)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
 				if errors.Is(tt.expectedError, ErrContextAlreadyExists) {
 					assert.ErrorIs(t, err, ErrContextAlreadyExists)
 				} else {
-					assert.Equal(t, tt.expectedError.Error(), err.Error())
+					assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 				}
 				assert.Nil(t, ctx)
 			} else {
@@ -374,9 +471,9 @@ func TestContextService_Update(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockRepoSetup: func(repo *MockContextRepository) {
-				oldCtx := &models.Context{ID: "ctx1", Name: "work", Color: "primary"}
+				oldCtx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work", Color: "primary"}
 				repo.On("GetContextByID", "ctx1").Return(oldCtx, nil)
-				repo.On("UpdateContext", "ctx1", "work", "danger").Return(nil)
+				repo.On("UpdateContext", "ctx1", "work", "danger", "").Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -388,9 +485,9 @@ func TestContextService_Update(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockRepoSetup: func(repo *MockContextRepository) {
-				oldCtx := &models.Context{ID: "ctx1", Name: "work", Color: "primary"}
+				oldCtx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work", Color: "primary"}
 				repo.On("GetContextByID", "ctx1").Return(oldCtx, nil)
-				repo.On("UpdateContext", "ctx1", "projects", "info").Return(nil)
+				repo.On("UpdateContext", "ctx1", "projects", "info", "").Return(nil)
 				repo.On("UpdateNotesContextName", "work", "projects", "user123").Return(nil)
 			},
 			expectedError: nil,
@@ -403,9 +500,9 @@ func TestContextService_Update(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockRepoSetup: func(repo *MockContextRepository) {
-				oldCtx := &models.Context{ID: "ctx1", Name: "work", Color: "info"}
+				oldCtx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work", Color: "info"}
 				repo.On("GetContextByID", "ctx1").Return(oldCtx, nil)
-				repo.On("UpdateContext", "ctx1", "work", "primary").Return(nil)
+				repo.On("UpdateContext", "ctx1", "work", "primary", "").Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -417,9 +514,9 @@ func TestContextService_Update(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockRepoSetup: func(repo *MockContextRepository) {
-				oldCtx := &models.Context{ID: "ctx1", Name: "work", Color: "info"}
+				oldCtx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work", Color: "info"}
 				repo.On("GetContextByID", "ctx1").Return(oldCtx, nil)
-				repo.On("UpdateContext", "ctx1", "work", "primary").Return(nil) // Default color
+				repo.On("UpdateContext", "ctx1", "work", "primary", "").Return(nil) // Default color
 			},
 			expectedError: nil,
 		},
@@ -435,6 +532,18 @@ func TestContextService_Update(t *testing.T) {
 			},
 			expectedError: ErrContextNotFound,
 		},
+		{
+			name:      "Error - Context of another user",
+			contextID: "ctx1",
+			newName:   "work",
+			color:     "primary",
+			userID:    "user123",
+			token:     nil,
+			mockRepoSetup: func(repo *MockContextRepository) {
+				repo.On("GetContextByID", "ctx1").Return(&models.Context{ID: "ctx1", UserID: "other", Name: "work"}, nil)
+			},
+			expectedError: ErrContextNotFound,
+		},
 		{
 			name:      "Error - GetContextByID fails",
 			contextID: "ctx1",
@@ -455,9 +564,9 @@ func TestContextService_Update(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockRepoSetup: func(repo *MockContextRepository) {
-				oldCtx := &models.Context{ID: "ctx1", Name: "work", Color: "info"}
+				oldCtx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work", Color: "info"}
 				repo.On("GetContextByID", "ctx1").Return(oldCtx, nil)
-				repo.On("UpdateContext", "ctx1", "work", "primary").Return(errors.New("database error"))
+				repo.On("UpdateContext", "ctx1", "work", "primary", "").Return(errors.New("database error"))
 			},
 			expectedError: errors.New("database error"),
 		},
@@ -469,9 +578,9 @@ func TestContextService_Update(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockRepoSetup: func(repo *MockContextRepository) {
-				oldCtx := &models.Context{ID: "ctx1", Name: "work", Color: "primary"}
+				oldCtx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work", Color: "primary"}
 				repo.On("GetContextByID", "ctx1").Return(oldCtx, nil)
-				repo.On("UpdateContext", "ctx1", "projects", "info").Return(nil)
+				repo.On("UpdateContext", "ctx1", "projects", "info", "").Return(nil)
 				repo.On("UpdateNotesContextName", "work", "projects", "user123").Return(errors.New("database error"))
 			},
 			expectedError: errors.New("database error"),
@@ -495,18 +604,19 @@ func TestContextService_Update(t *testing.T) {
 			}
 
 			service := &ContextService{
+				clock:          clock.Real(),
 				repo:           mockRepo,
 				storageFactory: storageFactory,
 			}
 
-			err := service.Update(tt.contextID, tt.newName, tt.color, tt.userID, tt.token)
+			err := service.Update(context.Background(), tt.contextID, tt.newName, tt.color, nil, tt.userID, tt.token)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
 				if errors.Is(tt.expectedError, ErrContextNotFound) {
 					assert.ErrorIs(t, err, ErrContextNotFound)
 				} else {
-					assert.Equal(t, tt.expectedError.Error(), err.Error())
+					assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 				}
 			} else {
 				assert.NoError(t, err)
@@ -523,6 +633,7 @@ func TestContextService_Delete(t *testing.T) {
 		contextID     string
 		userID        string
 		token         *oauth2.Token
+		noteTrash     time.Duration
 		mockSetup     func(*MockContextRepository)
 		expectedError error
 	}{
@@ -532,10 +643,10 @@ func TestContextService_Delete(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockSetup: func(repo *MockContextRepository) {
-				ctx := &models.Context{ID: "ctx1", Name: "work"}
+				ctx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work"}
 				repo.On("GetContextByID", "ctx1").Return(ctx, nil)
 				repo.On("GetNotesByContext", "user123", "work", 1000, 0).Return([]models.Note{}, nil)
-				repo.On("DeleteContext", "ctx1").Return(nil)
+				repo.On("DeleteContext", "ctx1", mock.Anything).Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -545,7 +656,7 @@ func TestContextService_Delete(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockSetup: func(repo *MockContextRepository) {
-				ctx := &models.Context{ID: "ctx1", Name: "work"}
+				ctx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work"}
 				notes := []models.Note{
 					{ID: "note1", Date: "2025-10-18"},
 					{ID: "note2", Date: "2025-10-17"},
@@ -554,7 +665,7 @@ func TestContextService_Delete(t *testing.T) {
 				repo.On("GetNotesByContext", "user123", "work", 1000, 0).Return(notes, nil)
 				repo.On("DeleteNote", "user123", "work", "2025-10-18").Return(nil)
 				repo.On("DeleteNote", "user123", "work", "2025-10-17").Return(nil)
-				repo.On("DeleteContext", "ctx1").Return(nil)
+				repo.On("DeleteContext", "ctx1", mock.Anything).Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -564,7 +675,7 @@ func TestContextService_Delete(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockSetup: func(repo *MockContextRepository) {
-				ctx := &models.Context{ID: "ctx1", Name: "work"}
+				ctx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work"}
 				notes := []models.Note{
 					{ID: "note1", Date: "2025-10-18"},
 					{ID: "note2", Date: "2025-10-17"},
@@ -573,10 +684,25 @@ func TestContextService_Delete(t *testing.T) {
 				repo.On("GetNotesByContext", "user123", "work", 1000, 0).Return(notes, nil)
 				repo.On("DeleteNote", "user123", "work", "2025-10-18").Return(errors.New("note error"))
 				repo.On("DeleteNote", "user123", "work", "2025-10-17").Return(nil)
-				repo.On("DeleteContext", "ctx1").Return(nil)
+				repo.On("DeleteContext", "ctx1", mock.Anything).Return(nil)
 			},
 			expectedError: nil, // Should still succeed
 		},
+		{
+			name:      "Success - Notes are kept in the trash while it's on",
+			contextID: "ctx1",
+			userID:    "user123",
+			token:     nil,
+			noteTrash: NoteTrashRetention,
+			mockSetup: func(repo *MockContextRepository) {
+				ctx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work"}
+				repo.On("GetContextByID", "ctx1").Return(ctx, nil)
+				repo.On("GetNotesByContext", "user123", "work", 1000, 0).Return([]models.Note{{ID: "note1", Date: "2025-10-18"}}, nil)
+				repo.On("TrashNote", "user123", "work", "2025-10-18", mock.Anything).Return(nil)
+				repo.On("DeleteContext", "ctx1", mock.Anything).Return(nil)
+			},
+			expectedError: nil,
+		},
 		{
 			name:      "Error - Context not found",
 			contextID: "ctx1",
@@ -587,6 +713,16 @@ func TestContextService_Delete(t *testing.T) {
 			},
 			expectedError: ErrContextNotFound,
 		},
+		{
+			name:      "Error - Context of another user",
+			contextID: "ctx1",
+			userID:    "user123",
+			token:     nil,
+			mockSetup: func(repo *MockContextRepository) {
+				repo.On("GetContextByID", "ctx1").Return(&models.Context{ID: "ctx1", UserID: "other", Name: "work"}, nil)
+			},
+			expectedError: ErrContextNotFound,
+		},
 		{
 			name:      "Error - GetContextByID fails",
 			contextID: "ctx1",
@@ -603,7 +739,7 @@ func TestContextService_Delete(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockSetup: func(repo *MockContextRepository) {
-				ctx := &models.Context{ID: "ctx1", Name: "work"}
+				ctx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work"}
 				repo.On("GetContextByID", "ctx1").Return(ctx, nil)
 				repo.On("GetNotesByContext", "user123", "work", 1000, 0).Return(nil, errors.New("database error"))
 			},
@@ -615,10 +751,10 @@ func TestContextService_Delete(t *testing.T) {
 			userID:    "user123",
 			token:     nil,
 			mockSetup: func(repo *MockContextRepository) {
-				ctx := &models.Context{ID: "ctx1", Name: "work"}
+				ctx := &models.Context{ID: "ctx1", UserID: "user123", Name: "work"}
 				repo.On("GetContextByID", "ctx1").Return(ctx, nil)
 				repo.On("GetNotesByContext", "user123", "work", 1000, 0).Return([]models.Note{}, nil)
-				repo.On("DeleteContext", "ctx1").Return(errors.New("database error"))
+				repo.On("DeleteContext", "ctx1", mock.Anything).Return(errors.New("database error"))
 			},
 			expectedError: errors.New("database error"),
 		},
@@ -632,18 +768,20 @@ func TestContextService_Delete(t *testing.T) {
 			}
 
 			service := &ContextService{
+				clock:          clock.Real(),
 				repo:           mockRepo,
 				storageFactory: nil,
+				noteTrash:      tt.noteTrash,
 			}
 
-			err := service.Delete(tt.contextID, tt.userID, tt.token)
+			err := service.Delete(context.Background(), tt.contextID, tt.userID, tt.token)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
 				if errors.Is(tt.expectedError, ErrContextNotFound) {
 					assert.ErrorIs(t, err, ErrContextNotFound)
 				} else {
-					assert.Equal(t, tt.expectedError.Error(), err.Error())
+					assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 				}
 			} else {
 				assert.NoError(t, err)
@@ -653,3 +791,390 @@ func TestContextService_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestContextService_Restore(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockSetup     func(*MockContextRepository)
+		expectedError error
+	}{
+		{
+			name: "Success - Restore recently deleted context",
+			mockSetup: func(repo *MockContextRepository) {
+				trashed := &models.TrashedContext{ID: "ctx1", UserID: "user123", Name: "work", Color: "info", DeletedAt: time.Now().Add(-time.Hour)}
+				repo.On("GetTrashedContext", "user123", "ctx1").Return(trashed, nil)
+				repo.On("GetContextByName", "user123", "work").Return(nil, nil)
+				repo.On("RestoreContext", "user123", "ctx1").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "Error - Context not in trash",
+			mockSetup: func(repo *MockContextRepository) {
+				repo.On("GetTrashedContext", "user123", "ctx1").Return(nil, nil)
+			},
+			expectedError: ErrContextNotInTrash,
+		},
+		{
+			name: "Error - Retention window expired",
+			mockSetup: func(repo *MockContextRepository) {
+				trashed := &models.TrashedContext{ID: "ctx1", UserID: "user123", Name: "work", DeletedAt: time.Now().Add(-ContextTrashRetention - time.Hour)}
+				repo.On("GetTrashedContext", "user123", "ctx1").Return(trashed, nil)
+			},
+			expectedError: ErrContextNotInTrash,
+		},
+		{
+			name: "Error - Name taken by another context",
+			mockSetup: func(repo *MockContextRepository) {
+				trashed := &models.TrashedContext{ID: "ctx1", UserID: "user123", Name: "work", DeletedAt: time.Now()}
+				repo.On("GetTrashedContext", "user123", "ctx1").Return(trashed, nil)
+				repo.On("GetContextByName", "user123", "work").Return(&models.Context{ID: "ctx2", Name: "work"}, nil)
+			},
+			expectedError: ErrContextAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockContextRepository)
+			tt.mockSetup(mockRepo)
+
+			service := &ContextService{repo: mockRepo, clock: clock.Real()}
+
+			ctx, err := service.Restore(context.Background(), "ctx1", "user123", nil)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, ctx)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "work", ctx.Name)
+				assert.Equal(t, "info", ctx.Color)
+			}
+
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestContextService_Suggest(t *testing.T) {
+	mockRepo := new(MockContextRepository)
+	mockRepo.On("GetContexts", "user123").Return([]models.Context{
+		{Name: "Work", Color: "info"},
+		{Name: "Fitness", Color: "success"},
+	}, nil)
+	mockRepo.On("GetAllNotesByUser", "user123").Return([]models.Note{
+		{Context: "Work", Content: "Deploy pipeline failed, fixed the build"},
+		{Context: "Fitness", Content: "Ran 5km, then squats and deadlifts"},
+		{Context: "Archived", Content: "Deploy build pipeline release"},
+	}, nil)
+
+	service := &ContextService{repo: mockRepo, clock: clock.Real()}
+
+	suggestions, err := service.Suggest(context.Background(), "user123", "The build pipeline is red again")
+
+	assert.NoError(t, err)
+	assert.Len(t, suggestions, 2) // Deleted contexts are not suggested
+	assert.Equal(t, "Work", suggestions[0].Context)
+	assert.Equal(t, "info", suggestions[0].Color)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestContextService_SetTemplate(t *testing.T) {
+	t.Run("Updates template of own context", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("GetContextByID", "ctx1").Return(&models.Context{ID: "ctx1", UserID: "user123", Name: "Work"}, nil)
+		mockRepo.On("UpdateContextTemplate", "ctx1", "# {{date}}").Return(nil)
+
+		service := NewContextService(mockRepo, nil)
+		ctx, err := service.SetTemplate(context.Background(), "ctx1", "user123", "# {{date}}")
+
+		require.NoError(t, err)
+		assert.Equal(t, "# {{date}}", ctx.Template)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects other users' contexts", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("GetContextByID", "ctx1").Return(&models.Context{ID: "ctx1", UserID: "someone-else"}, nil)
+
+		service := NewContextService(mockRepo, nil)
+		_, err := service.SetTemplate(context.Background(), "ctx1", "user123", "# {{date}}")
+
+		assert.ErrorIs(t, err, ErrContextNotFound)
+		mockRepo.AssertNotCalled(t, "UpdateContextTemplate", mock.Anything, mock.Anything)
+	})
+}
+
+func TestContextService_Reorder(t *testing.T) {
+	t.Run("Returns the contexts in their new order", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("ReorderContexts", "user123", []string{"ctx2", "ctx1"}).Return(true, nil)
+		mockRepo.On("GetContexts", "user123").Return([]models.Context{
+			{ID: "ctx2", UserID: "user123", Name: "personal", SortOrder: 0},
+			{ID: "ctx1", UserID: "user123", Name: "work", SortOrder: 1},
+		}, nil)
+
+		service := NewContextService(mockRepo, nil)
+		contexts, err := service.Reorder(context.Background(), "user123", []string{"ctx2", "ctx1"})
+
+		require.NoError(t, err)
+		require.Len(t, contexts, 2)
+		assert.Equal(t, "ctx2", contexts[0].ID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects unknown contexts", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("ReorderContexts", "user123", []string{"ctx1", "other"}).Return(false, nil)
+
+		service := NewContextService(mockRepo, nil)
+		_, err := service.Reorder(context.Background(), "user123", []string{"ctx1", "other"})
+
+		assert.ErrorIs(t, err, ErrContextNotFound)
+	})
+
+	t.Run("Rejects contexts listed twice", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+
+		service := NewContextService(mockRepo, nil)
+		_, err := service.Reorder(context.Background(), "user123", []string{"ctx1", "ctx2", "ctx1"})
+
+		assert.ErrorIs(t, err, ErrContextListedTwice)
+		mockRepo.AssertNotCalled(t, "ReorderContexts", mock.Anything, mock.Anything)
+	})
+}
+
+func TestContextService_Stats(t *testing.T) {
+	t.Run("Counts notes waiting for sync and flags failures", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("GetContextByID", "ctx1").Return(&models.Context{ID: "ctx1", UserID: "user123", Name: "work"}, nil)
+		mockRepo.On("GetContextStats", "user123", "work").Return(&models.ContextStats{
+			Context: "work", Notes: 10, Words: 420, FirstDate: "2025-01-06", LastDate: "2025-10-17",
+			Sync: models.ContextSync{Synced: 6, Failed: 1, Local: 1},
+		}, nil)
+
+		service := NewContextService(mockRepo, nil)
+		stats, err := service.Stats(context.Background(), "ctx1", "user123")
+
+		require.NoError(t, err)
+		assert.Equal(t, "ctx1", stats.ContextID)
+		assert.Equal(t, 2, stats.Sync.Pending)
+		assert.Equal(t, models.SyncHealthDegraded, stats.Sync.State)
+	})
+
+	t.Run("Contexts of other users are not found", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("GetContextByID", "ctx1").Return(&models.Context{ID: "ctx1", UserID: "other", Name: "work"}, nil)
+
+		service := NewContextService(mockRepo, nil)
+		_, err := service.Stats(context.Background(), "ctx1", "user123")
+
+		assert.ErrorIs(t, err, ErrContextNotFound)
+		mockRepo.AssertNotCalled(t, "GetContextStats", mock.Anything, mock.Anything)
+	})
+}
+
+func TestContextService_Language(t *testing.T) {
+	t.Run("Sets the language of own context", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("GetContextByID", "ctx1").Return(&models.Context{ID: "ctx1", UserID: "user123", Name: "Personal"}, nil)
+		mockRepo.On("UpdateContextLanguage", "ctx1", "es").Return(nil)
+
+		service := NewContextService(mockRepo, nil)
+		ctx, err := service.SetLanguage(context.Background(), "ctx1", "user123", "es")
+
+		require.NoError(t, err)
+		assert.Equal(t, "es", ctx.Language)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Looks up the language by context name", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("GetContextByName", "user123", "Personal").Return(&models.Context{Name: "Personal", Language: "es"}, nil)
+		mockRepo.On("GetContextByName", "user123", "Gone").Return(nil, nil)
+
+		service := NewContextService(mockRepo, nil)
+		language, err := service.Language(context.Background(), "user123", "Personal")
+		require.NoError(t, err)
+		assert.Equal(t, "es", language)
+
+		language, err = service.Language(context.Background(), "user123", "Gone")
+		require.NoError(t, err)
+		assert.Empty(t, language)
+	})
+}
+
+func TestContextService_QueuesFailedFolderChanges(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	provider := new(MockStorageService)
+	provider.On("RenameContext", "ctx1", "work", "projects").Return(errors.New("connection reset"))
+	provider.On("DeleteContext", "ctx2", "old").Return(nil)
+
+	mockRepo := new(MockContextRepository)
+	mockRepo.On("RecordSyncOperation", &models.SyncOperation{
+		UserID:      "user123",
+		Kind:        models.SyncOperationRename,
+		ContextID:   "ctx1",
+		ContextName: "work",
+		NewName:     "projects",
+		Error:       "connection reset",
+		CreatedAt:   now,
+	}).Return(nil).Once()
+
+	service := NewContextService(mockRepo, func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return provider, nil
+	})
+	service.SetClock(clock.NewFake(now))
+
+	// Called in the background by Update and Delete
+	service.renameDriveFolder("ctx1", "work", "projects", "user123", &oauth2.Token{})
+	service.deleteDriveFolder("ctx2", "old", "user123", &oauth2.Token{})
+
+	mockRepo.AssertExpectations(t)
+	provider.AssertExpectations(t)
+}
+
+func TestContextService_ListTrash_UsesClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC))
+	fake.Advance(48 * time.Hour)
+
+	mockRepo := new(MockContextRepository)
+	mockRepo.On("GetTrashedContexts", "user123", fake.Now().Add(-ContextTrashRetention)).Return([]models.TrashedContext{}, nil)
+
+	service := &ContextService{repo: mockRepo}
+	service.SetClock(fake)
+
+	_, err := service.ListTrash(context.Background(), "user123")
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestContextService_Create_Deterministic(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	mockRepo := new(MockContextRepository)
+	mockRepo.On("GetContextByName", "user123", "Work").Return(nil, nil)
+	mockRepo.On("CreateContext", mock.AnythingOfType("*models.Context")).Return(nil)
+
+	service := NewContextService(mockRepo, nil)
+	service.SetClock(clock.NewFake(now))
+	service.SetIDGenerator(idgen.NewSequence("ctx"))
+
+	ctx, err := service.Create(context.Background(), "user123", "Work", "info", "", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ctx-1", ctx.ID)
+	assert.Equal(t, now, ctx.CreatedAt)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestContextService_FolderJobs(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	noToken := func(userID string) (*oauth2.Token, error) { return nil, errors.New("not signed in") }
+	withToken := func(userID string) (*oauth2.Token, error) { return &oauth2.Token{AccessToken: "token"}, nil }
+
+	t.Run("Renames are queued as jobs", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("GetContextByID", "ctx1").Return(&models.Context{ID: "ctx1", UserID: "user123", Name: "work", Color: "primary"}, nil)
+		mockRepo.On("UpdateContext", "ctx1", "projects", "primary", "").Return(nil)
+		mockRepo.On("UpdateNotesContextName", "work", "projects", "user123").Return(nil)
+		queue := new(MockJobQueue)
+		queue.On("Enqueue", "user123", JobRenameFolder, folderChange{ContextID: "ctx1", Name: "work", NewName: "projects"}).Return(nil)
+
+		service := NewContextService(mockRepo, nil)
+		service.SetJobQueue(queue, withToken)
+
+		require.NoError(t, service.Update(context.Background(), "ctx1", "projects", "", nil, "user123", &oauth2.Token{}))
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("Jobs without a sign-in hand renames to sync retries", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("RecordSyncOperation", &models.SyncOperation{
+			UserID:      "user123",
+			Kind:        models.SyncOperationRename,
+			ContextID:   "ctx1",
+			ContextName: "work",
+			NewName:     "projects",
+			Error:       "failed to get authentication token: not signed in",
+			CreatedAt:   now,
+		}).Return(nil).Once()
+
+		service := NewContextService(mockRepo, nil)
+		service.SetClock(clock.NewFake(now))
+		service.SetJobQueue(new(MockJobQueue), noToken)
+
+		job := &models.Job{UserID: "user123", Kind: JobRenameFolder, Payload: `{"context_id":"ctx1","name":"work","new_name":"projects"}`}
+		require.NoError(t, service.RunFolderJob(context.Background(), job))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Icons are kept, changed or removed and saved to the config", func(t *testing.T) {
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("GetContextByID", "ctx1").Return(&models.Context{ID: "ctx1", UserID: "user123", Name: "work", Color: "primary", Icon: "briefcase"}, nil)
+		mockRepo.On("UpdateContext", "ctx1", "work", "primary", "briefcase").Return(nil).Once()
+		mockRepo.On("UpdateContext", "ctx1", "work", "primary", "🏠").Return(nil).Once()
+		mockRepo.On("UpdateContext", "ctx1", "work", "primary", "").Return(nil).Once()
+		queue := new(MockJobQueue)
+		queue.On("Enqueue", "user123", JobSaveConfig, folderChange{ContextID: "ctx1", Name: "work"}).Return(nil).Twice()
+
+		service := NewContextService(mockRepo, nil)
+		service.SetJobQueue(queue, withToken)
+
+		home, none := "🏠", ""
+		require.NoError(t, service.Update(context.Background(), "ctx1", "work", "primary", nil, "user123", &oauth2.Token{}))
+		require.NoError(t, service.Update(context.Background(), "ctx1", "work", "primary", &home, "user123", &oauth2.Token{}))
+		require.NoError(t, service.Update(context.Background(), "ctx1", "work", "primary", &none, "user123", &oauth2.Token{}))
+		mockRepo.AssertExpectations(t)
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("Config saves write the stored context", func(t *testing.T) {
+		stored := &models.Context{ID: "ctx1", UserID: "user123", Name: "work", Color: "info", Icon: "briefcase"}
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("GetContextByID", "ctx1").Return(stored, nil)
+		provider := &configProvider{MockStorageService: new(MockStorageService)}
+
+		service := NewContextService(mockRepo, func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+			return provider, nil
+		})
+		service.SetJobQueue(new(MockJobQueue), withToken)
+
+		job := &models.Job{UserID: "user123", Kind: JobSaveConfig, Payload: `{"context_id":"ctx1","name":"work"}`}
+		require.NoError(t, service.RunFolderJob(context.Background(), job))
+		assert.Equal(t, []models.Context{*stored}, provider.saved)
+	})
+
+	t.Run("Restores are retried by the queue", func(t *testing.T) {
+		restored := &models.Context{ID: "ctx1", UserID: "user123", Name: "work"}
+		mockRepo := new(MockContextRepository)
+		mockRepo.On("GetContextByID", "ctx1").Return(restored, nil)
+		provider := new(MockStorageService)
+		provider.On("RestoreContext", *restored).Return(errors.New("connection reset")).Once()
+
+		service := NewContextService(mockRepo, func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+			return provider, nil
+		})
+		job := &models.Job{UserID: "user123", Kind: JobRestoreFolder, Payload: `{"context_id":"ctx1","name":"work"}`}
+
+		service.SetJobQueue(new(MockJobQueue), noToken)
+		assert.ErrorContains(t, service.RunFolderJob(context.Background(), job), "not signed in")
+
+		service.SetJobQueue(new(MockJobQueue), withToken)
+		assert.ErrorContains(t, service.RunFolderJob(context.Background(), job), "connection reset")
+		provider.AssertExpectations(t)
+	})
+}
+
+// configProvider is a storage provider that keeps a config of contexts
+type configProvider struct {
+	*MockStorageService
+	saved []models.Context
+}
+
+func (p *configProvider) UpdateContextConfig(c models.Context) error {
+	p.saved = append(p.saved, c)
+	return nil
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/jobs"
+	"daily-notes/pkg/mail"
+	"daily-notes/pkg/maintenance"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/period"
+	"encoding/base64"
+	htmltemplate "html/template"
+	"log/slog"
+	"net/url"
+	"sort"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	// digestPollInterval is how often subscriptions are checked for a digest that is due
+	digestPollInterval = time.Hour
+	// digestHour is the local hour on Mondays from which the digest of the week before is sent
+	digestHour = 8
+	// digestExcerptLength caps the excerpt of each note in a digest, in runes
+	digestExcerptLength = 280
+)
+
+// digestJob is the payload of a JobDigest: the ISO week the digest covers
+type digestJob struct {
+	Week string `json:"week"`
+}
+
+// DigestService emails each subscribed user a weekly digest of their notes,
+// with an excerpt of and a link to each daily note of the week, by context.
+// Every Monday from 08:00 in the user's timezone, a job is queued sending the
+// digest of the week before; a digest missed while the server was down is sent
+// when it's back, the same week. Each email carries an unsubscribe link that
+// works without signing in.
+type DigestService struct {
+	repo      DigestRepository
+	mailer    Mailer
+	jobs      JobQueue
+	clock     clock.Clock
+	timeouts  Timeouts
+	publicURL string
+
+	maintenance *maintenance.Mode // No runs while the server is in maintenance; optional
+
+	run      sync.Mutex // One run at a time
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewDigestService creates a digest service; digests can't be sent until
+// SetMailer and SetJobQueue are called
+func NewDigestService(repo DigestRepository) *DigestService {
+	return &DigestService{repo: repo, clock: clock.Real(), timeouts: DefaultTimeouts}
+}
+
+// SetClock replaces the clock that decides when digests are due
+func (ds *DigestService) SetClock(c clock.Clock) {
+	ds.clock = c
+}
+
+// SetMailer sets how digests are sent and the address of the server their
+// links point to (PUBLIC_URL)
+func (ds *DigestService) SetMailer(mailer Mailer, publicURL string) {
+	ds.mailer = mailer
+	ds.publicURL = publicURL
+}
+
+// SetJobQueue sets the queue digests are sent through; RunDigestJob handles JobDigest
+func (ds *DigestService) SetJobQueue(queue JobQueue) {
+	ds.jobs = queue
+}
+
+// Enabled reports whether digests can be sent
+func (ds *DigestService) Enabled() bool {
+	return ds.mailer != nil && ds.publicURL != ""
+}
+
+// Get returns whether the server sends digests and whether the user gets them
+func (ds *DigestService) Get(ctx context.Context, userID string) (_ *models.DigestSettings, err error) {
+	defer wrapOp("get digest settings", &err)
+	ctx, cancel := ds.timeouts.query(ctx)
+	defer cancel()
+
+	subscription, err := ds.repo.GetDigestSubscription(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	settings := &models.DigestSettings{Enabled: ds.Enabled()}
+	if subscription != nil {
+		settings.Subscribed = true
+		settings.LastWeek = subscription.LastWeek
+	}
+	return settings, nil
+}
+
+// Subscribe sends the user the digest of each week from the current one, in
+// loc, the user's timezone. Subscribing again changes nothing.
+func (ds *DigestService) Subscribe(ctx context.Context, userID string, loc *time.Location) (_ *models.DigestSettings, err error) {
+	defer wrapOp("subscribe to digest", &err)
+	if !ds.Enabled() {
+		return nil, ErrDigestsDisabled
+	}
+
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	queryCtx, cancel := ds.timeouts.query(ctx)
+	defer cancel()
+	// The week before this one counts as sent, so the first digest is this week's
+	lastWeek := previousWeek(ds.clock.Now().In(loc))
+	if err := ds.repo.SubscribeDigest(queryCtx, userID, base64.RawURLEncoding.EncodeToString(token), lastWeek); err != nil {
+		return nil, err
+	}
+	return ds.Get(ctx, userID)
+}
+
+// Unsubscribe stops sending the user digests
+func (ds *DigestService) Unsubscribe(ctx context.Context, userID string) (err error) {
+	defer wrapOp("unsubscribe from digest", &err)
+	ctx, cancel := ds.timeouts.query(ctx)
+	defer cancel()
+
+	removed, err := ds.repo.UnsubscribeDigest(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrDigestNotFound
+	}
+	return nil
+}
+
+// UnsubscribeToken stops sending digests to the user whose unsubscribe link has
+// token; it fails with ErrDigestNotFound for unknown tokens, including those of
+// links already used
+func (ds *DigestService) UnsubscribeToken(ctx context.Context, token string) (err error) {
+	defer wrapOp("unsubscribe from digest", &err)
+	ctx, cancel := ds.timeouts.query(ctx)
+	defer cancel()
+
+	removed, err := ds.repo.UnsubscribeDigestByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrDigestNotFound
+	}
+	return nil
+}
+
+// SetMaintenance skips the runs due while the server is in maintenance; the
+// digests are queued on the first run after it
+func (ds *DigestService) SetMaintenance(mode *maintenance.Mode) {
+	ds.maintenance = mode
+}
+
+// Start queues due digests every digestPollInterval until Stop
+func (ds *DigestService) Start() {
+	ds.stopChan = make(chan struct{})
+	ds.done = make(chan struct{})
+
+	go func() {
+		defer close(ds.done)
+		ticker := time.NewTicker(digestPollInterval)
+		defer ticker.Stop()
+
+		for {
+			ds.RunDue(context.Background())
+			select {
+			case <-ticker.C:
+			case <-ds.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background loop, waiting for the run in progress
+func (ds *DigestService) Stop() {
+	if ds.stopChan == nil {
+		return
+	}
+	close(ds.stopChan)
+	<-ds.done
+	ds.stopChan = nil
+}
+
+// RunDue queues the digest of the week before for the subscriptions that didn't
+// get it yet, once it's past Monday 08:00 in the user's timezone, and returns
+// how many it queued. A digest that fails to queue is tried again on the next run.
+func (ds *DigestService) RunDue(ctx context.Context) int {
+	if ds.maintenance.Paused() {
+		return 0
+	}
+	ds.run.Lock()
+	defer ds.run.Unlock()
+
+	subscriptions, err := ds.repo.GetDigestSubscriptions(ctx)
+	if err != nil {
+		slog.Warn("failed to get digest subscriptions", "error", err)
+		return 0
+	}
+
+	now := ds.clock.Now()
+	queued := 0
+	for _, subscription := range subscriptions {
+		local := now.In(loadLocation(subscription.Timezone))
+		week := previousWeek(local)
+		if subscription.LastWeek == week || (local.Weekday() == time.Monday && local.Hour() < digestHour) {
+			continue
+		}
+
+		if err := ds.jobs.Enqueue(ctx, subscription.UserID, JobDigest, digestJob{Week: week}); err != nil {
+			slog.Warn("failed to queue digest", "user_id", subscription.UserID, "week", week, "error", err)
+			continue
+		}
+		queued++
+		if err := ds.repo.SetDigestWeek(ctx, subscription.UserID, week); err != nil {
+			slog.Warn("failed to record digest week", "user_id", subscription.UserID, "error", err)
+		}
+	}
+	return queued
+}
+
+// RunDigestJob emails the digest of a week queued by RunDue; weeks without
+// notes send nothing, and neither do users who unsubscribed since
+func (ds *DigestService) RunDigestJob(ctx context.Context, job *models.Job) (err error) {
+	defer wrapOp("run digest job", &err)
+	var payload digestJob
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+	if !ds.Enabled() {
+		return jobs.Permanent(ErrDigestsDisabled)
+	}
+	start, err := period.WeekStart(payload.Week)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+
+	queryCtx, cancel := ds.timeouts.query(ctx)
+	defer cancel()
+	subscription, err := ds.repo.GetDigestSubscription(queryCtx, job.UserID)
+	if err != nil {
+		return err
+	}
+	if subscription == nil {
+		return nil
+	}
+	from, to := start.Format(period.DateLayout), start.AddDate(0, 0, 6).Format(period.DateLayout)
+	notes, err := ds.repo.GetDigestNotes(queryCtx, job.UserID, from, to)
+	if err != nil {
+		return err
+	}
+	if len(notes) == 0 {
+		return nil
+	}
+
+	message, err := ds.message(subscription, start, notes)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	return ds.mailer.Send(ctx, *message)
+}
+
+// digestView is what the digest templates show
+type digestView struct {
+	Name           string
+	Week           string // "October 13 to 19"
+	Contexts       []digestContext
+	UnsubscribeURL string
+}
+
+// digestContext is the notes of one context in a digest, by date
+type digestContext struct {
+	Name  string
+	Notes []digestNote
+}
+
+// digestNote is a note in a digest
+type digestNote struct {
+	Title   string
+	Excerpt string
+	URL     string
+}
+
+// message builds the digest email of the week from start, with notes grouped by
+// context name
+func (ds *DigestService) message(subscription *models.DigestSubscription, start time.Time, notes []models.AgendaNote) (*mail.Message, error) {
+	view := digestView{
+		Name:           subscription.Name,
+		Week:           weekRange(start),
+		UnsubscribeURL: ds.publicURL + "/digest/unsubscribe/" + url.PathEscape(subscription.Token),
+	}
+	index := make(map[string]int)
+	for _, note := range notes {
+		i, ok := index[note.Context]
+		if !ok {
+			i = len(view.Contexts)
+			index[note.Context] = i
+			view.Contexts = append(view.Contexts, digestContext{Name: note.Context})
+		}
+		query := url.Values{"context": {note.Context}, "date": {note.Date}}
+		view.Contexts[i].Notes = append(view.Contexts[i].Notes, digestNote{
+			Title:   period.Title(note.Date),
+			Excerpt: markdown.Excerpt(note.Content, digestExcerptLength),
+			URL:     ds.publicURL + "/plain?" + query.Encode(),
+		})
+	}
+	sort.SliceStable(view.Contexts, func(i, j int) bool { return view.Contexts[i].Name < view.Contexts[j].Name })
+
+	var text, html bytes.Buffer
+	if err := digestText.Execute(&text, view); err != nil {
+		return nil, err
+	}
+	if err := digestHTML.Execute(&html, view); err != nil {
+		return nil, err
+	}
+	return &mail.Message{
+		To:      mail.Address(subscription.Name, subscription.Email),
+		Subject: "Your notes of " + view.Week,
+		Text:    text.String(),
+		HTML:    html.String(),
+		Headers: map[string]string{
+			// One-click unsubscribe (RFC 8058) posts to the same link
+			"List-Unsubscribe":      "<" + view.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}, nil
+}
+
+// previousWeek returns the ISO week before the one containing t
+func previousWeek(t time.Time) string {
+	return period.WeekKey(t.AddDate(0, 0, -7))
+}
+
+// weekRange describes the week from start: "October 13 to 19", or
+// "September 29 to October 5" across months
+func weekRange(start time.Time) string {
+	end := start.AddDate(0, 0, 6)
+	if end.Month() == start.Month() {
+		return start.Format("January 2") + " to " + end.Format("2")
+	}
+	return start.Format("January 2") + " to " + end.Format("January 2")
+}
+
+var digestText = texttemplate.Must(texttemplate.New("digest").Parse(`Hi{{if .Name}} {{.Name}}{{end}},
+
+These are your notes of {{.Week}}.
+{{range .Contexts}}
+== {{.Name}} ==
+{{range .Notes}}
+{{.Title}}
+{{.Excerpt}}
+{{.URL}}
+{{end}}{{end}}
+--
+You get this email because you subscribed to weekly digests. Unsubscribe:
+{{.UnsubscribeURL}}
+`))
+
+var digestHTML = htmltemplate.Must(htmltemplate.New("digest").Parse(`<!DOCTYPE html>
+<html lang="en">
+<body style="font-family: sans-serif; line-height: 1.5; max-width: 40rem;">
+<p>Hi{{if .Name}} {{.Name}}{{end}},</p>
+<p>These are your notes of {{.Week}}.</p>
+{{range .Contexts}}
+<h2>{{.Name}}</h2>
+{{range .Notes}}
+<p><a href="{{.URL}}">{{.Title}}</a><br>{{.Excerpt}}</p>
+{{end}}{{end}}
+<hr>
+<p><small>You get this email because you subscribed to weekly digests. <a href="{{.UnsubscribeURL}}">Unsubscribe</a></small></p>
+</body>
+</html>
+`))
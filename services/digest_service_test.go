@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockDigestRepository is a mock implementation of DigestRepository
+type MockDigestRepository struct {
+	mock.Mock
+}
+
+func (m *MockDigestRepository) GetDigestSubscription(ctx context.Context, userID string) (*models.DigestSubscription, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DigestSubscription), args.Error(1)
+}
+
+func (m *MockDigestRepository) GetDigestSubscriptions(ctx context.Context) ([]models.DigestSubscription, error) {
+	args := m.Called()
+	return args.Get(0).([]models.DigestSubscription), args.Error(1)
+}
+
+func (m *MockDigestRepository) SubscribeDigest(ctx context.Context, userID, token, lastWeek string) error {
+	args := m.Called(userID, token, lastWeek)
+	return args.Error(0)
+}
+
+func (m *MockDigestRepository) UnsubscribeDigest(ctx context.Context, userID string) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDigestRepository) UnsubscribeDigestByToken(ctx context.Context, token string) (bool, error) {
+	args := m.Called(token)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDigestRepository) SetDigestWeek(ctx context.Context, userID, week string) error {
+	args := m.Called(userID, week)
+	return args.Error(0)
+}
+
+func (m *MockDigestRepository) GetDigestNotes(ctx context.Context, userID, from, to string) ([]models.AgendaNote, error) {
+	args := m.Called(userID, from, to)
+	return args.Get(0).([]models.AgendaNote), args.Error(1)
+}
+
+// MockMailer is a mock implementation of Mailer
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg mail.Message) error {
+	args := m.Called(msg)
+	return args.Error(0)
+}
+
+func TestDigestService_Subscribe(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+
+	t.Run("Disabled without a mailer", func(t *testing.T) {
+		service := NewDigestService(new(MockDigestRepository))
+		_, err := service.Subscribe(context.Background(), "user123", time.UTC)
+		assert.ErrorIs(t, err, ErrDigestsDisabled)
+	})
+
+	t.Run("The first digest is of the current week", func(t *testing.T) {
+		repo := new(MockDigestRepository)
+		service := NewDigestService(repo)
+		service.SetMailer(new(MockMailer), "https://notes.example.com")
+		service.SetClock(clock.NewFake(now))
+
+		repo.On("SubscribeDigest", "user123", mock.MatchedBy(func(token string) bool { return len(token) == 32 }), "2025-W42").Return(nil)
+		repo.On("GetDigestSubscription", "user123").Return(&models.DigestSubscription{UserID: "user123", LastWeek: "2025-W42"}, nil)
+
+		settings, err := service.Subscribe(context.Background(), "user123", time.UTC)
+		require.NoError(t, err)
+		assert.Equal(t, &models.DigestSettings{Enabled: true, Subscribed: true, LastWeek: "2025-W42"}, settings)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestDigestService_Unsubscribe(t *testing.T) {
+	repo := new(MockDigestRepository)
+	service := NewDigestService(repo)
+	repo.On("UnsubscribeDigestByToken", "token-1").Return(true, nil).Once()
+	repo.On("UnsubscribeDigestByToken", "token-1").Return(false, nil)
+
+	require.NoError(t, service.UnsubscribeToken(context.Background(), "token-1"))
+	assert.ErrorIs(t, service.UnsubscribeToken(context.Background(), "token-1"), ErrDigestNotFound)
+}
+
+func TestDigestService_RunDue(t *testing.T) {
+	// 07:30 UTC on a Monday is 09:30 in Madrid
+	now := time.Date(2025, 10, 20, 7, 30, 0, 0, time.UTC)
+	repo := new(MockDigestRepository)
+	queue := new(MockJobQueue)
+	service := NewDigestService(repo)
+	service.SetMailer(new(MockMailer), "https://notes.example.com")
+	service.SetJobQueue(queue)
+	service.SetClock(clock.NewFake(now))
+
+	repo.On("GetDigestSubscriptions").Return([]models.DigestSubscription{
+		{UserID: "due", Timezone: "Europe/Madrid", LastWeek: "2025-W41"},
+		{UserID: "early", Timezone: "America/New_York", LastWeek: "2025-W41"},
+		{UserID: "sent", Timezone: "UTC", LastWeek: "2025-W42"},
+	}, nil)
+	queue.On("Enqueue", "due", JobDigest, digestJob{Week: "2025-W42"}).Return(nil)
+	repo.On("SetDigestWeek", "due", "2025-W42").Return(nil)
+
+	assert.Equal(t, 1, service.RunDue(context.Background()))
+	queue.AssertExpectations(t)
+	repo.AssertExpectations(t)
+	queue.AssertNotCalled(t, "Enqueue", "early", mock.Anything, mock.Anything)
+}
+
+func TestDigestService_RunDigestJob(t *testing.T) {
+	job := &models.Job{UserID: "user123", Kind: JobDigest, Payload: `{"week":"2025-W42"}`}
+	subscription := &models.DigestSubscription{UserID: "user123", Email: "ana@example.com", Name: "Ana", Token: "token-1"}
+
+	t.Run("Sends the week's notes by context", func(t *testing.T) {
+		repo := new(MockDigestRepository)
+		mailer := new(MockMailer)
+		service := NewDigestService(repo)
+		service.SetMailer(mailer, "https://notes.example.com")
+
+		repo.On("GetDigestSubscription", "user123").Return(subscription, nil)
+		repo.On("GetDigestNotes", "user123", "2025-10-13", "2025-10-19").Return([]models.AgendaNote{
+			{Context: "Work", Date: "2025-10-13", Content: "# Plans\n- Ship the digest"},
+			{Context: "Journal", Date: "2025-10-14", Content: "Rainy day"},
+		}, nil)
+		mailer.On("Send", mock.Anything).Return(nil)
+
+		require.NoError(t, service.RunDigestJob(context.Background(), job))
+		msg := mailer.Calls[0].Arguments.Get(0).(mail.Message)
+		assert.Equal(t, `"Ana" <ana@example.com>`, msg.To)
+		assert.Equal(t, "Your notes of October 13 to 19", msg.Subject)
+		assert.Equal(t, "<https://notes.example.com/digest/unsubscribe/token-1>", msg.Headers["List-Unsubscribe"])
+		assert.Equal(t, "List-Unsubscribe=One-Click", msg.Headers["List-Unsubscribe-Post"])
+		assert.Contains(t, msg.Text, "Plans Ship the digest")
+		assert.Contains(t, msg.HTML, `href="https://notes.example.com/plain?context=Work&amp;date=2025-10-13"`)
+		assert.Less(t, strings.Index(msg.Text, "== Journal =="), strings.Index(msg.Text, "== Work =="), "contexts are sorted by name")
+	})
+
+	t.Run("Weeks without notes send nothing", func(t *testing.T) {
+		repo := new(MockDigestRepository)
+		mailer := new(MockMailer)
+		service := NewDigestService(repo)
+		service.SetMailer(mailer, "https://notes.example.com")
+
+		repo.On("GetDigestSubscription", "user123").Return(subscription, nil)
+		repo.On("GetDigestNotes", "user123", "2025-10-13", "2025-10-19").Return([]models.AgendaNote{}, nil)
+
+		require.NoError(t, service.RunDigestJob(context.Background(), job))
+		mailer.AssertNotCalled(t, "Send", mock.Anything)
+	})
+
+	t.Run("Unsubscribed users get nothing", func(t *testing.T) {
+		repo := new(MockDigestRepository)
+		mailer := new(MockMailer)
+		service := NewDigestService(repo)
+		service.SetMailer(mailer, "https://notes.example.com")
+
+		repo.On("GetDigestSubscription", "user123").Return(nil, nil)
+
+		require.NoError(t, service.RunDigestJob(context.Background(), job))
+		mailer.AssertNotCalled(t, "Send", mock.Anything)
+	})
+}
+
+func TestWeekRange(t *testing.T) {
+	assert.Equal(t, "October 13 to 19", weekRange(time.Date(2025, 10, 13, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "September 29 to October 5", weekRange(time.Date(2025, 9, 29, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of drop box URLs
+const (
+	DefaultDropTTL     = 24 * time.Hour
+	MaxDropTTL         = 30 * 24 * time.Hour
+	DefaultDropMaxSize = 16 << 10
+)
+
+// DropService signs URLs that append one payload to a note without signing in,
+// for devices and scripts that can't hold a session or token. A URL is signed
+// with HMAC-SHA256 over its path, expiry and nonce, and the nonce is recorded
+// when it's used, so a replayed request is refused.
+type DropService struct {
+	repo     DropRepository
+	notes    *NoteService
+	clock    clock.Clock
+	timeouts Timeouts
+	secret   []byte
+	maxSize  int
+}
+
+// NewDropService creates a drop box service appending through notes; URLs
+// can't be signed until SetSecret is called
+func NewDropService(repo DropRepository, notes *NoteService) *DropService {
+	return &DropService{repo: repo, notes: notes, clock: clock.Real(), timeouts: DefaultTimeouts, maxSize: DefaultDropMaxSize}
+}
+
+// SetClock replaces the clock used for expiry times
+func (ds *DropService) SetClock(c clock.Clock) {
+	ds.clock = c
+}
+
+// SetSecret sets the key URLs are signed with; changing it invalidates every URL
+func (ds *DropService) SetSecret(secret []byte) {
+	ds.secret = secret
+}
+
+// SetMaxSize sets the largest payload accepted, in bytes
+func (ds *DropService) SetMaxSize(size int) {
+	ds.maxSize = size
+}
+
+// Enabled reports whether a secret is set
+func (ds *DropService) Enabled() bool {
+	return len(ds.secret) > 0
+}
+
+// Sign returns a URL, relative to the server, that appends one payload to the
+// note of a context and date until ttl has passed (DefaultDropTTL if zero)
+func (ds *DropService) Sign(ctx context.Context, userID, contextName, date string, ttl time.Duration) (_ *models.DropURL, err error) {
+	defer wrapOp("sign drop box URL", &err)
+	if !ds.Enabled() {
+		return nil, ErrDropsDisabled
+	}
+	if ttl <= 0 {
+		ttl = DefaultDropTTL
+	}
+	ttl = min(ttl, MaxDropTTL)
+
+	ctx, cancel := ds.timeouts.query(ctx)
+	defer cancel()
+	c, err := ds.repo.GetContextByName(ctx, database.ScopeUser(userID), contextName)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrContextNotFound
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	expiresAt := ds.clock.Now().Add(ttl).Truncate(time.Second)
+	query := models.DropQuery{Expires: expiresAt.Unix(), Nonce: base64.RawURLEncoding.EncodeToString(nonce)}
+	path := dropPath(userID, c.Name, date)
+	query.Signature = ds.sign(path, query)
+
+	values := url.Values{}
+	values.Set("expires", strconv.FormatInt(query.Expires, 10))
+	values.Set("nonce", query.Nonce)
+	values.Set("sig", query.Signature)
+	return &models.DropURL{
+		URL:       path + "?" + values.Encode(),
+		Context:   c.Name,
+		Date:      date,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Submit appends content to the end of the note a drop box URL was signed for,
+// once. It fails with ErrInvalidDropURL, ErrDropExpired or ErrDropUsed for URLs
+// that can't be used, and ErrDropTooLarge or ErrEmptyDrop for bad payloads.
+func (ds *DropService) Submit(ctx context.Context, userID, contextName, date string, query models.DropQuery, content string) (_ *models.Note, err error) {
+	defer wrapOp("submit to drop box", &err)
+	if !ds.Enabled() {
+		return nil, ErrDropsDisabled
+	}
+	expected := ds.sign(dropPath(userID, contextName, date), query)
+	if !hmac.Equal([]byte(query.Signature), []byte(expected)) {
+		return nil, ErrInvalidDropURL
+	}
+	expiresAt := time.Unix(query.Expires, 0).UTC()
+	now := ds.clock.Now()
+	if !now.Before(expiresAt) {
+		return nil, ErrDropExpired
+	}
+	if len(content) > ds.maxSize {
+		return nil, ErrDropTooLarge
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyDrop
+	}
+
+	if err := ds.useNonce(ctx, userID, query.Nonce, expiresAt, now); err != nil {
+		return nil, err
+	}
+
+	note, err := ds.notes.Append(ctx, userID, contextName, date, content, "", nil)
+	if err != nil {
+		// The payload wasn't saved, so the URL may be used to try again
+		releaseCtx, cancel := ds.timeouts.query(ctx)
+		defer cancel()
+		if releaseErr := ds.repo.ReleaseDropNonce(releaseCtx, query.Nonce); releaseErr != nil {
+			return nil, releaseErr
+		}
+		return nil, err
+	}
+	return note, nil
+}
+
+// useNonce records the use of a nonce, failing with ErrDropUsed if it was used before
+func (ds *DropService) useNonce(ctx context.Context, userID, nonce string, expiresAt, now time.Time) error {
+	ctx, cancel := ds.timeouts.query(ctx)
+	defer cancel()
+
+	fresh, err := ds.repo.UseDropNonce(ctx, userID, nonce, expiresAt, now)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrDropUsed
+	}
+	return nil
+}
+
+// sign returns the signature of a drop box URL
+func (ds *DropService) sign(path string, query models.DropQuery) string {
+	mac := hmac.New(sha256.New, ds.secret)
+	mac.Write([]byte(path + "\n" + strconv.FormatInt(query.Expires, 10) + "\n" + query.Nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// dropPath is the path of the drop box URL of a note
+func dropPath(userID, contextName, date string) string {
+	return "/api/drop/" + url.PathEscape(userID) + "/" + url.PathEscape(contextName) + "/" + url.PathEscape(date)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockDropRepository is a mock implementation of DropRepository
+type MockDropRepository struct {
+	mock.Mock
+}
+
+func (m *MockDropRepository) GetContextByName(ctx context.Context, scope database.UserScope, name string) (*models.Context, error) {
+	args := m.Called(scope.UserID(), name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Context), args.Error(1)
+}
+
+func (m *MockDropRepository) UseDropNonce(ctx context.Context, userID, nonce string, expiresAt, now time.Time) (bool, error) {
+	args := m.Called(userID, nonce, expiresAt, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDropRepository) ReleaseDropNonce(ctx context.Context, nonce string) error {
+	args := m.Called(nonce)
+	return args.Error(0)
+}
+
+// dropQuery reads the signature part of a signed drop box URL
+func dropQuery(t *testing.T, signed string) (string, models.DropQuery) {
+	t.Helper()
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	require.NoError(t, err)
+	return u.Path, models.DropQuery{Expires: expires, Nonce: u.Query().Get("nonce"), Signature: u.Query().Get("sig")}
+}
+
+func TestDropService(t *testing.T) {
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	repo := new(MockDropRepository)
+	noteRepo := new(MockRepository)
+	service := NewDropService(repo, &NoteService{repo: noteRepo, clock: fake})
+	service.SetClock(fake)
+	service.SetMaxSize(32)
+
+	_, err := service.Sign(context.Background(), "user123", "Sensors", "2025-10-16", 0)
+	assert.ErrorIs(t, err, ErrDropsDisabled)
+	service.SetSecret([]byte("drop-secret"))
+
+	repo.On("GetContextByName", "user123", "Sensors").Return(&models.Context{Name: "Sensors"}, nil)
+	drop, err := service.Sign(context.Background(), "user123", "Sensors", "2025-10-16", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), drop.ExpiresAt)
+	path, query := dropQuery(t, drop.URL)
+	assert.Equal(t, "/api/drop/user123/Sensors/2025-10-16", path)
+
+	t.Run("Signatures cover the note and the expiry", func(t *testing.T) {
+		_, err := service.Submit(context.Background(), "user123", "Journal", "2025-10-16", query, "21.5C")
+		assert.ErrorIs(t, err, ErrInvalidDropURL)
+
+		later := query
+		later.Expires += 3600
+		_, err = service.Submit(context.Background(), "user123", "Sensors", "2025-10-16", later, "21.5C")
+		assert.ErrorIs(t, err, ErrInvalidDropURL)
+	})
+
+	t.Run("Payloads are capped", func(t *testing.T) {
+		_, err := service.Submit(context.Background(), "user123", "Sensors", "2025-10-16", query, strings.Repeat("x", 33))
+		assert.ErrorIs(t, err, ErrDropTooLarge)
+		_, err = service.Submit(context.Background(), "user123", "Sensors", "2025-10-16", query, " \n")
+		assert.ErrorIs(t, err, ErrEmptyDrop)
+	})
+
+	t.Run("A URL appends once", func(t *testing.T) {
+		repo.On("UseDropNonce", "user123", query.Nonce, drop.ExpiresAt, now).Return(true, nil).Once()
+		repo.On("UseDropNonce", "user123", query.Nonce, drop.ExpiresAt, now).Return(false, nil)
+		noteRepo.On("GetNote", "user123", "Sensors", "2025-10-16").Return(&models.Note{Content: "# Readings", Revision: 1}, nil)
+		noteRepo.On("UpsertNoteAtRevision", mock.MatchedBy(func(n *models.Note) bool {
+			return n.Content == "# Readings\n21.5C\n"
+		}), 1, true).Return(true, nil)
+
+		note, err := service.Submit(context.Background(), "user123", "Sensors", "2025-10-16", query, "21.5C\n")
+		require.NoError(t, err)
+		assert.Equal(t, "# Readings\n21.5C\n", note.Content)
+
+		_, err = service.Submit(context.Background(), "user123", "Sensors", "2025-10-16", query, "21.5C\n")
+		assert.ErrorIs(t, err, ErrDropUsed)
+	})
+
+	t.Run("Expired URLs are refused", func(t *testing.T) {
+		fake.Advance(time.Hour)
+		_, err := service.Submit(context.Background(), "user123", "Sensors", "2025-10-16", query, "21.5C")
+		assert.ErrorIs(t, err, ErrDropExpired)
+	})
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/e2ee"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// EncryptionService turns end-to-end encryption of a user's notes on and off.
+// Clients derive a key from the user's passphrase with the parameters kept
+// here, encrypt notes into e2ee envelopes and save those as content. The
+// server never sees the passphrase or the key: it keeps a hash of the key's
+// verifier so clients can check a passphrase before encrypting with it, and
+// NoteService refuses plaintext notes of users who turned encryption on.
+type EncryptionService struct {
+	repo     EncryptionRepository
+	clock    clock.Clock
+	timeouts Timeouts
+}
+
+// NewEncryptionService creates a new encryption service
+func NewEncryptionService(repo EncryptionRepository) *EncryptionService {
+	return &EncryptionService{repo: repo, clock: clock.Real(), timeouts: DefaultTimeouts}
+}
+
+// SetClock replaces the clock that dates keys
+func (es *EncryptionService) SetClock(c clock.Clock) {
+	es.clock = c
+}
+
+// hashVerifier is what the database keeps of a key verifier, so a copy of the
+// database doesn't let anyone pass Verify
+func hashVerifier(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return hex.EncodeToString(sum[:])
+}
+
+// Enabled reports whether a user's notes are end-to-end encrypted, the
+// encryption setting
+func (es *EncryptionService) Enabled(ctx context.Context, userID string) (_ bool, err error) {
+	defer wrapOp("get encryption setting", &err)
+	ctx, cancel := es.timeouts.query(ctx)
+	defer cancel()
+
+	key, err := es.repo.GetEncryptionKey(ctx, userID)
+	return key != nil, err
+}
+
+// Status returns the key-derivation parameters of a user, which clients need
+// to derive the key on a new device, and how many notes are still plaintext
+func (es *EncryptionService) Status(ctx context.Context, userID string) (_ *models.EncryptionStatus, err error) {
+	defer wrapOp("get encryption status", &err)
+	ctx, cancel := es.timeouts.query(ctx)
+	defer cancel()
+
+	key, err := es.repo.GetEncryptionKey(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, plaintext, err := es.repo.CountEncryptedNotes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.EncryptionStatus{
+		Enabled:        key != nil,
+		Key:            key,
+		EncryptedNotes: encrypted,
+		PlaintextNotes: plaintext,
+	}, nil
+}
+
+// Enable turns on end-to-end encryption with the parameters the client
+// derived its key with. From then on notes must be saved encrypted with that
+// key; notes saved before stay as they are until clients encrypt them.
+func (es *EncryptionService) Enable(ctx context.Context, userID string, req models.EnableEncryptionRequest) (_ *models.EncryptionKey, err error) {
+	defer wrapOp("enable encryption", &err)
+	ctx, cancel := es.timeouts.query(ctx)
+	defer cancel()
+
+	salt, err := base64.StdEncoding.DecodeString(req.Salt)
+	if err != nil || len(salt) < e2ee.MinSaltSize {
+		return nil, ErrInvalidEncryptionSalt
+	}
+
+	existing, err := es.repo.GetEncryptionKey(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrEncryptionEnabled
+	}
+
+	key := &models.EncryptionKey{
+		UserID:       userID,
+		Cipher:       req.Cipher,
+		KDF:          req.KDF,
+		Iterations:   req.Iterations,
+		Salt:         base64.StdEncoding.EncodeToString(salt),
+		VerifierHash: hashVerifier(req.Verifier),
+		CreatedAt:    es.clock.Now(),
+	}
+	if err := es.repo.CreateEncryptionKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Verify reports whether a verifier was derived from the user's key, i.e.
+// whether the passphrase the client was given is the right one
+func (es *EncryptionService) Verify(ctx context.Context, userID, verifier string) (_ bool, err error) {
+	defer wrapOp("verify encryption key", &err)
+	ctx, cancel := es.timeouts.query(ctx)
+	defer cancel()
+
+	key, err := es.repo.GetEncryptionKey(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if key == nil {
+		return false, ErrEncryptionNotEnabled
+	}
+	return subtle.ConstantTimeCompare([]byte(hashVerifier(verifier)), []byte(key.VerifierHash)) == 1, nil
+}
+
+// Disable turns end-to-end encryption off once clients decrypted every note;
+// encrypted notes left couldn't be saved again
+func (es *EncryptionService) Disable(ctx context.Context, userID string) (err error) {
+	defer wrapOp("disable encryption", &err)
+	ctx, cancel := es.timeouts.query(ctx)
+	defer cancel()
+
+	encrypted, _, err := es.repo.CountEncryptedNotes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if encrypted > 0 {
+		return ErrEncryptedNotesRemain
+	}
+	removed, err := es.repo.DeleteEncryptionKey(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrEncryptionNotEnabled
+	}
+	return nil
+}
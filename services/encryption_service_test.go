@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/e2ee"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockEncryptionRepository is a mock implementation of EncryptionRepository
+type MockEncryptionRepository struct {
+	mock.Mock
+}
+
+func (m *MockEncryptionRepository) GetEncryptionKey(ctx context.Context, userID string) (*models.EncryptionKey, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.EncryptionKey), args.Error(1)
+}
+
+func (m *MockEncryptionRepository) CreateEncryptionKey(ctx context.Context, key *models.EncryptionKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockEncryptionRepository) DeleteEncryptionKey(ctx context.Context, userID string) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockEncryptionRepository) CountEncryptedNotes(ctx context.Context, userID string) (int, int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Int(1), args.Error(2)
+}
+
+// testSalt is the salt of the test key, base64 as the key keeps it
+var testSalt = bytes.Repeat([]byte{7}, e2ee.MinSaltSize)
+
+func TestEncryptionService(t *testing.T) {
+	salt := base64.StdEncoding.EncodeToString(testSalt)
+	enable := models.EnableEncryptionRequest{Cipher: e2ee.CipherAESGCM, KDF: e2ee.KDFPBKDF2, Iterations: e2ee.MinIterations, Salt: salt, Verifier: "dmVyaWZpZXI="}
+
+	t.Run("Short salts are refused", func(t *testing.T) {
+		req := enable
+		req.Salt = base64.StdEncoding.EncodeToString([]byte("short"))
+		_, err := NewEncryptionService(new(MockEncryptionRepository)).Enable(context.Background(), "user", req)
+		assert.ErrorIs(t, err, ErrInvalidEncryptionSalt)
+	})
+
+	t.Run("Keys aren't replaced", func(t *testing.T) {
+		repo := new(MockEncryptionRepository)
+		repo.On("GetEncryptionKey", "user").Return(&models.EncryptionKey{UserID: "user"}, nil)
+		_, err := NewEncryptionService(repo).Enable(context.Background(), "user", enable)
+		assert.ErrorIs(t, err, ErrEncryptionEnabled)
+		repo.AssertNotCalled(t, "CreateEncryptionKey", mock.Anything)
+	})
+
+	t.Run("Only a hash of the verifier is kept", func(t *testing.T) {
+		repo := new(MockEncryptionRepository)
+		var saved *models.EncryptionKey
+		repo.On("GetEncryptionKey", "user").Return(nil, nil).Once()
+		repo.On("CreateEncryptionKey", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*models.EncryptionKey)
+		}).Return(nil)
+		service := NewEncryptionService(repo)
+
+		_, err := service.Enable(context.Background(), "user", enable)
+		require.NoError(t, err)
+		assert.NotContains(t, saved.VerifierHash, "dmVyaWZpZXI")
+		assert.Equal(t, salt, saved.Salt)
+
+		repo.On("GetEncryptionKey", "user").Return(saved, nil)
+		valid, err := service.Verify(context.Background(), "user", "dmVyaWZpZXI=")
+		require.NoError(t, err)
+		assert.True(t, valid)
+		valid, err = service.Verify(context.Background(), "user", "b3RoZXI=")
+		require.NoError(t, err)
+		assert.False(t, valid)
+	})
+
+	t.Run("Encryption stays on while notes are encrypted", func(t *testing.T) {
+		repo := new(MockEncryptionRepository)
+		repo.On("CountEncryptedNotes", "user").Return(2, 0, nil)
+		assert.ErrorIs(t, NewEncryptionService(repo).Disable(context.Background(), "user"), ErrEncryptedNotesRemain)
+		repo.AssertNotCalled(t, "DeleteEncryptionKey", mock.Anything)
+	})
+}
+
+func TestNoteService_Encryption(t *testing.T) {
+	key := &models.EncryptionKey{UserID: "user", Iterations: e2ee.MinIterations, Salt: base64.StdEncoding.EncodeToString(testSalt)}
+	seal := func(salt []byte) string {
+		k, err := e2ee.DeriveKey("passphrase", salt, e2ee.MinIterations)
+		require.NoError(t, err)
+		envelope, err := e2ee.Seal(k, salt, e2ee.MinIterations, "Dear diary")
+		require.NoError(t, err)
+		return envelope.String()
+	}
+	newService := func(key *models.EncryptionKey) (*NoteService, *MockRepository) {
+		repo := new(MockRepository)
+		keys := new(MockEncryptionRepository)
+		keys.On("GetEncryptionKey", "user").Return(key, nil)
+		service := NewNoteService(repo, nil)
+		service.SetEncryption(keys)
+		return service, repo
+	}
+
+	t.Run("Plaintext is refused while encryption is on", func(t *testing.T) {
+		service, repo := newService(key)
+		_, err := service.Upsert(context.Background(), "user", "Journal", "2025-10-16", "Dear diary")
+		assert.ErrorIs(t, err, ErrPlaintextNote)
+		repo.AssertNotCalled(t, "UpsertNote", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Envelopes of the user's key are saved", func(t *testing.T) {
+		service, repo := newService(key)
+		content := seal(testSalt)
+		repo.On("UpsertNote", mock.MatchedBy(func(n *models.Note) bool { return n.Content == content }), true).Return(nil)
+		_, err := service.Upsert(context.Background(), "user", "Journal", "2025-10-16", content)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Envelopes of other keys, malformed ones and those of users without a key are refused", func(t *testing.T) {
+		service, _ := newService(key)
+		_, err := service.Upsert(context.Background(), "user", "Journal", "2025-10-16", seal(bytes.Repeat([]byte{8}, e2ee.MinSaltSize)))
+		assert.ErrorIs(t, err, ErrEncryptionKeyMismatch)
+		_, err = service.Upsert(context.Background(), "user", "Journal", "2025-10-16", e2ee.Begin+"\nDear diary\n"+e2ee.End)
+		assert.ErrorIs(t, err, ErrInvalidEncryptedNote)
+
+		service, _ = newService(nil)
+		_, err = service.Upsert(context.Background(), "user", "Journal", "2025-10-16", seal(testSalt))
+		assert.ErrorIs(t, err, ErrEncryptionNotEnabled)
+	})
+
+	t.Run("Encrypted notes can't be edited in place", func(t *testing.T) {
+		service, repo := newService(key)
+		repo.On("GetNote", "user", "Journal", "2025-10-16").Return(&models.Note{Content: seal(testSalt), Encrypted: true, Revision: 1}, nil)
+		_, err := service.Append(context.Background(), "user", "Journal", "2025-10-16", "More", "", nil)
+		assert.ErrorIs(t, err, ErrNoteEncrypted)
+	})
+}
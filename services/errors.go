@@ -2,6 +2,10 @@ package services
 
 import "errors"
 
+// Service methods return these sentinels wrapped in an *OpError naming the
+// failed operation, as they do repository and storage errors. Match them with
+// errors.Is, never ==.
+
 // Common service-level errors
 var (
 	// Auth errors
@@ -16,7 +20,154 @@ var (
 	// Context errors
 	ErrContextNotFound      = errors.New("context not found")
 	ErrContextAlreadyExists = errors.New("context already exists")
+	ErrContextNotInTrash    = errors.New("context not found in trash")
+	ErrNoContextSuggestion  = errors.New("no context could be suggested")
+	ErrContextListedTwice   = errors.New("a context is listed twice")
+
+	// Profile errors
+	ErrUnsupportedProfile = errors.New("profile was exported by a newer version")
+
+	// Import errors
+	ErrInvalidArchive          = errors.New("upload is not a valid zip archive")
+	ErrTooManyImportFiles      = errors.New("archive contains more than 5000 notes")
+	ErrUnsupportedImportFormat = errors.New("import format must be folders, obsidian or json")
+
+	// Public page errors
+	ErrHandleTaken        = errors.New("handle is already taken")
+	ErrContextNotPublic   = errors.New("context is not published")
+	ErrPublicPageNotFound = errors.New("page not found")
+	ErrShareLocalOnly     = errors.New("local-only notes can't be shared")
+	ErrShareEncrypted     = errors.New("encrypted notes can't be shared")
+	ErrShareNotFound      = errors.New("share link not found")
+
+	// Context member errors
+	ErrMemberNotFound     = errors.New("member not found")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInviteSelf         = errors.New("you can't invite yourself")
+	ErrContextReadOnly    = errors.New("context is shared with you read-only")
+	ErrSharedContextMove  = errors.New("notes can't move between contexts of different owners")
+
+	ErrInvitationEmailDisabled = errors.New("invitation emails are not enabled on this server")
+
+	// End-to-end encryption errors
+	ErrEncryptionEnabled     = errors.New("notes are already encrypted; turn encryption off first")
+	ErrEncryptionNotEnabled  = errors.New("notes are not encrypted")
+	ErrEncryptedNotesRemain  = errors.New("decrypt the encrypted notes before turning encryption off")
+	ErrInvalidEncryptionSalt = errors.New("salt must be base64 of at least 16 bytes")
+	ErrPlaintextNote         = errors.New("notes must be saved encrypted while encryption is on")
+	ErrInvalidEncryptedNote  = errors.New("encrypted note is malformed")
+	ErrEncryptionKeyMismatch = errors.New("note is encrypted with another key")
+	ErrNoteEncrypted         = errors.New("encrypted notes can only be replaced as a whole")
+
+	// Publishing errors
+	ErrInvalidPublishTarget  = errors.New("invalid publish target")
+	ErrPublishTargetNotFound = errors.New("publish target not found")
+	ErrPublishingUnavailable = errors.New("publishing is not available on this server")
+
+	// Timezone errors
+	ErrTimezoneChangeNotFound = errors.New("timezone change not found")
+
+	// Storage provider errors
+	ErrStorageUnavailable  = errors.New("storage provider is not available on this server")
+	ErrStorageNotConnected = errors.New("storage provider is not connected")
+	ErrStorageIsSignIn     = errors.New("the sign-in storage provider cannot be disconnected")
 
 	// Note errors
-	ErrNoteNotFound = errors.New("note not found")
+	ErrNoteNotFound     = errors.New("note not found")
+	ErrRevisionConflict = errors.New("note was updated elsewhere")
+	ErrInvalidPeriodKey = errors.New("key does not match note type")
+	ErrInvalidMonth     = errors.New("invalid year or month")
+	ErrInvalidInclude   = errors.New("include must be preview or content")
+	ErrNoteExists       = errors.New("note already exists in the target context")
+	ErrSameContext      = errors.New("source and target context are the same")
+	ErrTransferRange    = errors.New("give a date or a date range of at most 366 days")
+	ErrNoteAtNewDate    = errors.New("note already exists at the new date")
+	ErrAgendaRange      = errors.New("agenda range must be at most 62 days")
+	ErrMetaRange        = errors.New("range must be at most 366 days")
+	ErrActivityRange    = errors.New("activity range must be at most 366 days")
+	ErrDuplicateNote    = errors.New("the same note is listed twice")
+	ErrInvalidLineRange = errors.New("line range is outside the note")
+	ErrSameNote         = errors.New("source and target note are the same")
+	ErrSectionNotFound  = errors.New("section not found")
+	ErrEmptySearch      = errors.New("search query is empty")
+	ErrEmptyTag         = errors.New("tag is empty")
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrConflictNotFound = errors.New("note has no sync conflict")
+	ErrNoteNotInTrash   = errors.New("note not found in trash")
+	ErrNoteDayTaken     = errors.New("a note was written on that day since; delete it first")
+
+	// Task errors
+	ErrInvalidTaskStatus = errors.New("status must be open, done or all")
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrTaskChanged       = errors.New("task changed in its note; reload the tasks")
+
+	// Attachment errors
+	ErrEmptyAttachment    = errors.New("attachment is empty")
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+
+	// API token errors
+	ErrInvalidAPIToken  = errors.New("invalid API token")
+	ErrAPITokenNotFound = errors.New("API token not found")
+	ErrTokenScope       = errors.New("API token doesn't allow this")
+
+	// API key errors
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrKeyScope       = errors.New("API key doesn't allow this")
+
+	// Note schedule errors
+	ErrScheduleNotFound = errors.New("no note schedule is set")
+
+	// Drop box errors
+	ErrDropsDisabled  = errors.New("drop box URLs are not enabled on this server")
+	ErrInvalidDropURL = errors.New("drop box URL signature is invalid")
+	ErrDropExpired    = errors.New("drop box URL has expired")
+	ErrDropUsed       = errors.New("drop box URL was already used")
+	ErrDropTooLarge   = errors.New("payload is larger than drop box URLs accept")
+	ErrEmptyDrop      = errors.New("payload is empty")
+
+	// Weekly digest errors
+	ErrDigestsDisabled = errors.New("weekly digests are not enabled on this server")
+	ErrDigestNotFound  = errors.New("not subscribed to the weekly digest")
+
+	// Reminder errors
+	ErrRemindersDisabled     = errors.New("reminders are not enabled on this server")
+	ErrInvalidReminderStatus = errors.New("status must be pending, sent, missed or all")
+	ErrInvalidReminderTime   = errors.New("remind_at must be a local time like 2025-11-02 09:00")
+	ErrReminderNotFound      = errors.New("reminder not found")
+	ErrReminderInNote        = errors.New("reminder is written in a note; remove it from the note instead")
+
+	// Web push errors
+	ErrPushDisabled             = errors.New("push notifications are not enabled on this server")
+	ErrInvalidPushSubscription  = errors.New("invalid push subscription")
+	ErrPushSubscriptionNotFound = errors.New("push subscription not found")
+
+	// Tag errors
+	ErrInvalidTag     = errors.New("tags may only contain letters, digits, _, - and /")
+	ErrSameTag        = errors.New("tag is the same as the new one")
+	ErrTagNotFound    = errors.New("tag is not used by any note")
+	ErrTagExists      = errors.New("tag is already used; merge into it instead")
+	ErrTagJobNotFound = errors.New("tag job not found")
 )
+
+// OpError records the service operation that failed and the error behind it
+type OpError struct {
+	Op  string
+	Err error
+}
+
+func (e *OpError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// wrapOp wraps a non-nil *err in an OpError for op; call it deferred with a named result
+func wrapOp(op string, err *error) {
+	if *err != nil {
+		*err = &OpError{Op: op, Err: *err}
+	}
+}
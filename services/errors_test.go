@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpError(t *testing.T) {
+	t.Run("Wrapped errors keep their identity", func(t *testing.T) {
+		repo := new(MockContextRepository)
+		repo.On("GetContexts", "user123").Return(nil, ErrContextNotFound)
+		service := NewContextService(repo, nil)
+
+		_, err := service.List(context.Background(), "user123")
+		assert.ErrorIs(t, err, ErrContextNotFound)
+		assert.EqualError(t, err, "list contexts: context not found")
+
+		var opErr *OpError
+		require.ErrorAs(t, err, &opErr)
+		assert.Equal(t, "list contexts", opErr.Op)
+	})
+
+	t.Run("Nil errors stay nil", func(t *testing.T) {
+		var err error
+		wrapOp("list contexts", &err)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Errors are wrapped once per operation", func(t *testing.T) {
+		err := errors.New("database error")
+		wrapped := err
+		wrapOp("get storage provider", &wrapped)
+		wrapOp("get storage status", &wrapped)
+		assert.EqualError(t, wrapped, "get storage status: get storage provider: database error")
+		assert.ErrorIs(t, wrapped, err)
+	})
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"archive/zip"
+	"daily-notes/models"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Obsidian vaults are imported like this:
+//   - The vault is the folder holding .obsidian/, or the archive root without one
+//   - The first folder below the vault is the context (Journal/2025/10/2025-10-16.md goes
+//     to Journal); notes directly in the vault go to ObsidianRootContext
+//   - The date comes from the file name in the common daily-note formats, else from the
+//     date or created property of the frontmatter
+//   - The frontmatter is removed from the content; its tags are kept as #hashtags and
+//     its created property becomes the note's creation time
+
+// ObsidianRootContext is the context of notes stored directly in the vault folder,
+// where Obsidian keeps daily notes by default
+const ObsidianRootContext = "Daily"
+
+// obsidianDateLayouts are daily-note file names tried after importDateLayouts
+var obsidianDateLayouts = []string{
+	"2006.01.02",
+	"2006_01_02",
+	"20060102",
+	"January 2, 2006",
+	"Monday, January 2, 2006",
+	"Jan 2, 2006",
+}
+
+// obsidianDatePrefix matches file names starting with a date followed by a weekday or
+// title, e.g. "2025-10-16 Thursday" or "2025-10-16 - Standup"
+var obsidianDatePrefix = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})[\s_-]`)
+
+// obsidianTimeLayouts are the values accepted in date and created properties
+var obsidianTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// obsidianFrontmatter holds the properties of a note the import understands
+type obsidianFrontmatter struct {
+	Date    string      `yaml:"date"`
+	Created string      `yaml:"created"`
+	Tags    interface{} `yaml:"tags"` // A list or a comma or space separated string
+}
+
+// vaultRoot returns the folder of the Obsidian vault in an archive, with a trailing
+// slash: the shallowest folder holding .obsidian/, or "" for the archive root
+func vaultRoot(files []*zip.File) string {
+	root, found := "", false
+	for _, f := range files {
+		parts := strings.Split(f.Name, "/")
+		for i, part := range parts {
+			if part != ".obsidian" {
+				continue
+			}
+			dir := strings.Join(parts[:i], "/")
+			if dir != "" {
+				dir += "/"
+			}
+			if !found || len(dir) < len(root) {
+				root, found = dir, true
+			}
+			break
+		}
+	}
+	return root
+}
+
+// readObsidianNote reads a markdown file of an Obsidian vault
+func (run *importRun) readObsidianNote(f *zip.File, file *models.ImportFileResult) (*importedNote, error) {
+	name := path.Clean(f.Name)
+	ext := path.Ext(name)
+	if !isMarkdownExt(ext) {
+		return nil, importFailure("not a markdown file")
+	}
+	if !strings.HasPrefix(name, run.vault) {
+		return nil, importFailure("file is outside the vault")
+	}
+
+	rel := strings.TrimPrefix(name, run.vault)
+	file.Context = ObsidianRootContext
+	if i := strings.Index(rel, "/"); i >= 0 {
+		file.Context = strings.TrimSpace(rel[:i])
+	}
+
+	content, err := readImportFile(f)
+	if err != nil {
+		return nil, err
+	}
+	props, body := splitFrontmatter(content)
+
+	date, ok := parseObsidianDate(strings.TrimSuffix(path.Base(rel), ext))
+	if !ok {
+		date, ok = propertyDate(props.Date, props.Created)
+	}
+	if !ok {
+		return nil, importFailure("not a daily note: no date in the file name or frontmatter")
+	}
+	file.Date = date
+
+	note := &importedNote{content: withTags(body, frontmatterTags(props.Tags))}
+	if created, ok := parsePropertyTime(props.Created); ok {
+		note.createdAt = created
+	}
+	return note, nil
+}
+
+// parseObsidianDate reads a note date from a daily-note file name
+func parseObsidianDate(name string) (string, bool) {
+	if date, ok := parseImportDate(name); ok {
+		return date, true
+	}
+	for _, layout := range obsidianDateLayouts {
+		if t, err := time.Parse(layout, name); err == nil {
+			return t.Format("2006-01-02"), true
+		}
+	}
+	if m := obsidianDatePrefix.FindStringSubmatch(name); m != nil {
+		return parseImportDate(m[1])
+	}
+	return "", false
+}
+
+// propertyDate returns the day of the first frontmatter value that is a date
+func propertyDate(values ...string) (string, bool) {
+	for _, v := range values {
+		if t, ok := parsePropertyTime(v); ok {
+			return t.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
+
+// parsePropertyTime reads a date or date and time frontmatter value
+func parsePropertyTime(v string) (time.Time, bool) {
+	v = strings.TrimSpace(v)
+	for _, layout := range obsidianTimeLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// splitFrontmatter separates a leading YAML frontmatter block from the note body
+// Content whose frontmatter isn't valid YAML is returned unchanged.
+func splitFrontmatter(content string) (obsidianFrontmatter, string) {
+	var props obsidianFrontmatter
+
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return props, content
+	}
+	rest := normalized[len("---\n"):]
+
+	end, next := -1, 0
+	for offset := 0; offset <= len(rest); {
+		line := rest[offset:]
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		if trimmed := strings.TrimRight(line, " \t"); trimmed == "---" || trimmed == "..." {
+			end, next = offset, offset+len(line)+1
+			break
+		}
+		offset += len(line) + 1
+	}
+	if end < 0 {
+		return props, content
+	}
+
+	if err := yaml.Unmarshal([]byte(rest[:end]), &props); err != nil {
+		return obsidianFrontmatter{}, content
+	}
+	if next > len(rest) {
+		next = len(rest)
+	}
+	return props, strings.TrimLeft(rest[next:], "\n")
+}
+
+// frontmatterTags returns the tags of a tags property, without leading #
+func frontmatterTags(v interface{}) []string {
+	var raw []string
+	switch tags := v.(type) {
+	case string:
+		raw = strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == ' ' })
+	case []interface{}:
+		for _, tag := range tags {
+			raw = append(raw, fmt.Sprint(tag))
+		}
+	}
+
+	var tags []string
+	for _, tag := range raw {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "#"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// withTags appends tags to a note body as a line of #hashtags
+func withTags(body string, tags []string) string {
+	if len(tags) == 0 {
+		return body
+	}
+	line := "#" + strings.Join(tags, " #")
+	if strings.TrimSpace(body) == "" {
+		return line + "\n"
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + line + "\n"
+}
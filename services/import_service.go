@@ -0,0 +1,368 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"daily-notes/pkg/rendercache"
+	"daily-notes/validator"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Import limits, so an upload can't expand into an unbounded amount of work
+const (
+	MaxImportFiles    = 5000
+	MaxImportFileSize = 1 << 20 // 1 MB per note
+)
+
+// importDateLayouts are the file names accepted for a day's note, without the extension
+var importDateLayouts = []string{"2006-01-02", "02-01-2006"}
+
+// ImportFormat is the layout of an imported archive
+type ImportFormat string
+
+const (
+	// ImportFormatFolders is one folder per context and one file per day named after its date
+	ImportFormatFolders ImportFormat = "folders"
+	// ImportFormatObsidian is a zipped Obsidian vault (see import_obsidian.go)
+	ImportFormatObsidian ImportFormat = "obsidian"
+	// ImportFormatJSON is an account export (see ProfileService.ExportAccount); its notes
+	// are saved with ImportNotes
+	ImportFormatJSON ImportFormat = "json"
+)
+
+// ParseImportFormat reads the format parameter of an import; empty means ImportFormatFolders
+func ParseImportFormat(s string) (ImportFormat, error) {
+	switch format := ImportFormat(strings.ToLower(strings.TrimSpace(s))); format {
+	case "":
+		return ImportFormatFolders, nil
+	case ImportFormatFolders, ImportFormatObsidian, ImportFormatJSON:
+		return format, nil
+	}
+	return "", ErrUnsupportedImportFormat
+}
+
+// ImportService imports notes from a zip archive with one folder per context and
+// one markdown file per day, e.g. Work/2025-10-16.md or Work/16-10-2025.md, or
+// from an Obsidian vault.
+type ImportService struct {
+	repo      ImportRepository
+	clock     clock.Clock
+	ids       idgen.Generator
+	validator *validator.Validator
+	renders   *rendercache.Cache
+}
+
+// NewImportService creates a new import service
+func NewImportService(repo ImportRepository) *ImportService {
+	return &ImportService{repo: repo, clock: clock.Real(), ids: idgen.UUID(), validator: validator.New()}
+}
+
+// SetClock replaces the clock used for note and context timestamps
+func (is *ImportService) SetClock(c clock.Clock) {
+	is.clock = c
+}
+
+// SetIDGenerator replaces the generator used for created context IDs
+func (is *ImportService) SetIDGenerator(g idgen.Generator) {
+	is.ids = g
+}
+
+// SetRenderCache registers the cache of rendered HTML so overwritten notes are invalidated
+func (is *ImportService) SetRenderCache(cache *rendercache.Cache) {
+	is.renders = cache
+}
+
+// importRun is the state of one archive import
+type importRun struct {
+	userID   string
+	format   ImportFormat
+	vault    string            // Folder of the Obsidian vault inside the archive, "" for its root
+	contexts map[string]bool   // Context names known to exist
+	saved    map[string]string // Path of the file saved for each context/date
+	result   *models.NoteImportResult
+}
+
+// Import saves every note of a zip archive in the given format, queued for sync, and
+// creates missing contexts. Files that can't be imported (bad names, not markdown, too
+// large) are reported in the result instead of failing the import; existing notes are replaced.
+func (is *ImportService) Import(ctx context.Context, userID string, format ImportFormat, archive io.ReaderAt, size int64) (_ *models.NoteImportResult, err error) {
+	defer wrapOp("import notes", &err)
+	if format != ImportFormatFolders && format != ImportFormatObsidian {
+		return nil, ErrUnsupportedImportFormat
+	}
+	zr, err := zip.NewReader(archive, size)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+
+	files := make([]*zip.File, 0, len(zr.File))
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() && !skipImportEntry(f.Name) {
+			files = append(files, f)
+		}
+	}
+	if len(files) > MaxImportFiles {
+		return nil, ErrTooManyImportFiles
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	run := &importRun{
+		userID:   userID,
+		format:   format,
+		vault:    vaultRoot(zr.File),
+		contexts: make(map[string]bool),
+		saved:    make(map[string]string),
+		result:   &models.NoteImportResult{Files: make([]models.ImportFileResult, 0, len(files))},
+	}
+	for _, f := range files {
+		file := models.ImportFileResult{Path: f.Name}
+		if err := is.importFile(ctx, run, f, &file); err != nil {
+			var failure importFailure
+			if !errors.As(err, &failure) {
+				return nil, err
+			}
+			file.Error = failure.Error()
+			run.result.Failed++
+		} else {
+			run.result.Imported++
+		}
+		run.result.Files = append(run.result.Files, file)
+	}
+
+	return run.result, nil
+}
+
+// ImportNotes saves the notes of an account export, queued for sync unless they
+// were exported local only, keeping their creation times, and creates missing contexts. Each note is reported like an archive
+// file, with the path context/date; existing notes are replaced.
+func (is *ImportService) ImportNotes(ctx context.Context, userID string, notes []models.ExportedNote) (_ *models.NoteImportResult, err error) {
+	defer wrapOp("import notes", &err)
+	if len(notes) > MaxImportFiles {
+		return nil, ErrTooManyImportFiles
+	}
+
+	run := &importRun{
+		userID:   userID,
+		format:   ImportFormatJSON,
+		contexts: make(map[string]bool),
+		saved:    make(map[string]string),
+		result:   &models.NoteImportResult{Files: make([]models.ImportFileResult, 0, len(notes))},
+	}
+	for _, exported := range notes {
+		file := models.ImportFileResult{
+			Path:    exported.Context + "/" + exported.Date,
+			Context: exported.Context,
+			Date:    exported.Date,
+		}
+		if _, ok := run.saved[file.Path]; ok {
+			file.Error = "note appears more than once"
+			run.result.Failed++
+			run.result.Files = append(run.result.Files, file)
+			continue
+		}
+
+		if err := is.ensureContext(ctx, run, exported.Context); err != nil {
+			return nil, err
+		}
+		note := &models.Note{
+			UserID:    userID,
+			Context:   exported.Context,
+			Date:      exported.Date,
+			Content:   exported.Content,
+			LocalOnly: exported.LocalOnly,
+			CreatedAt: exported.CreatedAt,
+			UpdatedAt: is.clock.Now(),
+		}
+		if note.CreatedAt.IsZero() {
+			note.CreatedAt = is.clock.Now()
+		}
+		if err := is.repo.UpsertNote(ctx, note, true); err != nil {
+			return nil, err
+		}
+		if is.renders != nil {
+			is.renders.Invalidate(note.ID)
+		}
+		run.saved[file.Path] = file.Path
+		run.result.Imported++
+		run.result.Files = append(run.result.Files, file)
+	}
+
+	return run.result, nil
+}
+
+// importFailure is a problem with one file, reported to the user instead of aborting the import
+type importFailure string
+
+func (f importFailure) Error() string {
+	return string(f)
+}
+
+// importFile saves the note of one archive file, filling in its context and date
+func (is *ImportService) importFile(ctx context.Context, run *importRun, f *zip.File, file *models.ImportFileResult) error {
+	read := readFolderNote
+	if run.format == ImportFormatObsidian {
+		read = run.readObsidianNote
+	}
+	imported, err := read(f, file)
+	if err != nil {
+		return err
+	}
+
+	item := models.BatchNoteItem{Context: file.Context, Date: file.Date}
+	if err := is.validator.Validate(&item); err != nil {
+		return importFailure(err.Error())
+	}
+
+	key := file.Context + "/" + file.Date
+	if other, ok := run.saved[key]; ok {
+		return importFailure("same note as " + other)
+	}
+
+	if err := is.ensureContext(ctx, run, file.Context); err != nil {
+		return err
+	}
+
+	createdAt := is.clock.Now()
+	if !imported.createdAt.IsZero() && imported.createdAt.Before(createdAt) {
+		createdAt = imported.createdAt
+	}
+	note := &models.Note{
+		UserID:    run.userID,
+		Context:   file.Context,
+		Date:      file.Date,
+		Content:   imported.content,
+		CreatedAt: createdAt,
+		UpdatedAt: is.clock.Now(),
+	}
+	if err := is.repo.UpsertNote(ctx, note, true); err != nil {
+		return err
+	}
+	if is.renders != nil {
+		is.renders.Invalidate(note.ID)
+	}
+	run.saved[key] = f.Name
+	return nil
+}
+
+// importedNote is the note read from one archive file
+type importedNote struct {
+	content   string
+	createdAt time.Time // Zero when the archive doesn't record it
+}
+
+// readFolderNote reads a file of a folders archive: the context is the folder the
+// file is in and the date is the file name
+func readFolderNote(f *zip.File, file *models.ImportFileResult) (*importedNote, error) {
+	dir, name := path.Split(path.Clean(f.Name))
+	ext := path.Ext(name)
+	if !isMarkdownExt(ext) {
+		return nil, importFailure("not a markdown file")
+	}
+	if dir == "" {
+		return nil, importFailure("file is not inside a context folder")
+	}
+	file.Context = strings.TrimSpace(path.Base(dir))
+
+	date, ok := parseImportDate(strings.TrimSuffix(name, ext))
+	if !ok {
+		return nil, importFailure("file name is not a date (YYYY-MM-DD or DD-MM-YYYY)")
+	}
+	file.Date = date
+
+	content, err := readImportFile(f)
+	if err != nil {
+		return nil, err
+	}
+	return &importedNote{content: content}, nil
+}
+
+// isMarkdownExt reports whether a file extension is one of a markdown file
+func isMarkdownExt(ext string) bool {
+	return strings.EqualFold(ext, ".md") || strings.EqualFold(ext, ".markdown")
+}
+
+// ensureContext creates a context the user doesn't have yet
+func (is *ImportService) ensureContext(ctx context.Context, run *importRun, name string) error {
+	if run.contexts[name] {
+		return nil
+	}
+
+	existing, err := is.repo.GetContextByName(ctx, database.ScopeUser(run.userID), name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		c := &models.Context{
+			ID:        is.ids.NewID(),
+			UserID:    run.userID,
+			Name:      name,
+			Color:     "primary",
+			CreatedAt: is.clock.Now(),
+		}
+		if err := is.repo.CreateContext(ctx, c); err != nil {
+			return err
+		}
+		run.result.ContextsCreated++
+	}
+
+	run.contexts[name] = true
+	return nil
+}
+
+// readImportFile returns the text of an archive file, refusing files over MaxImportFileSize
+func readImportFile(f *zip.File) (string, error) {
+	if f.UncompressedSize64 > MaxImportFileSize {
+		return "", importFailure("file is larger than 1 MB")
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return "", importFailure("file could not be read: " + err.Error())
+	}
+	defer rc.Close()
+
+	// The recorded size can't be trusted, so stop reading past the limit too
+	data, err := io.ReadAll(io.LimitReader(rc, MaxImportFileSize+1))
+	if err != nil {
+		return "", importFailure("file could not be read: " + err.Error())
+	}
+	if len(data) > MaxImportFileSize {
+		return "", importFailure("file is larger than 1 MB")
+	}
+	if !utf8.Valid(data) {
+		return "", importFailure("file is not UTF-8 text")
+	}
+	return strings.TrimPrefix(string(data), "\ufeff"), nil
+}
+
+// parseImportDate reads a note date from a file name in one of importDateLayouts
+func parseImportDate(name string) (string, bool) {
+	for _, layout := range importDateLayouts {
+		if t, err := time.Parse(layout, name); err == nil {
+			return t.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
+
+// skipImportEntry reports whether an archive entry is metadata added by the OS or
+// zip tool (hidden files, macOS resource forks) rather than one of the user's files
+func skipImportEntry(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		hidden := strings.HasPrefix(part, ".") && part != "." && part != ".."
+		if hidden || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"daily-notes/models"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// zipArchive builds an in-memory zip with one entry per path
+func zipArchive(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestImportService_Import(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Imports context folders with both date formats", func(t *testing.T) {
+		repo := new(MockContextRepository)
+		repo.On("GetContextByName", "user123", "Work").Return(&models.Context{Name: "Work"}, nil)
+		repo.On("GetContextByName", "user123", "Personal").Return(nil, nil)
+		repo.On("CreateContext", mock.MatchedBy(func(c *models.Context) bool {
+			return c.Name == "Personal" && c.UserID == "user123"
+		})).Return(nil)
+		repo.On("UpsertNote", mock.AnythingOfType("*models.Note"), true).Return(nil)
+
+		archive := zipArchive(t, map[string]string{
+			"export/Work/2025-10-16.md":       "# Planning",
+			"export/Work/17-10-2025.md":       "Retro",
+			"export/Personal/2025-10-18.md":   "Groceries",
+			"export/Personal/.DS_Store":       "",
+			"__MACOSX/export/Work/._17-10.md": "",
+		})
+
+		result, err := NewImportService(repo).Import(ctx, "user123", ImportFormatFolders, archive, archive.Size())
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Imported)
+		assert.Zero(t, result.Failed)
+		assert.Equal(t, 1, result.ContextsCreated)
+		require.Len(t, result.Files, 3)
+		assert.Equal(t, models.ImportFileResult{Path: "export/Work/17-10-2025.md", Context: "Work", Date: "2025-10-17"}, result.Files[1])
+
+		repo.AssertCalled(t, "UpsertNote", mock.MatchedBy(func(n *models.Note) bool {
+			return n.Context == "Work" && n.Date == "2025-10-17" && n.Content == "Retro" && n.UserID == "user123"
+		}), true)
+		repo.AssertNumberOfCalls(t, "CreateContext", 1)
+	})
+
+	t.Run("Reports files that can't be imported", func(t *testing.T) {
+		repo := new(MockContextRepository)
+		repo.On("GetContextByName", "user123", "Work").Return(&models.Context{Name: "Work"}, nil)
+		repo.On("UpsertNote", mock.AnythingOfType("*models.Note"), true).Return(nil)
+
+		archive := zipArchive(t, map[string]string{
+			"Work/2025-10-16.md":      "saved",
+			"Work/16-10-2025.md":      "same day",
+			"Work/notes.md":           "no date",
+			"Work/2025-10-17.txt":     "not markdown",
+			"2025-10-18.md":           "no folder",
+			"Bad<Name>/2025-10-19.md": "invalid context",
+			"Work/2025-10-20.md":      strings.Repeat("x", MaxImportFileSize+1),
+			"Work/2025-10-21.md":      "\xff\xfe",
+		})
+
+		result, err := NewImportService(repo).Import(ctx, "user123", ImportFormatFolders, archive, archive.Size())
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Imported)
+		assert.Equal(t, 7, result.Failed)
+
+		failures := map[string]string{}
+		for _, file := range result.Files {
+			if file.Error != "" {
+				failures[file.Path] = file.Error
+			}
+		}
+		assert.Equal(t, "same note as Work/16-10-2025.md", failures["Work/2025-10-16.md"])
+		assert.Contains(t, failures["Work/notes.md"], "not a date")
+		assert.Equal(t, "not a markdown file", failures["Work/2025-10-17.txt"])
+		assert.Equal(t, "file is not inside a context folder", failures["2025-10-18.md"])
+		assert.Contains(t, failures["Bad<Name>/2025-10-19.md"], "invalid characters")
+		assert.Equal(t, "file is larger than 1 MB", failures["Work/2025-10-20.md"])
+		assert.Equal(t, "file is not UTF-8 text", failures["Work/2025-10-21.md"])
+		repo.AssertNumberOfCalls(t, "UpsertNote", 1)
+	})
+
+	t.Run("Rejects uploads that aren't zip archives", func(t *testing.T) {
+		archive := bytes.NewReader([]byte("not a zip"))
+		_, err := NewImportService(new(MockContextRepository)).Import(ctx, "user123", ImportFormatFolders, archive, archive.Size())
+		assert.ErrorIs(t, err, ErrInvalidArchive)
+	})
+
+	t.Run("Repository errors abort the import", func(t *testing.T) {
+		repo := new(MockContextRepository)
+		repo.On("GetContextByName", "user123", "Work").Return(nil, errors.New("database error"))
+
+		archive := zipArchive(t, map[string]string{"Work/2025-10-16.md": "saved"})
+		_, err := NewImportService(repo).Import(ctx, "user123", ImportFormatFolders, archive, archive.Size())
+		assert.EqualError(t, err, "import notes: database error")
+	})
+}
+
+func TestImportService_ImportNotes(t *testing.T) {
+	created := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	repo := new(MockContextRepository)
+	repo.On("GetContextByName", "user123", "Work").Return(&models.Context{Name: "Work"}, nil)
+	repo.On("GetContextByName", "user123", "Home").Return(nil, nil)
+	repo.On("CreateContext", mock.MatchedBy(func(c *models.Context) bool { return c.Name == "Home" })).Return(nil)
+	repo.On("UpsertNote", mock.AnythingOfType("*models.Note"), true).Return(nil)
+
+	result, err := NewImportService(repo).ImportNotes(context.Background(), "user123", []models.ExportedNote{
+		{Context: "Work", Type: "day", Date: "2025-10-16", Content: "Restored", CreatedAt: created},
+		{Context: "Home", Type: "week", Date: "2025-W42", Content: "Week plan"},
+		{Context: "Work", Type: "day", Date: "2025-10-16", Content: "Duplicate"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 1, result.ContextsCreated)
+	assert.Equal(t, models.ImportFileResult{Path: "Work/2025-10-16", Context: "Work", Date: "2025-10-16", Error: "note appears more than once"}, result.Files[2])
+
+	repo.AssertCalled(t, "UpsertNote", mock.MatchedBy(func(n *models.Note) bool {
+		return n.Content == "Restored" && n.CreatedAt.Equal(created) && n.UserID == "user123"
+	}), true)
+	repo.AssertNumberOfCalls(t, "UpsertNote", 2)
+}
+
+func TestImportService_ImportObsidian(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(MockContextRepository)
+	repo.On("GetContextByName", "user123", mock.Anything).Return(&models.Context{}, nil)
+	repo.On("UpsertNote", mock.AnythingOfType("*models.Note"), true).Return(nil)
+
+	archive := zipArchive(t, map[string]string{
+		"backup/Vault/.obsidian/daily-notes.json":          "{}",
+		"backup/Vault/2025-10-16.md":                       "Root note",
+		"backup/Vault/Journal/2025/10/October 17, 2025.md": "---\ncreated: 2025-10-17T08:30:00Z\ntags:\n  - log\n  - \"#work\"\n---\n\nBody",
+		"backup/Vault/Journal/Meeting notes.md":            "---\ndate: 2025-10-18\n---\nFrom frontmatter",
+		"backup/Vault/Journal/Ideas.md":                    "No date anywhere",
+		"backup/Vault/Journal/cover.png":                   "",
+		"backup/other/2025-10-19.md":                       "Outside",
+	})
+
+	result, err := NewImportService(repo).Import(ctx, "user123", ImportFormatObsidian, archive, archive.Size())
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Imported)
+	assert.Equal(t, 3, result.Failed)
+
+	files := map[string]models.ImportFileResult{}
+	for _, file := range result.Files {
+		files[path.Base(file.Path)] = file
+	}
+	assert.Equal(t, ObsidianRootContext, files["2025-10-16.md"].Context)
+	assert.Equal(t, "Journal", files["October 17, 2025.md"].Context)
+	assert.Equal(t, "2025-10-17", files["October 17, 2025.md"].Date)
+	assert.Equal(t, "2025-10-18", files["Meeting notes.md"].Date)
+	assert.Contains(t, files["Ideas.md"].Error, "not a daily note")
+	assert.Equal(t, "not a markdown file", files["cover.png"].Error)
+	assert.Equal(t, "file is outside the vault", files["2025-10-19.md"].Error)
+
+	repo.AssertCalled(t, "UpsertNote", mock.MatchedBy(func(n *models.Note) bool {
+		return n.Context == "Journal" && n.Date == "2025-10-17" && n.Content == "Body\n\n#log #work\n" &&
+			n.CreatedAt.Equal(time.Date(2025, 10, 17, 8, 30, 0, 0, time.UTC))
+	}), true)
+	repo.AssertCalled(t, "UpsertNote", mock.MatchedBy(func(n *models.Note) bool {
+		return n.Date == "2025-10-18" && n.Content == "From frontmatter"
+	}), true)
+}
+
+func TestParseObsidianDate(t *testing.T) {
+	for name, want := range map[string]string{
+		"2025-10-16":                 "2025-10-16",
+		"16-10-2025":                 "2025-10-16",
+		"2025.10.16":                 "2025-10-16",
+		"20251016":                   "2025-10-16",
+		"October 16, 2025":           "2025-10-16",
+		"Thursday, October 16, 2025": "2025-10-16",
+		"2025-10-16 Thursday":        "2025-10-16",
+		"2025-10-16 - Standup":       "2025-10-16",
+	} {
+		got, ok := parseObsidianDate(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, got, name)
+	}
+
+	for _, name := range []string{"Ideas", "2025-13-40", "2025-10-16x"} {
+		_, ok := parseObsidianDate(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestSplitFrontmatter(t *testing.T) {
+	props, body := splitFrontmatter("---\r\ndate: 2025-10-16\r\ntags: a, b\r\n---\r\nText")
+	assert.Equal(t, "2025-10-16", props.Date)
+	assert.Equal(t, []string{"a", "b"}, frontmatterTags(props.Tags))
+	assert.Equal(t, "Text", body)
+
+	content := "---\nnot: [valid\n---\nText"
+	_, body = splitFrontmatter(content)
+	assert.Equal(t, content, body)
+
+	_, body = splitFrontmatter("---\nunterminated")
+	assert.Equal(t, "---\nunterminated", body)
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"daily-notes/database"
 	"daily-notes/models"
-	"daily-notes/storage/drive"
+	"daily-notes/pkg/mail"
+	"daily-notes/pkg/visibility"
+	"daily-notes/pkg/webpush"
+	"daily-notes/storage"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -12,36 +15,220 @@ import (
 
 // NoteRepository defines the interface for note data access
 type NoteRepository interface {
-	GetNote(userID, contextName, date string) (*models.Note, error)
-	UpsertNote(note *models.Note, syncPending bool) error
-	DeleteNote(userID, contextName, date string) error
-	GetNotesByContext(userID, contextName string, limit, offset int) ([]models.Note, error)
-	GetFailedSyncNotes(userID string, limit int) ([]models.Note, error)
-	GetPendingSyncNotes(limit int) ([]database.NoteWithMeta, error)
-	RetrySyncNote(noteID string) error
+	GetNote(ctx context.Context, scope database.UserScope, contextName, date string) (*models.Note, error)
+	UpsertNote(ctx context.Context, note *models.Note, syncPending bool) error
+	UpsertNoteAtRevision(ctx context.Context, note *models.Note, baseRevision int, syncPending bool) (bool, error)
+	UpsertNotes(ctx context.Context, notes []*models.Note, syncPending bool) error
+	DeleteNote(ctx context.Context, scope database.UserScope, contextName, date string) error
+	GetNotesByContext(ctx context.Context, scope database.UserScope, contextName string, limit, offset int) ([]models.Note, error)
+	GetNoteChanges(ctx context.Context, userID string, since int64, limit int) ([]models.NoteChange, error)
+	GetAllNotesByUser(ctx context.Context, scope database.UserScope) ([]models.Note, error)
+	GetNotesByKeys(ctx context.Context, scope database.UserScope, contextName string, keys []string) ([]models.Note, error)
+	GetMonthNotes(ctx context.Context, scope database.UserScope, contextNames, keys []string) ([]models.Note, error)
+	GetLiveSharedNote(ctx context.Context, noteID string, now time.Time) (*models.Note, error)
+	GetAgendaNotes(ctx context.Context, scope database.UserScope, from, to string) ([]models.AgendaNote, error)
+	GetCalendarDays(ctx context.Context, scope database.UserScope, contextName, from, to string) ([]models.CalendarDay, error)
+	GetActivityDays(ctx context.Context, userID, from, to string) ([]models.ActivityDay, error)
+	GetContextStreaks(ctx context.Context, userID, today string) ([]models.ContextStreak, error)
+	GetAllActivityDays(ctx context.Context, userID string) ([]models.ActivityDay, error)
+	GetWritingTotals(ctx context.Context, userID string) (*models.WritingTotals, error)
+	GetContextWriting(ctx context.Context, userID string, limit int) ([]models.ContextWriting, error)
+	GetAdjacentNoteDates(ctx context.Context, scope database.UserScope, contextName, key string) (previous, next string, err error)
+	GetNotesOnDate(ctx context.Context, scope database.UserScope, key string) ([]models.AgendaNote, error)
+	SplitNote(ctx context.Context, source *models.Note, sourceRevision int, target *models.Note, targetRevision int) (bool, error)
+	TransferNotes(ctx context.Context, scope database.UserScope, fromContext, toContext string, keys []string, move bool, trashedAt time.Time) ([]models.Note, error)
+	RedateNotes(ctx context.Context, scope database.UserScope, contextName string, dates []string, days int, trashedAt time.Time) ([]models.Note, error)
+	SearchNotes(ctx context.Context, userID, query string, filter models.NoteSearchFilter, limit, offset int) ([]models.NoteSearchResult, error)
+	GetNoteSizeStats(ctx context.Context, userID string, limit, largest int) (*models.NoteSizeStats, error)
+	GetTags(ctx context.Context, userID string) ([]models.Tag, error)
+	GetNotesByTag(ctx context.Context, userID, tag string, limit, offset int) ([]models.Note, error)
+	GetTasks(ctx context.Context, userID, status, contextName string, limit, offset int) ([]models.Task, error)
+	GetTask(ctx context.Context, userID string, id int64) (*models.Task, error)
+	GetNoteRevisions(ctx context.Context, userID, contextName, date string) ([]models.NoteRevision, error)
+	GetNoteRevision(ctx context.Context, userID string, id int64) (*models.NoteRevision, error)
+	GetNoteConflicts(ctx context.Context, userID string) ([]models.NoteConflict, error)
+	GetNoteConflict(ctx context.Context, userID, contextName, date string) (*models.NoteConflict, error)
+	ResolveNoteConflict(ctx context.Context, note *models.Note) (bool, error)
+	GetContextByName(ctx context.Context, scope database.UserScope, name string) (*models.Context, error)
+	GetFailedSyncNotes(ctx context.Context, userID string, limit int) ([]models.Note, error)
+	GetPendingSyncNotes(ctx context.Context, limit int) ([]database.NoteWithMeta, error)
+	RetrySyncNote(ctx context.Context, noteID string) error
+	RetryUserSyncNotes(ctx context.Context, userID string) (int, error)
+	GetUserSyncOperations(ctx context.Context, userID string) ([]models.SyncOperation, error)
+	RetryUserSyncOperations(ctx context.Context, userID string) (int, error)
+	SetNoteLocalOnly(ctx context.Context, userID, contextName, date string, localOnly bool) (bool, error)
+	CountLocalOnlyNotes(ctx context.Context, userID string) (int, error)
+	SetNotePinned(ctx context.Context, userID, contextName, date string, pinnedAt *time.Time) (bool, error)
+	GetPinnedNotes(ctx context.Context, userID, contextName string) ([]models.Note, error)
+	TrashNote(ctx context.Context, scope database.UserScope, contextName, date string, deletedAt time.Time) error
+	GetTrashedNotes(ctx context.Context, scope database.UserScope, since time.Time) ([]models.TrashedNote, error)
+	GetTrashedNote(ctx context.Context, scope database.UserScope, id int64) (*models.TrashedNote, error)
+	RestoreTrashedNote(ctx context.Context, id int64, note *models.Note) (bool, error)
+	PurgeNoteTrash(ctx context.Context, scope database.UserScope, before time.Time) (int64, error)
 }
 
 // SyncWorker defines the interface for background sync operations
 type SyncWorker interface {
 	SyncNoteImmediate(userID, contextName, date string)
+	SyncNoteDebounced(userID, contextName, date string)
+	SyncNotesImmediate(userID string, notes []models.Note)
 	ImportFromDrive(userID string, token *oauth2.Token) error
+	RetryAfterSignIn(userID string)
+	RetryNow()
 }
 
 // ContextRepository defines the interface for context data access
 type ContextRepository interface {
-	GetContexts(userID string) ([]models.Context, error)
-	GetContextByName(userID, name string) (*models.Context, error)
-	GetContextByID(contextID string) (*models.Context, error)
-	CreateContext(ctx *models.Context) error
-	UpdateContext(contextID, name, color string) error
-	UpdateNotesContextName(oldName, newName, userID string) error
-	DeleteContext(contextID string) error
-	GetNotesByContext(userID, contextName string, limit, offset int) ([]models.Note, error)
-	DeleteNote(userID, contextName, date string) error
-}
-
-// StorageService represents Google Drive service operations needed by services
-// Interface for testability - production uses drive.Service
+	GetContexts(ctx context.Context, scope database.UserScope) ([]models.Context, error)
+	GetContextByName(ctx context.Context, scope database.UserScope, name string) (*models.Context, error)
+	GetContextByID(ctx context.Context, scope database.UserScope, contextID string) (*models.Context, error)
+	CreateContext(ctx context.Context, c *models.Context) error
+	UpdateContext(ctx context.Context, scope database.UserScope, contextID, name, color, icon string) error
+	UpdateNotesContextName(ctx context.Context, scope database.UserScope, oldName, newName string) error
+	DeleteContext(ctx context.Context, scope database.UserScope, contextID string, deletedAt time.Time) error
+	GetTrashedContexts(ctx context.Context, scope database.UserScope, since time.Time) ([]models.TrashedContext, error)
+	GetTrashedContext(ctx context.Context, scope database.UserScope, contextID string) (*models.TrashedContext, error)
+	RestoreContext(ctx context.Context, scope database.UserScope, contextID string) error
+	ReorderContexts(ctx context.Context, scope database.UserScope, ids []string) (bool, error)
+	UpdateContextTemplate(ctx context.Context, scope database.UserScope, contextID, template string) error
+	SetContextLocalOnly(ctx context.Context, scope database.UserScope, contextID string, localOnly bool) error
+	UpdateContextLanguage(ctx context.Context, scope database.UserScope, contextID, language string) error
+	GetContextStats(ctx context.Context, userID, contextName string) (*models.ContextStats, error)
+	RecordSyncOperation(ctx context.Context, op *models.SyncOperation) error
+	GetNotesByContext(ctx context.Context, scope database.UserScope, contextName string, limit, offset int) ([]models.Note, error)
+	GetAllNotesByUser(ctx context.Context, scope database.UserScope) ([]models.Note, error)
+	UpsertNote(ctx context.Context, note *models.Note, syncPending bool) error
+	DeleteNote(ctx context.Context, scope database.UserScope, contextName, date string) error
+	TrashNote(ctx context.Context, scope database.UserScope, contextName, date string, deletedAt time.Time) error
+}
+
+// StorageProviderRepository defines the data access needed to pick a storage provider
+type StorageProviderRepository interface {
+	GetStorageProvider(ctx context.Context, userID string) (string, error)
+	SetStorageProvider(ctx context.Context, userID, provider string) error
+	GetStorageToken(ctx context.Context, userID, provider string) (*oauth2.Token, error)
+	SaveStorageToken(ctx context.Context, userID, provider string, token *oauth2.Token) error
+	DeleteStorageToken(ctx context.Context, userID, provider string) error
+	MarkUserNotesForSync(ctx context.Context, userID string) (int, error)
+}
+
+// AttachmentRepository defines the data access for files attached to notes
+type AttachmentRepository interface {
+	GetContextByName(ctx context.Context, scope database.UserScope, name string) (*models.Context, error)
+	CreateAttachment(ctx context.Context, a *models.Attachment) error
+	GetAttachment(ctx context.Context, userID, id string) (*models.Attachment, error)
+	GetNoteAttachments(ctx context.Context, userID, contextName, date string) ([]models.Attachment, error)
+	SetAttachmentStorageStatus(ctx context.Context, id, status, errorMsg string) error
+}
+
+// APITokenRepository defines the data access for the API tokens of integrations
+type APITokenRepository interface {
+	GetContextByName(ctx context.Context, scope database.UserScope, name string) (*models.Context, error)
+	CreateAPIToken(ctx context.Context, token *models.APIToken, hash string) error
+	GetAPITokens(ctx context.Context, userID string) ([]models.APIToken, error)
+	GetAPITokenByHash(ctx context.Context, hash string) (*models.APIToken, error)
+	TouchAPIToken(ctx context.Context, id int64, at time.Time) error
+	DeleteAPIToken(ctx context.Context, userID string, id int64) (bool, error)
+}
+
+// APIKeyRepository defines the data access for the API keys of scripts and apps
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey, hash string) error
+	GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int64, at time.Time) error
+	DeleteAPIKey(ctx context.Context, userID string, id int64) (bool, error)
+}
+
+// NoteScheduleRepository defines the data access for creating daily notes on a schedule
+type NoteScheduleRepository interface {
+	GetNoteSchedule(ctx context.Context, userID string) (*models.NoteSchedule, error)
+	GetNoteSchedules(ctx context.Context) ([]models.NoteSchedule, error)
+	SetNoteSchedule(ctx context.Context, userID, localTime string) error
+	SetNoteScheduleRun(ctx context.Context, userID, date string) error
+	DeleteNoteSchedule(ctx context.Context, userID string) (bool, error)
+	GetContexts(ctx context.Context, scope database.UserScope) ([]models.Context, error)
+}
+
+// DropRepository defines the data access for signed drop box URLs
+type DropRepository interface {
+	GetContextByName(ctx context.Context, scope database.UserScope, name string) (*models.Context, error)
+	UseDropNonce(ctx context.Context, userID, nonce string, expiresAt, now time.Time) (bool, error)
+	ReleaseDropNonce(ctx context.Context, nonce string) error
+}
+
+// DigestRepository defines the data access for weekly email digests
+type DigestRepository interface {
+	GetDigestSubscription(ctx context.Context, userID string) (*models.DigestSubscription, error)
+	GetDigestSubscriptions(ctx context.Context) ([]models.DigestSubscription, error)
+	SubscribeDigest(ctx context.Context, userID, token, lastWeek string) error
+	UnsubscribeDigest(ctx context.Context, userID string) (bool, error)
+	UnsubscribeDigestByToken(ctx context.Context, token string) (bool, error)
+	SetDigestWeek(ctx context.Context, userID, week string) error
+	GetDigestNotes(ctx context.Context, userID, from, to string) ([]models.AgendaNote, error)
+}
+
+// ReminderRepository defines the data access for reminders
+type ReminderRepository interface {
+	GetReminders(ctx context.Context, userID, status string, limit, offset int) ([]models.Reminder, error)
+	GetReminder(ctx context.Context, userID string, id int64) (*models.Reminder, error)
+	CreateReminder(ctx context.Context, reminder *models.Reminder) error
+	DeleteReminder(ctx context.Context, userID string, id int64) (bool, error)
+	GetDueReminders(ctx context.Context, before string) ([]models.Reminder, error)
+	SetReminderStatus(ctx context.Context, id int64, status string, at time.Time) error
+	GetUser(ctx context.Context, userID string) (*models.User, error)
+}
+
+// PushRepository defines the data access for web push subscriptions
+type PushRepository interface {
+	SavePushSubscription(ctx context.Context, subscription *models.PushSubscription) error
+	GetPushSubscriptions(ctx context.Context, userID string) ([]models.PushSubscription, error)
+	DeletePushSubscription(ctx context.Context, userID, endpoint string) (bool, error)
+	DeletePushSubscriptionByID(ctx context.Context, id int64) error
+}
+
+// MemberRepository defines the data access for the members of shared contexts
+type MemberRepository interface {
+	CreateContextInvitation(ctx context.Context, m *models.ContextMember) error
+	GetContextMembers(ctx context.Context, contextID string) ([]models.ContextMember, error)
+	DeleteContextMember(ctx context.Context, contextID string, id int64) (bool, error)
+	GetContextInvitation(ctx context.Context, id int64) (*models.ContextInvitation, error)
+	GetContextInvitations(ctx context.Context, userID, email string) ([]models.ContextInvitation, error)
+	AcceptContextInvitation(ctx context.Context, id int64, userID, email string, at time.Time) (bool, error)
+	LeaveContext(ctx context.Context, id int64, userID, email string) (bool, error)
+	GetSharedContexts(ctx context.Context, userID string) ([]models.Context, error)
+	GetContextAccess(ctx context.Context, userID, contextName string) (*models.ContextAccess, error)
+	GetContextByID(ctx context.Context, scope database.UserScope, contextID string) (*models.Context, error)
+	GetUser(ctx context.Context, userID string) (*models.User, error)
+}
+
+// EncryptionRepository defines the data access for end-to-end encrypted notes
+type EncryptionRepository interface {
+	GetEncryptionKey(ctx context.Context, userID string) (*models.EncryptionKey, error)
+	CreateEncryptionKey(ctx context.Context, key *models.EncryptionKey) error
+	DeleteEncryptionKey(ctx context.Context, userID string) (bool, error)
+	CountEncryptedNotes(ctx context.Context, userID string) (encrypted, plaintext int, err error)
+}
+
+// Pusher sends web push notifications to a user's browsers (see PushService)
+type Pusher interface {
+	Enabled() bool
+	Send(ctx context.Context, userID string, notification models.PushNotification) error
+}
+
+// PushSender sends web push messages signed with the server's VAPID keys (see pkg/webpush)
+type PushSender interface {
+	PublicKey() string
+	Send(ctx context.Context, sub webpush.Subscription, payload []byte, opts webpush.Options) error
+}
+
+// Mailer sends email (see pkg/mail)
+type Mailer interface {
+	Send(ctx context.Context, m mail.Message) error
+}
+
+// StorageService represents storage provider operations needed by services
+// Interface for testability - production uses a storage.Provider (Drive, Dropbox)
 type StorageService interface {
 	UpsertNote(contextName, date, content string) (*models.Note, error)
 	DeleteNote(contextName, date string) error
@@ -49,15 +236,39 @@ type StorageService interface {
 	GetContexts() ([]models.Context, error)
 	RenameContext(contextID, oldName, newName string) error
 	DeleteContext(contextID, contextName string) error
+	RestoreContext(ctx models.Context) error
 	GetSettings() (models.UserSettings, error)
-	GetConfig() (*drive.Config, error)
+	GetConfig() (*storage.Config, error)
 	GetCurrentToken() (*oauth2.Token, error)
 	CleanupOldDeletedFolders() error
 }
 
-// StorageFactory creates Drive service instances
+// StorageFactory opens the storage provider for a user
 type StorageFactory func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error)
 
+// TokenSource returns the storage token of a user, for work done outside their requests
+type TokenSource func(userID string) (*oauth2.Token, error)
+
+// JobQueue queues background work that survives restarts (see pkg/jobs)
+type JobQueue interface {
+	Enqueue(ctx context.Context, userID, kind string, payload any) error
+}
+
+// Kinds of the jobs services queue
+const (
+	JobRenameFolder  = "context.rename_folder" // Handled by ContextService.RunFolderJob
+	JobDeleteFolder  = "context.delete_folder"
+	JobRestoreFolder = "context.restore_folder"
+	JobSaveConfig    = "context.save_config"
+	JobImport        = "storage.import" // Handled by AuthService.RunStorageJob
+	JobCleanup       = "storage.cleanup"
+	JobAttachment    = "attachment.upload" // Handled by AttachmentService.RunUploadJob
+	JobDigest        = "digest.send"       // Handled by DigestService.RunDigestJob
+	JobReminder      = "reminder.send"     // Handled by ReminderService.RunReminderJob
+	JobPush          = "push.send"         // Handled by PushService.RunPushJob
+	JobInvitation    = "member.invite"     // Handled by MemberService.RunInvitationJob
+)
+
 // SessionStore defines the interface for session management
 type SessionStore interface {
 	Create(userID, email, name, picture, accessToken, refreshToken string, tokenExpiry time.Time, settings models.UserSettings) (*models.Session, error)
@@ -69,6 +280,77 @@ type SessionStore interface {
 
 // AuthRepository defines the interface for auth-related data access
 type AuthRepository interface {
-	UpsertUser(user *models.User) error
-	GetContexts(userID string) ([]models.Context, error)
+	UpsertUser(ctx context.Context, user *models.User) error
+	GetContexts(ctx context.Context, scope database.UserScope) ([]models.Context, error)
+}
+
+// PaletteRepository defines the data access needed by the command palette
+type PaletteRepository interface {
+	GetContexts(ctx context.Context, scope database.UserScope) ([]models.Context, error)
+	GetAllNotesByUser(ctx context.Context, scope database.UserScope) ([]models.Note, error)
+}
+
+// ProfileRepository defines the data access needed to export and import profiles
+type ProfileRepository interface {
+	GetContexts(ctx context.Context, scope database.UserScope) ([]models.Context, error)
+	GetContextByName(ctx context.Context, scope database.UserScope, name string) (*models.Context, error)
+	CreateContext(ctx context.Context, c *models.Context) error
+	UpdateContext(ctx context.Context, scope database.UserScope, contextID, name, color, icon string) error
+	UpdateContextTemplate(ctx context.Context, scope database.UserScope, contextID, template string) error
+	SetContextLocalOnly(ctx context.Context, scope database.UserScope, contextID string, localOnly bool) error
+	UpdateContextLanguage(ctx context.Context, scope database.UserScope, contextID, language string) error
+	GetVisibleNotes(ctx context.Context, scope database.UserScope, policy visibility.Policy) ([]models.Note, error)
+	UpdateUserSettings(ctx context.Context, userID string, settings models.UserSettings) error
+}
+
+// TimezoneRepository defines the data access for reviewing timezone changes
+type TimezoneRepository interface {
+	RecordTimezoneChange(ctx context.Context, userID, from, to string, at time.Time) error
+	GetTimezoneChange(ctx context.Context, userID string) (*models.TimezoneChange, error)
+	DismissTimezoneChange(ctx context.Context, userID string, id int64, at time.Time) (bool, error)
+	GetDayNotesCreatedSince(ctx context.Context, userID string, since time.Time) ([]models.Note, error)
+	GetNoteDates(ctx context.Context, userID string) ([]string, error)
+}
+
+// ImportRepository defines the data access needed to import notes from an archive
+type ImportRepository interface {
+	GetContextByName(ctx context.Context, scope database.UserScope, name string) (*models.Context, error)
+	CreateContext(ctx context.Context, c *models.Context) error
+	UpsertNote(ctx context.Context, note *models.Note, syncPending bool) error
+}
+
+// LinkPreviewRepository defines the data access for previews of linked web pages
+type LinkPreviewRepository interface {
+	GetLinkPreviews(ctx context.Context, urls []string) ([]models.LinkPreview, error)
+	GetStaleLinkURLs(ctx context.Context, urls []string, fetchedBefore, failedBefore time.Time) ([]string, error)
+	SaveLinkPreview(ctx context.Context, preview models.LinkPreview, fetchErr string) error
+}
+
+// PublicRepository defines the data access for contexts published at /@handle
+// and notes shared by link
+type PublicRepository interface {
+	GetContextByID(ctx context.Context, scope database.UserScope, contextID string) (*models.Context, error)
+	GetNote(ctx context.Context, scope database.UserScope, contextName, date string) (*models.Note, error)
+	GetPublicContext(ctx context.Context, handle string) (*models.PublicContext, error)
+	GetPublicContexts(ctx context.Context, userID string) ([]models.PublicContext, error)
+	PublishContext(ctx context.Context, p *models.PublicContext) error
+	UnpublishContext(ctx context.Context, userID, contextID string) (bool, error)
+	GetPublicNotes(ctx context.Context, userID, contextName string, limit int) ([]models.Note, error)
+	CreateShare(ctx context.Context, share *models.NoteShare) error
+	GetShares(ctx context.Context, userID string) ([]models.NoteShare, error)
+	DeleteShare(ctx context.Context, userID string, id int64) (bool, error)
+	GetSharedNote(ctx context.Context, token string, now time.Time) (*models.Note, error)
+}
+
+// PublishRepository defines the data access for publish targets and note publications
+type PublishRepository interface {
+	CreatePublishTarget(ctx context.Context, t *models.PublishTarget) error
+	GetPublishTargets(ctx context.Context, userID string) ([]models.PublishTarget, error)
+	GetPublishTarget(ctx context.Context, userID, targetID string) (*models.PublishTarget, error)
+	DeletePublishTarget(ctx context.Context, userID, targetID string) (bool, error)
+	QueuePublication(ctx context.Context, p *models.NotePublication, contextName, date string) (bool, error)
+	GetNotePublications(ctx context.Context, userID, contextName, date string) ([]models.NotePublication, error)
+	GetDuePublications(ctx context.Context, now time.Time, limit int) ([]models.NotePublication, error)
+	GetPublicationNote(ctx context.Context, p models.NotePublication) (*models.Note, error)
+	SavePublication(ctx context.Context, p *models.NotePublication, queuedAt time.Time) (bool, error)
 }
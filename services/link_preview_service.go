@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/markdown"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// LinkPreviewTTL is how long a fetched preview is kept before it's fetched again
+	LinkPreviewTTL = 7 * 24 * time.Hour
+	// linkPreviewRetry is how long a failed fetch is remembered before it's tried again
+	linkPreviewRetry = 24 * time.Hour
+	// maxLinkPreviewsPerSave caps the links fetched for one save
+	maxLinkPreviewsPerSave = 20
+	// linkPreviewFetches is how many pages are fetched at the same time
+	linkPreviewFetches = 4
+	// linkPreviewTimeout bounds one background fetch and its writes
+	linkPreviewTimeout = 30 * time.Second
+)
+
+// LinkFetcher reads the preview of a web page (see unfurl.Fetcher)
+type LinkFetcher interface {
+	Fetch(ctx context.Context, url string) (*models.LinkPreview, error)
+}
+
+// LinkPreviewService keeps previews of the web pages linked from notes, so clients
+// can show link cards without fetching other sites themselves. Previews are
+// fetched in the background after saves, when fetching is enabled.
+type LinkPreviewService struct {
+	repo    LinkPreviewRepository
+	fetcher LinkFetcher // nil when fetching is disabled
+	clock   clock.Clock
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+	slots    chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewLinkPreviewService creates a link preview service; previews are only read
+// until SetFetcher enables fetching
+func NewLinkPreviewService(repo LinkPreviewRepository) *LinkPreviewService {
+	return &LinkPreviewService{
+		repo:     repo,
+		clock:    clock.Real(),
+		inFlight: make(map[string]bool),
+		slots:    make(chan struct{}, linkPreviewFetches),
+	}
+}
+
+// SetFetcher enables fetching previews with f
+func (ls *LinkPreviewService) SetFetcher(f LinkFetcher) {
+	ls.fetcher = f
+}
+
+// SetClock replaces the clock used for fetch times
+func (ls *LinkPreviewService) SetClock(c clock.Clock) {
+	ls.clock = c
+}
+
+// Enabled reports whether previews are fetched
+func (ls *LinkPreviewService) Enabled() bool {
+	return ls.fetcher != nil
+}
+
+// ForContent returns the stored previews of the links in note content
+func (ls *LinkPreviewService) ForContent(ctx context.Context, content string) (_ []models.LinkPreview, err error) {
+	defer wrapOp("get link previews", &err)
+	return ls.repo.GetLinkPreviews(ctx, markdown.ExtractLinks(content))
+}
+
+// Queue fetches, in the background, the previews of the links in saved note
+// content that are missing or stale. It does nothing when fetching is disabled.
+func (ls *LinkPreviewService) Queue(content string) {
+	if ls.fetcher == nil {
+		return
+	}
+	urls := markdown.ExtractLinks(content)
+	if len(urls) > maxLinkPreviewsPerSave {
+		urls = urls[:maxLinkPreviewsPerSave]
+	}
+
+	ls.mu.Lock()
+	queued := urls[:0]
+	for _, u := range urls {
+		if !ls.inFlight[u] {
+			ls.inFlight[u] = true
+			queued = append(queued, u)
+		}
+	}
+	ls.mu.Unlock()
+	if len(queued) == 0 {
+		return
+	}
+
+	ls.wg.Add(1)
+	go func() {
+		defer ls.wg.Done()
+		defer ls.release(queued)
+		ls.fetchStale(queued)
+	}()
+}
+
+// Wait blocks until the queued fetches are done
+func (ls *LinkPreviewService) Wait() {
+	ls.wg.Wait()
+}
+
+// fetchStale fetches the previews of urls that are missing or out of date
+func (ls *LinkPreviewService) fetchStale(urls []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), linkPreviewTimeout)
+	defer cancel()
+
+	now := ls.clock.Now().UTC()
+	stale, err := ls.repo.GetStaleLinkURLs(ctx, urls, now.Add(-LinkPreviewTTL), now.Add(-linkPreviewRetry))
+	if err != nil {
+		slog.Warn("failed to check link previews", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, u := range stale {
+		wg.Add(1)
+		ls.slots <- struct{}{}
+		go func(u string) {
+			defer wg.Done()
+			defer func() { <-ls.slots }()
+			ls.fetch(ctx, u)
+		}(u)
+	}
+	wg.Wait()
+}
+
+// fetch stores the preview of a URL, or why it couldn't be fetched
+func (ls *LinkPreviewService) fetch(ctx context.Context, url string) {
+	preview, err := ls.fetcher.Fetch(ctx, url)
+	fetchErr := ""
+	if err != nil {
+		preview = &models.LinkPreview{URL: url}
+		fetchErr = err.Error()
+	}
+	preview.FetchedAt = ls.clock.Now().UTC()
+
+	if err := ls.repo.SaveLinkPreview(ctx, *preview, fetchErr); err != nil {
+		slog.Warn("failed to save link preview", "url", url, "error", err)
+	}
+}
+
+// release lets urls be queued again
+func (ls *LinkPreviewService) release(urls []string) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	for _, u := range urls {
+		delete(ls.inFlight, u)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockLinkPreviewRepository is a mock implementation of LinkPreviewRepository
+type MockLinkPreviewRepository struct {
+	mock.Mock
+}
+
+func (m *MockLinkPreviewRepository) GetLinkPreviews(ctx context.Context, urls []string) ([]models.LinkPreview, error) {
+	args := m.Called(urls)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.LinkPreview), args.Error(1)
+}
+
+func (m *MockLinkPreviewRepository) GetStaleLinkURLs(ctx context.Context, urls []string, fetchedBefore, failedBefore time.Time) ([]string, error) {
+	args := m.Called(urls, fetchedBefore, failedBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockLinkPreviewRepository) SaveLinkPreview(ctx context.Context, preview models.LinkPreview, fetchErr string) error {
+	args := m.Called(preview, fetchErr)
+	return args.Error(0)
+}
+
+// fakeFetcher returns a preview titled after each URL, or an error for the URLs in fail
+type fakeFetcher struct {
+	mu      sync.Mutex
+	fail    map[string]error
+	fetched []string
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, url string) (*models.LinkPreview, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetched = append(f.fetched, url)
+	if err := f.fail[url]; err != nil {
+		return nil, err
+	}
+	return &models.LinkPreview{URL: url, Title: "Title of " + url}, nil
+}
+
+func TestLinkPreviewService_Queue(t *testing.T) {
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	content := "Read https://example.com/a and [b](https://example.com/b), again https://example.com/a"
+
+	t.Run("Fetches stale links and records failures", func(t *testing.T) {
+		repo := new(MockLinkPreviewRepository)
+		repo.On("GetStaleLinkURLs", []string{"https://example.com/a", "https://example.com/b"}, now.Add(-LinkPreviewTTL), now.Add(-linkPreviewRetry)).
+			Return([]string{"https://example.com/a", "https://example.com/b"}, nil)
+		repo.On("SaveLinkPreview", models.LinkPreview{URL: "https://example.com/a", Title: "Title of https://example.com/a", FetchedAt: now}, "").Return(nil)
+		repo.On("SaveLinkPreview", models.LinkPreview{URL: "https://example.com/b", FetchedAt: now}, "page is not HTML").Return(nil)
+
+		fetcher := &fakeFetcher{fail: map[string]error{"https://example.com/b": errors.New("page is not HTML")}}
+		service := NewLinkPreviewService(repo)
+		service.SetClock(clock.NewFake(now))
+		service.SetFetcher(fetcher)
+
+		service.Queue(content)
+		service.Wait()
+
+		repo.AssertExpectations(t)
+		assert.ElementsMatch(t, []string{"https://example.com/a", "https://example.com/b"}, fetcher.fetched)
+	})
+
+	t.Run("Fresh links aren't fetched again", func(t *testing.T) {
+		repo := new(MockLinkPreviewRepository)
+		repo.On("GetStaleLinkURLs", mock.Anything, mock.Anything, mock.Anything).Return([]string{}, nil)
+
+		fetcher := &fakeFetcher{}
+		service := NewLinkPreviewService(repo)
+		service.SetFetcher(fetcher)
+
+		service.Queue(content)
+		service.Wait()
+
+		assert.Empty(t, fetcher.fetched)
+		repo.AssertNotCalled(t, "SaveLinkPreview", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Nothing is fetched unless enabled", func(t *testing.T) {
+		repo := new(MockLinkPreviewRepository)
+		service := NewLinkPreviewService(repo)
+		assert.False(t, service.Enabled())
+
+		service.Queue(content)
+		service.Wait()
+
+		repo.AssertNotCalled(t, "GetStaleLinkURLs", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestLinkPreviewService_ForContent(t *testing.T) {
+	repo := new(MockLinkPreviewRepository)
+	preview := models.LinkPreview{URL: "https://example.com/a", Title: "A"}
+	repo.On("GetLinkPreviews", []string{"https://example.com/a"}).Return([]models.LinkPreview{preview}, nil)
+
+	previews, err := NewLinkPreviewService(repo).ForContent(context.Background(), "See https://example.com/a")
+	require.NoError(t, err)
+	assert.Equal(t, []models.LinkPreview{preview}, previews)
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/jobs"
+	"daily-notes/pkg/mail"
+	"log/slog"
+	"strings"
+)
+
+// invitationJob is the payload of a JobInvitation
+type invitationJob struct {
+	ID int64 `json:"id"`
+}
+
+// MemberService shares contexts with other accounts. The owner invites an
+// email address with a role; the account signed in with that address accepts
+// and from then on reaches the context's notes by its name, reading them, or
+// writing them too with MemberWrite. NoteService enforces the role through
+// GetContextAccess on every note operation.
+type MemberService struct {
+	repo      MemberRepository
+	mailer    Mailer
+	jobs      JobQueue
+	clock     clock.Clock
+	timeouts  Timeouts
+	publicURL string
+}
+
+// NewMemberService creates a member service; invitations aren't emailed until
+// SetMailer and SetJobQueue are called
+func NewMemberService(repo MemberRepository) *MemberService {
+	return &MemberService{repo: repo, clock: clock.Real(), timeouts: DefaultTimeouts}
+}
+
+// SetClock replaces the clock that dates invitations
+func (ms *MemberService) SetClock(c clock.Clock) {
+	ms.clock = c
+}
+
+// SetMailer sets how invitations are emailed and the address of the server
+// they link to (PUBLIC_URL)
+func (ms *MemberService) SetMailer(mailer Mailer, publicURL string) {
+	ms.mailer = mailer
+	ms.publicURL = publicURL
+}
+
+// SetJobQueue sets the queue invitations are emailed through; RunInvitationJob handles JobInvitation
+func (ms *MemberService) SetJobQueue(queue JobQueue) {
+	ms.jobs = queue
+}
+
+// emailEnabled reports whether invitations can be emailed
+func (ms *MemberService) emailEnabled() bool {
+	return ms.mailer != nil && ms.jobs != nil && ms.publicURL != ""
+}
+
+// ownedContext returns a context of the user, failing with ErrContextNotFound
+// for contexts of others, shared with the user or not
+func (ms *MemberService) ownedContext(ctx context.Context, userID, contextID string) (*models.Context, error) {
+	c, err := ms.repo.GetContextByID(ctx, database.ScopeUser(userID), contextID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.UserID != userID {
+		return nil, ErrContextNotFound
+	}
+	return c, nil
+}
+
+// Members lists the members and pending invitations of one of the user's contexts
+func (ms *MemberService) Members(ctx context.Context, userID, contextID string) (_ []models.ContextMember, err error) {
+	defer wrapOp("list context members", &err)
+	ctx, cancel := ms.timeouts.query(ctx)
+	defer cancel()
+
+	if _, err := ms.ownedContext(ctx, userID, contextID); err != nil {
+		return nil, err
+	}
+	return ms.repo.GetContextMembers(ctx, contextID)
+}
+
+// Invite shares one of the user's contexts with the account of an email
+// address, and emails the address when the server sends mail. Inviting a
+// member again changes their role.
+func (ms *MemberService) Invite(ctx context.Context, userID, contextID string, req models.InviteMemberRequest) (_ *models.ContextMember, err error) {
+	defer wrapOp("invite context member", &err)
+	queryCtx, cancel := ms.timeouts.query(ctx)
+	defer cancel()
+
+	if _, err := ms.ownedContext(queryCtx, userID, contextID); err != nil {
+		return nil, err
+	}
+	owner, err := ms.repo.GetUser(queryCtx, userID)
+	if err != nil {
+		return nil, err
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if owner != nil && strings.EqualFold(owner.Email, email) {
+		return nil, ErrInviteSelf
+	}
+
+	member := &models.ContextMember{
+		ContextID: contextID,
+		Email:     email,
+		Role:      req.Role,
+		CreatedAt: ms.clock.Now(),
+	}
+	if err := ms.repo.CreateContextInvitation(queryCtx, member); err != nil {
+		return nil, err
+	}
+
+	// Only new invitations are emailed; the invitation stands if queueing fails
+	if member.AcceptedAt == nil && ms.emailEnabled() {
+		if err := ms.jobs.Enqueue(ctx, userID, JobInvitation, invitationJob{ID: member.ID}); err != nil {
+			slog.Warn("failed to queue invitation email", "user_id", userID, "member_id", member.ID, "error", err)
+		}
+	}
+	return member, nil
+}
+
+// Remove takes a member or pending invitation off one of the user's contexts
+func (ms *MemberService) Remove(ctx context.Context, userID, contextID string, id int64) (err error) {
+	defer wrapOp("remove context member", &err)
+	ctx, cancel := ms.timeouts.query(ctx)
+	defer cancel()
+
+	if _, err := ms.ownedContext(ctx, userID, contextID); err != nil {
+		return err
+	}
+	removed, err := ms.repo.DeleteContextMember(ctx, contextID, id)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// user returns the signed-in user, whose email address invitations are matched against
+func (ms *MemberService) user(ctx context.Context, userID string) (*models.User, error) {
+	user, err := ms.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUnauthorized
+	}
+	return user, nil
+}
+
+// Invitations lists the contexts shared with the user: pending invitations to
+// their email address and the ones they accepted
+func (ms *MemberService) Invitations(ctx context.Context, userID string) (_ []models.ContextInvitation, err error) {
+	defer wrapOp("list invitations", &err)
+	ctx, cancel := ms.timeouts.query(ctx)
+	defer cancel()
+
+	user, err := ms.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return ms.repo.GetContextInvitations(ctx, userID, strings.ToLower(user.Email))
+}
+
+// Accept makes the user a member through an invitation to their email address.
+// It fails with ErrContextAlreadyExists when a context of the same name, their
+// own or shared with them, would hide the shared one.
+func (ms *MemberService) Accept(ctx context.Context, userID string, id int64) (_ *models.ContextInvitation, err error) {
+	defer wrapOp("accept invitation", &err)
+	ctx, cancel := ms.timeouts.query(ctx)
+	defer cancel()
+
+	user, err := ms.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	email := strings.ToLower(user.Email)
+	invitation, err := ms.repo.GetContextInvitation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || invitation.Accepted || invitation.Email != email {
+		return nil, ErrInvitationNotFound
+	}
+
+	access, err := ms.repo.GetContextAccess(ctx, userID, invitation.Context)
+	if err != nil {
+		return nil, err
+	}
+	if access.ContextID != "" {
+		return nil, ErrContextAlreadyExists
+	}
+
+	accepted, err := ms.repo.AcceptContextInvitation(ctx, id, userID, email, ms.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvitationNotFound
+	}
+	invitation.Accepted = true
+	return invitation, nil
+}
+
+// Leave ends the user's membership of a context shared with them, or declines an invitation
+func (ms *MemberService) Leave(ctx context.Context, userID string, id int64) (err error) {
+	defer wrapOp("leave context", &err)
+	ctx, cancel := ms.timeouts.query(ctx)
+	defer cancel()
+
+	user, err := ms.user(ctx, userID)
+	if err != nil {
+		return err
+	}
+	left, err := ms.repo.LeaveContext(ctx, id, userID, strings.ToLower(user.Email))
+	if err != nil {
+		return err
+	}
+	if !left {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// RunInvitationJob emails an invitation queued by Invite
+func (ms *MemberService) RunInvitationJob(ctx context.Context, job *models.Job) (err error) {
+	defer wrapOp("run invitation job", &err)
+	var payload invitationJob
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+	if ms.mailer == nil || ms.publicURL == "" {
+		return jobs.Permanent(ErrInvitationEmailDisabled)
+	}
+
+	queryCtx, cancel := ms.timeouts.query(ctx)
+	defer cancel()
+	invitation, err := ms.repo.GetContextInvitation(queryCtx, payload.ID)
+	if err != nil {
+		return err
+	}
+	// Invitations revoked or accepted in the meantime need no email
+	if invitation == nil || invitation.Accepted {
+		return nil
+	}
+	return ms.mailer.Send(ctx, ms.message(invitation))
+}
+
+// message is the email inviting an address to a context
+func (ms *MemberService) message(invitation *models.ContextInvitation) mail.Message {
+	can := "read"
+	if invitation.Role == models.MemberWrite {
+		can = "read and write"
+	}
+
+	var body strings.Builder
+	body.WriteString(invitation.Owner + " shared their " + invitation.Context + " notes with you on Daily Notes.\n")
+	body.WriteString("You can " + can + " them once you accept.\n\n")
+	body.WriteString("Sign in with this email address to accept:\n")
+	body.WriteString(ms.publicURL + "/\n")
+
+	return mail.Message{
+		To:      mail.Address("", invitation.Email),
+		Subject: invitation.Owner + " shared " + invitation.Context + " with you",
+		Text:    body.String(),
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/mail"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockMemberRepository is a mock implementation of MemberRepository
+type MockMemberRepository struct {
+	mock.Mock
+}
+
+func (m *MockMemberRepository) CreateContextInvitation(ctx context.Context, member *models.ContextMember) error {
+	args := m.Called(member)
+	return args.Error(0)
+}
+
+func (m *MockMemberRepository) GetContextMembers(ctx context.Context, contextID string) ([]models.ContextMember, error) {
+	args := m.Called(contextID)
+	return args.Get(0).([]models.ContextMember), args.Error(1)
+}
+
+func (m *MockMemberRepository) DeleteContextMember(ctx context.Context, contextID string, id int64) (bool, error) {
+	args := m.Called(contextID, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMemberRepository) GetContextInvitation(ctx context.Context, id int64) (*models.ContextInvitation, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ContextInvitation), args.Error(1)
+}
+
+func (m *MockMemberRepository) GetContextInvitations(ctx context.Context, userID, email string) ([]models.ContextInvitation, error) {
+	args := m.Called(userID, email)
+	return args.Get(0).([]models.ContextInvitation), args.Error(1)
+}
+
+func (m *MockMemberRepository) AcceptContextInvitation(ctx context.Context, id int64, userID, email string, at time.Time) (bool, error) {
+	args := m.Called(id, userID, email, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMemberRepository) LeaveContext(ctx context.Context, id int64, userID, email string) (bool, error) {
+	args := m.Called(id, userID, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMemberRepository) GetSharedContexts(ctx context.Context, userID string) ([]models.Context, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Context), args.Error(1)
+}
+
+func (m *MockMemberRepository) GetContextAccess(ctx context.Context, userID, contextName string) (*models.ContextAccess, error) {
+	args := m.Called(userID, contextName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ContextAccess), args.Error(1)
+}
+
+func (m *MockMemberRepository) GetContextByID(ctx context.Context, _ database.UserScope, contextID string) (*models.Context, error) {
+	args := m.Called(contextID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Context), args.Error(1)
+}
+
+func (m *MockMemberRepository) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func TestMemberService_Invite(t *testing.T) {
+	family := &models.Context{ID: "ctx-family", UserID: "owner", Name: "Family"}
+	owner := &models.User{ID: "owner", Email: "Luis@example.com", Name: "Luis"}
+
+	t.Run("Only the owner invites", func(t *testing.T) {
+		repo := new(MockMemberRepository)
+		repo.On("GetContextByID", "ctx-family").Return(family, nil)
+		_, err := NewMemberService(repo).Invite(context.Background(), "member", "ctx-family", models.InviteMemberRequest{Email: "ana@example.com", Role: models.MemberRead})
+		assert.ErrorIs(t, err, ErrContextNotFound)
+	})
+
+	t.Run("Owners can't invite themselves", func(t *testing.T) {
+		repo := new(MockMemberRepository)
+		repo.On("GetContextByID", "ctx-family").Return(family, nil)
+		repo.On("GetUser", "owner").Return(owner, nil)
+		_, err := NewMemberService(repo).Invite(context.Background(), "owner", "ctx-family", models.InviteMemberRequest{Email: "luis@example.com", Role: models.MemberRead})
+		assert.ErrorIs(t, err, ErrInviteSelf)
+	})
+
+	t.Run("New invitations are emailed", func(t *testing.T) {
+		repo := new(MockMemberRepository)
+		queue := new(MockJobQueue)
+		service := NewMemberService(repo)
+		service.SetMailer(new(MockMailer), "https://notes.example.com")
+		service.SetJobQueue(queue)
+
+		repo.On("GetContextByID", "ctx-family").Return(family, nil)
+		repo.On("GetUser", "owner").Return(owner, nil)
+		repo.On("CreateContextInvitation", mock.MatchedBy(func(m *models.ContextMember) bool {
+			return m.ContextID == "ctx-family" && m.Email == "ana@example.com" && m.Role == models.MemberWrite
+		})).Run(func(args mock.Arguments) {
+			args.Get(0).(*models.ContextMember).ID = 7
+		}).Return(nil)
+		queue.On("Enqueue", "owner", JobInvitation, invitationJob{ID: 7}).Return(nil)
+
+		member, err := service.Invite(context.Background(), "owner", "ctx-family", models.InviteMemberRequest{Email: " Ana@Example.com", Role: models.MemberWrite})
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), member.ID)
+		queue.AssertExpectations(t)
+	})
+}
+
+func TestMemberService_Accept(t *testing.T) {
+	member := &models.User{ID: "member", Email: "Ana@example.com", Name: "Ana"}
+	invitation := func() *models.ContextInvitation {
+		return &models.ContextInvitation{ID: 7, ContextID: "ctx-family", Context: "Family", Owner: "Luis", Email: "ana@example.com", Role: models.MemberRead}
+	}
+
+	t.Run("Invitations to other addresses aren't found", func(t *testing.T) {
+		repo := new(MockMemberRepository)
+		repo.On("GetUser", "member").Return(&models.User{ID: "member", Email: "eve@example.com"}, nil)
+		repo.On("GetContextInvitation", int64(7)).Return(invitation(), nil)
+		_, err := NewMemberService(repo).Accept(context.Background(), "member", 7)
+		assert.ErrorIs(t, err, ErrInvitationNotFound)
+	})
+
+	t.Run("A context of the same name is in the way", func(t *testing.T) {
+		repo := new(MockMemberRepository)
+		repo.On("GetUser", "member").Return(member, nil)
+		repo.On("GetContextInvitation", int64(7)).Return(invitation(), nil)
+		repo.On("GetContextAccess", "member", "Family").Return(&models.ContextAccess{OwnerID: "member", ContextID: "ctx-own"}, nil)
+		_, err := NewMemberService(repo).Accept(context.Background(), "member", 7)
+		assert.ErrorIs(t, err, ErrContextAlreadyExists)
+		repo.AssertNotCalled(t, "AcceptContextInvitation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Accepts", func(t *testing.T) {
+		repo := new(MockMemberRepository)
+		repo.On("GetUser", "member").Return(member, nil)
+		repo.On("GetContextInvitation", int64(7)).Return(invitation(), nil)
+		repo.On("GetContextAccess", "member", "Family").Return(&models.ContextAccess{OwnerID: "member"}, nil)
+		repo.On("AcceptContextInvitation", int64(7), "member", "ana@example.com", mock.Anything).Return(true, nil)
+
+		accepted, err := NewMemberService(repo).Accept(context.Background(), "member", 7)
+		require.NoError(t, err)
+		assert.True(t, accepted.Accepted)
+	})
+}
+
+func TestMemberService_RunInvitationJob(t *testing.T) {
+	repo := new(MockMemberRepository)
+	mailer := new(MockMailer)
+	service := NewMemberService(repo)
+	service.SetMailer(mailer, "https://notes.example.com")
+
+	repo.On("GetContextInvitation", int64(7)).Return(&models.ContextInvitation{ID: 7, Context: "Family", Owner: "Luis", Email: "ana@example.com", Role: models.MemberWrite}, nil)
+	mailer.On("Send", mock.Anything).Return(nil)
+
+	require.NoError(t, service.RunInvitationJob(context.Background(), &models.Job{UserID: "owner", Kind: JobInvitation, Payload: `{"id":7}`}))
+	msg := mailer.Calls[0].Arguments.Get(0).(mail.Message)
+	assert.Equal(t, "<ana@example.com>", msg.To)
+	assert.Equal(t, "Luis shared Family with you", msg.Subject)
+	assert.Contains(t, msg.Text, "You can read and write them")
+	assert.Contains(t, msg.Text, "https://notes.example.com/")
+}
+
+func TestNoteService_SharedContexts(t *testing.T) {
+	members := new(MockMemberRepository)
+	members.On("GetContextAccess", "reader", "Family").Return(&models.ContextAccess{OwnerID: "owner", ContextID: "ctx-family", Role: models.MemberRead}, nil)
+	members.On("GetContextAccess", "writer", "Family").Return(&models.ContextAccess{OwnerID: "owner", ContextID: "ctx-family", Role: models.MemberWrite}, nil)
+	members.On("GetContextAccess", "writer", "Work").Return(&models.ContextAccess{OwnerID: "writer", ContextID: "ctx-work"}, nil)
+	members.On("GetContextAccess", "owner", "Family").Return(&models.ContextAccess{OwnerID: "owner", ContextID: "ctx-family"}, nil)
+
+	t.Run("Members read the owner's notes", func(t *testing.T) {
+		repo := new(MockRepository)
+		service := NewNoteService(repo, nil)
+		service.SetMembers(members)
+		repo.On("GetNote", "owner", "Family", "2025-10-16").Return(&models.Note{UserID: "owner", Context: "Family", Content: "Groceries"}, nil)
+
+		note, err := service.Get(context.Background(), "reader", "Family", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, "Groceries", note.Content)
+	})
+
+	t.Run("Read-only members can't write", func(t *testing.T) {
+		repo := new(MockRepository)
+		service := NewNoteService(repo, nil)
+		service.SetMembers(members)
+
+		_, err := service.Upsert(context.Background(), "reader", "Family", "2025-10-16", "Mine now")
+		assert.ErrorIs(t, err, ErrContextReadOnly)
+		assert.ErrorIs(t, service.Delete(context.Background(), "reader", "Family", "2025-10-16"), ErrContextReadOnly)
+		repo.AssertNotCalled(t, "UpsertNote", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Writers save to the owner's notes", func(t *testing.T) {
+		repo := new(MockRepository)
+		syncWorker := new(MockSyncWorker)
+		service := NewNoteService(repo, syncWorker)
+		service.SetMembers(members)
+		repo.On("UpsertNote", mock.MatchedBy(func(n *models.Note) bool { return n.UserID == "owner" }), true).Return(nil)
+		syncWorker.On("SyncNoteImmediate", "owner", "Family", "2025-10-16").Return()
+
+		note, err := service.Upsert(context.Background(), "writer", "Family", "2025-10-16", "Milk")
+		require.NoError(t, err)
+		assert.Equal(t, "owner", note.UserID)
+		syncWorker.AssertExpectations(t)
+	})
+
+	t.Run("Notes don't move between owners", func(t *testing.T) {
+		service := NewNoteService(new(MockRepository), nil)
+		service.SetMembers(members)
+		_, err := service.Transfer(context.Background(), "writer", "Work", "Family", []string{"2025-10-16"}, true, false)
+		assert.ErrorIs(t, err, ErrSharedContextMove)
+	})
+}
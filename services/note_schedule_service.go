@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/maintenance"
+	"daily-notes/pkg/period"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// schedulePollInterval is how often schedules are checked for a local time that has passed
+const schedulePollInterval = time.Minute
+
+// NoteScheduleService creates the daily notes of users who set a schedule: once
+// a day, at the user's local time, each context with a template gets today's
+// note from it unless the note exists. A run missed while the server was down
+// happens when it's back, the same day.
+type NoteScheduleService struct {
+	repo     NoteScheduleRepository
+	notes    *NoteService
+	clock    clock.Clock
+	timeouts Timeouts
+
+	maintenance *maintenance.Mode // No runs while the server is in maintenance; optional
+
+	run      sync.Mutex // One run at a time
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewNoteScheduleService creates a schedule service creating notes through notes
+func NewNoteScheduleService(repo NoteScheduleRepository, notes *NoteService) *NoteScheduleService {
+	return &NoteScheduleService{repo: repo, notes: notes, clock: clock.Real(), timeouts: DefaultTimeouts}
+}
+
+// SetClock replaces the clock that decides when schedules are due
+func (ss *NoteScheduleService) SetClock(c clock.Clock) {
+	ss.clock = c
+}
+
+// Get returns the user's schedule, nil if they have none
+func (ss *NoteScheduleService) Get(ctx context.Context, userID string) (_ *models.NoteSchedule, err error) {
+	defer wrapOp("get note schedule", &err)
+	ctx, cancel := ss.timeouts.query(ctx)
+	defer cancel()
+
+	return ss.repo.GetNoteSchedule(ctx, userID)
+}
+
+// Set creates notes from templates every day at localTime ("07:30") in the
+// user's timezone. A schedule that already ran today waits for tomorrow.
+func (ss *NoteScheduleService) Set(ctx context.Context, userID, localTime string) (_ *models.NoteSchedule, err error) {
+	defer wrapOp("set note schedule", &err)
+	ctx, cancel := ss.timeouts.query(ctx)
+	defer cancel()
+
+	if err := ss.repo.SetNoteSchedule(ctx, userID, localTime); err != nil {
+		return nil, err
+	}
+	return ss.repo.GetNoteSchedule(ctx, userID)
+}
+
+// Delete turns the user's schedule off
+func (ss *NoteScheduleService) Delete(ctx context.Context, userID string) (err error) {
+	defer wrapOp("delete note schedule", &err)
+	ctx, cancel := ss.timeouts.query(ctx)
+	defer cancel()
+
+	deleted, err := ss.repo.DeleteNoteSchedule(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+// SetMaintenance skips the runs due while the server is in maintenance; they
+// happen on the first run after it
+func (ss *NoteScheduleService) SetMaintenance(mode *maintenance.Mode) {
+	ss.maintenance = mode
+}
+
+// Start runs due schedules every schedulePollInterval until Stop
+func (ss *NoteScheduleService) Start() {
+	ss.stopChan = make(chan struct{})
+	ss.done = make(chan struct{})
+
+	go func() {
+		defer close(ss.done)
+		ticker := time.NewTicker(schedulePollInterval)
+		defer ticker.Stop()
+
+		for {
+			ss.RunDue(context.Background())
+			select {
+			case <-ticker.C:
+			case <-ss.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background loop, waiting for the run in progress
+func (ss *NoteScheduleService) Stop() {
+	if ss.stopChan == nil {
+		return
+	}
+	close(ss.stopChan)
+	<-ss.done
+	ss.stopChan = nil
+}
+
+// RunDue creates today's notes for the schedules whose local time has passed
+// and that didn't run today, and returns how many notes it created. A schedule
+// with a failed note is tried again on the next run; notes that exist by then
+// are left alone.
+func (ss *NoteScheduleService) RunDue(ctx context.Context) int {
+	if ss.maintenance.Paused() {
+		return 0
+	}
+	ss.run.Lock()
+	defer ss.run.Unlock()
+
+	schedules, err := ss.repo.GetNoteSchedules(ctx)
+	if err != nil {
+		slog.Warn("failed to get note schedules", "error", err)
+		return 0
+	}
+
+	now := ss.clock.Now()
+	created := 0
+	for _, schedule := range schedules {
+		local := now.In(loadLocation(schedule.Timezone))
+		today := local.Format(period.DateLayout)
+		if schedule.LastRun == today || local.Format("15:04") < schedule.Time {
+			continue
+		}
+
+		n, err := ss.runOne(ctx, schedule.UserID, today)
+		created += n
+		if err != nil {
+			slog.Warn("failed to create scheduled notes", "user_id", schedule.UserID, "date", today, "error", err)
+			continue
+		}
+		if err := ss.repo.SetNoteScheduleRun(ctx, schedule.UserID, today); err != nil {
+			slog.Warn("failed to record note schedule run", "user_id", schedule.UserID, "error", err)
+		}
+	}
+	return created
+}
+
+// runOne creates the note of date in each of the user's contexts with a
+// template, and returns how many it created and the first error
+func (ss *NoteScheduleService) runOne(ctx context.Context, userID, date string) (int, error) {
+	contexts, err := ss.repo.GetContexts(ctx, database.ScopeUser(userID))
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	var firstErr error
+	for _, c := range contexts {
+		if c.Template == "" {
+			continue
+		}
+		_, ok, err := ss.notes.Scaffold(ctx, userID, c.Name, date)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, firstErr
+}
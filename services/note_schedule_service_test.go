@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/notetemplate"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockNoteScheduleRepository is a mock implementation of NoteScheduleRepository
+type MockNoteScheduleRepository struct {
+	mock.Mock
+}
+
+func (m *MockNoteScheduleRepository) GetNoteSchedule(ctx context.Context, userID string) (*models.NoteSchedule, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.NoteSchedule), args.Error(1)
+}
+
+func (m *MockNoteScheduleRepository) GetNoteSchedules(ctx context.Context) ([]models.NoteSchedule, error) {
+	args := m.Called()
+	return args.Get(0).([]models.NoteSchedule), args.Error(1)
+}
+
+func (m *MockNoteScheduleRepository) SetNoteSchedule(ctx context.Context, userID, localTime string) error {
+	args := m.Called(userID, localTime)
+	return args.Error(0)
+}
+
+func (m *MockNoteScheduleRepository) SetNoteScheduleRun(ctx context.Context, userID, date string) error {
+	args := m.Called(userID, date)
+	return args.Error(0)
+}
+
+func (m *MockNoteScheduleRepository) DeleteNoteSchedule(ctx context.Context, userID string) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNoteScheduleRepository) GetContexts(ctx context.Context, scope database.UserScope) ([]models.Context, error) {
+	args := m.Called(scope.UserID())
+	return args.Get(0).([]models.Context), args.Error(1)
+}
+
+func TestNoteScheduleService_RunDue(t *testing.T) {
+	// 06:00 UTC is 08:00 in Madrid
+	now := time.Date(2025, 10, 16, 6, 0, 0, 0, time.UTC)
+	noteRepo := new(MockRepository)
+	notes := NewNoteService(noteRepo, nil)
+	notes.SetTemplateEngine(notetemplate.New(nil))
+	repo := new(MockNoteScheduleRepository)
+	service := NewNoteScheduleService(repo, notes)
+	service.SetClock(clock.NewFake(now))
+
+	repo.On("GetNoteSchedules").Return([]models.NoteSchedule{
+		{UserID: "due", Time: "07:30", Timezone: "Europe/Madrid"},
+		{UserID: "early", Time: "07:00", Timezone: "UTC"},
+		{UserID: "done", Time: "07:30", Timezone: "Europe/Madrid", LastRun: "2025-10-16"},
+	}, nil)
+	repo.On("GetContexts", "due").Return([]models.Context{
+		{Name: "Work", Template: "# Standup"},
+		{Name: "Journal", Template: "# Today"},
+		{Name: "Ideas"},
+	}, nil)
+	repo.On("SetNoteScheduleRun", "due", "2025-10-16").Return(nil)
+
+	noteRepo.On("GetNote", "due", "Work", "2025-10-16").Return(nil, nil)
+	noteRepo.On("GetContextByName", "due", "Work").Return(&models.Context{Name: "Work", Template: "# Standup"}, nil)
+	noteRepo.On("UpsertNoteAtRevision", mock.MatchedBy(func(n *models.Note) bool {
+		return n.Context == "Work" && n.Content == "# Standup"
+	}), 0, true).Return(true, nil)
+	noteRepo.On("GetNote", "due", "Journal", "2025-10-16").Return(&models.Note{Content: "Written early"}, nil)
+
+	assert.Equal(t, 1, service.RunDue(context.Background()))
+	repo.AssertCalled(t, "SetNoteScheduleRun", "due", "2025-10-16")
+	repo.AssertNotCalled(t, "GetContexts", "early")
+	repo.AssertNotCalled(t, "GetContexts", "done")
+	noteRepo.AssertNotCalled(t, "GetNote", "due", "Ideas", mock.Anything)
+	noteRepo.AssertNumberOfCalls(t, "UpsertNoteAtRevision", 1)
+}
+
+func TestNoteScheduleService_DeleteWithoutSchedule(t *testing.T) {
+	repo := new(MockNoteScheduleRepository)
+	service := NewNoteScheduleService(repo, nil)
+	repo.On("DeleteNoteSchedule", "user123").Return(false, nil)
+
+	assert.ErrorIs(t, service.Delete(context.Background(), "user123"), ErrScheduleNotFound)
+}
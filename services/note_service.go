@@ -1,14 +1,50 @@
 package services
 
 import (
+	"context"
+	"daily-notes/database"
 	"daily-notes/models"
+	"daily-notes/pkg/classifier"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/notetemplate"
+	"daily-notes/pkg/period"
+	"daily-notes/pkg/pubsub"
+	"daily-notes/pkg/rendercache"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
+// Weights for the related-notes score on top of lexical similarity
+const (
+	relatedTagWeight  = 0.15
+	relatedLinkWeight = 0.25
+	relatedMinScore   = 0.05
+)
+
 // NoteService handles business logic for notes
 type NoteService struct {
 	repo       NoteRepository
+	members    MemberRepository
+	keys       EncryptionRepository
 	syncWorker SyncWorker
+	templates  *notetemplate.Engine
+	renders    *rendercache.Cache
+	prerenders sync.WaitGroup
+	previews   *LinkPreviewService
+	events     *pubsub.Broker[models.NoteEvent]
+	clock      clock.Clock
+	timeouts   Timeouts
+	sizeLimit  int           // Bytes above which saves get a size warning; 0 disables it
+	trash      time.Duration // How long deleted notes can be restored; 0 keeps no trash
 }
 
 // NewNoteService creates a new note service
@@ -16,110 +52,2182 @@ func NewNoteService(repo NoteRepository, syncWorker SyncWorker) *NoteService {
 	return &NoteService{
 		repo:       repo,
 		syncWorker: syncWorker,
+		clock:      clock.Real(),
+		timeouts:   DefaultTimeouts,
+		trash:      NoteTrashRetention,
+	}
+}
+
+// SetClock replaces the clock used for note timestamps
+func (ns *NoteService) SetClock(c clock.Clock) {
+	ns.clock = c
+}
+
+// SetTimeouts replaces the per-operation deadlines
+func (ns *NoteService) SetTimeouts(t Timeouts) {
+	ns.timeouts = t.WithDefaults()
+}
+
+// SetSizeWarning sets the note size in bytes above which saves return a warning; 0 disables it
+func (ns *NoteService) SetSizeWarning(limit int) {
+	ns.sizeLimit = limit
+}
+
+// SetTrashRetention sets how long deleted notes can be restored; 0 deletes
+// notes for good once storage is in step, keeping no trash
+func (ns *NoteService) SetTrashRetention(d time.Duration) {
+	ns.trash = d
+}
+
+// SetTemplateEngine enables scaffolding new daily notes from their context template
+func (ns *NoteService) SetTemplateEngine(engine *notetemplate.Engine) {
+	ns.templates = engine
+}
+
+// SetRenderCache registers the cache of rendered HTML so saved notes are invalidated
+func (ns *NoteService) SetRenderCache(cache *rendercache.Cache) {
+	ns.renders = cache
+}
+
+// SetLinkPreviews fetches previews of the links in saved notes through links
+func (ns *NoteService) SetLinkPreviews(links *LinkPreviewService) {
+	ns.previews = links
+}
+
+// SetNoteEvents publishes the changes of saved and deleted notes to events,
+// keyed by user ID
+func (ns *NoteService) SetNoteEvents(events *pubsub.Broker[models.NoteEvent]) {
+	ns.events = events
+}
+
+// SetMembers lets the members of shared contexts use their notes (see MemberService)
+func (ns *NoteService) SetMembers(members MemberRepository) {
+	ns.members = members
+}
+
+// SetEncryption makes saves follow each owner's end-to-end encryption setting (see EncryptionService)
+func (ns *NoteService) SetEncryption(keys EncryptionRepository) {
+	ns.keys = keys
+}
+
+// checkContent refuses content the owner's encryption setting doesn't allow:
+// plaintext while their notes are end-to-end encrypted, and envelopes that are
+// malformed or sealed with a key other than theirs. Empty content always passes.
+func (ns *NoteService) checkContent(ctx context.Context, ownerID, content string) error {
+	encrypted := e2ee.IsEncrypted(content)
+	if ns.keys == nil && !encrypted {
+		return nil
+	}
+
+	var envelope *e2ee.Envelope
+	if encrypted {
+		var err error
+		if envelope, err = e2ee.Parse(content); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidEncryptedNote, err)
+		}
+	}
+	if ns.keys == nil {
+		return nil
+	}
+
+	key, err := ns.keys.GetEncryptionKey(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case key == nil && encrypted:
+		return ErrEncryptionNotEnabled
+	case key == nil:
+		return nil
+	case !encrypted && strings.TrimSpace(content) != "":
+		return ErrPlaintextNote
+	case encrypted && (base64.StdEncoding.EncodeToString(envelope.Salt) != key.Salt || envelope.Iterations != key.Iterations):
+		return ErrEncryptionKeyMismatch
+	}
+	return nil
+}
+
+// owner returns whose notes contextName leads userID to: the owner's for a
+// context shared with the user, otherwise the user's own. Writing to a context
+// shared read-only fails with ErrContextReadOnly.
+func (ns *NoteService) owner(ctx context.Context, userID, contextName string, write bool) (string, error) {
+	if ns.members == nil {
+		return userID, nil
+	}
+	access, err := ns.members.GetContextAccess(ctx, userID, contextName)
+	if err != nil {
+		return "", err
+	}
+	if write && access.Role == models.MemberRead {
+		return "", ErrContextReadOnly
+	}
+	return access.OwnerID, nil
+}
+
+// sameOwner returns the owner of two contexts the user writes to, failing with
+// ErrSharedContextMove when their notes belong to different users
+func (ns *NoteService) sameOwner(ctx context.Context, userID, from, to string) (string, error) {
+	owner, err := ns.owner(ctx, userID, from, true)
+	if err != nil {
+		return "", err
+	}
+	toOwner, err := ns.owner(ctx, userID, to, true)
+	if err != nil {
+		return "", err
+	}
+	if owner != toOwner {
+		return "", ErrSharedContextMove
+	}
+	return owner, nil
+}
+
+// publishNote tells the user's open clients that a note changed
+func (ns *NoteService) publishNote(eventType string, note *models.Note) {
+	if ns.events != nil {
+		ns.events.Publish(note.UserID, models.NoteEvent{
+			Type:    eventType,
+			NoteID:  note.ID,
+			Context: note.Context,
+			Date:    note.Date,
+			Source:  models.NoteSourceAPI,
+			At:      ns.clock.Now(),
+		})
+	}
+}
+
+// publishDeleted tells the user's open clients that a note is gone
+func (ns *NoteService) publishDeleted(userID, contextName, date string) {
+	ns.publishNote(models.NoteEventDeleted, &models.Note{
+		ID:      fmt.Sprintf("%s-%s-%s", userID, contextName, date),
+		UserID:  userID,
+		Context: contextName,
+		Date:    date,
+	})
+}
+
+// queueLinkPreviews fetches previews of the links in saved content in the background
+func (ns *NoteService) queueLinkPreviews(content string) {
+	if ns.previews != nil {
+		ns.previews.Queue(content)
+	}
+}
+
+// autosaveKey is the context key marking the saves of an autosave
+type autosaveKey struct{}
+
+// AutosaveKey is where handlers mark a request as an autosave, in the request
+// context (c.Locals), so the saves it makes sync once the autosaves pause
+var AutosaveKey = autosaveKey{}
+
+// syncSaved queues the sync of a saved note: right away, or once the autosaves
+// of the note pause when ctx is marked with AutosaveKey
+func (ns *NoteService) syncSaved(ctx context.Context, userID, contextName, date string) {
+	if autosave, _ := ctx.Value(AutosaveKey).(bool); autosave {
+		ns.syncWorker.SyncNoteDebounced(userID, contextName, date)
+		return
+	}
+	ns.syncWorker.SyncNoteImmediate(userID, contextName, date)
+}
+
+// invalidateRender drops the cached HTML of a note after it changed. Notes
+// with live share links are rendered again in the background, so their public
+// page is served from the cache.
+func (ns *NoteService) invalidateRender(noteID string) {
+	if ns.renders == nil {
+		return
+	}
+	ns.renders.Invalidate(noteID)
+
+	ns.prerenders.Add(1)
+	go func() {
+		defer ns.prerenders.Done()
+		ns.prerenderShared(noteID)
+	}()
+}
+
+// prerenderShared caches the HTML of a note when one of its share links is live
+func (ns *NoteService) prerenderShared(noteID string) {
+	ctx, cancel := ns.timeouts.query(context.Background())
+	defer cancel()
+
+	note, err := ns.repo.GetLiveSharedNote(ctx, noteID, ns.clock.Now().UTC())
+	if err != nil {
+		slog.Warn("failed to check share links of note", "note_id", noteID, "error", err)
+		return
 	}
+	if note == nil {
+		return
+	}
+	if _, err := ns.render(note); err != nil {
+		slog.Warn("failed to render shared note", "note_id", noteID, "error", err)
+	}
+}
+
+// WaitPrerenders blocks until the background renders of shared notes are done
+func (ns *NoteService) WaitPrerenders() {
+	ns.prerenders.Wait()
 }
 
 // Get retrieves a note for a specific context and date
-func (ns *NoteService) Get(userID, contextName, date string) (*models.Note, error) {
-	note, err := ns.repo.GetNote(userID, contextName, date)
+func (ns *NoteService) Get(ctx context.Context, userID, contextName, date string) (_ *models.Note, err error) {
+	defer wrapOp("get note", &err)
+	if err := authorizeToken(ctx, contextName, models.TokenRead); err != nil {
+		return nil, err
+	}
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, false); err != nil {
+		return nil, err
+	}
+	note, err := ns.repo.GetNote(ctx, database.ScopeUser(userID), contextName, date)
 	if err != nil {
 		return nil, err
 	}
 
 	// If note doesn't exist, return empty note structure
 	if note == nil {
+		content, err := ns.scaffold(ctx, userID, contextName, date)
+		if err != nil {
+			return nil, err
+		}
 		return &models.Note{
 			UserID:  userID,
 			Context: contextName,
 			Date:    date,
-			Content: "",
+			Type:    period.Kind(date),
+			Content: content,
+			Tags:    markdown.ExtractHashtags(content),
 		}, nil
 	}
 
 	return note, nil
 }
 
+// scaffold renders the context template for a daily note that doesn't exist yet
+// The result is only a starting point; nothing is saved until the user edits the note
+func (ns *NoteService) scaffold(ctx context.Context, userID, contextName, date string) (string, error) {
+	if ns.templates == nil || period.Kind(date) != period.Day {
+		return "", nil
+	}
+
+	c, err := ns.repo.GetContextByName(ctx, database.ScopeUser(userID), contextName)
+	if err != nil {
+		return "", err
+	}
+	if c == nil || c.Template == "" {
+		return "", nil
+	}
+
+	noteDate, err := time.Parse(period.DateLayout, date)
+	if err != nil {
+		return "", nil
+	}
+
+	return ns.templates.Render(ctx, c.Template, notetemplate.Vars{
+		Date:    noteDate,
+		Context: contextName,
+		Previous: func(ctx context.Context, ref string) (*notetemplate.PreviousNote, error) {
+			return ns.previousNote(ctx, userID, contextName, noteDate, ref)
+		},
+	}), nil
+}
+
+// Scaffold saves the daily note of a context from its template if the note
+// doesn't exist yet, and reports whether it did. Contexts without a template
+// get no note.
+func (ns *NoteService) Scaffold(ctx context.Context, userID, contextName, date string) (_ *models.Note, created bool, err error) {
+	defer wrapOp("scaffold note", &err)
+	queryCtx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(queryCtx, userID, contextName, true); err != nil {
+		return nil, false, err
+	}
+	existing, err := ns.repo.GetNote(queryCtx, database.ScopeUser(userID), contextName, date)
+	if err != nil || existing != nil {
+		return existing, false, err
+	}
+	content, err := ns.scaffold(queryCtx, userID, contextName, date)
+	if err != nil || content == "" {
+		return nil, false, err
+	}
+
+	// Revision 0 only saves if the note still doesn't exist
+	note, err := ns.UpsertAtRevision(ctx, userID, contextName, date, content, 0)
+	if errors.Is(err, ErrRevisionConflict) {
+		return note, false, nil
+	}
+	// Templates are plaintext; clients start the notes of encrypted accounts themselves
+	if errors.Is(err, ErrPlaintextNote) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return note, true, nil
+}
+
+// previousNote loads the daily note a template lookback refers to: yesterday's,
+// or the latest one before date. It returns nil when there is no such note.
+func (ns *NoteService) previousNote(ctx context.Context, userID, contextName string, date time.Time, ref string) (*notetemplate.PreviousNote, error) {
+	key := date.AddDate(0, 0, -1).Format(period.DateLayout)
+	if ref == notetemplate.LastNote {
+		previous, _, err := ns.repo.GetAdjacentNoteDates(ctx, database.ScopeUser(userID), contextName, date.Format(period.DateLayout))
+		if err != nil || previous == "" {
+			return nil, err
+		}
+		key = previous
+	}
+
+	note, err := ns.repo.GetNote(ctx, database.ScopeUser(userID), contextName, key)
+	if err != nil || note == nil {
+		return nil, err
+	}
+	noteDate, err := time.Parse(period.DateLayout, key)
+	if err != nil {
+		return nil, err
+	}
+	return &notetemplate.PreviousNote{Date: noteDate, Content: note.Content}, nil
+}
+
 // Upsert creates or updates a note
-func (ns *NoteService) Upsert(userID, contextName, date, content string) (*models.Note, error) {
+func (ns *NoteService) Upsert(ctx context.Context, userID, contextName, date, content string) (_ *models.Note, err error) {
+	defer wrapOp("save note", &err)
+	if err := authorizeToken(ctx, contextName, models.TokenWrite); err != nil {
+		return nil, err
+	}
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, true); err != nil {
+		return nil, err
+	}
+	if err := ns.checkContent(ctx, userID, content); err != nil {
+		return nil, err
+	}
 	note := &models.Note{
 		UserID:    userID,
 		Context:   contextName,
 		Date:      date,
 		Content:   content,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: ns.clock.Now(),
+		UpdatedAt: ns.clock.Now(),
 	}
 
 	// Save to local database immediately (fast response)
 	// Mark for sync with Drive (sync_pending = true)
-	if err := ns.repo.UpsertNote(note, true); err != nil {
+	if err := ns.repo.UpsertNote(ctx, note, true); err != nil {
 		return nil, err
 	}
+	ns.invalidateRender(note.ID)
+	ns.publishNote(models.NoteEventUpdated, note)
+	ns.queueLinkPreviews(note.Content)
 
-	// Trigger immediate sync in background (non-blocking)
-	if ns.syncWorker != nil {
-		ns.syncWorker.SyncNoteImmediate(userID, contextName, date)
+	// Trigger sync in background (non-blocking); local-only notes never sync
+	if ns.syncWorker != nil && !note.LocalOnly {
+		ns.syncSaved(ctx, userID, contextName, date)
 	}
 
 	return note, nil
 }
 
-// Delete marks a note as deleted
-func (ns *NoteService) Delete(userID, contextName, date string) error {
-	// Mark note as deleted (will be synced by background worker)
-	return ns.repo.DeleteNote(userID, contextName, date)
+// UpsertBatch saves several notes in one transaction, so either all of them are
+// saved or none is, and queues them for a single sync. A note may only be listed once.
+func (ns *NoteService) UpsertBatch(ctx context.Context, userID string, items []models.BatchNoteItem) (_ []models.Note, err error) {
+	defer wrapOp("save notes", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	notes := make([]*models.Note, 0, len(items))
+	listed := make(map[string]bool, len(items))
+	for _, item := range items {
+		key := item.Context + "/" + item.Date
+		if listed[key] {
+			return nil, ErrDuplicateNote
+		}
+		listed[key] = true
+
+		owner, err := ns.owner(ctx, userID, item.Context, true)
+		if err != nil {
+			return nil, err
+		}
+		if err := ns.checkContent(ctx, owner, item.Content); err != nil {
+			return nil, err
+		}
+		notes = append(notes, &models.Note{
+			UserID:    owner,
+			Context:   item.Context,
+			Date:      item.Date,
+			Content:   item.Content,
+			CreatedAt: ns.clock.Now(),
+			UpdatedAt: ns.clock.Now(),
+		})
+	}
+
+	if err := ns.repo.UpsertNotes(ctx, notes, true); err != nil {
+		return nil, err
+	}
+
+	saved := make([]models.Note, len(notes))
+	toSync := make(map[string][]models.Note) // Notes of shared contexts sync to their owner's storage
+	for i, note := range notes {
+		ns.invalidateRender(note.ID)
+		ns.publishNote(models.NoteEventUpdated, note)
+		ns.queueLinkPreviews(note.Content)
+		saved[i] = *note
+		if !note.LocalOnly {
+			toSync[note.UserID] = append(toSync[note.UserID], *note)
+		}
+	}
+
+	if ns.syncWorker != nil {
+		for owner, notes := range toSync {
+			ns.syncWorker.SyncNotesImmediate(owner, notes)
+		}
+	}
+
+	return saved, nil
 }
 
-// ListByContext retrieves all notes for a specific context with pagination
-func (ns *NoteService) ListByContext(userID, contextName string, limit, offset int) ([]models.Note, error) {
-	// Validate and normalize pagination params
-	if limit < 1 || limit > 100 {
-		limit = 30
+// UpsertAtRevision saves a note only if it is still at baseRevision
+// On mismatch it returns the current note together with ErrRevisionConflict,
+// so the client can offer to reload or merge instead of overwriting
+func (ns *NoteService) UpsertAtRevision(ctx context.Context, userID, contextName, date, content string, baseRevision int) (_ *models.Note, err error) {
+	defer wrapOp("save note", &err)
+	if err := authorizeToken(ctx, contextName, models.TokenWrite); err != nil {
+		return nil, err
 	}
-	if offset < 0 {
-		offset = 0
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, true); err != nil {
+		return nil, err
+	}
+	if err := ns.checkContent(ctx, userID, content); err != nil {
+		return nil, err
+	}
+	note := &models.Note{
+		UserID:    userID,
+		Context:   contextName,
+		Date:      date,
+		Content:   content,
+		CreatedAt: ns.clock.Now(),
+		UpdatedAt: ns.clock.Now(),
+	}
+
+	saved, err := ns.repo.UpsertNoteAtRevision(ctx, note, baseRevision, true)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		current, err := ns.Get(ctx, userID, contextName, date)
+		if err != nil {
+			return nil, err
+		}
+		return current, ErrRevisionConflict
+	}
+	ns.invalidateRender(note.ID)
+	ns.publishNote(models.NoteEventUpdated, note)
+	ns.queueLinkPreviews(note.Content)
+
+	if ns.syncWorker != nil && !note.LocalOnly {
+		ns.syncSaved(ctx, userID, contextName, date)
 	}
 
-	return ns.repo.GetNotesByContext(userID, contextName, limit, offset)
+	return note, nil
 }
 
-// GetSyncStatus returns sync status information for the user
-func (ns *NoteService) GetSyncStatus(userID string) (map[string]interface{}, error) {
-	// Get failed sync notes (up to 50)
-	failedNotes, err := ns.repo.GetFailedSyncNotes(userID, 50)
+// UpsertUnmodifiedSince saves a note unless it was updated after since, the
+// precondition of an If-Unmodified-Since header; like UpsertAtRevision it returns
+// the current note with ErrRevisionConflict when it was. Times compare to the
+// second, as HTTP dates have no finer precision.
+func (ns *NoteService) UpsertUnmodifiedSince(ctx context.Context, userID, contextName, date, content string, since time.Time) (*models.Note, error) {
+	revision, current, err := ns.revisionUnmodifiedSince(ctx, userID, contextName, date, since)
 	if err != nil {
+		return current, err
+	}
+	// Saving at the revision read keeps a save made meanwhile from being overwritten
+	return ns.UpsertAtRevision(ctx, userID, contextName, date, content, revision)
+}
+
+// revisionUnmodifiedSince returns the revision of a note that wasn't updated
+// after since, 0 when there is no note yet
+func (ns *NoteService) revisionUnmodifiedSince(ctx context.Context, userID, contextName, date string, since time.Time) (_ int, _ *models.Note, err error) {
+	defer wrapOp("save note", &err)
+	if err := authorizeToken(ctx, contextName, models.TokenWrite); err != nil {
+		return 0, nil, err
+	}
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, true); err != nil {
+		return 0, nil, err
+	}
+	current, err := ns.repo.GetNote(ctx, database.ScopeUser(userID), contextName, date)
+	if err != nil || current == nil {
+		return 0, nil, err
+	}
+	if current.UpdatedAt.Truncate(time.Second).After(since) {
+		return 0, current, ErrRevisionConflict
+	}
+	return current.Revision, nil, nil
+}
+
+// CheckSave runs the checks saving req would, without saving it: the token's
+// scope, the user's role in the context, which must exist, and whether the
+// owner's encryption setting allows the content. It returns the current note
+// like Get; maintenance journals saves that pass to replay them later.
+func (ns *NoteService) CheckSave(ctx context.Context, userID string, req models.CreateNoteRequest) (_ *models.Note, err error) {
+	defer wrapOp("check note save", &err)
+	operation := models.TokenWrite
+	if req.Append {
+		operation = models.TokenAppend
+	}
+	if err := authorizeToken(ctx, req.Context, operation); err != nil {
 		return nil, err
 	}
+	queryCtx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
 
-	// Get pending sync notes count
-	pendingNotes, err := ns.repo.GetPendingSyncNotes(50)
+	owner, err := ns.owner(queryCtx, userID, req.Context, true)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ns.repo.GetContextByName(queryCtx, database.ScopeUser(owner), req.Context)
 	if err != nil {
 		return nil, err
 	}
+	if c == nil {
+		return nil, ErrContextNotFound
+	}
 
-	// Count only this user's pending notes
-	userPendingCount := 0
-	for _, note := range pendingNotes {
-		if note.UserID == userID {
-			userPendingCount++
+	current, err := ns.Get(ctx, userID, req.Context, req.Date)
+	if err != nil {
+		return nil, err
+	}
+	content := req.Content
+	if req.Append {
+		if current.Encrypted {
+			return nil, ErrNoteEncrypted
+		}
+		content = markdown.AppendToSection(current.Content, req.Section, req.Content)
+	}
+	if err := ns.checkContent(queryCtx, owner, content); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// maxEditAttempts bounds how often a partial edit re-reads a note that changed under it
+const maxEditAttempts = 3
+
+// Append adds content under the markdown heading section of a note instead of
+// replacing it; an empty section appends to the end of the note. Missing notes
+// start from the context template and missing sections are created.
+// With a baseRevision the append fails with ErrRevisionConflict like UpsertAtRevision;
+// without one, concurrent edits are merged by re-reading the note and appending again.
+func (ns *NoteService) Append(ctx context.Context, userID, contextName, date, content, section string, baseRevision *int) (_ *models.Note, err error) {
+	defer wrapOp("append to note", &err)
+	if err := authorizeToken(ctx, contextName, models.TokenAppend); err != nil {
+		return nil, err
+	}
+	return ns.edit(ctx, userID, contextName, date, baseRevision, func(current string) (string, error) {
+		return markdown.AppendToSection(current, section, content), nil
+	})
+}
+
+// ResolveAppend turns a quick append into the append it makes, as of now in
+// loc: to today's note when it names no date, and with the entry starting with
+// the local time ("14:05") when it asks for a timestamp
+func (ns *NoteService) ResolveAppend(req models.AppendNoteRequest, loc *time.Location) models.CreateNoteRequest {
+	now := ns.clock.Now().In(loc)
+	date := req.Date
+	if date == "" {
+		date = now.Format("2006-01-02")
+	}
+	content := req.Content
+	if req.Timestamp {
+		content = markdown.PrefixEntry(content, now.Format("15:04"))
+	}
+	return models.CreateNoteRequest{
+		Context:  req.Context,
+		Date:     date,
+		Content:  content,
+		Revision: req.Revision,
+		Append:   true,
+		Section:  req.Section,
+	}
+}
+
+// Sections lists the markdown headings of a note with the text under each
+func (ns *NoteService) Sections(ctx context.Context, userID, contextName, date string) (_ *models.Note, _ []models.NoteSection, err error) {
+	defer wrapOp("get note sections", &err)
+	note, err := ns.Get(ctx, userID, contextName, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	return note, noteSections(note.Content), nil
+}
+
+// UpdateSection replaces the text under one heading of a note, leaving the rest untouched
+// Conflicts are handled like Append. Returns ErrSectionNotFound if no heading has the slug.
+func (ns *NoteService) UpdateSection(ctx context.Context, userID, contextName, date, slug, content string, baseRevision *int) (_ *models.Note, _ *models.NoteSection, err error) {
+	defer wrapOp("update note section", &err)
+	note, err := ns.edit(ctx, userID, contextName, date, baseRevision, func(current string) (string, error) {
+		updated, ok := markdown.ReplaceSection(current, slug, content)
+		if !ok {
+			return "", ErrSectionNotFound
 		}
+		return updated, nil
+	})
+	if err != nil {
+		return note, nil, err
 	}
 
-	return map[string]interface{}{
-		"pending_count": userPendingCount,
-		"failed_count":  len(failedNotes),
-		"failed_notes":  failedNotes,
-	}, nil
+	for _, section := range noteSections(note.Content) {
+		if section.Slug == slug {
+			return note, &section, nil
+		}
+	}
+	return note, nil, nil
 }
 
-// RetrySync retries synchronization for a failed note
-func (ns *NoteService) RetrySync(noteID, userID string) error {
-	// Verify the note belongs to this user by parsing the note ID
-	// Note IDs follow the format: userID-context-date
-	if len(noteID) < len(userID)+2 || noteID[:len(userID)+1] != userID+"-" {
-		return ErrUnauthorized
+// edit applies change to the current content of a note and saves the result
+// Without a baseRevision, a note that changed between the read and the write is
+// re-read and change applied again, up to maxEditAttempts times. Encrypted
+// notes fail with ErrNoteEncrypted, as the server can't read them.
+func (ns *NoteService) edit(ctx context.Context, userID, contextName, date string, baseRevision *int, change func(content string) (string, error)) (*models.Note, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := ns.Get(ctx, userID, contextName, date)
+		if err != nil {
+			return nil, err
+		}
+		if baseRevision != nil && *baseRevision != current.Revision {
+			return current, ErrRevisionConflict
+		}
+		if current.Encrypted {
+			return nil, ErrNoteEncrypted
+		}
+
+		content, err := change(current.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		note, err := ns.UpsertAtRevision(ctx, userID, contextName, date, content, current.Revision)
+		if errors.Is(err, ErrRevisionConflict) && baseRevision == nil && attempt < maxEditAttempts {
+			continue
+		}
+		return note, err
 	}
+}
 
-	// Reset the note's sync status to retry
-	return ns.repo.RetrySyncNote(noteID)
+// noteSections converts the headings of content into API sections
+func noteSections(content string) []models.NoteSection {
+	lines := strings.Split(content, "\n")
+	parsed := markdown.Sections(content)
+
+	sections := make([]models.NoteSection, 0, len(parsed))
+	for _, s := range parsed {
+		sections = append(sections, models.NoteSection{
+			Slug:    s.Slug,
+			Title:   s.Title,
+			Level:   s.Level,
+			Line:    s.Start + 1,
+			Content: strings.Trim(strings.Join(lines[s.Start+1:s.BodyEnd], "\n"), "\n"),
+		})
+	}
+	return sections
+}
+
+// GetPeriod retrieves a week, month or year note together with a rollup linking
+// the finer notes it covers (days of a week, weeks of a month, months of a year)
+func (ns *NoteService) GetPeriod(ctx context.Context, userID, contextName, noteType, key string) (_ *models.Note, _ []models.NoteLink, err error) {
+	defer wrapOp("get period note", &err)
+	if noteType == period.Day || period.Kind(key) != noteType {
+		return nil, nil, ErrInvalidPeriodKey
+	}
+
+	note, err := ns.Get(ctx, userID, contextName, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	children, err := period.Children(key)
+	if err != nil {
+		return nil, nil, ErrInvalidPeriodKey
+	}
+
+	rollup, err := ns.links(ctx, userID, contextName, children)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return note, rollup, nil
+}
+
+// Month prefetch options and payload guards
+const (
+	MonthIncludePreview = "preview"
+	MonthIncludeContent = "content"
+
+	monthPreviewLength = 160
+	// monthContentBudget caps the total content bytes of a month response;
+	// notes past the budget fall back to a preview and are marked truncated
+	monthContentBudget = 512 * 1024
+)
+
+// Month returns the existing daily notes of a context for a calendar month in a
+// single query, with previews or full content. truncated reports whether any
+// note's content was left out to respect the payload budget.
+func (ns *NoteService) Month(ctx context.Context, userID, contextName string, year, month int, include string) (notes []models.MonthNote, truncated bool, err error) {
+	defer wrapOp("list month notes", &err)
+	if include == "" {
+		include = MonthIncludePreview
+	}
+	if include != MonthIncludePreview && include != MonthIncludeContent {
+		return nil, false, ErrInvalidInclude
+	}
+	if year < 1 || year > 9999 || month < 1 || month > 12 {
+		return nil, false, ErrInvalidMonth
+	}
+
+	days, err := period.Days(fmt.Sprintf("%04d-%02d", year, month))
+	if err != nil {
+		return nil, false, ErrInvalidMonth
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, false); err != nil {
+		return nil, false, err
+	}
+	found, err := ns.repo.GetNotesByKeys(ctx, database.ScopeUser(userID), contextName, days)
+	if err != nil {
+		return nil, false, err
+	}
+
+	notes = make([]models.MonthNote, 0, len(found))
+	budget := monthContentBudget
+	for _, note := range found {
+		entry := models.MonthNote{
+			Date:      note.Date,
+			Size:      len(note.Content),
+			Encrypted: note.Encrypted,
+			UpdatedAt: note.UpdatedAt,
+		}
+		if include == MonthIncludeContent && len(note.Content) <= budget {
+			entry.Content = note.Content
+			budget -= len(note.Content)
+		} else {
+			// Envelopes have nothing to preview; clients decrypt the content
+			if !note.Encrypted {
+				entry.Preview = markdown.Excerpt(note.Content, monthPreviewLength)
+			}
+			if include == MonthIncludeContent {
+				entry.Truncated = true
+				truncated = true
+			}
+		}
+		notes = append(notes, entry)
+	}
+
+	return notes, truncated, nil
+}
+
+// MonthNotes returns the daily notes of several contexts for a calendar month by
+// context name, with full content. The notes of contexts owned by the same user,
+// the user's own or those shared with them, are loaded in a single query.
+func (ns *NoteService) MonthNotes(ctx context.Context, userID string, contextNames []string, year, month int) (_ map[string][]models.Note, err error) {
+	defer wrapOp("list month notes", &err)
+	if year < 1 || year > 9999 || month < 1 || month > 12 {
+		return nil, ErrInvalidMonth
+	}
+	days, err := period.Days(fmt.Sprintf("%04d-%02d", year, month))
+	if err != nil {
+		return nil, ErrInvalidMonth
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	byOwner := make(map[string][]string)
+	for _, name := range contextNames {
+		if err := authorizeToken(ctx, name, models.TokenRead); err != nil {
+			return nil, err
+		}
+		owner, err := ns.owner(ctx, userID, name, false)
+		if err != nil {
+			return nil, err
+		}
+		byOwner[owner] = append(byOwner[owner], name)
+	}
+
+	notes := make(map[string][]models.Note, len(contextNames))
+	for _, name := range contextNames {
+		notes[name] = []models.Note{}
+	}
+	for owner, names := range byOwner {
+		found, err := ns.repo.GetMonthNotes(ctx, database.ScopeUser(owner), names, days)
+		if err != nil {
+			return nil, err
+		}
+		for _, note := range found {
+			notes[note.Context] = append(notes[note.Context], note)
+		}
+	}
+	return notes, nil
+}
+
+// Calendar returns every day of a calendar month in a context, in order, with
+// whether it has a note and that note's word count and sync status, so calendar
+// views don't load the notes one by one
+func (ns *NoteService) Calendar(ctx context.Context, userID, contextName string, year, month int) (_ []models.CalendarDay, err error) {
+	defer wrapOp("get calendar", &err)
+	if err := authorizeToken(ctx, contextName, models.TokenRead); err != nil {
+		return nil, err
+	}
+	if year < 1 || year > 9999 || month < 1 || month > 12 {
+		return nil, ErrInvalidMonth
+	}
+	days, err := period.Days(fmt.Sprintf("%04d-%02d", year, month))
+	if err != nil {
+		return nil, ErrInvalidMonth
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, false); err != nil {
+		return nil, err
+	}
+	found, err := ns.repo.GetCalendarDays(ctx, database.ScopeUser(userID), contextName, days[0], days[len(days)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]models.CalendarDay, len(found))
+	for _, day := range found {
+		byDate[day.Date] = day
+	}
+	calendar := make([]models.CalendarDay, len(days))
+	for i, date := range days {
+		calendar[i] = models.CalendarDay{Date: date}
+		if day, ok := byDate[date]; ok {
+			calendar[i] = day
+		}
+	}
+	return calendar, nil
+}
+
+// Split moves lines startLine..endLine (1-based, inclusive) of a note to the end
+// of the note for toContext and toDate, creating that note if needed. Both notes
+// are saved in one transaction and queued for sync. When baseRevision is set it
+// must match the source note. On a concurrent change ErrRevisionConflict is
+// returned together with the current source note.
+func (ns *NoteService) Split(ctx context.Context, userID, contextName, date string, startLine, endLine int, toContext, toDate string, baseRevision *int) (source, target *models.Note, err error) {
+	defer wrapOp("split note", &err)
+	if contextName == toContext && date == toDate {
+		return nil, nil, ErrSameNote
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.sameOwner(ctx, userID, contextName, toContext); err != nil {
+		return nil, nil, err
+	}
+	current, err := ns.repo.GetNote(ctx, database.ScopeUser(userID), contextName, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	if current == nil {
+		return nil, nil, ErrNoteNotFound
+	}
+	if baseRevision != nil && *baseRevision != current.Revision {
+		return current, nil, ErrRevisionConflict
+	}
+	if current.Encrypted {
+		return nil, nil, ErrNoteEncrypted
+	}
+
+	lines := strings.Split(current.Content, "\n")
+	if startLine < 1 || endLine < startLine || endLine > len(lines) {
+		return nil, nil, ErrInvalidLineRange
+	}
+	selection := strings.Join(lines[startLine-1:endLine], "\n")
+	remaining := append(append([]string{}, lines[:startLine-1]...), lines[endLine:]...)
+
+	if toContext != contextName {
+		c, err := ns.repo.GetContextByName(ctx, database.ScopeUser(userID), toContext)
+		if err != nil {
+			return nil, nil, err
+		}
+		if c == nil {
+			return nil, nil, ErrContextNotFound
+		}
+	}
+
+	existing, err := ns.repo.GetNote(ctx, database.ScopeUser(userID), toContext, toDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := ns.clock.Now()
+	source = &models.Note{
+		ID:        current.ID,
+		UserID:    userID,
+		Context:   contextName,
+		Date:      date,
+		Type:      current.Type,
+		Content:   strings.Join(remaining, "\n"),
+		CreatedAt: current.CreatedAt,
+		UpdatedAt: now,
+	}
+	target = &models.Note{
+		UserID:    userID,
+		Context:   toContext,
+		Date:      toDate,
+		Content:   selection,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	targetRevision := 0
+	if existing != nil {
+		targetRevision = existing.Revision
+		target.ID = existing.ID
+		target.CreatedAt = existing.CreatedAt
+		if strings.TrimSpace(existing.Content) != "" {
+			target.Content = strings.TrimRight(existing.Content, "\n") + "\n\n" + selection
+		}
+	}
+
+	saved, err := ns.repo.SplitNote(ctx, source, current.Revision, target, targetRevision)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !saved {
+		latest, err := ns.Get(ctx, userID, contextName, date)
+		if err != nil {
+			return nil, nil, err
+		}
+		return latest, nil, ErrRevisionConflict
+	}
+	ns.invalidateRender(source.ID)
+	ns.invalidateRender(target.ID)
+	ns.publishNote(models.NoteEventUpdated, source)
+	ns.publishNote(models.NoteEventUpdated, target)
+
+	if ns.syncWorker != nil {
+		if !source.LocalOnly {
+			ns.syncWorker.SyncNoteImmediate(userID, contextName, date)
+		}
+		if !target.LocalOnly {
+			ns.syncWorker.SyncNoteImmediate(userID, toContext, toDate)
+		}
+	}
+
+	return source, target, nil
+}
+
+// maxAgendaDays caps the range of an agenda, enough for a printed month with its edges
+const maxAgendaDays = 62
+
+// agendaTitleLength caps the preview used as title of notes without a heading
+const agendaTitleLength = 80
+
+// Agenda returns the daily notes of every context from from to to, grouped by day,
+// with the color of their context, their title and their checkbox tasks rolled up
+// per note, per day and for the whole range. One query feeds printed agendas and digests.
+func (ns *NoteService) Agenda(ctx context.Context, userID, from, to string) (_ *models.Agenda, err error) {
+	defer wrapOp("get agenda", &err)
+	days, err := period.Range(from, to)
+	if err != nil || len(days) > maxAgendaDays {
+		return nil, ErrAgendaRange
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	notes, err := ns.repo.GetAgendaNotes(ctx, database.ScopeUser(userID), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	agenda := &models.Agenda{From: from, To: to, Days: make([]models.AgendaDay, len(days))}
+	index := make(map[string]int, len(days))
+	for i, date := range days {
+		agenda.Days[i] = models.AgendaDay{Date: date, Title: period.Title(date), Notes: []models.AgendaNote{}}
+		index[date] = i
+	}
+
+	for _, note := range notes {
+		fillAgendaNote(&note)
+
+		i, ok := index[note.Date]
+		if !ok {
+			continue
+		}
+		day := &agenda.Days[i]
+		day.Notes = append(day.Notes, note)
+		day.Rollup.Open += note.Rollup.Open
+		day.Rollup.Done += note.Rollup.Done
+		agenda.Rollup.Open += note.Rollup.Open
+		agenda.Rollup.Done += note.Rollup.Done
+	}
+
+	return agenda, nil
+}
+
+// Day returns the daily notes of every context the user owns on a date, as
+// one agenda day, so a dashboard of the day needs a single request
+func (ns *NoteService) Day(ctx context.Context, userID, date string) (_ *models.AgendaDay, err error) {
+	defer wrapOp("get day", &err)
+	if period.Kind(date) != period.Day {
+		return nil, ErrInvalidPeriodKey
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	notes, err := ns.repo.GetNotesOnDate(ctx, database.ScopeUser(userID), date)
+	if err != nil {
+		return nil, err
+	}
+
+	day := &models.AgendaDay{Date: date, Title: period.Title(date), Notes: []models.AgendaNote{}}
+	for _, note := range notes {
+		fillAgendaNote(&note)
+		day.Notes = append(day.Notes, note)
+		day.Rollup.Open += note.Rollup.Open
+		day.Rollup.Done += note.Rollup.Done
+	}
+	return day, nil
+}
+
+// fillAgendaNote sets the title, tags and tasks of an agenda note from its content
+func fillAgendaNote(note *models.AgendaNote) {
+	note.Title = noteTitle(note.Content)
+	note.Encrypted = e2ee.IsEncrypted(note.Content)
+	note.Tags = markdown.ExtractHashtags(note.Content)
+	note.Tasks = []models.AgendaTask{}
+	for _, task := range markdown.ExtractTasks(note.Content) {
+		note.Tasks = append(note.Tasks, models.AgendaTask{Text: task.Text, Done: task.Done})
+		if task.Done {
+			note.Rollup.Done++
+		} else {
+			note.Rollup.Open++
+		}
+	}
+}
+
+// maxMetaDays caps the range of notes metadata, enough for a year of dashboards
+const maxMetaDays = 366
+
+// Meta returns per-day metadata of the daily notes of every context from from to
+// to: word counts, moods, tags and tasks, without content. A day's mood is the
+// first one found, going through its notes by context name.
+func (ns *NoteService) Meta(ctx context.Context, userID, from, to string) (_ *models.NotesMeta, err error) {
+	defer wrapOp("get notes meta", &err)
+	days, err := period.Range(from, to)
+	if err != nil || len(days) > maxMetaDays {
+		return nil, ErrMetaRange
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	notes, err := ns.repo.GetAgendaNotes(ctx, database.ScopeUser(userID), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &models.NotesMeta{
+		From:      from,
+		To:        to,
+		Dates:     days,
+		Notes:     make([]int, len(days)),
+		Words:     make([]int, len(days)),
+		Moods:     make([]*string, len(days)),
+		Tags:      make([][]string, len(days)),
+		TasksOpen: make([]int, len(days)),
+		TasksDone: make([]int, len(days)),
+	}
+	index := make(map[string]int, len(days))
+	for i, date := range days {
+		index[date] = i
+		meta.Tags[i] = []string{}
+	}
+
+	for _, note := range notes {
+		i, ok := index[note.Date]
+		if !ok {
+			continue
+		}
+		meta.Notes[i]++
+		if e2ee.IsEncrypted(note.Content) {
+			continue
+		}
+		meta.Words[i] += markdown.WordCount(note.Content)
+		if mood := markdown.ExtractMood(note.Content); mood != "" && meta.Moods[i] == nil {
+			meta.Moods[i] = &mood
+		}
+		for _, tag := range markdown.ExtractHashtags(note.Content) {
+			if !slices.Contains(meta.Tags[i], tag) {
+				meta.Tags[i] = append(meta.Tags[i], tag)
+			}
+		}
+		for _, task := range markdown.ExtractTasks(note.Content) {
+			if task.Done {
+				meta.TasksDone[i]++
+			} else {
+				meta.TasksOpen[i]++
+			}
+		}
+	}
+
+	return meta, nil
+}
+
+// maxActivityDays caps the range of activity stats, a year of contribution graph
+const maxActivityDays = 366
+
+// Activity returns the daily notes and words written each day from from to to,
+// days without notes included, and the streaks of every context as of today in
+// loc
+func (ns *NoteService) Activity(ctx context.Context, userID, from, to string, loc *time.Location) (_ *models.Activity, err error) {
+	defer wrapOp("get activity", &err)
+	days, err := period.Range(from, to)
+	if err != nil || len(days) > maxActivityDays {
+		return nil, ErrActivityRange
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	written, err := ns.repo.GetActivityDays(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	today := ns.clock.Now().In(loc).Format(period.DateLayout)
+	streaks, err := ns.repo.GetContextStreaks(ctx, userID, today)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := &models.Activity{
+		From:    from,
+		To:      to,
+		Today:   today,
+		Days:    make([]models.ActivityDay, len(days)),
+		Streaks: streaks,
+	}
+	index := make(map[string]int, len(days))
+	for i, date := range days {
+		index[date] = i
+		activity.Days[i].Date = date
+	}
+	for _, day := range written {
+		if i, ok := index[day.Date]; ok {
+			activity.Days[i] = day
+		}
+	}
+	return activity, nil
+}
+
+// mostActiveContexts is how many contexts writing stats list
+const mostActiveContexts = 5
+
+// Writing returns how much the user has written: totals over every note, daily
+// notes by weekday and the contexts with the most words
+func (ns *NoteService) Writing(ctx context.Context, userID string) (_ *models.WritingStats, err error) {
+	defer wrapOp("get writing stats", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	totals, err := ns.repo.GetWritingTotals(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	days, err := ns.repo.GetAllActivityDays(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	contexts, err := ns.repo.GetContextWriting(ctx, userID, mostActiveContexts)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.WritingStats{Totals: *totals, Weekdays: make([]models.WeekdayWriting, 7), Contexts: contexts}
+	if totals.Notes > 0 {
+		stats.Totals.AverageWords = float64(totals.Words) / float64(totals.Notes)
+	}
+	for i := range stats.Weekdays {
+		// Monday first
+		stats.Weekdays[i].Weekday = strings.ToLower(time.Weekday((i + 1) % 7).String())
+	}
+	for _, day := range days {
+		date, err := time.Parse(period.DateLayout, day.Date)
+		if err != nil {
+			continue
+		}
+		weekday := &stats.Weekdays[(int(date.Weekday())+6)%7]
+		weekday.Days++
+		weekday.Notes += day.Notes
+		weekday.Words += day.Words
+	}
+	for i := range stats.Weekdays {
+		if weekday := &stats.Weekdays[i]; weekday.Days > 0 {
+			weekday.AverageWords = float64(weekday.Words) / float64(weekday.Days)
+		}
+	}
+	return stats, nil
+}
+
+// View returns a note rendered for reading, with the keys of the context's
+// previous and next notes of the same kind and the notes with the same key in
+// the other contexts
+func (ns *NoteService) View(ctx context.Context, userID, contextName, key string) (_ *models.NoteView, err error) {
+	defer wrapOp("view note", &err)
+	if period.Kind(key) == "" {
+		return nil, ErrInvalidPeriodKey
+	}
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	owner, err := ns.owner(ctx, userID, contextName, false)
+	if err != nil {
+		return nil, err
+	}
+	shared := owner != userID
+	userID = owner
+
+	note, err := ns.repo.GetNote(ctx, database.ScopeUser(userID), contextName, key)
+	if err != nil {
+		return nil, err
+	}
+	if note == nil {
+		return nil, ErrNoteNotFound
+	}
+	html, err := ns.render(note)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, next, err := ns.repo.GetAdjacentNoteDates(ctx, database.ScopeUser(userID), contextName, key)
+	if err != nil {
+		return nil, err
+	}
+	// The owner's other contexts aren't shared, so shared notes list none
+	var others []models.AgendaNote
+	if !shared {
+		if others, err = ns.repo.GetNotesOnDate(ctx, database.ScopeUser(userID), key); err != nil {
+			return nil, err
+		}
+	}
+	sameDay := []models.SameDayNote{}
+	for _, other := range others {
+		if other.Context != contextName {
+			sameDay = append(sameDay, models.SameDayNote{Context: other.Context, Color: other.Color, Title: noteTitle(other.Content)})
+		}
+	}
+
+	return &models.NoteView{
+		Note:     note,
+		HTML:     string(html),
+		Title:    period.Title(key),
+		Previous: previous,
+		Next:     next,
+		SameDay:  sameDay,
+	}, nil
+}
+
+// render returns the HTML of a note, from the render cache when it has one
+func (ns *NoteService) render(note *models.Note) ([]byte, error) {
+	renderNote := func() ([]byte, error) { return markdown.Render(note.Content) }
+	if ns.renders == nil {
+		return renderNote()
+	}
+	return ns.renders.GetOrRender(note.ID, note.Revision, renderNote)
+}
+
+// noteTitle returns the first heading of a note, or a preview of its first
+// line; encrypted notes have no title the server can read
+func noteTitle(content string) string {
+	if e2ee.IsEncrypted(content) {
+		return ""
+	}
+	if sections := markdown.Sections(content); len(sections) > 0 {
+		return sections[0].Title
+	}
+	return markdown.FirstLine(content, agendaTitleLength)
+}
+
+// maxTransferNotes caps how many notes a single copy or move may cover
+const maxTransferNotes = 366
+
+// Transfer copies the notes of fromContext with the given keys to toContext, or
+// moves them when move is set. Revisions, granularity and timestamps are kept.
+// Uploads and the removal of moved files go through the sync queue.
+// Unless overwrite is set, ErrNoteExists is returned with the target notes that
+// are in the way and nothing is written.
+func (ns *NoteService) Transfer(ctx context.Context, userID, fromContext, toContext string, keys []string, move, overwrite bool) (_ []models.Note, err error) {
+	defer wrapOp("transfer notes", &err)
+	if fromContext == toContext {
+		return nil, ErrSameContext
+	}
+	if len(keys) == 0 || len(keys) > maxTransferNotes {
+		return nil, ErrTransferRange
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.sameOwner(ctx, userID, fromContext, toContext); err != nil {
+		return nil, err
+	}
+	target, err := ns.repo.GetContextByName(ctx, database.ScopeUser(userID), toContext)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrContextNotFound
+	}
+
+	if !overwrite {
+		existing, err := ns.repo.GetNotesByKeys(ctx, database.ScopeUser(userID), toContext, keys)
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			return existing, ErrNoteExists
+		}
+	}
+
+	notes, err := ns.repo.TransferNotes(ctx, database.ScopeUser(userID), fromContext, toContext, keys, move, ns.trashedAt())
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return nil, ErrNoteNotFound
+	}
+
+	for i, note := range notes {
+		ns.invalidateRender(note.ID)
+		if move {
+			ns.invalidateRender(fmt.Sprintf("%s-%s-%s", userID, fromContext, note.Date))
+			ns.publishDeleted(userID, fromContext, note.Date)
+		}
+		ns.publishNote(models.NoteEventUpdated, &notes[i])
+	}
+
+	// A single note is pushed right away; ranges are left to the background worker
+	if ns.syncWorker != nil && len(notes) == 1 && !notes[0].LocalOnly {
+		ns.syncWorker.SyncNoteImmediate(userID, toContext, notes[0].Date)
+	}
+
+	return notes, nil
+}
+
+// Redate moves the daily notes of a context at the given dates by days, as
+// after a timezone change put them a day off. Notes moving onto dates that are
+// themselves in the range don't count as in the way. Unless overwrite is set,
+// ErrNoteAtNewDate is returned with the notes that are and nothing is written.
+func (ns *NoteService) Redate(ctx context.Context, userID, contextName string, dates []string, days int, overwrite bool) (_ []models.Note, err error) {
+	defer wrapOp("redate notes", &err)
+	if len(dates) == 0 || len(dates) > maxTransferNotes {
+		return nil, ErrTransferRange
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, true); err != nil {
+		return nil, err
+	}
+
+	if !overwrite {
+		moving := make(map[string]bool, len(dates))
+		for _, date := range dates {
+			moving[date] = true
+		}
+		var targets []string
+		for _, date := range dates {
+			target, err := period.AddDays(date, days)
+			if err != nil {
+				return nil, ErrInvalidPeriodKey
+			}
+			if !moving[target] {
+				targets = append(targets, target)
+			}
+		}
+
+		existing, err := ns.repo.GetNotesByKeys(ctx, database.ScopeUser(userID), contextName, targets)
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			return existing, ErrNoteAtNewDate
+		}
+	}
+
+	notes, err := ns.repo.RedateNotes(ctx, database.ScopeUser(userID), contextName, dates, days, ns.trashedAt())
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return nil, ErrNoteNotFound
+	}
+
+	for _, note := range notes {
+		ns.invalidateRender(note.ID)
+		if from, err := period.AddDays(note.Date, -days); err == nil {
+			ns.invalidateRender(fmt.Sprintf("%s-%s-%s", userID, contextName, from))
+			ns.publishDeleted(userID, contextName, from)
+		}
+	}
+	for i := range notes {
+		ns.publishNote(models.NoteEventUpdated, &notes[i])
+	}
+
+	// A single note is pushed right away; ranges are left to the background worker
+	if ns.syncWorker != nil && len(notes) == 1 && !notes[0].LocalOnly {
+		ns.syncWorker.SyncNoteImmediate(userID, contextName, notes[0].Date)
+	}
+
+	return notes, nil
+}
+
+// Backlinks returns links to the coarser notes containing key, nearest first
+// (a day links to its week, month and year)
+func (ns *NoteService) Backlinks(ctx context.Context, userID, contextName, key string) (_ []models.NoteLink, err error) {
+	defer wrapOp("list backlinks", &err)
+	ancestors, err := period.Ancestors(key)
+	if err != nil {
+		return nil, ErrInvalidPeriodKey
+	}
+	return ns.links(ctx, userID, contextName, ancestors)
+}
+
+// SeedPeriod creates a week, month or year note pre-filled with a digest of the
+// notes it rolls up. Existing notes with content are left untouched.
+// created reports whether a new note was written.
+func (ns *NoteService) SeedPeriod(ctx context.Context, userID, contextName, noteType, key string) (note *models.Note, created bool, err error) {
+	defer wrapOp("seed period note", &err)
+	note, rollup, err := ns.GetPeriod(ctx, userID, contextName, noteType, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if note.Content != "" {
+		return note, false, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("# " + period.Title(key) + "\n")
+	for _, link := range rollup {
+		if !link.Exists {
+			continue
+		}
+		b.WriteString("\n## " + period.Title(link.Key) + "\n\n")
+		if link.Excerpt != "" {
+			b.WriteString(link.Excerpt + "\n")
+		}
+	}
+
+	note, err = ns.Upsert(ctx, userID, contextName, key, b.String())
+	if err != nil {
+		return nil, false, err
+	}
+	return note, true, nil
+}
+
+// links builds note links for keys, marking which notes exist
+func (ns *NoteService) links(ctx context.Context, userID, contextName string, keys []string) ([]models.NoteLink, error) {
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	userID, err := ns.owner(ctx, userID, contextName, false)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := ns.repo.GetNotesByKeys(ctx, database.ScopeUser(userID), contextName, keys)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]models.Note, len(notes))
+	for _, n := range notes {
+		byKey[n.Date] = n
+	}
+
+	links := make([]models.NoteLink, 0, len(keys))
+	for _, key := range keys {
+		link := models.NoteLink{Type: period.Kind(key), Key: key}
+		if n, ok := byKey[key]; ok {
+			link.Exists = true
+			link.Excerpt = markdown.Excerpt(n.Content, 120)
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// Delete marks a note as deleted
+func (ns *NoteService) Delete(ctx context.Context, userID, contextName, date string) (err error) {
+	defer wrapOp("delete note", &err)
+	if err := authorizeToken(ctx, contextName, models.TokenWrite); err != nil {
+		return err
+	}
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, true); err != nil {
+		return err
+	}
+
+	// Mark note as deleted (will be synced by background worker), keeping it in
+	// the trash while the trash is on
+	if ns.trash > 0 {
+		now := ns.clock.Now()
+		if err := ns.repo.TrashNote(ctx, database.ScopeUser(userID), contextName, date, now); err != nil {
+			return err
+		}
+		if _, err := ns.repo.PurgeNoteTrash(ctx, database.ScopeUser(userID), now.Add(-ns.trash)); err != nil {
+			return err
+		}
+	} else if err := ns.repo.DeleteNote(ctx, database.ScopeUser(userID), contextName, date); err != nil {
+		return err
+	}
+	ns.invalidateRender(fmt.Sprintf("%s-%s-%s", userID, contextName, date))
+	ns.publishDeleted(userID, contextName, date)
+	return nil
+}
+
+// trashedAt stamps the trash entries of notes deleted now; zero, keeping
+// none, while the trash is off
+func (ns *NoteService) trashedAt() time.Time {
+	if ns.trash <= 0 {
+		return time.Time{}
+	}
+	return ns.clock.Now()
+}
+
+// Trash lists the user's deleted notes that can still be restored, most recently
+// deleted first; those past the trash window are purged on the way
+func (ns *NoteService) Trash(ctx context.Context, userID string) (_ []models.TrashedNote, err error) {
+	defer wrapOp("list trashed notes", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	since := ns.clock.Now().Add(-ns.trash)
+	if _, err := ns.repo.PurgeNoteTrash(ctx, database.ScopeUser(userID), since); err != nil {
+		return nil, err
+	}
+	notes, err := ns.repo.GetTrashedNotes(ctx, database.ScopeUser(userID), since)
+	if err != nil {
+		return nil, err
+	}
+	for i := range notes {
+		note := &notes[i]
+		note.Encrypted = e2ee.IsEncrypted(note.Content)
+		if !note.Encrypted {
+			note.Excerpt = markdown.Excerpt(note.Content, 120)
+		}
+		note.ExpiresAt = note.DeletedAt.Add(ns.trash)
+	}
+	return notes, nil
+}
+
+// RestoreFromTrash brings a deleted note back to its context and day and queues
+// it for sync. The context must exist, and no note may have been written on the
+// day since (ErrNoteDayTaken).
+func (ns *NoteService) RestoreFromTrash(ctx context.Context, userID string, id int64) (_ *models.Note, err error) {
+	defer wrapOp("restore note", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	trashed, err := ns.repo.GetTrashedNote(ctx, database.ScopeUser(userID), id)
+	if err != nil {
+		return nil, err
+	}
+	if trashed == nil || ns.clock.Now().Sub(trashed.DeletedAt) > ns.trash {
+		return nil, ErrNoteNotInTrash
+	}
+
+	c, err := ns.repo.GetContextByName(ctx, database.ScopeUser(userID), trashed.Context)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrContextNotFound
+	}
+	// Encryption may have been turned on or off since the note was deleted
+	if err := ns.checkContent(ctx, userID, trashed.Content); err != nil {
+		return nil, err
+	}
+
+	note := &models.Note{
+		UserID:    userID,
+		Context:   trashed.Context,
+		Date:      trashed.Date,
+		Type:      trashed.Type,
+		Content:   trashed.Content,
+		Revision:  trashed.Revision,
+		LocalOnly: trashed.LocalOnly,
+		CreatedAt: ns.clock.Now(),
+		UpdatedAt: ns.clock.Now(),
+	}
+	restored, err := ns.repo.RestoreTrashedNote(ctx, id, note)
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrNoteDayTaken
+	}
+	ns.invalidateRender(note.ID)
+	ns.publishNote(models.NoteEventUpdated, note)
+
+	if ns.syncWorker != nil && !note.LocalOnly {
+		ns.syncSaved(ctx, userID, note.Context, note.Date)
+	}
+	return note, nil
+}
+
+// ListByContext retrieves all notes for a specific context with pagination
+func (ns *NoteService) ListByContext(ctx context.Context, userID, contextName string, limit, offset int) (_ []models.Note, err error) {
+	defer wrapOp("list notes", &err)
+	// Validate and normalize pagination params
+	if limit < 1 || limit > 100 {
+		limit = 30
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, false); err != nil {
+		return nil, err
+	}
+	return ns.repo.GetNotesByContext(ctx, database.ScopeUser(userID), contextName, limit, offset)
+}
+
+// Changes returns a page of the user's note changes after the cursor since, for
+// clients keeping an offline copy: 0 lists every note. A limit outside 1-500 is 100.
+func (ns *NoteService) Changes(ctx context.Context, userID string, since int64, limit int) (_ *models.NoteChanges, err error) {
+	defer wrapOp("list note changes", &err)
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+	if since < 0 {
+		since = 0
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	// One more than asked tells whether another page follows
+	changes, err := ns.repo.GetNoteChanges(ctx, userID, since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.NoteChanges{Changes: changes, Cursor: since}
+	if len(changes) > limit {
+		page.Changes, page.HasMore = changes[:limit], true
+	}
+	if len(page.Changes) > 0 {
+		page.Cursor = page.Changes[len(page.Changes)-1].Cursor
+	}
+	return page, nil
+}
+
+// Tags lists the user's #tags with how many notes use each, most used first
+func (ns *NoteService) Tags(ctx context.Context, userID string) (_ []models.Tag, err error) {
+	defer wrapOp("list tags", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	return ns.repo.GetTags(ctx, userID)
+}
+
+// ListByTag retrieves the user's notes tagged with tag, across contexts, with pagination
+// The tag may be given with its leading # and in any case.
+func (ns *NoteService) ListByTag(ctx context.Context, userID, tag string, limit, offset int) (_ []models.Note, err error) {
+	defer wrapOp("list notes by tag", &err)
+	tag = normalizeTag(tag)
+	if tag == "" {
+		return nil, ErrEmptyTag
+	}
+	if limit < 1 || limit > 100 {
+		limit = 30
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	return ns.repo.GetNotesByTag(ctx, userID, tag, limit, offset)
+}
+
+// Tasks lists the checkbox tasks of the user's notes, newest notes first.
+// status is open, done, or all ("" too); contextName keeps one context.
+func (ns *NoteService) Tasks(ctx context.Context, userID, status, contextName string, limit, offset int) (_ []models.Task, err error) {
+	defer wrapOp("list tasks", &err)
+	switch status {
+	case "all":
+		status = ""
+	case "", models.TaskOpen, models.TaskDone:
+	default:
+		return nil, ErrInvalidTaskStatus
+	}
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	return ns.repo.GetTasks(ctx, userID, status, contextName, limit, offset)
+}
+
+// ToggleTask checks an open task or unchecks a done one by rewriting its line
+// of the note. It fails with ErrTaskChanged if the line no longer holds the task.
+func (ns *NoteService) ToggleTask(ctx context.Context, userID string, id int64) (_ *models.Task, _ *models.Note, err error) {
+	defer wrapOp("toggle task", &err)
+	queryCtx, cancel := ns.timeouts.query(ctx)
+	task, err := ns.repo.GetTask(queryCtx, userID, id)
+	cancel()
+	if err != nil {
+		return nil, nil, err
+	}
+	if task == nil {
+		return nil, nil, ErrTaskNotFound
+	}
+
+	done := !task.Done
+	note, err := ns.edit(ctx, userID, task.Context, task.Date, nil, func(content string) (string, error) {
+		content, ok := markdown.CheckTask(content, task.Line, task.Text, done)
+		if !ok {
+			return "", ErrTaskChanged
+		}
+		return content, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	task.Done = done
+	return task, note, nil
+}
+
+// Revisions lists the earlier versions of a note, newest first
+func (ns *NoteService) Revisions(ctx context.Context, userID, contextName, date string) (_ []models.NoteRevision, err error) {
+	defer wrapOp("list revisions", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, false); err != nil {
+		return nil, err
+	}
+	return ns.repo.GetNoteRevisions(ctx, userID, contextName, date)
+}
+
+// RestoreRevision makes an earlier version the current content of its note
+// The replaced content becomes a revision itself, so a restore can be undone.
+func (ns *NoteService) RestoreRevision(ctx context.Context, userID string, id int64) (_ *models.Note, err error) {
+	defer wrapOp("restore revision", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	revision, err := ns.repo.GetNoteRevision(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, ErrRevisionNotFound
+	}
+
+	return ns.Upsert(ctx, userID, revision.Context, revision.Date, revision.Content)
+}
+
+// Conflict resolutions, see ResolveConflict
+const (
+	ResolveLocal  = "local"
+	ResolveRemote = "remote"
+	ResolveMerged = "merged"
+)
+
+// Conflicts lists the user's notes that changed both locally and in storage since their last sync
+func (ns *NoteService) Conflicts(ctx context.Context, userID string) (_ []models.NoteConflict, err error) {
+	defer wrapOp("list conflicts", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	return ns.repo.GetNoteConflicts(ctx, userID)
+}
+
+// ResolveConflict ends the sync conflict of a note by keeping the local version,
+// the storage version or merged content, and uploads the result
+// The local content is kept in the revision history when it is replaced.
+func (ns *NoteService) ResolveConflict(ctx context.Context, userID, contextName, date, resolution, merged string) (_ *models.Note, err error) {
+	defer wrapOp("resolve conflict", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	conflict, err := ns.repo.GetNoteConflict(ctx, userID, contextName, date)
+	if err != nil {
+		return nil, err
+	}
+	if conflict == nil {
+		return nil, ErrConflictNotFound
+	}
+
+	note := &models.Note{
+		UserID:    userID,
+		Context:   contextName,
+		Date:      date,
+		Content:   conflict.LocalContent,
+		UpdatedAt: ns.clock.Now(),
+	}
+	switch resolution {
+	case ResolveRemote:
+		note.Content = conflict.RemoteContent
+	case ResolveMerged:
+		if err := ns.checkContent(ctx, userID, merged); err != nil {
+			return nil, err
+		}
+		note.Content = merged
+	}
+
+	resolved, err := ns.repo.ResolveNoteConflict(ctx, note)
+	if err != nil {
+		return nil, err
+	}
+	if !resolved {
+		return nil, ErrConflictNotFound
+	}
+	ns.invalidateRender(note.ID)
+	ns.publishNote(models.NoteEventUpdated, note)
+	ns.queueLinkPreviews(note.Content)
+
+	if ns.syncWorker != nil && !note.LocalOnly {
+		ns.syncWorker.SyncNoteImmediate(userID, contextName, date)
+	}
+
+	return note, nil
+}
+
+// Search finds the user's notes containing every word of query, best match first
+// filter narrows the search to a context, a date range or a sync status.
+func (ns *NoteService) Search(ctx context.Context, userID, query string, filter models.NoteSearchFilter, limit, offset int) (_ []models.NoteSearchResult, err error) {
+	defer wrapOp("search notes", &err)
+	if strings.TrimSpace(query) == "" {
+		return nil, ErrEmptySearch
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	return ns.repo.SearchNotes(ctx, userID, query, filter, limit, offset)
+}
+
+// noteLargestListed is how many of the largest notes SizeStats lists
+const noteLargestListed = 10
+
+// SizeWarning returns a warning when a saved note is larger than the configured limit
+// The suggestions name the workflows that make it smaller: "split" moves part of
+// it to another note, "attachment" moves pasted data out of the text.
+func (ns *NoteService) SizeWarning(note *models.Note) *models.NoteSizeWarning {
+	if note == nil || ns.sizeLimit <= 0 || len(note.Content) <= ns.sizeLimit {
+		return nil
+	}
+	return &models.NoteSizeWarning{
+		Size:  len(note.Content),
+		Limit: ns.sizeLimit,
+		Message: fmt.Sprintf("This note is %d KB, above the %d KB that keeps editing and sync fast. Split it into smaller notes or move pasted data into attachments.",
+			(len(note.Content)+1023)/1024, ns.sizeLimit/1024),
+		Suggestions: []string{"split", "attachment"},
+	}
+}
+
+// SizeStats returns content size percentiles and the largest notes of a user
+func (ns *NoteService) SizeStats(ctx context.Context, userID string) (_ *models.NoteSizeStats, err error) {
+	defer wrapOp("get size stats", &err)
+	ctx, cancel := ns.timeouts.scan(ctx)
+	defer cancel()
+
+	return ns.repo.GetNoteSizeStats(ctx, userID, ns.sizeLimit, noteLargestListed)
+}
+
+// Related finds past notes that are most similar to the note for a context and date
+// Scores combine TF-IDF lexical similarity with shared #tags and links
+func (ns *NoteService) Related(ctx context.Context, userID, contextName, date string, limit int) (_ []models.RelatedNote, err error) {
+	defer wrapOp("list related notes", &err)
+	if limit < 1 || limit > 20 {
+		limit = 5
+	}
+
+	ctx, cancel := ns.timeouts.scan(ctx)
+	defer cancel()
+
+	owner, err := ns.owner(ctx, userID, contextName, false)
+	if err != nil {
+		return nil, err
+	}
+	// In a shared context, only its own notes are compared: the owner's other
+	// contexts aren't shared
+	shared := owner != userID
+	userID = owner
+
+	current, err := ns.repo.GetNote(ctx, database.ScopeUser(userID), contextName, date)
+	if err != nil {
+		return nil, err
+	}
+	related := make([]models.RelatedNote, 0)
+	if current == nil || current.Content == "" {
+		return related, nil
+	}
+
+	allNotes, err := ns.repo.GetAllNotesByUser(ctx, database.ScopeUser(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	// Only look back in time, never at the note itself
+	var candidates []models.Note
+	var contents []string
+	for _, note := range allNotes {
+		if note.Date > date || (note.Date == date && note.Context == contextName) || (shared && note.Context != contextName) {
+			continue
+		}
+		candidates = append(candidates, note)
+		contents = append(contents, note.Content)
+	}
+
+	currentTags := markdown.ExtractHashtags(current.Content)
+	currentLinks := markdown.ExtractLinks(current.Content)
+	scores := classifier.Similarity(current.Content, contents)
+
+	for i, note := range candidates {
+		sharedTags := intersect(currentTags, markdown.ExtractHashtags(note.Content))
+		sharedLinks := intersect(currentLinks, markdown.ExtractLinks(note.Content))
+
+		score := scores[i] +
+			relatedTagWeight*float64(len(sharedTags)) +
+			relatedLinkWeight*float64(len(sharedLinks))
+		if score < relatedMinScore {
+			continue
+		}
+
+		related = append(related, models.RelatedNote{
+			Context:     note.Context,
+			Date:        note.Date,
+			Score:       score,
+			SharedTags:  sharedTags,
+			SharedLinks: sharedLinks,
+			Excerpt:     markdown.Excerpt(note.Content, 160),
+		})
+	}
+
+	sort.SliceStable(related, func(i, j int) bool {
+		return related[i].Score > related[j].Score
+	})
+	if len(related) > limit {
+		related = related[:limit]
+	}
+
+	return related, nil
+}
+
+// intersect returns the values of a that also appear in b
+func intersect(a, b []string) []string {
+	set := make(map[string]bool, len(b))
+	for _, v := range b {
+		set[v] = true
+	}
+
+	var shared []string
+	for _, v := range a {
+		if set[v] {
+			shared = append(shared, v)
+		}
+	}
+	return shared
+}
+
+// GetSyncStatus returns sync status information for the user
+func (ns *NoteService) GetSyncStatus(ctx context.Context, userID string) (_ map[string]interface{}, err error) {
+	defer wrapOp("get sync status", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	// Get failed sync notes (up to 50)
+	failedNotes, err := ns.repo.GetFailedSyncNotes(ctx, userID, 50)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get pending sync notes count
+	pendingNotes, err := ns.repo.GetPendingSyncNotes(ctx, 50)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count only this user's pending notes
+	userPendingCount := 0
+	for _, note := range pendingNotes {
+		if note.UserID == userID {
+			userPendingCount++
+		}
+	}
+
+	// Notes kept out of sync on purpose, so clients don't take them for unsynced ones
+	localOnlyCount, err := ns.repo.CountLocalOnlyNotes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Context folder renames and deletions waiting for a retry
+	operations, err := ns.repo.GetUserSyncOperations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"pending_count":     userPendingCount,
+		"failed_count":      len(failedNotes),
+		"failed_notes":      failedNotes,
+		"failed_operations": operations,
+		"local_only_count":  localOnlyCount,
+	}, nil
+}
+
+// RetrySync retries synchronization for a failed note
+func (ns *NoteService) RetrySync(ctx context.Context, noteID, userID string) (err error) {
+	defer wrapOp("retry sync", &err)
+	// Verify the note belongs to this user by parsing the note ID
+	// Note IDs follow the format: userID-context-date
+	if len(noteID) < len(userID)+2 || noteID[:len(userID)+1] != userID+"-" {
+		return ErrUnauthorized
+	}
+
+	// Reset the note's sync status to retry
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	return ns.repo.RetrySyncNote(ctx, noteID)
+}
+
+// RetryAllSync gives every failed or abandoned note of the user, deletions
+// included, and every failed context folder change a fresh start, and has the
+// sync worker retry them right away. Returns how many notes and operations were queued.
+func (ns *NoteService) RetryAllSync(ctx context.Context, userID string) (notes, operations int, err error) {
+	defer wrapOp("retry all sync", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if notes, err = ns.repo.RetryUserSyncNotes(ctx, userID); err != nil {
+		return 0, 0, err
+	}
+	if operations, err = ns.repo.RetryUserSyncOperations(ctx, userID); err != nil {
+		return 0, 0, err
+	}
+	if ns.syncWorker != nil && notes+operations > 0 {
+		ns.syncWorker.RetryNow()
+	}
+	return notes, operations, nil
+}
+
+// SetLocalOnly marks a note local only, so it is never synced to cloud storage
+// from now on, or lets it sync again. A copy uploaded before is left in storage.
+// Notes of a local-only context stay local only either way.
+func (ns *NoteService) SetLocalOnly(ctx context.Context, userID, contextName, date string, localOnly bool) (_ *models.Note, err error) {
+	defer wrapOp("set note local only", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, true); err != nil {
+		return nil, err
+	}
+
+	updated, err := ns.repo.SetNoteLocalOnly(ctx, userID, contextName, date, localOnly)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrNoteNotFound
+	}
+
+	note, err := ns.repo.GetNote(ctx, database.ScopeUser(userID), contextName, date)
+	if err != nil {
+		return nil, err
+	}
+	if note == nil {
+		return nil, ErrNoteNotFound
+	}
+
+	// A note that may sync again goes out right away, like a saved one
+	if ns.syncWorker != nil && !note.LocalOnly {
+		ns.syncWorker.SyncNoteImmediate(userID, contextName, date)
+	}
+	return note, nil
+}
+
+// SetPinned pins a note to the top of its context, or unpins it. Pinning doesn't
+// change the note, so it isn't synced again.
+func (ns *NoteService) SetPinned(ctx context.Context, userID, contextName, date string, pinned bool) (_ *models.Note, err error) {
+	defer wrapOp("pin note", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, true); err != nil {
+		return nil, err
+	}
+
+	var pinnedAt *time.Time
+	if pinned {
+		now := ns.clock.Now()
+		pinnedAt = &now
+	}
+	updated, err := ns.repo.SetNotePinned(ctx, userID, contextName, date, pinnedAt)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrNoteNotFound
+	}
+
+	note, err := ns.repo.GetNote(ctx, database.ScopeUser(userID), contextName, date)
+	if err != nil {
+		return nil, err
+	}
+	if note == nil {
+		return nil, ErrNoteNotFound
+	}
+	return note, nil
+}
+
+// Pinned lists the pinned notes of a context, most recently pinned first,
+// without their content
+func (ns *NoteService) Pinned(ctx context.Context, userID, contextName string) (_ []models.Note, err error) {
+	defer wrapOp("list pinned notes", &err)
+	ctx, cancel := ns.timeouts.query(ctx)
+	defer cancel()
+
+	if userID, err = ns.owner(ctx, userID, contextName, false); err != nil {
+		return nil, err
+	}
+	return ns.repo.GetPinnedNotes(ctx, userID, contextName)
 }
@@ -1,14 +1,22 @@
 package services
 
 import (
+	"context"
 	"daily-notes/database"
 	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/pkg/notetemplate"
+	"daily-notes/pkg/pubsub"
+	"daily-notes/pkg/rendercache"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/oauth2"
 )
 
@@ -22,33 +30,254 @@ type MockRepository struct {
 // Ensure MockRepository implements NoteRepository interface
 var _ NoteRepository = (*MockRepository)(nil)
 
-func (m *MockRepository) GetNote(userID, contextName, date string) (*models.Note, error) {
-	args := m.Called(userID, contextName, date)
+func (m *MockRepository) GetNote(_ context.Context, scope database.UserScope, contextName, date string) (*models.Note, error) {
+	args := m.Called(scope.UserID(), contextName, date)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Note), args.Error(1)
 }
 
-func (m *MockRepository) UpsertNote(note *models.Note, syncPending bool) error {
+func (m *MockRepository) UpsertNote(_ context.Context, note *models.Note, syncPending bool) error {
 	args := m.Called(note, syncPending)
 	return args.Error(0)
 }
 
-func (m *MockRepository) DeleteNote(userID, contextName, date string) error {
-	args := m.Called(userID, contextName, date)
+func (m *MockRepository) UpsertNotes(_ context.Context, notes []*models.Note, syncPending bool) error {
+	args := m.Called(notes, syncPending)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpsertNoteAtRevision(_ context.Context, note *models.Note, baseRevision int, syncPending bool) (bool, error) {
+	args := m.Called(note, baseRevision, syncPending)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) DeleteNote(_ context.Context, scope database.UserScope, contextName, date string) error {
+	args := m.Called(scope.UserID(), contextName, date)
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetNotesByContext(userID, contextName string, limit, offset int) ([]models.Note, error) {
-	args := m.Called(userID, contextName, limit, offset)
+func (m *MockRepository) GetNotesByContext(_ context.Context, scope database.UserScope, contextName string, limit, offset int) ([]models.Note, error) {
+	args := m.Called(scope.UserID(), contextName, limit, offset)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]models.Note), args.Error(1)
 }
 
-func (m *MockRepository) GetFailedSyncNotes(userID string, limit int) ([]models.Note, error) {
+func (m *MockRepository) GetTags(_ context.Context, userID string) ([]models.Tag, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Tag), args.Error(1)
+}
+
+func (m *MockRepository) GetNotesByTag(_ context.Context, userID, tag string, limit, offset int) ([]models.Note, error) {
+	args := m.Called(userID, tag, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func (m *MockRepository) GetTasks(_ context.Context, userID, status, contextName string, limit, offset int) ([]models.Task, error) {
+	args := m.Called(userID, status, contextName, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Task), args.Error(1)
+}
+
+func (m *MockRepository) GetTask(_ context.Context, userID string, id int64) (*models.Task, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Task), args.Error(1)
+}
+
+func (m *MockRepository) GetNoteChanges(_ context.Context, userID string, since int64, limit int) ([]models.NoteChange, error) {
+	args := m.Called(userID, since, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.NoteChange), args.Error(1)
+}
+
+func (m *MockRepository) GetNoteRevisions(_ context.Context, userID, contextName, date string) ([]models.NoteRevision, error) {
+	args := m.Called(userID, contextName, date)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.NoteRevision), args.Error(1)
+}
+
+func (m *MockRepository) GetNoteRevision(_ context.Context, userID string, id int64) (*models.NoteRevision, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.NoteRevision), args.Error(1)
+}
+
+func (m *MockRepository) GetNoteConflicts(_ context.Context, userID string) ([]models.NoteConflict, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.NoteConflict), args.Error(1)
+}
+
+func (m *MockRepository) GetNoteConflict(_ context.Context, userID, contextName, date string) (*models.NoteConflict, error) {
+	args := m.Called(userID, contextName, date)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.NoteConflict), args.Error(1)
+}
+
+func (m *MockRepository) ResolveNoteConflict(_ context.Context, note *models.Note) (bool, error) {
+	args := m.Called(note)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) GetCalendarDays(_ context.Context, scope database.UserScope, contextName, from, to string) ([]models.CalendarDay, error) {
+	args := m.Called(scope.UserID(), contextName, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.CalendarDay), args.Error(1)
+}
+
+func (m *MockRepository) GetNotesByKeys(_ context.Context, scope database.UserScope, contextName string, keys []string) ([]models.Note, error) {
+	args := m.Called(scope.UserID(), contextName, keys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func (m *MockRepository) GetMonthNotes(_ context.Context, scope database.UserScope, contextNames, keys []string) ([]models.Note, error) {
+	args := m.Called(scope.UserID(), contextNames, keys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func (m *MockRepository) GetLiveSharedNote(_ context.Context, noteID string, now time.Time) (*models.Note, error) {
+	args := m.Called(noteID, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Note), args.Error(1)
+}
+
+func (m *MockRepository) SearchNotes(_ context.Context, userID, query string, filter models.NoteSearchFilter, limit, offset int) ([]models.NoteSearchResult, error) {
+	args := m.Called(userID, query, filter, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.NoteSearchResult), args.Error(1)
+}
+
+func (m *MockRepository) GetNoteSizeStats(_ context.Context, userID string, limit, largest int) (*models.NoteSizeStats, error) {
+	args := m.Called(userID, limit, largest)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.NoteSizeStats), args.Error(1)
+}
+
+func (m *MockRepository) SplitNote(_ context.Context, source *models.Note, sourceRevision int, target *models.Note, targetRevision int) (bool, error) {
+	args := m.Called(source, sourceRevision, target, targetRevision)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) TransferNotes(_ context.Context, scope database.UserScope, fromContext, toContext string, keys []string, move bool, _ time.Time) ([]models.Note, error) {
+	args := m.Called(scope.UserID(), fromContext, toContext, keys, move)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func (m *MockRepository) RedateNotes(_ context.Context, scope database.UserScope, contextName string, dates []string, days int, _ time.Time) ([]models.Note, error) {
+	args := m.Called(scope.UserID(), contextName, dates, days)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func (m *MockRepository) GetContextByName(_ context.Context, scope database.UserScope, name string) (*models.Context, error) {
+	args := m.Called(scope.UserID(), name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Context), args.Error(1)
+}
+
+func (m *MockRepository) GetAllNotesByUser(_ context.Context, scope database.UserScope) ([]models.Note, error) {
+	args := m.Called(scope.UserID())
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func (m *MockRepository) GetAgendaNotes(_ context.Context, scope database.UserScope, from, to string) ([]models.AgendaNote, error) {
+	args := m.Called(scope.UserID(), from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AgendaNote), args.Error(1)
+}
+
+func (m *MockRepository) GetActivityDays(_ context.Context, userID, from, to string) ([]models.ActivityDay, error) {
+	args := m.Called(userID, from, to)
+	return args.Get(0).([]models.ActivityDay), args.Error(1)
+}
+
+func (m *MockRepository) GetContextStreaks(_ context.Context, userID, today string) ([]models.ContextStreak, error) {
+	args := m.Called(userID, today)
+	return args.Get(0).([]models.ContextStreak), args.Error(1)
+}
+
+func (m *MockRepository) GetAllActivityDays(_ context.Context, userID string) ([]models.ActivityDay, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.ActivityDay), args.Error(1)
+}
+
+func (m *MockRepository) GetWritingTotals(_ context.Context, userID string) (*models.WritingTotals, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WritingTotals), args.Error(1)
+}
+
+func (m *MockRepository) GetContextWriting(_ context.Context, userID string, limit int) ([]models.ContextWriting, error) {
+	args := m.Called(userID, limit)
+	return args.Get(0).([]models.ContextWriting), args.Error(1)
+}
+
+func (m *MockRepository) GetAdjacentNoteDates(_ context.Context, scope database.UserScope, contextName, key string) (string, string, error) {
+	args := m.Called(scope.UserID(), contextName, key)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockRepository) GetNotesOnDate(_ context.Context, scope database.UserScope, key string) ([]models.AgendaNote, error) {
+	args := m.Called(scope.UserID(), key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AgendaNote), args.Error(1)
+}
+
+func (m *MockRepository) GetFailedSyncNotes(_ context.Context, userID string, limit int) ([]models.Note, error) {
 	args := m.Called(userID, limit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -56,7 +285,7 @@ func (m *MockRepository) GetFailedSyncNotes(userID string, limit int) ([]models.
 	return args.Get(0).([]models.Note), args.Error(1)
 }
 
-func (m *MockRepository) GetPendingSyncNotes(limit int) ([]database.NoteWithMeta, error) {
+func (m *MockRepository) GetPendingSyncNotes(_ context.Context, limit int) ([]database.NoteWithMeta, error) {
 	args := m.Called(limit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -64,11 +293,83 @@ func (m *MockRepository) GetPendingSyncNotes(limit int) ([]database.NoteWithMeta
 	return args.Get(0).([]database.NoteWithMeta), args.Error(1)
 }
 
-func (m *MockRepository) RetrySyncNote(noteID string) error {
+func (m *MockRepository) RetrySyncNote(_ context.Context, noteID string) error {
 	args := m.Called(noteID)
 	return args.Error(0)
 }
 
+func (m *MockRepository) RetryUserSyncNotes(_ context.Context, userID string) (int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) GetUserSyncOperations(_ context.Context, userID string) ([]models.SyncOperation, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.SyncOperation), args.Error(1)
+}
+
+func (m *MockRepository) RetryUserSyncOperations(_ context.Context, userID string) (int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) SetNoteLocalOnly(_ context.Context, userID, contextName, date string, localOnly bool) (bool, error) {
+	args := m.Called(userID, contextName, date, localOnly)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) SetNotePinned(_ context.Context, userID, contextName, date string, pinnedAt *time.Time) (bool, error) {
+	args := m.Called(userID, contextName, date, pinnedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) GetPinnedNotes(_ context.Context, userID, contextName string) ([]models.Note, error) {
+	args := m.Called(userID, contextName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func (m *MockRepository) TrashNote(_ context.Context, scope database.UserScope, contextName, date string, deletedAt time.Time) error {
+	args := m.Called(scope.UserID(), contextName, date, deletedAt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetTrashedNotes(_ context.Context, scope database.UserScope, since time.Time) ([]models.TrashedNote, error) {
+	args := m.Called(scope.UserID(), since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TrashedNote), args.Error(1)
+}
+
+func (m *MockRepository) GetTrashedNote(_ context.Context, scope database.UserScope, id int64) (*models.TrashedNote, error) {
+	args := m.Called(scope.UserID(), id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TrashedNote), args.Error(1)
+}
+
+func (m *MockRepository) RestoreTrashedNote(_ context.Context, id int64, note *models.Note) (bool, error) {
+	args := m.Called(id, note)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) PurgeNoteTrash(_ context.Context, scope database.UserScope, before time.Time) (int64, error) {
+	args := m.Called(scope.UserID(), before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) CountLocalOnlyNotes(_ context.Context, userID string) (int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
 // MockSyncWorker is a mock implementation of SyncWorker interface
 type MockSyncWorker struct {
 	mock.Mock
@@ -81,11 +382,27 @@ func (m *MockSyncWorker) SyncNoteImmediate(userID, contextName, date string) {
 	m.Called(userID, contextName, date)
 }
 
+func (m *MockSyncWorker) SyncNoteDebounced(userID, contextName, date string) {
+	m.Called(userID, contextName, date)
+}
+
+func (m *MockSyncWorker) SyncNotesImmediate(userID string, notes []models.Note) {
+	m.Called(userID, notes)
+}
+
 func (m *MockSyncWorker) ImportFromDrive(userID string, token *oauth2.Token) error {
 	args := m.Called(userID, token)
 	return args.Error(0)
 }
 
+func (m *MockSyncWorker) RetryAfterSignIn(userID string) {
+	m.Called(userID)
+}
+
+func (m *MockSyncWorker) RetryNow() {
+	m.Called()
+}
+
 // ==================== TESTS ====================
 
 func TestNoteService_Get(t *testing.T) {
@@ -159,15 +476,16 @@ func TestNoteService_Get(t *testing.T) {
 			}
 
 			service := &NoteService{
+				clock:      clock.Real(),
 				repo:       mockRepo,
 				syncWorker: nil,
 			}
 
-			note, err := service.Get(tt.userID, tt.contextName, tt.date)
+			note, err := service.Get(context.Background(), tt.userID, tt.contextName, tt.date)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
-				assert.Equal(t, tt.expectedError.Error(), err.Error())
+				assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 				assert.Nil(t, note)
 			} else {
 				assert.NoError(t, err)
@@ -183,6 +501,54 @@ func TestNoteService_Get(t *testing.T) {
 	}
 }
 
+func TestNoteService_GetScaffoldsFromTemplate(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetNote", "user123", "work", "2025-10-17").Return(nil, nil)
+	mockRepo.On("GetContextByName", "user123", "work").Return(&models.Context{
+		Name:     "work",
+		Template: "# {{weekday}}\n{{quote}}{{broken}}",
+	}, nil)
+
+	engine := notetemplate.New(nil)
+	engine.Register(notetemplate.StaticList("quote", []string{"Keep going"}))
+	engine.Register(notetemplate.Func("broken", func(context.Context, notetemplate.Vars) (string, error) {
+		return "", errors.New("provider down")
+	}))
+
+	service := NewNoteService(mockRepo, nil)
+	service.SetTemplateEngine(engine)
+
+	note, err := service.Get(context.Background(), "user123", "work", "2025-10-17")
+	require.NoError(t, err)
+	assert.Equal(t, "# Friday\nKeep going", note.Content)
+	assert.Equal(t, 0, note.Revision)
+}
+
+func TestNoteService_GetScaffoldsLookbacks(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetNote", "user123", "work", "2025-10-20").Return(nil, nil)
+	mockRepo.On("GetContextByName", "user123", "work").Return(&models.Context{
+		Name:     "work",
+		Template: "## Yesterday\n{{yesterday.section(\"Today\")}}\n## Carried over from {{last_note.date}}\n{{last_note.tasks_open}}",
+	}, nil)
+	// Monday: nothing on Sunday, the last note is Friday's
+	mockRepo.On("GetNote", "user123", "work", "2025-10-19").Return(nil, nil)
+	mockRepo.On("GetAdjacentNoteDates", "user123", "work", "2025-10-20").Return("2025-10-17", "", nil)
+	mockRepo.On("GetNote", "user123", "work", "2025-10-17").Return(&models.Note{
+		Context: "work",
+		Date:    "2025-10-17",
+		Content: "## Today\n- [x] deploy\n- [ ] write docs\n## Notes\nquiet day",
+	}, nil)
+
+	service := NewNoteService(mockRepo, nil)
+	service.SetTemplateEngine(notetemplate.New(nil))
+
+	note, err := service.Get(context.Background(), "user123", "work", "2025-10-20")
+	require.NoError(t, err)
+	assert.Equal(t, "## Yesterday\n\n## Carried over from 2025-10-17\n- [ ] write docs", note.Content)
+	mockRepo.AssertNumberOfCalls(t, "GetAdjacentNoteDates", 1)
+}
+
 func TestNoteService_Upsert(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -222,6 +588,20 @@ func TestNoteService_Upsert(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			name:        "Success - Local-only note isn't synced",
+			userID:      "user123",
+			contextName: "journal",
+			date:        "2025-10-18",
+			content:     "Private",
+			mockRepoSetup: func(repo *MockRepository) {
+				repo.On("UpsertNote", mock.AnythingOfType("*models.Note"), true).Run(func(args mock.Arguments) {
+					args.Get(0).(*models.Note).LocalOnly = true
+				}).Return(nil)
+			},
+			mockWorkerSetup: func(worker *MockSyncWorker) {},
+			expectedError:   nil,
+		},
 		{
 			name:        "Error - Repository upsert fails",
 			userID:      "user123",
@@ -251,15 +631,16 @@ func TestNoteService_Upsert(t *testing.T) {
 			}
 
 			service := &NoteService{
+				clock:      clock.Real(),
 				repo:       mockRepo,
 				syncWorker: mockWorker,
 			}
 
-			note, err := service.Upsert(tt.userID, tt.contextName, tt.date, tt.content)
+			note, err := service.Upsert(context.Background(), tt.userID, tt.contextName, tt.date, tt.content)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
-				assert.Equal(t, tt.expectedError.Error(), err.Error())
+				assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 				assert.Nil(t, note)
 			} else {
 				assert.NoError(t, err)
@@ -278,6 +659,834 @@ func TestNoteService_Upsert(t *testing.T) {
 	}
 }
 
+func TestNoteService_UpsertAtRevision(t *testing.T) {
+	t.Run("Success - Revision matches", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockWorker := new(MockSyncWorker)
+		mockRepo.On("UpsertNoteAtRevision", mock.AnythingOfType("*models.Note"), 3, true).Return(true, nil)
+		mockWorker.On("SyncNoteImmediate", "user123", "work", "2025-10-18").Return()
+
+		service := &NoteService{repo: mockRepo, syncWorker: mockWorker, clock: clock.Real()}
+
+		note, err := service.UpsertAtRevision(context.Background(), "user123", "work", "2025-10-18", "Edited", 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Edited", note.Content)
+		mockRepo.AssertExpectations(t)
+		mockWorker.AssertExpectations(t)
+	})
+
+	t.Run("Conflict - Returns current note", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		current := &models.Note{
+			UserID:   "user123",
+			Context:  "work",
+			Date:     "2025-10-18",
+			Content:  "Edited on another device",
+			Revision: 5,
+		}
+		mockRepo.On("UpsertNoteAtRevision", mock.AnythingOfType("*models.Note"), 3, true).Return(false, nil)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(current, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		note, err := service.UpsertAtRevision(context.Background(), "user123", "work", "2025-10-18", "Stale edit", 3)
+
+		assert.ErrorIs(t, err, ErrRevisionConflict)
+		assert.Equal(t, current, note)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestNoteService_Autosave(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockWorker := new(MockSyncWorker)
+	mockRepo.On("UpsertNote", mock.AnythingOfType("*models.Note"), true).Return(nil)
+	mockRepo.On("UpsertNoteAtRevision", mock.AnythingOfType("*models.Note"), 3, true).Return(true, nil)
+	mockWorker.On("SyncNoteDebounced", "user123", "work", "2025-10-18").Return()
+
+	service := &NoteService{repo: mockRepo, syncWorker: mockWorker, clock: clock.Real()}
+	ctx := context.WithValue(context.Background(), AutosaveKey, true)
+
+	_, err := service.Upsert(ctx, "user123", "work", "2025-10-18", "Draft")
+	assert.NoError(t, err)
+	_, err = service.UpsertAtRevision(ctx, "user123", "work", "2025-10-18", "Draft done", 3)
+	assert.NoError(t, err)
+
+	mockWorker.AssertNumberOfCalls(t, "SyncNoteDebounced", 2)
+	mockWorker.AssertNotCalled(t, "SyncNoteImmediate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNoteService_UpsertUnmodifiedSince(t *testing.T) {
+	since := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	t.Run("Success - Saves at the revision read", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		// Saved within the second of the header's date
+		current := &models.Note{Content: "Before", Revision: 4, UpdatedAt: since.Add(500 * time.Millisecond)}
+		mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(current, nil)
+		mockRepo.On("UpsertNoteAtRevision", mock.AnythingOfType("*models.Note"), 4, true).Return(true, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		note, err := service.UpsertUnmodifiedSince(context.Background(), "user123", "work", "2025-10-18", "Edited", since)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Edited", note.Content)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Success - Creates missing notes", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(nil, nil)
+		mockRepo.On("UpsertNoteAtRevision", mock.AnythingOfType("*models.Note"), 0, true).Return(true, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		_, err := service.UpsertUnmodifiedSince(context.Background(), "user123", "work", "2025-10-18", "First", since)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Conflict - Updated since", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		current := &models.Note{Content: "Edited on another device", Revision: 5, UpdatedAt: since.Add(time.Second)}
+		mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(current, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		note, err := service.UpsertUnmodifiedSince(context.Background(), "user123", "work", "2025-10-18", "Stale edit", since)
+
+		assert.ErrorIs(t, err, ErrRevisionConflict)
+		assert.Equal(t, current, note)
+		mockRepo.AssertNotCalled(t, "UpsertNoteAtRevision", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestNoteService_Calendar(t *testing.T) {
+	t.Run("Success - Every day of the month", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetCalendarDays", "user123", "work", "2025-02-01", "2025-02-28").Return([]models.CalendarDay{
+			{Date: "2025-02-03", Exists: true, WordCount: 12, SyncStatus: models.SyncStatusSynced},
+		}, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		days, err := service.Calendar(context.Background(), "user123", "work", 2025, 2)
+
+		require.NoError(t, err)
+		require.Len(t, days, 28)
+		assert.Equal(t, models.CalendarDay{Date: "2025-02-01"}, days[0])
+		assert.Equal(t, models.CalendarDay{Date: "2025-02-03", Exists: true, WordCount: 12, SyncStatus: models.SyncStatusSynced}, days[2])
+		assert.Equal(t, "2025-02-28", days[27].Date)
+	})
+
+	t.Run("Error - Invalid month", func(t *testing.T) {
+		service := &NoteService{repo: new(MockRepository), clock: clock.Real()}
+		_, err := service.Calendar(context.Background(), "user123", "work", 2025, 13)
+		assert.ErrorIs(t, err, ErrInvalidMonth)
+	})
+}
+
+func TestNoteService_UpsertBatch(t *testing.T) {
+	t.Run("Success - One save and one sync", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockWorker := new(MockSyncWorker)
+		mockRepo.On("UpsertNotes", mock.AnythingOfType("[]*models.Note"), true).Return(nil)
+		mockWorker.On("SyncNotesImmediate", "user123", mock.AnythingOfType("[]models.Note")).Return()
+
+		service := &NoteService{repo: mockRepo, syncWorker: mockWorker, clock: clock.Real()}
+
+		notes, err := service.UpsertBatch(context.Background(), "user123", []models.BatchNoteItem{
+			{Context: "work", Date: "2025-10-17", Content: "Offline 1"},
+			{Context: "work", Date: "2025-10-18", Content: "Offline 2"},
+		})
+
+		assert.NoError(t, err)
+		require.Len(t, notes, 2)
+		assert.Equal(t, "user123", notes[1].UserID)
+		assert.Equal(t, "Offline 2", notes[1].Content)
+		mockRepo.AssertExpectations(t)
+		mockWorker.AssertExpectations(t)
+	})
+
+	t.Run("Duplicate - Nothing is saved", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		_, err := service.UpsertBatch(context.Background(), "user123", []models.BatchNoteItem{
+			{Context: "work", Date: "2025-10-18", Content: "First"},
+			{Context: "work", Date: "2025-10-18", Content: "Second"},
+		})
+
+		assert.ErrorIs(t, err, ErrDuplicateNote)
+		mockRepo.AssertNotCalled(t, "UpsertNotes", mock.Anything, mock.Anything)
+	})
+}
+
+func TestNoteService_Tasks(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetTasks", "user123", models.TaskOpen, "work", 100, 0).Return([]models.Task{{ID: 1, Text: "ship"}}, nil)
+	mockRepo.On("GetTasks", "user123", "", "", 20, 0).Return([]models.Task{}, nil)
+	service := NewNoteService(mockRepo, nil)
+
+	tasks, err := service.Tasks(context.Background(), "user123", "open", "work", 0, -1)
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	_, err = service.Tasks(context.Background(), "user123", "all", "", 20, 0)
+	require.NoError(t, err)
+
+	_, err = service.Tasks(context.Background(), "user123", "someday", "", 20, 0)
+	assert.ErrorIs(t, err, ErrInvalidTaskStatus)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestNoteService_ToggleTask(t *testing.T) {
+	t.Run("Rewrites the task's line", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetTask", "user123", int64(7)).Return(&models.Task{ID: 7, Context: "work", Date: "2025-10-18", Line: 2, Text: "ship"}, nil)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(&models.Note{Content: "## Tasks\n- [ ] ship", Revision: 2}, nil)
+		mockRepo.On("UpsertNoteAtRevision", mock.MatchedBy(func(n *models.Note) bool {
+			return n.Content == "## Tasks\n- [x] ship"
+		}), 2, true).Return(true, nil)
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		task, note, err := service.ToggleTask(context.Background(), "user123", 7)
+		require.NoError(t, err)
+		assert.True(t, task.Done)
+		assert.Equal(t, "## Tasks\n- [x] ship", note.Content)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Missing and moved tasks", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetTask", "user123", int64(8)).Return(nil, nil)
+		mockRepo.On("GetTask", "user123", int64(7)).Return(&models.Task{ID: 7, Context: "work", Date: "2025-10-18", Line: 2, Text: "ship"}, nil)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(&models.Note{Content: "- [ ] ship\n- [ ] review", Revision: 3}, nil)
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		_, _, err := service.ToggleTask(context.Background(), "user123", 8)
+		assert.ErrorIs(t, err, ErrTaskNotFound)
+		_, _, err = service.ToggleTask(context.Background(), "user123", 7)
+		assert.ErrorIs(t, err, ErrTaskChanged)
+		mockRepo.AssertNotCalled(t, "UpsertNoteAtRevision", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestNoteService_Append(t *testing.T) {
+	t.Run("Appends under the section", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(&models.Note{
+			Content:  "## Tasks\n- [ ] ship\n\n## Notes\n",
+			Revision: 2,
+		}, nil)
+		mockRepo.On("UpsertNoteAtRevision", mock.MatchedBy(func(n *models.Note) bool {
+			return n.Content == "## Tasks\n- [ ] ship\n- [ ] review\n\n## Notes\n"
+		}), 2, true).Return(true, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		note, err := service.Append(context.Background(), "user123", "work", "2025-10-18", "- [ ] review", "Tasks", nil)
+
+		assert.NoError(t, err)
+		assert.Contains(t, note.Content, "- [ ] review")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Re-reads the note after a concurrent edit", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(&models.Note{Content: "a", Revision: 1}, nil).Once()
+		mockRepo.On("UpsertNoteAtRevision", mock.AnythingOfType("*models.Note"), 1, true).Return(false, nil).Once()
+		mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(&models.Note{Content: "a\nb", Revision: 2}, nil)
+		mockRepo.On("UpsertNoteAtRevision", mock.MatchedBy(func(n *models.Note) bool {
+			return n.Content == "a\nb\nc\n"
+		}), 2, true).Return(true, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		note, err := service.Append(context.Background(), "user123", "work", "2025-10-18", "c", "", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "a\nb\nc\n", note.Content)
+	})
+
+	t.Run("Stale base revision conflicts", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		current := &models.Note{Content: "a", Revision: 4}
+		mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(current, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+		base := 3
+		note, err := service.Append(context.Background(), "user123", "work", "2025-10-18", "b", "", &base)
+
+		assert.ErrorIs(t, err, ErrRevisionConflict)
+		assert.Equal(t, current, note)
+		mockRepo.AssertNotCalled(t, "UpsertNoteAtRevision", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestNoteService_Sections(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(&models.Note{
+		Content:  "## Standup\nyesterday\n\n## Habits\n- [x] run\n",
+		Revision: 2,
+	}, nil)
+	mockRepo.On("UpsertNoteAtRevision", mock.MatchedBy(func(n *models.Note) bool {
+		return n.Content == "## Standup\ntoday\n\n## Habits\n- [x] run\n"
+	}), 2, true).Return(true, nil)
+
+	service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+	_, sections, err := service.Sections(context.Background(), "user123", "work", "2025-10-18")
+	require.NoError(t, err)
+	require.Len(t, sections, 2)
+	assert.Equal(t, models.NoteSection{Slug: "standup", Title: "Standup", Level: 2, Line: 1, Content: "yesterday"}, sections[0])
+
+	_, section, err := service.UpdateSection(context.Background(), "user123", "work", "2025-10-18", "standup", "today", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "today", section.Content)
+
+	_, _, err = service.UpdateSection(context.Background(), "user123", "work", "2025-10-18", "missing", "x", nil)
+	assert.ErrorIs(t, err, ErrSectionNotFound)
+}
+
+func TestNoteService_Related(t *testing.T) {
+	mockRepo := new(MockRepository)
+	current := &models.Note{Context: "work", Date: "2025-10-18", Content: "Kubernetes deployment failed again #incident"}
+	mockRepo.On("GetNote", "user123", "work", "2025-10-18").Return(current, nil)
+	mockRepo.On("GetAllNotesByUser", "user123").Return([]models.Note{
+		*current,
+		{Context: "work", Date: "2025-10-19", Content: "Kubernetes deployment failed (future note)"},
+		{Context: "work", Date: "2025-06-03", Content: "Kubernetes deployment rollback after errors #incident"},
+		{Context: "personal", Date: "2025-06-04", Content: "Bought groceries"},
+	}, nil)
+
+	service := &NoteService{repo: mockRepo, clock: clock.Real()}
+
+	related, err := service.Related(context.Background(), "user123", "work", "2025-10-18", 5)
+
+	assert.NoError(t, err)
+	if assert.Len(t, related, 1) {
+		assert.Equal(t, "2025-06-03", related[0].Date)
+		assert.Equal(t, []string{"incident"}, related[0].SharedTags)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestNoteService_GetPeriod(t *testing.T) {
+	t.Run("Week note with rollup of daily notes", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNote", "user123", "work", "2025-W42").Return(nil, nil)
+		mockRepo.On("GetNotesByKeys", "user123", "work", []string{
+			"2025-10-13", "2025-10-14", "2025-10-15", "2025-10-16",
+			"2025-10-17", "2025-10-18", "2025-10-19",
+		}).Return([]models.Note{
+			{Context: "work", Date: "2025-10-15", Content: "Sprint review"},
+		}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		note, rollup, err := service.GetPeriod(context.Background(), "user123", "work", "week", "2025-W42")
+
+		require.NoError(t, err)
+		assert.Equal(t, "week", note.Type)
+		assert.Equal(t, "2025-W42", note.Date)
+		require.Len(t, rollup, 7)
+		assert.Equal(t, "2025-10-13", rollup[0].Key)
+		assert.False(t, rollup[0].Exists)
+		assert.True(t, rollup[2].Exists)
+		assert.Equal(t, "Sprint review", rollup[2].Excerpt)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Key must match type", func(t *testing.T) {
+		service := NewNoteService(new(MockRepository), nil)
+		_, _, err := service.GetPeriod(context.Background(), "user123", "work", "week", "2025-10-17")
+		assert.ErrorIs(t, err, ErrInvalidPeriodKey)
+
+		_, _, err = service.GetPeriod(context.Background(), "user123", "work", "day", "2025-10-17")
+		assert.ErrorIs(t, err, ErrInvalidPeriodKey)
+	})
+}
+
+func TestNoteService_Month(t *testing.T) {
+	t.Run("Previews by default", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNotesByKeys", "user123", "work", mock.MatchedBy(func(keys []string) bool {
+			return len(keys) == 29 && keys[0] == "2024-02-01" && keys[28] == "2024-02-29"
+		})).Return([]models.Note{
+			{Context: "work", Date: "2024-02-05", Content: "# Monday\n\n- Planning"},
+		}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		notes, truncated, err := service.Month(context.Background(), "user123", "work", 2024, 2, "")
+
+		require.NoError(t, err)
+		assert.False(t, truncated)
+		require.Len(t, notes, 1)
+		assert.Equal(t, "Monday Planning", notes[0].Preview)
+		assert.Empty(t, notes[0].Content)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Content past the budget is truncated", func(t *testing.T) {
+		big := strings.Repeat("a", monthContentBudget)
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNotesByKeys", "user123", "work", mock.Anything).Return([]models.Note{
+			{Context: "work", Date: "2024-02-01", Content: big},
+			{Context: "work", Date: "2024-02-02", Content: "second"},
+		}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		notes, truncated, err := service.Month(context.Background(), "user123", "work", 2024, 2, MonthIncludeContent)
+
+		require.NoError(t, err)
+		assert.True(t, truncated)
+		require.Len(t, notes, 2)
+		assert.Equal(t, big, notes[0].Content)
+		assert.True(t, notes[1].Truncated)
+		assert.Equal(t, "second", notes[1].Preview)
+	})
+
+	t.Run("Invalid input", func(t *testing.T) {
+		service := NewNoteService(new(MockRepository), nil)
+
+		_, _, err := service.Month(context.Background(), "user123", "work", 2024, 13, "")
+		assert.ErrorIs(t, err, ErrInvalidMonth)
+
+		_, _, err = service.Month(context.Background(), "user123", "work", 2024, 2, "html")
+		assert.ErrorIs(t, err, ErrInvalidInclude)
+	})
+}
+
+
+func TestNoteService_MonthNotes(t *testing.T) {
+	t.Run("Loads the contexts together", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetMonthNotes", "user123", []string{"work", "home"}, mock.MatchedBy(func(keys []string) bool {
+			return len(keys) == 31 && keys[0] == "2025-10-01"
+		})).Return([]models.Note{
+			{Context: "work", Date: "2025-10-01", Content: "Kickoff"},
+			{Context: "work", Date: "2025-10-02", Content: "Standup"},
+		}, nil).Once()
+
+		service := NewNoteService(mockRepo, nil)
+		notes, err := service.MonthNotes(context.Background(), "user123", []string{"work", "home"}, 2025, 10)
+
+		require.NoError(t, err)
+		assert.Len(t, notes["work"], 2)
+		assert.NotNil(t, notes["home"], "contexts without notes are listed empty")
+		assert.Empty(t, notes["home"])
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error - Invalid month", func(t *testing.T) {
+		service := NewNoteService(new(MockRepository), nil)
+		_, err := service.MonthNotes(context.Background(), "user123", []string{"work"}, 2025, 0)
+		assert.ErrorIs(t, err, ErrInvalidMonth)
+	})
+}
+func TestNoteService_Agenda(t *testing.T) {
+	t.Run("Groups notes by day with task rollups", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetAgendaNotes", "user123", "2025-10-13", "2025-10-19").Return([]models.AgendaNote{
+			{Context: "Home", Color: "success", Date: "2025-10-13", Content: "Groceries\n- [x] milk\n- [ ] bread #shopping"},
+			{Context: "Work", Color: "primary", Date: "2025-10-13", Content: "# Planning\n- [ ] roadmap"},
+			{Context: "Work", Color: "primary", Date: "2025-10-15", Content: "# Retro\n- [x] notes"},
+		}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		agenda, err := service.Agenda(context.Background(), "user123", "2025-10-13", "2025-10-19")
+
+		require.NoError(t, err)
+		require.Len(t, agenda.Days, 7)
+		assert.Equal(t, models.TaskRollup{Open: 2, Done: 2}, agenda.Rollup)
+
+		monday := agenda.Days[0]
+		assert.Equal(t, "Monday, October 13, 2025", monday.Title)
+		require.Len(t, monday.Notes, 2)
+		assert.Equal(t, models.TaskRollup{Open: 2, Done: 1}, monday.Rollup)
+		assert.Equal(t, "Groceries", monday.Notes[0].Title)
+		assert.Equal(t, []string{"shopping"}, monday.Notes[0].Tags)
+		assert.Equal(t, []models.AgendaTask{{Text: "milk", Done: true}, {Text: "bread #shopping"}}, monday.Notes[0].Tasks)
+		assert.Equal(t, "Planning", monday.Notes[1].Title)
+
+		assert.Empty(t, agenda.Days[1].Notes)
+		assert.Equal(t, models.TaskRollup{Done: 1}, agenda.Days[2].Rollup)
+	})
+
+	t.Run("Invalid ranges", func(t *testing.T) {
+		service := NewNoteService(new(MockRepository), nil)
+
+		_, err := service.Agenda(context.Background(), "user123", "2025-10-19", "2025-10-13")
+		assert.ErrorIs(t, err, ErrAgendaRange)
+
+		_, err = service.Agenda(context.Background(), "user123", "2025-01-01", "2025-03-31")
+		assert.ErrorIs(t, err, ErrAgendaRange)
+	})
+}
+
+func TestNoteService_Day(t *testing.T) {
+	t.Run("Lists the notes of every context", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNotesOnDate", "user123", "2025-10-18").Return([]models.AgendaNote{
+			{Context: "Home", Color: "success", Date: "2025-10-18", Content: "Groceries\n- [x] milk #shopping"},
+			{Context: "Work", Color: "primary", Date: "2025-10-18", Content: "# Release\n- [ ] tag v2"},
+		}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		day, err := service.Day(context.Background(), "user123", "2025-10-18")
+
+		require.NoError(t, err)
+		assert.Equal(t, "Saturday, October 18, 2025", day.Title)
+		assert.Equal(t, models.TaskRollup{Open: 1, Done: 1}, day.Rollup)
+		require.Len(t, day.Notes, 2)
+		assert.Equal(t, "Groceries", day.Notes[0].Title)
+		assert.Equal(t, []string{"shopping"}, day.Notes[0].Tags)
+		assert.Equal(t, []models.AgendaTask{{Text: "tag v2"}}, day.Notes[1].Tasks)
+	})
+
+	t.Run("Days without notes", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNotesOnDate", "user123", "2025-10-19").Return([]models.AgendaNote(nil), nil)
+
+		day, err := NewNoteService(mockRepo, nil).Day(context.Background(), "user123", "2025-10-19")
+
+		require.NoError(t, err)
+		assert.Empty(t, day.Notes)
+		assert.NotNil(t, day.Notes)
+	})
+
+	t.Run("Only days", func(t *testing.T) {
+		_, err := NewNoteService(new(MockRepository), nil).Day(context.Background(), "user123", "2025-W42")
+		assert.ErrorIs(t, err, ErrInvalidPeriodKey)
+	})
+}
+
+func TestNoteService_Meta(t *testing.T) {
+	t.Run("Sums up each day without content", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetAgendaNotes", "user123", "2025-10-13", "2025-10-15").Return([]models.AgendaNote{
+			{Context: "Home", Date: "2025-10-13", Content: "Mood: 7/10\n- [x] milk\n- [ ] bread #shopping"},
+			{Context: "Work", Date: "2025-10-13", Content: "Mood: tired\n# Planning #work #shopping\n- [ ] roadmap"},
+			{Context: "Work", Date: "2025-10-15", Content: "Retro notes"},
+		}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		meta, err := service.Meta(context.Background(), "user123", "2025-10-13", "2025-10-15")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"2025-10-13", "2025-10-14", "2025-10-15"}, meta.Dates)
+		assert.Equal(t, []int{2, 0, 1}, meta.Notes)
+		assert.Equal(t, []int{11, 0, 2}, meta.Words)
+		require.NotNil(t, meta.Moods[0])
+		assert.Equal(t, "7/10", *meta.Moods[0])
+		assert.Nil(t, meta.Moods[2])
+		assert.Equal(t, [][]string{{"shopping", "work"}, {}, {}}, meta.Tags)
+		assert.Equal(t, []int{2, 0, 0}, meta.TasksOpen)
+		assert.Equal(t, []int{1, 0, 0}, meta.TasksDone)
+	})
+
+	t.Run("Invalid ranges", func(t *testing.T) {
+		service := NewNoteService(new(MockRepository), nil)
+
+		_, err := service.Meta(context.Background(), "user123", "2025-10-19", "2025-10-13")
+		assert.ErrorIs(t, err, ErrMetaRange)
+
+		_, err = service.Meta(context.Background(), "user123", "2024-01-01", "2025-01-01")
+		assert.ErrorIs(t, err, ErrMetaRange)
+	})
+}
+
+func TestNoteService_Activity(t *testing.T) {
+	t.Run("Fills in the days without notes", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetActivityDays", "user123", "2025-10-14", "2025-10-16").Return([]models.ActivityDay{
+			{Date: "2025-10-15", Notes: 2, Words: 120},
+		}, nil)
+		// 23:30 UTC on the 15th is already the 16th in Madrid
+		mockRepo.On("GetContextStreaks", "user123", "2025-10-16").Return([]models.ContextStreak{
+			{Context: "Work", Current: 3, Longest: 9},
+		}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		service.SetClock(clock.NewFake(time.Date(2025, 10, 15, 23, 30, 0, 0, time.UTC)))
+		madrid, _ := time.LoadLocation("Europe/Madrid")
+		activity, err := service.Activity(context.Background(), "user123", "2025-10-14", "2025-10-16", madrid)
+
+		require.NoError(t, err)
+		assert.Equal(t, "2025-10-16", activity.Today)
+		assert.Equal(t, []models.ActivityDay{
+			{Date: "2025-10-14"},
+			{Date: "2025-10-15", Notes: 2, Words: 120},
+			{Date: "2025-10-16"},
+		}, activity.Days)
+		assert.Equal(t, []models.ContextStreak{{Context: "Work", Current: 3, Longest: 9}}, activity.Streaks)
+	})
+
+	t.Run("Invalid ranges", func(t *testing.T) {
+		service := NewNoteService(new(MockRepository), nil)
+
+		_, err := service.Activity(context.Background(), "user123", "2024-01-01", "2025-01-01", time.UTC)
+		assert.ErrorIs(t, err, ErrActivityRange)
+	})
+}
+
+func TestNoteService_Writing(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetWritingTotals", "user123").Return(&models.WritingTotals{Notes: 4, Words: 300, Chars: 1500}, nil)
+	mockRepo.On("GetAllActivityDays", "user123").Return([]models.ActivityDay{
+		{Date: "2025-10-06", Notes: 1, Words: 100}, // Monday
+		{Date: "2025-10-13", Notes: 2, Words: 50},  // Monday
+		{Date: "2025-10-19", Notes: 1, Words: 150}, // Sunday
+	}, nil)
+	mockRepo.On("GetContextWriting", "user123", mostActiveContexts).Return([]models.ContextWriting{
+		{Context: "Journal", Notes: 3, Words: 250, Chars: 1200},
+	}, nil)
+
+	stats, err := NewNoteService(mockRepo, nil).Writing(context.Background(), "user123")
+
+	require.NoError(t, err)
+	assert.Equal(t, 75.0, stats.Totals.AverageWords)
+	require.Len(t, stats.Weekdays, 7)
+	assert.Equal(t, models.WeekdayWriting{Weekday: "monday", Days: 2, Notes: 3, Words: 150, AverageWords: 75}, stats.Weekdays[0])
+	assert.Equal(t, models.WeekdayWriting{Weekday: "tuesday"}, stats.Weekdays[1])
+	assert.Equal(t, models.WeekdayWriting{Weekday: "sunday", Days: 1, Notes: 1, Words: 150, AverageWords: 150}, stats.Weekdays[6])
+	assert.Equal(t, "Journal", stats.Contexts[0].Context)
+}
+
+func TestNoteService_View(t *testing.T) {
+	t.Run("Renders the note with its neighbours", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNote", "user123", "Work", "2025-10-15").Return(&models.Note{
+			ID: "user123-Work-2025-10-15", UserID: "user123", Context: "Work", Date: "2025-10-15", Content: "# Retro\n**went well**",
+		}, nil)
+		mockRepo.On("GetAdjacentNoteDates", "user123", "Work", "2025-10-15").Return("2025-10-10", "", nil)
+		mockRepo.On("GetNotesOnDate", "user123", "2025-10-15").Return([]models.AgendaNote{
+			{Context: "Home", Color: "success", Date: "2025-10-15", Content: "Groceries\n- [ ] bread"},
+			{Context: "Work", Color: "primary", Date: "2025-10-15", Content: "# Retro"},
+		}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		view, err := service.View(context.Background(), "user123", "Work", "2025-10-15")
+
+		require.NoError(t, err)
+		assert.Equal(t, "user123-Work-2025-10-15", view.Note.ID)
+		assert.Contains(t, view.HTML, "<strong>went well</strong>")
+		assert.Equal(t, "Wednesday, October 15, 2025", view.Title)
+		assert.Equal(t, "2025-10-10", view.Previous)
+		assert.Empty(t, view.Next)
+		assert.Equal(t, []models.SameDayNote{{Context: "Home", Color: "success", Title: "Groceries"}}, view.SameDay)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Missing notes and invalid dates", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNote", "user123", "Work", "2025-10-16").Return(nil, nil)
+		service := NewNoteService(mockRepo, nil)
+
+		_, err := service.View(context.Background(), "user123", "Work", "2025-10-16")
+		assert.ErrorIs(t, err, ErrNoteNotFound)
+
+		_, err = service.View(context.Background(), "user123", "Work", "yesterday")
+		assert.ErrorIs(t, err, ErrInvalidPeriodKey)
+	})
+}
+
+func TestNoteService_Split(t *testing.T) {
+	source := &models.Note{ID: "user123-work-2025-10-17", Context: "work", Date: "2025-10-17", Revision: 3,
+		Content: "# Friday\n- ship release\n- call Ana\n- review PR"}
+
+	t.Run("Moves lines to the end of yesterday's note", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockWorker := new(MockSyncWorker)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-17").Return(source, nil)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-16").Return(&models.Note{
+			ID: "user123-work-2025-10-16", Content: "# Thursday\n", Revision: 2,
+		}, nil)
+		mockRepo.On("SplitNote",
+			mock.MatchedBy(func(n *models.Note) bool { return n.Content == "# Friday\n- review PR" }), 3,
+			mock.MatchedBy(func(n *models.Note) bool { return n.Content == "# Thursday\n\n- ship release\n- call Ana" }), 2,
+		).Return(true, nil)
+		mockWorker.On("SyncNoteImmediate", "user123", "work", "2025-10-17").Return()
+		mockWorker.On("SyncNoteImmediate", "user123", "work", "2025-10-16").Return()
+
+		service := NewNoteService(mockRepo, mockWorker)
+		updated, target, err := service.Split(context.Background(), "user123", "work", "2025-10-17", 2, 3, "work", "2025-10-16", nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "# Friday\n- review PR", updated.Content)
+		assert.Equal(t, "2025-10-16", target.Date)
+		mockRepo.AssertExpectations(t)
+		mockWorker.AssertExpectations(t)
+	})
+
+	t.Run("Stale base revision conflicts", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-17").Return(source, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		stale := 2
+		current, _, err := service.Split(context.Background(), "user123", "work", "2025-10-17", 1, 1, "work", "2025-10-16", &stale)
+
+		assert.ErrorIs(t, err, ErrRevisionConflict)
+		assert.Equal(t, 3, current.Revision)
+	})
+
+	t.Run("Line range outside the note", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-17").Return(source, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		_, _, err := service.Split(context.Background(), "user123", "work", "2025-10-17", 3, 9, "work", "2025-10-16", nil)
+
+		assert.ErrorIs(t, err, ErrInvalidLineRange)
+	})
+}
+
+func TestNoteService_Transfer(t *testing.T) {
+	keys := []string{"2025-10-17"}
+
+	t.Run("Refuses to overwrite without permission", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetContextByName", "user123", "personal").Return(&models.Context{Name: "personal"}, nil)
+		mockRepo.On("GetNotesByKeys", "user123", "personal", keys).Return([]models.Note{
+			{Context: "personal", Date: "2025-10-17", Content: "already here"},
+		}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		existing, err := service.Transfer(context.Background(), "user123", "work", "personal", keys, true, false)
+
+		assert.ErrorIs(t, err, ErrNoteExists)
+		require.Len(t, existing, 1)
+		mockRepo.AssertNotCalled(t, "TransferNotes", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Moves and syncs a single note immediately", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockWorker := new(MockSyncWorker)
+		mockRepo.On("GetContextByName", "user123", "personal").Return(&models.Context{Name: "personal"}, nil)
+		mockRepo.On("TransferNotes", "user123", "work", "personal", keys, true).Return([]models.Note{
+			{ID: "user123-personal-2025-10-17", Context: "personal", Date: "2025-10-17", Revision: 4},
+		}, nil)
+		mockWorker.On("SyncNoteImmediate", "user123", "personal", "2025-10-17").Return()
+
+		service := NewNoteService(mockRepo, mockWorker)
+		notes, err := service.Transfer(context.Background(), "user123", "work", "personal", keys, true, true)
+
+		require.NoError(t, err)
+		require.Len(t, notes, 1)
+		assert.Equal(t, 4, notes[0].Revision)
+		mockRepo.AssertExpectations(t)
+		mockWorker.AssertExpectations(t)
+	})
+
+	t.Run("Rejects same context", func(t *testing.T) {
+		service := NewNoteService(new(MockRepository), nil)
+		_, err := service.Transfer(context.Background(), "user123", "work", "work", keys, false, false)
+		assert.ErrorIs(t, err, ErrSameContext)
+	})
+}
+
+func TestNoteService_Redate(t *testing.T) {
+	dates := []string{"2025-10-16", "2025-10-17"}
+
+	t.Run("Refuses to overwrite notes outside the range", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNotesByKeys", "user123", "work", []string{"2025-10-15"}).Return([]models.Note{
+			{Context: "work", Date: "2025-10-15", Content: "already here"},
+		}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		existing, err := service.Redate(context.Background(), "user123", "work", dates, -1, false)
+
+		assert.ErrorIs(t, err, ErrNoteAtNewDate)
+		require.Len(t, existing, 1)
+		mockRepo.AssertNotCalled(t, "RedateNotes", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Moves a range and leaves the upload to the worker", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockWorker := new(MockSyncWorker)
+		mockRepo.On("GetNotesByKeys", "user123", "work", []string{"2025-10-18"}).Return(nil, nil)
+		mockRepo.On("RedateNotes", "user123", "work", dates, 1).Return([]models.Note{
+			{Context: "work", Date: "2025-10-17"},
+			{Context: "work", Date: "2025-10-18"},
+		}, nil)
+
+		service := NewNoteService(mockRepo, mockWorker)
+		notes, err := service.Redate(context.Background(), "user123", "work", dates, 1, false)
+
+		require.NoError(t, err)
+		assert.Len(t, notes, 2)
+		mockRepo.AssertExpectations(t)
+		mockWorker.AssertNotCalled(t, "SyncNoteImmediate", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Empty range", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("RedateNotes", "user123", "work", dates, 1).Return(nil, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		_, err := service.Redate(context.Background(), "user123", "work", dates, 1, true)
+		assert.ErrorIs(t, err, ErrNoteNotFound)
+	})
+}
+
+func TestNoteService_Backlinks(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetNotesByKeys", "user123", "work", []string{"2025-W42", "2025-10", "2025"}).Return([]models.Note{
+		{Context: "work", Date: "2025-10", Content: "October goals"},
+	}, nil)
+
+	service := NewNoteService(mockRepo, nil)
+	links, err := service.Backlinks(context.Background(), "user123", "work", "2025-10-17")
+
+	require.NoError(t, err)
+	require.Len(t, links, 3)
+	assert.Equal(t, models.NoteLink{Type: "week", Key: "2025-W42"}, links[0])
+	assert.Equal(t, models.NoteLink{Type: "month", Key: "2025-10", Exists: true, Excerpt: "October goals"}, links[1])
+	assert.Equal(t, "year", links[2].Type)
+}
+
+func TestNoteService_SeedPeriod(t *testing.T) {
+	t.Run("Seeds month digest from its weeks", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNote", "user123", "work", "2025-10").Return(nil, nil)
+		mockRepo.On("GetNotesByKeys", "user123", "work", mock.Anything).Return([]models.Note{
+			{Context: "work", Date: "2025-W42", Content: "Shipped the release"},
+		}, nil)
+		mockRepo.On("UpsertNote", mock.MatchedBy(func(n *models.Note) bool {
+			return n.Date == "2025-10" &&
+				n.Content == "# October 2025\n\n## Week 42, 2025\n\nShipped the release\n"
+		}), true).Return(nil)
+
+		service := NewNoteService(mockRepo, nil)
+		note, created, err := service.SeedPeriod(context.Background(), "user123", "work", "month", "2025-10")
+
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, "2025-10", note.Date)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Leaves existing notes untouched", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNote", "user123", "work", "2025").Return(&models.Note{Date: "2025", Content: "My year"}, nil)
+		mockRepo.On("GetNotesByKeys", "user123", "work", mock.Anything).Return([]models.Note{}, nil)
+
+		service := NewNoteService(mockRepo, nil)
+		note, created, err := service.SeedPeriod(context.Background(), "user123", "work", "year", "2025")
+
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, "My year", note.Content)
+		mockRepo.AssertNotCalled(t, "UpsertNote", mock.Anything, mock.Anything)
+	})
+}
+
 func TestNoteService_Delete(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -317,15 +1526,16 @@ func TestNoteService_Delete(t *testing.T) {
 			}
 
 			service := &NoteService{
+				clock:      clock.Real(),
 				repo:       mockRepo,
 				syncWorker: nil,
 			}
 
-			err := service.Delete(tt.userID, tt.contextName, tt.date)
+			err := service.Delete(context.Background(), tt.userID, tt.contextName, tt.date)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
-				assert.Equal(t, tt.expectedError.Error(), err.Error())
+				assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 			} else {
 				assert.NoError(t, err)
 			}
@@ -335,6 +1545,117 @@ func TestNoteService_Delete(t *testing.T) {
 	}
 }
 
+func TestNoteService_Trash(t *testing.T) {
+	now := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+	newService := func(repo *MockRepository) *NoteService {
+		service := NewNoteService(repo, nil)
+		service.SetClock(clock.NewFake(now))
+		service.SetTrashRetention(7 * 24 * time.Hour)
+		return service
+	}
+	weekAgo := now.Add(-7 * 24 * time.Hour)
+
+	t.Run("Deleted notes go to the trash, expired ones leave it", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("TrashNote", "user123", "work", "2025-10-17", now).Return(nil)
+		mockRepo.On("PurgeNoteTrash", "user123", weekAgo).Return(int64(1), nil)
+
+		require.NoError(t, newService(mockRepo).Delete(context.Background(), "user123", "work", "2025-10-17"))
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "DeleteNote", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Lists excerpts and when notes expire", func(t *testing.T) {
+		deletedAt := now.Add(-24 * time.Hour)
+		mockRepo := new(MockRepository)
+		mockRepo.On("PurgeNoteTrash", "user123", weekAgo).Return(int64(0), nil)
+		mockRepo.On("GetTrashedNotes", "user123", weekAgo).Return([]models.TrashedNote{
+			{ID: 2, Context: "work", Date: "2025-10-17", Content: "# Standup\nShipped it", DeletedAt: deletedAt},
+			{ID: 1, Context: "work", Date: "2025-10-16", Content: e2ee.Begin + "\nciphertext", DeletedAt: deletedAt},
+		}, nil)
+
+		notes, err := newService(mockRepo).Trash(context.Background(), "user123")
+		require.NoError(t, err)
+		require.Len(t, notes, 2)
+		assert.NotEmpty(t, notes[0].Excerpt)
+		assert.Equal(t, deletedAt.Add(7*24*time.Hour), notes[0].ExpiresAt)
+		assert.True(t, notes[1].Encrypted)
+		assert.Empty(t, notes[1].Excerpt, "encrypted notes have no excerpt")
+	})
+
+	t.Run("Restores into a free day of a live context", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetTrashedNote", "user123", int64(2)).Return(&models.TrashedNote{ID: 2, UserID: "user123", Context: "work", Date: "2025-10-17", Type: "day", Revision: 4, Content: "Shipped it", DeletedAt: now.Add(-time.Hour)}, nil)
+		mockRepo.On("GetContextByName", "user123", "work").Return(&models.Context{ID: "ctx1", UserID: "user123", Name: "work"}, nil)
+		mockRepo.On("RestoreTrashedNote", int64(2), mock.MatchedBy(func(n *models.Note) bool {
+			return n.UserID == "user123" && n.Context == "work" && n.Date == "2025-10-17" && n.Content == "Shipped it" && n.Type == "day" && n.Revision == 4
+		})).Return(true, nil)
+
+		note, err := newService(mockRepo).RestoreFromTrash(context.Background(), "user123", 2)
+		require.NoError(t, err)
+		assert.Equal(t, "Shipped it", note.Content)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Refuses expired notes, missing contexts and taken days", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetTrashedNote", "user123", int64(1)).Return(&models.TrashedNote{ID: 1, Context: "work", Date: "2025-10-01", DeletedAt: weekAgo.Add(-time.Hour)}, nil)
+		mockRepo.On("GetTrashedNote", "user123", int64(2)).Return(&models.TrashedNote{ID: 2, Context: "gone", Date: "2025-10-17", DeletedAt: now}, nil)
+		mockRepo.On("GetTrashedNote", "user123", int64(3)).Return(&models.TrashedNote{ID: 3, Context: "work", Date: "2025-10-17", DeletedAt: now}, nil)
+		mockRepo.On("GetTrashedNote", "user123", int64(4)).Return(nil, nil)
+		mockRepo.On("GetContextByName", "user123", "gone").Return(nil, nil)
+		mockRepo.On("GetContextByName", "user123", "work").Return(&models.Context{ID: "ctx1", UserID: "user123", Name: "work"}, nil)
+		mockRepo.On("RestoreTrashedNote", int64(3), mock.AnythingOfType("*models.Note")).Return(false, nil)
+		service := newService(mockRepo)
+
+		_, err := service.RestoreFromTrash(context.Background(), "user123", 1)
+		assert.ErrorIs(t, err, ErrNoteNotInTrash)
+		_, err = service.RestoreFromTrash(context.Background(), "user123", 2)
+		assert.ErrorIs(t, err, ErrContextNotFound)
+		_, err = service.RestoreFromTrash(context.Background(), "user123", 3)
+		assert.ErrorIs(t, err, ErrNoteDayTaken)
+		_, err = service.RestoreFromTrash(context.Background(), "user123", 4)
+		assert.ErrorIs(t, err, ErrNoteNotInTrash)
+	})
+}
+
+func TestNoteService_PublishesNoteEvents(t *testing.T) {
+	now := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+	mockRepo := new(MockRepository)
+	mockRepo.On("UpsertNote", mock.AnythingOfType("*models.Note"), true).Return(nil)
+	mockRepo.On("DeleteNote", "user123", "work", "2025-10-19").Return(nil)
+
+	events := pubsub.New[models.NoteEvent](pubsub.DefaultBuffer)
+	mine, unsubscribe := events.Subscribe("user123")
+	defer unsubscribe()
+	others, unsubscribeOthers := events.Subscribe("user456")
+	defer unsubscribeOthers()
+
+	service := &NoteService{repo: mockRepo, clock: clock.NewFake(now)}
+	service.SetNoteEvents(events)
+
+	_, err := service.Upsert(context.Background(), "user123", "work", "2025-10-18", "Edited")
+	require.NoError(t, err)
+	require.NoError(t, service.Delete(context.Background(), "user123", "work", "2025-10-19"))
+
+	assert.Equal(t, models.NoteEvent{
+		Type:    models.NoteEventUpdated,
+		Context: "work",
+		Date:    "2025-10-18",
+		Source:  models.NoteSourceAPI,
+		At:      now,
+	}, <-mine)
+	assert.Equal(t, models.NoteEvent{
+		Type:    models.NoteEventDeleted,
+		NoteID:  "user123-work-2025-10-19",
+		Context: "work",
+		Date:    "2025-10-19",
+		Source:  models.NoteSourceAPI,
+		At:      now,
+	}, <-mine)
+	assert.Empty(t, others, "other users get no events")
+}
+
 func TestNoteService_ListByContext(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -435,15 +1756,16 @@ func TestNoteService_ListByContext(t *testing.T) {
 			}
 
 			service := &NoteService{
+				clock:      clock.Real(),
 				repo:       mockRepo,
 				syncWorker: nil,
 			}
 
-			notes, err := service.ListByContext(tt.userID, tt.contextName, tt.limit, tt.offset)
+			notes, err := service.ListByContext(context.Background(), tt.userID, tt.contextName, tt.limit, tt.offset)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
-				assert.Equal(t, tt.expectedError.Error(), err.Error())
+				assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 				assert.Nil(t, notes)
 			} else {
 				assert.NoError(t, err)
@@ -455,6 +1777,184 @@ func TestNoteService_ListByContext(t *testing.T) {
 	}
 }
 
+func TestNoteService_Search(t *testing.T) {
+	t.Run("Empty query is rejected", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := &NoteService{clock: clock.Real(), repo: mockRepo}
+
+		_, err := service.Search(context.Background(), "user123", "   ", models.NoteSearchFilter{}, 20, 0)
+		assert.ErrorIs(t, err, ErrEmptySearch)
+		mockRepo.AssertNotCalled(t, "SearchNotes")
+	})
+
+	t.Run("Pagination is normalized", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		results := []models.NoteSearchResult{{Context: "work", Date: "2025-10-17", Snippet: "<mark>roadmap</mark>"}}
+		mockRepo.On("SearchNotes", "user123", "roadmap", models.NoteSearchFilter{}, 20, 0).Return(results, nil)
+		service := &NoteService{clock: clock.Real(), repo: mockRepo}
+
+		found, err := service.Search(context.Background(), "user123", "roadmap", models.NoteSearchFilter{}, 500, -1)
+		require.NoError(t, err)
+		assert.Equal(t, results, found)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Filters are passed on", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		filter := models.NoteSearchFilter{Context: "work", From: "2025-10-01", To: "2025-10-31", SyncStatus: models.SyncStatusFailed}
+		mockRepo.On("SearchNotes", "user123", "roadmap", filter, 20, 0).Return([]models.NoteSearchResult{}, nil)
+		service := &NoteService{clock: clock.Real(), repo: mockRepo}
+
+		_, err := service.Search(context.Background(), "user123", "roadmap", filter, 20, 0)
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestNoteService_ListByTag(t *testing.T) {
+	t.Run("Empty tag is rejected", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := &NoteService{clock: clock.Real(), repo: mockRepo}
+
+		_, err := service.ListByTag(context.Background(), "user123", " # ", 30, 0)
+		assert.ErrorIs(t, err, ErrEmptyTag)
+		mockRepo.AssertNotCalled(t, "GetNotesByTag")
+	})
+
+	t.Run("Tag and pagination are normalized", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		notes := []models.Note{{Context: "work", Date: "2025-10-17", Tags: []string{"release"}}}
+		mockRepo.On("GetNotesByTag", "user123", "release", 30, 0).Return(notes, nil)
+		service := &NoteService{clock: clock.Real(), repo: mockRepo}
+
+		found, err := service.ListByTag(context.Background(), "user123", "#Release", 0, -5)
+		require.NoError(t, err)
+		assert.Equal(t, notes, found)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestNoteService_RestoreRevision(t *testing.T) {
+	t.Run("Unknown revision", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNoteRevision", "user123", int64(7)).Return(nil, nil)
+		service := &NoteService{clock: clock.Real(), repo: mockRepo}
+
+		_, err := service.RestoreRevision(context.Background(), "user123", 7)
+		assert.ErrorIs(t, err, ErrRevisionNotFound)
+		mockRepo.AssertNotCalled(t, "UpsertNote")
+	})
+
+	t.Run("Saves the old content as the current note", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNoteRevision", "user123", int64(7)).Return(&models.NoteRevision{
+			ID: 7, Context: "work", Date: "2025-10-16", Revision: 2, Content: "old text",
+		}, nil)
+		mockRepo.On("UpsertNote", mock.MatchedBy(func(n *models.Note) bool {
+			return n.UserID == "user123" && n.Context == "work" && n.Date == "2025-10-16" && n.Content == "old text"
+		}), true).Return(nil)
+		service := &NoteService{clock: clock.Real(), repo: mockRepo}
+
+		note, err := service.RestoreRevision(context.Background(), "user123", 7)
+		require.NoError(t, err)
+		assert.Equal(t, "old text", note.Content)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestNoteService_ResolveConflict(t *testing.T) {
+	conflict := &models.NoteConflict{Context: "work", Date: "2025-10-16", LocalContent: "laptop", RemoteContent: "phone"}
+
+	t.Run("Note without conflict", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNoteConflict", "user123", "work", "2025-10-16").Return(nil, nil)
+		service := &NoteService{clock: clock.Real(), repo: mockRepo}
+
+		_, err := service.ResolveConflict(context.Background(), "user123", "work", "2025-10-16", ResolveLocal, "")
+		assert.ErrorIs(t, err, ErrConflictNotFound)
+		mockRepo.AssertNotCalled(t, "ResolveNoteConflict")
+	})
+
+	for resolution, want := range map[string]string{ResolveLocal: "laptop", ResolveRemote: "phone", ResolveMerged: "laptop and phone"} {
+		t.Run("Keeps the "+resolution+" content", func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("GetNoteConflict", "user123", "work", "2025-10-16").Return(conflict, nil)
+			mockRepo.On("ResolveNoteConflict", mock.MatchedBy(func(n *models.Note) bool {
+				return n.UserID == "user123" && n.Content == want
+			})).Return(true, nil)
+			service := &NoteService{clock: clock.Real(), repo: mockRepo}
+
+			note, err := service.ResolveConflict(context.Background(), "user123", "work", "2025-10-16", resolution, "laptop and phone")
+			require.NoError(t, err)
+			assert.Equal(t, want, note.Content)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNoteService_SizeWarning(t *testing.T) {
+	service := &NoteService{clock: clock.Real()}
+	note := &models.Note{Content: strings.Repeat("x", 3000)}
+
+	assert.Nil(t, service.SizeWarning(note), "disabled by default")
+
+	service.SetSizeWarning(2048)
+	warning := service.SizeWarning(note)
+	require.NotNil(t, warning)
+	assert.Equal(t, 3000, warning.Size)
+	assert.Equal(t, 2048, warning.Limit)
+	assert.Equal(t, []string{"split", "attachment"}, warning.Suggestions)
+	assert.Contains(t, warning.Message, "3 KB")
+
+	assert.Nil(t, service.SizeWarning(&models.Note{Content: "short"}))
+}
+
+func TestNoteService_Changes(t *testing.T) {
+	changes := []models.NoteChange{
+		{Cursor: 4, Kind: models.NoteCreated, NoteID: "n1"},
+		{Cursor: 7, Kind: models.NoteDeleted, NoteID: "n2"},
+		{Cursor: 9, Kind: models.NoteUpdated, NoteID: "n3"},
+	}
+
+	t.Run("A full page says more follow", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNoteChanges", "user123", int64(0), 3).Return(changes, nil)
+		service := NewNoteService(mockRepo, nil)
+
+		page, err := service.Changes(context.Background(), "user123", -5, 2)
+		require.NoError(t, err)
+		assert.Len(t, page.Changes, 2)
+		assert.Equal(t, int64(7), page.Cursor)
+		assert.True(t, page.HasMore)
+	})
+
+	t.Run("Without changes the cursor stays", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetNoteChanges", "user123", int64(9), 101).Return([]models.NoteChange{}, nil)
+		service := NewNoteService(mockRepo, nil)
+
+		page, err := service.Changes(context.Background(), "user123", 9, 0)
+		require.NoError(t, err)
+		assert.Empty(t, page.Changes)
+		assert.Equal(t, int64(9), page.Cursor)
+		assert.False(t, page.HasMore)
+	})
+}
+
+func TestNoteService_SizeStats(t *testing.T) {
+	mockRepo := new(MockRepository)
+	stats := &models.NoteSizeStats{Count: 2, Limit: 2048}
+	mockRepo.On("GetNoteSizeStats", "user123", 2048, noteLargestListed).Return(stats, nil)
+
+	service := &NoteService{clock: clock.Real(), repo: mockRepo}
+	service.SetSizeWarning(2048)
+
+	found, err := service.SizeStats(context.Background(), "user123")
+	require.NoError(t, err)
+	assert.Equal(t, stats, found)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestNoteService_GetSyncStatus(t *testing.T) {
 	now := time.Now()
 
@@ -478,13 +1978,21 @@ func TestNoteService_GetSyncStatus(t *testing.T) {
 				}
 				repo.On("GetFailedSyncNotes", "user123", 50).Return(failedNotes, nil)
 				repo.On("GetPendingSyncNotes", 50).Return(pendingNotes, nil)
+				repo.On("CountLocalOnlyNotes", "user123").Return(2, nil)
+				repo.On("GetUserSyncOperations", "user123").Return([]models.SyncOperation{
+					{ID: 1, UserID: "user123", Kind: models.SyncOperationRename, ContextID: "ctx1", ContextName: "work", NewName: "projects", Status: models.SyncStatusFailed},
+				}, nil)
 			},
 			expectedStatus: map[string]interface{}{
 				"pending_count": 1, // Only user123's pending notes
 				"failed_count":  1,
+				"local_only_count": 2,
 				"failed_notes": []models.Note{
 					{ID: "user123-work-2025-10-18", UserID: "user123", SyncStatus: models.SyncStatusFailed},
 				},
+				"failed_operations": []models.SyncOperation{
+					{ID: 1, UserID: "user123", Kind: models.SyncOperationRename, ContextID: "ctx1", ContextName: "work", NewName: "projects", Status: models.SyncStatusFailed},
+				},
 			},
 			expectedError: nil,
 		},
@@ -494,11 +2002,15 @@ func TestNoteService_GetSyncStatus(t *testing.T) {
 			mockSetup: func(repo *MockRepository) {
 				repo.On("GetFailedSyncNotes", "user123", 50).Return([]models.Note{}, nil)
 				repo.On("GetPendingSyncNotes", 50).Return([]database.NoteWithMeta{}, nil)
+				repo.On("CountLocalOnlyNotes", "user123").Return(0, nil)
+				repo.On("GetUserSyncOperations", "user123").Return([]models.SyncOperation{}, nil)
 			},
 			expectedStatus: map[string]interface{}{
 				"pending_count": 0,
 				"failed_count":  0,
+				"local_only_count": 0,
 				"failed_notes":  []models.Note{},
+				"failed_operations": []models.SyncOperation{},
 			},
 			expectedError: nil,
 		},
@@ -531,21 +2043,24 @@ func TestNoteService_GetSyncStatus(t *testing.T) {
 			}
 
 			service := &NoteService{
+				clock:      clock.Real(),
 				repo:       mockRepo,
 				syncWorker: nil,
 			}
 
-			status, err := service.GetSyncStatus(tt.userID)
+			status, err := service.GetSyncStatus(context.Background(), tt.userID)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
-				assert.Equal(t, tt.expectedError.Error(), err.Error())
+				assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 				assert.Nil(t, status)
 			} else {
 				assert.NoError(t, err)
 				assert.NotNil(t, status)
 				assert.Equal(t, tt.expectedStatus["pending_count"], status["pending_count"])
 				assert.Equal(t, tt.expectedStatus["failed_count"], status["failed_count"])
+				assert.Equal(t, tt.expectedStatus["local_only_count"], status["local_only_count"])
+				assert.Equal(t, tt.expectedStatus["failed_operations"], status["failed_operations"])
 			}
 
 			mockRepo.AssertExpectations(t)
@@ -556,6 +2071,39 @@ func TestNoteService_GetSyncStatus(t *testing.T) {
 	_ = now
 }
 
+func TestNoteService_RetryAllSync(t *testing.T) {
+	t.Run("Queues failed notes and operations and retries right away", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockWorker := new(MockSyncWorker)
+		mockRepo.On("RetryUserSyncNotes", "user123").Return(3, nil)
+		mockRepo.On("RetryUserSyncOperations", "user123").Return(1, nil)
+		mockWorker.On("RetryNow").Return()
+
+		service := NewNoteService(mockRepo, mockWorker)
+		notes, operations, err := service.RetryAllSync(context.Background(), "user123")
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, notes)
+		assert.Equal(t, 1, operations)
+		mockRepo.AssertExpectations(t)
+		mockWorker.AssertExpectations(t)
+	})
+
+	t.Run("Nothing to retry", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockWorker := new(MockSyncWorker)
+		mockRepo.On("RetryUserSyncNotes", "user123").Return(0, nil)
+		mockRepo.On("RetryUserSyncOperations", "user123").Return(0, nil)
+
+		service := NewNoteService(mockRepo, mockWorker)
+		notes, operations, err := service.RetryAllSync(context.Background(), "user123")
+
+		require.NoError(t, err)
+		assert.Zero(t, notes+operations)
+		mockWorker.AssertNotCalled(t, "RetryNow")
+	})
+}
+
 func TestNoteService_RetrySync(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -606,18 +2154,19 @@ func TestNoteService_RetrySync(t *testing.T) {
 			}
 
 			service := &NoteService{
+				clock:      clock.Real(),
 				repo:       mockRepo,
 				syncWorker: nil,
 			}
 
-			err := service.RetrySync(tt.noteID, tt.userID)
+			err := service.RetrySync(context.Background(), tt.noteID, tt.userID)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
 				if errors.Is(tt.expectedError, ErrUnauthorized) {
 					assert.ErrorIs(t, err, ErrUnauthorized)
 				} else {
-					assert.Equal(t, tt.expectedError.Error(), err.Error())
+					assert.Equal(t, tt.expectedError.Error(), errors.Unwrap(err).Error())
 				}
 			} else {
 				assert.NoError(t, err)
@@ -627,3 +2176,138 @@ func TestNoteService_RetrySync(t *testing.T) {
 		})
 	}
 }
+
+func TestNoteService_SetLocalOnly(t *testing.T) {
+	t.Run("Marks the note without syncing it", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockWorker := new(MockSyncWorker)
+		mockRepo.On("SetNoteLocalOnly", "user123", "journal", "2025-10-18", true).Return(true, nil)
+		mockRepo.On("GetNote", "user123", "journal", "2025-10-18").Return(&models.Note{
+			UserID: "user123", Context: "journal", Date: "2025-10-18", LocalOnly: true, SyncStatus: models.SyncStatusLocal,
+		}, nil)
+
+		service := &NoteService{repo: mockRepo, syncWorker: mockWorker, clock: clock.Real()}
+		note, err := service.SetLocalOnly(context.Background(), "user123", "journal", "2025-10-18", true)
+
+		require.NoError(t, err)
+		assert.True(t, note.LocalOnly)
+		mockRepo.AssertExpectations(t)
+		mockWorker.AssertNotCalled(t, "SyncNoteImmediate", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Syncs the note once it may", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockWorker := new(MockSyncWorker)
+		mockRepo.On("SetNoteLocalOnly", "user123", "journal", "2025-10-18", false).Return(true, nil)
+		mockRepo.On("GetNote", "user123", "journal", "2025-10-18").Return(&models.Note{
+			UserID: "user123", Context: "journal", Date: "2025-10-18", SyncStatus: models.SyncStatusPending,
+		}, nil)
+		mockWorker.On("SyncNoteImmediate", "user123", "journal", "2025-10-18").Return()
+
+		service := &NoteService{repo: mockRepo, syncWorker: mockWorker, clock: clock.Real()}
+		_, err := service.SetLocalOnly(context.Background(), "user123", "journal", "2025-10-18", false)
+
+		require.NoError(t, err)
+		mockWorker.AssertExpectations(t)
+	})
+
+	t.Run("Missing note", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("SetNoteLocalOnly", "user123", "journal", "2025-10-18", true).Return(false, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.Real()}
+		_, err := service.SetLocalOnly(context.Background(), "user123", "journal", "2025-10-18", true)
+
+		assert.ErrorIs(t, err, ErrNoteNotFound)
+	})
+}
+
+func TestNoteService_SetPinned(t *testing.T) {
+	now := time.Date(2025, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	t.Run("Pins the note at the current time", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("SetNotePinned", "user123", "work", "2025-10-17", &now).Return(true, nil)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-17").Return(&models.Note{
+			UserID: "user123", Context: "work", Date: "2025-10-17", Pinned: true,
+		}, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.NewFake(now)}
+		note, err := service.SetPinned(context.Background(), "user123", "work", "2025-10-17", true)
+
+		require.NoError(t, err)
+		assert.True(t, note.Pinned)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Unpins the note", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("SetNotePinned", "user123", "work", "2025-10-17", (*time.Time)(nil)).Return(true, nil)
+		mockRepo.On("GetNote", "user123", "work", "2025-10-17").Return(&models.Note{
+			UserID: "user123", Context: "work", Date: "2025-10-17",
+		}, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.NewFake(now)}
+		note, err := service.SetPinned(context.Background(), "user123", "work", "2025-10-17", false)
+
+		require.NoError(t, err)
+		assert.False(t, note.Pinned)
+	})
+
+	t.Run("Missing note", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("SetNotePinned", "user123", "work", "2025-10-17", &now).Return(false, nil)
+
+		service := &NoteService{repo: mockRepo, clock: clock.NewFake(now)}
+		_, err := service.SetPinned(context.Background(), "user123", "work", "2025-10-17", true)
+
+		assert.ErrorIs(t, err, ErrNoteNotFound)
+	})
+}
+
+func TestNoteService_ResolveAppend(t *testing.T) {
+	service := NewNoteService(new(MockRepository), nil)
+	service.SetClock(clock.NewFake(time.Date(2025, 10, 16, 23, 30, 0, 0, time.UTC)))
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	t.Run("Appends to today's note in the timezone", func(t *testing.T) {
+		req := service.ResolveAppend(models.AppendNoteRequest{Context: "Work", Content: "Call Sam", Section: "Log"}, tokyo)
+		assert.Equal(t, models.CreateNoteRequest{Context: "Work", Date: "2025-10-17", Content: "Call Sam", Append: true, Section: "Log"}, req)
+
+		req = service.ResolveAppend(models.AppendNoteRequest{Context: "Work", Content: "Call Sam"}, time.UTC)
+		assert.Equal(t, "2025-10-16", req.Date)
+	})
+
+	t.Run("Timestamps are local and follow list markers", func(t *testing.T) {
+		req := service.ResolveAppend(models.AppendNoteRequest{Context: "Work", Date: "2025-10-01", Content: "- [ ] Call Sam", Timestamp: true}, tokyo)
+		assert.Equal(t, "2025-10-01", req.Date)
+		assert.Equal(t, "- [ ] 08:30 Call Sam", req.Content)
+	})
+}
+
+func TestNoteService_PrerenderShared(t *testing.T) {
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetLiveSharedNote", "shared-note", now).Return(&models.Note{ID: "shared-note", Revision: 3, Content: "# Plans"}, nil)
+	mockRepo.On("GetLiveSharedNote", "private-note", now).Return(nil, nil)
+
+	renders := rendercache.New(10)
+	renders.Put("shared-note", 2, []byte("<h1>Old plans</h1>"))
+	renders.Put("private-note", 1, []byte("<p>Diary</p>"))
+	service := NewNoteService(mockRepo, nil)
+	service.SetClock(clock.NewFake(now))
+	service.SetRenderCache(renders)
+
+	service.invalidateRender("shared-note")
+	service.invalidateRender("private-note")
+	service.WaitPrerenders()
+
+	html, ok := renders.Get("shared-note", 3)
+	require.True(t, ok, "notes with live share links are rendered again")
+	assert.Contains(t, string(html), "Plans")
+	_, ok = renders.Get("shared-note", 2)
+	assert.False(t, ok)
+	assert.Equal(t, 1, renders.Len(), "other notes are only dropped")
+	mockRepo.AssertExpectations(t)
+}
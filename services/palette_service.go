@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/markdown"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Palette result types
+const (
+	PaletteTypeContext = "context"
+	PaletteTypeNote    = "note"
+	PaletteTypeTag     = "tag"
+	PaletteTypeCommand = "command"
+)
+
+// Type weights applied on top of the match score so that exact commands and
+// contexts outrank loose content matches
+var paletteTypeWeights = map[string]float64{
+	PaletteTypeCommand: 1.0,
+	PaletteTypeContext: 0.95,
+	PaletteTypeTag:     0.85,
+	PaletteTypeNote:    0.7,
+}
+
+// paletteCommands are the static actions the palette can run
+var paletteCommands = []models.PaletteResult{
+	{Type: PaletteTypeCommand, Title: "Go to today", Action: "goto-today"},
+	{Type: PaletteTypeCommand, Title: "New context", Action: "new-context"},
+	{Type: PaletteTypeCommand, Title: "Open settings", Action: "open-settings"},
+	{Type: PaletteTypeCommand, Title: "Show sync status", Action: "sync-status"},
+	{Type: PaletteTypeCommand, Title: "Toggle theme", Action: "toggle-theme"},
+	{Type: PaletteTypeCommand, Title: "Sign out", Action: "logout"},
+}
+
+// PaletteService searches contexts, dates, tags, notes and commands in one ranked list
+type PaletteService struct {
+	repo  PaletteRepository
+	clock clock.Clock
+}
+
+// NewPaletteService creates a new palette service
+func NewPaletteService(repo PaletteRepository) *PaletteService {
+	return &PaletteService{repo: repo, clock: clock.Real()}
+}
+
+// SetClock replaces the clock that resolves "today", "yesterday" and "tomorrow"
+func (ps *PaletteService) SetClock(c clock.Clock) {
+	ps.clock = c
+}
+
+// Search returns typed results for the query, best match first
+func (ps *PaletteService) Search(ctx context.Context, userID, query string, limit int) (_ []models.PaletteResult, err error) {
+	defer wrapOp("search palette", &err)
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+
+	query = strings.TrimSpace(query)
+	results := make([]models.PaletteResult, 0)
+	if query == "" {
+		return results, nil
+	}
+
+	for _, cmd := range paletteCommands {
+		if score := matchScore(query, cmd.Title); score > 0 {
+			cmd.Score = score
+			results = append(results, cmd)
+		}
+	}
+
+	contexts, err := ps.repo.GetContexts(ctx, database.ScopeUser(userID))
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range contexts {
+		if score := matchScore(query, c.Name); score > 0 {
+			results = append(results, models.PaletteResult{
+				Type:    PaletteTypeContext,
+				Title:   c.Name,
+				Context: c.Name,
+				Color:   c.Color,
+				Score:   score,
+			})
+		}
+	}
+
+	notes, err := ps.repo.GetAllNotesByUser(ctx, database.ScopeUser(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	date, isDate := parsePaletteDate(query, ps.clock.Now())
+	tagQuery := strings.TrimPrefix(strings.ToLower(query), "#")
+	tagCounts := make(map[string]int)
+	lowerQuery := strings.ToLower(query)
+
+	for _, note := range notes {
+		for _, tag := range markdown.ExtractHashtags(note.Content) {
+			tagCounts[tag]++
+		}
+
+		var score float64
+		switch {
+		case isDate && note.Date == date:
+			score = 1
+		case strings.Contains(strings.ToLower(note.Content), lowerQuery):
+			score = 0.6
+		}
+		if score == 0 {
+			continue
+		}
+
+		results = append(results, models.PaletteResult{
+			Type:     PaletteTypeNote,
+			Title:    note.Date,
+			Subtitle: markdown.Excerpt(note.Content, 80),
+			Context:  note.Context,
+			Date:     note.Date,
+			Score:    score,
+		})
+	}
+
+	for tag, count := range tagCounts {
+		if score := matchScore(tagQuery, tag); score > 0 {
+			results = append(results, models.PaletteResult{
+				Type:  PaletteTypeTag,
+				Title: "#" + tag,
+				Count: count,
+				Score: score,
+			})
+		}
+	}
+
+	// A recognised date is always reachable, even if no note exists yet
+	if isDate {
+		results = append(results, models.PaletteResult{
+			Type:   PaletteTypeCommand,
+			Title:  "Go to " + date,
+			Date:   date,
+			Action: "goto-date",
+			Score:  1,
+		})
+	}
+
+	for i := range results {
+		results[i].Score *= paletteTypeWeights[results[i].Type]
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Title < results[j].Title
+		}
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// matchScore rates how well text matches the query: exact, prefix, word prefix,
+// substring and finally in-order subsequence ("stst" matches "Show sync status")
+func matchScore(query, text string) float64 {
+	q := strings.ToLower(query)
+	t := strings.ToLower(text)
+
+	switch {
+	case q == "" || t == "":
+		return 0
+	case t == q:
+		return 1
+	case strings.HasPrefix(t, q):
+		return 0.9
+	case strings.Contains(t, " "+q):
+		return 0.8
+	case strings.Contains(t, q):
+		return 0.6
+	}
+
+	// Subsequence match
+	remaining := []rune(q)
+	for _, r := range t {
+		if len(remaining) > 0 && r == remaining[0] {
+			remaining = remaining[1:]
+		}
+	}
+	if len(remaining) == 0 {
+		return 0.3
+	}
+	return 0
+}
+
+// parsePaletteDate recognises YYYY-MM-DD dates and relative keywords
+func parsePaletteDate(query string, now time.Time) (string, bool) {
+	switch strings.ToLower(query) {
+	case "today":
+		return now.Format("2006-01-02"), true
+	case "yesterday":
+		return now.AddDate(0, 0, -1).Format("2006-01-02"), true
+	case "tomorrow":
+		return now.AddDate(0, 0, 1).Format("2006-01-02"), true
+	}
+
+	if t, err := time.Parse("2006-01-02", query); err == nil {
+		return t.Format("2006-01-02"), true
+	}
+	return "", false
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaletteService_Search(t *testing.T) {
+	newService := func() (*PaletteService, *MockContextRepository) {
+		repo := new(MockContextRepository)
+		repo.On("GetContexts", "user123").Return([]models.Context{
+			{Name: "Work", Color: "primary"},
+			{Name: "Personal", Color: "success"},
+		}, nil)
+		repo.On("GetAllNotesByUser", "user123").Return([]models.Note{
+			{Context: "Work", Date: "2025-10-17", Content: "Planning #roadmap with the team"},
+			{Context: "Personal", Date: "2025-10-18", Content: "Groceries and #workout"},
+		}, nil)
+		return NewPaletteService(repo), repo
+	}
+
+	t.Run("Empty query returns nothing", func(t *testing.T) {
+		service, repo := newService()
+		results, err := service.Search(context.Background(), "user123", "  ", 20)
+		require.NoError(t, err)
+		assert.Empty(t, results)
+		repo.AssertNotCalled(t, "GetContexts", "user123")
+	})
+
+	t.Run("Exact context ranks first", func(t *testing.T) {
+		service, _ := newService()
+		results, err := service.Search(context.Background(), "user123", "work", 20)
+		require.NoError(t, err)
+		require.NotEmpty(t, results)
+		assert.Equal(t, PaletteTypeContext, results[0].Type)
+		assert.Equal(t, "Work", results[0].Title)
+
+		var types []string
+		for _, r := range results {
+			types = append(types, r.Type)
+		}
+		assert.Contains(t, types, PaletteTypeTag)
+		assert.Contains(t, types, PaletteTypeNote)
+	})
+
+	t.Run("Date query finds notes and a goto command", func(t *testing.T) {
+		service, _ := newService()
+		results, err := service.Search(context.Background(), "user123", "2025-10-18", 20)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, PaletteTypeCommand, results[0].Type)
+		assert.Equal(t, "goto-date", results[0].Action)
+		assert.Equal(t, PaletteTypeNote, results[1].Type)
+		assert.Equal(t, "Personal", results[1].Context)
+	})
+
+	t.Run("Limit caps results", func(t *testing.T) {
+		service, _ := newService()
+		results, err := service.Search(context.Background(), "user123", "o", 2)
+		require.NoError(t, err)
+		assert.Len(t, results, 2)
+	})
+}
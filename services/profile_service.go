@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/visibility"
+	"sort"
+	"strings"
+)
+
+// ProfileVersion is the format version written to exported profiles
+const ProfileVersion = 1
+
+// ProfileService exports and imports a user's setup (settings, contexts, templates)
+// so it can be replicated on another instance. Note content is only included in
+// account exports (ExportAccount).
+type ProfileService struct {
+	repo  ProfileRepository
+	clock clock.Clock
+	ids   idgen.Generator
+}
+
+// NewProfileService creates a new profile service
+func NewProfileService(repo ProfileRepository) *ProfileService {
+	return &ProfileService{repo: repo, clock: clock.Real(), ids: idgen.UUID()}
+}
+
+// SetClock replaces the clock used for export and creation timestamps
+func (ps *ProfileService) SetClock(c clock.Clock) {
+	ps.clock = c
+}
+
+// SetIDGenerator replaces the generator used for imported context IDs
+func (ps *ProfileService) SetIDGenerator(g idgen.Generator) {
+	ps.ids = g
+}
+
+// Export builds the portable profile for a user
+func (ps *ProfileService) Export(ctx context.Context, userID string, settings models.UserSettings) (_ *models.Profile, err error) {
+	defer wrapOp("export profile", &err)
+	profile, _, err := ps.export(ctx, userID, settings, visibility.Export)
+	return profile, err
+}
+
+// ExportAccount builds the profile of a user together with all their notes, a
+// document that restores the account on this or another instance. Local-only
+// notes are only exported with includeLocalOnly, marked so they stay local
+// only when the export is imported.
+func (ps *ProfileService) ExportAccount(ctx context.Context, userID string, settings models.UserSettings, includeLocalOnly bool) (_ *models.AccountExport, err error) {
+	defer wrapOp("export account", &err)
+	policy := visibility.Export
+	if includeLocalOnly {
+		policy = policy.WithLocalOnly()
+	}
+	profile, notes, err := ps.export(ctx, userID, settings, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &models.AccountExport{
+		Profile: *profile,
+		Notes:   make([]models.ExportedNote, 0, len(notes)),
+	}
+	for _, note := range notes {
+		account.Notes = append(account.Notes, models.ExportedNote{
+			Context:   note.Context,
+			Type:      note.Type,
+			Date:      note.Date,
+			Content:   note.Content,
+			LocalOnly: note.LocalOnly,
+			CreatedAt: note.CreatedAt,
+			UpdatedAt: note.UpdatedAt,
+		})
+	}
+	sort.Slice(account.Notes, func(i, j int) bool {
+		a, b := account.Notes[i], account.Notes[j]
+		if a.Context != b.Context {
+			return a.Context < b.Context
+		}
+		return a.Date < b.Date
+	})
+
+	return account, nil
+}
+
+// export builds the profile of a user and returns the notes it was built from,
+// those policy shows
+func (ps *ProfileService) export(ctx context.Context, userID string, settings models.UserSettings, policy visibility.Policy) (*models.Profile, []models.Note, error) {
+	contexts, err := ps.repo.GetContexts(ctx, database.ScopeUser(userID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notes, err := ps.repo.GetVisibleNotes(ctx, database.ScopeUser(userID), policy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profile := &models.Profile{
+		Version:    ProfileVersion,
+		ExportedAt: ps.clock.Now(),
+		Settings: models.UpdateSettingsRequest{
+			Theme:                settings.Theme,
+			WeekStart:            settings.WeekStart,
+			Timezone:             settings.Timezone,
+			DateFormat:           settings.DateFormat,
+			UniqueContextMode:    settings.UniqueContextMode,
+			ShowBreadcrumb:       settings.ShowBreadcrumb,
+			ShowMarkdownEditor:   settings.ShowMarkdownEditor,
+			HideNewContextButton: settings.HideNewContextButton,
+			SuggestContext:       settings.SuggestContext,
+		},
+		Contexts: make([]models.ProfileContext, 0, len(contexts)),
+		Tags:     make([]string, 0),
+	}
+
+	for _, c := range contexts {
+		profile.Contexts = append(profile.Contexts, models.ProfileContext{
+			Name:      c.Name,
+			Color:     c.Color,
+			Icon:      c.Icon,
+			Template:  c.Template,
+			LocalOnly: c.LocalOnly,
+			Language:  c.Language,
+		})
+	}
+
+	// Tags live in note content; only their names travel with the profile
+	seen := make(map[string]bool)
+	for _, note := range notes {
+		for _, tag := range markdown.ExtractHashtags(note.Content) {
+			if !seen[tag] {
+				seen[tag] = true
+				profile.Tags = append(profile.Tags, tag)
+			}
+		}
+	}
+	sort.Strings(profile.Tags)
+
+	return profile, notes, nil
+}
+
+// Import applies a profile: settings are replaced, missing contexts are created and
+// existing contexts (matched by name) get the profile's color and template.
+// It returns the applied settings so the caller can refresh the session.
+func (ps *ProfileService) Import(ctx context.Context, userID string, profile *models.Profile) (_ *models.ProfileImportResult, err error) {
+	defer wrapOp("import profile", &err)
+	if profile.Version > ProfileVersion {
+		return nil, ErrUnsupportedProfile
+	}
+
+	settings := models.UserSettings{
+		Theme:                profile.Settings.Theme,
+		WeekStart:            profile.Settings.WeekStart,
+		Timezone:             profile.Settings.Timezone,
+		DateFormat:           profile.Settings.DateFormat,
+		UniqueContextMode:    profile.Settings.UniqueContextMode,
+		ShowBreadcrumb:       profile.Settings.ShowBreadcrumb,
+		ShowMarkdownEditor:   profile.Settings.ShowMarkdownEditor,
+		HideNewContextButton: profile.Settings.HideNewContextButton,
+		SuggestContext:       profile.Settings.SuggestContext,
+	}
+	if err := ps.repo.UpdateUserSettings(ctx, userID, settings); err != nil {
+		return nil, err
+	}
+
+	result := &models.ProfileImportResult{Settings: settings}
+	for _, pc := range profile.Contexts {
+		name := strings.TrimSpace(pc.Name)
+
+		existing, err := ps.repo.GetContextByName(ctx, database.ScopeUser(userID), name)
+		if err != nil {
+			return nil, err
+		}
+
+		if existing != nil {
+			// Profiles from before icons leave the context's icon alone
+			icon := existing.Icon
+			if pc.Icon != "" {
+				icon = pc.Icon
+			}
+			if err := ps.repo.UpdateContext(ctx, database.ScopeUser(userID), existing.ID, existing.Name, pc.Color, icon); err != nil {
+				return nil, err
+			}
+			if err := ps.repo.UpdateContextTemplate(ctx, database.ScopeUser(userID), existing.ID, pc.Template); err != nil {
+				return nil, err
+			}
+			// A profile may keep a context off storage, but never puts one back on it
+			if pc.LocalOnly && !existing.LocalOnly {
+				if err := ps.repo.SetContextLocalOnly(ctx, database.ScopeUser(userID), existing.ID, true); err != nil {
+					return nil, err
+				}
+			}
+			// Profiles from before languages leave the context's language alone
+			if pc.Language != "" {
+				if err := ps.repo.UpdateContextLanguage(ctx, database.ScopeUser(userID), existing.ID, pc.Language); err != nil {
+					return nil, err
+				}
+			}
+			result.ContextsUpdated++
+			continue
+		}
+
+		c := &models.Context{
+			ID:        ps.ids.NewID(),
+			UserID:    userID,
+			Name:      name,
+			Color:     pc.Color,
+			Icon:      pc.Icon,
+			Template:  pc.Template,
+			LocalOnly: pc.LocalOnly,
+			Language:  pc.Language,
+			CreatedAt: ps.clock.Now(),
+		}
+		if err := ps.repo.CreateContext(ctx, c); err != nil {
+			return nil, err
+		}
+		result.ContextsCreated++
+	}
+
+	return result, nil
+}
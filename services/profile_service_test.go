@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/visibility"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockProfileRepository is a mock implementation of ProfileRepository
+type MockProfileRepository struct {
+	MockContextRepository
+}
+
+// Ensure MockProfileRepository implements ProfileRepository interface
+var _ ProfileRepository = (*MockProfileRepository)(nil)
+
+func (m *MockProfileRepository) UpdateUserSettings(_ context.Context, userID string, settings models.UserSettings) error {
+	args := m.Called(userID, settings)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) GetVisibleNotes(_ context.Context, scope database.UserScope, policy visibility.Policy) ([]models.Note, error) {
+	args := m.Called(scope.UserID(), policy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func TestProfileService_Export(t *testing.T) {
+	repo := new(MockProfileRepository)
+	repo.On("GetContexts", "user123").Return([]models.Context{
+		{ID: "ctx1", Name: "Work", Color: "primary", Template: "# {{date}}"},
+	}, nil)
+	repo.On("GetVisibleNotes", "user123", visibility.Export).Return([]models.Note{
+		{Content: "Secret plans #roadmap #work"},
+		{Content: "More #work"},
+	}, nil)
+
+	service := NewProfileService(repo)
+	profile, err := service.Export(context.Background(), "user123", models.UserSettings{Theme: "dark", Timezone: "UTC"})
+
+	require.NoError(t, err)
+	assert.Equal(t, ProfileVersion, profile.Version)
+	assert.Equal(t, "dark", profile.Settings.Theme)
+	assert.Equal(t, []models.ProfileContext{{Name: "Work", Color: "primary", Template: "# {{date}}"}}, profile.Contexts)
+	assert.Equal(t, []string{"roadmap", "work"}, profile.Tags)
+}
+
+func TestProfileService_ExportAccount(t *testing.T) {
+	created := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	repo := new(MockProfileRepository)
+	repo.On("GetContexts", "user123").Return([]models.Context{{Name: "Work", Color: "primary"}}, nil)
+	repo.On("GetVisibleNotes", "user123", visibility.Export).Return([]models.Note{
+		{Context: "Work", Date: "2025-10-17", Type: "day", Content: "Later #work", CreatedAt: created},
+		{Context: "Work", Date: "2025-10-16", Type: "day", Content: "Earlier"},
+		{Context: "Home", Date: "2025-W42", Type: "week", Content: "Week plan"},
+	}, nil)
+
+	account, err := NewProfileService(repo).ExportAccount(context.Background(), "user123", models.UserSettings{Theme: "dark"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, ProfileVersion, account.Version)
+	assert.Equal(t, "dark", account.Settings.Theme)
+	assert.Equal(t, []string{"work"}, account.Tags)
+	require.Len(t, account.Notes, 3)
+	assert.Equal(t, []string{"2025-W42", "2025-10-16", "2025-10-17"},
+		[]string{account.Notes[0].Date, account.Notes[1].Date, account.Notes[2].Date})
+	assert.Equal(t, models.ExportedNote{Context: "Work", Type: "day", Date: "2025-10-17", Content: "Later #work", CreatedAt: created}, account.Notes[2])
+}
+
+func TestProfileService_ExportAccount_LocalOnly(t *testing.T) {
+	repo := new(MockProfileRepository)
+	repo.On("GetContexts", "user123").Return([]models.Context{{Name: "Journal", Color: "dark", LocalOnly: true}}, nil)
+	shared := models.Note{Context: "Work", Date: "2025-10-16", Type: "day", Content: "Shared"}
+	repo.On("GetVisibleNotes", "user123", visibility.Export).Return([]models.Note{shared}, nil)
+	repo.On("GetVisibleNotes", "user123", visibility.Export.WithLocalOnly()).Return([]models.Note{
+		{Context: "Journal", Date: "2025-10-16", Type: "day", Content: "Private", LocalOnly: true},
+		shared,
+	}, nil)
+	service := NewProfileService(repo)
+
+	account, err := service.ExportAccount(context.Background(), "user123", models.UserSettings{}, false)
+	require.NoError(t, err)
+	require.Len(t, account.Notes, 1)
+	assert.Equal(t, "Shared", account.Notes[0].Content)
+	assert.True(t, account.Contexts[0].LocalOnly, "the context stays local only when imported")
+
+	account, err = service.ExportAccount(context.Background(), "user123", models.UserSettings{}, true)
+	require.NoError(t, err)
+	require.Len(t, account.Notes, 2)
+	assert.True(t, account.Notes[0].LocalOnly)
+	assert.False(t, account.Notes[1].LocalOnly)
+}
+
+func TestProfileService_Import(t *testing.T) {
+	t.Run("Creates missing and updates existing contexts", func(t *testing.T) {
+		repo := new(MockProfileRepository)
+		repo.On("UpdateUserSettings", "user123", mock.MatchedBy(func(s models.UserSettings) bool {
+			return s.Theme == "dark" && s.WeekStart == 1
+		})).Return(nil)
+		repo.On("GetContextByName", "user123", "Work").Return(&models.Context{ID: "ctx1", Name: "Work"}, nil)
+		repo.On("UpdateContext", "ctx1", "Work", "danger", "").Return(nil)
+		repo.On("UpdateContextTemplate", "ctx1", "## Tasks").Return(nil)
+		repo.On("UpdateContextLanguage", "ctx1", "en").Return(nil)
+		repo.On("GetContextByName", "user123", "Personal").Return(nil, nil)
+		repo.On("CreateContext", mock.MatchedBy(func(c *models.Context) bool {
+			return c.Name == "Personal" && c.UserID == "user123" && c.ID != ""
+		})).Return(nil)
+
+		service := NewProfileService(repo)
+		result, err := service.Import(context.Background(), "user123", &models.Profile{
+			Version:  1,
+			Settings: models.UpdateSettingsRequest{Theme: "dark", WeekStart: 1},
+			Contexts: []models.ProfileContext{
+				{Name: "Work", Color: "danger", Template: "## Tasks", Language: "en"},
+				{Name: "Personal", Color: "success"},
+			},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.ContextsCreated)
+		assert.Equal(t, 1, result.ContextsUpdated)
+		assert.Equal(t, "dark", result.Settings.Theme)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Rejects newer profile versions", func(t *testing.T) {
+		service := NewProfileService(new(MockProfileRepository))
+		_, err := service.Import(context.Background(), "user123", &models.Profile{Version: ProfileVersion + 1})
+		assert.ErrorIs(t, err, ErrUnsupportedProfile)
+	})
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/period"
+	"daily-notes/pkg/rendercache"
+	"daily-notes/pkg/visibility"
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	// maxPublicNotes caps the notes listed on a public page
+	maxPublicNotes = 100
+	// publicFeedNotes is how many of the latest notes the RSS feed carries
+	publicFeedNotes = 20
+)
+
+// PublicService publishes contexts read-only under a handle (/@handle), like a
+// small blog of their daily notes. Only daily notes with content are shown.
+// Single notes of any context can be shared too, through secret links (/s/<token>).
+type PublicService struct {
+	repo    PublicRepository
+	renders *rendercache.Cache
+	clock   clock.Clock
+}
+
+// NewPublicService creates a new public service
+func NewPublicService(repo PublicRepository) *PublicService {
+	return &PublicService{repo: repo, clock: clock.Real()}
+}
+
+// SetClock replaces the clock used for publication times
+func (ps *PublicService) SetClock(c clock.Clock) {
+	ps.clock = c
+}
+
+// SetRenderCache registers the cache of rendered HTML shared with the note service,
+// which invalidates it on saves
+func (ps *PublicService) SetRenderCache(cache *rendercache.Cache) {
+	ps.renders = cache
+}
+
+// List returns the user's published contexts
+func (ps *PublicService) List(ctx context.Context, userID string) (_ []models.PublicContext, err error) {
+	defer wrapOp("list public contexts", &err)
+	return ps.repo.GetPublicContexts(ctx, userID)
+}
+
+// Publish makes a user's context public under a handle; publishing it again changes
+// its handle and whether search engines may index it
+func (ps *PublicService) Publish(ctx context.Context, userID, contextID string, req models.PublishContextRequest) (_ *models.PublicContext, err error) {
+	defer wrapOp("publish context", &err)
+	c, err := ps.repo.GetContextByID(ctx, database.ScopeUser(userID), contextID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.UserID != userID {
+		return nil, ErrContextNotFound
+	}
+
+	existing, err := ps.repo.GetPublicContext(ctx, req.Handle)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ContextID != contextID {
+		return nil, ErrHandleTaken
+	}
+
+	if err := ps.repo.PublishContext(ctx, &models.PublicContext{
+		Handle:    req.Handle,
+		ContextID: contextID,
+		UserID:    userID,
+		Indexable: req.Indexable,
+		CreatedAt: ps.clock.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	return ps.repo.GetPublicContext(ctx, req.Handle)
+}
+
+// Unpublish takes a user's context off its public page
+func (ps *PublicService) Unpublish(ctx context.Context, userID, contextID string) (err error) {
+	defer wrapOp("unpublish context", &err)
+	removed, err := ps.repo.UnpublishContext(ctx, userID, contextID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrContextNotPublic
+	}
+	return nil
+}
+
+// Index returns the context published under handle with its latest notes, without their HTML
+func (ps *PublicService) Index(ctx context.Context, handle string) (_ *models.PublicContext, _ []models.PublicNote, err error) {
+	defer wrapOp("get public page", &err)
+	return ps.notes(ctx, handle, maxPublicNotes, false)
+}
+
+// Feed returns the context published under handle with its latest notes rendered for RSS
+func (ps *PublicService) Feed(ctx context.Context, handle string) (_ *models.PublicContext, _ []models.PublicNote, err error) {
+	defer wrapOp("get public feed", &err)
+	return ps.notes(ctx, handle, publicFeedNotes, true)
+}
+
+// Note returns the context published under handle and its rendered note of a day
+func (ps *PublicService) Note(ctx context.Context, handle, date string) (_ *models.PublicContext, _ *models.PublicNote, err error) {
+	defer wrapOp("get public note", &err)
+	p, err := ps.published(ctx, handle)
+	if err != nil {
+		return nil, nil, err
+	}
+	if period.Kind(date) != period.Day {
+		return nil, nil, ErrPublicPageNotFound
+	}
+
+	note, err := ps.repo.GetNote(ctx, database.ScopeUser(p.UserID), p.Context, date)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The context is published, so it isn't in the trash
+	if note == nil || strings.TrimSpace(note.Content) == "" || !visibility.Public.Allows(note.LocalOnly, false, note.Encrypted) {
+		return nil, nil, ErrPublicPageNotFound
+	}
+
+	public, err := ps.publicNote(*note, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, public, nil
+}
+
+// Share creates a secret link showing a note read-only, expiring after
+// expiresInDays days, or never when 0. Local-only and encrypted notes can't be shared.
+func (ps *PublicService) Share(ctx context.Context, userID, contextName, date string, expiresInDays int) (_ *models.NoteShare, err error) {
+	defer wrapOp("share note", &err)
+	note, err := ps.repo.GetNote(ctx, database.ScopeUser(userID), contextName, date)
+	if err != nil {
+		return nil, err
+	}
+	if note == nil {
+		return nil, ErrNoteNotFound
+	}
+	if note.Encrypted {
+		return nil, ErrShareEncrypted
+	}
+	if !visibility.Public.Allows(note.LocalOnly, false, false) {
+		return nil, ErrShareLocalOnly
+	}
+
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	now := ps.clock.Now().UTC()
+	share := &models.NoteShare{
+		Token:     base64.RawURLEncoding.EncodeToString(token),
+		UserID:    userID,
+		NoteID:    note.ID,
+		Context:   note.Context,
+		Date:      note.Date,
+		CreatedAt: now,
+	}
+	if expiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, expiresInDays)
+		share.ExpiresAt = &expiresAt
+	}
+	if err := ps.repo.CreateShare(ctx, share); err != nil {
+		return nil, err
+	}
+	share.URL = shareURL(share.Token)
+	return share, nil
+}
+
+// Shares returns the user's share links, newest first
+func (ps *PublicService) Shares(ctx context.Context, userID string) (_ []models.NoteShare, err error) {
+	defer wrapOp("list share links", &err)
+	shares, err := ps.repo.GetShares(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range shares {
+		shares[i].URL = shareURL(shares[i].Token)
+	}
+	return shares, nil
+}
+
+// Unshare revokes a share link of the user
+func (ps *PublicService) Unshare(ctx context.Context, userID string, id int64) (err error) {
+	defer wrapOp("revoke share link", &err)
+	removed, err := ps.repo.DeleteShare(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// SharedNote returns the rendered note of a share link and the name of its
+// context; links that expired or were revoked, and notes that can no longer be
+// shown, fail with ErrPublicPageNotFound
+func (ps *PublicService) SharedNote(ctx context.Context, token string) (_ *models.PublicNote, contextName string, err error) {
+	defer wrapOp("get shared note", &err)
+	note, err := ps.repo.GetSharedNote(ctx, token, ps.clock.Now().UTC())
+	if err != nil {
+		return nil, "", err
+	}
+	if note == nil {
+		return nil, "", ErrPublicPageNotFound
+	}
+
+	public, err := ps.publicNote(*note, true)
+	if err != nil {
+		return nil, "", err
+	}
+	return public, note.Context, nil
+}
+
+// shareURL is the path of the page a share link shows
+func shareURL(token string) string {
+	return "/s/" + token
+}
+
+// published looks up the context published under handle
+func (ps *PublicService) published(ctx context.Context, handle string) (*models.PublicContext, error) {
+	p, err := ps.repo.GetPublicContext(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrPublicPageNotFound
+	}
+	return p, nil
+}
+
+// notes returns a published context with up to limit of its latest notes
+func (ps *PublicService) notes(ctx context.Context, handle string, limit int, render bool) (*models.PublicContext, []models.PublicNote, error) {
+	p, err := ps.published(ctx, handle)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notes, err := ps.repo.GetPublicNotes(ctx, p.UserID, p.Context, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	public := make([]models.PublicNote, 0, len(notes))
+	for _, note := range notes {
+		pn, err := ps.publicNote(note, render)
+		if err != nil {
+			return nil, nil, err
+		}
+		public = append(public, *pn)
+	}
+	return p, public, nil
+}
+
+// publicNote converts a note for a public page, rendering its HTML when asked
+func (ps *PublicService) publicNote(note models.Note, render bool) (*models.PublicNote, error) {
+	public := &models.PublicNote{
+		Date:      note.Date,
+		Title:     period.Title(note.Date),
+		Heading:   noteTitle(note.Content),
+		UpdatedAt: note.UpdatedAt,
+	}
+	if !render {
+		return public, nil
+	}
+
+	renderNote := func() ([]byte, error) { return markdown.Render(note.Content) }
+	var html []byte
+	var err error
+	if ps.renders != nil {
+		html, err = ps.renders.GetOrRender(note.ID, note.Revision, renderNote)
+	} else {
+		html, err = renderNote()
+	}
+	if err != nil {
+		return nil, err
+	}
+	public.HTML = string(html)
+	return public, nil
+}
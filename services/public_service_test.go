@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockPublicRepository is a mock implementation of PublicRepository
+type MockPublicRepository struct {
+	mock.Mock
+}
+
+func (m *MockPublicRepository) GetContextByID(ctx context.Context, _ database.UserScope, contextID string) (*models.Context, error) {
+	args := m.Called(contextID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Context), args.Error(1)
+}
+
+func (m *MockPublicRepository) GetNote(ctx context.Context, scope database.UserScope, contextName, date string) (*models.Note, error) {
+	args := m.Called(scope.UserID(), contextName, date)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Note), args.Error(1)
+}
+
+func (m *MockPublicRepository) GetPublicContext(ctx context.Context, handle string) (*models.PublicContext, error) {
+	args := m.Called(handle)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PublicContext), args.Error(1)
+}
+
+func (m *MockPublicRepository) GetPublicContexts(ctx context.Context, userID string) ([]models.PublicContext, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PublicContext), args.Error(1)
+}
+
+func (m *MockPublicRepository) PublishContext(ctx context.Context, p *models.PublicContext) error {
+	args := m.Called(p)
+	return args.Error(0)
+}
+
+func (m *MockPublicRepository) UnpublishContext(ctx context.Context, userID, contextID string) (bool, error) {
+	args := m.Called(userID, contextID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPublicRepository) GetPublicNotes(ctx context.Context, userID, contextName string, limit int) ([]models.Note, error) {
+	args := m.Called(userID, contextName, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func (m *MockPublicRepository) CreateShare(ctx context.Context, share *models.NoteShare) error {
+	args := m.Called(share)
+	return args.Error(0)
+}
+
+func (m *MockPublicRepository) GetShares(ctx context.Context, userID string) ([]models.NoteShare, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.NoteShare), args.Error(1)
+}
+
+func (m *MockPublicRepository) DeleteShare(ctx context.Context, userID string, id int64) (bool, error) {
+	args := m.Called(userID, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPublicRepository) GetSharedNote(ctx context.Context, token string, now time.Time) (*models.Note, error) {
+	args := m.Called(token, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Note), args.Error(1)
+}
+
+func TestPublicService_Publish(t *testing.T) {
+	ctx := context.Background()
+	garden := &models.Context{ID: "ctx-garden", UserID: "user-1", Name: "Garden"}
+
+	t.Run("Someone else's context", func(t *testing.T) {
+		repo := new(MockPublicRepository)
+		repo.On("GetContextByID", "ctx-garden").Return(garden, nil)
+
+		_, err := NewPublicService(repo).Publish(ctx, "user-2", "ctx-garden", models.PublishContextRequest{Handle: "garden"})
+		assert.ErrorIs(t, err, ErrContextNotFound)
+		repo.AssertNotCalled(t, "PublishContext", mock.Anything)
+	})
+
+	t.Run("Handle taken by another context", func(t *testing.T) {
+		repo := new(MockPublicRepository)
+		repo.On("GetContextByID", "ctx-garden").Return(garden, nil)
+		repo.On("GetPublicContext", "garden").Return(&models.PublicContext{Handle: "garden", ContextID: "ctx-other"}, nil)
+
+		_, err := NewPublicService(repo).Publish(ctx, "user-1", "ctx-garden", models.PublishContextRequest{Handle: "garden"})
+		assert.ErrorIs(t, err, ErrHandleTaken)
+		repo.AssertNotCalled(t, "PublishContext", mock.Anything)
+	})
+
+	t.Run("Republishing under the same handle", func(t *testing.T) {
+		repo := new(MockPublicRepository)
+		published := &models.PublicContext{Handle: "garden", ContextID: "ctx-garden", Context: "Garden", UserID: "user-1"}
+		repo.On("GetContextByID", "ctx-garden").Return(garden, nil)
+		repo.On("GetPublicContext", "garden").Return(published, nil)
+		repo.On("PublishContext", mock.MatchedBy(func(p *models.PublicContext) bool {
+			return p.Handle == "garden" && p.UserID == "user-1" && p.Indexable
+		})).Return(nil)
+
+		p, err := NewPublicService(repo).Publish(ctx, "user-1", "ctx-garden", models.PublishContextRequest{Handle: "garden", Indexable: true})
+		require.NoError(t, err)
+		assert.Equal(t, published, p)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestPublicService_Note(t *testing.T) {
+	ctx := context.Background()
+	published := &models.PublicContext{Handle: "garden", ContextID: "ctx-garden", Context: "Garden", UserID: "user-1"}
+
+	repo := new(MockPublicRepository)
+	repo.On("GetPublicContext", "garden").Return(published, nil)
+	repo.On("GetPublicContext", "nobody").Return(nil, nil)
+	repo.On("GetNote", "user-1", "Garden", "2025-10-16").Return(&models.Note{ID: "n1", Date: "2025-10-16", Content: "# Sprouts\n\n**Finally**"}, nil)
+	repo.On("GetNote", "user-1", "Garden", "2025-10-17").Return(nil, nil)
+	service := NewPublicService(repo)
+
+	p, note, err := service.Note(ctx, "garden", "2025-10-16")
+	require.NoError(t, err)
+	assert.Equal(t, published, p)
+	assert.Equal(t, "Sprouts", note.Heading)
+	assert.Contains(t, note.HTML, "<strong>Finally</strong>")
+
+	for _, tc := range []struct{ handle, date string }{
+		{"nobody", "2025-10-16"},
+		{"garden", "2025-10-17"},
+		{"garden", "2025-W42"},
+	} {
+		_, _, err := service.Note(ctx, tc.handle, tc.date)
+		assert.ErrorIs(t, err, ErrPublicPageNotFound, "%s/%s", tc.handle, tc.date)
+	}
+}
+
+func TestPublicService_Share(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	t.Run("Local-only notes aren't shared", func(t *testing.T) {
+		repo := new(MockPublicRepository)
+		repo.On("GetNote", "user-1", "Private", "2025-10-16").Return(&models.Note{ID: "note-1", LocalOnly: true}, nil)
+
+		_, err := NewPublicService(repo).Share(ctx, "user-1", "Private", "2025-10-16", 0)
+		assert.ErrorIs(t, err, ErrShareLocalOnly)
+	})
+
+	t.Run("Missing notes", func(t *testing.T) {
+		repo := new(MockPublicRepository)
+		repo.On("GetNote", "user-1", "Work", "2025-10-16").Return(nil, nil)
+
+		_, err := NewPublicService(repo).Share(ctx, "user-1", "Work", "2025-10-16", 0)
+		assert.ErrorIs(t, err, ErrNoteNotFound)
+	})
+
+	t.Run("Links expire after the days asked for", func(t *testing.T) {
+		repo := new(MockPublicRepository)
+		service := NewPublicService(repo)
+		service.SetClock(clock.NewFake(now))
+		repo.On("GetNote", "user-1", "Work", "2025-10-16").Return(&models.Note{ID: "note-1", Context: "Work", Date: "2025-10-16"}, nil)
+		repo.On("CreateShare", mock.MatchedBy(func(s *models.NoteShare) bool {
+			return s.NoteID == "note-1" && len(s.Token) == 32 && s.ExpiresAt != nil && s.ExpiresAt.Equal(now.AddDate(0, 0, 7))
+		})).Return(nil)
+
+		share, err := service.Share(ctx, "user-1", "Work", "2025-10-16", 7)
+		require.NoError(t, err)
+		assert.Equal(t, "/s/"+share.Token, share.URL)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestPublicService_SharedNote(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)
+	repo := new(MockPublicRepository)
+	service := NewPublicService(repo)
+	service.SetClock(clock.NewFake(now))
+	repo.On("GetSharedNote", "token-1", now).Return(&models.Note{ID: "note-1", Context: "Work", Date: "2025-10-16", Content: "# Standup\n- Ship shares"}, nil)
+	repo.On("GetSharedNote", "revoked", now).Return(nil, nil)
+
+	note, contextName, err := service.SharedNote(ctx, "token-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Work", contextName)
+	assert.Equal(t, "Standup", note.Heading)
+	assert.Contains(t, note.HTML, "<li>Ship shares</li>")
+
+	_, _, err = service.SharedNote(ctx, "revoked")
+	assert.ErrorIs(t, err, ErrPublicPageNotFound)
+}
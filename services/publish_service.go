@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"daily-notes/pkg/maintenance"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/period"
+	"daily-notes/publish"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// publishPollInterval is how often due publications are looked for between wake-ups
+	publishPollInterval = time.Minute
+	// maxPublishAttempts is how many times a publication is tried before it fails
+	maxPublishAttempts = 5
+	// publishBatch caps the publications handled per run
+	publishBatch = 20
+	// publishTimeout bounds one publication, reading the note and writing the outcome included
+	publishTimeout = time.Minute
+)
+
+// PublishTargetOpener connects to a user's publish target (see setup.OpenPublishTarget)
+type PublishTargetOpener func(target models.PublishTarget) (publish.Target, error)
+
+// PublishService publishes notes to external blogs (Ghost, WordPress, a Hugo site
+// in git). Publishing a note queues a publication that a background loop pushes
+// to the target once due; failures are retried with growing delays. Publishing
+// the same note again updates the post it created.
+type PublishService struct {
+	repo  PublishRepository
+	open  PublishTargetOpener // nil when publishing is disabled
+	clock clock.Clock
+	ids   idgen.Generator
+
+	maintenance *maintenance.Mode // No runs while the server is in maintenance; optional
+
+	run      sync.Mutex // One run at a time
+	wake     chan struct{}
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewPublishService creates a publish service; nothing is pushed until SetOpener enables it
+func NewPublishService(repo PublishRepository) *PublishService {
+	return &PublishService{
+		repo:  repo,
+		clock: clock.Real(),
+		ids:   idgen.UUID(),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// SetOpener enables publishing through open
+func (ps *PublishService) SetOpener(open PublishTargetOpener) {
+	ps.open = open
+}
+
+// SetClock replaces the clock used for publish times
+func (ps *PublishService) SetClock(c clock.Clock) {
+	ps.clock = c
+}
+
+// SetIDGenerator replaces the generator of target and publication IDs
+func (ps *PublishService) SetIDGenerator(g idgen.Generator) {
+	ps.ids = g
+}
+
+// Targets returns a user's publish targets
+func (ps *PublishService) Targets(ctx context.Context, userID string) (_ []models.PublishTarget, err error) {
+	defer wrapOp("list publish targets", &err)
+	return ps.repo.GetPublishTargets(ctx, userID)
+}
+
+// CreateTarget adds a publish target for a user
+func (ps *PublishService) CreateTarget(ctx context.Context, userID string, req models.CreatePublishTargetRequest) (_ *models.PublishTarget, err error) {
+	defer wrapOp("create publish target", &err)
+	switch {
+	case req.Kind != publish.Hugo && req.URL == "":
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidPublishTarget)
+	case req.Kind == publish.WordPress && req.Username == "":
+		return nil, fmt.Errorf("%w: username is required", ErrInvalidPublishTarget)
+	case req.Kind == publish.Hugo && req.Repo == "":
+		return nil, fmt.Errorf("%w: repo is required", ErrInvalidPublishTarget)
+	}
+
+	target := &models.PublishTarget{
+		ID:        ps.ids.NewID(),
+		UserID:    userID,
+		Kind:      req.Kind,
+		Name:      req.Name,
+		URL:       req.URL,
+		Username:  req.Username,
+		Secret:    req.Secret,
+		Repo:      req.Repo,
+		Branch:    req.Branch,
+		Dir:       req.Dir,
+		SiteURL:   req.SiteURL,
+		CreatedAt: ps.clock.Now().UTC(),
+	}
+	// Catch malformed keys and repositories now rather than on the first publication
+	if ps.open != nil {
+		if _, err := ps.open(*target); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPublishTarget, err)
+		}
+	}
+
+	if err := ps.repo.CreatePublishTarget(ctx, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// DeleteTarget deletes a user's publish target; posts already published stay on the blog
+func (ps *PublishService) DeleteTarget(ctx context.Context, userID, targetID string) (err error) {
+	defer wrapOp("delete publish target", &err)
+	deleted, err := ps.repo.DeletePublishTarget(ctx, userID, targetID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPublishTargetNotFound
+	}
+	return nil
+}
+
+// Publish queues a user's note for publishing to a target, now or at req.PublishAt
+func (ps *PublishService) Publish(ctx context.Context, userID string, req models.PublishNoteRequest) (_ *models.NotePublication, err error) {
+	defer wrapOp("publish note", &err)
+	if ps.open == nil {
+		return nil, ErrPublishingUnavailable
+	}
+
+	target, err := ps.repo.GetPublishTarget(ctx, userID, req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrPublishTargetNotFound
+	}
+
+	now := ps.clock.Now().UTC()
+	publication := &models.NotePublication{
+		ID:        ps.ids.NewID(),
+		UserID:    userID,
+		TargetID:  target.ID,
+		PublishAt: now,
+		UpdatedAt: now,
+	}
+	if req.PublishAt != nil && req.PublishAt.After(now) {
+		publication.PublishAt = req.PublishAt.UTC()
+	}
+
+	found, err := ps.repo.QueuePublication(ctx, publication, req.Context, req.Date)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrNoteNotFound
+	}
+
+	if !publication.PublishAt.After(now) {
+		ps.Wake()
+	}
+	return publication, nil
+}
+
+// Publications returns where a user's note was published or is queued to be
+func (ps *PublishService) Publications(ctx context.Context, userID, contextName, date string) (_ []models.NotePublication, err error) {
+	defer wrapOp("get note publications", &err)
+	return ps.repo.GetNotePublications(ctx, userID, contextName, date)
+}
+
+// SetMaintenance holds the publications due while the server is in maintenance
+func (ps *PublishService) SetMaintenance(mode *maintenance.Mode) {
+	ps.maintenance = mode
+}
+
+// Start runs due publications in the background until Stop
+func (ps *PublishService) Start() {
+	ps.stopChan = make(chan struct{})
+	ps.done = make(chan struct{})
+
+	go func() {
+		defer close(ps.done)
+		ticker := time.NewTicker(publishPollInterval)
+		defer ticker.Stop()
+
+		for {
+			ps.RunDue(context.Background())
+			select {
+			case <-ticker.C:
+			case <-ps.wake:
+			case <-ps.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background loop, waiting for the publication in progress
+func (ps *PublishService) Stop() {
+	if ps.stopChan == nil {
+		return
+	}
+	close(ps.stopChan)
+	<-ps.done
+	ps.stopChan = nil
+}
+
+// Wake makes the background loop look for due publications now
+func (ps *PublishService) Wake() {
+	select {
+	case ps.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RunDue pushes the publications that are due and returns how many were tried
+func (ps *PublishService) RunDue(ctx context.Context) int {
+	if ps.open == nil || ps.maintenance.Paused() {
+		return 0
+	}
+	ps.run.Lock()
+	defer ps.run.Unlock()
+
+	due, err := ps.repo.GetDuePublications(ctx, ps.clock.Now().UTC(), publishBatch)
+	if err != nil {
+		slog.Warn("failed to get due publications", "error", err)
+		return 0
+	}
+	for _, publication := range due {
+		ps.runOne(ctx, publication)
+	}
+	return len(due)
+}
+
+// runOne pushes one publication and stores the outcome
+func (ps *PublishService) runOne(ctx context.Context, publication models.NotePublication) {
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	queuedAt := publication.UpdatedAt
+	result, err := ps.push(ctx, publication)
+
+	now := ps.clock.Now().UTC()
+	publication.Attempts++
+	publication.UpdatedAt = now
+	if err != nil {
+		publication.Error = err.Error()
+		if publication.Attempts >= maxPublishAttempts {
+			publication.Status = models.PublicationFailed
+		} else {
+			// 1, 4, 9, 16 minutes
+			publication.PublishAt = now.Add(time.Duration(publication.Attempts*publication.Attempts) * time.Minute)
+		}
+		slog.Warn("failed to publish note", "publication", publication.ID, "attempt", publication.Attempts, "error", err)
+	} else {
+		publication.Status = models.PublicationPublished
+		publication.ExternalID = result.ExternalID
+		publication.ExternalURL = result.URL
+		publication.Error = ""
+		publication.PublishedAt = &now
+	}
+
+	if _, err := ps.repo.SavePublication(ctx, &publication, queuedAt); err != nil {
+		slog.Warn("failed to save publication", "publication", publication.ID, "error", err)
+	}
+}
+
+// push converts a publication's note and sends it to its target
+func (ps *PublishService) push(ctx context.Context, publication models.NotePublication) (*publish.Result, error) {
+	note, err := ps.repo.GetPublicationNote(ctx, publication)
+	if err != nil {
+		return nil, err
+	}
+	if note == nil {
+		return nil, ErrNoteNotFound
+	}
+	target, err := ps.repo.GetPublishTarget(ctx, publication.UserID, publication.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrPublishTargetNotFound
+	}
+
+	blog, err := ps.open(*target)
+	if err != nil {
+		return nil, err
+	}
+	post, err := notePost(*note)
+	if err != nil {
+		return nil, err
+	}
+	post.ExternalID = publication.ExternalID
+	return blog.Publish(ctx, post)
+}
+
+// notePost converts a note to a post: a leading "# Heading" becomes the title,
+// otherwise the note's period title (e.g. "Thursday, October 16, 2025") is used
+func notePost(note models.Note) (publish.Post, error) {
+	title, body := splitTitle(note.Content)
+	if title == "" {
+		title = period.Title(note.Date)
+	}
+	html, err := markdown.Render(body)
+	if err != nil {
+		return publish.Post{}, err
+	}
+
+	slug := note.Date
+	if s := slugify(title); s != "" && title != period.Title(note.Date) {
+		slug += "-" + s
+	}
+	return publish.Post{
+		Title:    title,
+		Slug:     slug,
+		Markdown: body,
+		HTML:     string(html),
+		Tags:     note.Tags,
+		Date:     note.CreatedAt,
+	}, nil
+}
+
+// splitTitle takes a level-one heading off the start of content
+func splitTitle(content string) (title, body string) {
+	trimmed := strings.TrimLeft(content, " \t\r\n")
+	line, rest, _ := strings.Cut(trimmed, "\n")
+	if heading, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+		return strings.TrimSpace(heading), strings.TrimLeft(rest, "\r\n")
+	}
+	return "", content
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify lowercases s and joins its ASCII letters and digits with dashes
+func slugify(s string) string {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	return slug
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"daily-notes/publish"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockPublishRepository is a mock implementation of PublishRepository
+type MockPublishRepository struct {
+	mock.Mock
+}
+
+func (m *MockPublishRepository) CreatePublishTarget(ctx context.Context, t *models.PublishTarget) error {
+	return m.Called(t).Error(0)
+}
+
+func (m *MockPublishRepository) GetPublishTargets(ctx context.Context, userID string) ([]models.PublishTarget, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PublishTarget), args.Error(1)
+}
+
+func (m *MockPublishRepository) GetPublishTarget(ctx context.Context, userID, targetID string) (*models.PublishTarget, error) {
+	args := m.Called(userID, targetID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PublishTarget), args.Error(1)
+}
+
+func (m *MockPublishRepository) DeletePublishTarget(ctx context.Context, userID, targetID string) (bool, error) {
+	args := m.Called(userID, targetID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPublishRepository) QueuePublication(ctx context.Context, p *models.NotePublication, contextName, date string) (bool, error) {
+	args := m.Called(p, contextName, date)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPublishRepository) GetNotePublications(ctx context.Context, userID, contextName, date string) ([]models.NotePublication, error) {
+	args := m.Called(userID, contextName, date)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.NotePublication), args.Error(1)
+}
+
+func (m *MockPublishRepository) GetDuePublications(ctx context.Context, now time.Time, limit int) ([]models.NotePublication, error) {
+	args := m.Called(now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.NotePublication), args.Error(1)
+}
+
+func (m *MockPublishRepository) GetPublicationNote(ctx context.Context, p models.NotePublication) (*models.Note, error) {
+	args := m.Called(p.ID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Note), args.Error(1)
+}
+
+func (m *MockPublishRepository) SavePublication(ctx context.Context, p *models.NotePublication, queuedAt time.Time) (bool, error) {
+	args := m.Called(*p, queuedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+// fakeBlog records the posts it's given and fails while err is set
+type fakeBlog struct {
+	posts []publish.Post
+	err   error
+}
+
+func (b *fakeBlog) Publish(ctx context.Context, post publish.Post) (*publish.Result, error) {
+	b.posts = append(b.posts, post)
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &publish.Result{ExternalID: "post-1", URL: "https://blog.example.com/post-1/"}, nil
+}
+
+func TestPublishService_CreateTarget(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockPublishRepository)
+	service := NewPublishService(repo)
+	service.SetIDGenerator(idgen.NewSequence("target"))
+
+	for _, req := range []models.CreatePublishTargetRequest{
+		{Kind: publish.Ghost, Name: "Blog", Secret: "id:00"},
+		{Kind: publish.WordPress, Name: "Blog", URL: "https://example.com", Secret: "pass"},
+		{Kind: publish.Hugo, Name: "Site", Secret: "token"},
+	} {
+		_, err := service.CreateTarget(ctx, "user-1", req)
+		assert.ErrorIs(t, err, ErrInvalidPublishTarget, req.Kind)
+	}
+
+	service.SetOpener(func(target models.PublishTarget) (publish.Target, error) {
+		return nil, errors.New(`ghost admin API key must look like "id:secret"`)
+	})
+	_, err := service.CreateTarget(ctx, "user-1", models.CreatePublishTargetRequest{Kind: publish.Ghost, Name: "Blog", URL: "https://blog.example.com", Secret: "bad"})
+	assert.ErrorIs(t, err, ErrInvalidPublishTarget)
+	assert.ErrorContains(t, err, "id:secret")
+
+	service.SetOpener(func(target models.PublishTarget) (publish.Target, error) { return &fakeBlog{}, nil })
+	repo.On("CreatePublishTarget", mock.MatchedBy(func(target *models.PublishTarget) bool {
+		return target.ID != "" && target.UserID == "user-1" && target.Secret == "id:00"
+	})).Return(nil)
+	target, err := service.CreateTarget(ctx, "user-1", models.CreatePublishTargetRequest{Kind: publish.Ghost, Name: "Blog", URL: "https://blog.example.com", Secret: "id:00"})
+	require.NoError(t, err)
+	assert.Equal(t, "Blog", target.Name)
+	repo.AssertExpectations(t)
+}
+
+func TestPublishService_Publish(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	target := &models.PublishTarget{ID: "blog", UserID: "user-1", Kind: publish.Ghost}
+
+	repo := new(MockPublishRepository)
+	repo.On("GetPublishTarget", "user-1", "blog").Return(target, nil)
+	repo.On("GetPublishTarget", "user-1", "missing").Return(nil, nil)
+	repo.On("QueuePublication", mock.Anything, "Garden", "2025-10-16").Return(true, nil)
+	repo.On("QueuePublication", mock.Anything, "Garden", "2025-10-17").Return(false, nil)
+
+	service := NewPublishService(repo)
+	service.SetClock(clock.NewFake(now))
+
+	_, err := service.Publish(ctx, "user-1", models.PublishNoteRequest{Context: "Garden", Date: "2025-10-16", TargetID: "blog"})
+	assert.ErrorIs(t, err, ErrPublishingUnavailable)
+
+	service.SetOpener(func(models.PublishTarget) (publish.Target, error) { return &fakeBlog{}, nil })
+
+	_, err = service.Publish(ctx, "user-1", models.PublishNoteRequest{Context: "Garden", Date: "2025-10-16", TargetID: "missing"})
+	assert.ErrorIs(t, err, ErrPublishTargetNotFound)
+	_, err = service.Publish(ctx, "user-1", models.PublishNoteRequest{Context: "Garden", Date: "2025-10-17", TargetID: "blog"})
+	assert.ErrorIs(t, err, ErrNoteNotFound)
+
+	later := now.Add(24 * time.Hour)
+	publication, err := service.Publish(ctx, "user-1", models.PublishNoteRequest{Context: "Garden", Date: "2025-10-16", TargetID: "blog", PublishAt: &later})
+	require.NoError(t, err)
+	assert.True(t, publication.PublishAt.Equal(later))
+
+	earlier := now.Add(-time.Hour)
+	publication, err = service.Publish(ctx, "user-1", models.PublishNoteRequest{Context: "Garden", Date: "2025-10-16", TargetID: "blog", PublishAt: &earlier})
+	require.NoError(t, err)
+	assert.True(t, publication.PublishAt.Equal(now), "times in the past publish now")
+}
+
+func TestPublishService_RunDue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	queuedAt := now.Add(-time.Minute)
+	target := &models.PublishTarget{ID: "blog", UserID: "user-1", Kind: publish.Ghost}
+	note := &models.Note{
+		Context: "Garden", Date: "2025-10-16", Content: "# Red Tomatoes\n\nRipe **at last** #garden",
+		Tags: []string{"garden"}, CreatedAt: now,
+	}
+
+	run := func(blog *fakeBlog, publication models.NotePublication) models.NotePublication {
+		repo := new(MockPublishRepository)
+		repo.On("GetDuePublications", now, publishBatch).Return([]models.NotePublication{publication}, nil)
+		repo.On("GetPublicationNote", publication.ID).Return(note, nil)
+		repo.On("GetPublishTarget", "user-1", "blog").Return(target, nil)
+		var saved models.NotePublication
+		repo.On("SavePublication", mock.Anything, queuedAt).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(models.NotePublication)
+		}).Return(true, nil)
+
+		service := NewPublishService(repo)
+		service.SetClock(clock.NewFake(now))
+		service.SetOpener(func(models.PublishTarget) (publish.Target, error) { return blog, nil })
+		assert.Equal(t, 1, service.RunDue(ctx))
+		return saved
+	}
+	queued := models.NotePublication{ID: "pub-1", UserID: "user-1", TargetID: "blog", Status: models.PublicationPending, PublishAt: queuedAt, UpdatedAt: queuedAt}
+
+	t.Run("Published", func(t *testing.T) {
+		blog := &fakeBlog{}
+		saved := run(blog, queued)
+
+		require.Len(t, blog.posts, 1)
+		post := blog.posts[0]
+		assert.Equal(t, "Red Tomatoes", post.Title)
+		assert.Equal(t, "2025-10-16-red-tomatoes", post.Slug)
+		assert.Equal(t, "Ripe **at last** #garden", post.Markdown)
+		assert.Contains(t, post.HTML, "<strong>at last</strong>")
+		assert.NotContains(t, post.HTML, "<h1>")
+		assert.Equal(t, []string{"garden"}, post.Tags)
+		assert.Empty(t, post.ExternalID)
+
+		assert.Equal(t, models.PublicationPublished, saved.Status)
+		assert.Equal(t, "post-1", saved.ExternalID)
+		assert.Equal(t, "https://blog.example.com/post-1/", saved.ExternalURL)
+		assert.Equal(t, 1, saved.Attempts)
+		require.NotNil(t, saved.PublishedAt)
+	})
+
+	t.Run("Republishing updates the post", func(t *testing.T) {
+		blog := &fakeBlog{}
+		republished := queued
+		republished.ExternalID = "post-1"
+		run(blog, republished)
+
+		require.Len(t, blog.posts, 1)
+		assert.Equal(t, "post-1", blog.posts[0].ExternalID)
+	})
+
+	t.Run("Failures are retried, then given up", func(t *testing.T) {
+		blog := &fakeBlog{err: errors.New("status 502")}
+		saved := run(blog, queued)
+		assert.Equal(t, models.PublicationPending, saved.Status)
+		assert.Equal(t, "status 502", saved.Error)
+		assert.True(t, saved.PublishAt.Equal(now.Add(time.Minute)))
+
+		failing := queued
+		failing.Attempts = maxPublishAttempts - 1
+		saved = run(blog, failing)
+		assert.Equal(t, models.PublicationFailed, saved.Status)
+	})
+}
+
+func TestNotePost(t *testing.T) {
+	post, err := notePost(models.Note{Date: "2025-10-16", Content: "Just a line"})
+	require.NoError(t, err)
+	assert.Equal(t, "Thursday, October 16, 2025", post.Title)
+	assert.Equal(t, "2025-10-16", post.Slug)
+	assert.Equal(t, "Just a line", post.Markdown)
+
+	post, err = notePost(models.Note{Date: "2025-W42", Content: "\n# Week 42: Plans & Ideas!\nShip it"})
+	require.NoError(t, err)
+	assert.Equal(t, "Week 42: Plans & Ideas!", post.Title)
+	assert.Equal(t, "2025-W42-week-42-plans-ideas", post.Slug)
+	assert.Equal(t, "Ship it", post.Markdown)
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/jobs"
+	"daily-notes/pkg/period"
+	"daily-notes/pkg/webpush"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// PushService sends web push notifications to the browsers users subscribed
+// with, such as for notes whose sync was abandoned and reminders that came due.
+// Notify queues a job so callers never wait for push services; subscriptions the
+// push service reports gone are deleted.
+type PushService struct {
+	repo     PushRepository
+	sender   PushSender
+	jobs     JobQueue
+	clock    clock.Clock
+	timeouts Timeouts
+}
+
+// NewPushService creates a push service; nothing is sent until SetSender and
+// SetJobQueue are called
+func NewPushService(repo PushRepository) *PushService {
+	return &PushService{repo: repo, clock: clock.Real(), timeouts: DefaultTimeouts}
+}
+
+// SetClock replaces the clock that dates subscriptions
+func (ps *PushService) SetClock(c clock.Clock) {
+	ps.clock = c
+}
+
+// SetSender sets how messages are signed and sent (the server's VAPID keys)
+func (ps *PushService) SetSender(sender PushSender) {
+	ps.sender = sender
+}
+
+// SetJobQueue sets the queue notifications are sent through; RunPushJob handles JobPush
+func (ps *PushService) SetJobQueue(queue JobQueue) {
+	ps.jobs = queue
+}
+
+// Enabled reports whether notifications can be sent
+func (ps *PushService) Enabled() bool {
+	return ps.sender != nil
+}
+
+// Settings returns whether the server sends notifications and the key browsers subscribe with
+func (ps *PushService) Settings() *models.PushSettings {
+	if !ps.Enabled() {
+		return &models.PushSettings{}
+	}
+	return &models.PushSettings{Enabled: true, PublicKey: ps.sender.PublicKey()}
+}
+
+// Subscribe sends the user's notifications to a browser from now on
+func (ps *PushService) Subscribe(ctx context.Context, userID string, req *models.PushSubscribeRequest) (err error) {
+	defer wrapOp("subscribe to push", &err)
+	if !ps.Enabled() {
+		return ErrPushDisabled
+	}
+	subscription := webpush.Subscription{Endpoint: req.Endpoint, P256dh: req.Keys.P256dh, Auth: req.Keys.Auth}
+	if err := subscription.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPushSubscription, err)
+	}
+
+	ctx, cancel := ps.timeouts.query(ctx)
+	defer cancel()
+
+	return ps.repo.SavePushSubscription(ctx, &models.PushSubscription{
+		UserID:    userID,
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		CreatedAt: ps.clock.Now(),
+	})
+}
+
+// Unsubscribe stops sending the user's notifications to the browser of endpoint
+func (ps *PushService) Unsubscribe(ctx context.Context, userID, endpoint string) (err error) {
+	defer wrapOp("unsubscribe from push", &err)
+	ctx, cancel := ps.timeouts.query(ctx)
+	defer cancel()
+
+	removed, err := ps.repo.DeletePushSubscription(ctx, userID, endpoint)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrPushSubscriptionNotFound
+	}
+	return nil
+}
+
+// Notify queues a notification to every browser of the user; it does nothing
+// when push is disabled
+func (ps *PushService) Notify(ctx context.Context, userID string, notification models.PushNotification) error {
+	if !ps.Enabled() {
+		return nil
+	}
+	return ps.jobs.Enqueue(ctx, userID, JobPush, notification)
+}
+
+// NoteAbandoned notifies the user of a note whose sync was abandoned after too
+// many failures, so it isn't lost silently; see sync.Worker.SetAbandonHandler
+func (ps *PushService) NoteAbandoned(userID string, event models.SyncEvent) {
+	ctx, cancel := ps.timeouts.query(context.Background())
+	defer cancel()
+
+	what := "Your " + event.Context + " note of " + period.Title(event.Date)
+	if event.Deleted {
+		what = "The deletion of your " + event.Context + " note of " + period.Title(event.Date)
+	}
+	err := ps.Notify(ctx, userID, models.PushNotification{
+		Title: "A note stopped syncing",
+		Body:  what + " failed to sync too many times. Open Daily Notes to retry it.",
+		URL:   "/",
+		Tag:   "sync-abandoned",
+	})
+	if err != nil {
+		slog.Warn("failed to queue sync notification", "user_id", userID, "note_id", event.NoteID, "error", err)
+	}
+}
+
+// RunPushJob sends a notification queued by Notify
+func (ps *PushService) RunPushJob(ctx context.Context, job *models.Job) (err error) {
+	defer wrapOp("run push job", &err)
+	var notification models.PushNotification
+	if err := jobs.Decode(job, &notification); err != nil {
+		return err
+	}
+	if !ps.Enabled() {
+		return jobs.Permanent(ErrPushDisabled)
+	}
+	return ps.Send(ctx, job.UserID, notification)
+}
+
+// Send sends a notification to every browser of the user now. Subscriptions the
+// push service reports gone are deleted; it fails only when every browser failed,
+// so a retry doesn't notify the others twice.
+func (ps *PushService) Send(ctx context.Context, userID string, notification models.PushNotification) (err error) {
+	defer wrapOp("send push notification", &err)
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+
+	queryCtx, cancel := ps.timeouts.query(ctx)
+	subscriptions, err := ps.repo.GetPushSubscriptions(queryCtx, userID)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	var failed []error
+	for _, subscription := range subscriptions {
+		sub := webpush.Subscription{Endpoint: subscription.Endpoint, P256dh: subscription.P256dh, Auth: subscription.Auth}
+		err := ps.sender.Send(ctx, sub, payload, webpush.Options{Topic: notification.Tag})
+		if errors.Is(err, webpush.ErrGone) {
+			queryCtx, cancel := ps.timeouts.query(ctx)
+			if err := ps.repo.DeletePushSubscriptionByID(queryCtx, subscription.ID); err != nil {
+				slog.Warn("failed to delete gone push subscription", "user_id", userID, "error", err)
+			}
+			cancel()
+			continue
+		}
+		if err != nil {
+			slog.Warn("failed to send push notification", "user_id", userID, "subscription_id", subscription.ID, "error", err)
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) > 0 && len(failed) == len(subscriptions) {
+		return errors.Join(failed...)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/webpush"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockPushRepository is a mock implementation of PushRepository
+type MockPushRepository struct {
+	mock.Mock
+}
+
+func (m *MockPushRepository) SavePushSubscription(ctx context.Context, subscription *models.PushSubscription) error {
+	args := m.Called(subscription)
+	return args.Error(0)
+}
+
+func (m *MockPushRepository) GetPushSubscriptions(ctx context.Context, userID string) ([]models.PushSubscription, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.PushSubscription), args.Error(1)
+}
+
+func (m *MockPushRepository) DeletePushSubscription(ctx context.Context, userID, endpoint string) (bool, error) {
+	args := m.Called(userID, endpoint)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPushRepository) DeletePushSubscriptionByID(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// MockPushSender is a mock implementation of PushSender
+type MockPushSender struct {
+	mock.Mock
+}
+
+func (m *MockPushSender) PublicKey() string {
+	return "public-key"
+}
+
+func (m *MockPushSender) Send(ctx context.Context, sub webpush.Subscription, payload []byte, opts webpush.Options) error {
+	args := m.Called(sub.Endpoint, string(payload), opts)
+	return args.Error(0)
+}
+
+func TestPushService_Subscribe(t *testing.T) {
+	req := &models.PushSubscribeRequest{Endpoint: "https://push.example.com/1"}
+	req.Keys.P256dh = "BNcRdreALRFXTkOOUHK1EtK2wtaz5Ry4YfYCA_0QTpQtUbVlUls0VJXg7A8u-Ts1XbjhazAkj7I99e8QcYP7DkM"
+	req.Keys.Auth = "tBHItJI5svbpez7KI4CCXg"
+
+	t.Run("Disabled without VAPID keys", func(t *testing.T) {
+		service := NewPushService(new(MockPushRepository))
+		assert.ErrorIs(t, service.Subscribe(context.Background(), "user123", req), ErrPushDisabled)
+		assert.Equal(t, &models.PushSettings{}, service.Settings())
+	})
+
+	t.Run("Keys must decode", func(t *testing.T) {
+		service := NewPushService(new(MockPushRepository))
+		service.SetSender(new(MockPushSender))
+		invalid := *req
+		invalid.Keys.Auth = "short"
+		assert.ErrorIs(t, service.Subscribe(context.Background(), "user123", &invalid), ErrInvalidPushSubscription)
+	})
+
+	t.Run("Saves the browser's keys", func(t *testing.T) {
+		repo := new(MockPushRepository)
+		service := NewPushService(repo)
+		service.SetSender(new(MockPushSender))
+		repo.On("SavePushSubscription", mock.MatchedBy(func(s *models.PushSubscription) bool {
+			return s.UserID == "user123" && s.Endpoint == req.Endpoint && s.P256dh == req.Keys.P256dh && s.Auth == req.Keys.Auth
+		})).Return(nil)
+
+		require.NoError(t, service.Subscribe(context.Background(), "user123", req))
+		assert.Equal(t, &models.PushSettings{Enabled: true, PublicKey: "public-key"}, service.Settings())
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestPushService_Send(t *testing.T) {
+	notification := models.PushNotification{Title: "Reminder", Body: "Call the bank", Tag: "reminder-1"}
+	payload, err := json.Marshal(notification)
+	require.NoError(t, err)
+	subscriptions := []models.PushSubscription{
+		{ID: 1, UserID: "user123", Endpoint: "https://push.example.com/1"},
+		{ID: 2, UserID: "user123", Endpoint: "https://push.example.com/2"},
+	}
+
+	t.Run("Gone subscriptions are deleted", func(t *testing.T) {
+		repo := new(MockPushRepository)
+		sender := new(MockPushSender)
+		service := NewPushService(repo)
+		service.SetSender(sender)
+
+		repo.On("GetPushSubscriptions", "user123").Return(subscriptions, nil)
+		sender.On("Send", "https://push.example.com/1", string(payload), webpush.Options{Topic: "reminder-1"}).Return(webpush.ErrGone)
+		sender.On("Send", "https://push.example.com/2", string(payload), webpush.Options{Topic: "reminder-1"}).Return(nil)
+		repo.On("DeletePushSubscriptionByID", int64(1)).Return(nil)
+
+		require.NoError(t, service.Send(context.Background(), "user123", notification))
+		repo.AssertExpectations(t)
+		sender.AssertExpectations(t)
+	})
+
+	t.Run("Fails when every browser failed", func(t *testing.T) {
+		repo := new(MockPushRepository)
+		sender := new(MockPushSender)
+		service := NewPushService(repo)
+		service.SetSender(sender)
+
+		repo.On("GetPushSubscriptions", "user123").Return(subscriptions, nil)
+		sender.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("timeout"))
+
+		assert.Error(t, service.Send(context.Background(), "user123", notification))
+	})
+
+	t.Run("Some browsers failing is enough", func(t *testing.T) {
+		repo := new(MockPushRepository)
+		sender := new(MockPushSender)
+		service := NewPushService(repo)
+		service.SetSender(sender)
+
+		repo.On("GetPushSubscriptions", "user123").Return(subscriptions, nil)
+		sender.On("Send", "https://push.example.com/1", mock.Anything, mock.Anything).Return(errors.New("timeout"))
+		sender.On("Send", "https://push.example.com/2", mock.Anything, mock.Anything).Return(nil)
+
+		assert.NoError(t, service.Send(context.Background(), "user123", notification))
+	})
+}
+
+func TestPushService_NoteAbandoned(t *testing.T) {
+	queue := new(MockJobQueue)
+	service := NewPushService(new(MockPushRepository))
+	service.SetJobQueue(queue)
+
+	// Disabled: nothing is queued
+	service.NoteAbandoned("user123", models.SyncEvent{Context: "Work", Date: "2025-10-16", Status: models.SyncStatusAbandoned})
+	queue.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything, mock.Anything)
+
+	service.SetSender(new(MockPushSender))
+	queue.On("Enqueue", "user123", JobPush, mock.MatchedBy(func(n models.PushNotification) bool {
+		return n.Title == "A note stopped syncing" && n.Tag == "sync-abandoned" &&
+			n.Body == "Your Work note of Thursday, October 16, 2025 failed to sync too many times. Open Daily Notes to retry it."
+	})).Return(nil)
+
+	service.NoteAbandoned("user123", models.SyncEvent{Context: "Work", Date: "2025-10-16", Status: models.SyncStatusAbandoned})
+	queue.AssertExpectations(t)
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/jobs"
+	"daily-notes/pkg/mail"
+	"daily-notes/pkg/maintenance"
+	"daily-notes/pkg/markdown"
+	"daily-notes/pkg/period"
+	"daily-notes/pkg/visibility"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// reminderPollInterval is how often pending reminders are checked for a time that has passed
+	reminderPollInterval = time.Minute
+	// reminderGrace is how late a reminder may still be sent, e.g. after the
+	// server was down; later ones are marked missed
+	reminderGrace = 24 * time.Hour
+	// maxUTCOffset is the furthest ahead of UTC a timezone is (Kiribati), so no
+	// reminder later than UTC plus this is due anywhere
+	maxUTCOffset = 14 * time.Hour
+)
+
+// reminderJob is the payload of a JobReminder
+type reminderJob struct {
+	ID int64 `json:"id"`
+}
+
+// ReminderService delivers reminders by email and web push at their local time
+// in the user's timezone setting. Reminders are written in notes as @remind(2025-11-02 09:00),
+// and parsed on every save, or made through the API. A poll every minute queues
+// a job sending each reminder that came due; reminders that came due more than
+// reminderGrace ago, while they couldn't be sent, are marked missed instead.
+type ReminderService struct {
+	repo      ReminderRepository
+	mailer    Mailer
+	pusher    Pusher
+	jobs      JobQueue
+	clock     clock.Clock
+	timeouts  Timeouts
+	publicURL string
+
+	maintenance *maintenance.Mode // No runs while the server is in maintenance; optional
+
+	run      sync.Mutex // One run at a time
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewReminderService creates a reminder service; reminders aren't delivered
+// until SetMailer or SetPusher, and SetJobQueue, are called
+func NewReminderService(repo ReminderRepository) *ReminderService {
+	return &ReminderService{repo: repo, clock: clock.Real(), timeouts: DefaultTimeouts}
+}
+
+// SetClock replaces the clock that decides when reminders are due
+func (rs *ReminderService) SetClock(c clock.Clock) {
+	rs.clock = c
+}
+
+// SetMailer sets how reminders are emailed and the address of the server their
+// links point to (PUBLIC_URL)
+func (rs *ReminderService) SetMailer(mailer Mailer, publicURL string) {
+	rs.mailer = mailer
+	rs.publicURL = publicURL
+}
+
+// SetPusher sets how reminders are sent as web push notifications
+func (rs *ReminderService) SetPusher(pusher Pusher) {
+	rs.pusher = pusher
+}
+
+// SetJobQueue sets the queue reminders are sent through; RunReminderJob handles JobReminder
+func (rs *ReminderService) SetJobQueue(queue JobQueue) {
+	rs.jobs = queue
+}
+
+// Enabled reports whether reminders can be delivered, by email or web push
+func (rs *ReminderService) Enabled() bool {
+	return rs.emailEnabled() || (rs.pusher != nil && rs.pusher.Enabled())
+}
+
+// emailEnabled reports whether reminders can be emailed
+func (rs *ReminderService) emailEnabled() bool {
+	return rs.mailer != nil && rs.publicURL != ""
+}
+
+// List returns the user's reminders, soonest first. status is pending, sent,
+// missed, or all ("" too).
+func (rs *ReminderService) List(ctx context.Context, userID, status string, limit, offset int) (_ []models.Reminder, err error) {
+	defer wrapOp("list reminders", &err)
+	switch status {
+	case "all":
+		status = ""
+	case "", models.ReminderPending, models.ReminderSent, models.ReminderMissed:
+	default:
+		return nil, ErrInvalidReminderStatus
+	}
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx, cancel := rs.timeouts.query(ctx)
+	defer cancel()
+
+	return rs.repo.GetReminders(ctx, userID, status, limit, offset)
+}
+
+// Create reminds the user of text at remindAt, a local time ("2025-11-02 09:00")
+// in their timezone setting
+func (rs *ReminderService) Create(ctx context.Context, userID, text, remindAt string) (_ *models.Reminder, err error) {
+	defer wrapOp("create reminder", &err)
+	if _, err := time.Parse(markdown.ReminderLayout, remindAt); err != nil {
+		return nil, ErrInvalidReminderTime
+	}
+
+	ctx, cancel := rs.timeouts.query(ctx)
+	defer cancel()
+
+	reminder := &models.Reminder{
+		UserID:    userID,
+		Text:      strings.TrimSpace(text),
+		RemindAt:  remindAt,
+		Status:    models.ReminderPending,
+		CreatedAt: rs.clock.Now(),
+	}
+	if err := rs.repo.CreateReminder(ctx, reminder); err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
+
+// Delete deletes a reminder made through the API. Reminders of notes fail with
+// ErrReminderInNote: they go away when their token is removed from the note.
+func (rs *ReminderService) Delete(ctx context.Context, userID string, id int64) (err error) {
+	defer wrapOp("delete reminder", &err)
+	ctx, cancel := rs.timeouts.query(ctx)
+	defer cancel()
+
+	deleted, err := rs.repo.DeleteReminder(ctx, userID, id)
+	if err != nil || deleted {
+		return err
+	}
+	reminder, err := rs.repo.GetReminder(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if reminder != nil {
+		return ErrReminderInNote
+	}
+	return ErrReminderNotFound
+}
+
+// SetMaintenance skips the runs due while the server is in maintenance; the
+// reminders are queued on the first run after it
+func (rs *ReminderService) SetMaintenance(mode *maintenance.Mode) {
+	rs.maintenance = mode
+}
+
+// Start queues due reminders every reminderPollInterval until Stop
+func (rs *ReminderService) Start() {
+	rs.stopChan = make(chan struct{})
+	rs.done = make(chan struct{})
+
+	go func() {
+		defer close(rs.done)
+		ticker := time.NewTicker(reminderPollInterval)
+		defer ticker.Stop()
+
+		for {
+			rs.RunDue(context.Background())
+			select {
+			case <-ticker.C:
+			case <-rs.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background loop, waiting for the run in progress
+func (rs *ReminderService) Stop() {
+	if rs.stopChan == nil {
+		return
+	}
+	close(rs.stopChan)
+	<-rs.done
+	rs.stopChan = nil
+}
+
+// RunDue queues the reminders whose local time has passed and returns how many
+// it queued. A reminder that fails to queue is tried again on the next run.
+func (rs *ReminderService) RunDue(ctx context.Context) int {
+	if rs.maintenance.Paused() {
+		return 0
+	}
+	rs.run.Lock()
+	defer rs.run.Unlock()
+
+	now := rs.clock.Now()
+	reminders, err := rs.repo.GetDueReminders(ctx, now.UTC().Add(maxUTCOffset).Format(markdown.ReminderLayout))
+	if err != nil {
+		slog.Warn("failed to get due reminders", "error", err)
+		return 0
+	}
+
+	queued := 0
+	for _, reminder := range reminders {
+		at, err := time.ParseInLocation(markdown.ReminderLayout, reminder.RemindAt, loadLocation(reminder.Timezone))
+		if err != nil || now.Before(at) {
+			continue
+		}
+
+		status := models.ReminderSent
+		if now.Sub(at) > reminderGrace {
+			status = models.ReminderMissed
+		} else if err := rs.jobs.Enqueue(ctx, reminder.UserID, JobReminder, reminderJob{ID: reminder.ID}); err != nil {
+			slog.Warn("failed to queue reminder", "user_id", reminder.UserID, "reminder_id", reminder.ID, "error", err)
+			continue
+		} else {
+			queued++
+		}
+		if err := rs.repo.SetReminderStatus(ctx, reminder.ID, status, now); err != nil {
+			slog.Warn("failed to record reminder status", "reminder_id", reminder.ID, "error", err)
+		}
+	}
+	return queued
+}
+
+// RunReminderJob sends a reminder queued by RunDue as a web push notification
+// and by email; reminders deleted since send nothing. Push is best effort, as
+// retries email the reminder again: a failed notification is only logged.
+func (rs *ReminderService) RunReminderJob(ctx context.Context, job *models.Job) (err error) {
+	defer wrapOp("run reminder job", &err)
+	var payload reminderJob
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+	if !rs.Enabled() {
+		return jobs.Permanent(ErrRemindersDisabled)
+	}
+
+	queryCtx, cancel := rs.timeouts.query(ctx)
+	defer cancel()
+	reminder, err := rs.repo.GetReminder(queryCtx, job.UserID, payload.ID)
+	if err != nil {
+		return err
+	}
+	if reminder == nil {
+		return nil
+	}
+
+	if rs.pusher != nil && rs.pusher.Enabled() {
+		if err := rs.pusher.Send(ctx, job.UserID, reminderNotification(reminder)); err != nil {
+			slog.Warn("failed to push reminder", "user_id", job.UserID, "reminder_id", reminder.ID, "error", err)
+		}
+	}
+	if !rs.emailEnabled() {
+		return nil
+	}
+	user, err := rs.repo.GetUser(queryCtx, job.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+	return rs.mailer.Send(ctx, rs.message(reminder, user))
+}
+
+// reminderText is the text of a reminder as notifications show it: empty for
+// reminders of notes the Notify policy doesn't show. Encrypted notes have no
+// reminders, as the server can't read them.
+func reminderText(reminder *models.Reminder) string {
+	if !visibility.Notify.Allows(reminder.LocalOnly, false, false) {
+		return ""
+	}
+	return reminder.Text
+}
+
+// noteLink is the path of the plain HTML version of a reminder's note, empty
+// for reminders made through the API
+func noteLink(reminder *models.Reminder) string {
+	if reminder.Context == "" {
+		return ""
+	}
+	query := url.Values{"context": {reminder.Context}, "date": {reminder.Date}}
+	return "/plain?" + query.Encode()
+}
+
+// reminderNotification builds the web push notification of a reminder; the
+// tag keeps a retried reminder from showing twice
+func reminderNotification(reminder *models.Reminder) models.PushNotification {
+	notification := models.PushNotification{
+		Title: "Reminder",
+		Body:  reminderText(reminder),
+		URL:   noteLink(reminder),
+		Tag:   "reminder-" + strconv.FormatInt(reminder.ID, 10),
+	}
+	if notification.URL == "" {
+		notification.URL = "/"
+	}
+	return notification
+}
+
+// message builds the email of a reminder. Reminders of notes the Notify policy
+// doesn't show are sent without their text.
+func (rs *ReminderService) message(reminder *models.Reminder, user *models.User) mail.Message {
+	text := reminderText(reminder)
+	subject := "Reminder"
+	if text != "" {
+		subject += ": " + text
+	}
+
+	var body strings.Builder
+	if text != "" {
+		body.WriteString(text + "\n\n")
+	}
+	body.WriteString("Reminder for " + reminder.RemindAt + " (" + user.Settings.Timezone + ")\n")
+	if link := noteLink(reminder); link != "" {
+		body.WriteString("\nIn your " + reminder.Context + " note of " + period.Title(reminder.Date) + ":\n")
+		body.WriteString(rs.publicURL + link + "\n")
+	}
+
+	return mail.Message{
+		To:      mail.Address(user.Name, user.Email),
+		Subject: subject,
+		Text:    body.String(),
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/mail"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockReminderRepository is a mock implementation of ReminderRepository
+type MockReminderRepository struct {
+	mock.Mock
+}
+
+func (m *MockReminderRepository) GetReminders(ctx context.Context, userID, status string, limit, offset int) ([]models.Reminder, error) {
+	args := m.Called(userID, status, limit, offset)
+	return args.Get(0).([]models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderRepository) GetReminder(ctx context.Context, userID string, id int64) (*models.Reminder, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderRepository) CreateReminder(ctx context.Context, reminder *models.Reminder) error {
+	args := m.Called(reminder)
+	return args.Error(0)
+}
+
+func (m *MockReminderRepository) DeleteReminder(ctx context.Context, userID string, id int64) (bool, error) {
+	args := m.Called(userID, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockReminderRepository) GetDueReminders(ctx context.Context, before string) ([]models.Reminder, error) {
+	args := m.Called(before)
+	return args.Get(0).([]models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderRepository) SetReminderStatus(ctx context.Context, id int64, status string, at time.Time) error {
+	args := m.Called(id, status, at)
+	return args.Error(0)
+}
+
+func (m *MockReminderRepository) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func TestReminderService_Create(t *testing.T) {
+	repo := new(MockReminderRepository)
+	service := NewReminderService(repo)
+
+	_, err := service.Create(context.Background(), "user123", "Call the bank", "2025-11-02T09:00")
+	assert.ErrorIs(t, err, ErrInvalidReminderTime)
+
+	repo.On("CreateReminder", mock.MatchedBy(func(r *models.Reminder) bool {
+		return r.UserID == "user123" && r.Text == "Call the bank" && r.RemindAt == "2025-11-02 09:00" && r.Status == models.ReminderPending
+	})).Return(nil)
+	reminder, err := service.Create(context.Background(), "user123", " Call the bank ", "2025-11-02 09:00")
+	require.NoError(t, err)
+	assert.Equal(t, "Call the bank", reminder.Text)
+	repo.AssertExpectations(t)
+}
+
+func TestReminderService_Delete(t *testing.T) {
+	repo := new(MockReminderRepository)
+	service := NewReminderService(repo)
+	repo.On("DeleteReminder", "user123", int64(1)).Return(true, nil)
+	repo.On("DeleteReminder", "user123", mock.Anything).Return(false, nil)
+	repo.On("GetReminder", "user123", int64(2)).Return(&models.Reminder{ID: 2, Context: "Work"}, nil)
+	repo.On("GetReminder", "user123", int64(3)).Return(nil, nil)
+
+	require.NoError(t, service.Delete(context.Background(), "user123", 1))
+	assert.ErrorIs(t, service.Delete(context.Background(), "user123", 2), ErrReminderInNote)
+	assert.ErrorIs(t, service.Delete(context.Background(), "user123", 3), ErrReminderNotFound)
+}
+
+func TestReminderService_List(t *testing.T) {
+	repo := new(MockReminderRepository)
+	service := NewReminderService(repo)
+	repo.On("GetReminders", "user123", "", 100, 0).Return([]models.Reminder{}, nil)
+
+	_, err := service.List(context.Background(), "user123", "all", 0, -1)
+	require.NoError(t, err)
+	_, err = service.List(context.Background(), "user123", "done", 100, 0)
+	assert.ErrorIs(t, err, ErrInvalidReminderStatus)
+	repo.AssertExpectations(t)
+}
+
+func TestReminderService_RunDue(t *testing.T) {
+	// 08:30 UTC is 09:30 in Madrid and 04:30 in New York
+	now := time.Date(2025, 11, 2, 8, 30, 0, 0, time.UTC)
+	repo := new(MockReminderRepository)
+	queue := new(MockJobQueue)
+	service := NewReminderService(repo)
+	service.SetJobQueue(queue)
+	service.SetClock(clock.NewFake(now))
+
+	repo.On("GetDueReminders", "2025-11-02 22:30").Return([]models.Reminder{
+		{ID: 1, UserID: "due", RemindAt: "2025-11-02 09:00", Timezone: "Europe/Madrid"},
+		{ID: 2, UserID: "early", RemindAt: "2025-11-02 09:00", Timezone: "America/New_York"},
+		{ID: 3, UserID: "late", RemindAt: "2025-10-30 09:00", Timezone: "UTC"},
+	}, nil)
+	queue.On("Enqueue", "due", JobReminder, reminderJob{ID: 1}).Return(nil)
+	repo.On("SetReminderStatus", int64(1), models.ReminderSent, now).Return(nil)
+	repo.On("SetReminderStatus", int64(3), models.ReminderMissed, now).Return(nil)
+
+	assert.Equal(t, 1, service.RunDue(context.Background()))
+	queue.AssertExpectations(t)
+	repo.AssertExpectations(t)
+	queue.AssertNotCalled(t, "Enqueue", "early", mock.Anything, mock.Anything)
+	queue.AssertNotCalled(t, "Enqueue", "late", mock.Anything, mock.Anything)
+}
+
+func TestReminderService_RunReminderJob(t *testing.T) {
+	job := &models.Job{UserID: "user123", Kind: JobReminder, Payload: `{"id":1}`}
+	user := &models.User{ID: "user123", Email: "ana@example.com", Name: "Ana", Settings: models.UserSettings{Timezone: "Europe/Madrid"}}
+
+	t.Run("Links to the note", func(t *testing.T) {
+		repo := new(MockReminderRepository)
+		mailer := new(MockMailer)
+		service := NewReminderService(repo)
+		service.SetMailer(mailer, "https://notes.example.com")
+
+		repo.On("GetReminder", "user123", int64(1)).Return(&models.Reminder{ID: 1, Context: "Work", Date: "2025-10-30", Text: "Call the bank", RemindAt: "2025-11-02 09:00"}, nil)
+		repo.On("GetUser", "user123").Return(user, nil)
+		mailer.On("Send", mock.Anything).Return(nil)
+
+		require.NoError(t, service.RunReminderJob(context.Background(), job))
+		msg := mailer.Calls[0].Arguments.Get(0).(mail.Message)
+		assert.Equal(t, `"Ana" <ana@example.com>`, msg.To)
+		assert.Equal(t, "Reminder: Call the bank", msg.Subject)
+		assert.Contains(t, msg.Text, "2025-11-02 09:00 (Europe/Madrid)")
+		assert.Contains(t, msg.Text, "https://notes.example.com/plain?context=Work&date=2025-10-30")
+	})
+
+	t.Run("Local-only notes aren't quoted", func(t *testing.T) {
+		repo := new(MockReminderRepository)
+		mailer := new(MockMailer)
+		service := NewReminderService(repo)
+		service.SetMailer(mailer, "https://notes.example.com")
+
+		repo.On("GetReminder", "user123", int64(1)).Return(&models.Reminder{ID: 1, Context: "Private", Date: "2025-10-30", Text: "Secret", RemindAt: "2025-11-02 09:00", LocalOnly: true}, nil)
+		repo.On("GetUser", "user123").Return(user, nil)
+		mailer.On("Send", mock.Anything).Return(nil)
+
+		require.NoError(t, service.RunReminderJob(context.Background(), job))
+		msg := mailer.Calls[0].Arguments.Get(0).(mail.Message)
+		assert.Equal(t, "Reminder", msg.Subject)
+		assert.NotContains(t, msg.Text, "Secret")
+	})
+
+	t.Run("Pushes without email", func(t *testing.T) {
+		repo := new(MockReminderRepository)
+		pushRepo := new(MockPushRepository)
+		sender := new(MockPushSender)
+		push := NewPushService(pushRepo)
+		push.SetSender(sender)
+		service := NewReminderService(repo)
+		service.SetPusher(push)
+		require.True(t, service.Enabled())
+
+		repo.On("GetReminder", "user123", int64(1)).Return(&models.Reminder{ID: 1, Context: "Work", Date: "2025-10-30", Text: "Call the bank", RemindAt: "2025-11-02 09:00"}, nil)
+		pushRepo.On("GetPushSubscriptions", "user123").Return([]models.PushSubscription{{ID: 1, Endpoint: "https://push.example.com/1"}}, nil)
+		sender.On("Send", "https://push.example.com/1",
+			`{"title":"Reminder","body":"Call the bank","url":"/plain?context=Work\u0026date=2025-10-30","tag":"reminder-1"}`,
+			mock.Anything).Return(nil)
+
+		require.NoError(t, service.RunReminderJob(context.Background(), job))
+		sender.AssertExpectations(t)
+		repo.AssertNotCalled(t, "GetUser", mock.Anything)
+	})
+
+	t.Run("Deleted reminders send nothing", func(t *testing.T) {
+		repo := new(MockReminderRepository)
+		mailer := new(MockMailer)
+		service := NewReminderService(repo)
+		service.SetMailer(mailer, "https://notes.example.com")
+
+		repo.On("GetReminder", "user123", int64(1)).Return(nil, nil)
+
+		require.NoError(t, service.RunReminderJob(context.Background(), job))
+		mailer.AssertNotCalled(t, "Send", mock.Anything)
+	})
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"slices"
+
+	"golang.org/x/oauth2"
+)
+
+// StorageProviderService lets each user pick which storage provider their notes sync to
+// Google Drive is the sign-in provider and local disk needs no authorization, so
+// both are always connected; Dropbox is connected through its own OAuth flow
+// and keeps a token per user.
+type StorageProviderService struct {
+	repo      StorageProviderRepository
+	available []string
+}
+
+// NewStorageProviderService creates a storage provider service offering the given providers
+// storage.Drive is always offered.
+func NewStorageProviderService(repo StorageProviderRepository, available ...string) *StorageProviderService {
+	ss := &StorageProviderService{repo: repo}
+	ss.SetAvailable(available...)
+	return ss
+}
+
+// SetAvailable replaces the providers users can pick, e.g. with those registered at startup
+// storage.Drive is always offered.
+func (ss *StorageProviderService) SetAvailable(available ...string) {
+	providers := []string{storage.Drive}
+	for _, name := range available {
+		if !slices.Contains(providers, name) {
+			providers = append(providers, name)
+		}
+	}
+	ss.available = providers
+}
+
+// Available reports whether a provider is configured on this server
+func (ss *StorageProviderService) Available(provider string) bool {
+	return slices.Contains(ss.available, provider)
+}
+
+// Current returns the provider a user's notes sync to
+func (ss *StorageProviderService) Current(ctx context.Context, userID string) (_ string, err error) {
+	defer wrapOp("get storage provider", &err)
+	provider, err := ss.repo.GetStorageProvider(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if provider == "" {
+		return storage.Drive, nil
+	}
+	return provider, nil
+}
+
+// Status returns the selected provider and whether each provider can be picked
+func (ss *StorageProviderService) Status(ctx context.Context, userID string) (_ *models.StorageStatus, err error) {
+	defer wrapOp("get storage status", &err)
+	current, err := ss.Current(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.StorageStatus{Provider: current}
+	for _, name := range []string{storage.Drive, storage.Dropbox, storage.Local, storage.S3, storage.WebDAV} {
+		connected, err := ss.connected(ctx, userID, name)
+		if err != nil {
+			return nil, err
+		}
+		status.Providers = append(status.Providers, models.StorageProviderStatus{
+			Name:      name,
+			Available: ss.Available(name),
+			Connected: connected,
+		})
+	}
+	return status, nil
+}
+
+// Select switches a user's notes to another provider
+// Every note is queued for upload so the new provider receives the full history.
+// Returns how many notes were queued.
+func (ss *StorageProviderService) Select(ctx context.Context, userID, provider string) (_ int, err error) {
+	defer wrapOp("select storage provider", &err)
+	if !ss.Available(provider) {
+		return 0, ErrStorageUnavailable
+	}
+
+	connected, err := ss.connected(ctx, userID, provider)
+	if err != nil {
+		return 0, err
+	}
+	if !connected {
+		return 0, ErrStorageNotConnected
+	}
+
+	current, err := ss.Current(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if current == provider {
+		return 0, nil
+	}
+
+	return ss.switchTo(ctx, userID, provider)
+}
+
+// Connect stores the token a user authorized for a provider
+func (ss *StorageProviderService) Connect(ctx context.Context, userID, provider string, token *oauth2.Token) (err error) {
+	defer wrapOp("connect storage provider", &err)
+	if provider == storage.Drive {
+		return nil
+	}
+	if !ss.Available(provider) {
+		return ErrStorageUnavailable
+	}
+	if token == nil || token.AccessToken == "" {
+		return ErrInvalidToken
+	}
+	return ss.repo.SaveStorageToken(ctx, userID, provider, token)
+}
+
+// Disconnect forgets a provider's token
+// Users syncing to that provider are moved back to Google Drive first.
+func (ss *StorageProviderService) Disconnect(ctx context.Context, userID, provider string) (err error) {
+	defer wrapOp("disconnect storage provider", &err)
+	if provider == storage.Drive {
+		return ErrStorageIsSignIn
+	}
+
+	current, err := ss.Current(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if current == provider {
+		if _, err := ss.switchTo(ctx, userID, storage.Drive); err != nil {
+			return err
+		}
+	}
+
+	return ss.repo.DeleteStorageToken(ctx, userID, provider)
+}
+
+func (ss *StorageProviderService) switchTo(ctx context.Context, userID, provider string) (int, error) {
+	if err := ss.repo.SetStorageProvider(ctx, userID, provider); err != nil {
+		return 0, err
+	}
+	return ss.repo.MarkUserNotesForSync(ctx, userID)
+}
+
+// connected reports whether a user authorized a provider; only Dropbox needs its own token
+func (ss *StorageProviderService) connected(ctx context.Context, userID, provider string) (bool, error) {
+	if provider != storage.Dropbox {
+		return true, nil
+	}
+	token, err := ss.repo.GetStorageToken(ctx, userID, provider)
+	if err != nil {
+		return false, err
+	}
+	return token != nil, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"daily-notes/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// MockStorageProviderRepository is a mock implementation of StorageProviderRepository
+type MockStorageProviderRepository struct {
+	mock.Mock
+}
+
+// Ensure MockStorageProviderRepository implements StorageProviderRepository interface
+var _ StorageProviderRepository = (*MockStorageProviderRepository)(nil)
+
+func (m *MockStorageProviderRepository) GetStorageProvider(_ context.Context, userID string) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockStorageProviderRepository) SetStorageProvider(_ context.Context, userID, provider string) error {
+	args := m.Called(userID, provider)
+	return args.Error(0)
+}
+
+func (m *MockStorageProviderRepository) GetStorageToken(_ context.Context, userID, provider string) (*oauth2.Token, error) {
+	args := m.Called(userID, provider)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*oauth2.Token), args.Error(1)
+}
+
+func (m *MockStorageProviderRepository) SaveStorageToken(_ context.Context, userID, provider string, token *oauth2.Token) error {
+	args := m.Called(userID, provider, token)
+	return args.Error(0)
+}
+
+func (m *MockStorageProviderRepository) DeleteStorageToken(_ context.Context, userID, provider string) error {
+	args := m.Called(userID, provider)
+	return args.Error(0)
+}
+
+func (m *MockStorageProviderRepository) MarkUserNotesForSync(_ context.Context, userID string) (int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
+func TestStorageProviderService_Status(t *testing.T) {
+	repo := new(MockStorageProviderRepository)
+	repo.On("GetStorageProvider", "user123").Return("", nil)
+	repo.On("GetStorageToken", "user123", storage.Dropbox).Return(nil, nil)
+
+	service := NewStorageProviderService(repo)
+	status, err := service.Status(context.Background(), "user123")
+
+	require.NoError(t, err)
+	assert.Equal(t, storage.Drive, status.Provider)
+	require.Len(t, status.Providers, 5)
+	assert.True(t, status.Providers[0].Available)
+	assert.True(t, status.Providers[0].Connected)
+	assert.False(t, status.Providers[1].Available, "dropbox is not offered unless configured")
+	assert.False(t, status.Providers[1].Connected)
+	assert.Equal(t, storage.Local, status.Providers[2].Name)
+	assert.True(t, status.Providers[2].Connected, "local disk needs no authorization")
+	assert.Equal(t, storage.S3, status.Providers[3].Name)
+	assert.False(t, status.Providers[3].Available)
+	assert.Equal(t, storage.WebDAV, status.Providers[4].Name)
+}
+
+func TestStorageProviderService_Select(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Rejects providers not configured on the server", func(t *testing.T) {
+		service := NewStorageProviderService(new(MockStorageProviderRepository))
+		_, err := service.Select(ctx, "user123", storage.Dropbox)
+		assert.ErrorIs(t, err, ErrStorageUnavailable)
+	})
+
+	t.Run("Requires the provider to be connected", func(t *testing.T) {
+		repo := new(MockStorageProviderRepository)
+		repo.On("GetStorageToken", "user123", storage.Dropbox).Return(nil, nil)
+
+		service := NewStorageProviderService(repo, storage.Dropbox)
+		_, err := service.Select(ctx, "user123", storage.Dropbox)
+		assert.ErrorIs(t, err, ErrStorageNotConnected)
+	})
+
+	t.Run("Switches and queues every note", func(t *testing.T) {
+		repo := new(MockStorageProviderRepository)
+		repo.On("GetStorageToken", "user123", storage.Dropbox).Return(&oauth2.Token{AccessToken: "dbx"}, nil)
+		repo.On("GetStorageProvider", "user123").Return(storage.Drive, nil)
+		repo.On("SetStorageProvider", "user123", storage.Dropbox).Return(nil)
+		repo.On("MarkUserNotesForSync", "user123").Return(12, nil)
+
+		service := NewStorageProviderService(repo, storage.Dropbox)
+		queued, err := service.Select(ctx, "user123", storage.Dropbox)
+
+		require.NoError(t, err)
+		assert.Equal(t, 12, queued)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Selecting the current provider is a no-op", func(t *testing.T) {
+		repo := new(MockStorageProviderRepository)
+		repo.On("GetStorageProvider", "user123").Return(storage.Drive, nil)
+
+		service := NewStorageProviderService(repo)
+		queued, err := service.Select(ctx, "user123", storage.Drive)
+
+		require.NoError(t, err)
+		assert.Zero(t, queued)
+		repo.AssertNotCalled(t, "MarkUserNotesForSync", mock.Anything)
+	})
+}
+
+func TestStorageProviderService_Disconnect(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Moves the user back to Drive", func(t *testing.T) {
+		repo := new(MockStorageProviderRepository)
+		repo.On("GetStorageProvider", "user123").Return(storage.Dropbox, nil)
+		repo.On("SetStorageProvider", "user123", storage.Drive).Return(nil)
+		repo.On("MarkUserNotesForSync", "user123").Return(3, nil)
+		repo.On("DeleteStorageToken", "user123", storage.Dropbox).Return(nil)
+
+		service := NewStorageProviderService(repo, storage.Dropbox)
+		require.NoError(t, service.Disconnect(ctx, "user123", storage.Dropbox))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Drive cannot be disconnected", func(t *testing.T) {
+		service := NewStorageProviderService(new(MockStorageProviderRepository))
+		assert.ErrorIs(t, service.Disconnect(ctx, "user123", storage.Drive), ErrStorageIsSignIn)
+	})
+}
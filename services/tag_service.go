@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"daily-notes/pkg/maintenance"
+	"daily-notes/pkg/markdown"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// tagJobRetention is how long the progress of a finished tag job can still be read
+	tagJobRetention = time.Hour
+
+	// tagJobPage is how many tagged notes are listed per query when a job starts
+	tagJobPage = 100
+
+	// tagJobMaintenancePoll is how often a job waiting for maintenance checks whether it ended
+	tagJobMaintenancePoll = 5 * time.Second
+)
+
+var (
+	// errTagUnchanged ends the edit of a note that no longer uses the tags
+	errTagUnchanged = errors.New("note does not use the tags")
+
+	// errTagJobStopped fails the jobs still running when the server stops
+	errTagJobStopped = errors.New("server is shutting down")
+)
+
+// TagService renames and merges #tags across a user's notes. Rewrites run in the
+// background, one job at a time, and save each note like an edit, so its tags are
+// re-indexed, it is queued for sync and open clients hear about it.
+type TagService struct {
+	notes *NoteService
+	clock clock.Clock
+	ids   idgen.Generator
+
+	maintenance *maintenance.Mode // Jobs wait while the server is in maintenance; optional
+
+	mu   sync.Mutex
+	jobs map[string]*models.TagJob
+
+	running sync.Mutex // Held by the job rewriting notes
+	wg      sync.WaitGroup
+	stop    chan struct{}
+}
+
+// NewTagService creates a new tag service rewriting notes through notes
+func NewTagService(notes *NoteService) *TagService {
+	return &TagService{
+		notes: notes,
+		clock: clock.Real(),
+		ids:   idgen.UUID(),
+		jobs:  make(map[string]*models.TagJob),
+		stop:  make(chan struct{}),
+	}
+}
+
+// SetClock replaces the clock used for job timestamps
+func (ts *TagService) SetClock(c clock.Clock) {
+	ts.clock = c
+}
+
+// SetIDGenerator replaces the generator used for job IDs
+func (ts *TagService) SetIDGenerator(g idgen.Generator) {
+	ts.ids = g
+}
+
+// SetMaintenance makes running jobs wait while the server is in maintenance
+func (ts *TagService) SetMaintenance(mode *maintenance.Mode) {
+	ts.maintenance = mode
+}
+
+// Rename queues rewriting #from as #to in every note using it. Tags may be given
+// with their leading # and in any case. A tag already in use can't be renamed
+// to; merge into it instead.
+func (ts *TagService) Rename(ctx context.Context, userID, from, to string) (_ *models.TagJob, err error) {
+	defer wrapOp("rename tag", &err)
+	from, to = normalizeTag(from), normalizeTag(to)
+	if !markdown.IsHashtag(from) || !markdown.IsHashtag(to) {
+		return nil, ErrInvalidTag
+	}
+	if from == to {
+		return nil, ErrSameTag
+	}
+
+	used, err := ts.usedTags(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !used[from] {
+		return nil, ErrTagNotFound
+	}
+	if used[to] {
+		return nil, ErrTagExists
+	}
+
+	return ts.queue(userID, models.TagJobRename, []string{from}, to), nil
+}
+
+// Merge queues rewriting each of tags as #into in every note using them; into may
+// be in use already and listing it among tags is allowed. Listed tags no note uses
+// are left out, but at least one must be used.
+func (ts *TagService) Merge(ctx context.Context, userID string, tags []string, into string) (_ *models.TagJob, err error) {
+	defer wrapOp("merge tags", &err)
+	into = normalizeTag(into)
+	if !markdown.IsHashtag(into) {
+		return nil, ErrInvalidTag
+	}
+
+	used, err := ts.usedTags(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var from []string
+	listed := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if !markdown.IsHashtag(tag) {
+			return nil, ErrInvalidTag
+		}
+		if tag == into || listed[tag] {
+			continue
+		}
+		listed[tag] = true
+		if used[tag] {
+			from = append(from, tag)
+		}
+	}
+	if len(listed) == 0 {
+		return nil, ErrSameTag
+	}
+	if len(from) == 0 {
+		return nil, ErrTagNotFound
+	}
+
+	return ts.queue(userID, models.TagJobMerge, from, into), nil
+}
+
+// Job returns the progress of one of the user's tag jobs
+func (ts *TagService) Job(userID, id string) (_ *models.TagJob, err error) {
+	defer wrapOp("get tag job", &err)
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	job, ok := ts.jobs[id]
+	if !ok || job.UserID != userID {
+		return nil, ErrTagJobNotFound
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// Stop fails the jobs that are queued or running once their current note is
+// saved, and waits for them
+func (ts *TagService) Stop() {
+	close(ts.stop)
+	ts.wg.Wait()
+}
+
+// usedTags returns the tags of the user's live notes
+func (ts *TagService) usedTags(ctx context.Context, userID string) (map[string]bool, error) {
+	tags, err := ts.notes.Tags(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		used[tag.Name] = true
+	}
+	return used, nil
+}
+
+// queue records a new job and starts it once the jobs before it are done
+func (ts *TagService) queue(userID, kind string, from []string, to string) *models.TagJob {
+	now := ts.clock.Now()
+	job := &models.TagJob{
+		ID:        ts.ids.NewID(),
+		UserID:    userID,
+		Kind:      kind,
+		From:      from,
+		To:        to,
+		Status:    models.TagJobQueued,
+		CreatedAt: now,
+	}
+
+	ts.mu.Lock()
+	for id, old := range ts.jobs {
+		if old.FinishedAt != nil && now.Sub(*old.FinishedAt) > tagJobRetention {
+			delete(ts.jobs, id)
+		}
+	}
+	ts.jobs[job.ID] = job
+	snapshot := *job
+	ts.mu.Unlock()
+
+	ts.wg.Add(1)
+	go func() {
+		defer ts.wg.Done()
+		ts.run(job)
+	}()
+	return &snapshot
+}
+
+// run rewrites the tags of a job in every note using them. Notes that fail to
+// save are counted and skipped; the job only fails if its notes can't be listed.
+func (ts *TagService) run(job *models.TagJob) {
+	ts.running.Lock()
+	defer ts.running.Unlock()
+
+	ts.update(job, func() { job.Status = models.TagJobRunning })
+
+	notes, err := ts.taggedNotes(job.UserID, job.From)
+	if err != nil {
+		ts.finish(job, err)
+		return
+	}
+	ts.update(job, func() { job.Total = len(notes) })
+
+	for _, note := range notes {
+		if !ts.holdForMaintenance() {
+			ts.finish(job, errTagJobStopped)
+			return
+		}
+
+		_, err := ts.notes.edit(context.Background(), job.UserID, note.Context, note.Date, nil, func(content string) (string, error) {
+			updated := content
+			for _, tag := range job.From {
+				updated = markdown.ReplaceHashtag(updated, tag, job.To)
+			}
+			if updated == content {
+				return "", errTagUnchanged
+			}
+			return updated, nil
+		})
+		if err != nil && !errors.Is(err, errTagUnchanged) {
+			slog.Warn("failed to rewrite tags of note", "job", job.ID, "context", note.Context, "date", note.Date, "error", err)
+		}
+
+		ts.update(job, func() {
+			job.Processed++
+			switch {
+			case err == nil:
+				job.Updated++
+			case !errors.Is(err, errTagUnchanged):
+				job.Failed++
+			}
+		})
+	}
+	ts.finish(job, nil)
+}
+
+// holdForMaintenance waits while the server is in maintenance; false when the
+// service stops
+func (ts *TagService) holdForMaintenance() bool {
+	for {
+		select {
+		case <-ts.stop:
+			return false
+		default:
+		}
+		if !ts.maintenance.Paused() {
+			return true
+		}
+		select {
+		case <-ts.stop:
+			return false
+		case <-time.After(tagJobMaintenancePoll):
+		}
+	}
+}
+
+// taggedNotes lists the user's live notes using any of tags, once each
+func (ts *TagService) taggedNotes(userID string, tags []string) ([]models.Note, error) {
+	var notes []models.Note
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		for offset := 0; ; offset += tagJobPage {
+			page, err := ts.notes.ListByTag(context.Background(), userID, tag, tagJobPage, offset)
+			if err != nil {
+				return nil, err
+			}
+			for _, note := range page {
+				if key := note.Context + "/" + note.Date; !seen[key] {
+					seen[key] = true
+					notes = append(notes, note)
+				}
+			}
+			if len(page) < tagJobPage {
+				break
+			}
+		}
+	}
+	return notes, nil
+}
+
+// update changes a job under the lock readers of its progress take
+func (ts *TagService) update(job *models.TagJob, change func()) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	change()
+}
+
+// finish marks a job done, or failed with err
+func (ts *TagService) finish(job *models.TagJob, err error) {
+	now := ts.clock.Now()
+	ts.update(job, func() {
+		job.Status = models.TagJobDone
+		if err != nil {
+			job.Status = models.TagJobFailed
+			job.Error = err.Error()
+		}
+		job.FinishedAt = &now
+	})
+}
+
+// normalizeTag turns a tag given by a user into its indexed form: without its
+// leading # and lowercased
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
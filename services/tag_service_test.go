@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTagService(repo *MockRepository, worker *MockSyncWorker) *TagService {
+	service := NewTagService(NewNoteService(repo, worker))
+	service.SetClock(clock.NewFake(time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC)))
+	service.SetIDGenerator(idgen.NewSequence("job"))
+	return service
+}
+
+// waitForTagJob waits until a job is done or failed and returns it
+func waitForTagJob(t *testing.T, service *TagService, userID, id string) *models.TagJob {
+	t.Helper()
+	var job *models.TagJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = service.Job(userID, id)
+		require.NoError(t, err)
+		return job.FinishedAt != nil
+	}, time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestTagService_Rename(t *testing.T) {
+	tags := []models.Tag{{Name: "deploy", Count: 2}, {Name: "ops", Count: 1}}
+
+	t.Run("Tags are checked before queueing", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetTags", "user123").Return(tags, nil)
+		service := newTestTagService(repo, new(MockSyncWorker))
+
+		_, err := service.Rename(context.Background(), "user123", "deploy", "two words")
+		assert.ErrorIs(t, err, ErrInvalidTag)
+		_, err = service.Rename(context.Background(), "user123", "#Deploy", "deploy")
+		assert.ErrorIs(t, err, ErrSameTag)
+		_, err = service.Rename(context.Background(), "user123", "release", "shipping")
+		assert.ErrorIs(t, err, ErrTagNotFound)
+		_, err = service.Rename(context.Background(), "user123", "deploy", "ops")
+		assert.ErrorIs(t, err, ErrTagExists)
+		repo.AssertNotCalled(t, "GetNotesByTag")
+	})
+
+	t.Run("Notes using the tag are rewritten and queued for sync", func(t *testing.T) {
+		repo := new(MockRepository)
+		worker := new(MockSyncWorker)
+		repo.On("GetTags", "user123").Return(tags, nil)
+		repo.On("GetNotesByTag", "user123", "deploy", tagJobPage, 0).Return([]models.Note{
+			{Context: "work", Date: "2025-01-06"},
+			{Context: "home", Date: "2025-01-07"},
+		}, nil)
+		repo.On("GetNote", "user123", "work", "2025-01-06").
+			Return(&models.Note{Context: "work", Date: "2025-01-06", Content: "#Deploy done, #deploy-notes kept", Revision: 3}, nil)
+		// Edited since the job listed it
+		repo.On("GetNote", "user123", "home", "2025-01-07").
+			Return(&models.Note{Context: "home", Date: "2025-01-07", Content: "no tags left", Revision: 1}, nil)
+		repo.On("UpsertNoteAtRevision", mock.MatchedBy(func(note *models.Note) bool {
+			return note.Date == "2025-01-06" && note.Content == "#release done, #deploy-notes kept"
+		}), 3, true).Return(true, nil).Once()
+		worker.On("SyncNoteImmediate", "user123", "work", "2025-01-06").Return().Once()
+		service := newTestTagService(repo, worker)
+
+		job, err := service.Rename(context.Background(), "user123", "#Deploy", "Release")
+		require.NoError(t, err)
+		assert.Equal(t, "job-1", job.ID)
+		assert.Equal(t, []string{"deploy"}, job.From)
+		assert.Equal(t, "release", job.To)
+
+		job = waitForTagJob(t, service, "user123", job.ID)
+		assert.Equal(t, models.TagJobDone, job.Status)
+		assert.Equal(t, 2, job.Total)
+		assert.Equal(t, 2, job.Processed)
+		assert.Equal(t, 1, job.Updated)
+		assert.Equal(t, 0, job.Failed)
+		repo.AssertExpectations(t)
+		worker.AssertExpectations(t)
+
+		_, err = service.Job("other", job.ID)
+		assert.ErrorIs(t, err, ErrTagJobNotFound)
+	})
+}
+
+func TestTagService_Merge(t *testing.T) {
+	tags := []models.Tag{{Name: "ops", Count: 1}, {Name: "release", Count: 1}}
+
+	t.Run("Only the target is listed", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetTags", "user123").Return(tags, nil)
+		service := newTestTagService(repo, new(MockSyncWorker))
+
+		_, err := service.Merge(context.Background(), "user123", []string{"#release"}, "Release")
+		assert.ErrorIs(t, err, ErrSameTag)
+		_, err = service.Merge(context.Background(), "user123", []string{"unused"}, "release")
+		assert.ErrorIs(t, err, ErrTagNotFound)
+	})
+
+	t.Run("Used tags are merged into the target", func(t *testing.T) {
+		repo := new(MockRepository)
+		worker := new(MockSyncWorker)
+		repo.On("GetTags", "user123").Return(tags, nil)
+		repo.On("GetNotesByTag", "user123", "ops", tagJobPage, 0).Return([]models.Note{{Context: "work", Date: "2025-01-06"}}, nil)
+		repo.On("GetNote", "user123", "work", "2025-01-06").
+			Return(&models.Note{Context: "work", Date: "2025-01-06", Content: "#ops and #release", Revision: 2}, nil)
+		repo.On("UpsertNoteAtRevision", mock.MatchedBy(func(note *models.Note) bool {
+			return note.Content == "#release and #release"
+		}), 2, true).Return(true, nil).Once()
+		worker.On("SyncNoteImmediate", "user123", "work", "2025-01-06").Return().Once()
+		service := newTestTagService(repo, worker)
+
+		job, err := service.Merge(context.Background(), "user123", []string{"#Ops", "release", "unused", "ops"}, "release")
+		require.NoError(t, err)
+		assert.Equal(t, models.TagJobMerge, job.Kind)
+		assert.Equal(t, []string{"ops"}, job.From)
+
+		job = waitForTagJob(t, service, "user123", job.ID)
+		assert.Equal(t, models.TagJobDone, job.Status)
+		assert.Equal(t, 1, job.Updated)
+		repo.AssertExpectations(t)
+		worker.AssertExpectations(t)
+	})
+}
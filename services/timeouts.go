@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouts bounds how long each class of operation may run. Service methods
+// derive their context from the caller's, so whichever ends first (client
+// gone, server shutting down, or the deadline) cancels the work.
+type Timeouts struct {
+	Query   time.Duration // Single-row lookups and writes
+	Scan    time.Duration // Queries over all of a user's notes (related notes, palette, export)
+	Storage time.Duration // Cloud storage calls (Drive listings, folder moves)
+}
+
+// DefaultTimeouts are used unless overridden through configuration
+var DefaultTimeouts = Timeouts{
+	Query:   5 * time.Second,
+	Scan:    30 * time.Second,
+	Storage: 2 * time.Minute,
+}
+
+// WithDefaults fills unset timeouts from DefaultTimeouts
+func (t Timeouts) WithDefaults() Timeouts {
+	if t.Query <= 0 {
+		t.Query = DefaultTimeouts.Query
+	}
+	if t.Scan <= 0 {
+		t.Scan = DefaultTimeouts.Scan
+	}
+	if t.Storage <= 0 {
+		t.Storage = DefaultTimeouts.Storage
+	}
+	return t
+}
+
+func (t Timeouts) query(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, t.Query)
+}
+
+func (t Timeouts) scan(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, t.Scan)
+}
+
+// storage starts a detached context for cloud storage work that outlives the request
+// (folder renames and moves run in the background after the response is sent)
+func (t Timeouts) storage() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), t.Storage)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/period"
+	"sort"
+	"time"
+)
+
+// timezoneSuspectWindow is how long before a timezone change notes are checked
+// for a date that may be off by one
+const timezoneSuspectWindow = 7 * 24 * time.Hour
+
+// TimezoneService helps users check their notes after changing timezone. Notes
+// are dated by the user's "today", so a note written near midnight before the
+// change may belong to another day in the new timezone, and streaks shift.
+type TimezoneService struct {
+	repo  TimezoneRepository
+	clock clock.Clock
+}
+
+// NewTimezoneService creates a new timezone service
+func NewTimezoneService(repo TimezoneRepository) *TimezoneService {
+	return &TimezoneService{repo: repo, clock: clock.Real()}
+}
+
+// SetClock replaces the clock used for change times and "today"
+func (ts *TimezoneService) SetClock(c clock.Clock) {
+	ts.clock = c
+}
+
+// RecordChange records that a user switched from one timezone to another, for
+// review. Saving the same timezone again isn't a change.
+func (ts *TimezoneService) RecordChange(ctx context.Context, userID, from, to string) (err error) {
+	defer wrapOp("record timezone change", &err)
+	if from == "" || from == to {
+		return nil
+	}
+	return ts.repo.RecordTimezoneChange(ctx, userID, from, to, ts.clock.Now())
+}
+
+// Review returns the user's latest unreviewed timezone change with the daily
+// notes whose date may be off by one, nil if there is nothing to review. Before
+// are the day statistics as they were in the old timezone; After are those in
+// the new timezone once the suspect notes are re-dated as suggested.
+func (ts *TimezoneService) Review(ctx context.Context, userID string) (_ *models.TimezoneReview, err error) {
+	defer wrapOp("review timezone change", &err)
+	change, err := ts.repo.GetTimezoneChange(ctx, userID)
+	if err != nil || change == nil {
+		return nil, err
+	}
+
+	from, to := loadLocation(change.From), loadLocation(change.To)
+
+	notes, err := ts.repo.GetDayNotesCreatedSince(ctx, userID, change.ChangedAt.Add(-timezoneSuspectWindow))
+	if err != nil {
+		return nil, err
+	}
+	suspects := []models.TimezoneSuspect{}
+	for _, note := range notes {
+		written, moved := note.CreatedAt.In(from).Format(period.DateLayout), note.CreatedAt.In(to).Format(period.DateLayout)
+		if note.Date == written && written != moved {
+			suspects = append(suspects, models.TimezoneSuspect{
+				Context:       note.Context,
+				Date:          note.Date,
+				SuggestedDate: moved,
+				CreatedAt:     note.CreatedAt,
+			})
+		}
+	}
+
+	dates, err := ts.repo.GetNoteDates(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := ts.clock.Now()
+	return &models.TimezoneReview{
+		Change:   *change,
+		Before:   dayStats(change.From, dates, now.In(from)),
+		After:    dayStats(change.To, redated(dates, suspects), now.In(to)),
+		Suspects: suspects,
+	}, nil
+}
+
+// Dismiss marks a timezone change, and the ones before it, as reviewed
+func (ts *TimezoneService) Dismiss(ctx context.Context, userID string, id int64) (err error) {
+	defer wrapOp("dismiss timezone change", &err)
+	found, err := ts.repo.DismissTimezoneChange(ctx, userID, id, ts.clock.Now())
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrTimezoneChangeNotFound
+	}
+	return nil
+}
+
+// loadLocation returns the named timezone, UTC if it's unknown
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// dayStats computes the statistics of the days with notes as of now; dates
+// are the sorted dates of the notes
+func dayStats(timezone string, dates []string, now time.Time) models.DayStats {
+	days := make([]string, 0, len(dates))
+	for _, date := range dates {
+		if len(days) == 0 || days[len(days)-1] != date {
+			days = append(days, date)
+		}
+	}
+
+	today := now.Format(period.DateLayout)
+	current, longest := period.Streaks(days, today)
+	return models.DayStats{
+		Timezone:      timezone,
+		Today:         today,
+		DaysWritten:   len(days),
+		CurrentStreak: current,
+		LongestStreak: longest,
+	}
+}
+
+// redated returns the dates of the notes once the suspects are moved to their
+// suggested dates, sorted
+func redated(dates []string, suspects []models.TimezoneSuspect) []string {
+	count := make(map[string]int, len(dates))
+	for _, date := range dates {
+		count[date]++
+	}
+	for _, s := range suspects {
+		count[s.Date]--
+		count[s.SuggestedDate]++
+	}
+
+	var result []string
+	for date, n := range count {
+		for ; n > 0; n-- {
+			result = append(result, date)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
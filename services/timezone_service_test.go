@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockTimezoneRepository is a mock implementation of TimezoneRepository
+type MockTimezoneRepository struct {
+	mock.Mock
+}
+
+func (m *MockTimezoneRepository) RecordTimezoneChange(ctx context.Context, userID, from, to string, at time.Time) error {
+	args := m.Called(userID, from, to, at)
+	return args.Error(0)
+}
+
+func (m *MockTimezoneRepository) GetTimezoneChange(ctx context.Context, userID string) (*models.TimezoneChange, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TimezoneChange), args.Error(1)
+}
+
+func (m *MockTimezoneRepository) DismissTimezoneChange(ctx context.Context, userID string, id int64, at time.Time) (bool, error) {
+	args := m.Called(userID, id, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTimezoneRepository) GetDayNotesCreatedSince(ctx context.Context, userID string, since time.Time) ([]models.Note, error) {
+	args := m.Called(userID, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Note), args.Error(1)
+}
+
+func (m *MockTimezoneRepository) GetNoteDates(ctx context.Context, userID string) ([]string, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func TestTimezoneService_RecordChange(t *testing.T) {
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	repo := new(MockTimezoneRepository)
+	repo.On("RecordTimezoneChange", "user123", "UTC", "America/Bogota", now).Return(nil)
+
+	service := NewTimezoneService(repo)
+	service.SetClock(clock.NewFake(now))
+
+	require.NoError(t, service.RecordChange(context.Background(), "user123", "UTC", "America/Bogota"))
+	require.NoError(t, service.RecordChange(context.Background(), "user123", "UTC", "UTC"))
+	require.NoError(t, service.RecordChange(context.Background(), "user123", "", "UTC"))
+	repo.AssertNumberOfCalls(t, "RecordTimezoneChange", 1)
+}
+
+func TestTimezoneService_Review(t *testing.T) {
+	changedAt := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Nothing to review", func(t *testing.T) {
+		repo := new(MockTimezoneRepository)
+		repo.On("GetTimezoneChange", "user123").Return(nil, nil)
+
+		review, err := NewTimezoneService(repo).Review(context.Background(), "user123")
+		require.NoError(t, err)
+		assert.Nil(t, review)
+	})
+
+	t.Run("Flags notes written late at night and recomputes streaks", func(t *testing.T) {
+		repo := new(MockTimezoneRepository)
+		repo.On("GetTimezoneChange", "user123").Return(&models.TimezoneChange{
+			ID: 7, From: "UTC", To: "America/Bogota", ChangedAt: changedAt,
+		}, nil)
+		repo.On("GetDayNotesCreatedSince", "user123", changedAt.Add(-timezoneSuspectWindow)).Return([]models.Note{
+			// 02:00 UTC is still the evening before in Bogota
+			{Context: "Work", Date: "2025-10-15", CreatedAt: time.Date(2025, 10, 15, 2, 0, 0, 0, time.UTC)},
+			{Context: "Work", Date: "2025-10-16", CreatedAt: time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC)},
+		}, nil)
+		repo.On("GetNoteDates", "user123").Return([]string{"2025-10-13", "2025-10-15", "2025-10-16", "2025-10-16"}, nil)
+
+		service := NewTimezoneService(repo)
+		service.SetClock(clock.NewFake(time.Date(2025, 10, 17, 3, 0, 0, 0, time.UTC)))
+		review, err := service.Review(context.Background(), "user123")
+
+		require.NoError(t, err)
+		require.NotNil(t, review)
+		assert.Equal(t, int64(7), review.Change.ID)
+		require.Len(t, review.Suspects, 1)
+		assert.Equal(t, "2025-10-15", review.Suspects[0].Date)
+		assert.Equal(t, "2025-10-14", review.Suspects[0].SuggestedDate)
+
+		assert.Equal(t, models.DayStats{
+			Timezone: "UTC", Today: "2025-10-17", DaysWritten: 3, CurrentStreak: 2, LongestStreak: 2,
+		}, review.Before)
+		assert.Equal(t, models.DayStats{
+			Timezone: "America/Bogota", Today: "2025-10-16", DaysWritten: 3, CurrentStreak: 1, LongestStreak: 2,
+		}, review.After)
+	})
+}
+
+func TestTimezoneService_Dismiss(t *testing.T) {
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	repo := new(MockTimezoneRepository)
+	repo.On("DismissTimezoneChange", "user123", int64(7), now).Return(true, nil)
+	repo.On("DismissTimezoneChange", "user123", int64(8), now).Return(false, nil)
+
+	service := NewTimezoneService(repo)
+	service.SetClock(clock.NewFake(now))
+
+	require.NoError(t, service.Dismiss(context.Background(), "user123", 7))
+	assert.ErrorIs(t, service.Dismiss(context.Background(), "user123", 8), ErrTimezoneChangeNotFound)
+}
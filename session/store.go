@@ -2,25 +2,49 @@ package session
 
 import (
 	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"daily-notes/pkg/seal"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
-
-	"github.com/google/uuid"
 )
 
+// Database runs the session queries, written for SQLite with ? placeholders;
+// *database.DB rewrites them for other SQL dialects
+type Database interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 // Store handles session persistence
 type Store struct {
-	db *sql.DB
+	db    Database
+	clock clock.Clock
+	ids   idgen.Generator
+	// tokens encrypts OAuth tokens at rest once SetTokenKey is called
+	tokens *seal.Box
 }
 
 // NewStore creates a new session store with the given database connection
-func NewStore(database *sql.DB) *Store {
+func NewStore(database Database) *Store {
 	if database == nil {
 		panic("session.NewStore called with nil database")
 	}
 	fmt.Println("[Session Store] Initialized with database connection")
-	return &Store{db: database}
+	return &Store{db: database, clock: clock.Real(), ids: idgen.UUID()}
+}
+
+// SetClock replaces the clock used for session timestamps and expiry
+func (s *Store) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// SetIDGenerator replaces the generator used for session IDs
+func (s *Store) SetIDGenerator(g idgen.Generator) {
+	s.ids = g
 }
 
 // scannable represents anything that can be scanned (sql.Row or sql.Rows)
@@ -30,7 +54,8 @@ type scannable interface {
 
 // scanSession is a helper to scan session data from database rows
 // This eliminates duplication across Get, GetByUserID, and Update
-func scanSession(scanner scannable) (*models.Session, error) {
+// OAuth tokens are decrypted when the store has a key.
+func (s *Store) scanSession(scanner scannable) (*models.Session, error) {
 	var session models.Session
 	var settings models.UserSettings
 
@@ -40,13 +65,19 @@ func scanSession(scanner scannable) (*models.Session, error) {
 		&settings.Theme, &settings.WeekStart, &settings.Timezone,
 		&settings.DateFormat, &settings.UniqueContextMode,
 		&settings.ShowBreadcrumb, &settings.ShowMarkdownEditor,
-		&settings.HideNewContextButton,
+		&settings.HideNewContextButton, &settings.SuggestContext,
 		&session.ExpiresAt, &session.CreatedAt, &session.LastUsedAt,
 	)
 
 	if err != nil {
 		return nil, err
 	}
+	if session.AccessToken, err = s.openToken("access_token", session.AccessToken); err != nil {
+		return nil, err
+	}
+	if session.RefreshToken, err = s.openToken("refresh_token", session.RefreshToken); err != nil {
+		return nil, err
+	}
 
 	session.Settings = settings
 	return &session, nil
@@ -58,27 +89,32 @@ func (s *Store) Create(userID, email, name, picture, accessToken, refreshToken s
 		return nil, sql.ErrConnDone
 	}
 
-	sessionID := uuid.New().String()
-	now := time.Now()
+	sessionID := s.ids.NewID()
+	now := s.clock.Now()
 	expiresAt := now.Add(30 * 24 * time.Hour)
 
-	_, err := s.db.Exec(`
+	sealedAccess, sealedRefresh, err := s.sealTokens(accessToken, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(`
 		INSERT INTO sessions (
 			id, user_id, email, name, picture,
 			access_token, refresh_token, token_expiry,
 			settings_theme, settings_week_start, settings_timezone,
 			settings_date_format, settings_unique_context_mode,
 			settings_show_breadcrumb, settings_show_markdown_editor,
-			settings_hide_new_context_button,
+			settings_hide_new_context_button, settings_suggest_context,
 			expires_at, created_at, last_used_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		sessionID, userID, email, name, picture,
-		accessToken, refreshToken, tokenExpiry,
+		sealedAccess, sealedRefresh, tokenExpiry,
 		settings.Theme, settings.WeekStart, settings.Timezone,
 		settings.DateFormat, settings.UniqueContextMode,
 		settings.ShowBreadcrumb, settings.ShowMarkdownEditor,
-		settings.HideNewContextButton,
+		settings.HideNewContextButton, settings.SuggestContext,
 		expiresAt, now, now,
 	)
 	if err != nil {
@@ -113,14 +149,14 @@ func (s *Store) Get(sessionID string) (*models.Session, error) {
 			settings_theme, settings_week_start, settings_timezone,
 			settings_date_format, settings_unique_context_mode,
 			settings_show_breadcrumb, settings_show_markdown_editor,
-			settings_hide_new_context_button,
+			settings_hide_new_context_button, settings_suggest_context,
 			expires_at, created_at, last_used_at
 		FROM sessions
 		WHERE id = ? AND expires_at > ?
-	`, sessionID, time.Now())
+	`, sessionID, s.clock.Now())
 
-	session, err := scanSession(row)
-	if err == sql.ErrNoRows {
+	session, err := s.scanSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	return session, err
@@ -134,15 +170,15 @@ func (s *Store) GetByUserID(userID string) *models.Session {
 			settings_theme, settings_week_start, settings_timezone,
 			settings_date_format, settings_unique_context_mode,
 			settings_show_breadcrumb, settings_show_markdown_editor,
-			settings_hide_new_context_button,
+			settings_hide_new_context_button, settings_suggest_context,
 			expires_at, created_at, last_used_at
 		FROM sessions
 		WHERE user_id = ? AND expires_at > ?
 		ORDER BY last_used_at DESC
 		LIMIT 1
-	`, userID, time.Now())
+	`, userID, s.clock.Now())
 
-	session, err := scanSession(row)
+	session, err := s.scanSession(row)
 	if err != nil {
 		return nil
 	}
@@ -151,9 +187,14 @@ func (s *Store) GetByUserID(userID string) *models.Session {
 
 // Update updates an existing session
 func (s *Store) Update(sessionID string, session *models.Session) error {
-	now := time.Now()
+	now := s.clock.Now()
+
+	accessToken, refreshToken, err := s.sealTokens(session.AccessToken, session.RefreshToken)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.db.Exec(`
+	_, err = s.db.Exec(`
 		UPDATE sessions SET
 			email = ?,
 			name = ?,
@@ -169,15 +210,17 @@ func (s *Store) Update(sessionID string, session *models.Session) error {
 			settings_show_breadcrumb = ?,
 			settings_show_markdown_editor = ?,
 			settings_hide_new_context_button = ?,
+			settings_suggest_context = ?,
 			last_used_at = ?
 		WHERE id = ?
 	`,
 		session.Email, session.Name, session.Picture,
-		session.AccessToken, session.RefreshToken, session.TokenExpiry,
+		accessToken, refreshToken, session.TokenExpiry,
 		session.Settings.Theme, session.Settings.WeekStart, session.Settings.Timezone,
 		session.Settings.DateFormat, session.Settings.UniqueContextMode,
 		session.Settings.ShowBreadcrumb, session.Settings.ShowMarkdownEditor,
 		session.Settings.HideNewContextButton,
+		session.Settings.SuggestContext,
 		now, sessionID,
 	)
 
@@ -186,7 +229,12 @@ func (s *Store) Update(sessionID string, session *models.Session) error {
 
 // UpdateUserToken updates just the OAuth tokens for a specific user
 func (s *Store) UpdateUserToken(userID string, accessToken, refreshToken string, tokenExpiry time.Time) error {
-	_, err := s.db.Exec(`
+	accessToken, refreshToken, err := s.sealTokens(accessToken, refreshToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
 		UPDATE sessions SET
 			access_token = ?,
 			refresh_token = ?,
@@ -194,7 +242,7 @@ func (s *Store) UpdateUserToken(userID string, accessToken, refreshToken string,
 			last_used_at = ?
 		WHERE user_id = ?
 	`,
-		accessToken, refreshToken, tokenExpiry, time.Now(), userID,
+		accessToken, refreshToken, tokenExpiry, s.clock.Now(), userID,
 	)
 
 	return err
@@ -208,7 +256,7 @@ func (s *Store) Delete(sessionID string) error {
 
 // CleanupExpired removes all expired sessions from the database
 func (s *Store) CleanupExpired() {
-	_, err := s.db.Exec("DELETE FROM sessions WHERE expires_at < ?", time.Now())
+	_, err := s.db.Exec("DELETE FROM sessions WHERE expires_at < ?", s.clock.Now())
 	if err != nil {
 		// Log error but don't crash
 		return
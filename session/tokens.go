@@ -0,0 +1,127 @@
+package session
+
+import (
+	"daily-notes/pkg/seal"
+	"strings"
+)
+
+// tokenPrefix marks OAuth tokens a Store encrypted before saving them; tokens
+// without it were saved before SESSION_ENC_KEY was set and are read as they are
+const tokenPrefix = seal.Prefix
+
+// TokenKeySize is the size of SESSION_ENC_KEY, an AES-256 key
+const TokenKeySize = seal.KeySize
+
+var (
+	// ErrTokenKeyMissing is returned when reading a session whose tokens were
+	// encrypted by a store that had a key, with a store that has none
+	ErrTokenKeyMissing = seal.ErrKeyMissing
+
+	// ErrTokenUnreadable is returned when a session's tokens don't decrypt with
+	// the store's key, e.g. after the key was changed
+	ErrTokenUnreadable = seal.ErrUnreadable
+)
+
+// ParseTokenKey decodes SESSION_ENC_KEY, 32 random bytes in base64 as printed
+// by "openssl rand -base64 32"
+func ParseTokenKey(s string) ([]byte, error) {
+	return seal.ParseKey(s)
+}
+
+// SetTokenKey makes the store encrypt OAuth tokens with AES-256-GCM before
+// saving them and decrypt them on reads
+func (s *Store) SetTokenKey(key []byte) error {
+	box, err := seal.New(key)
+	if err != nil {
+		return err
+	}
+	s.tokens = box
+	return nil
+}
+
+// sealToken encrypts a token for its column, which is authenticated along with
+// it so an access token can't be passed off as a refresh token. Empty tokens
+// stay empty, and without a key tokens are saved as they are.
+func (s *Store) sealToken(column, token string) (string, error) {
+	return s.tokens.Seal(column, token)
+}
+
+// openToken decrypts a token sealToken saved in column; plaintext tokens of
+// rows saved before the key was set are returned as they are
+func (s *Store) openToken(column, token string) (string, error) {
+	return s.tokens.Open(column, token)
+}
+
+// sealTokens encrypts a session's access and refresh tokens
+func (s *Store) sealTokens(accessToken, refreshToken string) (string, string, error) {
+	access, err := s.sealToken("access_token", accessToken)
+	if err != nil {
+		return "", "", err
+	}
+	refresh, err := s.sealToken("refresh_token", refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
+
+// plaintextSession is a session whose tokens were saved before a key was set
+type plaintextSession struct {
+	id, accessToken, refreshToken string
+}
+
+// plaintextSessions returns the sessions with a token saved in plaintext
+func (s *Store) plaintextSessions() ([]plaintextSession, error) {
+	rows, err := s.db.Query(`
+		SELECT id, access_token, COALESCE(refresh_token, '')
+		FROM sessions
+		WHERE (access_token != '' AND access_token NOT LIKE ?)
+			OR (refresh_token != '' AND refresh_token NOT LIKE ?)
+	`, tokenPrefix+"%", tokenPrefix+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []plaintextSession
+	for rows.Next() {
+		var p plaintextSession
+		if err := rows.Scan(&p.id, &p.accessToken, &p.refreshToken); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, p)
+	}
+	return sessions, rows.Err()
+}
+
+// EncryptTokens encrypts the tokens of sessions saved in plaintext, before a
+// key was set, and returns how many sessions it rewrote. Without a key it
+// does nothing.
+func (s *Store) EncryptTokens() (int, error) {
+	if s.tokens == nil {
+		return 0, nil
+	}
+
+	sessions, err := s.plaintextSessions()
+	if err != nil {
+		return 0, err
+	}
+	for i, p := range sessions {
+		accessToken, refreshToken := p.accessToken, p.refreshToken
+		if !strings.HasPrefix(accessToken, tokenPrefix) {
+			if accessToken, err = s.sealToken("access_token", accessToken); err != nil {
+				return i, err
+			}
+		}
+		if !strings.HasPrefix(refreshToken, tokenPrefix) {
+			if refreshToken, err = s.sealToken("refresh_token", refreshToken); err != nil {
+				return i, err
+			}
+		}
+		if _, err := s.db.Exec(`UPDATE sessions SET access_token = ?, refresh_token = ? WHERE id = ?`,
+			accessToken, refreshToken, p.id); err != nil {
+			return i, err
+		}
+	}
+	return len(sessions), nil
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"bytes"
+	"daily-notes/database"
+	"daily-notes/models"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenEncryption(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Migrate())
+	_, err = db.Exec(`INSERT INTO users (id, google_id, email) VALUES ('user', 'google', 'user@example.com')`)
+	require.NoError(t, err)
+
+	rawTokens := func(sessionID string) (string, string) {
+		var access, refresh string
+		require.NoError(t, db.QueryRow(`SELECT access_token, refresh_token FROM sessions WHERE id = ?`, sessionID).Scan(&access, &refresh))
+		return access, refresh
+	}
+	key := bytes.Repeat([]byte{1}, TokenKeySize)
+
+	// A session saved before the key was set
+	plain := NewStore(db)
+	old, err := plain.Create("user", "user@example.com", "User", "", "old-access", "old-refresh", time.Now(), models.UserSettings{})
+	require.NoError(t, err)
+
+	store := NewStore(db)
+	require.NoError(t, store.SetTokenKey(key))
+
+	t.Run("Plaintext sessions stay readable and are encrypted once", func(t *testing.T) {
+		sess, err := store.Get(old.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "old-access", sess.AccessToken)
+
+		encrypted, err := store.EncryptTokens()
+		require.NoError(t, err)
+		assert.Equal(t, 1, encrypted)
+		encrypted, err = store.EncryptTokens()
+		require.NoError(t, err)
+		assert.Zero(t, encrypted)
+
+		access, refresh := rawTokens(old.ID)
+		assert.True(t, strings.HasPrefix(access, tokenPrefix))
+		assert.NotContains(t, refresh, "old-refresh")
+
+		sess, err = store.Get(old.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "old-access", sess.AccessToken)
+		assert.Equal(t, "old-refresh", sess.RefreshToken)
+	})
+
+	t.Run("Tokens are encrypted on every write", func(t *testing.T) {
+		sess, err := store.Create("user", "user@example.com", "User", "", "new-access", "", time.Now(), models.UserSettings{})
+		require.NoError(t, err)
+		access, refresh := rawTokens(sess.ID)
+		assert.NotContains(t, access, "new-access")
+		assert.Empty(t, refresh)
+
+		require.NoError(t, store.UpdateUserToken("user", "refreshed-access", "refreshed-refresh", time.Now()))
+		access, _ = rawTokens(sess.ID)
+		assert.NotContains(t, access, "refreshed-access")
+
+		got := store.GetByUserID("user")
+		require.NotNil(t, got)
+		assert.Equal(t, "refreshed-access", got.AccessToken)
+		assert.Equal(t, "refreshed-refresh", got.RefreshToken)
+	})
+
+	t.Run("Encrypted sessions need the key", func(t *testing.T) {
+		_, err := plain.Get(old.ID)
+		assert.ErrorIs(t, err, ErrTokenKeyMissing)
+
+		other := NewStore(db)
+		require.NoError(t, other.SetTokenKey(bytes.Repeat([]byte{2}, TokenKeySize)))
+		_, err = other.Get(old.ID)
+		assert.ErrorIs(t, err, ErrTokenUnreadable)
+	})
+
+	t.Run("Tokens can't swap columns", func(t *testing.T) {
+		access, _ := rawTokens(old.ID)
+		_, err := db.Exec(`UPDATE sessions SET refresh_token = ? WHERE id = ?`, access, old.ID)
+		require.NoError(t, err)
+		_, err = store.Get(old.ID)
+		assert.ErrorIs(t, err, ErrTokenUnreadable)
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		_, err := ParseTokenKey("c2hvcnQ=")
+		assert.Error(t, err)
+		parsed, err := ParseTokenKey("AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=\n")
+		require.NoError(t, err)
+		assert.Equal(t, key, parsed)
+	})
+}
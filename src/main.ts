@@ -132,6 +132,37 @@ class Application {
       console.log('[Note] Saved')
     })
 
+    // An autosave went over a version saved elsewhere: show that version, with
+    // the lines of ours it left out, and let the user save ours over it
+    events.on(EVENT.NOTE_CONFLICT, (e: CustomEvent) => {
+      const { context, date, content, mine, diff } = e.detail
+      if (context === state.get('selectedContext') && date === state.get('selectedDate')) {
+        markdownEditor.setContent(content)
+      }
+
+      const lines = (diff?.hunks ?? []).flatMap((hunk: { lines: { op: string; text: string }[] }) => hunk.lines)
+      const added = lines.filter((line: { op: string }) => line.op === 'insert').length
+      const removed = lines.filter((line: { op: string }) => line.op === 'delete').length
+      const changes = diff ? ` Your version added ${added} and removed ${removed} lines.` : ''
+      notifications.warning(
+        `This note was updated elsewhere, so the latest version is shown.${changes}`,
+        {
+          title: 'Note changed elsewhere',
+          duration: 0,
+          actionLabel: 'Keep mine',
+          onAction: () => {
+            if (context === state.get('selectedContext') && date === state.get('selectedDate')) {
+              markdownEditor.setContent(mine)
+            }
+            notes.saveNote(context, date, mine)
+          }
+        }
+      )
+      if (diff) {
+        console.info(`[Note] Changes not saved in ${context}/${date}:\n${diff.unified}`)
+      }
+    })
+
     // Context events
     events.on(EVENT.CONTEXT_CHANGED, async (e: CustomEvent) => {
       const context = e.detail.context
@@ -5,7 +5,7 @@
 
 import { state } from '@/utils/state'
 import { events, EVENT } from '@/utils/events'
-import type { User, Context, Note, UserSettings } from '@/types'
+import type { User, Context, ContextStats, Note, NoteAutosave, TrashedNote, UserSettings } from '@/types'
 
 interface AuthResponse {
   authenticated: boolean
@@ -22,6 +22,10 @@ interface NoteResponse {
   note: Note
 }
 
+interface NoteTrashResponse {
+  notes: TrashedNote[]
+}
+
 interface NotesListResponse {
   notes: Note[]
   total?: number
@@ -31,17 +35,30 @@ interface ContextsResponse {
   contexts: Context[]
 }
 
+interface ContextStatsResponse {
+  stats: ContextStats
+}
+
 interface ServerTimeResponse {
   timestamp: number  // Unix timestamp in seconds
   timezone: string
   iso: string
 }
 
+// APIError is a failed request with the status and body of its response, e.g.
+// the current note of a 409 save
+export class APIError extends Error {
+  constructor(message: string, public status: number, public data: any) {
+    super(message)
+  }
+}
+
 export class APIClient {
   // Base URL is empty string since we use relative paths
   // private baseUrl = ''
 
-  async request<T = any>(endpoint: string, options: RequestInit = {}): Promise<T> {
+  // Errors are shown to the user unless their status is one the caller handles
+  async request<T = any>(endpoint: string, options: RequestInit = {}, handled: number[] = []): Promise<T> {
     try {
       const response = await fetch(endpoint, {
         ...options,
@@ -53,7 +70,7 @@ export class APIClient {
       })
 
       if (!response.ok) {
-        const data = await response.json().catch(() => ({})) as { error?: string }
+        const data = await response.json().catch(() => ({})) as { error?: string; [key: string]: any }
 
         if (response.status === 401 || response.status === 403) {
           if (!state.get('isLoggingOut')) {
@@ -70,14 +87,15 @@ export class APIClient {
           throw new Error('Session expired')
         }
 
-        throw new Error(data.error || `Request failed with status ${response.status}`)
+        throw new APIError(data.error || `Request failed with status ${response.status}`, response.status, data)
       }
 
       return await response.json()
     } catch (error) {
       if (!state.get('isLoggingOut')) {
         // Don't show error notification if it's already been handled
-        if (error instanceof Error && !error.message.includes('Session expired')) {
+        const isHandled = error instanceof APIError && handled.includes(error.status)
+        if (error instanceof Error && !error.message.includes('Session expired') && !isHandled) {
           events.emit(EVENT.SHOW_ERROR, {
             message: error.message || 'An error occurred'
           })
@@ -134,20 +152,31 @@ export class APIClient {
     return await this.request<ContextsResponse>('/api/contexts')
   }
 
-  async createContext(data: { name: string; color?: string }): Promise<Context> {
+  async createContext(data: { name: string; color?: string; icon?: string }): Promise<Context> {
     return await this.request<Context>('/api/contexts', {
       method: 'POST',
       body: JSON.stringify(data)
     })
   }
 
-  async updateContext(id: string, data: { name?: string; color?: string }): Promise<Context> {
+  async updateContext(id: string, data: { name?: string; color?: string; icon?: string }): Promise<Context> {
     return await this.request<Context>(`/api/contexts/${id}`, {
       method: 'PUT',
       body: JSON.stringify(data)
     })
   }
 
+  async reorderContexts(ids: string[]): Promise<ContextsResponse> {
+    return await this.request<ContextsResponse>('/api/contexts/reorder', {
+      method: 'PATCH',
+      body: JSON.stringify({ ids })
+    })
+  }
+
+  async getContextStats(id: string): Promise<ContextStatsResponse> {
+    return await this.request<ContextStatsResponse>(`/api/contexts/${id}/stats`)
+  }
+
   async deleteContext(id: string): Promise<void> {
     await this.request(`/api/contexts/${id}`, {
       method: 'DELETE'
@@ -168,6 +197,17 @@ export class APIClient {
     })
   }
 
+  // Autosaves return the new revision without the content; the server syncs
+  // the note to storage once the autosaves pause. revision is the one the
+  // content was edited from (0 for a new note); when the note changed since,
+  // this fails with an APIError of status 409 holding a NoteConflict.
+  async autosaveNote(data: { context: string; date: string; content: string; revision: number }): Promise<NoteAutosave> {
+    return await this.request<NoteAutosave>('/api/notes', {
+      method: 'PATCH',
+      body: JSON.stringify(data)
+    }, [409])
+  }
+
   async getNotesList(context: string, limit = 50, offset = 0): Promise<NotesListResponse> {
     return await this.request<NotesListResponse>(
       `/api/notes/list?context=${encodeURIComponent(context)}&limit=${limit}&offset=${offset}`
@@ -183,6 +223,16 @@ export class APIClient {
     })
   }
 
+  async getNoteTrash(): Promise<NoteTrashResponse> {
+    return await this.request<NoteTrashResponse>('/api/notes/trash')
+  }
+
+  async restoreTrashedNote(id: number): Promise<NoteResponse> {
+    return await this.request<NoteResponse>(`/api/notes/${id}/restore`, {
+      method: 'POST'
+    })
+  }
+
   // Settings endpoints
   async updateSettings(settings: Partial<UserSettings>): Promise<UserSettings> {
     return await this.request<UserSettings>('/api/settings', {
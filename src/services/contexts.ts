@@ -115,6 +115,32 @@ class ContextsManager {
     }
   }
 
+  // Saves the order contexts were dragged into; contextIds lists the user's own contexts
+  async reorderContexts(contextIds: string[]): Promise<boolean> {
+    const currentContexts = state.get('contexts')
+    const position = new Map(contextIds.map((id, i) => [id, i]))
+    const reordered = [...currentContexts].sort(
+      (a, b) => (position.get(a.id) ?? contextIds.length) - (position.get(b.id) ?? contextIds.length)
+    )
+
+    // Update UI immediately (optimistic), then keep the server's order
+    await cache.saveContexts(reordered)
+    state.set('contexts', reordered)
+
+    try {
+      const response = await api.reorderContexts(contextIds)
+      const contexts = response?.contexts || reordered
+      await cache.saveContexts(contexts)
+      state.set('contexts', contexts)
+      return true
+    } catch (error) {
+      await cache.saveContexts(currentContexts)
+      state.set('contexts', currentContexts)
+      events.emit(EVENT.SHOW_ERROR, { message: 'Failed to reorder contexts' })
+      return false
+    }
+  }
+
   selectContext(contextName: string | null): void {
     state.set('selectedContext', contextName)
     events.emit(EVENT.CONTEXT_CHANGED, { context: contextName })
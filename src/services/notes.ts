@@ -4,16 +4,23 @@
  */
 
 import { state } from '@/utils/state'
-import { api } from './api'
+import { api, APIError } from './api'
 import { cache } from '@/utils/cache'
 import { events, EVENT } from '@/utils/events'
-import type { Note } from '@/types'
+import type { Note, NoteConflict } from '@/types'
 
 class NotesManager {
   private saveTimeout: number | null = null
   private currentNoteContent = ''
   private currentLoadToken = 0 // Token to cancel old load operations
   private currentSelectToken = 0 // Token to cancel old date selection operations
+  // Revision of each note as last seen from the server, sent with autosaves so
+  // edits made elsewhere in the meantime aren't overwritten
+  private revisions = new Map<string, number>()
+
+  private revisionKey(context: string, date: string): string {
+    return `${context}/${date}`
+  }
 
   async loadNote(context: string, date: string): Promise<Note | null> {
     if (!context || !date) {
@@ -61,6 +68,8 @@ class NotesManager {
         return null
       }
 
+      this.revisions.set(this.revisionKey(context, date), note.revision ?? 0)
+
       // Determine which version is more recent
       const serverUpdatedAt = note.updated_at ? new Date(note.updated_at).getTime() : 0
       const cachedUpdatedAt = cachedNote?.updated_at ? new Date(cachedNote.updated_at).getTime() : 0
@@ -140,17 +149,39 @@ class NotesManager {
     // 2. Update note in the list (update content, keep position)
     this.updateNoteInList(context, date, content)
 
-    // 3. Sync to server immediately (backend will handle Drive sync)
+    // 3. Sync to server immediately (backend will handle Drive sync once the edits pause)
     console.log(`[Notes] Syncing to server - content length: ${content.length}`)
+    const key = this.revisionKey(context, date)
     try {
-      await api.saveNote({ context, date, content, updated_at: now })
+      const saved = await api.autosaveNote({ context, date, content, revision: this.revisions.get(key) ?? 0 })
+      this.revisions.set(key, saved.revision)
       console.log(`[Notes] Successfully synced to server`)
     } catch (error) {
+      if (error instanceof APIError && error.status === 409) {
+        await this.applyConflict(context, date, content, error.data as NoteConflict)
+        return
+      }
       console.error('[Notes] Failed to sync to server:', error)
       // Note is still saved locally in cache, will retry on next app load
     }
   }
 
+  // applyConflict shows the note as saved elsewhere after an autosave of mine
+  // was refused; saving mine again goes over that version
+  private async applyConflict(context: string, date: string, mine: string, conflict: NoteConflict): Promise<void> {
+    const { note, diff } = conflict
+    console.warn(`[Notes] ${context}/${date} changed elsewhere, now at revision ${note.revision}`)
+
+    this.revisions.set(this.revisionKey(context, date), note.revision ?? 0)
+    await cache.saveNote({ ...note, context, date })
+    this.updateNoteInList(context, date, note.content)
+
+    if (context === state.get('selectedContext') && date === state.get('selectedDate')) {
+      this.currentNoteContent = note.content
+    }
+    events.emit(EVENT.NOTE_CONFLICT, { context, date, content: note.content, mine, diff })
+  }
+
   async loadNotesList(context: string, limit = 50, offset = 0): Promise<Note[]> {
     if (!context) {
       state.update({
@@ -239,6 +270,8 @@ class NotesManager {
         context: context,
         date: date,
         content: '',
+        word_count: 0,
+        char_count: 0,
         created_at: new Date().toISOString(),
         updated_at: new Date().toISOString()
       }
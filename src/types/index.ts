@@ -9,6 +9,7 @@ export interface UserSettings {
   showBreadcrumb: boolean
   showMarkdownEditor: boolean
   hideNewContextButton: boolean
+  encryption?: boolean
 }
 
 export interface User {
@@ -25,17 +26,149 @@ export interface Note {
   context: string
   date: string
   content: string
+  revision?: number
   sync_status?: string
   sync_error?: string
+  local_only?: boolean
+  encrypted?: boolean
+  pinned?: boolean
+  word_count: number
+  char_count: number
   created_at: string
   updated_at: string
 }
 
+// Response of PATCH /api/notes, the autosave of a note
+export interface NoteAutosave {
+  revision: number
+  updated_at: string
+  sync_status: string
+  sync_health?: { state: 'ok' | 'degraded' | 'offline'; message?: string }
+  size_warning?: { size: number; limit: number; message: string; suggestions: string[] }
+}
+
+// One line of a NoteDiffHunk
+export interface NoteDiffLine {
+  op: 'equal' | 'delete' | 'insert'
+  text: string
+}
+
+// A run of changes of a NoteConflictDiff, as in a unified diff
+export interface NoteDiffHunk {
+  old_start: number
+  old_lines: number
+  new_start: number
+  new_lines: number
+  lines: NoteDiffLine[]
+}
+
+// Diff of a 409 save response, from the current content to the content sent
+export interface NoteConflictDiff {
+  hunks: NoteDiffHunk[]
+  unified: string
+}
+
+// Body of a 409 save response: the note as saved elsewhere and the diff to the
+// content that wasn't saved
+export interface NoteConflict {
+  error: string
+  note: Note
+  diff?: NoteConflictDiff
+}
+
+// A note with the same date in another context, see NoteView
+export interface SameDayNote {
+  context: string
+  color: string
+  title: string
+}
+
+// Response of GET /api/notes/view, a note rendered for reading
+export interface NoteView {
+  note: Note
+  html: string
+  title: string
+  previous?: string
+  next?: string
+  same_day: SameDayNote[]
+}
+
+// A day of GET /api/notes/calendar; word_count and sync_status only for days with a note
+export interface CalendarDay {
+  date: string
+  exists: boolean
+  word_count: number
+  sync_status?: string
+}
+
 export interface Context {
   id: string
   user_id: string
   name: string
   color: string
+  icon?: string // Emoji or icon name, e.g. 'briefcase'
+  local_only?: boolean
+  language?: string
+  role?: 'read' | 'write' // Set for contexts shared with the user
+  owner?: string
+  sort_order?: number // Position in the owner's list, see reorderContexts
+  created_at: string
+}
+
+// A deleted note that can still be restored (GET /api/notes/trash)
+export interface TrashedNote {
+  id: number
+  user_id: string
+  context: string
+  date: string
+  type: string // Granularity: day, week, month or year
+  revision: number
+  excerpt: string
+  encrypted?: boolean
+  local_only?: boolean
+  deleted_at: string
+  expires_at: string
+}
+
+// What a context holds (GET /api/contexts/:id/stats); the dates are of its first and last daily notes
+export interface ContextStats {
+  context_id: string
+  context: string
+  notes: number
+  words: number
+  first_date?: string
+  last_date?: string
+  sync: {
+    state: 'ok' | 'degraded' | 'offline'
+    message?: string
+    synced: number
+    pending: number
+    failed: number
+    conflicts: number
+    local: number
+  }
+}
+
+// A member of one of the user's contexts, or an invitation not accepted yet (GET /api/contexts/:id/members)
+export interface ContextMember {
+  id: number
+  context_id: string
+  email: string
+  user_id?: string
+  name?: string
+  role: 'read' | 'write'
+  created_at: string
+  accepted_at?: string
+}
+
+// A context shared with the user (GET /api/invitations)
+export interface ContextInvitation {
+  id: number
+  context_id: string
+  context: string
+  owner: string
+  role: 'read' | 'write'
+  accepted: boolean
   created_at: string
 }
 
@@ -43,6 +176,250 @@ export interface SyncStatus {
   pending_count: number
   failed_count: number
   failed_notes: Note[]
+  failed_operations: SyncOperation[]
+  local_only_count: number
+}
+
+// A context folder rename or deletion that failed in storage and is retried
+export interface SyncOperation {
+  id: number
+  kind: 'rename_context' | 'delete_context'
+  context_id: string
+  context_name: string
+  new_name?: string
+  status: string
+  retry_count: number
+  error?: string
+  error_class?: string
+  next_retry_at?: string
+  created_at: string
+}
+
+// Data of a "sync" event on the GET /api/sync/events stream
+export interface SyncEvent {
+  note_id: string
+  context: string
+  date: string
+  status: string
+  deleted?: boolean
+  error?: string
+  error_class?: string
+  next_retry_at?: string
+  at: string
+}
+
+// Message of the /ws WebSocket: a note changed on another device or in storage
+export interface NoteEvent {
+  type: 'note.updated' | 'note.deleted'
+  note_id: string
+  context: string
+  date: string
+  source: 'api' | 'sync'
+  at: string
+}
+
+// When daily notes are created from templates (GET /api/notes/schedule)
+export interface NoteSchedule {
+  time: string
+  timezone: string
+  last_run?: string
+}
+
+// Whether the server emails weekly digests and the user gets them (GET /api/digest)
+export interface DigestSettings {
+  enabled: boolean
+  subscribed: boolean
+  last_week?: string
+}
+
+// A reminder written in a note as @remind(...) or made through POST /api/reminders (GET /api/reminders)
+export interface Reminder {
+  id: number
+  context?: string
+  date?: string
+  line?: number
+  text: string
+  remind_at: string
+  status: 'pending' | 'sent' | 'missed'
+  sent_at?: string
+  created_at: string
+}
+
+// Whether the server sends web push notifications, and the key to subscribe with (GET /api/push)
+export interface PushSettings {
+  enabled: boolean
+  public_key?: string
+}
+
+// How clients derive the key of end-to-end encrypted notes from the user's passphrase
+export interface EncryptionKey {
+  cipher: 'AES-256-GCM'
+  kdf: 'PBKDF2-SHA256'
+  iterations: number
+  salt: string
+  created_at: string
+}
+
+// Whether notes are end-to-end encrypted, and how many aren't yet (GET /api/encryption)
+export interface EncryptionStatus {
+  enabled: boolean
+  key?: EncryptionKey
+  encrypted_notes: number
+  plaintext_notes: number
+}
+
+// A secret link showing one note read-only at url (POST /api/notes/share)
+export interface NoteShare {
+  id: number
+  token: string
+  context: string
+  date: string
+  url: string
+  expires_at?: string
+  created_at: string
+}
+
+// A token limiting an integration to one context (GET /api/tokens); token is only set when created
+export interface APIToken {
+  id: number
+  name: string
+  context: string
+  operations: ('read' | 'append' | 'write')[]
+  token?: string
+  created_at: string
+  last_used_at?: string
+}
+
+// A key letting scripts and apps use the API as the user (GET /api/keys); key is only set when created
+export interface APIKey {
+  id: number
+  name: string
+  scope: 'read' | 'read-write'
+  key?: string
+  created_at: string
+  last_used_at?: string
+}
+
+// A signed URL appending one payload to a note without signing in (POST /api/drops)
+export interface DropURL {
+  url: string
+  context: string
+  date: string
+  expires_at: string
+}
+
+// A checkbox task of a note (GET /api/tasks)
+export interface Task {
+  id: number
+  context: string
+  date: string
+  line: number
+  text: string
+  done: boolean
+}
+
+// Per-day metadata of the daily notes in a range (GET /api/notes/meta), one entry per date in each column
+export interface NotesMeta {
+  from: string
+  to: string
+  dates: string[]
+  notes: number[]
+  words: number[]
+  moods: (string | null)[]
+  tags: string[][]
+  tasks_open: number[]
+  tasks_done: number[]
+}
+
+// Daily notes and words written each day of a range, with every context's streaks (GET /api/stats/activity)
+export interface Activity {
+  from: string
+  to: string
+  today: string
+  days: { date: string; notes: number; words: number }[]
+  streaks: { context: string; current: number; longest: number }[]
+}
+
+// How much the user has written (GET /api/stats/writing); weekdays start on Monday
+export interface WritingStats {
+  totals: { notes: number; words: number; chars: number; average_words: number }
+  weekdays: { weekday: string; days: number; notes: number; words: number; average_words: number }[]
+  contexts: { context: string; notes: number; words: number; chars: number }[]
+}
+
+// A note changed after a cursor (GET /api/notes/changes); note is missing when deleted
+export interface NoteChange {
+  cursor: number
+  kind: 'created' | 'updated' | 'deleted'
+  note_id: string
+  context: string
+  date: string
+  note?: Note
+}
+
+// Response of GET /api/notes/changes?since=<cursor>
+export interface NoteChanges {
+  changes: NoteChange[]
+  cursor: number
+  has_more: boolean
+}
+
+// A file attached to a note (POST /api/notes/attachments)
+export interface Attachment {
+  id: string
+  context: string
+  date: string
+  filename: string
+  content_type: string
+  size: number
+  storage_status: 'pending' | 'stored' | 'not_stored'
+  storage_error?: string
+  url: string
+  markdown: string
+  created_at: string
+}
+
+// A tag rename or merge running in the background (GET /api/tags/jobs/:id)
+export interface TagJob {
+  id: string
+  kind: 'rename' | 'merge'
+  from: string[]
+  to: string
+  status: 'queued' | 'running' | 'done' | 'failed'
+  total: number
+  processed: number
+  updated: number
+  failed: number
+  error?: string
+  created_at: string
+  finished_at?: string
+}
+
+// Statistics of the days with notes, as seen from a timezone
+export interface DayStats {
+  timezone: string
+  today: string
+  days_written: number
+  current_streak: number
+  longest_streak: number
+}
+
+// Response of GET /api/timezone/review (null when there is nothing to review)
+export interface TimezoneReview {
+  change: {
+    id: number
+    from: string
+    to: string
+    changed_at: string
+  }
+  before: DayStats
+  after: DayStats
+  suspects: {
+    context: string
+    date: string
+    suggested_date: string
+    created_at: string
+  }[]
 }
 
 export interface AppState {
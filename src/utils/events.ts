@@ -3,6 +3,8 @@
  * Central event system for app-wide communication
  */
 
+import type { NoteConflictDiff } from '@/types'
+
 // Event detail types
 interface NoteEventDetail {
   context: string
@@ -10,6 +12,16 @@ interface NoteEventDetail {
   content?: string
 }
 
+// An autosave refused because the note changed elsewhere: content is the note
+// as saved there, mine the content that wasn't saved
+interface NoteConflictDetail {
+  context: string
+  date: string
+  content: string
+  mine: string
+  diff?: NoteConflictDiff
+}
+
 interface SyncStatusDetail {
   pending: number
   syncing: boolean
@@ -31,6 +43,7 @@ interface EventTypeMap {
   'note-loaded': NoteEventDetail
   'note-saved': NoteEventDetail
   'note-changed': NoteEventDetail
+  'note-conflict': NoteConflictDetail
 
   // Sync
   'sync-status': SyncStatusDetail
@@ -98,6 +111,7 @@ export const EVENT = {
   NOTE_LOADED: 'note-loaded' as const,
   NOTE_SAVED: 'note-saved' as const,
   NOTE_CHANGED: 'note-changed' as const,
+  NOTE_CONFLICT: 'note-conflict' as const,
 
   // Sync
   SYNC_STATUS: 'sync-status' as const,
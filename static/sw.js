@@ -38,3 +38,23 @@ self.addEventListener('fetch', e => {
       .catch(() => caches.match(request).then(res => res || caches.match('/')))
   );
 });
+
+// Web push notifications (POST /api/push/subscribe): reminders and notes that stopped syncing
+self.addEventListener('push', e => {
+  const data = e.data ? e.data.json() : {};
+  e.waitUntil(self.registration.showNotification(data.title || 'Daily Notes', {
+    body: data.body,
+    tag: data.tag,
+    icon: '/static/icons/icon-192x192.png',
+    data: {url: data.url || '/'}
+  }));
+});
+
+self.addEventListener('notificationclick', e => {
+  e.notification.close();
+  const url = new URL(e.notification.data.url, self.location.origin).href;
+  e.waitUntil(clients.matchAll({type: 'window', includeUncontrolled: true}).then(windows => {
+    const open = windows.find(w => w.url === url);
+    return open ? open.focus() : clients.openWindow(url);
+  }));
+});
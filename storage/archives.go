@@ -0,0 +1,13 @@
+package storage
+
+import "io"
+
+// NoteArchiver is implemented by providers that keep compressed snapshots of all of a
+// user's notes in BackupsFolder, next to the note files synced one by one. A
+// snapshot still holds a consistent copy when files were lost or corrupted since.
+// Archive names are chosen by the caller; ListArchives returns them sorted by name.
+type NoteArchiver interface {
+	UploadArchive(name string, content io.Reader) error
+	ListArchives() ([]string, error)
+	DeleteArchive(name string) error
+}
@@ -0,0 +1,14 @@
+package storage
+
+import "io"
+
+// AttachmentsFolder holds the files attached to a context's notes, inside the
+// context folder so they are renamed, deleted and restored with it. Sync only
+// reads markdown files, so attachments are never taken for notes.
+const AttachmentsFolder = "_attachments"
+
+// AttachmentUploader is implemented by providers that keep note attachments in
+// AttachmentsFolder. Uploading a name again replaces the file.
+type AttachmentUploader interface {
+	UploadAttachment(contextName, name, contentType string, content io.Reader) error
+}
@@ -0,0 +1,23 @@
+package storage
+
+import "daily-notes/models"
+
+// NoteChangeLister is implemented by providers that can list the note files changed
+// since an earlier point, so edits made outside the app can be pulled in.
+// Tokens are opaque to callers: NoteChangesStartToken marks the current state, and
+// ListNoteChanges returns the notes written after a token (ID holds the file ID,
+// UpdatedAt the file's modification time) along with the token to continue from.
+// Removed files are not listed.
+type NoteChangeLister interface {
+	NoteChangesStartToken() (string, error)
+	ListNoteChanges(pageToken string) ([]models.Note, string, error)
+}
+
+// NoteChangeWatcher is implemented by providers that can notify an address as soon as
+// changes are made after pageToken, so they can be pulled without waiting for the
+// next poll. WatchNoteChanges subscribes the channel and sets its ResourceID and
+// Expiration; StopWatching ends a subscription early.
+type NoteChangeWatcher interface {
+	WatchNoteChanges(pageToken, address string, channel *models.ChangeChannel) error
+	StopWatching(channel models.ChangeChannel) error
+}
@@ -0,0 +1,288 @@
+//go:build chaos
+
+// Package chaos injects storage faults to test how sync recovers from them:
+// rate limits (429), timeouts and expired sign-ins, at random with configurable
+// rates. It only exists in builds with the chaos tag, so release binaries can't
+// inject faults whatever their environment says.
+package chaos
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Fault is a kind of injected failure
+type Fault string
+
+// Injected faults
+const (
+	RateLimit   Fault = "rate_limit"   // Storage answers 429
+	Timeout     Fault = "timeout"      // The call times out, before or after storage did the work
+	TokenExpiry Fault = "token_expiry" // Storage answers 401, as for an expired sign-in
+)
+
+// Config is the probability of each fault per storage call, from 0 to 1
+type Config struct {
+	Seed        uint64 // Of the random faults, for reproducible runs; 0 picks one
+	RateLimit   float64
+	Timeout     float64
+	TokenExpiry float64
+}
+
+// ConfigFromEnv reads the fault rates from CHAOS_RATE_LIMIT, CHAOS_TIMEOUT and
+// CHAOS_TOKEN_EXPIRY and the seed from CHAOS_SEED; unset rates are 0
+func ConfigFromEnv() (Config, error) {
+	var config Config
+	var err error
+	if seed := os.Getenv("CHAOS_SEED"); seed != "" {
+		if config.Seed, err = strconv.ParseUint(seed, 10, 64); err != nil {
+			return config, fmt.Errorf("invalid CHAOS_SEED: %w", err)
+		}
+	}
+	for name, rate := range map[string]*float64{
+		"CHAOS_RATE_LIMIT":   &config.RateLimit,
+		"CHAOS_TIMEOUT":      &config.Timeout,
+		"CHAOS_TOKEN_EXPIRY": &config.TokenExpiry,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if *rate, err = strconv.ParseFloat(value, 64); err != nil || *rate < 0 || *rate > 1 {
+			return config, fmt.Errorf("invalid %s: must be a rate from 0 to 1", name)
+		}
+	}
+	if config.RateLimit+config.Timeout+config.TokenExpiry > 1 {
+		return config, fmt.Errorf("chaos rates add up to more than 1")
+	}
+	return config, nil
+}
+
+// StatusError is an injected failure answered with an HTTP status, see storage.StatusError
+type StatusError struct {
+	Op     string
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("chaos: %s: %d %s", e.Op, e.Status, http.StatusText(e.Status))
+}
+
+// HTTPStatus returns the injected status, see storage.StatusError
+func (e *StatusError) HTTPStatus() int {
+	return e.Status
+}
+
+// Injector decides which storage calls fail, and counts the faults it injected
+type Injector struct {
+	mu     sync.Mutex
+	rand   *rand.Rand
+	config Config
+	counts map[Fault]int
+}
+
+// New creates an injector failing calls at the rates of config
+func New(config Config) *Injector {
+	seed := config.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	return &Injector{rand: rand.New(rand.NewPCG(seed, seed)), config: config, counts: make(map[Fault]int)}
+}
+
+// SetRates replaces the fault rates, keeping the random sequence; zero rates
+// stop injecting faults
+func (in *Injector) SetRates(rateLimit, timeout, tokenExpiry float64) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.config.RateLimit, in.config.Timeout, in.config.TokenExpiry = rateLimit, timeout, tokenExpiry
+}
+
+// Counts returns how many faults of each kind were injected so far
+func (in *Injector) Counts() map[Fault]int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	counts := make(map[Fault]int, len(in.counts))
+	for fault, n := range in.counts {
+		counts[fault] = n
+	}
+	return counts
+}
+
+// next picks the fault of the next call, "" for none, and for timeouts whether
+// storage does the work before the call times out
+func (in *Injector) next() (Fault, bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	var fault Fault
+	r := in.rand.Float64()
+	switch {
+	case r < in.config.RateLimit:
+		fault = RateLimit
+	case r < in.config.RateLimit+in.config.Timeout:
+		fault = Timeout
+	case r < in.config.RateLimit+in.config.Timeout+in.config.TokenExpiry:
+		fault = TokenExpiry
+	default:
+		return "", false
+	}
+	in.counts[fault]++
+	return fault, in.rand.IntN(2) == 0
+}
+
+// call runs do, the storage call op, unless a fault is injected instead. Half
+// of the timeouts happen after do ran, like a response lost on its way back,
+// which is what makes retried uploads write a note twice.
+func (in *Injector) call(op string, do func() error) error {
+	fault, after := in.next()
+	switch fault {
+	case RateLimit:
+		return &StatusError{Op: op, Status: http.StatusTooManyRequests}
+	case TokenExpiry:
+		return &StatusError{Op: op, Status: http.StatusUnauthorized}
+	case Timeout:
+		if after {
+			if err := do(); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("chaos: %s: %w", op, context.DeadlineExceeded)
+	}
+	return do()
+}
+
+// result is call for storage calls returning a value
+func result[T any](in *Injector, op string, do func() (T, error)) (T, error) {
+	var value T
+	err := in.call(op, func() error {
+		var err error
+		value, err = do()
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value, nil
+}
+
+// Wrap returns a factory opening the providers of factory with faults injected
+// into opening and every call. Wrapped providers can read single notes, but hide
+// the other optional capabilities (archives, paging, change lists, hashes), so
+// chaos builds exercise the note sync path.
+func (in *Injector) Wrap(factory storage.Factory) storage.Factory {
+	return func(ctx context.Context, token *oauth2.Token, userID string) (storage.Provider, error) {
+		return result(in, "open", func() (storage.Provider, error) {
+			inner, err := factory(ctx, token, userID)
+			if err != nil {
+				return nil, err
+			}
+			return &provider{inner: inner, in: in}, nil
+		})
+	}
+}
+
+// provider is a storage provider with faults injected
+type provider struct {
+	inner storage.Provider
+	in    *Injector
+}
+
+// Ensure provider implements storage.Provider, storage.NoteReader and storage.ContextConfigWriter
+var (
+	_ storage.Provider            = (*provider)(nil)
+	_ storage.NoteReader          = (*provider)(nil)
+	_ storage.ContextConfigWriter = (*provider)(nil)
+)
+
+func (p *provider) UpsertNote(contextName, date, content string) (*models.Note, error) {
+	return result(p.in, "upsert note", func() (*models.Note, error) {
+		return p.inner.UpsertNote(contextName, date, content)
+	})
+}
+
+func (p *provider) DeleteNote(contextName, date string) error {
+	return p.in.call("delete note", func() error {
+		return p.inner.DeleteNote(contextName, date)
+	})
+}
+
+// GetNote reads a note through the inner provider; nil, as for a missing file,
+// when it can't read single notes
+func (p *provider) GetNote(contextName, date string) (*models.Note, error) {
+	reader, ok := p.inner.(storage.NoteReader)
+	if !ok {
+		return nil, nil
+	}
+	return result(p.in, "get note", func() (*models.Note, error) {
+		return reader.GetNote(contextName, date)
+	})
+}
+
+func (p *provider) GetAllNotesInContext(contextName string) ([]models.Note, error) {
+	return result(p.in, "list notes", func() ([]models.Note, error) {
+		return p.inner.GetAllNotesInContext(contextName)
+	})
+}
+
+func (p *provider) GetContexts() ([]models.Context, error) {
+	return result(p.in, "get contexts", p.inner.GetContexts)
+}
+
+func (p *provider) RenameContext(contextID, oldName, newName string) error {
+	return p.in.call("rename context", func() error {
+		return p.inner.RenameContext(contextID, oldName, newName)
+	})
+}
+
+func (p *provider) DeleteContext(contextID, contextName string) error {
+	return p.in.call("delete context", func() error {
+		return p.inner.DeleteContext(contextID, contextName)
+	})
+}
+
+func (p *provider) RestoreContext(ctx models.Context) error {
+	return p.in.call("restore context", func() error {
+		return p.inner.RestoreContext(ctx)
+	})
+}
+
+// UpdateContextConfig writes a context through the inner provider; nothing when
+// it keeps no config
+func (p *provider) UpdateContextConfig(c models.Context) error {
+	writer, ok := p.inner.(storage.ContextConfigWriter)
+	if !ok {
+		return nil
+	}
+	return p.in.call("update context config", func() error {
+		return writer.UpdateContextConfig(c)
+	})
+}
+
+func (p *provider) GetSettings() (models.UserSettings, error) {
+	return result(p.in, "get settings", p.inner.GetSettings)
+}
+
+func (p *provider) GetConfig() (*storage.Config, error) {
+	return result(p.in, "get config", p.inner.GetConfig)
+}
+
+// GetCurrentToken is never failed: it reads the token in memory
+func (p *provider) GetCurrentToken() (*oauth2.Token, error) {
+	return p.inner.GetCurrentToken()
+}
+
+func (p *provider) CleanupOldDeletedFolders() error {
+	return p.in.call("clean up deleted folders", p.inner.CleanupOldDeletedFolders)
+}
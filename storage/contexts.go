@@ -0,0 +1,32 @@
+package storage
+
+import "daily-notes/models"
+
+// ContextConfigWriter is implemented by providers that keep the details of
+// contexts in ConfigFile, so another install signing in to the same storage
+// finds their colors and icons. The database stays the source of truth.
+type ContextConfigWriter interface {
+	// UpdateContextConfig writes the color and icon of c to the context of
+	// ConfigFile with its name, adding the context when it isn't listed
+	UpdateContextConfig(c models.Context) error
+}
+
+// SetConfigContext writes the color and icon of c to the context of config with
+// its name, or adds c to config when it has no such context
+func SetConfigContext(config *Config, c models.Context) {
+	for i := range config.Contexts {
+		if config.Contexts[i].Name == c.Name {
+			config.Contexts[i].Color = c.Color
+			config.Contexts[i].Icon = c.Icon
+			return
+		}
+	}
+	config.Contexts = append(config.Contexts, models.Context{
+		ID:        c.ID,
+		UserID:    c.UserID,
+		Name:      c.Name,
+		Color:     c.Color,
+		Icon:      c.Icon,
+		CreatedAt: c.CreatedAt,
+	})
+}
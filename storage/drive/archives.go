@@ -0,0 +1,84 @@
+package drive
+
+import (
+	"daily-notes/storage"
+	"fmt"
+	"io"
+)
+
+// archiveMimeType is the type of the compressed note archives
+const archiveMimeType = "application/gzip"
+
+// maxArchives is the most archives listed, Drive's largest page; rotation keeps far fewer
+const maxArchives = 1000
+
+// ArchiveManager keeps the compressed snapshots of a user's notes in the _BACKUPS folder
+type ArchiveManager struct {
+	client        *Client
+	folderManager *FolderManager
+	fileManager   *FileManager
+}
+
+// NewArchiveManager creates a new archive manager
+func NewArchiveManager(client *Client, folderMgr *FolderManager, fileMgr *FileManager) *ArchiveManager {
+	return &ArchiveManager{
+		client:        client,
+		folderManager: folderMgr,
+		fileManager:   fileMgr,
+	}
+}
+
+// folder returns the ID of the _BACKUPS folder, creating it if needed
+func (am *ArchiveManager) folder() (string, error) {
+	rootFolderID, err := am.folderManager.GetRootFolder()
+	if err != nil {
+		return "", err
+	}
+	return am.folderManager.GetOrCreate(storage.BackupsFolder, rootFolderID)
+}
+
+// Upload stores an archive in the _BACKUPS folder
+func (am *ArchiveManager) Upload(name string, content io.Reader) error {
+	folderID, err := am.folder()
+	if err != nil {
+		return err
+	}
+	if _, err := am.fileManager.Create(name, folderID, archiveMimeType, content, nil); err != nil {
+		return fmt.Errorf("failed to upload archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// List returns the names of the archives in the _BACKUPS folder, sorted by name
+func (am *ArchiveManager) List() ([]string, error) {
+	folderID, err := am.folder()
+	if err != nil {
+		return nil, err
+	}
+	files, err := am.fileManager.ListInFolder(folderID, "", "name", maxArchives)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	return names, nil
+}
+
+// Delete permanently removes an archive from the _BACKUPS folder
+func (am *ArchiveManager) Delete(name string) error {
+	folderID, err := am.folder()
+	if err != nil {
+		return err
+	}
+	file, err := am.fileManager.Find(name, folderID)
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		return nil
+	}
+	return am.fileManager.Delete(file.Id)
+}
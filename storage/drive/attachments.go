@@ -0,0 +1,58 @@
+package drive
+
+import (
+	"daily-notes/storage"
+	"fmt"
+	"io"
+)
+
+// AttachmentManager keeps the files attached to notes in the _attachments folder
+// of their context folder
+type AttachmentManager struct {
+	client        *Client
+	folderManager *FolderManager
+	fileManager   *FileManager
+}
+
+// NewAttachmentManager creates a new attachment manager
+func NewAttachmentManager(client *Client, folderMgr *FolderManager, fileMgr *FileManager) *AttachmentManager {
+	return &AttachmentManager{
+		client:        client,
+		folderManager: folderMgr,
+		fileManager:   fileMgr,
+	}
+}
+
+// folder returns the ID of a context's _attachments folder, creating it if needed
+func (am *AttachmentManager) folder(contextName string) (string, error) {
+	rootFolderID, err := am.folderManager.GetRootFolder()
+	if err != nil {
+		return "", err
+	}
+	contextFolderID, err := am.folderManager.GetOrCreate(contextName, rootFolderID)
+	if err != nil {
+		return "", err
+	}
+	return am.folderManager.GetOrCreate(storage.AttachmentsFolder, contextFolderID)
+}
+
+// Upload stores an attachment in a context's _attachments folder, replacing a file of the same name
+func (am *AttachmentManager) Upload(contextName, name, contentType string, content io.Reader) error {
+	folderID, err := am.folder(contextName)
+	if err != nil {
+		return err
+	}
+	file, err := am.fileManager.Find(name, folderID)
+	if err != nil {
+		return err
+	}
+	if file != nil {
+		err = am.fileManager.Update(file.Id, content, nil)
+	} else {
+		_, err = am.fileManager.Create(name, folderID, contentType, content, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload attachment %s: %w", name, err)
+	}
+	return nil
+}
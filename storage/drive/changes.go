@@ -0,0 +1,144 @@
+package drive
+
+import (
+	"daily-notes/models"
+	"daily-notes/storage"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+const (
+	// changesPageSize is how many changes are requested per call to the changes API
+	changesPageSize = 100
+	// channelLifetime is how long a watch channel lasts, the longest Drive allows for changes
+	channelLifetime = 7 * 24 * time.Hour
+)
+
+// ChangeManager lists the note files changed in Drive, using the changes API
+type ChangeManager struct {
+	client        *Client
+	folderManager *FolderManager
+	fileManager   *FileManager
+}
+
+// NewChangeManager creates a new change manager
+func NewChangeManager(client *Client, folderMgr *FolderManager, fileMgr *FileManager) *ChangeManager {
+	return &ChangeManager{
+		client:        client,
+		folderManager: folderMgr,
+		fileManager:   fileMgr,
+	}
+}
+
+// StartToken returns the page token from which later changes are listed
+func (cm *ChangeManager) StartToken() (string, error) {
+	resp, err := cm.client.Service().Changes.GetStartPageToken().Context(cm.client.Context()).Do()
+	if err != nil {
+		return "", err
+	}
+	return resp.StartPageToken, nil
+}
+
+// List downloads the notes changed since pageToken and returns the token to continue from
+// Only note files directly inside a context folder count; trashed and removed
+// files, the config and anything else the app can see are skipped.
+func (cm *ChangeManager) List(pageToken string) ([]models.Note, string, error) {
+	rootFolderID, err := cm.folderManager.GetRootFolder()
+	if err != nil {
+		return nil, "", err
+	}
+
+	folders, err := cm.folderManager.List(rootFolderID)
+	if err != nil {
+		return nil, "", err
+	}
+	contexts := make(map[string]string, len(folders))
+	for _, folder := range folders {
+		if folder.Name != storage.DeletedFolder && folder.Name != storage.BackupsFolder {
+			contexts[folder.Id] = folder.Name
+		}
+	}
+
+	var notes []models.Note
+	for {
+		resp, err := cm.client.Service().Changes.List(pageToken).
+			Fields("nextPageToken, newStartPageToken, changes(fileId, removed, file(id, name, parents, trashed, createdTime, modifiedTime))").
+			PageSize(changesPageSize).
+			Context(cm.client.Context()).
+			Do()
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, change := range resp.Changes {
+			file := change.File
+			if change.Removed || file == nil || file.Trashed || !strings.HasSuffix(file.Name, ".md") {
+				continue
+			}
+			contextName := ""
+			for _, parent := range file.Parents {
+				if name, ok := contexts[parent]; ok {
+					contextName = name
+				}
+			}
+			date, err := storage.NoteKey(file.Name)
+			if contextName == "" || err != nil {
+				continue
+			}
+
+			// Fail the whole listing so the token isn't advanced past the note
+			contentBytes, err := cm.fileManager.Download(file.Id)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to download %s: %w", file.Name, err)
+			}
+
+			createdAt, _ := time.Parse(time.RFC3339, file.CreatedTime)
+			updatedAt, _ := time.Parse(time.RFC3339, file.ModifiedTime)
+
+			notes = append(notes, models.Note{
+				ID:        file.Id,
+				UserID:    cm.client.UserID(),
+				Context:   contextName,
+				Date:      date,
+				Content:   string(contentBytes),
+				CreatedAt: createdAt,
+				UpdatedAt: updatedAt,
+			})
+		}
+
+		if resp.NewStartPageToken != "" {
+			return notes, resp.NewStartPageToken, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// Watch subscribes a web hook channel to the changes made after pageToken
+// Drive posts to address with the channel's ID and token on every change until
+// the channel expires or is stopped.
+func (cm *ChangeManager) Watch(pageToken, address string, channel *models.ChangeChannel) error {
+	resp, err := cm.client.Service().Changes.Watch(pageToken, &drive.Channel{
+		Id:         channel.ID,
+		Type:       "web_hook",
+		Address:    address,
+		Token:      channel.Token,
+		Expiration: time.Now().Add(channelLifetime).UnixMilli(),
+	}).Context(cm.client.Context()).Do()
+	if err != nil {
+		return err
+	}
+	channel.ResourceID = resp.ResourceId
+	channel.Expiration = time.UnixMilli(resp.Expiration)
+	return nil
+}
+
+// Stop ends a channel subscribed by Watch
+func (cm *ChangeManager) Stop(channel models.ChangeChannel) error {
+	return cm.client.Service().Channels.Stop(&drive.Channel{
+		Id:         channel.ID,
+		ResourceId: channel.ResourceID,
+	}).Context(cm.client.Context()).Do()
+}
@@ -11,7 +11,10 @@ import (
 )
 
 // Client wraps the Google Drive API client and handles authentication
+// Provider instances are created per operation, so the context given to NewClient
+// also bounds every API call made through the client.
 type Client struct {
+	ctx         context.Context
 	service     *drive.Service
 	tokenSource oauth2.TokenSource
 	userID      string
@@ -37,6 +40,7 @@ func NewClient(ctx context.Context, token *oauth2.Token, userID string) (*Client
 	}
 
 	return &Client{
+		ctx:         ctx,
 		service:     srv,
 		tokenSource: tokenSource,
 		userID:      userID,
@@ -48,6 +52,11 @@ func (c *Client) GetCurrentToken() (*oauth2.Token, error) {
 	return c.tokenSource.Token()
 }
 
+// Context returns the context that cancels API calls made through this client
+func (c *Client) Context() context.Context {
+	return c.ctx
+}
+
 // UserID returns the user ID associated with this client
 func (c *Client) UserID() string {
 	return c.userID
@@ -2,6 +2,7 @@ package drive
 
 import (
 	"daily-notes/models"
+	"daily-notes/storage"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,11 +10,8 @@ import (
 	"time"
 )
 
-// Config represents the user's configuration stored in Drive
-type Config struct {
-	Contexts []models.Context    `json:"contexts"`
-	Settings models.UserSettings `json:"settings"`
-}
+// Config is kept as an alias so existing callers of drive.Config keep compiling
+type Config = storage.Config
 
 // ConfigManager handles configuration file operations
 type ConfigManager struct {
@@ -39,7 +37,7 @@ func (cm *ConfigManager) Get() (*Config, error) {
 	}
 
 	// Find config.json
-	file, err := cm.fileManager.Find("config.json", rootFolderID)
+	file, err := cm.fileManager.Find(storage.ConfigFile, rootFolderID)
 	if err != nil {
 		return nil, err
 	}
@@ -79,18 +77,18 @@ func (cm *ConfigManager) Save(config *Config) error {
 	reader := strings.NewReader(string(data))
 
 	// Check if config.json exists
-	existingFile, err := cm.fileManager.Find("config.json", rootFolderID)
+	existingFile, err := cm.fileManager.Find(storage.ConfigFile, rootFolderID)
 	if err != nil {
 		return err
 	}
 
 	if existingFile != nil {
 		// Update existing config
-		return cm.fileManager.Update(existingFile.Id, reader)
+		return cm.fileManager.Update(existingFile.Id, reader, nil)
 	}
 
 	// Create new config
-	_, err = cm.fileManager.Create("config.json", rootFolderID, "application/json", reader)
+	_, err = cm.fileManager.Create(storage.ConfigFile, rootFolderID, "application/json", reader, nil)
 	return err
 }
 
@@ -188,14 +186,14 @@ func (cm *ConfigManager) DeleteContext(contextID, contextName string) error {
 	}
 
 	// Create _DELETED folder
-	deletedFolderID, err := cm.folderManager.GetOrCreate("_DELETED", rootFolderID)
+	deletedFolderID, err := cm.folderManager.GetOrCreate(storage.DeletedFolder, rootFolderID)
 	if err != nil {
 		return err
 	}
 
 	// Move context folder to _DELETED with timestamp
 	if contextID != "" {
-		newName := fmt.Sprintf("%s_%s", contextName, time.Now().Format("20060102_150405"))
+		newName := fmt.Sprintf("%s_%s", contextName, time.Now().Format(storage.DeletedTimestampLayout))
 		if err := cm.folderManager.Rename(contextID, newName); err != nil {
 			return fmt.Errorf("failed to rename folder: %w", err)
 		}
@@ -222,6 +220,89 @@ func (cm *ConfigManager) DeleteContext(contextID, contextName string) error {
 	return cm.Save(config)
 }
 
+// RestoreContext moves a context folder back from _DELETED and re-adds it to config
+func (cm *ConfigManager) RestoreContext(ctx models.Context) error {
+	rootFolderID, err := cm.folderManager.GetRootFolder()
+	if err != nil {
+		return err
+	}
+
+	exists, deletedFolderID, err := cm.folderManager.Exists(storage.DeletedFolder, rootFolderID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("no deleted contexts found")
+	}
+
+	folderID, err := cm.findDeletedContextFolder(ctx, deletedFolderID)
+	if err != nil {
+		return err
+	}
+
+	// Restore the original name and move the folder back under the root
+	if err := cm.folderManager.Rename(folderID, ctx.Name); err != nil {
+		return fmt.Errorf("failed to rename folder: %w", err)
+	}
+
+	if err := cm.folderManager.Move(folderID, rootFolderID, deletedFolderID); err != nil {
+		return fmt.Errorf("failed to move folder out of _DELETED: %w", err)
+	}
+
+	// Re-add to config
+	config, err := cm.Get()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range config.Contexts {
+		if existing.ID == ctx.ID {
+			return nil
+		}
+	}
+
+	config.Contexts = append(config.Contexts, ctx)
+	return cm.Save(config)
+}
+
+// findDeletedContextFolder locates a context folder inside _DELETED
+// Folders are matched by ID first, then by the "<name>_<timestamp>" naming used on delete
+func (cm *ConfigManager) findDeletedContextFolder(ctx models.Context, deletedFolderID string) (string, error) {
+	folders, err := cm.folderManager.List(deletedFolderID)
+	if err != nil {
+		return "", err
+	}
+
+	var match string
+	var matchModified string
+	for _, folder := range folders {
+		if folder.Id == ctx.ID {
+			return folder.Id, nil
+		}
+		// Keep the most recently deleted folder with this name
+		if strings.HasPrefix(folder.Name, ctx.Name+"_") && folder.ModifiedTime > matchModified {
+			match = folder.Id
+			matchModified = folder.ModifiedTime
+		}
+	}
+
+	if match == "" {
+		return "", fmt.Errorf("deleted folder for context %q not found", ctx.Name)
+	}
+
+	return match, nil
+}
+
+// UpdateContext writes the color and icon of a context to config
+func (cm *ConfigManager) UpdateContext(c models.Context) error {
+	config, err := cm.Get()
+	if err != nil {
+		return err
+	}
+	storage.SetConfigContext(config, c)
+	return cm.Save(config)
+}
+
 // UpdateSettings updates user settings in config
 func (cm *ConfigManager) UpdateSettings(settings models.UserSettings) error {
 	config, err := cm.Get()
@@ -250,7 +331,7 @@ func (cm *ConfigManager) createDefaultConfig(rootFolderID string) (*Config, erro
 		fmt.Printf("[Drive] Found %d existing context folders, migrating to config.json\n", len(existingContexts))
 		defaultConfig := &Config{
 			Contexts: existingContexts,
-			Settings: cm.getDefaultSettings(),
+			Settings: storage.DefaultSettings(),
 		}
 		if err := cm.Save(defaultConfig); err != nil {
 			return nil, err
@@ -261,7 +342,7 @@ func (cm *ConfigManager) createDefaultConfig(rootFolderID string) (*Config, erro
 	// No existing contexts - create empty config
 	defaultConfig := &Config{
 		Contexts: []models.Context{},
-		Settings: cm.getDefaultSettings(),
+		Settings: storage.DefaultSettings(),
 	}
 	if err := cm.Save(defaultConfig); err != nil {
 		return nil, err
@@ -278,6 +359,9 @@ func (cm *ConfigManager) detectExistingContexts(rootFolderID string) ([]models.C
 
 	var contexts []models.Context
 	for _, folder := range folders {
+		if folder.Name == storage.DeletedFolder || folder.Name == storage.BackupsFolder {
+			continue
+		}
 		createdAt, _ := time.Parse(time.RFC3339, folder.CreatedTime)
 		contexts = append(contexts, models.Context{
 			ID:        folder.Id,
@@ -291,16 +375,6 @@ func (cm *ConfigManager) detectExistingContexts(rootFolderID string) ([]models.C
 	return contexts, nil
 }
 
-// getDefaultSettings returns default user settings
-func (cm *ConfigManager) getDefaultSettings() models.UserSettings {
-	return models.UserSettings{
-		Theme:      "dark",
-		WeekStart:  0,
-		Timezone:   "UTC",
-		DateFormat: "DD-MM-YY",
-	}
-}
-
 // IsFirstLogin checks if user has any data in Drive
 func (cm *ConfigManager) IsFirstLogin() (bool, error) {
 	// Check if dailynotes.dev folder exists
@@ -314,7 +388,7 @@ func (cm *ConfigManager) IsFirstLogin() (bool, error) {
 	}
 
 	// Check if config.json exists
-	file, err := cm.fileManager.Find("config.json", folderID)
+	file, err := cm.fileManager.Find(storage.ConfigFile, folderID)
 	if err != nil {
 		return false, err
 	}
@@ -330,7 +404,7 @@ func (cm *ConfigManager) CleanupOldDeletedFolders() error {
 	}
 
 	// Check if _DELETED exists
-	exists, deletedFolderID, err := cm.folderManager.Exists("_DELETED", rootFolderID)
+	exists, deletedFolderID, err := cm.folderManager.Exists(storage.DeletedFolder, rootFolderID)
 	if err != nil {
 		return err
 	}
@@ -346,7 +420,7 @@ func (cm *ConfigManager) CleanupOldDeletedFolders() error {
 	}
 
 	// Delete folders older than 10 days
-	cutoffTime := time.Now().AddDate(0, 0, -10)
+	cutoffTime := time.Now().AddDate(0, 0, -storage.DeletedRetentionDays)
 
 	for _, folder := range folders {
 		modifiedTime, err := time.Parse(time.RFC3339, folder.ModifiedTime)
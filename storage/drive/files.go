@@ -24,6 +24,7 @@ func (fm *FileManager) Find(filename, parentID string) (*drive.File, error) {
 	fileList, err := fm.client.Service().Files.List().
 		Q(query).
 		Fields("files(id, name, createdTime, modifiedTime)").
+		Context(fm.client.Context()).
 		Do()
 	if err != nil {
 		return nil, err
@@ -38,7 +39,7 @@ func (fm *FileManager) Find(filename, parentID string) (*drive.File, error) {
 
 // Download downloads the content of a file
 func (fm *FileManager) Download(fileID string) ([]byte, error) {
-	resp, err := fm.client.Service().Files.Get(fileID).Download()
+	resp, err := fm.client.Service().Files.Get(fileID).Context(fm.client.Context()).Download()
 	if err != nil {
 		return nil, err
 	}
@@ -47,17 +48,21 @@ func (fm *FileManager) Download(fileID string) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-// Create creates a new file with the given content
-func (fm *FileManager) Create(name, parentID, mimeType string, content io.Reader) (*drive.File, error) {
-	fileMetadata := &drive.File{
-		Name:     name,
-		Parents:  []string{parentID},
-		MimeType: mimeType,
+// Create creates a new file with the given content; meta (may be nil) carries
+// further metadata such as the description and app properties
+func (fm *FileManager) Create(name, parentID, mimeType string, content io.Reader, meta *drive.File) (*drive.File, error) {
+	fileMetadata := &drive.File{}
+	if meta != nil {
+		fileMetadata = meta
 	}
+	fileMetadata.Name = name
+	fileMetadata.Parents = []string{parentID}
+	fileMetadata.MimeType = mimeType
 
 	file, err := fm.client.Service().Files.Create(fileMetadata).
 		Media(content).
 		Fields("id, createdTime, modifiedTime").
+		Context(fm.client.Context()).
 		Do()
 	if err != nil {
 		return nil, err
@@ -66,17 +71,22 @@ func (fm *FileManager) Create(name, parentID, mimeType string, content io.Reader
 	return file, nil
 }
 
-// Update updates an existing file's content
-func (fm *FileManager) Update(fileID string, content io.Reader) error {
-	_, err := fm.client.Service().Files.Update(fileID, &drive.File{}).
+// Update updates an existing file's content; meta (may be nil) updates its
+// metadata, with app properties merged into the file's
+func (fm *FileManager) Update(fileID string, content io.Reader, meta *drive.File) error {
+	if meta == nil {
+		meta = &drive.File{}
+	}
+	_, err := fm.client.Service().Files.Update(fileID, meta).
 		Media(content).
+		Context(fm.client.Context()).
 		Do()
 	return err
 }
 
 // Delete moves a file to trash
 func (fm *FileManager) Delete(fileID string) error {
-	return fm.client.Service().Files.Delete(fileID).Do()
+	return fm.client.Service().Files.Delete(fileID).Context(fm.client.Context()).Do()
 }
 
 // List returns all files matching a query
@@ -93,7 +103,7 @@ func (fm *FileManager) List(query string, fields string, orderBy string, pageSiz
 		call.PageSize(pageSize)
 	}
 
-	fileList, err := call.Do()
+	fileList, err := call.Context(fm.client.Context()).Do()
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +118,7 @@ func (fm *FileManager) ListInFolder(parentID, pattern string, orderBy string, li
 		query += fmt.Sprintf(" and name contains '%s'", pattern)
 	}
 
-	fields := "files(id, name, createdTime, modifiedTime)"
+	fields := "files(id, name, createdTime, modifiedTime, appProperties)"
 	pageSize := int64(limit)
 	if pageSize == 0 {
 		pageSize = 100
@@ -117,11 +127,36 @@ func (fm *FileManager) ListInFolder(parentID, pattern string, orderBy string, li
 	return fm.List(query, fields, orderBy, pageSize)
 }
 
+// ListPage returns one page of the files in a folder, ordered by name, and the token of the next page
+// An empty pageToken starts at the first page; an empty next token means this was the last.
+func (fm *FileManager) ListPage(parentID, pattern, pageToken string, pageSize int) ([]*drive.File, string, error) {
+	query := fmt.Sprintf("'%s' in parents and trashed=false", parentID)
+	if pattern != "" {
+		query += fmt.Sprintf(" and name contains '%s'", pattern)
+	}
+
+	call := fm.client.Service().Files.List().
+		Q(query).
+		Fields("nextPageToken, files(id, name, createdTime, modifiedTime)").
+		OrderBy("name").
+		PageSize(int64(pageSize))
+	if pageToken != "" {
+		call.PageToken(pageToken)
+	}
+
+	fileList, err := call.Context(fm.client.Context()).Do()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return fileList.Files, fileList.NextPageToken, nil
+}
+
 // Rename renames a file
 func (fm *FileManager) Rename(fileID, newName string) error {
 	fileMetadata := &drive.File{
 		Name: newName,
 	}
-	_, err := fm.client.Service().Files.Update(fileID, fileMetadata).Do()
+	_, err := fm.client.Service().Files.Update(fileID, fileMetadata).Context(fm.client.Context()).Do()
 	return err
 }
@@ -29,6 +29,7 @@ func (fm *FolderManager) GetOrCreate(name string, parentID string) (string, erro
 	fileList, err := fm.client.Service().Files.List().
 		Q(query).
 		Fields("files(id, name)").
+		Context(fm.client.Context()).
 		Do()
 	if err != nil {
 		return "", err
@@ -48,6 +49,7 @@ func (fm *FolderManager) GetOrCreate(name string, parentID string) (string, erro
 
 	file, err := fm.client.Service().Files.Create(fileMetadata).
 		Fields("id").
+		Context(fm.client.Context()).
 		Do()
 	if err != nil {
 		return "", err
@@ -66,6 +68,7 @@ func (fm *FolderManager) Move(folderID, newParentID, oldParentID string) error {
 	_, err := fm.client.Service().Files.Update(folderID, &drive.File{}).
 		AddParents(newParentID).
 		RemoveParents(oldParentID).
+		Context(fm.client.Context()).
 		Do()
 	return err
 }
@@ -75,7 +78,7 @@ func (fm *FolderManager) Rename(folderID, newName string) error {
 	fileMetadata := &drive.File{
 		Name: newName,
 	}
-	_, err := fm.client.Service().Files.Update(folderID, fileMetadata).Do()
+	_, err := fm.client.Service().Files.Update(folderID, fileMetadata).Context(fm.client.Context()).Do()
 	return err
 }
 
@@ -85,6 +88,7 @@ func (fm *FolderManager) List(parentID string) ([]*drive.File, error) {
 	fileList, err := fm.client.Service().Files.List().
 		Q(query).
 		Fields("files(id, name, createdTime, modifiedTime)").
+		Context(fm.client.Context()).
 		Do()
 	if err != nil {
 		return nil, err
@@ -94,7 +98,7 @@ func (fm *FolderManager) List(parentID string) ([]*drive.File, error) {
 
 // Delete permanently deletes a folder
 func (fm *FolderManager) Delete(folderID string) error {
-	return fm.client.Service().Files.Delete(folderID).Do()
+	return fm.client.Service().Files.Delete(folderID).Context(fm.client.Context()).Do()
 }
 
 // Exists checks if a folder with the given name exists in the parent
@@ -107,6 +111,7 @@ func (fm *FolderManager) Exists(name, parentID string) (bool, string, error) {
 	fileList, err := fm.client.Service().Files.List().
 		Q(query).
 		Fields("files(id)").
+		Context(fm.client.Context()).
 		Do()
 	if err != nil {
 		return false, "", err
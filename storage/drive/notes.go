@@ -2,12 +2,31 @@ package drive
 
 import (
 	"daily-notes/models"
+	"daily-notes/pkg/e2ee"
+	"daily-notes/pkg/markdown"
+	"daily-notes/storage"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// App properties of note files, next to storage.HashProperty
+const (
+	contextProperty = "context"
+	tagsProperty    = "tags" // The note's #tags without #, space separated
 )
 
+// descriptionLength caps the first line of a note kept as its file description
+const descriptionLength = 200
+
+// maxPropertyBytes is what Drive allows for the key and value of an app property
+const maxPropertyBytes = 124
+
 // NoteManager handles note-specific operations
 type NoteManager struct {
 	client        *Client
@@ -38,7 +57,7 @@ func (nm *NoteManager) Get(contextName, date string) (*models.Note, error) {
 	}
 
 	// Find note file
-	filename := dateToFilename(date)
+	filename := storage.NoteFilename(date)
 	file, err := nm.fileManager.Find(filename, contextFolderID)
 	if err != nil {
 		return nil, err
@@ -82,8 +101,9 @@ func (nm *NoteManager) Upsert(contextName, date, content string) (*models.Note,
 		return nil, err
 	}
 
-	filename := dateToFilename(date)
+	filename := storage.NoteFilename(date)
 	reader := strings.NewReader(content)
+	meta := noteMetadata(contextName, content)
 	now := time.Now()
 
 	// Check if file exists
@@ -100,12 +120,12 @@ func (nm *NoteManager) Upsert(contextName, date, content string) (*models.Note,
 		fileID = existingFile.Id
 		createdAt, _ = time.Parse(time.RFC3339, existingFile.CreatedTime)
 
-		if err := nm.fileManager.Update(fileID, reader); err != nil {
+		if err := nm.fileManager.Update(fileID, reader, meta); err != nil {
 			return nil, err
 		}
 	} else {
 		// Create new file
-		file, err := nm.fileManager.Create(filename, contextFolderID, "text/markdown", reader)
+		file, err := nm.fileManager.Create(filename, contextFolderID, "text/markdown", reader, meta)
 		if err != nil {
 			return nil, err
 		}
@@ -125,6 +145,41 @@ func (nm *NoteManager) Upsert(contextName, date, content string) (*models.Note,
 	}, nil
 }
 
+// noteMetadata describes a note file for Drive's own search and UI: the first
+// line of the note as description, and its context and #tags as app properties
+// next to the content hash. The description is always sent, so clearing the
+// note clears it. End-to-end encrypted notes are uploaded as the ciphertext
+// envelope they're stored as, and get no description or tags.
+func noteMetadata(contextName, content string) *drive.File {
+	description, tags := markdown.FirstLine(content, descriptionLength), markdown.ExtractHashtags(content)
+	if e2ee.IsEncrypted(content) {
+		description, tags = "", nil
+	}
+	return &drive.File{
+		Description: description,
+		AppProperties: map[string]string{
+			storage.HashProperty: storage.ContentHash(content),
+			contextProperty:      fitProperty(contextProperty, contextName),
+			tagsProperty:         fitProperty(tagsProperty, strings.Join(tags, " ")),
+		},
+		ForceSendFields: []string{"Description"},
+	}
+}
+
+// fitProperty cuts value to the bytes Drive allows per app property (key and
+// value together), at a space if it has one so no tag is cut in half
+func fitProperty(key, value string) string {
+	limit := maxPropertyBytes - len(key)
+	if len(value) <= limit {
+		return value
+	}
+	value = value[:limit]
+	if i := strings.LastIndex(value, " "); i > 0 {
+		return value[:i]
+	}
+	return strings.ToValidUTF8(value, "")
+}
+
 // Delete removes a note from Drive
 func (nm *NoteManager) Delete(contextName, date string) error {
 	rootFolderID, err := nm.folderManager.GetRootFolder()
@@ -137,7 +192,7 @@ func (nm *NoteManager) Delete(contextName, date string) error {
 		return err
 	}
 
-	filename := dateToFilename(date)
+	filename := storage.NoteFilename(date)
 	file, err := nm.fileManager.Find(filename, contextFolderID)
 	if err != nil {
 		return err
@@ -171,7 +226,7 @@ func (nm *NoteManager) ListByContext(contextName string, limit, offset int) ([]m
 
 	var allNotes []models.Note
 	for _, file := range files {
-		date, err := filenameToDate(file.Name)
+		date, err := storage.NoteKey(file.Name)
 		if err != nil {
 			continue // Skip invalid filenames
 		}
@@ -223,7 +278,7 @@ func (nm *NoteManager) GetAllInContext(contextName string) ([]models.Note, error
 
 	var notes []models.Note
 	for _, file := range files {
-		date, err := filenameToDate(file.Name)
+		date, err := storage.NoteKey(file.Name)
 		if err != nil {
 			continue
 		}
@@ -251,21 +306,159 @@ func (nm *NoteManager) GetAllInContext(contextName string) ([]models.Note, error
 	return notes, nil
 }
 
-// dateToFilename converts YYYY-MM-DD to DD-MM-YYYY.md
-func dateToFilename(date string) string {
-	parts := strings.Split(date, "-")
-	if len(parts) != 3 {
-		return date + ".md" // fallback
+// GetHashed retrieves all notes of a context with the hash stored on each file
+func (nm *NoteManager) GetHashed(contextName string) ([]storage.HashedNote, error) {
+	rootFolderID, err := nm.folderManager.GetRootFolder()
+	if err != nil {
+		return nil, err
+	}
+
+	contextFolderID, err := nm.folderManager.GetOrCreate(contextName, rootFolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := nm.fileManager.ListInFolder(contextFolderID, ".md", "", 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []storage.HashedNote
+	for _, file := range files {
+		date, err := storage.NoteKey(file.Name)
+		if err != nil {
+			continue
+		}
+
+		// A file that can't be read is a finding, not something to skip
+		contentBytes, err := nm.fileManager.Download(file.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", file.Name, err)
+		}
+
+		createdAt, _ := time.Parse(time.RFC3339, file.CreatedTime)
+		updatedAt, _ := time.Parse(time.RFC3339, file.ModifiedTime)
+
+		notes = append(notes, storage.HashedNote{
+			Note: models.Note{
+				ID:        file.Id,
+				UserID:    nm.client.UserID(),
+				Context:   contextName,
+				Date:      date,
+				Content:   string(contentBytes),
+				CreatedAt: createdAt,
+				UpdatedAt: updatedAt,
+			},
+			Hash: file.AppProperties[storage.HashProperty],
+		})
+	}
+
+	return notes, nil
+}
+
+// Page downloads one page of a context's notes, in file name order
+// Download errors fail the page rather than skipping the note, so an import
+// retried from the same page token doesn't lose it.
+func (nm *NoteManager) Page(contextName, pageToken string, pageSize int) (*storage.NotePage, error) {
+	rootFolderID, err := nm.folderManager.GetRootFolder()
+	if err != nil {
+		return nil, err
+	}
+
+	contextFolderID, err := nm.folderManager.GetOrCreate(contextName, rootFolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, nextPageToken, err := nm.fileManager.ListPage(contextFolderID, ".md", pageToken, pageSize)
+	if err != nil {
+		// Drive answers 400 for a page token it doesn't accept (anymore)
+		var apiErr *googleapi.Error
+		if pageToken != "" && errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest {
+			return nil, fmt.Errorf("%w: %v", storage.ErrPageExpired, err)
+		}
+		return nil, err
+	}
+
+	page := &storage.NotePage{NextPageToken: nextPageToken}
+	for _, file := range files {
+		date, err := storage.NoteKey(file.Name)
+		if err != nil {
+			continue
+		}
+
+		contentBytes, err := nm.fileManager.Download(file.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", file.Name, err)
+		}
+
+		createdAt, _ := time.Parse(time.RFC3339, file.CreatedTime)
+		updatedAt, _ := time.Parse(time.RFC3339, file.ModifiedTime)
+
+		page.Notes = append(page.Notes, storage.PagedNote{
+			Note: models.Note{
+				ID:        file.Id,
+				UserID:    nm.client.UserID(),
+				Context:   contextName,
+				Date:      date,
+				Content:   string(contentBytes),
+				CreatedAt: createdAt,
+				UpdatedAt: updatedAt,
+			},
+			File: file.Name,
+		})
+	}
+
+	return page, nil
+}
+
+// ListFiles returns the note files of a context folder
+func (nm *NoteManager) ListFiles(contextName string) ([]storage.NoteFile, error) {
+	rootFolderID, err := nm.folderManager.GetRootFolder()
+	if err != nil {
+		return nil, err
+	}
+
+	contextFolderID, err := nm.folderManager.GetOrCreate(contextName, rootFolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := nm.fileManager.ListInFolder(contextFolderID, ".md", "", 1000)
+	if err != nil {
+		return nil, err
+	}
+
+	noteFiles := make([]storage.NoteFile, 0, len(files))
+	for _, file := range files {
+		noteFiles = append(noteFiles, storage.NoteFile{ID: file.Id, Name: file.Name})
 	}
-	return fmt.Sprintf("%s-%s-%s.md", parts[2], parts[1], parts[0])
+	return noteFiles, nil
 }
 
-// filenameToDate converts DD-MM-YYYY.md to YYYY-MM-DD
-func filenameToDate(filename string) (string, error) {
-	name := strings.TrimSuffix(filename, ".md")
-	parts := strings.Split(name, "-")
-	if len(parts) != 3 {
-		return "", errors.New("invalid filename format")
+// RenameFile renames a note file in place; the file keeps its ID
+// Drive allows duplicate names, so an existing file with the new name is an error.
+func (nm *NoteManager) RenameFile(contextName string, file storage.NoteFile, newName string) (storage.NoteFile, error) {
+	rootFolderID, err := nm.folderManager.GetRootFolder()
+	if err != nil {
+		return storage.NoteFile{}, err
+	}
+
+	contextFolderID, err := nm.folderManager.GetOrCreate(contextName, rootFolderID)
+	if err != nil {
+		return storage.NoteFile{}, err
+	}
+
+	existing, err := nm.fileManager.Find(newName, contextFolderID)
+	if err != nil {
+		return storage.NoteFile{}, err
+	}
+	if existing != nil {
+		return storage.NoteFile{}, fmt.Errorf("%w: %s exists", storage.ErrFilenameConflict, newName)
+	}
+
+	if err := nm.fileManager.Rename(file.ID, newName); err != nil {
+		return storage.NoteFile{}, err
 	}
-	return fmt.Sprintf("%s-%s-%s", parts[2], parts[1], parts[0]), nil
+	return storage.NoteFile{ID: file.ID, Name: newName}, nil
 }
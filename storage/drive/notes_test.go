@@ -0,0 +1,52 @@
+package drive
+
+import (
+	"daily-notes/pkg/e2ee"
+	"daily-notes/storage"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoteMetadata(t *testing.T) {
+	content := "## Standup\n- shipped #release, talked to #Ops\n"
+	meta := noteMetadata("Work", content)
+
+	assert.Equal(t, "Standup", meta.Description)
+	assert.Equal(t, map[string]string{
+		storage.HashProperty: storage.ContentHash(content),
+		contextProperty:      "Work",
+		tagsProperty:         "release ops",
+	}, meta.AppProperties)
+	assert.Contains(t, meta.ForceSendFields, "Description")
+
+	t.Run("Empty notes clear the description and tags", func(t *testing.T) {
+		meta := noteMetadata("Work", "")
+		assert.Equal(t, "", meta.Description)
+		assert.Equal(t, "", meta.AppProperties[tagsProperty])
+	})
+
+	t.Run("Encrypted notes have no description", func(t *testing.T) {
+		content := e2ee.Begin + "\nVersion: 1\n\nZm9v\n" + e2ee.End + "\n"
+		meta := noteMetadata("Journal", content)
+		assert.Equal(t, "", meta.Description)
+		assert.Equal(t, "", meta.AppProperties[tagsProperty])
+		assert.Equal(t, storage.ContentHash(content), meta.AppProperties[storage.HashProperty])
+	})
+}
+
+func TestFitProperty(t *testing.T) {
+	assert.Equal(t, "a b", fitProperty(tagsProperty, "a b"))
+
+	tags := strings.Repeat("project ", 20)
+	fitted := fitProperty(tagsProperty, tags)
+	assert.LessOrEqual(t, len(tagsProperty)+len(fitted), maxPropertyBytes)
+	assert.True(t, strings.HasSuffix(fitted, "project"))
+
+	name := strings.Repeat("é", 100)
+	fitted = fitProperty(contextProperty, name)
+	assert.LessOrEqual(t, len(contextProperty)+len(fitted), maxPropertyBytes)
+	assert.True(t, utf8.ValidString(fitted))
+}
@@ -3,6 +3,8 @@ package drive
 import (
 	"context"
 	"daily-notes/models"
+	"daily-notes/storage"
+	"io"
 
 	"golang.org/x/oauth2"
 )
@@ -10,11 +12,14 @@ import (
 // Service is the main coordinator for all Drive operations
 // It delegates to specialized managers for different concerns
 type Service struct {
-	client        *Client
-	folderManager *FolderManager
-	fileManager   *FileManager
-	noteManager   *NoteManager
-	configManager *ConfigManager
+	client            *Client
+	folderManager     *FolderManager
+	fileManager       *FileManager
+	noteManager       *NoteManager
+	configManager     *ConfigManager
+	changeManager     *ChangeManager
+	archiveManager    *ArchiveManager
+	attachmentManager *AttachmentManager
 }
 
 // NewService creates a new Drive service with all managers initialized
@@ -30,13 +35,19 @@ func NewService(ctx context.Context, token *oauth2.Token, userID string) (*Servi
 	fileMgr := NewFileManager(client)
 	noteMgr := NewNoteManager(client, folderMgr, fileMgr)
 	configMgr := NewConfigManager(client, folderMgr, fileMgr)
+	changeMgr := NewChangeManager(client, folderMgr, fileMgr)
+	archiveMgr := NewArchiveManager(client, folderMgr, fileMgr)
+	attachmentMgr := NewAttachmentManager(client, folderMgr, fileMgr)
 
 	return &Service{
-		client:        client,
-		folderManager: folderMgr,
-		fileManager:   fileMgr,
-		noteManager:   noteMgr,
-		configManager: configMgr,
+		client:            client,
+		folderManager:     folderMgr,
+		fileManager:       fileMgr,
+		noteManager:       noteMgr,
+		configManager:     configMgr,
+		changeManager:     changeMgr,
+		archiveManager:    archiveMgr,
+		attachmentManager: attachmentMgr,
 	}, nil
 }
 
@@ -72,6 +83,70 @@ func (s *Service) GetAllNotesInContext(contextName string) ([]models.Note, error
 	return s.noteManager.GetAllInContext(contextName)
 }
 
+// GetHashedNotes retrieves all notes of a context with their upload hashes
+func (s *Service) GetHashedNotes(contextName string) ([]storage.HashedNote, error) {
+	return s.noteManager.GetHashed(contextName)
+}
+
+// NotePage downloads one page of a context's notes, in file name order
+func (s *Service) NotePage(contextName, pageToken string, pageSize int) (*storage.NotePage, error) {
+	return s.noteManager.Page(contextName, pageToken, pageSize)
+}
+
+// ListNoteFiles returns the note files of a context folder
+func (s *Service) ListNoteFiles(contextName string) ([]storage.NoteFile, error) {
+	return s.noteManager.ListFiles(contextName)
+}
+
+// RenameNoteFile renames a note file within its context folder
+func (s *Service) RenameNoteFile(contextName string, file storage.NoteFile, newName string) (storage.NoteFile, error) {
+	return s.noteManager.RenameFile(contextName, file, newName)
+}
+
+// NoteChangesStartToken returns the token from which later note changes are listed
+func (s *Service) NoteChangesStartToken() (string, error) {
+	return s.changeManager.StartToken()
+}
+
+// ListNoteChanges downloads the notes changed in Drive since pageToken
+func (s *Service) ListNoteChanges(pageToken string) ([]models.Note, string, error) {
+	return s.changeManager.List(pageToken)
+}
+
+// WatchNoteChanges has Drive post to address when files change after pageToken
+func (s *Service) WatchNoteChanges(pageToken, address string, channel *models.ChangeChannel) error {
+	return s.changeManager.Watch(pageToken, address, channel)
+}
+
+// StopWatching ends a subscription made by WatchNoteChanges
+func (s *Service) StopWatching(channel models.ChangeChannel) error {
+	return s.changeManager.Stop(channel)
+}
+
+// ==================== ARCHIVE OPERATIONS ====================
+
+// UploadArchive stores a compressed snapshot of the user's notes in _BACKUPS
+func (s *Service) UploadArchive(name string, content io.Reader) error {
+	return s.archiveManager.Upload(name, content)
+}
+
+// ListArchives returns the names of the archives in _BACKUPS, sorted by name
+func (s *Service) ListArchives() ([]string, error) {
+	return s.archiveManager.List()
+}
+
+// DeleteArchive removes an archive from _BACKUPS
+func (s *Service) DeleteArchive(name string) error {
+	return s.archiveManager.Delete(name)
+}
+
+// ==================== ATTACHMENT OPERATIONS ====================
+
+// UploadAttachment stores a file attached to a note in the context's _attachments folder
+func (s *Service) UploadAttachment(contextName, name, contentType string, content io.Reader) error {
+	return s.attachmentManager.Upload(contextName, name, contentType, content)
+}
+
 // ==================== CONTEXT OPERATIONS ====================
 
 // GetContexts returns all contexts from config
@@ -94,6 +169,11 @@ func (s *Service) DeleteContext(contextID, contextName string) error {
 	return s.configManager.DeleteContext(contextID, contextName)
 }
 
+// RestoreContext moves a context back from _DELETED
+func (s *Service) RestoreContext(ctx models.Context) error {
+	return s.configManager.RestoreContext(ctx)
+}
+
 // ==================== SETTINGS OPERATIONS ====================
 
 // UpdateSettings updates user settings
@@ -125,7 +205,26 @@ func (s *Service) IsFirstLogin() (bool, error) {
 	return s.configManager.IsFirstLogin()
 }
 
+// UpdateContextConfig writes the color and icon of a context to config.json
+func (s *Service) UpdateContextConfig(c models.Context) error {
+	return s.configManager.UpdateContext(c)
+}
+
 // CleanupOldDeletedFolders removes old folders from _DELETED
 func (s *Service) CleanupOldDeletedFolders() error {
 	return s.configManager.CleanupOldDeletedFolders()
 }
+
+// Ensure Service implements storage.Provider and the optional provider interfaces
+var (
+	_ storage.Provider            = (*Service)(nil)
+	_ storage.NoteFileRenamer     = (*Service)(nil)
+	_ storage.NotePager           = (*Service)(nil)
+	_ storage.NoteReader          = (*Service)(nil)
+	_ storage.NoteHashReader      = (*Service)(nil)
+	_ storage.NoteChangeLister    = (*Service)(nil)
+	_ storage.NoteChangeWatcher   = (*Service)(nil)
+	_ storage.NoteArchiver        = (*Service)(nil)
+	_ storage.AttachmentUploader  = (*Service)(nil)
+	_ storage.ContextConfigWriter = (*Service)(nil)
+)
@@ -0,0 +1,65 @@
+package dropbox
+
+import (
+	"daily-notes/config"
+	"log"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// Endpoint is Dropbox's OAuth 2 endpoint
+var Endpoint = oauth2.Endpoint{
+	AuthURL:  "https://www.dropbox.com/oauth2/authorize",
+	TokenURL: "https://api.dropboxapi.com/oauth2/token",
+}
+
+// Enabled reports whether a Dropbox app is configured
+func Enabled() bool {
+	return config.AppConfig != nil && config.AppConfig.DropboxAppKey != "" && config.AppConfig.DropboxAppSecret != ""
+}
+
+// OAuthConfig returns the OAuth settings of the configured Dropbox app
+func OAuthConfig() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     config.AppConfig.DropboxAppKey,
+		ClientSecret: config.AppConfig.DropboxAppSecret,
+		RedirectURL:  config.AppConfig.DropboxRedirectURL,
+		Endpoint:     Endpoint,
+	}
+}
+
+// AuthCodeURL returns the URL that asks the user to connect their Dropbox
+// Offline access is requested so the app receives a refresh token
+func AuthCodeURL(state string) string {
+	return OAuthConfig().AuthCodeURL(state, oauth2.SetAuthURLParam("token_access_type", "offline"))
+}
+
+// savingTokenSource hands out tokens from src and calls save whenever Dropbox
+// issued a new access token, so refreshed tokens outlive the provider instance
+type savingTokenSource struct {
+	src  oauth2.TokenSource
+	save func(*oauth2.Token) error
+
+	mu   sync.Mutex
+	last string
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tok.AccessToken != s.last {
+		s.last = tok.AccessToken
+		if s.save != nil {
+			if err := s.save(tok); err != nil {
+				log.Printf("[Dropbox] Failed to store refreshed token: %v", err)
+			}
+		}
+	}
+	return tok, nil
+}
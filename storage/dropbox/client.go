@@ -0,0 +1,210 @@
+package dropbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Dropbox API v2 hosts
+const (
+	apiURL     = "https://api.dropboxapi.com/2"
+	contentURL = "https://content.dropboxapi.com/2"
+)
+
+// Client performs Dropbox API v2 calls for a single user
+// The HTTP client carries the user's OAuth token and refreshes it as needed.
+type Client struct {
+	ctx        context.Context
+	http       *http.Client
+	apiURL     string
+	contentURL string
+	userID     string
+}
+
+// APIError is a failed Dropbox call
+// Summary holds Dropbox's error_summary (e.g. "path/not_found/..") for endpoint errors
+type APIError struct {
+	Status  int
+	Summary string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("dropbox: %d %s", e.Status, e.Summary)
+}
+
+// HTTPStatus returns the status code of the response, see storage.StatusError
+func (e *APIError) HTTPStatus() int {
+	return e.Status
+}
+
+// isNotFound reports whether err is Dropbox's path/not_found or path_lookup/not_found
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.Status == http.StatusConflict && strings.Contains(apiErr.Summary, "not_found")
+}
+
+// metadata is the subset of Dropbox file and folder metadata used here
+type metadata struct {
+	Tag            string    `json:".tag"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	PathDisplay    string    `json:"path_display"`
+	ClientModified time.Time `json:"client_modified"`
+	ServerModified time.Time `json:"server_modified"`
+}
+
+// rpc calls an RPC-style endpoint with a JSON argument and decodes the JSON result into out
+func (c *Client) rpc(route string, arg, out interface{}) error {
+	body, err := json.Marshal(arg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.apiURL+"/"+route, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// upload writes content to path, overwriting any existing file
+func (c *Client) upload(path string, content []byte) (*metadata, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.contentURL+"/files/upload", bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if err := setArg(req, map[string]interface{}{"path": path, "mode": "overwrite", "mute": true}); err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var meta metadata
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// download reads the file at path
+func (c *Client) download(path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.contentURL+"/files/download", nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := setArg(req, map[string]string{"path": path}); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// list returns the entries directly inside the folder at path, following pagination
+func (c *Client) list(path string) ([]metadata, error) {
+	var page struct {
+		Entries []metadata `json:"entries"`
+		Cursor  string     `json:"cursor"`
+		HasMore bool       `json:"has_more"`
+	}
+	if err := c.rpc("files/list_folder", map[string]interface{}{"path": path, "limit": 2000}, &page); err != nil {
+		return nil, err
+	}
+
+	entries := page.Entries
+	for page.HasMore {
+		cursor := page.Cursor
+		page.Entries = nil
+		if err := c.rpc("files/list_folder/continue", map[string]string{"cursor": cursor}, &page); err != nil {
+			return nil, err
+		}
+		entries = append(entries, page.Entries...)
+	}
+	return entries, nil
+}
+
+// move renames or moves a file or folder
+func (c *Client) move(from, to string) error {
+	return c.rpc("files/move_v2", map[string]interface{}{"from_path": from, "to_path": to}, nil)
+}
+
+// remove deletes a file or folder
+func (c *Client) remove(path string) error {
+	return c.rpc("files/delete_v2", map[string]string{"path": path}, nil)
+}
+
+// do sends the request and turns non-2xx responses into APIErrors
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	apiErr := &APIError{Status: resp.StatusCode, Summary: strings.TrimSpace(string(body))}
+
+	var endpointErr struct {
+		ErrorSummary string `json:"error_summary"`
+	}
+	if json.Unmarshal(body, &endpointErr) == nil && endpointErr.ErrorSummary != "" {
+		apiErr.Summary = endpointErr.ErrorSummary
+	}
+	return nil, apiErr
+}
+
+// setArg sets the Dropbox-API-Arg header used by content endpoints
+// Dropbox requires non-ASCII characters in the header to be escaped
+func setArg(req *http.Request, arg interface{}) error {
+	data, err := json.Marshal(arg)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, r := range string(data) {
+		if r > 0x7e {
+			if r > 0xffff {
+				// Encode as a UTF-16 surrogate pair
+				r -= 0x10000
+				fmt.Fprintf(&b, `\u%04x\u%04x`, 0xd800+(r>>10), 0xdc00+(r&0x3ff))
+				continue
+			}
+			fmt.Fprintf(&b, `\u%04x`, r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	req.Header.Set("Dropbox-API-Arg", b.String())
+	return nil
+}
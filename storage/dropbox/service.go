@@ -0,0 +1,375 @@
+// Package dropbox stores notes in the app folder of a user's Dropbox, using the
+// same layout as Google Drive: config.json at the root, a folder per context
+// and a DD-MM-YYYY.md file per note.
+package dropbox
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Credentials are the user's Dropbox OAuth token and a callback storing refreshed tokens
+type Credentials struct {
+	Token *oauth2.Token
+	Save  func(*oauth2.Token) error
+}
+
+// Service implements storage.Provider on top of the Dropbox API
+type Service struct {
+	client       *Client
+	sessionToken *oauth2.Token
+}
+
+// Ensure Service implements storage.Provider, storage.NoteFileRenamer and storage.ContextConfigWriter
+var (
+	_ storage.Provider            = (*Service)(nil)
+	_ storage.NoteFileRenamer     = (*Service)(nil)
+	_ storage.ContextConfigWriter = (*Service)(nil)
+)
+
+// NewService opens a user's Dropbox
+// sessionToken is the user's sign-in token; it is returned unchanged by
+// GetCurrentToken since Dropbox tokens are stored separately through creds.Save.
+func NewService(ctx context.Context, creds Credentials, sessionToken *oauth2.Token, userID string) (*Service, error) {
+	if creds.Token == nil {
+		return nil, errors.New("dropbox is not connected")
+	}
+
+	src := &savingTokenSource{
+		src:  OAuthConfig().TokenSource(ctx, creds.Token),
+		save: creds.Save,
+		last: creds.Token.AccessToken,
+	}
+
+	return &Service{
+		client: &Client{
+			ctx:        ctx,
+			http:       oauth2.NewClient(ctx, src),
+			apiURL:     apiURL,
+			contentURL: contentURL,
+			userID:     userID,
+		},
+		sessionToken: sessionToken,
+	}, nil
+}
+
+// GetCurrentToken returns the sign-in token the service was opened with
+func (s *Service) GetCurrentToken() (*oauth2.Token, error) {
+	return s.sessionToken, nil
+}
+
+// ==================== NOTE OPERATIONS ====================
+
+// UpsertNote creates or overwrites a note file
+func (s *Service) UpsertNote(contextName, date, content string) (*models.Note, error) {
+	meta, err := s.client.upload(notePath(contextName, date), []byte(content))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Note{
+		ID:        meta.ID,
+		UserID:    s.client.userID,
+		Context:   contextName,
+		Date:      date,
+		Content:   content,
+		CreatedAt: meta.ClientModified,
+		UpdatedAt: meta.ServerModified,
+	}, nil
+}
+
+// DeleteNote removes a note file; missing files are ignored
+func (s *Service) DeleteNote(contextName, date string) error {
+	if err := s.client.remove(notePath(contextName, date)); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// GetAllNotesInContext downloads every note in a context folder
+func (s *Service) GetAllNotesInContext(contextName string) ([]models.Note, error) {
+	entries, err := s.client.list(folderPath(contextName))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var notes []models.Note
+	for _, entry := range entries {
+		if entry.Tag != "file" {
+			continue
+		}
+		date, err := storage.NoteKey(entry.Name)
+		if err != nil {
+			continue
+		}
+
+		content, err := s.client.download(entry.PathDisplay)
+		if err != nil {
+			continue
+		}
+
+		notes = append(notes, models.Note{
+			ID:        entry.ID,
+			UserID:    s.client.userID,
+			Context:   contextName,
+			Date:      date,
+			Content:   string(content),
+			CreatedAt: entry.ClientModified,
+			UpdatedAt: entry.ServerModified,
+		})
+	}
+
+	return notes, nil
+}
+
+// ListNoteFiles returns the note files of a context folder
+func (s *Service) ListNoteFiles(contextName string) ([]storage.NoteFile, error) {
+	entries, err := s.client.list(folderPath(contextName))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []storage.NoteFile
+	for _, entry := range entries {
+		if entry.Tag == "file" && strings.HasSuffix(entry.Name, ".md") {
+			files = append(files, storage.NoteFile{ID: entry.ID, Name: entry.Name})
+		}
+	}
+	return files, nil
+}
+
+// RenameNoteFile renames a note file within its context folder
+// Dropbox keeps file IDs across moves, so the ID is unchanged.
+func (s *Service) RenameNoteFile(contextName string, file storage.NoteFile, newName string) (storage.NoteFile, error) {
+	folder := folderPath(contextName)
+	if err := s.client.move(path.Join(folder, file.Name), path.Join(folder, newName)); err != nil {
+		if apiErr, ok := err.(*APIError); ok && strings.Contains(apiErr.Summary, "to/conflict") {
+			return storage.NoteFile{}, fmt.Errorf("%w: %s exists", storage.ErrFilenameConflict, newName)
+		}
+		return storage.NoteFile{}, err
+	}
+	return storage.NoteFile{ID: file.ID, Name: newName}, nil
+}
+
+// ==================== CONTEXT OPERATIONS ====================
+
+// GetContexts returns the contexts listed in config.json
+func (s *Service) GetContexts() ([]models.Context, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return config.Contexts, nil
+}
+
+// RenameContext renames a context folder and its config entry
+func (s *Service) RenameContext(contextID, oldName, newName string) error {
+	if err := s.client.move(folderPath(oldName), folderPath(newName)); err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to rename folder: %w", err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	for i, c := range config.Contexts {
+		if c.ID == contextID {
+			config.Contexts[i].Name = newName
+		}
+	}
+	return s.saveConfig(config)
+}
+
+// DeleteContext moves a context folder to _DELETED and removes it from config
+func (s *Service) DeleteContext(contextID, contextName string) error {
+	trashed := path.Join(folderPath(storage.DeletedFolder),
+		fmt.Sprintf("%s_%s", contextName, time.Now().Format(storage.DeletedTimestampLayout)))
+	if err := s.client.move(folderPath(contextName), trashed); err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to move folder to %s: %w", storage.DeletedFolder, err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	contexts := []models.Context{}
+	for _, c := range config.Contexts {
+		if c.ID != contextID {
+			contexts = append(contexts, c)
+		}
+	}
+	config.Contexts = contexts
+	return s.saveConfig(config)
+}
+
+// RestoreContext moves the most recently deleted folder of a context back and re-adds it to config
+func (s *Service) RestoreContext(ctx models.Context) error {
+	entries, err := s.client.list(folderPath(storage.DeletedFolder))
+	if err != nil {
+		if isNotFound(err) {
+			return errors.New("no deleted contexts found")
+		}
+		return err
+	}
+
+	// Timestamps sort lexically, so the greatest name is the latest deletion
+	var match, matchName string
+	for _, entry := range entries {
+		if entry.Tag == "folder" && strings.HasPrefix(entry.Name, ctx.Name+"_") && entry.Name > matchName {
+			match, matchName = entry.PathDisplay, entry.Name
+		}
+	}
+	if match == "" {
+		return fmt.Errorf("deleted folder for context %q not found", ctx.Name)
+	}
+
+	if err := s.client.move(match, folderPath(ctx.Name)); err != nil {
+		return fmt.Errorf("failed to move folder out of %s: %w", storage.DeletedFolder, err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	for _, existing := range config.Contexts {
+		if existing.ID == ctx.ID {
+			return nil
+		}
+	}
+	config.Contexts = append(config.Contexts, ctx)
+	return s.saveConfig(config)
+}
+
+// UpdateContextConfig writes the color and icon of a context to config.json
+func (s *Service) UpdateContextConfig(c models.Context) error {
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	storage.SetConfigContext(config, c)
+	return s.saveConfig(config)
+}
+
+// CleanupOldDeletedFolders permanently removes context folders deleted more than
+// storage.DeletedRetentionDays ago, using the timestamp in their name
+func (s *Service) CleanupOldDeletedFolders() error {
+	entries, err := s.client.list(folderPath(storage.DeletedFolder))
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -storage.DeletedRetentionDays)
+	for _, entry := range entries {
+		if entry.Tag != "folder" || len(entry.Name) <= len(storage.DeletedTimestampLayout) {
+			continue
+		}
+		deletedAt, err := time.ParseInLocation(storage.DeletedTimestampLayout,
+			entry.Name[len(entry.Name)-len(storage.DeletedTimestampLayout):], time.Local)
+		if err != nil || !deletedAt.Before(cutoff) {
+			continue
+		}
+
+		log.Printf("[Dropbox] Permanently deleting old folder: %s", entry.Name)
+		if err := s.client.remove(entry.PathDisplay); err != nil {
+			log.Printf("[Dropbox] Failed to delete folder %s: %v", entry.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ==================== CONFIG OPERATIONS ====================
+
+// GetSettings returns user settings from config
+func (s *Service) GetSettings() (models.UserSettings, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return models.UserSettings{}, err
+	}
+	return config.Settings, nil
+}
+
+// GetConfig reads config.json, creating it from existing context folders if missing
+func (s *Service) GetConfig() (*storage.Config, error) {
+	data, err := s.client.download(folderPath(storage.ConfigFile))
+	if err != nil {
+		if isNotFound(err) {
+			return s.createDefaultConfig()
+		}
+		return nil, err
+	}
+
+	var config storage.Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// saveConfig writes config.json
+func (s *Service) saveConfig(config *storage.Config) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = s.client.upload(folderPath(storage.ConfigFile), data)
+	return err
+}
+
+// createDefaultConfig writes a config listing the folders already in the app folder
+func (s *Service) createDefaultConfig() (*storage.Config, error) {
+	entries, err := s.client.list("")
+	if err != nil {
+		return nil, err
+	}
+
+	config := &storage.Config{
+		Contexts: []models.Context{},
+		Settings: storage.DefaultSettings(),
+	}
+	for _, entry := range entries {
+		if entry.Tag != "folder" || entry.Name == storage.DeletedFolder {
+			continue
+		}
+		config.Contexts = append(config.Contexts, models.Context{
+			ID:     entry.ID,
+			UserID: s.client.userID,
+			Name:   entry.Name,
+			Color:  "primary",
+		})
+	}
+
+	if err := s.saveConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// folderPath returns the Dropbox path of an entry directly under the app folder
+func folderPath(name string) string {
+	return "/" + name
+}
+
+// notePath returns the Dropbox path of a note file
+func notePath(contextName, date string) string {
+	return path.Join(folderPath(contextName), storage.NoteFilename(date))
+}
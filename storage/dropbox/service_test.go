@@ -0,0 +1,133 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDropbox serves the files endpoints used by Service from an in-memory tree
+type fakeDropbox struct {
+	mu    sync.Mutex
+	files map[string]string // lower-cased path -> content
+	names map[string]string // lower-cased path -> path as uploaded
+}
+
+func (f *fakeDropbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var arg map[string]interface{}
+	if header := r.Header.Get("Dropbox-API-Arg"); header != "" {
+		json.Unmarshal([]byte(header), &arg)
+	} else {
+		json.NewDecoder(r.Body).Decode(&arg)
+	}
+	p, _ := arg["path"].(string)
+
+	switch r.URL.Path {
+	case "/files/upload":
+		body, _ := io.ReadAll(r.Body)
+		f.files[strings.ToLower(p)] = string(body)
+		f.names[strings.ToLower(p)] = p
+		json.NewEncoder(w).Encode(metadata{Tag: "file", ID: "id:" + p, PathDisplay: p})
+	case "/files/download":
+		content, ok := f.files[strings.ToLower(p)]
+		if !ok {
+			w.WriteHeader(http.StatusConflict)
+			io.WriteString(w, `{"error_summary": "path/not_found/.."}`)
+			return
+		}
+		io.WriteString(w, content)
+	case "/files/list_folder":
+		prefix := strings.ToLower(p) + "/"
+		var entries []metadata
+		for name := range f.files {
+			if rest, ok := strings.CutPrefix(name, prefix); ok && !strings.Contains(rest, "/") {
+				display := f.names[name]
+				entries = append(entries, metadata{Tag: "file", ID: "id:" + name, Name: display[len(display)-len(rest):], PathDisplay: display})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"entries": entries})
+	case "/files/delete_v2":
+		if _, ok := f.files[strings.ToLower(p)]; !ok {
+			w.WriteHeader(http.StatusConflict)
+			io.WriteString(w, `{"error_summary": "path_lookup/not_found/.."}`)
+			return
+		}
+		delete(f.files, strings.ToLower(p))
+		io.WriteString(w, `{}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestService(t *testing.T) (*Service, *fakeDropbox) {
+	t.Helper()
+
+	fake := &fakeDropbox{files: map[string]string{}, names: map[string]string{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return &Service{client: &Client{
+		ctx:        t.Context(),
+		http:       server.Client(),
+		apiURL:     server.URL,
+		contentURL: server.URL,
+		userID:     "user123",
+	}}, fake
+}
+
+func TestService_Notes(t *testing.T) {
+	service, fake := newTestService(t)
+
+	_, err := service.UpsertNote("Work", "2025-10-17", "# Friday")
+	require.NoError(t, err)
+	_, err = service.UpsertNote("Work", "2025-W42", "week plan")
+	require.NoError(t, err)
+	assert.Equal(t, "# Friday", fake.files["/work/17-10-2025.md"])
+
+	notes, err := service.GetAllNotesInContext("Work")
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+
+	byDate := map[string]string{}
+	for _, note := range notes {
+		assert.Equal(t, "user123", note.UserID)
+		byDate[note.Date] = note.Content
+	}
+	assert.Equal(t, map[string]string{"2025-10-17": "# Friday", "2025-W42": "week plan"}, byDate)
+
+	require.NoError(t, service.DeleteNote("Work", "2025-10-17"))
+	require.NoError(t, service.DeleteNote("Work", "2025-10-17"), "deleting a missing note is not an error")
+	assert.NotContains(t, fake.files, "/work/17-10-2025.md")
+}
+
+func TestService_GetConfigCreatesDefault(t *testing.T) {
+	service, fake := newTestService(t)
+
+	config, err := service.GetConfig()
+	require.NoError(t, err)
+	assert.Empty(t, config.Contexts)
+	assert.Equal(t, "dark", config.Settings.Theme)
+	assert.Contains(t, fake.files, "/config.json")
+}
+
+func TestSetArgEscapesNonASCII(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	require.NoError(t, setArg(req, map[string]string{"path": "/Café/📝.md"}))
+
+	header := req.Header.Get("Dropbox-API-Arg")
+	assert.Equal(t, `{"path":"/Caf\u00e9/\ud83d\udcdd.md"}`, header)
+
+	var decoded map[string]string
+	require.NoError(t, json.Unmarshal([]byte(header), &decoded))
+	assert.Equal(t, "/Café/📝.md", decoded["path"])
+}
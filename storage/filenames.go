@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"daily-notes/pkg/period"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Filename patterns for day notes
+const (
+	// FilenameDayFirst names day notes DD-MM-YYYY.md, the original layout
+	FilenameDayFirst = "dd-mm-yyyy"
+	// FilenameISO names day notes YYYY-MM-DD.md, which sorts by date and can't be read as MM-DD
+	FilenameISO = "yyyy-mm-dd"
+)
+
+// ErrFilenameConflict is returned when a migration would overwrite or merge note files
+var ErrFilenameConflict = errors.New("note file name conflict")
+
+var filenamePattern atomic.Value
+
+// SetFilenamePattern selects the pattern new note files are written with
+// Files in the other pattern stay readable; ApplyFilenameMigration renames them.
+func SetFilenamePattern(pattern string) error {
+	if pattern != FilenameDayFirst && pattern != FilenameISO {
+		return fmt.Errorf("unknown filename pattern %q (want %s or %s)", pattern, FilenameDayFirst, FilenameISO)
+	}
+	filenamePattern.Store(pattern)
+	return nil
+}
+
+// FilenamePattern returns the pattern new note files are written with
+func FilenamePattern() string {
+	if pattern, ok := filenamePattern.Load().(string); ok {
+		return pattern
+	}
+	return FilenameDayFirst
+}
+
+// FilenameFor returns the file name of a note key in the given pattern
+func FilenameFor(date, pattern string) string {
+	if kind := period.Kind(date); kind != period.Day || pattern == FilenameISO {
+		return date + ".md"
+	}
+	parts := strings.Split(date, "-")
+	return fmt.Sprintf("%s-%s-%s.md", parts[2], parts[1], parts[0])
+}
+
+// ==================== FILENAME MIGRATION ====================
+
+// NoteFile is a note file as a provider stores it
+type NoteFile struct {
+	ID   string // Provider file ID, as stored in notes.drive_file_id
+	Name string
+}
+
+// NoteFileRenamer is implemented by providers whose note files can be renamed in place
+type NoteFileRenamer interface {
+	ListNoteFiles(contextName string) ([]NoteFile, error)
+	// RenameNoteFile renames a file within its context folder, failing if newName exists
+	RenameNoteFile(contextName string, file NoteFile, newName string) (NoteFile, error)
+}
+
+// FilenameRename is one planned or applied rename
+type FilenameRename struct {
+	Context string
+	Key     string // Note key, the same before and after
+	From    NoteFile
+	To      NoteFile // ID is set once applied
+}
+
+// PlanFilenameMigration lists the renames that bring the notes of contexts to pattern
+// Every new name is checked to map back to the same key, and to not collide with
+// another file; any failure aborts the plan before anything is renamed.
+func PlanFilenameMigration(r NoteFileRenamer, contexts []string, pattern string) ([]FilenameRename, error) {
+	var plan []FilenameRename
+	for _, contextName := range contexts {
+		files, err := r.ListNoteFiles(contextName)
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", contextName, err)
+		}
+
+		names := make(map[string]bool, len(files))
+		for _, f := range files {
+			names[f.Name] = true
+		}
+
+		targets := map[string]string{}
+		for _, f := range files {
+			key, err := NoteKey(f.Name)
+			if err != nil {
+				continue // Not a note
+			}
+			target := FilenameFor(key, pattern)
+			if target == f.Name {
+				continue
+			}
+
+			if back, err := NoteKey(target); err != nil || back != key {
+				return nil, fmt.Errorf("%s/%s: %s does not map back to %s", contextName, f.Name, target, key)
+			}
+			if names[target] {
+				return nil, fmt.Errorf("%w: %s/%s and %s are both note %s", ErrFilenameConflict, contextName, f.Name, target, key)
+			}
+			if other, ok := targets[target]; ok {
+				return nil, fmt.Errorf("%w: %s/%s and %s both become %s", ErrFilenameConflict, contextName, other, f.Name, target)
+			}
+			targets[target] = f.Name
+
+			plan = append(plan, FilenameRename{Context: contextName, Key: key, From: f, To: NoteFile{Name: target}})
+		}
+	}
+
+	sort.SliceStable(plan, func(i, j int) bool {
+		if plan[i].Context != plan[j].Context {
+			return plan[i].Context < plan[j].Context
+		}
+		return plan[i].Key < plan[j].Key
+	})
+	return plan, nil
+}
+
+// ApplyFilenameMigration performs a plan, then re-lists every migrated context to
+// verify each note is found under its new name and no old name is left
+// If a rename or the verification fails, completed renames are reverted.
+// Returns the plan with the new file IDs.
+func ApplyFilenameMigration(r NoteFileRenamer, plan []FilenameRename) ([]FilenameRename, error) {
+	applied := make([]FilenameRename, 0, len(plan))
+	rollback := func(cause error) error {
+		for i := len(applied) - 1; i >= 0; i-- {
+			a := applied[i]
+			if _, err := r.RenameNoteFile(a.Context, a.To, a.From.Name); err != nil {
+				return fmt.Errorf("%w (rollback of %s/%s failed: %v)", cause, a.Context, a.To.Name, err)
+			}
+		}
+		return cause
+	}
+
+	for _, rename := range plan {
+		renamed, err := r.RenameNoteFile(rename.Context, rename.From, rename.To.Name)
+		if err != nil {
+			return nil, rollback(fmt.Errorf("rename %s/%s: %w", rename.Context, rename.From.Name, err))
+		}
+		rename.To = renamed
+		applied = append(applied, rename)
+	}
+
+	if err := verifyFilenameMigration(r, applied); err != nil {
+		return nil, rollback(err)
+	}
+	return applied, nil
+}
+
+// verifyFilenameMigration checks every renamed note exists once, under its new name only
+func verifyFilenameMigration(r NoteFileRenamer, applied []FilenameRename) error {
+	byContext := map[string][]FilenameRename{}
+	for _, a := range applied {
+		byContext[a.Context] = append(byContext[a.Context], a)
+	}
+
+	for contextName, renames := range byContext {
+		files, err := r.ListNoteFiles(contextName)
+		if err != nil {
+			return fmt.Errorf("verify %s: %w", contextName, err)
+		}
+		names := make(map[string]bool, len(files))
+		for _, f := range files {
+			names[f.Name] = true
+		}
+
+		for _, a := range renames {
+			if !names[a.To.Name] {
+				return fmt.Errorf("verify %s: %s missing after rename", contextName, a.To.Name)
+			}
+			if names[a.From.Name] {
+				return fmt.Errorf("verify %s: %s still present after rename", contextName, a.From.Name)
+			}
+			if key, err := NoteKey(a.To.Name); err != nil || key != a.Key {
+				return fmt.Errorf("verify %s: %s reads back as %q, want %q", contextName, a.To.Name, key, a.Key)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRenamer keeps note file names per context; failOn makes renaming to that name fail
+type memRenamer struct {
+	files  map[string]map[string]bool
+	failOn string
+}
+
+func newMemRenamer(files map[string][]string) *memRenamer {
+	r := &memRenamer{files: map[string]map[string]bool{}}
+	for contextName, names := range files {
+		r.files[contextName] = map[string]bool{}
+		for _, name := range names {
+			r.files[contextName][name] = true
+		}
+	}
+	return r
+}
+
+func (r *memRenamer) ListNoteFiles(contextName string) ([]NoteFile, error) {
+	var files []NoteFile
+	for name := range r.files[contextName] {
+		files = append(files, NoteFile{ID: contextName + "/" + name, Name: name})
+	}
+	return files, nil
+}
+
+func (r *memRenamer) RenameNoteFile(contextName string, file NoteFile, newName string) (NoteFile, error) {
+	if newName == r.failOn {
+		return NoteFile{}, errors.New("quota exceeded")
+	}
+	if r.files[contextName][newName] {
+		return NoteFile{}, ErrFilenameConflict
+	}
+	delete(r.files[contextName], file.Name)
+	r.files[contextName][newName] = true
+	return NoteFile{ID: contextName + "/" + newName, Name: newName}, nil
+}
+
+func TestFilenameFor(t *testing.T) {
+	assert.Equal(t, "17-10-2025.md", FilenameFor("2025-10-17", FilenameDayFirst))
+	assert.Equal(t, "2025-10-17.md", FilenameFor("2025-10-17", FilenameISO))
+	assert.Equal(t, "2025-W42.md", FilenameFor("2025-W42", FilenameDayFirst))
+	assert.Equal(t, "2025-W42.md", FilenameFor("2025-W42", FilenameISO))
+}
+
+func TestNoteKey_ReadsBothPatterns(t *testing.T) {
+	for _, name := range []string{"17-10-2025.md", "2025-10-17.md"} {
+		key, err := NoteKey(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, "2025-10-17", key, name)
+	}
+}
+
+func TestSetFilenamePattern(t *testing.T) {
+	t.Cleanup(func() { SetFilenamePattern(FilenameDayFirst) })
+
+	assert.Error(t, SetFilenamePattern("mm-dd-yyyy"))
+	assert.Equal(t, FilenameDayFirst, FilenamePattern())
+	assert.Equal(t, "17-10-2025.md", NoteFilename("2025-10-17"))
+
+	require.NoError(t, SetFilenamePattern(FilenameISO))
+	assert.Equal(t, "2025-10-17.md", NoteFilename("2025-10-17"))
+}
+
+func TestFilenameMigration(t *testing.T) {
+	r := newMemRenamer(map[string][]string{
+		"Work":     {"17-10-2025.md", "2025-W42.md", "2025-10-16.md", "notes.txt"},
+		"Personal": {"01-02-2025.md"},
+	})
+
+	plan, err := PlanFilenameMigration(r, []string{"Work", "Personal"}, FilenameISO)
+	require.NoError(t, err)
+	require.Len(t, plan, 2, "periods, ISO files and non-notes are left alone")
+	assert.Equal(t, FilenameRename{Context: "Personal", Key: "2025-02-01", From: NoteFile{ID: "Personal/01-02-2025.md", Name: "01-02-2025.md"}, To: NoteFile{Name: "2025-02-01.md"}}, plan[0])
+	assert.Equal(t, "Work", plan[1].Context)
+
+	applied, err := ApplyFilenameMigration(r, plan)
+	require.NoError(t, err)
+	assert.Equal(t, "Work/2025-10-17.md", applied[1].To.ID)
+	assert.True(t, r.files["Work"]["2025-10-17.md"])
+	assert.False(t, r.files["Work"]["17-10-2025.md"])
+
+	// Migrating back restores the original names
+	plan, err = PlanFilenameMigration(r, []string{"Work", "Personal"}, FilenameDayFirst)
+	require.NoError(t, err)
+	assert.Len(t, plan, 3, "includes the note that was ISO from the start")
+}
+
+func TestPlanFilenameMigration_Conflict(t *testing.T) {
+	r := newMemRenamer(map[string][]string{
+		"Work": {"17-10-2025.md", "2025-10-17.md"},
+	})
+
+	_, err := PlanFilenameMigration(r, []string{"Work"}, FilenameISO)
+	assert.ErrorIs(t, err, ErrFilenameConflict)
+}
+
+func TestApplyFilenameMigration_RollsBack(t *testing.T) {
+	r := newMemRenamer(map[string][]string{
+		"Work": {"16-10-2025.md", "17-10-2025.md"},
+	})
+	r.failOn = "2025-10-17.md"
+
+	plan, err := PlanFilenameMigration(r, []string{"Work"}, FilenameISO)
+	require.NoError(t, err)
+
+	_, err = ApplyFilenameMigration(r, plan)
+	require.Error(t, err)
+	assert.Equal(t, map[string]bool{"16-10-2025.md": true, "17-10-2025.md": true}, r.files["Work"])
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"daily-notes/models"
+	"encoding/hex"
+)
+
+// HashProperty is the file property holding the SHA-256 of a note's content at upload
+const HashProperty = "sha256"
+
+// ContentHash returns the hex SHA-256 of a note's content
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashedNote is a note read back from storage with the hash stored alongside its file
+type HashedNote struct {
+	models.Note
+	Hash string // HashProperty of the file; empty for files not uploaded by us
+}
+
+// NoteHashReader is implemented by providers that store a content hash with each note file
+// Verification compares the content of every file with the hash recorded at its
+// last sync, which catches edits by other apps and silent corruption.
+type NoteHashReader interface {
+	GetHashedNotes(contextName string) ([]HashedNote, error)
+}
@@ -0,0 +1,503 @@
+// Package local stores notes in a directory on the server's disk, using the same
+// layout as Google Drive: config.json at the root, a folder per context and a
+// DD-MM-YYYY.md file per note. Each user gets their own subdirectory, which
+// self-hosters can back up or replicate with their own tools (Syncthing, rsync).
+package local
+
+import (
+	"bytes"
+	"daily-notes/config"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Enabled reports whether a storage directory is configured
+func Enabled() bool {
+	return config.AppConfig != nil && config.AppConfig.LocalStorageDir != ""
+}
+
+// Dir returns the configured storage directory
+func Dir() string {
+	if config.AppConfig == nil {
+		return ""
+	}
+	return config.AppConfig.LocalStorageDir
+}
+
+// Service implements storage.Provider on a local directory
+type Service struct {
+	root         string
+	userID       string
+	sessionToken *oauth2.Token
+}
+
+// Ensure Service implements storage.Provider and the optional provider interfaces
+var (
+	_ storage.Provider            = (*Service)(nil)
+	_ storage.NoteFileRenamer     = (*Service)(nil)
+	_ storage.AttachmentUploader  = (*Service)(nil)
+	_ storage.ContextConfigWriter = (*Service)(nil)
+)
+
+// NewService opens a user's folder under dir, creating it if needed
+// sessionToken is the user's sign-in token; it is returned unchanged by GetCurrentToken.
+func NewService(dir string, sessionToken *oauth2.Token, userID string) (*Service, error) {
+	if dir == "" {
+		return nil, errors.New("local storage directory is not configured")
+	}
+	if err := checkName(userID); err != nil {
+		return nil, err
+	}
+
+	root := filepath.Join(dir, userID)
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage folder: %w", err)
+	}
+
+	return &Service{root: root, userID: userID, sessionToken: sessionToken}, nil
+}
+
+// GetCurrentToken returns the sign-in token the service was opened with
+func (s *Service) GetCurrentToken() (*oauth2.Token, error) {
+	return s.sessionToken, nil
+}
+
+// ==================== NOTE OPERATIONS ====================
+
+// UpsertNote creates or overwrites a note file
+func (s *Service) UpsertNote(contextName, date, content string) (*models.Note, error) {
+	dir, err := s.contextDir(contextName)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	filename := filepath.Join(dir, storage.NoteFilename(date))
+	if err := writeFile(filename, []byte(content)); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Note{
+		ID:        noteID(contextName, date),
+		UserID:    s.userID,
+		Context:   contextName,
+		Date:      date,
+		Content:   content,
+		CreatedAt: info.ModTime(),
+		UpdatedAt: info.ModTime(),
+	}, nil
+}
+
+// DeleteNote removes a note file; missing files are ignored
+func (s *Service) DeleteNote(contextName, date string) error {
+	dir, err := s.contextDir(contextName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, storage.NoteFilename(date))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// GetAllNotesInContext reads every note in a context folder
+func (s *Service) GetAllNotesInContext(contextName string) ([]models.Note, error) {
+	dir, err := s.contextDir(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var notes []models.Note
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		date, err := storage.NoteKey(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		notes = append(notes, models.Note{
+			ID:        noteID(contextName, date),
+			UserID:    s.userID,
+			Context:   contextName,
+			Date:      date,
+			Content:   string(content),
+			CreatedAt: info.ModTime(),
+			UpdatedAt: info.ModTime(),
+		})
+	}
+
+	return notes, nil
+}
+
+// ListNoteFiles returns the note files of a context folder
+func (s *Service) ListNoteFiles(contextName string) ([]storage.NoteFile, error) {
+	dir, err := s.contextDir(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []storage.NoteFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+		files = append(files, storage.NoteFile{ID: contextName + "/" + entry.Name(), Name: entry.Name()})
+	}
+	return files, nil
+}
+
+// RenameNoteFile renames a note file within its context folder
+func (s *Service) RenameNoteFile(contextName string, file storage.NoteFile, newName string) (storage.NoteFile, error) {
+	dir, err := s.contextDir(contextName)
+	if err != nil {
+		return storage.NoteFile{}, err
+	}
+	if err := checkName(file.Name); err != nil {
+		return storage.NoteFile{}, err
+	}
+	if err := checkName(newName); err != nil {
+		return storage.NoteFile{}, err
+	}
+
+	// os.Rename replaces an existing target, so check first
+	target := filepath.Join(dir, newName)
+	if _, err := os.Stat(target); err == nil {
+		return storage.NoteFile{}, fmt.Errorf("%w: %s exists", storage.ErrFilenameConflict, newName)
+	}
+	if err := os.Rename(filepath.Join(dir, file.Name), target); err != nil {
+		return storage.NoteFile{}, err
+	}
+	return storage.NoteFile{ID: contextName + "/" + newName, Name: newName}, nil
+}
+
+// ==================== ATTACHMENT OPERATIONS ====================
+
+// UploadAttachment writes a file attached to a note into the context's _attachments folder
+func (s *Service) UploadAttachment(contextName, name, _ string, content io.Reader) error {
+	dir, err := s.contextDir(contextName)
+	if err != nil {
+		return err
+	}
+	if err := checkName(name); err != nil {
+		return err
+	}
+	dir = filepath.Join(dir, storage.AttachmentsFolder)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return writeFileFrom(filepath.Join(dir, name), content)
+}
+
+// ==================== CONTEXT OPERATIONS ====================
+
+// GetContexts returns the contexts listed in config.json
+func (s *Service) GetContexts() ([]models.Context, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return config.Contexts, nil
+}
+
+// RenameContext renames a context folder and its config entry
+func (s *Service) RenameContext(contextID, oldName, newName string) error {
+	oldDir, err := s.contextDir(oldName)
+	if err != nil {
+		return err
+	}
+	newDir, err := s.contextDir(newName)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(oldDir, newDir); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rename folder: %w", err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	for i, c := range config.Contexts {
+		if c.ID == contextID {
+			config.Contexts[i].Name = newName
+		}
+	}
+	return s.saveConfig(config)
+}
+
+// DeleteContext moves a context folder to _DELETED and removes it from config
+func (s *Service) DeleteContext(contextID, contextName string) error {
+	dir, err := s.contextDir(contextName)
+	if err != nil {
+		return err
+	}
+
+	trash := filepath.Join(s.root, storage.DeletedFolder)
+	if err := os.MkdirAll(trash, 0o755); err != nil {
+		return err
+	}
+	trashed := filepath.Join(trash, fmt.Sprintf("%s_%s", contextName, time.Now().Format(storage.DeletedTimestampLayout)))
+	if err := os.Rename(dir, trashed); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to move folder to %s: %w", storage.DeletedFolder, err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	contexts := []models.Context{}
+	for _, c := range config.Contexts {
+		if c.ID != contextID {
+			contexts = append(contexts, c)
+		}
+	}
+	config.Contexts = contexts
+	return s.saveConfig(config)
+}
+
+// RestoreContext moves the most recently deleted folder of a context back and re-adds it to config
+func (s *Service) RestoreContext(ctx models.Context) error {
+	dir, err := s.contextDir(ctx.Name)
+	if err != nil {
+		return err
+	}
+
+	trash := filepath.Join(s.root, storage.DeletedFolder)
+	entries, err := os.ReadDir(trash)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("no deleted contexts found")
+		}
+		return err
+	}
+
+	// Timestamps sort lexically, so the greatest name is the latest deletion
+	var match string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), ctx.Name+"_") && entry.Name() > match {
+			match = entry.Name()
+		}
+	}
+	if match == "" {
+		return fmt.Errorf("deleted folder for context %q not found", ctx.Name)
+	}
+
+	if err := os.Rename(filepath.Join(trash, match), dir); err != nil {
+		return fmt.Errorf("failed to move folder out of %s: %w", storage.DeletedFolder, err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	for _, existing := range config.Contexts {
+		if existing.ID == ctx.ID {
+			return nil
+		}
+	}
+	config.Contexts = append(config.Contexts, ctx)
+	return s.saveConfig(config)
+}
+
+// UpdateContextConfig writes the color and icon of a context to config.json
+func (s *Service) UpdateContextConfig(c models.Context) error {
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	storage.SetConfigContext(config, c)
+	return s.saveConfig(config)
+}
+
+// CleanupOldDeletedFolders permanently removes context folders deleted more than
+// storage.DeletedRetentionDays ago, using the timestamp in their name
+func (s *Service) CleanupOldDeletedFolders() error {
+	trash := filepath.Join(s.root, storage.DeletedFolder)
+	entries, err := os.ReadDir(trash)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -storage.DeletedRetentionDays)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || len(name) <= len(storage.DeletedTimestampLayout) {
+			continue
+		}
+		deletedAt, err := time.ParseInLocation(storage.DeletedTimestampLayout,
+			name[len(name)-len(storage.DeletedTimestampLayout):], time.Local)
+		if err != nil || !deletedAt.Before(cutoff) {
+			continue
+		}
+
+		log.Printf("[Local Storage] Permanently deleting old folder: %s", name)
+		if err := os.RemoveAll(filepath.Join(trash, name)); err != nil {
+			log.Printf("[Local Storage] Failed to delete folder %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ==================== CONFIG OPERATIONS ====================
+
+// GetSettings returns user settings from config
+func (s *Service) GetSettings() (models.UserSettings, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return models.UserSettings{}, err
+	}
+	return config.Settings, nil
+}
+
+// GetConfig reads config.json, creating it from existing context folders if missing
+func (s *Service) GetConfig() (*storage.Config, error) {
+	data, err := os.ReadFile(filepath.Join(s.root, storage.ConfigFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s.createDefaultConfig()
+		}
+		return nil, err
+	}
+
+	var config storage.Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// saveConfig writes config.json
+func (s *Service) saveConfig(config *storage.Config) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(s.root, storage.ConfigFile), data)
+}
+
+// createDefaultConfig writes a config listing the folders already in the user's directory
+func (s *Service) createDefaultConfig() (*storage.Config, error) {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &storage.Config{
+		Contexts: []models.Context{},
+		Settings: storage.DefaultSettings(),
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == storage.DeletedFolder || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		config.Contexts = append(config.Contexts, models.Context{
+			ID:     entry.Name(),
+			UserID: s.userID,
+			Name:   entry.Name(),
+			Color:  "primary",
+		})
+	}
+	sort.Slice(config.Contexts, func(i, j int) bool { return config.Contexts[i].Name < config.Contexts[j].Name })
+
+	if err := s.saveConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// contextDir returns the folder of a context, rejecting names that would leave the user's directory
+func (s *Service) contextDir(contextName string) (string, error) {
+	if err := checkName(contextName); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, contextName), nil
+}
+
+// checkName rejects empty names, "." and "..", and names containing path separators
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid folder name %q", name)
+	}
+	return nil
+}
+
+// writeFile replaces filename atomically, so sync tools never pick up half-written notes
+func writeFile(filename string, data []byte) error {
+	return writeFileFrom(filename, bytes.NewReader(data))
+}
+
+// writeFileFrom is writeFile reading the content from a reader
+func writeFileFrom(filename string, content io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// noteID identifies a note file by its path relative to the user's directory
+func noteID(contextName, date string) string {
+	return contextName + "/" + storage.NoteFilename(date)
+}
@@ -0,0 +1,144 @@
+package local
+
+import (
+	"daily-notes/models"
+	"daily-notes/storage"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Notes(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewService(dir, nil, "user123")
+	require.NoError(t, err)
+
+	_, err = service.UpsertNote("Work", "2025-10-17", "# Friday")
+	require.NoError(t, err)
+	_, err = service.UpsertNote("Work", "2025-W42", "week plan")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "user123", "Work", "17-10-2025.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Friday", string(data))
+
+	notes, err := service.GetAllNotesInContext("Work")
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	byDate := map[string]string{}
+	for _, note := range notes {
+		byDate[note.Date] = note.Content
+	}
+	assert.Equal(t, map[string]string{"2025-10-17": "# Friday", "2025-W42": "week plan"}, byDate)
+
+	require.NoError(t, service.DeleteNote("Work", "2025-10-17"))
+	require.NoError(t, service.DeleteNote("Work", "2025-10-17"), "deleting a missing note is not an error")
+	notes, err = service.GetAllNotesInContext("Work")
+	require.NoError(t, err)
+	assert.Len(t, notes, 1)
+}
+
+func TestService_Attachments(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewService(dir, nil, "user123")
+	require.NoError(t, err)
+
+	_, err = service.UpsertNote("Work", "2025-10-17", "![board](/api/attachments/att-1)")
+	require.NoError(t, err)
+	require.NoError(t, service.UploadAttachment("Work", "att-1-board.png", "image/png", strings.NewReader("png bytes")))
+
+	data, err := os.ReadFile(filepath.Join(dir, "user123", "Work", storage.AttachmentsFolder, "att-1-board.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "png bytes", string(data))
+
+	notes, err := service.GetAllNotesInContext("Work")
+	require.NoError(t, err)
+	assert.Len(t, notes, 1, "attachments aren't notes")
+
+	assert.Error(t, service.UploadAttachment("Work", "../config.json", "text/plain", strings.NewReader("{}")))
+}
+
+func TestService_Contexts(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewService(dir, nil, "user123")
+	require.NoError(t, err)
+
+	_, err = service.UpsertNote("Work", "2025-10-17", "note")
+	require.NoError(t, err)
+
+	config, err := service.GetConfig()
+	require.NoError(t, err)
+	require.Len(t, config.Contexts, 1)
+	assert.Equal(t, "Work", config.Contexts[0].Name)
+	assert.Equal(t, storage.DefaultSettings(), config.Settings)
+
+	work := config.Contexts[0]
+	require.NoError(t, service.DeleteContext(work.ID, "Work"))
+	contexts, err := service.GetContexts()
+	require.NoError(t, err)
+	assert.Empty(t, contexts)
+
+	require.NoError(t, service.RestoreContext(models.Context{ID: work.ID, Name: "Work"}))
+	notes, err := service.GetAllNotesInContext("Work")
+	require.NoError(t, err)
+	assert.Len(t, notes, 1)
+
+	require.NoError(t, service.RenameContext(work.ID, "Work", "Job"))
+	contexts, err = service.GetContexts()
+	require.NoError(t, err)
+	require.Len(t, contexts, 1)
+	assert.Equal(t, "Job", contexts[0].Name)
+
+	require.NoError(t, service.UpdateContextConfig(models.Context{ID: work.ID, Name: "Job", Color: "danger", Icon: "briefcase"}))
+	require.NoError(t, service.UpdateContextConfig(models.Context{ID: "ctx-home", Name: "Home", Icon: "🏠"}))
+	contexts, err = service.GetContexts()
+	require.NoError(t, err)
+	require.Len(t, contexts, 2)
+	assert.Equal(t, "briefcase", contexts[0].Icon)
+	assert.Equal(t, "danger", contexts[0].Color)
+	assert.Equal(t, "Home", contexts[1].Name)
+	assert.Equal(t, "🏠", contexts[1].Icon)
+}
+
+func TestService_RejectsPathsOutsideUserFolder(t *testing.T) {
+	service, err := NewService(t.TempDir(), nil, "user123")
+	require.NoError(t, err)
+
+	_, err = service.UpsertNote("..", "2025-10-17", "escape")
+	assert.Error(t, err)
+
+	_, err = NewService(t.TempDir(), nil, "../other")
+	assert.Error(t, err)
+}
+
+func TestService_FilenameMigration(t *testing.T) {
+	dir := t.TempDir()
+	service, err := NewService(dir, nil, "user123")
+	require.NoError(t, err)
+
+	_, err = service.UpsertNote("Work", "2025-10-17", "# Friday")
+	require.NoError(t, err)
+
+	plan, err := storage.PlanFilenameMigration(service, []string{"Work"}, storage.FilenameISO)
+	require.NoError(t, err)
+	applied, err := storage.ApplyFilenameMigration(service, plan)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, storage.NoteFile{ID: "Work/2025-10-17.md", Name: "2025-10-17.md"}, applied[0].To)
+
+	notes, err := service.GetAllNotesInContext("Work")
+	require.NoError(t, err)
+	require.Len(t, notes, 1)
+	assert.Equal(t, "2025-10-17", notes[0].Date)
+	assert.Equal(t, "# Friday", notes[0].Content)
+
+	// Renaming onto an existing file is refused instead of overwriting it
+	_, err = service.UpsertNote("Work", "2025-10-18", "# Saturday")
+	require.NoError(t, err)
+	_, err = service.RenameNoteFile("Work", storage.NoteFile{Name: "18-10-2025.md"}, "2025-10-17.md")
+	assert.ErrorIs(t, err, storage.ErrFilenameConflict)
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"daily-notes/models"
+	"errors"
+	"sort"
+)
+
+// ErrPageExpired is returned by a NotePager for a page token it no longer accepts
+var ErrPageExpired = errors.New("page token expired")
+
+// PagedNote is a note with the name of the file it was read from
+type PagedNote struct {
+	models.Note
+	File string
+}
+
+// NotePage is one batch of a context's notes
+type NotePage struct {
+	Notes         []PagedNote
+	NextPageToken string // Empty on the last page
+}
+
+// NotePager is implemented by providers that can read a context's notes in batches
+// Reading the same page token again returns the same files in the same order, so a
+// reader can resume inside a page by skipping up to the last file it handled.
+// A token that is no longer valid fails with ErrPageExpired.
+type NotePager interface {
+	NotePage(contextName, pageToken string, pageSize int) (*NotePage, error)
+}
+
+// SinglePage wraps notes read in one go as a NotePage for readers expecting pages
+// Providers without paging have no file names to hand out, so notes are ordered
+// and named by their key.
+func SinglePage(notes []models.Note) *NotePage {
+	page := &NotePage{Notes: make([]PagedNote, len(notes))}
+	for i, note := range notes {
+		page.Notes[i] = PagedNote{Note: note, File: note.Date}
+	}
+	sort.Slice(page.Notes, func(i, j int) bool { return page.Notes[i].File < page.Notes[j].File })
+	return page
+}
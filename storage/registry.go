@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrProviderUnavailable is returned when a user's provider isn't registered on this server
+var ErrProviderUnavailable = errors.New("storage provider is not available")
+
+// Registry maps provider names to the factories that open them
+// The server registers every configured backend once at startup; each user's
+// choice is then resolved on every open, so switching providers takes effect
+// on the next sync without a restart.
+type Registry struct {
+	mu        sync.RWMutex
+	fallback  string
+	names     []string
+	factories map[string]Factory
+}
+
+// NewRegistry creates an empty registry; users without a choice get fallback
+func NewRegistry(fallback string) *Registry {
+	return &Registry{fallback: fallback, factories: make(map[string]Factory)}
+}
+
+// Register adds or replaces a provider
+func (r *Registry) Register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.factories[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.factories[name] = factory
+}
+
+// Names returns the registered providers in registration order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.names)
+}
+
+// Has reports whether a provider is registered
+func (r *Registry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.factories[name]
+	return ok
+}
+
+// Open opens a user's storage on the named provider; an empty name opens the fallback
+func (r *Registry) Open(ctx context.Context, name string, token *oauth2.Token, userID string) (Provider, error) {
+	if name == "" {
+		name = r.fallback
+	}
+
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrProviderUnavailable, name)
+	}
+	return factory(ctx, token, userID)
+}
+
+// Factory returns a factory that looks up each user's provider with resolve before opening it
+func (r *Registry) Factory(resolve func(ctx context.Context, userID string) (string, error)) Factory {
+	return func(ctx context.Context, token *oauth2.Token, userID string) (Provider, error) {
+		name, err := resolve(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return r.Open(ctx, name, token, userID)
+	}
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// namedProvider is a Provider stub that remembers which backend opened it
+type namedProvider struct {
+	Provider
+	name   string
+	userID string
+}
+
+func opener(name string) Factory {
+	return func(_ context.Context, _ *oauth2.Token, userID string) (Provider, error) {
+		return &namedProvider{name: name, userID: userID}, nil
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry(Drive)
+	registry.Register(Drive, opener(Drive))
+	registry.Register(Local, opener(Local))
+	registry.Register(Drive, opener("drive-v2"))
+
+	assert.Equal(t, []string{Drive, Local}, registry.Names(), "re-registering keeps the order")
+	assert.True(t, registry.Has(Local))
+	assert.False(t, registry.Has(Dropbox))
+
+	choices := map[string]string{"alice": Local, "bob": "", "carol": Dropbox}
+	factory := registry.Factory(func(_ context.Context, userID string) (string, error) {
+		if userID == "broken" {
+			return "", errors.New("database down")
+		}
+		return choices[userID], nil
+	})
+	ctx := context.Background()
+
+	provider, err := factory(ctx, nil, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, &namedProvider{name: Local, userID: "alice"}, provider)
+
+	provider, err = factory(ctx, nil, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "drive-v2", provider.(*namedProvider).name, "no choice opens the fallback")
+
+	_, err = factory(ctx, nil, "carol")
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+
+	_, err = factory(ctx, nil, "broken")
+	assert.EqualError(t, err, "database down")
+
+	// Choices are resolved on every open
+	choices["bob"] = Local
+	provider, err = factory(ctx, nil, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, Local, provider.(*namedProvider).name)
+}
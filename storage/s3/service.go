@@ -0,0 +1,476 @@
+// Package s3 stores notes in an S3-compatible bucket (AWS S3, MinIO, Backblaze B2),
+// using the same layout as Google Drive under <prefix>/<user id>/: config.json,
+// a "folder" per context and a DD-MM-YYYY.md object per note.
+package s3
+
+import (
+	"context"
+	"daily-notes/config"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Enabled reports whether a bucket and credentials are configured
+func Enabled() bool {
+	return config.AppConfig != nil && config.AppConfig.S3Bucket != "" &&
+		config.AppConfig.S3AccessKeyID != "" && config.AppConfig.S3SecretAccessKey != ""
+}
+
+// Prefix returns the configured key prefix
+func Prefix() string {
+	if config.AppConfig == nil {
+		return ""
+	}
+	return config.AppConfig.S3Prefix
+}
+
+// NewClient creates a client for the configured bucket
+// S3_ENDPOINT selects an S3-compatible service; without it AWS S3 in S3_REGION is used.
+func NewClient() (*Client, error) {
+	if !Enabled() {
+		return nil, errors.New("s3 storage is not configured")
+	}
+	cfg := config.AppConfig
+
+	endpoint := cfg.S3Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.S3Region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", endpoint)
+	}
+
+	return &Client{
+		http:      &http.Client{Timeout: time.Minute},
+		endpoint:  u,
+		bucket:    cfg.S3Bucket,
+		region:    cfg.S3Region,
+		accessKey: cfg.S3AccessKeyID,
+		secretKey: cfg.S3SecretAccessKey,
+		pathStyle: cfg.S3PathStyle,
+		now:       time.Now,
+	}, nil
+}
+
+// Service implements storage.Provider on a bucket prefix of one user
+type Service struct {
+	ctx          context.Context
+	client       *Client
+	root         string // "<prefix>/<user id>/"
+	userID       string
+	sessionToken *oauth2.Token
+}
+
+// Ensure Service implements storage.Provider, storage.NoteFileRenamer and storage.ContextConfigWriter
+var (
+	_ storage.Provider            = (*Service)(nil)
+	_ storage.NoteFileRenamer     = (*Service)(nil)
+	_ storage.ContextConfigWriter = (*Service)(nil)
+)
+
+// NewService opens a user's folder in the bucket under prefix
+// sessionToken is the user's sign-in token; it is returned unchanged by GetCurrentToken.
+func NewService(ctx context.Context, client *Client, prefix string, sessionToken *oauth2.Token, userID string) (*Service, error) {
+	if userID == "" || strings.Contains(userID, "/") {
+		return nil, fmt.Errorf("invalid user id %q", userID)
+	}
+
+	root := userID + "/"
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		root = prefix + "/" + root
+	}
+
+	return &Service{ctx: ctx, client: client, root: root, userID: userID, sessionToken: sessionToken}, nil
+}
+
+// GetCurrentToken returns the sign-in token the service was opened with
+func (s *Service) GetCurrentToken() (*oauth2.Token, error) {
+	return s.sessionToken, nil
+}
+
+// ==================== NOTE OPERATIONS ====================
+
+// UpsertNote creates or overwrites a note object
+func (s *Service) UpsertNote(contextName, date, content string) (*models.Note, error) {
+	key, err := s.noteKey(contextName, date)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.put(s.ctx, key, []byte(content)); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &models.Note{
+		ID:        key,
+		UserID:    s.userID,
+		Context:   contextName,
+		Date:      date,
+		Content:   content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// DeleteNote removes a note object; missing objects are ignored
+func (s *Service) DeleteNote(contextName, date string) error {
+	key, err := s.noteKey(contextName, date)
+	if err != nil {
+		return err
+	}
+	return s.client.remove(s.ctx, key)
+}
+
+// GetAllNotesInContext downloads every note in a context folder
+func (s *Service) GetAllNotesInContext(contextName string) ([]models.Note, error) {
+	folder, err := s.folder(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, _, err := s.client.list(s.ctx, folder, "/")
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []models.Note
+	for _, obj := range objects {
+		date, err := storage.NoteKey(path.Base(obj.Key))
+		if err != nil {
+			continue
+		}
+
+		content, err := s.client.get(s.ctx, obj.Key)
+		if err != nil {
+			continue
+		}
+
+		notes = append(notes, models.Note{
+			ID:        obj.Key,
+			UserID:    s.userID,
+			Context:   contextName,
+			Date:      date,
+			Content:   string(content),
+			CreatedAt: obj.LastModified,
+			UpdatedAt: obj.LastModified,
+		})
+	}
+
+	return notes, nil
+}
+
+// ListNoteFiles returns the note objects of a context folder
+func (s *Service) ListNoteFiles(contextName string) ([]storage.NoteFile, error) {
+	folder, err := s.folder(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, _, err := s.client.list(s.ctx, folder, "/")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []storage.NoteFile
+	for _, obj := range objects {
+		if name := path.Base(obj.Key); strings.HasSuffix(name, ".md") {
+			files = append(files, storage.NoteFile{ID: obj.Key, Name: name})
+		}
+	}
+	return files, nil
+}
+
+// RenameNoteFile renames a note object within its context folder by copying and deleting it
+func (s *Service) RenameNoteFile(contextName string, file storage.NoteFile, newName string) (storage.NoteFile, error) {
+	folder, err := s.folder(contextName)
+	if err != nil {
+		return storage.NoteFile{}, err
+	}
+	if strings.Contains(newName, "/") || strings.Contains(file.Name, "/") {
+		return storage.NoteFile{}, fmt.Errorf("invalid file name %q", newName)
+	}
+
+	from, to := folder+file.Name, folder+newName
+	if _, err := s.client.get(s.ctx, to); err == nil {
+		return storage.NoteFile{}, fmt.Errorf("%w: %s exists", storage.ErrFilenameConflict, newName)
+	} else if err != errNotFound {
+		return storage.NoteFile{}, err
+	}
+
+	if err := s.client.copy(s.ctx, from, to); err != nil {
+		return storage.NoteFile{}, err
+	}
+	if err := s.client.remove(s.ctx, from); err != nil {
+		return storage.NoteFile{}, err
+	}
+	return storage.NoteFile{ID: to, Name: newName}, nil
+}
+
+// ==================== CONTEXT OPERATIONS ====================
+
+// GetContexts returns the contexts listed in config.json
+func (s *Service) GetContexts() ([]models.Context, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return config.Contexts, nil
+}
+
+// RenameContext moves a context's objects to the new folder and renames its config entry
+// S3 has no rename, so every object is copied and then deleted.
+func (s *Service) RenameContext(contextID, oldName, newName string) error {
+	from, err := s.folder(oldName)
+	if err != nil {
+		return err
+	}
+	to, err := s.folder(newName)
+	if err != nil {
+		return err
+	}
+	if err := s.moveFolder(from, to); err != nil {
+		return fmt.Errorf("failed to rename folder: %w", err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	for i, c := range config.Contexts {
+		if c.ID == contextID {
+			config.Contexts[i].Name = newName
+		}
+	}
+	return s.saveConfig(config)
+}
+
+// DeleteContext moves a context folder to _DELETED and removes it from config
+func (s *Service) DeleteContext(contextID, contextName string) error {
+	from, err := s.folder(contextName)
+	if err != nil {
+		return err
+	}
+	to := s.root + storage.DeletedFolder + "/" +
+		fmt.Sprintf("%s_%s", contextName, time.Now().Format(storage.DeletedTimestampLayout)) + "/"
+	if err := s.moveFolder(from, to); err != nil {
+		return fmt.Errorf("failed to move folder to %s: %w", storage.DeletedFolder, err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	contexts := []models.Context{}
+	for _, c := range config.Contexts {
+		if c.ID != contextID {
+			contexts = append(contexts, c)
+		}
+	}
+	config.Contexts = contexts
+	return s.saveConfig(config)
+}
+
+// RestoreContext moves the most recently deleted folder of a context back and re-adds it to config
+func (s *Service) RestoreContext(ctx models.Context) error {
+	to, err := s.folder(ctx.Name)
+	if err != nil {
+		return err
+	}
+
+	trash := s.root + storage.DeletedFolder + "/"
+	_, folders, err := s.client.list(s.ctx, trash, "/")
+	if err != nil {
+		return err
+	}
+
+	// Timestamps sort lexically, so the greatest name is the latest deletion
+	var match, matchName string
+	for _, folder := range folders {
+		name := path.Base(folder)
+		if strings.HasPrefix(name, ctx.Name+"_") && name > matchName {
+			match, matchName = folder, name
+		}
+	}
+	if match == "" {
+		return fmt.Errorf("deleted folder for context %q not found", ctx.Name)
+	}
+
+	if err := s.moveFolder(match, to); err != nil {
+		return fmt.Errorf("failed to move folder out of %s: %w", storage.DeletedFolder, err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	for _, existing := range config.Contexts {
+		if existing.ID == ctx.ID {
+			return nil
+		}
+	}
+	config.Contexts = append(config.Contexts, ctx)
+	return s.saveConfig(config)
+}
+
+// UpdateContextConfig writes the color and icon of a context to config.json
+func (s *Service) UpdateContextConfig(c models.Context) error {
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	storage.SetConfigContext(config, c)
+	return s.saveConfig(config)
+}
+
+// CleanupOldDeletedFolders permanently removes context folders deleted more than
+// storage.DeletedRetentionDays ago, using the timestamp in their name
+func (s *Service) CleanupOldDeletedFolders() error {
+	_, folders, err := s.client.list(s.ctx, s.root+storage.DeletedFolder+"/", "/")
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -storage.DeletedRetentionDays)
+	for _, folder := range folders {
+		name := path.Base(folder)
+		if len(name) <= len(storage.DeletedTimestampLayout) {
+			continue
+		}
+		deletedAt, err := time.ParseInLocation(storage.DeletedTimestampLayout,
+			name[len(name)-len(storage.DeletedTimestampLayout):], time.Local)
+		if err != nil || !deletedAt.Before(cutoff) {
+			continue
+		}
+
+		log.Printf("[S3] Permanently deleting old folder: %s", name)
+		objects, _, err := s.client.list(s.ctx, folder, "")
+		if err != nil {
+			log.Printf("[S3] Failed to list folder %s: %v", name, err)
+			continue
+		}
+		for _, obj := range objects {
+			if err := s.client.remove(s.ctx, obj.Key); err != nil {
+				log.Printf("[S3] Failed to delete %s: %v", obj.Key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ==================== CONFIG OPERATIONS ====================
+
+// GetSettings returns user settings from config
+func (s *Service) GetSettings() (models.UserSettings, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return models.UserSettings{}, err
+	}
+	return config.Settings, nil
+}
+
+// GetConfig reads config.json, creating it from existing context folders if missing
+func (s *Service) GetConfig() (*storage.Config, error) {
+	data, err := s.client.get(s.ctx, s.root+storage.ConfigFile)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return s.createDefaultConfig()
+		}
+		return nil, err
+	}
+
+	var config storage.Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// saveConfig writes config.json
+func (s *Service) saveConfig(config *storage.Config) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.client.put(s.ctx, s.root+storage.ConfigFile, data)
+}
+
+// createDefaultConfig writes a config listing the folders already under the user's prefix
+func (s *Service) createDefaultConfig() (*storage.Config, error) {
+	_, folders, err := s.client.list(s.ctx, s.root, "/")
+	if err != nil {
+		return nil, err
+	}
+
+	config := &storage.Config{
+		Contexts: []models.Context{},
+		Settings: storage.DefaultSettings(),
+	}
+	for _, folder := range folders {
+		name := path.Base(folder)
+		if name == storage.DeletedFolder {
+			continue
+		}
+		config.Contexts = append(config.Contexts, models.Context{
+			ID:     name,
+			UserID: s.userID,
+			Name:   name,
+			Color:  "primary",
+		})
+	}
+	sort.Slice(config.Contexts, func(i, j int) bool { return config.Contexts[i].Name < config.Contexts[j].Name })
+
+	if err := s.saveConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// moveFolder copies every object under the from prefix to the to prefix, then deletes the originals
+func (s *Service) moveFolder(from, to string) error {
+	objects, _, err := s.client.list(s.ctx, from, "")
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		if err := s.client.copy(s.ctx, obj.Key, to+strings.TrimPrefix(obj.Key, from)); err != nil {
+			return err
+		}
+	}
+	for _, obj := range objects {
+		if err := s.client.remove(s.ctx, obj.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// folder returns the key prefix of a context, rejecting names that would escape the user's prefix
+func (s *Service) folder(contextName string) (string, error) {
+	if contextName == "" || contextName == "." || contextName == ".." || strings.Contains(contextName, "/") {
+		return "", fmt.Errorf("invalid folder name %q", contextName)
+	}
+	return s.root + contextName + "/", nil
+}
+
+// noteKey returns the object key of a note
+func (s *Service) noteKey(contextName, date string) (string, error) {
+	folder, err := s.folder(contextName)
+	if err != nil {
+		return "", err
+	}
+	return folder + storage.NoteFilename(date), nil
+}
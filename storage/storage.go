@@ -0,0 +1,109 @@
+// Package storage defines the contract every cloud storage backend implements
+// and the file layout they share: a config.json at the root, one folder per
+// context holding one markdown file per note, a _DELETED folder for removed
+// contexts and, for providers that keep them, a _BACKUPS folder of archives.
+package storage
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/period"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// Provider names, as stored per user
+const (
+	Drive   = "drive"
+	Dropbox = "dropbox"
+	Local   = "local"
+	S3      = "s3"
+	WebDAV  = "webdav"
+)
+
+// Shared layout names
+const (
+	ConfigFile    = "config.json"
+	DeletedFolder = "_DELETED"
+	BackupsFolder = "_BACKUPS"
+)
+
+// DeletedTimestampLayout suffixes context folders moved to DeletedFolder ("Work_20251017_093000")
+const DeletedTimestampLayout = "20060102_150405"
+
+// DeletedRetentionDays is how long deleted context folders are kept before cleanup
+const DeletedRetentionDays = 10
+
+// Config represents the user's configuration stored alongside their notes
+type Config struct {
+	Contexts []models.Context    `json:"contexts"`
+	Settings models.UserSettings `json:"settings"`
+}
+
+// Provider is a storage backend holding a user's notes, contexts and settings
+// Instances are opened per operation for a single user.
+type Provider interface {
+	UpsertNote(contextName, date, content string) (*models.Note, error)
+	DeleteNote(contextName, date string) error
+	GetAllNotesInContext(contextName string) ([]models.Note, error)
+	GetContexts() ([]models.Context, error)
+	RenameContext(contextID, oldName, newName string) error
+	DeleteContext(contextID, contextName string) error
+	RestoreContext(ctx models.Context) error
+	GetSettings() (models.UserSettings, error)
+	GetConfig() (*Config, error)
+	// GetCurrentToken returns the sign-in token, refreshed if the provider used it
+	GetCurrentToken() (*oauth2.Token, error)
+	CleanupOldDeletedFolders() error
+}
+
+// NoteReader is implemented by providers that can read a single note
+// GetNote returns nil without error when the note has no file; UpdatedAt is the
+// file's modification time in storage, used by sync to detect edits made there.
+type NoteReader interface {
+	GetNote(contextName, date string) (*models.Note, error)
+}
+
+// StatusError is implemented by provider errors that carry the HTTP status storage answered with
+// Sync uses the status to tell quota, sign-in and payload problems from network failures.
+type StatusError interface {
+	error
+	HTTPStatus() int
+}
+
+// Factory opens a user's provider; token is the user's sign-in token
+type Factory func(ctx context.Context, token *oauth2.Token, userID string) (Provider, error)
+
+// DefaultSettings are the settings written to a new config
+func DefaultSettings() models.UserSettings {
+	return models.UserSettings{
+		Theme:      "dark",
+		WeekStart:  0,
+		Timezone:   "UTC",
+		DateFormat: "DD-MM-YY",
+	}
+}
+
+// NoteFilename returns the file name of a note key in the server's filename pattern
+// Days become DD-MM-YYYY.md, or YYYY-MM-DD.md with FilenameISO; week, month and
+// year keys are used as-is (2025-W42.md, 2025-10.md, 2025.md)
+func NoteFilename(date string) string {
+	return FilenameFor(date, FilenamePattern())
+}
+
+// NoteKey converts a note file name in either pattern (DD-MM-YYYY.md or YYYY-MM-DD.md) to its key
+// Week, month and year files map back to their key
+func NoteKey(filename string) (string, error) {
+	name := strings.TrimSuffix(filename, ".md")
+	if period.Kind(name) != "" {
+		return name, nil
+	}
+	parts := strings.Split(name, "-")
+	if len(parts) != 3 {
+		return "", errors.New("invalid filename format")
+	}
+	return fmt.Sprintf("%s-%s-%s", parts[2], parts[1], parts[0]), nil
+}
@@ -0,0 +1,226 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// errNotFound is returned for missing files and folders
+var errNotFound = errors.New("webdav: not found")
+
+// Client performs WebDAV calls below a base folder URL with basic auth
+// Paths passed to its methods are relative to the base folder and use "/" as separator.
+type Client struct {
+	http     *http.Client
+	base     *url.URL // Always ends with "/"
+	username string
+	password string
+}
+
+// StatusError is a failed WebDAV call
+type StatusError struct {
+	Method string
+	Path   string
+	Status int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webdav: %s %s: %d %s", e.Method, e.Path, e.Status, http.StatusText(e.Status))
+}
+
+// HTTPStatus returns the status code of the response, see storage.StatusError
+func (e *StatusError) HTTPStatus() int {
+	return e.Status
+}
+
+// entry is a file or folder returned by PROPFIND
+type entry struct {
+	Name     string
+	Folder   bool
+	Modified time.Time
+}
+
+// put writes a file, replacing any existing one
+func (c *Client) put(ctx context.Context, name string, body []byte) error {
+	return c.call(ctx, http.MethodPut, name, body, nil)
+}
+
+// get reads a file; missing files return errNotFound
+func (c *Client) get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// remove deletes a file or a folder with its contents; missing ones are ignored
+func (c *Client) remove(ctx context.Context, name string) error {
+	if err := c.call(ctx, http.MethodDelete, name, nil, nil); err != nil && err != errNotFound {
+		return err
+	}
+	return nil
+}
+
+// move renames a file or folder, failing if the destination exists
+func (c *Client) move(ctx context.Context, from, to string) error {
+	return c.call(ctx, "MOVE", from, nil, map[string]string{
+		"Destination": c.url(to),
+		"Overwrite":   "F",
+	})
+}
+
+// mkdirAll creates a folder and its missing parents
+func (c *Client) mkdirAll(ctx context.Context, name string) error {
+	name = strings.Trim(name, "/")
+	if name == "" || name == "." {
+		return nil
+	}
+
+	// MKCOL answers 405 when the folder exists and 409 when a parent is missing
+	err := c.call(ctx, "MKCOL", name+"/", nil, nil)
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &statusErr) && statusErr.Status == http.StatusMethodNotAllowed:
+		return nil
+	case errors.As(err, &statusErr) && statusErr.Status == http.StatusConflict:
+		if err := c.mkdirAll(ctx, path.Dir(name)); err != nil {
+			return err
+		}
+		return c.call(ctx, "MKCOL", name+"/", nil, nil)
+	default:
+		return err
+	}
+}
+
+// propfindBody asks for the only properties the provider uses
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getlastmodified/></d:prop></d:propfind>`
+
+// list returns the direct children of a folder; a missing folder returns errNotFound
+func (c *Client) list(ctx context.Context, folder string) ([]entry, error) {
+	folder = strings.Trim(folder, "/") + "/"
+	resp, err := c.do(ctx, "PROPFIND", folder, []byte(propfindBody), map[string]string{
+		"Depth":        "1",
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Responses []struct {
+			Href     string `xml:"href"`
+			Propstat []struct {
+				Prop struct {
+					ResourceType struct {
+						Collection *struct{} `xml:"collection"`
+					} `xml:"resourcetype"`
+					LastModified string `xml:"getlastmodified"`
+				} `xml:"prop"`
+			} `xml:"propstat"`
+		} `xml:"response"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("webdav: invalid PROPFIND response: %w", err)
+	}
+
+	self := strings.TrimSuffix(c.resolve(folder).Path, "/")
+	var entries []entry
+	for _, r := range result.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		hrefPath := strings.TrimSuffix(href.Path, "/")
+		if hrefPath == self || path.Dir(hrefPath) != self {
+			continue
+		}
+
+		e := entry{Name: path.Base(hrefPath)}
+		for _, ps := range r.Propstat {
+			if ps.Prop.ResourceType.Collection != nil {
+				e.Folder = true
+			}
+			if modified, err := http.ParseTime(ps.Prop.LastModified); err == nil {
+				e.Modified = modified
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// call sends a request and discards the response body
+func (c *Client) call(ctx context.Context, method, name string, body []byte, headers map[string]string) error {
+	resp, err := c.do(ctx, method, name, body, headers)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
+
+// do sends an authenticated request for name
+// Non-2xx responses become StatusErrors, 404s errNotFound.
+func (c *Client) do(ctx context.Context, method, name string, body []byte, headers map[string]string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url(name), reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	return nil, &StatusError{Method: method, Path: name, Status: resp.StatusCode}
+}
+
+// url returns the absolute URL of name
+func (c *Client) url(name string) string {
+	return c.resolve(name).String()
+}
+
+// resolve joins name onto the base folder, escaping each path segment
+func (c *Client) resolve(name string) *url.URL {
+	segments := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	// "./" keeps names containing ":" from parsing as a URL scheme
+	ref, _ := url.Parse("./" + strings.Join(segments, "/"))
+	return c.base.ResolveReference(ref)
+}
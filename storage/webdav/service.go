@@ -0,0 +1,455 @@
+// Package webdav stores notes on a WebDAV server such as Nextcloud or ownCloud,
+// using the same layout as Google Drive in a folder per user below WEBDAV_URL:
+// config.json, a folder per context and a DD-MM-YYYY.md file per note.
+package webdav
+
+import (
+	"context"
+	"daily-notes/config"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Enabled reports whether a WebDAV server is configured
+func Enabled() bool {
+	return config.AppConfig != nil && config.AppConfig.WebDAVURL != ""
+}
+
+// NewClient creates a client for the configured server folder
+// For Nextcloud the URL looks like https://cloud.example.com/remote.php/dav/files/<user>/DailyNotes.
+func NewClient() (*Client, error) {
+	if !Enabled() {
+		return nil, errors.New("webdav storage is not configured")
+	}
+	cfg := config.AppConfig
+
+	base, err := url.Parse(cfg.WebDAVURL)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid WEBDAV_URL %q", cfg.WebDAVURL)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	return &Client{
+		http:     &http.Client{Timeout: time.Minute},
+		base:     base,
+		username: cfg.WebDAVUsername,
+		password: cfg.WebDAVPassword,
+	}, nil
+}
+
+// Service implements storage.Provider on a user's folder of a WebDAV server
+type Service struct {
+	ctx          context.Context
+	client       *Client
+	root         string // "<user id>/"
+	userID       string
+	sessionToken *oauth2.Token
+}
+
+// Ensure Service implements storage.Provider, storage.NoteFileRenamer and storage.ContextConfigWriter
+var (
+	_ storage.Provider            = (*Service)(nil)
+	_ storage.NoteFileRenamer     = (*Service)(nil)
+	_ storage.ContextConfigWriter = (*Service)(nil)
+)
+
+// NewService opens a user's folder on the server, creating it if needed
+// sessionToken is the user's sign-in token; it is returned unchanged by GetCurrentToken.
+func NewService(ctx context.Context, client *Client, sessionToken *oauth2.Token, userID string) (*Service, error) {
+	if err := checkName(userID); err != nil {
+		return nil, err
+	}
+
+	root := userID + "/"
+	if err := client.mkdirAll(ctx, root); err != nil {
+		return nil, fmt.Errorf("failed to create storage folder: %w", err)
+	}
+
+	return &Service{ctx: ctx, client: client, root: root, userID: userID, sessionToken: sessionToken}, nil
+}
+
+// GetCurrentToken returns the sign-in token the service was opened with
+func (s *Service) GetCurrentToken() (*oauth2.Token, error) {
+	return s.sessionToken, nil
+}
+
+// ==================== NOTE OPERATIONS ====================
+
+// UpsertNote creates or overwrites a note file
+func (s *Service) UpsertNote(contextName, date, content string) (*models.Note, error) {
+	folder, err := s.folder(contextName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.mkdirAll(s.ctx, folder); err != nil {
+		return nil, err
+	}
+	if err := s.client.put(s.ctx, folder+storage.NoteFilename(date), []byte(content)); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &models.Note{
+		ID:        noteID(contextName, date),
+		UserID:    s.userID,
+		Context:   contextName,
+		Date:      date,
+		Content:   content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// DeleteNote removes a note file; missing files are ignored
+func (s *Service) DeleteNote(contextName, date string) error {
+	folder, err := s.folder(contextName)
+	if err != nil {
+		return err
+	}
+	return s.client.remove(s.ctx, folder+storage.NoteFilename(date))
+}
+
+// GetAllNotesInContext downloads every note in a context folder
+func (s *Service) GetAllNotesInContext(contextName string) ([]models.Note, error) {
+	folder, err := s.folder(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.client.list(s.ctx, folder)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var notes []models.Note
+	for _, e := range entries {
+		if e.Folder {
+			continue
+		}
+		date, err := storage.NoteKey(e.Name)
+		if err != nil {
+			continue
+		}
+
+		content, err := s.client.get(s.ctx, folder+e.Name)
+		if err != nil {
+			continue
+		}
+
+		notes = append(notes, models.Note{
+			ID:        noteID(contextName, date),
+			UserID:    s.userID,
+			Context:   contextName,
+			Date:      date,
+			Content:   string(content),
+			CreatedAt: e.Modified,
+			UpdatedAt: e.Modified,
+		})
+	}
+
+	return notes, nil
+}
+
+// ListNoteFiles returns the note files of a context folder
+func (s *Service) ListNoteFiles(contextName string) ([]storage.NoteFile, error) {
+	folder, err := s.folder(contextName)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.client.list(s.ctx, folder)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []storage.NoteFile
+	for _, e := range entries {
+		if !e.Folder && strings.HasSuffix(e.Name, ".md") {
+			files = append(files, storage.NoteFile{ID: contextName + "/" + e.Name, Name: e.Name})
+		}
+	}
+	return files, nil
+}
+
+// RenameNoteFile renames a note file within its context folder; MOVE refuses to overwrite
+func (s *Service) RenameNoteFile(contextName string, file storage.NoteFile, newName string) (storage.NoteFile, error) {
+	folder, err := s.folder(contextName)
+	if err != nil {
+		return storage.NoteFile{}, err
+	}
+	if err := checkName(file.Name); err != nil {
+		return storage.NoteFile{}, err
+	}
+	if err := checkName(newName); err != nil {
+		return storage.NoteFile{}, err
+	}
+
+	if err := s.client.move(s.ctx, folder+file.Name, folder+newName); err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.Status == http.StatusPreconditionFailed {
+			return storage.NoteFile{}, fmt.Errorf("%w: %s exists", storage.ErrFilenameConflict, newName)
+		}
+		return storage.NoteFile{}, err
+	}
+	return storage.NoteFile{ID: contextName + "/" + newName, Name: newName}, nil
+}
+
+// ==================== CONTEXT OPERATIONS ====================
+
+// GetContexts returns the contexts listed in config.json
+func (s *Service) GetContexts() ([]models.Context, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return config.Contexts, nil
+}
+
+// RenameContext renames a context folder and its config entry
+func (s *Service) RenameContext(contextID, oldName, newName string) error {
+	from, err := s.folder(oldName)
+	if err != nil {
+		return err
+	}
+	to, err := s.folder(newName)
+	if err != nil {
+		return err
+	}
+	if err := s.client.move(s.ctx, from, to); err != nil && err != errNotFound {
+		return fmt.Errorf("failed to rename folder: %w", err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	for i, c := range config.Contexts {
+		if c.ID == contextID {
+			config.Contexts[i].Name = newName
+		}
+	}
+	return s.saveConfig(config)
+}
+
+// DeleteContext moves a context folder to _DELETED and removes it from config
+func (s *Service) DeleteContext(contextID, contextName string) error {
+	from, err := s.folder(contextName)
+	if err != nil {
+		return err
+	}
+
+	trash := s.root + storage.DeletedFolder + "/"
+	if err := s.client.mkdirAll(s.ctx, trash); err != nil {
+		return err
+	}
+	to := trash + fmt.Sprintf("%s_%s", contextName, time.Now().Format(storage.DeletedTimestampLayout)) + "/"
+	if err := s.client.move(s.ctx, from, to); err != nil && err != errNotFound {
+		return fmt.Errorf("failed to move folder to %s: %w", storage.DeletedFolder, err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	contexts := []models.Context{}
+	for _, c := range config.Contexts {
+		if c.ID != contextID {
+			contexts = append(contexts, c)
+		}
+	}
+	config.Contexts = contexts
+	return s.saveConfig(config)
+}
+
+// RestoreContext moves the most recently deleted folder of a context back and re-adds it to config
+func (s *Service) RestoreContext(ctx models.Context) error {
+	to, err := s.folder(ctx.Name)
+	if err != nil {
+		return err
+	}
+
+	trash := s.root + storage.DeletedFolder + "/"
+	entries, err := s.client.list(s.ctx, trash)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return errors.New("no deleted contexts found")
+		}
+		return err
+	}
+
+	// Timestamps sort lexically, so the greatest name is the latest deletion
+	var match string
+	for _, e := range entries {
+		if e.Folder && strings.HasPrefix(e.Name, ctx.Name+"_") && e.Name > match {
+			match = e.Name
+		}
+	}
+	if match == "" {
+		return fmt.Errorf("deleted folder for context %q not found", ctx.Name)
+	}
+
+	if err := s.client.move(s.ctx, trash+match+"/", to); err != nil {
+		return fmt.Errorf("failed to move folder out of %s: %w", storage.DeletedFolder, err)
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	for _, existing := range config.Contexts {
+		if existing.ID == ctx.ID {
+			return nil
+		}
+	}
+	config.Contexts = append(config.Contexts, ctx)
+	return s.saveConfig(config)
+}
+
+// UpdateContextConfig writes the color and icon of a context to config.json
+func (s *Service) UpdateContextConfig(c models.Context) error {
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	storage.SetConfigContext(config, c)
+	return s.saveConfig(config)
+}
+
+// CleanupOldDeletedFolders permanently removes context folders deleted more than
+// storage.DeletedRetentionDays ago, using the timestamp in their name
+func (s *Service) CleanupOldDeletedFolders() error {
+	trash := s.root + storage.DeletedFolder + "/"
+	entries, err := s.client.list(s.ctx, trash)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -storage.DeletedRetentionDays)
+	for _, e := range entries {
+		if !e.Folder || len(e.Name) <= len(storage.DeletedTimestampLayout) {
+			continue
+		}
+		deletedAt, err := time.ParseInLocation(storage.DeletedTimestampLayout,
+			e.Name[len(e.Name)-len(storage.DeletedTimestampLayout):], time.Local)
+		if err != nil || !deletedAt.Before(cutoff) {
+			continue
+		}
+
+		log.Printf("[WebDAV] Permanently deleting old folder: %s", e.Name)
+		if err := s.client.remove(s.ctx, trash+e.Name+"/"); err != nil {
+			log.Printf("[WebDAV] Failed to delete folder %s: %v", e.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ==================== CONFIG OPERATIONS ====================
+
+// GetSettings returns user settings from config
+func (s *Service) GetSettings() (models.UserSettings, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return models.UserSettings{}, err
+	}
+	return config.Settings, nil
+}
+
+// GetConfig reads config.json, creating it from existing context folders if missing
+func (s *Service) GetConfig() (*storage.Config, error) {
+	data, err := s.client.get(s.ctx, s.root+storage.ConfigFile)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return s.createDefaultConfig()
+		}
+		return nil, err
+	}
+
+	var config storage.Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// saveConfig writes config.json
+func (s *Service) saveConfig(config *storage.Config) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.client.put(s.ctx, s.root+storage.ConfigFile, data)
+}
+
+// createDefaultConfig writes a config listing the folders already in the user's folder
+func (s *Service) createDefaultConfig() (*storage.Config, error) {
+	entries, err := s.client.list(s.ctx, s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &storage.Config{
+		Contexts: []models.Context{},
+		Settings: storage.DefaultSettings(),
+	}
+	for _, e := range entries {
+		if !e.Folder || e.Name == storage.DeletedFolder || strings.HasPrefix(e.Name, ".") {
+			continue
+		}
+		config.Contexts = append(config.Contexts, models.Context{
+			ID:     e.Name,
+			UserID: s.userID,
+			Name:   e.Name,
+			Color:  "primary",
+		})
+	}
+	sort.Slice(config.Contexts, func(i, j int) bool { return config.Contexts[i].Name < config.Contexts[j].Name })
+
+	if err := s.saveConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// folder returns the path of a context folder, rejecting names that would leave the user's folder
+func (s *Service) folder(contextName string) (string, error) {
+	if err := checkName(contextName); err != nil {
+		return "", err
+	}
+	return s.root + contextName + "/", nil
+}
+
+// checkName rejects empty names, "." and "..", and names containing path separators
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid folder name %q", name)
+	}
+	return nil
+}
+
+// noteID identifies a note file by its path relative to the user's folder
+func noteID(contextName, date string) string {
+	return contextName + "/" + storage.NoteFilename(date)
+}
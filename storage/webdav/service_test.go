@@ -0,0 +1,126 @@
+package webdav
+
+import (
+	"context"
+	"daily-notes/storage"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+)
+
+func newTestService(t *testing.T) (*Service, *Client) {
+	t.Helper()
+
+	handler := &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	base, err := url.Parse(server.URL + "/dav/Daily Notes/")
+	require.NoError(t, err)
+	client := &Client{http: server.Client(), base: base, username: "alice", password: "secret"}
+	require.NoError(t, handler.FileSystem.Mkdir(context.Background(), "/Daily Notes", 0o755))
+
+	service, err := NewService(context.Background(), client, nil, "user123")
+	require.NoError(t, err)
+	return service, client
+}
+
+func TestService_Notes(t *testing.T) {
+	service, client := newTestService(t)
+	ctx := context.Background()
+
+	_, err := service.UpsertNote("Work: Q4", "2025-10-17", "# Friday")
+	require.NoError(t, err)
+	_, err = service.UpsertNote("Work: Q4", "2025-W42", "week plan")
+	require.NoError(t, err)
+
+	data, err := client.get(ctx, "user123/Work: Q4/17-10-2025.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# Friday", string(data))
+
+	notes, err := service.GetAllNotesInContext("Work: Q4")
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	byDate := map[string]string{}
+	for _, note := range notes {
+		byDate[note.Date] = note.Content
+		assert.False(t, note.UpdatedAt.IsZero())
+	}
+	assert.Equal(t, map[string]string{"2025-10-17": "# Friday", "2025-W42": "week plan"}, byDate)
+
+	require.NoError(t, service.DeleteNote("Work: Q4", "2025-10-17"))
+	require.NoError(t, service.DeleteNote("Work: Q4", "2025-10-17"), "deleting a missing note is not an error")
+	notes, err = service.GetAllNotesInContext("Work: Q4")
+	require.NoError(t, err)
+	assert.Len(t, notes, 1)
+
+	notes, err = service.GetAllNotesInContext("Empty")
+	require.NoError(t, err)
+	assert.Empty(t, notes)
+
+	_, err = service.UpsertNote("..", "2025-10-17", "escape")
+	assert.Error(t, err)
+}
+
+func TestService_Contexts(t *testing.T) {
+	service, client := newTestService(t)
+	ctx := context.Background()
+
+	_, err := service.UpsertNote("Work", "2025-10-17", "note")
+	require.NoError(t, err)
+
+	config, err := service.GetConfig()
+	require.NoError(t, err)
+	require.Len(t, config.Contexts, 1)
+	assert.Equal(t, "Work", config.Contexts[0].Name)
+	assert.Equal(t, storage.DefaultSettings(), config.Settings)
+
+	work := config.Contexts[0]
+	require.NoError(t, service.RenameContext(work.ID, "Work", "Job"))
+	_, err = client.get(ctx, "user123/Job/17-10-2025.md")
+	require.NoError(t, err)
+	work.Name = "Job"
+
+	require.NoError(t, service.DeleteContext(work.ID, "Job"))
+	contexts, err := service.GetContexts()
+	require.NoError(t, err)
+	assert.Empty(t, contexts)
+	_, err = client.get(ctx, "user123/Job/17-10-2025.md")
+	assert.Equal(t, errNotFound, err)
+
+	require.NoError(t, service.CleanupOldDeletedFolders(), "recent deletions are kept")
+	require.NoError(t, service.RestoreContext(work))
+	data, err := client.get(ctx, "user123/Job/17-10-2025.md")
+	require.NoError(t, err)
+	assert.Equal(t, "note", string(data))
+
+	contexts, err = service.GetContexts()
+	require.NoError(t, err)
+	require.Len(t, contexts, 1)
+	assert.Equal(t, "Job", contexts[0].Name)
+}
+
+func TestClient_Unauthorized(t *testing.T) {
+	service, client := newTestService(t)
+	client.password = "wrong"
+
+	_, err := service.GetContexts()
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusUnauthorized, statusErr.Status)
+}
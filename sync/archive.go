@@ -0,0 +1,208 @@
+package sync
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"daily-notes/database"
+	"daily-notes/pkg/visibility"
+	"daily-notes/storage"
+	"errors"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ==================== NOTE ARCHIVES ====================
+
+const (
+	// archivePrefix and archiveSuffix surround the UTC time in archive names
+	archivePrefix = "notes-"
+	archiveSuffix = ".tar.gz"
+	archiveLayout = "20060102T150405Z"
+	// archiveSchema is the file of an archive holding the database schema
+	archiveSchema = "schema.sql"
+)
+
+// ErrArchiveUnsupported is returned by Archive for storage providers that keep no archives
+var ErrArchiveUnsupported = errors.New("storage provider does not keep note archives")
+
+// SetArchiveInterval sets how often a compressed archive of each user's notes is
+// uploaded to storage, and how many archives are kept; an interval of 0 turns it off
+// Takes effect on Start.
+func (w *Worker) SetArchiveInterval(d time.Duration, retain int) {
+	w.archiveInterval = d
+	w.archivesKept = retain
+}
+
+// Archive uploads a tar.gz of all of the user's notes and the database schema to
+// the backups folder of their storage, then deletes the oldest archives beyond the
+// retention. Unlike the per-file sync, an archive is one consistent snapshot, which
+// survives note files lost or corrupted in storage. Nothing is uploaded when no note
+// changed since the newest archive. Returns the name of the uploaded archive, "" if
+// there was none.
+func (w *Worker) Archive(userID string) (string, error) {
+	token, err := w.getUserToken(userID)
+	if err != nil {
+		return "", err
+	}
+	provider, err := w.storageFactory(w.ctx, token, userID)
+	if err != nil {
+		return "", err
+	}
+	archiver, ok := provider.(storage.NoteArchiver)
+	if !ok {
+		return "", ErrArchiveUnsupported
+	}
+	defer w.updateTokenIfRefreshed(provider, token, userID, "Sync Archive")
+
+	archives, err := archiver.ListArchives()
+	if err != nil {
+		return "", err
+	}
+	archives = filterArchives(archives)
+	changedAt, err := w.repo.GetLastNoteChange(w.ctx, database.ScopeUser(userID))
+	if err != nil {
+		return "", err
+	}
+	if len(archives) > 0 {
+		newest, _ := parseArchiveName(archives[len(archives)-1])
+		if !changedAt.After(newest) {
+			return "", nil
+		}
+	}
+
+	content, err := w.buildArchive(userID)
+	if err != nil {
+		return "", err
+	}
+	name := archivePrefix + w.clock.Now().UTC().Format(archiveLayout) + archiveSuffix
+	if err := archiver.UploadArchive(name, content); err != nil {
+		return "", err
+	}
+	archives = append(archives, name)
+
+	for len(archives) > w.archivesKept && w.archivesKept > 0 {
+		if err := archiver.DeleteArchive(archives[0]); err != nil {
+			log.Printf("[Sync Archive] Failed to delete archive %s of user %s: %v", archives[0], userID, err)
+		}
+		archives = archives[1:]
+	}
+	return name, nil
+}
+
+// buildArchive writes the user's notes, one file per note in a folder per context
+// as storage keeps them, and the database schema into a tar.gz. Only notes that
+// may leave the server are in it, see visibility.Storage.
+func (w *Worker) buildArchive(userID string) (*bytes.Buffer, error) {
+	notes, err := w.repo.GetVisibleNotes(w.ctx, database.ScopeUser(userID), visibility.Storage)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := w.repo.DumpSchema(w.ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].Context != notes[j].Context {
+			return notes[i].Context < notes[j].Context
+		}
+		return notes[i].Date < notes[j].Date
+	})
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := writeArchiveFile(tw, archiveSchema, schema, w.clock.Now()); err != nil {
+		return nil, err
+	}
+	pattern := storage.FilenamePattern()
+	for _, note := range notes {
+		name := path.Join(note.Context, storage.FilenameFor(note.Date, pattern))
+		if err := writeArchiveFile(tw, name, note.Content, note.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// writeArchiveFile adds a file to a tar archive
+func writeArchiveFile(tw *tar.Writer, name, content string, modTime time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write([]byte(content))
+	return err
+}
+
+// filterArchives keeps the names of archives made by Archive, oldest first
+func filterArchives(names []string) []string {
+	var archives []string
+	for _, name := range names {
+		if _, ok := parseArchiveName(name); ok {
+			archives = append(archives, name)
+		}
+	}
+	sort.Strings(archives)
+	return archives
+}
+
+// parseArchiveName returns the time in an archive's name
+func parseArchiveName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, archivePrefix) || !strings.HasSuffix(name, archiveSuffix) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(archiveLayout, strings.TrimSuffix(strings.TrimPrefix(name, archivePrefix), archiveSuffix))
+	return t, err == nil
+}
+
+// runArchive archives the notes of every signed-in user once per archiveInterval
+func (w *Worker) runArchive() {
+	ticker := time.NewTicker(w.archiveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.archiveAll()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// archiveAll archives the notes of each signed-in user with synced notes
+func (w *Worker) archiveAll() {
+	users, err := w.repo.GetUsersToPull(w.ctx)
+	if err != nil {
+		log.Printf("[Sync Archive] Failed to get users to archive: %v", err)
+		return
+	}
+
+	for _, userID := range users {
+		name, err := w.Archive(userID)
+		if errors.Is(err, ErrArchiveUnsupported) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[Sync Archive] Failed to archive notes of user %s: %v", userID, err)
+			continue
+		}
+		if name != "" {
+			log.Printf("[Sync Archive] Uploaded archive %s of user %s", name, userID)
+		}
+	}
+}
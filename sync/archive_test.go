@@ -0,0 +1,127 @@
+package sync
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// fakeArchiver is a fakeDrive that also keeps note archives
+type fakeArchiver struct {
+	fakeDrive
+	archives map[string][]byte
+}
+
+func (a *fakeArchiver) UploadArchive(name string, content io.Reader) error {
+	data, err := io.ReadAll(content)
+	a.archives[name] = data
+	return err
+}
+
+func (a *fakeArchiver) ListArchives() ([]string, error) {
+	var names []string
+	for name := range a.archives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (a *fakeArchiver) DeleteArchive(name string) error {
+	delete(a.archives, name)
+	return nil
+}
+
+// readArchive returns the files in a tar.gz by name
+func readArchive(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 10, 17, 3, 0, 0, 0, time.UTC)
+	fakeClock := clock.NewFake(start)
+	w, repo := newImportWorker(t, nil)
+	w.SetClock(fakeClock)
+	w.SetArchiveInterval(7*24*time.Hour, 2)
+
+	t.Run("Providers without archives", func(t *testing.T) {
+		_, err := w.Archive("test-user")
+		assert.ErrorIs(t, err, ErrArchiveUnsupported)
+	})
+
+	archiver := &fakeArchiver{fakeDrive: fakeDrive{files: map[string]models.Note{}}, archives: map[string][]byte{}}
+	w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return archiver, nil
+	}
+	save := func(contextName, date, content string) {
+		t.Helper()
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: contextName, Date: date, Content: content,
+			CreatedAt: fakeClock.Now(), UpdatedAt: fakeClock.Now(),
+		}, true))
+	}
+	save("Work", "2025-10-16", "standup")
+	save("Personal", "2025-10-17", "groceries")
+
+	t.Run("Notes and schema are archived", func(t *testing.T) {
+		fakeClock.Advance(time.Hour)
+		name, err := w.Archive("test-user")
+		require.NoError(t, err)
+		assert.Equal(t, "notes-20251017T040000Z.tar.gz", name)
+
+		files := readArchive(t, archiver.archives[name])
+		assert.Equal(t, "standup", files["Work/16-10-2025.md"])
+		assert.Equal(t, "groceries", files["Personal/17-10-2025.md"])
+		assert.Contains(t, files["schema.sql"], "CREATE TABLE notes")
+	})
+
+	t.Run("Unchanged notes are not archived again", func(t *testing.T) {
+		fakeClock.Advance(7 * 24 * time.Hour)
+		name, err := w.Archive("test-user")
+		require.NoError(t, err)
+		assert.Empty(t, name)
+		assert.Len(t, archiver.archives, 1)
+	})
+
+	t.Run("The oldest archives are rotated out", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			fakeClock.Advance(7 * 24 * time.Hour)
+			save("Work", "2025-10-16", "standup, edited")
+			name, err := w.Archive("test-user")
+			require.NoError(t, err)
+			require.NotEmpty(t, name)
+		}
+
+		names, err := archiver.ListArchives()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"notes-20251031T040000Z.tar.gz", "notes-20251107T040000Z.tar.gz"}, names)
+		assert.Equal(t, "standup, edited", readArchive(t, archiver.archives[names[1]])["Work/16-10-2025.md"])
+	})
+}
@@ -0,0 +1,138 @@
+//go:build chaos
+
+package sync
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/storage"
+	"daily-notes/storage/chaos"
+	"daily-notes/storage/local"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// Runs with go test -tags chaos ./sync: notes are edited, created and deleted
+// while a third of the storage calls fail, and sync must still end with storage
+// holding exactly the notes of the database. Set seeds replay the same run.
+func TestSyncConvergesUnderChaos(t *testing.T) {
+	for seed := uint64(1); seed <= 10; seed++ {
+		t.Run(fmt.Sprintf("Seed %d", seed), func(t *testing.T) {
+			runChaos(t, seed)
+		})
+	}
+}
+
+func runChaos(t *testing.T, seed uint64) {
+	ctx := context.Background()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.Migrate())
+	t.Cleanup(func() { db.Close() })
+	repo := database.NewRepository(db)
+	require.NoError(t, repo.UpsertUser(ctx, &models.User{ID: "test-user", GoogleID: "google-123", Email: "test@example.com", CreatedAt: time.Now()}))
+
+	dir := t.TempDir()
+	injector := chaos.New(chaos.Config{Seed: seed, RateLimit: 0.1, Timeout: 0.15, TokenExpiry: 0.05})
+	open := injector.Wrap(func(ctx context.Context, token *oauth2.Token, userID string) (storage.Provider, error) {
+		return local.NewService(dir, token, userID)
+	})
+	factory := func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return open(ctx, token, userID)
+	}
+	getUserToken := func(userID string) (*oauth2.Token, error) {
+		return &oauth2.Token{AccessToken: "token"}, nil
+	}
+	w := NewWorker(repo, nil, factory, getUserToken)
+	fake := clock.NewFake(time.Now())
+	w.SetClock(fake)
+
+	// The user's edits are random too, but from their own seed
+	edits := rand.New(rand.NewPCG(seed, 0))
+	contexts := []string{"Work", "Journal"}
+	save := func(contextName, date, content string) {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{
+			UserID: "test-user", Context: contextName, Date: date, Content: content,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}, true))
+	}
+	for i := 1; i <= 20; i++ {
+		save(contexts[i%2], fmt.Sprintf("2025-10-%02d", i), fmt.Sprintf("note %d", i))
+	}
+
+	const editRounds, maxRounds = 20, 200
+	round := 0
+	for ; round < maxRounds; round++ {
+		if round < editRounds {
+			contextName := contexts[edits.IntN(2)]
+			date := fmt.Sprintf("2025-10-%02d", 1+edits.IntN(28))
+			if edits.IntN(4) == 0 {
+				require.NoError(t, repo.DeleteNote(ctx, database.ScopeUser("test-user"), contextName, date))
+			} else {
+				save(contextName, date, fmt.Sprintf("edit %d", round))
+			}
+		}
+
+		// Backoff is scheduled on the worker's clock but due by the database's, so
+		// the time that passes between runs is both
+		fake.Advance(time.Minute)
+		_, err := db.Exec(`UPDATE notes SET next_retry_at = NULL WHERE sync_pending = 1`)
+		require.NoError(t, err)
+
+		w.syncPendingNotes()
+
+		var pending int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM notes WHERE sync_pending = 1`).Scan(&pending))
+		if round < editRounds || pending > 0 {
+			continue
+		}
+		// Notes abandoned after too many failures wait for the user to retry them
+		retried, err := repo.RetryUserSyncNotes(ctx, "test-user")
+		require.NoError(t, err)
+		if retried == 0 {
+			break
+		}
+	}
+	require.Less(t, round, maxRounds, "sync didn't converge")
+
+	counts := injector.Counts()
+	for _, fault := range []chaos.Fault{chaos.RateLimit, chaos.Timeout, chaos.TokenExpiry} {
+		assert.Positive(t, counts[fault], "no %s was injected", fault)
+	}
+
+	var unsynced, deleted int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM notes WHERE sync_status != ?`, string(models.SyncStatusSynced)).Scan(&unsynced))
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM notes WHERE deleted = 1`).Scan(&deleted))
+	assert.Zero(t, unsynced, "notes were left failed or abandoned")
+	assert.Zero(t, deleted, "deletions never reached storage")
+
+	notes, err := repo.GetAllNotesByUser(ctx, database.ScopeUser("test-user"))
+	require.NoError(t, err)
+	expected := map[string]string{}
+	for _, note := range notes {
+		expected[note.Context+"/"+note.Date] = note.Content
+	}
+
+	provider, err := local.NewService(dir, nil, "test-user")
+	require.NoError(t, err)
+	stored := map[string]string{}
+	for _, contextName := range contexts {
+		files, err := provider.GetAllNotesInContext(contextName)
+		require.NoError(t, err)
+		for _, file := range files {
+			key := file.Context + "/" + file.Date
+			assert.NotContains(t, stored, key, "note stored twice")
+			stored[key] = file.Content
+		}
+	}
+	assert.Equal(t, expected, stored)
+}
@@ -0,0 +1,45 @@
+package sync
+
+import (
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"errors"
+	"time"
+)
+
+// ==================== CONFLICT DETECTION ====================
+
+// conflictClockSkew is how much later than synced_at a file's modification time
+// must be to count as an edit made in storage. Storage time-stamps our own uploads
+// with its clock, so this absorbs the difference to the server's clock.
+const conflictClockSkew = 5 * time.Second
+
+// errSyncConflict reports that a note was not uploaded because it changed in storage
+var errSyncConflict = errors.New("note changed in storage since the last sync")
+
+// detectConflict compares a note about to be uploaded with its file in storage.
+// If the note synced before and the file was modified after that sync and holds
+// different content, both versions are kept, the note is marked as conflicted and
+// errSyncConflict is returned. Notes that never synced and providers that can't
+// read single notes are not checked.
+func (w *Worker) detectConflict(provider StorageService, note *database.NoteWithMeta) error {
+	reader, ok := provider.(storage.NoteReader)
+	if !ok || note.SyncedAt == nil {
+		return nil
+	}
+
+	remote, err := reader.GetNote(note.Context, note.Date)
+	if err != nil || remote == nil {
+		return err
+	}
+	if remote.Content == note.Content || !remote.UpdatedAt.After(note.SyncedAt.Add(conflictClockSkew)) {
+		return nil
+	}
+
+	if err := w.repo.MarkNoteConflict(w.ctx, note.ID, remote.Content, remote.UpdatedAt); err != nil {
+		return err
+	}
+	w.publishNote(note, models.SyncStatusConflict)
+	return errSyncConflict
+}
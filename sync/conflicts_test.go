@@ -0,0 +1,151 @@
+package sync
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// fakeDrive stores single notes and reports their modification times, upload
+// hashes and changes, like Drive
+type fakeDrive struct {
+	files   map[string]models.Note
+	hashes  map[string]string
+	changes []models.Note
+	watched []models.ChangeChannel
+	stopped []string
+	uploads int
+}
+
+func (d *fakeDrive) GetNote(contextName, date string) (*models.Note, error) {
+	if note, ok := d.files[contextName+"/"+date]; ok {
+		return &note, nil
+	}
+	return nil, nil
+}
+
+func (d *fakeDrive) UpsertNote(contextName, date, content string) (*models.Note, error) {
+	d.uploads++
+	note := models.Note{ID: "file-" + date, Context: contextName, Date: date, Content: content, UpdatedAt: time.Now()}
+	d.files[contextName+"/"+date] = note
+	if d.hashes == nil {
+		d.hashes = map[string]string{}
+	}
+	d.hashes[contextName+"/"+date] = storage.ContentHash(content)
+	d.changes = append(d.changes, note)
+	return &note, nil
+}
+
+func (d *fakeDrive) NoteChangesStartToken() (string, error) {
+	return strconv.Itoa(len(d.changes)), nil
+}
+
+func (d *fakeDrive) ListNoteChanges(pageToken string) ([]models.Note, string, error) {
+	start, err := strconv.Atoi(pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	return d.changes[start:], strconv.Itoa(len(d.changes)), nil
+}
+
+func (d *fakeDrive) WatchNoteChanges(pageToken, address string, channel *models.ChangeChannel) error {
+	channel.ResourceID = "changes"
+	channel.Expiration = time.Now().Add(7 * 24 * time.Hour)
+	d.watched = append(d.watched, *channel)
+	return nil
+}
+
+func (d *fakeDrive) StopWatching(channel models.ChangeChannel) error {
+	d.stopped = append(d.stopped, channel.ID)
+	return nil
+}
+
+func (d *fakeDrive) GetHashedNotes(contextName string) ([]storage.HashedNote, error) {
+	var notes []storage.HashedNote
+	for key, note := range d.files {
+		if note.Context == contextName {
+			notes = append(notes, storage.HashedNote{Note: note, Hash: d.hashes[key]})
+		}
+	}
+	return notes, nil
+}
+
+func (d *fakeDrive) DeleteNote(contextName, date string) error { return nil }
+
+func (d *fakeDrive) GetAllNotesInContext(contextName string) ([]models.Note, error) { return nil, nil }
+
+func (d *fakeDrive) GetConfig() (*storage.Config, error) { return &storage.Config{}, nil }
+
+func (d *fakeDrive) GetCurrentToken() (*oauth2.Token, error) { return nil, nil }
+
+func TestSyncConflicts(t *testing.T) {
+	ctx := context.Background()
+	drive := &fakeDrive{files: map[string]models.Note{}}
+	w, repo := newImportWorker(t, nil)
+	w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return drive, nil
+	}
+
+	// save writes a note locally and runs one sync of the pending notes
+	save := func(date, content string) *syncResult {
+		t.Helper()
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: date, Content: content}, true))
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		return w.syncNotesWithDrive("test-user", pending, "Test")
+	}
+	editInDrive := func(date, content string, modifiedAt time.Time) {
+		drive.files["Work/"+date] = models.Note{Context: "Work", Date: date, Content: content, UpdatedAt: modifiedAt}
+	}
+
+	t.Run("Local edits of unchanged files upload", func(t *testing.T) {
+		assert.Equal(t, 1, save("2025-10-16", "v1").syncedCount)
+		assert.Equal(t, 1, save("2025-10-16", "v2").syncedCount)
+		assert.Equal(t, "v2", drive.files["Work/2025-10-16"].Content)
+	})
+
+	t.Run("Files edited in Drive since the sync are kept", func(t *testing.T) {
+		editInDrive("2025-10-16", "phone edit", time.Now().Add(time.Minute))
+
+		result := save("2025-10-16", "v3")
+		assert.Equal(t, 1, result.conflicts)
+		assert.Zero(t, result.syncedCount)
+		assert.Equal(t, "phone edit", drive.files["Work/2025-10-16"].Content)
+
+		conflict, err := repo.GetNoteConflict(ctx, "test-user", "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, conflict)
+		assert.Equal(t, "v3", conflict.LocalContent)
+		assert.Equal(t, "phone edit", conflict.RemoteContent)
+	})
+
+	t.Run("Resolved notes upload", func(t *testing.T) {
+		resolved, err := repo.ResolveNoteConflict(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "v3 + phone edit"})
+		require.NoError(t, err)
+		require.True(t, resolved)
+
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, w.syncNotesWithDrive("test-user", pending, "Test").syncedCount)
+		assert.Equal(t, "v3 + phone edit", drive.files["Work/2025-10-16"].Content)
+	})
+
+	t.Run("Notes that never synced overwrite existing files", func(t *testing.T) {
+		editInDrive("2025-10-17", "created on the phone", time.Now().Add(time.Minute))
+		assert.Equal(t, 1, save("2025-10-17", "created here").syncedCount)
+	})
+}
+
+var (
+	_ storage.NoteReader        = (*fakeDrive)(nil)
+	_ storage.NoteHashReader    = (*fakeDrive)(nil)
+	_ storage.NoteChangeLister  = (*fakeDrive)(nil)
+	_ storage.NoteChangeWatcher = (*fakeDrive)(nil)
+)
@@ -0,0 +1,79 @@
+package sync
+
+import (
+	"time"
+)
+
+// ==================== DEBOUNCED SYNC ====================
+
+// debounceKey identifies a note waiting for its debounced sync
+type debounceKey struct {
+	userID, context, date string
+}
+
+// debounced is the timer of a note waiting for its sync and since when it waits
+type debounced struct {
+	timer *time.Timer
+	since time.Time
+}
+
+// debounceMaxWaits is how many debounce windows a note saved over and over
+// waits at most, so an editor that never pauses still syncs now and then
+const debounceMaxWaits = 4
+
+// SetDebounce sets how long SyncNoteDebounced waits for a note to stop being
+// saved before syncing it; 0 syncs right away
+func (w *Worker) SetDebounce(d time.Duration) {
+	w.debounceMu.Lock()
+	defer w.debounceMu.Unlock()
+	w.debounce = d
+}
+
+// SyncNoteDebounced syncs a note once it hasn't been saved for the debounce
+// window, so the saves of an autosaving editor go out to storage as one upload.
+// Every call restarts the note's timer, up to debounceMaxWaits windows after
+// the first. The note stays pending meanwhile, so the sync loop or a restart
+// still picks it up if the timer doesn't get to it.
+func (w *Worker) SyncNoteDebounced(userID, noteContext, date string) {
+	w.debounceMu.Lock()
+	defer w.debounceMu.Unlock()
+	if w.debounce <= 0 {
+		w.SyncNoteImmediate(userID, noteContext, date)
+		return
+	}
+
+	key := debounceKey{userID, noteContext, date}
+	now := w.clock.Now()
+	since := now
+	if pending, ok := w.debounced[key]; ok {
+		pending.timer.Stop()
+		since = pending.since
+	}
+	wait := min(w.debounce, since.Add(debounceMaxWaits*w.debounce).Sub(now))
+
+	var timer *time.Timer
+	timer = time.AfterFunc(wait, func() {
+		w.debounceMu.Lock()
+		// A timer that fired while a later save replaced it leaves the sync to that one
+		current := w.debounced[key].timer == timer
+		if current {
+			delete(w.debounced, key)
+		}
+		w.debounceMu.Unlock()
+		if current && w.ctx.Err() == nil {
+			w.SyncNoteImmediate(userID, noteContext, date)
+		}
+	})
+	w.debounced[key] = debounced{timer: timer, since: since}
+}
+
+// stopDebounced drops the timers of notes waiting for their sync; the notes are
+// still pending and sync on the next run
+func (w *Worker) stopDebounced() {
+	w.debounceMu.Lock()
+	defer w.debounceMu.Unlock()
+	for key, pending := range w.debounced {
+		pending.timer.Stop()
+		delete(w.debounced, key)
+	}
+}
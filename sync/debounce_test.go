@@ -0,0 +1,138 @@
+package sync
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestSyncNoteDebounced(t *testing.T) {
+	ctx := context.Background()
+	drive := &fakeDrive{files: map[string]models.Note{}}
+	w, repo := newImportWorker(t, nil)
+	w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return drive, nil
+	}
+	w.SetDebounce(100 * time.Millisecond)
+
+	events, unsubscribe := w.Subscribe("test-user")
+	defer unsubscribe()
+	// synced waits for the next upload and returns its date
+	synced := func(within time.Duration) (string, bool) {
+		timeout := time.After(within)
+		for {
+			select {
+			case event := <-events:
+				if event.Status == models.SyncStatusSynced {
+					return event.Date, true
+				}
+			case <-timeout:
+				return "", false
+			}
+		}
+	}
+	autosave := func(date, content string) {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: date, Content: content}, true))
+		w.SyncNoteDebounced("test-user", "Work", date)
+	}
+
+	t.Run("Saves in a row sync once they pause", func(t *testing.T) {
+		autosave("2025-10-16", "Dr")
+		autosave("2025-10-16", "Draft")
+		autosave("2025-10-17", "Other note")
+		autosave("2025-10-16", "Draft done")
+
+		_, ok := synced(50 * time.Millisecond)
+		assert.False(t, ok, "nothing syncs while the saves go on")
+
+		dates := map[string]bool{}
+		for range 2 {
+			date, ok := synced(time.Second)
+			require.True(t, ok)
+			dates[date] = true
+		}
+		assert.Equal(t, map[string]bool{"2025-10-16": true, "2025-10-17": true}, dates, "each note has its own timer")
+		_, ok = synced(200 * time.Millisecond)
+		assert.False(t, ok, "one upload per note")
+
+		note, err := repo.GetNote(ctx, database.ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, models.SyncStatusSynced, note.SyncStatus)
+		assert.Equal(t, "Draft done", note.Content)
+	})
+
+	t.Run("Notes saved without a pause still sync", func(t *testing.T) {
+		stop := time.After(700 * time.Millisecond)
+		ticker := time.NewTicker(40 * time.Millisecond)
+		defer ticker.Stop()
+		uploads := 0
+		for saving := true; saving; {
+			select {
+			case <-ticker.C:
+				autosave("2025-10-18", time.Now().String())
+			case event := <-events:
+				if event.Status == models.SyncStatusSynced {
+					uploads++
+				}
+			case <-stop:
+				saving = false
+			}
+		}
+		assert.GreaterOrEqual(t, uploads, 1)
+	})
+
+	t.Run("Stop drops the timers", func(t *testing.T) {
+		autosave("2025-10-19", "Unsynced")
+		w.stopDebounced()
+		_, ok := synced(300 * time.Millisecond)
+		assert.False(t, ok)
+
+		note, err := repo.GetNote(ctx, database.ScopeUser("test-user"), "Work", "2025-10-19")
+		require.NoError(t, err)
+		assert.Equal(t, models.SyncStatusPending, note.SyncStatus, "left for the sync loop")
+	})
+}
+
+// The clock is set before the worker starts any sync, which reads it
+func TestSyncNoteDebouncedClock(t *testing.T) {
+	ctx := context.Background()
+	drive := &fakeDrive{files: map[string]models.Note{}}
+	w, repo := newImportWorker(t, nil)
+	w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return drive, nil
+	}
+	w.SetDebounce(100 * time.Millisecond)
+	now := clock.NewFake(time.Now())
+	w.SetClock(now)
+
+	events, unsubscribe := w.Subscribe("test-user")
+	defer unsubscribe()
+	autosave := func(content string) {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-20", Content: content}, true))
+		w.SyncNoteDebounced("test-user", "Work", "2025-10-20")
+	}
+
+	autosave("Started")
+	now.Advance(debounceMaxWaits * 100 * time.Millisecond)
+	autosave("Still typing")
+
+	timeout := time.After(50 * time.Millisecond)
+	for {
+		select {
+		case event := <-events:
+			if event.Status == models.SyncStatusSynced {
+				assert.Equal(t, "2025-10-20", event.Date)
+				return
+			}
+		case <-timeout:
+			t.Fatal("waits are measured on the worker clock, on which the max wait has passed")
+		}
+	}
+}
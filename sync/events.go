@@ -0,0 +1,66 @@
+package sync
+
+import (
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/pubsub"
+)
+
+// ==================== SYNC EVENTS ====================
+
+// Subscribe returns the sync state changes of a user's notes from now on, and a
+// function ending the subscription. The channel is closed when the worker stops.
+// Events are dropped for subscribers that fall behind.
+func (w *Worker) Subscribe(userID string) (<-chan models.SyncEvent, func()) {
+	return w.events.Subscribe(userID)
+}
+
+// publish tells the user's subscribers that a note's sync state changed
+func (w *Worker) publish(userID string, event models.SyncEvent) {
+	event.At = w.clock.Now()
+	w.events.Publish(userID, event)
+}
+
+// publishNote publishes the new sync status of a note the worker is syncing
+func (w *Worker) publishNote(note *database.NoteWithMeta, status models.SyncStatus) {
+	w.publish(note.UserID, noteEvent(note, status))
+}
+
+// SetNoteEvents publishes the notes that imports and pulls bring in from storage
+// to events, keyed by user ID. Call it before Start.
+func (w *Worker) SetNoteEvents(events *pubsub.Broker[models.NoteEvent]) {
+	w.noteEvents = events
+}
+
+// SetAbandonHandler calls handle with the event of every note whose sync is
+// abandoned after too many failures, e.g. to notify the user, who would otherwise
+// only see it in the app. handle must not block. Call it before Start.
+func (w *Worker) SetAbandonHandler(handle func(userID string, event models.SyncEvent)) {
+	w.onAbandon = handle
+}
+
+// publishNoteChange tells the user's open clients that storage changed a note
+func (w *Worker) publishNoteChange(note *models.Note) {
+	if w.noteEvents == nil {
+		return
+	}
+	w.noteEvents.Publish(note.UserID, models.NoteEvent{
+		Type:    models.NoteEventUpdated,
+		NoteID:  note.ID,
+		Context: note.Context,
+		Date:    note.Date,
+		Source:  models.NoteSourceSync,
+		At:      w.clock.Now(),
+	})
+}
+
+// noteEvent is the event of a note entering status
+func noteEvent(note *database.NoteWithMeta, status models.SyncStatus) models.SyncEvent {
+	return models.SyncEvent{
+		NoteID:  note.ID,
+		Context: note.Context,
+		Date:    note.Date,
+		Status:  status,
+		Deleted: note.Deleted,
+	}
+}
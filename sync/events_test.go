@@ -0,0 +1,170 @@
+package sync
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/pubsub"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestSyncEvents(t *testing.T) {
+	ctx := context.Background()
+	drive := &fakeDrive{files: map[string]models.Note{}}
+	w, repo := newImportWorker(t, nil)
+	w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return drive, nil
+	}
+
+	events, unsubscribe := w.Subscribe("test-user")
+	defer unsubscribe()
+	others, unsubscribeOthers := w.Subscribe("other-user")
+	defer unsubscribeOthers()
+
+	// received drains the events published so far
+	received := func() []models.SyncEvent {
+		var got []models.SyncEvent
+		for {
+			select {
+			case event := <-events:
+				got = append(got, event)
+			default:
+				return got
+			}
+		}
+	}
+	runSync := func() {
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		w.syncNotesWithDrive("test-user", pending, "Test")
+	}
+
+	require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "plans"}, true))
+
+	t.Run("Uploads go from syncing to synced", func(t *testing.T) {
+		runSync()
+		got := received()
+		require.Len(t, got, 2)
+		assert.Equal(t, models.SyncStatusSyncing, got[0].Status)
+		assert.Equal(t, models.SyncStatusSynced, got[1].Status)
+		assert.Equal(t, "Work", got[1].Context)
+		assert.Equal(t, "2025-10-16", got[1].Date)
+		assert.NotEmpty(t, got[1].NoteID)
+		assert.False(t, got[1].At.IsZero())
+	})
+
+	t.Run("Failures carry the error and the next retry", func(t *testing.T) {
+		w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+			return nil, errors.New("connection refused")
+		}
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-16", Content: "more plans"}, true))
+		runSync()
+
+		got := received()
+		require.Len(t, got, 1)
+		assert.Equal(t, models.SyncStatusFailed, got[0].Status)
+		assert.Equal(t, models.SyncErrorNetwork, got[0].ErrorClass)
+		assert.Contains(t, got[0].Error, "connection refused")
+		require.NotNil(t, got[0].NextRetryAt)
+		assert.True(t, got[0].NextRetryAt.After(time.Now()))
+	})
+
+	t.Run("Abandoned notes go to the abandon handler", func(t *testing.T) {
+		var abandoned []models.SyncEvent
+		w.SetAbandonHandler(func(userID string, event models.SyncEvent) {
+			assert.Equal(t, "test-user", userID)
+			abandoned = append(abandoned, event)
+		})
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-17", Content: "notes"}, true))
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		require.NotEmpty(t, pending)
+
+		pending[0].SyncRetryCount = models.MaxSyncRetries - 2
+		w.markNoteFailed(&pending[0], models.SyncErrorNetwork, "connection refused")
+		assert.Empty(t, abandoned, "notes with retries left aren't abandoned")
+
+		pending[0].SyncRetryCount = models.MaxSyncRetries - 1
+		w.markNoteFailed(&pending[0], models.SyncErrorNetwork, "connection refused")
+		require.Len(t, abandoned, 1)
+		assert.Equal(t, models.SyncStatusAbandoned, abandoned[0].Status)
+		assert.Equal(t, pending[0].Date, abandoned[0].Date)
+		received()
+	})
+
+	t.Run("Other users' subscribers hear nothing", func(t *testing.T) {
+		assert.Empty(t, others)
+	})
+
+	t.Run("Stopping the worker ends subscriptions", func(t *testing.T) {
+		w.events.Close()
+		_, open := <-events
+		assert.False(t, open)
+	})
+}
+
+func TestNoteEvents(t *testing.T) {
+	noteEvents := pubsub.New[models.NoteEvent](pubsub.DefaultBuffer)
+	events, unsubscribe := noteEvents.Subscribe("test-user")
+	defer unsubscribe()
+
+	// received drains the events published so far
+	received := func() []models.NoteEvent {
+		var got []models.NoteEvent
+		for {
+			select {
+			case event := <-events:
+				got = append(got, event)
+			default:
+				return got
+			}
+		}
+	}
+
+	t.Run("Imported notes are published", func(t *testing.T) {
+		w, _ := newImportWorker(t, newFakePager(3))
+		w.SetNoteEvents(noteEvents)
+		require.NoError(t, w.ImportFromDrive("test-user", &oauth2.Token{AccessToken: "token"}))
+
+		got := received()
+		require.Len(t, got, 3)
+		for i, event := range got {
+			assert.Equal(t, models.NoteEventUpdated, event.Type)
+			assert.Equal(t, models.NoteSourceSync, event.Source)
+			assert.Equal(t, "Work", event.Context)
+			assert.Equal(t, fmt.Sprintf("2025-10-%02d", i+1), event.Date)
+			assert.NotEmpty(t, event.NoteID)
+		}
+	})
+
+	t.Run("Pulled notes are published", func(t *testing.T) {
+		ctx := context.Background()
+		drive := &fakeDrive{files: map[string]models.Note{}}
+		w, repo := newImportWorker(t, nil)
+		w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+			return drive, nil
+		}
+		w.SetNoteEvents(noteEvents)
+		require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-work", UserID: "test-user", Name: "Work", Color: "#3b82f6"}))
+
+		_, err := w.PullChanges("test-user") // The first pull starts from now
+		require.NoError(t, err)
+		note := models.Note{ID: "file-2025-10-16", Context: "Work", Date: "2025-10-16", Content: "written on the phone", UpdatedAt: time.Now()}
+		drive.files["Work/2025-10-16"] = note
+		drive.changes = append(drive.changes, note)
+		_, err = w.PullChanges("test-user")
+		require.NoError(t, err)
+
+		got := received()
+		require.Len(t, got, 1)
+		assert.Equal(t, models.NoteSourceSync, got[0].Source)
+		assert.Equal(t, "2025-10-16", got[0].Date)
+		assert.Equal(t, "test-user-Work-2025-10-16", got[0].NoteID)
+	})
+}
@@ -1,8 +1,10 @@
 package sync
 
 import (
-	"context"
 	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -14,7 +16,7 @@ import (
 // Returns true if work was found, false otherwise
 func (w *Worker) syncPendingNotes() bool {
 	// Get batch of pending notes (only retry old ones to avoid race with immediate sync)
-	notes, err := w.repo.GetPendingSyncNotes(50)
+	notes, err := w.repo.GetPendingSyncNotes(w.ctx, 50)
 	if err != nil {
 		log.Printf("[Sync Worker] Failed to get pending notes: %v", err)
 		return false
@@ -25,7 +27,7 @@ func (w *Worker) syncPendingNotes() bool {
 	}
 
 	// Filter notes older than 30 seconds (avoid race with immediate sync)
-	oldNotes := filterOldNotes(notes, 30*time.Second)
+	oldNotes := filterOldNotes(notes, 30*time.Second, w.clock.Now())
 
 	if len(oldNotes) == 0 {
 		return false
@@ -61,22 +63,25 @@ func (w *Worker) syncUserNotes(userID string, notes []database.NoteWithMeta) {
 // It handles token retrieval, storage provider creation, note syncing, and token refresh
 func (w *Worker) syncNotesWithDrive(userID string, notes []database.NoteWithMeta, logPrefix string) *syncResult {
 	result := &syncResult{}
+	defer w.recordHealth(userID, result)
 
 	// Get user's token
 	token, err := w.getUserToken(userID)
 	if err != nil {
 		log.Printf("[%s] Failed to get token for user %s: %v", logPrefix, userID, err)
-		w.markNotesAsFailed(notes, fmt.Sprintf("Failed to get authentication token: %v", err))
+		w.markNotesAsFailed(notes, models.SyncErrorAuth, fmt.Sprintf("Failed to get authentication token: %v", err))
 		result.failedCount = len(notes)
+		result.unreachable = true
 		return result
 	}
 
 	// Create storage provider
-	provider, err := w.storageFactory(context.Background(), token, userID)
+	provider, err := w.storageFactory(w.ctx, token, userID)
 	if err != nil {
 		log.Printf("[%s] Failed to create storage provider for user %s: %v", logPrefix, userID, err)
-		w.markNotesAsFailed(notes, fmt.Sprintf("Failed to connect to cloud storage: %v", err))
+		w.markNotesAsFailed(notes, classifyError(err), fmt.Sprintf("Failed to connect to cloud storage: %v", err))
 		result.failedCount = len(notes)
+		result.unreachable = true
 		return result
 	}
 
@@ -94,21 +99,22 @@ func (w *Worker) syncNotesWithDrive(userID string, notes []database.NoteWithMeta
 	// Process deletions first (higher priority)
 	for _, note := range deleteOps {
 		// Mark note as currently syncing
-		if err := w.repo.MarkNoteSyncing(note.ID); err != nil {
+		if err := w.repo.MarkNoteSyncing(w.ctx, note.ID); err != nil {
 			log.Printf("[%s] Failed to mark note as syncing: %v", logPrefix, err)
 		}
+		w.publishNote(&note, models.SyncStatusSyncing)
 
 		if err := w.syncNote(provider, &note); err != nil {
 			// Check if it's a token expiration error
 			if isTokenExpiredError(err) {
 				log.Printf("[%s] Token expired for user %s, stopping sync", logPrefix, userID)
 				result.tokenExpired = true
-				w.repo.MarkNoteSyncFailed(note.ID, "Authentication token expired")
+				w.markNoteFailed(&note, models.SyncErrorAuth, "Authentication token expired")
 				result.failedCount++
 				break
 			}
 			// Mark as failed with error message
-			w.repo.MarkNoteSyncFailed(note.ID, fmt.Sprintf("Delete failed: %v", err))
+			w.markNoteFailed(&note, classifyError(err), fmt.Sprintf("Delete failed: %v", err))
 			result.failedCount++
 			continue
 		}
@@ -119,21 +125,28 @@ func (w *Worker) syncNotesWithDrive(userID string, notes []database.NoteWithMeta
 	if !result.tokenExpired {
 		for _, note := range regularOps {
 			// Mark note as currently syncing
-			if err := w.repo.MarkNoteSyncing(note.ID); err != nil {
+			if err := w.repo.MarkNoteSyncing(w.ctx, note.ID); err != nil {
 				log.Printf("[%s] Failed to mark note as syncing: %v", logPrefix, err)
 			}
+			w.publishNote(&note, models.SyncStatusSyncing)
 
 			if err := w.syncNote(provider, &note); err != nil {
+				// Already recorded as a conflict, nothing to retry
+				if errors.Is(err, errSyncConflict) {
+					log.Printf("[%s] Note %s/%s changed in storage, kept both versions", logPrefix, note.Context, note.Date)
+					result.conflicts++
+					continue
+				}
 				// Check if it's a token expiration error
 				if isTokenExpiredError(err) {
 					log.Printf("[%s] Token expired for user %s, stopping sync", logPrefix, userID)
 					result.tokenExpired = true
-					w.repo.MarkNoteSyncFailed(note.ID, "Authentication token expired")
+					w.markNoteFailed(&note, models.SyncErrorAuth, "Authentication token expired")
 					result.failedCount++
 					break
 				}
 				// Mark as failed with error message
-				w.repo.MarkNoteSyncFailed(note.ID, fmt.Sprintf("Sync failed: %v", err))
+				w.markNoteFailed(&note, classifyError(err), fmt.Sprintf("Sync failed: %v", err))
 				result.failedCount++
 				continue
 			}
@@ -145,9 +158,7 @@ func (w *Worker) syncNotesWithDrive(userID string, notes []database.NoteWithMeta
 	if result.tokenExpired {
 		log.Printf("[%s] Marking remaining notes as failed due to expired token", logPrefix)
 		errorMsg := "Authentication token expired, please sign in again"
-		for _, note := range notes {
-			w.repo.MarkNoteSyncFailed(note.ID, errorMsg)
-		}
+		w.markNotesAsFailed(notes, models.SyncErrorAuth, errorMsg)
 		return result
 	}
 
@@ -165,7 +176,16 @@ func (w *Worker) syncNote(provider StorageService, note *database.NoteWithMeta)
 			return err
 		}
 		// Hard delete from database after successful deletion
-		return w.repo.HardDeleteNote(note.UserID, note.Context, note.Date)
+		if err := w.repo.HardDeleteNote(w.ctx, database.ScopeUser(note.UserID), note.Context, note.Date); err != nil {
+			return err
+		}
+		w.publishNote(note, models.SyncStatusSynced)
+		return nil
+	}
+
+	// Don't overwrite edits made in storage since the last sync
+	if err := w.detectConflict(provider, note); err != nil {
+		return err
 	}
 
 	// Upload to storage
@@ -175,24 +195,40 @@ func (w *Worker) syncNote(provider StorageService, note *database.NoteWithMeta)
 	}
 
 	// Mark as synced in database
-	return w.repo.MarkNoteSynced(note.ID, syncedNote.ID)
+	if err := w.repo.MarkNoteSynced(w.ctx, note.ID, syncedNote.ID, storage.ContentHash(note.Content)); err != nil {
+		return err
+	}
+	w.publishNote(note, models.SyncStatusSynced)
+	return nil
 }
 
 // SyncNoteImmediate attempts to sync a single note immediately (non-blocking)
-// This is called when a user saves a note for instant sync to Drive
+// This is called when a user saves a note for instant sync to Drive; local-only
+// notes are left alone
 func (w *Worker) SyncNoteImmediate(userID, noteContext, date string) {
 	go func() {
 		// Get the note from database
-		note, err := w.repo.GetNote(userID, noteContext, date)
+		note, err := w.repo.GetNote(w.ctx, database.ScopeUser(userID), noteContext, date)
 		if err != nil {
 			log.Printf("[Immediate Sync] Failed to get note %s/%s: %v", noteContext, date, err)
 			return
 		}
+		if note == nil || note.LocalOnly {
+			return
+		}
+
+		syncedAt, err := w.repo.GetNoteSyncedAt(w.ctx, note.ID)
+		if err != nil {
+			log.Printf("[Immediate Sync] Failed to get sync time of note %s/%s: %v", noteContext, date, err)
+			return
+		}
 
 		// Convert to NoteWithMeta for unified sync
 		noteMeta := database.NoteWithMeta{
-			Note: *note,
+			Note:     *note,
+			SyncedAt: syncedAt,
 		}
+		w.publishNote(&noteMeta, models.SyncStatusPending)
 
 		// Use unified sync logic
 		result := w.syncNotesWithDrive(userID, []database.NoteWithMeta{noteMeta}, "Immediate Sync")
@@ -205,3 +241,36 @@ func (w *Worker) SyncNoteImmediate(userID, noteContext, date string) {
 		}
 	}()
 }
+
+// SyncNotesImmediate attempts to sync several of a user's notes at once (non-blocking)
+// This is called after a batch save, so all the notes go out in one sync run
+func (w *Worker) SyncNotesImmediate(userID string, notes []models.Note) {
+	go func() {
+		batch := make([]database.NoteWithMeta, 0, len(notes))
+		for _, saved := range notes {
+			// Read the notes again in case they changed since the batch was saved
+			note, err := w.repo.GetNote(w.ctx, database.ScopeUser(userID), saved.Context, saved.Date)
+			if err != nil || note == nil {
+				log.Printf("[Immediate Sync] Failed to get note %s/%s: %v", saved.Context, saved.Date, err)
+				continue
+			}
+			if note.LocalOnly {
+				continue
+			}
+
+			syncedAt, err := w.repo.GetNoteSyncedAt(w.ctx, note.ID)
+			if err != nil {
+				log.Printf("[Immediate Sync] Failed to get sync time of note %s/%s: %v", saved.Context, saved.Date, err)
+				continue
+			}
+			batch = append(batch, database.NoteWithMeta{Note: *note, SyncedAt: syncedAt})
+			w.publishNote(&batch[len(batch)-1], models.SyncStatusPending)
+		}
+		if len(batch) == 0 {
+			return
+		}
+
+		result := w.syncNotesWithDrive(userID, batch, "Immediate Sync")
+		log.Printf("[Immediate Sync] Synced %d of %d batch-saved notes of user %s", result.syncedCount, len(batch), userID)
+	}()
+}
@@ -0,0 +1,80 @@
+package sync
+
+import (
+	"daily-notes/models"
+	"fmt"
+	"time"
+)
+
+// ==================== SYNC HEALTH ====================
+
+// offlineAfterFailures is how many consecutive sync attempts with no successful
+// note must fail before a degraded user is reported as offline
+const offlineAfterFailures = 3
+
+// userHealth tracks the sync health of a single user between attempts
+type userHealth struct {
+	health              models.SyncHealth
+	consecutiveFailures int
+}
+
+// Health returns the current sync health for a user
+// Users the worker has not synced yet are reported as ok
+func (w *Worker) Health(userID string) models.SyncHealth {
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+
+	if h, ok := w.health[userID]; ok {
+		return h.health
+	}
+	return models.SyncHealth{State: models.SyncHealthOK}
+}
+
+// recordHealth updates a user's sync health from the outcome of a sync attempt
+func (w *Worker) recordHealth(userID string, result *syncResult) {
+	now := w.clock.Now()
+
+	w.healthMu.Lock()
+	defer w.healthMu.Unlock()
+
+	h, ok := w.health[userID]
+	if !ok {
+		h = &userHealth{health: models.SyncHealth{State: models.SyncHealthOK}}
+		w.health[userID] = h
+	}
+
+	if result.syncedCount > 0 {
+		h.health.LastSyncedAt = &now
+	}
+	if result.failedCount == 0 && !result.unreachable && !result.tokenExpired {
+		h.consecutiveFailures = 0
+		h.setState(models.SyncHealthOK, "", now)
+		return
+	}
+
+	if result.syncedCount == 0 {
+		h.consecutiveFailures++
+	} else {
+		h.consecutiveFailures = 0
+	}
+
+	switch {
+	case result.tokenExpired:
+		h.setState(models.SyncHealthOffline, "Cloud storage access expired, please sign in again", now)
+	case result.unreachable:
+		h.setState(models.SyncHealthOffline, "Cloud storage is unreachable, notes are saved locally", now)
+	case h.consecutiveFailures >= offlineAfterFailures:
+		h.setState(models.SyncHealthOffline, fmt.Sprintf("Sync has failed %d times in a row, notes are saved locally", h.consecutiveFailures), now)
+	default:
+		h.setState(models.SyncHealthDegraded, fmt.Sprintf("%d note(s) failed to sync and will be retried", result.failedCount), now)
+	}
+}
+
+// setState changes the state and message, keeping Since when the state is unchanged
+func (h *userHealth) setState(state models.SyncHealthState, message string, now time.Time) {
+	if h.health.State != state || h.health.Since == nil {
+		h.health.Since = &now
+	}
+	h.health.State = state
+	h.health.Message = message
+}
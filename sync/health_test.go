@@ -0,0 +1,55 @@
+package sync
+
+import (
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerHealth(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC))
+	w := NewWorker(nil, nil, nil, nil)
+	w.SetClock(clk)
+
+	t.Run("Unknown user is ok", func(t *testing.T) {
+		assert.Equal(t, models.SyncHealthOK, w.Health("user-1").State)
+	})
+
+	t.Run("Partial failure is degraded", func(t *testing.T) {
+		w.recordHealth("user-1", &syncResult{syncedCount: 1, failedCount: 1})
+		h := w.Health("user-1")
+		assert.Equal(t, models.SyncHealthDegraded, h.State)
+		require.NotNil(t, h.LastSyncedAt)
+		assert.Equal(t, clk.Now(), *h.LastSyncedAt)
+	})
+
+	t.Run("Unreachable storage is offline", func(t *testing.T) {
+		clk.Advance(time.Minute)
+		w.recordHealth("user-1", &syncResult{failedCount: 1, unreachable: true})
+		h := w.Health("user-1")
+		assert.Equal(t, models.SyncHealthOffline, h.State)
+		assert.NotEmpty(t, h.Message)
+		require.NotNil(t, h.Since)
+		assert.Equal(t, clk.Now(), *h.Since)
+	})
+
+	t.Run("Repeated failures go offline", func(t *testing.T) {
+		for i := 0; i < offlineAfterFailures-1; i++ {
+			w.recordHealth("user-2", &syncResult{failedCount: 1})
+			assert.Equal(t, models.SyncHealthDegraded, w.Health("user-2").State)
+		}
+		w.recordHealth("user-2", &syncResult{failedCount: 1})
+		assert.Equal(t, models.SyncHealthOffline, w.Health("user-2").State)
+	})
+
+	t.Run("Successful sync recovers", func(t *testing.T) {
+		w.recordHealth("user-1", &syncResult{syncedCount: 2})
+		h := w.Health("user-1")
+		assert.Equal(t, models.SyncHealthOK, h.State)
+		assert.Empty(t, h.Message)
+	})
+}
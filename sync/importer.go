@@ -1,7 +1,9 @@
 package sync
 
 import (
-	"context"
+	"daily-notes/database"
+	"daily-notes/storage"
+	"errors"
 	"log"
 
 	"golang.org/x/oauth2"
@@ -9,13 +11,25 @@ import (
 
 // ==================== CLOUD STORAGE IMPORT ====================
 
+// importPageSize is how many notes are downloaded and saved per batch
+// Only one batch of content is held in memory at a time.
+const importPageSize = 100
+
 // ImportFromDrive imports all notes and contexts from cloud storage for a user
-// This is typically called on first login or when user requests a full sync
+// This is typically called on first login or when user requests a full sync.
+// Progress is checkpointed per context after every saved note, so an import
+// interrupted by a restart continues where it stopped (see ResumeImports).
 func (w *Worker) ImportFromDrive(userID string, token *oauth2.Token) error {
+	if !w.beginImport(userID) {
+		log.Printf("[Sync Worker] Storage import for user %s is already running", userID)
+		return nil
+	}
+	defer w.endImport(userID)
+
 	log.Printf("[Sync Worker] Starting storage import for user %s", userID)
 
 	// Create storage provider
-	provider, err := w.storageFactory(context.Background(), token, userID)
+	provider, err := w.storageFactory(w.ctx, token, userID)
 	if err != nil {
 		return err
 	}
@@ -26,36 +40,187 @@ func (w *Worker) ImportFromDrive(userID string, token *oauth2.Token) error {
 		return err
 	}
 
+	// Record the import before creating contexts: once a user has contexts the
+	// login no longer imports, so the checkpoints are what bring a restart back here
+	names := make([]string, len(config.Contexts))
+	for i, c := range config.Contexts {
+		names[i] = c.Name
+	}
+	if err := w.repo.StartImport(w.ctx, userID, names); err != nil {
+		return err
+	}
+	checkpoints, err := w.repo.GetImportCheckpoints(w.ctx, userID)
+	if err != nil {
+		return err
+	}
+
 	// Import contexts
-	for _, ctx := range config.Contexts {
-		if err := w.repo.CreateContext(&ctx); err != nil {
-			log.Printf("[Sync Worker] Failed to import context %s: %v", ctx.Name, err)
+	for _, c := range config.Contexts {
+		if err := w.repo.CreateContext(w.ctx, &c); err != nil {
+			log.Printf("[Sync Worker] Failed to import context %s: %v", c.Name, err)
 		}
 	}
 
 	// Import notes for each context
-	totalNotes := 0
-	for _, ctx := range config.Contexts {
-		notes, err := provider.GetAllNotesInContext(ctx.Name)
-		if err != nil {
-			log.Printf("[Sync Worker] Failed to import notes for context %s: %v", ctx.Name, err)
+	totalNotes, failedContexts := 0, 0
+	for _, c := range config.Contexts {
+		cp := checkpoints[c.Name]
+		if cp.Done {
 			continue
 		}
+		cp.UserID, cp.Context = userID, c.Name
 
-		for _, note := range notes {
-			note.UserID = userID
-			// Mark as already synced (sync_pending = false)
-			if err := w.repo.UpsertNote(&note, false); err != nil {
-				log.Printf("[Sync Worker] Failed to import note %s: %v", note.ID, err)
-			} else {
-				totalNotes++
-			}
+		imported, err := w.importContext(provider, &cp)
+		totalNotes += imported
+		if err != nil {
+			log.Printf("[Sync Worker] Failed to import notes for context %s after %d notes: %v", c.Name, cp.Imported, err)
+			failedContexts++
 		}
 	}
 
 	// Update the token in the session if it was refreshed
 	w.updateTokenIfRefreshed(provider, token, userID, "Sync Worker")
 
+	if failedContexts > 0 {
+		log.Printf("[Sync Worker] Imported %d notes from storage; %d contexts will resume on next start", totalNotes, failedContexts)
+		return nil
+	}
+	if err := w.repo.FinishImport(w.ctx, userID); err != nil {
+		log.Printf("[Sync Worker] Failed to clear import checkpoints for user %s: %v", userID, err)
+	}
+
 	log.Printf("[Sync Worker] Imported %d contexts and %d notes from storage", len(config.Contexts), totalNotes)
 	return nil
 }
+
+// importContext imports a context's notes page by page from its checkpoint
+// Returns the number of notes saved in this run. Any failure stops the context
+// with the checkpoint at the last saved note, so nothing is skipped on resume.
+func (w *Worker) importContext(provider StorageService, cp *database.ImportCheckpoint) (int, error) {
+	// A context that was partly imported before keeps the notes it already has:
+	// they came from this import or were edited since, and must not be overwritten
+	resumed := cp.Imported > 0
+
+	imported, restarted := 0, false
+	for {
+		page, restart, err := w.readImportPage(provider, cp, !restarted)
+		if err != nil {
+			return imported, err
+		}
+		restarted = restarted || restart
+		resumed = resumed || (restart && cp.Imported > 0)
+
+		// A resumed page is read again from the start; skip what was saved before
+		skip := 0
+		if cp.LastFile != "" {
+			for i, note := range page.Notes {
+				if note.File == cp.LastFile {
+					skip = i + 1
+					break
+				}
+			}
+		}
+
+		for _, note := range page.Notes[skip:] {
+			if err := w.holdForMaintenance(); err != nil {
+				return imported, err
+			}
+			if resumed {
+				existing, err := w.repo.GetNote(w.ctx, database.ScopeUser(cp.UserID), cp.Context, note.Date)
+				if err != nil {
+					return imported, err
+				}
+				if existing != nil {
+					continue
+				}
+			}
+
+			note.UserID = cp.UserID
+			// Mark as already synced (sync_pending = false)
+			if err := w.repo.UpsertNote(w.ctx, &note.Note, false); err != nil {
+				return imported, err
+			}
+			w.publishNoteChange(&note.Note)
+			imported++
+
+			cp.LastFile = note.File
+			cp.Imported++
+			if err := w.repo.SaveImportCheckpoint(w.ctx, *cp); err != nil {
+				return imported, err
+			}
+		}
+
+		cp.PageToken, cp.LastFile = page.NextPageToken, ""
+		cp.Done = page.NextPageToken == ""
+		if err := w.repo.SaveImportCheckpoint(w.ctx, *cp); err != nil {
+			return imported, err
+		}
+		if cp.Done {
+			return imported, nil
+		}
+	}
+}
+
+// readImportPage reads the page a checkpoint points at
+// Page tokens can expire (storage.ErrPageExpired); with canRestart the context
+// is then read again from its first page and restart is true. Providers without paging return all
+// notes as one page.
+func (w *Worker) readImportPage(provider StorageService, cp *database.ImportCheckpoint, canRestart bool) (page *storage.NotePage, restart bool, err error) {
+	pager, ok := provider.(storage.NotePager)
+	if !ok {
+		notes, err := provider.GetAllNotesInContext(cp.Context)
+		if err != nil {
+			return nil, false, err
+		}
+		return storage.SinglePage(notes), false, nil
+	}
+
+	page, err = pager.NotePage(cp.Context, cp.PageToken, importPageSize)
+	if !errors.Is(err, storage.ErrPageExpired) || !canRestart {
+		return page, false, err
+	}
+
+	log.Printf("[Sync Worker] Import page of context %s is no longer available, restarting the context: %v", cp.Context, err)
+	cp.PageToken, cp.LastFile = "", ""
+	page, err = pager.NotePage(cp.Context, "", importPageSize)
+	return page, true, err
+}
+
+// ResumeImports continues every import that was interrupted, e.g. by a restart
+// Users whose token isn't available are left for the next start.
+func (w *Worker) ResumeImports() {
+	userIDs, err := w.repo.GetUnfinishedImports(w.ctx)
+	if err != nil {
+		log.Printf("[Sync Worker] Failed to list unfinished imports: %v", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		token, err := w.getUserToken(userID)
+		if err != nil {
+			log.Printf("[Sync Worker] Cannot resume import for user %s: %v", userID, err)
+			continue
+		}
+		if err := w.ImportFromDrive(userID, token); err != nil {
+			log.Printf("[Sync Worker] Failed to resume import for user %s: %v", userID, err)
+		}
+	}
+}
+
+// beginImport marks a user's import as running; false if it already is
+func (w *Worker) beginImport(userID string) bool {
+	w.importsMu.Lock()
+	defer w.importsMu.Unlock()
+	if w.imports[userID] {
+		return false
+	}
+	w.imports[userID] = true
+	return true
+}
+
+// endImport clears the mark set by beginImport
+func (w *Worker) endImport(userID string) {
+	w.importsMu.Lock()
+	defer w.importsMu.Unlock()
+	delete(w.imports, userID)
+}
@@ -0,0 +1,194 @@
+package sync
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// fakePager serves one context in pages of two notes; page tokens are offsets
+type fakePager struct {
+	notes         []storage.PagedNote
+	failAtToken   string // Reading this page fails, like a restart mid-import
+	expiredTokens bool   // Every non-empty page token is rejected
+	reads         int
+}
+
+func newFakePager(count int) *fakePager {
+	p := &fakePager{}
+	for i := 1; i <= count; i++ {
+		date := fmt.Sprintf("2025-10-%02d", i)
+		p.notes = append(p.notes, storage.PagedNote{
+			Note: models.Note{Context: "Work", Date: date, Content: "remote " + date},
+			File: storage.FilenameFor(date, storage.FilenameDayFirst),
+		})
+	}
+	return p
+}
+
+func (p *fakePager) NotePage(contextName, pageToken string, pageSize int) (*storage.NotePage, error) {
+	p.reads++
+	if pageToken != "" && pageToken == p.failAtToken {
+		return nil, errors.New("connection reset")
+	}
+	if pageToken != "" && p.expiredTokens {
+		return nil, storage.ErrPageExpired
+	}
+	start, _ := strconv.Atoi(pageToken)
+	end := min(start+2, len(p.notes))
+
+	page := &storage.NotePage{Notes: append([]storage.PagedNote(nil), p.notes[start:end]...)}
+	if end < len(p.notes) {
+		page.NextPageToken = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+func (p *fakePager) GetConfig() (*storage.Config, error) {
+	return &storage.Config{Contexts: []models.Context{{ID: "ctx-work", UserID: "test-user", Name: "Work", Color: "blue"}}}, nil
+}
+
+func (p *fakePager) GetAllNotesInContext(contextName string) ([]models.Note, error) {
+	return nil, errors.New("not used when paging")
+}
+
+func (p *fakePager) UpsertNote(contextName, date, content string) (*models.Note, error) {
+	return nil, nil
+}
+
+func (p *fakePager) DeleteNote(contextName, date string) error { return nil }
+
+func (p *fakePager) GetCurrentToken() (*oauth2.Token, error) { return nil, nil }
+
+func newImportWorker(t *testing.T, provider *fakePager) (*Worker, *database.Repository) {
+	t.Helper()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.Migrate())
+	t.Cleanup(func() { db.Close() })
+
+	repo := database.NewRepository(db)
+	require.NoError(t, repo.UpsertUser(context.Background(), &models.User{ID: "test-user", GoogleID: "google-123", Email: "test@example.com", CreatedAt: time.Now()}))
+
+	factory := func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return provider, nil
+	}
+	getUserToken := func(userID string) (*oauth2.Token, error) {
+		return &oauth2.Token{AccessToken: "token"}, nil
+	}
+	return NewWorker(repo, nil, factory, getUserToken), repo
+}
+
+func TestImportFromDrive_Resumes(t *testing.T) {
+	ctx := context.Background()
+	token := &oauth2.Token{AccessToken: "token"}
+
+	interrupt := func(t *testing.T) (*Worker, *database.Repository, *fakePager) {
+		provider := newFakePager(5)
+		provider.failAtToken = "2"
+		w, repo := newImportWorker(t, provider)
+
+		require.NoError(t, w.ImportFromDrive("test-user", token))
+		checkpoints, err := repo.GetImportCheckpoints(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, database.ImportCheckpoint{UserID: "test-user", Context: "Work", PageToken: "2", Imported: 2}, checkpoints["Work"])
+
+		// Edited locally before the import resumes
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-01", Content: "edited"}, true))
+		return w, repo, provider
+	}
+
+	assertImported := func(t *testing.T, repo *database.Repository) {
+		notes, err := repo.GetAllNotesByUser(ctx, database.ScopeUser("test-user"))
+		require.NoError(t, err)
+		contents := map[string]string{}
+		for _, note := range notes {
+			contents[note.Date] = note.Content
+		}
+		assert.Equal(t, map[string]string{
+			"2025-10-01": "edited",
+			"2025-10-02": "remote 2025-10-02",
+			"2025-10-03": "remote 2025-10-03",
+			"2025-10-04": "remote 2025-10-04",
+			"2025-10-05": "remote 2025-10-05",
+		}, contents)
+
+		unfinished, err := repo.GetUnfinishedImports(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, unfinished)
+		checkpoints, err := repo.GetImportCheckpoints(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Empty(t, checkpoints, "checkpoints are dropped once the import is complete")
+	}
+
+	t.Run("Continues from the saved page", func(t *testing.T) {
+		w, repo, provider := interrupt(t)
+		provider.failAtToken = ""
+		provider.reads = 0
+
+		require.NoError(t, w.ImportFromDrive("test-user", token))
+		assert.Equal(t, 2, provider.reads, "only the pages after the checkpoint are read")
+		assertImported(t, repo)
+	})
+
+	t.Run("Restarts the context when the page token expired", func(t *testing.T) {
+		w, repo, provider := interrupt(t)
+		provider.failAtToken = ""
+		provider.expiredTokens = true
+		provider.reads = 0
+
+		require.NoError(t, w.ImportFromDrive("test-user", token))
+		// The restart reads the first page again, then stops at the next token, which
+		// this fake rejects as well; the context is only restarted once per run
+		assert.Equal(t, 3, provider.reads)
+		unfinished, err := repo.GetUnfinishedImports(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"test-user"}, unfinished)
+
+		provider.expiredTokens = false
+		require.NoError(t, w.ImportFromDrive("test-user", token))
+		assertImported(t, repo)
+	})
+
+	t.Run("Resumed on worker start", func(t *testing.T) {
+		w, repo, provider := interrupt(t)
+		provider.failAtToken = ""
+
+		w.ResumeImports()
+		assertImported(t, repo)
+	})
+}
+
+func TestImportFromDrive_SkipsWithinResumedPage(t *testing.T) {
+	ctx := context.Background()
+	provider := newFakePager(3)
+	w, repo := newImportWorker(t, provider)
+
+	// Stopped after the first note of the first page
+	require.NoError(t, repo.StartImport(ctx, "test-user", []string{"Work"}))
+	require.NoError(t, repo.SaveImportCheckpoint(ctx, database.ImportCheckpoint{
+		UserID: "test-user", Context: "Work", LastFile: provider.notes[0].File, Imported: 1,
+	}))
+
+	require.NoError(t, w.ImportFromDrive("test-user", &oauth2.Token{AccessToken: "token"}))
+
+	notes, err := repo.GetAllNotesByUser(ctx, database.ScopeUser("test-user"))
+	require.NoError(t, err)
+	var dates []string
+	for _, note := range notes {
+		dates = append(dates, note.Date)
+	}
+	assert.ElementsMatch(t, []string{"2025-10-02", "2025-10-03"}, dates, "the first note was saved by the earlier run")
+}
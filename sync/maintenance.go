@@ -0,0 +1,31 @@
+package sync
+
+import (
+	"daily-notes/pkg/maintenance"
+	"time"
+)
+
+// ==================== MAINTENANCE ====================
+
+// maintenancePoll is how often work held for maintenance checks whether it ended
+const maintenancePoll = 5 * time.Second
+
+// SetMaintenance holds the work that writes notes from storage (pulls, imports
+// and verification) while the server is in maintenance. Uploads go on, so the
+// worker drains.
+func (w *Worker) SetMaintenance(mode *maintenance.Mode) {
+	w.maintenance = mode
+}
+
+// holdForMaintenance waits while the server is in maintenance; it fails when
+// the worker stops meanwhile
+func (w *Worker) holdForMaintenance() error {
+	for w.maintenance.Paused() {
+		select {
+		case <-time.After(maintenancePoll):
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		}
+	}
+	return nil
+}
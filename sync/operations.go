@@ -0,0 +1,108 @@
+package sync
+
+import (
+	"daily-notes/models"
+	"fmt"
+	"log"
+)
+
+// ==================== CONTEXT FOLDER OPERATIONS ====================
+
+// contextFolders is implemented by storage providers keeping a folder per context
+type contextFolders interface {
+	RenameContext(contextID, oldName, newName string) error
+	DeleteContext(contextID, contextName string) error
+}
+
+// retryOperations applies the context folder changes that failed before (see
+// database.RecordSyncOperation) and are due, oldest first. While a change fails,
+// the later changes of the same context wait, so renames apply in order.
+// Returns true if work was found.
+func (w *Worker) retryOperations() bool {
+	w.opsMu.Lock()
+	defer w.opsMu.Unlock()
+
+	ops, err := w.repo.GetFailedSyncOperations(w.ctx, 50)
+	if err != nil {
+		log.Printf("[Sync Worker] Failed to get failed sync operations: %v", err)
+		return false
+	}
+
+	now := w.clock.Now()
+	waiting := make(map[string]bool) // Contexts with an earlier change still failing
+	providers := make(map[string]contextFolders)
+	hadWork := false
+	for i := range ops {
+		op := &ops[i]
+		if waiting[op.ContextID] {
+			continue
+		}
+		if op.NextRetryAt != nil && op.NextRetryAt.After(now) {
+			waiting[op.ContextID] = true
+			continue
+		}
+		hadWork = true
+
+		folders, ok := providers[op.UserID]
+		if !ok {
+			token, err := w.getUserToken(op.UserID)
+			if err != nil {
+				w.markOperationFailed(op, models.SyncErrorAuth, fmt.Sprintf("Failed to get authentication token: %v", err))
+				waiting[op.ContextID] = true
+				continue
+			}
+			provider, err := w.storageFactory(w.ctx, token, op.UserID)
+			if err != nil {
+				w.markOperationFailed(op, classifyError(err), fmt.Sprintf("Failed to connect to cloud storage: %v", err))
+				waiting[op.ContextID] = true
+				continue
+			}
+			folders, _ = provider.(contextFolders) // nil for providers without folders
+			providers[op.UserID] = folders
+		}
+
+		if err := applyOperation(folders, op); err != nil {
+			w.markOperationFailed(op, classifyError(err), err.Error())
+			waiting[op.ContextID] = true
+			continue
+		}
+		if err := w.repo.CompleteSyncOperation(w.ctx, op.ID); err != nil {
+			log.Printf("[Sync Worker] Failed to complete sync operation %d: %v", op.ID, err)
+		}
+		log.Printf("[Sync Worker] Applied %s of context %s for user %s", op.Kind, op.ContextID, op.UserID)
+	}
+	return hadWork
+}
+
+// applyOperation makes one context folder change in storage
+// Providers without context folders have nothing to change.
+func applyOperation(folders contextFolders, op *models.SyncOperation) error {
+	if folders == nil {
+		return nil
+	}
+	switch op.Kind {
+	case models.SyncOperationRename:
+		return folders.RenameContext(op.ContextID, op.ContextName, op.NewName)
+	case models.SyncOperationDelete:
+		return folders.DeleteContext(op.ContextID, op.ContextName)
+	}
+	return fmt.Errorf("unknown sync operation %q", op.Kind)
+}
+
+// markOperationFailed records a failed attempt at an operation and schedules its
+// retry by error class, like markNoteFailed for notes
+func (w *Worker) markOperationFailed(op *models.SyncOperation, class models.SyncErrorClass, errorMsg string) {
+	retryAt := nextRetryAt(class, op.RetryCount, w.clock.Now())
+	if err := w.repo.MarkSyncOperationFailed(w.ctx, op.ID, errorMsg, class, retryAt); err != nil {
+		log.Printf("[Sync Worker] Failed to mark sync operation %d as failed: %v", op.ID, err)
+	}
+}
+
+// RetryNow syncs the pending notes and applies the due context folder changes
+// right away instead of at the next interval (non-blocking)
+func (w *Worker) RetryNow() {
+	go func() {
+		w.syncPendingNotes()
+		w.retryOperations()
+	}()
+}
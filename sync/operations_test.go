@@ -0,0 +1,86 @@
+package sync
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// fakeFolders is a storage provider with context folders that can be made to fail
+type fakeFolders struct {
+	*fakeDrive
+	fail    error
+	applied []string
+}
+
+func (f *fakeFolders) RenameContext(contextID, oldName, newName string) error {
+	if f.fail != nil {
+		return f.fail
+	}
+	f.applied = append(f.applied, "rename "+oldName+" to "+newName)
+	return nil
+}
+
+func (f *fakeFolders) DeleteContext(contextID, contextName string) error {
+	if f.fail != nil {
+		return f.fail
+	}
+	f.applied = append(f.applied, "delete "+contextName)
+	return nil
+}
+
+func TestRetryOperations(t *testing.T) {
+	ctx := context.Background()
+	fakeClock := clock.NewFake(time.Date(2025, 10, 16, 9, 0, 0, 0, time.UTC))
+	folders := &fakeFolders{fakeDrive: &fakeDrive{files: map[string]models.Note{}}, fail: errors.New("connection reset")}
+	w, repo := newImportWorker(t, nil)
+	w.SetClock(fakeClock)
+	w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return folders, nil
+	}
+
+	record := func(op models.SyncOperation) {
+		op.UserID, op.CreatedAt = "test-user", fakeClock.Now()
+		require.NoError(t, repo.RecordSyncOperation(ctx, &op))
+	}
+	record(models.SyncOperation{Kind: models.SyncOperationRename, ContextID: "ctx-work", ContextName: "Work", NewName: "Projects"})
+	record(models.SyncOperation{Kind: models.SyncOperationRename, ContextID: "ctx-work", ContextName: "Projects", NewName: "Archive"})
+	record(models.SyncOperation{Kind: models.SyncOperationDelete, ContextID: "ctx-old", ContextName: "Old"})
+
+	t.Run("Failures are scheduled for a retry", func(t *testing.T) {
+		assert.True(t, w.retryOperations())
+		assert.Empty(t, folders.applied)
+
+		ops, err := repo.GetUserSyncOperations(ctx, "test-user")
+		require.NoError(t, err)
+		require.Len(t, ops, 3)
+		assert.Equal(t, models.SyncErrorNetwork, ops[0].ErrorClass)
+		require.NotNil(t, ops[0].NextRetryAt)
+		assert.Equal(t, 1, ops[0].RetryCount)
+		assert.Zero(t, ops[1].RetryCount, "later changes of a context wait for the earlier ones")
+		assert.Equal(t, 1, ops[2].RetryCount)
+	})
+
+	t.Run("Nothing is retried before it is due", func(t *testing.T) {
+		folders.fail = nil
+		assert.False(t, w.retryOperations())
+		assert.Empty(t, folders.applied)
+	})
+
+	t.Run("Due changes are applied in order", func(t *testing.T) {
+		fakeClock.Advance(networkRetryMax)
+		assert.True(t, w.retryOperations())
+		assert.Equal(t, []string{"rename Work to Projects", "rename Projects to Archive", "delete Old"}, folders.applied)
+
+		ops, err := repo.GetUserSyncOperations(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Empty(t, ops)
+	})
+}
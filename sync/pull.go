@@ -0,0 +1,215 @@
+package sync
+
+import (
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"log"
+	"time"
+)
+
+// ==================== PULL FROM STORAGE ====================
+
+// SetPullInterval sets how often changes made in storage are pulled; 0 turns pulling off
+// Takes effect on Start.
+func (w *Worker) SetPullInterval(d time.Duration) {
+	w.pullInterval = d
+}
+
+// PullChanges applies the notes changed in the user's storage since the last pull
+// and returns how many local notes changed. The first pull only records where
+// later pulls start, as the import brought in everything before. Providers that
+// can't list changes are skipped.
+func (w *Worker) PullChanges(userID string) (int, error) {
+	// Changes stay listed in storage, so the first pull after maintenance brings them in
+	if w.maintenance.Paused() {
+		return 0, nil
+	}
+	token, err := w.getUserToken(userID)
+	if err != nil {
+		return 0, err
+	}
+	provider, err := w.storageFactory(w.ctx, token, userID)
+	if err != nil {
+		return 0, err
+	}
+	lister, ok := provider.(storage.NoteChangeLister)
+	if !ok {
+		return 0, nil
+	}
+	defer w.updateTokenIfRefreshed(provider, token, userID, "Sync Pull")
+
+	pageToken, err := w.repo.GetChangeToken(w.ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if pageToken == "" {
+		start, err := lister.NoteChangesStartToken()
+		if err != nil {
+			return 0, err
+		}
+		if err := w.repo.SaveChangeToken(w.ctx, userID, start); err != nil {
+			return 0, err
+		}
+		w.watchChanges(userID, provider, start)
+		return 0, nil
+	}
+
+	notes, next, err := lister.ListNoteChanges(pageToken)
+	if err != nil {
+		return 0, err
+	}
+	applied := 0
+	for i := range notes {
+		// Stopping before the change token is saved lists the rest again next time
+		if w.maintenance.Paused() {
+			return applied, nil
+		}
+		changed, err := w.applyRemoteNote(userID, &notes[i])
+		if err != nil {
+			return applied, err
+		}
+		if changed {
+			applied++
+		}
+	}
+	if err := w.repo.SaveChangeToken(w.ctx, userID, next); err != nil {
+		return applied, err
+	}
+	w.watchChanges(userID, provider, next)
+	return applied, nil
+}
+
+// RequestPull pulls the user's storage changes in the background, e.g. when
+// storage reports a change
+func (w *Worker) RequestPull(userID string) {
+	if w.beginPull(userID) {
+		go w.pullUntilDone(userID)
+	}
+}
+
+// pullUser pulls the user's storage changes unless a pull is already running for them
+func (w *Worker) pullUser(userID string) {
+	if w.beginPull(userID) {
+		w.pullUntilDone(userID)
+	}
+}
+
+// pullUntilDone runs PullChanges for a user marked by beginPull, once more for
+// every pull requested meanwhile, as it may have been notified of changes the
+// running pull had already listed past
+func (w *Worker) pullUntilDone(userID string) {
+	for {
+		applied, err := w.PullChanges(userID)
+		if err != nil {
+			log.Printf("[Sync Pull] Failed to pull changes of user %s: %v", userID, err)
+		}
+		if applied > 0 {
+			log.Printf("[Sync Pull] Pulled %d changed notes of user %s", applied, userID)
+		}
+		if !w.endPull(userID) {
+			return
+		}
+	}
+}
+
+// beginPull marks a user's pull as running; false (noting the request) if it already is
+func (w *Worker) beginPull(userID string) bool {
+	w.pullsMu.Lock()
+	defer w.pullsMu.Unlock()
+	if _, running := w.pulls[userID]; running {
+		w.pulls[userID] = true
+		return false
+	}
+	w.pulls[userID] = false
+	return true
+}
+
+// endPull clears the mark set by beginPull, unless another pull was requested meanwhile
+// Returns whether to pull again.
+func (w *Worker) endPull(userID string) bool {
+	w.pullsMu.Lock()
+	defer w.pullsMu.Unlock()
+	if w.pulls[userID] {
+		w.pulls[userID] = false
+		return true
+	}
+	delete(w.pulls, userID)
+	return false
+}
+
+// applyRemoteNote brings one note changed in storage into the local notes.
+// Files that hold what was last uploaded (our own uploads) or what the note holds
+// already change nothing. A note with local changes not uploaded yet keeps both
+// versions as a sync conflict; otherwise the storage version replaces it, with
+// the local content kept in the revision history. Notes of contexts unknown here,
+// local-only notes and contexts and notes waiting to be deleted are skipped.
+func (w *Worker) applyRemoteNote(userID string, remote *models.Note) (bool, error) {
+	c, err := w.repo.GetContextByName(w.ctx, database.ScopeUser(userID), remote.Context)
+	if err != nil || c == nil || c.LocalOnly {
+		return false, err
+	}
+
+	local, err := w.repo.GetNoteSyncState(w.ctx, userID, remote.Context, remote.Date)
+	if err != nil {
+		return false, err
+	}
+	hash := storage.ContentHash(remote.Content)
+	if local != nil && (local.Deleted || local.LocalOnly || local.ContentHash == hash || local.Content == remote.Content) {
+		return false, nil
+	}
+	if local != nil && local.Pending {
+		if err := w.repo.MarkNoteConflict(w.ctx, local.ID, remote.Content, remote.UpdatedAt); err != nil {
+			return false, err
+		}
+		w.publish(userID, models.SyncEvent{NoteID: local.ID, Context: remote.Context, Date: remote.Date, Status: models.SyncStatusConflict})
+		return true, nil
+	}
+
+	baseRevision := 0
+	if local != nil {
+		baseRevision = local.Revision
+	}
+	note := &models.Note{
+		UserID:    userID,
+		Context:   remote.Context,
+		Date:      remote.Date,
+		Content:   remote.Content,
+		CreatedAt: remote.CreatedAt,
+		UpdatedAt: remote.UpdatedAt,
+	}
+	applied, err := w.repo.ApplyRemoteNote(w.ctx, note, remote.ID, hash, baseRevision)
+	if applied {
+		w.publishNoteChange(note)
+	}
+	return applied, err
+}
+
+// runPull pulls every signed-in user's storage changes once per pullInterval
+// This also renews the channels of push notifications before they expire.
+func (w *Worker) runPull() {
+	ticker := time.NewTicker(w.pullInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.pullAll()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// pullAll pulls the storage changes of each signed-in user with synced notes
+func (w *Worker) pullAll() {
+	users, err := w.repo.GetUsersToPull(w.ctx)
+	if err != nil {
+		log.Printf("[Sync Pull] Failed to get users to pull: %v", err)
+		return
+	}
+
+	for _, userID := range users {
+		w.pullUser(userID)
+	}
+}
@@ -0,0 +1,99 @@
+package sync
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestPullChanges(t *testing.T) {
+	ctx := context.Background()
+	drive := &fakeDrive{files: map[string]models.Note{}}
+	w, repo := newImportWorker(t, nil)
+	w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return drive, nil
+	}
+	require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-work", UserID: "test-user", Name: "Work", Color: "#3b82f6"}))
+
+	// editInDrive stands in for an edit made in the Drive app
+	editInDrive := func(contextName, date, content string) {
+		note := models.Note{ID: "file-" + date, Context: contextName, Date: date, Content: content, UpdatedAt: time.Now()}
+		drive.files[contextName+"/"+date] = note
+		drive.changes = append(drive.changes, note)
+	}
+	pull := func() int {
+		t.Helper()
+		applied, err := w.PullChanges("test-user")
+		require.NoError(t, err)
+		return applied
+	}
+	content := func(date string) string {
+		t.Helper()
+		note, err := repo.GetNote(ctx, database.ScopeUser("test-user"), "Work", date)
+		require.NoError(t, err)
+		require.NotNil(t, note)
+		return note.Content
+	}
+
+	t.Run("The first pull starts from now", func(t *testing.T) {
+		editInDrive("Work", "2025-10-15", "before the first pull")
+		assert.Zero(t, pull())
+
+		token, err := repo.GetChangeToken(ctx, "test-user")
+		require.NoError(t, err)
+		assert.Equal(t, "1", token)
+	})
+
+	t.Run("New and edited files are pulled", func(t *testing.T) {
+		editInDrive("Work", "2025-10-16", "written on the phone")
+		assert.Equal(t, 1, pull())
+		assert.Equal(t, "written on the phone", content("2025-10-16"))
+
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		assert.Empty(t, pending, "pulled notes are not uploaded again")
+
+		editInDrive("Work", "2025-10-16", "edited on the phone")
+		assert.Equal(t, 1, pull())
+		assert.Equal(t, "edited on the phone", content("2025-10-16"))
+
+		revisions, err := repo.GetNoteRevisions(ctx, "test-user", "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.Len(t, revisions, 1)
+		assert.Equal(t, "written on the phone", revisions[0].Content)
+	})
+
+	t.Run("Our own uploads change nothing", func(t *testing.T) {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-17", Content: "v1"}, true))
+		pending, err := repo.GetPendingSyncNotes(ctx, 10)
+		require.NoError(t, err)
+		require.Equal(t, 1, w.syncNotesWithDrive("test-user", pending, "Test").syncedCount)
+
+		// Edited again before the pull sees the upload of v1
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: "2025-10-17", Content: "v2"}, true))
+		assert.Zero(t, pull())
+		assert.Equal(t, "v2", content("2025-10-17"))
+	})
+
+	t.Run("Unsynced local edits become conflicts", func(t *testing.T) {
+		editInDrive("Work", "2025-10-17", "phone edit")
+		assert.Equal(t, 1, pull())
+		assert.Equal(t, "v2", content("2025-10-17"))
+
+		conflict, err := repo.GetNoteConflict(ctx, "test-user", "Work", "2025-10-17")
+		require.NoError(t, err)
+		require.NotNil(t, conflict)
+		assert.Equal(t, "phone edit", conflict.RemoteContent)
+	})
+
+	t.Run("Unknown contexts are skipped", func(t *testing.T) {
+		editInDrive("Elsewhere", "2025-10-16", "not a context here")
+		assert.Zero(t, pull())
+	})
+}
@@ -2,9 +2,15 @@ package sync
 
 import (
 	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"errors"
 	"log"
+	"net/http"
 	"strings"
 	"time"
+
+	"google.golang.org/api/googleapi"
 )
 
 // ==================== RETRY LOGIC & BACKOFF ====================
@@ -13,15 +19,16 @@ import (
 type syncResult struct {
 	syncedCount  int
 	failedCount  int
+	conflicts    int // Notes held back because they also changed in storage
 	tokenExpired bool
+	unreachable  bool // Token or storage provider could not be obtained
 }
 
 // filterOldNotes filters notes that are older than the specified duration
 // This prevents race conditions with immediate sync by only processing notes
 // that haven't been recently modified
-func filterOldNotes(notes []database.NoteWithMeta, minAge time.Duration) []database.NoteWithMeta {
+func filterOldNotes(notes []database.NoteWithMeta, minAge time.Duration, now time.Time) []database.NoteWithMeta {
 	var oldNotes []database.NoteWithMeta
-	now := time.Now()
 
 	for _, note := range notes {
 		if note.SyncLastAttemptAt != nil {
@@ -52,11 +59,132 @@ func isTokenExpiredError(err error) bool {
 		strings.Contains(errMsg, "401")
 }
 
-// markNotesAsFailed marks a batch of notes as failed with an error message
-func (w *Worker) markNotesAsFailed(notes []database.NoteWithMeta, errorMsg string) {
-	for _, note := range notes {
-		if err := w.repo.MarkNoteSyncFailed(note.ID, errorMsg); err != nil {
-			log.Printf("[Sync Worker] Failed to mark note %s as failed: %v", note.ID, err)
+// Retry delays per error class
+const (
+	authRetryDelay    = 24 * time.Hour // Only if the user doesn't sign in again; signing in retries right away
+	quotaRetryDelay   = time.Hour
+	payloadRetryDelay = 6 * time.Hour
+	networkRetryBase  = 2 * time.Minute // Doubles with every counted failure
+	networkRetryMax   = time.Hour
+)
+
+// driveQuotaReasons are the Drive error reasons of rate limits and exhausted storage
+var driveQuotaReasons = map[string]bool{
+	"rateLimitExceeded":     true,
+	"userRateLimitExceeded": true,
+	"dailyLimitExceeded":    true,
+	"quotaExceeded":         true,
+	"storageQuotaExceeded":  true,
+}
+
+// classifyError groups a sync error by cause, judging by the HTTP status storage
+// answered with. Errors without a status (timeouts, refused connections) and
+// server errors are network errors.
+func classifyError(err error) models.SyncErrorClass {
+	if isTokenExpiredError(err) {
+		return models.SyncErrorAuth
+	}
+
+	var status int
+	var apiErr *googleapi.Error
+	var statusErr storage.StatusError
+	switch {
+	case errors.As(err, &apiErr):
+		for _, item := range apiErr.Errors {
+			if driveQuotaReasons[item.Reason] {
+				return models.SyncErrorQuota
+			}
+		}
+		status = apiErr.Code
+	case errors.As(err, &statusErr):
+		status = statusErr.HTTPStatus()
+	}
+
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return models.SyncErrorAuth
+	case http.StatusTooManyRequests, http.StatusInsufficientStorage:
+		return models.SyncErrorQuota
+	case http.StatusConflict:
+		// Dropbox answers 409 for a full account as well as for invalid paths
+		if strings.Contains(err.Error(), "insufficient_space") {
+			return models.SyncErrorQuota
 		}
+		return models.SyncErrorPayload
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return models.SyncErrorPayload
 	}
+	return models.SyncErrorNetwork
+}
+
+// nextRetryAt returns when a note that failed with an error of class is due again
+// retries is the number of counted failures before this one.
+func nextRetryAt(class models.SyncErrorClass, retries int, now time.Time) time.Time {
+	switch class {
+	case models.SyncErrorAuth:
+		return now.Add(authRetryDelay)
+	case models.SyncErrorQuota:
+		return now.Add(quotaRetryDelay)
+	case models.SyncErrorPayload:
+		return now.Add(payloadRetryDelay)
+	}
+
+	delay := networkRetryBase
+	for i := 0; i < retries && delay < networkRetryMax; i++ {
+		delay *= 2
+	}
+	return now.Add(min(delay, networkRetryMax))
+}
+
+// markNoteFailed records a failed sync of a note and schedules its retry by error class
+func (w *Worker) markNoteFailed(note *database.NoteWithMeta, class models.SyncErrorClass, errorMsg string) {
+	retryAt := nextRetryAt(class, note.SyncRetryCount, w.clock.Now())
+	if err := w.repo.MarkNoteSyncFailed(w.ctx, note.ID, errorMsg, class, retryAt); err != nil {
+		log.Printf("[Sync Worker] Failed to mark note %s as failed: %v", note.ID, err)
+		return
+	}
+
+	// Mirrors MarkNoteSyncFailed: counted failures past the limit abandon the note
+	event := noteEvent(note, models.SyncStatusFailed)
+	if class.CountsAsRetry() && note.SyncRetryCount+1 >= models.MaxSyncRetries {
+		event.Status = models.SyncStatusAbandoned
+	} else {
+		event.NextRetryAt = &retryAt
+	}
+	event.Error = errorMsg
+	event.ErrorClass = class
+	w.publish(note.UserID, event)
+	if event.Status == models.SyncStatusAbandoned && w.onAbandon != nil {
+		w.onAbandon(note.UserID, event)
+	}
+}
+
+// markNotesAsFailed marks a batch of notes as failed with an error message
+func (w *Worker) markNotesAsFailed(notes []database.NoteWithMeta, class models.SyncErrorClass, errorMsg string) {
+	for i := range notes {
+		w.markNoteFailed(&notes[i], class, errorMsg)
+	}
+}
+
+// RetryAfterSignIn makes the user's notes and context folder changes that failed
+// for lack of a valid sign-in due again and retries them right away (non-blocking).
+// Called when the user signs in.
+func (w *Worker) RetryAfterSignIn(userID string) {
+	go func() {
+		released, err := w.repo.ReleaseAuthFailedNotes(w.ctx, userID)
+		if err != nil {
+			log.Printf("[Sync Worker] Failed to release notes of user %s after sign-in: %v", userID, err)
+		} else if released > 0 {
+			log.Printf("[Sync Worker] Retrying %d notes of user %s after sign-in", released, userID)
+			w.syncPendingNotes()
+		}
+
+		operations, err := w.repo.ReleaseAuthFailedSyncOperations(w.ctx, userID)
+		if err != nil {
+			log.Printf("[Sync Worker] Failed to release sync operations of user %s after sign-in: %v", userID, err)
+		} else if operations > 0 {
+			log.Printf("[Sync Worker] Retrying %d context folder changes of user %s after sign-in", operations, userID)
+			w.retryOperations()
+		}
+	}()
 }
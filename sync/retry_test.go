@@ -0,0 +1,58 @@
+package sync
+
+import (
+	"daily-notes/models"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+)
+
+// statusError is a provider error carrying an HTTP status, like the Dropbox, S3 and WebDAV clients return
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string   { return e.msg }
+func (e *statusError) HTTPStatus() int { return e.status }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want models.SyncErrorClass
+	}{
+		{"Expired token", errors.New("oauth2: token expired and refresh token is not set"), models.SyncErrorAuth},
+		{"Drive rate limit", &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}}}, models.SyncErrorQuota},
+		{"Drive permission", &googleapi.Error{Code: 403, Errors: []googleapi.ErrorItem{{Reason: "insufficientPermissions"}}}, models.SyncErrorAuth},
+		{"Drive server error", fmt.Errorf("upload: %w", &googleapi.Error{Code: 503}), models.SyncErrorNetwork},
+		{"Too many requests", &statusError{429, "s3: 429 SlowDown"}, models.SyncErrorQuota},
+		{"Full Dropbox", &statusError{409, "dropbox: 409 path/insufficient_space/"}, models.SyncErrorQuota},
+		{"Invalid Dropbox path", &statusError{409, "dropbox: 409 path/malformed_path/"}, models.SyncErrorPayload},
+		{"Payload too large", &statusError{413, "webdav: PUT note.md: 413"}, models.SyncErrorPayload},
+		{"Connection refused", errors.New("dial tcp: connection refused"), models.SyncErrorNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, classifyError(tt.err))
+		})
+	}
+}
+
+func TestNextRetryAt(t *testing.T) {
+	now := time.Date(2025, 10, 17, 9, 0, 0, 0, time.UTC)
+	after := func(class models.SyncErrorClass, retries int) time.Duration {
+		return nextRetryAt(class, retries, now).Sub(now)
+	}
+
+	assert.Equal(t, 2*time.Minute, after(models.SyncErrorNetwork, 0))
+	assert.Equal(t, 8*time.Minute, after(models.SyncErrorNetwork, 2))
+	assert.Equal(t, time.Hour, after(models.SyncErrorNetwork, 10), "network backoff is capped")
+	assert.Equal(t, time.Hour, after(models.SyncErrorQuota, 0))
+	assert.Equal(t, 6*time.Hour, after(models.SyncErrorPayload, 0))
+	assert.Equal(t, 24*time.Hour, after(models.SyncErrorAuth, 0))
+}
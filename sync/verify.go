@@ -0,0 +1,130 @@
+package sync
+
+import (
+	"daily-notes/models"
+	"daily-notes/storage"
+	"errors"
+	"log"
+	"time"
+)
+
+// ==================== SYNC VERIFICATION ====================
+
+// ErrVerifyUnsupported is returned by Verify for storage providers that keep no content hashes
+var ErrVerifyUnsupported = errors.New("storage provider does not keep content hashes")
+
+// SetVerifyInterval sets how often all synced notes are verified; 0 turns the job off
+// Takes effect on Start.
+func (w *Worker) SetVerifyInterval(d time.Duration) {
+	w.verifyInterval = d
+}
+
+// Verify compares the user's synced notes in a context with their files in storage.
+// A file whose content no longer hashes to the note's last upload was edited by
+// another app or corrupted: it is reported and the note is marked as a sync
+// conflict holding the file's content, so neither version overwrites the other.
+// Synced notes without a file are reported only.
+func (w *Worker) Verify(userID, contextName string) (*models.SyncVerification, error) {
+	token, err := w.getUserToken(userID)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := w.storageFactory(w.ctx, token, userID)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := provider.(storage.NoteHashReader)
+	if !ok {
+		return nil, ErrVerifyUnsupported
+	}
+
+	synced, err := w.repo.GetSyncedNoteHashes(w.ctx, userID, contextName)
+	if err != nil {
+		return nil, err
+	}
+	files, err := reader.GetHashedNotes(contextName)
+	if err != nil {
+		return nil, err
+	}
+	w.updateTokenIfRefreshed(provider, token, userID, "Sync Verify")
+
+	byDate := make(map[string]storage.HashedNote, len(files))
+	for _, file := range files {
+		byDate[file.Date] = file
+	}
+
+	result := &models.SyncVerification{
+		Context:    contextName,
+		Checked:    len(synced),
+		Mismatches: []models.SyncMismatch{},
+		VerifiedAt: w.clock.Now(),
+	}
+	for _, note := range synced {
+		file, ok := byDate[note.Date]
+		if !ok {
+			result.Mismatches = append(result.Mismatches, models.SyncMismatch{
+				Date: note.Date, Problem: models.SyncMismatchMissing, SyncedHash: note.Hash,
+			})
+			continue
+		}
+
+		contentHash := storage.ContentHash(file.Content)
+		if contentHash == note.Hash {
+			continue
+		}
+		result.Mismatches = append(result.Mismatches, models.SyncMismatch{
+			Date: note.Date, Problem: models.SyncMismatchChanged, SyncedHash: note.Hash,
+			FileHash: file.Hash, ContentHash: contentHash,
+		})
+		if err := w.repo.MarkNoteConflict(w.ctx, note.NoteID, file.Content, file.UpdatedAt); err != nil {
+			return nil, err
+		}
+		w.publish(userID, models.SyncEvent{NoteID: note.NoteID, Context: contextName, Date: note.Date, Status: models.SyncStatusConflict})
+	}
+	return result, nil
+}
+
+// runVerification verifies every user's synced notes once per verifyInterval
+func (w *Worker) runVerification() {
+	ticker := time.NewTicker(w.verifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.verifyAll()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// verifyAll verifies each context holding synced notes and logs the mismatches found
+func (w *Worker) verifyAll() {
+	// Verification marks conflicts, which waits for the next run after maintenance
+	if w.maintenance.Paused() {
+		return
+	}
+	contexts, err := w.repo.GetHashedContexts(w.ctx)
+	if err != nil {
+		log.Printf("[Sync Verify] Failed to get contexts to verify: %v", err)
+		return
+	}
+
+	for userID, names := range contexts {
+		for _, name := range names {
+			result, err := w.Verify(userID, name)
+			if errors.Is(err, ErrVerifyUnsupported) {
+				break
+			}
+			if err != nil {
+				log.Printf("[Sync Verify] Failed to verify context %s of user %s: %v", name, userID, err)
+				continue
+			}
+			if len(result.Mismatches) > 0 {
+				log.Printf("[Sync Verify] %d of %d notes in context %s of user %s don't match storage",
+					len(result.Mismatches), result.Checked, name, userID)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package sync
+
+import (
+	"context"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	drive := &fakeDrive{files: map[string]models.Note{}}
+	w, repo := newImportWorker(t, nil)
+
+	t.Run("Providers without hashes", func(t *testing.T) {
+		_, err := w.Verify("test-user", "Work")
+		assert.ErrorIs(t, err, ErrVerifyUnsupported)
+	})
+
+	w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return drive, nil
+	}
+	for _, date := range []string{"2025-10-15", "2025-10-16", "2025-10-17"} {
+		require.NoError(t, repo.UpsertNote(ctx, &models.Note{UserID: "test-user", Context: "Work", Date: date, Content: "note " + date}, true))
+	}
+	pending, err := repo.GetPendingSyncNotes(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, 3, w.syncNotesWithDrive("test-user", pending, "Test").syncedCount)
+
+	t.Run("Untouched files match", func(t *testing.T) {
+		result, err := w.Verify("test-user", "Work")
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Checked)
+		assert.Empty(t, result.Mismatches)
+	})
+
+	t.Run("Changed and missing files are reported", func(t *testing.T) {
+		edited := drive.files["Work/2025-10-16"]
+		edited.Content = "edited by another app"
+		edited.UpdatedAt = time.Now()
+		drive.files["Work/2025-10-16"] = edited
+		delete(drive.files, "Work/2025-10-17")
+
+		result, err := w.Verify("test-user", "Work")
+		require.NoError(t, err)
+		require.Len(t, result.Mismatches, 2)
+
+		changed := result.Mismatches[0]
+		assert.Equal(t, "2025-10-16", changed.Date)
+		assert.Equal(t, models.SyncMismatchChanged, changed.Problem)
+		assert.Equal(t, storage.ContentHash("note 2025-10-16"), changed.SyncedHash)
+		assert.Equal(t, changed.SyncedHash, changed.FileHash, "the file kept the hash of our upload")
+		assert.Equal(t, storage.ContentHash("edited by another app"), changed.ContentHash)
+
+		assert.Equal(t, "2025-10-17", result.Mismatches[1].Date)
+		assert.Equal(t, models.SyncMismatchMissing, result.Mismatches[1].Problem)
+	})
+
+	t.Run("Changed files become conflicts", func(t *testing.T) {
+		conflict, err := repo.GetNoteConflict(ctx, "test-user", "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, conflict)
+		assert.Equal(t, "note 2025-10-16", conflict.LocalContent)
+		assert.Equal(t, "edited by another app", conflict.RemoteContent)
+
+		result, err := w.Verify("test-user", "Work")
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Checked, "notes in conflict are not checked again")
+	})
+
+	t.Run("Contexts to verify", func(t *testing.T) {
+		contexts, err := repo.GetHashedContexts(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{"test-user": {"Work"}}, contexts)
+	})
+}
@@ -0,0 +1,76 @@
+package sync
+
+import (
+	"crypto/subtle"
+	"daily-notes/models"
+	"daily-notes/storage"
+	"errors"
+	"log"
+	"time"
+)
+
+// ==================== STORAGE CHANGE NOTIFICATIONS ====================
+
+// channelRenewal is how long before it expires a channel is replaced by a new one
+// The periodic pull renews channels, so this must be well above the pull interval.
+const channelRenewal = 24 * time.Hour
+
+// ErrUnknownChannel is returned for notifications to a channel that isn't (or is no longer) ours
+var ErrUnknownChannel = errors.New("unknown change channel")
+
+// SetWebhookURL sets the public address storage posts change notifications to
+// Without it, changes are only pulled periodically.
+func (w *Worker) SetWebhookURL(url string) {
+	w.webhookURL = url
+}
+
+// watchChanges subscribes the user's storage to change notifications from pageToken,
+// unless their channel is good for a while yet. The replaced channel is stopped.
+// Errors are only logged: the periodic pull still brings the changes in.
+func (w *Worker) watchChanges(userID string, provider StorageService, pageToken string) {
+	watcher, ok := provider.(storage.NoteChangeWatcher)
+	if w.webhookURL == "" || !ok {
+		return
+	}
+
+	current, err := w.repo.GetChangeChannel(w.ctx, userID)
+	if err != nil {
+		log.Printf("[Sync Watch] Failed to get the change channel of user %s: %v", userID, err)
+		return
+	}
+	if current != nil && current.Expiration.After(w.clock.Now().Add(channelRenewal)) {
+		return
+	}
+
+	channel := &models.ChangeChannel{ID: w.ids.NewID(), UserID: userID, Token: w.ids.NewID()}
+	if err := watcher.WatchNoteChanges(pageToken, w.webhookURL, channel); err != nil {
+		log.Printf("[Sync Watch] Failed to watch the changes of user %s: %v", userID, err)
+		return
+	}
+	if err := w.repo.SaveChangeChannel(w.ctx, channel); err != nil {
+		log.Printf("[Sync Watch] Failed to save the change channel of user %s: %v", userID, err)
+		return
+	}
+
+	if current != nil && current.Expiration.After(w.clock.Now()) {
+		if err := watcher.StopWatching(*current); err != nil {
+			log.Printf("[Sync Watch] Failed to stop the old change channel of user %s: %v", userID, err)
+		}
+	}
+}
+
+// NotifyChanges handles a notification that the storage of a channel's user changed
+// by pulling their changes in the background. Returns ErrUnknownChannel when the
+// channel isn't the user's current one or the token doesn't match.
+func (w *Worker) NotifyChanges(channelID, token string) error {
+	channel, err := w.repo.GetChangeChannelByID(w.ctx, channelID)
+	if err != nil {
+		return err
+	}
+	if channel == nil || subtle.ConstantTimeCompare([]byte(token), []byte(channel.Token)) != 1 {
+		return ErrUnknownChannel
+	}
+
+	w.RequestPull(channel.UserID)
+	return nil
+}
@@ -0,0 +1,97 @@
+package sync
+
+import (
+	"context"
+	"daily-notes/database"
+	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestChangeNotifications(t *testing.T) {
+	ctx := context.Background()
+	drive := &fakeDrive{files: map[string]models.Note{}}
+	w, repo := newImportWorker(t, nil)
+	w.storageFactory = func(ctx context.Context, token *oauth2.Token, userID string) (StorageService, error) {
+		return drive, nil
+	}
+	w.SetWebhookURL("https://notes.example.com/api/drive/webhook")
+	now := clock.NewFake(time.Now())
+	w.SetClock(now)
+	w.SetIDGenerator(idgen.NewSequence("channel"))
+	require.NoError(t, repo.CreateContext(ctx, &models.Context{ID: "ctx-work", UserID: "test-user", Name: "Work", Color: "#3b82f6"}))
+
+	pull := func() {
+		t.Helper()
+		_, err := w.PullChanges("test-user")
+		require.NoError(t, err)
+	}
+	channel := func() *models.ChangeChannel {
+		t.Helper()
+		channel, err := repo.GetChangeChannel(ctx, "test-user")
+		require.NoError(t, err)
+		require.NotNil(t, channel)
+		return channel
+	}
+
+	t.Run("Pulling subscribes once", func(t *testing.T) {
+		pull()
+		pull()
+		require.Len(t, drive.watched, 1)
+		assert.Equal(t, drive.watched[0].ID, channel().ID)
+		assert.Equal(t, "channel-1", channel().ID)
+		assert.Equal(t, "channel-2", channel().Token)
+	})
+
+	t.Run("Notifications need the channel token", func(t *testing.T) {
+		current := channel()
+		assert.Equal(t, ErrUnknownChannel, w.NotifyChanges(current.ID, "wrong token"))
+		assert.Equal(t, ErrUnknownChannel, w.NotifyChanges("other channel", current.Token))
+	})
+
+	t.Run("Notifications pull right away", func(t *testing.T) {
+		note := models.Note{ID: "file-2025-10-16", Context: "Work", Date: "2025-10-16", Content: "from the phone", UpdatedAt: time.Now()}
+		drive.changes = append(drive.changes, note)
+
+		current := channel()
+		require.NoError(t, w.NotifyChanges(current.ID, current.Token))
+		require.Eventually(t, func() bool {
+			w.pullsMu.Lock()
+			defer w.pullsMu.Unlock()
+			return len(w.pulls) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+
+		pulled, err := repo.GetNote(ctx, database.ScopeUser("test-user"), "Work", "2025-10-16")
+		require.NoError(t, err)
+		require.NotNil(t, pulled)
+		assert.Equal(t, "from the phone", pulled.Content)
+	})
+
+	t.Run("Expiring channels are replaced", func(t *testing.T) {
+		expiring := channel()
+		expiring.Expiration = time.Now().Add(time.Hour)
+		require.NoError(t, repo.SaveChangeChannel(ctx, expiring))
+
+		pull()
+		require.Len(t, drive.watched, 2)
+		assert.Equal(t, []string{expiring.ID}, drive.stopped)
+		assert.Equal(t, drive.watched[1].ID, channel().ID)
+		assert.Equal(t, ErrUnknownChannel, w.NotifyChanges(expiring.ID, expiring.Token))
+	})
+
+	t.Run("Channels are renewed on the worker clock", func(t *testing.T) {
+		current := channel()
+		now.Advance(time.Until(current.Expiration) - channelRenewal + time.Minute)
+
+		pull()
+		require.Len(t, drive.watched, 3)
+		assert.Equal(t, []string{drive.watched[0].ID, current.ID}, drive.stopped)
+		assert.Equal(t, "channel-5", channel().ID)
+	})
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"daily-notes/database"
 	"daily-notes/models"
+	"daily-notes/pkg/clock"
+	"daily-notes/pkg/idgen"
+	"daily-notes/pkg/maintenance"
+	"daily-notes/pkg/pubsub"
 	"daily-notes/session"
-	"daily-notes/storage/drive"
+	"daily-notes/storage"
 	"log"
 	"sync"
 	"time"
@@ -18,7 +22,7 @@ type StorageService interface {
 	UpsertNote(contextName, date, content string) (*models.Note, error)
 	DeleteNote(contextName, date string) error
 	GetAllNotesInContext(contextName string) ([]models.Note, error)
-	GetConfig() (*drive.Config, error)
+	GetConfig() (*storage.Config, error)
 	GetCurrentToken() (*oauth2.Token, error)
 }
 
@@ -31,6 +35,16 @@ type StorageFactory func(ctx context.Context, token *oauth2.Token, userID string
 // - retry.go: Retry and backoff strategies
 // - importer.go: Cloud storage import operations
 // - token_manager.go: OAuth token refresh handling
+// - health.go: Per-user sync health (ok/degraded/offline)
+// - conflicts.go: Detection of notes also edited in storage
+// - verify.go: Content hash verification of synced notes
+// - pull.go: Notes changed in storage pulled into the database
+// - watch.go: Push notifications of storage changes
+// - archive.go: Compressed snapshots of all notes uploaded to storage
+// - operations.go: Failed context folder renames and deletions, retried
+// - events.go: Sync state changes of notes, and notes changed from storage, published to subscribers
+// - debounce.go: Syncs of autosaved notes delayed until the saves pause
+// - maintenance.go: Work writing notes from storage held during maintenance
 type Worker struct {
 	repo            *database.Repository
 	sessionStore    *session.Store
@@ -42,10 +56,37 @@ type Worker struct {
 	mu              sync.Mutex
 	stopChan        chan struct{}
 	getUserToken    func(userID string) (*oauth2.Token, error)
+	clock           clock.Clock
+	ids             idgen.Generator // IDs and tokens of change channels, see watch.go
+	ctx             context.Context // Canceled by Stop to abort in-flight queries and storage calls
+	cancel          context.CancelFunc
+	health          map[string]*userHealth // Per-user sync health, see health.go
+	healthMu        sync.Mutex
+	imports         map[string]bool // Users with an import running, see importer.go
+	importsMu       sync.Mutex
+	verifyInterval  time.Duration   // How often all synced notes are verified, see verify.go
+	pullInterval    time.Duration   // How often changes made in storage are pulled, see pull.go
+	pulls           map[string]bool // Users with a pull running; true when another was requested, see pull.go
+	pullsMu         sync.Mutex
+	opsMu           sync.Mutex    // Serializes retries of context folder changes, see operations.go
+	webhookURL      string        // Where storage posts change notifications, see watch.go
+	archiveInterval time.Duration // How often notes are archived to storage, see archive.go
+	archivesKept    int           // Archives kept per user; 0 keeps them all
+	debounce        time.Duration // How long autosaved notes wait for their sync, see debounce.go
+	debounced       map[debounceKey]debounced
+	debounceMu      sync.Mutex
+
+	events     *pubsub.Broker[models.SyncEvent] // Sync state changes by user ID, see events.go
+	noteEvents *pubsub.Broker[models.NoteEvent] // Notes imported or pulled from storage by user ID; optional
+
+	onAbandon func(userID string, event models.SyncEvent) // Told of notes abandoned after too many failures, see events.go; optional
+
+	maintenance *maintenance.Mode // Holds pulls, imports and verification, see maintenance.go; optional
 }
 
 // NewWorker creates a new sync worker instance
 func NewWorker(repo *database.Repository, sessionStore *session.Store, storageFactory StorageFactory, getUserToken func(userID string) (*oauth2.Token, error)) *Worker {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Worker{
 		repo:            repo,
 		sessionStore:    sessionStore,
@@ -55,9 +96,28 @@ func NewWorker(repo *database.Repository, sessionStore *session.Store, storageFa
 		currentInterval: 2 * time.Minute, // Start with base interval
 		getUserToken:    getUserToken,
 		stopChan:        make(chan struct{}),
+		clock:           clock.Real(),
+		ids:             idgen.UUID(),
+		ctx:             ctx,
+		cancel:          cancel,
+		health:          make(map[string]*userHealth),
+		imports:         make(map[string]bool),
+		pulls:           make(map[string]bool),
+		debounced:       make(map[debounceKey]debounced),
+		events:          pubsub.New[models.SyncEvent](pubsub.DefaultBuffer),
 	}
 }
 
+// SetClock replaces the clock used to decide when notes are due for a retry
+func (w *Worker) SetClock(c clock.Clock) {
+	w.clock = c
+}
+
+// SetIDGenerator replaces the generator used for change channel IDs and tokens
+func (w *Worker) SetIDGenerator(g idgen.Generator) {
+	w.ids = g
+}
+
 // Start begins the background sync worker
 func (w *Worker) Start() {
 	w.mu.Lock()
@@ -71,6 +131,16 @@ func (w *Worker) Start() {
 	log.Println("[Sync Worker] Starting background sync worker")
 
 	go w.run()
+	go w.ResumeImports()
+	if w.verifyInterval > 0 {
+		go w.runVerification()
+	}
+	if w.pullInterval > 0 {
+		go w.runPull()
+	}
+	if w.archiveInterval > 0 {
+		go w.runArchive()
+	}
 }
 
 // Stop gracefully stops the background sync worker
@@ -84,6 +154,9 @@ func (w *Worker) Stop() {
 
 	log.Println("[Sync Worker] Stopping background sync worker")
 	close(w.stopChan)
+	w.cancel()
+	w.stopDebounced()
+	w.events.Close()
 	w.running = false
 }
 
@@ -94,11 +167,15 @@ func (w *Worker) run() {
 
 	// Run immediately on start
 	w.syncPendingNotes()
+	w.retryOperations()
 
 	for {
 		select {
 		case <-ticker.C:
 			hadWork := w.syncPendingNotes()
+			if w.retryOperations() {
+				hadWork = true
+			}
 
 			// Adaptive backoff: increase interval when no work, reset when there's work
 			w.mu.Lock()
@@ -1,10 +1,12 @@
 package validator
 
 import (
+	"daily-notes/pkg/period"
 	"fmt"
 	"reflect"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -50,9 +52,14 @@ func New() *Validator {
 	// Register custom validators
 	v.RegisterValidation("contextname", validateContextName)
 	v.RegisterValidation("dateformat", validateDateFormat)
+	v.RegisterValidation("periodkey", validatePeriodKey)
+	v.RegisterValidation("notbefore", validateNotBefore)
 	v.RegisterValidation("bulmacolor", validateBulmaColor)
 	v.RegisterValidation("theme", validateTheme)
 	v.RegisterValidation("timezone", validateTimezone)
+	v.RegisterValidation("handle", validateHandle)
+	v.RegisterValidation("language", validateLanguage)
+	v.RegisterValidation("contexticon", validateContextIcon)
 
 	return &Validator{validate: v}
 }
@@ -97,12 +104,22 @@ func msgForTag(fe validator.FieldError) string {
 		return fmt.Sprintf("%s contains invalid characters (only letters, numbers, spaces, and -_.,&() are allowed)", field)
 	case "dateformat":
 		return fmt.Sprintf("%s must be in YYYY-MM-DD format", field)
+	case "periodkey":
+		return fmt.Sprintf("%s must be a valid key for the note type (week: 2025-W42, month: 2025-10, year: 2025)", field)
+	case "notbefore":
+		return fmt.Sprintf("%s must not be before %s", field, strings.ToLower(fe.Param()))
 	case "bulmacolor":
 		return fmt.Sprintf("%s must be one of: text, link, primary, info, success, warning, danger", field)
 	case "theme":
 		return fmt.Sprintf("%s must be either 'light' or 'dark'", field)
 	case "timezone":
 		return fmt.Sprintf("%s must be a valid timezone", field)
+	case "handle":
+		return fmt.Sprintf("%s must be 3 to 32 lowercase letters, numbers or hyphens, starting and ending with a letter or number", field)
+	case "language":
+		return fmt.Sprintf("%s must be a lowercase ISO 639-1 language code, e.g. en or es", field)
+	case "contexticon":
+		return fmt.Sprintf("%s must be one emoji or an icon name of lowercase letters, numbers and hyphens, e.g. briefcase", field)
 	case "gte":
 		return fmt.Sprintf("%s must be greater than or equal to %s", field, fe.Param())
 	case "lte":
@@ -132,6 +149,25 @@ func validateDateFormat(fl validator.FieldLevel) bool {
 	return datePattern.MatchString(date)
 }
 
+// validatePeriodKey validates that a note key matches the granularity in the field named by the param
+func validatePeriodKey(fl validator.FieldLevel) bool {
+	kind := fl.Parent().FieldByName(fl.Param())
+	if !kind.IsValid() {
+		return false
+	}
+	return period.Kind(fl.Field().String()) == kind.String()
+}
+
+// validateNotBefore validates that a date is not before the date in the field named by the param
+// Dates are YYYY-MM-DD, which compare as strings; an empty param field always passes.
+func validateNotBefore(fl validator.FieldLevel) bool {
+	other := fl.Parent().FieldByName(fl.Param())
+	if !other.IsValid() {
+		return false
+	}
+	return other.String() == "" || fl.Field().String() >= other.String()
+}
+
 // validateBulmaColor validates Bulma CSS color names
 func validateBulmaColor(fl validator.FieldLevel) bool {
 	color := fl.Field().String()
@@ -160,3 +196,43 @@ func validateTimezone(fl validator.FieldLevel) bool {
 	// In production, you might want to check against time.LoadLocation
 	return len(timezone) > 0 && len(timezone) < 100
 }
+
+// handlePattern matches public handles: lowercase letters, numbers and inner hyphens
+var handlePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,30}[a-z0-9]$`)
+
+// validateHandle validates the handle a context is published under (/@handle)
+func validateHandle(fl validator.FieldLevel) bool {
+	return handlePattern.MatchString(fl.Field().String())
+}
+
+// languagePattern matches the language codes Whisper accepts: ISO 639-1, or 639-2 where there is no shorter code
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// validateLanguage validates the language of a context's notes
+func validateLanguage(fl validator.FieldLevel) bool {
+	return languagePattern.MatchString(fl.Field().String())
+}
+
+// iconNamePattern matches icon names such as "briefcase" or "book-open"
+var iconNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// emojiPattern matches one emoji: a flag (a pair of regional indicators), or
+// symbols joined with ZWJs, each followed by an optional variation selector,
+// skin tone, keycap or tag sequence. Two emoji side by side don't match.
+var emojiPattern = regexp.MustCompile(`^(?:[\x{1F1E6}-\x{1F1FF}]{2}|` + emojiPart + `(?:\x{200D}` + emojiPart + `)*)$`)
+
+// emojiPart is a symbol with its modifiers, see emojiPattern
+const emojiPart = `\p{So}\x{FE0F}?[\x{1F3FB}-\x{1F3FF}]?\x{20E3}?(?:[\x{E0020}-\x{E007E}]+\x{E007F})?`
+
+// maxIconRunes bounds emoji sequences, long enough for family and flag emoji
+const maxIconRunes = 16
+
+// validateContextIcon validates the icon of a context: one emoji or an icon name,
+// or empty for none (which pointer fields set to "" reach despite omitempty)
+func validateContextIcon(fl validator.FieldLevel) bool {
+	icon := fl.Field().String()
+	if icon == "" || len(icon) <= 40 && iconNamePattern.MatchString(icon) {
+		return true
+	}
+	return utf8.RuneCountInString(icon) <= maxIconRunes && emojiPattern.MatchString(icon)
+}
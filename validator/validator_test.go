@@ -1,6 +1,8 @@
 package validator
 
 import (
+	"daily-notes/models"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -118,6 +120,83 @@ func TestValidator_CreateNote(t *testing.T) {
 	}
 }
 
+type TestPeriodNoteRequest struct {
+	Type string `json:"type" validate:"required,oneof=week"`
+	Key  string `json:"key" validate:"required,periodkey=Type"`
+}
+
+func TestValidator_PeriodKey(t *testing.T) {
+	v := New()
+
+	assert.NoError(t, v.Validate(&TestPeriodNoteRequest{Type: "week", Key: "2025-W42"}))
+
+	err := v.Validate(&TestPeriodNoteRequest{Type: "week", Key: "2025-10-17"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "key must be a valid key")
+
+	assert.Error(t, v.Validate(&TestPeriodNoteRequest{Type: "week", Key: "2025-W60"}))
+}
+
+type TestDateRangeRequest struct {
+	From string `json:"from" validate:"omitempty,dateformat"`
+	To   string `json:"to" validate:"omitempty,dateformat,notbefore=From"`
+}
+
+func TestValidator_NotBefore(t *testing.T) {
+	v := New()
+
+	assert.NoError(t, v.Validate(&TestDateRangeRequest{From: "2025-10-01", To: "2025-10-31"}))
+	assert.NoError(t, v.Validate(&TestDateRangeRequest{From: "2025-10-01", To: "2025-10-01"}))
+	assert.NoError(t, v.Validate(&TestDateRangeRequest{To: "2025-10-31"}))
+	assert.NoError(t, v.Validate(&TestDateRangeRequest{From: "2025-10-01"}))
+
+	err := v.Validate(&TestDateRangeRequest{From: "2025-10-31", To: "2025-10-01"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "to must not be before from")
+}
+
+type TestContextLanguageRequest struct {
+	Language string `json:"language" validate:"omitempty,language"`
+}
+
+func TestValidator_Language(t *testing.T) {
+	v := New()
+
+	assert.NoError(t, v.Validate(&TestContextLanguageRequest{Language: "es"}))
+	assert.NoError(t, v.Validate(&TestContextLanguageRequest{Language: "haw"}))
+	assert.NoError(t, v.Validate(&TestContextLanguageRequest{}))
+
+	err := v.Validate(&TestContextLanguageRequest{Language: "Spanish"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "language must be a lowercase ISO 639-1 language code")
+}
+
+type TestContextIconRequest struct {
+	Icon string `json:"icon" validate:"omitempty,contexticon"`
+}
+
+func TestValidator_ContextIcon(t *testing.T) {
+	v := New()
+
+	for _, icon := range []string{"", "briefcase", "book-open", "📚", "❤️", "👍🏽", "👩‍💻", "🇪🇸", "🏳️‍🌈", "🏴󠁧󠁢󠁳󠁣󠁴󠁿"} {
+		assert.NoError(t, v.Validate(&TestContextIconRequest{Icon: icon}), icon)
+	}
+	for _, icon := range []string{"Briefcase", "book open", "-book", "a📚", "📚 notes", "<svg>", "📚📚", "👍🏽👍🏽", "🇪🇸🇪", strings.Repeat("📚", 20)} {
+		err := v.Validate(&TestContextIconRequest{Icon: icon})
+		assert.Error(t, err, icon)
+	}
+
+	err := v.Validate(&TestContextIconRequest{Icon: "my icon"})
+	assert.Contains(t, err.Error(), "icon must be one emoji or an icon name")
+
+	// Updates clear the icon with an empty one and keep it without any
+	clear, keep := "", (*string)(nil)
+	assert.NoError(t, v.Validate(&models.UpdateContextRequest{Name: "Work", Color: "info", Icon: &clear}))
+	assert.NoError(t, v.Validate(&models.UpdateContextRequest{Name: "Work", Color: "info", Icon: keep}))
+	bad := "not an icon"
+	assert.Error(t, v.Validate(&models.UpdateContextRequest{Name: "Work", Color: "info", Icon: &bad}))
+}
+
 func TestValidator_CreateContext(t *testing.T) {
 	v := New()
 